@@ -0,0 +1,82 @@
+// Package admin implements the operator tasks served by the admin API.
+//
+// These are the things operators would otherwise do by hand against a
+// service's database: deleting manifests, running updaters or garbage
+// collection on demand, and running migrations.
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/remind101/migrate"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// ErrNotConfigured is returned for tasks needing a feature that isn't
+// configured.
+var ErrNotConfigured = clairerror.New(clairerror.Unimplemented, "admin: not configured")
+
+// Migrations is a set of migrations recorded in a single table.
+type Migrations struct {
+	Table      string
+	Migrations []migrate.Migration
+}
+
+// MigrationStatus reports the version of a set of migrations.
+type MigrationStatus struct {
+	Table   string `json:"table"`
+	Version int    `json:"version"`
+}
+
+// Migrator runs a service's migrations on demand.
+type Migrator struct {
+	connString string
+	sets       []Migrations
+}
+
+// NewMigrator returns a Migrator running the provided sets of migrations, in
+// order, against the database at connString.
+func NewMigrator(connString string, sets ...Migrations) *Migrator {
+	return &Migrator{
+		connString: connString,
+		sets:       sets,
+	}
+}
+
+// Migrate runs any outstanding migrations and reports the resulting version
+// of each set.
+func (m *Migrator) Migrate(ctx context.Context) ([]MigrationStatus, error) {
+	db, err := sql.Open("pgx", m.connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db: %w", err)
+	}
+	defer db.Close()
+	st := make([]MigrationStatus, 0, len(m.sets))
+	for _, set := range m.sets {
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = set.Table
+		if err := migrator.Exec(migrate.Up, set.Migrations...); err != nil {
+			return nil, fmt.Errorf("failed to perform %s migrations: %w", set.Table, err)
+		}
+		s := MigrationStatus{Table: set.Table}
+		// The table name can't be a parameter, but it's never user input.
+		q := fmt.Sprintf(`SELECT COALESCE(max(version), 0) FROM %s;`, set.Table)
+		if err := db.QueryRowContext(ctx, q).Scan(&s.Version); err != nil {
+			return nil, fmt.Errorf("failed to read %s version: %w", set.Table, err)
+		}
+		st = append(st, s)
+	}
+	return st, nil
+}
+
+// Services are the admin interfaces for the services run by a process.
+// Members for services not running locally are nil.
+type Services struct {
+	Indexer  *Indexer
+	Matcher  *Matcher
+	Notifier *Migrator
+}
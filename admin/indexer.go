@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer/gc"
+)
+
+// Indexer performs administrative tasks against the indexer database.
+type Indexer struct {
+	*Migrator
+	pool      *pgxpool.Pool
+	collector *gc.Collector
+}
+
+// NewIndexer returns an Indexer using the database behind pool, which must be
+// the indexer's database.
+//
+// The Collector is run by GC, and may be nil if garbage collection isn't
+// configured.
+func NewIndexer(pool *pgxpool.Pool, c *gc.Collector, m *Migrator) *Indexer {
+	return &Indexer{
+		Migrator:  m,
+		pool:      pool,
+		collector: c,
+	}
+}
+
+// DeleteManifests removes the manifests and their index reports, reporting
+// which manifests existed. Layers no other manifest uses are removed as well.
+func (i *Indexer) DeleteManifests(ctx context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+	deleted, _, err := gc.Delete(ctx, i.pool, ds...)
+	return deleted, err
+}
+
+// GC runs a full garbage collection, reporting how many manifests and layers
+// were removed.
+//
+// ErrNotConfigured is returned if garbage collection isn't configured.
+func (i *Indexer) GC(ctx context.Context) (manifests, layers int64, err error) {
+	if i.collector == nil {
+		return 0, 0, ErrNotConfigured
+	}
+	return i.collector.CollectAll(ctx)
+}
@@ -0,0 +1,115 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/dryrun"
+)
+
+// Updater is the subset of claircore's Libvuln used by Matcher.
+type Updater interface {
+	FetchUpdates(context.Context) error
+	GCFull(context.Context) (int64, error)
+	UpdateOperations(context.Context, ...string) (map[string][]driver.UpdateOperation, error)
+	LatestUpdateOperations(context.Context) (map[string][]driver.UpdateOperation, error)
+}
+
+// Matcher performs administrative tasks against the matcher database.
+type Matcher struct {
+	*Migrator
+	u Updater
+	r *dryrun.Runner
+}
+
+// NewMatcher returns a Matcher running updates and garbage collection with
+// the provided Updater, and dry runs with the provided Runner. Dry runs
+// report ErrNotConfigured if r is nil.
+func NewMatcher(u Updater, r *dryrun.Runner, m *Migrator) *Matcher {
+	return &Matcher{
+		Migrator: m,
+		u:        u,
+		r:        r,
+	}
+}
+
+// RunUpdaters runs every configured updater once, reporting the new update
+// operation for each updater that found changes.
+//
+// This blocks until all the updaters finish.
+func (m *Matcher) RunUpdaters(ctx context.Context) (map[string]uuid.UUID, error) {
+	prev, err := m.u.LatestUpdateOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.u.FetchUpdates(ctx); err != nil {
+		return nil, err
+	}
+	cur, err := m.u.LatestUpdateOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	updated := make(map[string]uuid.UUID)
+	for name, ops := range cur {
+		if len(ops) == 0 {
+			continue
+		}
+		ref := ops[0].Ref
+		if p := prev[name]; len(p) != 0 && p[0].Ref == ref {
+			continue
+		}
+		updated[name] = ref
+	}
+	return updated, nil
+}
+
+// GC runs garbage collection to completion, reporting how many update
+// operations were removed.
+func (m *Matcher) GC(ctx context.Context) (int64, error) {
+	before, err := m.count(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := m.u.GCFull(ctx); err != nil {
+		return 0, err
+	}
+	after, err := m.count(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return before - after, nil
+}
+
+// Count reports the number of update operations.
+func (m *Matcher) count(ctx context.Context) (int64, error) {
+	ops, err := m.u.UpdateOperations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, os := range ops {
+		n += int64(len(os))
+	}
+	return n, nil
+}
+
+// DryRun matches the index report against the snapshot, reporting how the
+// report's current vulnerability report, from s, would change if the
+// snapshot replaced its updaters' data.
+//
+// Nothing is written to the database.
+func (m *Matcher) DryRun(ctx context.Context, snap *dryrun.Snapshot, ir *claircore.IndexReport, s matcher.Scanner) (*dryrun.Diff, error) {
+	if m.r == nil {
+		return nil, ErrNotConfigured
+	}
+	cur, err := s.Scan(ctx, ir)
+	if err != nil {
+		return nil, fmt.Errorf("admin: failed to get current report: %w", err)
+	}
+	return m.r.Run(ctx, snap, ir, cur)
+}
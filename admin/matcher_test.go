@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// FakeUpdater has a single updater, "test", which finds a change every run
+// and keeps the two latest update operations.
+type fakeUpdater struct {
+	ops []driver.UpdateOperation
+}
+
+func (f *fakeUpdater) FetchUpdates(_ context.Context) error {
+	f.ops = append([]driver.UpdateOperation{{Ref: uuid.New(), Updater: "test"}}, f.ops...)
+	return nil
+}
+
+func (f *fakeUpdater) GCFull(_ context.Context) (int64, error) {
+	if len(f.ops) > 2 {
+		f.ops = f.ops[:2]
+	}
+	return 0, nil
+}
+
+func (f *fakeUpdater) UpdateOperations(_ context.Context, _ ...string) (map[string][]driver.UpdateOperation, error) {
+	return map[string][]driver.UpdateOperation{"test": f.ops}, nil
+}
+
+func (f *fakeUpdater) LatestUpdateOperations(_ context.Context) (map[string][]driver.UpdateOperation, error) {
+	if len(f.ops) == 0 {
+		return map[string][]driver.UpdateOperation{}, nil
+	}
+	return map[string][]driver.UpdateOperation{"test": f.ops[:1]}, nil
+}
+
+func TestMatcher(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeUpdater{}
+	m := NewMatcher(f, nil, nil)
+
+	for i := 0; i < 4; i++ {
+		updated, err := m.RunUpdaters(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := updated["test"], f.ops[0].Ref; got != want {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	}
+
+	n, err := m.GC(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(2); got != want {
+		t.Errorf("got: %d collected, want: %d", got, want)
+	}
+}
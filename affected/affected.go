@@ -0,0 +1,241 @@
+// Package affected looks up the vulnerabilities in the matcher's database by
+// name, so the indexer can be asked which manifests they affect.
+package affected
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+// Finder returns the current vulnerabilities with a name or ID.
+type Finder interface {
+	// Vulnerabilities returns the vulnerabilities named "id", or with the
+	// database ID "id". If namespace is not empty, only vulnerabilities
+	// from the updater or distribution with that name are returned.
+	Vulnerabilities(ctx context.Context, id, namespace string) ([]claircore.Vulnerability, error)
+}
+
+// Store is a Finder using the matcher's database.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+var _ Finder = (*Store)(nil)
+
+// NewStore returns a Store using the matcher database behind pool.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Only vulnerabilities from each updater's latest run are current; older
+// rows are kept around for diffs until they're garbage collected.
+const selectVulnerabilities = `
+WITH latest AS (
+	SELECT DISTINCT ON (updater) id
+	FROM update_operation
+	ORDER BY updater, id DESC
+)
+SELECT DISTINCT
+	vuln.id,
+	vuln.name,
+	vuln.updater,
+	vuln.description,
+	vuln.issued,
+	vuln.links,
+	vuln.severity,
+	vuln.normalized_severity,
+	vuln.package_name,
+	vuln.package_version,
+	vuln.package_module,
+	vuln.package_arch,
+	vuln.package_kind,
+	vuln.dist_id,
+	vuln.dist_name,
+	vuln.dist_version,
+	vuln.dist_version_code_name,
+	vuln.dist_version_id,
+	vuln.dist_arch,
+	vuln.dist_cpe,
+	vuln.dist_pretty_name,
+	vuln.arch_operation,
+	vuln.repo_name,
+	vuln.repo_key,
+	vuln.repo_uri,
+	vuln.fixed_in_version
+FROM vuln
+JOIN uo_vuln ON uo_vuln.vuln = vuln.id
+JOIN latest ON latest.id = uo_vuln.uo
+WHERE (vuln.name = $1 OR vuln.id = $2)
+	AND ($3 = '' OR vuln.updater = $3 OR vuln.dist_id = $3)
+ORDER BY vuln.id;`
+
+// Vulnerabilities implements Finder.
+func (s *Store) Vulnerabilities(ctx context.Context, id, namespace string) ([]claircore.Vulnerability, error) {
+	// An ID that isn't a number can only match a name.
+	dbID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		dbID = -1
+	}
+	rows, err := s.pool.Query(ctx, selectVulnerabilities, id, dbID, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("affected: failed to look up vulnerabilities: %w", err)
+	}
+	defer rows.Close()
+	var out []claircore.Vulnerability
+	for rows.Next() {
+		v, err := scanVulnerability(rows)
+		if err != nil {
+			return nil, fmt.Errorf("affected: failed to look up vulnerabilities: %w", err)
+		}
+		out = append(out, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("affected: failed to look up vulnerabilities: %w", err)
+	}
+	return out, nil
+}
+
+// Exporter streams the current vulnerabilities in a namespace.
+type Exporter interface {
+	// Export calls fn for every current vulnerability from the updater or
+	// distribution named by namespace, in order of ID. A distribution may
+	// be qualified with its version ID, as in "rhel:9". Exporting stops at
+	// the first error fn returns.
+	Export(ctx context.Context, namespace string, fn func(*claircore.Vulnerability) error) error
+}
+
+var _ Exporter = (*Store)(nil)
+
+const selectNamespace = `
+WITH latest AS (
+	SELECT DISTINCT ON (updater) id
+	FROM update_operation
+	ORDER BY updater, id DESC
+)
+SELECT DISTINCT
+	vuln.id,
+	vuln.name,
+	vuln.updater,
+	vuln.description,
+	vuln.issued,
+	vuln.links,
+	vuln.severity,
+	vuln.normalized_severity,
+	vuln.package_name,
+	vuln.package_version,
+	vuln.package_module,
+	vuln.package_arch,
+	vuln.package_kind,
+	vuln.dist_id,
+	vuln.dist_name,
+	vuln.dist_version,
+	vuln.dist_version_code_name,
+	vuln.dist_version_id,
+	vuln.dist_arch,
+	vuln.dist_cpe,
+	vuln.dist_pretty_name,
+	vuln.arch_operation,
+	vuln.repo_name,
+	vuln.repo_key,
+	vuln.repo_uri,
+	vuln.fixed_in_version
+FROM vuln
+JOIN uo_vuln ON uo_vuln.vuln = vuln.id
+JOIN latest ON latest.id = uo_vuln.uo
+WHERE vuln.updater = $1
+	OR vuln.dist_id = $1
+	OR vuln.dist_id || ':' || vuln.dist_version_id = $1
+ORDER BY vuln.id;`
+
+// Export implements Exporter.
+//
+// Rows are read as they're returned, so a namespace doesn't need to fit in
+// memory.
+func (s *Store) Export(ctx context.Context, namespace string, fn func(*claircore.Vulnerability) error) error {
+	rows, err := s.pool.Query(ctx, selectNamespace, namespace)
+	if err != nil {
+		return fmt.Errorf("affected: failed to export vulnerabilities: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		v, err := scanVulnerability(rows)
+		if err != nil {
+			return fmt.Errorf("affected: failed to export vulnerabilities: %w", err)
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("affected: failed to export vulnerabilities: %w", err)
+	}
+	return nil
+}
+
+// ScanVulnerability reads a vulnerability from a row with the columns
+// selected above.
+func scanVulnerability(rows pgx.Rows) (*claircore.Vulnerability, error) {
+	v := claircore.Vulnerability{
+		Package: &claircore.Package{},
+		Dist:    &claircore.Distribution{},
+		Repo:    &claircore.Repository{},
+	}
+	var id int64
+	if err := rows.Scan(
+		&id,
+		&v.Name,
+		&v.Updater,
+		&v.Description,
+		&v.Issued,
+		&v.Links,
+		&v.Severity,
+		&v.NormalizedSeverity,
+		&v.Package.Name,
+		&v.Package.Version,
+		&v.Package.Module,
+		&v.Package.Arch,
+		&v.Package.Kind,
+		&v.Dist.DID,
+		&v.Dist.Name,
+		&v.Dist.Version,
+		&v.Dist.VersionCodeName,
+		&v.Dist.VersionID,
+		&v.Dist.Arch,
+		&v.Dist.CPE,
+		&v.Dist.PrettyName,
+		&v.ArchOperation,
+		&v.Repo.Name,
+		&v.Repo.Key,
+		&v.Repo.URI,
+		&v.FixedInVersion,
+	); err != nil {
+		return nil, err
+	}
+	v.ID = strconv.FormatInt(id, 10)
+	return &v, nil
+}
+
+// Matcher wraps a matcher.Service, adding vulnerability lookups.
+type Matcher struct {
+	matcher.Service
+	Finder
+}
+
+var _ matcher.Service = (*Matcher)(nil)
+
+// NewMatcher returns a Matcher looking up vulnerabilities with f.
+func NewMatcher(s matcher.Service, f Finder) *Matcher {
+	return &Matcher{Service: s, Finder: f}
+}
+
+// Unwrap returns the wrapped matcher.Service.
+func (m *Matcher) Unwrap() matcher.Service {
+	return m.Service
+}
@@ -0,0 +1,136 @@
+// Package archive copies generated index and vulnerability reports to object
+// storage, for long-term audit and offline analysis.
+//
+// Reports are written asynchronously, so a slow or unavailable bucket
+// doesn't slow down the API. If the queue of pending writes fills up, new
+// reports are dropped and counted.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// QueueSize is the number of reports waiting to be written before new
+	// ones are dropped.
+	QueueSize = 256
+	// Workers is the number of concurrent writes.
+	Workers = 4
+	// WriteTimeout bounds a single write.
+	WriteTimeout = time.Minute
+)
+
+// Store is an object store reports are written to.
+type Store interface {
+	// Put writes the JSON object at key, replacing any existing object.
+	Put(ctx context.Context, key string, body []byte) error
+}
+
+// Archiver queues reports and writes them to a Store.
+type Archiver struct {
+	store  Store
+	prefix string
+	queue  chan object
+
+	written metric.Int64Counter
+	dropped metric.Int64Counter
+	failed  metric.Int64Counter
+}
+
+// Object is a pending write.
+type object struct {
+	key  string
+	body []byte
+}
+
+// NewArchiver returns an Archiver writing objects under prefix in the
+// Store. Writes happen until the context is canceled.
+func NewArchiver(ctx context.Context, s Store, prefix string) *Archiver {
+	meter := metric.Must(otel.Meter("clair"))
+	a := &Archiver{
+		store:  s,
+		prefix: prefix,
+		queue:  make(chan object, QueueSize),
+		written: meter.NewInt64Counter(
+			"clair_archive_objects_written_total",
+			metric.WithDescription("number of reports written to the archive"),
+		),
+		dropped: meter.NewInt64Counter(
+			"clair_archive_objects_dropped_total",
+			metric.WithDescription("number of reports dropped because the archive queue was full"),
+		),
+		failed: meter.NewInt64Counter(
+			"clair_archive_objects_failed_total",
+			metric.WithDescription("number of reports that failed to be written to the archive"),
+		),
+	}
+	for i := 0; i < Workers; i++ {
+		go a.run(ctx)
+	}
+	return a
+}
+
+// IndexReportKey returns the key an index report is archived under. Index
+// reports don't change once finished, so only the latest is kept.
+func (a *Archiver) IndexReportKey(d claircore.Digest) string {
+	return path.Join(a.prefix, "index_report", d.String()+".json")
+}
+
+// VulnerabilityReportKey returns the key a vulnerability report generated
+// at time t is archived under. Every report is kept, so the history of a
+// manifest's findings can be reconstructed.
+func (a *Archiver) VulnerabilityReportKey(d claircore.Digest, t time.Time) string {
+	return path.Join(a.prefix, "vulnerability_report", d.String(), t.UTC().Format("20060102T150405.000000000Z")+".json")
+}
+
+// Archive queues v to be written as JSON at key.
+func (a *Archiver) Archive(ctx context.Context, key string, v interface{}) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "archive/Archiver.Archive").
+		Str("key", key).
+		Logger()
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to encode report")
+		a.failed.Add(ctx, 1)
+		return
+	}
+	select {
+	case a.queue <- object{key: key, body: b}:
+	default:
+		log.Warn().Msg("archive queue full, dropping report")
+		a.dropped.Add(ctx, 1)
+	}
+}
+
+// Run writes queued objects until the context is canceled.
+func (a *Archiver) run(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "archive/Archiver.run").
+		Logger()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case o := <-a.queue:
+			wctx, done := context.WithTimeout(ctx, WriteTimeout)
+			err := a.store.Put(wctx, o.key, o.body)
+			done()
+			if err != nil {
+				log.Warn().Err(err).Str("key", o.key).Msg("failed to archive report")
+				a.failed.Add(ctx, 1)
+				continue
+			}
+			log.Debug().Str("key", o.key).Msg("archived report")
+			a.written.Add(ctx, 1)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package archive
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/internal/gcp"
+)
+
+type memStore struct {
+	sync.Mutex
+	objs map[string][]byte
+	done chan struct{}
+}
+
+func (m *memStore) Put(_ context.Context, key string, body []byte) error {
+	m.Lock()
+	defer m.Unlock()
+	m.objs[key] = body
+	m.done <- struct{}{}
+	return nil
+}
+
+func TestArchiver(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	st := &memStore{
+		objs: make(map[string][]byte),
+		done: make(chan struct{}, 1),
+	}
+	a := NewArchiver(ctx, st, "clair")
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	key := a.IndexReportKey(d)
+	if got, want := key, "clair/index_report/sha256:"+strings.Repeat("a", 64)+".json"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	a.Archive(ctx, key, &claircore.IndexReport{Hash: d, Success: true})
+	select {
+	case <-st.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for write")
+	}
+	st.Lock()
+	defer st.Unlock()
+	var ir claircore.IndexReport
+	if err := json.Unmarshal(st.objs[key], &ir); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ir.Hash.String(), d.String(); got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestGCS(t *testing.T) {
+	ctx := context.Background()
+	const obj = "clair/index_report/x.json"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/token"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"tok","expires_in":3600,"token_type":"Bearer"}`))
+			return
+		case r.Header.Get("Authorization") != "Bearer tok":
+			t.Errorf("unexpected authorization: %q", r.Header.Get("Authorization"))
+		case r.URL.Path != "/upload/storage/v1/b/bucket/o":
+			t.Errorf("unexpected path: %q", r.URL.Path)
+		case r.URL.Query().Get("uploadType") != "multipart":
+			t.Errorf("unexpected query: %q", r.URL.RawQuery)
+		}
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Error(err)
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		p, err := mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var meta map[string]string
+		if err := json.NewDecoder(p).Decode(&meta); err != nil {
+			t.Error(err)
+		}
+		if got, want := meta["name"], obj; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := meta["storageClass"], "NEARLINE"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		p, err = mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(p)
+		if got, want := string(b), `{}`; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(map[string]string{
+		"client_email": "clair@example.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    srv.URL + "/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa, err := gcp.ParseServiceAccount(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGCS(srv.Client(), GCSOpts{
+		Bucket:       "bucket",
+		Endpoint:     srv.URL + "/",
+		StorageClass: "NEARLINE",
+		Credentials:  sa,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Put(ctx, obj, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+
+	"github.com/quay/clair/v4/internal/gcp"
+)
+
+// DefaultGCSEndpoint is the Cloud Storage API root used if one is not
+// configured.
+const DefaultGCSEndpoint = "https://storage.googleapis.com/"
+
+// GCS is a Store writing to a Google Cloud Storage bucket.
+type GCS struct {
+	c            *http.Client
+	ts           *gcp.TokenSource
+	endpoint     *url.URL
+	bucket       string
+	storageClass string
+}
+
+var _ Store = (*GCS)(nil)
+
+// GCSOpts configures a GCS store.
+type GCSOpts struct {
+	Bucket string
+	// Endpoint is the API root. Mostly useful for emulators and testing.
+	Endpoint     string
+	StorageClass string
+	// Credentials is a service account key. If nil, credentials are obtained
+	// from the metadata server.
+	Credentials *gcp.ServiceAccount
+}
+
+// NewGCS returns a GCS store. A nil client means http.DefaultClient.
+func NewGCS(c *http.Client, opts GCSOpts) (*GCS, error) {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("gcs archive requires a bucket")
+	}
+	ep := opts.Endpoint
+	if ep == "" {
+		ep = DefaultGCSEndpoint
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse gcs endpoint: %v", err)
+	}
+	return &GCS{
+		c:            c,
+		ts:           gcp.NewTokenSource(c, opts.Credentials, gcp.StorageScope),
+		endpoint:     u,
+		bucket:       opts.Bucket,
+		storageClass: opts.StorageClass,
+	}, nil
+}
+
+// Put implements Store.
+//
+// Objects are written with a multipart upload, so that the storage class
+// can be set along with the contents.
+func (g *GCS) Put(ctx context.Context, key string, body []byte) error {
+	u, err := g.endpoint.Parse(fmt.Sprintf("upload/storage/v1/b/%s/o?uploadType=multipart", url.PathEscape(g.bucket)))
+	if err != nil {
+		return err
+	}
+	meta, err := json.Marshal(struct {
+		Name         string `json:"name"`
+		ContentType  string `json:"contentType"`
+		StorageClass string `json:"storageClass,omitempty"`
+	}{
+		Name:         key,
+		ContentType:  "application/json",
+		StorageClass: g.storageClass,
+	})
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, p := range [][]byte{meta, body} {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Type", "application/json")
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	tok, err := g.ts.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "multipart/related; boundary="+mw.Boundary())
+	req.Header.Set("Authorization", "Bearer "+tok)
+	res, err := g.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected response writing %q: %s: %s", key, res.Status, msg)
+	}
+	return nil
+}
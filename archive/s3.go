@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	awsauth "github.com/quay/clair/v4/internal/aws"
+)
+
+// S3 is a Store writing to an S3 (or S3 compatible) bucket.
+type S3 struct {
+	c            *http.Client
+	endpoint     *url.URL
+	bucket       string
+	region       string
+	pathStyle    bool
+	storageClass string
+	creds        *awsauth.Provider
+}
+
+var _ Store = (*S3)(nil)
+
+// S3Opts configures an S3 store.
+type S3Opts struct {
+	Bucket string
+	Region string
+	// Endpoint is the API root. If empty, the AWS endpoint for the region is
+	// used.
+	Endpoint string
+	// PathStyle puts the bucket in the path instead of the hostname, as some
+	// S3 compatible stores require.
+	PathStyle    bool
+	StorageClass string
+	// Credentials provides credentials for requests. If nil, the default
+	// credential chain is used.
+	Credentials *awsauth.Provider
+}
+
+// NewS3 returns an S3 store. A nil client means http.DefaultClient.
+func NewS3(c *http.Client, opts S3Opts) (*S3, error) {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("s3 archive requires a bucket")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Credentials == nil {
+		opts.Credentials = awsauth.NewProvider(c, awsauth.Credentials{}, opts.Region, "")
+	}
+	ep := opts.Endpoint
+	if ep == "" {
+		ep = fmt.Sprintf("https://s3.%s.amazonaws.com/", opts.Region)
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse s3 endpoint: %v", err)
+	}
+	return &S3{
+		c:            c,
+		endpoint:     u,
+		bucket:       opts.Bucket,
+		region:       opts.Region,
+		pathStyle:    opts.PathStyle,
+		storageClass: opts.StorageClass,
+		creds:        opts.Credentials,
+	}, nil
+}
+
+// ObjectURL returns the URL for the object at key.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return &u
+}
+
+// Put implements Store.
+func (s *S3) Put(ctx context.Context, key string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.storageClass != "" {
+		req.Header.Set("X-Amz-Storage-Class", s.storageClass)
+	}
+	creds, err := s.creds.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	awsauth.Sign(req, body, creds, s.region, "s3", time.Now())
+	res, err := s.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected response writing %q: %s: %s", key, res.Status, msg)
+	}
+	return nil
+}
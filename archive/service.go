@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"context"
+	"time"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// Indexer wraps an indexer.Service and archives the index reports it
+// finishes.
+type Indexer struct {
+	indexer.Service
+	a *Archiver
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer archiving with the Archiver.
+func NewIndexer(s indexer.Service, a *Archiver) *Indexer {
+	return &Indexer{
+		Service: s,
+		a:       a,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	ir, err := i.Service.Index(ctx, m)
+	if err == nil && ir.Success {
+		i.a.Archive(ctx, i.a.IndexReportKey(ir.Hash), ir)
+	}
+	return ir, err
+}
+
+// Matcher wraps a matcher.Service and archives the vulnerability reports it
+// generates.
+type Matcher struct {
+	matcher.Service
+	a *Archiver
+}
+
+var _ matcher.Service = (*Matcher)(nil)
+
+// NewMatcher returns a Matcher archiving with the Archiver.
+func NewMatcher(s matcher.Service, a *Archiver) *Matcher {
+	return &Matcher{
+		Service: s,
+		a:       a,
+	}
+}
+
+// Unwrap returns the wrapped matcher.Service.
+func (m *Matcher) Unwrap() matcher.Service {
+	return m.Service
+}
+
+// Scan implements matcher.Scanner.
+func (m *Matcher) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	vr, err := m.Service.Scan(ctx, ir)
+	if err == nil {
+		m.a.Archive(ctx, m.a.VulnerabilityReportKey(vr.Hash, time.Now()), vr)
+	}
+	return vr, err
+}
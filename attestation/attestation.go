@@ -0,0 +1,160 @@
+// Package attestation renders vulnerability reports as in-toto statements
+// with the cosign "vuln" predicate, and wraps them in DSSE envelopes, so
+// policy engines reading attestations from a registry can use Clair's
+// results.
+//
+// See https://github.com/sigstore/cosign/blob/main/specs/COSIGN_VULN_ATTESTATION_SPEC.md
+package attestation
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+// These are the media types of statements and envelopes.
+const (
+	StatementType = "application/vnd.in-toto+json"
+	EnvelopeType  = "application/vnd.dsse.envelope.v1+json"
+)
+
+// These identify the statement and predicate formats.
+const (
+	InTotoStatement = "https://in-toto.io/Statement/v0.1"
+	PredicateVuln   = "https://cosign.sigstore.dev/attestation/vuln/v1"
+)
+
+// ScannerURI identifies Clair as the scanner in predicates.
+const ScannerURI = "https://github.com/quay/clair"
+
+// Statement is an in-toto statement about a manifest.
+type Statement struct {
+	Type          string    `json:"_type"`
+	PredicateType string    `json:"predicateType"`
+	Subject       []Subject `json:"subject"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is the artifact a Statement is about.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate is the cosign vuln predicate.
+type Predicate struct {
+	Invocation Invocation `json:"invocation"`
+	Scanner    Scanner    `json:"scanner"`
+	Metadata   Metadata   `json:"metadata"`
+}
+
+// Invocation describes what ran the scan. Clair has nothing to report
+// here, but the member is required.
+type Invocation struct {
+	Parameters interface{} `json:"parameters"`
+	URI        string      `json:"uri"`
+	EventID    string      `json:"event_id"`
+	BuilderID  string      `json:"builder.id"`
+}
+
+// Scanner describes the scanner and holds its result.
+type Scanner struct {
+	URI     string      `json:"uri"`
+	Version string      `json:"version"`
+	DB      DB          `json:"db"`
+	Result  interface{} `json:"result"`
+}
+
+// DB describes the vulnerability database used.
+type DB struct {
+	URI     string `json:"uri"`
+	Version string `json:"version"`
+}
+
+// Metadata records when the scan happened.
+type Metadata struct {
+	ScanStartedOn  time.Time `json:"scanStartedOn"`
+	ScanFinishedOn time.Time `json:"scanFinishedOn"`
+}
+
+// NewStatement returns a Statement about the manifest, holding the report
+// as its result. The name is the subject's name, usually the repository; if
+// empty, the manifest digest is used. The database version is the latest
+// update operation the report was matched with, if known.
+func NewStatement(name string, manifest claircore.Digest, report interface{}, db string, now time.Time) *Statement {
+	if name == "" {
+		name = manifest.String()
+	}
+	now = now.UTC()
+	return &Statement{
+		Type:          InTotoStatement,
+		PredicateType: PredicateVuln,
+		Subject: []Subject{{
+			Name: name,
+			Digest: map[string]string{
+				manifest.Algorithm(): hex.EncodeToString(manifest.Checksum()),
+			},
+		}},
+		Predicate: Predicate{
+			Scanner: Scanner{
+				URI:     ScannerURI,
+				Version: "v4",
+				DB:      DB{Version: db},
+				Result:  report,
+			},
+			Metadata: Metadata{
+				ScanStartedOn:  now,
+				ScanFinishedOn: now,
+			},
+		},
+	}
+}
+
+// Envelope is a DSSE envelope.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a signature in an Envelope.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Signer produces raw signatures.
+type Signer interface {
+	SignBytes([]byte) ([]byte, error)
+	KeyID() string
+}
+
+// Seal encodes the Statement and signs it into an Envelope.
+func Seal(st *Statement, s Signer) (*Envelope, error) {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return nil, fmt.Errorf("attestation: unable to encode statement: %w", err)
+	}
+	sig, err := s.SignBytes(PAE(StatementType, b))
+	if err != nil {
+		return nil, fmt.Errorf("attestation: %w", err)
+	}
+	return &Envelope{
+		PayloadType: StatementType,
+		Payload:     base64.StdEncoding.EncodeToString(b),
+		Signatures: []Signature{{
+			KeyID: s.KeyID(),
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		}},
+	}, nil
+}
+
+// PAE returns the DSSE pre-authentication encoding of the payload, which is
+// what's signed.
+func PAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
@@ -0,0 +1,235 @@
+package clairerror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind classifies an error by what a caller can do about it.
+//
+// Kinds are errors themselves, so the kind of any error can be checked with
+// errors.Is, e.g. errors.Is(err, clairerror.NotFound). The error types in
+// this package report their kinds this way, as does Error.
+type Kind int
+
+// These are the kinds of errors. The zero Kind is Internal, which is also
+// the kind of any error that doesn't report one.
+const (
+	// Internal is an unexpected failure in Clair.
+	Internal Kind = iota
+	// NotFound means the requested thing doesn't exist.
+	NotFound
+	// Conflict means the request conflicts with the current state, e.g. a
+	// precondition didn't hold.
+	Conflict
+	// Unauthenticated means credentials were missing or not accepted.
+	Unauthenticated
+	// DependencyUnavailable means something Clair depends on, like a
+	// database or another Clair service, couldn't be reached or failed.
+	DependencyUnavailable
+	// Invalid means the request was malformed or its arguments weren't
+	// acceptable.
+	Invalid
+	// Forbidden means the caller isn't allowed to do what was asked.
+	Forbidden
+	// MethodNotAllowed means the endpoint doesn't support the request's
+	// method.
+	MethodNotAllowed
+	// NotAcceptable means no acceptable representation can be produced.
+	NotAcceptable
+	// TooLarge means the request, or something it refers to, is over a
+	// configured size limit.
+	TooLarge
+	// UnsupportedMediaType means the request's content isn't of a type
+	// that's understood.
+	UnsupportedMediaType
+	// Overloaded means Clair has too much work queued, and the request may
+	// be retried later.
+	Overloaded
+	// Unimplemented means the request needs something that isn't configured
+	// or supported.
+	Unimplemented
+)
+
+var _ error = Internal
+
+// Error implements error.
+func (k Kind) Error() string {
+	switch k {
+	case NotFound:
+		return "not found"
+	case Conflict:
+		return "conflict"
+	case Unauthenticated:
+		return "unauthenticated"
+	case DependencyUnavailable:
+		return "dependency unavailable"
+	case Invalid:
+		return "invalid"
+	case Forbidden:
+		return "forbidden"
+	case MethodNotAllowed:
+		return "method not allowed"
+	case NotAcceptable:
+		return "not acceptable"
+	case TooLarge:
+		return "too large"
+	case UnsupportedMediaType:
+		return "unsupported media type"
+	case Overloaded:
+		return "overloaded"
+	case Unimplemented:
+		return "unimplemented"
+	default:
+		return "internal error"
+	}
+}
+
+// Code returns the code errors of this kind have in API responses.
+func (k Kind) Code() string {
+	switch k {
+	case NotFound:
+		return "not-found"
+	case Conflict:
+		return "conflict"
+	case Unauthenticated:
+		return "unauthenticated"
+	case DependencyUnavailable:
+		return "dependency-unavailable"
+	case Invalid:
+		return "bad-request"
+	case Forbidden:
+		return "forbidden"
+	case MethodNotAllowed:
+		return "method-not-allowed"
+	case NotAcceptable:
+		return "not-acceptable"
+	case TooLarge:
+		return "request-entity-too-large"
+	case UnsupportedMediaType:
+		return "unsupported-media-type"
+	case Overloaded:
+		return "too-busy"
+	case Unimplemented:
+		return "not-implemented"
+	default:
+		return "internal-server-error"
+	}
+}
+
+// Status returns the HTTP status errors of this kind are reported with.
+func (k Kind) Status() int {
+	switch k {
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case DependencyUnavailable, Overloaded:
+		return http.StatusServiceUnavailable
+	case Invalid:
+		return http.StatusBadRequest
+	case Forbidden:
+		return http.StatusForbidden
+	case MethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case NotAcceptable:
+		return http.StatusNotAcceptable
+	case TooLarge:
+		return http.StatusRequestEntityTooLarge
+	case UnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case Unimplemented:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Kinds lists every Kind.
+var kinds = []Kind{
+	Internal, NotFound, Conflict, Unauthenticated, DependencyUnavailable,
+	Invalid, Forbidden, MethodNotAllowed, NotAcceptable, TooLarge,
+	UnsupportedMediaType, Overloaded, Unimplemented,
+}
+
+// KindOf returns the kind of the error, or Internal if it doesn't have one.
+//
+// If errors in the chain have different kinds, the outermost one wins: a
+// lookup failing because a dependency is down isn't a NotFound error, even
+// if the dependency reported one.
+func KindOf(err error) Kind {
+	for err != nil {
+		if k, ok := err.(Kind); ok {
+			return k
+		}
+		if x, ok := err.(interface{ Is(error) bool }); ok {
+			for _, k := range kinds {
+				if x.Is(k) {
+					return k
+				}
+			}
+		}
+		err = errors.Unwrap(err)
+	}
+	return Internal
+}
+
+// Error is an error of a particular Kind, with an optional cause.
+type Error struct {
+	Kind Kind
+	Msg  string
+	Err  error
+}
+
+// New returns an error of the kind with the message.
+func New(k Kind, msg string) error {
+	return &Error{Kind: k, Msg: msg}
+}
+
+// Wrap returns an error of the kind, caused by err. The message is prefixed
+// to err's.
+func Wrap(k Kind, err error, msg string) error {
+	return &Error{Kind: k, Msg: msg, Err: err}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Msg
+	case e.Msg == "":
+		return e.Err.Error()
+	default:
+		return e.Msg + ": " + e.Err.Error()
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is the error's Kind.
+func (e *Error) Is(target error) bool {
+	k, ok := target.(Kind)
+	return ok && k == e.Kind
+}
+
+// KindForStatus returns the kind of error an HTTP response status indicates,
+// and whether it indicates one at all.
+func KindForStatus(code int) (Kind, bool) {
+	switch code {
+	case http.StatusNotFound:
+		return NotFound, true
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return Conflict, true
+	case http.StatusUnauthorized:
+		return Unauthenticated, true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return DependencyUnavailable, true
+	}
+	if code >= 500 {
+		return Internal, true
+	}
+	return Internal, false
+}
@@ -0,0 +1,63 @@
+package clairerror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	tt := []struct {
+		Name string
+		Err  error
+		Want Kind
+	}{
+		{"Plain", errors.New("oops"), Internal},
+		{"Nil", nil, Internal},
+		{"Error", New(Conflict, "stale"), Conflict},
+		{"Wrapped", fmt.Errorf("lookup: %w", New(NotFound, "gone")), NotFound},
+		{"RequestFail", &ErrRequestFail{Code: 503}, DependencyUnavailable},
+		{"Outermost", Wrap(DependencyUnavailable, &ErrRequestFail{Code: 404}, "remote"), DependencyUnavailable},
+		{"DeliveryFailed", &ErrDeliveryFailed{E: errors.New("refused")}, DependencyUnavailable},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got, want := KindOf(tc.Err), tc.Want; got != want {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Wrap(DependencyUnavailable, cause, "database")
+	if got, want := err.Error(), "database: connection refused"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if !errors.Is(err, DependencyUnavailable) {
+		t.Error("not DependencyUnavailable")
+	}
+	if errors.Is(err, NotFound) {
+		t.Error("unexpectedly NotFound")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("cause lost")
+	}
+}
+
+func TestKinds(t *testing.T) {
+	codes := make(map[string]Kind)
+	for _, k := range kinds {
+		if prev, ok := codes[k.Code()]; ok {
+			t.Errorf("%v and %v share the code %q", prev, k, k.Code())
+		}
+		codes[k.Code()] = k
+		if k != Internal && k.Status() == Internal.Status() {
+			t.Errorf("%v: reported as an internal error", k)
+		}
+		if got := KindOf(New(k, "x")); got != k {
+			t.Errorf("got: %v, want: %v", got, k)
+		}
+	}
+}
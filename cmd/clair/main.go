@@ -4,9 +4,10 @@ import (
 	"context"
 	"flag"
 	golog "log"
+	"net/http"
 	"os"
 	"os/signal"
-	"time"
+	"syscall"
 
 	"github.com/rs/zerolog"
 	yaml "gopkg.in/yaml.v3"
@@ -60,7 +61,7 @@ func main() {
 
 	// register signal handler
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	logger.Info().Msg("registered signal handler")
 
 	// introspection server
@@ -68,7 +69,7 @@ func main() {
 		logger.Info().Msg("launching introspection server")
 		go func() {
 			err := init.Introspection.ListenAndServe()
-			if err != nil {
+			if err != nil && err != http.ErrServerClosed {
 				logger.Err(err).Msg("introspection server failed to launch. continuing anyway")
 			}
 		}()
@@ -78,7 +79,7 @@ func main() {
 	logger.Info().Msg("launching http transport")
 	go func() {
 		err := init.HttpTransport.ListenAndServe()
-		if err != nil {
+		if err != nil && err != http.ErrServerClosed {
 			logger.Err(err).Msg("http transport failed to listen and serve")
 			init.GlobalCancel()
 		}
@@ -88,15 +89,16 @@ func main() {
 	logger.Info().Str("version", Version).Msg("ready")
 	select {
 	case sig := <-c:
-		// received a SIGINT for graceful shutdown
+		// received a SIGINT or SIGTERM for graceful shutdown
 		logger.Info().
 			Str("signal", sig.String()).
+			Dur("drain_timeout", conf.Shutdown.DrainTimeout).
 			Msg("gracefully shutting down")
-		tctx, cancel := context.WithTimeout(init.GlobalCTX, 10*time.Second)
+		tctx, cancel := context.WithTimeout(context.Background(), conf.Shutdown.DrainTimeout)
 		defer cancel()
-		init.HttpTransport.Shutdown(tctx)
-		// cancel the entire application root ctx
-		init.GlobalCancel()
+		if err := init.Shutdown(tctx); err != nil {
+			logger.Warn().Err(err).Msg("error during shutdown")
+		}
 	case <-init.GlobalCTX.Done():
 		// main cancel func called indicating error initializing
 		logger.Fatal().Msg("initialization failed")
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quay/claircore"
+	je "github.com/quay/claircore/pkg/jsonerr"
+	"github.com/urfave/cli/v2"
+
+	"github.com/quay/clair/v4/httptransport"
+)
+
+// AdminCmd is the "admin" subcommand.
+var AdminCmd = &cli.Command{
+	Name: "admin",
+	Description: "Perform administrative tasks using the admin API.\n\n" +
+		"These need the admin permission, which the configured pre-shared key has.",
+	Usage: "administrative tasks",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "host",
+			Usage:   "URL for the clairv4 v1 API.",
+			Value:   "http://localhost:6060/",
+			EnvVars: []string{"CLAIR_API"},
+		},
+	},
+	Subcommands: []*cli.Command{
+		{
+			Name:      "delete-manifest",
+			Usage:     "delete manifests and their index reports",
+			ArgsUsage: "digest...",
+			Action:    adminDeleteManifestAction,
+		},
+		{
+			Name:        "run-updater",
+			Usage:       "run the updaters now",
+			Description: "Run every configured updater once, waiting for them all to finish.",
+			Action:      adminRunUpdaterAction,
+		},
+		{
+			Name:      "gc",
+			Usage:     "run garbage collection now",
+			ArgsUsage: "[indexer|matcher]...",
+			Description: "Run garbage collection to completion in the named services,\n" +
+				"or both if none are named.",
+			Action: adminGCAction,
+		},
+		{
+			Name:  "deadletter",
+			Usage: "inspect and replay notifications that failed delivery",
+			Subcommands: []*cli.Command{
+				{
+					Name:   "list",
+					Usage:  "list notifications that failed delivery",
+					Action: adminDeadLetterListAction,
+				},
+				{
+					Name:      "replay",
+					Usage:     "queue notifications for delivery again",
+					ArgsUsage: "[notification_id]...",
+					Description: "Queue the named notification ids for delivery again, or every\n" +
+						"notification that failed delivery if none are named.\n\n" +
+						"Notifications already delivered may be replayed, too.",
+					Action: adminDeadLetterReplayAction,
+				},
+			},
+		},
+		{
+			Name:      "migrate",
+			Usage:     "run outstanding database migrations",
+			ArgsUsage: "[indexer|matcher|notifier]...",
+			Description: "Run outstanding database migrations in the named services,\n" +
+				"or all of them if none are named.",
+			Action: adminMigrateAction,
+		},
+	},
+}
+
+// Admin makes a request to the admin API, decoding the response into out if
+// it's not nil.
+func (c *Client) admin(ctx context.Context, method, p string, out interface{}) error {
+	u, err := c.host.Parse(p)
+	if err != nil {
+		debug.Printf("unable to construct admin url: %v", err)
+		return err
+	}
+	req := c.request(ctx, u, method)
+	res, err := c.client.Do(req)
+	if res != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		debug.Printf("request failed for url %q: %v", req.URL.String(), err)
+		return err
+	}
+	debug.Printf("%s %s: %s", res.Request.Method, res.Request.URL.Path, res.Status)
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+	default:
+		var e je.Response
+		if err := json.NewDecoder(res.Body).Decode(&e); err == nil && e.Message != "" {
+			return &adminError{Code: res.StatusCode, Message: e.Message}
+		}
+		return &adminError{Code: res.StatusCode, Message: res.Status}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		debug.Printf("unable to decode json payload: %v", err)
+		return err
+	}
+	return nil
+}
+
+// AdminError is an error response from the admin API.
+type adminError struct {
+	Code    int
+	Message string
+}
+
+func (e *adminError) Error() string {
+	return fmt.Sprintf("unexpected return status: %d: %s", e.Code, e.Message)
+}
+
+// AdminServices returns the services named in the arguments, or all of the
+// provided services if none are.
+func adminServices(c *cli.Context, all ...string) ([]string, error) {
+	if c.Args().Len() == 0 {
+		return all, nil
+	}
+	for _, a := range c.Args().Slice() {
+		ok := false
+		for _, s := range all {
+			ok = ok || a == s
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown service %q", a)
+		}
+	}
+	return c.Args().Slice(), nil
+}
+
+func adminDeleteManifestAction(c *cli.Context) error {
+	args := c.Args()
+	if args.Len() == 0 {
+		return errors.New("missing needed arguments")
+	}
+	ds := make([]claircore.Digest, args.Len())
+	for i := range ds {
+		var err error
+		ds[i], err = claircore.ParseDigest(args.Get(i))
+		if err != nil {
+			return err
+		}
+	}
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	var failed bool
+	for _, d := range ds {
+		err := cc.admin(c.Context, http.MethodDelete, path.Join(httptransport.ManifestAdminPath, d.String()), nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", d, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("deleted %s\n", d)
+	}
+	if failed {
+		return errors.New("some manifests were not deleted")
+	}
+	return nil
+}
+
+func adminRunUpdaterAction(c *cli.Context) error {
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	var res httptransport.UpdaterRunResponse
+	if err := cc.admin(c.Context, http.MethodPost, httptransport.UpdaterRunPath, &res); err != nil {
+		return err
+	}
+	if len(res.Updated) == 0 {
+		fmt.Println("no updaters found changes")
+		return nil
+	}
+	names := make([]string, 0, len(res.Updated))
+	for n := range res.Updated {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(tw, "UPDATER\tUPDATE OPERATION")
+	for _, n := range names {
+		fmt.Fprintf(tw, "%s\t%s\n", n, res.Updated[n])
+	}
+	return tw.Flush()
+}
+
+func adminGCAction(c *cli.Context) error {
+	svcs, err := adminServices(c, "indexer", "matcher")
+	if err != nil {
+		return err
+	}
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	for _, s := range svcs {
+		switch s {
+		case "indexer":
+			var res httptransport.IndexerGCResponse
+			err := cc.admin(c.Context, http.MethodPost, httptransport.IndexerGCPath, &res)
+			var ae *adminError
+			if errors.As(err, &ae) && ae.Code == http.StatusNotImplemented {
+				fmt.Println("indexer: garbage collection not configured")
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("indexer: %w", err)
+			}
+			fmt.Printf("indexer: removed %d manifests, %d layers\n", res.Manifests, res.Layers)
+		case "matcher":
+			var res httptransport.MatcherGCResponse
+			if err := cc.admin(c.Context, http.MethodPost, httptransport.MatcherGCPath, &res); err != nil {
+				return fmt.Errorf("matcher: %w", err)
+			}
+			fmt.Printf("matcher: removed %d update operations\n", res.UpdateOperations)
+		}
+	}
+	return nil
+}
+
+func adminDeadLetterListAction(c *cli.Context) error {
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	var res httptransport.DeadLetterResponse
+	if err := cc.admin(c.Context, http.MethodGet, httptransport.DeadLetterPath, &res); err != nil {
+		return err
+	}
+	return writeDeadLetters(os.Stdout, res.DeadLetters)
+}
+
+func writeDeadLetters(w io.Writer, ls []httptransport.DeadLetter) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(tw, "NOTIFICATION ID\tUPDATE OPERATION\tSINCE")
+	for _, l := range ls {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", l.NotificationID, l.UpdateOperation, l.Since.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func adminDeadLetterReplayAction(c *cli.Context) error {
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	ps := []string{httptransport.DeadLetterPath}
+	if c.Args().Len() != 0 {
+		ps = ps[:0]
+		for _, id := range c.Args().Slice() {
+			ps = append(ps, httptransport.DeadLetterPath+id)
+		}
+	}
+	var n int64
+	for _, p := range ps {
+		var res httptransport.ReplayResponse
+		if err := cc.admin(c.Context, http.MethodPost, p, &res); err != nil {
+			return err
+		}
+		n += res.Replayed
+	}
+	fmt.Printf("replayed %d notifications\n", n)
+	return nil
+}
+
+func adminMigrateAction(c *cli.Context) error {
+	svcs, err := adminServices(c, "indexer", "matcher", "notifier")
+	if err != nil {
+		return err
+	}
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	paths := map[string]string{
+		"indexer":  httptransport.IndexerMigratePath,
+		"matcher":  httptransport.MatcherMigratePath,
+		"notifier": httptransport.NotifierMigratePath,
+	}
+	for _, s := range svcs {
+		var res httptransport.MigrateResponse
+		if err := cc.admin(c.Context, http.MethodPost, paths[s], &res); err != nil {
+			return fmt.Errorf("%s: %w", s, err)
+		}
+		for _, m := range res.Migrations {
+			fmt.Printf("%s: %s at version %d\n", s, m.Table, m.Version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	je "github.com/quay/claircore/pkg/jsonerr"
+
+	"github.com/quay/clair/v4/httptransport"
+)
+
+func TestAdminClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case httptransport.MatcherGCPath:
+			w.Header().Set("content-type", "application/json")
+			w.Write([]byte(`{"update_operations":3}`))
+		default:
+			je.Error(w, &je.Response{Code: "not-implemented", Message: "could not collect index reports: not configured"}, http.StatusNotImplemented)
+		}
+	}))
+	defer srv.Close()
+	cc, err := NewClient(srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var res httptransport.MatcherGCResponse
+	if err := cc.admin(ctx, http.MethodPost, httptransport.MatcherGCPath, &res); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.UpdateOperations, int64(3); got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+
+	err = cc.admin(ctx, http.MethodPost, httptransport.IndexerGCPath, nil)
+	var ae *adminError
+	if !errors.As(err, &ae) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := ae.Code, http.StatusNotImplemented; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if got, want := ae.Message, "could not collect index reports: not configured"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
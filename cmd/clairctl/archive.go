@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/quay/claircore"
+	"github.com/urfave/cli/v2"
+)
+
+// These are the prefixes for container references naming images on local
+// disk, following the transport names skopeo uses.
+//
+// Host filesystems have no transport in skopeo, so "rootfs:" names a mounted
+// root directory and "rootfs-archive:" a tarball of one.
+const (
+	dockerArchivePrefix = "docker-archive:"
+	dockerDaemonPrefix  = "docker-daemon:"
+	ociLayoutPrefix     = "oci:"
+	rootfsPrefix        = "rootfs:"
+	rootfsArchivePrefix = "rootfs-archive:"
+)
+
+// RefNameAnnotation is the OCI image layout annotation naming an image.
+const refNameAnnotation = "org.opencontainers.image.ref.name"
+
+// IsLocalRef reports whether the container reference names an image on
+// local disk.
+func isLocalRef(r string) bool {
+	for _, p := range []string{dockerArchivePrefix, dockerDaemonPrefix, ociLayoutPrefix, rootfsPrefix, rootfsArchivePrefix} {
+		if strings.HasPrefix(r, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalImage is an image read from a docker-archive tarball, a container
+// engine, or an OCI image layout.
+type localImage struct {
+	// Hash is the digest used as the manifest digest.
+	//
+	// OCI layouts have real manifests, but docker archives don't, so the
+	// image ID (the digest of the image configuration) is used instead.
+	Hash   claircore.Digest
+	Layers []localLayer
+}
+
+// LocalLayer is a layer of a localImage.
+type localLayer struct {
+	Hash        claircore.Digest
+	ContentType string
+	Open        func() (io.ReadCloser, error)
+}
+
+// OpenLocal opens the image named by a "docker-archive:", "docker-daemon:",
+// "oci:", "rootfs:", or "rootfs-archive:" reference.
+//
+// The "docker-archive:" and "oci:" forms take a path and an optional
+// reference selecting an image, after a colon:
+// "docker-archive:image.tar:example.com/app:latest" or "oci:layout-dir:latest".
+// The reference may be omitted if there's only one image. The
+// "docker-daemon:" form takes the name or ID of an image in the local Docker
+// or Podman engine. The rootfs forms only take a path.
+func openLocal(r string) (*localImage, error) {
+	switch {
+	case strings.HasPrefix(r, rootfsPrefix):
+		return openRootfs(strings.TrimPrefix(r, rootfsPrefix))
+	case strings.HasPrefix(r, rootfsArchivePrefix):
+		return openRootfsArchive(strings.TrimPrefix(r, rootfsArchivePrefix))
+	case strings.HasPrefix(r, dockerArchivePrefix):
+		p, ref := splitLocalRef(strings.TrimPrefix(r, dockerArchivePrefix))
+		return openDockerArchive(p, ref)
+	case strings.HasPrefix(r, dockerDaemonPrefix):
+		return openDaemon(strings.TrimPrefix(r, dockerDaemonPrefix))
+	case strings.HasPrefix(r, ociLayoutPrefix):
+		p, ref := splitLocalRef(strings.TrimPrefix(r, ociLayoutPrefix))
+		return openOCILayout(p, ref)
+	}
+	return nil, fmt.Errorf("%q does not name a local image", r)
+}
+
+// SplitLocalRef splits the path from the optional image reference.
+func splitLocalRef(s string) (string, string) {
+	i := strings.IndexByte(s, ':')
+	if i == -1 {
+		return s, ""
+	}
+	return s[:i], s[i+1:]
+}
+
+func openDockerArchive(p, ref string) (*localImage, error) {
+	var tag *name.Tag
+	if ref != "" {
+		t, err := name.NewTag(ref)
+		if err != nil {
+			return nil, err
+		}
+		tag = &t
+	}
+	img, err := tarball.ImageFromPath(p, tag)
+	if err != nil {
+		return nil, err
+	}
+	return dockerArchiveImage(img)
+}
+
+// DockerArchiveImage returns a localImage for an image read from a
+// docker-archive tarball.
+func dockerArchiveImage(img v1.Image) (*localImage, error) {
+	id, err := img.ConfigName()
+	if err != nil {
+		return nil, err
+	}
+	out := localImage{}
+	out.Hash, err = claircore.ParseDigest(id.String())
+	if err != nil {
+		return nil, err
+	}
+	ls, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	// Layers are served uncompressed and identified by their diff ID, so
+	// nothing needs to be recompressed.
+	for _, l := range ls {
+		d, err := l.DiffID()
+		if err != nil {
+			return nil, err
+		}
+		ccd, err := claircore.ParseDigest(d.String())
+		if err != nil {
+			return nil, err
+		}
+		out.Layers = append(out.Layers, localLayer{
+			Hash:        ccd,
+			ContentType: "application/x-tar",
+			Open:        l.Uncompressed,
+		})
+	}
+	return &out, nil
+}
+
+func openOCILayout(p, ref string) (*localImage, error) {
+	lp, err := layout.FromPath(p)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := lp.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	im, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	var desc *v1.Descriptor
+	for i := range im.Manifests {
+		d := &im.Manifests[i]
+		if ref == "" || d.Annotations[refNameAnnotation] == ref {
+			if desc != nil {
+				return nil, errors.New("layout contains multiple images, a reference is needed")
+			}
+			desc = d
+		}
+	}
+	switch {
+	case desc == nil && ref == "":
+		return nil, errors.New("layout contains no images")
+	case desc == nil:
+		return nil, fmt.Errorf("no image %q in layout", ref)
+	case desc.MediaType == types.OCIImageIndex || desc.MediaType == types.DockerManifestList:
+		return nil, fmt.Errorf("%v is an image index, not an image", desc.Digest)
+	}
+	img, err := idx.Image(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	out := localImage{}
+	out.Hash, err = claircore.ParseDigest(desc.Digest.String())
+	if err != nil {
+		return nil, err
+	}
+	ls, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range ls {
+		d, err := l.Digest()
+		if err != nil {
+			return nil, err
+		}
+		ccd, err := claircore.ParseDigest(d.String())
+		if err != nil {
+			return nil, err
+		}
+		out.Layers = append(out.Layers, localLayer{
+			Hash: ccd,
+			// Let the indexer work out the compression.
+			ContentType: "application/octet-stream",
+			Open:        l.Compressed,
+		})
+	}
+	return &out, nil
+}
+
+// LayerServer serves the layers of local images over HTTP, so that an
+// indexer can fetch them like layers in a registry.
+//
+// The server only starts listening once the first Manifest is made.
+type layerServer struct {
+	addr      string
+	advertise string
+
+	mu     sync.Mutex
+	srv    *http.Server
+	base   *url.URL
+	layers map[string]localLayer
+}
+
+// ServeFlags are the flags for commands accepting local images.
+var serveFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "serve-addr",
+		Usage: "address to serve the layers of local images on",
+		Value: "localhost:0",
+	},
+	&cli.StringFlag{
+		Name:  "serve-url",
+		Usage: "URL the indexer should fetch the layers of local images from, if not the serve address",
+	},
+}
+
+// LayerServerFor returns the layerServer configured by the flags, and a
+// function to stop it.
+func layerServerFor(c *cli.Context) (*layerServer, func()) {
+	s := &layerServer{
+		addr:      c.String("serve-addr"),
+		advertise: c.String("serve-url"),
+		layers:    make(map[string]localLayer),
+	}
+	return s, func() { s.Close() }
+}
+
+// Manifest returns a Manifest for the image, with layer URIs pointing at the
+// server.
+func (s *layerServer) Manifest(img *localImage) (*claircore.Manifest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	m := claircore.Manifest{Hash: img.Hash}
+	for _, l := range img.Layers {
+		s.layers[l.Hash.String()] = l
+		u, err := s.base.Parse("blobs/" + l.Hash.String())
+		if err != nil {
+			return nil, err
+		}
+		m.Layers = append(m.Layers, &claircore.Layer{
+			Hash:    l.Hash,
+			URI:     u.String(),
+			Headers: make(map[string][]string),
+		})
+	}
+	return &m, nil
+}
+
+// Start begins serving, if needed. The caller must hold the lock.
+func (s *layerServer) start() error {
+	if s.srv != nil {
+		return nil
+	}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	base := s.advertise
+	if base == "" {
+		base = "http://" + ln.Addr().String()
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	s.base, err = url.Parse(base)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	s.srv = &http.Server{Handler: s}
+	go s.srv.Serve(ln)
+	debug.Printf("serving local layers at %v", s.base)
+	return nil
+}
+
+// URL reports where the server is serving layers from, or nil if it hasn't
+// started.
+func (s *layerServer) URL() *url.URL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.base
+}
+
+// ServeHTTP implements http.Handler.
+func (s *layerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	p := strings.TrimPrefix(r.URL.Path, s.base.Path)
+	if !strings.HasPrefix(p, "blobs/") {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	l, ok := s.layers[strings.TrimPrefix(p, "blobs/")]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("content-type", l.ContentType)
+	if r.Method == http.MethodHead {
+		return
+	}
+	rc, err := l.Open()
+	if err != nil {
+		debug.Printf("%v: %v", l.Hash, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+	debug.Printf("%v: serving layer to %s", l.Hash, r.RemoteAddr)
+	if _, err := io.Copy(w, rc); err != nil {
+		debug.Printf("%v: %v", l.Hash, err)
+	}
+}
+
+// Close stops the server.
+func (s *layerServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.srv == nil {
+		return nil
+	}
+	return s.srv.Shutdown(context.Background())
+}
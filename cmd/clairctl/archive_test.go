@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// TestLocalImage checks that local images are served with layer contents
+// matching the manifest.
+func TestLocalImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clairctl-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	img, err := random.Image(1024, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := name.NewTag("example.com/app:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "image.tar")
+	if err := tarball.WriteToFile(archive, tag, img); err != nil {
+		t.Fatal(err)
+	}
+	lp, err := layout.Write(filepath.Join(dir, "layout"), empty.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lp.AppendImage(img, layout.WithAnnotations(map[string]string{
+		refNameAnnotation: "latest",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	ociDigest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDigest, err := img.ConfigName()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &layerServer{addr: "localhost:0", layers: make(map[string]localLayer)}
+	defer srv.Close()
+	tt := []struct {
+		Ref  string
+		Hash string
+	}{
+		{Ref: "docker-archive:" + archive, Hash: configDigest.String()},
+		{Ref: "docker-archive:" + archive + ":example.com/app:latest", Hash: configDigest.String()},
+		{Ref: "oci:" + string(lp), Hash: ociDigest.String()},
+		{Ref: "oci:" + string(lp) + ":latest", Hash: ociDigest.String()},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Ref, func(t *testing.T) {
+			li, err := openLocal(tc.Ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := srv.Manifest(li)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := m.Hash.String(), tc.Hash; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+			if got, want := len(m.Layers), 3; got != want {
+				t.Fatalf("got: %d layers, want: %d", got, want)
+			}
+			for _, l := range m.Layers {
+				res, err := http.Get(l.URI)
+				if err != nil {
+					t.Fatal(err)
+				}
+				h := l.Hash.Hash()
+				_, err = io.Copy(h, res.Body)
+				res.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := h.Sum(nil), l.Hash.Checksum(); !bytes.Equal(got, want) {
+					t.Errorf("%v: content has checksum %x", l.Hash, got)
+				}
+			}
+		})
+	}
+
+	if _, err := openLocal("oci:" + string(lp) + ":missing"); err == nil {
+		t.Error("expected error for missing image")
+	}
+}
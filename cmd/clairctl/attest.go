@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/attestation"
+	"github.com/quay/clair/v4/httptransport"
+)
+
+var _ Formatter = (*attestationFormatter)(nil)
+
+// AttestationFormatter prints each report's signed attestation, one DSSE
+// envelope per line, as cosign's "attest --type vuln" would produce.
+type attestationFormatter struct {
+	w io.WriteCloser
+}
+
+func (f *attestationFormatter) Format(r *Result) error {
+	if r.Err != nil {
+		return fmt.Errorf("%s: %w", r.Name, r.Err)
+	}
+	b := bytes.TrimSpace(r.Attestation)
+	b = append(b, '\n')
+	_, err := f.w.Write(b)
+	return err
+}
+
+func (f *attestationFormatter) Close() error {
+	return f.w.Close()
+}
+
+// Attestation returns the manifest's vulnerability report as a signed
+// attestation about the named subject. The Clair instance must have report
+// signing configured.
+func (c *Client) Attestation(ctx context.Context, id claircore.Digest, subject string) ([]byte, error) {
+	u, err := c.host.Parse(path.Join(httptransport.VulnerabilityReportPath, id.String()))
+	if err != nil {
+		debug.Printf("unable to construct vulnerability_report url: %v", err)
+		return nil, err
+	}
+	u.RawQuery = url.Values{"subject": {subject}}.Encode()
+	req := c.request(ctx, u, http.MethodGet)
+	req.Header.Del("if-none-match")
+	req.Header.Set("accept", httptransport.AttestationEnvelopeType)
+	res, err := c.client.Do(req)
+	if res != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		debug.Printf("request failed for url %q: %v", req.URL.String(), err)
+		return nil, err
+	}
+	debug.Printf("%s %s: %s", res.Request.Method, res.Request.URL.Path, res.Status)
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotAcceptable:
+		return nil, fmt.Errorf("attestations need report signing configured in Clair")
+	default:
+		return nil, fmt.Errorf("unexpected return status: %d", res.StatusCode)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// These are used in the manifests attestations are pushed in.
+const (
+	mediaOCIManifest = `application/vnd.oci.image.manifest.v1+json`
+	mediaOCIConfig   = `application/vnd.oci.image.config.v1+json`
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType,omitempty"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+// PushAttestation adds the attestation to the image's attestations in its
+// repository, where cosign keeps them: a manifest tagged with the image's
+// digest and a ".att" suffix, with a layer per attestation.
+func pushAttestation(ctx context.Context, ref string, d claircore.Digest, env []byte) error {
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return err
+	}
+	repo := r.Context()
+	auth, err := authn.DefaultKeychain.Resolve(repo)
+	if err != nil {
+		return err
+	}
+	rt, err := transport.New(repo.Registry, auth, http.DefaultTransport, []string{repo.Scope(transport.PushScope)})
+	if err != nil {
+		return err
+	}
+	reg := &registry{
+		c:    &http.Client{Transport: rt},
+		base: fmt.Sprintf("%s://%s/v2/%s/", repo.Registry.Scheme(), repo.RegistryStr(), repo.RepositoryStr()),
+	}
+	tag := strings.Replace(d.String(), ":", "-", 1) + ".att"
+
+	m, err := reg.manifest(ctx, tag)
+	if err != nil {
+		return err
+	}
+	ld, err := reg.upload(ctx, env)
+	if err != nil {
+		return err
+	}
+	ld.MediaType = attestation.EnvelopeType
+	ld.Annotations = map[string]string{
+		"dev.cosignproject.cosign/signature": "",
+		"predicateType":                      attestation.PredicateVuln,
+	}
+	m.Layers = append(m.Layers, ld)
+
+	// Cosign's config lists the layers as an image's would.
+	var cfg struct {
+		Architecture string                 `json:"architecture"`
+		OS           string                 `json:"os"`
+		Config       map[string]interface{} `json:"config"`
+		RootFS       struct {
+			Type    string   `json:"type"`
+			DiffIDs []string `json:"diff_ids"`
+		} `json:"rootfs"`
+	}
+	cfg.Config = map[string]interface{}{}
+	cfg.RootFS.Type = "layers"
+	for _, l := range m.Layers {
+		cfg.RootFS.DiffIDs = append(cfg.RootFS.DiffIDs, l.Digest)
+	}
+	b, err := json.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+	m.Config, err = reg.upload(ctx, b)
+	if err != nil {
+		return err
+	}
+	m.Config.MediaType = mediaOCIConfig
+	debug.Printf("%s: pushing attestation to tag %q", ref, tag)
+	return reg.putManifest(ctx, tag, m)
+}
+
+// Registry is just enough of a registry client to push attestations.
+type registry struct {
+	c    *http.Client
+	base string
+}
+
+func (r *registry) do(ctx context.Context, method, u string, body []byte, h http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range h {
+		req.Header[k] = vs
+	}
+	req.Header.Set("user-agent", userAgent)
+	return r.c.Do(req)
+}
+
+// Manifest returns the manifest at tag, or an empty one if there isn't one.
+func (r *registry) manifest(ctx context.Context, tag string) (*ociManifest, error) {
+	res, err := r.do(ctx, http.MethodGet, r.base+"manifests/"+tag, nil, http.Header{"Accept": {mediaOCIManifest}})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	m := ociManifest{SchemaVersion: 2, MediaType: mediaOCIManifest}
+	switch res.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+			return nil, fmt.Errorf("malformed attestation manifest: %w", err)
+		}
+	case http.StatusNotFound:
+	default:
+		return nil, fmt.Errorf("unexpected response fetching %q: %s", tag, res.Status)
+	}
+	return &m, nil
+}
+
+// Upload pushes the blob in a single request, if it's not already present.
+func (r *registry) upload(ctx context.Context, b []byte) (ociDescriptor, error) {
+	sum := sha256.Sum256(b)
+	dg := "sha256:" + hex.EncodeToString(sum[:])
+	desc := ociDescriptor{Digest: dg, Size: int64(len(b))}
+
+	res, err := r.do(ctx, http.MethodHead, r.base+"blobs/"+dg, nil, nil)
+	if err != nil {
+		return desc, err
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return desc, nil
+	}
+
+	res, err = r.do(ctx, http.MethodPost, r.base+"blobs/uploads/", nil, nil)
+	if err != nil {
+		return desc, err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		return desc, fmt.Errorf("unexpected response starting upload: %s", res.Status)
+	}
+	loc, err := res.Request.URL.Parse(res.Header.Get("Location"))
+	if err != nil {
+		return desc, err
+	}
+	q := loc.Query()
+	q.Set("digest", dg)
+	loc.RawQuery = q.Encode()
+	res, err = r.do(ctx, http.MethodPut, loc.String(), b, http.Header{"Content-Type": {"application/octet-stream"}})
+	if err != nil {
+		return desc, err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		return desc, fmt.Errorf("unexpected response uploading blob: %s", res.Status)
+	}
+	return desc, nil
+}
+
+func (r *registry) putManifest(ctx context.Context, tag string, m *ociManifest) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	res, err := r.do(ctx, http.MethodPut, r.base+"manifests/"+tag, b, http.Header{"Content-Type": {mediaOCIManifest}})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected response pushing %q: %s: %s", tag, res.Status, msg)
+	}
+	return nil
+}
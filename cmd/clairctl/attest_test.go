@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/attestation"
+)
+
+// TestPushAttestation checks that attestations are added to the manifest
+// cosign keeps them in, next to any already there.
+func TestPushAttestation(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	blobs := make(map[string][]byte)
+	manifests := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		p := r.URL.Path
+		switch {
+		case p == "/v2/":
+		case r.Method == http.MethodHead && strings.HasPrefix(p, "/v2/app/blobs/"):
+			if _, ok := blobs[strings.TrimPrefix(p, "/v2/app/blobs/")]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPost && p == "/v2/app/blobs/uploads/":
+			w.Header().Set("Location", "/v2/app/blobs/uploads/1?state=x")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && p == "/v2/app/blobs/uploads/1":
+			if r.URL.Query().Get("state") != "x" {
+				t.Error("upload state lost")
+			}
+			b, _ := ioutil.ReadAll(r.Body)
+			blobs[r.URL.Query().Get("digest")] = b
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(p, "/v2/app/manifests/"):
+			tag := strings.TrimPrefix(p, "/v2/app/manifests/")
+			switch r.Method {
+			case http.MethodGet:
+				b, ok := manifests[tag]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Write(b)
+			case http.MethodPut:
+				b, _ := ioutil.ReadAll(r.Body)
+				manifests[tag] = b
+				w.WriteHeader(http.StatusCreated)
+			}
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, p)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/app:latest"
+	for _, env := range []string{`{"n":1}`, `{"n":2}`} {
+		if err := pushAttestation(ctx, ref, d, []byte(env)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tag := "sha256-" + strings.Repeat("a", 64) + ".att"
+	var m ociManifest
+	if err := json.Unmarshal(manifests[tag], &m); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(m.Layers), 2; got != want {
+		t.Fatalf("got: %d layers, want: %d", got, want)
+	}
+	for i, l := range m.Layers {
+		if got, want := l.MediaType, attestation.EnvelopeType; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := l.Annotations["predicateType"], attestation.PredicateVuln; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := string(blobs[l.Digest]), []string{`{"n":1}`, `{"n":2}`}[i]; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+	if _, ok := blobs[m.Config.Digest]; !ok {
+		t.Error("config not uploaded")
+	}
+}
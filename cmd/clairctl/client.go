@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
 	"sync"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/quay/claircore"
 	"github.com/tomnomnom/linkheader"
+	"github.com/urfave/cli/v2"
 
 	"github.com/quay/clair/v4/httptransport"
 )
@@ -55,6 +57,27 @@ func rt(ref string) (http.RoundTripper, error) {
 	return rt, nil
 }
 
+// ContextClient returns a Client for the "host" flag, configured for
+// authentication if a config file is present.
+func contextClient(c *cli.Context) (*Client, error) {
+	// Do we have a config?
+	fi, err := os.Stat(c.Path("config"))
+	useCfg := err == nil && !fi.IsDir()
+
+	if !useCfg {
+		return NewClient(nil, c.String("host"))
+	}
+	cfg, err := loadConfig(c.Path("config"))
+	if err != nil {
+		return nil, err
+	}
+	hc, _, err := cfg.Client(nil, commonClaim)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(hc, c.String("host"))
+}
+
 // TODO Maybe turn this into a real client, once it's proved useful.
 type Client struct {
 	host   *url.URL
@@ -216,6 +239,42 @@ func (c *Client) VulnerabilityReport(ctx context.Context, id claircore.Digest) (
 	return &report, nil
 }
 
+// Scan matches an index report that didn't come from the indexer, such as one
+// converted from an SBOM.
+func (c *Client) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(ir); err != nil {
+		debug.Printf("unable to encode json payload: %v", err)
+		return nil, err
+	}
+	u, err := c.host.Parse(httptransport.VulnerabilityReportPath)
+	if err != nil {
+		debug.Printf("unable to construct vulnerability_report url: %v", err)
+		return nil, err
+	}
+	req := c.request(ctx, u, http.MethodPost)
+	req.Header.Set("content-type", "application/json")
+	req.Body = ioutil.NopCloser(&buf)
+	res, err := c.client.Do(req)
+	if res != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		debug.Printf("request failed for url %q: %v", req.URL.String(), err)
+		return nil, err
+	}
+	debug.Printf("%s %s: %s", res.Request.Method, res.Request.URL.Path, res.Status)
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected return status: %d", res.StatusCode)
+	}
+	var report claircore.VulnerabilityReport
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		debug.Printf("unable to decode json payload: %v", err)
+		return nil, err
+	}
+	return &report, nil
+}
+
 func (c *Client) request(ctx context.Context, u *url.URL, m string) *http.Request {
 	req := &http.Request{
 		Method:     m,
@@ -229,6 +288,7 @@ func (c *Client) request(ctx context.Context, u *url.URL, m string) *http.Reques
 	}
 	req = req.WithContext(ctx)
 	req.Header.Set("user-agent", userAgent)
+	req.Header.Set(httptransport.APIVersionHeader, httptransport.LatestAPIVersion())
 	if v := c.getValidator(u.EscapedPath()); v != "" {
 		req.Header.Set("if-none-match", v)
 	}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/urfave/cli/v2"
+)
+
+// CompletionCmd is the "completion" subcommand.
+var CompletionCmd = &cli.Command{
+	Name: "completion",
+	Description: "Print a script completing clairctl's commands and flags for the named shell.\n\n" +
+		"For bash, add 'source <(clairctl completion bash)' to ~/.bashrc. For zsh,\n" +
+		"add 'source <(clairctl completion zsh)' to ~/.zshrc after compinit runs.\n" +
+		"For fish, write the output to ~/.config/fish/completions/clairctl.fish.\n\n" +
+		"Plugins are completed by running them with the \"--generate-bash-completion\"\n" +
+		"flag, which they may answer by printing one candidate per line.",
+	Action:    completionAction,
+	Usage:     "print a shell completion script",
+	ArgsUsage: "bash|zsh|fish",
+}
+
+func completionAction(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return errors.New("need exactly one shell")
+	}
+	w := c.App.Writer
+	switch sh := c.Args().First(); sh {
+	case "bash":
+		_, err := io.WriteString(w, bashCompletion)
+		return err
+	case "zsh":
+		_, err := io.WriteString(w, zshCompletion)
+		return err
+	case "fish":
+		s, err := c.App.ToFishCompletion()
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, s)
+		return err
+	default:
+		return fmt.Errorf("unsupported shell %q", sh)
+	}
+}
+
+// These are the scripts urfave/cli ships for its "--generate-bash-completion"
+// flag, with the program name filled in.
+const (
+	bashCompletion = `_clairctl_bash_autocomplete() {
+  if [[ "${COMP_WORDS[0]}" != "source" ]]; then
+    local cur opts
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    if [[ "$cur" == "-"* ]]; then
+      opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} ${cur} --generate-bash-completion )
+    else
+      opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} --generate-bash-completion )
+    fi
+    COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
+    return 0
+  fi
+}
+
+complete -o bashdefault -o default -o nospace -F _clairctl_bash_autocomplete clairctl
+`
+	zshCompletion = `#compdef clairctl
+
+_clairctl_zsh_autocomplete() {
+  local -a opts
+  local cur
+  cur=${words[-1]}
+  if [[ "$cur" == "-"* ]]; then
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} ${cur} --generate-bash-completion)}")
+  else
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} --generate-bash-completion)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  fi
+
+  return
+}
+
+compdef _clairctl_zsh_autocomplete clairctl
+`
+)
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// DaemonSockets are the engine API sockets tried, in order, when neither
+// DOCKER_HOST nor CONTAINER_HOST is set. The Podman socket is found relative
+// to XDG_RUNTIME_DIR for rootless use.
+var daemonSockets = []string{
+	"/var/run/docker.sock",
+	filepath.Join("$XDG_RUNTIME_DIR", "podman", "podman.sock"),
+	"/run/podman/podman.sock",
+}
+
+// DaemonClient returns a client talking to the local container engine's
+// Docker-compatible API, and the base URL to use with it.
+//
+// The engine is found from DOCKER_HOST or CONTAINER_HOST, which may be
+// "unix://" or "tcp://" URLs, or else the first of daemonSockets that exists.
+func daemonClient() (*http.Client, *url.URL, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = os.Getenv("CONTAINER_HOST")
+	}
+	if host == "" {
+		for _, p := range daemonSockets {
+			p = os.ExpandEnv(p)
+			if fi, err := os.Stat(p); err == nil && fi.Mode()&os.ModeSocket != 0 {
+				host = "unix://" + p
+				break
+			}
+		}
+	}
+	if host == "" {
+		return nil, nil, fmt.Errorf("no container engine socket found; set DOCKER_HOST")
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad engine address %q: %w", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		p := u.Path
+		tr := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", p)
+			},
+		}
+		// The host is ignored, but must be present.
+		return &http.Client{Transport: tr}, &url.URL{Scheme: "http", Host: "engine", Path: "/"}, nil
+	case "tcp":
+		return &http.Client{}, &url.URL{Scheme: "http", Host: u.Host, Path: "/"}, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported engine address %q", host)
+}
+
+// OpenDaemon exports the named image from the local container engine.
+//
+// The export is a docker-archive tarball, which is written to a temporary
+// file so its layers can be served more than once. As with openRootfs, the
+// file is unlinked right away.
+func openDaemon(ref string) (*localImage, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("missing image name")
+	}
+	c, base, err := daemonClient()
+	if err != nil {
+		return nil, err
+	}
+	u, err := base.Parse("images/get?" + url.Values{"names": {ref}}.Encode())
+	if err != nil {
+		return nil, err
+	}
+	debug.Printf("%s: exporting from %v", ref, base)
+	res, err := c.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(io.LimitReader(res.Body, 64*1024)).Decode(&e); err != nil || e.Message == "" {
+			e.Message = res.Status
+		}
+		return nil, fmt.Errorf("%s: engine: %s", ref, e.Message)
+	}
+
+	f, err := ioutil.TempFile("", "clairctl-daemon-")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	sz, err := io.Copy(f, res.Body)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	debug.Printf("%s: export is %d bytes", ref, sz)
+	img, err := tarball.Image(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(io.NewSectionReader(f, 0, sz)), nil
+	}, nil)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	li, err := dockerArchiveImage(img)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return li, nil
+}
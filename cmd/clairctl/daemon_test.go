@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// TestDaemon checks that images are exported from an engine listening on a
+// unix socket.
+func TestDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clairctl-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	img, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := name.NewTag("localhost/app:dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDigest, err := img.ConfigName()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(dir, "engine.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/images/get" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("names") != tag.String() {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"reference does not exist"}`))
+			return
+		}
+		if err := tarball.Write(tag, img, w); err != nil {
+			t.Error(err)
+		}
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	defer os.Setenv("DOCKER_HOST", os.Getenv("DOCKER_HOST"))
+	os.Setenv("DOCKER_HOST", "unix://"+sock)
+
+	li, err := openLocal(dockerDaemonPrefix + "localhost/app:dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := li.Hash.String(), configDigest.String(); got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := len(li.Layers), 2; got != want {
+		t.Fatalf("got: %d layers, want: %d", got, want)
+	}
+	// Layers must be readable more than once.
+	for i := 0; i < 2; i++ {
+		rc, err := li.Layers[0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := li.Layers[0].Hash.Hash()
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Write(b)
+		if got, want := h.Sum(nil), li.Layers[0].Hash.Checksum(); string(got) != string(want) {
+			t.Errorf("read %d: content has checksum %x", i, got)
+		}
+	}
+
+	_, err = openLocal(dockerDaemonPrefix + "localhost/missing:dev")
+	if err == nil {
+		t.Fatal("expected error for missing image")
+	}
+	t.Log(err)
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/quay/claircore"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+// DiffCmd is the "diff" subcommand.
+var DiffCmd = &cli.Command{
+	Name: "diff",
+	Description: "Request vulnerability reports for two manifests and print the differences between them.\n\n" +
+		"Arguments may be manifest digests already known to Clair or container\n" +
+		"references, which are indexed first. Containers may also be \"docker-archive:\"\n" +
+		"tarballs or \"oci:\" image layouts on local disk, \"docker-daemon:\" images in\n" +
+		"a local Docker or Podman engine, or host filesystems named by \"rootfs:\"\n" +
+		"directories or \"rootfs-archive:\" tarballs.",
+	Action:    diffAction,
+	Usage:     "compare the vulnerability reports of two manifests",
+	ArgsUsage: "old new",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:    "host",
+			Usage:   "URL for the clairv4 v1 API.",
+			Value:   "http://localhost:6060/",
+			EnvVars: []string{"CLAIR_API"},
+		},
+		&cli.StringFlag{
+			Name:    "out",
+			Aliases: []string{"o"},
+			Usage:   "output format: table, json",
+			Value:   "table",
+		},
+	}, serveFlags...),
+}
+
+func diffAction(c *cli.Context) error {
+	args := c.Args()
+	if args.Len() != 2 {
+		return errors.New("need exactly two arguments")
+	}
+	switch f := c.String("out"); f {
+	case "table", "json":
+	default:
+		return fmt.Errorf("unrecognized output format %q", f)
+	}
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	srv, stop := layerServerFor(c)
+	defer stop()
+
+	var reports [2]*claircore.VulnerabilityReport
+	eg, ctx := errgroup.WithContext(c.Context)
+	for i := range reports {
+		i := i
+		arg := args.Get(i)
+		eg.Go(func() error {
+			// Arguments can be either a bare digest, for manifests Clair
+			// already knows about, or a container reference.
+			d, err := claircore.ParseDigest(arg)
+			if err != nil {
+				d, err = indexRef(ctx, cc, srv, arg)
+				if err != nil {
+					return err
+				}
+			}
+			reports[i], err = cc.VulnerabilityReport(ctx, d)
+			if err != nil {
+				return fmt.Errorf("%s: %w", arg, err)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	d := diffReports(reports[0], reports[1])
+	switch c.String("out") {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	default:
+		return d.WriteTable(os.Stdout)
+	}
+}
+
+// ReportDiff is the difference between two VulnerabilityReports.
+type ReportDiff struct {
+	Old claircore.Digest `json:"old"`
+	New claircore.Digest `json:"new"`
+
+	AddedPackages   []DiffPackage `json:"added_packages"`
+	RemovedPackages []DiffPackage `json:"removed_packages"`
+	ChangedPackages []DiffPackage `json:"changed_packages"`
+
+	AddedVulnerabilities   []DiffVulnerability `json:"added_vulnerabilities"`
+	RemovedVulnerabilities []DiffVulnerability `json:"removed_vulnerabilities"`
+	ChangedVulnerabilities []DiffVulnerability `json:"changed_vulnerabilities"`
+}
+
+// DiffPackage describes a package in a ReportDiff.
+//
+// For changed packages, OldVersion is populated.
+type DiffPackage struct {
+	Name       string `json:"name"`
+	Arch       string `json:"arch,omitempty"`
+	PackageDB  string `json:"package_db,omitempty"`
+	Version    string `json:"version"`
+	OldVersion string `json:"old_version,omitempty"`
+}
+
+// DiffVulnerability describes a vulnerability affecting a package in a
+// ReportDiff.
+//
+// For changed vulnerabilities, OldSeverity and OldFixedInVersion are
+// populated.
+type DiffVulnerability struct {
+	Name              string `json:"name"`
+	Package           string `json:"package"`
+	Arch              string `json:"arch,omitempty"`
+	PackageDB         string `json:"package_db,omitempty"`
+	Severity          string `json:"severity"`
+	FixedInVersion    string `json:"fixed_in_version,omitempty"`
+	OldSeverity       string `json:"old_severity,omitempty"`
+	OldFixedInVersion string `json:"old_fixed_in_version,omitempty"`
+}
+
+// PkgKey identifies a package across reports. Package IDs are database
+// identifiers and can't be compared between reports.
+//
+// Packages of the same name from different package databases, like a
+// distribution package and a language package, are different packages.
+type pkgKey struct {
+	Name, Arch, PackageDB string
+}
+
+func keyOf(p *claircore.Package) pkgKey {
+	return pkgKey{Name: p.Name, Arch: p.Arch, PackageDB: p.PackageDB}
+}
+
+// VulnKey identifies a vulnerability affecting a package across reports.
+type vulnKey struct {
+	pkgKey
+	Vuln string
+}
+
+// ReportPackages returns the versions of every package in the report. There
+// may be more than one version of a package installed.
+func reportPackages(r *claircore.VulnerabilityReport) map[pkgKey]map[string]bool {
+	m := make(map[pkgKey]map[string]bool, len(r.Packages))
+	for _, p := range r.Packages {
+		k := keyOf(p)
+		if m[k] == nil {
+			m[k] = make(map[string]bool)
+		}
+		m[k][p.Version] = true
+	}
+	return m
+}
+
+// ReportVulns returns the vulnerabilities affecting every package in the
+// report.
+//
+// If a vulnerability affects several versions of a package, the record with
+// the highest severity is used, then the lowest fixed in version, so the
+// choice doesn't depend on map order.
+func reportVulns(r *claircore.VulnerabilityReport) map[vulnKey]*claircore.Vulnerability {
+	m := make(map[vulnKey]*claircore.Vulnerability)
+	for pkgID, vs := range r.PackageVulnerabilities {
+		p, ok := r.Packages[pkgID]
+		if !ok {
+			continue
+		}
+		for _, id := range vs {
+			v, ok := r.Vulnerabilities[id]
+			if !ok {
+				continue
+			}
+			k := vulnKey{pkgKey: keyOf(p), Vuln: v.Name}
+			if o, ok := m[k]; ok {
+				if o.NormalizedSeverity > v.NormalizedSeverity ||
+					(o.NormalizedSeverity == v.NormalizedSeverity && o.FixedInVersion <= v.FixedInVersion) {
+					continue
+				}
+			}
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// Missing returns the versions in a and not in b, sorted.
+func missing(a, b map[string]bool) []string {
+	var out []string
+	for v := range a {
+		if !b[v] {
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// DiffReports computes the differences going from report a to report b.
+//
+// A package is changed if exactly one version of it was replaced by another.
+// Otherwise, versions are reported as added or removed.
+func diffReports(a, b *claircore.VulnerabilityReport) *ReportDiff {
+	d := ReportDiff{
+		Old: a.Hash,
+		New: b.Hash,
+
+		AddedPackages:   []DiffPackage{},
+		RemovedPackages: []DiffPackage{},
+		ChangedPackages: []DiffPackage{},
+
+		AddedVulnerabilities:   []DiffVulnerability{},
+		RemovedVulnerabilities: []DiffVulnerability{},
+		ChangedVulnerabilities: []DiffVulnerability{},
+	}
+
+	ap, bp := reportPackages(a), reportPackages(b)
+	keys := make(map[pkgKey]struct{}, len(ap)+len(bp))
+	for k := range ap {
+		keys[k] = struct{}{}
+	}
+	for k := range bp {
+		keys[k] = struct{}{}
+	}
+	for k := range keys {
+		p := DiffPackage{Name: k.Name, Arch: k.Arch, PackageDB: k.PackageDB}
+		added, removed := missing(bp[k], ap[k]), missing(ap[k], bp[k])
+		if len(added) == 1 && len(removed) == 1 {
+			p.Version, p.OldVersion = added[0], removed[0]
+			d.ChangedPackages = append(d.ChangedPackages, p)
+			continue
+		}
+		for _, v := range added {
+			p.Version = v
+			d.AddedPackages = append(d.AddedPackages, p)
+		}
+		for _, v := range removed {
+			p.Version = v
+			d.RemovedPackages = append(d.RemovedPackages, p)
+		}
+	}
+
+	av, bv := reportVulns(a), reportVulns(b)
+	for k, v := range bv {
+		dv := diffVuln(k, v)
+		o, ok := av[k]
+		switch {
+		case !ok:
+			d.AddedVulnerabilities = append(d.AddedVulnerabilities, dv)
+		case o.NormalizedSeverity != v.NormalizedSeverity || o.FixedInVersion != v.FixedInVersion:
+			dv.OldSeverity = o.NormalizedSeverity.String()
+			dv.OldFixedInVersion = o.FixedInVersion
+			d.ChangedVulnerabilities = append(d.ChangedVulnerabilities, dv)
+		}
+	}
+	for k, v := range av {
+		if _, ok := bv[k]; !ok {
+			d.RemovedVulnerabilities = append(d.RemovedVulnerabilities, diffVuln(k, v))
+		}
+	}
+
+	// Map iteration is random, so sort everything for stable output.
+	for _, ps := range [][]DiffPackage{d.AddedPackages, d.RemovedPackages, d.ChangedPackages} {
+		sort.Slice(ps, func(i, j int) bool {
+			a, b := &ps[i], &ps[j]
+			switch {
+			case a.Name != b.Name:
+				return a.Name < b.Name
+			case a.Arch != b.Arch:
+				return a.Arch < b.Arch
+			case a.PackageDB != b.PackageDB:
+				return a.PackageDB < b.PackageDB
+			}
+			return a.Version < b.Version
+		})
+	}
+	for _, vs := range [][]DiffVulnerability{d.AddedVulnerabilities, d.RemovedVulnerabilities, d.ChangedVulnerabilities} {
+		sort.Slice(vs, func(i, j int) bool {
+			a, b := &vs[i], &vs[j]
+			switch {
+			case a.Package != b.Package:
+				return a.Package < b.Package
+			case a.Arch != b.Arch:
+				return a.Arch < b.Arch
+			case a.PackageDB != b.PackageDB:
+				return a.PackageDB < b.PackageDB
+			}
+			return a.Name < b.Name
+		})
+	}
+	return &d
+}
+
+func diffVuln(k vulnKey, v *claircore.Vulnerability) DiffVulnerability {
+	return DiffVulnerability{
+		Name:           k.Vuln,
+		Package:        k.Name,
+		Arch:           k.Arch,
+		PackageDB:      k.PackageDB,
+		Severity:       v.NormalizedSeverity.String(),
+		FixedInVersion: v.FixedInVersion,
+	}
+}
+
+// Label names a package in table output, with its architecture if known.
+func label(name, arch string) string {
+	if arch == "" {
+		return name
+	}
+	return name + ":" + arch
+}
+
+// WriteTable writes the diff as columnar text.
+func (d *ReportDiff) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	for _, p := range d.AddedPackages {
+		fmt.Fprintf(tw, "+\tpackage\t%s\t%s\n", label(p.Name, p.Arch), p.Version)
+	}
+	for _, p := range d.RemovedPackages {
+		fmt.Fprintf(tw, "-\tpackage\t%s\t%s\n", label(p.Name, p.Arch), p.Version)
+	}
+	for _, p := range d.ChangedPackages {
+		fmt.Fprintf(tw, "~\tpackage\t%s\t%s → %s\n", label(p.Name, p.Arch), p.OldVersion, p.Version)
+	}
+	for _, v := range d.AddedVulnerabilities {
+		fmt.Fprintf(tw, "+\tvulnerability\t%s\t%s\t%s\n", label(v.Package, v.Arch), v.Name, v.Severity)
+	}
+	for _, v := range d.RemovedVulnerabilities {
+		fmt.Fprintf(tw, "-\tvulnerability\t%s\t%s\t%s\n", label(v.Package, v.Arch), v.Name, v.Severity)
+	}
+	for _, v := range d.ChangedVulnerabilities {
+		fmt.Fprintf(tw, "~\tvulnerability\t%s\t%s\t%s → %s\n", label(v.Package, v.Arch), v.Name, v.OldSeverity, v.Severity)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestDiffReports(t *testing.T) {
+	a := &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"1": {Name: "openssl", Version: "1.1.1a"},
+			"2": {Name: "bash", Version: "5.0"},
+			"3": {Name: "curl", Version: "7.64"},
+			"4": {Name: "requests", Version: "2.20", PackageDB: "python:usr/lib/python3/site-packages"},
+			"5": {Name: "glibc", Version: "2.28", Arch: "x86_64"},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"10": {Name: "CVE-2019-0001", NormalizedSeverity: claircore.High, FixedInVersion: "1.1.1b"},
+			"11": {Name: "CVE-2019-0002", NormalizedSeverity: claircore.Low},
+			"12": {Name: "CVE-2019-0003", NormalizedSeverity: claircore.Medium},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"10"},
+			"2": {"11"},
+			"3": {"12"},
+		},
+	}
+	b := &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"4":  {Name: "openssl", Version: "1.1.1b"},
+			"5":  {Name: "bash", Version: "5.0"},
+			"6":  {Name: "zlib", Version: "1.2"},
+			"7":  {Name: "requests", Version: "2.20", PackageDB: "python:usr/lib/python3/site-packages"},
+			"8":  {Name: "requests", Version: "2.31", PackageDB: "python:opt/app/site-packages"},
+			"9":  {Name: "glibc", Version: "2.28", Arch: "x86_64"},
+			"10": {Name: "glibc", Version: "2.28", Arch: "i686"},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"20": {Name: "CVE-2019-0002", NormalizedSeverity: claircore.Critical},
+			"21": {Name: "CVE-2020-0001", NormalizedSeverity: claircore.Medium},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"5": {"20"},
+			"6": {"21"},
+		},
+	}
+
+	want := &ReportDiff{
+		AddedPackages: []DiffPackage{
+			{Name: "glibc", Arch: "i686", Version: "2.28"},
+			{Name: "requests", PackageDB: "python:opt/app/site-packages", Version: "2.31"},
+			{Name: "zlib", Version: "1.2"},
+		},
+		RemovedPackages: []DiffPackage{{Name: "curl", Version: "7.64"}},
+		ChangedPackages: []DiffPackage{{Name: "openssl", Version: "1.1.1b", OldVersion: "1.1.1a"}},
+		AddedVulnerabilities: []DiffVulnerability{
+			{Name: "CVE-2020-0001", Package: "zlib", Severity: "Medium"},
+		},
+		RemovedVulnerabilities: []DiffVulnerability{
+			{Name: "CVE-2019-0003", Package: "curl", Severity: "Medium"},
+			{Name: "CVE-2019-0001", Package: "openssl", Severity: "High", FixedInVersion: "1.1.1b"},
+		},
+		ChangedVulnerabilities: []DiffVulnerability{
+			{Name: "CVE-2019-0002", Package: "bash", Severity: "Critical", OldSeverity: "Low"},
+		},
+	}
+	got := diffReports(a, b)
+	// The zero Digest has unexported fields; compare it by its string form.
+	opt := cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+	if !cmp.Equal(got, want, opt) {
+		t.Error(cmp.Diff(got, want, opt))
+	}
+}
+
+func TestDiffReportsEmpty(t *testing.T) {
+	r := &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"1": {Name: "bash", Version: "5.0"},
+			// Two versions of the same package.
+			"2": {Name: "six", Version: "1.15", PackageDB: "python:site-packages"},
+			"3": {Name: "six", Version: "1.16", PackageDB: "python:site-packages"},
+		},
+	}
+	b, err := json.Marshal(diffReports(r, r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range got {
+		if k == "old" || k == "new" {
+			continue
+		}
+		if l, ok := v.([]interface{}); !ok || len(l) != 0 {
+			t.Errorf("%s: got: %v, want: empty list", k, v)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// GithubFlags are the flags controlling upload to GitHub code scanning.
+//
+// The defaults come from the environment GitHub Actions provides.
+var githubFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "upload-github",
+		Usage: "upload the results as SARIF to GitHub code scanning",
+	},
+	&cli.StringFlag{
+		Name:    "github-api",
+		Usage:   "URL for the GitHub API",
+		Value:   "https://api.github.com",
+		EnvVars: []string{"GITHUB_API_URL"},
+	},
+	&cli.StringFlag{
+		Name:    "github-repo",
+		Usage:   `repository to upload results to, as "owner/repo"`,
+		EnvVars: []string{"GITHUB_REPOSITORY"},
+	},
+	&cli.StringFlag{
+		Name:    "github-ref",
+		Usage:   `git ref the results are for, e.g. "refs/heads/main"`,
+		EnvVars: []string{"GITHUB_REF"},
+	},
+	&cli.StringFlag{
+		Name:    "github-sha",
+		Usage:   "commit the results are for",
+		EnvVars: []string{"GITHUB_SHA"},
+	},
+	&cli.StringFlag{
+		Name:    "github-token",
+		Usage:   `token with the "security_events" scope`,
+		EnvVars: []string{"GITHUB_TOKEN"},
+	},
+}
+
+// GithubUpload is the information needed to upload a SARIF log.
+type githubUpload struct {
+	API   *url.URL
+	Repo  string
+	Ref   string
+	SHA   string
+	Token string
+}
+
+func githubUploadFromFlags(c *cli.Context) (*githubUpload, error) {
+	u := githubUpload{
+		Repo:  c.String("github-repo"),
+		Ref:   c.String("github-ref"),
+		SHA:   c.String("github-sha"),
+		Token: c.String("github-token"),
+	}
+	var missing []string
+	for _, f := range []struct{ name, v string }{
+		{"github-repo", u.Repo},
+		{"github-ref", u.Ref},
+		{"github-sha", u.SHA},
+		{"github-token", u.Token},
+	} {
+		if f.v == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("--upload-github needs: %s", strings.Join(missing, ", "))
+	}
+	if strings.Count(u.Repo, "/") != 1 {
+		return nil, fmt.Errorf("bad repository %q: want \"owner/repo\"", u.Repo)
+	}
+	var err error
+	u.API, err = url.Parse(c.String("github-api"))
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Upload sends the SARIF log to the code scanning API.
+//
+// See https://docs.github.com/en/rest/reference/code-scanning#upload-a-sarif-file
+func (u *githubUpload) Upload(ctx context.Context, c *http.Client, sarif []byte) error {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(sarif); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{
+		"commit_sha": u.SHA,
+		"ref":        u.Ref,
+		"sarif":      base64.StdEncoding.EncodeToString(gz.Bytes()),
+		"tool_name":  "clair",
+	})
+	if err != nil {
+		return err
+	}
+	ep := *u.API
+	ep.Path = path.Join(ep.Path, "repos", u.Repo, "code-scanning", "sarifs")
+	req, err := http.NewRequest(http.MethodPost, ep.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("accept", "application/vnd.github.v3+json")
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("authorization", "token "+u.Token)
+	req.Header.Set("user-agent", userAgent)
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	debug.Printf("%s %s: %s", res.Request.Method, res.Request.URL.Path, res.Status)
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+	default:
+		b, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("github upload failed: %s: %s", res.Status, bytes.TrimSpace(b))
+	}
+	var status struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&status); err != nil {
+		return err
+	}
+	if status.ID == "" {
+		return errors.New("github upload failed: no upload id returned")
+	}
+	debug.Printf("github sarif upload id: %s", status.ID)
+	return nil
+}
+
+// TeeFormatter sends results to two Formatters.
+type teeFormatter struct {
+	a, b Formatter
+}
+
+var _ Formatter = (*teeFormatter)(nil)
+
+func (f *teeFormatter) Format(r *Result) error {
+	errA := f.a.Format(r)
+	errB := f.b.Format(r)
+	if errA != nil {
+		return errA
+	}
+	return errB
+}
+
+func (f *teeFormatter) Close() error {
+	errA := f.a.Close()
+	errB := f.b.Close()
+	if errA != nil {
+		return errA
+	}
+	return errB
+}
+
+// NopCloser adds a no-op Close method to a bytes.Buffer.
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestSarif(t *testing.T) {
+	var buf bytes.Buffer
+	f := newSarifFormatter(nopCloser{&buf})
+	err := f.Format(&Result{
+		Name: "quay.io/example/app:latest",
+		Report: &claircore.VulnerabilityReport{
+			Packages: map[string]*claircore.Package{
+				"1": {Name: "openssl", Version: "1.1.1a"},
+				"2": {Name: "bash", Version: "5.0"},
+			},
+			Vulnerabilities: map[string]*claircore.Vulnerability{
+				"10": {Name: "CVE-2019-0001", NormalizedSeverity: claircore.High, FixedInVersion: "1.1.1b", Links: "https://example.com/CVE-2019-0001 https://example.org"},
+				"11": {Name: "CVE-2019-0002", NormalizedSeverity: claircore.Low},
+			},
+			PackageVulnerabilities: map[string][]string{
+				"1": {"10", "11"},
+				"2": {"11"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := log.Version, "2.1.0"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	run := log.Runs[0]
+	if got, want := len(run.Tool.Driver.Rules), 2; got != want {
+		t.Fatalf("rules: got: %d, want: %d", got, want)
+	}
+	if got, want := run.Tool.Driver.Rules[0].HelpURI, "https://example.com/CVE-2019-0001"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := len(run.Results), 3; got != want {
+		t.Fatalf("results: got: %d, want: %d", got, want)
+	}
+	for _, r := range run.Results {
+		if got, want := r.Locations[0].PhysicalLocation.ArtifactLocation.URI, "quay.io/example/app:latest"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		want := "note"
+		if r.RuleID == "CVE-2019-0001" {
+			want = "error"
+		}
+		if got := r.Level; got != want {
+			t.Errorf("%s: got: %q, want: %q", r.RuleID, got, want)
+		}
+	}
+}
+
+func TestGithubUpload(t *testing.T) {
+	const sarif = `{"version":"2.1.0"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/repos/quay/clair/code-scanning/sarifs"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := r.Header.Get("authorization"), "token secret"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if got, want := body["commit_sha"], "deadbeef"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := body["ref"], "refs/heads/main"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		b, err := base64.StdEncoding.DecodeString(body["sarif"])
+		if err != nil {
+			t.Error(err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err = ioutil.ReadAll(zr)
+		if err != nil {
+			t.Error(err)
+		}
+		if got, want := string(b), sarif; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"47177e22-5596-11eb-80a1-c1e54ef945c6"}`))
+	}))
+	defer srv.Close()
+
+	api, _ := url.Parse(srv.URL)
+	u := githubUpload{
+		API:   api,
+		Repo:  "quay/clair",
+		Ref:   "refs/heads/main",
+		SHA:   "deadbeef",
+		Token: "secret",
+	}
+	if err := u.Upload(context.Background(), srv.Client(), []byte(sarif)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/urfave/cli/v2"
+
+	"github.com/quay/clair/v4/httptransport"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// StateCmd is the "state" subcommand.
+var StateCmd = &cli.Command{
+	Name: "state",
+	Description: "Print the indexer's state, a fingerprint of its scanners and their versions.\n\n" +
+		"Manifests indexed under a different state may need to be indexed again.",
+	Usage:  "print the indexer state",
+	Action: stateAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "host",
+			Usage:   "URL for the clairv4 v1 API.",
+			Value:   "http://localhost:6060/",
+			EnvVars: []string{"CLAIR_API"},
+		},
+		&cli.StringFlag{
+			Name:    "out",
+			Aliases: []string{"o"},
+			Usage:   "output format: text, json",
+			Value:   "text",
+		},
+	},
+}
+
+// HealthCmd is the "health" subcommand.
+var HealthCmd = &cli.Command{
+	Name: "health",
+	Description: "Check the named services, or all of them if none are named: that the\n" +
+		"indexer reports its state, that every updater has run recently, and that the\n" +
+		"notifier is keeping up with delivery.\n\n" +
+		"Exits non-zero if any check fails.",
+	Usage:     "check the health of a deployment",
+	ArgsUsage: "[indexer|matcher|notifier]...",
+	Action:    healthAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "host",
+			Usage:   "URL for the clairv4 v1 API.",
+			Value:   "http://localhost:6060/",
+			EnvVars: []string{"CLAIR_API"},
+		},
+		&cli.DurationFlag{
+			Name:  "updater-max-age",
+			Usage: "longest an updater may go without running",
+			Value: 12 * time.Hour,
+		},
+		&cli.DurationFlag{
+			Name:  "notifier-max-lag",
+			Usage: "longest a notification may wait on delivery",
+			Value: time.Hour,
+		},
+		&cli.StringFlag{
+			Name:    "out",
+			Aliases: []string{"o"},
+			Usage:   "output format: text, json",
+			Value:   "text",
+		},
+	},
+}
+
+func stateAction(c *cli.Context) error {
+	switch f := c.String("out"); f {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unrecognized output format %q", f)
+	}
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	var res struct {
+		State string `json:"state"`
+	}
+	// The admin helper works for any JSON endpoint.
+	if err := cc.admin(c.Context, http.MethodGet, httptransport.IndexStateAPIPath, &res); err != nil {
+		return err
+	}
+	if c.String("out") == "json" {
+		return json.NewEncoder(os.Stdout).Encode(&res)
+	}
+	fmt.Println(res.State)
+	return nil
+}
+
+// HealthCheck is the outcome of checking a service.
+type healthCheck struct {
+	Service string `json:"service"`
+	Healthy bool   `json:"healthy"`
+	Detail  string `json:"detail"`
+}
+
+// HealthLimits are the thresholds health checks fail at.
+type healthLimits struct {
+	UpdaterMaxAge  time.Duration
+	NotifierMaxLag time.Duration
+}
+
+func healthAction(c *cli.Context) error {
+	svcs, err := adminServices(c, "indexer", "matcher", "notifier")
+	if err != nil {
+		return err
+	}
+	switch f := c.String("out"); f {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unrecognized output format %q", f)
+	}
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	lim := healthLimits{
+		UpdaterMaxAge:  c.Duration("updater-max-age"),
+		NotifierMaxLag: c.Duration("notifier-max-lag"),
+	}
+	cs := checkHealth(c.Context, cc, svcs, lim, time.Now())
+	if c.String("out") == "json" {
+		err = json.NewEncoder(os.Stdout).Encode(cs)
+	} else {
+		err = writeHealth(os.Stdout, cs)
+	}
+	if err != nil {
+		return err
+	}
+	for _, hc := range cs {
+		if !hc.Healthy {
+			return errors.New("unhealthy")
+		}
+	}
+	return nil
+}
+
+// CheckHealth checks each of the named services.
+func checkHealth(ctx context.Context, cc *Client, svcs []string, lim healthLimits, now time.Time) []healthCheck {
+	out := make([]healthCheck, 0, len(svcs))
+	for _, s := range svcs {
+		hc := healthCheck{Service: s}
+		var err error
+		switch s {
+		case "indexer":
+			hc.Detail, err = checkIndexer(ctx, cc)
+		case "matcher":
+			hc.Detail, err = checkMatcher(ctx, cc, lim.UpdaterMaxAge, now)
+		case "notifier":
+			hc.Detail, err = checkNotifier(ctx, cc, lim.NotifierMaxLag, now)
+		}
+		if err != nil {
+			hc.Detail = err.Error()
+		} else {
+			hc.Healthy = true
+		}
+		out = append(out, hc)
+	}
+	return out
+}
+
+func checkIndexer(ctx context.Context, cc *Client) (string, error) {
+	var res struct {
+		State string `json:"state"`
+	}
+	if err := cc.admin(ctx, http.MethodGet, httptransport.IndexStateAPIPath, &res); err != nil {
+		return "", err
+	}
+	return "state " + res.State, nil
+}
+
+func checkMatcher(ctx context.Context, cc *Client, maxAge time.Duration, now time.Time) (string, error) {
+	var res map[string][]driver.UpdateOperation
+	if err := cc.admin(ctx, http.MethodGet, httptransport.UpdateOperationAPIPath+"?latest=true", &res); err != nil {
+		return "", err
+	}
+	if len(res) == 0 {
+		return "", errors.New("no updaters have run")
+	}
+	var stale []string
+	var oldest time.Time
+	for u, ops := range res {
+		if len(ops) == 0 {
+			continue
+		}
+		d := ops[0].Date
+		if oldest.IsZero() || d.Before(oldest) {
+			oldest = d
+		}
+		if now.Sub(d) > maxAge {
+			stale = append(stale, u)
+		}
+	}
+	if len(stale) != 0 {
+		sort.Strings(stale)
+		return "", fmt.Errorf("updaters not run in %v: %s", maxAge, strings.Join(stale, ", "))
+	}
+	return fmt.Sprintf("%d updaters, oldest update %v ago", len(res), now.Sub(oldest).Truncate(time.Second)), nil
+}
+
+func checkNotifier(ctx context.Context, cc *Client, maxLag time.Duration, now time.Time) (string, error) {
+	var b notifier.Backlog
+	if err := cc.admin(ctx, http.MethodGet, httptransport.BacklogAPIPath, &b); err != nil {
+		return "", err
+	}
+	var lag time.Duration
+	if b.Oldest != nil {
+		lag = now.Sub(*b.Oldest).Truncate(time.Second)
+	}
+	detail := fmt.Sprintf("%d pending, %d failed, lag %v", b.Pending, b.Failed, lag)
+	if lag > maxLag {
+		return "", fmt.Errorf("%s exceeds %v", detail, maxLag)
+	}
+	return detail, nil
+}
+
+func writeHealth(w io.Writer, cs []healthCheck) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tSTATUS\tDETAIL")
+	for _, hc := range cs {
+		st := "ok"
+		if !hc.Healthy {
+			st = "failing"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", hc.Service, st, hc.Detail)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/clair/v4/httptransport"
+)
+
+func TestCheckHealth(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	var backlog string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		switch r.URL.Path {
+		case httptransport.IndexStateAPIPath:
+			w.Write([]byte(`{"state":"abc123"}`))
+		case httptransport.UpdateOperationAPIPath:
+			if r.URL.Query().Get("latest") != "true" {
+				t.Errorf("unexpected query: %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{
+"alpine":[{"updater":"alpine","date":"2021-03-01T11:00:00Z"}],
+"debian":[{"updater":"debian","date":"2021-02-27T12:00:00Z"}]
+}`))
+		case httptransport.BacklogAPIPath:
+			w.Write([]byte(backlog))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	cc, err := NewClient(srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	lim := healthLimits{UpdaterMaxAge: 24 * time.Hour, NotifierMaxLag: time.Hour}
+
+	backlog = `{"pending":2,"failed":1,"oldest_pending":"2021-03-01T11:50:00Z"}`
+	got := checkHealth(ctx, cc, []string{"indexer", "matcher", "notifier"}, lim, now)
+	want := []healthCheck{
+		{Service: "indexer", Healthy: true, Detail: "state abc123"},
+		{Service: "matcher", Detail: "updaters not run in 24h0m0s: debian"},
+		{Service: "notifier", Healthy: true, Detail: "2 pending, 1 failed, lag 10m0s"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	backlog = `{"pending":5,"failed":0,"oldest_pending":"2021-03-01T09:00:00Z"}`
+	lim.UpdaterMaxAge = 72 * time.Hour
+	got = checkHealth(ctx, cc, []string{"matcher", "notifier"}, lim, now)
+	want = []healthCheck{
+		{Service: "matcher", Healthy: true, Detail: "2 updaters, oldest update 48h0m0s ago"},
+		{Service: "notifier", Detail: "5 pending, 0 failed, lag 3h0m0s exceeds 1h0m0s"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
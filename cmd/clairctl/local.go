@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex"
+	"github.com/quay/claircore/libvuln"
+	"github.com/urfave/cli/v2"
+
+	"github.com/quay/clair/v4/embedded"
+)
+
+// ReportClient is the set of operations the report flow needs, implemented
+// by both the API Client and the in-process localClient.
+type reportClient interface {
+	IndexReport(context.Context, claircore.Digest, *claircore.Manifest) error
+	VulnerabilityReport(context.Context, claircore.Digest) (*claircore.VulnerabilityReport, error)
+	Scan(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error)
+}
+
+var (
+	_ reportClient = (*Client)(nil)
+	_ reportClient = (*localClient)(nil)
+)
+
+// LocalClient indexes and matches in-process, instead of asking a Clair
+// instance to.
+type localClient struct {
+	db   *embedded.Database
+	idx  *libindex.Libindex
+	vuln *libvuln.Libvuln
+}
+
+// NewLocalClient returns a localClient using the database at connString,
+// which may use the embedded scheme.
+//
+// If update is set, the vulnerability database is updated before returning.
+func newLocalClient(ctx context.Context, connString string, update bool) (*localClient, error) {
+	l := &localClient{}
+	if embedded.Is(connString) {
+		debug.Printf("starting embedded database: %s", connString)
+		db, err := embedded.Start(ctx, connString)
+		if err != nil {
+			return nil, err
+		}
+		l.db = db
+		connString = db.ConnString
+	}
+	var err error
+	l.idx, err = libindex.New(ctx, &libindex.Opts{
+		ConnString: connString,
+		Migrations: true,
+	})
+	if err != nil {
+		l.Close(ctx)
+		return nil, fmt.Errorf("failed to initialize libindex: %w", err)
+	}
+	l.vuln, err = libvuln.New(ctx, &libvuln.Opts{
+		ConnString: connString,
+		Migrations: true,
+
+		DisableBackgroundUpdates: true,
+	})
+	if err != nil {
+		l.Close(ctx)
+		return nil, fmt.Errorf("failed to initialize libvuln: %w", err)
+	}
+	if update {
+		debug.Println("updating vulnerability database")
+		if err := l.vuln.FetchUpdates(ctx); err != nil {
+			l.Close(ctx)
+			return nil, fmt.Errorf("failed to update vulnerability database: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// IndexReport implements reportClient.
+//
+// The manifest is always needed, so that libindex can decide whether it
+// needs to be (re)indexed.
+func (l *localClient) IndexReport(ctx context.Context, id claircore.Digest, m *claircore.Manifest) error {
+	if m == nil {
+		return errNeedManifest
+	}
+	debug.Printf("indexing manifest %v", id)
+	report, err := l.idx.Index(ctx, m)
+	if err != nil {
+		return err
+	}
+	if !report.Success && report.Err != "" {
+		return errors.New("indexer error: " + report.Err)
+	}
+	return nil
+}
+
+// VulnerabilityReport implements reportClient.
+func (l *localClient) VulnerabilityReport(ctx context.Context, id claircore.Digest) (*claircore.VulnerabilityReport, error) {
+	ir, ok, err := l.idx.IndexReport(ctx, id)
+	switch {
+	case err != nil:
+		return nil, err
+	case !ok:
+		return nil, fmt.Errorf("index report for manifest %q not found", id)
+	}
+	return l.vuln.Scan(ctx, ir)
+}
+
+// Scan implements reportClient.
+func (l *localClient) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	return l.vuln.Scan(ctx, ir)
+}
+
+// Close releases the libraries and stops the embedded database, if any.
+func (l *localClient) Close(ctx context.Context) error {
+	if l.idx != nil {
+		l.idx.Close(ctx)
+	}
+	if l.db != nil {
+		return l.db.Close()
+	}
+	return nil
+}
+
+// LocalFlags are the flags for commands supporting local operation.
+var localFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "local",
+		Usage: "index and match in-process instead of using a Clair API",
+	},
+	&cli.StringFlag{
+		Name:    "local-db",
+		Usage:   "database connection string to use with --local",
+		Value:   embedded.Scheme + "://",
+		EnvVars: []string{"CLAIRCTL_LOCAL_DB"},
+	},
+	&cli.BoolFlag{
+		Name:  "skip-update",
+		Usage: "don't update the vulnerability database before a --local report",
+	},
+}
+
+// ReportClientFor returns the reportClient selected by the flags, and a
+// function to release it.
+func reportClientFor(c *cli.Context) (reportClient, func(), error) {
+	if !c.Bool("local") {
+		cc, err := contextClient(c)
+		return cc, func() {}, err
+	}
+	ctx := c.Context
+	l, err := newLocalClient(ctx, c.String("local-db"), !c.Bool("skip-update"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, func() { l.Close(ctx) }, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/quay/clair/v4/embedded"
+)
+
+func TestLocalFlags(t *testing.T) {
+	type flags struct {
+		Local, SkipUpdate bool
+		DB                string
+	}
+	tt := []struct {
+		Name string
+		Args []string
+		Env  string
+		Want flags
+	}{
+		{
+			Name: "Default",
+			Want: flags{DB: embedded.Scheme + "://"},
+		},
+		{
+			Name: "Local",
+			Args: []string{"--local", "--skip-update"},
+			Want: flags{Local: true, SkipUpdate: true, DB: embedded.Scheme + "://"},
+		},
+		{
+			Name: "Database",
+			Args: []string{"--local", "--local-db", "embedded:///tmp/clair?port=5555"},
+			Want: flags{Local: true, DB: "embedded:///tmp/clair?port=5555"},
+		},
+		{
+			Name: "Environment",
+			Args: []string{"--local"},
+			Env:  "host=localhost dbname=clair",
+			Want: flags{Local: true, DB: "host=localhost dbname=clair"},
+		},
+	}
+	defer os.Setenv("CLAIRCTL_LOCAL_DB", os.Getenv("CLAIRCTL_LOCAL_DB"))
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			if tc.Env != "" {
+				os.Setenv("CLAIRCTL_LOCAL_DB", tc.Env)
+			} else {
+				os.Unsetenv("CLAIRCTL_LOCAL_DB")
+			}
+			var got flags
+			app := &cli.App{
+				Flags: localFlags,
+				Action: func(c *cli.Context) error {
+					got = flags{
+						Local:      c.Bool("local"),
+						SkipUpdate: c.Bool("skip-update"),
+						DB:         c.String("local-db"),
+					}
+					return nil
+				},
+			}
+			if err := app.Run(append([]string{"clairctl"}, tc.Args...)); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.Want {
+				t.Errorf("got: %+v, want: %+v", got, tc.Want)
+			}
+		})
+	}
+}
+
+// DpkgLayer returns a layer with a Debian release file and a dpkg database
+// holding one package.
+func dpkgLayer(t *testing.T) []byte {
+	t.Helper()
+	files := []struct{ Name, Body string }{
+		{"etc/os-release", "ID=debian\nVERSION_ID=\"10\"\nVERSION_CODENAME=buster\nPRETTY_NAME=\"Debian GNU/Linux 10 (buster)\"\n"},
+		{"var/lib/dpkg/status", "Package: tzdata\nStatus: install ok installed\nPriority: required\nArchitecture: all\nVersion: 2021a-0+deb10u1\nDescription: time zone and daylight-saving time data\n\n"},
+	}
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, f := range files {
+		if err := w.WriteHeader(&tar.Header{
+			Name:     f.Name,
+			Mode:     0644,
+			Size:     int64(len(f.Body)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.Body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestLocalClient indexes and matches a manifest using a temporary embedded
+// database.
+func TestLocalClient(t *testing.T) {
+	integration.Skip(t)
+	ctx := zlog.Test(context.Background(), t)
+	dir, err := ioutil.TempDir("", "clairctl-local-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	layer := dpkgLayer(t)
+	sum := sha256.Sum256(layer)
+	ld, err := claircore.ParseDigest("sha256:" + hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(layer)
+	}))
+	defer srv.Close()
+
+	c, err := newLocalClient(ctx, fmt.Sprintf("%s://%s?port=%d", embedded.Scheme, dir, port), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(ctx)
+
+	m := &claircore.Manifest{
+		Hash: ld,
+		Layers: []*claircore.Layer{{
+			Hash:    ld,
+			URI:     srv.URL + "/layer",
+			Headers: map[string][]string{},
+		}},
+	}
+	if err := c.IndexReport(ctx, m.Hash, m); err != nil {
+		t.Fatal(err)
+	}
+	vr, err := c.VulnerabilityReport(ctx, m.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, p := range vr.Packages {
+		if p.Name == "tzdata" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("package not found in report: %+v", vr.Packages)
+	}
+}
@@ -38,8 +38,16 @@ func main() {
 		Commands: []*cli.Command{
 			ManifestCmd,
 			ReportCmd,
+			DiffCmd,
+			SummaryCmd,
+			WatchCmd,
 			ExportCmd,
 			ImportCmd,
+			ImportSBOMCmd,
+			AdminCmd,
+			StateCmd,
+			HealthCmd,
+			CompletionCmd,
 		},
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
@@ -62,6 +70,7 @@ func main() {
 			},
 		},
 	}
+	app.Commands = append(app.Commands, pluginCommands(os.Getenv("PATH"), app.Commands)...)
 	log.SetFlags(log.Flags())
 
 	if err := app.RunContext(ctx, os.Args); err != nil {
@@ -69,6 +78,9 @@ func main() {
 		if err, ok := err.(cli.ExitCoder); ok {
 			exit = err.ExitCode()
 		}
-		log.Println(err)
+		// Plugins report their own errors.
+		if msg := err.Error(); msg != "" {
+			log.Println(msg)
+		}
 	}
 }
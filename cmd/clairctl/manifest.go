@@ -5,11 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path"
 	"strings"
+	"syscall"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
@@ -19,10 +22,15 @@ import (
 )
 
 var ManifestCmd = &cli.Command{
-	Name:        "manifest",
-	Description: "print a clair manifest for the named container",
-	Usage:       "print a clair manifest for the named container",
-	Action:      manifestAction,
+	Name: "manifest",
+	Description: "print a clair manifest for the named container\n\n" +
+		"Containers may also be \"docker-archive:\" tarballs or \"oci:\" image layouts\n" +
+		"on local disk, \"docker-daemon:\" images in a local Docker or Podman engine,\n" +
+		"or host filesystems named by \"rootfs:\" directories or \"rootfs-archive:\"\n" +
+		"tarballs. Their layers are served until clairctl is interrupted.",
+	Usage:  "print a clair manifest for the named container",
+	Action: manifestAction,
+	Flags:  serveFlags,
 }
 
 func manifestAction(c *cli.Context) error {
@@ -31,6 +39,8 @@ func manifestAction(c *cli.Context) error {
 	if args.Len() == 0 {
 		return errors.New("missing needed arguments")
 	}
+	srv, stop := layerServerFor(c)
+	defer stop()
 
 	result := make(chan *claircore.Manifest)
 	done := make(chan struct{})
@@ -50,7 +60,7 @@ func manifestAction(c *cli.Context) error {
 		name := args.Get(i)
 		debug.Printf("%s: fetching", name)
 		eg.Go(func() error {
-			m, err := Inspect(ctx, name)
+			m, err := inspect(ctx, srv, name)
 			if err != nil {
 				debug.Printf("%s: err: %v", name, err)
 				return err
@@ -65,9 +75,33 @@ func manifestAction(c *cli.Context) error {
 	}
 	close(result)
 	<-done
+
+	if u := srv.URL(); u != nil {
+		fmt.Fprintf(os.Stderr, "serving layers at %v, interrupt to stop\n", u)
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(ch)
+		select {
+		case <-ch:
+		case <-c.Context.Done():
+		}
+	}
 	return nil
 }
 
+// Inspect returns a Manifest for the named container, which may be a local
+// image served by srv.
+func inspect(ctx context.Context, srv *layerServer, r string) (*claircore.Manifest, error) {
+	if !isLocalRef(r) {
+		return Inspect(ctx, r)
+	}
+	img, err := openLocal(r)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Manifest(img)
+}
+
 func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
 	rt, err := rt(r)
 	if err != nil {
@@ -102,6 +136,18 @@ func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
 		return nil, err
 	}
 	debug.Printf("%s: found %d layers", r, len(ls))
+	im, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	// Foreign layers, like the base layers of Windows images, aren't in the
+	// registry, so they're fetched from the URLs in their descriptors.
+	foreign := make(map[string]string)
+	for _, d := range im.Layers {
+		if !d.MediaType.IsDistributable() && len(d.URLs) != 0 {
+			foreign[d.Digest.String()] = d.URLs[0]
+		}
+	}
 
 	repo := ref.Context()
 	rURL := url.URL{
@@ -121,6 +167,15 @@ func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
 		if err != nil {
 			return nil, err
 		}
+		if u, ok := foreign[d.String()]; ok {
+			debug.Printf("%s: layer %v is foreign", r, d)
+			out.Layers = append(out.Layers, &claircore.Layer{
+				Hash:    ccd,
+				URI:     u,
+				Headers: make(map[string][]string),
+			})
+			continue
+		}
 		u, err := rURL.Parse(path.Join("/", "v2", strings.TrimPrefix(repo.RepositoryStr(), repo.RegistryStr()), "blobs", d.String()))
 		if err != nil {
 			return nil, err
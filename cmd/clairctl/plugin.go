@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// PluginPrefix is the prefix of the executables found in PATH that are run
+// as clairctl subcommands: "clairctl foo" runs "clairctl-foo".
+const pluginPrefix = "clairctl-"
+
+// PluginCommands returns a command for every plugin found in the
+// directories of path, in the style of kubectl and git. The first plugin
+// found with a name wins, and plugins can't replace the builtin commands.
+//
+// Plugins are handed their arguments unparsed, along with the global options
+// as environment variables:
+//
+//	CLAIRCTL_CONFIG  the "config" option
+//	CLAIRCTL_ISSUER  the "issuer" option
+//	CLAIRCTL_DEBUG   "1" if the "D" option was given
+func pluginCommands(path string, builtin []*cli.Command) []*cli.Command {
+	taken := map[string]bool{"help": true, "h": true}
+	for _, c := range builtin {
+		for _, n := range c.Names() {
+			taken[n] = true
+		}
+	}
+	var out []*cli.Command
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			// An empty entry means the current directory, which plugins
+			// aren't loaded from.
+			continue
+		}
+		ms, err := filepath.Glob(filepath.Join(dir, pluginPrefix+"*"))
+		if err != nil {
+			continue
+		}
+		sort.Strings(ms)
+		for _, p := range ms {
+			name := strings.TrimPrefix(filepath.Base(p), pluginPrefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if name == "" || taken[name] || !executable(p) {
+				continue
+			}
+			taken[name] = true
+			out = append(out, pluginCommand(name, p))
+		}
+	}
+	return out
+}
+
+// Executable reports whether p is a file that can be executed.
+func executable(p string) bool {
+	fi, err := os.Stat(p)
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(p), ".exe")
+	}
+	return fi.Mode().Perm()&0111 != 0
+}
+
+// PluginCommand returns the command running the plugin at p.
+func pluginCommand(name, p string) *cli.Command {
+	return &cli.Command{
+		Name:            name,
+		Usage:           "plugin " + p,
+		Category:        "plugins",
+		SkipFlagParsing: true,
+		HideHelp:        true,
+		Action: func(c *cli.Context) error {
+			return runPlugin(c, p, c.Args().Slice())
+		},
+		BashComplete: func(c *cli.Context) {
+			// A plugin that doesn't complete its arguments doesn't get any.
+			runPlugin(c, p, append(c.Args().Slice(), "--generate-bash-completion"))
+		},
+	}
+}
+
+// RunPlugin runs the plugin at p with the provided arguments, connected to
+// clairctl's standard input and outputs. The plugin's exit code is returned
+// as clairctl's.
+func runPlugin(c *cli.Context, p string, args []string) error {
+	cmd := exec.CommandContext(c.Context, p, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = c.App.Writer
+	cmd.Stderr = c.App.ErrWriter
+	cmd.Env = append(os.Environ(),
+		"CLAIRCTL_CONFIG="+c.String("config"),
+		"CLAIRCTL_ISSUER="+c.String("issuer"),
+	)
+	if c.Bool("D") {
+		cmd.Env = append(cmd.Env, "CLAIRCTL_DEBUG=1")
+	}
+	debug.Printf("running plugin %q", p)
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return cli.Exit("", exit.ExitCode())
+	}
+	return err
+}
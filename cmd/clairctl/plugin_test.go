@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestPluginCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by their mode bits in this test")
+	}
+	dir, err := ioutil.TempDir("", "clairctl-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	for p, mode := range map[string]os.FileMode{
+		filepath.Join(a, "clairctl-hello"):  0755,
+		filepath.Join(a, "clairctl-report"): 0755, // shadowed by the builtin
+		filepath.Join(a, "clairctl-notes"):  0644, // not executable
+		filepath.Join(b, "clairctl-hello"):  0755, // shadowed by the first
+		filepath.Join(b, "clairctl-triage"): 0755,
+	} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmds := pluginCommands(a+string(filepath.ListSeparator)+b, []*cli.Command{ReportCmd})
+	got := make(map[string]string)
+	for _, c := range cmds {
+		got[c.Name] = c.Usage
+	}
+	want := map[string]string{
+		"hello":  "plugin " + filepath.Join(a, "clairctl-hello"),
+		"triage": "plugin " + filepath.Join(b, "clairctl-triage"),
+	}
+	if len(got) != len(want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	for n, u := range want {
+		if got[n] != u {
+			t.Errorf("%s: got: %q, want: %q", n, got[n], u)
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 
 	"github.com/google/go-containerregistry/pkg/name"
@@ -18,12 +21,17 @@ import (
 
 // ReportCmd is the "report" subcommand.
 var ReportCmd = &cli.Command{
-	Name:        "report",
-	Description: "Request and print a Clair vulnerability report for the named container(s).",
-	Action:      reportAction,
-	Usage:       "request vulnerability reports for the named containers",
-	ArgsUsage:   "container...",
-	Flags: []cli.Flag{
+	Name: "report",
+	Description: "Request and print a Clair vulnerability report for the named container(s).\n\n" +
+		"Containers may also be \"docker-archive:\" tarballs or \"oci:\" image layouts\n" +
+		"on local disk, or \"docker-daemon:\" images in a local Docker or Podman\n" +
+		"engine, whose layers are served to the indexer while clairctl runs.\n\n" +
+		"Hosts and VM images are scanned with \"rootfs:\" and a mounted root\n" +
+		"directory, or \"rootfs-archive:\" and a tarball of one.",
+	Action:    reportAction,
+	Usage:     "request vulnerability reports for the named containers",
+	ArgsUsage: "container...",
+	Flags: append([]cli.Flag{
 		&cli.StringFlag{
 			Name:    "host",
 			Usage:   "URL for the clairv4 v1 API.",
@@ -33,11 +41,15 @@ var ReportCmd = &cli.Command{
 		&cli.GenericFlag{
 			Name:        "out",
 			Aliases:     []string{"o"},
-			Usage:       "output format: text, json, xml",
+			Usage:       "output format: text, json, xml, sarif, attestation",
 			DefaultText: "text",
 			Value:       &outFmt{},
 		},
-	},
+		&cli.BoolFlag{
+			Name:  "push-attestation",
+			Usage: "push each report as a cosign vuln attestation to the image's repository",
+		},
+	}, append(append(localFlags, serveFlags...), githubFlags...)...),
 }
 
 // OutFmt is a flag that creates a Formatter for us.
@@ -50,6 +62,8 @@ func (o *outFmt) Set(v string) error {
 	case "text":
 	case "json":
 	case "xml":
+	case "sarif":
+	case "attestation":
 	default:
 		return fmt.Errorf("unrecognized output format %q", v)
 	}
@@ -82,6 +96,12 @@ func (o *outFmt) Formatter(w io.WriteCloser) Formatter {
 			enc: xml.NewEncoder(w),
 			c:   w,
 		}
+	case "sarif":
+		debug.Println("using sarif output")
+		return newSarifFormatter(w)
+	case "attestation":
+		debug.Println("using attestation output")
+		return &attestationFormatter{w: w}
 	default:
 	}
 	panic("unreachable") // Somehow dodged the initial Set call.
@@ -100,6 +120,8 @@ type Result struct {
 	Name   string
 	Err    error
 	Report *claircore.VulnerabilityReport
+	// Attestation is the report as a signed attestation, if asked for.
+	Attestation []byte
 }
 
 func reportAction(c *cli.Context) error {
@@ -108,35 +130,41 @@ func reportAction(c *cli.Context) error {
 		return errors.New("missing needed arguments")
 	}
 
-	// Do we have a config?
-	fi, err := os.Stat(c.Path("config"))
-	useCfg := err == nil && !fi.IsDir()
-
-	var cc *Client
-	if useCfg {
-		cfg, e := loadConfig(c.Path("config"))
-		if e != nil {
-			return e
-		}
-		hc, _, e := cfg.Client(nil, commonClaim)
-		if e != nil {
-			return e
+	var gh *githubUpload
+	var sarif bytes.Buffer
+	if c.Bool("upload-github") {
+		var err error
+		gh, err = githubUploadFromFlags(c)
+		if err != nil {
+			return err
 		}
-		cc, err = NewClient(hc, c.String("host"))
-	} else {
-		cc, err = NewClient(nil, c.String("host"))
 	}
+	cc, done, err := reportClientFor(c)
 	if err != nil {
 		return err
 	}
+	defer done()
+	attest := c.Generic("out").(*outFmt).fmt == "attestation" || c.Bool("push-attestation")
+	var ac *Client
+	if attest {
+		var ok bool
+		if ac, ok = cc.(*Client); !ok {
+			return errors.New("attestations need a Clair instance with report signing")
+		}
+	}
+	srv, stop := layerServerFor(c)
+	defer stop()
 
 	result := make(chan *Result)
-	done := make(chan struct{})
+	finished := make(chan struct{})
 	eg, ctx := errgroup.WithContext(c.Context)
 	go func() {
-		defer close(done)
+		defer close(finished)
 		out := c.Generic("out").(*outFmt)
 		f := out.Formatter(os.Stdout)
+		if gh != nil {
+			f = &teeFormatter{a: f, b: newSarifFormatter(nopCloser{&sarif})}
+		}
 		defer f.Close()
 		for r := range result {
 			if err := f.Format(r); err != nil {
@@ -149,33 +177,8 @@ func reportAction(c *cli.Context) error {
 		ref := args.Get(i)
 		debug.Printf("%s: fetching", ref)
 		eg.Go(func() error {
-			d, err := resolveRef(ref)
+			d, err := indexRef(ctx, cc, srv, ref)
 			if err != nil {
-				debug.Printf("%s: error: %v", ref, err)
-				return err
-			}
-			debug.Printf("%s: manifest: %v", ref, d)
-
-			// This bit is tricky:
-			//
-			// Initially start with a nil manifest, which optimistically
-			// prevents us from generating one.
-			//
-			// If we need the manifest, populate the manifest and jump to Again.
-			var m *claircore.Manifest
-		Again:
-			err = cc.IndexReport(ctx, d, m)
-			switch {
-			case err == nil:
-			case errors.Is(err, errNeedManifest):
-				m, err = Inspect(ctx, ref)
-				if err != nil {
-					debug.Printf("%s: manifest error: %v", ref, err)
-					return err
-				}
-				goto Again
-			default:
-				debug.Printf("%s: index error: %v", ref, err)
 				return err
 			}
 
@@ -183,6 +186,16 @@ func reportAction(c *cli.Context) error {
 				Name: ref,
 			}
 			r.Report, r.Err = cc.VulnerabilityReport(ctx, d)
+			if attest && r.Err == nil {
+				r.Attestation, r.Err = ac.Attestation(ctx, d, subjectName(ref))
+			}
+			if r.Err == nil && c.Bool("push-attestation") {
+				if isLocalRef(ref) {
+					r.Err = fmt.Errorf("%s: attestations can only be pushed to registries", ref)
+				} else {
+					r.Err = pushAttestation(ctx, ref, d, r.Attestation)
+				}
+			}
 			result <- &r
 			return nil
 		})
@@ -191,11 +204,82 @@ func reportAction(c *cli.Context) error {
 		return err
 	}
 	close(result)
-	<-done
+	<-finished
+
+	if gh != nil {
+		debug.Printf("uploading results to github repository %q", gh.Repo)
+		if err := gh.Upload(c.Context, http.DefaultClient, sarif.Bytes()); err != nil {
+			return err
+		}
+	}
 	return nil
 
 }
 
+// IndexRef resolves the named container, makes sure Clair has indexed it, and
+// reports its manifest digest.
+//
+// Local images have their layers served by srv.
+func indexRef(ctx context.Context, cc reportClient, srv *layerServer, ref string) (claircore.Digest, error) {
+	var d claircore.Digest
+	var img *localImage
+	var err error
+	if isLocalRef(ref) {
+		img, err = openLocal(ref)
+		if err == nil {
+			d = img.Hash
+		}
+	} else {
+		d, err = resolveRef(ref)
+	}
+	if err != nil {
+		debug.Printf("%s: error: %v", ref, err)
+		return d, err
+	}
+	debug.Printf("%s: manifest: %v", ref, d)
+
+	// This bit is tricky:
+	//
+	// Initially start with a nil manifest, which optimistically
+	// prevents us from generating one.
+	//
+	// If we need the manifest, populate the manifest and jump to Again.
+	var m *claircore.Manifest
+Again:
+	err = cc.IndexReport(ctx, d, m)
+	switch {
+	case err == nil:
+	case errors.Is(err, errNeedManifest):
+		if img != nil {
+			m, err = srv.Manifest(img)
+		} else {
+			m, err = Inspect(ctx, ref)
+		}
+		if err != nil {
+			debug.Printf("%s: manifest error: %v", ref, err)
+			return d, err
+		}
+		goto Again
+	default:
+		debug.Printf("%s: index error: %v", ref, err)
+		return d, err
+	}
+	return d, nil
+}
+
+// SubjectName returns the name attestations about the container use: its
+// repository, or the reference itself for local images.
+func subjectName(ref string) string {
+	if isLocalRef(ref) {
+		return ref
+	}
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return ref
+	}
+	return r.Context().Name()
+}
+
 func resolveRef(r string) (claircore.Digest, error) {
 	var d claircore.Digest
 	rt, err := rt(r)
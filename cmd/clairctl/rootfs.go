@@ -0,0 +1,199 @@
+package main
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// RootfsExclude are the directories, relative to the root, left out when
+// snapshotting a host filesystem. They're either virtual or hold nothing an
+// indexer looks at.
+var rootfsExclude = []string{
+	"dev",
+	"mnt",
+	"media",
+	"proc",
+	"run",
+	"sys",
+	"tmp",
+	"var/tmp",
+}
+
+// OpenRootfsArchive opens a tarball of a host's root filesystem, possibly
+// compressed, as a single layer image.
+func openRootfsArchive(p string) (*localImage, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return rootfsImage(h.Sum(nil), func() (io.ReadCloser, error) {
+		return os.Open(p)
+	})
+}
+
+// OpenRootfs snapshots the root filesystem mounted at dir as a single layer
+// image.
+//
+// The snapshot is written to a temporary file first, because the layer's
+// digest needs to be known before the indexer fetches it and a live
+// filesystem may change in between. The file is unlinked right away and read
+// through the open descriptor, so nothing is left behind when clairctl exits.
+func openRootfs(dir string) (*localImage, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s: not a directory", dir)
+	}
+	f, err := ioutil.TempFile("", "clairctl-rootfs-")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	h := sha256.New()
+	if err := writeRootfs(io.MultiWriter(f, h), dir); err != nil {
+		f.Close()
+		return nil, err
+	}
+	sz, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	debug.Printf("%s: snapshot is %d bytes", dir, sz)
+	return rootfsImage(h.Sum(nil), func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(io.NewSectionReader(f, 0, sz)), nil
+	})
+}
+
+// RootfsImage returns a single layer image for a host filesystem with the
+// provided sha256 sum.
+//
+// Hosts don't have manifests, so the manifest digest is derived from the
+// layer digest: the same snapshot always gets the same manifest.
+func rootfsImage(sum []byte, open func() (io.ReadCloser, error)) (*localImage, error) {
+	ld, err := claircore.NewDigest("sha256", sum)
+	if err != nil {
+		return nil, err
+	}
+	ms := sha256.Sum256([]byte("rootfs:" + ld.String()))
+	md, err := claircore.NewDigest("sha256", ms[:])
+	if err != nil {
+		return nil, err
+	}
+	debug.Printf("rootfs layer %v, manifest %v", ld, md)
+	return &localImage{
+		Hash: md,
+		Layers: []localLayer{{
+			Hash: ld,
+			// Let the indexer work out the compression of archives.
+			ContentType: "application/octet-stream",
+			Open:        open,
+		}},
+	}, nil
+}
+
+// WriteRootfs writes a tarball of the filesystem rooted at dir to w.
+//
+// Directories, regular files, and symlinks are included. Anything that can't
+// be read, like files only root may open, is skipped rather than failing the
+// snapshot; the indexer only needs the package databases and release files.
+func writeRootfs(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			debug.Printf("%s: skipping: %v", p, err)
+			if fi != nil && fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		for _, x := range rootfsExclude {
+			if rel == x {
+				return filepath.SkipDir
+			}
+		}
+
+		var link string
+		switch m := fi.Mode(); {
+		case m.IsDir(), m.IsRegular():
+		case m&os.ModeSymlink != 0:
+			if link, err = os.Readlink(p); err != nil {
+				debug.Printf("%s: skipping: %v", p, err)
+				return nil
+			}
+		default:
+			// Devices, sockets, and pipes.
+			return nil
+		}
+		var rc io.ReadCloser
+		if fi.Mode().IsRegular() {
+			if rc, err = os.Open(p); err != nil {
+				debug.Printf("%s: skipping: %v", p, err)
+				return nil
+			}
+			defer rc.Close()
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if fi.IsDir() && !strings.HasSuffix(hdr.Name, "/") {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if rc != nil {
+			// The file may shrink or grow while it's read; the header has
+			// already promised a size, so copy exactly that much.
+			n, err := io.CopyN(tw, rc, hdr.Size)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if n < hdr.Size {
+				if _, err := io.CopyN(tw, zeroes{}, hdr.Size-n); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// Zeroes is an endless reader of zero bytes.
+type zeroes struct{}
+
+func (zeroes) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestRootfs checks that host filesystems are snapshotted as a single layer
+// matching its digest, without the virtual filesystems.
+func TestRootfs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clairctl-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	for p, c := range map[string]string{
+		"usr/lib/os-release":      "ID=debian\nVERSION_ID=\"10\"\n",
+		"var/lib/dpkg/status":     "Package: bash\nStatus: install ok installed\n",
+		"proc/1/status":           "Name: init\n",
+		"var/tmp/scratch":         "scratch\n",
+		"home/user/.bash_history": "ls\n",
+	} {
+		p = filepath.Join(root, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../usr/lib/os-release", filepath.Join(root, "etc", "os-release")); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := openLocal(rootfsPrefix + root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(img.Layers), 1; got != want {
+		t.Fatalf("got: %d layers, want: %d", got, want)
+	}
+	l := img.Layers[0]
+	rc, err := l.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	h := sha256.New()
+	tr := tar.NewReader(io.TeeReader(rc, h))
+	var got []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		got = append(got, hdr.Name)
+		if hdr.Name == "etc/os-release" && hdr.Linkname != "../usr/lib/os-release" {
+			t.Errorf("symlink: got: %q", hdr.Linkname)
+		}
+	}
+	io.Copy(ioutil.Discard, rc)
+	sort.Strings(got)
+	want := []string{
+		"etc/os-release",
+		"home/user/.bash_history",
+		"usr/lib/os-release",
+		"var/lib/dpkg/status",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got, want := h.Sum(nil), l.Hash.Checksum(); !cmp.Equal(got, want) {
+		t.Errorf("layer digest mismatch: got: %x, want: %x", got, want)
+	}
+
+	// The same snapshot as a tarball gets the same manifest.
+	rc, err = l.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	archive := filepath.Join(dir, "snapshot.tar")
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(archive, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ai, err := openLocal(rootfsArchivePrefix + archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ai.Hash, img.Hash; got.String() != want.String() {
+		t.Errorf("manifest: got: %v, want: %v", got, want)
+	}
+
+	if _, err := openLocal(rootfsPrefix + archive); err == nil {
+		t.Error("expected error for a file named as a directory")
+	}
+}
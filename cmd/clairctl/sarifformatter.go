@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/quay/claircore"
+)
+
+var _ Formatter = (*sarifFormatter)(nil)
+
+// SarifFormatter collects results and writes a single SARIF log on Close.
+//
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html for
+// the format and
+// https://docs.github.com/en/code-security/secure-coding/sarif-support-for-code-scanning
+// for the subset GitHub understands.
+type sarifFormatter struct {
+	sync.Mutex
+	w       io.WriteCloser
+	rules   map[string]*sarifRule
+	results []sarifResult
+}
+
+func newSarifFormatter(w io.WriteCloser) *sarifFormatter {
+	return &sarifFormatter{
+		w:     w,
+		rules: make(map[string]*sarifRule),
+	}
+}
+
+func (f *sarifFormatter) Format(r *Result) error {
+	if r.Err != nil {
+		return nil
+	}
+	f.Lock()
+	defer f.Unlock()
+	rep := r.Report
+	for pkgID, vIDs := range rep.PackageVulnerabilities {
+		pkg := rep.Packages[pkgID]
+		if pkg == nil {
+			continue
+		}
+		for _, vID := range vIDs {
+			v := rep.Vulnerabilities[vID]
+			if v == nil {
+				continue
+			}
+			if _, ok := f.rules[v.Name]; !ok {
+				f.rules[v.Name] = newSarifRule(v)
+			}
+			msg := fmt.Sprintf("%s %s is affected by %s.", pkg.Name, pkg.Version, v.Name)
+			if v.FixedInVersion != "" {
+				msg += fmt.Sprintf(" Fixed in %s.", v.FixedInVersion)
+			}
+			f.results = append(f.results, sarifResult{
+				RuleID:  v.Name,
+				Level:   sarifLevel(v.NormalizedSeverity),
+				Message: sarifMessage{Text: msg},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: r.Name},
+						Region:           sarifRegion{StartLine: 1},
+					},
+				}},
+				PartialFingerprints: map[string]string{
+					"clairPackageVulnerability": strings.Join([]string{r.Name, pkg.Name, pkg.Version, v.Name}, "/"),
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func (f *sarifFormatter) Close() error {
+	defer f.w.Close()
+	f.Lock()
+	defer f.Unlock()
+	rules := make([]*sarifRule, 0, len(f.rules))
+	for _, r := range f.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	sort.SliceStable(f.results, func(i, j int) bool {
+		return f.results[i].PartialFingerprints["clairPackageVulnerability"] < f.results[j].PartialFingerprints["clairPackageVulnerability"]
+	})
+	results := f.results
+	if results == nil {
+		results = []sarifResult{}
+	}
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "clair",
+				InformationURI: "https://github.com/quay/clair",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	enc := json.NewEncoder(f.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&log)
+}
+
+// SarifLevel maps a severity to a SARIF result level.
+func sarifLevel(s claircore.Severity) string {
+	switch s {
+	case claircore.Critical, claircore.High:
+		return "error"
+	case claircore.Medium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// SecuritySeverity maps a severity to the numeric "security-severity"
+// property GitHub uses to rank alerts.
+func securitySeverity(s claircore.Severity) string {
+	switch s {
+	case claircore.Critical:
+		return "9.5"
+	case claircore.High:
+		return "8.0"
+	case claircore.Medium:
+		return "5.5"
+	case claircore.Low:
+		return "2.0"
+	default:
+		return "0.0"
+	}
+}
+
+func newSarifRule(v *claircore.Vulnerability) *sarifRule {
+	desc := v.Description
+	if desc == "" {
+		desc = v.Name
+	}
+	r := sarifRule{
+		ID:               v.Name,
+		Name:             v.Name,
+		ShortDescription: sarifMessage{Text: v.Name},
+		FullDescription:  sarifMessage{Text: desc},
+		Properties: sarifRuleProperties{
+			Tags:             []string{"security", "vulnerability"},
+			SecuritySeverity: securitySeverity(v.NormalizedSeverity),
+		},
+	}
+	r.DefaultConfiguration.Level = sarifLevel(v.NormalizedSeverity)
+	// Links is a space-separated list; the first one is the best bet.
+	if ls := strings.Fields(v.Links); len(ls) != 0 {
+		r.HelpURI = ls[0]
+	}
+	return &r
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	Name                 string       `json:"name"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	FullDescription      sarifMessage `json:"fullDescription"`
+	HelpURI              string       `json:"helpUri,omitempty"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+	Properties sarifRuleProperties `json:"properties"`
+}
+
+type sarifRuleProperties struct {
+	Tags             []string `json:"tags"`
+	SecuritySeverity string   `json:"security-severity"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
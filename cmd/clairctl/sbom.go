@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/quay/claircore"
+	"github.com/urfave/cli/v2"
+
+	"github.com/quay/clair/v4/sbom"
+)
+
+// ImportSBOMCmd is the "import-sbom" subcommand.
+var ImportSBOMCmd = &cli.Command{
+	Name: "import-sbom",
+	Description: "Match the packages in CycloneDX or SPDX JSON documents against the\n" +
+		"vulnerability database, without access to the artifacts they describe.\n\n" +
+		"Packages are identified by their package URLs. Debian, Ubuntu, and Alpine\n" +
+		"packages and Python packages from PyPI are matched. Use \"-\" to read a\n" +
+		"document from stdin.",
+	Action:    importSBOMAction,
+	Usage:     "request vulnerability reports for SBOM documents",
+	ArgsUsage: "sbom...",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:    "host",
+			Usage:   "URL for the clairv4 v1 API.",
+			Value:   "http://localhost:6060/",
+			EnvVars: []string{"CLAIR_API"},
+		},
+		&cli.GenericFlag{
+			Name:        "out",
+			Aliases:     []string{"o"},
+			Usage:       "output format: text, json, xml, sarif",
+			DefaultText: "text",
+			Value:       &outFmt{},
+		},
+	}, localFlags...),
+}
+
+func importSBOMAction(c *cli.Context) error {
+	args := c.Args()
+	if args.Len() == 0 {
+		return errors.New("missing needed arguments")
+	}
+	cc, done, err := reportClientFor(c)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	out := c.Generic("out").(*outFmt)
+	f := out.Formatter(os.Stdout)
+	defer f.Close()
+	for i := 0; i < args.Len(); i++ {
+		name := args.Get(i)
+		r := Result{Name: name}
+		var ir *claircore.IndexReport
+		ir, r.Err = sbomIndexReport(c, name)
+		if r.Err == nil {
+			r.Report, r.Err = cc.Scan(c.Context, ir)
+		}
+		if err := f.Format(&r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SbomIndexReport reads the named SBOM document and converts it to an index
+// report.
+func sbomIndexReport(c *cli.Context, name string) (*claircore.IndexReport, error) {
+	var b []byte
+	var err error
+	if name == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d, err := sbom.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	debug.Printf("%s: %s document with %d components", name, d.Format, len(d.Components))
+	ir, err := d.IndexReport(c.Context)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert document: %w", err)
+	}
+	return ir, nil
+}
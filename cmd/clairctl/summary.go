@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/quay/claircore"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+// SummaryCmd is the "summary" subcommand.
+var SummaryCmd = &cli.Command{
+	Name: "summary",
+	Description: "Request vulnerability reports for the named manifests and print totals across all of them:\n" +
+		"vulnerabilities by severity, how many have a fix available, and the most\n" +
+		"vulnerable packages.\n\n" +
+		"Arguments may be manifest digests already known to Clair or container\n" +
+		"references, which are indexed first. Containers may also be \"docker-archive:\"\n" +
+		"tarballs or \"oci:\" image layouts on local disk, \"docker-daemon:\" images in\n" +
+		"a local Docker or Podman engine, or host filesystems named by \"rootfs:\"\n" +
+		"directories or \"rootfs-archive:\" tarballs.\n\n" +
+		"A vulnerability is counted once per package it affects in each manifest.",
+	Action:    summaryAction,
+	Usage:     "summarize the vulnerability reports of many manifests",
+	ArgsUsage: "manifest...",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:    "host",
+			Usage:   "URL for the clairv4 v1 API.",
+			Value:   "http://localhost:6060/",
+			EnvVars: []string{"CLAIR_API"},
+		},
+		&cli.StringFlag{
+			Name:    "out",
+			Aliases: []string{"o"},
+			Usage:   "output format: table, json, csv",
+			Value:   "table",
+		},
+		&cli.IntFlag{
+			Name:  "top",
+			Usage: "number of most vulnerable packages to list",
+			Value: 10,
+		},
+	}, serveFlags...),
+}
+
+func summaryAction(c *cli.Context) error {
+	args := c.Args()
+	if args.Len() == 0 {
+		return errors.New("need at least one argument")
+	}
+	switch f := c.String("out"); f {
+	case "table", "json", "csv":
+	default:
+		return fmt.Errorf("unrecognized output format %q", f)
+	}
+	cc, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+	srv, stop := layerServerFor(c)
+	defer stop()
+
+	reports := make([]*claircore.VulnerabilityReport, args.Len())
+	eg, ctx := errgroup.WithContext(c.Context)
+	for i := range reports {
+		i := i
+		arg := args.Get(i)
+		eg.Go(func() error {
+			d, err := claircore.ParseDigest(arg)
+			if err != nil {
+				d, err = indexRef(ctx, cc, srv, arg)
+				if err != nil {
+					return err
+				}
+			}
+			reports[i], err = cc.VulnerabilityReport(ctx, d)
+			if err != nil {
+				return fmt.Errorf("%s: %w", arg, err)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	s := summarizeReports(reports, c.Int("top"))
+	switch c.String("out") {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case "csv":
+		return s.WriteCSV(os.Stdout)
+	default:
+		return s.WriteTable(os.Stdout)
+	}
+}
+
+// ReportSummary totals the vulnerabilities in a set of VulnerabilityReports.
+type ReportSummary struct {
+	Manifests       []claircore.Digest `json:"manifests"`
+	Vulnerabilities int                `json:"vulnerabilities"`
+	Fixable         int                `json:"fixable"`
+	// Severities is ordered from most to least severe, and only includes
+	// severities that were seen.
+	Severities []SeverityCount `json:"severities"`
+	// Packages is ordered from most to least vulnerable.
+	Packages []PackageCount `json:"top_packages"`
+}
+
+// SeverityCount counts the vulnerabilities of a severity.
+type SeverityCount struct {
+	Severity        string `json:"severity"`
+	Vulnerabilities int    `json:"vulnerabilities"`
+	Fixable         int    `json:"fixable"`
+}
+
+// PackageCount counts the vulnerabilities affecting a package, by name, and
+// the manifests it's vulnerable in.
+type PackageCount struct {
+	Name            string `json:"name"`
+	Vulnerabilities int    `json:"vulnerabilities"`
+	Fixable         int    `json:"fixable"`
+	Manifests       int    `json:"manifests"`
+}
+
+// SummarizeReports totals the reports, listing at most top packages.
+func summarizeReports(rs []*claircore.VulnerabilityReport, top int) *ReportSummary {
+	s := ReportSummary{
+		Manifests:  make([]claircore.Digest, 0, len(rs)),
+		Severities: []SeverityCount{},
+		Packages:   []PackageCount{},
+	}
+	var sevs [claircore.Critical + 1]SeverityCount
+	pkgs := make(map[string]*PackageCount)
+	for _, r := range rs {
+		s.Manifests = append(s.Manifests, r.Hash)
+		seen := make(map[string]bool)
+		for k, v := range reportVulns(r) {
+			fixable := v.FixedInVersion != ""
+			sc := &sevs[v.NormalizedSeverity]
+			pc, ok := pkgs[k.Name]
+			if !ok {
+				pc = &PackageCount{Name: k.Name}
+				pkgs[k.Name] = pc
+			}
+			if !seen[k.Name] {
+				seen[k.Name] = true
+				pc.Manifests++
+			}
+			s.Vulnerabilities++
+			sc.Vulnerabilities++
+			pc.Vulnerabilities++
+			if fixable {
+				s.Fixable++
+				sc.Fixable++
+				pc.Fixable++
+			}
+		}
+	}
+	for i := len(sevs) - 1; i >= 0; i-- {
+		sc := sevs[i]
+		if sc.Vulnerabilities == 0 {
+			continue
+		}
+		sc.Severity = claircore.Severity(i).String()
+		s.Severities = append(s.Severities, sc)
+	}
+	for _, pc := range pkgs {
+		s.Packages = append(s.Packages, *pc)
+	}
+	sort.Slice(s.Packages, func(i, j int) bool {
+		a, b := s.Packages[i], s.Packages[j]
+		if a.Vulnerabilities != b.Vulnerabilities {
+			return a.Vulnerabilities > b.Vulnerabilities
+		}
+		return a.Name < b.Name
+	})
+	if top >= 0 && len(s.Packages) > top {
+		s.Packages = s.Packages[:top]
+	}
+	return &s
+}
+
+// WriteTable writes the summary as columnar text.
+func (s *ReportSummary) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d manifests\n\n", len(s.Manifests))
+	fmt.Fprintln(tw, "SEVERITY\tVULNERABILITIES\tFIXABLE")
+	for _, sc := range s.Severities {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", sc.Severity, sc.Vulnerabilities, sc.Fixable)
+	}
+	fmt.Fprintf(tw, "Total\t%d\t%d\n", s.Vulnerabilities, s.Fixable)
+	if len(s.Packages) != 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "PACKAGE\tVULNERABILITIES\tFIXABLE\tMANIFESTS")
+		for _, pc := range s.Packages {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", pc.Name, pc.Vulnerabilities, pc.Fixable, pc.Manifests)
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes the summary as CSV, one row per severity and package, with
+// a header row. The "kind" column tells the rows apart; package rows also
+// fill in the "manifests" column.
+func (s *ReportSummary) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"kind", "name", "vulnerabilities", "fixable", "manifests"})
+	for _, sc := range s.Severities {
+		cw.Write([]string{"severity", sc.Severity, strconv.Itoa(sc.Vulnerabilities), strconv.Itoa(sc.Fixable), ""})
+	}
+	cw.Write([]string{"total", "", strconv.Itoa(s.Vulnerabilities), strconv.Itoa(s.Fixable), strconv.Itoa(len(s.Manifests))})
+	for _, pc := range s.Packages {
+		cw.Write([]string{"package", pc.Name, strconv.Itoa(pc.Vulnerabilities), strconv.Itoa(pc.Fixable), strconv.Itoa(pc.Manifests)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
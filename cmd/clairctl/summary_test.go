@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestSummarizeReports(t *testing.T) {
+	a := &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"1": {Name: "openssl", Version: "1.1.1a"},
+			"2": {Name: "bash", Version: "5.0"},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"10": {Name: "CVE-2019-0001", NormalizedSeverity: claircore.High, FixedInVersion: "1.1.1b"},
+			"11": {Name: "CVE-2019-0002", NormalizedSeverity: claircore.Low},
+			"12": {Name: "CVE-2019-0003", NormalizedSeverity: claircore.Critical, FixedInVersion: "1.1.1c"},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"10", "12"},
+			"2": {"11"},
+		},
+	}
+	b := &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"3": {Name: "openssl", Version: "1.1.1a"},
+			"4": {Name: "zlib", Version: "1.2"},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"20": {Name: "CVE-2019-0001", NormalizedSeverity: claircore.High, FixedInVersion: "1.1.1b"},
+			"21": {Name: "CVE-2020-0001", NormalizedSeverity: claircore.Low},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"3": {"20"},
+			"4": {"21"},
+		},
+	}
+
+	got := summarizeReports([]*claircore.VulnerabilityReport{a, b}, 2)
+	want := &ReportSummary{
+		Vulnerabilities: 5,
+		Fixable:         3,
+		Severities: []SeverityCount{
+			{Severity: "Critical", Vulnerabilities: 1, Fixable: 1},
+			{Severity: "High", Vulnerabilities: 2, Fixable: 2},
+			{Severity: "Low", Vulnerabilities: 2},
+		},
+		Packages: []PackageCount{
+			{Name: "openssl", Vulnerabilities: 3, Fixable: 3, Manifests: 2},
+			{Name: "bash", Vulnerabilities: 1, Manifests: 1},
+		},
+	}
+	if len(got.Manifests) != 2 {
+		t.Errorf("got %d manifests, want 2", len(got.Manifests))
+	}
+	// The zero Digest has unexported fields; the manifests are checked above.
+	got.Manifests = nil
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	var csv strings.Builder
+	if err := got.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := `kind,name,vulnerabilities,fixable,manifests
+severity,Critical,1,1,
+severity,High,2,2,
+severity,Low,2,0,
+total,,5,3,0
+package,openssl,3,3,2
+package,bash,1,0,1
+`
+	if got := csv.String(); got != wantCSV {
+		t.Error(cmp.Diff(got, wantCSV))
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/quay/claircore"
+	"github.com/urfave/cli/v2"
+)
+
+// WatchCmd is the "watch" subcommand.
+var WatchCmd = &cli.Command{
+	Name: "watch",
+	Description: "Poll the named repositories for new tags or tags pointing at new manifests,\n" +
+		"index them, and print a summary of each vulnerability report.\n\n" +
+		"The manifest last seen for every tag is kept in a state file, so restarting\n" +
+		"doesn't reprocess images.",
+	Action:    watchAction,
+	Usage:     "continuously report on new images in the named repositories",
+	ArgsUsage: "repository...",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:    "host",
+			Usage:   "URL for the clairv4 v1 API.",
+			Value:   "http://localhost:6060/",
+			EnvVars: []string{"CLAIR_API"},
+		},
+		&cli.DurationFlag{
+			Name:  "interval",
+			Usage: "time between polls of the repositories",
+			Value: 5 * time.Minute,
+		},
+		&cli.PathFlag{
+			Name:      "state",
+			Usage:     "file recording the manifests already reported on",
+			Value:     "clairctl-watch.json",
+			TakesFile: true,
+		},
+		&cli.StringFlag{
+			Name:  "post",
+			Usage: "URL to POST each summary to as JSON, in addition to printing it",
+		},
+		&cli.StringFlag{
+			Name:    "out",
+			Aliases: []string{"o"},
+			Usage:   "output format: text, json",
+			Value:   "text",
+		},
+		&cli.BoolFlag{
+			Name:  "once",
+			Usage: "poll once and exit",
+		},
+	}, localFlags...),
+}
+
+// WatchSummary summarizes the vulnerability report for a tag.
+type WatchSummary struct {
+	Reference       string           `json:"reference"`
+	Manifest        claircore.Digest `json:"manifest"`
+	Vulnerabilities int              `json:"vulnerabilities"`
+	Severities      map[string]int   `json:"severities"`
+	Time            time.Time        `json:"time"`
+}
+
+// WatchState maps tag references to the manifest digest last reported on.
+type watchState map[string]string
+
+func watchAction(c *cli.Context) error {
+	args := c.Args()
+	if args.Len() == 0 {
+		return errors.New("missing needed arguments")
+	}
+	switch f := c.String("out"); f {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unrecognized output format %q", f)
+	}
+	repos := make([]name.Repository, args.Len())
+	for i := range repos {
+		var err error
+		repos[i], err = name.NewRepository(args.Get(i))
+		if err != nil {
+			return err
+		}
+	}
+	statePath := c.Path("state")
+	state, err := loadWatchState(statePath)
+	if err != nil {
+		return err
+	}
+	cc, done, err := reportClientFor(c)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	w := watcher{
+		cc:    cc,
+		state: state,
+		save:  func(s watchState) error { return saveWatchState(statePath, s) },
+		out:   os.Stdout,
+		json:  c.String("out") == "json",
+		post:  c.String("post"),
+	}
+	ctx := c.Context
+	t := time.NewTicker(c.Duration("interval"))
+	defer t.Stop()
+	for {
+		for _, r := range repos {
+			if err := w.Poll(ctx, r); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				fmt.Fprintf(os.Stderr, "%s: %v\n", r, err)
+			}
+		}
+		if c.Bool("once") {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Watcher reports on the tags in a repository that have changed since the
+// last poll.
+type watcher struct {
+	cc    reportClient
+	state watchState
+	save  func(watchState) error
+	out   io.Writer
+	json  bool
+	post  string
+}
+
+// Poll lists the repository's tags and reports on any pointing at a
+// manifest not seen before.
+//
+// Errors with individual tags are printed and skipped, so they're retried
+// on the next poll.
+func (w *watcher) Poll(ctx context.Context, repo name.Repository) error {
+	rt, err := rt(repo.String())
+	if err != nil {
+		return err
+	}
+	tags, err := remote.List(repo, remote.WithTransport(rt))
+	if err != nil {
+		return fmt.Errorf("listing tags: %w", err)
+	}
+	sort.Strings(tags)
+	debug.Printf("%s: %d tags", repo, len(tags))
+	for _, tag := range tags {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ref := repo.Tag(tag).String()
+		d, err := resolveRef(ref)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", ref, err)
+			continue
+		}
+		if w.state[ref] == d.String() {
+			continue
+		}
+		debug.Printf("%s: new manifest %v", ref, d)
+		if err := w.report(ctx, ref); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", ref, err)
+			continue
+		}
+		w.state[ref] = d.String()
+		if err := w.save(w.state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Report indexes the reference and emits a summary of its vulnerability
+// report.
+func (w *watcher) report(ctx context.Context, ref string) error {
+	d, err := indexRef(ctx, w.cc, nil, ref)
+	if err != nil {
+		return err
+	}
+	vr, err := w.cc.VulnerabilityReport(ctx, d)
+	if err != nil {
+		return err
+	}
+	s := summarize(ref, vr)
+	if err := s.Write(w.out, w.json); err != nil {
+		return err
+	}
+	if w.post != "" {
+		return s.Post(ctx, http.DefaultClient, w.post)
+	}
+	return nil
+}
+
+// Summarize counts the vulnerabilities in the report by severity.
+func summarize(ref string, vr *claircore.VulnerabilityReport) *WatchSummary {
+	s := WatchSummary{
+		Reference:  ref,
+		Manifest:   vr.Hash,
+		Severities: make(map[string]int),
+		Time:       time.Now().UTC(),
+	}
+	for _, ids := range vr.PackageVulnerabilities {
+		for _, id := range ids {
+			v, ok := vr.Vulnerabilities[id]
+			if !ok {
+				continue
+			}
+			s.Vulnerabilities++
+			s.Severities[v.NormalizedSeverity.String()]++
+		}
+	}
+	return &s
+}
+
+// Write prints the summary as a line of text or JSON.
+func (s *WatchSummary) Write(w io.Writer, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(s)
+	}
+	var b strings.Builder
+	for i := int(claircore.Critical); i >= int(claircore.Unknown); i-- {
+		sev := claircore.Severity(i)
+		n, ok := s.Severities[sev.String()]
+		if !ok {
+			continue
+		}
+		if b.Len() != 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s: %d", sev, n)
+	}
+	_, err := fmt.Fprintf(w, "%s\t%v\t%d vulnerabilities\t%s\n", s.Reference, s.Manifest, s.Vulnerabilities, b.String())
+	return err
+}
+
+// Post sends the summary to the URL as JSON.
+func (s *WatchSummary) Post(ctx context.Context, c *http.Client, u string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("user-agent", userAgent)
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected response posting summary: %s", res.Status)
+	}
+	return nil
+}
+
+// LoadWatchState reads the state file, treating a missing file as empty.
+func loadWatchState(p string) (watchState, error) {
+	s := make(watchState)
+	b, err := ioutil.ReadFile(p)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("reading state file %q: %w", p, err)
+	}
+	return s, nil
+}
+
+// SaveWatchState replaces the state file, so that it's never left partially
+// written.
+func saveWatchState(p string, s watchState) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(p), ".clairctl-watch")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestWatchSummary(t *testing.T) {
+	vr := &claircore.VulnerabilityReport{
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"1": {Name: "CVE-2019-0001", NormalizedSeverity: claircore.High},
+			"2": {Name: "CVE-2019-0002", NormalizedSeverity: claircore.Low},
+			"3": {Name: "CVE-2019-0003", NormalizedSeverity: claircore.High},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"10": {"1", "2"},
+			"11": {"3"},
+		},
+	}
+	s := summarize("quay.io/projectquay/clair:latest", vr)
+	if got, want := s.Vulnerabilities, 3; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if got, want := s.Severities, map[string]int{"High": 2, "Low": 1}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	var buf bytes.Buffer
+	if err := s.Write(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "quay.io/projectquay/clair:latest\t" + s.Manifest.String() + "\t3 vulnerabilities\tHigh: 2, Low: 1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestWatchState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clairctl-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "state.json")
+
+	s, err := loadWatchState(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 0 {
+		t.Errorf("unexpected state: %v", s)
+	}
+	s["quay.io/projectquay/clair:latest"] = "sha256:" + string(bytes.Repeat([]byte("a"), 64))
+	if err := saveWatchState(p, s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadWatchState(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(got, s) {
+		t.Error(cmp.Diff(got, s))
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import "fmt"
+
+// Archive configures copying generated reports to object storage.
+//
+// The store is selected by the "Name" member and configured by the
+// corresponding struct.
+type Archive struct {
+	// One of the following strings:
+	// "": archival is disabled
+	// "s3"
+	// "gcs"
+	Name string `yaml:"name" json:"name"`
+	// Which reports to archive, any of:
+	// "index"
+	// "vulnerability"
+	//
+	// Defaults to both.
+	Reports []string `yaml:"reports" json:"reports"`
+	// A prefix for object keys, e.g. "clair/".
+	Prefix string     `yaml:"prefix" json:"prefix"`
+	S3     ArchiveS3  `yaml:"s3" json:"s3"`
+	GCS    ArchiveGCS `yaml:"gcs" json:"gcs"`
+}
+
+// ArchiveS3 configures the S3 archive store.
+type ArchiveS3 struct {
+	Bucket string `yaml:"bucket" json:"bucket"`
+	// Defaults to "us-east-1".
+	Region string `yaml:"region" json:"region"`
+	// The API root, for S3 compatible stores. Defaults to the AWS endpoint
+	// for the region.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// Put the bucket in the path instead of the hostname.
+	PathStyle bool `yaml:"path_style" json:"path_style"`
+	// The storage class objects are written with, e.g. "STANDARD_IA".
+	StorageClass string `yaml:"storage_class" json:"storage_class"`
+	// Static credentials. If unset, credentials are found as the AWS SDKs
+	// do: from the environment, a web identity token, or the container or
+	// instance role.
+	AccessKeyID     string `yaml:"access_key_id" json:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key" json:"secret_access_key" secret:"true"`
+	SessionToken    string `yaml:"session_token" json:"session_token" secret:"true"`
+	// A role to assume with the credentials.
+	RoleARN string `yaml:"role_arn" json:"role_arn"`
+}
+
+// ArchiveGCS configures the Google Cloud Storage archive store.
+type ArchiveGCS struct {
+	Bucket string `yaml:"bucket" json:"bucket"`
+	// The API root. Mostly useful for emulators and testing.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// The storage class objects are written with, e.g. "NEARLINE".
+	StorageClass string `yaml:"storage_class" json:"storage_class"`
+	// The filesystem path where a service account key, in the JSON format
+	// issued by GCP, can be read.
+	//
+	// If empty, credentials are obtained from the metadata server.
+	CredentialsFile string `yaml:"credentials_file" json:"credentials_file"`
+}
+
+// Enabled reports whether archival is configured.
+func (a *Archive) Enabled() bool {
+	return a.Name != ""
+}
+
+// Archives reports whether the named kind of report is archived.
+func (a *Archive) Archives(kind string) bool {
+	if !a.Enabled() {
+		return false
+	}
+	if len(a.Reports) == 0 {
+		return true
+	}
+	for _, r := range a.Reports {
+		if r == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the Archive configuration.
+func (a *Archive) Validate() error {
+	var bucket string
+	switch a.Name {
+	case "":
+		return nil
+	case "s3":
+		bucket = a.S3.Bucket
+	case "gcs":
+		bucket = a.GCS.Bucket
+	default:
+		return fmt.Errorf("unknown archive store %q", a.Name)
+	}
+	if bucket == "" {
+		return fmt.Errorf("archive store %q requires a bucket", a.Name)
+	}
+	for _, r := range a.Reports {
+		switch r {
+		case "index", "vulnerability":
+		default:
+			return fmt.Errorf("unknown archive report kind %q", r)
+		}
+	}
+	return nil
+}
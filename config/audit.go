@@ -0,0 +1,48 @@
+package config
+
+import "net/http"
+
+// Audit configures the audit log.
+//
+// The audit log records every API request in a stable JSON schema. The sink
+// is selected by the "Name" member and configured by the corresponding
+// struct.
+type Audit struct {
+	// One of the following strings:
+	// "": the audit log is disabled
+	// "file"
+	// "syslog"
+	// "http"
+	Name   string      `yaml:"name" json:"name"`
+	File   AuditFile   `yaml:"file" json:"file"`
+	Syslog AuditSyslog `yaml:"syslog" json:"syslog"`
+	HTTP   AuditHTTP   `yaml:"http" json:"http"`
+}
+
+// AuditFile configures the file audit sink.
+type AuditFile struct {
+	// The filesystem path to append audit records to. The file is created if
+	// it does not exist.
+	//
+	// The special value "-" writes to stdout.
+	Path string `yaml:"path" json:"path"`
+}
+
+// AuditSyslog configures the syslog audit sink.
+type AuditSyslog struct {
+	// Network and Address of the syslog daemon, as accepted by net.Dial.
+	//
+	// If both are empty, the local syslog daemon is used.
+	Network string `yaml:"network" json:"network"`
+	Address string `yaml:"address" json:"address"`
+	// The syslog tag. Defaults to "clair-audit".
+	Tag string `yaml:"tag" json:"tag"`
+}
+
+// AuditHTTP configures the HTTP audit sink.
+type AuditHTTP struct {
+	// The URL to POST audit records to.
+	URL string `yaml:"url" json:"url"`
+	// Any HTTP headers necessary for the request to URL.
+	Headers http.Header `yaml:"headers" json:"headers" secret:"true"`
+}
@@ -1,6 +1,13 @@
 package config
 
-import "encoding/base64"
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/quay/clair/v4/middleware/rbac"
+)
 
 // Auth holds the specific configs for different authentication methods.
 //
@@ -9,12 +16,29 @@ import "encoding/base64"
 type Auth struct {
 	PSK       *AuthPSK       `yaml:"psk,omitempty" json:"psk,omitempty"`
 	Keyserver *AuthKeyserver `yaml:"keyserver,omitempty" json:"keyserver,omitempty"`
+	// Workload authenticates requests between Clair services with workload
+	// identities instead of a key shared by the services.
+	Workload *AuthWorkload `yaml:"workload,omitempty" json:"workload,omitempty"`
+	// Introspection authenticates opaque bearer tokens by asking the OAuth2
+	// authorization server that issued them.
+	Introspection *AuthIntrospection `yaml:"introspection,omitempty" json:"introspection,omitempty"`
+	// RBAC restricts what authenticated principals may do, based on the
+	// roles in their tokens.
+	//
+	// If nil, every authenticated principal may do everything.
+	RBAC *AuthRBAC `yaml:"rbac,omitempty" json:"rbac,omitempty"`
+	// Admin authenticates requests to the admin API and the introspection
+	// server separately from the rest of the API, so the credentials clients
+	// use can't reach them.
+	Admin *AuthAdmin `yaml:"admin,omitempty" json:"admin,omitempty"`
 }
 
 // Any reports whether any sort of authentication is configured.
 func (a Auth) Any() bool {
 	return a.PSK != nil ||
-		a.Keyserver != nil
+		a.Keyserver != nil ||
+		a.Workload != nil ||
+		a.Introspection != nil
 }
 
 // AuthKeyserver is the configuration for doing authentication with the Quay
@@ -24,11 +48,11 @@ func (a Auth) Any() bool {
 // "combo".
 type AuthKeyserver struct {
 	API          string `yaml:"api" json:"api"`
-	Intraservice []byte `yaml:"intraservice" json:"intraservice"`
+	Intraservice []byte `yaml:"intraservice" json:"intraservice" secret:"true"`
 }
 type keyserverConfig struct {
 	API          string `yaml:"api" json:"api"`
-	Intraservice string `yaml:"intraservice" json:"intraservice"`
+	Intraservice string `yaml:"intraservice" json:"intraservice" secret:"true"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -57,13 +81,19 @@ func (a *AuthKeyserver) MarshalYAML() (interface{}, error) {
 // AuthPSK is the configuration for doing pre-shared key based authentication.
 //
 // The "Issuer" key is what the service expects to verify as the "issuer" claim.
+//
+// The "Intraservice" key, if set, is used for requests between Clair services
+// instead of "Key". Only requests signed with it are trusted as coming from
+// another Clair service, so it must not be given to clients.
 type AuthPSK struct {
-	Key    []byte   `yaml:"key" json:"key"`
-	Issuer []string `yaml:"iss" json:"iss"`
+	Key          []byte   `yaml:"key" json:"key" secret:"true"`
+	Issuer       []string `yaml:"iss" json:"iss"`
+	Intraservice []byte   `yaml:"intraservice,omitempty" json:"intraservice,omitempty" secret:"true"`
 }
 type pskConfig struct {
-	Key    string   `yaml:"key" json:"key"`
-	Issuer []string `yaml:"iss" json:"iss"`
+	Key          string   `yaml:"key" json:"key" secret:"true"`
+	Issuer       []string `yaml:"iss" json:"iss"`
+	Intraservice string   `yaml:"intraservice,omitempty" json:"intraservice,omitempty" secret:"true"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -78,13 +108,258 @@ func (a *AuthPSK) UnmarshalYAML(f func(interface{}) error) error {
 		return err
 	}
 	a.Key = s
+	if m.Intraservice != "" {
+		s, err := base64.StdEncoding.DecodeString(m.Intraservice)
+		if err != nil {
+			return err
+		}
+		a.Intraservice = s
+	}
 	return nil
 }
 
 // MarshalYAML implements yaml.Marshaler.
 func (a *AuthPSK) MarshalYAML() (interface{}, error) {
-	return &pskConfig{
+	c := &pskConfig{
 		Key:    base64.StdEncoding.EncodeToString(a.Key),
 		Issuer: a.Issuer,
-	}, nil
+	}
+	if a.Intraservice != nil {
+		c.Intraservice = base64.StdEncoding.EncodeToString(a.Intraservice)
+	}
+	return c, nil
+}
+
+// AuthWorkload is the configuration for authenticating requests between
+// Clair services with workload identity tokens: Kubernetes projected service
+// account tokens or SPIFFE JWT-SVIDs.
+//
+// Other clients are still authenticated with the "psk" or "keyserver"
+// methods, if one is configured.
+type AuthWorkload struct {
+	// TokenFile is the path of the token this service presents to the
+	// others. It's expected to be kept up to date by the kubelet or a SPIFFE
+	// helper, and is read again whenever it changes.
+	TokenFile string `yaml:"token_file" json:"token_file"`
+	// JWKS is the URL or path of the key set other services' tokens are
+	// verified with, like "https://kubernetes.default.svc/openid/v1/jwks" or
+	// a SPIFFE JWT bundle.
+	JWKS string `yaml:"jwks" json:"jwks"`
+	// JWKSCA is the path of PEM certificates trusted when fetching the key
+	// set. If empty, the system's are used.
+	JWKSCA string `yaml:"jwks_ca" json:"jwks_ca"`
+	// JWKSTokenFile is the path of a token presented when fetching the key
+	// set, like the pod's default service account token.
+	JWKSTokenFile string `yaml:"jwks_token_file" json:"jwks_token_file"`
+	// Audience is the audience tokens must be issued for.
+	Audience string `yaml:"audience" json:"audience"`
+	// Issuers are the accepted token issuers. If empty, any issuer is.
+	Issuers []string `yaml:"issuers" json:"issuers"`
+	// Subjects are the identities of Clair services, like
+	// "system:serviceaccount:clair:clair" or "spiffe://example.org/clair".
+	Subjects []string `yaml:"subjects" json:"subjects"`
+}
+
+// Validate checks the workload identity configuration.
+func (a *AuthWorkload) Validate() error {
+	if a == nil {
+		return nil
+	}
+	switch {
+	case a.TokenFile == "":
+		return fmt.Errorf("workload auth: token_file is required")
+	case a.JWKS == "":
+		return fmt.Errorf("workload auth: jwks is required")
+	case a.Audience == "":
+		return fmt.Errorf("workload auth: audience is required")
+	case len(a.Subjects) == 0:
+		return fmt.Errorf("workload auth: subjects are required")
+	}
+	return nil
+}
+
+// AuthIntrospection is the configuration for authenticating opaque bearer
+// tokens with an OAuth2 token introspection endpoint, as described in RFC
+// 7662.
+//
+// Other clients are still authenticated with the "psk" or "keyserver"
+// methods, if one is configured.
+type AuthIntrospection struct {
+	// Endpoint is the URL of the introspection endpoint.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// ClientID and ClientSecret are the credentials Clair authenticates to
+	// the endpoint with, using HTTP basic authentication.
+	ClientID     string `yaml:"client_id" json:"client_id"`
+	ClientSecret string `yaml:"client_secret" json:"client_secret" secret:"true"`
+	// CA is the path of PEM certificates trusted when calling the endpoint.
+	// If empty, the system's are used.
+	CA string `yaml:"ca" json:"ca"`
+	// Audience, if set, is an audience tokens must be issued for.
+	Audience string `yaml:"audience" json:"audience"`
+	// Issuers are the accepted token issuers. If empty, any issuer is.
+	Issuers []string `yaml:"issuers" json:"issuers"`
+	// CacheTTL is how long the endpoint's answer for a token is reused. An
+	// active token is never cached past its expiry.
+	//
+	// The default is one minute.
+	CacheTTL time.Duration `yaml:"cache_ttl" json:"cache_ttl"`
+}
+
+// Validate checks the introspection configuration and fills in defaults.
+func (a *AuthIntrospection) Validate() error {
+	const DefaultCacheTTL = time.Minute
+	if a == nil {
+		return nil
+	}
+	u, err := url.Parse(a.Endpoint)
+	switch {
+	case a.Endpoint == "":
+		return fmt.Errorf("introspection auth: endpoint is required")
+	case err != nil:
+		return fmt.Errorf("introspection auth: bad endpoint: %w", err)
+	case u.Scheme != "http" && u.Scheme != "https":
+		return fmt.Errorf("introspection auth: endpoint must be an http or https URL")
+	case a.CacheTTL < 0:
+		return fmt.Errorf("introspection auth: cache_ttl must not be negative")
+	case a.CacheTTL == 0:
+		a.CacheTTL = DefaultCacheTTL
+	}
+	return nil
+}
+
+// AuthAdmin is the configuration for authenticating requests to the admin
+// API and the introspection server, other than its health check.
+//
+// The methods are configured as they are for the rest of the API, but with
+// their own keys, audiences, or endpoints, and any of them is accepted. If
+// MTLS is set, the introspection server instead requires a client
+// certificate.
+type AuthAdmin struct {
+	PSK           *AuthPSK           `yaml:"psk,omitempty" json:"psk,omitempty"`
+	Keyserver     *AuthKeyserver     `yaml:"keyserver,omitempty" json:"keyserver,omitempty"`
+	Workload      *AuthWorkload      `yaml:"workload,omitempty" json:"workload,omitempty"`
+	Introspection *AuthIntrospection `yaml:"introspection,omitempty" json:"introspection,omitempty"`
+	// MTLS serves the introspection server over TLS, requiring clients to
+	// present a certificate.
+	//
+	// If it's the only method, the admin API isn't reachable through the
+	// API server at all.
+	MTLS *AuthMTLS `yaml:"mtls,omitempty" json:"mtls,omitempty"`
+}
+
+// AuthMTLS is the configuration for requiring client certificates.
+type AuthMTLS struct {
+	// Cert and Key are the paths of the server's PEM certificate and key.
+	Cert string `yaml:"cert" json:"cert"`
+	Key  string `yaml:"key" json:"key"`
+	// ClientCA is the path of PEM certificates client certificates must be
+	// issued by.
+	ClientCA string `yaml:"client_ca" json:"client_ca"`
+}
+
+// Auth returns the token authentication methods as an Auth.
+func (a *AuthAdmin) Auth() Auth {
+	if a == nil {
+		return Auth{}
+	}
+	return Auth{
+		PSK:           a.PSK,
+		Keyserver:     a.Keyserver,
+		Workload:      a.Workload,
+		Introspection: a.Introspection,
+	}
+}
+
+// Validate checks the admin authentication configuration and fills in
+// defaults.
+func (a *AuthAdmin) Validate() error {
+	if a == nil {
+		return nil
+	}
+	if !a.Auth().Any() && a.MTLS == nil {
+		return fmt.Errorf("admin auth: no methods configured")
+	}
+	if m := a.MTLS; m != nil {
+		switch {
+		case m.Cert == "" || m.Key == "":
+			return fmt.Errorf("admin auth: mtls: cert and key are required")
+		case m.ClientCA == "":
+			return fmt.Errorf("admin auth: mtls: client_ca is required")
+		}
+	}
+	if err := a.Workload.Validate(); err != nil {
+		return fmt.Errorf("admin auth: %w", err)
+	}
+	if err := a.Introspection.Validate(); err != nil {
+		return fmt.Errorf("admin auth: %w", err)
+	}
+	return nil
+}
+
+// AuthRBAC maps roles claimed in request tokens to permissions.
+//
+// The permissions are:
+// "indexer.write": submit manifests and layers, and delete index reports
+// "reports.read": read index and vulnerability reports
+// "notifications.read": read notifications
+// "notifications.write": delete notifications
+// "admin": everything, including the internal API and changing Clair's state
+type AuthRBAC struct {
+	// Claim is the JWT claim holding roles, either a list of strings or a
+	// space-separated string.
+	//
+	// The default is "roles".
+	Claim string `yaml:"claim" json:"claim"`
+	// Roles maps role names to the permissions they grant. A claimed value
+	// that isn't a role but is a permission name grants that permission.
+	Roles map[string][]string `yaml:"roles" json:"roles"`
+	// Default lists permissions granted to every authenticated principal.
+	Default []string `yaml:"default" json:"default"`
+}
+
+// Validate checks the RBAC configuration against the rest of the Config.
+func (a *AuthRBAC) Validate(c *Config) error {
+	if a == nil {
+		return nil
+	}
+	if !c.Auth.Any() {
+		return fmt.Errorf("rbac requires authentication to be configured")
+	}
+	if a.Claim == "" {
+		a.Claim = "roles"
+	}
+	if _, err := a.Policy(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Policy returns an rbac.Policy granting the configured permissions. The
+// caller is responsible for filling in the rules.
+func (a *AuthRBAC) Policy() (*rbac.Policy, error) {
+	parse := func(ss []string) ([]rbac.Permission, error) {
+		out := make([]rbac.Permission, 0, len(ss))
+		for _, s := range ss {
+			p, err := rbac.ParsePermission(s)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, p)
+		}
+		return out, nil
+	}
+	p := rbac.Policy{
+		Claim: a.Claim,
+		Roles: make(map[string][]rbac.Permission, len(a.Roles)),
+	}
+	var err error
+	if p.Default, err = parse(a.Default); err != nil {
+		return nil, fmt.Errorf("rbac default: %w", err)
+	}
+	for role, ss := range a.Roles {
+		if p.Roles[role], err = parse(ss); err != nil {
+			return nil, fmt.Errorf("rbac role %q: %w", role, err)
+		}
+	}
+	return &p, nil
 }
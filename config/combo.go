@@ -0,0 +1,27 @@
+package config
+
+// Combo configures which services run in combo mode. The indexer always runs,
+// as the others need it; a deployment that only needs an indexer should use
+// indexer mode instead.
+type Combo struct {
+	// A "true" or "false" value
+	//
+	// Whether to leave out the matcher, and with it the notifier, which
+	// needs it.
+	DisableMatcher bool `yaml:"disable_matcher" json:"disable_matcher"`
+	// A "true" or "false" value
+	//
+	// Whether to leave out the notifier. Its configuration isn't needed
+	// then.
+	DisableNotifier bool `yaml:"disable_notifier" json:"disable_notifier"`
+}
+
+// Matcher reports whether combo mode runs the matcher.
+func (c *Combo) Matcher() bool {
+	return !c.DisableMatcher
+}
+
+// Notifier reports whether combo mode runs the notifier.
+func (c *Combo) Notifier() bool {
+	return !c.DisableMatcher && !c.DisableNotifier
+}
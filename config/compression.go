@@ -0,0 +1,20 @@
+package config
+
+// Compression configures transparent compression of index and vulnerability
+// report responses.
+//
+// Compression is negotiated with the client via the "Accept-Encoding"
+// header; zstd and gzip are supported.
+type Compression struct {
+	// Disable turns off response compression.
+	Disable bool `yaml:"disable" json:"disable"`
+	// The smallest response body, in bytes, that will be compressed.
+	//
+	// If 0, a default of 1024 is used. A negative number compresses every
+	// response.
+	MinSize int `yaml:"min_size" json:"min_size"`
+	// The compression level, from 1 (fastest) to 9 (smallest).
+	//
+	// If 0, the fastest level is used.
+	Level int `yaml:"level" json:"level"`
+}
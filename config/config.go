@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -31,10 +32,12 @@ type Config struct {
 	// "matcher": runs just the matcher node
 	// "combo":	will run both indexer and matcher on the same node.
 	Mode string `yaml:"-" json:"-"`
+	// Combo selects which services run in combo mode.
+	Combo Combo `yaml:"combo" json:"combo"`
 	// A string in <host>:<port> format where <host> can be an empty string.
 	//
 	// exposes Clair node's functionality to the network.
-	// see /openapi/v1 for api spec.
+	// see /openapi/v1.1 for api spec.
 	HTTPListenAddr string `yaml:"http_listen_addr" json:"http_listen_addr"`
 	// A string in <host>:<port> format where <host> can be an empty string.
 	//
@@ -50,7 +53,9 @@ type Config struct {
 	// "error"
 	// "fatal"
 	// "panic"
-	LogLevel string   `yaml:"log_level" json:"log_level"`
+	LogLevel string `yaml:"log_level" json:"log_level"`
+	// Logging configures the log format, sampling, and per-component levels.
+	Logging  Logging  `yaml:"logging" json:"logging"`
 	Indexer  Indexer  `yaml:"indexer" json:"indexer"`
 	Matcher  Matcher  `yaml:"matcher" json:"matcher"`
 	Notifier Notifier `yaml:"notifier" json:"notifier"`
@@ -58,6 +63,25 @@ type Config struct {
 	Trace    Trace    `yaml:"trace" json:"trace"`
 	Metrics  Metrics  `yaml:"metrics" json:"metrics"`
 	Updaters Updaters `yaml:"updaters,omitempty" json:"updaters,omitempty"`
+	Audit    Audit    `yaml:"audit" json:"audit"`
+	// Compression configures response compression for the report endpoints.
+	Compression Compression `yaml:"compression" json:"compression"`
+	// Limits configures request body size limits and timeouts for the HTTP
+	// API.
+	Limits Limits `yaml:"limits" json:"limits"`
+	// IntraServiceClient configures retries and circuit breaking for
+	// requests between Clair services.
+	IntraServiceClient IntraServiceClient `yaml:"intraservice_client" json:"intraservice_client"`
+	// Tenancy configures scoping of data to tenants.
+	Tenancy Tenancy `yaml:"tenancy" json:"tenancy"`
+	// Archive configures copying generated reports to object storage.
+	Archive Archive `yaml:"archive" json:"archive"`
+	// Locks configures the backend for deployment-wide locks.
+	Locks Locks `yaml:"locks" json:"locks"`
+	// Integrations configures integrations with other systems.
+	Integrations Integrations `yaml:"integrations" json:"integrations"`
+	// Shutdown configures how in-flight work is drained when Clair stops.
+	Shutdown Shutdown `yaml:"shutdown" json:"shutdown"`
 }
 
 // Updaters configures updater behavior.
@@ -83,10 +107,41 @@ type Updaters struct {
 	//
 	// These are defined by the updater implementation and can't be documented
 	// here. Improving the documentation for these is an open issue.
+	//
+	// Every block may also set "proxy" and "mirror" to change where that
+	// updater set or updater fetches from.
 	Config map[string]yaml.Node `yaml:"config" json:"config"`
 	// Filter is a regexp that disallows updaters that do not match from
 	// running.
 	Filter string `yaml:"filter" json:"filter"`
+	// Overrides allows updater sets and updaters to be disabled and
+	// reconfigured at runtime through the matcher API.
+	//
+	// Overrides are stored in the matcher database and take effect at the
+	// next update run.
+	Overrides bool `yaml:"overrides" json:"overrides"`
+	// HTTP configures the HTTP client updaters share.
+	HTTP UpdaterHTTP `yaml:"http" json:"http"`
+}
+
+// UpdaterHTTP configures the HTTP client updaters share, to keep update runs
+// from saturating slow links or fetching unchanged databases again.
+type UpdaterHTTP struct {
+	// MaxBandwidth is the most bytes per second updaters download, across
+	// all of them. Zero doesn't limit anything.
+	MaxBandwidth int64 `yaml:"max_bandwidth" json:"max_bandwidth"`
+	// CacheDir is a directory responses are cached in, if set. Cached
+	// responses are revalidated with their ETag or Last-Modified date, and
+	// served from the directory if unchanged.
+	CacheDir string `yaml:"cache_dir" json:"cache_dir"`
+}
+
+// Validate checks that the bandwidth limit isn't negative.
+func (h *UpdaterHTTP) Validate() error {
+	if h.MaxBandwidth < 0 {
+		return errors.New("updaters: max_bandwidth can't be negative")
+	}
+	return nil
 }
 
 func (u *Updaters) FilterSets(m map[string]driver.UpdaterSetFactory) {
@@ -110,16 +165,23 @@ func Validate(conf *Config) error {
 	if conf.HTTPListenAddr == "" {
 		conf.HTTPListenAddr = DefaultAddress
 	}
+	if err := conf.Logging.Validate(); err != nil {
+		return err
+	}
 	switch strings.ToLower(conf.Mode) {
 	case ComboMode:
 		if err := conf.Indexer.Validate(); err != nil {
 			return err
 		}
-		if err := conf.Matcher.Validate(); err != nil {
-			return err
+		if conf.Combo.Matcher() {
+			if err := conf.Matcher.Validate(); err != nil {
+				return err
+			}
 		}
-		if err := conf.Notifier.Validate(); err != nil {
-			return err
+		if conf.Combo.Notifier() {
+			if err := conf.Notifier.Validate(); err != nil {
+				return err
+			}
 		}
 	case IndexerMode:
 		if err := conf.Indexer.Validate(); err != nil {
@@ -136,5 +198,38 @@ func Validate(conf *Config) error {
 	default:
 		return fmt.Errorf("unknown mode received: %v", conf.Mode)
 	}
+	if err := conf.Tenancy.Validate(conf); err != nil {
+		return err
+	}
+	if err := conf.Auth.Workload.Validate(); err != nil {
+		return err
+	}
+	if err := conf.Auth.Introspection.Validate(); err != nil {
+		return err
+	}
+	if err := conf.Auth.RBAC.Validate(conf); err != nil {
+		return err
+	}
+	if err := conf.Auth.Admin.Validate(); err != nil {
+		return err
+	}
+	if err := conf.Archive.Validate(); err != nil {
+		return err
+	}
+	if err := conf.Limits.Validate(); err != nil {
+		return err
+	}
+	if err := conf.Locks.Validate(); err != nil {
+		return err
+	}
+	if err := conf.Integrations.Validate(conf); err != nil {
+		return err
+	}
+	if err := conf.Updaters.HTTP.Validate(); err != nil {
+		return err
+	}
+	if err := conf.Shutdown.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
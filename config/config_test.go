@@ -3,6 +3,7 @@ package config_test
 import (
 	"log"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"gopkg.in/yaml.v3"
@@ -60,6 +61,68 @@ func Test_Config_Validate_Failure(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "IndexerMode, Bad sslmode",
+			conf: config.Config{
+				Mode:           config.IndexerMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					ConnString: "host=db user=clair sslmode=sometimes",
+				},
+			},
+		},
+		{
+			name: "IndexerMode, IAM With Password",
+			conf: config.Config{
+				Mode:           config.IndexerMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					ConnString: "postgres://clair:hunter2@db/clair?sslmode=verify-full",
+					IAM:        &config.DatabaseIAM{Cloud: "aws"},
+				},
+			},
+		},
+		{
+			name: "IndexerMode, Fulcio Roots Without Identities",
+			conf: config.Config{
+				Mode:           config.IndexerMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					ConnString: "host=db user=clair",
+					Signatures: &config.IndexerSignatures{
+						FulcioRoots: []string{"fulcio.pem"},
+						RekorKeys:   []string{"rekor.pem"},
+					},
+				},
+			},
+		},
+		{
+			name: "NotifierMode, IAM Without TLS",
+			conf: config.Config{
+				Mode:           config.NotifierMode,
+				HTTPListenAddr: "localhost:8080",
+				Notifier: config.Notifier{
+					ConnString:  "host=db user=clair sslmode=disable",
+					IndexerAddr: "http://indexer",
+					MatcherAddr: "http://matcher",
+					IAM:         &config.DatabaseIAM{Cloud: "gcp"},
+				},
+			},
+		},
+		{
+			name: "IndexerMode, Tenancy Without Intraservice Key",
+			conf: config.Config{
+				Mode:           config.IndexerMode,
+				HTTPListenAddr: "localhost:8080",
+				Indexer: config.Indexer{
+					ConnString: "host=db user=clair sslmode=disable",
+				},
+				Auth: config.Auth{
+					PSK: &config.AuthPSK{Key: []byte("deadbeefdeadbeef")},
+				},
+				Tenancy: config.Tenancy{Source: "subject"},
+			},
+		},
 	}
 
 	for _, tab := range table {
@@ -90,6 +153,18 @@ iss:
 					Issuer: []string{"iss"},
 				},
 			},
+			{
+				In: `---
+key: >-
+  ZGVhZGJlZWZkZWFkYmVlZg==
+intraservice: >-
+  Y2FmZWJhYmVjYWZlYmFiZQ==
+`,
+				Want: config.AuthPSK{
+					Key:          []byte("deadbeefdeadbeef"),
+					Intraservice: []byte("cafebabecafebabe"),
+				},
+			},
 		}
 
 		check := func(t *testing.T, tc testcase) {
@@ -139,3 +214,58 @@ intraservice: >-
 		}
 	})
 }
+
+func TestLimits(t *testing.T) {
+	const in = `
+max_body_size: 1024
+write_timeout: 1m
+routes:
+  /indexer/api/v1/index_report:
+    max_body_size: 4096
+  /notifier/api/v1/notification/stream:
+    write_timeout: -1s
+`
+	var l config.Limits
+	if err := yaml.Unmarshal([]byte(in), &l); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		Route string
+		Want  config.RouteLimits
+	}{
+		{"/matcher/api/v1/vulnerability_report/", config.RouteLimits{MaxBodySize: 1024, WriteTimeout: time.Minute}},
+		{"/indexer/api/v1/index_report", config.RouteLimits{MaxBodySize: 4096, WriteTimeout: time.Minute}},
+		{"/notifier/api/v1/notification/stream", config.RouteLimits{MaxBodySize: 1024}},
+	}
+	for _, tc := range tt {
+		if got, want := l.For(tc.Route), tc.Want; !cmp.Equal(got, want) {
+			t.Errorf("%s: %s", tc.Route, cmp.Diff(got, want))
+		}
+	}
+}
+
+func TestComboServices(t *testing.T) {
+	conf := config.Config{
+		Mode:    config.ComboMode,
+		Indexer: config.Indexer{ConnString: "host=indexer"},
+	}
+	if err := config.Validate(&conf); err == nil {
+		t.Error("expected error for combo mode without a matcher connstring")
+	}
+	conf.Combo.DisableMatcher = true
+	if err := config.Validate(&conf); err != nil {
+		t.Errorf("matcher disabled: %v", err)
+	}
+	if conf.Combo.Notifier() {
+		t.Error("notifier runs without a matcher")
+	}
+
+	conf.Combo = config.Combo{DisableNotifier: true}
+	conf.Matcher = config.Matcher{ConnString: "host=matcher", IndexerAddr: "http://localhost:6060/"}
+	if err := config.Validate(&conf); err != nil {
+		t.Errorf("notifier disabled: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/quay/clair/v4/dbauth"
+	"github.com/quay/clair/v4/embedded"
+)
+
+// DatabaseIAM configures authenticating to a managed database with a cloud
+// identity instead of a password.
+//
+// Connection strings using it must not hold a password and must set an
+// "sslmode" of "require", "verify-ca", or "verify-full".
+type DatabaseIAM struct {
+	// One of the following strings:
+	// "aws": RDS IAM authentication, for RDS and Aurora
+	// "gcp": Cloud SQL IAM database authentication
+	Cloud string `yaml:"cloud" json:"cloud"`
+	// The region of an RDS database. Defaults to the region in the
+	// database's hostname, or else the AWS_REGION environment variable.
+	Region string `yaml:"region" json:"region"`
+	// A role to assume before generating RDS authentication tokens.
+	RoleARN string `yaml:"role_arn" json:"role_arn"`
+	// The filesystem path where a service account key, in the JSON format
+	// issued by GCP, can be read.
+	//
+	// If empty, credentials are obtained from the metadata server.
+	CredentialsFile string `yaml:"credentials_file" json:"credentials_file"`
+}
+
+// CheckDatabase reports an error if the connection string can't be used,
+// like if its TLS settings are invalid or it would send IAM tokens in the
+// clear. The name is used in errors.
+func checkDatabase(name, connString string, iam *DatabaseIAM) error {
+	if connString == "" || embedded.Is(connString) {
+		if iam != nil && connString != "" {
+			return fmt.Errorf("%s: iam authentication can't be used with an embedded database", name)
+		}
+		return nil
+	}
+	if err := dbauth.Check(connString, iam != nil); err != nil {
+		return fmt.Errorf("%s: bad connection string: %w", name, err)
+	}
+	if iam == nil {
+		return nil
+	}
+	switch iam.Cloud {
+	case dbauth.CloudAWS:
+	case dbauth.CloudGCP:
+		if f := iam.CredentialsFile; f != "" {
+			if _, err := os.Stat(f); err != nil {
+				return fmt.Errorf("%s: bad iam credentials_file: %w", name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%s: unknown iam cloud %q", name, iam.Cloud)
+	}
+	return nil
+}
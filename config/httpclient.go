@@ -6,6 +6,8 @@ import (
 
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/quay/clair/v4/middleware/auth"
 )
 
 // Client returns an http.Client configured according to the supplied
@@ -14,21 +16,26 @@ import (
 // It returns an *http.Client and a boolean indicating whether the client is
 // configured for authentication, or an error that occurred during construction.
 func (cfg *Config) Client(next *http.Transport, cl jwt.Claims) (c *http.Client, authed bool, err error) {
-	if next == nil {
-		next = http.DefaultTransport.(*http.Transport).Clone()
-	}
-	authed = false
-	sk := jose.SigningKey{Algorithm: jose.HS256}
-
+	var key []byte
 	// Keep this organized from "best" to "worst". That way, we can add methods
 	// and keep everything working with some careful cluster rolling.
 	switch {
 	case cfg.Auth.Keyserver != nil:
-		sk.Key = cfg.Auth.Keyserver.Intraservice
+		key = cfg.Auth.Keyserver.Intraservice
 	case cfg.Auth.PSK != nil:
-		sk.Key = cfg.Auth.PSK.Key
+		key = cfg.Auth.PSK.Key
 	default:
 	}
+	return signingClient(next, cl, key)
+}
+
+// SigningClient returns an http.Client signing requests with the key, if
+// it's not nil.
+func signingClient(next *http.Transport, cl jwt.Claims, key []byte) (c *http.Client, authed bool, err error) {
+	if next == nil {
+		next = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	sk := jose.SigningKey{Algorithm: jose.HS256, Key: key}
 	rt := &transport{
 		next: next,
 		base: cl,
@@ -47,13 +54,43 @@ func (cfg *Config) Client(next *http.Transport, cl jwt.Claims) (c *http.Client,
 	return c, authed, nil
 }
 
+// IntraserviceClient returns an http.Client for requests to other Clair
+// services.
+//
+// If workload identities are configured, requests carry this service's
+// workload token. If a PSK intraservice key is configured, requests are
+// signed with it. Otherwise, it's the same as Client. The token shouldn't be
+// sent anywhere else, so this client mustn't be used for other requests.
+func (cfg *Config) IntraserviceClient(next *http.Transport, cl jwt.Claims) (c *http.Client, authed bool, err error) {
+	w := cfg.Auth.Workload
+	if w == nil {
+		if p := cfg.Auth.PSK; cfg.Auth.Keyserver == nil && p != nil && p.Intraservice != nil {
+			return signingClient(next, cl, p.Intraservice)
+		}
+		return cfg.Client(next, cl)
+	}
+	if next == nil {
+		next = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	rt := &transport{
+		next:  next,
+		token: auth.NewTokenFile(w.TokenFile),
+	}
+	// Fail early if the token isn't there at all.
+	if _, err := rt.token.Token(); err != nil {
+		return nil, false, err
+	}
+	return &http.Client{Transport: rt}, true, nil
+}
+
 var _ http.RoundTripper = (*transport)(nil)
 
 // Transport does request modification common to all requests.
 type transport struct {
 	jose.Signer
-	next http.RoundTripper
-	base jwt.Claims
+	next  http.RoundTripper
+	base  jwt.Claims
+	token *auth.TokenFile
 }
 
 func (cs *transport) RoundTrip(r *http.Request) (*http.Response, error) {
@@ -61,7 +98,14 @@ func (cs *transport) RoundTrip(r *http.Request) (*http.Response, error) {
 		userAgent = `clair/v4`
 	)
 	r.Header.Set("user-agent", userAgent)
-	if cs.Signer != nil {
+	switch {
+	case cs.token != nil:
+		t, err := cs.token.Token()
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Add("authorization", "Bearer "+t)
+	case cs.Signer != nil:
 		// TODO(hank) Make this mint longer-lived tokens and re-use them, only
 		// refreshing when needed. Like a resettable sync.Once.
 		now := time.Now()
@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/quay/clair/v4/indexer/exclude"
 )
 
 // Indexer provides Clair Indexer node configuration
@@ -15,6 +18,15 @@ type Indexer struct {
 	// or
 	// string: "user=pqgotest dbname=pqgotest sslmode=verify-full"
 	ConnString string `yaml:"connstring" json:"connstring"`
+	// A Postgres connection string, in the same formats as ConnString.
+	//
+	// If set, index reports are read from this database, which should be a
+	// read replica of the one named by ConnString. Reads that miss or fail
+	// on the replica fall back to ConnString.
+	ReadConnString string `yaml:"read_connstring" json:"read_connstring"`
+	// IAM authenticates to the database, and any read replica, with a cloud
+	// identity instead of a password.
+	IAM *DatabaseIAM `yaml:"iam" json:"iam"`
 	// A positive value representing seconds.
 	//
 	// Concurrent Indexers lock on manifest scans to avoid clobbering.
@@ -26,6 +38,15 @@ type Indexer struct {
 	// Indexers will index a Manifest's layers concurrently.
 	// This value tunes the number of layers an Indexer will scan in parallel.
 	LayerScanConcurrency int `yaml:"layer_scan_concurrency" json:"layer_scan_concurrency"`
+	// Concurrency tunes how much of each kind of indexing work happens at
+	// once.
+	Concurrency IndexerConcurrency `yaml:"concurrency" json:"concurrency"`
+	// ScanLimits bounds how much of each layer is scanned. Layers over the
+	// limits are scanned in part, and reported as truncated.
+	ScanLimits IndexerScanLimits `yaml:"scan_limits" json:"scan_limits"`
+	// Layers decides which layers of submitted manifests are indexed, by
+	// their media types.
+	Layers IndexerLayers `yaml:"layers" json:"layers"`
 	// A "true" or "false" value
 	//
 	// Whether Indexer nodes handle migrations to their database.
@@ -35,6 +56,389 @@ type Indexer struct {
 	// Airgap disables scanners that have signaled they expect to talk to the
 	// Internet.
 	Airgap bool `yaml:"airgap" json:"airgap"`
+	// GC configures garbage collection of stale index reports.
+	GC IndexerGC `yaml:"gc" json:"gc"`
+	// Registries maps registry hostnames (including the port, if any) to
+	// credentials used when fetching layers from them.
+	//
+	// Layers submitted with an Authorization header are fetched as-is.
+	Registries map[string]IndexerRegistry `yaml:"registries" json:"registries"`
+	// Uploads enables the layer upload API, for clients that need to push
+	// layer contents instead of supplying URIs Clair can fetch.
+	Uploads *IndexerUploads `yaml:"uploads" json:"uploads"`
+	// Signatures enables verification of cosign signatures on manifests
+	// before they're indexed.
+	Signatures *IndexerSignatures `yaml:"signatures" json:"signatures"`
+	// Referrers enables discovery of artifacts attached to manifests, such
+	// as SBOMs and provenance attestations, after they're indexed.
+	Referrers *IndexerReferrers `yaml:"referrers" json:"referrers"`
+	// Exclude removes packages from index reports, e.g. ones found in test
+	// fixtures or vendored sample code.
+	Exclude *IndexerExclude `yaml:"exclude" json:"exclude"`
+	// Reindex enables indexing manifests again in the background after their
+	// scanners are upgraded.
+	Reindex *IndexerReindex `yaml:"reindex" json:"reindex"`
+	// Events enables recording when manifests start and finish indexing, for
+	// the notifier to relay.
+	Events *IndexerEvents `yaml:"events" json:"events"`
+	// Hooks runs external scanners, like ClamAV, over layer contents while
+	// manifests are indexed.
+	Hooks *IndexerHooks `yaml:"hooks" json:"hooks"`
+	// BaseImages enables detecting the probable base image of each indexed
+	// manifest, so reports can tell vulnerabilities inherited from it from
+	// ones introduced on top of it.
+	BaseImages *IndexerBaseImages `yaml:"base_images" json:"base_images"`
+	// Cache keeps fetched layers, so indexing a layer again doesn't fetch it
+	// from the registry.
+	Cache *IndexerCache `yaml:"cache" json:"cache"`
+	// Annotations enables the API for attaching key/value annotations to
+	// index reports, which are echoed in vulnerability reports and
+	// notifications.
+	Annotations *IndexerAnnotations `yaml:"annotations" json:"annotations"`
+	// Inventory enables recording when manifests are indexed and listing
+	// the indexed manifests.
+	Inventory bool `yaml:"inventory" json:"inventory"`
+	// EndOfLife enables flagging reports of manifests with distributions
+	// past their end of life, which get no new vulnerability data.
+	EndOfLife *IndexerEndOfLife `yaml:"end_of_life" json:"end_of_life"`
+}
+
+// IndexerConcurrency configures indexing concurrency. Zero values mean no
+// limit, except as noted.
+type IndexerConcurrency struct {
+	// A positive integer
+	//
+	// The number of layers fetched at once, across all manifests.
+	Fetch int `yaml:"fetch" json:"fetch"`
+	// A positive integer
+	//
+	// The number of layers whose contents are walked by content hooks at
+	// once, across all manifests.
+	Walk int `yaml:"walk" json:"walk"`
+	// A positive integer
+	//
+	// The number of package, distribution, and repository scanners run at
+	// once for each manifest. If set, it takes the place of
+	// LayerScanConcurrency, whose default is used otherwise.
+	Scan int `yaml:"scan" json:"scan"`
+	// A positive integer
+	//
+	// The number of manifests indexed at once. Other index requests wait
+	// their turn.
+	Index int `yaml:"index" json:"index"`
+	// A positive integer
+	//
+	// The number of index requests that may wait at once. Requests beyond
+	// it are refused, so clients can retry elsewhere or later.
+	Queue int `yaml:"queue" json:"queue"`
+	// A time.ParseDuration parsable string
+	//
+	// How long an index request may wait before it's refused.
+	QueueTimeout time.Duration `yaml:"queue_timeout" json:"queue_timeout"`
+}
+
+// IndexerScanLimits configures per-layer scan limits. Zero values mean no
+// limit.
+type IndexerScanLimits struct {
+	// A positive integer
+	//
+	// The most files, directories, and other tar entries scanned in a layer.
+	MaxFiles int64 `yaml:"max_files" json:"max_files"`
+	// A positive integer
+	//
+	// The most bytes of uncompressed file contents scanned in a layer.
+	MaxSize int64 `yaml:"max_size" json:"max_size"`
+}
+
+// Enabled reports whether any limit is configured.
+func (l *IndexerScanLimits) Enabled() bool {
+	return l.MaxFiles > 0 || l.MaxSize > 0
+}
+
+// Foreign layer policies.
+const (
+	ForeignFetch = "fetch"
+	ForeignSkip  = "skip"
+)
+
+// IndexerLayers configures which layers of submitted manifests are indexed.
+// Only layers submitted with a media type are affected.
+type IndexerLayers struct {
+	// One of "fetch" or "skip"
+	//
+	// What's done with foreign, or non-distributable, layers, like the base
+	// layers of Windows images: "fetch" indexes them from their URI like any
+	// other layer, "skip" leaves them out. Defaults to "fetch".
+	Foreign string `yaml:"foreign" json:"foreign"`
+	// A "true" or "false" value
+	//
+	// Whether layers with media types the indexer can't read, like those of
+	// Helm charts or signatures, are left out instead of the manifest being
+	// rejected.
+	SkipUnsupported bool `yaml:"skip_unsupported" json:"skip_unsupported"`
+}
+
+// IndexerBaseImages configures base image detection.
+type IndexerBaseImages struct {
+	// A positive integer
+	//
+	// The number of other indexed manifests that must start with the same
+	// layers for them to be taken as a base image, when no indexed manifest
+	// is made of exactly those layers. Defaults to 2.
+	MinShared int `yaml:"min_shared" json:"min_shared"`
+}
+
+// IndexerEndOfLife configures end of life distribution detection.
+type IndexerEndOfLife struct {
+	// Releases added to the builtin list, or overriding the dates of
+	// builtin ones.
+	Releases []IndexerEndOfLifeRelease `yaml:"releases" json:"releases"`
+}
+
+// IndexerEndOfLifeRelease is a distribution release and its end of life.
+type IndexerEndOfLifeRelease struct {
+	// The os-release ID of the distribution, e.g. "debian".
+	ID string `yaml:"id" json:"id"`
+	// The os-release VERSION_ID of the release, e.g. "12". Point releases
+	// of it match as well.
+	VersionID string `yaml:"version_id" json:"version_id"`
+	// A date in the form "2006-01-02"
+	//
+	// When the release stops getting security updates.
+	Date string `yaml:"date" json:"date"`
+}
+
+// Validate checks the release.
+func (r *IndexerEndOfLifeRelease) Validate() error {
+	if r.ID == "" || r.VersionID == "" {
+		return fmt.Errorf("indexer end_of_life releases need an id and version_id")
+	}
+	if _, err := time.Parse("2006-01-02", r.Date); err != nil {
+		return fmt.Errorf("indexer end_of_life release %s %s: bad date: %v", r.ID, r.VersionID, err)
+	}
+	return nil
+}
+
+// IndexerCache configures the layer cache.
+type IndexerCache struct {
+	// One of "filesystem" (the default), "s3", or "swift".
+	Backend string `yaml:"backend" json:"backend"`
+	// A time.ParseDuration parsable string
+	//
+	// How long a layer is kept. Defaults to 24 hours.
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+	// A prefix for object keys, e.g. "clair/layers/".
+	Prefix string `yaml:"prefix" json:"prefix"`
+	// The directory the "filesystem" backend keeps layers in. Defaults to a
+	// directory in the system temporary directory.
+	Dir string `yaml:"dir" json:"dir"`
+	// The "s3" backend, for S3 compatible stores like MinIO. It's configured
+	// as for archival.
+	S3 ArchiveS3 `yaml:"s3" json:"s3"`
+	// The "swift" backend.
+	Swift IndexerCacheSwift `yaml:"swift" json:"swift"`
+}
+
+// IndexerCacheSwift configures the OpenStack Swift layer cache store.
+type IndexerCacheSwift struct {
+	// The identity endpoint. Keystone v3 endpoints end in "/v3"; anything
+	// else is treated as a v1 endpoint, like Swift's TempAuth.
+	AuthURL  string `yaml:"auth_url" json:"auth_url"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password" secret:"true"`
+	// The project and domain Keystone v3 tokens are scoped to. The domain
+	// defaults to "Default".
+	Project string `yaml:"project" json:"project"`
+	Domain  string `yaml:"domain" json:"domain"`
+	// The region of the object-store endpoint in the Keystone v3 catalog.
+	Region    string `yaml:"region" json:"region"`
+	Container string `yaml:"container" json:"container"`
+}
+
+// IndexerHooks configures content hooks.
+type IndexerHooks struct {
+	// A time.ParseDuration parsable string
+	//
+	// How long fetching and scanning a layer may take. Defaults to 5
+	// minutes.
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// The scanners every layer is handed to.
+	Scanners []IndexerHookScanner `yaml:"scanners" json:"scanners"`
+}
+
+// IndexerHookScanner configures a content hook.
+type IndexerHookScanner struct {
+	// A unique name, reported with the scanner's findings.
+	Name string `yaml:"name" json:"name"`
+	// One of "clamav", "command", or "scope". The "scope" kind needs no
+	// other configuration: it reports which Python packages are only
+	// development dependencies.
+	Kind string `yaml:"kind" json:"kind"`
+	// The clamd socket, as a "unix" or "tcp" URL, for the "clamav" kind.
+	Address string `yaml:"address" json:"address"`
+	// The program and arguments to run for the "command" kind. The layer is
+	// written to its standard input and it must write a JSON array of
+	// findings to its standard output.
+	Command []string `yaml:"command" json:"command"`
+}
+
+// IndexerEvents configures recording of index events.
+type IndexerEvents struct {
+	// A time.ParseDuration parsable string
+	//
+	// How long events are kept. Defaults to 24 hours.
+	Retention time.Duration `yaml:"retention" json:"retention"`
+}
+
+// IndexerReindex configures background reindexing of manifests scanned by
+// older versions of the current scanners.
+type IndexerReindex struct {
+	// A time.ParseDuration parsable string
+	//
+	// How often a batch of stale manifests is reindexed. Defaults to 1
+	// minute.
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// A positive integer
+	//
+	// The number of manifests reindexed per batch. Defaults to 10.
+	BatchSize int `yaml:"batch_size" json:"batch_size"`
+}
+
+// IndexerExclude configures packages left out of index reports.
+//
+// Patterns are globs: "*" matches within a path segment, "**" matches across
+// segments, and "?" matches a single character.
+type IndexerExclude struct {
+	// A list of path globs
+	//
+	// Packages found in package databases matching any of these, e.g.
+	// "usr/share/doc/**" or "**/testdata/**", are excluded. Paths are
+	// relative to the root of the image.
+	Paths []string `yaml:"paths" json:"paths"`
+	// A list of package name globs
+	//
+	// Packages with names matching any of these are excluded, wherever
+	// they're found.
+	Packages []string `yaml:"packages" json:"packages"`
+}
+
+// IndexerSignatures configures verification of manifest signatures.
+//
+// At least one of Keys or FulcioRoots must be set.
+type IndexerSignatures struct {
+	// One of "flag" or "enforce"
+	//
+	// In "flag" mode, the outcome is recorded in the index report. In
+	// "enforce" mode, manifests that don't verify are also refused. Defaults
+	// to "flag".
+	Mode string `yaml:"mode" json:"mode"`
+	// A list of PEM-encoded public key files
+	//
+	// Signatures made with any of these keys are trusted.
+	Keys []string `yaml:"keys" json:"keys"`
+	// A list of PEM-encoded certificate files
+	//
+	// Keyless signatures with certificates issued by any of these Fulcio
+	// roots are trusted.
+	FulcioRoots []string `yaml:"fulcio_roots" json:"fulcio_roots"`
+	// A list of PEM-encoded public key files
+	//
+	// The Rekor transparency log keys, needed to check when keyless
+	// signatures were made.
+	RekorKeys []string `yaml:"rekor_keys" json:"rekor_keys"`
+	// Identities restricts which keyless signers are trusted. Required with
+	// FulcioRoots, as Fulcio issues certificates to anyone.
+	Identities []IndexerSignatureIdentity `yaml:"identities" json:"identities"`
+}
+
+// IndexerReferrers configures discovery of manifests' referrers with the
+// OCI referrers API.
+type IndexerReferrers struct {
+	// A "true" or "false" value
+	//
+	// Whether packages listed in attached SBOMs are added to index reports.
+	IngestSBOMs bool `yaml:"ingest_sboms" json:"ingest_sboms"`
+}
+
+// IndexerAnnotations configures annotations on index reports.
+type IndexerAnnotations struct {
+	// The most annotations a manifest may have. The default is 64.
+	MaxKeys int `yaml:"max_keys" json:"max_keys"`
+}
+
+// IndexerSignatureIdentity matches the identity in a keyless signature's
+// certificate. Empty members match anything.
+type IndexerSignatureIdentity struct {
+	// The OIDC issuer that authenticated the signer.
+	Issuer string `yaml:"issuer" json:"issuer"`
+	// The signer's email address or URI.
+	Subject string `yaml:"subject" json:"subject"`
+}
+
+// IndexerUploads configures storage of uploaded layers.
+type IndexerUploads struct {
+	// A directory on local disk
+	//
+	// Where uploaded layers are kept. Defaults to a directory in the system
+	// temporary directory.
+	Dir string `yaml:"dir" json:"dir"`
+	// A positive integer
+	//
+	// The largest layer accepted, in bytes. Defaults to 2 GiB.
+	MaxSize int64 `yaml:"max_size" json:"max_size"`
+	// A time.ParseDuration parsable string
+	//
+	// How long uploaded layers are kept. Manifests referring to them must be
+	// indexed within this time. Defaults to 1 hour.
+	MaxAge time.Duration `yaml:"max_age" json:"max_age"`
+}
+
+// IndexerRegistry configures credentials for a registry.
+//
+// Exactly one of Token, Helper, Cloud, or Username and Password should be
+// set.
+type IndexerRegistry struct {
+	// A username and password, exchanged for a token with the registry's
+	// token service.
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password" secret:"true"`
+	// A bearer token, sent as-is.
+	Token string `yaml:"token" json:"token" secret:"true"`
+	// The name of a docker credential helper, e.g. "ecr-login" or "gcr". The
+	// program "docker-credential-<helper>" must be in the PATH.
+	Helper string `yaml:"helper" json:"helper"`
+	// The cloud whose workload identity is exchanged for registry
+	// credentials: "aws" for ECR, "gcp" for Container Registry and Artifact
+	// Registry, or "azure" for ACR.
+	Cloud string `yaml:"cloud" json:"cloud"`
+	// The client ID of a user-assigned managed identity, if Cloud is
+	// "azure".
+	ClientID string `yaml:"client_id" json:"client_id"`
+}
+
+// IndexerGC configures garbage collection of index reports and the layers
+// only they reference.
+//
+// Collection is enabled if either MaxAge or MaxReports is set.
+type IndexerGC struct {
+	// A time.ParseDuration parsable string
+	//
+	// How often collection runs. Defaults to 6 hours.
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// A time.ParseDuration parsable string
+	//
+	// Manifests not indexed or requested in this long are removed.
+	MaxAge time.Duration `yaml:"max_age" json:"max_age"`
+	// A positive integer
+	//
+	// Only this many of the most recently used manifests of each repository
+	// are kept.
+	MaxReports int `yaml:"max_reports" json:"max_reports"`
+}
+
+// Enabled reports whether any collection policy is configured.
+func (g *IndexerGC) Enabled() bool {
+	return g.MaxAge > 0 || g.MaxReports > 0
 }
 
 func (i *Indexer) Validate() error {
@@ -44,13 +448,150 @@ func (i *Indexer) Validate() error {
 	if i.ConnString == "" {
 		return fmt.Errorf("indexer mode requires a database connection string")
 	}
+	if err := checkDatabase("indexer", i.ConnString, i.IAM); err != nil {
+		return err
+	}
+	if err := checkDatabase("indexer read replica", i.ReadConnString, i.IAM); err != nil {
+		return err
+	}
 	if i.ScanLockRetry == 0 {
 		i.ScanLockRetry = 1
 	}
+	if i.GC.MaxAge < 0 || i.GC.MaxReports < 0 {
+		return fmt.Errorf("indexer gc policy must not be negative")
+	}
+	if c := i.Concurrency; c.Fetch < 0 || c.Walk < 0 || c.Scan < 0 || c.Index < 0 || c.Queue < 0 || c.QueueTimeout < 0 {
+		return fmt.Errorf("indexer concurrency limits must not be negative")
+	}
+	if c := i.Concurrency; c.Index == 0 && (c.Queue != 0 || c.QueueTimeout != 0) {
+		return fmt.Errorf("indexer concurrency queue limits need an index limit")
+	}
+	if l := i.ScanLimits; l.MaxFiles < 0 || l.MaxSize < 0 {
+		return fmt.Errorf("indexer scan limits must not be negative")
+	}
+	switch i.Layers.Foreign {
+	case "", ForeignFetch, ForeignSkip:
+	default:
+		return fmt.Errorf("unknown indexer foreign layer policy %q", i.Layers.Foreign)
+	}
+	if e := i.EndOfLife; e != nil {
+		for j := range e.Releases {
+			if err := e.Releases[j].Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if b := i.BaseImages; b != nil && b.MinShared < 0 {
+		return fmt.Errorf("indexer base image min_shared must not be negative")
+	}
+	if u := i.Uploads; u != nil && (u.MaxSize < 0 || u.MaxAge < 0) {
+		return fmt.Errorf("indexer upload limits must not be negative")
+	}
+	if sig := i.Signatures; sig != nil {
+		switch sig.Mode {
+		case "":
+			sig.Mode = "flag"
+		case "flag", "enforce":
+		default:
+			return fmt.Errorf("unknown indexer signature mode %q", sig.Mode)
+		}
+		if len(sig.Keys) == 0 && len(sig.FulcioRoots) == 0 {
+			return fmt.Errorf("indexer signatures need keys or fulcio_roots")
+		}
+		if len(sig.FulcioRoots) != 0 && len(sig.RekorKeys) == 0 {
+			return fmt.Errorf("indexer signature fulcio_roots need rekor_keys")
+		}
+		if len(sig.FulcioRoots) != 0 && len(sig.Identities) == 0 {
+			return fmt.Errorf("indexer signature fulcio_roots need identities")
+		}
+		for _, id := range sig.Identities {
+			if id.Issuer == "" && id.Subject == "" {
+				return fmt.Errorf("indexer signature identities need an issuer or subject")
+			}
+		}
+	}
+	if c := i.Cache; c != nil {
+		switch c.Backend {
+		case "":
+			c.Backend = "filesystem"
+		case "filesystem":
+		case "s3":
+			if c.S3.Bucket == "" {
+				return fmt.Errorf("s3 layer cache requires a bucket")
+			}
+		case "swift":
+			if c.Swift.AuthURL == "" || c.Swift.Container == "" {
+				return fmt.Errorf("swift layer cache requires an auth_url and container")
+			}
+		default:
+			return fmt.Errorf("unknown layer cache backend %q", c.Backend)
+		}
+		if c.TTL < 0 {
+			return fmt.Errorf("layer cache ttl must not be negative")
+		}
+	}
+	if a := i.Annotations; a != nil && a.MaxKeys < 0 {
+		return fmt.Errorf("indexer annotations max_keys must not be negative")
+	}
+	if r := i.Reindex; r != nil && (r.Interval < 0 || r.BatchSize < 0) {
+		return fmt.Errorf("indexer reindex limits must not be negative")
+	}
+	if ev := i.Events; ev != nil {
+		switch {
+		case ev.Retention < 0:
+			return fmt.Errorf("indexer events retention must not be negative")
+		case ev.Retention == 0:
+			ev.Retention = 24 * time.Hour
+		}
+	}
+	if h := i.Hooks; h != nil {
+		switch {
+		case h.Timeout < 0:
+			return fmt.Errorf("indexer hooks timeout must not be negative")
+		case h.Timeout == 0:
+			h.Timeout = 5 * time.Minute
+		}
+		seen := make(map[string]bool, len(h.Scanners))
+		for _, sc := range h.Scanners {
+			switch {
+			case sc.Name == "":
+				return fmt.Errorf("indexer hook scanners need a name")
+			case seen[sc.Name]:
+				return fmt.Errorf("duplicate indexer hook scanner %q", sc.Name)
+			}
+			seen[sc.Name] = true
+			switch sc.Kind {
+			case "clamav":
+				if sc.Address == "" {
+					return fmt.Errorf("indexer hook scanner %q needs an address", sc.Name)
+				}
+			case "command":
+				if len(sc.Command) == 0 {
+					return fmt.Errorf("indexer hook scanner %q needs a command", sc.Name)
+				}
+			case "scope":
+			default:
+				return fmt.Errorf("unknown kind %q for indexer hook scanner %q", sc.Kind, sc.Name)
+			}
+		}
+	}
+	if ex := i.Exclude; ex != nil {
+		if _, err := exclude.NewFilter(ex.Paths, ex.Packages); err != nil {
+			return fmt.Errorf("indexer: %w", err)
+		}
+	}
 	return nil
 }
 
 type ScannerConfig struct {
+	// Enable lists the only ecosystems whose scanners are run, if not
+	// empty.
+	//
+	// The ecosystems are "dpkg", "alpine", "rhel", "rpm", and "python".
+	// Claircore v0.3.0 has no other language ecosystems, like Go or Java.
+	Enable []string `yaml:"enable" json:"enable"`
+	// Disable lists ecosystems whose scanners aren't run, e.g. "python".
+	Disable []string             `yaml:"disable" json:"disable"`
 	Package map[string]yaml.Node `yaml:"package" json:"package"`
 	Dist    map[string]yaml.Node `yaml:"dist" json:"dist"`
 	Repo    map[string]yaml.Node `yaml:"repo" json:"repo"`
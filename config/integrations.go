@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Integrations configures integrations with other systems.
+type Integrations struct {
+	// Quay configures the Quay integration, which indexes manifests pushed
+	// to a Quay registry and sends the results back to it.
+	Quay *IntegrationsQuay `yaml:"quay" json:"quay"`
+}
+
+// IntegrationsQuay configures the Quay integration.
+//
+// The integration runs on combo and matcher nodes.
+type IntegrationsQuay struct {
+	// The Quay instance's base URL, e.g. "https://quay.example.com".
+	URL string `yaml:"url" json:"url"`
+	// An OAuth access token for a Quay application with permission to read
+	// the repositories and administer their security scans.
+	Token string `yaml:"token" json:"token" secret:"true"`
+	// A list of organizations or users
+	//
+	// Repositories in these namespaces are periodically checked for
+	// manifests Quay hasn't got security information for, which are then
+	// indexed. If empty, there's no backfill.
+	Namespaces []string `yaml:"namespaces" json:"namespaces"`
+	// A time.ParseDuration parsable string
+	//
+	// How often the backfill runs. Defaults to 24 hours.
+	BackfillInterval time.Duration `yaml:"backfill_interval" json:"backfill_interval"`
+	// An address to listen on for Quay's repository push notifications,
+	// e.g. ":6062". If empty, only the backfill runs.
+	HookAddr string `yaml:"hook_addr" json:"hook_addr"`
+	// A shared secret Quay's notifications must present in the "secret"
+	// query parameter of the webhook URL. Required if HookAddr is set.
+	HookSecret string `yaml:"hook_secret" json:"hook_secret" secret:"true"`
+	// A positive integer
+	//
+	// The number of manifests indexed at once. Defaults to 4.
+	Concurrency int `yaml:"concurrency" json:"concurrency"`
+}
+
+// Validate checks the Integrations configuration.
+func (i *Integrations) Validate(c *Config) error {
+	q := i.Quay
+	if q == nil {
+		return nil
+	}
+	switch {
+	case c.Mode == ComboMode && c.Combo.Matcher():
+	case c.Mode == MatcherMode:
+	default:
+		return fmt.Errorf("quay integration requires a matcher, in combo or matcher mode")
+	}
+	if q.URL == "" {
+		return fmt.Errorf("quay integration requires the url field")
+	}
+	if _, err := url.Parse(q.URL); err != nil {
+		return fmt.Errorf("quay integration: failed to parse url: %v", err)
+	}
+	if q.Token == "" {
+		return fmt.Errorf("quay integration requires the token field")
+	}
+	if q.HookAddr != "" && q.HookSecret == "" {
+		return fmt.Errorf("quay integration hook_addr requires hook_secret")
+	}
+	if q.BackfillInterval < 0 || q.Concurrency < 0 {
+		return fmt.Errorf("quay integration intervals and limits must not be negative")
+	}
+	if q.BackfillInterval == 0 {
+		q.BackfillInterval = 24 * time.Hour
+	}
+	if q.Concurrency == 0 {
+		q.Concurrency = 4
+	}
+	return nil
+}
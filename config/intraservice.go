@@ -0,0 +1,36 @@
+package config
+
+import "time"
+
+// IntraServiceClient configures how requests between Clair services are
+// retried: the matcher's requests to the indexer, and the notifier's requests
+// to the indexer and matcher.
+//
+// Zero values select the defaults; negative values disable the feature.
+type IntraServiceClient struct {
+	// The number of times a failed request is retried.
+	//
+	// Defaults to 3.
+	Retries int `yaml:"retries" json:"retries"`
+	// A time.ParseDuration parsable string
+	//
+	// The wait before the first retry, doubled for every retry after.
+	// Defaults to 100ms.
+	Backoff time.Duration `yaml:"backoff" json:"backoff"`
+	// A time.ParseDuration parsable string
+	//
+	// The longest wait between retries. Defaults to 5s.
+	MaxBackoff time.Duration `yaml:"max_backoff" json:"max_backoff"`
+	// A time.ParseDuration parsable string
+	//
+	// The timeout for each attempt. By default, attempts aren't limited.
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// The number of consecutive failures that opens the circuit breaker.
+	//
+	// Defaults to 5.
+	BreakerThreshold int `yaml:"breaker_threshold" json:"breaker_threshold"`
+	// A time.ParseDuration parsable string
+	//
+	// How long the circuit breaker stays open. Defaults to 30s.
+	BreakerCooldown time.Duration `yaml:"breaker_cooldown" json:"breaker_cooldown"`
+}
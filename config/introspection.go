@@ -13,7 +13,7 @@ type Jaeger struct {
 	Collector struct {
 		Endpoint string  `yaml:"endpoint" json:"endpoint"`
 		Username *string `yaml:"username" json:"username"`
-		Password *string `yaml:"password" json:"password"`
+		Password *string `yaml:"password" json:"password" secret:"true"`
 	} `yaml:"collector" json:"collector"`
 	ServiceName string            `yaml:"service_name" json:"service_name"`
 	Tags        map[string]string `yaml:"tags" json:"tags"`
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Limits configures limits on requests to the HTTP API, to protect it from
+// clients sending enormous or stalled request bodies.
+//
+// Zero values don't limit anything.
+type Limits struct {
+	RouteLimits `yaml:",inline"`
+	// Routes holds limits for specific routes, keyed by path, e.g.
+	// "/indexer/api/v1/index_report". Limits not set for a route are taken
+	// from the top level, and negative ones remove the top-level limit.
+	Routes map[string]RouteLimits `yaml:"routes" json:"routes"`
+}
+
+// RouteLimits are the limits for requests to a route.
+type RouteLimits struct {
+	// The largest request body, in bytes, that will be accepted. Larger bodies
+	// are answered with a 413.
+	MaxBodySize int64 `yaml:"max_body_size" json:"max_body_size"`
+	// A time.ParseDuration parsable string
+	//
+	// How long reading the request body may take, from when the request
+	// arrives. Requests not read in time are answered with a 408.
+	ReadTimeout time.Duration `yaml:"read_timeout" json:"read_timeout"`
+	// A time.ParseDuration parsable string
+	//
+	// How long handling the request may take, from when it arrives. The
+	// request's work is canceled when it expires.
+	WriteTimeout time.Duration `yaml:"write_timeout" json:"write_timeout"`
+}
+
+// For returns the limits for the route, with any unset ones taken from the
+// top level.
+func (l *Limits) For(route string) RouteLimits {
+	out := l.RouteLimits
+	r, ok := l.Routes[route]
+	if !ok {
+		return out
+	}
+	// Negative route limits remove the top-level ones.
+	switch {
+	case r.MaxBodySize < 0:
+		out.MaxBodySize = 0
+	case r.MaxBodySize > 0:
+		out.MaxBodySize = r.MaxBodySize
+	}
+	switch {
+	case r.ReadTimeout < 0:
+		out.ReadTimeout = 0
+	case r.ReadTimeout > 0:
+		out.ReadTimeout = r.ReadTimeout
+	}
+	switch {
+	case r.WriteTimeout < 0:
+		out.WriteTimeout = 0
+	case r.WriteTimeout > 0:
+		out.WriteTimeout = r.WriteTimeout
+	}
+	return out
+}
+
+// Validate checks that the top-level limits aren't negative and that routes
+// are paths.
+func (l *Limits) Validate() error {
+	r := &l.RouteLimits
+	if r.MaxBodySize < 0 || r.ReadTimeout < 0 || r.WriteTimeout < 0 {
+		return errors.New("limits: top-level limits can't be negative")
+	}
+	for route := range l.Routes {
+		if !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("limits: route %q isn't a path", route)
+		}
+	}
+	return nil
+}
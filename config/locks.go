@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Locks configures the backend for deployment-wide locks, which are used for
+// the matcher's updater leader election.
+//
+// The indexer's per-manifest scan locks aren't configurable: claircore
+// v0.3.0's libindex always takes Postgres advisory locks.
+type Locks struct {
+	// One of the following strings:
+	// "" or "postgres": Postgres advisory locks are used
+	// "redis": locks are kept in Redis
+	Backend string `yaml:"backend" json:"backend"`
+	// RedisURL locates the Redis server for the "redis" backend, e.g.
+	// "redis://:password@localhost:6379/0".
+	RedisURL string `yaml:"redis_url" json:"redis_url"`
+	// TTL is how long a lock is kept if the process holding it goes away
+	// without releasing it. Held locks are refreshed well before this.
+	//
+	// The default is 30 seconds.
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+}
+
+// Validate checks the Locks configuration and fills in defaults.
+func (l *Locks) Validate() error {
+	const DefaultTTL = 30 * time.Second
+	switch l.Backend {
+	case "", "postgres":
+		return nil
+	case "redis":
+	default:
+		return fmt.Errorf("unknown lock backend %q", l.Backend)
+	}
+	if l.RedisURL == "" {
+		return fmt.Errorf("redis lock backend requires a redis_url")
+	}
+	switch {
+	case l.TTL == 0:
+		l.TTL = DefaultTTL
+	case l.TTL < time.Second:
+		return fmt.Errorf("lock ttl must be at least one second")
+	}
+	return nil
+}
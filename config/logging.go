@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Logging configures log output beyond the level.
+type Logging struct {
+	// One of "json" (the default) or "console".
+	//
+	// "console" is human-readable, colorized output. A log_level of
+	// "debug-color" implies "console".
+	Format string `yaml:"format" json:"format"`
+	// Sampling, if set, limits the rate of debug and info messages. Warnings
+	// and errors are never sampled.
+	Sampling *LogSampling `yaml:"sampling" json:"sampling"`
+	// Components maps component prefixes to log levels, overriding the
+	// log_level for matching loggers. For example, "notifier/" applies to
+	// every logger in the notifier.
+	Components map[string]string `yaml:"components" json:"components"`
+}
+
+// LogSampling allows the first Burst messages in every Period, and then every
+// Every-th message after that.
+type LogSampling struct {
+	Burst uint32 `yaml:"burst" json:"burst"`
+	// A time.ParseDuration parsable string
+	//
+	// Defaults to 1 second.
+	Period time.Duration `yaml:"period" json:"period"`
+	// If 0, messages past the burst are dropped.
+	Every uint32 `yaml:"every" json:"every"`
+}
+
+// Validate confirms the Logging configuration is well-formed.
+func (l *Logging) Validate() error {
+	switch strings.ToLower(l.Format) {
+	case "", "json", "console":
+	default:
+		return fmt.Errorf("logging: unknown format %q", l.Format)
+	}
+	if s := l.Sampling; s != nil {
+		if s.Period < 0 {
+			return fmt.Errorf("logging: sampling period must be positive")
+		}
+		if s.Burst == 0 && s.Every == 0 {
+			return fmt.Errorf("logging: sampling needs a burst or every value")
+		}
+	}
+	return nil
+}
@@ -14,6 +14,15 @@ type Matcher struct {
 	// or
 	// string: "user=pqgotest dbname=pqgotest sslmode=verify-full"
 	ConnString string `yaml:"connstring" json:"connstring"`
+	// A Postgres connection string, in the same formats as ConnString.
+	//
+	// If set, vulnerability reports are generated using this database, which
+	// should be a read replica of the one named by ConnString. Reports that
+	// fail on the replica fall back to ConnString.
+	ReadConnString string `yaml:"read_connstring" json:"read_connstring"`
+	// IAM authenticates to the database, and any read replica, with a cloud
+	// identity instead of a password.
+	IAM *DatabaseIAM `yaml:"iam" json:"iam"`
 	// A positive integer
 	//
 	// Clair allows for a custom connection pool size.
@@ -38,6 +47,11 @@ type Matcher struct {
 	// This should be toggled on if vulnerabilities are being provided by
 	// another mechanism.
 	DisableUpdaters bool `yaml:"disable_updaters" json:"disable_updaters"`
+	// LeaderElection makes matchers sharing a database elect a single
+	// process to run updaters, instead of every process running them.
+	//
+	// Has no effect if DisableUpdaters is set.
+	LeaderElection bool `yaml:"leader_election" json:"leader_election"`
 	// UpdateRetention controls the number of updates to retain between
 	// garbage collection periods.
 	//
@@ -46,6 +60,206 @@ type Matcher struct {
 	//
 	// A value of 0 disables GC.
 	UpdateRetention int `yaml:"update_retention" json:"update_retention"`
+	// Policies is a list of paths to Rego policies and data files.
+	// Directories are loaded recursively.
+	//
+	// If provided, the policy evaluation endpoint is enabled.
+	Policies []string `yaml:"policies" json:"policies"`
+	// VEX configures the use of VEX documents to suppress vulnerabilities
+	// that vendors have declared don't apply.
+	//
+	// If provided, the VEX document endpoints are enabled.
+	VEX *MatcherVEX `yaml:"vex" json:"vex"`
+	// ReportSigning configures signing of vulnerability reports.
+	//
+	// If provided, reports can be requested as signed JWS documents.
+	ReportSigning *MatcherReportSigning `yaml:"report_signing" json:"report_signing"`
+	// Cache configures caching of vulnerability reports.
+	//
+	// If nil, reports are generated for every request.
+	Cache *MatcherCache `yaml:"cache" json:"cache"`
+	// GraphQL configures the GraphQL endpoint for exploring reports.
+	//
+	// If provided, the endpoint is enabled.
+	GraphQL *MatcherGraphQL `yaml:"graphql" json:"graphql"`
+	// Severity configures overrides of vulnerabilities' normalized
+	// severities, applied before reports and notifications are produced.
+	//
+	// If nil, severities are reported as the updaters normalized them.
+	Severity *MatcherSeverity `yaml:"severity" json:"severity"`
+	// History configures recording every version of each manifest's
+	// vulnerability report.
+	//
+	// If provided, the report history endpoint is enabled.
+	History *MatcherHistory `yaml:"history" json:"history"`
+	// Plugins are external HTTP services consulted as additional matchers,
+	// for vulnerability sources claircore doesn't have.
+	Plugins []MatcherPlugin `yaml:"plugins" json:"plugins"`
+}
+
+// MatcherPlugin configures a remote matcher.
+type MatcherPlugin struct {
+	// A unique name, used as the updater of the plugin's vulnerabilities if
+	// they don't name one.
+	Name string `yaml:"name" json:"name"`
+	// The URL index records are POSTed to.
+	URL string `yaml:"url" json:"url"`
+	// A bearer token sent with each request, if set.
+	Token string `yaml:"token" json:"token" secret:"true"`
+	// A positive integer
+	//
+	// The most index records sent in one request. Defaults to 500.
+	BatchSize int `yaml:"batch_size" json:"batch_size"`
+	// A time.ParseDuration parsable string
+	//
+	// How long a request may take. Defaults to 30 seconds; all of a report's
+	// requests to a plugin must finish within a minute regardless.
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// MatcherHistory configures vulnerability report history.
+type MatcherHistory struct {
+	// Retain is the number of versions kept for each manifest.
+	//
+	// The default is 100.
+	Retain int `yaml:"retain" json:"retain"`
+}
+
+// MatcherSeverity configures severity normalization.
+//
+// Severities are named as in reports: "Unknown", "Negligible", "Low",
+// "Medium", "High", or "Critical".
+type MatcherSeverity struct {
+	// Mapping maps vendor severities, compared case-insensitively, to
+	// normalized severities, e.g. "Important" to "High".
+	Mapping map[string]string `yaml:"mapping" json:"mapping"`
+	// Namespaces are rules for the vulnerabilities of some updaters. The
+	// first rule whose namespace matches applies.
+	Namespaces []MatcherSeverityNamespace `yaml:"namespaces" json:"namespaces"`
+}
+
+// MatcherSeverityNamespace configures severity normalization for the
+// vulnerabilities of some updaters.
+type MatcherSeverityNamespace struct {
+	// Namespace is matched against the prefix of the name of the updater a
+	// vulnerability came from, e.g. "RHEL8" or "alpine".
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// Mapping is consulted before the top-level mapping.
+	Mapping map[string]string `yaml:"mapping" json:"mapping"`
+	// Floor is the lowest severity reported.
+	Floor string `yaml:"floor" json:"floor"`
+	// Ceiling is the highest severity reported.
+	Ceiling string `yaml:"ceiling" json:"ceiling"`
+}
+
+// Severities lists the severity names, in ascending order.
+var severities = []string{"Unknown", "Negligible", "Low", "Medium", "High", "Critical"}
+
+// SeverityRank returns the position of the named severity, or -1 if it's not
+// a known severity.
+func severityRank(name string) int {
+	for i, s := range severities {
+		if s == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MatcherSeverity) validate() error {
+	check := func(m map[string]string) error {
+		for k, v := range m {
+			if severityRank(v) < 0 {
+				return fmt.Errorf("severity mapping for %q: unknown severity %q", k, v)
+			}
+		}
+		return nil
+	}
+	if err := check(s.Mapping); err != nil {
+		return err
+	}
+	for _, ns := range s.Namespaces {
+		if err := check(ns.Mapping); err != nil {
+			return fmt.Errorf("namespace %q: %w", ns.Namespace, err)
+		}
+		lo, hi := 0, len(severities)-1
+		if ns.Floor != "" {
+			if lo = severityRank(ns.Floor); lo < 0 {
+				return fmt.Errorf("namespace %q: unknown floor severity %q", ns.Namespace, ns.Floor)
+			}
+		}
+		if ns.Ceiling != "" {
+			if hi = severityRank(ns.Ceiling); hi < 0 {
+				return fmt.Errorf("namespace %q: unknown ceiling severity %q", ns.Namespace, ns.Ceiling)
+			}
+		}
+		if lo > hi {
+			return fmt.Errorf("namespace %q: floor %q is above ceiling %q", ns.Namespace, ns.Floor, ns.Ceiling)
+		}
+	}
+	return nil
+}
+
+// MatcherGraphQL configures the limits of the GraphQL endpoint.
+type MatcherGraphQL struct {
+	// MaxDepth is the deepest fields in a query may nest.
+	//
+	// The default is 10.
+	MaxDepth int `yaml:"max_depth" json:"max_depth"`
+	// MaxFields is the most fields a query may select, counting a
+	// fragment's fields every time it's spread.
+	//
+	// The default is 500.
+	MaxFields int `yaml:"max_fields" json:"max_fields"`
+	// MaxPageSize is the most items returned from a paginated field, and the
+	// number returned if the query doesn't ask for fewer.
+	//
+	// The default is 100.
+	MaxPageSize int `yaml:"max_page_size" json:"max_page_size"`
+}
+
+// MatcherReportSigning configures the key vulnerability reports are signed
+// with.
+type MatcherReportSigning struct {
+	// Key is the path to a PEM encoded RSA, ECDSA, or Ed25519 private key.
+	Key string `yaml:"key" json:"key"`
+	// KeyID is the "kid" of signed reports. If empty, the key's RFC 7638
+	// thumbprint is used.
+	KeyID string `yaml:"key_id" json:"key_id"`
+}
+
+// MatcherVEX configures VEX processing.
+type MatcherVEX struct {
+	// Documents is a list of paths to OpenVEX or CSAF documents loaded at
+	// startup, in addition to any uploaded via the API.
+	Documents []string `yaml:"documents" json:"documents"`
+	// One of "filter" (the default), which removes vulnerabilities marked
+	// not affected from reports, or "annotate", which leaves them in place
+	// and lists the VEX statements alongside the report.
+	Mode string `yaml:"mode" json:"mode"`
+}
+
+// MatcherCache configures the vulnerability report cache.
+type MatcherCache struct {
+	// One of "memory" (the default), which caches reports in each matcher
+	// process, or "redis", which shares them through Redis.
+	Backend string `yaml:"backend" json:"backend"`
+	// Size is the number of reports kept by the "memory" backend.
+	//
+	// The default is 1024.
+	Size int `yaml:"size" json:"size"`
+	// TTL is the longest a report is kept.
+	//
+	// The default is 1 hour.
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+	// RedisURL locates the Redis server for the "redis" backend, e.g.
+	// "redis://:password@localhost:6379/0".
+	RedisURL string `yaml:"redis_url" json:"redis_url"`
+	// Precompute is the number of most recently requested manifests whose
+	// reports are regenerated after the vulnerability database is updated.
+	//
+	// If zero, reports are only generated when requested.
+	Precompute int `yaml:"precompute" json:"precompute"`
 }
 
 func (m *Matcher) Validate() error {
@@ -56,6 +270,12 @@ func (m *Matcher) Validate() error {
 	if m.ConnString == "" {
 		return fmt.Errorf("matcher requies a database connection string")
 	}
+	if err := checkDatabase("matcher", m.ConnString, m.IAM); err != nil {
+		return err
+	}
+	if err := checkDatabase("matcher read replica", m.ReadConnString, m.IAM); err != nil {
+		return err
+	}
 	if m.IndexerAddr == "" {
 		return fmt.Errorf("matcher mode requires a remote Indexer address")
 	}
@@ -70,5 +290,87 @@ func (m *Matcher) Validate() error {
 	if m.UpdateRetention == 1 || m.UpdateRetention < 0 {
 		m.UpdateRetention = DefaultRetention
 	}
+	if m.VEX != nil {
+		switch m.VEX.Mode {
+		case "":
+			m.VEX.Mode = "filter"
+		case "filter", "annotate":
+		default:
+			return fmt.Errorf("unknown vex mode %q", m.VEX.Mode)
+		}
+	}
+	if h := m.History; h != nil {
+		switch {
+		case h.Retain < 0:
+			return fmt.Errorf("history retain must not be negative")
+		case h.Retain == 0:
+			h.Retain = 100
+		}
+	}
+	seen := make(map[string]bool, len(m.Plugins))
+	for _, p := range m.Plugins {
+		switch {
+		case p.Name == "":
+			return fmt.Errorf("matcher plugins need a name")
+		case seen[p.Name]:
+			return fmt.Errorf("duplicate matcher plugin %q", p.Name)
+		case p.URL == "":
+			return fmt.Errorf("matcher plugin %q needs a url", p.Name)
+		case p.BatchSize < 0 || p.Timeout < 0:
+			return fmt.Errorf("matcher plugin %q limits must not be negative", p.Name)
+		}
+		if _, err := url.Parse(p.URL); err != nil {
+			return fmt.Errorf("matcher plugin %q: %w", p.Name, err)
+		}
+		seen[p.Name] = true
+	}
+	if m.ReportSigning != nil && m.ReportSigning.Key == "" {
+		return fmt.Errorf("report signing requires a key")
+	}
+	if g := m.GraphQL; g != nil {
+		if g.MaxDepth < 0 || g.MaxFields < 0 || g.MaxPageSize < 0 {
+			return fmt.Errorf("graphql limits must not be negative")
+		}
+		if g.MaxDepth == 0 {
+			g.MaxDepth = 10
+		}
+		if g.MaxFields == 0 {
+			g.MaxFields = 500
+		}
+		if g.MaxPageSize == 0 {
+			g.MaxPageSize = 100
+		}
+	}
+	if m.Severity != nil {
+		if err := m.Severity.validate(); err != nil {
+			return err
+		}
+	}
+	if c := m.Cache; c != nil {
+		const (
+			DefaultCacheSize = 1024
+			DefaultCacheTTL  = time.Hour
+		)
+		switch c.Backend {
+		case "":
+			c.Backend = "memory"
+		case "memory":
+		case "redis":
+			if c.RedisURL == "" {
+				return fmt.Errorf("redis report cache requires a redis_url")
+			}
+		default:
+			return fmt.Errorf("unknown report cache backend %q", c.Backend)
+		}
+		if c.Size <= 0 {
+			c.Size = DefaultCacheSize
+		}
+		if c.TTL <= 0 {
+			c.TTL = DefaultCacheTTL
+		}
+		if c.Precompute < 0 {
+			return fmt.Errorf("report cache precompute must not be negative")
+		}
+	}
 	return nil
 }
@@ -5,6 +5,15 @@ import (
 	"time"
 
 	"github.com/quay/clair/v4/notifier/amqp"
+	"github.com/quay/clair/v4/notifier/aws"
+	"github.com/quay/clair/v4/notifier/defectdojo"
+	"github.com/quay/clair/v4/notifier/jira"
+	"github.com/quay/clair/v4/notifier/nats"
+	"github.com/quay/clair/v4/notifier/opsgenie"
+	"github.com/quay/clair/v4/notifier/pagerduty"
+	"github.com/quay/clair/v4/notifier/pubsub"
+	"github.com/quay/clair/v4/notifier/redis"
+	"github.com/quay/clair/v4/notifier/servicebus"
 	"github.com/quay/clair/v4/notifier/stomp"
 	"github.com/quay/clair/v4/notifier/webhook"
 )
@@ -18,6 +27,9 @@ type Notifier struct {
 	// or
 	// string: "user=pqgotest dbname=pqgotest sslmode=verify-full"
 	ConnString string `yaml:"connstring" json:"connstring"`
+	// IAM authenticates to the database with a cloud identity instead of a
+	// password.
+	IAM *DatabaseIAM `yaml:"iam" json:"iam"`
 	// A "true" or "false" value
 	//
 	// Whether Notifier nodes handle migrations to their database.
@@ -45,6 +57,21 @@ type Notifier struct {
 	// If a value smaller then 1 second is provided it will be replaced with the
 	// default 5 second delivery interval.
 	DeliveryInterval time.Duration `yaml:"delivery_interval" json:"delivery_interval"`
+	// DeliveryBatchSize is the number of notification ids a notifier claims
+	// for delivery at a time. Claimed notification ids aren't delivered by
+	// other notifiers sharing the database, so smaller batches spread
+	// deliveries more evenly between replicas.
+	//
+	// The default is 10.
+	DeliveryBatchSize int `yaml:"delivery_batch_size" json:"delivery_batch_size"`
+	// A time.ParseDuration parsable string
+	//
+	// DeliveryLease is how long claimed notification ids are reserved. If a
+	// notifier goes away while delivering, other replicas deliver its
+	// notification ids once the lease is up.
+	//
+	// The default is 5 minutes.
+	DeliveryLease time.Duration `yaml:"delivery_lease" json:"delivery_lease"`
 	// DisableSummary disables summarizing vulnerabilities per-manifest.
 	//
 	// The default is to summarize any new vulnerabilities to the most severe
@@ -55,6 +82,42 @@ type Notifier struct {
 	// For a machine-consumption use case, it may be easier to instead have the
 	// notifier push all the data.
 	DisableSummary bool `yaml:"disable_summary" json:"disable_summary"`
+	// LayerAttribution records, in each notification, the layers of the
+	// manifest that introduced the affected package.
+	//
+	// This lets receivers tell findings in a base image from ones in the
+	// layers built on top of it, at the cost of retrieving the index report
+	// of every affected manifest. If the indexer detects base images, each
+	// notification also records which of the two it is.
+	LayerAttribution bool `yaml:"layer_attribution" json:"layer_attribution"`
+	// Annotations includes, in each notification, the annotations clients
+	// attached to the manifest, if the indexer records them.
+	//
+	// This costs a lookup for every affected manifest.
+	Annotations bool `yaml:"annotations" json:"annotations"`
+	// DeliveryConcurrency is the number of notification ids delivered at
+	// once.
+	//
+	// The default is 4.
+	DeliveryConcurrency int `yaml:"delivery_concurrency" json:"delivery_concurrency"`
+	// DeliveryRateLimit, if set, is the most deliveries per second made to
+	// each target, such as a webhook URL. Deliveries over the limit wait
+	// their turn instead of being attempted and failing.
+	DeliveryRateLimit float64 `yaml:"delivery_rate_limit" json:"delivery_rate_limit"`
+	// DeliveryBurst is the number of deliveries to a target that may be made
+	// at once before DeliveryRateLimit applies.
+	//
+	// The default is 1.
+	DeliveryBurst int `yaml:"delivery_burst" json:"delivery_burst"`
+	// A time.ParseDuration parsable string
+	//
+	// If set, notifications created within this window of each other, such
+	// as by a burst of updater runs, are merged into one notification id
+	// before delivery. A vulnerability affecting a manifest is only reported
+	// once, with its latest change.
+	CoalesceWindow time.Duration `yaml:"coalesce_window" json:"coalesce_window"`
+	// Retention configures pruning of old notifications.
+	Retention NotifierRetention `yaml:"retention" json:"retention"`
 	// Only one of the following should be provided in the configuration
 	//
 	// Configures the notifier for webhook delivery
@@ -63,6 +126,38 @@ type Notifier struct {
 	AMQP *amqp.Config `yaml:"amqp" json:"amqp"`
 	// Configures the notifier for STOMP delivery.
 	STOMP *stomp.Config `yaml:"stomp" json:"stomp"`
+	// Configures the notifier for Google Pub/Sub delivery.
+	PubSub *pubsub.Config `yaml:"pubsub" json:"pubsub"`
+	// Configures the notifier for Azure Service Bus delivery.
+	AzureServiceBus *servicebus.Config `yaml:"azure_servicebus" json:"azure_servicebus"`
+	// Configures the notifier for AWS SNS or SQS delivery.
+	AWS *aws.Config `yaml:"aws" json:"aws"`
+	// Configures the notifier for NATS delivery.
+	NATS *nats.Config `yaml:"nats" json:"nats"`
+	// Configures the notifier to open PagerDuty incidents.
+	PagerDuty *pagerduty.Config `yaml:"pagerduty" json:"pagerduty"`
+	// Configures the notifier to create OpsGenie alerts.
+	OpsGenie *opsgenie.Config `yaml:"opsgenie" json:"opsgenie"`
+	// Configures the notifier to open Jira issues.
+	Jira *jira.Config `yaml:"jira" json:"jira"`
+	// Configures the notifier to import findings into DefectDojo.
+	DefectDojo *defectdojo.Config `yaml:"defectdojo" json:"defectdojo"`
+	// Configures the notifier for Redis Streams delivery.
+	Redis *redis.Config `yaml:"redis" json:"redis"`
+}
+
+// NotifierRetention configures how long notifications are kept.
+//
+// Pruning is enabled if MaxAge is set.
+type NotifierRetention struct {
+	// A time.ParseDuration parsable string
+	//
+	// How often pruning runs. Defaults to 1 hour.
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// A time.ParseDuration parsable string
+	//
+	// Notifications created longer ago than this are removed.
+	MaxAge time.Duration `yaml:"max_age" json:"max_age"`
 }
 
 func (n *Notifier) Validate() error {
@@ -73,6 +168,9 @@ func (n *Notifier) Validate() error {
 	if n.ConnString == "" {
 		return fmt.Errorf("notifier mode requires a database connection string")
 	}
+	if err := checkDatabase("notifier", n.ConnString, n.IAM); err != nil {
+		return err
+	}
 	if n.IndexerAddr == "" {
 		return fmt.Errorf("notifier mode requires a remote Indexer")
 	}
@@ -85,5 +183,23 @@ func (n *Notifier) Validate() error {
 	if n.DeliveryInterval < 1*time.Second {
 		n.DeliveryInterval = DefaultDeliveryInterval
 	}
+	if n.DeliveryBatchSize < 0 {
+		return fmt.Errorf("notifier delivery batch size must not be negative")
+	}
+	if n.DeliveryLease != 0 && n.DeliveryLease < 10*time.Second {
+		return fmt.Errorf("notifier delivery lease must be at least 10 seconds")
+	}
+	if n.DeliveryConcurrency < 0 {
+		return fmt.Errorf("notifier delivery concurrency must not be negative")
+	}
+	if n.DeliveryRateLimit < 0 || n.DeliveryBurst < 0 {
+		return fmt.Errorf("notifier delivery rate limit must not be negative")
+	}
+	if n.CoalesceWindow < 0 {
+		return fmt.Errorf("notifier coalesce window must not be negative")
+	}
+	if n.Retention.MaxAge < 0 || n.Retention.Interval < 0 {
+		return fmt.Errorf("notifier retention policy must not be negative")
+	}
 	return nil
 }
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces secrets in the output of Redact.
+const Redacted = "REDACTED"
+
+// Secret fields are tagged `secret:"true"`. Their values are replaced
+// wherever they appear, and so are all the values of secret maps, like HTTP
+// headers.
+const secretTag = "secret"
+
+// OpaqueSecret matches the keys holding secrets in parts of the
+// configuration without a Go type to consult, like scanner and updater
+// configuration.
+var opaqueSecret = regexp.MustCompile(`(?i)(key|secret|token|password|passcode)$`)
+
+// DsnPassword matches a password in a key=value connection string or a URL
+// query.
+var dsnPassword = regexp.MustCompile(`(?i)(password=)('[^']*'|[^\s&]+)`)
+
+// Redact returns the configuration as a generic document, shaped as it
+// would be written in YAML, with the values of secret fields replaced by
+// Redacted. Passwords in URLs and connection strings are replaced, leaving
+// the rest of the value.
+func Redact(c *Config) (map[string]interface{}, error) {
+	n, err := encodeNode(c)
+	if err != nil {
+		return nil, err
+	}
+	redactValue(reflect.ValueOf(c), n)
+	var out map[string]interface{}
+	if err := n.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EncodeNode returns the configuration as a YAML node tree.
+func encodeNode(c *Config) (*yaml.Node, error) {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var n yaml.Node
+	if err := yaml.Unmarshal(b, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+var (
+	marshalerType = reflect.TypeOf((*yaml.Marshaler)(nil)).Elem()
+	nodeType      = reflect.TypeOf(yaml.Node{})
+)
+
+// RedactValue redacts the node v was encoded as, following v's type to find
+// secret fields.
+func redactValue(v reflect.Value, n *yaml.Node) {
+	if n.Kind == yaml.DocumentNode {
+		for _, c := range n.Content {
+			redactValue(v, c)
+		}
+		return
+	}
+	for {
+		if !v.IsValid() {
+			redactOpaque(n)
+			return
+		}
+		if v.Kind() != reflect.Ptr && v.CanAddr() && v.Addr().Type().Implements(marshalerType) {
+			v = v.Addr()
+		}
+		if m, ok := v.Interface().(yaml.Marshaler); ok && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+			// Follow what was actually encoded.
+			out, err := m.MarshalYAML()
+			if err != nil {
+				redactOpaque(n)
+				return
+			}
+			v = reflect.ValueOf(out)
+			continue
+		}
+		if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			break
+		}
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if n.Kind == yaml.ScalarNode {
+		n.Value = redactString(n.Value)
+		return
+	}
+	if v.Type() == nodeType {
+		redactOpaque(n)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if n.Kind == yaml.MappingNode {
+			redactStruct(v, n)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		kt := v.Type().Key()
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := reflect.ValueOf(n.Content[i].Value)
+			if !k.Type().ConvertibleTo(kt) {
+				redactOpaque(n.Content[i+1])
+				continue
+			}
+			redactValue(v.MapIndex(k.Convert(kt)), n.Content[i+1])
+		}
+	case reflect.Slice, reflect.Array:
+		if n.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, c := range n.Content {
+			if i < v.Len() {
+				redactValue(v.Index(i), c)
+			}
+		}
+	default:
+		redactOpaque(n)
+	}
+}
+
+// RedactStruct redacts the fields of the struct v was encoded as the mapping
+// n.
+func redactStruct(v reflect.Value, n *yaml.Node) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		inline := false
+		for _, o := range tag[1:] {
+			inline = inline || o == "inline"
+		}
+		if inline {
+			redactValue(v.Field(i), n)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		var c *yaml.Node
+		for j := 0; j+1 < len(n.Content); j += 2 {
+			if n.Content[j].Value == name {
+				c = n.Content[j+1]
+				break
+			}
+		}
+		switch {
+		case c == nil:
+		case f.Tag.Get(secretTag) == "true":
+			redactSecret(c)
+		default:
+			redactValue(v.Field(i), c)
+		}
+	}
+}
+
+// RedactSecret replaces every value in n.
+func redactSecret(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			redactSecret(c)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			redactSecret(n.Content[i])
+		}
+	case yaml.ScalarNode:
+		if n.Value != "" && n.Tag != "!!null" {
+			*n = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: Redacted}
+		}
+	default:
+		*n = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: Redacted}
+	}
+}
+
+// RedactOpaque redacts n without knowing its type, going by the names of
+// keys.
+func redactOpaque(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			redactOpaque(c)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if opaqueSecret.MatchString(n.Content[i].Value) {
+				redactSecret(n.Content[i+1])
+				continue
+			}
+			redactOpaque(n.Content[i+1])
+		}
+	case yaml.ScalarNode:
+		n.Value = redactString(n.Value)
+	}
+}
+
+// RedactString replaces a password in a URL or connection string.
+func redactString(s string) string {
+	if strings.Contains(s, "://") {
+		if u, err := url.Parse(s); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), Redacted)
+				s = u.String()
+			}
+		}
+	}
+	return dsnPassword.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// Changes reports the settings that differ between the configurations, as
+// dotted paths of YAML keys, e.g. "matcher.period".
+func Changes(a, b *Config) ([]string, error) {
+	var da, db map[string]interface{}
+	for _, p := range []struct {
+		c *Config
+		d *map[string]interface{}
+	}{{a, &da}, {b, &db}} {
+		n, err := encodeNode(p.c)
+		if err != nil {
+			return nil, err
+		}
+		if err := n.Decode(p.d); err != nil {
+			return nil, err
+		}
+	}
+	var out []string
+	diffTree(&out, "", da, db)
+	sort.Strings(out)
+	return out, nil
+}
+
+func diffTree(out *[]string, path string, a, b interface{}) {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if !aok || !bok {
+		if !reflect.DeepEqual(a, b) {
+			*out = append(*out, path)
+		}
+		return
+	}
+	seen := make(map[string]struct{}, len(am)+len(bm))
+	for _, m := range []map[string]interface{}{am, bm} {
+		for k := range m {
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			diffTree(out, p, am[k], bm[k])
+		}
+	}
+}
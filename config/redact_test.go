@@ -0,0 +1,185 @@
+package config_test
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/clair/v4/config"
+	"github.com/quay/clair/v4/notifier/opsgenie"
+	"github.com/quay/clair/v4/notifier/webhook"
+)
+
+func TestRedact(t *testing.T) {
+	conf := config.Config{
+		Indexer: config.Indexer{
+			ConnString: "host=db user=clair password=hunter2 sslmode=disable",
+		},
+		Matcher: config.Matcher{
+			ConnString: "postgres://clair:hunter2@db/clair",
+			Cache: &config.MatcherCache{
+				RedisURL: "redis://:hunter2@redis:6379/0",
+			},
+		},
+		Notifier: config.Notifier{
+			Webhook: &webhook.Config{
+				Target:  "https://example.com/hook",
+				Headers: http.Header{"Authorization": {"Bearer hunter2"}},
+			},
+			OpsGenie: &opsgenie.Config{APIKey: "hunter2"},
+		},
+		Auth: config.Auth{
+			PSK:           &config.AuthPSK{Key: []byte("hunter2"), Issuer: []string{"clairctl"}},
+			Introspection: &config.AuthIntrospection{ClientID: "clair", ClientSecret: "hunter2"},
+		},
+		Audit: config.Audit{
+			HTTP: config.AuditHTTP{
+				URL:     "https://example.com/audit",
+				Headers: http.Header{"X-Api-Key": {"hunter2"}},
+			},
+		},
+	}
+	out, err := config.Redact(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(m map[string]interface{}, path ...string) interface{} {
+		var v interface{} = m
+		for _, p := range path {
+			v = v.(map[string]interface{})[p]
+		}
+		return v
+	}
+	for _, tc := range []struct {
+		path []string
+		want string
+	}{
+		{[]string{"indexer", "connstring"}, "host=db user=clair password=REDACTED sslmode=disable"},
+		{[]string{"matcher", "connstring"}, "postgres://clair:REDACTED@db/clair"},
+		{[]string{"matcher", "cache", "redis_url"}, "redis://:REDACTED@redis:6379/0"},
+		{[]string{"auth", "psk", "key"}, config.Redacted},
+		{[]string{"auth", "introspection", "client_id"}, "clair"},
+		{[]string{"auth", "introspection", "client_secret"}, config.Redacted},
+		{[]string{"notifier", "opsgenie", "api_key"}, config.Redacted},
+		{[]string{"notifier", "webhook", "target"}, "https://example.com/hook"},
+		{[]string{"audit", "http", "url"}, "https://example.com/audit"},
+	} {
+		if got := get(out, tc.path...); got != tc.want {
+			t.Errorf("%v: got: %q, want: %q", tc.path, got, tc.want)
+		}
+	}
+	if got, want := get(out, "auth", "psk", "iss"), []interface{}{"clairctl"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	for _, p := range [][]string{
+		{"notifier", "webhook", "headers", "Authorization"},
+		{"audit", "http", "headers", "X-Api-Key"},
+	} {
+		if got, want := get(out, p...), []interface{}{config.Redacted}; !cmp.Equal(got, want) {
+			t.Errorf("%v: %s", p, cmp.Diff(got, want))
+		}
+	}
+}
+
+// TestSecretFields walks every field of the configuration, failing on fields
+// named like they hold a secret that aren't marked as one and aren't listed
+// here as not holding one.
+func TestSecretFields(t *testing.T) {
+	// Fields holding paths to secrets, identifiers, and connection strings,
+	// whose passwords are redacted by looking at the value.
+	notSecret := map[string]bool{
+		"config.DatabaseIAM.CredentialsFile": true,
+		"config.IndexerSignatures.Keys":      true,
+		"config.IndexerSignatures.RekorKeys": true,
+		"config.ArchiveS3.AccessKeyID":       true,
+		"config.MatcherReportSigning.Key":    true,
+		"config.MatcherReportSigning.KeyID":  true,
+		"config.AuthWorkload.TokenFile":      true,
+		"config.AuthWorkload.JWKSTokenFile":  true,
+		"config.AuthMTLS.Key":                true,
+		"config.ArchiveGCS.CredentialsFile":  true,
+		"amqp.Config.RoutingKey":             true,
+		"amqp.TLS.Key":                       true,
+		"stomp.TLS.Key":                      true,
+		"nats.TLS.Key":                       true,
+		"nats.Config.CredentialsFile":        true,
+		"pubsub.Config.CredentialsFile":      true,
+	}
+	name := regexp.MustCompile(`(?i)key|secret|token|pass`)
+	seen := make(map[reflect.Type]bool)
+	var walk func(reflect.Type, string)
+	walk = func(typ reflect.Type, path string) {
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct || seen[typ] {
+			return
+		}
+		if typ.Name() != "" && !strings.HasPrefix(typ.PkgPath(), "github.com/quay/clair/") {
+			return
+		}
+		seen[typ] = true
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			tag := strings.Split(f.Tag.Get("yaml"), ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if tag == "" {
+				tag = strings.ToLower(f.Name)
+			}
+			p := path + "." + tag
+			id := typ.String() + "." + f.Name
+			if typ.Name() == "" {
+				id = p
+			}
+			secret := f.Tag.Get("secret") == "true"
+			switch {
+			case !holdsString(f.Type):
+			case secret && notSecret[id]:
+				t.Errorf("%s (%s): both secret and listed as not", p, id)
+			case !secret && !notSecret[id] && name.MatchString(tag):
+				t.Errorf("%s (%s): unclassified, tag it secret or list it here", p, id)
+			}
+			walk(f.Type, p)
+		}
+	}
+	walk(reflect.TypeOf(config.Config{}), "")
+}
+
+// HoldsString reports whether values of the type are or contain strings.
+func holdsString(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map || t.Kind() == reflect.Array {
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return true
+		}
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String
+}
+
+func TestChanges(t *testing.T) {
+	a := config.Config{
+		LogLevel: "info",
+		Matcher:  config.Matcher{Period: 30 * time.Minute},
+	}
+	b := a
+	b.Matcher.Period = time.Hour
+	b.Matcher.Policies = []string{"/etc/clair/policy"}
+	got, err := config.Changes(&a, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"matcher.period", "matcher.policies"}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Shutdown configures how Clair stops when asked to.
+type Shutdown struct {
+	// A time.ParseDuration parsable string
+	//
+	// DrainTimeout is how long in-flight requests and background jobs, like
+	// index requests and updater runs, are given to finish once Clair
+	// stops accepting new work. Whatever is still running afterwards is
+	// canceled.
+	//
+	// The default is 10 seconds.
+	DrainTimeout time.Duration `yaml:"drain_timeout" json:"drain_timeout"`
+}
+
+// Validate checks the Shutdown configuration and fills in defaults.
+func (s *Shutdown) Validate() error {
+	const DefaultDrainTimeout = 10 * time.Second
+	switch {
+	case s.DrainTimeout == 0:
+		s.DrainTimeout = DefaultDrainTimeout
+	case s.DrainTimeout < 0:
+		return fmt.Errorf("shutdown drain_timeout must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tenancy configures scoping of manifests, index and vulnerability reports,
+// and notifications to the tenant that submitted them.
+//
+// Tenancy is disabled if Source is empty.
+type Tenancy struct {
+	// One of the following strings:
+	// "": tenancy is disabled
+	// "subject": the tenant is the "sub" claim of the request's JWT
+	// "header": the tenant is the value of the "Clair-Tenant" header
+	Source string `yaml:"source" json:"source"`
+	// A Postgres connection string.
+	//
+	// The database tenant records are kept in. Defaults to the indexer's
+	// database; notifier nodes must be pointed at the same database.
+	ConnString string `yaml:"connstring" json:"connstring"`
+	// A "true" or "false" value
+	//
+	// Whether to run the tenancy migrations.
+	Migrations bool `yaml:"migrations" json:"migrations"`
+}
+
+// Enabled reports whether tenancy is configured.
+func (t *Tenancy) Enabled() bool {
+	return t.Source != ""
+}
+
+// Validate checks the Tenancy configuration against the rest of the Config,
+// filling in the connection string if possible.
+func (t *Tenancy) Validate(c *Config) error {
+	switch t.Source {
+	case "":
+		return nil
+	case "subject":
+		if !c.Auth.Any() {
+			return fmt.Errorf("tenancy source %q requires authentication to be configured", t.Source)
+		}
+	case "header":
+	default:
+		return fmt.Errorf("unknown tenancy source %q", t.Source)
+	}
+	// Requests from other services may name any tenant, so they must be
+	// told apart from clients' requests by more than their claims.
+	if a := c.Auth; a.PSK != nil && a.PSK.Intraservice == nil &&
+		a.Keyserver == nil && a.Workload == nil &&
+		strings.ToLower(c.Mode) != ComboMode {
+		return fmt.Errorf("tenancy requires a psk intraservice key outside of combo mode")
+	}
+	// Notifications are only delivered to targets configured per tenant, so
+	// deliverers without them can't be used.
+	if n := &c.Notifier; n.PubSub != nil || n.AzureServiceBus != nil ||
+		n.AWS != nil || n.NATS != nil || n.PagerDuty != nil ||
+		n.OpsGenie != nil || n.Jira != nil || n.DefectDojo != nil ||
+		n.Redis != nil {
+		switch strings.ToLower(c.Mode) {
+		case ComboMode, NotifierMode:
+			return fmt.Errorf("tenancy only supports the webhook, amqp, and stomp deliverers")
+		}
+	}
+	if t.ConnString == "" {
+		t.ConnString = c.Indexer.ConnString
+	}
+	if t.ConnString == "" && c.Mode != MatcherMode {
+		return fmt.Errorf("tenancy requires a database connection string")
+	}
+	return nil
+}
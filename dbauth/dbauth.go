@@ -0,0 +1,129 @@
+// Package dbauth authenticates to managed PostgreSQL databases with cloud
+// identities instead of static passwords.
+//
+// IAM authentication tokens are short-lived, but connection strings are
+// handed to libraries that only read them once. So instead of putting a
+// token in the connection string, a Proxy listening on the loopback
+// interface stands in for the database: every connection made to it is
+// authenticated to the real database with a fresh token, then passed
+// through untouched.
+package dbauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+
+	awsauth "github.com/quay/clair/v4/internal/aws"
+	"github.com/quay/clair/v4/internal/gcp"
+)
+
+// These are the clouds whose identities can be used to authenticate.
+const (
+	// CloudAWS generates RDS IAM authentication tokens, for RDS and Aurora.
+	CloudAWS = "aws"
+	// CloudGCP uses OAuth2 access tokens, for Cloud SQL IAM database
+	// authentication.
+	CloudGCP = "gcp"
+)
+
+// TokenFunc returns a password for the user on the database server at host
+// and port.
+type TokenFunc func(ctx context.Context, host string, port uint16, user string) (string, error)
+
+// TokenLifetime is how long RDS authentication tokens are valid for. Tokens
+// are only checked when connecting.
+const tokenLifetime = 15 * time.Minute
+
+// RdsHost matches RDS and Aurora endpoints, capturing the region.
+var rdsHost = regexp.MustCompile(`\.([a-z0-9-]+)\.rds\.amazonaws\.com(?:\.cn)?$`)
+
+// RDSToken returns a TokenFunc generating RDS IAM authentication tokens with
+// the Provider's credentials.
+//
+// If region is empty, it's taken from the database's hostname if that's an
+// RDS endpoint, or else from the AWS_REGION environment variable.
+func RDSToken(p *awsauth.Provider, region string) TokenFunc {
+	return func(ctx context.Context, host string, port uint16, user string) (string, error) {
+		r := region
+		if r == "" {
+			if m := rdsHost.FindStringSubmatch(host); m != nil {
+				r = m[1]
+			}
+		}
+		if r == "" {
+			r = os.Getenv("AWS_REGION")
+		}
+		if r == "" {
+			return "", fmt.Errorf("dbauth: unable to determine the region of %q", host)
+		}
+		creds, err := p.Credentials(ctx)
+		if err != nil {
+			return "", fmt.Errorf("dbauth: %w", err)
+		}
+		u := url.URL{
+			Scheme:   "https",
+			Host:     net.JoinHostPort(host, strconv.Itoa(int(port))),
+			Path:     "/",
+			RawQuery: url.Values{"Action": {"connect"}, "DBUser": {user}}.Encode(),
+		}
+		empty := sha256.Sum256(nil)
+		awsauth.Presign(&u, http.MethodGet, hex.EncodeToString(empty[:]), creds, r, "rds-db", time.Now(), tokenLifetime)
+		return strings.TrimPrefix(u.String(), "https://"), nil
+	}
+}
+
+// CloudSQLToken returns a TokenFunc using access tokens from the
+// TokenSource, which must have the gcp.SQLLoginScope.
+func CloudSQLToken(s *gcp.TokenSource) TokenFunc {
+	return func(ctx context.Context, _ string, _ uint16, _ string) (string, error) {
+		t, err := s.Token(ctx)
+		if err != nil {
+			return "", fmt.Errorf("dbauth: %w", err)
+		}
+		return t, nil
+	}
+}
+
+// ErrInsecure is returned by Check for connection strings that would send
+// tokens without TLS, or that also hold a password.
+var ErrInsecure = errors.New("insecure connection string")
+
+// Check reports whether the connection string is usable: that it parses,
+// and that its TLS settings, like "sslmode" and "sslrootcert", are valid.
+//
+// If iam is set, the connection string must also require TLS and must not
+// hold a password.
+func Check(connString string, iam bool) error {
+	cfg, err := pgconn.ParseConfig(connString)
+	if err != nil {
+		return err
+	}
+	if !iam {
+		return nil
+	}
+	if cfg.Password != "" {
+		return fmt.Errorf("%w: a password can't be used with iam authentication", ErrInsecure)
+	}
+	if cfg.TLSConfig == nil {
+		return fmt.Errorf(`%w: iam authentication needs an sslmode of "require", "verify-ca", or "verify-full"`, ErrInsecure)
+	}
+	for _, fb := range cfg.Fallbacks {
+		if fb.TLSConfig == nil {
+			return fmt.Errorf(`%w: iam authentication needs an sslmode of "require", "verify-ca", or "verify-full"`, ErrInsecure)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package dbauth
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/rs/zerolog"
+)
+
+// Proxy is a running authenticating proxy for one database.
+type Proxy struct {
+	// ConnString is a connection string for the proxy, suitable for passing
+	// to pgx in place of the one the Proxy was started with.
+	ConnString string
+
+	l     net.Listener
+	conf  *pgconn.Config
+	token TokenFunc
+	wg    sync.WaitGroup
+
+	mu sync.Mutex
+	// backends maps the process IDs of proxied connections to the address
+	// of the server they're on, for forwarding cancel requests.
+	backends map[uint32]net.Addr
+}
+
+// Start starts a Proxy for the database the connection string points at,
+// authenticating with passwords from the TokenFunc. The Proxy is stopped
+// when the Context is canceled.
+func Start(ctx context.Context, connString string, token TokenFunc) (*Proxy, error) {
+	conf, err := pgconn.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("dbauth: %w", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("dbauth: %w", err)
+	}
+	cs, err := rewrite(connString, l.Addr().(*net.TCPAddr).Port)
+	if err != nil {
+		l.Close()
+		return nil, fmt.Errorf("dbauth: %w", err)
+	}
+	p := &Proxy{
+		ConnString: cs,
+		l:          l,
+		conf:       conf,
+		token:      token,
+		backends:   make(map[uint32]net.Addr),
+	}
+	p.wg.Add(1)
+	go p.serve(ctx)
+	go func() {
+		<-ctx.Done()
+		p.l.Close()
+	}()
+	return p, nil
+}
+
+// Close stops accepting connections. Connections already proxied are left
+// open until their clients close them.
+func (p *Proxy) Close() error {
+	err := p.l.Close()
+	p.wg.Wait()
+	return err
+}
+
+// Rewrite returns the connection string pointed at the proxy's port on the
+// loopback interface, without TLS or a password. Everything else, like the
+// user, database, and pool settings, is kept.
+func rewrite(connString string, port int) (string, error) {
+	if strings.HasPrefix(connString, "postgres://") || strings.HasPrefix(connString, "postgresql://") {
+		u, err := url.Parse(connString)
+		if err != nil {
+			return "", err
+		}
+		if u.User != nil {
+			u.User = url.User(u.User.Username())
+		}
+		u.Host = net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+		q := u.Query()
+		for _, k := range []string{"host", "port", "password", "passfile", "sslkey", "sslcert", "sslrootcert"} {
+			q.Del(k)
+		}
+		q.Set("sslmode", "disable")
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	// Later settings win in DSNs.
+	return fmt.Sprintf("%s host=127.0.0.1 port=%d sslmode=disable", connString, port), nil
+}
+
+func (p *Proxy) serve(ctx context.Context) {
+	defer p.wg.Done()
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "dbauth/Proxy.serve").
+		Str("host", p.conf.Host).
+		Logger()
+	ctx = log.WithContext(ctx)
+	for {
+		c, err := p.l.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return
+		}
+		go p.handle(ctx, c)
+	}
+}
+
+// Handle authenticates the client's connection to the database, then copies
+// bytes between them until either side closes it.
+func (p *Proxy) handle(ctx context.Context, c net.Conn) {
+	defer c.Close()
+	log := zerolog.Ctx(ctx)
+	be := pgproto3.NewBackend(pgproto3.NewChunkReader(c), c)
+	var sm *pgproto3.StartupMessage
+	for sm == nil {
+		msg, err := be.ReceiveStartupMessage()
+		if err != nil {
+			log.Debug().Err(err).Msg("bad startup message")
+			return
+		}
+		switch m := msg.(type) {
+		case *pgproto3.SSLRequest:
+			// The proxy only listens on the loopback interface.
+			if _, err := c.Write([]byte("N")); err != nil {
+				return
+			}
+		case *pgproto3.CancelRequest:
+			p.cancel(ctx, m)
+			return
+		case *pgproto3.StartupMessage:
+			sm = m
+		default:
+			return
+		}
+	}
+
+	conf := p.conf.Copy()
+	conf.RuntimeParams = make(map[string]string, len(sm.Parameters))
+	for k, v := range sm.Parameters {
+		switch k {
+		case "user", "database":
+		default:
+			conf.RuntimeParams[k] = v
+		}
+	}
+	tok, err := p.token(ctx, conf.Host, conf.Port, conf.User)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to get authentication token")
+		be.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: "28000", Message: err.Error()})
+		return
+	}
+	conf.Password = tok
+	server, err := pgconn.ConnectConfig(ctx, conf)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to connect to database")
+		resp := &pgproto3.ErrorResponse{Severity: "FATAL", Code: "08001", Message: err.Error()}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			resp.Severity, resp.Code, resp.Message = pgErr.Severity, pgErr.Code, pgErr.Message
+		}
+		be.Send(resp)
+		return
+	}
+	hc, err := server.Hijack()
+	if err != nil {
+		server.Close(ctx)
+		return
+	}
+	defer hc.Conn.Close()
+
+	msgs := []pgproto3.BackendMessage{&pgproto3.AuthenticationOk{}}
+	for k, v := range hc.ParameterStatuses {
+		msgs = append(msgs, &pgproto3.ParameterStatus{Name: k, Value: v})
+	}
+	msgs = append(msgs,
+		&pgproto3.BackendKeyData{ProcessID: hc.PID, SecretKey: hc.SecretKey},
+		&pgproto3.ReadyForQuery{TxStatus: hc.TxStatus},
+	)
+	for _, m := range msgs {
+		if err := be.Send(m); err != nil {
+			return
+		}
+	}
+
+	p.mu.Lock()
+	p.backends[hc.PID] = hc.Conn.RemoteAddr()
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.backends, hc.PID)
+		p.mu.Unlock()
+	}()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(hc.Conn, c)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(c, hc.Conn)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// Cancel forwards a cancel request to the server the connection it names is
+// on. Cancel requests aren't authenticated beyond the secret key, and get no
+// response.
+func (p *Proxy) cancel(ctx context.Context, m *pgproto3.CancelRequest) {
+	p.mu.Lock()
+	addr, ok := p.backends[m.ProcessID]
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+	c, err := p.conf.DialFunc(ctx, addr.Network(), addr.String())
+	if err != nil {
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("unable to forward cancel request")
+		return
+	}
+	defer c.Close()
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint32(buf[0:4], 16)
+	binary.BigEndian.PutUint32(buf[4:8], 80877102)
+	binary.BigEndian.PutUint32(buf[8:12], m.ProcessID)
+	binary.BigEndian.PutUint32(buf[12:16], m.SecretKey)
+	c.Write(buf)
+}
@@ -0,0 +1,152 @@
+// Package embedded runs a private PostgreSQL server, so that Clair can be run
+// for development or local scanning without setting up a database.
+//
+// The embedded server is selected by using a connection string of the form:
+//
+//	embedded:///path/to/dir?port=54329
+//
+// The path is where the server's binaries and data are kept. If omitted, a
+// directory in the user's cache directory is used. The PostgreSQL binaries
+// are downloaded on first use.
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/rs/zerolog"
+)
+
+// Scheme is the connection string scheme that selects the embedded server.
+const Scheme = "embedded"
+
+// DefaultPort is the port the embedded server listens on if one is not
+// provided. It's deliberately not the PostgreSQL default, to avoid colliding
+// with a real server.
+const DefaultPort = 54329
+
+const (
+	database = "clair"
+	username = "clair"
+	password = "clair"
+)
+
+// Is reports whether the connection string selects the embedded server.
+func Is(connString string) bool {
+	return strings.HasPrefix(connString, Scheme+"://")
+}
+
+// Database is a running embedded server.
+type Database struct {
+	// ConnString is a connection string for the running server, suitable for
+	// passing to pgx.
+	ConnString string
+
+	key  string
+	refs int
+	pg   *embeddedpostgres.EmbeddedPostgres
+}
+
+var (
+	mu      sync.Mutex
+	running = map[string]*Database{}
+)
+
+// Start starts the server described by the connection string, or returns
+// the server already started for the same directory.
+//
+// Every call to Start should be paired with a call to Close.
+func Start(ctx context.Context, connString string) (*Database, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "embedded/Start").
+		Logger()
+	if !Is(connString) {
+		return nil, fmt.Errorf("embedded: not an embedded connection string: %q", connString)
+	}
+	u, err := url.Parse(connString)
+	if err != nil {
+		return nil, fmt.Errorf("embedded: bad connection string: %w", err)
+	}
+	dir := u.Path
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("embedded: unable to find a directory: %w", err)
+		}
+		dir = filepath.Join(cache, "clair", "embedded")
+	}
+	port := DefaultPort
+	if p := u.Query().Get("port"); p != "" {
+		port, err = strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("embedded: bad port: %w", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if db, ok := running[dir]; ok {
+		db.refs++
+		return db, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("embedded: %w", err)
+	}
+	log.Info().
+		Str("dir", dir).
+		Int("port", port).
+		Msg("starting embedded database")
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(uint32(port)).
+		Database(database).
+		Username(username).
+		Password(password).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		StartTimeout(time.Minute).
+		Logger(logWriter{log}))
+	if err := pg.Start(); err != nil {
+		return nil, fmt.Errorf("embedded: unable to start database: %w", err)
+	}
+	db := &Database{
+		ConnString: fmt.Sprintf("host=localhost port=%d user=%s password=%s dbname=%s sslmode=disable",
+			port, username, password, database),
+		key:  dir,
+		refs: 1,
+		pg:   pg,
+	}
+	running[dir] = db
+	return db, nil
+}
+
+// Close stops the server once every user has closed it.
+func (d *Database) Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	d.refs--
+	if d.refs > 0 {
+		return nil
+	}
+	delete(running, d.key)
+	return d.pg.Stop()
+}
+
+// LogWriter sends the server's output to the debug log.
+type logWriter struct {
+	log zerolog.Logger
+}
+
+func (w logWriter) Write(b []byte) (int, error) {
+	for _, l := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		w.log.Debug().Msg(l)
+	}
+	return len(b), nil
+}
@@ -0,0 +1,68 @@
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+)
+
+func TestIs(t *testing.T) {
+	for s, want := range map[string]bool{
+		"embedded://":                   true,
+		"embedded:///tmp/db?port=54330": true,
+		"host=localhost dbname=clair":   false,
+		"postgres://localhost/clair":    false,
+	} {
+		if got := Is(s); got != want {
+			t.Errorf("%q: got: %v, want: %v", s, got, want)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	integration.Skip(t)
+	ctx := zlog.Test(context.Background(), t)
+	dir, err := ioutil.TempDir("", "clair-embedded-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	cs := fmt.Sprintf("%s://%s?port=%d", Scheme, dir, port)
+
+	a, err := Start(ctx, cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A second user shares the running server.
+	b, err := Start(ctx, cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Error("second Start started another server")
+	}
+	if err := b.Close(); err != nil {
+		t.Error(err)
+	}
+	conn, err := pgx.Connect(ctx, a.ConnString)
+	if err != nil {
+		t.Fatalf("server stopped while still in use: %v", err)
+	}
+	conn.Close(ctx)
+	if err := a.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,15 +4,21 @@ go 1.13
 
 require (
 	github.com/docker/docker v1.13.1 // indirect
+	github.com/fergusstrange/embedded-postgres v1.5.0
+	github.com/go-redis/redis/v8 v8.4.10
 	github.com/go-stomp/stomp v2.0.6+incompatible
 	github.com/google/go-cmp v0.5.4
 	github.com/google/go-containerregistry v0.0.0-20191206185556-eb7c14b719c6
 	github.com/google/uuid v1.1.2
 	github.com/gregjones/httpcache v0.0.0-20190611155906-901d90724c79
+	github.com/jackc/pgconn v1.6.2
+	github.com/jackc/pgproto3/v2 v2.0.2
 	github.com/jackc/pgx/v4 v4.7.2
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/klauspost/compress v1.10.11
 	github.com/mattn/go-sqlite3 v1.11.0 // indirect
+	github.com/nats-io/nats.go v1.10.0
+	github.com/open-policy-agent/opa v0.26.0
 	github.com/prometheus/procfs v0.3.0 // indirect
 	github.com/quay/claircore v0.3.0
 	github.com/quay/zlog v0.0.0-20210113185248-ce16eed1dcec
@@ -0,0 +1,207 @@
+// Package history records the vulnerability reports generated for each
+// manifest, so how its findings changed as the vulnerability database was
+// updated can be reviewed later.
+//
+// Reports are stored by the digest of their contents, so a manifest whose
+// report doesn't change across updates costs one copy. Each manifest keeps
+// a bounded number of versions.
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+)
+
+// DefaultRetain is the number of versions kept for each manifest if not
+// configured.
+const DefaultRetain = 100
+
+// Entry is one version of a manifest's vulnerability report.
+type Entry struct {
+	// UpdateOperation is the latest update operation when the report was
+	// generated.
+	UpdateOperation uuid.UUID `json:"update_operation"`
+	// Created is when the version was first generated.
+	Created time.Time `json:"created"`
+	// Digest identifies the report's contents.
+	Digest string `json:"digest"`
+	// Vulnerabilities is the number of vulnerabilities in the report.
+	Vulnerabilities int `json:"vulnerabilities"`
+	// Severities counts the vulnerabilities by normalized severity.
+	Severities map[string]int `json:"severities"`
+}
+
+// Store persists report history in the matcher's database.
+type Store struct {
+	pool   *pgxpool.Pool
+	retain int
+}
+
+// NewStore returns a Store using the database behind pool, which must have
+// had the history migrations applied, keeping retain versions per manifest.
+func NewStore(pool *pgxpool.Pool, retain int) *Store {
+	if retain <= 0 {
+		retain = DefaultRetain
+	}
+	return &Store{pool: pool, retain: retain}
+}
+
+// Digest returns the digest identifying the encoded report.
+func Digest(b []byte) string {
+	s := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(s[:])
+}
+
+const (
+	seenVersion = `
+SELECT EXISTS (SELECT 1 FROM report_history WHERE manifest = $1 AND update_ref = $2 AND digest = $3);`
+	putContent = `
+INSERT INTO report_content (digest, report, vulnerabilities, severities) VALUES ($1, $2, $3, $4)
+ON CONFLICT (digest) DO NOTHING;`
+	putVersion = `
+INSERT INTO report_history (manifest, update_ref, digest) VALUES ($1, $2, $3)
+ON CONFLICT (manifest, update_ref, digest) DO NOTHING;`
+	pruneVersions = `
+DELETE FROM report_history
+WHERE manifest = $1
+  AND (update_ref, digest) NOT IN (
+	SELECT update_ref, digest FROM report_history
+	WHERE manifest = $1
+	ORDER BY created DESC
+	LIMIT $2)
+RETURNING digest;`
+	pruneContent = `
+DELETE FROM report_content c
+WHERE c.digest = ANY($1::text[])
+  AND NOT EXISTS (SELECT 1 FROM report_history h WHERE h.digest = c.digest);`
+)
+
+// Record stores the report as the manifest's version as of the update
+// operation, if it isn't already, and drops versions beyond the retention.
+func (s *Store) Record(ctx context.Context, ref uuid.UUID, vr *claircore.VulnerabilityReport) error {
+	b, err := json.Marshal(vr)
+	if err != nil {
+		return fmt.Errorf("history: failed to encode report: %w", err)
+	}
+	d := Digest(b)
+	m := vr.Hash.String()
+	var seen bool
+	if err := s.pool.QueryRow(ctx, seenVersion, m, ref, d).Scan(&seen); err != nil {
+		return fmt.Errorf("history: failed to look up version: %w", err)
+	}
+	if seen {
+		return nil
+	}
+	sev := make(map[string]int)
+	for _, v := range vr.Vulnerabilities {
+		sev[v.NormalizedSeverity.String()]++
+	}
+	sb, err := json.Marshal(sev)
+	if err != nil {
+		return fmt.Errorf("history: failed to encode severities: %w", err)
+	}
+
+	if err := s.record(ctx, m, ref, d, b, len(vr.Vulnerabilities), sb); err != nil {
+		return fmt.Errorf("history: failed to record version: %w", err)
+	}
+	return nil
+}
+
+// Record adds the version and prunes old ones in one transaction.
+func (s *Store) record(ctx context.Context, m string, ref uuid.UUID, d string, b []byte, n int, sev []byte) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, putContent, d, b, n, sev); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, putVersion, m, ref, d); err != nil {
+		return err
+	}
+	rows, err := tx.Query(ctx, pruneVersions, m, s.retain)
+	if err != nil {
+		return err
+	}
+	var gone []string
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			rows.Close()
+			return err
+		}
+		gone = append(gone, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(gone) != 0 {
+		if _, err := tx.Exec(ctx, pruneContent, gone); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+const selectHistory = `
+SELECT h.update_ref, h.created, h.digest, c.vulnerabilities, c.severities
+FROM report_history h
+JOIN report_content c ON c.digest = h.digest
+WHERE h.manifest = $1
+ORDER BY h.created DESC;`
+
+// History returns the manifest's versions, newest first.
+func (s *Store) History(ctx context.Context, manifest claircore.Digest) ([]Entry, error) {
+	rows, err := s.pool.Query(ctx, selectHistory, manifest.String())
+	if err != nil {
+		return nil, fmt.Errorf("history: failed to read history: %w", err)
+	}
+	defer rows.Close()
+	out := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var sb []byte
+		if err := rows.Scan(&e.UpdateOperation, &e.Created, &e.Digest, &e.Vulnerabilities, &sb); err != nil {
+			return nil, fmt.Errorf("history: failed to read history: %w", err)
+		}
+		if err := json.Unmarshal(sb, &e.Severities); err != nil {
+			return nil, fmt.Errorf("history: failed to read history: %w", err)
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("history: failed to read history: %w", err)
+	}
+	return out, nil
+}
+
+const selectReport = `
+SELECT c.report
+FROM report_content c
+WHERE c.digest = $2
+  AND EXISTS (SELECT 1 FROM report_history h WHERE h.manifest = $1 AND h.digest = c.digest);`
+
+// Report returns the encoded report with the digest, if it's one of the
+// manifest's versions.
+func (s *Store) Report(ctx context.Context, manifest claircore.Digest, digest string) ([]byte, bool, error) {
+	var b []byte
+	err := s.pool.QueryRow(ctx, selectReport, manifest.String(), digest).Scan(&b)
+	switch {
+	case err == pgx.ErrNoRows:
+		return nil, false, nil
+	case err != nil:
+		return nil, false, fmt.Errorf("history: failed to read report: %w", err)
+	}
+	return b, true, nil
+}
@@ -0,0 +1,114 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/remind101/migrate"
+
+	"github.com/quay/clair/v4/history/migrations"
+)
+
+func testPool(ctx context.Context, t *testing.T) (*pgxpool.Pool, func()) {
+	if os.Getenv(integration.EnvPGConnString) == "" {
+		os.Setenv(integration.EnvPGConnString, `host=localhost port=5432 user=clair dbname=clair sslmode=disable`)
+	}
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, db.Config())
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	sdb := stdlib.OpenDB(*db.Config().ConnConfig)
+	defer sdb.Close()
+	migrator := migrate.NewPostgresMigrator(sdb)
+	migrator.Table = migrations.MigrationTable
+	if err := migrator.Exec(migrate.Up, migrations.Migrations...); err != nil {
+		t.Fatalf("failed to perform migrations: %v", err)
+	}
+	return pool, func() {
+		pool.Close()
+		db.Close(ctx, t)
+	}
+}
+
+// TestStore confirms versions are recorded once, identical reports share
+// storage, and old versions are pruned.
+func TestStore(t *testing.T) {
+	integration.Skip(t)
+	ctx := context.Background()
+	pool, done := testPool(ctx, t)
+	defer done()
+	s := NewStore(pool, 2)
+
+	m := claircore.MustParseDigest("sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a")
+	clean := &claircore.VulnerabilityReport{Hash: m}
+	vuln := &claircore.VulnerabilityReport{
+		Hash: m,
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"1": {ID: "1", Name: "CVE-2021-0001", NormalizedSeverity: claircore.High},
+		},
+	}
+	refs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for _, tc := range []struct {
+		ref uuid.UUID
+		vr  *claircore.VulnerabilityReport
+	}{
+		{refs[0], clean},
+		{refs[0], clean}, // Already recorded.
+		{refs[1], vuln},
+		{refs[2], vuln},
+	} {
+		if err := s.Record(ctx, tc.ref, tc.vr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	es, err := s.History(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 2 {
+		t.Fatalf("got %d versions, want 2: %+v", len(es), es)
+	}
+	if es[0].UpdateOperation != refs[2] || es[0].Vulnerabilities != 1 || es[0].Severities["High"] != 1 {
+		t.Errorf("unexpected version: %+v", es[0])
+	}
+	if es[0].Digest != es[1].Digest {
+		t.Errorf("identical reports have different digests: %q, %q", es[0].Digest, es[1].Digest)
+	}
+
+	b, ok, err := s.Report(ctx, m, es[0].Digest)
+	if err != nil || !ok {
+		t.Fatalf("report not found: %v", err)
+	}
+	var got claircore.VulnerabilityReport
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Vulnerabilities) != 1 {
+		t.Errorf("unexpected report: %+v", got)
+	}
+
+	// The clean report was pruned along with its version.
+	cb, _ := json.Marshal(clean)
+	if _, ok, err := s.Report(ctx, m, Digest(cb)); err != nil || ok {
+		t.Errorf("pruned report found: %v", err)
+	}
+	var n int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM report_content;`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("got %d stored reports, want 1", n)
+	}
+}
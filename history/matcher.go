@@ -0,0 +1,65 @@
+package history
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+// Matcher wraps a matcher.Service and records the vulnerability reports it
+// generates.
+type Matcher struct {
+	matcher.Service
+	store *Store
+}
+
+var _ matcher.Service = (*Matcher)(nil)
+
+// NewMatcher returns a Matcher recording reports in the Store.
+func NewMatcher(s matcher.Service, store *Store) *Matcher {
+	return &Matcher{
+		Service: s,
+		store:   store,
+	}
+}
+
+// Unwrap returns the wrapped matcher.Service.
+func (m *Matcher) Unwrap() matcher.Service {
+	return m.Service
+}
+
+// Scan implements matcher.Scanner.
+//
+// Failing to record a report doesn't fail the scan.
+func (m *Matcher) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	vr, err := m.Service.Scan(ctx, ir)
+	if err != nil {
+		return vr, err
+	}
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "history/Matcher.Scan").
+		Stringer("manifest", ir.Hash).
+		Logger()
+	ref, err := m.Service.LatestUpdateOperation(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to determine update operation")
+		return vr, nil
+	}
+	if err := m.store.Record(ctx, ref, vr); err != nil {
+		log.Warn().Err(err).Msg("unable to record report history")
+	}
+	return vr, nil
+}
+
+// History returns the manifest's report versions, newest first.
+func (m *Matcher) History(ctx context.Context, manifest claircore.Digest) ([]Entry, error) {
+	return m.store.History(ctx, manifest)
+}
+
+// Report returns the encoded report version with the digest.
+func (m *Matcher) Report(ctx context.Context, manifest claircore.Digest, digest string) ([]byte, bool, error) {
+	return m.store.Report(ctx, manifest, digest)
+}
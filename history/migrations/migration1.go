@@ -0,0 +1,28 @@
+package migrations
+
+const (
+	// migration1 adds storage for vulnerability report history.
+	migration1 = `
+	--- a relation holding vulnerability reports, keyed by the digest of
+	--- their contents so identical reports are stored once
+	CREATE TABLE IF NOT EXISTS report_content
+	(
+		digest          text PRIMARY KEY,
+		report          bytea   NOT NULL,
+		vulnerabilities integer NOT NULL,
+		severities      jsonb   NOT NULL
+	);
+	--- a relation recording which report a manifest had as of an update
+	--- operation
+	CREATE TABLE IF NOT EXISTS report_history
+	(
+		manifest   text        NOT NULL,
+		update_ref uuid        NOT NULL,
+		digest     text        NOT NULL REFERENCES report_content (digest),
+		created    timestamptz NOT NULL DEFAULT now(),
+		PRIMARY KEY (manifest, update_ref, digest)
+	);
+	CREATE INDEX IF NOT EXISTS report_history_manifest_idx ON report_history (manifest, created DESC);
+	CREATE INDEX IF NOT EXISTS report_history_digest_idx ON report_history (digest);
+	`
+)
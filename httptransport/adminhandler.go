@@ -0,0 +1,349 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	je "github.com/quay/claircore/pkg/jsonerr"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/admin"
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/dryrun"
+	"github.com/quay/clair/v4/notifier/service"
+	"github.com/quay/clair/v4/tenant"
+)
+
+// IndexerGCResponse reports what an indexer garbage collection removed.
+type IndexerGCResponse struct {
+	Manifests int64 `json:"manifests"`
+	Layers    int64 `json:"layers"`
+}
+
+// MatcherGCResponse reports what a matcher garbage collection removed.
+type MatcherGCResponse struct {
+	UpdateOperations int64 `json:"update_operations"`
+}
+
+// UpdaterRunResponse reports the new update operation for each updater that
+// found changes.
+type UpdaterRunResponse struct {
+	Updated map[string]uuid.UUID `json:"updated"`
+}
+
+// DryRunResponse reports how the vulnerability reports of the requested
+// manifests would change with a candidate snapshot.
+type DryRunResponse struct {
+	// Updaters are the updaters whose data the snapshot replaced.
+	Updaters []string `json:"updaters"`
+	// Vulnerabilities is the number of vulnerabilities in the snapshot.
+	Vulnerabilities int            `json:"vulnerabilities"`
+	Results         []DryRunResult `json:"results"`
+}
+
+// DryRunResult is the diff for a manifest in a dry run, or the error that
+// kept it from being computed.
+type DryRunResult struct {
+	Manifest string       `json:"manifest_hash"`
+	Diff     *dryrun.Diff `json:"diff,omitempty"`
+	Error    *je.Response `json:"error,omitempty"`
+}
+
+// MigrateResponse reports the version of each set of migrations after a
+// migration.
+type MigrateResponse struct {
+	Migrations []admin.MigrationStatus `json:"migrations"`
+}
+
+// DeadLetter is a notification that failed delivery.
+type DeadLetter struct {
+	NotificationID  uuid.UUID `json:"notification_id"`
+	UpdateOperation uuid.UUID `json:"update_operation"`
+	// Since is when the latest delivery attempt failed.
+	Since time.Time `json:"since"`
+}
+
+// DeadLetterResponse lists notifications that failed delivery.
+type DeadLetterResponse struct {
+	DeadLetters []DeadLetter `json:"dead_letters"`
+}
+
+// ReplayResponse reports how many notification ids were queued for delivery.
+type ReplayResponse struct {
+	Replayed int64 `json:"replayed"`
+}
+
+// AdminMethod writes an error and returns false if the request's method
+// isn't m.
+func adminMethod(w http.ResponseWriter, r *http.Request, m string) bool {
+	if r.Method == m {
+		return true
+	}
+	apiError(w, clairerror.New(clairerror.MethodNotAllowed, fmt.Sprintf("endpoint only allows %s", m)), "")
+	return false
+}
+
+// AdminError writes the appropriate error response for err.
+func adminError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, admin.ErrNotConfigured):
+		apiError(w, err, msg)
+	case errors.Is(err, tenant.ErrForbidden):
+		apiError(w, clairerror.New(clairerror.Forbidden, "tenants may not use the admin API"), "")
+	default:
+		zerolog.Ctx(r.Context()).Warn().
+			Str("component", "httptransport/adminError").
+			Err(err).
+			Msg(msg)
+		apiError(w, err, msg)
+	}
+}
+
+// ManifestDeleteHandler removes the manifest named by the last path element,
+// along with its index report.
+func ManifestDeleteHandler(a *admin.Indexer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if !adminMethod(w, r, http.MethodDelete) {
+			return
+		}
+		d, err := claircore.ParseDigest(path.Base(r.URL.Path))
+		if err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "malformed path"), "")
+			return
+		}
+		deleted, err := a.DeleteManifests(ctx, d)
+		if err != nil {
+			adminError(w, r, err, "could not delete manifest")
+			return
+		}
+		if len(deleted) == 0 {
+			apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("manifest %q not found", d)), "")
+			return
+		}
+		zerolog.Ctx(ctx).Info().
+			Str("component", "httptransport/ManifestDeleteHandler").
+			Str("manifest", d.String()).
+			Msg("deleted manifest")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// IndexerGCHandler runs index report garbage collection to completion.
+func IndexerGCHandler(a *admin.Indexer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if !adminMethod(w, r, http.MethodPost) {
+			return
+		}
+		ms, ls, err := a.GC(ctx)
+		if err != nil {
+			adminError(w, r, err, "could not collect index reports")
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(&IndexerGCResponse{Manifests: ms, Layers: ls})
+	}
+}
+
+// UpdaterRunHandler runs every configured updater once, responding when
+// they've all finished.
+func UpdaterRunHandler(a *admin.Matcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if !adminMethod(w, r, http.MethodPost) {
+			return
+		}
+		updated, err := a.RunUpdaters(ctx)
+		if err != nil {
+			adminError(w, r, err, "could not run updaters")
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(&UpdaterRunResponse{Updated: updated})
+	}
+}
+
+const (
+	// MaxDryRunManifests is the most manifests a dry run may name.
+	maxDryRunManifests = 100
+	// MaxDryRunVulnerabilities is the most vulnerabilities a dry run's
+	// snapshot may hold.
+	maxDryRunVulnerabilities = 500000
+	// MaxDryRunBody bounds the size of a dry run's snapshot.
+	maxDryRunBody = 512 << 20
+)
+
+// MatcherDryRunHandler matches the manifests named by the "manifest" query
+// parameters against the snapshot in the request body, reporting how their
+// vulnerability reports would change.
+//
+// The snapshot is newline delimited vulnerabilities, as served by the
+// updates export endpoint. It's only held for the duration of the request.
+func MatcherDryRunHandler(a *admin.Matcher, idx indexer.Reporter, m matcher.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if !adminMethod(w, r, http.MethodPost) {
+			return
+		}
+		ms := r.URL.Query()["manifest"]
+		if n := len(ms); n == 0 || n > maxDryRunManifests {
+			apiError(w, clairerror.New(clairerror.Invalid, fmt.Sprintf("dry runs must name between 1 and %d manifests", maxDryRunManifests)), "")
+			return
+		}
+		snap, err := dryrun.Load(http.MaxBytesReader(w, r.Body, maxDryRunBody), maxDryRunVulnerabilities)
+		switch {
+		case errors.Is(err, dryrun.ErrTooLarge):
+			apiError(w, clairerror.New(clairerror.TooLarge, fmt.Sprintf("snapshot has more than %d vulnerabilities", maxDryRunVulnerabilities)), "")
+			return
+		case err != nil:
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to load snapshot"), "")
+			return
+		case snap.Len() == 0:
+			apiError(w, clairerror.New(clairerror.Invalid, "snapshot is empty"), "")
+			return
+		}
+
+		resp := DryRunResponse{
+			Updaters:        snap.Updaters(),
+			Vulnerabilities: snap.Len(),
+			Results:         make([]DryRunResult, len(ms)),
+		}
+		for i, h := range ms {
+			res := &resp.Results[i]
+			res.Manifest = h
+			d, err := claircore.ParseDigest(h)
+			if err != nil {
+				res.Error = errorResponse(clairerror.Wrap(clairerror.Invalid, err, "malformed manifest"), "")
+				continue
+			}
+			ir, ok, err := idx.IndexReport(ctx, d)
+			switch {
+			case err != nil:
+				res.Error = errorResponse(err, "could not retrieve index report")
+				continue
+			case !ok:
+				res.Error = errorResponse(clairerror.New(clairerror.NotFound, fmt.Sprintf("index report for manifest %q not found", h)), "")
+				continue
+			}
+			res.Diff, err = a.DryRun(ctx, snap, ir, m)
+			switch {
+			case errors.Is(err, admin.ErrNotConfigured):
+				adminError(w, r, err, "could not run dry run")
+				return
+			case err != nil:
+				res.Error = errorResponse(err, "")
+			}
+		}
+		zerolog.Ctx(ctx).Info().
+			Str("component", "httptransport/MatcherDryRunHandler").
+			Strs("updaters", resp.Updaters).
+			Int("manifests", len(ms)).
+			Msg("ran dry run")
+		w.Header().Set("content-type", "application/json")
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(&resp)
+	}
+}
+
+// MatcherGCHandler runs update operation garbage collection to completion.
+func MatcherGCHandler(a *admin.Matcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if !adminMethod(w, r, http.MethodPost) {
+			return
+		}
+		n, err := a.GC(ctx)
+		if err != nil {
+			adminError(w, r, err, "could not collect update operations")
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(&MatcherGCResponse{UpdateOperations: n})
+	}
+}
+
+// MigrateHandler runs a service's outstanding migrations.
+func MigrateHandler(m *admin.Migrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if !adminMethod(w, r, http.MethodPost) {
+			return
+		}
+		st, err := m.Migrate(ctx)
+		if err != nil {
+			adminError(w, r, err, "could not perform migrations")
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(&MigrateResponse{Migrations: st})
+	}
+}
+
+// DeadLetterHandler lists notifications that failed delivery on GET.
+//
+// A POST queues the notification id named by the last path element for
+// delivery again, or every notification that failed delivery if there isn't
+// one. Notifications already delivered may be replayed this way, too.
+func DeadLetterHandler(serv service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var out interface{}
+		switch r.Method {
+		case http.MethodGet:
+			rs, err := serv.DeadLetters(ctx)
+			if err != nil {
+				adminError(w, r, err, "could not list dead letters")
+				return
+			}
+			res := DeadLetterResponse{DeadLetters: make([]DeadLetter, len(rs))}
+			for i, rc := range rs {
+				res.DeadLetters[i] = DeadLetter{
+					NotificationID:  rc.NotificationID,
+					UpdateOperation: rc.UOID,
+					Since:           rc.TS,
+				}
+			}
+			out = &res
+		case http.MethodPost:
+			var ids []uuid.UUID
+			if p := path.Base(r.URL.Path); p != path.Base(DeadLetterPath) {
+				id, err := uuid.Parse(p)
+				if err != nil {
+					apiError(w, clairerror.Wrap(clairerror.Invalid, err, "could not parse notification id"), "")
+					return
+				}
+				ids = append(ids, id)
+			}
+			n, err := serv.Replay(ctx, ids...)
+			if err != nil {
+				adminError(w, r, err, "could not replay notifications")
+				return
+			}
+			zerolog.Ctx(ctx).Info().
+				Str("component", "httptransport/DeadLetterHandler").
+				Int64("replayed", n).
+				Msg("replayed notifications")
+			out = &ReplayResponse{Replayed: n}
+		default:
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET or POST"), "")
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		var err error
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(out)
+	}
+}
@@ -0,0 +1,98 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+
+	"github.com/quay/clair/v4/notifier"
+	"github.com/quay/clair/v4/notifier/service"
+	"github.com/quay/clair/v4/tenant"
+)
+
+// TestDeadLetterHandler confirms dead letters are listed and replayed.
+func TestDeadLetterHandler(t *testing.T) {
+	failed := notifier.Receipt{
+		UOID:           uuid.New(),
+		NotificationID: uuid.New(),
+		Status:         notifier.DeliveryFailed,
+		TS:             time.Now().UTC().Truncate(time.Second),
+	}
+	var replayed []uuid.UUID
+	nm := &service.Mock{
+		DeadLetters_: func(_ context.Context) ([]notifier.Receipt, error) {
+			return []notifier.Receipt{failed}, nil
+		},
+		Replay_: func(_ context.Context, ids ...uuid.UUID) (int64, error) {
+			replayed = ids
+			if len(ids) == 0 {
+				return 1, nil
+			}
+			return int64(len(ids)), nil
+		},
+	}
+	h := DeadLetterHandler(nm)
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, DeadLetterPath, nil))
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+	var list DeadLetterResponse
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	want := []DeadLetter{{
+		NotificationID:  failed.NotificationID,
+		UpdateOperation: failed.UOID,
+		Since:           failed.TS,
+	}}
+	if !cmp.Equal(list.DeadLetters, want) {
+		t.Error(cmp.Diff(list.DeadLetters, want))
+	}
+
+	for _, tc := range []struct {
+		path string
+		ids  []uuid.UUID
+	}{
+		{path: DeadLetterPath},
+		{path: DeadLetterPath + failed.NotificationID.String(), ids: []uuid.UUID{failed.NotificationID}},
+	} {
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodPost, tc.path, nil))
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		var res ReplayResponse
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := res.Replayed, int64(1); got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+		if !cmp.Equal(replayed, tc.ids) {
+			t.Error(cmp.Diff(replayed, tc.ids))
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodPost, DeadLetterPath+"bogus", nil))
+	if got, want := rr.Code, http.StatusBadRequest; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+
+	nm.DeadLetters_ = func(_ context.Context) ([]notifier.Receipt, error) {
+		return nil, tenant.ErrForbidden
+	}
+	rr = httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, DeadLetterPath, nil))
+	if got, want := rr.Code, http.StatusForbidden; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/affected"
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/tenant"
+)
+
+// ManifestPage describes a page of affected manifests.
+type ManifestPage struct {
+	Size uint64 `json:"size"`
+	// The manifest hash to request as "next" for the following page, if
+	// there is one.
+	Next string `json:"next,omitempty"`
+}
+
+// AffectedManifest is a manifest and the vulnerabilities affecting it.
+type AffectedManifest struct {
+	ManifestHash    string   `json:"manifest_hash"`
+	Vulnerabilities []string `json:"vulnerabilities"`
+}
+
+// AffectedManifestsResponse is a page of manifests affected by a
+// vulnerability.
+type AffectedManifestsResponse struct {
+	Page ManifestPage `json:"page"`
+	// Vulnerabilities holds the vulnerabilities referenced in the page, keyed
+	// by ID.
+	Vulnerabilities map[string]*claircore.Vulnerability `json:"vulnerabilities"`
+	Manifests       []AffectedManifest                  `json:"manifests"`
+}
+
+// AffectedManifestsHandler reports the indexed manifests affected by the
+// vulnerability named by the "vulnerability_id" query parameter, which may be
+// a name like "CVE-2021-3156" or a vulnerability's ID.
+//
+// The optional "namespace" parameter restricts the vulnerabilities to those
+// from an updater or distribution ID. Manifests are returned in order of
+// their hash, "page_size" at a time, starting at the "next" parameter.
+//
+// Requests made on behalf of a tenant need the indexer to be able to page
+// through the manifests the tenant submitted, and are refused otherwise.
+func AffectedManifestsHandler(f affected.Finder, idx indexer.Affected) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Method != http.MethodGet {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
+			return
+		}
+		q := r.URL.Query()
+		id := q.Get("vulnerability_id")
+		if id == "" {
+			apiError(w, clairerror.New(clairerror.Invalid, "request must provide a \"vulnerability_id\" query param"), "")
+			return
+		}
+		var pageSize uint64
+		if param := q.Get("page_size"); param != "" {
+			var err error
+			pageSize, err = strconv.ParseUint(param, 10, 64)
+			if err != nil {
+				apiError(w, clairerror.New(clairerror.Invalid, "could not parse \"page_size\" query param into integer"), "")
+				return
+			}
+		}
+		if pageSize == 0 {
+			pageSize = DefaultPageSize
+		}
+		next := q.Get("next")
+
+		vs, err := f.Vulnerabilities(ctx, id, q.Get("namespace"))
+		if err != nil {
+			apiError(w, err, "failed to look up vulnerability")
+			return
+		}
+		if len(vs) == 0 {
+			apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("no vulnerability %q", id)), "")
+			return
+		}
+		var a *claircore.AffectedManifests
+		var following string
+		p, paged := affectedPager(idx)
+		_, scoped := tenant.FromContext(ctx)
+		switch {
+		case paged:
+			a, following, err = p.AffectedManifestsPage(ctx, vs, next, int(pageSize))
+		case scoped:
+			apiError(w, clairerror.New(clairerror.Forbidden, "affected manifests can't be scoped to a tenant"), "")
+			return
+		default:
+			a, err = idx.AffectedManifests(ctx, vs)
+			if err == nil {
+				a, following = indexer.PageAffected(a, next, int(pageSize))
+			}
+		}
+		if err != nil {
+			apiError(w, err, "failed to find affected manifests")
+			return
+		}
+
+		hs := make([]string, 0, len(a.VulnerableManifests))
+		for h := range a.VulnerableManifests {
+			hs = append(hs, h)
+		}
+		sort.Strings(hs)
+		resp := AffectedManifestsResponse{
+			Page:            ManifestPage{Size: pageSize, Next: following},
+			Vulnerabilities: a.Vulnerabilities,
+			Manifests:       make([]AffectedManifest, 0, len(hs)),
+		}
+		for _, h := range hs {
+			resp.Manifests = append(resp.Manifests, AffectedManifest{
+				ManifestHash:    h,
+				Vulnerabilities: a.VulnerableManifests[h],
+			})
+		}
+
+		defer writerError(w, &err)()
+		w.Header().Set("content-type", "application/json")
+		err = json.NewEncoder(w).Encode(&resp)
+	}
+}
+
+// AffectedPager finds an indexer.AffectedPager among the wrapped indexers, if
+// there is one.
+func affectedPager(s indexer.Affected) (indexer.AffectedPager, bool) {
+	var p indexer.AffectedPager
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		p, ok = s.(indexer.AffectedPager)
+		return ok
+	})
+	return p, ok
+}
+
+// AffectedMatcher finds the affected.Matcher among the wrapped matchers, if
+// there is one.
+func affectedMatcher(s matcher.Service) (*affected.Matcher, bool) {
+	var m *affected.Matcher
+	ok := matcher.Walk(s, func(s interface{}) bool {
+		var ok bool
+		m, ok = s.(*affected.Matcher)
+		return ok
+	})
+	return m, ok
+}
@@ -0,0 +1,176 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/tenant"
+)
+
+type finderFunc func(ctx context.Context, id, namespace string) ([]claircore.Vulnerability, error)
+
+func (f finderFunc) Vulnerabilities(ctx context.Context, id, namespace string) ([]claircore.Vulnerability, error) {
+	return f(ctx, id, namespace)
+}
+
+// PagedIndexer is an indexer able to page through affected manifests.
+type pagedIndexer struct {
+	*indexer.Mock
+	page func(next string, size int) (*claircore.AffectedManifests, string, error)
+}
+
+func (i *pagedIndexer) AffectedManifestsPage(_ context.Context, _ []claircore.Vulnerability, next string, size int) (*claircore.AffectedManifests, string, error) {
+	return i.page(next, size)
+}
+
+func TestAffectedManifestsHandler(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := finderFunc(func(_ context.Context, id, ns string) ([]claircore.Vulnerability, error) {
+		if id != "CVE-2021-3156" || ns != "debian" {
+			return nil, nil
+		}
+		return []claircore.Vulnerability{{ID: "1", Name: id}, {ID: "2", Name: id}}, nil
+	})
+	idx := &indexer.Mock{
+		AffectedManifests_: func(_ context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+			if len(vs) != 2 {
+				t.Errorf("got: %d vulnerabilities, want: 2", len(vs))
+			}
+			a := claircore.NewAffectedManifests()
+			for i := range vs {
+				a.Vulnerabilities[vs[i].ID] = &vs[i]
+			}
+			a.VulnerableManifests["sha256:c"] = []string{"1"}
+			a.VulnerableManifests["sha256:a"] = []string{"1", "2"}
+			a.VulnerableManifests["sha256:b"] = []string{"2"}
+			return &a, nil
+		},
+	}
+	srv := httptest.NewServer(AffectedManifestsHandler(f, idx))
+	defer srv.Close()
+	c := srv.Client()
+
+	get := func(t *testing.T, query string, want int) *AffectedManifestsResponse {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if got := res.StatusCode; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		if want != http.StatusOK {
+			return nil
+		}
+		var out AffectedManifestsResponse
+		if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return &out
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		const q = "vulnerability_id=CVE-2021-3156&namespace=debian&page_size=2"
+		r := get(t, q, http.StatusOK)
+		if got, want := r.Page, (ManifestPage{Size: 2, Next: "sha256:c"}); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		want := []AffectedManifest{
+			{ManifestHash: "sha256:a", Vulnerabilities: []string{"1", "2"}},
+			{ManifestHash: "sha256:b", Vulnerabilities: []string{"2"}},
+		}
+		if got := r.Manifests; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		if got, want := len(r.Vulnerabilities), 2; got != want {
+			t.Errorf("got: %d vulnerabilities, want: %d", got, want)
+		}
+
+		r = get(t, q+"&next="+r.Page.Next, http.StatusOK)
+		if got, want := r.Page, (ManifestPage{Size: 2}); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		want = []AffectedManifest{
+			{ManifestHash: "sha256:c", Vulnerabilities: []string{"1"}},
+		}
+		if got := r.Manifests; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		if _, ok := r.Vulnerabilities["2"]; ok {
+			t.Error("unreferenced vulnerability in page")
+		}
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		get(t, "vulnerability_id=CVE-2021-3156&namespace=ubuntu", http.StatusNotFound)
+	})
+	t.Run("BadRequest", func(t *testing.T) {
+		get(t, "namespace=debian", http.StatusBadRequest)
+		get(t, "vulnerability_id=CVE-2021-3156&page_size=many", http.StatusBadRequest)
+	})
+	t.Run("Tenant", func(t *testing.T) {
+		const q = "vulnerability_id=CVE-2021-3156&namespace=debian&page_size=2"
+		scoped := func(h http.Handler) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				h.ServeHTTP(w, r.WithContext(tenant.WithTenant(r.Context(), "team-a")))
+			}))
+		}
+		// The mock indexer can't scope its results, so they're refused.
+		srv := scoped(AffectedManifestsHandler(f, idx))
+		defer srv.Close()
+		res, err := srv.Client().Get(srv.URL + "?" + q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusForbidden; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+
+		paged := &pagedIndexer{
+			Mock: idx,
+			page: func(next string, size int) (*claircore.AffectedManifests, string, error) {
+				if next != "sha256:b" || size != 2 {
+					t.Errorf("got: %q, %d, want: %q, %d", next, size, "sha256:b", 2)
+				}
+				a := claircore.NewAffectedManifests()
+				a.Vulnerabilities["2"] = &claircore.Vulnerability{ID: "2"}
+				a.VulnerableManifests["sha256:b"] = []string{"2"}
+				return &a, "sha256:d", nil
+			},
+		}
+		srv = scoped(AffectedManifestsHandler(f, paged))
+		defer srv.Close()
+		res, err = srv.Client().Get(srv.URL + "?" + q + "&next=sha256:b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		var r AffectedManifestsResponse
+		if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := r.Page, (ManifestPage{Size: 2, Next: "sha256:d"}); !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+		want := []AffectedManifest{{ManifestHash: "sha256:b", Vulnerabilities: []string{"2"}}}
+		if got := r.Manifests; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/annotations"
+)
+
+// AnnotationsSuffix is the path element following a manifest in the index
+// report path for its annotations.
+const annotationsSuffix = "/annotations"
+
+// MaxAnnotationsBody bounds the size of an annotations patch.
+const maxAnnotationsBody = 1 << 20
+
+// AnnotationsResponse holds a manifest's annotations.
+type AnnotationsResponse struct {
+	Annotations map[string]string `json:"annotations"`
+}
+
+// AnnotationsHandler serves the annotations of the manifest named in the
+// path: a GET returns them, and a PATCH applies a JSON merge patch to them,
+// where keys with a null value are removed.
+//
+// Annotations can only be set on manifests that have been indexed, and are
+// those of the request's tenant.
+func annotationsHandler(w http.ResponseWriter, r *http.Request, serv indexer.StateReporter) {
+	ctx := r.Context()
+	switch r.Method {
+	case http.MethodGet, http.MethodPatch:
+	default:
+		apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET or PATCH"), "")
+		return
+	}
+	ai, ok := annotationsIndexer(serv)
+	if !ok {
+		apiError(w, clairerror.New(clairerror.Unimplemented, "annotations are not configured"), "")
+		return
+	}
+	p := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, IndexReportAPIPath), annotationsSuffix)
+	manifest, err := claircore.ParseDigest(p)
+	if err != nil {
+		apiError(w, clairerror.Wrap(clairerror.Invalid, err, "malformed path"), "")
+		return
+	}
+	// Going through the index report keeps annotations to manifests the
+	// caller could see anyway.
+	_, ok, err = serv.IndexReport(ctx, manifest)
+	if err != nil {
+		apiError(w, err, "")
+		return
+	}
+	if !ok {
+		apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("index report for manifest %q not found", manifest.String())), "")
+		return
+	}
+
+	var as map[string]string
+	switch r.Method {
+	case http.MethodGet:
+		as, err = ai.Annotations(ctx, manifest)
+		if err != nil {
+			apiError(w, err, "could not retrieve annotations")
+			return
+		}
+	case http.MethodPatch:
+		var patch annotations.Patch
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAnnotationsBody)).Decode(&patch); err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to deserialize annotations"), "")
+			return
+		}
+		if err := patch.Validate(); err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, ""), "")
+			return
+		}
+		as, err = ai.Annotate(ctx, manifest, patch)
+		switch {
+		case errors.Is(err, annotations.ErrTooMany):
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, ""), "")
+			return
+		case err != nil:
+			apiError(w, err, "could not update annotations")
+			return
+		}
+		zerolog.Ctx(ctx).Debug().
+			Str("component", "httptransport/annotationsHandler").
+			Str("manifest", manifest.String()).
+			Int("count", len(as)).
+			Msg("updated annotations")
+	}
+	if as == nil {
+		as = map[string]string{}
+	}
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	err = json.NewEncoder(w).Encode(&AnnotationsResponse{Annotations: as})
+}
+
+// AnnotationsIndexer finds the annotations.Indexer among the wrapped
+// indexers, if there is one.
+func annotationsIndexer(s interface{}) (*annotations.Indexer, bool) {
+	var i *annotations.Indexer
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		i, ok = s.(*annotations.Indexer)
+		return ok
+	})
+	return i, ok
+}
+
+// Annotator finds an annotations.Annotator among the wrapped indexers, if
+// there is one. Remote indexers implement it themselves.
+func annotator(s interface{}) (annotations.Annotator, bool) {
+	var a annotations.Annotator
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		a, ok = s.(annotations.Annotator)
+		return ok
+	})
+	return a, ok
+}
+
+// ManifestAnnotations returns the manifest's annotations, or nil if it has
+// none or the indexer doesn't record them.
+func manifestAnnotations(ctx context.Context, serv interface{}, manifest claircore.Digest) (map[string]string, error) {
+	a, ok := annotator(serv)
+	if !ok {
+		return nil, nil
+	}
+	return a.Annotations(ctx, manifest)
+}
@@ -0,0 +1,72 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// APIVersionHeader is the request header a client names the API version it
+// was written against with, and the response header the server answers
+// with the version it served.
+//
+// Responses for older versions leave out the fields added since, as
+// described by the OpenAPI document served for that version.
+const APIVersionHeader = "X-Clair-API-Version"
+
+// APIVersions returns the API versions the server speaks, oldest first.
+func APIVersions() []string {
+	return append([]string(nil), _openapiVersions...)
+}
+
+// LatestAPIVersion returns the newest API version the server speaks.
+func LatestAPIVersion() string {
+	return _openapiVersions[len(_openapiVersions)-1]
+}
+
+// OpenAPIPath returns the path the OpenAPI document for the API version is
+// served at.
+func OpenAPIPath(version string) string {
+	return openapiRoot + version
+}
+
+type apiVersionKey struct{}
+
+// APIVersion reports the API version negotiated for the request the Context
+// belongs to, or the latest one if there wasn't a negotiation.
+func apiVersion(ctx context.Context) string {
+	if v, ok := ctx.Value(apiVersionKey{}).(string); ok {
+		return v
+	}
+	return LatestAPIVersion()
+}
+
+// LegacyAPI reports whether the request asked for the original version of
+// the API, which predates every optional report field.
+func legacyAPI(ctx context.Context) bool {
+	return apiVersion(ctx) == _openapiVersions[0]
+}
+
+// WithAPIVersion negotiates the API version of every request.
+//
+// Requests without the APIVersionHeader get the latest version. Requests
+// for a version the server doesn't speak are answered with a 400 listing
+// the ones it does.
+func withAPIVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := strings.TrimSpace(r.Header.Get(APIVersionHeader))
+		if v == "" {
+			v = LatestAPIVersion()
+		}
+		if _, ok := _openapiJSON[v]; !ok {
+			apiError(w, clairerror.New(clairerror.Invalid, "unsupported API version "+v+"; supported versions: "+strings.Join(_openapiVersions, ", ")), "")
+			return
+		}
+		w.Header().Set(APIVersionHeader, v)
+		w.Header().Add("vary", APIVersionHeader)
+		ctx := context.WithValue(r.Context(), apiVersionKey{}, v)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,63 @@
+package httptransport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIVersion(t *testing.T) {
+	var seen string
+	h := withAPIVersion(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = apiVersion(r.Context())
+	}))
+
+	for _, tc := range []struct {
+		Name    string
+		Header  string
+		Status  int
+		Version string
+	}{
+		{
+			Name:    "Missing",
+			Status:  http.StatusOK,
+			Version: LatestAPIVersion(),
+		},
+		{
+			Name:    "Original",
+			Header:  "1",
+			Status:  http.StatusOK,
+			Version: "1",
+		},
+		{
+			Name:    "Latest",
+			Header:  LatestAPIVersion(),
+			Status:  http.StatusOK,
+			Version: LatestAPIVersion(),
+		},
+		{
+			Name:   "Unsupported",
+			Header: "0.9",
+			Status: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			seen = ""
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.Header != "" {
+				r.Header.Set(APIVersionHeader, tc.Header)
+			}
+			h.ServeHTTP(w, r)
+			if got, want := w.Code, tc.Status; got != want {
+				t.Fatalf("got status: %d, want: %d", got, want)
+			}
+			if got, want := seen, tc.Version; got != want {
+				t.Errorf("got version: %q, want: %q", got, want)
+			}
+			if got, want := w.Header().Get(APIVersionHeader), tc.Version; got != want {
+				t.Errorf("got response header: %q, want: %q", got, want)
+			}
+		})
+	}
+}
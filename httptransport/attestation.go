@@ -0,0 +1,47 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/attestation"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// These are the media types a client asks for, via the Accept header, to
+// receive a vulnerability report as an in-toto statement with the cosign
+// "vuln" predicate, either bare or signed in a DSSE envelope.
+const (
+	AttestationType         = attestation.StatementType
+	AttestationEnvelopeType = attestation.EnvelopeType
+)
+
+// SubjectParam is the query parameter naming an attestation's subject,
+// usually the image's repository.
+const subjectParam = "subject"
+
+// WantsAttestation reports whether the request asked for an unsigned
+// attestation.
+func wantsAttestation(r *http.Request) bool {
+	return accepts(r, AttestationType)
+}
+
+// WantsAttestationEnvelope reports whether the request asked for a signed
+// attestation.
+func wantsAttestationEnvelope(r *http.Request) bool {
+	return accepts(r, AttestationEnvelopeType)
+}
+
+// ReportStatement returns an in-toto statement holding the report. The
+// latest update operation is used as the database version, if it can be
+// found.
+func reportStatement(ctx context.Context, r *http.Request, service matcher.Service, manifest claircore.Digest, report interface{}) *attestation.Statement {
+	var db string
+	if ref, err := service.LatestUpdateOperation(ctx); err == nil {
+		db = ref.String()
+	}
+	return attestation.NewStatement(r.URL.Query().Get(subjectParam), manifest, report, db, time.Now())
+}
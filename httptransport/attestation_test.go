@@ -0,0 +1,149 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/attestation"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/signing"
+)
+
+// TestAttestation confirms reports are served as in-toto statements, and
+// that signed envelopes verify with the report signing key.
+func TestAttestation(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := signing.NewSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("d", 64))
+	ref := uuid.New()
+	mock := &matcher.Mock{
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+		LatestUpdateOperation_: func(context.Context) (uuid.UUID, error) {
+			return ref, nil
+		},
+	}
+	b, err := json.Marshal(&claircore.IndexReport{Hash: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := func(accept string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, VulnerabilityReportPath+"?subject=quay.io/example/app", bytes.NewReader(b))
+		r.Header.Set("accept", accept)
+		return r
+	}
+	check := func(t *testing.T, payload []byte) {
+		t.Helper()
+		var st struct {
+			attestation.Statement
+			Predicate struct {
+				Scanner struct {
+					DB     attestation.DB                `json:"db"`
+					Result claircore.VulnerabilityReport `json:"result"`
+				} `json:"scanner"`
+			} `json:"predicate"`
+		}
+		if err := json.Unmarshal(payload, &st); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := st.PredicateType, attestation.PredicateVuln; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := st.Subject[0].Name, "quay.io/example/app"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := st.Subject[0].Digest["sha256"], strings.Repeat("d", 64); got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := st.Predicate.Scanner.DB.Version, ref.String(); got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := st.Predicate.Scanner.Result.Hash.String(), d.String(); got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+
+	t.Run("Statement", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		VulnerabilityReportHandler(mock, &indexer.Mock{})(rr, req(AttestationType))
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		if got, want := rr.Header().Get("content-type"), AttestationType; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		check(t, rr.Body.Bytes())
+	})
+
+	t.Run("Unconfigured", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		VulnerabilityReportHandler(mock, &indexer.Mock{})(rr, req(AttestationEnvelopeType))
+		if got, want := rr.Code, http.StatusNotAcceptable; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+
+	t.Run("Envelope", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		VulnerabilityReportHandler(signing.NewMatcher(mock, s), &indexer.Mock{})(rr, req(AttestationEnvelopeType))
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		var env attestation.Envelope
+		if err := json.NewDecoder(rr.Body).Decode(&env); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := env.PayloadType, AttestationType; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		payload, err := base64.StdEncoding.DecodeString(env.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(sig, &rs); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(attestation.PAE(env.PayloadType, payload))
+		if !ecdsa.Verify(&key.PublicKey, sum[:], rs.R, rs.S) {
+			t.Error("envelope signature doesn't verify")
+		}
+		if got, want := env.Signatures[0].KeyID, "test"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		check(t, payload)
+	})
+}
@@ -0,0 +1,30 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quay/clair/v4/config"
+	"github.com/quay/clair/v4/middleware/audit"
+)
+
+// AuditSink returns the audit.Sink described by the provided Audit
+// configuration.
+func auditSink(ctx context.Context, cfg *config.Audit) (audit.Sink, error) {
+	switch cfg.Name {
+	case "file":
+		if cfg.File.Path == "" {
+			return nil, fmt.Errorf("audit log %q requires a path", cfg.Name)
+		}
+		return audit.NewFileSink(cfg.File.Path)
+	case "syslog":
+		return audit.NewSyslogSink(cfg.Syslog.Network, cfg.Syslog.Address, cfg.Syslog.Tag)
+	case "http":
+		if cfg.HTTP.URL == "" {
+			return nil, fmt.Errorf("audit log %q requires a url", cfg.Name)
+		}
+		return audit.NewHTTPSink(ctx, cfg.HTTP.URL, cfg.HTTP.Headers, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", cfg.Name)
+	}
+}
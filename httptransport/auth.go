@@ -1,8 +1,11 @@
 package httptransport
 
 import (
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/quay/clair/v4/config"
 	"github.com/quay/clair/v4/middleware/auth"
@@ -10,29 +13,96 @@ import (
 
 // AuthHandler returns an http.Handler wrapping the provided Handler, as
 // described by the provided Config.
+//
+// If admin authentication is configured, requests for the admin API are
+// authenticated with it instead.
 func authHandler(cfg *config.Config, next http.Handler) (http.Handler, error) {
+	checks, err := authCheckers(&cfg.Auth, true)
+	if err != nil {
+		return nil, err
+	}
+	api := next
+	if len(checks) != 0 {
+		api = auth.Handler(next, checks...)
+	}
+	if cfg.Auth.Admin == nil {
+		return api, nil
+	}
+	admin, err := AdminAuthHandler(cfg, next)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if adminPath(r.URL.Path) {
+			admin.ServeHTTP(w, r)
+			return
+		}
+		api.ServeHTTP(w, r)
+	}), nil
+}
+
+// AdminAuthHandler returns an http.Handler wrapping the provided Handler with
+// the admin authentication described by the provided Config.
+//
+// Requests are rejected if only mTLS is configured, as that's enforced by
+// the introspection server's listener.
+func AdminAuthHandler(cfg *config.Config, next http.Handler) (http.Handler, error) {
+	a := cfg.Auth.Admin.Auth()
+	checks, err := authCheckers(&a, false)
+	if err != nil {
+		return nil, fmt.Errorf("admin auth: %w", err)
+	}
+	return auth.Handler(next, checks...), nil
+}
+
+// AdminPath reports whether the path is part of the admin API.
+func adminPath(p string) bool {
+	for _, root := range []string{indexerRoot, matcherRoot, notifierRoot} {
+		if strings.HasPrefix(p, root+adminRoot) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthCheckers returns the Checkers for the configured methods. If
+// intraservice is set, tokens minted by other Clair services are accepted
+// as well.
+func authCheckers(a *config.Auth, intraservice bool) ([]auth.Checker, error) {
 	var checks []auth.Checker
 
 	// Keep this ordered "best" to "worst".
 	switch {
-	case cfg.Auth.Keyserver != nil:
-		cfg := cfg.Auth.Keyserver
+	case a.Keyserver != nil:
+		cfg := a.Keyserver
 		ks, err := auth.NewQuayKeyserver(cfg.API)
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize quay keyserver: %v", err)
 		}
 		checks = append(checks, ks)
-		if cfg.Intraservice != nil {
+		if cfg.Intraservice != nil && intraservice {
 			psk, err := auth.NewPSK(cfg.Intraservice, []string{IntraserviceIssuer})
 			if err != nil {
 				return nil, fmt.Errorf("failed to initialize quay keyserver: %w", err)
 			}
-			checks = append(checks, psk)
+			checks = append(checks, auth.Intraservice(psk))
 		}
-	case cfg.Auth.PSK != nil:
-		cfg := cfg.Auth.PSK
+	case a.PSK != nil:
+		cfg := a.PSK
+		if cfg.Intraservice != nil && intraservice {
+			psk, err := auth.NewPSK(cfg.Intraservice, []string{IntraserviceIssuer})
+			if err != nil {
+				return nil, err
+			}
+			checks = append(checks, auth.Intraservice(psk))
+		}
+		// Without a separate key, other services' tokens are still
+		// accepted but can't be told apart from clients' tokens, so they
+		// aren't trusted as such.
 		issuers := make([]string, 0, 1+len(cfg.Issuer))
-		issuers = append(issuers, IntraserviceIssuer)
+		if cfg.Intraservice == nil && intraservice {
+			issuers = append(issuers, IntraserviceIssuer)
+		}
 		issuers = append(issuers, cfg.Issuer...)
 
 		psk, err := auth.NewPSK(cfg.Key, issuers)
@@ -40,9 +110,68 @@ func authHandler(cfg *config.Config, next http.Handler) (http.Handler, error) {
 			return nil, err
 		}
 		checks = append(checks, psk)
-	default:
-		return next, nil
 	}
+	if cfg := a.Workload; cfg != nil {
+		o := auth.WorkloadOpts{
+			JWKS:     cfg.JWKS,
+			Audience: cfg.Audience,
+			Issuers:  cfg.Issuers,
+			Subjects: cfg.Subjects,
+		}
+		if cfg.JWKSCA != "" {
+			b, err := ioutil.ReadFile(cfg.JWKSCA)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize workload auth: %w", err)
+			}
+			o.Roots = x509.NewCertPool()
+			if !o.Roots.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("failed to initialize workload auth: no certificates in %q", cfg.JWKSCA)
+			}
+		}
+		if cfg.JWKSTokenFile != "" {
+			o.Token = auth.NewTokenFile(cfg.JWKSTokenFile)
+		}
+		w, err := auth.NewWorkload(&o)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize workload auth: %w", err)
+		}
+		// Only other services' workload identities are accepted.
+		checks = append(checks, auth.Intraservice(w))
+	}
+	if cfg := a.Introspection; cfg != nil {
+		o := auth.IntrospectionOpts{
+			Endpoint:     cfg.Endpoint,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Audience:     cfg.Audience,
+			Issuers:      cfg.Issuers,
+			CacheTTL:     cfg.CacheTTL,
+		}
+		if cfg.CA != "" {
+			b, err := ioutil.ReadFile(cfg.CA)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize introspection auth: %w", err)
+			}
+			o.Roots = x509.NewCertPool()
+			if !o.Roots.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("failed to initialize introspection auth: no certificates in %q", cfg.CA)
+			}
+		}
+		in, err := auth.NewIntrospection(&o)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize introspection auth: %w", err)
+		}
+		checks = append(checks, in)
+	}
+	return checks, nil
+}
 
-	return auth.Handler(next, checks...), nil
+// Intraservice reports whether the request was authenticated as coming from
+// another Clair service.
+//
+// This is decided by the key or identity the request's token was verified
+// with, never by its claims: clients holding the PSK can mint tokens with
+// any issuer.
+func intraservice(r *http.Request) bool {
+	return auth.FromIntraservice(r)
 }
@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 
 	"github.com/quay/clair/v4/config"
@@ -181,3 +183,130 @@ func TestAuth(t *testing.T) {
 		t.Run(tc.Name, tc.Run)
 	}
 }
+
+// TestAdminAuth confirms the admin API only accepts admin credentials, and
+// the rest of the API only accepts API credentials.
+func TestAdminAuth(t *testing.T) {
+	apiKey, adminKey := []byte("api key for the clair api"), []byte("admin key for clair operators")
+	cfg := config.Config{
+		Auth: config.Auth{
+			PSK: &config.AuthPSK{Key: apiKey, Issuer: []string{"quay"}},
+			Admin: &config.AuthAdmin{
+				PSK: &config.AuthPSK{Key: adminKey, Issuer: []string{"ops"}},
+			},
+		},
+	}
+	h, err := authHandler(&cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := func(key []byte, iss string) string {
+		s, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: key}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tok, err := jwt.Signed(s).Claims(jwt.Claims{
+			Issuer: iss,
+			Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+	api, admin, intra := token(apiKey, "quay"), token(adminKey, "ops"), token(apiKey, IntraserviceIssuer)
+
+	for _, tc := range []struct {
+		path, token string
+		want        int
+	}{
+		{IndexReportAPIPath, api, http.StatusOK},
+		{IndexReportAPIPath, admin, http.StatusUnauthorized},
+		{IndexerGCPath, api, http.StatusUnauthorized},
+		{IndexerGCPath, intra, http.StatusUnauthorized},
+		{IndexerGCPath, admin, http.StatusOK},
+		{DeadLetterPath, admin, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("authorization", "Bearer "+tc.token)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if got := rr.Code; got != tc.want {
+			t.Errorf("%s: got: %d, want: %d", tc.path, got, tc.want)
+		}
+	}
+}
+
+// TestIntraserviceTrust confirms only tokens signed with the intraservice key
+// are trusted as coming from another service, whatever their claims.
+func TestIntraserviceTrust(t *testing.T) {
+	clientKey, intraKey := []byte("client key for the clair api"), []byte("intraservice key for clair")
+	token := func(key []byte, iss string) string {
+		s, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: key}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tok, err := jwt.Signed(s).Claims(jwt.Claims{
+			Issuer: iss,
+			Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+	for _, tc := range []struct {
+		name    string
+		psk     config.AuthPSK
+		token   string
+		status  int
+		trusted bool
+	}{
+		{
+			name:    "Intraservice",
+			psk:     config.AuthPSK{Key: clientKey, Issuer: []string{"quay"}, Intraservice: intraKey},
+			token:   token(intraKey, IntraserviceIssuer),
+			status:  http.StatusOK,
+			trusted: true,
+		},
+		{
+			name:   "Client",
+			psk:    config.AuthPSK{Key: clientKey, Issuer: []string{"quay"}, Intraservice: intraKey},
+			token:  token(clientKey, "quay"),
+			status: http.StatusOK,
+		},
+		{
+			name:   "ClientMinted",
+			psk:    config.AuthPSK{Key: clientKey, Issuer: []string{"quay"}, Intraservice: intraKey},
+			token:  token(clientKey, IntraserviceIssuer),
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "SharedKey",
+			psk:    config.AuthPSK{Key: clientKey, Issuer: []string{"quay"}},
+			token:  token(clientKey, IntraserviceIssuer),
+			status: http.StatusOK,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Config{Auth: config.Auth{PSK: &tc.psk}}
+			var trusted bool
+			h, err := authHandler(&cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				trusted = intraservice(r)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, IndexReportAPIPath, nil)
+			req.Header.Set("authorization", "Bearer "+tc.token)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			if got, want := rr.Code, tc.status; got != want {
+				t.Errorf("status: got: %d, want: %d", got, want)
+			}
+			if got, want := trusted, tc.trusted; got != want {
+				t.Errorf("trusted: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
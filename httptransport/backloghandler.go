@@ -0,0 +1,43 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier/service"
+	"github.com/quay/clair/v4/tenant"
+)
+
+// BacklogHandler reports the notification ids waiting on delivery.
+func BacklogHandler(serv service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Method != http.MethodGet {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
+			return
+		}
+
+		b, err := serv.Backlog(ctx)
+		switch {
+		case err == nil:
+		case errors.Is(err, tenant.ErrForbidden):
+			apiError(w, clairerror.New(clairerror.Forbidden, "tenants may not view the delivery backlog"), "")
+			return
+		default:
+			zerolog.Ctx(ctx).Warn().
+				Str("component", "httptransport/BacklogHandler").
+				Err(err).
+				Msg("could not retrieve backlog")
+			apiError(w, err, "could not retrieve backlog")
+			return
+		}
+
+		w.Header().Set("content-type", "application/json")
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(&b)
+	}
+}
@@ -0,0 +1,57 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/clair/v4/notifier"
+	"github.com/quay/clair/v4/notifier/service"
+	"github.com/quay/clair/v4/tenant"
+)
+
+// TestBacklogHandler confirms the backlog is reported, except to tenants.
+func TestBacklogHandler(t *testing.T) {
+	oldest := time.Now().UTC().Truncate(time.Second)
+	want := notifier.Backlog{Pending: 3, Failed: 1, Oldest: &oldest}
+	var forbid bool
+	h := BacklogHandler(&service.Mock{
+		Backlog_: func(context.Context) (notifier.Backlog, error) {
+			if forbid {
+				return notifier.Backlog{}, tenant.ErrForbidden
+			}
+			return want, nil
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, BacklogAPIPath, nil))
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+	var got notifier.Backlog
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	forbid = true
+	rr = httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, BacklogAPIPath, nil))
+	if got, want := rr.Code, http.StatusForbidden; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+
+	rr = httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodPost, BacklogAPIPath, nil))
+	if got, want := rr.Code, http.StatusMethodNotAllowed; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/quay/claircore"
+	je "github.com/quay/claircore/pkg/jsonerr"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// MaxBatchSize is the most manifests a batch request may name.
+const maxBatchSize = 1000
+
+// BatchConcurrency is the number of reports in a batch generated at once.
+const batchConcurrency = 8
+
+// BatchReportRequest names the manifests to generate vulnerability reports
+// for.
+type BatchReportRequest struct {
+	Manifests []string `json:"manifests"`
+}
+
+// BatchReportResponse holds a result for every manifest in a batch
+// request, in the order they were requested.
+type BatchReportResponse struct {
+	Results []BatchReportResult `json:"results"`
+}
+
+// BatchReportResult is the vulnerability report for a manifest in a batch
+// request, or the error that kept it from being generated.
+type BatchReportResult struct {
+	Manifest string       `json:"manifest_hash"`
+	Report   interface{}  `json:"report,omitempty"`
+	Error    *je.Response `json:"error,omitempty"`
+}
+
+// BatchReportHandler generates vulnerability reports for many manifests in
+// one request, for clients refreshing reports in bulk.
+//
+// Results are written as one JSON object, or as a stream of newline
+// delimited results if the request accepts ReportStreamType. A manifest
+// that fails doesn't fail the request; its result carries the error
+// instead.
+func BatchReportHandler(service matcher.Service, indexer indexer.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Method != http.MethodPost {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows POST"), "")
+			return
+		}
+		if _, err := runtimeOnly(r); err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, ""), "")
+			return
+		}
+		var req BatchReportRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchSize*256)).Decode(&req); err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to deserialize batch request"), "")
+			return
+		}
+		if n := len(req.Manifests); n == 0 || n > maxBatchSize {
+			apiError(w, clairerror.New(clairerror.Invalid, fmt.Sprintf("batch requests must name between 1 and %d manifests", maxBatchSize)), "")
+			return
+		}
+
+		ctx, done := context.WithCancel(ctx)
+		defer done()
+		results := make([]chan BatchReportResult, len(req.Manifests))
+		for i := range results {
+			results[i] = make(chan BatchReportResult, 1)
+		}
+		go func() {
+			sem := make(chan struct{}, batchConcurrency)
+			for i, m := range req.Manifests {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					results[i] <- batchError(m, ctx.Err(), "batch canceled")
+					continue
+				}
+				go func(i int, m string) {
+					defer func() { <-sem }()
+					results[i] <- batchReport(ctx, r, service, indexer, m)
+				}(i, m)
+			}
+		}()
+
+		var err error
+		defer writerError(w, &err)()
+		if wantsReportStream(r) {
+			w.Header().Set("content-type", ReportStreamType)
+			w.WriteHeader(http.StatusOK)
+			f, _ := w.(http.Flusher)
+			enc := json.NewEncoder(w)
+			for _, ch := range results {
+				res := <-ch
+				if err = enc.Encode(&res); err != nil {
+					return
+				}
+				if f != nil {
+					f.Flush()
+				}
+			}
+			return
+		}
+		resp := BatchReportResponse{Results: make([]BatchReportResult, len(results))}
+		for i, ch := range results {
+			resp.Results[i] = <-ch
+		}
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(&resp)
+	}
+}
+
+// BatchReport generates the vulnerability report for one manifest of a
+// batch, as the vulnerability report handler would for a GET.
+func batchReport(ctx context.Context, r *http.Request, service matcher.Service, indexer indexer.Service, m string) BatchReportResult {
+	manifest, err := claircore.ParseDigest(m)
+	if err != nil {
+		return batchError(m, clairerror.Wrap(clairerror.Invalid, err, "malformed manifest"), "")
+	}
+	ir, ok, err := indexer.IndexReport(ctx, manifest)
+	switch {
+	case err != nil:
+		return batchError(m, err, "could not retrieve index report")
+	case !ok:
+		return batchError(m, clairerror.New(clairerror.NotFound, fmt.Sprintf("index report for manifest %q not found", m)), "")
+	}
+	// As for single reports, the base image and annotations are
+	// informational and the scopes are only needed if the client asked for
+	// runtime packages.
+	base, _ := baseImage(ctx, indexer, manifest)
+	as, _ := manifestAnnotations(ctx, indexer, manifest)
+	only, _ := runtimeOnly(r)
+	scopes, err := packageScopes(ctx, indexer, ir)
+	if err != nil && only {
+		return batchError(m, err, "failed to determine package scopes")
+	}
+	vr, err := service.Scan(ctx, ir)
+	if err != nil {
+		return batchError(m, err, "failed to start scan")
+	}
+	if only {
+		vr = withoutDevelopment(vr, scopes)
+	}
+	out, _ := annotateReport(ctx, service, vr, scopes, base, as, endOfLife(indexer, ir.Distributions))
+	return BatchReportResult{Manifest: m, Report: out}
+}
+
+func batchError(m string, err error, msg string) BatchReportResult {
+	return BatchReportResult{
+		Manifest: m,
+		Error:    errorResponse(err, msg),
+	}
+}
@@ -0,0 +1,134 @@
+package httptransport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// TestBatchReport confirms every requested manifest gets a result, in
+// order, whether or not its report could be generated.
+func TestBatchReport(t *testing.T) {
+	found := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	missing := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	broken := claircore.MustParseDigest("sha256:" + strings.Repeat("c", 64))
+	h := BatchReportHandler(
+		&matcher.Mock{
+			LatestUpdateOperation_: func(context.Context) (uuid.UUID, error) {
+				return uuid.Nil, nil
+			},
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return &claircore.VulnerabilityReport{Hash: ir.Hash, Packages: ir.Packages}, nil
+			},
+		},
+		&indexer.Mock{
+			IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+				switch d.String() {
+				case found.String():
+					return &claircore.IndexReport{
+						Hash:     d,
+						Packages: map[string]*claircore.Package{"1": {ID: "1", Name: "busybox"}},
+					}, true, nil
+				case broken.String():
+					return nil, false, errors.New("database on fire")
+				}
+				return nil, false, nil
+			},
+		},
+	)
+	req := BatchReportRequest{
+		Manifests: []string{found.String(), missing.String(), "bogus", broken.String()},
+	}
+	wantCodes := []string{"", "not-found", "bad-request", "internal-server-error"}
+	b, err := json.Marshal(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		Manifest string `json:"manifest_hash"`
+		Report   *struct {
+			Packages map[string]interface{} `json:"packages"`
+		} `json:"report"`
+		Error *struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	check := func(t *testing.T, rs []result) {
+		if got, want := len(rs), len(req.Manifests); got != want {
+			t.Fatalf("got: %d results, want: %d", got, want)
+		}
+		for i, r := range rs {
+			if got, want := r.Manifest, req.Manifests[i]; got != want {
+				t.Errorf("%d: got: %q, want: %q", i, got, want)
+			}
+			switch {
+			case wantCodes[i] == "" && (r.Report == nil || len(r.Report.Packages) != 1):
+				t.Errorf("%d: unexpected report: %+v", i, r.Report)
+			case wantCodes[i] != "" && (r.Error == nil || r.Error.Code != wantCodes[i]):
+				t.Errorf("%d: unexpected error: %+v, want code %q", i, r.Error, wantCodes[i])
+			}
+		}
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodPost, BatchReportAPIPath, bytes.NewReader(b)))
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		var res struct {
+			Results []result `json:"results"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		check(t, res.Results)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, BatchReportAPIPath, bytes.NewReader(b))
+		r.Header.Set("Accept", ReportStreamType)
+		h(rr, r)
+		if got, want := rr.Header().Get("content-type"), ReportStreamType; got != want {
+			t.Fatalf("got: %q, want: %q", got, want)
+		}
+		var rs []result
+		s := bufio.NewScanner(rr.Body)
+		for s.Scan() {
+			var r result
+			if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+				t.Fatal(err)
+			}
+			rs = append(rs, r)
+		}
+		check(t, rs)
+	})
+
+	t.Run("BadRequest", func(t *testing.T) {
+		for _, body := range []string{"{", `{"manifests":[]}`} {
+			rr := httptest.NewRecorder()
+			h(rr, httptest.NewRequest(http.MethodPost, BatchReportAPIPath, strings.NewReader(body)))
+			if got, want := rr.Code, http.StatusBadRequest; got != want {
+				t.Errorf("%q: got: %d, want: %d", body, got, want)
+			}
+		}
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodGet, BatchReportAPIPath, nil))
+		if got, want := rr.Code, http.StatusMethodNotAllowed; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/quay/clair/v4/httptransport"
+)
+
+// PinAPIVersion returns a copy of c that asks for the API version this
+// package was written against, so a newer remote service keeps answering
+// the way it expects.
+func pinAPIVersion(c *http.Client) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	nc := *c
+	nc.Transport = &versionTransport{
+		next:    c.Transport,
+		version: httptransport.LatestAPIVersion(),
+	}
+	return &nc
+}
+
+type versionTransport struct {
+	next    http.RoundTripper
+	version string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *versionTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if r.Header.Get(httptransport.APIVersionHeader) != "" {
+		return next.RoundTrip(r)
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set(httptransport.APIVersionHeader, t.version)
+	return next.RoundTrip(r)
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/quay/clair/v4/middleware/deadline"
+	"github.com/quay/clair/v4/middleware/requestid"
+)
+
+// ForwardDeadline returns a copy of c that passes along the deadline of a
+// request's Context, so the remote service gives up when the caller does.
+func forwardDeadline(c *http.Client) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	nc := *c
+	nc.Transport = deadline.Transport(c.Transport)
+	return &nc
+}
+
+// ForwardRequestID returns a copy of c that passes along the request ID of a
+// request's Context, so the remote service logs it too.
+func forwardRequestID(c *http.Client) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	nc := *c
+	nc.Transport = requestid.Transport(c.Transport)
+	return &nc
+}
@@ -57,6 +57,7 @@ type HTTP struct {
 	c             *http.Client
 	uoCache       *uoCache
 	uoLatestCache *uoCache
+	retry         *RetryOptions
 
 	diffValidator atomic.Value
 }
@@ -87,6 +88,13 @@ func NewHTTP(ctx context.Context, opt ...Option) (*HTTP, error) {
 			return nil, err
 		}
 	}
+	if c.retry != nil {
+		c.c = wrapClient(c.c, c.addr.Host, *c.retry)
+	}
+	c.c = forwardTenant(c.c)
+	c.c = forwardDeadline(c.c)
+	c.c = forwardRequestID(c.c)
+	c.c = pinAPIVersion(c.c)
 	return c, nil
 }
 
@@ -7,15 +7,28 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"strconv"
 
 	"github.com/quay/claircore"
 
 	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/httptransport"
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/annotations"
+	"github.com/quay/clair/v4/indexer/baseimage"
+	"github.com/quay/clair/v4/indexer/events"
+	"github.com/quay/clair/v4/indexer/hook"
+	"github.com/quay/clair/v4/indexer/layers"
 )
 
-var _ indexer.Service = (*HTTP)(nil)
+var (
+	_ indexer.Service       = (*HTTP)(nil)
+	_ events.Source         = (*HTTP)(nil)
+	_ layers.Lister         = (*HTTP)(nil)
+	_ hook.ScopeLister      = (*HTTP)(nil)
+	_ baseimage.Detector    = (*HTTP)(nil)
+	_ annotations.Annotator = (*HTTP)(nil)
+)
 
 func (s *HTTP) AffectedManifests(ctx context.Context, v []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
 	var affected claircore.AffectedManifests
@@ -26,7 +39,7 @@ func (s *HTTP) AffectedManifests(ctx context.Context, v []claircore.Vulnerabilit
 		v,
 	})
 	if err != nil {
-		return nil, &clairerror.ErrBadVulnerabilities{err}
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to encode vulnerabilities")
 	}
 
 	u, err := s.addr.Parse(httptransport.AffectedManifestAPIPath)
@@ -39,12 +52,12 @@ func (s *HTTP) AffectedManifests(ctx context.Context, v []claircore.Vulnerabilit
 	}
 	resp, err := s.c.Do(req)
 	if err != nil {
-		return nil, &clairerror.ErrRequestFail{Code: resp.StatusCode, Status: resp.Status}
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
 	}
 	defer resp.Body.Close()
 	err = json.NewDecoder(resp.Body).Decode(&affected)
 	if err != nil {
-		return nil, &clairerror.ErrBadAffectedManifests{err}
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to decode affected manifests")
 	}
 	return &affected, nil
 }
@@ -59,7 +72,7 @@ func (s *HTTP) Index(ctx context.Context, manifest *claircore.Manifest) (*clairc
 	buf := bytes.NewBuffer([]byte{})
 	err := json.NewEncoder(buf).Encode(manifest)
 	if err != nil {
-		return nil, &clairerror.ErrBadManifest{err}
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to encode manifest")
 	}
 
 	u, err := s.addr.Parse(httptransport.IndexAPIPath)
@@ -72,7 +85,7 @@ func (s *HTTP) Index(ctx context.Context, manifest *claircore.Manifest) (*clairc
 	}
 	resp, err := s.c.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to do request: %v", err)
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
@@ -82,7 +95,7 @@ func (s *HTTP) Index(ctx context.Context, manifest *claircore.Manifest) (*clairc
 	var sr *claircore.IndexReport
 	err = json.NewDecoder(resp.Body).Decode(sr)
 	if err != nil {
-		return nil, &clairerror.ErrBadIndexReport{err}
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to decode response")
 	}
 
 	return sr, nil
@@ -100,25 +113,175 @@ func (s *HTTP) IndexReport(ctx context.Context, manifest claircore.Digest) (*cla
 	}
 	resp, err := s.c.Do(req)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to do request: %v", err)
+		return nil, false, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusNotFound {
 		return nil, false, nil
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, false, &clairerror.ErrIndexReportRetrieval{&clairerror.ErrRequestFail{Code: resp.StatusCode, Status: resp.Status}}
+		return nil, false, &clairerror.ErrRequestFail{Code: resp.StatusCode, Status: resp.Status}
 	}
 
 	ir := &claircore.IndexReport{}
 	err = json.NewDecoder(resp.Body).Decode(ir)
 	if err != nil {
-		return nil, false, &clairerror.ErrBadIndexReport{err}
+		return nil, false, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to decode response")
 	}
 
 	return ir, true, nil
 }
 
+// Layers implements layers.Lister, by asking for the manifest's index report
+// with layer attribution.
+//
+// If the indexer doesn't know the order of the manifest's layers, nil is
+// returned.
+func (s *HTTP) Layers(ctx context.Context, manifest claircore.Digest) ([]claircore.Digest, error) {
+	u, err := s.addr.Parse(path.Join(httptransport.IndexReportAPIPath, manifest.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("accept", httptransport.IndexReportV2Type)
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return []claircore.Digest{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &clairerror.ErrRequestFail{Code: resp.StatusCode, Status: resp.Status}
+	}
+	if ct := resp.Header.Get("content-type"); ct != httptransport.IndexReportV2Type {
+		// An indexer predating layer attribution.
+		return nil, nil
+	}
+
+	var ir struct {
+		Layers []layers.Layer `json:"layers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ir); err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to decode response")
+	}
+	out := make([]claircore.Digest, len(ir.Layers))
+	for i, l := range ir.Layers {
+		if l.Index != i {
+			return nil, nil
+		}
+		out[i] = l.Hash
+	}
+	return out, nil
+}
+
+// Scopes implements hook.ScopeLister, by asking for the report's index
+// report and reading the package scopes the indexer included.
+func (s *HTTP) Scopes(ctx context.Context, ir *claircore.IndexReport) (map[string]string, error) {
+	u, err := s.addr.Parse(path.Join(httptransport.IndexReportAPIPath, ir.Hash.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("accept", "application/json")
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &clairerror.ErrRequestFail{Code: resp.StatusCode, Status: resp.Status}
+	}
+
+	var r struct {
+		Scopes map[string]string `json:"package_scopes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to decode response")
+	}
+	return r.Scopes, nil
+}
+
+// Base implements baseimage.Detector, by asking for the manifest's index
+// report and reading the base image the indexer included.
+//
+// If the indexer doesn't detect base images, nil is returned.
+func (s *HTTP) Base(ctx context.Context, manifest claircore.Digest) (*baseimage.Base, error) {
+	u, err := s.addr.Parse(path.Join(httptransport.IndexReportAPIPath, manifest.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("accept", "application/json")
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &clairerror.ErrRequestFail{Code: resp.StatusCode, Status: resp.Status}
+	}
+
+	var r struct {
+		Base *baseimage.Base `json:"base_image"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to decode response")
+	}
+	return r.Base, nil
+}
+
+// Annotations implements annotations.Annotator by fetching the manifest's
+// annotations from the indexer.
+//
+// If the indexer doesn't record annotations or doesn't know the manifest,
+// nil is returned.
+func (s *HTTP) Annotations(ctx context.Context, manifest claircore.Digest) (map[string]string, error) {
+	u, err := s.addr.Parse(path.Join(httptransport.IndexReportAPIPath, manifest.String(), "annotations"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("accept", "application/json")
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusNotImplemented:
+		return nil, nil
+	default:
+		return nil, &clairerror.ErrRequestFail{Code: resp.StatusCode, Status: resp.Status}
+	}
+
+	var r httptransport.AnnotationsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to decode response")
+	}
+	return r.Annotations, nil
+}
+
 func (s *HTTP) State(ctx context.Context) (string, error) {
 	u, err := s.addr.Parse(httptransport.IndexStateAPIPath)
 	if err != nil {
@@ -130,7 +293,7 @@ func (s *HTTP) State(ctx context.Context) (string, error) {
 	}
 	resp, err := s.c.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to do request: %v", err)
+		return "", clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
 	}
 	defer resp.Body.Close()
 	buf := &bytes.Buffer{}
@@ -139,3 +302,35 @@ func (s *HTTP) State(ctx context.Context) (string, error) {
 	}
 	return buf.String(), nil
 }
+
+// IndexEvents implements events.Source.
+//
+// If the indexer isn't recording events, an ErrRequestFail with a 404 code is
+// returned.
+func (s *HTTP) IndexEvents(ctx context.Context, after int64, limit int) ([]events.Event, error) {
+	u, err := s.addr.Parse(httptransport.IndexEventsAPIPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	v := u.Query()
+	v.Set("after", strconv.FormatInt(after, 10))
+	v.Set("limit", strconv.Itoa(limit))
+	u.RawQuery = v.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &clairerror.ErrRequestFail{Code: resp.StatusCode, Status: resp.Status}
+	}
+	var evs []events.Event
+	if err := json.NewDecoder(resp.Body).Decode(&evs); err != nil {
+		return nil, fmt.Errorf("failed to decode index events: %v", err)
+	}
+	return evs, nil
+}
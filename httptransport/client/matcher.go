@@ -14,6 +14,7 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
 
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/httptransport"
 	"github.com/quay/clair/v4/matcher"
 )
@@ -39,7 +40,7 @@ func (c *HTTP) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.
 
 	resp, err := c.c.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
@@ -88,7 +89,7 @@ func (c *HTTP) DeleteUpdateOperations(ctx context.Context, ref ...uuid.UUID) (in
 				}
 				res, err := c.c.Do(req)
 				if err != nil {
-					errs[i] = err
+					errs[i] = clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
 					return
 				}
 				defer res.Body.Close()
@@ -173,7 +174,7 @@ func (c *HTTP) LatestUpdateOperations(ctx context.Context) (map[string][]driver.
 func (c *HTTP) updateOperations(ctx context.Context, req *http.Request, cache *uoCache) (map[string][]driver.UpdateOperation, error) {
 	res, err := c.c.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
@@ -218,7 +219,7 @@ func (c *HTTP) UpdateDiff(ctx context.Context, prev, cur uuid.UUID) (*driver.Upd
 
 	res, err := c.c.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to do request")
 	}
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
@@ -0,0 +1,302 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// These are the defaults used for zero values in RetryOptions.
+const (
+	DefaultRetries          = 3
+	DefaultBackoff          = 100 * time.Millisecond
+	DefaultMaxBackoff       = 5 * time.Second
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned for requests made while the circuit breaker is
+// open, meaning the remote service has failed repeatedly and isn't being
+// contacted until the cooldown passes.
+var ErrCircuitOpen = errors.New("client: circuit breaker open")
+
+// RetryOptions configures retries and circuit breaking of requests.
+//
+// Zero values select the defaults; negative values disable the feature.
+type RetryOptions struct {
+	// Retries is the number of times a failed request is retried.
+	Retries int
+	// Backoff is the wait before the first retry. It doubles with every
+	// retry, up to MaxBackoff, and is jittered.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout bounds each attempt. A zero Timeout leaves attempts bounded
+	// only by the request's Context.
+	Timeout time.Duration
+	// BreakerThreshold is the number of consecutive failures that opens the
+	// circuit breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before a single
+	// request is let through to test the remote service.
+	BreakerCooldown time.Duration
+}
+
+// WithRetry has the client retry failed requests with exponential backoff,
+// and stop contacting a remote service that keeps failing.
+//
+// Requests are retried on network errors and on 502, 503, and 504
+// responses. All the intra-service requests the client makes are safe to
+// repeat.
+func WithRetry(o RetryOptions) Option {
+	return func(s *HTTP) error {
+		s.retry = &o
+		return nil
+	}
+}
+
+// WrapClient returns a copy of c with its transport wrapped per o.
+func wrapClient(c *http.Client, host string, o RetryOptions) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	nc := *c
+	nc.Transport = newRetryTransport(next, host, o)
+	return &nc
+}
+
+// RetryTransport is an http.RoundTripper implementing RetryOptions.
+type retryTransport struct {
+	next http.RoundTripper
+	opts RetryOptions
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	host     label.KeyValue
+	retries  metric.Int64Counter
+	rejected metric.Int64Counter
+	trips    metric.Int64Counter
+}
+
+func newRetryTransport(next http.RoundTripper, host string, o RetryOptions) *retryTransport {
+	if o.Retries == 0 {
+		o.Retries = DefaultRetries
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+	if o.BreakerThreshold == 0 {
+		o.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if o.BreakerCooldown <= 0 {
+		o.BreakerCooldown = DefaultBreakerCooldown
+	}
+	meter := metric.Must(otel.Meter("clair"))
+	return &retryTransport{
+		next: next,
+		opts: o,
+		host: label.String("host", host),
+		retries: meter.NewInt64Counter(
+			"clair_client_retries_total",
+			metric.WithDescription("number of intra-service requests retried"),
+		),
+		rejected: meter.NewInt64Counter(
+			"clair_client_circuit_rejected_total",
+			metric.WithDescription("number of intra-service requests rejected by an open circuit breaker"),
+		),
+		trips: meter.NewInt64Counter(
+			"clair_client_circuit_trips_total",
+			metric.WithDescription("number of times an intra-service circuit breaker opened"),
+		),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "httptransport/client/retryTransport.RoundTrip").
+		Str("host", r.URL.Host).
+		Str("path", r.URL.Path).
+		Logger()
+	// A request with a body can only be retried if the body can be
+	// recreated.
+	retries := t.opts.Retries
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		retries = 0
+	}
+
+	backoff := t.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		res, failed, err := t.try(ctx, r, attempt)
+		if !failed || attempt >= retries || ctx.Err() != nil {
+			return res, err
+		}
+
+		ev := log.Debug().Int("attempt", attempt+1).Dur("backoff", backoff)
+		if err != nil {
+			ev = ev.Err(err)
+		} else {
+			ev = ev.Int("status", res.StatusCode)
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		ev.Msg("retrying request")
+		t.retries.Add(ctx, 1, t.host)
+
+		// Full jitter: wait a random time up to the backoff.
+		wait := time.Duration(rand.Int63n(int64(backoff))) + 1
+		tm := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			tm.Stop()
+			return nil, ctx.Err()
+		case <-tm.C:
+		}
+		backoff *= 2
+		if backoff > t.opts.MaxBackoff {
+			backoff = t.opts.MaxBackoff
+		}
+	}
+}
+
+// Try makes an attempt at the request if the circuit breaker allows it, and
+// records the outcome. It reports whether the attempt failed in a way worth
+// retrying.
+func (t *retryTransport) try(ctx context.Context, r *http.Request, attempt int) (*http.Response, bool, error) {
+	probe, err := t.allow()
+	if err != nil {
+		t.rejected.Add(ctx, 1, t.host)
+		return nil, false, err
+	}
+	// A probe ending without an outcome, because the request was canceled
+	// or its body couldn't be recreated, must not keep the breaker from
+	// letting the next one through.
+	if probe {
+		defer t.endProbe()
+	}
+	req := r
+	if attempt != 0 && r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, false, err
+		}
+		req = r.Clone(ctx)
+		req.Body = body
+	}
+	res, err := t.attempt(req)
+	failed := err != nil || retryable(res.StatusCode)
+	// A canceled request says nothing about the remote service, so it's
+	// neither a success nor a failure.
+	if ctx.Err() == nil {
+		t.record(ctx, !failed)
+	}
+	return res, failed, err
+}
+
+// Attempt performs a single request, bounded by the per-attempt timeout.
+func (t *retryTransport) attempt(r *http.Request) (*http.Response, error) {
+	if t.opts.Timeout <= 0 {
+		return t.next.RoundTrip(r)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), t.opts.Timeout)
+	res, err := t.next.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The timeout covers reading the body, so only cancel once it's closed.
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func retryable(code int) bool {
+	switch code {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Allow reports whether a request may be attempted, per the circuit breaker,
+// and whether the attempt is the probe of an open breaker. A probe must be
+// ended with endProbe.
+func (t *retryTransport) allow() (bool, error) {
+	if t.opts.BreakerThreshold < 0 {
+		return false, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failures < t.opts.BreakerThreshold {
+		return false, nil
+	}
+	// Open: after the cooldown, let a single request through.
+	if t.probing || time.Since(t.openedAt) < t.opts.BreakerCooldown {
+		return false, ErrCircuitOpen
+	}
+	t.probing = true
+	return true, nil
+}
+
+// EndProbe lets another probe through once the cooldown allows, whether or
+// not the last one recorded an outcome.
+func (t *retryTransport) endProbe() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.probing = false
+}
+
+// Record updates the circuit breaker with the outcome of an attempt.
+func (t *retryTransport) record(ctx context.Context, ok bool) {
+	if t.opts.BreakerThreshold < 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	wasProbing := t.probing
+	t.probing = false
+	if ok {
+		t.failures = 0
+		return
+	}
+	t.failures++
+	if t.failures == t.opts.BreakerThreshold || (wasProbing && t.failures > t.opts.BreakerThreshold) {
+		t.openedAt = time.Now()
+		t.trips.Add(ctx, 1, t.host)
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "httptransport/client/retryTransport.record").
+			Str("host", t.host.Value.AsString()).
+			Int("failures", t.failures).
+			Str("cooldown", t.opts.BreakerCooldown.String()).
+			Msg("circuit breaker opened")
+	}
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Flaky returns a server failing the first n requests with a 503, and a
+// counter of requests seen.
+func flaky(t *testing.T, n int32) (*httptest.Server, *int32) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := atomic.AddInt32(&count, 1)
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodPost && string(b) != "body" {
+			t.Errorf("request %d: got body %q", c, b)
+		}
+		if c <= n {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	return srv, &count
+}
+
+func TestRetry(t *testing.T) {
+	srv, count := flaky(t, 2)
+	defer srv.Close()
+	c := wrapClient(srv.Client(), "test", RetryOptions{
+		Retries:          3,
+		Backoff:          time.Millisecond,
+		BreakerThreshold: -1,
+	})
+
+	res, err := c.Post(srv.URL, "text/plain", bytes.NewReader([]byte("body")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if got, want := atomic.LoadInt32(count), int32(3); got != want {
+		t.Errorf("got: %d requests, want: %d", got, want)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	srv, count := flaky(t, 10)
+	defer srv.Close()
+	c := wrapClient(srv.Client(), "test", RetryOptions{
+		Retries:          2,
+		Backoff:          time.Millisecond,
+		BreakerThreshold: -1,
+	})
+
+	res, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusServiceUnavailable; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if got, want := atomic.LoadInt32(count), int32(3); got != want {
+		t.Errorf("got: %d requests, want: %d", got, want)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	srv, count := flaky(t, 3)
+	defer srv.Close()
+	c := wrapClient(srv.Client(), "test", RetryOptions{
+		Retries:          -1,
+		BreakerThreshold: 3,
+		BreakerCooldown:  50 * time.Millisecond,
+	})
+
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	// The breaker is open, so the server isn't contacted.
+	if _, err := c.Get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("got: %v, want: %v", err, ErrCircuitOpen)
+	}
+	if got, want := atomic.LoadInt32(count), int32(3); got != want {
+		t.Errorf("got: %d requests, want: %d", got, want)
+	}
+
+	// After the cooldown, a probe is let through and closes the breaker.
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	}
+}
+
+func TestCircuitBreakerCanceledProbe(t *testing.T) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch c := atomic.AddInt32(&count, 1); {
+		case c <= 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case c == 4:
+			// The probe hangs until it's canceled.
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+	c := wrapClient(srv.Client(), "test", RetryOptions{
+		Retries:          -1,
+		BreakerThreshold: 3,
+		BreakerCooldown:  50 * time.Millisecond,
+	})
+
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	time.Sleep(60 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// The canceled probe is neither a success nor a failure, and the next
+	// request is let through as a probe.
+	res, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+	c := wrapClient(srv.Client(), "test", RetryOptions{
+		Retries:          1,
+		Backoff:          time.Millisecond,
+		Timeout:          10 * time.Millisecond,
+		BreakerThreshold: -1,
+	})
+	start := time.Now()
+	if _, err := c.Get(srv.URL); err == nil {
+		t.Error("expected error")
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("attempts not bounded by timeout: took %v", d)
+	}
+}
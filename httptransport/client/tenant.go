@@ -0,0 +1,39 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/quay/clair/v4/tenant"
+)
+
+// ForwardTenant returns a copy of c that passes along the tenant of a
+// request's Context, so the remote service scopes the request the same way.
+func forwardTenant(c *http.Client) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	nc := *c
+	nc.Transport = &tenantTransport{next: next}
+	return &nc
+}
+
+// TenantTransport sets the tenant header on requests made on behalf of a
+// tenant.
+type tenantTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tenantTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	id, ok := tenant.FromContext(r.Context())
+	if !ok {
+		return t.next.RoundTrip(r)
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set(tenant.Header, id)
+	return t.next.RoundTrip(r)
+}
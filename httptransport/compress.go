@@ -0,0 +1,20 @@
+package httptransport
+
+import (
+	"net/http"
+
+	"github.com/quay/clair/v4/middleware/compress"
+)
+
+// Compress wraps the handler with response compression, unless it's been
+// disabled in the configuration.
+func (t *Server) compress(h http.Handler) http.Handler {
+	c := t.conf.Compression
+	if c.Disable {
+		return h
+	}
+	return compress.WithOptions(h, compress.Options{
+		MinSize: c.MinSize,
+		Level:   c.Level,
+	})
+}
@@ -0,0 +1,65 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+	"github.com/quay/clair/v4/notifier/service"
+	"github.com/quay/clair/v4/tenant"
+)
+
+// DeliveriesResponse reports each deliverer's attempts at delivering a
+// notification id.
+type DeliveriesResponse struct {
+	NotificationID uuid.UUID                 `json:"notification_id"`
+	Deliveries     []notifier.DeliveryStatus `json:"deliveries"`
+}
+
+// DeliveriesHandler reports the delivery attempts for the notification id
+// named by the "notification_id" query parameter.
+func DeliveriesHandler(serv service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Method != http.MethodGet {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
+			return
+		}
+		id, err := uuid.Parse(r.URL.Query().Get("notification_id"))
+		if err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "could not parse notification id"), "")
+			return
+		}
+
+		ds, err := serv.Deliveries(ctx, id)
+		switch {
+		case err == nil:
+		case errors.Is(err, clairerror.NotFound):
+			apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("notification id %s not found", id)), "")
+			return
+		case errors.Is(err, tenant.ErrForbidden):
+			apiError(w, clairerror.New(clairerror.Forbidden, "tenants may not view deliveries"), "")
+			return
+		default:
+			zerolog.Ctx(ctx).Warn().
+				Str("component", "httptransport/DeliveriesHandler").
+				Err(err).
+				Msg("could not retrieve deliveries")
+			apiError(w, err, "could not retrieve deliveries")
+			return
+		}
+
+		w.Header().Set("content-type", "application/json")
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(&DeliveriesResponse{
+			NotificationID: id,
+			Deliveries:     ds,
+		})
+	}
+}
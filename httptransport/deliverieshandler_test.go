@@ -0,0 +1,78 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+	"github.com/quay/clair/v4/notifier/service"
+)
+
+// TestDeliveriesHandler confirms delivery attempts are reported for known
+// notification ids.
+func TestDeliveriesHandler(t *testing.T) {
+	known := uuid.New()
+	ts := time.Now().UTC().Truncate(time.Second)
+	next := ts.Add(5 * time.Second)
+	ds := []notifier.DeliveryStatus{{
+		Deliverer: "webhook",
+		Target:    "https://example.com/callback",
+		Attempts: []notifier.Attempt{{
+			NotificationID: known,
+			Deliverer:      "webhook",
+			Target:         "https://example.com/callback",
+			TS:             ts,
+			Status:         notifier.AttemptFailed,
+			ResponseCode:   http.StatusBadGateway,
+			Error:          "code: 502 status 502 Bad Gateway",
+		}},
+		NextAttempt: &next,
+	}}
+	h := DeliveriesHandler(&service.Mock{
+		Deliveries_: func(_ context.Context, id uuid.UUID) ([]notifier.DeliveryStatus, error) {
+			if id != known {
+				return nil, clairerror.New(clairerror.NotFound, "no receipt exists for notification id "+id.String())
+			}
+			return ds, nil
+		},
+	})
+
+	for _, tc := range []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{name: "Known", query: "?notification_id=" + known.String(), code: http.StatusOK},
+		{name: "Unknown", query: "?notification_id=" + uuid.New().String(), code: http.StatusNotFound},
+		{name: "Missing", code: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h(rr, httptest.NewRequest(http.MethodGet, DeliveriesAPIPath+tc.query, nil))
+			if got, want := rr.Code, tc.code; got != want {
+				t.Fatalf("got: %d, want: %d", got, want)
+			}
+			if tc.code != http.StatusOK {
+				return
+			}
+			var res DeliveriesResponse
+			if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := res.NotificationID, known; got != want {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+			if !cmp.Equal(res.Deliveries, ds) {
+				t.Error(cmp.Diff(res.Deliveries, ds))
+			}
+		})
+	}
+}
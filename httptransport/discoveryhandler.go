@@ -4,7 +4,7 @@ import (
 	"io"
 	"net/http"
 
-	je "github.com/quay/claircore/pkg/jsonerr"
+	clairerror "github.com/quay/clair/v4/clair-error"
 )
 
 //go:generate go run openapigen.go
@@ -16,15 +16,18 @@ var okCT = map[string]string{
 	"*/*":                              "application/vnd.oai.openapi+json",
 }
 
-// DiscoveryHandler serves the embedded OpenAPI spec.
-func DiscoveryHandler() http.Handler {
+// DiscoveryHandler serves the embedded OpenAPI spec for the API version.
+//
+// It panics if the version isn't one of APIVersions.
+func DiscoveryHandler(version string) http.Handler {
+	doc, ok := _openapiJSON[version]
+	if !ok {
+		panic("programmer error: no OpenAPI document for version " + version)
+	}
+	etag := _openapiJSONEtag[version]
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			resp := &je.Response{
-				Code:    "method-not-allowed",
-				Message: "endpoint only allows GET",
-			}
-			je.Error(w, resp, http.StatusMethodNotAllowed)
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
 			return
 		}
 		w.Header().Set("content-type", okCT["*/*"])
@@ -40,17 +43,13 @@ func DiscoveryHandler() http.Handler {
 				}
 			}
 			if bail {
-				resp := &je.Response{
-					Code:    "unknown accept type",
-					Message: "endpoint only allows application/json or application/vnd.oai.openapi+json",
-				}
-				je.Error(w, resp, http.StatusBadRequest)
+				apiError(w, clairerror.New(clairerror.Invalid, "endpoint only allows application/json or application/vnd.oai.openapi+json"), "")
 				return
 			}
 		}
-		w.Header().Set("etag", _openapiJSONEtag)
+		w.Header().Set("etag", etag)
 		var err error
 		defer writerError(w, &err)()
-		_, err = io.WriteString(w, _openapiJSON)
+		_, err = io.WriteString(w, doc)
 	})
 }
@@ -2,7 +2,18 @@
 
 package httptransport
 
-const (
-	_openapiJSON     = `{"components":{"examples":{"Distribution":{"value":{"arch":"","cpe":"","did":"ubuntu","id":"1","name":"Ubuntu","pretty_name":"Ubuntu 18.04.3 LTS","version":"18.04.3 LTS (Bionic Beaver)","version_code_name":"bionic","version_id":"18.04"}},"Environment":{"value":{"distribution_id":"1","introduced_in":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","package_db":"var/lib/dpkg/status"}},"Package":{"value":{"arch":"x86","cpe":"","id":"10","kind":"binary","module":"","name":"libapt-pkg5.0","normalized_version":"","source":{"id":"9","kind":"source","name":"apt","source":null,"version":"1.6.11"},"version":"1.6.11"}},"VulnSummary":{"value":{"description":"In the GNU C Library (aka glibc or libc6) before 2.28,\nparse_reg_exp in posix/regcomp.c misparses alternatives,\nwhich allows attackers to cause a denial of service (assertion\nfailure and application exit) or trigger an incorrect result\nby attempting a regular-expression match.\"\n","dist":{"arch":"","cpe":"","did":"ubuntu","id":"0","name":"Ubuntu","pretty_name":"","version":"18.04.3 LTS (Bionic Beaver)","version_code_name":"bionic","version_id":"18.04"},"fixed_in_version":"v0.0.1","links":"http://link-to-advisory","name":"CVE-2009-5155","normalized_severity":"Low","package":{"id":"0","kind":"","name":"glibc","package_db":"","repository_hint":"","source":null,"version":""},"repo":{"id":"0","key":"","name":"Ubuntu 18.04.3 LTS","uri":""}}},"Vulnerability":{"value":{"description":"In the GNU C Library (aka glibc or libc6) before 2.28,\nparse_reg_exp in posix/regcomp.c misparses alternatives,\nwhich allows attackers to cause a denial of service (assertion\nfailure and application exit) or trigger an incorrect result\nby attempting a regular-expression match.\"\n","dist":{"arch":"","cpe":"","did":"ubuntu","id":"0","name":"Ubuntu","pretty_name":"","version":"18.04.3 LTS (Bionic Beaver)","version_code_name":"bionic","version_id":"18.04"},"fixed_in_version":"2.28-0ubuntu1","id":"356835","issued":"2019-10-12T07:20:50.52Z","links":"https://cve.mitre.org/cgi-bin/cvename.cgi?name=CVE-2009-5155\nhttp://people.canonical.com/~ubuntu-security/cve/2009/CVE-2009-5155.html\nhttps://sourceware.org/bugzilla/show_bug.cgi?id=11053\nhttps://debbugs.gnu.org/cgi/bugreport.cgi?bug=22793\nhttps://debbugs.gnu.org/cgi/bugreport.cgi?bug=32806\nhttps://debbugs.gnu.org/cgi/bugreport.cgi?bug=34238\nhttps://sourceware.org/bugzilla/show_bug.cgi?id=18986\"\n","name":"CVE-2009-5155","normalized_severity":"Low","package":{"id":"0","kind":"","name":"glibc","package_db":"","repository_hint":"","source":null,"version":""},"repo":{"id":"0","key":"","name":"Ubuntu 18.04.3 LTS","uri":""},"severity":"Low","updater":""}}},"responses":{"BadRequest":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Bad Request"},"InternalServerError":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Internal Server Error"},"MethodNotAllowed":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Method Not Allowed"},"NotFound":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Not Found"}},"schemas":{"Callback":{"description":"A callback for clients to retrieve notifications","properties":{"callback":{"description":"the url where notifications can be retrieved","example":"http://clair-notifier/notifier/api/v1/notifications/269886f3-0146-4f08-9bf7-cb1138d48643","type":"string"},"notification_id":{"description":"the unique identifier for this set of notifications","example":"269886f3-0146-4f08-9bf7-cb1138d48643","type":"string"}},"title":"Callback","type":"object"},"Digest":{"description":"A digest string with prefixed algorithm. The format is described here:\nhttps://github.com/opencontainers/image-spec/blob/master/descriptor.md#digests\n\nDigests are used throughout the API to identify Layers and Manifests.\n","example":"sha256:fc84b5febd328eccaa913807716887b3eb5ed08bc22cc6933a9ebf82766725e3","title":"Digest","type":"string"},"Distribution":{"description":"An indexed distribution discovered in a layer. See\nhttps://www.freedesktop.org/software/systemd/man/os-release.html\nfor explanations and example of fields.\n","example":{"$ref":"#/components/examples/Distribution/value"},"properties":{"arch":{"type":"string"},"cpe":{"type":"string"},"did":{"type":"string"},"id":{"description":"A unique ID representing this distribution","type":"string"},"name":{"type":"string"},"pretty_name":{"type":"string"},"version":{"type":"string"},"version_code_name":{"type":"string"},"version_id":{"type":"string"}},"required":["id","did","name","version","version_code_name","version_id","arch","cpe","pretty_name"],"title":"Distribution","type":"object"},"Environment":{"description":"The environment a particular package was discovered in.","properties":{"distribution_id":{"description":"The distribution ID found in an associated IndexReport or\nVulnerabilityReport.\n","example":"1","type":"string"},"introduced_in":{"$ref":"#/components/schemas/Digest"},"package_db":{"description":"The filesystem path or unique identifier of a package database.\n","example":"var/lib/dpkg/status","type":"string"}},"required":["package_db","introduced_in","distribution_id"],"title":"Environment","type":"object"},"Error":{"description":"A general error schema returned when status is not 200 OK","properties":{"code":{"description":"a code for this particular error","type":"string"},"message":{"description":"a message with further detail","type":"string"}},"title":"Error","type":"object"},"IndexReport":{"description":"A report of the Index process for a particular manifest. A\nclient's usage of this is largely information. Clair uses this\nreport for matching Vulnerabilities.\n","properties":{"distributions":{"additionalProperties":{"$ref":"#/components/schemas/Distribution"},"description":"A map of Distribution objects keyed by their Distribution.id\ndiscovered in the manifest.\n","example":{"1":{"$ref":"#/components/examples/Distribution/value"}},"type":"object"},"environments":{"additionalProperties":{"items":{"$ref":"#/components/schemas/Environment"},"type":"array"},"description":"A map of lists containing Environment objects keyed by the\nassociated Package.id.\n","example":{"10":[{"distribution_id":"1","introduced_in":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","package_db":"var/lib/dpkg/status"}]},"type":"object"},"err":{"description":"An error message on event of unsuccessful index","example":"","type":"string"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"packages":{"additionalProperties":{"$ref":"#/components/schemas/Package"},"description":"A map of Package objects indexed by Package.id","example":{"10":{"$ref":"#/components/examples/Package/value"}},"type":"object"},"state":{"description":"The current state of the index operation","example":"IndexFinished","type":"string"},"success":{"description":"A bool indicating succcessful index","example":true,"type":"boolean"}},"required":["manifest_hash","state","packages","distributions","environments","success","err"],"title":"IndexReport","type":"object"},"Layer":{"description":"A Layer within a Manifest and where Clair may retrieve it.","properties":{"hash":{"$ref":"#/components/schemas/Digest"},"headers":{"additionalProperties":{"items":{"type":"string"},"type":"array"},"description":"map of arrays of header values keyed by header\nvalue. e.g. map[string][]string\n","type":"object"},"uri":{"description":"A URI describing where the layer may be found. Implementations\nMUST support http(s) schemes and MAY support additional\nschemes.\n","example":"https://storage.example.com/blob/2f077db56abccc19f16f140f629ae98e904b4b7d563957a7fc319bd11b82ba36\n","type":"string"}},"required":["hash","uri","headers"],"title":"Layer","type":"object"},"Manifest":{"description":"A Manifest representing a container. The 'layers' array must\npreserve the original container's layer order for accurate usage.\n","properties":{"hash":{"$ref":"#/components/schemas/Digest"},"layers":{"items":{"$ref":"#/components/schemas/Layer"},"type":"array"}},"required":["hash","layers"],"title":"Manifest","type":"object"},"Notification":{"description":"A notification expressing a change in a manifest affected by a\nvulnerability.\n","properties":{"id":{"description":"a unique identifier for this notification","example":"5e4b387e-88d3-4364-86fd-063447a6fad2","type":"string"},"manifest":{"description":"The hash of the manifest affected by the provided vulnerability.\n","example":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","type":"string"},"reason":{"description":"the reason for the notifcation, [added | removed]","example":"added","type":"string"},"vulnerability":{"$ref":"#/components/schemas/VulnSummary"}},"title":"Notification","type":"object"},"Package":{"description":"A package discovered by indexing a Manifest","example":{"$ref":"#/components/examples/Package/value"},"properties":{"arch":{"description":"The package's target system architecture","type":"string"},"cpe":{"description":"A CPE identifying the package","type":"string"},"id":{"description":"A unique ID representing this package","type":"string"},"kind":{"description":"Kind of package. Source | Binary","type":"string"},"module":{"description":"A module further defining a namespace for a package","type":"string"},"name":{"description":"Name of the Package","type":"string"},"normalized_version":{"$ref":"#/components/schemas/Version"},"source":{"$ref":"#/components/schemas/SourcePackage"},"version":{"description":"Version of the Package","type":"string"}},"required":["id","name","version"],"title":"Package","type":"object"},"Page":{"description":"A page object indicating to the client how to retrieve multiple pages of\na particular entity.\n","properties":{"next":{"description":"The next id to submit to the api to continue paging","example":"1b4d0db2-e757-4150-bbbb-543658144205","type":"string"},"size":{"description":"The maximum number of elements in a page","example":1,"type":"int"}},"title":"Page"},"PagedNotifications":{"description":"A page object followed by a list of notifications","properties":{"notifications":{"description":"A list of notifications within this page","items":{"$ref":"#/components/schemas/Notification"},"type":"array"},"page":{"description":"A page object informing the client the next page to retrieve.\nIf page.next becomes \"-1\" the client should stop paging.\n","example":{"next":"1b4d0db2-e757-4150-bbbb-543658144205","size":100},"type":"object"}},"title":"PagedNotifications","type":"object"},"Repository":{"description":"A package repository","properties":{"cpe":{"type":"string"},"id":{"type":"string"},"key":{"type":"string"},"name":{"type":"string"},"uri":{"type":"string"}},"title":"Repository","type":"object"},"SourcePackage":{"description":"A source package affiliated with a Package","example":{"$ref":"#/components/examples/Package/value"},"properties":{"arch":{"type":"string"},"cpe":{"description":"A CPE identifying the package","type":"string"},"id":{"description":"A unique ID representing this package","type":"string"},"kind":{"description":"Kind of package. Source | Binary","type":"string"},"module":{"type":"string"},"name":{"description":"Name of the Package","type":"string"},"normalized_version":{"$ref":"#/components/schemas/Version"},"source":{"type":"string"},"version":{"description":"Version of the Package","type":"string"}},"required":["id","name","version"],"title":"SourcePackage","type":"object"},"State":{"description":"an opaque identifier","example":{"state":"aae368a064d7c5a433d0bf2c4f5554cc"},"properties":{"state":{"description":"an opaque identifier","type":"string"}},"required":["state"],"title":"State","type":"object"},"Version":{"description":"Version is a normalized claircore version, composed of a \"kind\" and an\narray of integers such that two versions of the same kind have the\ncorrect ordering when the integers are compared pair-wise.\n","example":"pep440:0.0.0.0.0.0.0.0.0","title":"Version","type":"string"},"VulnSummary":{"description":"A summary of a vulnerability","properties":{"description":{"description":"the vulnerability name","example":"In the GNU C Library (aka glibc or libc6) before 2.28,\nparse_reg_exp in posix/regcomp.c misparses alternatives,\nwhich allows attackers to cause a denial of service (assertion\nfailure and application exit) or trigger an incorrect result\nby attempting a regular-expression match.\"\n","type":"string"},"distribution":{"$ref":"#/components/schemas/Distribution"},"fixed_in_version":{"description":"The version which the vulnerability is fixed in. Empty if not fixed.\n","example":"v0.0.1","type":"string"},"links":{"description":"links to external information about vulnerability","example":"http://link-to-advisory","type":"string"},"name":{"description":"the vulnerability name","example":"CVE-2009-5155","type":"string"},"normalized_severity":{"description":"A well defined set of severity strings guaranteed to be present.\n","enum":["Unknown","Negligible","Low","Medium","High","Critical"],"type":"string"},"package":{"$ref":"#/components/schemas/Package"},"repository":{"$ref":"#/components/schemas/Repository"}},"title":"VulnSummary","type":"object"},"Vulnerability":{"description":"A unique vulnerability indexed by Clair","example":{"$ref":"#/components/examples/Vulnerability/value"},"properties":{"description":{"description":"A description of this specific vulnerability.","type":"string"},"distribution":{"$ref":"#/components/schemas/Distribution"},"fixed_in_version":{"description":"A unique ID representing this vulnerability.","type":"string"},"id":{"description":"A unique ID representing this vulnerability.","type":"string"},"issued":{"description":"The timestamp in which the vulnerability was issued\n","type":"string"},"links":{"description":"A space separate list of links to any external information.\n","type":"string"},"name":{"description":"Name of this specific vulnerability.","type":"string"},"normalized_severity":{"description":"A well defined set of severity strings guaranteed to be present.\n","enum":["Unknown","Negligible","Low","Medium","High","Critical"],"type":"string"},"package":{"$ref":"#/components/schemas/Package"},"range":{"description":"The range of package versions affected by this vulnerability.\n","type":"string"},"repository":{"$ref":"#/components/schemas/Repository"},"severity":{"description":"A severity keyword taken verbatim from the vulnerability source.\n","type":"string"},"updater":{"description":"A unique ID representing this vulnerability.","type":"string"}},"required":["id","updater","name","description","links","severity","normalized_severity","fixed_in_version"],"title":"Vulnerability","type":"object"},"VulnerabilityReport":{"description":"A report expressing discovered packages, package environments,\nand package vulnerabilities within a Manifest.\n","properties":{"distributions":{"additionalProperties":{"$ref":"#/components/schemas/Distribution"},"description":"A map of Distribution objects indexed by Distribution.id.\n","example":{"1":{"$ref":"#/components/examples/Distribution/value"}},"type":"object"},"environments":{"additionalProperties":{"items":{"$ref":"#/components/schemas/Environment"},"type":"array"},"description":"A mapping of Environment lists indexed by Package.id","example":{"10":[{"distribution_id":"1","introduced_in":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","package_db":"var/lib/dpkg/status"}]},"type":"object"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"package_vulnerabilities":{"additionalProperties":{"items":{"type":"string"},"type":"array"},"description":"A mapping of Vulnerability.id lists indexed by Package.id.\n","example":{"10":["356835"]}},"packages":{"additionalProperties":{"$ref":"#/components/schemas/Package"},"description":"A map of Package objects indexed by Package.id","example":{"10":{"$ref":"#/components/examples/Package/value"}},"type":"object"},"vulnerabilities":{"additionalProperties":{"$ref":"#/components/schemas/Vulnerability"},"description":"A map of Vulnerabilities indexed by Vulnerability.id","example":{"356835":{"$ref":"#/components/examples/Vulnerability/value"}},"type":"object"}},"required":["manifest_hash","packages","distributions","environments","vulnerabilities","package_vulnerabilities"],"title":"VulnerabilityReport","type":"object"}}},"info":{"contact":{"email":"quay-devel@redhat.com","name":"Clair Team","url":"http://github.com/quay/clair"},"description":"ClairV4 is a set of cooperating microservices which scan, index, and\nmatch your container's content with known vulnerabilities.\n","license":{"name":"Apache License 2.0","url":"http://www.apache.org/licenses/"},"termsOfService":"","title":"ClairV4","version":"0.1"},"openapi":"3.0.2","paths":{"indexer/api/v1/index_report":{"post":{"description":"By submitting a Manifest object to this endpoint Clair will fetch the\nlayers, scan each layer's contents, and provide an index of discovered\npackages, repository and distribution information.\n","operationId":"Index","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Manifest"}}},"required":true},"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexReport"}}},"description":"IndexReport Created"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Index the contents of a Manifest","tags":["Indexer"]}},"indexer/api/v1/index_report/{manifest_hash}":{"get":{"description":"Given a Manifest's content addressable hash an IndexReport will\nbe retrieved if exists.\n","operationId":"GetIndexReport","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexReport"}}},"description":"IndexReport retrieved"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve an IndexReport for the given Manifest hash if exists.","tags":["Indexer"]}},"indexer/api/v1/index_state":{"get":{"description":"The index state endpoint returns a json structure indicating the\nindexer's internal configuration state.\n\nA client may be interested in this as a signal that manifests may need\nto be re-indexed.\n","operationId":"IndexState","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/State"}}},"description":"Indexer State","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"304":{"description":"Indexer State Unchanged"}},"summary":"Report the indexer's internal configuration and state.","tags":["Indexer"]}},"matcher/api/v1/vulnerability_report/{manifest_hash}":{"get":{"description":"Given a Manifest's content addressable hash a VulnerabilityReport\nwill be created. The Manifest **must** have been Indexed first\nvia the Index endpoint.\n","operationId":"GetVulnerabilityReport","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}}},"description":"VulnerabilityReport Created"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve a VulnerabilityReport for a given manifest's content\naddressable hash.\n","tags":["Matcher"]}},"notifier/api/v1/notification/{notification_id}":{"delete":{"description":"Issues a delete of the provided notification id and all associated\nnotifications. After this delete clients will no longer be able to\nretrieve notifications.\n","operationId":"DeleteNotification","parameters":[{"description":"A notification ID returned by a callback","in":"path","name":"notification_id","schema":{"type":"string"}}],"responses":{"200":{"description":"OK"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"tags":["Notifier"]},"get":{"description":"By performing a GET with a notification_id as a path parameter, the\nclient will retrieve a paginated response of notification objects.\n","operationId":"GetNotification","parameters":[{"description":"A notification ID returned by a callback","in":"path","name":"notification_id","schema":{"type":"string"}},{"description":"The maximum number of notifications to deliver in a single page.\n","in":"query","name":"page_size","schema":{"type":"int"}},{"description":"The next page to fetch via id. Typically this number is provided\non initial response in the page.next field.\nThe first GET request may omit this field.\n","in":"query","name":"next","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PagedNotifications"}}},"description":"A paginated list of notifications"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve a paginated result of notifications for the provided id.","tags":["Notifier"]}}}}`
-	_openapiJSONEtag = `"907188ab3ca63662ad76b1d9a2ff6d01c38d3d90a17f65f60b4e0b64de1b0196"`
+// _openapiVersions are the embedded API versions, oldest first.
+var _openapiVersions = []string{"1", "1.1"}
+
+// _openapiJSON and _openapiJSONEtag are the embedded documents and their
+// validators, keyed by API version.
+var (
+	_openapiJSON = map[string]string{
+		"1":   `{"components":{"examples":{"Distribution":{"value":{"arch":"","cpe":"","did":"ubuntu","id":"1","name":"Ubuntu","pretty_name":"Ubuntu 18.04.3 LTS","version":"18.04.3 LTS (Bionic Beaver)","version_code_name":"bionic","version_id":"18.04"}},"Environment":{"value":{"distribution_id":"1","introduced_in":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","package_db":"var/lib/dpkg/status"}},"Package":{"value":{"arch":"x86","cpe":"","id":"10","kind":"binary","module":"","name":"libapt-pkg5.0","normalized_version":"","source":{"id":"9","kind":"source","name":"apt","source":null,"version":"1.6.11"},"version":"1.6.11"}},"VulnSummary":{"value":{"description":"In the GNU C Library (aka glibc or libc6) before 2.28,\nparse_reg_exp in posix/regcomp.c misparses alternatives,\nwhich allows attackers to cause a denial of service (assertion\nfailure and application exit) or trigger an incorrect result\nby attempting a regular-expression match.\"\n","dist":{"arch":"","cpe":"","did":"ubuntu","id":"0","name":"Ubuntu","pretty_name":"","version":"18.04.3 LTS (Bionic Beaver)","version_code_name":"bionic","version_id":"18.04"},"fixed_in_version":"v0.0.1","links":"http://link-to-advisory","name":"CVE-2009-5155","normalized_severity":"Low","package":{"id":"0","kind":"","name":"glibc","package_db":"","repository_hint":"","source":null,"version":""},"repo":{"id":"0","key":"","name":"Ubuntu 18.04.3 LTS","uri":""}}},"Vulnerability":{"value":{"description":"In the GNU C Library (aka glibc or libc6) before 2.28,\nparse_reg_exp in posix/regcomp.c misparses alternatives,\nwhich allows attackers to cause a denial of service (assertion\nfailure and application exit) or trigger an incorrect result\nby attempting a regular-expression match.\"\n","dist":{"arch":"","cpe":"","did":"ubuntu","id":"0","name":"Ubuntu","pretty_name":"","version":"18.04.3 LTS (Bionic Beaver)","version_code_name":"bionic","version_id":"18.04"},"fixed_in_version":"2.28-0ubuntu1","id":"356835","issued":"2019-10-12T07:20:50.52Z","links":"https://cve.mitre.org/cgi-bin/cvename.cgi?name=CVE-2009-5155\nhttp://people.canonical.com/~ubuntu-security/cve/2009/CVE-2009-5155.html\nhttps://sourceware.org/bugzilla/show_bug.cgi?id=11053\nhttps://debbugs.gnu.org/cgi/bugreport.cgi?bug=22793\nhttps://debbugs.gnu.org/cgi/bugreport.cgi?bug=32806\nhttps://debbugs.gnu.org/cgi/bugreport.cgi?bug=34238\nhttps://sourceware.org/bugzilla/show_bug.cgi?id=18986\"\n","name":"CVE-2009-5155","normalized_severity":"Low","package":{"id":"0","kind":"","name":"glibc","package_db":"","repository_hint":"","source":null,"version":""},"repo":{"id":"0","key":"","name":"Ubuntu 18.04.3 LTS","uri":""},"severity":"Low","updater":""}}},"responses":{"BadRequest":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Bad Request"},"Forbidden":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Forbidden"},"InternalServerError":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Internal Server Error"},"MethodNotAllowed":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Method Not Allowed"},"NotAcceptable":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Not Acceptable"},"NotFound":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Not Found"}},"schemas":{"Attestation":{"description":"An in-toto statement about the manifest, with the cosign \"vuln\"\npredicate. The predicate's \"scanner.result\" holds the\nVulnerabilityReport as it would be served, and \"scanner.db.version\"\nthe latest update operation.\n","properties":{"_type":{"example":"https://in-toto.io/Statement/v0.1","type":"string"},"predicate":{"type":"object"},"predicateType":{"example":"https://cosign.sigstore.dev/attestation/vuln/v1","type":"string"},"subject":{"items":{"properties":{"digest":{"additionalProperties":{"type":"string"},"type":"object"},"name":{"type":"string"}},"type":"object"},"type":"array"}},"title":"Attestation","type":"object"},"AttestationEnvelope":{"description":"A DSSE envelope holding an Attestation, signed with the report signing\nkey. The \"keyid\" of the signature names the key in the report keys\nset.\n","properties":{"payload":{"format":"byte","type":"string"},"payloadType":{"example":"application/vnd.in-toto+json","type":"string"},"signatures":{"items":{"properties":{"keyid":{"type":"string"},"sig":{"format":"byte","type":"string"}},"type":"object"},"type":"array"}},"title":"AttestationEnvelope","type":"object"},"BaseImage":{"description":"A manifest's probable base image: the leading layers it shares with\nother indexed manifests. Only present if the indexer detects base\nimages and found one.\n","properties":{"layers":{"description":"The manifest's layers that came with the base image","items":{"$ref":"#/components/schemas/Digest"},"type":"array"},"manifest":{"$ref":"#/components/schemas/Digest"},"shared":{"description":"The number of other indexed manifests starting with these layers","example":12,"type":"integer"}},"required":["layers","shared"],"title":"BaseImage","type":"object"},"Callback":{"description":"A callback for clients to retrieve notifications","properties":{"callback":{"description":"the url where notifications can be retrieved","example":"http://clair-notifier/notifier/api/v1/notifications/269886f3-0146-4f08-9bf7-cb1138d48643","type":"string"},"notification_id":{"description":"the unique identifier for this set of notifications","example":"269886f3-0146-4f08-9bf7-cb1138d48643","type":"string"}},"title":"Callback","type":"object"},"Change":{"description":"How the vulnerability in a notification differs from what affected\nthe manifest as of the previous update operation. Not present for\nnotifications with the \"removed\" reason.\n","properties":{"fixed_in_version":{"example":"v0.0.1","type":"string"},"kinds":{"description":"The ways the vulnerability changed. \"added\" notifications are\nalways \"introduced\". \"changed\" notifications may have none, if\nnothing summarized here changed.\n","items":{"enum":["introduced","fixed","severity_changed"],"type":"string"},"type":"array"},"previous_fixed_in_version":{"example":"","type":"string"},"previous_severity":{"example":"Medium","type":"string"},"severity":{"example":"High","type":"string"}},"required":["kinds","severity"],"title":"Change","type":"object"},"ContentFinding":{"description":"Something a content hook reported in a layer.","properties":{"detail":{"type":"string"},"name":{"example":"Eicar-Signature","type":"string"},"path":{"description":"The file it was found in, if the hook reports one","type":"string"}},"required":["name"],"title":"ContentFinding","type":"object"},"ContentScan":{"description":"A content hook's scan of a layer, e.g. by ClamAV.","properties":{"error":{"description":"Why the scan didn't complete, if it didn't","example":"","type":"string"},"findings":{"items":{"$ref":"#/components/schemas/ContentFinding"},"type":"array"},"layer":{"$ref":"#/components/schemas/Digest"},"scanned":{"description":"When the layer was scanned","format":"date-time","type":"string"},"scanner":{"description":"The configured name of the hook","example":"clamav","type":"string"}},"required":["layer","scanner","findings","scanned"],"title":"ContentScan","type":"object"},"DeadLetterResponse":{"description":"Notifications that failed delivery.","properties":{"dead_letters":{"items":{"properties":{"notification_id":{"description":"The notification ID.","type":"string"},"since":{"description":"When the latest delivery attempt failed.","format":"date-time","type":"string"},"update_operation":{"description":"The update operation that created the notification.","type":"string"}},"type":"object"},"type":"array"}},"required":["dead_letters"],"title":"DeadLetterResponse","type":"object"},"DeliveriesResponse":{"description":"Delivery attempts for a notification ID.","properties":{"deliveries":{"description":"An entry per configured deliverer, followed by any deliverers no\nlonger configured that attempted delivery.\n","items":{"$ref":"#/components/schemas/DeliveryStatus"},"type":"array"},"notification_id":{"description":"The notification ID.","type":"string"}},"required":["notification_id","deliveries"],"title":"DeliveriesResponse","type":"object"},"DeliveryAttempt":{"description":"A single attempt at delivering a notification ID.","properties":{"deliverer":{"description":"The name of the deliverer.","type":"string"},"error":{"description":"Why the attempt failed.","type":"string"},"notification_id":{"description":"The notification ID.","type":"string"},"response_code":{"description":"The response code the target returned, if there was one.","type":"integer"},"status":{"description":"The outcome of the attempt. \"filtered\" means no notifications\npassed the deliverer's filter, so nothing was sent.\n","enum":["delivered","failed","filtered"],"type":"string"},"target":{"description":"Where the deliverer sent the notification ID.","type":"string"},"timestamp":{"description":"When the attempt finished.","format":"date-time","type":"string"}},"required":["notification_id","deliverer","timestamp","status"],"title":"DeliveryAttempt","type":"object"},"DeliveryStatus":{"description":"A deliverer's attempts at delivering a notification ID.","properties":{"attempts":{"description":"The deliverer's attempts, oldest first.","items":{"$ref":"#/components/schemas/DeliveryAttempt"},"type":"array"},"deliverer":{"description":"The name of the deliverer.","type":"string"},"next_attempt":{"description":"When delivery is next expected to be attempted. Absent once the\nnotification ID has been delivered.\n","format":"date-time","type":"string"},"target":{"description":"Where the deliverer sends notifications, if it reports it.","type":"string"}},"required":["deliverer","attempts"],"title":"DeliveryStatus","type":"object"},"Digest":{"description":"A digest string with prefixed algorithm. The format is described here:\nhttps://github.com/opencontainers/image-spec/blob/master/descriptor.md#digests\n\nDigests are used throughout the API to identify Layers and Manifests.\n","example":"sha256:fc84b5febd328eccaa913807716887b3eb5ed08bc22cc6933a9ebf82766725e3","title":"Digest","type":"string"},"Distribution":{"description":"An indexed distribution discovered in a layer. See\nhttps://www.freedesktop.org/software/systemd/man/os-release.html\nfor explanations and example of fields.\n","example":{"$ref":"#/components/examples/Distribution/value"},"properties":{"arch":{"type":"string"},"cpe":{"type":"string"},"did":{"type":"string"},"id":{"description":"A unique ID representing this distribution","type":"string"},"name":{"type":"string"},"pretty_name":{"type":"string"},"version":{"type":"string"},"version_code_name":{"type":"string"},"version_id":{"type":"string"}},"required":["id","did","name","version","version_code_name","version_id","arch","cpe","pretty_name"],"title":"Distribution","type":"object"},"Environment":{"description":"The environment a particular package was discovered in.","properties":{"distribution_id":{"description":"The distribution ID found in an associated IndexReport or\nVulnerabilityReport.\n","example":"1","type":"string"},"introduced_in":{"$ref":"#/components/schemas/Digest"},"package_db":{"description":"The filesystem path or unique identifier of a package database.\n","example":"var/lib/dpkg/status","type":"string"}},"required":["package_db","introduced_in","distribution_id"],"title":"Environment","type":"object"},"Error":{"description":"A general error schema returned when status is not 200 OK","properties":{"code":{"description":"a code for this particular error. Besides codes specific to an endpoint, errors are classified as \"not-found\", \"conflict\", \"unauthenticated\", \"dependency-unavailable\", or \"internal-server-error\"","type":"string"},"message":{"description":"a message with further detail","type":"string"}},"title":"Error","type":"object"},"Exclusion":{"description":"A package excluded from an index report.","properties":{"package":{"example":"pytest","type":"string"},"package_db":{"description":"The package database, if it was excluded by path","example":"app/tests/fixtures/site-packages","type":"string"},"rule":{"description":"The configured pattern that matched","example":"**/fixtures/**","type":"string"},"version":{"example":"6.2.0","type":"string"}},"required":["package","version","rule"],"title":"Exclusion","type":"object"},"GraphQLRequest":{"properties":{"operationName":{"type":"string"},"query":{"example":"{ manifest(hash: \"sha256:...\") { packages { totalCount } } }","type":"string"},"variables":{"type":"object"}},"required":["query"],"title":"GraphQLRequest","type":"object"},"GraphQLResponse":{"description":"The query's result. \"data\" is absent if the query couldn't be run at\nall, and \"errors\" lists any problems.\n","properties":{"data":{"type":"object"},"errors":{"items":{"properties":{"locations":{"items":{"properties":{"column":{"type":"integer"},"line":{"type":"integer"}},"type":"object"},"type":"array"},"message":{"type":"string"},"path":{"items":{},"type":"array"}},"required":["message"],"type":"object"},"type":"array"}},"title":"GraphQLResponse","type":"object"},"IndexReport":{"description":"A report of the Index process for a particular manifest. A\nclient's usage of this is largely information. Clair uses this\nreport for matching Vulnerabilities.\n","properties":{"distributions":{"additionalProperties":{"$ref":"#/components/schemas/Distribution"},"description":"A map of Distribution objects keyed by their Distribution.id\ndiscovered in the manifest.\n","example":{"1":{"$ref":"#/components/examples/Distribution/value"}},"type":"object"},"environments":{"additionalProperties":{"items":{"$ref":"#/components/schemas/Environment"},"type":"array"},"description":"A map of lists containing Environment objects keyed by the\nassociated Package.id.\n","example":{"10":[{"distribution_id":"1","introduced_in":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","package_db":"var/lib/dpkg/status"}]},"type":"object"},"err":{"description":"An error message on event of unsuccessful index","example":"","type":"string"},"layers":{"description":"The layers of the manifest and the packages each introduced. Only\npresent in \"application/vnd.clair.indexreport.v2+json\" responses.\n","items":{"$ref":"#/components/schemas/LayerAttribution"},"type":"array"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"packages":{"additionalProperties":{"$ref":"#/components/schemas/Package"},"description":"A map of Package objects indexed by Package.id","example":{"10":{"$ref":"#/components/examples/Package/value"}},"type":"object"},"state":{"description":"The current state of the index operation","example":"IndexFinished","type":"string"},"success":{"description":"A bool indicating succcessful index","example":true,"type":"boolean"}},"required":["manifest_hash","state","packages","distributions","environments","success","err"],"title":"IndexReport","type":"object"},"IndexerGCResponse":{"description":"What index report garbage collection removed.","properties":{"layers":{"type":"integer"},"manifests":{"type":"integer"}},"required":["manifests","layers"],"title":"IndexerGCResponse","type":"object"},"Layer":{"description":"A Layer within a Manifest and where Clair may retrieve it.","properties":{"hash":{"$ref":"#/components/schemas/Digest"},"headers":{"additionalProperties":{"items":{"type":"string"},"type":"array"},"description":"map of arrays of header values keyed by header\nvalue. e.g. map[string][]string\n","type":"object"},"uri":{"description":"A URI describing where the layer may be found. Implementations\nMUST support http(s) schemes and MAY support additional\nschemes.\n","example":"https://storage.example.com/blob/2f077db56abccc19f16f140f629ae98e904b4b7d563957a7fc319bd11b82ba36\n","type":"string"}},"required":["hash","uri","headers"],"title":"Layer","type":"object"},"LayerAttribution":{"description":"What one layer of a manifest introduced, for telling findings in a\nbase image from ones in the layers built on top of it.\n","properties":{"hash":{"$ref":"#/components/schemas/Digest"},"index":{"description":"The layer's position in the manifest, starting from the base, or\n-1 if the order of the layers isn't known.\n","example":0,"type":"integer"},"packages":{"description":"The Package.id of each package the layer introduced.","example":["10"],"items":{"type":"string"},"type":"array"},"vulnerabilities":{"description":"The Vulnerability.id of each vulnerability affecting those\npackages. Only present in vulnerability reports.\n","example":["356835"],"items":{"type":"string"},"type":"array"}},"required":["hash","index","packages"],"title":"LayerAttribution","type":"object"},"Manifest":{"description":"A Manifest representing a container. The 'layers' array must\npreserve the original container's layer order for accurate usage.\n","properties":{"hash":{"$ref":"#/components/schemas/Digest"},"layers":{"items":{"$ref":"#/components/schemas/Layer"},"type":"array"}},"required":["hash","layers"],"title":"Manifest","type":"object"},"MatcherGCResponse":{"description":"What update operation garbage collection removed.","properties":{"update_operations":{"type":"integer"}},"required":["update_operations"],"title":"MatcherGCResponse","type":"object"},"MigrateResponse":{"description":"The version of each set of migrations.","properties":{"migrations":{"items":{"properties":{"table":{"type":"string"},"version":{"type":"integer"}},"type":"object"},"type":"array"}},"required":["migrations"],"title":"MigrateResponse","type":"object"},"Notification":{"description":"A notification expressing a change in a manifest affected by a\nvulnerability.\n","properties":{"change":{"$ref":"#/components/schemas/Change"},"id":{"description":"a unique identifier for this notification","example":"5e4b387e-88d3-4364-86fd-063447a6fad2","type":"string"},"manifest":{"description":"The hash of the manifest affected by the provided vulnerability.\n","example":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","type":"string"},"origin":{"description":"Whether the affected package came with the manifest's base image.\nOnly present if the notifier attributes layers and a base image\nwas detected for the manifest.\n","enum":["base","application"],"type":"string"},"reason":{"description":"the reason for the notifcation, [added | removed | changed]","example":"added","type":"string"},"vulnerability":{"$ref":"#/components/schemas/VulnSummary"}},"title":"Notification","type":"object"},"Package":{"description":"A package discovered by indexing a Manifest","example":{"$ref":"#/components/examples/Package/value"},"properties":{"arch":{"description":"The package's target system architecture","type":"string"},"cpe":{"description":"A CPE identifying the package","type":"string"},"id":{"description":"A unique ID representing this package","type":"string"},"kind":{"description":"Kind of package. Source | Binary","type":"string"},"module":{"description":"A module further defining a namespace for a package","type":"string"},"name":{"description":"Name of the Package","type":"string"},"normalized_version":{"$ref":"#/components/schemas/Version"},"source":{"$ref":"#/components/schemas/SourcePackage"},"version":{"description":"Version of the Package","type":"string"}},"required":["id","name","version"],"title":"Package","type":"object"},"PackageScopes":{"additionalProperties":{"enum":["runtime","development"],"type":"string"},"description":"The scope of each package whose scope is known, keyed by Package.id:\n\"runtime\" for packages the application needs to run, and\n\"development\" for packages only needed to build or test it. Scopes\nare known for Python packages named in a dependency manifest, when a\n\"scope\" indexer hook is configured.\n","example":{"10":"development"},"title":"PackageScopes","type":"object"},"Page":{"description":"A page object indicating to the client how to retrieve multiple pages of\na particular entity.\n","properties":{"next":{"description":"The next id to submit to the api to continue paging","example":"1b4d0db2-e757-4150-bbbb-543658144205","type":"string"},"size":{"description":"The maximum number of elements in a page","example":1,"type":"int"}},"title":"Page"},"PagedAffectedManifests":{"description":"A page of manifests affected by a vulnerability.","properties":{"manifests":{"items":{"properties":{"manifest_hash":{"$ref":"#/components/schemas/Digest"},"vulnerabilities":{"description":"The IDs of the vulnerabilities affecting the manifest.","items":{"type":"string"},"type":"array"}},"type":"object"},"type":"array"},"page":{"description":"The page size and, if there are more manifests, the \"next\" value\nto request the following page with.\n","example":{"next":"sha256:fc84b5febd328eccaa913807716887b3eb5ed08bc22cc6933a9ebf82766725e3","size":100},"type":"object"},"vulnerabilities":{"additionalProperties":{"$ref":"#/components/schemas/Vulnerability"},"description":"The vulnerabilities referenced in the page, keyed by ID.","type":"object"}},"required":["page","vulnerabilities","manifests"],"title":"PagedAffectedManifests","type":"object"},"PagedNotifications":{"description":"A page object followed by a list of notifications","properties":{"notifications":{"description":"A list of notifications within this page","items":{"$ref":"#/components/schemas/Notification"},"type":"array"},"page":{"description":"A page object informing the client the next page to retrieve.\nIf page.next becomes \"-1\" the client should stop paging.\n","example":{"next":"1b4d0db2-e757-4150-bbbb-543658144205","size":100},"type":"object"}},"title":"PagedNotifications","type":"object"},"PolicyDecision":{"description":"The outcome of evaluating policy against a manifest.","properties":{"allow":{"description":"Whether the manifest passed every policy.","type":"boolean"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"violations":{"description":"The values produced by the \"deny\" rule of the \"clair\" package.\nThese are usually strings.\n","items":{},"type":"array"}},"required":["manifest_hash","allow","violations"],"title":"PolicyDecision","type":"object"},"PolicyRequest":{"description":"A request to evaluate policy against a manifest.","properties":{"manifest_hash":{"$ref":"#/components/schemas/Digest"}},"required":["manifest_hash"],"title":"PolicyRequest","type":"object"},"PurgeResponse":{"description":"The outcome of purging notifications.","properties":{"purged":{"description":"The number of notification IDs removed.","type":"integer"}},"required":["purged"],"title":"PurgeResponse","type":"object"},"ReplayResponse":{"description":"The outcome of replaying notifications.","properties":{"replayed":{"description":"The number of notification IDs queued for delivery.","type":"integer"}},"required":["replayed"],"title":"ReplayResponse","type":"object"},"ReportExtensions":{"description":"Results of indexer extensions inspecting layers beyond package\ndiscovery. Only present if any are configured and produced results.\n","properties":{"content":{"description":"What the configured content hooks found in each layer","items":{"$ref":"#/components/schemas/ContentScan"},"type":"array"}},"title":"ReportExtensions","type":"object"},"ReportHistory":{"description":"The recorded versions of a manifest's VulnerabilityReport, newest\nfirst.\n","properties":{"history":{"items":{"$ref":"#/components/schemas/ReportHistoryEntry"},"type":"array"},"manifest_hash":{"$ref":"#/components/schemas/Digest"}},"title":"ReportHistory","type":"object"},"ReportHistoryEntry":{"description":"One version of a manifest's VulnerabilityReport.\n","properties":{"created":{"description":"When the version was first generated.","format":"date-time","type":"string"},"digest":{"$ref":"#/components/schemas/Digest"},"severities":{"additionalProperties":{"type":"integer"},"description":"The number of vulnerabilities of each normalized severity.","type":"object"},"update_operation":{"description":"The latest update operation when the report was generated.","format":"uuid","type":"string"},"vulnerabilities":{"description":"The number of vulnerabilities in the report.","type":"integer"}},"title":"ReportHistoryEntry","type":"object"},"ReportRecord":{"description":"One line of a streamed VulnerabilityReport.\n\nThe first record is always of kind \"manifest\". Distributions,\nrepositories, and vulnerabilities follow, then every package\nfollowed by its environments and vulnerability IDs, and finally any\nVEX suppressions.\n","properties":{"id":{"description":"The value's key in the VulnerabilityReport. For \"environments\"\nand \"package_vulnerabilities\" records, the package ID.\n","type":"string"},"kind":{"enum":["manifest","distribution","repository","vulnerability","package","environments","package_vulnerabilities","vex"],"type":"string"},"value":{"description":"The object, shaped as in the VulnerabilityReport."}},"required":["kind","value"],"title":"ReportRecord","type":"object"},"Repository":{"description":"A package repository","properties":{"cpe":{"type":"string"},"id":{"type":"string"},"key":{"type":"string"},"name":{"type":"string"},"uri":{"type":"string"}},"title":"Repository","type":"object"},"SignatureStatus":{"description":"The outcome of verifying a manifest's cosign signatures. Only present\nif signature verification is configured.\n","properties":{"checked":{"description":"When verification happened","format":"date-time","type":"string"},"reason":{"description":"Why the manifest didn't verify","example":"","type":"string"},"signer":{"description":"The key or certificate identity that verified the manifest","example":"builder@example.com","type":"string"},"status":{"enum":["verified","unsigned","invalid","error"],"example":"verified","type":"string"}},"required":["status","checked"],"title":"SignatureStatus","type":"object"},"SignedReport":{"description":"A JWS in compact serialization, with a \"typ\" header of\n\"application/vnd.clair.report.v1+jws\" and a \"kid\" header naming the\nkey in the report keys set.\n\nThe payload is a JSON object with the members \"version\" (currently\n\"v1\"), \"issued_at\", and \"report\", which holds the VulnerabilityReport\nas it would be served unsigned.\n","title":"SignedReport","type":"string"},"SourcePackage":{"description":"A source package affiliated with a Package","example":{"$ref":"#/components/examples/Package/value"},"properties":{"arch":{"type":"string"},"cpe":{"description":"A CPE identifying the package","type":"string"},"id":{"description":"A unique ID representing this package","type":"string"},"kind":{"description":"Kind of package. Source | Binary","type":"string"},"module":{"type":"string"},"name":{"description":"Name of the Package","type":"string"},"normalized_version":{"$ref":"#/components/schemas/Version"},"source":{"type":"string"},"version":{"description":"Version of the Package","type":"string"}},"required":["id","name","version"],"title":"SourcePackage","type":"object"},"State":{"description":"an opaque identifier","example":{"state":"aae368a064d7c5a433d0bf2c4f5554cc"},"properties":{"state":{"description":"an opaque identifier","type":"string"}},"required":["state"],"title":"State","type":"object"},"StreamEvent":{"description":"A page of notifications sent in a notification stream","properties":{"notification_id":{"description":"the unique identifier for this set of notifications","example":"269886f3-0146-4f08-9bf7-cb1138d48643","type":"string"},"notifications":{"description":"A list of notifications within this page","items":{"$ref":"#/components/schemas/Notification"},"type":"array"}},"title":"StreamEvent","type":"object"},"Truncation":{"description":"A layer scanned in part. Packages in the rest of the layer are missing\nfrom the report.\n","properties":{"files":{"description":"The number of entries scanned before the limit","example":100000,"type":"integer"},"layer":{"$ref":"#/components/schemas/Digest"},"reason":{"description":"The limit that was exceeded","enum":["files","size"],"type":"string"},"size":{"description":"The bytes of file contents scanned before the limit","example":1073741824,"type":"integer"}},"required":["layer","files","size","reason"],"title":"Truncation","type":"object"},"UpdaterOverride":{"description":"An override for an updater set or updater.","properties":{"config":{"description":"Configuration used in place of the configuration file's.","type":"object"},"disabled":{"description":"Excludes the updater set or updater from update runs.","type":"boolean"}},"title":"UpdaterOverride","type":"object"},"UpdaterOverrides":{"additionalProperties":{"$ref":"#/components/schemas/UpdaterOverride"},"description":"Updater overrides, keyed by updater set or updater name.","title":"UpdaterOverrides","type":"object"},"UpdaterRunResponse":{"description":"The new update operation for each updater that found changes.","properties":{"updated":{"additionalProperties":{"type":"string"},"type":"object"}},"required":["updated"],"title":"UpdaterRunResponse","type":"object"},"VEXDocument":{"description":"A VEX document in use by the matcher.","properties":{"format":{"enum":["openvex","csaf"],"type":"string"},"id":{"description":"The document's ID.","type":"string"},"statements":{"description":"The number of statements in the document.","type":"integer"}},"required":["id","format","statements"],"title":"VEXDocument","type":"object"},"Version":{"description":"Version is a normalized claircore version, composed of a \"kind\" and an\narray of integers such that two versions of the same kind have the\ncorrect ordering when the integers are compared pair-wise.\n","example":"pep440:0.0.0.0.0.0.0.0.0","title":"Version","type":"string"},"VulnSummary":{"description":"A summary of a vulnerability","properties":{"description":{"description":"the vulnerability name","example":"In the GNU C Library (aka glibc or libc6) before 2.28,\nparse_reg_exp in posix/regcomp.c misparses alternatives,\nwhich allows attackers to cause a denial of service (assertion\nfailure and application exit) or trigger an incorrect result\nby attempting a regular-expression match.\"\n","type":"string"},"distribution":{"$ref":"#/components/schemas/Distribution"},"fixed_in_version":{"description":"The version which the vulnerability is fixed in. Empty if not fixed.\n","example":"v0.0.1","type":"string"},"links":{"description":"links to external information about vulnerability","example":"http://link-to-advisory","type":"string"},"name":{"description":"the vulnerability name","example":"CVE-2009-5155","type":"string"},"normalized_severity":{"description":"A well defined set of severity strings guaranteed to be present.\n","enum":["Unknown","Negligible","Low","Medium","High","Critical"],"type":"string"},"package":{"$ref":"#/components/schemas/Package"},"repository":{"$ref":"#/components/schemas/Repository"}},"title":"VulnSummary","type":"object"},"Vulnerability":{"description":"A unique vulnerability indexed by Clair","example":{"$ref":"#/components/examples/Vulnerability/value"},"properties":{"description":{"description":"A description of this specific vulnerability.","type":"string"},"distribution":{"$ref":"#/components/schemas/Distribution"},"fixed_in_version":{"description":"A unique ID representing this vulnerability.","type":"string"},"id":{"description":"A unique ID representing this vulnerability.","type":"string"},"issued":{"description":"The timestamp in which the vulnerability was issued\n","type":"string"},"links":{"description":"A space separate list of links to any external information.\n","type":"string"},"name":{"description":"Name of this specific vulnerability.","type":"string"},"normalized_severity":{"description":"A well defined set of severity strings guaranteed to be present.\n","enum":["Unknown","Negligible","Low","Medium","High","Critical"],"type":"string"},"package":{"$ref":"#/components/schemas/Package"},"range":{"description":"The range of package versions affected by this vulnerability.\n","type":"string"},"repository":{"$ref":"#/components/schemas/Repository"},"severity":{"description":"A severity keyword taken verbatim from the vulnerability source.\n","type":"string"},"updater":{"description":"A unique ID representing this vulnerability.","type":"string"}},"required":["id","updater","name","description","links","severity","normalized_severity","fixed_in_version"],"title":"Vulnerability","type":"object"},"VulnerabilityReport":{"description":"A report expressing discovered packages, package environments,\nand package vulnerabilities within a Manifest.\n","properties":{"distributions":{"additionalProperties":{"$ref":"#/components/schemas/Distribution"},"description":"A map of Distribution objects indexed by Distribution.id.\n","example":{"1":{"$ref":"#/components/examples/Distribution/value"}},"type":"object"},"environments":{"additionalProperties":{"items":{"$ref":"#/components/schemas/Environment"},"type":"array"},"description":"A mapping of Environment lists indexed by Package.id","example":{"10":[{"distribution_id":"1","introduced_in":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","package_db":"var/lib/dpkg/status"}]},"type":"object"},"layers":{"description":"The layers of the manifest and the packages and vulnerabilities\neach introduced. Only present in\n\"application/vnd.clair.vulnerabilityreport.v2+json\" responses.\n","items":{"$ref":"#/components/schemas/LayerAttribution"},"type":"array"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"package_vulnerabilities":{"additionalProperties":{"items":{"type":"string"},"type":"array"},"description":"A mapping of Vulnerability.id lists indexed by Package.id.\n","example":{"10":["356835"]}},"packages":{"additionalProperties":{"$ref":"#/components/schemas/Package"},"description":"A map of Package objects indexed by Package.id","example":{"10":{"$ref":"#/components/examples/Package/value"}},"type":"object"},"vulnerabilities":{"additionalProperties":{"$ref":"#/components/schemas/Vulnerability"},"description":"A map of Vulnerabilities indexed by Vulnerability.id","example":{"356835":{"$ref":"#/components/examples/Vulnerability/value"}},"type":"object"}},"required":["manifest_hash","packages","distributions","environments","vulnerabilities","package_vulnerabilities"],"title":"VulnerabilityReport","type":"object"}}},"info":{"contact":{"email":"quay-devel@redhat.com","name":"Clair Team","url":"http://github.com/quay/clair"},"description":"ClairV4 is a set of cooperating microservices which scan, index, and\nmatch your container's content with known vulnerabilities.\n","license":{"name":"Apache License 2.0","url":"http://www.apache.org/licenses/"},"termsOfService":"","title":"ClairV4","version":"1"},"openapi":"3.0.2","paths":{"indexer/api/v1/admin/gc":{"post":{"description":"Runs index report garbage collection to completion. Responds 501 if\ngarbage collection is not configured.\n","operationId":"CollectIndexReports","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexerGCResponse"}}},"description":"What was removed"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run index report garbage collection.","tags":["Indexer"]}},"indexer/api/v1/admin/manifest/{manifest_hash}":{"delete":{"description":"Removes the manifest and its index report, along with any of its\nlayers no other manifest uses.\n","operationId":"DeleteManifest","parameters":[{"in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"204":{"description":"The manifest was deleted"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Delete a manifest and its index report.","tags":["Indexer"]}},"indexer/api/v1/admin/migrate":{"post":{"operationId":"MigrateIndexer","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/MigrateResponse"}}},"description":"The resulting migration versions"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run outstanding indexer database migrations.","tags":["Indexer"]}},"indexer/api/v1/index_report":{"post":{"description":"By submitting a Manifest object to this endpoint Clair will fetch the\nlayers, scan each layer's contents, and provide an index of discovered\npackages, repository and distribution information.\n\nIf the \"If-None-Match\" header matches the Etag of the manifest's\ncurrent IndexReport, the manifest is not indexed again.\n\nRequesting the \"application/vnd.clair.indexreport.v2+json\" media type\nadds the layers of the manifest, in order, with the packages each\nintroduced.\n","operationId":"Index","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Manifest"}}},"required":true},"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexReport"}},"application/vnd.clair.indexreport.v2+json":{"schema":{"$ref":"#/components/schemas/IndexReport"}}},"description":"IndexReport Created","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"The manifest's signatures didn't verify and signature verification\nis enforced.\n"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"412":{"description":"IndexReport Unchanged"},"500":{"$ref":"#/components/responses/InternalServerError"},"503":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Too many index requests are queued; retry after the delay in the\n\"Retry-After\" header.\n","headers":{"Retry-After":{"description":"Seconds to wait before retrying","schema":{"type":"integer"}}}}},"summary":"Index the contents of a Manifest","tags":["Indexer"]}},"indexer/api/v1/index_report/{manifest_hash}":{"get":{"description":"Given a Manifest's content addressable hash an IndexReport will\nbe retrieved if exists.\n\nThe Etag changes when the IndexReport does, or when the indexer's\nstate means the manifest should be indexed again.\n\nRequesting the \"application/vnd.clair.indexreport.v2+json\" media type\nadds the layers of the manifest, in order, with the packages each\nintroduced.\n","operationId":"GetIndexReport","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexReport"}},"application/vnd.clair.indexreport.v2+json":{"schema":{"$ref":"#/components/schemas/IndexReport"}}},"description":"IndexReport retrieved","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"304":{"description":"IndexReport Unchanged"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve an IndexReport for the given Manifest hash if exists.","tags":["Indexer"]}},"indexer/api/v1/index_state":{"get":{"description":"The index state endpoint returns a json structure indicating the\nindexer's internal configuration state.\n\nA client may be interested in this as a signal that manifests may need\nto be re-indexed.\n","operationId":"IndexState","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/State"}}},"description":"Indexer State","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"304":{"description":"Indexer State Unchanged"}},"summary":"Report the indexer's internal configuration and state.","tags":["Indexer"]}},"indexer/api/v1/layers/{digest}":{"head":{"operationId":"CheckLayer","responses":{"200":{"description":"Layer present"},"404":{"description":"Layer not present"}},"summary":"Report whether a layer has been uploaded.","tags":["Indexer"]},"parameters":[{"description":"The digest of the layer's contents.","in":"path","name":"digest","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"put":{"description":"Stores a layer for indexing. Layers in a submitted Manifest with an\nempty URI are read from uploads, so clients can index layers Clair\ncan't fetch. Uploads expire after a configured time.\n\nThis endpoint is only available if uploads are configured.\n","operationId":"UploadLayer","requestBody":{"content":{"application/octet-stream":{"schema":{"format":"binary","type":"string"}}},"required":true},"responses":{"201":{"description":"Layer stored"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"413":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Layer too large"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Upload a layer's contents.","tags":["Indexer"]}},"matcher/api/v1/admin/gc":{"post":{"operationId":"CollectUpdateOperations","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/MatcherGCResponse"}}},"description":"What was removed"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run update operation garbage collection.","tags":["Matcher"]}},"matcher/api/v1/admin/migrate":{"post":{"operationId":"MigrateMatcher","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/MigrateResponse"}}},"description":"The resulting migration versions"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run outstanding matcher database migrations.","tags":["Matcher"]}},"matcher/api/v1/admin/updaters/run":{"post":{"description":"Runs every configured updater once, responding when all have\nfinished.\n","operationId":"RunUpdaters","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/UpdaterRunResponse"}}},"description":"The updaters that found changes"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run the updaters.","tags":["Matcher"]}},"matcher/api/v1/affected_manifests":{"get":{"description":"Looks up the current vulnerabilities with the provided name or ID and\nreports the indexed manifests they affect, ordered by manifest hash.\n\nA vulnerability name may match several vulnerabilities, e.g. one per\ndistribution release. The \"namespace\" parameter restricts the lookup\nto an updater or distribution ID.\n\nWith tenancy, only manifests the requesting tenant submitted are\nreported. Nodes unable to scope the results refuse the request.\n","operationId":"GetAffectedManifests","parameters":[{"description":"A vulnerability name, such as a CVE, or ID.","in":"query","name":"vulnerability_id","required":true,"schema":{"type":"string"}},{"description":"An updater name or distribution ID, e.g. \"debian\".","in":"query","name":"namespace","required":false,"schema":{"type":"string"}},{"description":"The maximum number of manifests in the page.","in":"query","name":"page_size","required":false,"schema":{"type":"integer"}},{"description":"The \"page.next\" value of the previous page.","in":"query","name":"next","required":false,"schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PagedAffectedManifests"}}},"description":"A page of affected manifests"},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"$ref":"#/components/responses/Forbidden"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"List the indexed manifests affected by a vulnerability.","tags":["Matcher"]}},"matcher/api/v1/graphql":{"get":{"description":"Runs the GraphQL query in the \"query\" parameter over manifests'\nindex and vulnerability reports. Without a query, returns the schema\nin the GraphQL schema definition language. This endpoint is only\navailable when GraphQL is configured.\n","operationId":"GraphQLQuery","parameters":[{"in":"query","name":"query","schema":{"type":"string"}},{"in":"query","name":"operationName","schema":{"type":"string"}},{"description":"A JSON object of variables","in":"query","name":"variables","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/GraphQLResponse"}},"text/plain":{"schema":{"type":"string"}}},"description":"A GraphQL response, or the schema"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Run a GraphQL query over reports, or retrieve the schema.","tags":["Matcher"]},"post":{"description":"Runs a GraphQL query over manifests' index and vulnerability reports.\nThis endpoint is only available when GraphQL is configured.\n","operationId":"GraphQLQueryPost","requestBody":{"content":{"application/graphql":{"schema":{"type":"string"}},"application/json":{"schema":{"$ref":"#/components/schemas/GraphQLRequest"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/GraphQLResponse"}}},"description":"A GraphQL response"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Run a GraphQL query over reports.","tags":["Matcher"]}},"matcher/api/v1/policy/evaluate":{"post":{"description":"Given a Manifest's content addressable hash a VulnerabilityReport\nwill be created and evaluated against the configured Rego policies.\nThe Manifest **must** have been Indexed first via the Index endpoint.\n\nThis endpoint is only available if policies are configured.\n","operationId":"EvaluatePolicy","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PolicyRequest"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PolicyDecision"}}},"description":"Policy Decision"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Evaluate the configured policies against a manifest's\nVulnerabilityReport.\n","tags":["Matcher"]}},"matcher/api/v1/report_keys":{"get":{"description":"Returns the JWK set holding the public key used to sign vulnerability\nreports. This endpoint is only available when report signing is\nconfigured.\n","operationId":"GetReportKeys","responses":{"200":{"content":{"application/jwk-set+json":{"schema":{"type":"object"}}},"description":"A JWK set"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Retrieve the keys signed vulnerability reports are verified with.","tags":["Matcher"]}},"matcher/api/v1/updaters/config":{"delete":{"operationId":"DeleteUpdaterOverride","parameters":[{"description":"The updater set or updater name.","in":"query","name":"name","required":true,"schema":{"type":"string"}}],"responses":{"204":{"description":"Updater override removed"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Remove an updater override.","tags":["Matcher"]},"get":{"description":"Reports the overrides disabling or reconfiguring updater sets and\nupdaters, keyed by updater set or updater name.\n\nThis endpoint is only available if updater overrides are configured.\n","operationId":"GetUpdaterOverrides","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/UpdaterOverrides"}}},"description":"Updater overrides"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Report the updater overrides.","tags":["Matcher"]},"put":{"description":"Stores the provided overrides, replacing any existing ones with the\nsame names. Overrides not named in the request are left alone.\nChanges take effect at the next update run.\n\nThis endpoint is only available if updater overrides are configured.\n","operationId":"SetUpdaterOverrides","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/UpdaterOverrides"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/UpdaterOverrides"}}},"description":"Updater overrides"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Set updater overrides.","tags":["Matcher"]}},"matcher/api/v1/vex":{"delete":{"operationId":"DeleteVEXDocument","parameters":[{"description":"The document ID.","in":"query","name":"id","required":true,"schema":{"type":"string"}}],"responses":{"204":{"description":"VEX Document removed"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Remove an uploaded VEX document.","tags":["Matcher"]},"get":{"description":"Lists the VEX documents used to suppress vulnerabilities, both those\nloaded from the configuration and those uploaded.\n\nThis endpoint is only available if VEX is configured.\n","operationId":"ListVEXDocuments","responses":{"200":{"content":{"application/json":{"schema":{"items":{"$ref":"#/components/schemas/VEXDocument"},"type":"array"}}},"description":"VEX Documents"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"List the VEX documents in use.","tags":["Matcher"]},"post":{"description":"Stores an OpenVEX or CSAF VEX document. A document with the same ID\nreplaces any previously uploaded one.\n\nThis endpoint is only available if VEX is configured.\n","operationId":"UploadVEXDocument","requestBody":{"content":{"application/json":{"schema":{}}},"required":true},"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/VEXDocument"}}},"description":"VEX Document stored"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Upload a VEX document.","tags":["Matcher"]}},"matcher/api/v1/vulnerability_report/":{"post":{"description":"Given an IndexReport a VulnerabilityReport will be created, without\nthe Manifest needing to be Indexed. This is used to match index\nreports produced elsewhere, such as ones converted from an SBOM by\n\"clairctl import-sbom\".\n\nRequesting the \"application/x-ndjson\" media type returns the report\nas a stream of newline delimited ReportRecord objects.\n\nRequesting the \"application/vnd.clair.report.v1+jws\" media type\nreturns the report signed with the configured key, as a JWS in\ncompact serialization.\n\nRequesting the \"application/vnd.in-toto+json\" media type returns the\nreport as an in-toto statement with the cosign \"vuln\" predicate.\nRequesting the \"application/vnd.dsse.envelope.v1+json\" media type\nreturns that statement signed with the configured key, in a DSSE\nenvelope, as cosign pushes attestations to registries. Attestations\nhave no Etag.\n\nRequesting the \"application/vnd.clair.vulnerabilityreport.v2+json\"\nmedia type adds the layers that introduced the report's packages and\nvulnerabilities. The order of the layers isn't known, so each has an\nindex of -1.\n","operationId":"ScanIndexReport","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexReport"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}},"application/vnd.clair.report.v1+jws":{"schema":{"$ref":"#/components/schemas/SignedReport"}},"application/vnd.clair.vulnerabilityreport.v2+json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}},"application/vnd.dsse.envelope.v1+json":{"schema":{"$ref":"#/components/schemas/AttestationEnvelope"}},"application/vnd.in-toto+json":{"schema":{"$ref":"#/components/schemas/Attestation"}},"application/x-ndjson":{"schema":{"$ref":"#/components/schemas/ReportRecord"}}},"description":"VulnerabilityReport Created"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Create a VulnerabilityReport for a provided IndexReport.\n","tags":["Matcher"]}},"matcher/api/v1/vulnerability_report/{manifest_hash}":{"get":{"description":"Given a Manifest's content addressable hash a VulnerabilityReport\nwill be created. The Manifest **must** have been Indexed first\nvia the Index endpoint.\n\nRequesting the \"application/x-ndjson\" media type returns the report\nas a stream of newline delimited ReportRecord objects, so large\nreports can be processed incrementally.\n\nRequesting the \"application/vnd.clair.report.v1+jws\" media type\nreturns the report signed with the configured key, as a JWS in\ncompact serialization. Signed reports have no Etag.\n\nRequesting the \"application/vnd.in-toto+json\" media type returns the\nreport as an in-toto statement with the cosign \"vuln\" predicate.\nRequesting the \"application/vnd.dsse.envelope.v1+json\" media type\nreturns that statement signed with the configured key, in a DSSE\nenvelope, as cosign pushes attestations to registries. Attestations\nhave no Etag.\n\nRequesting the \"application/vnd.clair.vulnerabilityreport.v2+json\"\nmedia type adds the layers of the manifest, in order, with the\npackages and vulnerabilities each introduced.\n\nThe Etag is derived from the IndexReport and the vulnerability data\nused to match it, so a conditional request for an unchanged report is\nanswered without matching again.\n","operationId":"GetVulnerabilityReport","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}},{"description":"The name of an attestation's subject, usually the image's\nrepository. Defaults to the manifest digest.\n","in":"query","name":"subject","required":false,"schema":{"type":"string"}},{"description":"If \"runtime\", packages known to only be development dependencies,\nand the vulnerabilities only they are affected by, are left out\nof the report.\n","in":"query","name":"scope","required":false,"schema":{"enum":["runtime"],"type":"string"}}],"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}},"application/vnd.clair.report.v1+jws":{"schema":{"$ref":"#/components/schemas/SignedReport"}},"application/vnd.clair.vulnerabilityreport.v2+json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}},"application/vnd.dsse.envelope.v1+json":{"schema":{"$ref":"#/components/schemas/AttestationEnvelope"}},"application/vnd.in-toto+json":{"schema":{"$ref":"#/components/schemas/Attestation"}},"application/x-ndjson":{"schema":{"$ref":"#/components/schemas/ReportRecord"}}},"description":"VulnerabilityReport Created","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"304":{"description":"VulnerabilityReport Unchanged"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"406":{"$ref":"#/components/responses/NotAcceptable"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve a VulnerabilityReport for a given manifest's content\naddressable hash.\n","tags":["Matcher"]}},"matcher/api/v1/vulnerability_report/{manifest_hash}/history":{"get":{"description":"Lists the versions of the manifest's VulnerabilityReport recorded as\nthe vulnerability database was updated, newest first. Versions are\nonly recorded when report history is configured.\n","operationId":"GetReportHistory","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/ReportHistory"}}},"description":"Report History"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve the recorded versions of a manifest's VulnerabilityReport.\n","tags":["Matcher"]}},"matcher/api/v1/vulnerability_report/{manifest_hash}/history/{report_digest}":{"get":{"description":"Returns the version of the manifest's VulnerabilityReport with the\ndigest, exactly as it was generated. A version never changes, so its\nEtag is its digest.\n","operationId":"GetReportVersion","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}},{"description":"The digest of a version, as listed in the manifest's ReportHistory.\n","in":"path","name":"report_digest","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}}},"description":"VulnerabilityReport Version","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"304":{"description":"VulnerabilityReport Unchanged"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve a recorded version of a manifest's VulnerabilityReport.\n","tags":["Matcher"]}},"notifier/api/v1/admin/deadletter/":{"get":{"description":"Lists the notification IDs whose latest delivery attempt failed,\noldest first. These are retried on every delivery interval.\n","operationId":"ListDeadLetters","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/DeadLetterResponse"}}},"description":"Notifications that failed delivery"},"403":{"$ref":"#/components/responses/Forbidden"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"List notifications that failed delivery.","tags":["Notifier"]},"post":{"description":"Returns every notification that failed delivery to created status.\n","operationId":"ReplayDeadLetters","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/ReplayResponse"}}},"description":"The number of notification IDs queued"},"403":{"$ref":"#/components/responses/Forbidden"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Queue every notification that failed delivery.","tags":["Notifier"]}},"notifier/api/v1/admin/deadletter/{notification_id}":{"post":{"description":"Returns the notification ID to created status, whether its delivery\nfailed or it was delivered. Deleted notifications are not replayed.\n","operationId":"ReplayNotification","parameters":[{"description":"A notification ID","in":"path","name":"notification_id","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/ReplayResponse"}}},"description":"The number of notification IDs queued"},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"$ref":"#/components/responses/Forbidden"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Queue a notification for delivery again.","tags":["Notifier"]}},"notifier/api/v1/admin/migrate":{"post":{"operationId":"MigrateNotifier","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/MigrateResponse"}}},"description":"The resulting migration versions"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run outstanding notifier database migrations.","tags":["Notifier"]}},"notifier/api/v1/admin/purge/{update_operation}":{"delete":{"description":"Removes the notifications created for the provided update operation\nif they have been delivered or deleted. If the update operation is\nthe latest for its updater, its receipt is kept so the notifications\naren't created again.\n","operationId":"PurgeNotifications","parameters":[{"description":"An update operation ID","in":"path","name":"update_operation","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PurgeResponse"}}},"description":"The number of notification IDs removed"},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"$ref":"#/components/responses/Forbidden"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Remove delivered notifications for an update operation.","tags":["Notifier"]}},"notifier/api/v1/deliveries":{"get":{"description":"Reports every attempt the configured deliverers made at delivering\nthe provided notification ID, along with when delivery will next be\nattempted if it hasn't succeeded yet.\n","operationId":"GetDeliveries","parameters":[{"description":"A notification ID returned by a callback","in":"query","name":"notification_id","required":true,"schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/DeliveriesResponse"}}},"description":"Delivery attempts for the notification ID"},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"$ref":"#/components/responses/Forbidden"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Report delivery attempts for a notification ID.","tags":["Notifier"]}},"notifier/api/v1/notification/stream":{"get":{"description":"Returns a stream of Server-Sent Events, as an alternative to polling\nfor callbacks.\n\nEvery notification ID is sent as one or more \"notifications\" events,\neach holding a StreamEvent with a page of its notifications. The last\nevent for a notification ID has an event ID, which is the cursor:\nreconnecting with it in the \"Last-Event-ID\" header resumes with the\nnext notification ID. Without a cursor the stream starts with the\noldest notification ID that hasn't been deleted.\n","operationId":"StreamNotifications","parameters":[{"description":"The cursor to resume after","in":"header","name":"Last-Event-ID","schema":{"type":"string"}},{"description":"The cursor to resume after, for clients unable to set the\nLast-Event-ID header.\n","in":"query","name":"cursor","schema":{"type":"string"}},{"description":"The maximum number of notifications to send in a single event.\n","in":"query","name":"page_size","schema":{"type":"int"}}],"responses":{"200":{"content":{"text/event-stream":{"schema":{"$ref":"#/components/schemas/StreamEvent"}}},"description":"A stream of notifications"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Stream notifications as they're created.","tags":["Notifier"]}},"notifier/api/v1/notification/{notification_id}":{"delete":{"description":"Issues a delete of the provided notification id and all associated\nnotifications. After this delete clients will no longer be able to\nretrieve notifications.\n","operationId":"DeleteNotification","parameters":[{"description":"A notification ID returned by a callback","in":"path","name":"notification_id","schema":{"type":"string"}}],"responses":{"200":{"description":"OK"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"tags":["Notifier"]},"get":{"description":"By performing a GET with a notification_id as a path parameter, the\nclient will retrieve a paginated response of notification objects.\n","operationId":"GetNotification","parameters":[{"description":"A notification ID returned by a callback","in":"path","name":"notification_id","schema":{"type":"string"}},{"description":"The maximum number of notifications to deliver in a single page.\n","in":"query","name":"page_size","schema":{"type":"int"}},{"description":"The next page to fetch via id. Typically this number is provided\non initial response in the page.next field.\nThe first GET request may omit this field.\n","in":"query","name":"next","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PagedNotifications"}}},"description":"A paginated list of notifications"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve a paginated result of notifications for the provided id.","tags":["Notifier"]}}}}`,
+		"1.1": `{"components":{"examples":{"Distribution":{"value":{"arch":"","cpe":"","did":"ubuntu","id":"1","name":"Ubuntu","pretty_name":"Ubuntu 18.04.3 LTS","version":"18.04.3 LTS (Bionic Beaver)","version_code_name":"bionic","version_id":"18.04"}},"Environment":{"value":{"distribution_id":"1","introduced_in":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","package_db":"var/lib/dpkg/status"}},"Package":{"value":{"arch":"x86","cpe":"","id":"10","kind":"binary","module":"","name":"libapt-pkg5.0","normalized_version":"","source":{"id":"9","kind":"source","name":"apt","source":null,"version":"1.6.11"},"version":"1.6.11"}},"VulnSummary":{"value":{"description":"In the GNU C Library (aka glibc or libc6) before 2.28,\nparse_reg_exp in posix/regcomp.c misparses alternatives,\nwhich allows attackers to cause a denial of service (assertion\nfailure and application exit) or trigger an incorrect result\nby attempting a regular-expression match.\"\n","dist":{"arch":"","cpe":"","did":"ubuntu","id":"0","name":"Ubuntu","pretty_name":"","version":"18.04.3 LTS (Bionic Beaver)","version_code_name":"bionic","version_id":"18.04"},"fixed_in_version":"v0.0.1","links":"http://link-to-advisory","name":"CVE-2009-5155","normalized_severity":"Low","package":{"id":"0","kind":"","name":"glibc","package_db":"","repository_hint":"","source":null,"version":""},"repo":{"id":"0","key":"","name":"Ubuntu 18.04.3 LTS","uri":""}}},"Vulnerability":{"value":{"description":"In the GNU C Library (aka glibc or libc6) before 2.28,\nparse_reg_exp in posix/regcomp.c misparses alternatives,\nwhich allows attackers to cause a denial of service (assertion\nfailure and application exit) or trigger an incorrect result\nby attempting a regular-expression match.\"\n","dist":{"arch":"","cpe":"","did":"ubuntu","id":"0","name":"Ubuntu","pretty_name":"","version":"18.04.3 LTS (Bionic Beaver)","version_code_name":"bionic","version_id":"18.04"},"fixed_in_version":"2.28-0ubuntu1","id":"356835","issued":"2019-10-12T07:20:50.52Z","links":"https://cve.mitre.org/cgi-bin/cvename.cgi?name=CVE-2009-5155\nhttp://people.canonical.com/~ubuntu-security/cve/2009/CVE-2009-5155.html\nhttps://sourceware.org/bugzilla/show_bug.cgi?id=11053\nhttps://debbugs.gnu.org/cgi/bugreport.cgi?bug=22793\nhttps://debbugs.gnu.org/cgi/bugreport.cgi?bug=32806\nhttps://debbugs.gnu.org/cgi/bugreport.cgi?bug=34238\nhttps://sourceware.org/bugzilla/show_bug.cgi?id=18986\"\n","name":"CVE-2009-5155","normalized_severity":"Low","package":{"id":"0","kind":"","name":"glibc","package_db":"","repository_hint":"","source":null,"version":""},"repo":{"id":"0","key":"","name":"Ubuntu 18.04.3 LTS","uri":""},"severity":"Low","updater":""}}},"responses":{"BadRequest":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Bad Request"},"Forbidden":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Forbidden"},"InternalServerError":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Internal Server Error"},"MethodNotAllowed":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Method Not Allowed"},"NotAcceptable":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Not Acceptable"},"NotFound":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Not Found"}},"schemas":{"Annotations":{"additionalProperties":{"type":"string"},"description":"The key/value annotations clients attached to the manifest, such as\nthe owning team or the commit it was built from. Only present if it\nhas any.\n","example":{"git_sha":"9fceb02d0ae598e95dc970b74767f19372d61af8","team":"payments"},"title":"Annotations","type":"object"},"AnnotationsPatch":{"additionalProperties":{"nullable":true,"type":"string"},"description":"A change to a manifest's annotations. Keys with a string value are\nset, and keys with a null value are removed. Keys are at most 128\nbytes and values at most 4096.\n","example":{"environment":"production","git_sha":null},"title":"AnnotationsPatch","type":"object"},"AnnotationsResponse":{"description":"A manifest's annotations.","properties":{"annotations":{"$ref":"#/components/schemas/Annotations"}},"required":["annotations"],"title":"AnnotationsResponse","type":"object"},"Attestation":{"description":"An in-toto statement about the manifest, with the cosign \"vuln\"\npredicate. The predicate's \"scanner.result\" holds the\nVulnerabilityReport as it would be served, and \"scanner.db.version\"\nthe latest update operation.\n","properties":{"_type":{"example":"https://in-toto.io/Statement/v0.1","type":"string"},"predicate":{"type":"object"},"predicateType":{"example":"https://cosign.sigstore.dev/attestation/vuln/v1","type":"string"},"subject":{"items":{"properties":{"digest":{"additionalProperties":{"type":"string"},"type":"object"},"name":{"type":"string"}},"type":"object"},"type":"array"}},"title":"Attestation","type":"object"},"AttestationEnvelope":{"description":"A DSSE envelope holding an Attestation, signed with the report signing\nkey. The \"keyid\" of the signature names the key in the report keys\nset.\n","properties":{"payload":{"format":"byte","type":"string"},"payloadType":{"example":"application/vnd.in-toto+json","type":"string"},"signatures":{"items":{"properties":{"keyid":{"type":"string"},"sig":{"format":"byte","type":"string"}},"type":"object"},"type":"array"}},"title":"AttestationEnvelope","type":"object"},"Backlog":{"description":"The notification IDs waiting on delivery.","properties":{"failed":{"description":"The number of notification IDs that failed delivery, which can\nbe replayed with the admin API.\n","type":"integer"},"oldest_pending":{"description":"When the oldest notification ID waiting on delivery was created.\nOnly present if any are waiting.\n","format":"date-time","type":"string"},"pending":{"description":"The number of notification IDs waiting on delivery.","type":"integer"}},"required":["pending","failed"],"title":"Backlog","type":"object"},"BaseImage":{"description":"A manifest's probable base image: the leading layers it shares with\nother indexed manifests. Only present if the indexer detects base\nimages and found one.\n","properties":{"layers":{"description":"The manifest's layers that came with the base image","items":{"$ref":"#/components/schemas/Digest"},"type":"array"},"manifest":{"$ref":"#/components/schemas/Digest"},"shared":{"description":"The number of other indexed manifests starting with these layers","example":12,"type":"integer"}},"required":["layers","shared"],"title":"BaseImage","type":"object"},"BatchReportRequest":{"description":"The manifests to create VulnerabilityReports for.","properties":{"manifests":{"description":"Between 1 and 1000 manifest hashes.","items":{"$ref":"#/components/schemas/Digest"},"type":"array"}},"required":["manifests"],"title":"BatchReportRequest","type":"object"},"BatchReportResponse":{"description":"A result for each requested manifest, in request order.","properties":{"results":{"items":{"$ref":"#/components/schemas/BatchReportResult"},"type":"array"}},"required":["results"],"title":"BatchReportResponse","type":"object"},"BatchReportResult":{"description":"The VulnerabilityReport for a manifest, or the error that kept it\nfrom being created.\n","properties":{"error":{"$ref":"#/components/schemas/Error"},"manifest_hash":{"description":"The manifest hash, as requested.","type":"string"},"report":{"$ref":"#/components/schemas/VulnerabilityReport"}},"required":["manifest_hash"],"title":"BatchReportResult","type":"object"},"Callback":{"description":"A callback for clients to retrieve notifications","properties":{"callback":{"description":"the url where notifications can be retrieved","example":"http://clair-notifier/notifier/api/v1/notifications/269886f3-0146-4f08-9bf7-cb1138d48643","type":"string"},"notification_id":{"description":"the unique identifier for this set of notifications","example":"269886f3-0146-4f08-9bf7-cb1138d48643","type":"string"}},"title":"Callback","type":"object"},"Change":{"description":"How the vulnerability in a notification differs from what affected\nthe manifest as of the previous update operation. Not present for\nnotifications with the \"removed\" reason.\n","properties":{"fixed_in_version":{"example":"v0.0.1","type":"string"},"kinds":{"description":"The ways the vulnerability changed. \"added\" notifications are\nalways \"introduced\". \"changed\" notifications may have none, if\nnothing summarized here changed.\n","items":{"enum":["introduced","fixed","severity_changed"],"type":"string"},"type":"array"},"previous_fixed_in_version":{"example":"","type":"string"},"previous_severity":{"example":"Medium","type":"string"},"severity":{"example":"High","type":"string"}},"required":["kinds","severity"],"title":"Change","type":"object"},"ContentFinding":{"description":"Something a content hook reported in a layer.","properties":{"detail":{"type":"string"},"name":{"example":"Eicar-Signature","type":"string"},"path":{"description":"The file it was found in, if the hook reports one","type":"string"}},"required":["name"],"title":"ContentFinding","type":"object"},"ContentScan":{"description":"A content hook's scan of a layer, e.g. by ClamAV.","properties":{"error":{"description":"Why the scan didn't complete, if it didn't","example":"","type":"string"},"findings":{"items":{"$ref":"#/components/schemas/ContentFinding"},"type":"array"},"layer":{"$ref":"#/components/schemas/Digest"},"scanned":{"description":"When the layer was scanned","format":"date-time","type":"string"},"scanner":{"description":"The configured name of the hook","example":"clamav","type":"string"}},"required":["layer","scanner","findings","scanned"],"title":"ContentScan","type":"object"},"DeadLetterResponse":{"description":"Notifications that failed delivery.","properties":{"dead_letters":{"items":{"properties":{"notification_id":{"description":"The notification ID.","type":"string"},"since":{"description":"When the latest delivery attempt failed.","format":"date-time","type":"string"},"update_operation":{"description":"The update operation that created the notification.","type":"string"}},"type":"object"},"type":"array"}},"required":["dead_letters"],"title":"DeadLetterResponse","type":"object"},"DeliveriesResponse":{"description":"Delivery attempts for a notification ID.","properties":{"deliveries":{"description":"An entry per configured deliverer, followed by any deliverers no\nlonger configured that attempted delivery.\n","items":{"$ref":"#/components/schemas/DeliveryStatus"},"type":"array"},"notification_id":{"description":"The notification ID.","type":"string"}},"required":["notification_id","deliveries"],"title":"DeliveriesResponse","type":"object"},"DeliveryAttempt":{"description":"A single attempt at delivering a notification ID.","properties":{"deliverer":{"description":"The name of the deliverer.","type":"string"},"error":{"description":"Why the attempt failed.","type":"string"},"notification_id":{"description":"The notification ID.","type":"string"},"response_code":{"description":"The response code the target returned, if there was one.","type":"integer"},"status":{"description":"The outcome of the attempt. \"filtered\" means no notifications\npassed the deliverer's filter, so nothing was sent.\n","enum":["delivered","failed","filtered"],"type":"string"},"target":{"description":"Where the deliverer sent the notification ID.","type":"string"},"timestamp":{"description":"When the attempt finished.","format":"date-time","type":"string"}},"required":["notification_id","deliverer","timestamp","status"],"title":"DeliveryAttempt","type":"object"},"DeliveryStatus":{"description":"A deliverer's attempts at delivering a notification ID.","properties":{"attempts":{"description":"The deliverer's attempts, oldest first.","items":{"$ref":"#/components/schemas/DeliveryAttempt"},"type":"array"},"deliverer":{"description":"The name of the deliverer.","type":"string"},"next_attempt":{"description":"When delivery is next expected to be attempted. Absent once the\nnotification ID has been delivered.\n","format":"date-time","type":"string"},"target":{"description":"Where the deliverer sends notifications, if it reports it.","type":"string"}},"required":["deliverer","attempts"],"title":"DeliveryStatus","type":"object"},"Digest":{"description":"A digest string with prefixed algorithm. The format is described here:\nhttps://github.com/opencontainers/image-spec/blob/master/descriptor.md#digests\n\nDigests are used throughout the API to identify Layers and Manifests.\n","example":"sha256:fc84b5febd328eccaa913807716887b3eb5ed08bc22cc6933a9ebf82766725e3","title":"Digest","type":"string"},"Distribution":{"description":"An indexed distribution discovered in a layer. See\nhttps://www.freedesktop.org/software/systemd/man/os-release.html\nfor explanations and example of fields.\n","example":{"$ref":"#/components/examples/Distribution/value"},"properties":{"arch":{"type":"string"},"cpe":{"type":"string"},"did":{"type":"string"},"id":{"description":"A unique ID representing this distribution","type":"string"},"name":{"type":"string"},"pretty_name":{"type":"string"},"version":{"type":"string"},"version_code_name":{"type":"string"},"version_id":{"type":"string"}},"required":["id","did","name","version","version_code_name","version_id","arch","cpe","pretty_name"],"title":"Distribution","type":"object"},"DryRunFinding":{"description":"A vulnerability affecting a package.","properties":{"package":{"$ref":"#/components/schemas/Package"},"package_id":{"type":"string"},"vulnerability":{"$ref":"#/components/schemas/Vulnerability"}},"required":["package_id","vulnerability"],"title":"DryRunFinding","type":"object"},"DryRunResponse":{"description":"How a candidate snapshot would change manifests' reports.","properties":{"results":{"items":{"$ref":"#/components/schemas/DryRunResult"},"type":"array"},"updaters":{"description":"The updaters whose data the snapshot replaced.","items":{"type":"string"},"type":"array"},"vulnerabilities":{"description":"The number of vulnerabilities in the snapshot.","type":"integer"}},"required":["updaters","vulnerabilities","results"],"title":"DryRunResponse","type":"object"},"DryRunResult":{"description":"The diff for a manifest, or the error that kept it from being\ncomputed.\n","properties":{"diff":{"properties":{"added":{"description":"Findings only present with the snapshot.","items":{"$ref":"#/components/schemas/DryRunFinding"},"type":"array"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"removed":{"description":"Findings only present without the snapshot.","items":{"$ref":"#/components/schemas/DryRunFinding"},"type":"array"},"unchanged":{"description":"The number of findings from the snapshot's updaters present\neither way.\n","type":"integer"}},"type":"object"},"error":{"$ref":"#/components/schemas/Error"},"manifest_hash":{"description":"The manifest hash, as requested.","type":"string"}},"required":["manifest_hash"],"title":"DryRunResult","type":"object"},"EndOfLife":{"description":"A distribution past its end of life.","properties":{"date":{"description":"When the release stopped getting security updates","example":"2022-06-30","format":"date","type":"string"},"did":{"description":"The distribution's os-release ID","example":"debian","type":"string"},"name":{"example":"Debian GNU/Linux 9 (stretch)","type":"string"},"version_id":{"description":"The release's os-release VERSION_ID","example":"9","type":"string"}},"required":["did","version_id","date"],"title":"EndOfLife","type":"object"},"Environment":{"description":"The environment a particular package was discovered in.","properties":{"distribution_id":{"description":"The distribution ID found in an associated IndexReport or\nVulnerabilityReport.\n","example":"1","type":"string"},"introduced_in":{"$ref":"#/components/schemas/Digest"},"package_db":{"description":"The filesystem path or unique identifier of a package database.\n","example":"var/lib/dpkg/status","type":"string"}},"required":["package_db","introduced_in","distribution_id"],"title":"Environment","type":"object"},"Error":{"description":"A general error schema returned when status is not 200 OK","properties":{"code":{"description":"a code for this particular error. Besides codes specific to an endpoint, errors are classified as \"not-found\", \"conflict\", \"unauthenticated\", \"dependency-unavailable\", or \"internal-server-error\"","type":"string"},"message":{"description":"a message with further detail","type":"string"}},"title":"Error","type":"object"},"Exclusion":{"description":"A package excluded from an index report.","properties":{"package":{"example":"pytest","type":"string"},"package_db":{"description":"The package database, if it was excluded by path","example":"app/tests/fixtures/site-packages","type":"string"},"rule":{"description":"The configured pattern that matched","example":"**/fixtures/**","type":"string"},"version":{"example":"6.2.0","type":"string"}},"required":["package","version","rule"],"title":"Exclusion","type":"object"},"GraphQLRequest":{"properties":{"operationName":{"type":"string"},"query":{"example":"{ manifest(hash: \"sha256:...\") { packages { totalCount } } }","type":"string"},"variables":{"type":"object"}},"required":["query"],"title":"GraphQLRequest","type":"object"},"GraphQLResponse":{"description":"The query's result. \"data\" is absent if the query couldn't be run at\nall, and \"errors\" lists any problems.\n","properties":{"data":{"type":"object"},"errors":{"items":{"properties":{"locations":{"items":{"properties":{"column":{"type":"integer"},"line":{"type":"integer"}},"type":"object"},"type":"array"},"message":{"type":"string"},"path":{"items":{},"type":"array"}},"required":["message"],"type":"object"},"type":"array"}},"title":"GraphQLResponse","type":"object"},"IndexReport":{"description":"A report of the Index process for a particular manifest. A\nclient's usage of this is largely information. Clair uses this\nreport for matching Vulnerabilities.\n","properties":{"annotations":{"$ref":"#/components/schemas/Annotations"},"base_image":{"$ref":"#/components/schemas/BaseImage"},"distributions":{"additionalProperties":{"$ref":"#/components/schemas/Distribution"},"description":"A map of Distribution objects keyed by their Distribution.id\ndiscovered in the manifest.\n","example":{"1":{"$ref":"#/components/examples/Distribution/value"}},"type":"object"},"end_of_life":{"description":"The manifest's distributions past their end of life, which get no\nnew vulnerability data, so the report may be missing\nvulnerabilities. Only present if end of life detection is\nconfigured and any were found.\n","items":{"$ref":"#/components/schemas/EndOfLife"},"type":"array"},"environments":{"additionalProperties":{"items":{"$ref":"#/components/schemas/Environment"},"type":"array"},"description":"A map of lists containing Environment objects keyed by the\nassociated Package.id.\n","example":{"10":[{"distribution_id":"1","introduced_in":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","package_db":"var/lib/dpkg/status"}]},"type":"object"},"err":{"description":"An error message on event of unsuccessful index","example":"","type":"string"},"excluded":{"description":"Packages removed from the report by the indexer's exclusion\nrules. Only present if any were.\n","items":{"$ref":"#/components/schemas/Exclusion"},"type":"array"},"extensions":{"$ref":"#/components/schemas/ReportExtensions"},"layers":{"description":"The layers of the manifest and the packages each introduced. Only\npresent in \"application/vnd.clair.indexreport.v2+json\" responses.\n","items":{"$ref":"#/components/schemas/LayerAttribution"},"type":"array"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"package_scopes":{"$ref":"#/components/schemas/PackageScopes"},"packages":{"additionalProperties":{"$ref":"#/components/schemas/Package"},"description":"A map of Package objects indexed by Package.id","example":{"10":{"$ref":"#/components/examples/Package/value"}},"type":"object"},"referrers":{"description":"Artifacts attached to the manifest in its registry, such as SBOMs\nand provenance attestations. Only present if referrer discovery\nis configured and any were found.\n","items":{"$ref":"#/components/schemas/Referrer"},"type":"array"},"signature":{"$ref":"#/components/schemas/SignatureStatus"},"state":{"description":"The current state of the index operation","example":"IndexFinished","type":"string"},"success":{"description":"A bool indicating succcessful index","example":true,"type":"boolean"},"truncated":{"description":"Layers only scanned in part, for exceeding the indexer's\nper-layer scan limits. Only present if any were.\n","items":{"$ref":"#/components/schemas/Truncation"},"type":"array"}},"required":["manifest_hash","state","packages","distributions","environments","success","err"],"title":"IndexReport","type":"object"},"IndexerGCResponse":{"description":"What index report garbage collection removed.","properties":{"layers":{"type":"integer"},"manifests":{"type":"integer"}},"required":["manifests","layers"],"title":"IndexerGCResponse","type":"object"},"InventoryManifest":{"description":"An indexed manifest.","properties":{"annotations":{"$ref":"#/components/schemas/Annotations"},"first_indexed":{"description":"When the manifest was first indexed. Only known for manifests\nindexed since the inventory was configured.\n","format":"date-time","type":"string"},"last_indexed":{"description":"When the manifest was last submitted and indexed. Only known for\nmanifests indexed since the inventory was configured.\n","format":"date-time","type":"string"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"scanners":{"description":"The scanners the manifest was indexed with.","items":{"properties":{"kind":{"example":"package","type":"string"},"name":{"example":"dpkg","type":"string"},"version":{"example":"4","type":"string"}},"type":"object"},"type":"array"},"state":{"description":"The state of the manifest's IndexReport.","example":"IndexFinished","type":"string"}},"required":["manifest_hash","state","scanners"],"title":"InventoryManifest","type":"object"},"InventoryResponse":{"description":"A page of indexed manifests.","properties":{"manifests":{"items":{"$ref":"#/components/schemas/InventoryManifest"},"type":"array"},"page":{"properties":{"next":{"description":"The value to pass as \"next\" for the following page. Only\npresent if there is one.\n","type":"string"},"size":{"type":"integer"}},"type":"object"}},"required":["page","manifests"],"title":"InventoryResponse","type":"object"},"Layer":{"description":"A Layer within a Manifest and where Clair may retrieve it.","properties":{"hash":{"$ref":"#/components/schemas/Digest"},"headers":{"additionalProperties":{"items":{"type":"string"},"type":"array"},"description":"map of arrays of header values keyed by header\nvalue. e.g. map[string][]string\n","type":"object"},"media_type":{"description":"The layer's media type, from the image manifest. Foreign, or\nnon-distributable, layers, like the base layers of Windows\nimages, are fetched from the URI like any other, unless the\nindexer is configured to skip them. Layers with other media types\nthe indexer can't read are rejected or skipped, as configured.\nLayers without a media type are always indexed.\n","example":"application/vnd.oci.image.layer.v1.tar+gzip","type":"string"},"uri":{"description":"A URI describing where the layer may be found. Implementations\nMUST support http(s) schemes and MAY support additional\nschemes.\n","example":"https://storage.example.com/blob/2f077db56abccc19f16f140f629ae98e904b4b7d563957a7fc319bd11b82ba36\n","type":"string"}},"required":["hash","uri","headers"],"title":"Layer","type":"object"},"LayerAttribution":{"description":"What one layer of a manifest introduced, for telling findings in a\nbase image from ones in the layers built on top of it.\n","properties":{"hash":{"$ref":"#/components/schemas/Digest"},"index":{"description":"The layer's position in the manifest, starting from the base, or\n-1 if the order of the layers isn't known.\n","example":0,"type":"integer"},"packages":{"description":"The Package.id of each package the layer introduced.","example":["10"],"items":{"type":"string"},"type":"array"},"vulnerabilities":{"description":"The Vulnerability.id of each vulnerability affecting those\npackages. Only present in vulnerability reports.\n","example":["356835"],"items":{"type":"string"},"type":"array"}},"required":["hash","index","packages"],"title":"LayerAttribution","type":"object"},"Manifest":{"description":"A Manifest representing a container. The 'layers' array must\npreserve the original container's layer order for accurate usage.\n","properties":{"hash":{"$ref":"#/components/schemas/Digest"},"layers":{"items":{"$ref":"#/components/schemas/Layer"},"type":"array"}},"required":["hash","layers"],"title":"Manifest","type":"object"},"MatcherGCResponse":{"description":"What update operation garbage collection removed.","properties":{"update_operations":{"type":"integer"}},"required":["update_operations"],"title":"MatcherGCResponse","type":"object"},"MigrateResponse":{"description":"The version of each set of migrations.","properties":{"migrations":{"items":{"properties":{"table":{"type":"string"},"version":{"type":"integer"}},"type":"object"},"type":"array"}},"required":["migrations"],"title":"MigrateResponse","type":"object"},"Notification":{"description":"A notification expressing a change in a manifest affected by a\nvulnerability.\n","properties":{"annotations":{"$ref":"#/components/schemas/Annotations"},"change":{"$ref":"#/components/schemas/Change"},"id":{"description":"a unique identifier for this notification","example":"5e4b387e-88d3-4364-86fd-063447a6fad2","type":"string"},"manifest":{"description":"The hash of the manifest affected by the provided vulnerability.\n","example":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","type":"string"},"origin":{"description":"Whether the affected package came with the manifest's base image.\nOnly present if the notifier attributes layers and a base image\nwas detected for the manifest.\n","enum":["base","application"],"type":"string"},"reason":{"description":"the reason for the notifcation, [added | removed | changed]","example":"added","type":"string"},"vulnerability":{"$ref":"#/components/schemas/VulnSummary"}},"title":"Notification","type":"object"},"Package":{"description":"A package discovered by indexing a Manifest","example":{"$ref":"#/components/examples/Package/value"},"properties":{"arch":{"description":"The package's target system architecture","type":"string"},"cpe":{"description":"A CPE identifying the package","type":"string"},"id":{"description":"A unique ID representing this package","type":"string"},"kind":{"description":"Kind of package. Source | Binary","type":"string"},"module":{"description":"A module further defining a namespace for a package","type":"string"},"name":{"description":"Name of the Package","type":"string"},"normalized_version":{"$ref":"#/components/schemas/Version"},"source":{"$ref":"#/components/schemas/SourcePackage"},"version":{"description":"Version of the Package","type":"string"}},"required":["id","name","version"],"title":"Package","type":"object"},"PackageScopes":{"additionalProperties":{"enum":["runtime","development"],"type":"string"},"description":"The scope of each package whose scope is known, keyed by Package.id:\n\"runtime\" for packages the application needs to run, and\n\"development\" for packages only needed to build or test it. Scopes\nare known for Python packages named in a dependency manifest, when a\n\"scope\" indexer hook is configured.\n","example":{"10":"development"},"title":"PackageScopes","type":"object"},"Page":{"description":"A page object indicating to the client how to retrieve multiple pages of\na particular entity.\n","properties":{"next":{"description":"The next id to submit to the api to continue paging","example":"1b4d0db2-e757-4150-bbbb-543658144205","type":"string"},"size":{"description":"The maximum number of elements in a page","example":1,"type":"int"}},"title":"Page"},"PagedAffectedManifests":{"description":"A page of manifests affected by a vulnerability.","properties":{"manifests":{"items":{"properties":{"manifest_hash":{"$ref":"#/components/schemas/Digest"},"vulnerabilities":{"description":"The IDs of the vulnerabilities affecting the manifest.","items":{"type":"string"},"type":"array"}},"type":"object"},"type":"array"},"page":{"description":"The page size and, if there are more manifests, the \"next\" value\nto request the following page with.\n","example":{"next":"sha256:fc84b5febd328eccaa913807716887b3eb5ed08bc22cc6933a9ebf82766725e3","size":100},"type":"object"},"vulnerabilities":{"additionalProperties":{"$ref":"#/components/schemas/Vulnerability"},"description":"The vulnerabilities referenced in the page, keyed by ID.","type":"object"}},"required":["page","vulnerabilities","manifests"],"title":"PagedAffectedManifests","type":"object"},"PagedNotifications":{"description":"A page object followed by a list of notifications","properties":{"notifications":{"description":"A list of notifications within this page","items":{"$ref":"#/components/schemas/Notification"},"type":"array"},"page":{"description":"A page object informing the client the next page to retrieve.\nIf page.next becomes \"-1\" the client should stop paging.\n","example":{"next":"1b4d0db2-e757-4150-bbbb-543658144205","size":100},"type":"object"}},"title":"PagedNotifications","type":"object"},"PolicyDecision":{"description":"The outcome of evaluating policy against a manifest.","properties":{"allow":{"description":"Whether the manifest passed every policy.","type":"boolean"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"violations":{"description":"The values produced by the \"deny\" rule of the \"clair\" package.\nThese are usually strings.\n","items":{},"type":"array"}},"required":["manifest_hash","allow","violations"],"title":"PolicyDecision","type":"object"},"PolicyRequest":{"description":"A request to evaluate policy against a manifest.","properties":{"manifest_hash":{"$ref":"#/components/schemas/Digest"}},"required":["manifest_hash"],"title":"PolicyRequest","type":"object"},"PurgeResponse":{"description":"The outcome of purging notifications.","properties":{"purged":{"description":"The number of notification IDs removed.","type":"integer"}},"required":["purged"],"title":"PurgeResponse","type":"object"},"Referrer":{"description":"An artifact attached to a manifest.","properties":{"annotations":{"additionalProperties":{"type":"string"},"type":"object"},"artifact_type":{"example":"application/spdx+json","type":"string"},"digest":{"description":"The digest of the referrer's manifest","example":"sha256:fc84b5febd328eccaa913807716887b3eb5ed08bc22cc6933a9ebf82766725e3","type":"string"},"error":{"description":"Why an SBOM couldn't be used","type":"string"},"kind":{"enum":["sbom","provenance","signature","other"],"example":"sbom","type":"string"},"packages":{"description":"The number of packages added to the report from an SBOM","type":"integer"}},"required":["digest","artifact_type","kind"],"title":"Referrer","type":"object"},"ReplayResponse":{"description":"The outcome of replaying notifications.","properties":{"replayed":{"description":"The number of notification IDs queued for delivery.","type":"integer"}},"required":["replayed"],"title":"ReplayResponse","type":"object"},"ReportExtensions":{"description":"Results of indexer extensions inspecting layers beyond package\ndiscovery. Only present if any are configured and produced results.\n","properties":{"content":{"description":"What the configured content hooks found in each layer","items":{"$ref":"#/components/schemas/ContentScan"},"type":"array"}},"title":"ReportExtensions","type":"object"},"ReportHistory":{"description":"The recorded versions of a manifest's VulnerabilityReport, newest\nfirst.\n","properties":{"history":{"items":{"$ref":"#/components/schemas/ReportHistoryEntry"},"type":"array"},"manifest_hash":{"$ref":"#/components/schemas/Digest"}},"title":"ReportHistory","type":"object"},"ReportHistoryEntry":{"description":"One version of a manifest's VulnerabilityReport.\n","properties":{"created":{"description":"When the version was first generated.","format":"date-time","type":"string"},"digest":{"$ref":"#/components/schemas/Digest"},"severities":{"additionalProperties":{"type":"integer"},"description":"The number of vulnerabilities of each normalized severity.","type":"object"},"update_operation":{"description":"The latest update operation when the report was generated.","format":"uuid","type":"string"},"vulnerabilities":{"description":"The number of vulnerabilities in the report.","type":"integer"}},"title":"ReportHistoryEntry","type":"object"},"ReportRecord":{"description":"One line of a streamed VulnerabilityReport.\n\nThe first record is always of kind \"manifest\". Distributions,\nrepositories, and vulnerabilities follow, then every package\nfollowed by its environments and vulnerability IDs, and finally any\nVEX suppressions.\n","properties":{"id":{"description":"The value's key in the VulnerabilityReport. For \"environments\"\nand \"package_vulnerabilities\" records, the package ID.\n","type":"string"},"kind":{"enum":["manifest","distribution","repository","vulnerability","package","environments","package_vulnerabilities","vex"],"type":"string"},"value":{"description":"The object, shaped as in the VulnerabilityReport."}},"required":["kind","value"],"title":"ReportRecord","type":"object"},"Repository":{"description":"A package repository","properties":{"cpe":{"type":"string"},"id":{"type":"string"},"key":{"type":"string"},"name":{"type":"string"},"uri":{"type":"string"}},"title":"Repository","type":"object"},"SignatureStatus":{"description":"The outcome of verifying a manifest's cosign signatures. Only present\nif signature verification is configured.\n","properties":{"checked":{"description":"When verification happened","format":"date-time","type":"string"},"reason":{"description":"Why the manifest didn't verify","example":"","type":"string"},"signer":{"description":"The key or certificate identity that verified the manifest","example":"builder@example.com","type":"string"},"status":{"enum":["verified","unsigned","invalid","error"],"example":"verified","type":"string"}},"required":["status","checked"],"title":"SignatureStatus","type":"object"},"SignedReport":{"description":"A JWS in compact serialization, with a \"typ\" header of\n\"application/vnd.clair.report.v1+jws\" and a \"kid\" header naming the\nkey in the report keys set.\n\nThe payload is a JSON object with the members \"version\" (currently\n\"v1\"), \"issued_at\", and \"report\", which holds the VulnerabilityReport\nas it would be served unsigned.\n","title":"SignedReport","type":"string"},"SourcePackage":{"description":"A source package affiliated with a Package","example":{"$ref":"#/components/examples/Package/value"},"properties":{"arch":{"type":"string"},"cpe":{"description":"A CPE identifying the package","type":"string"},"id":{"description":"A unique ID representing this package","type":"string"},"kind":{"description":"Kind of package. Source | Binary","type":"string"},"module":{"type":"string"},"name":{"description":"Name of the Package","type":"string"},"normalized_version":{"$ref":"#/components/schemas/Version"},"source":{"type":"string"},"version":{"description":"Version of the Package","type":"string"}},"required":["id","name","version"],"title":"SourcePackage","type":"object"},"State":{"description":"an opaque identifier","example":{"state":"aae368a064d7c5a433d0bf2c4f5554cc"},"properties":{"state":{"description":"an opaque identifier","type":"string"}},"required":["state"],"title":"State","type":"object"},"StreamEvent":{"description":"A page of notifications sent in a notification stream","properties":{"notification_id":{"description":"the unique identifier for this set of notifications","example":"269886f3-0146-4f08-9bf7-cb1138d48643","type":"string"},"notifications":{"description":"A list of notifications within this page","items":{"$ref":"#/components/schemas/Notification"},"type":"array"}},"title":"StreamEvent","type":"object"},"Truncation":{"description":"A layer scanned in part. Packages in the rest of the layer are missing\nfrom the report.\n","properties":{"files":{"description":"The number of entries scanned before the limit","example":100000,"type":"integer"},"layer":{"$ref":"#/components/schemas/Digest"},"reason":{"description":"The limit that was exceeded","enum":["files","size"],"type":"string"},"size":{"description":"The bytes of file contents scanned before the limit","example":1073741824,"type":"integer"}},"required":["layer","files","size","reason"],"title":"Truncation","type":"object"},"UpdaterOverride":{"description":"An override for an updater set or updater.","properties":{"config":{"description":"Configuration used in place of the configuration file's.","type":"object"},"disabled":{"description":"Excludes the updater set or updater from update runs.","type":"boolean"}},"title":"UpdaterOverride","type":"object"},"UpdaterOverrides":{"additionalProperties":{"$ref":"#/components/schemas/UpdaterOverride"},"description":"Updater overrides, keyed by updater set or updater name.","title":"UpdaterOverrides","type":"object"},"UpdaterRunResponse":{"description":"The new update operation for each updater that found changes.","properties":{"updated":{"additionalProperties":{"type":"string"},"type":"object"}},"required":["updated"],"title":"UpdaterRunResponse","type":"object"},"VEXDocument":{"description":"A VEX document in use by the matcher.","properties":{"format":{"enum":["openvex","csaf"],"type":"string"},"id":{"description":"The document's ID.","type":"string"},"statements":{"description":"The number of statements in the document.","type":"integer"}},"required":["id","format","statements"],"title":"VEXDocument","type":"object"},"Version":{"description":"Version is a normalized claircore version, composed of a \"kind\" and an\narray of integers such that two versions of the same kind have the\ncorrect ordering when the integers are compared pair-wise.\n","example":"pep440:0.0.0.0.0.0.0.0.0","title":"Version","type":"string"},"VulnSummary":{"description":"A summary of a vulnerability","properties":{"description":{"description":"the vulnerability name","example":"In the GNU C Library (aka glibc or libc6) before 2.28,\nparse_reg_exp in posix/regcomp.c misparses alternatives,\nwhich allows attackers to cause a denial of service (assertion\nfailure and application exit) or trigger an incorrect result\nby attempting a regular-expression match.\"\n","type":"string"},"distribution":{"$ref":"#/components/schemas/Distribution"},"fixed_in_version":{"description":"The version which the vulnerability is fixed in. Empty if not fixed.\n","example":"v0.0.1","type":"string"},"links":{"description":"links to external information about vulnerability","example":"http://link-to-advisory","type":"string"},"name":{"description":"the vulnerability name","example":"CVE-2009-5155","type":"string"},"normalized_severity":{"description":"A well defined set of severity strings guaranteed to be present.\n","enum":["Unknown","Negligible","Low","Medium","High","Critical"],"type":"string"},"package":{"$ref":"#/components/schemas/Package"},"purl":{"description":"The package URL of the vulnerable package, as the vulnerability\nnames it, so usually without a version. Only present if the\npackage's ecosystem has a package URL type.\n","example":"pkg:deb/debian/glibc?distro=debian-10","type":"string"},"repository":{"$ref":"#/components/schemas/Repository"}},"title":"VulnSummary","type":"object"},"Vulnerability":{"description":"A unique vulnerability indexed by Clair","example":{"$ref":"#/components/examples/Vulnerability/value"},"properties":{"description":{"description":"A description of this specific vulnerability.","type":"string"},"distribution":{"$ref":"#/components/schemas/Distribution"},"fixed_in_version":{"description":"A unique ID representing this vulnerability.","type":"string"},"id":{"description":"A unique ID representing this vulnerability.","type":"string"},"issued":{"description":"The timestamp in which the vulnerability was issued\n","type":"string"},"links":{"description":"A space separate list of links to any external information.\n","type":"string"},"name":{"description":"Name of this specific vulnerability.","type":"string"},"normalized_severity":{"description":"A well defined set of severity strings guaranteed to be present.\n","enum":["Unknown","Negligible","Low","Medium","High","Critical"],"type":"string"},"package":{"$ref":"#/components/schemas/Package"},"range":{"description":"The range of package versions affected by this vulnerability.\n","type":"string"},"repository":{"$ref":"#/components/schemas/Repository"},"severity":{"description":"A severity keyword taken verbatim from the vulnerability source.\n","type":"string"},"updater":{"description":"A unique ID representing this vulnerability.","type":"string"}},"required":["id","updater","name","description","links","severity","normalized_severity","fixed_in_version"],"title":"Vulnerability","type":"object"},"VulnerabilityReport":{"description":"A report expressing discovered packages, package environments,\nand package vulnerabilities within a Manifest.\n","properties":{"annotations":{"$ref":"#/components/schemas/Annotations"},"base_image":{"$ref":"#/components/schemas/BaseImage"},"distributions":{"additionalProperties":{"$ref":"#/components/schemas/Distribution"},"description":"A map of Distribution objects indexed by Distribution.id.\n","example":{"1":{"$ref":"#/components/examples/Distribution/value"}},"type":"object"},"end_of_life":{"description":"The manifest's distributions past their end of life, which get no\nnew vulnerability data, so the report may be missing\nvulnerabilities. Only present if end of life detection is\nconfigured and any were found.\n","items":{"$ref":"#/components/schemas/EndOfLife"},"type":"array"},"environments":{"additionalProperties":{"items":{"$ref":"#/components/schemas/Environment"},"type":"array"},"description":"A mapping of Environment lists indexed by Package.id","example":{"10":[{"distribution_id":"1","introduced_in":"sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a","package_db":"var/lib/dpkg/status"}]},"type":"object"},"layers":{"description":"The layers of the manifest and the packages and vulnerabilities\neach introduced. Only present in\n\"application/vnd.clair.vulnerabilityreport.v2+json\" responses.\n","items":{"$ref":"#/components/schemas/LayerAttribution"},"type":"array"},"manifest_hash":{"$ref":"#/components/schemas/Digest"},"package_purls":{"additionalProperties":{"enum":["base","application"],"type":"string"},"description":"The package URL of each package, keyed by Package.id, for\ncorrelating findings with SBOMs and other scanners. Packages\nwhose ecosystem has no package URL type are left out.\n","example":{"10":"pkg:deb/debian/libc6@2.28-10?arch=amd64\u0026distro=debian-10"},"type":"object"},"package_scopes":{"$ref":"#/components/schemas/PackageScopes"},"package_vulnerabilities":{"additionalProperties":{"items":{"type":"string"},"type":"array"},"description":"A mapping of Vulnerability.id lists indexed by Package.id.\n","example":{"10":["356835"]}},"packages":{"additionalProperties":{"$ref":"#/components/schemas/Package"},"description":"A map of Package objects indexed by Package.id","example":{"10":{"$ref":"#/components/examples/Package/value"}},"type":"object"},"vulnerabilities":{"additionalProperties":{"$ref":"#/components/schemas/Vulnerability"},"description":"A map of Vulnerabilities indexed by Vulnerability.id","example":{"356835":{"$ref":"#/components/examples/Vulnerability/value"}},"type":"object"},"vulnerability_origins":{"additionalProperties":{"type":"string"},"description":"Whether each vulnerability, keyed by id, was inherited from the\nbase image or introduced by the layers built on top of it. Only\npresent if a base image was detected.\n","example":{"356835":"base"},"type":"object"}},"required":["manifest_hash","packages","distributions","environments","vulnerabilities","package_vulnerabilities"],"title":"VulnerabilityReport","type":"object"}}},"info":{"contact":{"email":"quay-devel@redhat.com","name":"Clair Team","url":"http://github.com/quay/clair"},"description":"ClairV4 is a set of cooperating microservices which scan, index, and\nmatch your container's content with known vulnerabilities.\n","license":{"name":"Apache License 2.0","url":"http://www.apache.org/licenses/"},"termsOfService":"","title":"ClairV4","version":"1.1"},"openapi":"3.0.2","paths":{"indexer/api/v1/admin/gc":{"post":{"description":"Runs index report garbage collection to completion. Responds 501 if\ngarbage collection is not configured.\n","operationId":"CollectIndexReports","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexerGCResponse"}}},"description":"What was removed"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run index report garbage collection.","tags":["Indexer"]}},"indexer/api/v1/admin/manifest/{manifest_hash}":{"delete":{"description":"Removes the manifest and its index report, along with any of its\nlayers no other manifest uses.\n","operationId":"DeleteManifest","parameters":[{"in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"204":{"description":"The manifest was deleted"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Delete a manifest and its index report.","tags":["Indexer"]}},"indexer/api/v1/admin/migrate":{"post":{"operationId":"MigrateIndexer","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/MigrateResponse"}}},"description":"The resulting migration versions"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run outstanding indexer database migrations.","tags":["Indexer"]}},"indexer/api/v1/index_report":{"get":{"description":"Lists the manifests with an IndexReport, in order of their hash, with\nwhen they were indexed, their state, the scanners that indexed them,\nand their annotations. Responds 501 if the inventory is not\nconfigured.\n\nManifests indexed before the inventory was configured have no\ntimes, and are left out when filtering by age.\n","operationId":"ListManifests","parameters":[{"description":"The most manifests to return. At most 1000; the default is 500.\n","in":"query","name":"page_size","schema":{"type":"integer"}},{"description":"The \"next\" member of the previous page, to retrieve the\nfollowing one.\n","in":"query","name":"next","schema":{"type":"string"}},{"description":"Only list manifests last indexed longer ago than this duration,\ne.g. \"720h\".\n","in":"query","name":"older_than","schema":{"type":"string"}},{"description":"Only list manifests last indexed within this duration, e.g.\n\"24h\".\n","in":"query","name":"newer_than","schema":{"type":"string"}},{"description":"Only list manifests with this annotation, as \"key=value\". May be\nrepeated; manifests must have all of them.\n","explode":true,"in":"query","name":"annotation","schema":{"items":{"type":"string"},"type":"array"},"style":"form"}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/InventoryResponse"}}},"description":"A page of manifests"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"List the indexed Manifests.","tags":["Indexer"]},"post":{"description":"By submitting a Manifest object to this endpoint Clair will fetch the\nlayers, scan each layer's contents, and provide an index of discovered\npackages, repository and distribution information.\n\nIf the \"If-None-Match\" header matches the Etag of the manifest's\ncurrent IndexReport, the manifest is not indexed again.\n\nRequesting the \"application/vnd.clair.indexreport.v2+json\" media type\nadds the layers of the manifest, in order, with the packages each\nintroduced.\n","operationId":"Index","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Manifest"}}},"required":true},"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexReport"}},"application/vnd.clair.indexreport.v2+json":{"schema":{"$ref":"#/components/schemas/IndexReport"}}},"description":"IndexReport Created","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"The manifest's signatures didn't verify and signature verification\nis enforced.\n"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"412":{"description":"IndexReport Unchanged"},"415":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"A layer has a media type the indexer can't read, and the indexer\nisn't configured to skip those, or no layers were left to index.\n"},"500":{"$ref":"#/components/responses/InternalServerError"},"503":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Too many index requests are queued; retry after the delay in the\n\"Retry-After\" header.\n","headers":{"Retry-After":{"description":"Seconds to wait before retrying","schema":{"type":"integer"}}}}},"summary":"Index the contents of a Manifest","tags":["Indexer"]}},"indexer/api/v1/index_report/{manifest_hash}":{"get":{"description":"Given a Manifest's content addressable hash an IndexReport will\nbe retrieved if exists.\n\nThe Etag changes when the IndexReport does, or when the indexer's\nstate means the manifest should be indexed again.\n\nRequesting the \"application/vnd.clair.indexreport.v2+json\" media type\nadds the layers of the manifest, in order, with the packages each\nintroduced.\n","operationId":"GetIndexReport","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexReport"}},"application/vnd.clair.indexreport.v2+json":{"schema":{"$ref":"#/components/schemas/IndexReport"}}},"description":"IndexReport retrieved","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"304":{"description":"IndexReport Unchanged"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve an IndexReport for the given Manifest hash if exists.","tags":["Indexer"]}},"indexer/api/v1/index_report/{manifest_hash}/annotations":{"get":{"description":"Retrieves the key/value annotations clients attached to the\nmanifest. Responds 501 if annotations are not configured.\n","operationId":"GetAnnotations","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/AnnotationsResponse"}}},"description":"Annotations retrieved"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve the annotations attached to a Manifest.","tags":["Indexer"]},"parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"patch":{"description":"Applies a JSON merge patch to the manifest's annotations: keys with a\nstring value are set, and keys with a null value are removed.\nAnnotations survive the manifest being indexed again, and are\nincluded in its index report, vulnerability reports, and, if the\nnotifier is configured to, notifications.\n\nResponds 400 if the patch would leave the manifest with more\nannotations than allowed, and 501 if annotations are not configured.\n","operationId":"PatchAnnotations","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/AnnotationsPatch"}},"application/merge-patch+json":{"schema":{"$ref":"#/components/schemas/AnnotationsPatch"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/AnnotationsResponse"}}},"description":"The manifest's annotations after the change"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Change the annotations attached to a Manifest.","tags":["Indexer"]}},"indexer/api/v1/index_state":{"get":{"description":"The index state endpoint returns a json structure indicating the\nindexer's internal configuration state.\n\nA client may be interested in this as a signal that manifests may need\nto be re-indexed.\n","operationId":"IndexState","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/State"}}},"description":"Indexer State","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"304":{"description":"Indexer State Unchanged"}},"summary":"Report the indexer's internal configuration and state.","tags":["Indexer"]}},"indexer/api/v1/layers/{digest}":{"head":{"operationId":"CheckLayer","responses":{"200":{"description":"Layer present"},"404":{"description":"Layer not present"}},"summary":"Report whether a layer has been uploaded.","tags":["Indexer"]},"parameters":[{"description":"The digest of the layer's contents.","in":"path","name":"digest","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"put":{"description":"Stores a layer for indexing. Layers in a submitted Manifest with an\nempty URI are read from uploads, so clients can index layers Clair\ncan't fetch. Uploads expire after a configured time.\n\nThis endpoint is only available if uploads are configured.\n","operationId":"UploadLayer","requestBody":{"content":{"application/octet-stream":{"schema":{"format":"binary","type":"string"}}},"required":true},"responses":{"201":{"description":"Layer stored"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"413":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Layer too large"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Upload a layer's contents.","tags":["Indexer"]}},"matcher/api/v1/admin/dryrun":{"post":{"description":"Loads the newline delimited Vulnerability objects in the request\nbody, in the format served by the updates export endpoint, as a\ncandidate snapshot and matches the named manifests against it. The\nsnapshot replaces the data of every updater it has vulnerabilities\nfor; nothing is written to the database.\n\nEach manifest's result reports the findings from those updaters that\nthe snapshot would add and remove. A manifest that isn't found has\nan error in its result instead; it doesn't fail the request.\n","operationId":"DryRunSnapshot","parameters":[{"description":"A manifest hash to match. May be given up to 100 times.","explode":true,"in":"query","name":"manifest","required":true,"schema":{"items":{"type":"string"},"type":"array"}}],"requestBody":{"content":{"application/x-ndjson":{"schema":{"$ref":"#/components/schemas/Vulnerability"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/DryRunResponse"}}},"description":"The diff for each manifest"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"413":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"The snapshot is too large."},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Match manifests against a candidate vulnerability snapshot.","tags":["Matcher"]}},"matcher/api/v1/admin/gc":{"post":{"operationId":"CollectUpdateOperations","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/MatcherGCResponse"}}},"description":"What was removed"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run update operation garbage collection.","tags":["Matcher"]}},"matcher/api/v1/admin/migrate":{"post":{"operationId":"MigrateMatcher","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/MigrateResponse"}}},"description":"The resulting migration versions"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run outstanding matcher database migrations.","tags":["Matcher"]}},"matcher/api/v1/admin/updaters/run":{"post":{"description":"Runs every configured updater once, responding when all have\nfinished.\n","operationId":"RunUpdaters","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/UpdaterRunResponse"}}},"description":"The updaters that found changes"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run the updaters.","tags":["Matcher"]}},"matcher/api/v1/affected_manifests":{"get":{"description":"Looks up the current vulnerabilities with the provided name or ID and\nreports the indexed manifests they affect, ordered by manifest hash.\n\nA vulnerability name may match several vulnerabilities, e.g. one per\ndistribution release. The \"namespace\" parameter restricts the lookup\nto an updater or distribution ID.\n\nWith tenancy, only manifests the requesting tenant submitted are\nreported. Nodes unable to scope the results refuse the request.\n","operationId":"GetAffectedManifests","parameters":[{"description":"A vulnerability name, such as a CVE, or ID.","in":"query","name":"vulnerability_id","required":true,"schema":{"type":"string"}},{"description":"An updater name or distribution ID, e.g. \"debian\".","in":"query","name":"namespace","required":false,"schema":{"type":"string"}},{"description":"The maximum number of manifests in the page.","in":"query","name":"page_size","required":false,"schema":{"type":"integer"}},{"description":"The \"page.next\" value of the previous page.","in":"query","name":"next","required":false,"schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PagedAffectedManifests"}}},"description":"A page of affected manifests"},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"$ref":"#/components/responses/Forbidden"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"List the indexed manifests affected by a vulnerability.","tags":["Matcher"]}},"matcher/api/v1/graphql":{"get":{"description":"Runs the GraphQL query in the \"query\" parameter over manifests'\nindex and vulnerability reports. Without a query, returns the schema\nin the GraphQL schema definition language. This endpoint is only\navailable when GraphQL is configured.\n","operationId":"GraphQLQuery","parameters":[{"in":"query","name":"query","schema":{"type":"string"}},{"in":"query","name":"operationName","schema":{"type":"string"}},{"description":"A JSON object of variables","in":"query","name":"variables","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/GraphQLResponse"}},"text/plain":{"schema":{"type":"string"}}},"description":"A GraphQL response, or the schema"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Run a GraphQL query over reports, or retrieve the schema.","tags":["Matcher"]},"post":{"description":"Runs a GraphQL query over manifests' index and vulnerability reports.\nThis endpoint is only available when GraphQL is configured.\n","operationId":"GraphQLQueryPost","requestBody":{"content":{"application/graphql":{"schema":{"type":"string"}},"application/json":{"schema":{"$ref":"#/components/schemas/GraphQLRequest"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/GraphQLResponse"}}},"description":"A GraphQL response"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Run a GraphQL query over reports.","tags":["Matcher"]}},"matcher/api/v1/policy/evaluate":{"post":{"description":"Given a Manifest's content addressable hash a VulnerabilityReport\nwill be created and evaluated against the configured Rego policies.\nThe Manifest **must** have been Indexed first via the Index endpoint.\n\nThis endpoint is only available if policies are configured.\n","operationId":"EvaluatePolicy","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PolicyRequest"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PolicyDecision"}}},"description":"Policy Decision"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Evaluate the configured policies against a manifest's\nVulnerabilityReport.\n","tags":["Matcher"]}},"matcher/api/v1/report_keys":{"get":{"description":"Returns the JWK set holding the public key used to sign vulnerability\nreports. This endpoint is only available when report signing is\nconfigured.\n","operationId":"GetReportKeys","responses":{"200":{"content":{"application/jwk-set+json":{"schema":{"type":"object"}}},"description":"A JWK set"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Retrieve the keys signed vulnerability reports are verified with.","tags":["Matcher"]}},"matcher/api/v1/updaters/config":{"delete":{"operationId":"DeleteUpdaterOverride","parameters":[{"description":"The updater set or updater name.","in":"query","name":"name","required":true,"schema":{"type":"string"}}],"responses":{"204":{"description":"Updater override removed"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Remove an updater override.","tags":["Matcher"]},"get":{"description":"Reports the overrides disabling or reconfiguring updater sets and\nupdaters, keyed by updater set or updater name.\n\nThis endpoint is only available if updater overrides are configured.\n","operationId":"GetUpdaterOverrides","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/UpdaterOverrides"}}},"description":"Updater overrides"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Report the updater overrides.","tags":["Matcher"]},"put":{"description":"Stores the provided overrides, replacing any existing ones with the\nsame names. Overrides not named in the request are left alone.\nChanges take effect at the next update run.\n\nThis endpoint is only available if updater overrides are configured.\n","operationId":"SetUpdaterOverrides","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/UpdaterOverrides"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/UpdaterOverrides"}}},"description":"Updater overrides"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Set updater overrides.","tags":["Matcher"]}},"matcher/api/v1/vex":{"delete":{"operationId":"DeleteVEXDocument","parameters":[{"description":"The document ID.","in":"query","name":"id","required":true,"schema":{"type":"string"}}],"responses":{"204":{"description":"VEX Document removed"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Remove an uploaded VEX document.","tags":["Matcher"]},"get":{"description":"Lists the VEX documents used to suppress vulnerabilities, both those\nloaded from the configuration and those uploaded.\n\nThis endpoint is only available if VEX is configured.\n","operationId":"ListVEXDocuments","responses":{"200":{"content":{"application/json":{"schema":{"items":{"$ref":"#/components/schemas/VEXDocument"},"type":"array"}}},"description":"VEX Documents"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"List the VEX documents in use.","tags":["Matcher"]},"post":{"description":"Stores an OpenVEX or CSAF VEX document. A document with the same ID\nreplaces any previously uploaded one.\n\nThis endpoint is only available if VEX is configured.\n","operationId":"UploadVEXDocument","requestBody":{"content":{"application/json":{"schema":{}}},"required":true},"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/VEXDocument"}}},"description":"VEX Document stored"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Upload a VEX document.","tags":["Matcher"]}},"matcher/api/v1/vulnerability_report/":{"post":{"description":"Given an IndexReport a VulnerabilityReport will be created, without\nthe Manifest needing to be Indexed. This is used to match index\nreports produced elsewhere, such as ones converted from an SBOM by\n\"clairctl import-sbom\".\n\nRequesting the \"application/x-ndjson\" media type returns the report\nas a stream of newline delimited ReportRecord objects.\n\nRequesting the \"application/vnd.clair.report.v1+jws\" media type\nreturns the report signed with the configured key, as a JWS in\ncompact serialization.\n\nRequesting the \"application/vnd.in-toto+json\" media type returns the\nreport as an in-toto statement with the cosign \"vuln\" predicate.\nRequesting the \"application/vnd.dsse.envelope.v1+json\" media type\nreturns that statement signed with the configured key, in a DSSE\nenvelope, as cosign pushes attestations to registries. Attestations\nhave no Etag.\n\nRequesting the \"application/vnd.clair.vulnerabilityreport.v2+json\"\nmedia type adds the layers that introduced the report's packages and\nvulnerabilities. The order of the layers isn't known, so each has an\nindex of -1.\n","operationId":"ScanIndexReport","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/IndexReport"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}},"application/vnd.clair.report.v1+jws":{"schema":{"$ref":"#/components/schemas/SignedReport"}},"application/vnd.clair.vulnerabilityreport.v2+json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}},"application/vnd.dsse.envelope.v1+json":{"schema":{"$ref":"#/components/schemas/AttestationEnvelope"}},"application/vnd.in-toto+json":{"schema":{"$ref":"#/components/schemas/Attestation"}},"application/x-ndjson":{"schema":{"$ref":"#/components/schemas/ReportRecord"}}},"description":"VulnerabilityReport Created"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Create a VulnerabilityReport for a provided IndexReport.\n","tags":["Matcher"]}},"matcher/api/v1/vulnerability_report/batch":{"post":{"description":"Given up to 1000 Manifest content addressable hashes, a\nVulnerabilityReport is created for each, as the\nGetVulnerabilityReport operation would. Results are returned in the\norder the manifests were requested.\n\nA manifest that isn't found, or whose report can't be created, has\nan error in its result instead of a report; it doesn't fail the\nrequest.\n\nRequesting the \"application/x-ndjson\" media type returns the results\nas a stream of newline delimited BatchReportResult objects, each\nwritten as soon as it and those before it are done.\n","operationId":"GetVulnerabilityReports","parameters":[{"description":"If \"runtime\", packages known to only be development dependencies,\nand the vulnerabilities only they are affected by, are left out\nof each report.\n","in":"query","name":"scope","required":false,"schema":{"enum":["runtime"],"type":"string"}}],"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/BatchReportRequest"}}},"required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/BatchReportResponse"}},"application/x-ndjson":{"schema":{"$ref":"#/components/schemas/BatchReportResult"}}},"description":"VulnerabilityReports Created"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve VulnerabilityReports for many manifests at once.\n","tags":["Matcher"]}},"matcher/api/v1/vulnerability_report/{manifest_hash}":{"get":{"description":"Given a Manifest's content addressable hash a VulnerabilityReport\nwill be created. The Manifest **must** have been Indexed first\nvia the Index endpoint.\n\nRequesting the \"application/x-ndjson\" media type returns the report\nas a stream of newline delimited ReportRecord objects, so large\nreports can be processed incrementally.\n\nRequesting the \"application/vnd.clair.report.v1+jws\" media type\nreturns the report signed with the configured key, as a JWS in\ncompact serialization. Signed reports have no Etag.\n\nRequesting the \"application/vnd.in-toto+json\" media type returns the\nreport as an in-toto statement with the cosign \"vuln\" predicate.\nRequesting the \"application/vnd.dsse.envelope.v1+json\" media type\nreturns that statement signed with the configured key, in a DSSE\nenvelope, as cosign pushes attestations to registries. Attestations\nhave no Etag.\n\nRequesting the \"application/vnd.clair.vulnerabilityreport.v2+json\"\nmedia type adds the layers of the manifest, in order, with the\npackages and vulnerabilities each introduced.\n\nThe Etag is derived from the IndexReport and the vulnerability data\nused to match it, so a conditional request for an unchanged report is\nanswered without matching again.\n","operationId":"GetVulnerabilityReport","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}},{"description":"The name of an attestation's subject, usually the image's\nrepository. Defaults to the manifest digest.\n","in":"query","name":"subject","required":false,"schema":{"type":"string"}},{"description":"If \"runtime\", packages known to only be development dependencies,\nand the vulnerabilities only they are affected by, are left out\nof the report.\n","in":"query","name":"scope","required":false,"schema":{"enum":["runtime"],"type":"string"}}],"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}},"application/vnd.clair.report.v1+jws":{"schema":{"$ref":"#/components/schemas/SignedReport"}},"application/vnd.clair.vulnerabilityreport.v2+json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}},"application/vnd.dsse.envelope.v1+json":{"schema":{"$ref":"#/components/schemas/AttestationEnvelope"}},"application/vnd.in-toto+json":{"schema":{"$ref":"#/components/schemas/Attestation"}},"application/x-ndjson":{"schema":{"$ref":"#/components/schemas/ReportRecord"}}},"description":"VulnerabilityReport Created","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"304":{"description":"VulnerabilityReport Unchanged"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"406":{"$ref":"#/components/responses/NotAcceptable"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve a VulnerabilityReport for a given manifest's content\naddressable hash.\n","tags":["Matcher"]}},"matcher/api/v1/vulnerability_report/{manifest_hash}/history":{"get":{"description":"Lists the versions of the manifest's VulnerabilityReport recorded as\nthe vulnerability database was updated, newest first. Versions are\nonly recorded when report history is configured.\n","operationId":"GetReportHistory","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/ReportHistory"}}},"description":"Report History"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve the recorded versions of a manifest's VulnerabilityReport.\n","tags":["Matcher"]}},"matcher/api/v1/vulnerability_report/{manifest_hash}/history/{report_digest}":{"get":{"description":"Returns the version of the manifest's VulnerabilityReport with the\ndigest, exactly as it was generated. A version never changes, so its\nEtag is its digest.\n","operationId":"GetReportVersion","parameters":[{"description":"A digest of a manifest that has been indexed previous to this\nrequest.\n","in":"path","name":"manifest_hash","required":true,"schema":{"$ref":"#/components/schemas/Digest"}},{"description":"The digest of a version, as listed in the manifest's ReportHistory.\n","in":"path","name":"report_digest","required":true,"schema":{"$ref":"#/components/schemas/Digest"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/VulnerabilityReport"}}},"description":"VulnerabilityReport Version","headers":{"Etag":{"description":"Entity Tag","schema":{"type":"string"}}}},"304":{"description":"VulnerabilityReport Unchanged"},"400":{"$ref":"#/components/responses/BadRequest"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve a recorded version of a manifest's VulnerabilityReport.\n","tags":["Matcher"]}},"notifier/api/v1/admin/deadletter/":{"get":{"description":"Lists the notification IDs whose latest delivery attempt failed,\noldest first. These are retried on every delivery interval.\n","operationId":"ListDeadLetters","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/DeadLetterResponse"}}},"description":"Notifications that failed delivery"},"403":{"$ref":"#/components/responses/Forbidden"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"List notifications that failed delivery.","tags":["Notifier"]},"post":{"description":"Returns every notification that failed delivery to created status.\n","operationId":"ReplayDeadLetters","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/ReplayResponse"}}},"description":"The number of notification IDs queued"},"403":{"$ref":"#/components/responses/Forbidden"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Queue every notification that failed delivery.","tags":["Notifier"]}},"notifier/api/v1/admin/deadletter/{notification_id}":{"post":{"description":"Returns the notification ID to created status, whether its delivery\nfailed or it was delivered. Deleted notifications are not replayed.\n","operationId":"ReplayNotification","parameters":[{"description":"A notification ID","in":"path","name":"notification_id","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/ReplayResponse"}}},"description":"The number of notification IDs queued"},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"$ref":"#/components/responses/Forbidden"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Queue a notification for delivery again.","tags":["Notifier"]}},"notifier/api/v1/admin/migrate":{"post":{"operationId":"MigrateNotifier","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/MigrateResponse"}}},"description":"The resulting migration versions"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Run outstanding notifier database migrations.","tags":["Notifier"]}},"notifier/api/v1/admin/purge/{update_operation}":{"delete":{"description":"Removes the notifications created for the provided update operation\nif they have been delivered or deleted. If the update operation is\nthe latest for its updater, its receipt is kept so the notifications\naren't created again.\n","operationId":"PurgeNotifications","parameters":[{"description":"An update operation ID","in":"path","name":"update_operation","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PurgeResponse"}}},"description":"The number of notification IDs removed"},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"$ref":"#/components/responses/Forbidden"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Remove delivered notifications for an update operation.","tags":["Notifier"]}},"notifier/api/v1/backlog":{"get":{"description":"Reports how many notification IDs are waiting on delivery or failed\ndelivery, and when the oldest one waiting was created, for judging\nwhether the notifier is keeping up.\n","operationId":"GetBacklog","responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Backlog"}}},"description":"The delivery backlog"},"403":{"$ref":"#/components/responses/Forbidden"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Report the notification IDs waiting on delivery.","tags":["Notifier"]}},"notifier/api/v1/deliveries":{"get":{"description":"Reports every attempt the configured deliverers made at delivering\nthe provided notification ID, along with when delivery will next be\nattempted if it hasn't succeeded yet.\n","operationId":"GetDeliveries","parameters":[{"description":"A notification ID returned by a callback","in":"query","name":"notification_id","required":true,"schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/DeliveriesResponse"}}},"description":"Delivery attempts for the notification ID"},"400":{"$ref":"#/components/responses/BadRequest"},"403":{"$ref":"#/components/responses/Forbidden"},"404":{"$ref":"#/components/responses/NotFound"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Report delivery attempts for a notification ID.","tags":["Notifier"]}},"notifier/api/v1/notification/stream":{"get":{"description":"Returns a stream of Server-Sent Events, as an alternative to polling\nfor callbacks.\n\nEvery notification ID is sent as one or more \"notifications\" events,\neach holding a StreamEvent with a page of its notifications. The last\nevent for a notification ID has an event ID, which is the cursor:\nreconnecting with it in the \"Last-Event-ID\" header resumes with the\nnext notification ID. Without a cursor the stream starts with the\noldest notification ID that hasn't been deleted.\n","operationId":"StreamNotifications","parameters":[{"description":"The cursor to resume after","in":"header","name":"Last-Event-ID","schema":{"type":"string"}},{"description":"The cursor to resume after, for clients unable to set the\nLast-Event-ID header.\n","in":"query","name":"cursor","schema":{"type":"string"}},{"description":"The maximum number of notifications to send in a single event.\n","in":"query","name":"page_size","schema":{"type":"int"}}],"responses":{"200":{"content":{"text/event-stream":{"schema":{"$ref":"#/components/schemas/StreamEvent"}}},"description":"A stream of notifications"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"}},"summary":"Stream notifications as they're created.","tags":["Notifier"]}},"notifier/api/v1/notification/{notification_id}":{"delete":{"description":"Issues a delete of the provided notification id and all associated\nnotifications. After this delete clients will no longer be able to\nretrieve notifications.\n","operationId":"DeleteNotification","parameters":[{"description":"A notification ID returned by a callback","in":"path","name":"notification_id","schema":{"type":"string"}}],"responses":{"200":{"description":"OK"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"tags":["Notifier"]},"get":{"description":"By performing a GET with a notification_id as a path parameter, the\nclient will retrieve a paginated response of notification objects.\n","operationId":"GetNotification","parameters":[{"description":"A notification ID returned by a callback","in":"path","name":"notification_id","schema":{"type":"string"}},{"description":"The maximum number of notifications to deliver in a single page.\n","in":"query","name":"page_size","schema":{"type":"int"}},{"description":"The next page to fetch via id. Typically this number is provided\non initial response in the page.next field.\nThe first GET request may omit this field.\n","in":"query","name":"next","schema":{"type":"string"}},{"description":"Only return notifications at or above this severity. Callbacks\nfrom deliverers with a filter include this and the following\nparameters, which should be kept when fetching later pages.\n","in":"query","name":"min_severity","schema":{"type":"string"}},{"description":"Only return notifications whose distribution ID or name, or\nrepository name, is one of these.\n","explode":true,"in":"query","name":"namespace","schema":{"items":{"type":"string"},"type":"array"},"style":"form"},{"description":"Only return notifications for vulnerabilities with a fixed version.\n","in":"query","name":"fixed_only","schema":{"type":"boolean"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/PagedNotifications"}}},"description":"A paginated list of notifications"},"400":{"$ref":"#/components/responses/BadRequest"},"405":{"$ref":"#/components/responses/MethodNotAllowed"},"500":{"$ref":"#/components/responses/InternalServerError"}},"summary":"Retrieve a paginated result of notifications for the provided id.","tags":["Notifier"]}}}}`,
+	}
+	_openapiJSONEtag = map[string]string{
+		"1":   `"0c2777b63b07e5c87163484770a1707253a51362f963fb6580531bd283d63191"`,
+		"1.1": `"f671daafcbd9586d1ff7bd4713d03caa8bbb2c194b57f97a9bbfce223a90decc"`,
+	}
 )
@@ -17,37 +17,73 @@ import (
 )
 
 func TestDiscoveryEndpoint(t *testing.T) {
-	h := DiscoveryHandler()
+	for _, v := range APIVersions() {
+		t.Run(v, func(t *testing.T) {
+			h := DiscoveryHandler(v)
 
-	r := httptest.NewRecorder()
-	req := httptest.NewRequest("GET", "/openapi/v1", nil)
-	req.Header.Set("Accept", "application/json")
-	h.ServeHTTP(r, req)
+			r := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", OpenAPIPath(v), nil)
+			req.Header.Set("Accept", "application/json")
+			h.ServeHTTP(r, req)
 
-	resp := r.Result()
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("got status code: %v want status code: %v", resp.StatusCode, http.StatusOK)
-	}
+			resp := r.Result()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("got status code: %v want status code: %v", resp.StatusCode, http.StatusOK)
+			}
 
-	buf, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		t.Fatalf("failed to ready response body: %v", err)
-	}
+			buf, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to ready response body: %v", err)
+			}
 
-	m := map[string]interface{}{}
-	err = json.Unmarshal(buf, &m)
-	if err != nil {
-		t.Fatalf("failed to json parse returned bytes: %v", err)
+			m := map[string]interface{}{}
+			err = json.Unmarshal(buf, &m)
+			if err != nil {
+				t.Fatalf("failed to json parse returned bytes: %v", err)
+			}
+
+			if _, ok := m["openapi"]; !ok {
+				t.Fatalf("returned json did not container openapi key at the root")
+			}
+			t.Logf("openapi verion: %v", m["openapi"])
+			info := m["info"].(map[string]interface{})
+			if got, want := info["version"], v; got != want {
+				t.Errorf("got api version: %v, want: %v", got, want)
+			}
+			if bytes.Contains(buf, []byte("x-clair-")) {
+				t.Error("document contains version markers")
+			}
+		})
 	}
+}
 
-	if _, ok := m["openapi"]; !ok {
-		t.Fatalf("returned json did not container openapi key at the root")
+func TestDiscoveryVersions(t *testing.T) {
+	props := func(v string) map[string]interface{} {
+		var doc struct {
+			Components struct {
+				Schemas map[string]struct {
+					Properties map[string]interface{} `json:"properties"`
+				} `json:"schemas"`
+			} `json:"components"`
+		}
+		if err := json.Unmarshal([]byte(_openapiJSON[v]), &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc.Components.Schemas["IndexReport"].Properties
+	}
+	if _, ok := props("1")["base_image"]; ok {
+		t.Error("version 1 describes base_image")
+	}
+	if _, ok := props(LatestAPIVersion())["base_image"]; !ok {
+		t.Error("latest version doesn't describe base_image")
+	}
+	if _, ok := props("1")["manifest_hash"]; !ok {
+		t.Error("version 1 doesn't describe manifest_hash")
 	}
-	t.Logf("openapi verion: %v", m["openapi"])
 }
 
 func TestDiscoveryFailure(t *testing.T) {
-	h := DiscoveryHandler()
+	h := DiscoveryHandler(LatestAPIVersion())
 
 	r := httptest.NewRecorder()
 	req := httptest.NewRequest("GET", "/openapi/v1", nil)
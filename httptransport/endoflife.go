@@ -0,0 +1,22 @@
+package httptransport
+
+import (
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/eol"
+)
+
+// EndOfLife reports the end of life distributions among the report's, or
+// nil if there are none or the indexer doesn't detect them.
+func endOfLife(serv interface{}, dists map[string]*claircore.Distribution) []eol.Notice {
+	s, ok := serv.(indexer.Service)
+	if !ok {
+		return nil
+	}
+	c, ok := eol.Find(s)
+	if !ok {
+		return nil
+	}
+	return c.EndOfLife(dists)
+}
@@ -0,0 +1,232 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer/baseimage"
+	"github.com/quay/clair/v4/indexer/eol"
+	"github.com/quay/clair/v4/indexer/layers"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// Validator returns a strong validator covering all the provided parts.
+func validator(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+		// Keep adjacent parts from running together.
+		h.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// IndexReportValidator returns the validator for an index report response:
+// the indexer's state and the body. The state is included so clients see a
+// new validator when a manifest needs to be indexed again, even before it
+// has been.
+func indexReportValidator(state string, body []byte) string {
+	return validator([]byte(state), body)
+}
+
+// EncodeIndexReport encodes the index report as it's served, with the
+// manifest's signature status if signatures are being verified, its
+// referrers if they're being discovered, the excluded packages if exclusions
+// are configured, the truncated layers if scan limits are configured, the
+// base image if base image detection is configured, content hook results
+// and the packages' scopes if hooks are configured, the manifest's
+// annotations if annotations are configured, and its end of life
+// distributions if end of life detection is configured. If v2 is set, the
+// packages are also attributed to the layers that introduced them. Clients
+// of the original API version get none of the optional fields.
+//
+// If strict isn't set, a failure to read any of them is ignored and the
+// report is served without it.
+func encodeIndexReport(ctx context.Context, serv interface{}, report *claircore.IndexReport, strict, v2 bool) ([]byte, error) {
+	var out interface{} = report
+	resp := indexReportResponse{IndexReport: report}
+	if v2 {
+		order, err := layerOrder(ctx, serv, report.Hash)
+		if err != nil && strict {
+			return nil, err
+		}
+		resp.Layers = layers.Attribute(report.Environments, order)
+		out = &resp
+	}
+	if legacyAPI(ctx) {
+		// The optional fields came after the original version of the API.
+		return encodeJSON(out)
+	}
+	if si, ok := signatureIndexer(serv); ok {
+		st, err := si.Signature(ctx, report.Hash)
+		switch {
+		case err == nil:
+			resp.Signature = st
+			out = &resp
+		case strict:
+			return nil, err
+		}
+	}
+	if ri, ok := referrersIndexer(serv); ok {
+		rs, err := ri.Referrers(ctx, report.Hash)
+		switch {
+		case err == nil && len(rs) != 0:
+			resp.Referrers = rs
+			out = &resp
+		case err != nil && strict:
+			return nil, err
+		}
+	}
+	if ei, ok := excludeIndexer(serv); ok {
+		ex, err := ei.Excluded(ctx, report.Hash)
+		switch {
+		case err == nil:
+			resp.Excluded = ex
+			out = &resp
+		case strict:
+			return nil, err
+		}
+	}
+	if bi, ok := budgetIndexer(serv); ok {
+		ts, err := bi.Truncated(ctx, report.Hash)
+		switch {
+		case err == nil && len(ts) != 0:
+			resp.Truncated = ts
+			out = &resp
+		case err != nil && strict:
+			return nil, err
+		}
+	}
+	base, err := baseImage(ctx, serv, report.Hash)
+	switch {
+	case err == nil && base != nil:
+		resp.BaseImage = base
+		out = &resp
+	case err != nil && strict:
+		return nil, err
+	}
+	if hi, ok := hookIndexer(serv); ok {
+		rs, err := hi.Results(ctx, report.Hash)
+		switch {
+		case err == nil && len(rs) != 0:
+			resp.Extensions = &reportExtensions{Content: rs}
+			out = &resp
+		case err != nil && strict:
+			return nil, err
+		}
+	}
+	scopes, err := packageScopes(ctx, serv, report)
+	switch {
+	case err == nil && len(scopes) != 0:
+		resp.Scopes = scopes
+		out = &resp
+	case err != nil && strict:
+		return nil, err
+	}
+	as, err := manifestAnnotations(ctx, serv, report.Hash)
+	switch {
+	case err == nil && len(as) != 0:
+		resp.Annotations = as
+		out = &resp
+	case err != nil && strict:
+		return nil, err
+	}
+	if ns := endOfLife(serv, report.Distributions); len(ns) != 0 {
+		resp.EndOfLife = ns
+		out = &resp
+	}
+	return encodeJSON(out)
+}
+
+// EncodeJSON encodes v as a response body.
+func encodeJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Fingerprinter is implemented by matchers whose reports depend on data
+// beyond the vulnerability database, like VEX documents.
+type fingerprinter interface {
+	// Fingerprint returns a value that changes whenever that data does.
+	Fingerprint(context.Context) string
+}
+
+// FingerprintingMatcher finds a fingerprinter among the wrapped matchers, if
+// there is one.
+func fingerprintingMatcher(s matcher.Service) (fingerprinter, bool) {
+	var f fingerprinter
+	ok := matcher.Walk(s, func(s interface{}) bool {
+		var ok bool
+		f, ok = s.(fingerprinter)
+		return ok
+	})
+	return f, ok
+}
+
+// VulnerabilityReportValidator returns the validator for a vulnerability
+// report response, or an empty string if one can't be computed.
+//
+// Scanning is the expensive part, so the validator is computed from what
+// goes into the report instead of the report itself: the index report, its
+// base image, annotations, and end of life distributions, the latest update
+// operation, anything else the matcher reports a fingerprint for, and the
+// representation the client asked for, as reported by reportRepresentation.
+func vulnerabilityReportValidator(ctx context.Context, m matcher.Service, ir *claircore.IndexReport, base *baseimage.Base, as map[string]string, ns []eol.Notice, rep string) string {
+	ref, err := m.LatestUpdateOperation(ctx)
+	if err != nil {
+		return ""
+	}
+	b, err := json.Marshal(ir)
+	if err != nil {
+		return ""
+	}
+	bb, err := json.Marshal(base)
+	if err != nil {
+		return ""
+	}
+	// Maps are marshaled with sorted keys, so this is stable.
+	ab, err := json.Marshal(as)
+	if err != nil {
+		return ""
+	}
+	// End of life distributions change with the date, not the index report.
+	nb, err := json.Marshal(ns)
+	if err != nil {
+		return ""
+	}
+	var fp string
+	if f, ok := fingerprintingMatcher(m); ok {
+		fp = f.Fingerprint(ctx)
+	}
+	return validator([]byte(ir.Hash.String()), b, bb, ab, nb, []byte(ref.String()), []byte(fp), []byte(rep))
+}
+
+// ReportRepresentation names the representation of a vulnerability report
+// the request asked for, including any scope it was limited to and the API
+// version, or returns an empty string for the plain JSON of the whole report
+// in the latest version.
+func reportRepresentation(r *http.Request) string {
+	var rep string
+	switch {
+	case wantsReportStream(r):
+		rep = ReportStreamType
+	case wantsVulnerabilityReportV2(r):
+		rep = VulnerabilityReportV2Type
+	}
+	if s := r.URL.Query().Get(scopeParam); s != "" {
+		rep += ";" + scopeParam + "=" + s
+	}
+	if v := apiVersion(r.Context()); v != LatestAPIVersion() {
+		rep += ";version=" + v
+	}
+	return rep
+}
@@ -0,0 +1,165 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestUnmodified(t *testing.T) {
+	const v = `"abc"`
+	tt := []struct {
+		name   string
+		header []string
+		want   bool
+	}{
+		{name: "None", want: false},
+		{name: "Match", header: []string{`"abc"`}, want: true},
+		{name: "Mismatch", header: []string{`"def"`}, want: false},
+		{name: "List", header: []string{`"def", "abc"`}, want: true},
+		{name: "Multiple", header: []string{`"def"`, `"abc"`}, want: true},
+		{name: "Weak", header: []string{`W/"abc"`}, want: true},
+		{name: "Star", header: []string{`*`}, want: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, h := range tc.header {
+				r.Header.Add("If-None-Match", h)
+			}
+			if got, want := unmodified(r, v), tc.want; got != want {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestIndexReportETag(t *testing.T) {
+	ctx := context.Background()
+	digest := claircore.MustParseDigest(`sha256:` + "3d1c3b5f4f3ba7e2e9f1e1ccd2b5b1a4f8b8a7c0f0d1a1b1c1d1e1f101112131")
+	state := "state-1"
+	h := IndexReportHandler(&indexer.Mock{
+		State_: func(context.Context) (string, error) { return state, nil },
+		IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{Hash: digest, Success: true}, true, nil
+		},
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	c := srv.Client()
+	u := srv.URL + IndexReportAPIPath + digest.String()
+
+	get := func(etag string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res
+	}
+
+	res := get("")
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	etag := res.Header.Get("etag")
+	if etag == "" {
+		t.Fatal("missing etag")
+	}
+	res = get(etag)
+	if got, want := res.StatusCode, http.StatusNotModified; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := res.Header.Get("etag"), etag; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	// A new indexer state means the manifest should be indexed again.
+	state = "state-2"
+	res = get(etag)
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if res.Header.Get("etag") == etag {
+		t.Error("etag didn't change with the indexer state")
+	}
+}
+
+func TestVulnerabilityReportETag(t *testing.T) {
+	ctx := context.Background()
+	digest := claircore.MustParseDigest(`sha256:` + "3d1c3b5f4f3ba7e2e9f1e1ccd2b5b1a4f8b8a7c0f0d1a1b1c1d1e1f101112131")
+	ref := uuid.New()
+	scans := 0
+	m := &matcher.Mock{
+		LatestUpdateOperation_: func(context.Context) (uuid.UUID, error) { return ref, nil },
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			scans++
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+	}
+	i := &indexer.Mock{
+		IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+			return &claircore.IndexReport{Hash: digest, Success: true}, true, nil
+		},
+	}
+	srv := httptest.NewServer(VulnerabilityReportHandler(m, i))
+	defer srv.Close()
+	c := srv.Client()
+	u := srv.URL + VulnerabilityReportPath + digest.String()
+
+	get := func(etag string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res
+	}
+
+	res := get("")
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	etag := res.Header.Get("etag")
+	if etag == "" {
+		t.Fatal("missing etag")
+	}
+	res = get(etag)
+	if got, want := res.StatusCode, http.StatusNotModified; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := scans, 1; got != want {
+		t.Errorf("scans: got: %d, want: %d", got, want)
+	}
+
+	// A new update operation means the report may have changed.
+	ref = uuid.New()
+	res = get(etag)
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if res.Header.Get("etag") == etag {
+		t.Error("etag didn't change with the update operation")
+	}
+}
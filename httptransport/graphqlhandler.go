@@ -0,0 +1,76 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/config"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/graphql"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// MaxGraphQLRequest is the largest GraphQL request body accepted.
+const maxGraphQLRequest = 1 << 20
+
+// GraphQLHandler serves GraphQL queries over manifests' index and
+// vulnerability reports, so clients can select just the parts they need.
+//
+// Queries are accepted as a JSON body or "application/graphql" body in a
+// POST, or in the "query", "operationName", and "variables" parameters of a
+// GET. A GET without a query returns the schema.
+func GraphQLHandler(m matcher.Service, idx indexer.Service, conf *config.MatcherGraphQL) http.HandlerFunc {
+	schema := graphQLReportSchema(idx, m, conf)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req graphql.Request
+		switch r.Method {
+		case http.MethodGet:
+			q := r.URL.Query()
+			req.Query = q.Get("query")
+			req.OperationName = q.Get("operationName")
+			if req.Query == "" {
+				w.Header().Set("content-type", "text/plain; charset=utf-8")
+				var err error
+				defer writerError(w, &err)()
+				_, err = io.WriteString(w, GraphQLSchema)
+				return
+			}
+			if v := q.Get("variables"); v != "" {
+				if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+					apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to deserialize variables"), "")
+					return
+				}
+			}
+		case http.MethodPost:
+			body := http.MaxBytesReader(w, r.Body, maxGraphQLRequest)
+			mt, _, _ := mime.ParseMediaType(r.Header.Get("content-type"))
+			if mt == "application/graphql" {
+				b, err := ioutil.ReadAll(body)
+				if err != nil {
+					apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to read query"), "")
+					return
+				}
+				req.Query = string(b)
+				break
+			}
+			if err := json.NewDecoder(body).Decode(&req); err != nil {
+				apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to deserialize request"), "")
+				return
+			}
+		default:
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET or POST"), "")
+			return
+		}
+
+		res := graphql.Execute(ctx, schema, &req)
+		w.Header().Set("content-type", "application/json")
+		var err error
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(res)
+	}
+}
@@ -0,0 +1,195 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/config"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/graphql"
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestGraphQLHandler(t *testing.T) {
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("d", 64))
+	ir := &claircore.IndexReport{
+		Hash:    d,
+		Success: true,
+		Packages: map[string]*claircore.Package{
+			"1":  {ID: "1", Name: "openssl", Version: "1.1.1"},
+			"2":  {ID: "2", Name: "zlib", Version: "1.2.11"},
+			"10": {ID: "10", Name: "musl", Version: "1.2.2"},
+		},
+	}
+	scans := 0
+	h := GraphQLHandler(
+		&matcher.Mock{
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				scans++
+				return &claircore.VulnerabilityReport{
+					Hash:     ir.Hash,
+					Packages: ir.Packages,
+					Vulnerabilities: map[string]*claircore.Vulnerability{
+						"100": {ID: "100", Name: "CVE-1", NormalizedSeverity: claircore.High},
+						"101": {ID: "101", Name: "CVE-2", NormalizedSeverity: claircore.Low},
+					},
+					PackageVulnerabilities: map[string][]string{"1": {"100", "101"}, "2": {"101"}},
+				}, nil
+			},
+		},
+		&indexer.Mock{
+			IndexReport_: func(_ context.Context, got claircore.Digest) (*claircore.IndexReport, bool, error) {
+				if got.String() != d.String() {
+					return nil, false, nil
+				}
+				return ir, true, nil
+			},
+		},
+		&config.MatcherGraphQL{MaxDepth: 10, MaxPageSize: 2},
+	)
+	run := func(t *testing.T, query string) string {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodGet, GraphQLAPIPath+"?query="+url.QueryEscape(query), nil))
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		return strings.TrimSpace(rr.Body.String())
+	}
+
+	t.Run("Packages", func(t *testing.T) {
+		got := run(t, `{ manifest(hash: "`+d.String()+`") { success packages { totalCount nodes { name } pageInfo { endCursor hasNextPage } } } }`)
+		want := `{"data":{"manifest":{"success":true,"packages":{"totalCount":3,"nodes":[{"name":"openssl"},{"name":"zlib"}],"pageInfo":{"endCursor":"2","hasNextPage":true}}}}}`
+		if got != want {
+			t.Errorf("\ngot:  %s\nwant: %s", got, want)
+		}
+		got = run(t, `{ manifest(hash: "`+d.String()+`") { packages(after: "2") { nodes { name } pageInfo { hasNextPage } } } }`)
+		want = `{"data":{"manifest":{"packages":{"nodes":[{"name":"musl"}],"pageInfo":{"hasNextPage":false}}}}}`
+		if got != want {
+			t.Errorf("\ngot:  %s\nwant: %s", got, want)
+		}
+		if scans != 0 {
+			t.Errorf("manifest matched %d times for a query without vulnerabilities", scans)
+		}
+	})
+	t.Run("Vulnerabilities", func(t *testing.T) {
+		got := run(t, `{ manifest(hash: "`+d.String()+`") { vulnerabilities(minSeverity: Medium) { nodes { name normalizedSeverity packages { name } } } } }`)
+		want := `{"data":{"manifest":{"vulnerabilities":{"nodes":[{"name":"CVE-1","normalizedSeverity":"High","packages":[{"name":"openssl"}]}]}}}}`
+		if got != want {
+			t.Errorf("\ngot:  %s\nwant: %s", got, want)
+		}
+	})
+	t.Run("Missing", func(t *testing.T) {
+		got := run(t, `{ manifest(hash: "sha256:`+strings.Repeat("e", 64)+`") { hash } }`)
+		if want := `{"data":{"manifest":null}}`; got != want {
+			t.Errorf("got: %s, want: %s", got, want)
+		}
+	})
+	t.Run("Schema", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodGet, GraphQLAPIPath, nil))
+		if rr.Body.String() != GraphQLSchema {
+			t.Error("schema not served")
+		}
+	})
+	t.Run("Introspection", func(t *testing.T) {
+		got := run(t, `{ __schema { types { kind name fields { name args { name } type { name ofType { name ofType { name ofType { name } } } } } } } }`)
+		type typeRef struct {
+			Name   string
+			OfType *typeRef
+		}
+		var res struct {
+			Data struct {
+				Schema struct {
+					Types []struct {
+						Kind, Name string
+						Fields     []struct {
+							Name string
+							Args []struct{ Name string }
+							Type typeRef
+						}
+					}
+				} `json:"__schema"`
+			}
+			Errors []interface{}
+		}
+		if err := json.Unmarshal([]byte(got), &res); err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Errors) != 0 {
+			t.Fatalf("errors: %v", res.Errors)
+		}
+		// Every object type reachable from the query type must be described
+		// exactly by GraphQLSchema.
+		objs := make(map[string]*graphql.Object)
+		var walk func(*graphql.Object)
+		walk = func(o *graphql.Object) {
+			if _, ok := objs[o.Name]; ok {
+				return
+			}
+			objs[o.Name] = o
+			for _, f := range o.Fields {
+				if f.Type != nil {
+					walk(f.Type)
+				}
+			}
+		}
+		walk(graphQLReportSchema(nil, nil, &config.MatcherGraphQL{}).Query)
+		for _, ty := range res.Data.Schema.Types {
+			o, ok := objs[ty.Name]
+			if !ok {
+				continue
+			}
+			delete(objs, ty.Name)
+			if ty.Kind != "OBJECT" {
+				t.Errorf("%s: got kind %q", ty.Name, ty.Kind)
+			}
+			if got, want := len(ty.Fields), len(o.Fields); got != want {
+				t.Errorf("%s: described %d fields, has %d", ty.Name, got, want)
+			}
+			for _, f := range ty.Fields {
+				def, ok := o.Fields[f.Name]
+				if !ok {
+					t.Errorf("%s.%s: described but missing", ty.Name, f.Name)
+					continue
+				}
+				var args []string
+				for _, a := range f.Args {
+					args = append(args, a.Name)
+				}
+				if got, want := strings.Join(args, ","), strings.Join(def.Args, ","); got != want {
+					t.Errorf("%s.%s: described args %q, has %q", ty.Name, f.Name, got, want)
+				}
+				named := &f.Type
+				for named.OfType != nil {
+					named = named.OfType
+				}
+				if def.Type != nil && named.Name != def.Type.Name {
+					t.Errorf("%s.%s: described type %q, has %q", ty.Name, f.Name, named.Name, def.Type.Name)
+				}
+			}
+		}
+		for n := range objs {
+			t.Errorf("%s: not described", n)
+		}
+	})
+	t.Run("Post", func(t *testing.T) {
+		b, _ := json.Marshal(map[string]interface{}{
+			"query":     `query ($h: String!) { manifest(hash: $h) { hash } }`,
+			"variables": map[string]string{"h": d.String()},
+		})
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodPost, GraphQLAPIPath, strings.NewReader(string(b))))
+		want := `{"data":{"manifest":{"hash":"` + d.String() + `"}}}`
+		if got := strings.TrimSpace(rr.Body.String()); got != want {
+			t.Errorf("got: %s, want: %s", got, want)
+		}
+	})
+}
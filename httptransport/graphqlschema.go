@@ -0,0 +1,569 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/config"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/internal/graphql"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// GraphQLSchema is the schema served by the GraphQL endpoint, in the schema
+// definition language. It's also what introspection queries are answered
+// from, so it must describe the types graphQLReportSchema builds.
+const GraphQLSchema = `type Query {
+  # Null if the manifest hasn't been indexed.
+  manifest(hash: String!): Manifest
+}
+
+type Manifest {
+  hash: String!
+  state: String!
+  success: Boolean!
+  err: String!
+  packages(first: Int, after: String, name: String): PackageConnection!
+  distributions: [Distribution!]!
+  repositories: [Repository!]!
+  # Selecting this matches the manifest against the vulnerability database.
+  vulnerabilities(first: Int, after: String, minSeverity: Severity): VulnerabilityConnection!
+}
+
+type PackageConnection {
+  totalCount: Int!
+  nodes: [Package!]!
+  pageInfo: PageInfo!
+}
+
+type VulnerabilityConnection {
+  totalCount: Int!
+  nodes: [Vulnerability!]!
+  pageInfo: PageInfo!
+}
+
+type PageInfo {
+  # Pass as "after" to get the next page.
+  endCursor: String
+  hasNextPage: Boolean!
+}
+
+type Package {
+  id: String!
+  name: String!
+  version: String!
+  kind: String!
+  arch: String!
+  module: String!
+  source: Package
+  environments: [Environment!]!
+  vulnerabilities: [Vulnerability!]!
+}
+
+type Environment {
+  packageDB: String!
+  introducedIn: String!
+  distribution: Distribution
+  repositories: [Repository!]!
+}
+
+type Distribution {
+  id: String!
+  did: String!
+  name: String!
+  version: String!
+  versionCodeName: String!
+  versionID: String!
+  arch: String!
+  cpe: String!
+  prettyName: String!
+}
+
+type Repository {
+  id: String!
+  name: String!
+  key: String!
+  uri: String!
+  cpe: String!
+}
+
+type Vulnerability {
+  id: String!
+  name: String!
+  description: String!
+  issued: String
+  links: String!
+  severity: String!
+  normalizedSeverity: Severity!
+  fixedInVersion: String!
+  updater: String!
+  distribution: Distribution
+  repository: Repository
+  # The manifest's packages affected by the vulnerability.
+  packages: [Package!]!
+}
+
+enum Severity {
+  Unknown
+  Negligible
+  Low
+  Medium
+  High
+  Critical
+}
+`
+
+// GqlManifest is a manifest being queried. Its vulnerability report is
+// only generated if a query asks for it.
+type gqlManifest struct {
+	ir *claircore.IndexReport
+	m  matcher.Service
+	vr *claircore.VulnerabilityReport
+	// Affected maps vulnerability IDs to the IDs of the packages they
+	// affect.
+	affected map[string][]string
+}
+
+func (g *gqlManifest) report(ctx context.Context) (*claircore.VulnerabilityReport, error) {
+	if g.vr != nil {
+		return g.vr, nil
+	}
+	vr, err := g.m.Scan(ctx, g.ir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match manifest: %w", err)
+	}
+	g.vr = vr
+	g.affected = make(map[string][]string)
+	for p, vs := range vr.PackageVulnerabilities {
+		for _, v := range vs {
+			g.affected[v] = append(g.affected[v], p)
+		}
+	}
+	for _, ps := range g.affected {
+		sortIDs(ps)
+	}
+	return vr, nil
+}
+
+type gqlPackage struct {
+	m *gqlManifest
+	p *claircore.Package
+}
+
+type gqlVulnerability struct {
+	m *gqlManifest
+	v *claircore.Vulnerability
+}
+
+type gqlEnvironment struct {
+	m *gqlManifest
+	e *claircore.Environment
+}
+
+type gqlConnection struct {
+	total int
+	nodes []interface{}
+	end   string
+	more  bool
+}
+
+// SortIDs sorts claircore's numeric IDs numerically, and anything else
+// sensibly.
+func sortIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		if len(ids[i]) != len(ids[j]) {
+			return len(ids[i]) < len(ids[j])
+		}
+		return ids[i] < ids[j]
+	})
+}
+
+// Paginate returns the page of the sorted ids following the cursor.
+func paginate(ids []string, args graphql.Args, max int) ([]string, *gqlConnection, error) {
+	first, err := args.Int("first", max)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first < 0 || first > max {
+		return nil, nil, fmt.Errorf(`"first" must be between 0 and %d`, max)
+	}
+	after, err := args.String("after", "")
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &gqlConnection{total: len(ids)}
+	if after != "" {
+		i := sort.Search(len(ids), func(i int) bool {
+			if len(ids[i]) != len(after) {
+				return len(ids[i]) > len(after)
+			}
+			return ids[i] > after
+		})
+		ids = ids[i:]
+	}
+	if len(ids) > first {
+		ids, c.more = ids[:first], true
+	}
+	if len(ids) != 0 {
+		c.end = ids[len(ids)-1]
+	}
+	return ids, c, nil
+}
+
+// GraphQLReportSchema returns the executable GraphQLSchema.
+func graphQLReportSchema(idx indexer.Service, m matcher.Service, conf *config.MatcherGraphQL) *graphql.Schema {
+	maxPage := conf.MaxPageSize
+	str := func(f func(src interface{}) string) *graphql.FieldDef {
+		return &graphql.FieldDef{
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				return f(src), nil
+			},
+		}
+	}
+	dist := &graphql.Object{Name: "Distribution"}
+	dist.Fields = map[string]*graphql.FieldDef{
+		"id":              str(func(s interface{}) string { return s.(*claircore.Distribution).ID }),
+		"did":             str(func(s interface{}) string { return s.(*claircore.Distribution).DID }),
+		"name":            str(func(s interface{}) string { return s.(*claircore.Distribution).Name }),
+		"version":         str(func(s interface{}) string { return s.(*claircore.Distribution).Version }),
+		"versionCodeName": str(func(s interface{}) string { return s.(*claircore.Distribution).VersionCodeName }),
+		"versionID":       str(func(s interface{}) string { return s.(*claircore.Distribution).VersionID }),
+		"arch":            str(func(s interface{}) string { return s.(*claircore.Distribution).Arch }),
+		"cpe":             str(func(s interface{}) string { return s.(*claircore.Distribution).CPE.String() }),
+		"prettyName":      str(func(s interface{}) string { return s.(*claircore.Distribution).PrettyName }),
+	}
+	repo := &graphql.Object{Name: "Repository"}
+	repo.Fields = map[string]*graphql.FieldDef{
+		"id":   str(func(s interface{}) string { return s.(*claircore.Repository).ID }),
+		"name": str(func(s interface{}) string { return s.(*claircore.Repository).Name }),
+		"key":  str(func(s interface{}) string { return s.(*claircore.Repository).Key }),
+		"uri":  str(func(s interface{}) string { return s.(*claircore.Repository).URI }),
+		"cpe":  str(func(s interface{}) string { return s.(*claircore.Repository).CPE.String() }),
+	}
+	repos := func(m *gqlManifest, ids []string) []interface{} {
+		out := []interface{}{}
+		for _, id := range ids {
+			if r, ok := m.ir.Repositories[id]; ok && r != nil {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+	pageInfo := &graphql.Object{Name: "PageInfo"}
+	pageInfo.Fields = map[string]*graphql.FieldDef{
+		"endCursor": {
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				if c := src.(*gqlConnection); c.end != "" {
+					return c.end, nil
+				}
+				return nil, nil
+			},
+		},
+		"hasNextPage": {
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				return src.(*gqlConnection).more, nil
+			},
+		},
+	}
+	connection := func(name string, node *graphql.Object) *graphql.Object {
+		return &graphql.Object{
+			Name: name,
+			Fields: map[string]*graphql.FieldDef{
+				"totalCount": {
+					Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+						return src.(*gqlConnection).total, nil
+					},
+				},
+				"nodes": {
+					Type: node,
+					Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+						return src.(*gqlConnection).nodes, nil
+					},
+				},
+				"pageInfo": {
+					Type: pageInfo,
+					Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+						return src, nil
+					},
+				},
+			},
+		}
+	}
+
+	pkg := &graphql.Object{Name: "Package"}
+	vuln := &graphql.Object{Name: "Vulnerability"}
+	env := &graphql.Object{Name: "Environment"}
+	pkgs := func(m *gqlManifest, ids []string) []interface{} {
+		out := []interface{}{}
+		for _, id := range ids {
+			if p, ok := m.ir.Packages[id]; ok && p != nil {
+				out = append(out, &gqlPackage{m: m, p: p})
+			}
+		}
+		return out
+	}
+	pkg.Fields = map[string]*graphql.FieldDef{
+		"id":      str(func(s interface{}) string { return s.(*gqlPackage).p.ID }),
+		"name":    str(func(s interface{}) string { return s.(*gqlPackage).p.Name }),
+		"version": str(func(s interface{}) string { return s.(*gqlPackage).p.Version }),
+		"kind":    str(func(s interface{}) string { return s.(*gqlPackage).p.Kind }),
+		"arch":    str(func(s interface{}) string { return s.(*gqlPackage).p.Arch }),
+		"module":  str(func(s interface{}) string { return s.(*gqlPackage).p.Module }),
+		"source": {
+			Type: pkg,
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				p := src.(*gqlPackage)
+				if p.p.Source == nil || p.p.Source.Name == "" {
+					return nil, nil
+				}
+				return &gqlPackage{m: p.m, p: p.p.Source}, nil
+			},
+		},
+		"environments": {
+			Type: env,
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				p := src.(*gqlPackage)
+				out := []interface{}{}
+				for _, e := range p.m.ir.Environments[p.p.ID] {
+					out = append(out, &gqlEnvironment{m: p.m, e: e})
+				}
+				return out, nil
+			},
+		},
+		"vulnerabilities": {
+			Type: vuln,
+			Resolve: func(ctx context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				p := src.(*gqlPackage)
+				vr, err := p.m.report(ctx)
+				if err != nil {
+					return nil, err
+				}
+				ids := append([]string(nil), vr.PackageVulnerabilities[p.p.ID]...)
+				sortIDs(ids)
+				out := []interface{}{}
+				for _, id := range ids {
+					if v, ok := vr.Vulnerabilities[id]; ok && v != nil {
+						out = append(out, &gqlVulnerability{m: p.m, v: v})
+					}
+				}
+				return out, nil
+			},
+		},
+	}
+	env.Fields = map[string]*graphql.FieldDef{
+		"packageDB":    str(func(s interface{}) string { return s.(*gqlEnvironment).e.PackageDB }),
+		"introducedIn": str(func(s interface{}) string { return s.(*gqlEnvironment).e.IntroducedIn.String() }),
+		"distribution": {
+			Type: dist,
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				e := src.(*gqlEnvironment)
+				if d, ok := e.m.ir.Distributions[e.e.DistributionID]; ok && d != nil {
+					return d, nil
+				}
+				return nil, nil
+			},
+		},
+		"repositories": {
+			Type: repo,
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				e := src.(*gqlEnvironment)
+				return repos(e.m, e.e.RepositoryIDs), nil
+			},
+		},
+	}
+	vuln.Fields = map[string]*graphql.FieldDef{
+		"id":          str(func(s interface{}) string { return s.(*gqlVulnerability).v.ID }),
+		"name":        str(func(s interface{}) string { return s.(*gqlVulnerability).v.Name }),
+		"description": str(func(s interface{}) string { return s.(*gqlVulnerability).v.Description }),
+		"issued": {
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				if t := src.(*gqlVulnerability).v.Issued; !t.IsZero() {
+					return t.Format(time.RFC3339), nil
+				}
+				return nil, nil
+			},
+		},
+		"links":              str(func(s interface{}) string { return s.(*gqlVulnerability).v.Links }),
+		"severity":           str(func(s interface{}) string { return s.(*gqlVulnerability).v.Severity }),
+		"normalizedSeverity": str(func(s interface{}) string { return s.(*gqlVulnerability).v.NormalizedSeverity.String() }),
+		"fixedInVersion":     str(func(s interface{}) string { return s.(*gqlVulnerability).v.FixedInVersion }),
+		"updater":            str(func(s interface{}) string { return s.(*gqlVulnerability).v.Updater }),
+		"distribution": {
+			Type: dist,
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				if d := src.(*gqlVulnerability).v.Dist; d != nil && d.ID != "" {
+					return d, nil
+				}
+				return nil, nil
+			},
+		},
+		"repository": {
+			Type: repo,
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				if r := src.(*gqlVulnerability).v.Repo; r != nil && r.ID != "" {
+					return r, nil
+				}
+				return nil, nil
+			},
+		},
+		"packages": {
+			Type: pkg,
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				v := src.(*gqlVulnerability)
+				return pkgs(v.m, v.m.affected[v.v.ID]), nil
+			},
+		},
+	}
+
+	manifest := &graphql.Object{Name: "Manifest"}
+	manifest.Fields = map[string]*graphql.FieldDef{
+		"hash":  str(func(s interface{}) string { return s.(*gqlManifest).ir.Hash.String() }),
+		"state": str(func(s interface{}) string { return s.(*gqlManifest).ir.State }),
+		"err":   str(func(s interface{}) string { return s.(*gqlManifest).ir.Err }),
+		"success": {
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				return src.(*gqlManifest).ir.Success, nil
+			},
+		},
+		"packages": {
+			Type: connection("PackageConnection", pkg),
+			Args: []string{"first", "after", "name"},
+			Resolve: func(_ context.Context, src interface{}, args graphql.Args) (interface{}, error) {
+				m := src.(*gqlManifest)
+				name, err := args.String("name", "")
+				if err != nil {
+					return nil, err
+				}
+				ids := make([]string, 0, len(m.ir.Packages))
+				for id, p := range m.ir.Packages {
+					if p != nil && (name == "" || p.Name == name) {
+						ids = append(ids, id)
+					}
+				}
+				sortIDs(ids)
+				ids, c, err := paginate(ids, args, maxPage)
+				if err != nil {
+					return nil, err
+				}
+				c.nodes = pkgs(m, ids)
+				return c, nil
+			},
+		},
+		"distributions": {
+			Type: dist,
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				m := src.(*gqlManifest)
+				ids := make([]string, 0, len(m.ir.Distributions))
+				for id, d := range m.ir.Distributions {
+					if d != nil {
+						ids = append(ids, id)
+					}
+				}
+				sortIDs(ids)
+				out := []interface{}{}
+				for _, id := range ids {
+					out = append(out, m.ir.Distributions[id])
+				}
+				return out, nil
+			},
+		},
+		"repositories": {
+			Type: repo,
+			Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+				m := src.(*gqlManifest)
+				ids := make([]string, 0, len(m.ir.Repositories))
+				for id := range m.ir.Repositories {
+					ids = append(ids, id)
+				}
+				sortIDs(ids)
+				return repos(m, ids), nil
+			},
+		},
+		"vulnerabilities": {
+			Type: connection("VulnerabilityConnection", vuln),
+			Args: []string{"first", "after", "minSeverity"},
+			Resolve: func(ctx context.Context, src interface{}, args graphql.Args) (interface{}, error) {
+				m := src.(*gqlManifest)
+				sev, err := args.String("minSeverity", claircore.Unknown.String())
+				if err != nil {
+					return nil, err
+				}
+				min, ok := parseSeverity(sev)
+				if !ok {
+					return nil, fmt.Errorf("unknown severity %q", sev)
+				}
+				vr, err := m.report(ctx)
+				if err != nil {
+					return nil, err
+				}
+				ids := make([]string, 0, len(vr.Vulnerabilities))
+				for id, v := range vr.Vulnerabilities {
+					if v != nil && v.NormalizedSeverity >= min {
+						ids = append(ids, id)
+					}
+				}
+				sortIDs(ids)
+				ids, c, err := paginate(ids, args, maxPage)
+				if err != nil {
+					return nil, err
+				}
+				c.nodes = make([]interface{}, len(ids))
+				for i, id := range ids {
+					c.nodes[i] = &gqlVulnerability{m: m, v: vr.Vulnerabilities[id]}
+				}
+				return c, nil
+			},
+		},
+	}
+
+	query := &graphql.Object{Name: "Query"}
+	query.Fields = map[string]*graphql.FieldDef{
+		"manifest": {
+			Type: manifest,
+			Args: []string{"hash"},
+			Resolve: func(ctx context.Context, _ interface{}, args graphql.Args) (interface{}, error) {
+				h, err := args.String("hash", "")
+				if err != nil {
+					return nil, err
+				}
+				d, err := claircore.ParseDigest(h)
+				if err != nil {
+					return nil, fmt.Errorf("malformed manifest hash: %w", err)
+				}
+				ir, ok, err := idx.IndexReport(ctx, d)
+				switch {
+				case err != nil:
+					return nil, fmt.Errorf("failed to retrieve index report: %w", err)
+				case !ok:
+					return nil, nil
+				}
+				return &gqlManifest{ir: ir, m: m}, nil
+			},
+		},
+	}
+	types, err := graphql.ParseTypes(GraphQLSchema)
+	if err != nil {
+		panic(fmt.Sprintf("programmer error: bad GraphQLSchema: %v", err))
+	}
+	return &graphql.Schema{
+		Query:     query,
+		MaxDepth:  conf.MaxDepth,
+		MaxFields: conf.MaxFields,
+		Types:     types,
+	}
+}
+
+// ParseSeverity parses a claircore.Severity by name, ignoring case.
+func parseSeverity(s string) (claircore.Severity, bool) {
+	for sev := claircore.Unknown; sev <= claircore.Critical; sev++ {
+		if strings.EqualFold(sev.String(), s) {
+			return sev, true
+		}
+	}
+	return claircore.Unknown, false
+}
@@ -0,0 +1,68 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/events"
+)
+
+// DefaultEventLimit is the number of index events returned if the request
+// doesn't set a limit.
+const DefaultEventLimit = 100
+
+// IndexEventsHandler serves index events in order, for the notifier to relay.
+//
+// The "after" parameter is the sequence number of the last event the client
+// has seen, and "limit" the most events to return.
+func IndexEventsHandler(src events.Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Method != http.MethodGet {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
+			return
+		}
+		var after int64
+		limit := DefaultEventLimit
+		var err error
+		q := r.URL.Query()
+		if p := q.Get("after"); p != "" {
+			after, err = strconv.ParseInt(p, 10, 64)
+			if err != nil {
+				apiError(w, clairerror.New(clairerror.Invalid, "could not parse \"after\" query param into integer"), "")
+				return
+			}
+		}
+		if p := q.Get("limit"); p != "" {
+			limit, err = strconv.Atoi(p)
+			if err != nil || limit < 1 {
+				apiError(w, clairerror.New(clairerror.Invalid, "\"limit\" query param must be a positive integer"), "")
+				return
+			}
+		}
+
+		evs, err := src.IndexEvents(ctx, after, limit)
+		if err != nil {
+			apiError(w, err, "could not get index events")
+			return
+		}
+
+		defer writerError(w, &err)()
+		err = json.NewEncoder(w).Encode(&evs)
+	}
+}
+
+// EventsIndexer finds the events.Recorder among the wrapped indexers, if
+// there is one.
+func eventsIndexer(s interface{}) (*events.Recorder, bool) {
+	var i *events.Recorder
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		i, ok = s.(*events.Recorder)
+		return ok
+	})
+	return i, ok
+}
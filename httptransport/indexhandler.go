@@ -2,14 +2,17 @@ package httptransport
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
 
-	"github.com/quay/claircore"
-	je "github.com/quay/claircore/pkg/jsonerr"
+	"github.com/rs/zerolog"
 
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/limit"
+	"github.com/quay/clair/v4/indexer/mediatype"
 )
 
 const (
@@ -19,72 +22,100 @@ const (
 
 // IndexHandler utilizes an Indexer to begin a
 // Index of a manifest.
-func IndexHandler(serv indexer.StateIndexer) http.HandlerFunc {
+//
+// If the request's "If-None-Match" header matches the validator of the
+// manifest's current index report, the manifest isn't indexed again and a
+// 412 is returned.
+//
+// Layers submitted with a media type are indexed according to the
+// mediatype.Policy. Manifests it rejects are answered with a 415.
+//
+// A GET lists the indexed manifests, if the inventory is configured.
+func IndexHandler(serv indexer.Service, lp mediatype.Policy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		w.Header().Set("content-type", "application/json")
+		if r.Method == http.MethodGet {
+			inventoryHandler(w, r, serv)
+			return
+		}
 		if r.Method != http.MethodPost {
-			resp := &je.Response{
-				Code:    "method-not-allowed",
-				Message: "endpoint only allows POST",
-			}
-			je.Error(w, resp, http.StatusMethodNotAllowed)
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET or POST"), "")
 			return
 		}
 		state, err := serv.State(ctx)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "internal error",
-				Message: "could not retrieve indexer state " + err.Error(),
-			}
-			je.Error(w, resp, http.StatusInternalServerError)
+			apiError(w, err, "could not retrieve indexer state")
 			return
 		}
 
-		var m claircore.Manifest
-		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: fmt.Sprintf("failed to deserialize manifest: %v", err),
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+		var sub mediatype.Manifest
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to deserialize manifest"), "")
 			return
 		}
-		if m.Hash.String() == "" || len(m.Layers) == 0 {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "bogus manifest",
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+		if sub.Hash.String() == "" || len(sub.Layers) == 0 {
+			apiError(w, clairerror.New(clairerror.Invalid, "bogus manifest"), "")
+			return
+		}
+		m, skipped, err := lp.Apply(&sub)
+		if err != nil {
+			apiError(w, clairerror.Wrap(clairerror.UnsupportedMediaType, err, ""), "")
 			return
 		}
+		if len(skipped) != 0 {
+			zerolog.Ctx(ctx).Debug().
+				Str("manifest", m.Hash.String()).
+				Int("count", len(skipped)).
+				Msg("skipped layers by media type")
+		}
 		next := path.Join(IndexReportAPIPath, m.Hash.String())
 
 		w.Header().Add("link", fmt.Sprintf(linkIndex, next))
 		w.Header().Add("link", fmt.Sprintf(linkReport, path.Join(VulnerabilityReportPath, m.Hash.String())))
-		validator := `"` + state + `"`
-		if unmodified(r, validator) {
-			w.WriteHeader(http.StatusPreconditionFailed)
-			return
+		if _, ok := r.Header["If-None-Match"]; ok {
+			prev, ok, err := serv.IndexReport(ctx, m.Hash)
+			if err == nil && ok {
+				b, err := encodeIndexReport(ctx, serv, prev, false, wantsIndexReportV2(r))
+				if err == nil && unmodified(r, indexReportValidator(state, b)) {
+					w.WriteHeader(http.StatusPreconditionFailed)
+					return
+				}
+			}
 		}
 
 		// TODO Do we need some sort of background context embedded in the HTTP
 		// struct?
-		report, err := serv.Index(ctx, &m)
+		report, err := serv.Index(ctx, m)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "index-error",
-				Message: fmt.Sprintf("failed to start scan: %v", err),
+			// Rejected signatures are Forbidden, and a full queue is
+			// Overloaded and worth retrying.
+			w.Header().Del("link")
+			if errors.Is(err, limit.ErrQueueFull) {
+				w.Header().Set("retry-after", "30")
 			}
+			apiError(w, err, "failed to start scan")
+			return
+		}
+
+		// The signature status was just recorded, so a failure to read it
+		// back isn't worth failing the request over.
+		v2 := wantsIndexReportV2(r)
+		b, err := encodeIndexReport(ctx, serv, report, false, v2)
+		if err != nil {
 			w.Header().Del("link")
-			je.Error(w, resp, http.StatusInternalServerError)
+			apiError(w, err, "failed to encode index report")
 			return
 		}
 
-		w.Header().Set("etag", validator)
+		w.Header().Set("etag", indexReportValidator(state, b))
 		w.Header().Set("location", next)
+		w.Header().Add("vary", "accept")
+		if v2 {
+			w.Header().Set("content-type", IndexReportV2Type)
+		}
 		defer writerError(w, &err)()
 		w.WriteHeader(http.StatusCreated)
-		err = json.NewEncoder(w).Encode(report)
+		_, err = w.Write(b)
 	}
 }
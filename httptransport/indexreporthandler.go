@@ -1,85 +1,172 @@
 package httptransport
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/quay/claircore"
-	je "github.com/quay/claircore/pkg/jsonerr"
 
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/baseimage"
+	"github.com/quay/clair/v4/indexer/budget"
+	"github.com/quay/clair/v4/indexer/eol"
+	"github.com/quay/clair/v4/indexer/exclude"
+	"github.com/quay/clair/v4/indexer/hook"
+	"github.com/quay/clair/v4/indexer/layers"
+	"github.com/quay/clair/v4/indexer/referrers"
+	"github.com/quay/clair/v4/indexer/signature"
 )
 
 // IndexReportHandler utilizes a Reporter to serialize
 // and return a claircore.IndexReport given a path parameter
 func IndexReportHandler(serv indexer.StateReporter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, annotationsSuffix) {
+			annotationsHandler(w, r, serv)
+			return
+		}
 		if r.Method != http.MethodGet {
-			resp := &je.Response{
-				Code:    "method-not-allowed",
-				Message: "endpoint only allows GET",
-			}
-			je.Error(w, resp, http.StatusMethodNotAllowed)
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
 			return
 		}
 		ctx := r.Context()
 
 		manifestStr := strings.TrimPrefix(r.URL.Path, IndexReportAPIPath)
 		if manifestStr == "" {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "malformed path. provide a single manifest hash",
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.New(clairerror.Invalid, "malformed path. provide a single manifest hash"), "")
 			return
 		}
 		manifest, err := claircore.ParseDigest(manifestStr)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "malformed path: " + err.Error(),
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "malformed path"), "")
 			return
 		}
 
 		state, err := serv.State(ctx)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "internal error",
-				Message: "could not retrieve indexer state " + err.Error(),
-			}
-			je.Error(w, resp, http.StatusInternalServerError)
+			apiError(w, err, "could not retrieve indexer state")
 			return
 		}
-		validator := `"` + state + `"`
-		if unmodified(r, validator) {
-			w.WriteHeader(http.StatusNotModified)
+		report, ok, err := serv.IndexReport(ctx, manifest)
+		if err != nil {
+			apiError(w, err, "")
 			return
 		}
-
-		report, ok, err := serv.IndexReport(ctx, manifest)
 		if !ok {
-			resp := &je.Response{
-				Code:    "not-found",
-				Message: fmt.Sprintf("index report for manifest %q not found", manifest.String()),
-			}
-			je.Error(w, resp, http.StatusNotFound)
+			apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("index report for manifest %q not found", manifest.String())), "")
 			return
 		}
+
+		v2 := wantsIndexReportV2(r)
+		b, err := encodeIndexReport(ctx, serv, report, true, v2)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "internal-server-error",
-				Message: err.Error(),
-			}
-			je.Error(w, resp, http.StatusInternalServerError)
+			apiError(w, err, "")
+			return
+		}
+		validator := indexReportValidator(state, b)
+		w.Header().Set("etag", validator)
+		w.Header().Add("vary", "accept")
+		if unmodified(r, validator) {
+			w.WriteHeader(http.StatusNotModified)
 			return
 		}
 
-		w.Header().Add("etag", validator)
+		if v2 {
+			w.Header().Set("content-type", IndexReportV2Type)
+		} else {
+			w.Header().Set("content-type", "application/json")
+		}
 		defer writerError(w, &err)()
-		err = json.NewEncoder(w).Encode(report)
+		_, err = w.Write(b)
 	}
 }
+
+// IndexReportResponse is an index report with the outcome of verifying the
+// manifest's signatures, the packages excluded from it, the layers truncated
+// by scan limits, its base image, and what content hooks found in it, when
+// those are configured, the end of life distributions in it, and the layers
+// its packages came from, when asked for.
+type indexReportResponse struct {
+	*claircore.IndexReport
+	Signature  *signature.Status    `json:"signature,omitempty"`
+	Referrers  []referrers.Referrer `json:"referrers,omitempty"`
+	Excluded   []exclude.Exclusion  `json:"excluded,omitempty"`
+	Truncated  []budget.Truncation  `json:"truncated,omitempty"`
+	BaseImage  *baseimage.Base      `json:"base_image,omitempty"`
+	Extensions *reportExtensions    `json:"extensions,omitempty"`
+	Layers     []layers.Layer       `json:"layers,omitempty"`
+	Scopes     map[string]string    `json:"package_scopes,omitempty"`
+	// Annotations are the key/value pairs clients attached to the manifest.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// EndOfLife lists the manifest's distributions that no longer get
+	// security updates, so the report may be missing vulnerabilities.
+	EndOfLife []eol.Notice `json:"end_of_life,omitempty"`
+}
+
+// ReportExtensions holds the results of indexer extensions that inspect
+// layers beyond what claircore does.
+type reportExtensions struct {
+	Content []hook.Result `json:"content,omitempty"`
+}
+
+// SignatureIndexer finds the signature.Indexer among the wrapped indexers,
+// if there is one.
+func signatureIndexer(s interface{}) (*signature.Indexer, bool) {
+	var i *signature.Indexer
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		i, ok = s.(*signature.Indexer)
+		return ok
+	})
+	return i, ok
+}
+
+// ReferrersIndexer finds the referrers.Indexer among the wrapped indexers,
+// if there is one.
+func referrersIndexer(s interface{}) (*referrers.Indexer, bool) {
+	var i *referrers.Indexer
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		i, ok = s.(*referrers.Indexer)
+		return ok
+	})
+	return i, ok
+}
+
+// ExcludeIndexer finds the exclude.Indexer among the wrapped indexers, if
+// there is one.
+func excludeIndexer(s interface{}) (*exclude.Indexer, bool) {
+	var i *exclude.Indexer
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		i, ok = s.(*exclude.Indexer)
+		return ok
+	})
+	return i, ok
+}
+
+// BudgetIndexer finds the budget.Indexer among the wrapped indexers, if
+// there is one.
+func budgetIndexer(s interface{}) (*budget.Indexer, bool) {
+	var i *budget.Indexer
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		i, ok = s.(*budget.Indexer)
+		return ok
+	})
+	return i, ok
+}
+
+// HookIndexer finds the hook.Indexer among the wrapped indexers, if there is
+// one.
+func hookIndexer(s interface{}) (*hook.Indexer, bool) {
+	var i *hook.Indexer
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		i, ok = s.(*hook.Indexer)
+		return ok
+	})
+	return i, ok
+}
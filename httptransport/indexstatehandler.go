@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"net/http"
 
-	je "github.com/quay/claircore/pkg/jsonerr"
-
 	"github.com/quay/clair/v4/indexer"
 )
 
@@ -24,11 +22,7 @@ func IndexStateHandler(service indexer.Stater) http.HandlerFunc {
 		ctx := r.Context()
 		s, err := service.State(ctx)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "internal error",
-				Message: "could not retrieve indexer state " + err.Error(),
-			}
-			je.Error(w, resp, http.StatusInternalServerError)
+			apiError(w, err, "could not retrieve indexer state")
 			return
 		}
 
@@ -0,0 +1,102 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/inventory"
+)
+
+// InventoryResponse is a page of indexed manifests.
+type InventoryResponse struct {
+	Page      ManifestPage         `json:"page"`
+	Manifests []inventory.Manifest `json:"manifests"`
+}
+
+// InventoryHandler lists the indexed manifests, a page at a time.
+//
+// The "older_than" and "newer_than" parameters are durations limiting the
+// manifests by when they were last indexed, and each "annotation" parameter,
+// as "key=value", limits them to ones with that annotation.
+func inventoryHandler(w http.ResponseWriter, r *http.Request, serv indexer.Service) {
+	ctx := r.Context()
+	l, ok := inventory.Find(serv)
+	if !ok {
+		apiError(w, clairerror.New(clairerror.Unimplemented, "manifest inventory is not configured"), "")
+		return
+	}
+	q := r.URL.Query()
+	iq := inventory.Query{Next: q.Get("next")}
+	if param := q.Get("page_size"); param != "" {
+		n, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			apiError(w, clairerror.New(clairerror.Invalid, "could not parse \"page_size\" query param into integer"), "")
+			return
+		}
+		if n > inventory.MaxPageSize {
+			n = inventory.MaxPageSize
+		}
+		iq.Size = int(n)
+	}
+	if iq.Size == 0 {
+		iq.Size = DefaultPageSize
+	}
+	now := time.Now()
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{"older_than", &iq.IndexedBefore},
+		{"newer_than", &iq.IndexedAfter},
+	} {
+		param := q.Get(p.name)
+		if param == "" {
+			continue
+		}
+		d, err := time.ParseDuration(param)
+		if err != nil || d < 0 {
+			apiError(w, clairerror.New(clairerror.Invalid, fmt.Sprintf("could not parse %q query param into a duration", p.name)), "")
+			return
+		}
+		*p.t = now.Add(-d)
+	}
+	for _, a := range q["annotation"] {
+		i := strings.IndexByte(a, '=')
+		if i < 1 {
+			apiError(w, clairerror.New(clairerror.Invalid, fmt.Sprintf("malformed \"annotation\" query param %q, want key=value", a)), "")
+			return
+		}
+		if iq.Annotations == nil {
+			iq.Annotations = make(map[string]string)
+		}
+		iq.Annotations[a[:i]] = a[i+1:]
+	}
+
+	p, err := l.List(ctx, &iq)
+	switch {
+	case errors.Is(err, inventory.ErrNotConfigured):
+		apiError(w, clairerror.New(clairerror.Unimplemented, "manifest inventory is not configured"), "")
+		return
+	case errors.Is(err, inventory.ErrNoAnnotations):
+		apiError(w, clairerror.New(clairerror.Invalid, "annotations are not configured"), "")
+		return
+	case err != nil:
+		apiError(w, err, "could not list manifests")
+		return
+	}
+
+	resp := InventoryResponse{
+		Page:      ManifestPage{Size: uint64(iq.PageSize()), Next: p.Next},
+		Manifests: p.Manifests,
+	}
+	w.Header().Set("content-type", "application/json")
+	defer writerError(w, &err)()
+	err = json.NewEncoder(w).Encode(&resp)
+}
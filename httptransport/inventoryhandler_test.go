@@ -0,0 +1,117 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/inventory"
+	"github.com/quay/clair/v4/indexer/mediatype"
+)
+
+type listerIndexer struct {
+	indexer.Service
+	list func(context.Context, *inventory.Query) (*inventory.Page, error)
+}
+
+func (l *listerIndexer) List(ctx context.Context, q *inventory.Query) (*inventory.Page, error) {
+	return l.list(ctx, q)
+}
+
+func TestInventoryHandler(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var got *inventory.Query
+	idx := &listerIndexer{
+		Service: &indexer.Mock{},
+		list: func(_ context.Context, q *inventory.Query) (*inventory.Page, error) {
+			got = q
+			if len(q.Annotations) != 0 && q.Annotations["team"] != "payments" {
+				return nil, inventory.ErrNoAnnotations
+			}
+			return &inventory.Page{
+				Manifests: []inventory.Manifest{{
+					Hash:     claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64)),
+					State:    "IndexFinished",
+					Scanners: []inventory.Scanner{},
+				}},
+				Next: "sha256:b",
+			}, nil
+		},
+	}
+	srv := httptest.NewServer(IndexHandler(idx, mediatype.Policy{}))
+	defer srv.Close()
+	c := srv.Client()
+
+	get := func(t *testing.T, query string, want int) *InventoryResponse {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if got := res.StatusCode; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		if want != http.StatusOK {
+			return nil
+		}
+		var out InventoryResponse
+		if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return &out
+	}
+
+	t.Run("Query", func(t *testing.T) {
+		before := time.Now()
+		r := get(t, "page_size=2&next=sha256:a&older_than=1h&annotation=team=payments", http.StatusOK)
+		if r.Page.Size != 2 || r.Page.Next != "sha256:b" || len(r.Manifests) != 1 {
+			t.Errorf("unexpected response: %+v", r)
+		}
+		if got.Next != "sha256:a" || got.Annotations["team"] != "payments" || !got.IndexedAfter.IsZero() {
+			t.Errorf("unexpected query: %+v", got)
+		}
+		if d := before.Sub(got.IndexedBefore); d < time.Hour-time.Second || d > time.Hour+time.Second {
+			t.Errorf("unexpected cutoff: %v", got.IndexedBefore)
+		}
+	})
+	t.Run("PageSizeCapped", func(t *testing.T) {
+		r := get(t, "page_size=100000", http.StatusOK)
+		if got, want := r.Page.Size, uint64(inventory.MaxPageSize); got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("BadDuration", func(t *testing.T) {
+		get(t, "newer_than=soon", http.StatusBadRequest)
+	})
+	t.Run("BadAnnotation", func(t *testing.T) {
+		get(t, "annotation=team", http.StatusBadRequest)
+	})
+	t.Run("NoAnnotations", func(t *testing.T) {
+		get(t, "annotation=env=prod", http.StatusBadRequest)
+	})
+	t.Run("NotConfigured", func(t *testing.T) {
+		srv := httptest.NewServer(IndexHandler(&indexer.Mock{}, mediatype.Policy{}))
+		defer srv.Close()
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusNotImplemented; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+}
@@ -11,59 +11,37 @@ import (
 	"github.com/google/uuid"
 	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/notifier"
-	je "github.com/quay/claircore/pkg/jsonerr"
 )
 
 // KeyByIDHandler returns a particular key queried by ID in JWK format.
 func KeyByIDHandler(keystore notifier.KeyStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			resp := &je.Response{
-				Code:    "method-not-allowed",
-				Message: "endpoint only allows GET",
-			}
-			je.Error(w, resp, http.StatusMethodNotAllowed)
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
 			return
 		}
 		keyParam := path.Base(r.URL.Path)
 		if keyParam == "" {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "malformed path. must provide a key id",
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.New(clairerror.Invalid, "malformed path. must provide a key id"), "")
 			return
 		}
 		keyID, err := uuid.Parse(keyParam)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "malformed path. could not parse into uuid: " + err.Error(),
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "malformed path. could not parse into uuid"), "")
 			return
 		}
 
 		ctx := r.Context()
 		k, err := keystore.KeyByID(ctx, keyID)
 		switch {
-		case errors.As(err, &clairerror.ErrKeyNotFound{}):
-			resp := &je.Response{
-				Code:    "not-found",
-				Message: "the key id " + keyID.String() + " does not exist",
-			}
-			je.Error(w, resp, http.StatusNotFound)
+		case errors.Is(err, clairerror.NotFound):
+			apiError(w, clairerror.New(clairerror.NotFound, "the key id "+keyID.String()+" does not exist"), "")
 			return
 		case err == nil:
 			// hop out
 		default:
-			resp := &je.Response{
-				Code:    "internal-server-error",
-				Message: err.Error(),
-			}
-			je.Error(w, resp, http.StatusInternalServerError)
+			apiError(w, err, "")
 			return
-
 		}
 
 		jwk := jose.JSONWebKey{
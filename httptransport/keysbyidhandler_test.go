@@ -28,7 +28,7 @@ func testKeyByIDNotFound(t *testing.T) {
 	id := uuid.New()
 	mock := &notifier.MockKeyStore{
 		KeyByID_: func(ctx context.Context, ID uuid.UUID) (notifier.Key, error) {
-			return notifier.Key{}, clairerror.ErrKeyNotFound{id}
+			return notifier.Key{}, clairerror.New(clairerror.NotFound, "key with id "+id.String()+" not found")
 		},
 	}
 
@@ -76,7 +76,7 @@ func testKeyByID(t *testing.T) {
 	t.Parallel()
 	kp := (genKeyPair(t, 1))[0]
 	exp := time.Now().Add(10 * time.Minute)
-	key := notifier.Key{kp.ID, exp, kp.Public}
+	key := notifier.Key{ID: kp.ID, Expiration: exp, Public: kp.Public}
 
 	mock := &notifier.MockKeyStore{
 		KeyByID_: func(ctx context.Context, ID uuid.UUID) (notifier.Key, error) {
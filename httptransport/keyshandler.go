@@ -6,30 +6,22 @@ import (
 
 	jose "gopkg.in/square/go-jose.v2"
 
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/notifier"
-	je "github.com/quay/claircore/pkg/jsonerr"
 )
 
 // KeysHandler returns all keys persisted in the keystore in JWK set format.
 func KeysHandler(keystore notifier.KeyStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			resp := &je.Response{
-				Code:    "method-not-allowed",
-				Message: "endpoint only allows GET",
-			}
-			je.Error(w, resp, http.StatusMethodNotAllowed)
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
 			return
 		}
 
 		ctx := r.Context()
 		keys, err := keystore.Keys(ctx)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "internal-server-error",
-				Message: err.Error(),
-			}
-			je.Error(w, resp, http.StatusInternalServerError)
+			apiError(w, err, "")
 			return
 		}
 
@@ -38,11 +30,7 @@ func KeysHandler(keystore notifier.KeyStore) http.HandlerFunc {
 		}
 		for _, k := range keys {
 			if err := ctx.Err(); err != nil {
-				resp := &je.Response{
-					Code:    "internal-server-error",
-					Message: "internal server errror",
-				}
-				je.Error(w, resp, http.StatusInternalServerError)
+				apiError(w, clairerror.New(clairerror.Internal, "internal server errror"), "")
 				return
 			}
 			jwk := jose.JSONWebKey{
@@ -29,7 +29,9 @@ func testKeys(t *testing.T) {
 	keys := []notifier.Key{}
 	for _, kp := range kps {
 		keys = append(keys, notifier.Key{
-			kp.ID, exp, kp.Public,
+			ID:         kp.ID,
+			Expiration: exp,
+			Public:     kp.Public,
 		})
 	}
 
@@ -0,0 +1,70 @@
+package httptransport
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/quay/claircore"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/upload"
+)
+
+// UploadIndexer finds the upload.Indexer among the wrapped indexers, if there
+// is one.
+func uploadIndexer(s indexer.Service) (*upload.Indexer, bool) {
+	var u *upload.Indexer
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		u, ok = s.(*upload.Indexer)
+		return ok
+	})
+	return u, ok
+}
+
+// LayerHandler stores the layer named by the request path on PUT, and
+// reports whether it's present on HEAD.
+//
+// Layers uploaded this way are used for manifest layers submitted without a
+// URI.
+func LayerHandler(s *upload.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		d, err := claircore.ParseDigest(path.Base(r.URL.Path))
+		if err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "malformed path"), "")
+			return
+		}
+		switch r.Method {
+		case http.MethodHead:
+			if !s.Has(d) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case http.MethodPut:
+			if r.ContentLength > s.MaxSize() {
+				layerError(w, upload.ErrTooLarge, s)
+				return
+			}
+			if err := s.Put(ctx, d, r.Body); err != nil {
+				layerError(w, err, s)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows HEAD or PUT"), "")
+		}
+	}
+}
+
+func layerError(w http.ResponseWriter, err error, s *upload.Store) {
+	msg := ""
+	if errors.Is(err, upload.ErrTooLarge) {
+		msg = fmt.Sprintf("layers may be at most %d bytes", s.MaxSize())
+	}
+	apiError(w, err, msg)
+}
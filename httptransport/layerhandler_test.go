@@ -0,0 +1,23 @@
+package httptransport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/limit"
+	"github.com/quay/clair/v4/indexer/upload"
+)
+
+func TestUploadIndexerLimited(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	u, err := upload.NewIndexer(ctx, &indexer.Mock{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := uploadIndexer(limit.NewIndexer(u, 1, 0, 0))
+	if !ok || got != u {
+		t.Errorf("got: %v, %v; want: %v, true", got, ok, u)
+	}
+}
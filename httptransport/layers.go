@@ -0,0 +1,76 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/baseimage"
+	"github.com/quay/clair/v4/indexer/layers"
+)
+
+// These are the media types a client asks for, via the Accept header, to
+// receive reports with layer attribution: the packages, and for
+// vulnerability reports the vulnerabilities, each layer introduced.
+const (
+	IndexReportV2Type         = "application/vnd.clair.indexreport.v2+json"
+	VulnerabilityReportV2Type = "application/vnd.clair.vulnerabilityreport.v2+json"
+)
+
+// WantsIndexReportV2 reports whether the request asked for an index report
+// with layer attribution.
+func wantsIndexReportV2(r *http.Request) bool {
+	return accepts(r, IndexReportV2Type)
+}
+
+// WantsVulnerabilityReportV2 reports whether the request asked for a
+// vulnerability report with layer attribution.
+func wantsVulnerabilityReportV2(r *http.Request) bool {
+	return accepts(r, VulnerabilityReportV2Type)
+}
+
+// LayerLister finds a layers.Lister among the wrapped indexers, if there is
+// one. Remote indexers implement it themselves.
+func layerLister(s interface{}) (layers.Lister, bool) {
+	var l layers.Lister
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		l, ok = s.(layers.Lister)
+		return ok
+	})
+	return l, ok
+}
+
+// LayerOrder returns the manifest's layers, in order, or nil if the indexer
+// can't report them.
+func layerOrder(ctx context.Context, serv interface{}, manifest claircore.Digest) ([]claircore.Digest, error) {
+	l, ok := layerLister(serv)
+	if !ok {
+		return nil, nil
+	}
+	return l.Layers(ctx, manifest)
+}
+
+// BaseDetector finds a baseimage.Detector among the wrapped indexers, if
+// there is one. Remote indexers implement it themselves.
+func baseDetector(s interface{}) (baseimage.Detector, bool) {
+	var d baseimage.Detector
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		d, ok = s.(baseimage.Detector)
+		return ok
+	})
+	return d, ok
+}
+
+// BaseImage returns the manifest's probable base image, or nil if it has
+// none or the indexer can't detect them.
+func baseImage(ctx context.Context, serv interface{}, manifest claircore.Digest) (*baseimage.Base, error) {
+	d, ok := baseDetector(serv)
+	if !ok {
+		return nil, nil
+	}
+	return d.Base(ctx, manifest)
+}
@@ -0,0 +1,37 @@
+package httptransport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/archive"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/gc"
+)
+
+type layersIndexer struct {
+	indexer.Service
+	ls []claircore.Digest
+}
+
+func (l *layersIndexer) Layers(_ context.Context, _ claircore.Digest) ([]claircore.Digest, error) {
+	return l.ls, nil
+}
+
+func TestLayerListerWrapped(t *testing.T) {
+	ctx := context.Background()
+	want := []claircore.Digest{claircore.MustParseDigest("sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")}
+	var s indexer.Service = &layersIndexer{Service: &indexer.Mock{}, ls: want}
+	s = gc.NewTracker(s, nil)
+	s = archive.NewIndexer(s, nil)
+
+	got, err := layerOrder(ctx, s, want[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].String() != want[0].String() {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
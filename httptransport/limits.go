@@ -0,0 +1,175 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/config"
+	"github.com/quay/clair/v4/middleware/deadline"
+)
+
+var (
+	errBodyTooLarge = errors.New("request body too large")
+	errReadTimeout  = errors.New("request body not read in time")
+)
+
+// ConfigureWithLimits wraps the server's handler to enforce the configured
+// request limits, picked by the route each request is handled by.
+func (t *Server) configureWithLimits(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "httptransport/Server.configureWithLimits").
+		Logger()
+	for route := range t.conf.Limits.Routes {
+		if _, p := t.ServeMux.Handler(&http.Request{URL: &url.URL{Path: route}}); p != route {
+			log.Warn().Str("route", route).Msg("limits configured for an unknown route")
+		}
+	}
+	t.Server.Handler = withLimits(t.Server.Handler, t.ServeMux, &t.conf.Limits)
+}
+
+// ConfigureWithDeadlines wraps the server's handler to apply client-supplied
+// deadlines and record requests canceled by clients.
+func (t *Server) configureWithDeadlines() {
+	t.Server.Handler = deadline.Handler(t.Server.Handler, func(r *http.Request) string {
+		_, route := t.ServeMux.Handler(r)
+		return route
+	})
+}
+
+// WithLimits enforces the limits for the route the mux would handle each
+// request with.
+//
+// Bodies over the size limit are answered with a 413 and bodies not read
+// within the read timeout with a 408. If the body's length isn't known ahead
+// of time, the handler sees an error reading it, and the error status it
+// responds with is replaced.
+func withLimits(next http.Handler, mux *http.ServeMux, l *config.Limits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		rl := l.For(route)
+		if rl == (config.RouteLimits{}) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if rl.MaxBodySize > 0 && r.ContentLength > rl.MaxBodySize {
+			apiError(w, clairerror.New(clairerror.TooLarge, fmt.Sprintf("request body larger than %d bytes", rl.MaxBodySize)), "")
+			return
+		}
+		ctx := r.Context()
+		if rl.WriteTimeout > 0 {
+			var done context.CancelFunc
+			ctx, done = context.WithTimeout(ctx, rl.WriteTimeout)
+			defer done()
+		}
+		r = r.WithContext(ctx)
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+		b := &limitedBody{ReadCloser: r.Body, max: rl.MaxBodySize}
+		if rl.ReadTimeout > 0 {
+			t := time.AfterFunc(rl.ReadTimeout, b.expire)
+			defer t.Stop()
+		}
+		r.Body = b
+		next.ServeHTTP(&limitedWriter{ResponseWriter: w, body: b}, r)
+	})
+}
+
+// LimitedBody is a request body enforcing a size limit and read timeout.
+type limitedBody struct {
+	io.ReadCloser
+	max int64
+
+	mu   sync.Mutex
+	n    int64
+	eof  bool
+	fail error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	fail, n := b.fail, b.n
+	b.mu.Unlock()
+	if fail != nil {
+		return 0, fail
+	}
+	// Read one byte past the limit, to tell a body at the limit from one
+	// over it.
+	if b.max > 0 && int64(len(p)) > b.max-n+1 {
+		p = p[:b.max-n+1]
+	}
+	ct, err := b.ReadCloser.Read(p)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.n += int64(ct)
+	switch {
+	case b.fail != nil:
+		// Expired while reading.
+		return 0, b.fail
+	case b.max > 0 && b.n > b.max:
+		b.fail = errBodyTooLarge
+		return ct - int(b.n-b.max), b.fail
+	case err == io.EOF:
+		b.eof = true
+	}
+	return ct, err
+}
+
+// Expire fails the body if it hasn't been read completely, closing it to
+// unblock any pending Read.
+func (b *limitedBody) expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.eof || b.fail != nil {
+		return
+	}
+	b.fail = errReadTimeout
+	b.ReadCloser.Close()
+}
+
+// Status reports the status for the body's failure, if it failed.
+func (b *limitedBody) status() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.fail {
+	case errBodyTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case errReadTimeout:
+		return http.StatusRequestTimeout
+	}
+	return 0
+}
+
+// LimitedWriter replaces the error status a handler responds with when its
+// request body failed a limit.
+type limitedWriter struct {
+	http.ResponseWriter
+	body *limitedBody
+}
+
+func (w *limitedWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest {
+		if s := w.body.status(); s != 0 {
+			code = s
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher, if the wrapped ResponseWriter does.
+func (w *limitedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,97 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	je "github.com/quay/claircore/pkg/jsonerr"
+
+	"github.com/quay/clair/v4/config"
+)
+
+func TestLimits(t *testing.T) {
+	// Echo stands in for a handler decoding the request body.
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v interface{}
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			je.Error(w, &je.Response{Code: "bad-request", Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("missing deadline")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/small", echo)
+	mux.Handle("/large", echo)
+	l := &config.Limits{
+		RouteLimits: config.RouteLimits{
+			MaxBodySize:  16,
+			ReadTimeout:  50 * time.Millisecond,
+			WriteTimeout: time.Minute,
+		},
+		Routes: map[string]config.RouteLimits{
+			"/large": {MaxBodySize: 1024},
+		},
+	}
+	srv := httptest.NewServer(withLimits(mux, mux, l))
+	defer srv.Close()
+
+	do := func(path string, body io.Reader, length int64) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.ContentLength = length
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	big := `["` + strings.Repeat("a", 64) + `"]`
+	tt := []struct {
+		name   string
+		path   string
+		body   io.Reader
+		length int64
+		want   int
+	}{
+		{"Small", "/small", strings.NewReader(`{}`), 2, http.StatusOK},
+		{"ContentLength", "/small", strings.NewReader(big), int64(len(big)), http.StatusRequestEntityTooLarge},
+		// A length of -1 makes the client send the body chunked.
+		{"Chunked", "/small", strings.NewReader(big), -1, http.StatusRequestEntityTooLarge},
+		{"Route", "/large", strings.NewReader(big), -1, http.StatusOK},
+		{"Stalled", "/small", &stalled{}, -1, http.StatusRequestTimeout},
+	}
+	for _, tc := range tt {
+		if got := do(tc.path, tc.body, tc.length); got != tc.want {
+			t.Errorf("%s: got: %d, want: %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+// Stalled is a request body that sends the start of a JSON document and then
+// stops for longer than the read timeout.
+type stalled struct {
+	sent bool
+}
+
+func (s *stalled) Read(p []byte) (int, error) {
+	if !s.sent {
+		s.sent = true
+		return copy(p, `{"a":`), nil
+	}
+	time.Sleep(500 * time.Millisecond)
+	return 0, io.EOF
+}
@@ -17,6 +17,14 @@ func (lrw *httpStatusWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush implements http.Flusher, so handlers streaming responses still work
+// when logged.
+func (lrw *httpStatusWriter) Flush() {
+	if f, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // LoggingHandler will log HTTP requests using the pre initialized zerolog.
 func LoggingHandler(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
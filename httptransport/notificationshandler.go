@@ -2,16 +2,17 @@ package httptransport
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"path/filepath"
 	"strconv"
 
 	"github.com/google/uuid"
 
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/notifier"
 	"github.com/quay/clair/v4/notifier/service"
-	je "github.com/quay/claircore/pkg/jsonerr"
+	"github.com/quay/clair/v4/tenant"
 	"github.com/rs/zerolog"
 )
 
@@ -41,11 +42,7 @@ func (h *NotifHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case http.MethodDelete:
 		h.Delete(w, r)
 	default:
-		resp := &je.Response{
-			Code:    "method-not-allowed",
-			Message: "endpoint only allows POST",
-		}
-		je.Error(w, resp, http.StatusMethodNotAllowed)
+		apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows POST"), "")
 		return
 	}
 }
@@ -57,23 +54,19 @@ func (h *NotifHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := filepath.Base(path)
 	notificationID, err := uuid.Parse(id)
 	if err != nil {
-		resp := &je.Response{
-			Code:    "bad-request",
-			Message: fmt.Sprintf("could not parse notification id: %v", err),
-		}
 		log.Warn().Err(err).Msg("could not parse notification id")
-		je.Error(w, resp, http.StatusBadRequest)
+		apiError(w, clairerror.Wrap(clairerror.Invalid, err, "could not parse notification id"), "")
 		return
 	}
 
 	err = h.serv.DeleteNotifications(ctx, notificationID)
+	if errors.Is(err, tenant.ErrForbidden) {
+		apiError(w, clairerror.New(clairerror.Forbidden, "tenants may not delete notifications"), "")
+		return
+	}
 	if err != nil {
-		resp := &je.Response{
-			Code:    "internal-server-error",
-			Message: fmt.Sprintf("could not delete notification: %v", err),
-		}
 		log.Warn().Err(err).Msg("could not delete notification")
-		je.Error(w, resp, http.StatusInternalServerError)
+		apiError(w, err, "could not delete notification")
 		return
 	}
 	return
@@ -87,12 +80,8 @@ func (h *NotifHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := filepath.Base(path)
 	notificationID, err := uuid.Parse(id)
 	if err != nil {
-		resp := &je.Response{
-			Code:    "bad-request",
-			Message: fmt.Sprintf("could not parse notification id: %v", err),
-		}
 		log.Warn().Err(err).Msg("could not parse notification id")
-		je.Error(w, resp, http.StatusBadRequest)
+		apiError(w, clairerror.Wrap(clairerror.Invalid, err, "could not parse notification id"), "")
 		return
 	}
 
@@ -101,11 +90,7 @@ func (h *NotifHandler) Get(w http.ResponseWriter, r *http.Request) {
 	if param := r.URL.Query().Get("page_size"); param != "" {
 		pageSize, err = strconv.ParseUint(param, 10, 64)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "could not parse \"page_size\" query param into integer",
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.New(clairerror.Invalid, "could not parse \"page_size\" query param into integer"), "")
 			return
 		}
 	}
@@ -118,11 +103,7 @@ func (h *NotifHandler) Get(w http.ResponseWriter, r *http.Request) {
 	if param := r.URL.Query().Get("next"); param != "" {
 		n, err := uuid.Parse(param)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "could not parse \"next\" query param into uuid",
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.New(clairerror.Invalid, "could not parse \"next\" query param into uuid"), "")
 			return
 		}
 		if n != uuid.Nil {
@@ -130,20 +111,43 @@ func (h *NotifHandler) Get(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	inP := &notifier.Page{
-		Size: pageSize,
-		Next: next,
-	}
-	notifications, outP, err := h.serv.Notifications(ctx, notificationID, inP)
+	// optional filter parameters, added to the callbacks of deliverers with
+	// a filter
+	filter, err := notifier.FilterFromQuery(r.URL.Query())
 	if err != nil {
-		resp := &je.Response{
-			Code:    "internal-server-error",
-			Message: "failed to retrieve notifications: " + err.Error(),
-		}
-		je.Error(w, resp, http.StatusInternalServerError)
+		apiError(w, clairerror.Wrap(clairerror.Invalid, err, ""), "")
 		return
 	}
 
+	// With a filter, pages are read until one is filled or there are no
+	// more, so that a page is only empty at the end.
+	var notifications []notifier.Notification
+	var outP notifier.Page
+	for {
+		inP := &notifier.Page{
+			Size: pageSize - uint64(len(notifications)),
+			Next: next,
+		}
+		ns, p, err := h.serv.Notifications(ctx, notificationID, inP)
+		if err != nil {
+			apiError(w, err, "failed to retrieve notifications")
+			return
+		}
+		notifications = append(notifications, filter.Apply(ns)...)
+		outP, next = p, p.Next
+		if filter == nil || next == nil || uint64(len(notifications)) >= pageSize {
+			break
+		}
+	}
+	outP.Size = pageSize
+
+	if legacyAPI(ctx) {
+		// Package URLs came after the original version of the API.
+		for i := range notifications {
+			notifications[i].Vulnerability.PURL = ""
+		}
+	}
+
 	var response = Response{
 		Page:          outP,
 		Notifications: notifications,
@@ -19,6 +19,7 @@ func TestNotificationsHandler(t *testing.T) {
 	t.Run("Methods", testNotificationsHandlerMethods)
 	t.Run("Get", testNotificationHandlerGet)
 	t.Run("GetParams", testNotificationHandlerGetParams)
+	t.Run("GetFilter", testNotificationHandlerGetFilter)
 	t.Run("Delete", testNotificationHandlerDelete)
 }
 
@@ -171,6 +172,72 @@ func testNotificationHandlerGetParams(t *testing.T) {
 	}
 }
 
+// testNotificationHandlerGetFilter confirms the Get handler filters pages,
+// reading more until one is filled.
+func testNotificationHandlerGetFilter(t *testing.T) {
+	t.Parallel()
+	var (
+		noteID = uuid.New()
+		nextID = uuid.New()
+	)
+	note := func(sev string) notifier.Notification {
+		return notifier.Notification{ID: uuid.New(), Vulnerability: notifier.VulnSummary{Severity: sev}}
+	}
+	pages := []struct {
+		in  notifier.Page
+		out []notifier.Notification
+		p   notifier.Page
+	}{
+		{
+			in:  notifier.Page{Size: 2},
+			out: []notifier.Notification{note("Low"), note("High")},
+			p:   notifier.Page{Size: 2, Next: &nextID},
+		},
+		{
+			in:  notifier.Page{Size: 1, Next: &nextID},
+			out: []notifier.Notification{note("Critical")},
+			p:   notifier.Page{Size: 1},
+		},
+	}
+	var calls int
+	nm := &service.Mock{
+		Notifications_: func(ctx context.Context, id uuid.UUID, page *notifier.Page) ([]notifier.Notification, notifier.Page, error) {
+			if calls == len(pages) {
+				t.Fatal("too many calls")
+			}
+			pg := pages[calls]
+			calls++
+			if !cmp.Equal(page, &pg.in) {
+				t.Fatalf("got: %v, wanted: %v", page, pg.in)
+			}
+			return pg.out, pg.p, nil
+		},
+	}
+
+	h := NotificationHandler(nm)
+	rr := httptest.NewRecorder()
+	u, _ := url.Parse("http://clair-notifier/notifier/api/v1/notification/" + noteID.String() + "?page_size=2&min_severity=High")
+	h.Get(rr, &http.Request{URL: u, Method: http.MethodGet})
+	res := rr.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got: %v, wanted: %v", res.StatusCode, http.StatusOK)
+	}
+	// The notifications' zero digests don't decode, so only count them.
+	var noteResp struct {
+		Page          notifier.Page     `json:"page"`
+		Notifications []json.RawMessage `json:"notifications"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&noteResp); err != nil {
+		t.Fatalf("failed to deserialize notification response: %v", err)
+	}
+	if got, want := len(noteResp.Notifications), 2; got != want {
+		t.Errorf("got: %d notifications, want: %d", got, want)
+	}
+	if want := (notifier.Page{Size: 2}); !cmp.Equal(noteResp.Page, want) {
+		t.Errorf("got: %v, want: %v", noteResp.Page, want)
+	}
+}
+
 func testNotificationsHandlerMethods(t *testing.T) {
 	t.Parallel()
 	h := NotificationHandler(&service.Mock{})
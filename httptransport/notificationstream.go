@@ -0,0 +1,197 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+	"github.com/quay/clair/v4/notifier/service"
+)
+
+// NotificationStreamType is the media type of a notification stream.
+const NotificationStreamType = "text/event-stream"
+
+// NotificationStreamEvent is the event type of every event carrying
+// notifications in a notification stream.
+const NotificationStreamEvent = "notifications"
+
+// StreamEvent is the data of a "notifications" event: a page of the
+// notifications for a notification id.
+type StreamEvent struct {
+	NotificationID uuid.UUID               `json:"notification_id"`
+	Notifications  []notifier.Notification `json:"notifications"`
+}
+
+var (
+	// StreamPoll is how often the notifier is checked for new notification
+	// ids.
+	streamPoll = 2 * time.Second
+	// StreamKeepalive is how often a comment is written to an otherwise idle
+	// stream, so intermediaries don't close it.
+	streamKeepalive = 30 * time.Second
+)
+
+// StreamBatchSize is the number of notification ids fetched per poll.
+const streamBatchSize = 100
+
+// NotificationStreamHandler writes notifications as Server-Sent Events as
+// they're created, as an alternative to polling for callbacks.
+//
+// Every notification id is sent as one or more "notifications" events, one
+// per page of notifications. The last event for a notification id carries an
+// event id, which is the cursor: a client reconnecting with it in the
+// "Last-Event-ID" header, or the "cursor" query parameter, resumes with the
+// next notification id. Without a cursor, the stream starts with the oldest
+// notification id that hasn't been deleted.
+func NotificationStreamHandler(serv service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx).With().
+			Str("component", "httptransport/NotificationStreamHandler").
+			Logger()
+		if r.Method != http.MethodGet {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
+			return
+		}
+		f, ok := w.(http.Flusher)
+		if !ok {
+			apiError(w, clairerror.New(clairerror.Internal, "streaming unsupported"), "")
+			return
+		}
+
+		var cursor int64
+		c := r.Header.Get("Last-Event-ID")
+		if c == "" {
+			c = r.URL.Query().Get("cursor")
+		}
+		if c != "" {
+			var err error
+			cursor, err = strconv.ParseInt(c, 10, 64)
+			if err != nil || cursor < 0 {
+				apiError(w, clairerror.New(clairerror.Invalid, fmt.Sprintf("could not parse cursor %q", c)), "")
+				return
+			}
+		}
+		pageSize := uint64(DefaultPageSize)
+		if param := r.URL.Query().Get("page_size"); param != "" {
+			n, err := strconv.ParseUint(param, 10, 64)
+			if err != nil {
+				apiError(w, clairerror.New(clairerror.Invalid, "could not parse \"page_size\" query param into integer"), "")
+				return
+			}
+			if n != 0 {
+				pageSize = n
+			}
+		}
+
+		w.Header().Set("Content-Type", NotificationStreamType)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", streamPoll.Milliseconds())
+		f.Flush()
+
+		poll := time.NewTicker(streamPoll)
+		defer poll.Stop()
+		idle := time.Now()
+		for {
+			rs, err := serv.ReceiptsAfter(ctx, cursor, streamBatchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Warn().Err(err).Msg("failed to fetch receipts")
+				}
+				return
+			}
+			for _, rcpt := range rs {
+				if err := writeStreamEvents(w, serv, r, rcpt, pageSize); err != nil {
+					if ctx.Err() == nil {
+						log.Warn().Err(err).
+							Stringer("notification_id", rcpt.NotificationID).
+							Msg("failed to write notifications")
+					}
+					return
+				}
+				cursor = rcpt.Seq
+				f.Flush()
+				idle = time.Now()
+			}
+			// A full batch means there are probably more waiting.
+			if len(rs) == streamBatchSize {
+				continue
+			}
+			if time.Since(idle) >= streamKeepalive {
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+				f.Flush()
+				idle = time.Now()
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-draining(ctx):
+				// Clients reconnect with their last event id.
+				return
+			case <-poll.C:
+			}
+		}
+	}
+}
+
+// WriteStreamEvents writes the notifications for the receipt's notification
+// id, a page per event. The last event carries the receipt's sequence number
+// as its id.
+//
+// If there are no notifications to send, as happens when a tenant owns none
+// of the manifests, an event with only an id is written. Clients don't
+// dispatch it, but it still moves their cursor.
+func writeStreamEvents(w http.ResponseWriter, serv service.Service, r *http.Request, rcpt notifier.Receipt, size uint64) error {
+	ctx := r.Context()
+	page := &notifier.Page{Size: size}
+	var pending *StreamEvent
+	for {
+		ns, next, err := serv.Notifications(ctx, rcpt.NotificationID, page)
+		if err != nil {
+			return err
+		}
+		if len(ns) != 0 {
+			if pending != nil {
+				if err := writeStreamEvent(w, "", pending); err != nil {
+					return err
+				}
+			}
+			pending = &StreamEvent{NotificationID: rcpt.NotificationID, Notifications: ns}
+		}
+		if next.Next == nil {
+			break
+		}
+		page = &notifier.Page{Size: size, Next: next.Next}
+	}
+	id := strconv.FormatInt(rcpt.Seq, 10)
+	if pending == nil {
+		_, err := fmt.Fprintf(w, "id: %s\n\n", id)
+		return err
+	}
+	return writeStreamEvent(w, id, pending)
+}
+
+// WriteStreamEvent writes a single "notifications" event.
+func writeStreamEvent(w http.ResponseWriter, id string, ev *StreamEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", NotificationStreamEvent, b)
+	return err
+}
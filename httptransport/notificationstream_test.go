@@ -0,0 +1,178 @@
+package httptransport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+	"github.com/quay/clair/v4/notifier/service"
+)
+
+type sseEvent struct {
+	ID, Event, Data string
+}
+
+// ReadEvents parses n events, including ones only carrying an id, from the
+// stream.
+func readEvents(t *testing.T, s *bufio.Scanner, n int) []sseEvent {
+	var out []sseEvent
+	var cur sseEvent
+	var seen bool
+	for len(out) < n && s.Scan() {
+		l := s.Text()
+		switch {
+		case l == "":
+			if seen {
+				out = append(out, cur)
+			}
+			cur, seen = sseEvent{}, false
+		case strings.HasPrefix(l, "id: "):
+			cur.ID, seen = strings.TrimPrefix(l, "id: "), true
+		case strings.HasPrefix(l, "event: "):
+			cur.Event, seen = strings.TrimPrefix(l, "event: "), true
+		case strings.HasPrefix(l, "data: "):
+			cur.Data, seen = strings.TrimPrefix(l, "data: "), true
+		}
+	}
+	if len(out) != n {
+		t.Fatalf("got: %d events, want: %d (%v)", len(out), n, s.Err())
+	}
+	return out
+}
+
+// TestNotificationStream confirms notification ids are streamed a page at a
+// time, with the cursor on the last event, and that streams resume after the
+// provided cursor.
+func TestNotificationStream(t *testing.T) {
+	defer func(d time.Duration) { streamPoll = d }(streamPoll)
+	streamPoll = 10 * time.Millisecond
+	ctx, done := context.WithCancel(zlog.Test(context.Background(), t))
+	defer done()
+
+	paged, filtered := uuid.New(), uuid.New()
+	d := claircore.MustParseDigest("sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a")
+	ns := make([]notifier.Notification, 3)
+	for i := range ns {
+		ns[i] = notifier.Notification{ID: uuid.New(), Manifest: d, Reason: notifier.Added}
+	}
+	rs := []notifier.Receipt{
+		{NotificationID: paged, Status: notifier.Created, Seq: 4},
+		{NotificationID: filtered, Status: notifier.Delivered, Seq: 7},
+	}
+	var mu sync.Mutex
+	var cursors []int64
+	h := NotificationStreamHandler(&service.Mock{
+		ReceiptsAfter_: func(_ context.Context, seq int64, limit int) ([]notifier.Receipt, error) {
+			mu.Lock()
+			cursors = append(cursors, seq)
+			mu.Unlock()
+			var out []notifier.Receipt
+			for _, r := range rs {
+				if r.Seq > seq {
+					out = append(out, r)
+				}
+			}
+			return out, nil
+		},
+		Notifications_: func(_ context.Context, id uuid.UUID, p *notifier.Page) ([]notifier.Notification, notifier.Page, error) {
+			if id != paged {
+				return []notifier.Notification{}, notifier.Page{Size: p.Size}, nil
+			}
+			if p.Next == nil {
+				return ns[:2], notifier.Page{Size: p.Size, Next: &ns[1].ID}, nil
+			}
+			return ns[2:], notifier.Page{Size: p.Size}, nil
+		},
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	stream := func(cursor string) (*bufio.Scanner, func()) {
+		ctx, done := context.WithCancel(ctx)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?page_size=2", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cursor != "" {
+			req.Header.Set("Last-Event-ID", cursor)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := res.Header.Get("content-type"), NotificationStreamType; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		return bufio.NewScanner(res.Body), func() {
+			done()
+			res.Body.Close()
+		}
+	}
+
+	s, cancel := stream("")
+	evs := readEvents(t, s, 3)
+	cancel()
+	var got []uuid.UUID
+	for i, ev := range evs[:2] {
+		if ev.Event != NotificationStreamEvent {
+			t.Errorf("got: %q, want: %q", ev.Event, NotificationStreamEvent)
+		}
+		var se StreamEvent
+		if err := json.Unmarshal([]byte(ev.Data), &se); err != nil {
+			t.Fatal(err)
+		}
+		if se.NotificationID != paged {
+			t.Errorf("got: %v, want: %v", se.NotificationID, paged)
+		}
+		for _, n := range se.Notifications {
+			got = append(got, n.ID)
+		}
+		if wantID := map[int]string{0: "", 1: "4"}[i]; ev.ID != wantID {
+			t.Errorf("event %d: got id: %q, want: %q", i, ev.ID, wantID)
+		}
+	}
+	if len(got) != len(ns) {
+		t.Errorf("got: %d notifications, want: %d", len(got), len(ns))
+	}
+	// Nothing was left for the tenant, so only the cursor moves.
+	if got, want := evs[2], (sseEvent{ID: "7"}); got != want {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+
+	s, cancel = stream("4")
+	evs = readEvents(t, s, 1)
+	cancel()
+	if got, want := evs[0].ID, "7"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var resumed bool
+	for _, c := range cursors {
+		resumed = resumed || c == 4
+	}
+	if !resumed {
+		t.Errorf("stream didn't resume from cursor: %v", cursors)
+	}
+}
+
+func TestNotificationStreamBadCursor(t *testing.T) {
+	h := NotificationStreamHandler(&service.Mock{})
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, NotificationStreamPath+"?cursor=nope", nil)
+	h(rr, req)
+	if got, want := rr.Code, http.StatusBadRequest; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+}
@@ -1,23 +1,35 @@
+//go:build tools
 // +build tools
 
 // Openapigen is a script to take the OpenAPI YAML file, turn it into a JSON
-// document, and embed it into a source file for easy deployment.
+// document for every API version it describes, and embed them into a source
+// file for easy deployment.
+//
+// The versions are listed, oldest first, in the "x-clair-versions" key of
+// the document's info object. Any object with an "x-clair-since" key is left
+// out of the documents for versions before the one it names.
 package main
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"go/ast"
 	"go/format"
-	"go/token"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	versionsKey = "x-clair-versions"
+	sinceKey    = "x-clair-since"
+)
+
 func main() {
 	inFile := flag.String("in", "../openapi.yaml", "input YAML file")
 	outFile := flag.String("out", "discoveryhandler_gen.go", "output go file")
@@ -36,50 +48,46 @@ func main() {
 	if err := yaml.NewDecoder(inF).Decode(&tmp); err != nil {
 		log.Fatal(err)
 	}
-	embed, err := json.Marshal(convert(tmp))
-	if err != nil {
-		log.Fatal(err)
+	doc := convert(tmp).(map[string]interface{})
+	info, ok := doc["info"].(map[string]interface{})
+	if !ok {
+		log.Fatal("document has no info object")
+	}
+	var vs []string
+	if l, ok := info[versionsKey].([]interface{}); ok {
+		for _, v := range l {
+			vs = append(vs, fmt.Sprint(v))
+		}
+	}
+	if len(vs) == 0 {
+		log.Fatalf("document lists no versions in info.%s", versionsKey)
 	}
-	ck := sha256.Sum256(embed)
+	delete(info, versionsKey)
 
-	fs := token.NewFileSet()
-	// Make up a some file offsets to get things to not mash together so
-	// badly...
-	gf := fs.AddFile(*outFile, -1, 1024)
-	gf.AddLine(48)
-	gf.AddLine(49)
-	gf.AddLine(98)
-	gf.AddLine(99)
-	gend := &ast.File{
-		Package: gf.Pos(50),
-		Name:    ast.NewIdent(*pkgName),
-		Comments: []*ast.CommentGroup{
-			{List: []*ast.Comment{&ast.Comment{
-				Slash: gf.Pos(0),
-				Text:  `// Code generated by openapigen.go DO NOT EDIT.`,
-			}}}},
-		Decls: []ast.Decl{
-			&ast.GenDecl{
-				Tok:    token.CONST,
-				TokPos: gf.Pos(100),
-				Specs: []ast.Spec{
-					&ast.ValueSpec{
-						Names: []*ast.Ident{ast.NewIdent("_openapiJSON")},
-						Values: []ast.Expr{&ast.BasicLit{
-							Kind:  token.STRING,
-							Value: fmt.Sprintf("%#q", string(embed)),
-						}},
-					},
-					&ast.ValueSpec{
-						Names: []*ast.Ident{ast.NewIdent("_openapiJSONEtag")},
-						Values: []ast.Expr{&ast.BasicLit{
-							Kind:  token.STRING,
-							Value: fmt.Sprintf("`\"%x\"`", ck),
-						}},
-					},
-				},
-			},
-		},
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by openapigen.go DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", *pkgName)
+	fmt.Fprintln(&buf, "// _openapiVersions are the embedded API versions, oldest first.")
+	fmt.Fprintf(&buf, "var _openapiVersions = %#v\n\n", vs)
+	var docs, tags bytes.Buffer
+	for _, v := range vs {
+		d := prune(doc, v).(map[string]interface{})
+		d["info"].(map[string]interface{})["version"] = v
+		embed, err := json.Marshal(d)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(&docs, "%q: %#q,\n", v, string(embed))
+		fmt.Fprintf(&tags, "%q: `\"%x\"`,\n", v, sha256.Sum256(embed))
+	}
+	fmt.Fprintln(&buf, "// _openapiJSON and _openapiJSONEtag are the embedded documents and their")
+	fmt.Fprintln(&buf, "// validators, keyed by API version.")
+	fmt.Fprintf(&buf, "var (\n_openapiJSON = map[string]string{\n%s}\n", docs.String())
+	fmt.Fprintf(&buf, "_openapiJSONEtag = map[string]string{\n%s}\n)\n", tags.String())
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	outF, err := os.OpenFile(*outFile, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
@@ -87,7 +95,7 @@ func main() {
 		log.Fatal(err)
 	}
 	defer outF.Close()
-	if err := format.Node(outF, fs, gend); err != nil {
+	if _, err := outF.Write(out); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -113,3 +121,55 @@ func convert(i interface{}) interface{} {
 	}
 	return i
 }
+
+// Prune returns a copy of the document as of the version: without the
+// objects added after it, and without the keys marking them.
+func prune(i interface{}, version string) interface{} {
+	switch x := i.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, v := range x {
+			if k == sinceKey {
+				continue
+			}
+			if vm, ok := v.(map[string]interface{}); ok {
+				if s, ok := vm[sinceKey]; ok && older(version, fmt.Sprint(s)) {
+					continue
+				}
+			}
+			m[k] = prune(v, version)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, 0, len(x))
+		for _, v := range x {
+			if vm, ok := v.(map[string]interface{}); ok {
+				if s, ok := vm[sinceKey]; ok && older(version, fmt.Sprint(s)) {
+					continue
+				}
+			}
+			l = append(l, prune(v, version))
+		}
+		return l
+	}
+	return i
+}
+
+// Older reports whether version a comes before version b. Versions are
+// dot-separated integers.
+func older(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var an, bn int
+		if i < len(as) {
+			an, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bn, _ = strconv.Atoi(bs[i])
+		}
+		if an != bn {
+			return an < bn
+		}
+	}
+	return false
+}
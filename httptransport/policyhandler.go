@@ -0,0 +1,68 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/quay/claircore"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/policy"
+)
+
+// PolicyRequest is the body of a policy evaluation request.
+type PolicyRequest struct {
+	ManifestHash claircore.Digest `json:"manifest_hash"`
+}
+
+// PolicyEvaluateHandler creates a VulnerabilityReport for the requested
+// manifest and reports the decision of the policy Engine.
+func PolicyEvaluateHandler(p *policy.Engine, service matcher.Service, indexer indexer.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		w.Header().Set("content-type", "application/json")
+		if r.Method != http.MethodPost {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows POST"), "")
+			return
+		}
+
+		var req PolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to deserialize request"), "")
+			return
+		}
+		if req.ManifestHash.String() == "" {
+			apiError(w, clairerror.New(clairerror.Invalid, "request must provide a manifest hash"), "")
+			return
+		}
+
+		indexReport, ok, err := indexer.IndexReport(ctx, req.ManifestHash)
+		if err != nil {
+			apiError(w, err, "experienced a server side error")
+			return
+		}
+		if !ok {
+			apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("index report for manifest %q not found", req.ManifestHash.String())), "")
+			return
+		}
+
+		vulnReport, err := service.Scan(ctx, indexReport)
+		if err != nil {
+			apiError(w, err, "failed to start scan")
+			return
+		}
+
+		d, err := p.Evaluate(ctx, vulnReport)
+		if err != nil {
+			apiError(w, err, "")
+			return
+		}
+
+		defer writerError(w, &err)()
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(d)
+	}
+}
@@ -0,0 +1,60 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier/service"
+	"github.com/quay/clair/v4/tenant"
+)
+
+// PurgeResponse reports how many notification ids a purge removed.
+type PurgeResponse struct {
+	Purged int64 `json:"purged"`
+}
+
+// PurgeHandler removes the delivered notifications created for the update
+// operation named by the last path element.
+func PurgeHandler(serv service.Service) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx).With().
+			Str("component", "httptransport/PurgeHandler").
+			Logger()
+		if r.Method != http.MethodDelete {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows DELETE"), "")
+			return
+		}
+		uoid, err := uuid.Parse(filepath.Base(r.URL.Path))
+		if err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "could not parse update operation id"), "")
+			return
+		}
+
+		n, err := serv.PurgeDelivered(ctx, uoid)
+		if errors.Is(err, tenant.ErrForbidden) {
+			apiError(w, clairerror.New(clairerror.Forbidden, "tenants may not purge notifications"), "")
+			return
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("could not purge notifications")
+			apiError(w, err, "could not purge notifications")
+			return
+		}
+		log.Info().
+			Str("update_operation", uoid.String()).
+			Int64("purged", n).
+			Msg("purged delivered notifications")
+
+		w.Header().Set("content-type", "application/json")
+		if err := json.NewEncoder(w).Encode(&PurgeResponse{Purged: n}); err != nil {
+			log.Error().Err(err).Msg("failed to serialize response")
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package httptransport
+
+import (
+	"net/http"
+
+	"github.com/quay/clair/v4/middleware/rbac"
+)
+
+// RBACRules are the permissions needed for the API routes. Routes not listed,
+// including the internal API, need the admin permission.
+var rbacRules = []rbac.Rule{
+	{Path: IndexAPIPath, Methods: []string{http.MethodGet}, Permission: rbac.ReportsRead},
+	{Path: IndexAPIPath, Permission: rbac.IndexerWrite},
+	{Path: IndexReportAPIPath, Methods: []string{http.MethodGet}, Permission: rbac.ReportsRead},
+	{Path: IndexReportAPIPath, Methods: []string{http.MethodDelete, http.MethodPatch}, Permission: rbac.IndexerWrite},
+	{Path: IndexStateAPIPath, Permission: rbac.ReportsRead},
+	{Path: LayerAPIPath, Permission: rbac.IndexerWrite},
+	{Path: VulnerabilityReportPath, Permission: rbac.ReportsRead},
+	{Path: PolicyEvaluateAPIPath, Permission: rbac.ReportsRead},
+	{Path: AffectedManifestsPath, Permission: rbac.ReportsRead},
+	{Path: ReportKeysAPIPath, Permission: rbac.ReportsRead},
+	{Path: GraphQLAPIPath, Permission: rbac.ReportsRead},
+	{Path: VEXAPIPath, Methods: []string{http.MethodGet}, Permission: rbac.ReportsRead},
+	{Path: NotificationStreamPath, Methods: []string{http.MethodGet}, Permission: rbac.NotificationsRead},
+	{Path: NotificationAPIPath, Methods: []string{http.MethodGet}, Permission: rbac.NotificationsRead},
+	{Path: NotificationAPIPath, Methods: []string{http.MethodDelete}, Permission: rbac.NotificationsWrite},
+	{Path: DeliveriesAPIPath, Methods: []string{http.MethodGet}, Permission: rbac.NotificationsRead},
+	{Path: BacklogAPIPath, Methods: []string{http.MethodGet}, Permission: rbac.NotificationsRead},
+	{Path: KeysAPIPath, Permission: rbac.NotificationsRead},
+	{Path: KeyByIDAPIPath, Permission: rbac.NotificationsRead},
+}
+
+// configureWithRBAC will take the current handler and wrap it in an
+// authorization middleware handler.
+//
+// must be ran before configureWithAuth.
+func (t *Server) configureWithRBAC() error {
+	p, err := t.conf.Auth.RBAC.Policy()
+	if err != nil {
+		return err
+	}
+	p.Rules = rbacRules
+	p.Public = []string{openapiRoot}
+	if t.conf.Auth.Admin != nil {
+		// Only admins can authenticate to the admin API.
+		p.Public = append(p.Public,
+			indexerRoot+adminRoot,
+			matcherRoot+adminRoot,
+			notifierRoot+adminRoot,
+		)
+	}
+	p.Trusted = intraservice
+	t.Server.Handler = rbac.Handler(t.Server.Handler, p)
+	return nil
+}
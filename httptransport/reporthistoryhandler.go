@@ -0,0 +1,136 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/quay/claircore"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/history"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/tenant"
+)
+
+// ReportHistorySegment is the path segment following a manifest digest under
+// VulnerabilityReportPath that addresses its report history.
+const reportHistorySegment = "/history"
+
+// ReportHistory is implemented by matchers recording report history.
+type reportHistory interface {
+	History(context.Context, claircore.Digest) ([]history.Entry, error)
+	Report(context.Context, claircore.Digest, string) ([]byte, bool, error)
+}
+
+// HistoryMatcher finds a reportHistory among the wrapped matchers, if there
+// is one.
+func historyMatcher(s matcher.Service) (reportHistory, bool) {
+	var h reportHistory
+	ok := matcher.Walk(s, func(s interface{}) bool {
+		var ok bool
+		h, ok = s.(reportHistory)
+		return ok
+	})
+	return h, ok
+}
+
+// ReportHistoryResponse is the response listing a manifest's report
+// versions.
+type reportHistoryResponse struct {
+	Manifest claircore.Digest `json:"manifest_hash"`
+	History  []history.Entry  `json:"history"`
+}
+
+// IsReportHistory reports whether the request is for a report's history,
+// rather than the report.
+func isReportHistory(r *http.Request) bool {
+	rest := strings.TrimPrefix(r.URL.Path, VulnerabilityReportPath)
+	i := strings.IndexByte(rest, '/')
+	if i == -1 {
+		return false
+	}
+	return rest[i:] == reportHistorySegment || strings.HasPrefix(rest[i:], reportHistorySegment+"/")
+}
+
+// ReportHistoryHandler serves a manifest's report history.
+//
+// A GET of "{manifest}/history" lists the versions of the manifest's report,
+// newest first. A GET of "{manifest}/history/{digest}" returns the version
+// with the digest, as it was generated.
+//
+// A tenant only sees the history of manifests the indexer reports to it.
+func reportHistoryHandler(w http.ResponseWriter, r *http.Request, service matcher.Service, idx indexer.Reporter) {
+	if r.Method != http.MethodGet {
+		apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
+		return
+	}
+	h, ok := historyMatcher(service)
+	if !ok {
+		apiError(w, clairerror.New(clairerror.NotFound, "report history is not configured"), "")
+		return
+	}
+	ctx := r.Context()
+
+	rest := strings.TrimPrefix(r.URL.Path, VulnerabilityReportPath)
+	i := strings.IndexByte(rest, '/')
+	manifest, err := claircore.ParseDigest(rest[:i])
+	if err != nil {
+		apiError(w, clairerror.Wrap(clairerror.Invalid, err, "malformed path"), "")
+		return
+	}
+	if _, ok := tenant.FromContext(ctx); ok {
+		_, ok, err := idx.IndexReport(ctx, manifest)
+		switch {
+		case err != nil:
+			apiError(w, err, "could not retrieve index report")
+			return
+		case !ok:
+			apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("no report history for manifest %q", manifest)), "")
+			return
+		}
+	}
+	version := strings.TrimPrefix(rest[i:], reportHistorySegment+"/")
+	switch {
+	case rest[i:] == reportHistorySegment:
+	case version == "" || strings.Contains(version, "/"):
+		apiError(w, clairerror.New(clairerror.NotFound, "unknown path"), "")
+		return
+	default:
+		b, ok, err := h.Report(ctx, manifest, version)
+		switch {
+		case err != nil:
+			apiError(w, err, "failed to read report")
+			return
+		case !ok:
+			apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("no report %q for manifest %q", version, manifest)), "")
+			return
+		}
+		// A version never changes, so its digest is a fine validator.
+		w.Header().Set("etag", `"`+version+`"`)
+		if unmodified(r, `"`+version+`"`) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+		return
+	}
+
+	es, err := h.History(ctx, manifest)
+	if err != nil {
+		apiError(w, err, "failed to read report history")
+		return
+	}
+	defer writerError(w, &err)()
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(&reportHistoryResponse{
+		Manifest: manifest,
+		History:  es,
+	})
+}
@@ -0,0 +1,144 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/history"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/tenant"
+	"github.com/quay/clair/v4/vex"
+)
+
+// FakeHistory is a matcher with a recorded report history.
+type fakeHistory struct {
+	*matcher.Mock
+	entries []history.Entry
+	reports map[string][]byte
+}
+
+func (f *fakeHistory) History(context.Context, claircore.Digest) ([]history.Entry, error) {
+	return f.entries, nil
+}
+
+func (f *fakeHistory) Report(_ context.Context, _ claircore.Digest, d string) ([]byte, bool, error) {
+	b, ok := f.reports[d]
+	return b, ok, nil
+}
+
+func TestReportHistory(t *testing.T) {
+	m := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	body := []byte(`{"manifest_hash":"` + m.String() + `"}`)
+	d := history.Digest(body)
+	f := &fakeHistory{
+		Mock: &matcher.Mock{},
+		entries: []history.Entry{
+			{UpdateOperation: uuid.New(), Digest: d, Severities: map[string]int{}},
+		},
+		reports: map[string][]byte{d: body},
+	}
+	h := VulnerabilityReportHandler(f, &indexer.Mock{})
+	base := VulnerabilityReportPath + m.String() + "/history"
+
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{base, http.StatusOK},
+		{base + "/" + d, http.StatusOK},
+		{base + "/sha256:" + strings.Repeat("b", 64), http.StatusNotFound},
+		{base + "/", http.StatusNotFound},
+		{VulnerabilityReportPath + "bogus/history", http.StatusBadRequest},
+	} {
+		rr := httptest.NewRecorder()
+		h(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if got := rr.Code; got != tc.want {
+			t.Errorf("%s: got: %d, want: %d", tc.path, got, tc.want)
+			continue
+		}
+		if tc.want != http.StatusOK {
+			continue
+		}
+		if tc.path == base {
+			var got reportHistoryResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Manifest.String() != m.String() || len(got.History) != 1 || got.History[0].Digest != d {
+				t.Errorf("unexpected history: %+v", got)
+			}
+			continue
+		}
+		if got := rr.Body.String(); got != string(body) {
+			t.Errorf("got: %q, want: %q", got, body)
+		}
+		if got, want := rr.Header().Get("etag"), `"`+d+`"`; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+
+	// A tenant only sees the history of its own manifests.
+	owned := map[string]bool{}
+	idx := &indexer.Mock{
+		IndexReport_: func(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+			if !owned[d.String()] {
+				return nil, false, nil
+			}
+			return &claircore.IndexReport{Hash: d}, true, nil
+		},
+	}
+	th := VulnerabilityReportHandler(f, idx)
+	for _, tc := range []struct {
+		name  string
+		owned bool
+		want  int
+	}{
+		{"Unowned", false, http.StatusNotFound},
+		{"Owned", true, http.StatusOK},
+	} {
+		owned[m.String()] = tc.owned
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, base, nil)
+		req = req.WithContext(tenant.WithTenant(req.Context(), "tenant"))
+		th(rr, req)
+		if got := rr.Code; got != tc.want {
+			t.Errorf("%s: got: %d, want: %d", tc.name, got, tc.want)
+		}
+	}
+
+	// Without history, the endpoint doesn't exist.
+	rr := httptest.NewRecorder()
+	VulnerabilityReportHandler(&matcher.Mock{}, &indexer.Mock{})(rr, httptest.NewRequest(http.MethodGet, base, nil))
+	if got, want := rr.Code, http.StatusNotFound; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+}
+
+// FakeVEX is a matcher reporting VEX statements.
+type fakeVEX struct {
+	*matcher.Mock
+}
+
+func (fakeVEX) Annotations(context.Context, *claircore.VulnerabilityReport) []vex.Suppression {
+	return nil
+}
+
+func (fakeVEX) Fingerprint(context.Context) string { return "fingerprint" }
+
+func TestReportHistoryWrapsVEX(t *testing.T) {
+	var m matcher.Service = history.NewMatcher(fakeVEX{&matcher.Mock{}}, nil)
+	if _, ok := annotatingMatcher(m); !ok {
+		t.Error("VEX annotator not found through history matcher")
+	}
+	if _, ok := fingerprintingMatcher(m); !ok {
+		t.Error("fingerprinter not found through history matcher")
+	}
+}
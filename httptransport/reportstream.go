@@ -0,0 +1,247 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/vex"
+)
+
+// ReportStreamType is the media type a client asks for, via the Accept
+// header, to receive a vulnerability report as a stream of records instead
+// of a single JSON object.
+const ReportStreamType = "application/x-ndjson"
+
+// Record kinds in a vulnerability report stream.
+//
+// The "manifest" record is always first. The remaining kinds are written in
+// the order listed, so a client has seen every vulnerability before the
+// packages referencing them.
+const (
+	RecordManifest               = "manifest"
+	RecordDistribution           = "distribution"
+	RecordRepository             = "repository"
+	RecordVulnerability          = "vulnerability"
+	RecordPackage                = "package"
+	RecordEnvironments           = "environments"
+	RecordPackageVulnerabilities = "package_vulnerabilities"
+	RecordVEX                    = "vex"
+)
+
+// ReportRecord is one line of a vulnerability report stream.
+//
+// ID is the key the value has in the corresponding map of the
+// claircore.VulnerabilityReport. For "environments" and
+// "package_vulnerabilities" records, it's the package ID.
+type ReportRecord struct {
+	Kind  string          `json:"kind"`
+	ID    string          `json:"id,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// StreamFlushEvery is the number of records written between flushes.
+const streamFlushEvery = 256
+
+// WantsReportStream reports whether the request asked for a streamed
+// vulnerability report.
+func wantsReportStream(r *http.Request) bool {
+	return accepts(r, ReportStreamType)
+}
+
+// Accepts reports whether the request's Accept header lists the media type.
+func accepts(r *http.Request, mt string) bool {
+	for _, h := range r.Header.Values("Accept") {
+		for _, a := range strings.Split(h, ",") {
+			if i := strings.IndexByte(a, ';'); i != -1 {
+				a = a[:i]
+			}
+			if strings.TrimSpace(a) == mt {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteReportStream writes the report as newline delimited JSON records,
+// flushing periodically so the client can start processing before the whole
+// report is encoded.
+func writeReportStream(w io.Writer, vr *claircore.VulnerabilityReport, ss []vex.Suppression) error {
+	enc := json.NewEncoder(w)
+	f, _ := w.(http.Flusher)
+	n := 0
+	emit := func(kind, id string, v interface{}) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(&ReportRecord{Kind: kind, ID: id, Value: b}); err != nil {
+			return err
+		}
+		n++
+		if f != nil && n%streamFlushEvery == 0 {
+			f.Flush()
+		}
+		return nil
+	}
+
+	if err := emit(RecordManifest, "", struct {
+		Hash claircore.Digest `json:"manifest_hash"`
+	}{vr.Hash}); err != nil {
+		return err
+	}
+	// Keys are sorted, so streams are written in a stable order.
+	var ks []string
+	for k := range vr.Distributions {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	for _, id := range ks {
+		if err := emit(RecordDistribution, id, vr.Distributions[id]); err != nil {
+			return err
+		}
+	}
+	ks = ks[:0]
+	for k := range vr.Repositories {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	for _, id := range ks {
+		if err := emit(RecordRepository, id, vr.Repositories[id]); err != nil {
+			return err
+		}
+	}
+	ks = ks[:0]
+	for k := range vr.Vulnerabilities {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	for _, id := range ks {
+		if err := emit(RecordVulnerability, id, vr.Vulnerabilities[id]); err != nil {
+			return err
+		}
+	}
+	// Each package is followed by its environments and vulnerabilities, so a
+	// client can handle a package at a time.
+	ks = ks[:0]
+	for k := range vr.Packages {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	for _, id := range ks {
+		if err := emit(RecordPackage, id, vr.Packages[id]); err != nil {
+			return err
+		}
+		if es, ok := vr.Environments[id]; ok {
+			if err := emit(RecordEnvironments, id, es); err != nil {
+				return err
+			}
+		}
+		if vs, ok := vr.PackageVulnerabilities[id]; ok {
+			if err := emit(RecordPackageVulnerabilities, id, vs); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range ss {
+		if err := emit(RecordVEX, "", &ss[i]); err != nil {
+			return err
+		}
+	}
+	if f != nil {
+		f.Flush()
+	}
+	return nil
+}
+
+// DecodeReportStream reads a vulnerability report stream, calling fn for
+// every record. Decoding stops at the first error fn returns.
+func DecodeReportStream(r io.Reader, fn func(*ReportRecord) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var rec ReportRecord
+		switch err := dec.Decode(&rec); err {
+		case nil:
+		case io.EOF:
+			return nil
+		default:
+			return err
+		}
+		if err := fn(&rec); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadReportStream reassembles a streamed vulnerability report. VEX
+// suppressions, if any, are returned separately.
+func ReadReportStream(r io.Reader) (*claircore.VulnerabilityReport, []vex.Suppression, error) {
+	vr := &claircore.VulnerabilityReport{
+		Packages:               make(map[string]*claircore.Package),
+		Distributions:          make(map[string]*claircore.Distribution),
+		Repositories:           make(map[string]*claircore.Repository),
+		Environments:           make(map[string][]*claircore.Environment),
+		Vulnerabilities:        make(map[string]*claircore.Vulnerability),
+		PackageVulnerabilities: make(map[string][]string),
+	}
+	var ss []vex.Suppression
+	err := DecodeReportStream(r, func(rec *ReportRecord) error {
+		switch rec.Kind {
+		case RecordManifest:
+			return json.Unmarshal(rec.Value, vr)
+		case RecordDistribution:
+			var v claircore.Distribution
+			if err := json.Unmarshal(rec.Value, &v); err != nil {
+				return err
+			}
+			vr.Distributions[rec.ID] = &v
+		case RecordRepository:
+			var v claircore.Repository
+			if err := json.Unmarshal(rec.Value, &v); err != nil {
+				return err
+			}
+			vr.Repositories[rec.ID] = &v
+		case RecordVulnerability:
+			var v claircore.Vulnerability
+			if err := json.Unmarshal(rec.Value, &v); err != nil {
+				return err
+			}
+			vr.Vulnerabilities[rec.ID] = &v
+		case RecordPackage:
+			var v claircore.Package
+			if err := json.Unmarshal(rec.Value, &v); err != nil {
+				return err
+			}
+			vr.Packages[rec.ID] = &v
+		case RecordEnvironments:
+			var v []*claircore.Environment
+			if err := json.Unmarshal(rec.Value, &v); err != nil {
+				return err
+			}
+			vr.Environments[rec.ID] = v
+		case RecordPackageVulnerabilities:
+			var v []string
+			if err := json.Unmarshal(rec.Value, &v); err != nil {
+				return err
+			}
+			vr.PackageVulnerabilities[rec.ID] = v
+		case RecordVEX:
+			var v vex.Suppression
+			if err := json.Unmarshal(rec.Value, &v); err != nil {
+				return err
+			}
+			ss = append(ss, v)
+		}
+		// Unknown kinds are skipped, so that new ones can be added.
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return vr, ss, nil
+}
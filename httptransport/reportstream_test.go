@@ -0,0 +1,122 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestReportStream(t *testing.T) {
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	want := &claircore.VulnerabilityReport{
+		Hash: d,
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1"},
+			"2": {ID: "2", Name: "zlib", Version: "1.2.11"},
+		},
+		Distributions: map[string]*claircore.Distribution{
+			"1": {ID: "1", Name: "Debian", VersionID: "10"},
+		},
+		Repositories: map[string]*claircore.Repository{},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{PackageDB: "var/lib/dpkg/status", DistributionID: "1", IntroducedIn: d}},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"10": {ID: "10", Name: "CVE-2021-3449", Severity: "High", NormalizedSeverity: claircore.High},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"10"},
+		},
+	}
+	h := VulnerabilityReportHandler(
+		&matcher.Mock{
+			LatestUpdateOperation_: func(context.Context) (uuid.UUID, error) {
+				return uuid.Nil, nil
+			},
+			Scan_: func(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return want, nil
+			},
+		},
+		&indexer.Mock{
+			IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+				return &claircore.IndexReport{Hash: d}, true, nil
+			},
+		},
+	)
+	srv := httptest.NewServer(LoggingHandler(h))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+VulnerabilityReportPath+d.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/json;q=0.5, "+ReportStreamType)
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+	if got, want := res.Header.Get("content-type"), ReportStreamType; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	got, ss, err := ReadReportStream(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 0 {
+		t.Errorf("unexpected vex suppressions: %v", ss)
+	}
+	digestCmp := cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+	if !cmp.Equal(got, want, digestCmp) {
+		t.Error(cmp.Diff(got, want, digestCmp))
+	}
+}
+
+func TestReportStreamOrder(t *testing.T) {
+	vr := &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"b": {ID: "b"},
+			"a": {ID: "a"},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"v": {ID: "v"},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"a": {"v"},
+		},
+	}
+	var buf strings.Builder
+	if err := writeReportStream(&buf, vr, nil); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err := DecodeReportStream(strings.NewReader(buf.String()), func(r *ReportRecord) error {
+		got = append(got, r.Kind+":"+r.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"manifest:",
+		"vulnerability:v",
+		"package:a",
+		"package_vulnerabilities:a",
+		"package:b",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -0,0 +1,91 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/hook"
+)
+
+// ScopeParam is the query parameter selecting the packages a vulnerability
+// report covers by scope. The only supported value is "runtime", which
+// leaves out packages known to only be development dependencies.
+const scopeParam = "scope"
+
+// RuntimeOnly reports whether the request asked for a report without
+// development dependencies.
+func runtimeOnly(r *http.Request) (bool, error) {
+	switch v := r.URL.Query().Get(scopeParam); v {
+	case "":
+		return false, nil
+	case hook.ScopeRuntime:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown scope %q", v)
+	}
+}
+
+// ScopeLister finds a hook.ScopeLister among the wrapped indexers, if there
+// is one. Remote indexers implement it themselves.
+func scopeLister(s interface{}) (hook.ScopeLister, bool) {
+	var l hook.ScopeLister
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		l, ok = s.(hook.ScopeLister)
+		return ok
+	})
+	return l, ok
+}
+
+// PackageScopes returns the scope of the report's packages, keyed by package
+// id, or nil if the indexer can't report them.
+func packageScopes(ctx context.Context, serv interface{}, ir *claircore.IndexReport) (map[string]string, error) {
+	l, ok := scopeLister(serv)
+	if !ok {
+		return nil, nil
+	}
+	return l.Scopes(ctx, ir)
+}
+
+// WithoutDevelopment returns a copy of the report without the packages
+// scopes lists as development dependencies, or the report itself if there
+// aren't any.
+func withoutDevelopment(vr *claircore.VulnerabilityReport, scopes map[string]string) *claircore.VulnerabilityReport {
+	drop := false
+	for id := range vr.Packages {
+		if scopes[id] == hook.ScopeDevelopment {
+			drop = true
+			break
+		}
+	}
+	if !drop {
+		return vr
+	}
+	out := *vr
+	out.Packages = make(map[string]*claircore.Package, len(vr.Packages))
+	out.Environments = make(map[string][]*claircore.Environment, len(vr.Environments))
+	out.PackageVulnerabilities = make(map[string][]string, len(vr.PackageVulnerabilities))
+	out.Vulnerabilities = make(map[string]*claircore.Vulnerability, len(vr.Vulnerabilities))
+	for id, p := range vr.Packages {
+		if scopes[id] == hook.ScopeDevelopment {
+			continue
+		}
+		out.Packages[id] = p
+		if es, ok := vr.Environments[id]; ok {
+			out.Environments[id] = es
+		}
+		if vs, ok := vr.PackageVulnerabilities[id]; ok {
+			out.PackageVulnerabilities[id] = vs
+			for _, v := range vs {
+				if vuln, ok := vr.Vulnerabilities[v]; ok {
+					out.Vulnerabilities[v] = vuln
+				}
+			}
+		}
+	}
+	return &out
+}
@@ -4,17 +4,27 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 
+	je "github.com/quay/claircore/pkg/jsonerr"
 	"github.com/rs/zerolog"
 	othttp "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 
+	"github.com/quay/clair/v4/admin"
+	"github.com/quay/clair/v4/affected"
 	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/config"
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/mediatype"
 	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/middleware/audit"
 	intromw "github.com/quay/clair/v4/middleware/introspection"
+	"github.com/quay/clair/v4/middleware/requestid"
 	notifier "github.com/quay/clair/v4/notifier/service"
+	"github.com/quay/clair/v4/policy"
+	"github.com/quay/clair/v4/tenant"
 )
 
 const (
@@ -23,19 +33,57 @@ const (
 	matcherRoot             = "/matcher"
 	notifierRoot            = "/notifier"
 	internalRoot            = apiRoot + "internal/"
+	adminRoot               = apiRoot + "admin/"
 	IndexAPIPath            = indexerRoot + apiRoot + "index_report"
 	IndexReportAPIPath      = indexerRoot + apiRoot + "index_report/"
 	IndexStateAPIPath       = indexerRoot + apiRoot + "index_state"
+	ManifestAdminPath       = indexerRoot + adminRoot + "manifest/"
+	IndexerGCPath           = indexerRoot + adminRoot + "gc"
+	IndexerMigratePath      = indexerRoot + adminRoot + "migrate"
+	LayerAPIPath            = indexerRoot + apiRoot + "layers/"
 	AffectedManifestAPIPath = indexerRoot + internalRoot + "affected_manifest/"
+	IndexEventsAPIPath      = indexerRoot + internalRoot + "index_events"
 	VulnerabilityReportPath = matcherRoot + apiRoot + "vulnerability_report/"
+	BatchReportAPIPath      = VulnerabilityReportPath + "batch"
 	UpdateOperationAPIPath  = matcherRoot + internalRoot + "update_operation/"
 	UpdateDiffAPIPath       = matcherRoot + internalRoot + "update_diff/"
+	UpdatesExportAPIPath    = matcherRoot + internalRoot + "updates/export"
+	PolicyEvaluateAPIPath   = matcherRoot + apiRoot + "policy/evaluate"
+	VEXAPIPath              = matcherRoot + apiRoot + "vex"
+	UpdaterConfigAPIPath    = matcherRoot + apiRoot + "updaters/config"
+	AffectedManifestsPath   = matcherRoot + apiRoot + "affected_manifests"
+	ReportKeysAPIPath       = matcherRoot + apiRoot + "report_keys"
+	GraphQLAPIPath          = matcherRoot + apiRoot + "graphql"
+	UpdaterRunPath          = matcherRoot + adminRoot + "updaters/run"
+	MatcherGCPath           = matcherRoot + adminRoot + "gc"
+	MatcherDryRunPath       = matcherRoot + adminRoot + "dryrun"
+	MatcherMigratePath      = matcherRoot + adminRoot + "migrate"
 	NotificationAPIPath     = notifierRoot + apiRoot + "notification/"
+	NotificationStreamPath  = notifierRoot + apiRoot + "notification/stream"
+	NotificationPurgePath   = notifierRoot + adminRoot + "purge/"
+	DeadLetterPath          = notifierRoot + adminRoot + "deadletter/"
+	NotifierMigratePath     = notifierRoot + adminRoot + "migrate"
+	DeliveriesAPIPath       = notifierRoot + apiRoot + "deliveries"
+	BacklogAPIPath          = notifierRoot + apiRoot + "backlog"
 	KeysAPIPath             = notifierRoot + apiRoot + "services/notifier/keys"
 	KeyByIDAPIPath          = notifierRoot + apiRoot + "services/notifier/keys/"
-	OpenAPIV1Path           = "/openapi/v1"
+	OpenAPIV1Path           = openapiRoot + "1"
+	OpenAPIV11Path          = openapiRoot + "1.1"
 )
 
+// OpenAPIRoot prefixes the path of every version's OpenAPI document.
+const openapiRoot = "/openapi/v"
+
+type drainKey struct{}
+
+// Draining returns a channel that's closed once the Server serving the
+// request the Context belongs to starts shutting down. It returns nil outside
+// of a Server, which blocks forever.
+func draining(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(drainKey{}).(<-chan struct{})
+	return ch
+}
+
 // Server is the primary http server
 // Clair exposes it's functionality on.
 type Server struct {
@@ -49,6 +97,8 @@ type Server struct {
 	matcher  matcher.Service
 	notifier notifier.Service
 	traceOpt othttp.Option
+	// Audit is closed once the http.Server is shut down.
+	audit audit.Sink
 }
 
 func New(ctx context.Context, conf config.Config, indexer indexer.Service, matcher matcher.Service, notifier notifier.Service) (*Server, error) {
@@ -57,12 +107,18 @@ func New(ctx context.Context, conf config.Config, indexer indexer.Service, match
 		Logger()
 	ctx = log.WithContext(ctx)
 
+	// Drain is closed once Shutdown is called, so long-lived responses can
+	// end instead of holding the server open until the drain timeout.
+	drain := make(chan struct{})
+	var drainOnce sync.Once
+	reqCtx := context.WithValue(ctx, drainKey{}, (<-chan struct{})(drain))
 	serv := &http.Server{
 		Addr: conf.HTTPListenAddr,
 		// use the passed in global context as the base context
 		// for all http requests handled by this server
-		BaseContext: func(net.Listener) context.Context { return ctx },
+		BaseContext: func(net.Listener) context.Context { return reqCtx },
 	}
+	serv.RegisterOnShutdown(func() { drainOnce.Do(func() { close(drain) }) })
 	mux := http.NewServeMux()
 	t := &Server{
 		conf:     conf,
@@ -77,7 +133,7 @@ func New(ctx context.Context, conf config.Config, indexer indexer.Service, match
 	if err := t.configureDiscovery(ctx); err != nil {
 		log.Warn().Err(err).Msg("configuring openapi discovery failed")
 	} else {
-		log.Info().Str("path", OpenAPIV1Path).Msg("openapi discovery configured")
+		log.Info().Strs("versions", _openapiVersions).Msg("openapi discovery configured")
 	}
 
 	var e error
@@ -107,56 +163,114 @@ func New(ctx context.Context, conf config.Config, indexer indexer.Service, match
 	// attach HttpTransport to server, this works because we embed http.ServeMux
 	t.Server.Handler = t
 
+	// add request limits if configured. must happen first, so that the
+	// limits see the route the mux picks.
+	if conf.Limits.RouteLimits != (config.RouteLimits{}) || len(conf.Limits.Routes) != 0 {
+		t.configureWithLimits(ctx)
+		log.Info().Msg("request limits configured")
+	}
+
+	// honor client-supplied deadlines. must happen after limits, so that
+	// the limits see the route the mux picks.
+	t.configureWithDeadlines()
+
+	// negotiate the API version. must happen before anything that answers
+	// on a handler's behalf, so that those responses carry the version too.
+	t.Server.Handler = withAPIVersion(t.Server.Handler)
+
+	// add tenant scoping if configured. must happen before auth, so that
+	// only authenticated requests reach it.
+	if conf.Tenancy.Enabled() {
+		t.configureWithTenancy(ctx)
+		log.Info().Str("source", conf.Tenancy.Source).Msg("tenancy configured")
+	}
+
+	// add role based authorization if configured. must happen before auth,
+	// so that only authenticated requests reach it.
+	if conf.Auth.RBAC != nil {
+		if err := t.configureWithRBAC(); err != nil {
+			return nil, err
+		}
+		log.Info().Str("claim", conf.Auth.RBAC.Claim).Msg("rbac configured")
+	}
+
 	// add endpoint authentication if configured add auth. must happen after
 	// mux was configured for given mode.
-	if conf.Auth.Any() {
+	if conf.Auth.Any() || conf.Auth.Admin != nil {
 		err := t.configureWithAuth(ctx)
 		if err != nil {
 			log.Warn().Err(err).Msg("received error configuring auth middleware")
 		}
 	}
 
+	// add audit logging if configured. must happen after auth so that
+	// rejected requests are recorded.
+	if conf.Audit.Name != "" {
+		if err := t.configureWithAudit(ctx); err != nil {
+			return nil, err
+		}
+		log.Info().Str("sink", conf.Audit.Name).Msg("audit log configured")
+	}
+
+	// tag requests with an id. must happen last, so that everything logged
+	// on a request's behalf has it.
+	t.Server.Handler = requestid.Handler(t.Server.Handler)
+
 	return t, nil
 }
 
 // configureDiscovery() creates a discovery handler
-// for serving the v1 open api specification
+// for serving the open api specification of every API version
 func (t *Server) configureDiscovery(_ context.Context) error {
-	h := intromw.Handler(
-		othttp.NewHandler(
-			LoggingHandler(DiscoveryHandler()),
-			OpenAPIV1Path,
-			t.traceOpt,
-		),
-		OpenAPIV1Path,
-	)
-	t.Handle(OpenAPIV1Path, othttp.WithRouteTag(OpenAPIV1Path, h))
+	for _, v := range _openapiVersions {
+		p := OpenAPIPath(v)
+		h := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(DiscoveryHandler(v)),
+				p,
+				t.traceOpt,
+			),
+			p,
+		)
+		t.Handle(p, othttp.WithRouteTag(p, h))
+	}
 	return nil
 }
 
+// LayerPolicy returns the mediatype.Policy for submitted manifests.
+func (t *Server) layerPolicy() mediatype.Policy {
+	l := t.conf.Indexer.Layers
+	return mediatype.Policy{
+		SkipForeign:     l.Foreign == config.ForeignSkip,
+		SkipUnsupported: l.SkipUnsupported,
+	}
+}
+
 // configureDevMode configures the HttpTrasnport for
 // ComboMode.
 //
-// This mode runs both Indexer and Matcher in a single process.
+// This mode runs the Indexer, Matcher, and Notifier in a single process,
+// less any the configuration leaves out.
 func (t *Server) configureComboMode(ctx context.Context) error {
-	// requires both indexer and matcher services
-	if t.indexer == nil || t.matcher == nil {
-		return clairerror.ErrNotInitialized{"Combo mode requires both indexer and macher services"}
-	}
-
 	err := t.configureIndexerMode(ctx)
 	if err != nil {
-		return clairerror.ErrNotInitialized{"could not configure indexer: " + err.Error()}
+		return clairerror.Wrap(clairerror.Internal, err, "could not configure indexer")
 	}
 
+	if !t.conf.Combo.Matcher() {
+		return nil
+	}
 	err = t.configureMatcherMode(ctx)
 	if err != nil {
-		return clairerror.ErrNotInitialized{"could not configure matcher: " + err.Error()}
+		return clairerror.Wrap(clairerror.Internal, err, "could not configure matcher")
 	}
 
+	if !t.conf.Combo.Notifier() {
+		return nil
+	}
 	err = t.configureNotifierMode(ctx)
 	if err != nil {
-		return clairerror.ErrNotInitialized{"could not configure notifier: " + err.Error()}
+		return clairerror.Wrap(clairerror.Internal, err, "could not configure notifier")
 	}
 
 	return nil
@@ -168,7 +282,7 @@ func (t *Server) configureComboMode(ctx context.Context) error {
 func (t *Server) configureIndexerMode(_ context.Context) error {
 	// requires only indexer service
 	if t.indexer == nil {
-		return clairerror.ErrNotInitialized{"IndexerMode requires an indexer service"}
+		return clairerror.New(clairerror.Internal, "IndexerMode requires an indexer service")
 	}
 
 	// affected manifest handler register
@@ -185,7 +299,7 @@ func (t *Server) configureIndexerMode(_ context.Context) error {
 	// index handler register
 	indexH := intromw.Handler(
 		othttp.NewHandler(
-			LoggingHandler(IndexHandler(t.indexer)),
+			t.compress(LoggingHandler(IndexHandler(t.indexer, t.layerPolicy()))),
 			IndexAPIPath,
 			t.traceOpt,
 		),
@@ -196,7 +310,7 @@ func (t *Server) configureIndexerMode(_ context.Context) error {
 	// index report handler register
 	indexReportH := intromw.Handler(
 		othttp.NewHandler(
-			LoggingHandler(IndexReportHandler(t.indexer)),
+			t.compress(LoggingHandler(IndexReportHandler(t.indexer))),
 			IndexReportAPIPath,
 			t.traceOpt,
 		),
@@ -215,21 +329,47 @@ func (t *Server) configureIndexerMode(_ context.Context) error {
 	)
 	t.Handle(IndexStateAPIPath, othttp.WithRouteTag(IndexStateAPIPath, stateH))
 
+	// layer upload handler register, if uploads are configured
+	if u, ok := uploadIndexer(t.indexer); ok {
+		layerH := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(LayerHandler(u.Store())),
+				LayerAPIPath,
+				t.traceOpt,
+			),
+			LayerAPIPath,
+		)
+		t.Handle(LayerAPIPath, othttp.WithRouteTag(LayerAPIPath, layerH))
+	}
+
+	// index events handler register, if events are recorded
+	if e, ok := eventsIndexer(t.indexer); ok {
+		eventsH := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(IndexEventsHandler(e)),
+				IndexEventsAPIPath,
+				t.traceOpt,
+			),
+			IndexEventsAPIPath,
+		)
+		t.Handle(IndexEventsAPIPath, othttp.WithRouteTag(IndexEventsAPIPath, eventsH))
+	}
+
 	return nil
 }
 
 // configureMatcherMode configures HttpTransport
-func (t *Server) configureMatcherMode(_ context.Context) error {
+func (t *Server) configureMatcherMode(ctx context.Context) error {
 	// requires both an indexer and matcher service. indexer service
 	// is assumed to be a remote call over the network
 	if t.indexer == nil || t.matcher == nil {
-		return clairerror.ErrNotInitialized{"MatcherMode requires both indexer and matcher services"}
+		return clairerror.New(clairerror.Internal, "MatcherMode requires both indexer and matcher services")
 	}
 
 	// vulnerability report handler register
 	vulnReportH := intromw.Handler(
 		othttp.NewHandler(
-			LoggingHandler(VulnerabilityReportHandler(t.matcher, t.indexer)),
+			t.compress(LoggingHandler(VulnerabilityReportHandler(t.matcher, t.indexer))),
 			VulnerabilityReportPath,
 			t.traceOpt,
 		),
@@ -237,6 +377,17 @@ func (t *Server) configureMatcherMode(_ context.Context) error {
 	)
 	t.Handle(VulnerabilityReportPath, othttp.WithRouteTag(VulnerabilityReportPath, vulnReportH))
 
+	// batch vulnerability report handler register
+	batchH := intromw.Handler(
+		othttp.NewHandler(
+			t.compress(LoggingHandler(BatchReportHandler(t.matcher, t.indexer))),
+			BatchReportAPIPath,
+			t.traceOpt,
+		),
+		BatchReportAPIPath,
+	)
+	t.Handle(BatchReportAPIPath, othttp.WithRouteTag(BatchReportAPIPath, batchH))
+
 	// update operation handler register
 	opH := intromw.Handler(
 		othttp.NewHandler(
@@ -259,6 +410,102 @@ func (t *Server) configureMatcherMode(_ context.Context) error {
 	)
 	t.Handle(UpdateDiffAPIPath, othttp.WithRouteTag(UpdateDiffAPIPath, diffH))
 
+	// policy evaluation handler register, if any policies are configured
+	if ps := t.conf.Matcher.Policies; len(ps) != 0 {
+		p, err := policy.New(ctx, ps)
+		if err != nil {
+			return err
+		}
+		policyH := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(PolicyEvaluateHandler(p, t.matcher, t.indexer)),
+				PolicyEvaluateAPIPath,
+				t.traceOpt,
+			),
+			PolicyEvaluateAPIPath,
+		)
+		t.Handle(PolicyEvaluateAPIPath, othttp.WithRouteTag(PolicyEvaluateAPIPath, policyH))
+	}
+
+	// vex document handler register, if vex is configured
+	if m, ok := vexMatcher(t.matcher); ok {
+		vexH := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(VEXHandler(m)),
+				VEXAPIPath,
+				t.traceOpt,
+			),
+			VEXAPIPath,
+		)
+		t.Handle(VEXAPIPath, othttp.WithRouteTag(VEXAPIPath, vexH))
+	}
+
+	// updater override handler register, if overrides are configured
+	if m, ok := updaterMatcher(t.matcher); ok {
+		updaterH := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(UpdaterConfigHandler(m)),
+				UpdaterConfigAPIPath,
+				t.traceOpt,
+			),
+			UpdaterConfigAPIPath,
+		)
+		t.Handle(UpdaterConfigAPIPath, othttp.WithRouteTag(UpdaterConfigAPIPath, updaterH))
+	}
+
+	// affected manifests handler register, if lookups are available
+	if m, ok := affectedMatcher(t.matcher); ok {
+		affectedH := intromw.Handler(
+			othttp.NewHandler(
+				t.compress(LoggingHandler(AffectedManifestsHandler(m, t.indexer))),
+				AffectedManifestsPath,
+				t.traceOpt,
+			),
+			AffectedManifestsPath,
+		)
+		t.Handle(AffectedManifestsPath, othttp.WithRouteTag(AffectedManifestsPath, affectedH))
+
+		// updates export handler register, if the lookups can stream a
+		// namespace
+		if e, ok := m.Finder.(affected.Exporter); ok {
+			exportH := intromw.Handler(
+				othttp.NewHandler(
+					t.compress(LoggingHandler(UpdatesExportHandler(e))),
+					UpdatesExportAPIPath,
+					t.traceOpt,
+				),
+				UpdatesExportAPIPath,
+			)
+			t.Handle(UpdatesExportAPIPath, othttp.WithRouteTag(UpdatesExportAPIPath, exportH))
+		}
+	}
+
+	// report keys handler register, if reports are signed
+	if m, ok := signingMatcher(t.matcher); ok {
+		keysH := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(ReportKeysHandler(m)),
+				ReportKeysAPIPath,
+				t.traceOpt,
+			),
+			ReportKeysAPIPath,
+		)
+		t.Handle(ReportKeysAPIPath, othttp.WithRouteTag(ReportKeysAPIPath, keysH))
+	}
+
+	// graphql handler register, if enabled
+	if g := t.conf.Matcher.GraphQL; g != nil {
+		graphqlH := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(GraphQLHandler(t.matcher, t.indexer, g)),
+				GraphQLAPIPath,
+				t.traceOpt,
+			),
+			GraphQLAPIPath,
+		)
+		t.Handle(GraphQLAPIPath, othttp.WithRouteTag(GraphQLAPIPath, graphqlH))
+	}
+
 	return nil
 }
 
@@ -267,7 +514,7 @@ func (t *Server) configureNotifierMode(ctx context.Context) error {
 	// requires both an indexer and matcher service. indexer service
 	// is assumed to be a remote call over the network
 	if t.notifier == nil {
-		return clairerror.ErrNotInitialized{"NotifierMode requires a notifier service"}
+		return clairerror.New(clairerror.Internal, "NotifierMode requires a notifier service")
 	}
 
 	// notifications callback handler
@@ -281,9 +528,67 @@ func (t *Server) configureNotifierMode(ctx context.Context) error {
 	)
 	t.Handle(NotificationAPIPath, othttp.WithRouteTag(NotificationAPIPath, callbackH))
 
+	// notification stream handler
+	streamH := intromw.Handler(
+		othttp.NewHandler(
+			LoggingHandler(NotificationStreamHandler(t.notifier)),
+			NotificationStreamPath,
+			t.traceOpt,
+		),
+		NotificationStreamPath,
+	)
+	t.Handle(NotificationStreamPath, othttp.WithRouteTag(NotificationStreamPath, streamH))
+
+	// purge and dead letter handlers are part of the admin API.
+	if t.adminAuth() {
+		// purge handler
+		purgeH := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(PurgeHandler(t.notifier)),
+				NotificationPurgePath,
+				t.traceOpt,
+			),
+			NotificationPurgePath,
+		)
+		t.Handle(NotificationPurgePath, othttp.WithRouteTag(NotificationPurgePath, purgeH))
+
+		// dead letter handler
+		deadLetterH := intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(DeadLetterHandler(t.notifier)),
+				DeadLetterPath,
+				t.traceOpt,
+			),
+			DeadLetterPath,
+		)
+		t.Handle(DeadLetterPath, othttp.WithRouteTag(DeadLetterPath, deadLetterH))
+	}
+
+	// deliveries handler
+	deliveriesH := intromw.Handler(
+		othttp.NewHandler(
+			LoggingHandler(DeliveriesHandler(t.notifier)),
+			DeliveriesAPIPath,
+			t.traceOpt,
+		),
+		DeliveriesAPIPath,
+	)
+	t.Handle(DeliveriesAPIPath, othttp.WithRouteTag(DeliveriesAPIPath, deliveriesH))
+
+	// backlog handler
+	backlogH := intromw.Handler(
+		othttp.NewHandler(
+			LoggingHandler(BacklogHandler(t.notifier)),
+			BacklogAPIPath,
+			t.traceOpt,
+		),
+		BacklogAPIPath,
+	)
+	t.Handle(BacklogAPIPath, othttp.WithRouteTag(BacklogAPIPath, backlogH))
+
 	ks := t.notifier.KeyStore(ctx)
 	if ks == nil {
-		return clairerror.ErrNotInitialized{"NotifierMode requires the notifier to provide a non-nil key store"}
+		return clairerror.New(clairerror.Internal, "NotifierMode requires the notifier to provide a non-nil key store")
 	}
 
 	// keys handler
@@ -311,6 +616,51 @@ func (t *Server) configureNotifierMode(ctx context.Context) error {
 	return nil
 }
 
+// WithAdmin registers the admin API routes for the provided services. Routes
+// for nil members aren't registered.
+//
+// The admin API is only available with the admin permission, so nothing is
+// registered unless authentication is configured.
+func (t *Server) WithAdmin(s admin.Services) {
+	if !t.adminAuth() {
+		return
+	}
+	handle := func(path string, h http.Handler) {
+		h = intromw.Handler(
+			othttp.NewHandler(
+				LoggingHandler(h),
+				path,
+				t.traceOpt,
+			),
+			path,
+		)
+		t.Handle(path, othttp.WithRouteTag(path, h))
+	}
+	if a := s.Indexer; a != nil {
+		handle(ManifestAdminPath, ManifestDeleteHandler(a))
+		handle(IndexerGCPath, IndexerGCHandler(a))
+		handle(IndexerMigratePath, MigrateHandler(a.Migrator))
+	}
+	if a := s.Matcher; a != nil {
+		handle(UpdaterRunPath, UpdaterRunHandler(a))
+		handle(MatcherGCPath, MatcherGCHandler(a))
+		if t.indexer != nil && t.matcher != nil {
+			handle(MatcherDryRunPath, MatcherDryRunHandler(a, t.indexer, t.matcher))
+		}
+		handle(MatcherMigratePath, MigrateHandler(a.Migrator))
+	}
+	if m := s.Notifier; m != nil {
+		handle(NotifierMigratePath, MigrateHandler(m))
+	}
+}
+
+// adminAuth reports whether requests to the admin API are authenticated.
+//
+// Destructive routes are never served without it.
+func (t *Server) adminAuth() bool {
+	return t.conf.Auth.Any() || t.conf.Auth.Admin != nil
+}
+
 // IntraserviceIssuer is the issuer that will be used if Clair is configured to
 // mint its own JWTs.
 const IntraserviceIssuer = `clair-intraservice`
@@ -328,11 +678,69 @@ func (t *Server) configureWithAuth(_ context.Context) error {
 	return nil
 }
 
+// configureWithTenancy will take the current handler and wrap it in a
+// tenant scoping middleware handler.
+//
+// must be ran before configureWithAuth.
+func (t *Server) configureWithTenancy(_ context.Context) {
+	unscoped := []string{
+		openapiRoot,
+		indexerRoot + internalRoot,
+		matcherRoot + internalRoot,
+	}
+	if t.conf.Auth.Admin != nil {
+		// Admin credentials don't belong to a tenant.
+		unscoped = append(unscoped,
+			indexerRoot+adminRoot,
+			matcherRoot+adminRoot,
+			notifierRoot+adminRoot,
+		)
+	}
+	t.Server.Handler = tenant.Handler(t.Server.Handler,
+		tenant.Source(t.conf.Tenancy.Source),
+		intraservice,
+		unscoped...,
+	)
+}
+
+// configureWithAudit will take the current handler and wrap it in an audit
+// log middleware handler.
+//
+// must be ran after configureWithAuth.
+func (t *Server) configureWithAudit(ctx context.Context) error {
+	sink, err := auditSink(ctx, &t.conf.Audit)
+	if err != nil {
+		return err
+	}
+	t.audit = sink
+	t.Server.Handler = audit.Handler(t.Server.Handler, sink)
+	return nil
+}
+
+// Shutdown shuts down the http.Server, then closes the audit sink.
+//
+// The sink is closed only after, because requests still being served write to
+// it. Any that outlast ctx have their records dropped.
+func (t *Server) Shutdown(ctx context.Context) error {
+	err := t.Server.Shutdown(ctx)
+	if t.audit != nil {
+		if cerr := t.audit.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
 // Unmodified determines whether to return a conditional response.
+//
+// The "If-None-Match" header may hold a list of validators or "*", and uses
+// the weak comparison, so a "W/" prefix is ignored.
 func unmodified(r *http.Request, v string) bool {
-	if vs, ok := r.Header["If-None-Match"]; ok {
-		for _, rv := range vs {
-			if rv == v {
+	v = strings.TrimPrefix(v, "W/")
+	for _, h := range r.Header.Values("If-None-Match") {
+		for _, rv := range strings.Split(h, ",") {
+			rv = strings.TrimPrefix(strings.TrimSpace(rv), "W/")
+			if rv == v || rv == "*" {
 				return true
 			}
 		}
@@ -362,3 +770,21 @@ func writerError(w http.ResponseWriter, e *error) func() {
 		w.Header().Add(errHeader, (*e).Error())
 	}
 }
+
+// ApiError writes an error response for err, with the code and status of its
+// clairerror.Kind. A non-empty msg is prefixed to the error's message.
+func apiError(w http.ResponseWriter, err error, msg string) {
+	je.Error(w, errorResponse(err, msg), clairerror.KindOf(err).Status())
+}
+
+// ErrorResponse is the body apiError writes for err, for reporting errors
+// inside of successful responses.
+func errorResponse(err error, msg string) *je.Response {
+	if msg != "" {
+		msg += ": "
+	}
+	return &je.Response{
+		Code:    clairerror.KindOf(err).Code(),
+		Message: msg + err.Error(),
+	}
+}
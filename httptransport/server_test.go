@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/quay/clair/v4/admin"
+	"github.com/quay/clair/v4/config"
 	"github.com/quay/clair/v4/indexer"
 	"github.com/quay/clair/v4/matcher"
 	"github.com/quay/claircore"
@@ -69,3 +71,35 @@ func TestUpdateEndpoints(t *testing.T) {
 		t.Errorf("got: %v, want: %v", got, want)
 	}
 }
+
+// TestAdminRequiresAuth checks that the admin API is only registered when
+// requests to it are authenticated.
+func TestAdminRequiresAuth(t *testing.T) {
+	svc := admin.Services{
+		Indexer: admin.NewIndexer(nil, nil, admin.NewMigrator("")),
+	}
+	tt := []struct {
+		name string
+		auth config.Auth
+		want bool
+	}{
+		{name: "None"},
+		{name: "PSK", auth: config.Auth{PSK: &config.AuthPSK{}}, want: true},
+		{name: "Admin", auth: config.Auth{Admin: &config.AuthAdmin{}}, want: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &Server{
+				conf:     config.Config{Auth: tc.auth},
+				ServeMux: http.NewServeMux(),
+				traceOpt: othttp.WithTracerProvider(otel.GetTracerProvider()),
+			}
+			s.WithAdmin(svc)
+			req := httptest.NewRequest(http.MethodDelete, ManifestAdminPath, nil)
+			_, pattern := s.ServeMux.Handler(req)
+			if got, want := pattern != "", tc.want; got != want {
+				t.Errorf("registered: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
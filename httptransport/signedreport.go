@@ -0,0 +1,50 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/signing"
+)
+
+// SignedReportType is the media type a client asks for, via the Accept
+// header, to receive a vulnerability report as a signed JWS document.
+//
+// The JWS payload is a signing.Document holding the report as it would
+// otherwise be served.
+const SignedReportType = signing.MediaType
+
+// WantsSignedReport reports whether the request asked for a signed
+// vulnerability report.
+func wantsSignedReport(r *http.Request) bool {
+	return accepts(r, SignedReportType)
+}
+
+// ReportKeysHandler serves the JWK set signed vulnerability reports can be
+// verified with.
+func ReportKeysHandler(m *signing.Matcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
+			return
+		}
+		var err error
+		defer writerError(w, &err)()
+		w.Header().Set("content-type", "application/jwk-set+json")
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(m.Keys())
+	}
+}
+
+// SigningMatcher returns the signing.Matcher wrapped by "s", if any.
+func signingMatcher(s matcher.Service) (*signing.Matcher, bool) {
+	var m *signing.Matcher
+	ok := matcher.Walk(s, func(s interface{}) bool {
+		var ok bool
+		m, ok = s.(*signing.Matcher)
+		return ok
+	})
+	return m, ok
+}
@@ -0,0 +1,111 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/signing"
+)
+
+// TestSignedReport confirms a signed report verifies with the served key and
+// wraps the report, and that one is refused if signing isn't configured.
+func TestSignedReport(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := signing.NewSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("d", 64))
+	mock := &matcher.Mock{
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+	}
+	b, err := json.Marshal(&claircore.IndexReport{Hash: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, VulnerabilityReportPath, bytes.NewReader(b))
+		r.Header.Set("accept", SignedReportType)
+		return r
+	}
+
+	t.Run("Unconfigured", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		VulnerabilityReportHandler(mock, &indexer.Mock{})(rr, req())
+		if got, want := rr.Code, http.StatusNotAcceptable; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+
+	t.Run("Signed", func(t *testing.T) {
+		m := signing.NewMatcher(mock, s)
+		rr := httptest.NewRecorder()
+		VulnerabilityReportHandler(m, &indexer.Mock{})(rr, req())
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		if got, want := rr.Header().Get("content-type"), SignedReportType; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+
+		kr := httptest.NewRecorder()
+		ReportKeysHandler(m)(kr, httptest.NewRequest(http.MethodGet, ReportKeysAPIPath, nil))
+		var set jose.JSONWebKeySet
+		if err := json.NewDecoder(kr.Body).Decode(&set); err != nil {
+			t.Fatal(err)
+		}
+		ks := set.Key("test")
+		if len(ks) != 1 {
+			t.Fatalf("got: %d keys, want: 1", len(ks))
+		}
+
+		obj, err := jose.ParseSigned(rr.Body.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := obj.Signatures[0].Header.ExtraHeaders[jose.HeaderType], SignedReportType; got != want {
+			t.Errorf("got: %v, want: %q", got, want)
+		}
+		payload, err := obj.Verify(ks[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			Version string                        `json:"version"`
+			Report  claircore.VulnerabilityReport `json:"report"`
+		}
+		if err := json.Unmarshal(payload, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := doc.Version, signing.Version; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := doc.Report.Hash.String(), d.String(); got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	})
+}
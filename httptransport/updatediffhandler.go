@@ -2,12 +2,11 @@ package httptransport
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/matcher"
-	je "github.com/quay/claircore/pkg/jsonerr"
 )
 
 // UpdateDiffHandler provides an endpoint to GET update diffs
@@ -16,11 +15,7 @@ func UpdateDiffHandler(serv matcher.Differ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		if r.Method != http.MethodGet {
-			resp := &je.Response{
-				Code:    "method-not-allowed",
-				Message: "endpoint only allows GET",
-			}
-			je.Error(w, resp, http.StatusMethodNotAllowed)
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
 			return
 		}
 		// prev param is optional.
@@ -29,11 +24,7 @@ func UpdateDiffHandler(serv matcher.Differ) http.HandlerFunc {
 		if param := r.URL.Query().Get("prev"); param != "" {
 			prev, err = uuid.Parse(param)
 			if err != nil {
-				resp := &je.Response{
-					Code:    "bad-request",
-					Message: "could not parse \"prev\" query param into uuid",
-				}
-				je.Error(w, resp, http.StatusBadRequest)
+				apiError(w, clairerror.New(clairerror.Invalid, "could not parse \"prev\" query param into uuid"), "")
 				return
 			}
 		}
@@ -42,29 +33,17 @@ func UpdateDiffHandler(serv matcher.Differ) http.HandlerFunc {
 		var cur uuid.UUID
 		var param string
 		if param = r.URL.Query().Get("cur"); param == "" {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "\"cur\" query param is required",
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.New(clairerror.Invalid, "\"cur\" query param is required"), "")
 			return
 		}
 		if cur, err = uuid.Parse(param); err != nil {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "could not parse \"cur\" query param into uuid",
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.New(clairerror.Invalid, "could not parse \"cur\" query param into uuid"), "")
 			return
 		}
 
 		diff, err := serv.UpdateDiff(ctx, prev, cur)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "internal server error",
-				Message: fmt.Sprintf("could not get update operations: %v", err),
-			}
-			je.Error(w, resp, http.StatusInternalServerError)
+			apiError(w, err, "could not get update operations")
 			return
 		}
 
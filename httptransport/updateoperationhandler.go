@@ -2,15 +2,14 @@ package httptransport
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"path/filepath"
 	"strconv"
 
 	"github.com/google/uuid"
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/matcher"
 	"github.com/quay/claircore/libvuln/driver"
-	je "github.com/quay/claircore/pkg/jsonerr"
 	"github.com/rs/zerolog"
 )
 
@@ -39,11 +38,7 @@ func (h *UOHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case http.MethodDelete:
 		h.Delete(w, r)
 	default:
-		resp := &je.Response{
-			Code:    "method-not-allowed",
-			Message: "endpoint only allows POST",
-		}
-		je.Error(w, resp, http.StatusMethodNotAllowed)
+		apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows POST"), "")
 		return
 	}
 }
@@ -77,11 +72,7 @@ func (h *UOHandler) Get(w http.ResponseWriter, r *http.Request) {
 		uos, err = h.serv.UpdateOperations(ctx)
 	}
 	if err != nil {
-		resp := &je.Response{
-			Code:    "internal server error",
-			Message: fmt.Sprintf("could not get update operations: %v", err),
-		}
-		je.Error(w, resp, http.StatusInternalServerError)
+		apiError(w, err, "could not get update operations")
 		return
 	}
 
@@ -98,22 +89,14 @@ func (h *UOHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := filepath.Base(path)
 	uuid, err := uuid.Parse(id)
 	if err != nil {
-		resp := &je.Response{
-			Code:    "bad-request",
-			Message: fmt.Sprintf("could not deserialize manifest: %v", err),
-		}
 		log.Warn().Err(err).Msg("could not deserialize manifest")
-		je.Error(w, resp, http.StatusBadRequest)
+		apiError(w, clairerror.Wrap(clairerror.Invalid, err, "could not deserialize manifest"), "")
 		return
 	}
 
 	_, err = h.serv.DeleteUpdateOperations(ctx, uuid)
 	if err != nil {
-		resp := &je.Response{
-			Code:    "internal server error",
-			Message: fmt.Sprintf("could not get update operations: %v", err),
-		}
-		je.Error(w, resp, http.StatusInternalServerError)
+		apiError(w, err, "could not get update operations")
 		return
 	}
 	return
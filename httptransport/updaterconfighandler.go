@@ -0,0 +1,95 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/updaters"
+)
+
+// MaxUpdaterConfigSize is the largest request body accepted for updater
+// overrides.
+const maxUpdaterConfigSize = 1 << 20
+
+// UpdaterConfigHandler reports the updater overrides on GET, sets the
+// overrides in the request body on PUT, and removes the override named by the
+// "name" query parameter on DELETE.
+//
+// A PUT body is a JSON object of overrides keyed by updater set or updater
+// name, e.g.:
+//
+//	{"ubuntu":{"disabled":true},"rhel":{"config":{"url":"https://example.com/manifest"}}}
+//
+// Overrides not named in a PUT are left alone.
+func UpdaterConfigHandler(m *updaters.Matcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		w.Header().Set("content-type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var in map[string]updaters.Override
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdaterConfigSize)).Decode(&in); err != nil {
+				apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to deserialize request"), "")
+				return
+			}
+			// Check everything first, so a bad entry doesn't leave the
+			// request half-applied.
+			for name, o := range in {
+				if err := o.Validate(name); err != nil {
+					updaterConfigError(w, err)
+					return
+				}
+			}
+			for name, o := range in {
+				if err := m.Set(ctx, name, o); err != nil {
+					updaterConfigError(w, err)
+					return
+				}
+			}
+		case http.MethodDelete:
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				apiError(w, clairerror.New(clairerror.Invalid, "request must provide an updater name"), "")
+				return
+			}
+			ok, err := m.Delete(ctx, name)
+			switch {
+			case err != nil:
+				updaterConfigError(w, err)
+			case !ok:
+				apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("no override for %q", name)), "")
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		default:
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET, PUT, or DELETE"), "")
+			return
+		}
+		var err error
+		defer writerError(w, &err)()
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(m.List(ctx))
+	}
+}
+
+// UpdaterConfigError reports err: updaters.ErrInvalid is Invalid.
+func updaterConfigError(w http.ResponseWriter, err error) {
+	apiError(w, err, "")
+}
+
+// UpdaterMatcher finds the updaters.Matcher among the wrapped matchers, if
+// there is one.
+func updaterMatcher(s matcher.Service) (*updaters.Matcher, bool) {
+	var m *updaters.Matcher
+	ok := matcher.Walk(s, func(s interface{}) bool {
+		var ok bool
+		m, ok = s.(*updaters.Matcher)
+		return ok
+	})
+	return m, ok
+}
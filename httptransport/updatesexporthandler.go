@@ -0,0 +1,55 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/affected"
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// UpdatesExportHandler streams the current vulnerabilities in the namespace
+// named by the "namespace" query parameter: an updater name, a distribution
+// ID, or a distribution ID and version ID, as in "rhel:9".
+//
+// The response is newline delimited JSON, one claircore.Vulnerability per
+// line in order of ID. An error after the response has started is reported
+// in the "Clair-Error" trailer.
+func UpdatesExportHandler(e affected.Exporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Method != http.MethodGet {
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET"), "")
+			return
+		}
+		ns := r.URL.Query().Get("namespace")
+		if ns == "" {
+			apiError(w, clairerror.New(clairerror.Invalid, "request must provide a \"namespace\" query param"), "")
+			return
+		}
+
+		var err error
+		defer writerError(w, &err)()
+		w.Header().Set("content-type", ReportStreamType)
+		enc := json.NewEncoder(w)
+		f, _ := w.(http.Flusher)
+		n := 0
+		err = e.Export(ctx, ns, func(v *claircore.Vulnerability) error {
+			if err := enc.Encode(v); err != nil {
+				return err
+			}
+			n++
+			if f != nil && n%streamFlushEvery == 0 {
+				f.Flush()
+			}
+			return nil
+		})
+		// If nothing's been written yet, the error can be reported normally.
+		if err != nil && n == 0 {
+			apiError(w, err, "failed to export vulnerabilities")
+			err = nil
+		}
+	}
+}
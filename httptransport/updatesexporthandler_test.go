@@ -0,0 +1,87 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+type exporterFunc func(ctx context.Context, namespace string, fn func(*claircore.Vulnerability) error) error
+
+func (f exporterFunc) Export(ctx context.Context, namespace string, fn func(*claircore.Vulnerability) error) error {
+	return f(ctx, namespace, fn)
+}
+
+func TestUpdatesExportHandler(t *testing.T) {
+	e := exporterFunc(func(_ context.Context, ns string, fn func(*claircore.Vulnerability) error) error {
+		switch ns {
+		case "rhel:9":
+		case "broken":
+			return errors.New("database's gone")
+		default:
+			return nil
+		}
+		for _, id := range []string{"1", "2", "3"} {
+			if err := fn(&claircore.Vulnerability{ID: id, Name: "CVE-" + id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	srv := httptest.NewServer(UpdatesExportHandler(e))
+	defer srv.Close()
+	c := srv.Client()
+
+	t.Run("Export", func(t *testing.T) {
+		res, err := c.Get(srv.URL + "?namespace=rhel:9")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		if got, want := res.Header.Get("content-type"), ReportStreamType; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		dec := json.NewDecoder(res.Body)
+		var ids []string
+		for dec.More() {
+			var v claircore.Vulnerability
+			if err := dec.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, v.ID)
+		}
+		if got, want := len(ids), 3; got != want {
+			t.Errorf("got: %v, want: %d vulnerabilities", ids, want)
+		}
+	})
+
+	t.Run("NoNamespace", func(t *testing.T) {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		res, err := c.Get(srv.URL + "?namespace=broken")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/quay/claircore"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer/baseimage"
+	"github.com/quay/clair/v4/indexer/eol"
+	"github.com/quay/clair/v4/indexer/layers"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/vex"
+)
+
+// VEXDocument summarizes a VEX document in use by the matcher.
+type VEXDocument struct {
+	ID         string     `json:"id"`
+	Format     vex.Format `json:"format"`
+	Statements int        `json:"statements"`
+}
+
+func vexDocument(d *vex.Document) VEXDocument {
+	return VEXDocument{
+		ID:         d.ID,
+		Format:     d.Format,
+		Statements: len(d.Statements),
+	}
+}
+
+// MaxVEXDocumentSize is the largest VEX document accepted for upload.
+const maxVEXDocumentSize = 32 << 20
+
+// VEXHandler lists VEX documents on GET, uploads one on POST, and removes the
+// one named by the "id" query parameter on DELETE.
+func VEXHandler(m *vex.Matcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		w.Header().Set("content-type", "application/json")
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			ds := m.Documents(ctx)
+			out := make([]VEXDocument, len(ds))
+			for i, d := range ds {
+				out[i] = vexDocument(d)
+			}
+			defer writerError(w, &err)()
+			w.WriteHeader(http.StatusOK)
+			err = json.NewEncoder(w).Encode(out)
+		case http.MethodPost:
+			b, rerr := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxVEXDocumentSize))
+			if rerr != nil {
+				apiError(w, clairerror.Wrap(clairerror.Invalid, rerr, "failed to read request"), "")
+				return
+			}
+			d, perr := m.Put(ctx, b)
+			if perr != nil {
+				vexError(w, perr)
+				return
+			}
+			defer writerError(w, &err)()
+			w.WriteHeader(http.StatusCreated)
+			err = json.NewEncoder(w).Encode(vexDocument(d))
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				apiError(w, clairerror.New(clairerror.Invalid, "request must provide a document id"), "")
+				return
+			}
+			ok, derr := m.Delete(ctx, id)
+			switch {
+			case derr != nil:
+				vexError(w, derr)
+			case !ok:
+				apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("vex document %q not found", id)), "")
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+		default:
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET, POST, or DELETE"), "")
+		}
+	}
+}
+
+// VexError reports err: vex.ParseError is Invalid, and vex.ErrNoStore is
+// Unimplemented.
+func vexError(w http.ResponseWriter, err error) {
+	apiError(w, err, "")
+}
+
+// VEXAnnotator is implemented by matchers that report VEX statements
+// alongside vulnerability reports.
+type vexAnnotator interface {
+	Annotations(context.Context, *claircore.VulnerabilityReport) []vex.Suppression
+}
+
+// VEXMatcher finds the vex.Matcher among the wrapped matchers, if there is
+// one.
+func vexMatcher(s matcher.Service) (*vex.Matcher, bool) {
+	var m *vex.Matcher
+	ok := matcher.Walk(s, func(s interface{}) bool {
+		var ok bool
+		m, ok = s.(*vex.Matcher)
+		return ok
+	})
+	return m, ok
+}
+
+// AnnotatingMatcher finds a vexAnnotator among the wrapped matchers, if there
+// is one.
+func annotatingMatcher(s matcher.Service) (vexAnnotator, bool) {
+	var a vexAnnotator
+	ok := matcher.Walk(s, func(s interface{}) bool {
+		var ok bool
+		a, ok = s.(vexAnnotator)
+		return ok
+	})
+	return a, ok
+}
+
+// AnnotatedReport is a VulnerabilityReport with the VEX statements applying
+// to it, the scope of its packages, its base image, the manifest's
+// annotations and end of life distributions if known, and, when asked for,
+// the layers its findings came from.
+type annotatedReport struct {
+	*claircore.VulnerabilityReport
+	VEX       []vex.Suppression `json:"vex,omitempty"`
+	Layers    []layers.Layer    `json:"layers,omitempty"`
+	Scopes    map[string]string `json:"package_scopes,omitempty"`
+	BaseImage *baseimage.Base   `json:"base_image,omitempty"`
+	Origins   map[string]string `json:"vulnerability_origins,omitempty"`
+	PURLs     map[string]string `json:"package_purls,omitempty"`
+	// Annotations are the key/value pairs clients attached to the manifest.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// EndOfLife lists the manifest's distributions that no longer get
+	// security updates, so the report may be missing vulnerabilities.
+	EndOfLife []eol.Notice `json:"end_of_life,omitempty"`
+}
@@ -9,23 +9,48 @@ import (
 	"path"
 
 	"github.com/quay/claircore"
-	je "github.com/quay/claircore/pkg/jsonerr"
 	oteltrace "go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
 
+	"github.com/quay/clair/v4/attestation"
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/baseimage"
+	"github.com/quay/clair/v4/indexer/eol"
+	"github.com/quay/clair/v4/indexer/layers"
 	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/purl"
+	"github.com/quay/clair/v4/vex"
 )
 
 // VulnerabilityReportHandler utilizes a Service to serialize
 // and return a claircore.VulnerabilityReport
+//
+// A POST matches the IndexReport in the request body instead of one
+// retrieved from the indexer. Paths below a manifest's "history" serve its
+// report history, if recorded.
 func VulnerabilityReportHandler(service matcher.Service, indexer indexer.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			resp := &je.Response{
-				Code:    "method-not-allowed",
-				Message: "endpoint only allows GET",
+		if isReportHistory(r) {
+			reportHistoryHandler(w, r, service, indexer)
+			return
+		}
+		if wantsSignedReport(r) || wantsAttestationEnvelope(r) {
+			if _, ok := signingMatcher(service); !ok {
+				apiError(w, clairerror.New(clairerror.NotAcceptable, "report signing is not configured"), "")
+				return
 			}
-			je.Error(w, resp, http.StatusMethodNotAllowed)
+		}
+		if _, err := runtimeOnly(r); err != nil {
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, ""), "")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			scanIndexReport(w, r, service)
+			return
+		default:
+			apiError(w, clairerror.New(clairerror.MethodNotAllowed, "endpoint only allows GET or POST"), "")
 			return
 		}
 		ctx, done := context.WithCancel(r.Context())
@@ -34,56 +59,227 @@ func VulnerabilityReportHandler(service matcher.Service, indexer indexer.Service
 
 		manifestStr := path.Base(r.URL.Path)
 		if manifestStr == "" {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "malformed path. provide a single manifest hash",
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.New(clairerror.Invalid, "malformed path. provide a single manifest hash"), "")
 			return
 		}
 		manifest, err := claircore.ParseDigest(manifestStr)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "bad-request",
-				Message: "malformed path: " + err.Error(),
-			}
-			je.Error(w, resp, http.StatusBadRequest)
+			apiError(w, clairerror.Wrap(clairerror.Invalid, err, "malformed path"), "")
 			return
 		}
 
 		indexReport, ok, err := indexer.IndexReport(ctx, manifest)
 		// check err first
 		if err != nil {
-			resp := &je.Response{
-				Code:    "internal-server-error",
-				Message: fmt.Sprintf("experienced a server side error: %v", err),
-			}
-			je.Error(w, resp, http.StatusInternalServerError)
+			apiError(w, err, "could not retrieve index report")
 			return
 		}
 		// now check bool only after comfirning no errr
 		if !ok {
-			resp := &je.Response{
-				Code:    "not-found",
-				Message: fmt.Sprintf("index report for manifest %q not found", manifest.String()),
-			}
-			je.Error(w, resp, http.StatusNotFound)
+			apiError(w, clairerror.New(clairerror.NotFound, fmt.Sprintf("index report for manifest %q not found", manifest.String())), "")
 			return
 
 		}
 
+		// The base image is informational, so a failure to detect it
+		// doesn't fail the request.
+		base, _ := baseImage(ctx, indexer, manifest)
+		// So are the annotations.
+		as, _ := manifestAnnotations(ctx, indexer, manifest)
+		eols := endOfLife(indexer, indexReport.Distributions)
+
+		// The validator doesn't need a scan, so skip it if the client
+		// already has the report. Signed reports and attestations carry the
+		// time they were issued, so they never have a validator.
+		if !wantsSignedReport(r) && !wantsAttestation(r) && !wantsAttestationEnvelope(r) {
+			if v := vulnerabilityReportValidator(ctx, service, indexReport, base, as, eols, reportRepresentation(r)); v != "" {
+				w.Header().Set("etag", v)
+				w.Header().Add("vary", "accept")
+				if unmodified(r, v) {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+			}
+		}
+
+		var order []claircore.Digest
+		if wantsVulnerabilityReportV2(r) {
+			order, err = layerOrder(ctx, indexer, manifest)
+			if err != nil {
+				apiError(w, err, "failed to list layers")
+				return
+			}
+		}
+
+		// The scopes are only needed to filter the report if the client
+		// asked for that, otherwise they're informational.
+		only, _ := runtimeOnly(r)
+		scopes, err := packageScopes(ctx, indexer, indexReport)
+		if err != nil && only {
+			apiError(w, err, "failed to determine package scopes")
+			return
+		}
+
 		vulnReport, err := service.Scan(ctx, indexReport)
 		if err != nil {
-			resp := &je.Response{
-				Code:    "match-error",
-				Message: fmt.Sprintf("failed to start scan: %v", err),
+			apiError(w, err, "failed to start scan")
+			return
+		}
+		writeVulnerabilityReport(ctx, w, r, service, vulnReport, order, scopes, base, as, eols)
+	}
+}
+
+// AnnotateReport returns the value encoded for a vulnerability report: the
+// report itself, or the annotatedReport if there's anything to add to it.
+// The annotatedReport is returned either way, so callers can add more.
+func annotateReport(ctx context.Context, service matcher.Service, vulnReport *claircore.VulnerabilityReport, scopes map[string]string, base *baseimage.Base, as map[string]string, eols []eol.Notice) (interface{}, *annotatedReport) {
+	legacy := legacyAPI(ctx)
+	if legacy {
+		// The scopes, base image, annotations, end of life distributions,
+		// and package URLs came after the original version of the API.
+		scopes, base, as, eols = nil, nil, nil, nil
+	}
+	var ss []vex.Suppression
+	if a, ok := annotatingMatcher(service); ok {
+		ss = a.Annotations(ctx, vulnReport)
+	}
+
+	var out interface{} = vulnReport
+	ar := &annotatedReport{VulnerabilityReport: vulnReport, VEX: ss}
+	if len(ss) != 0 {
+		out = ar
+	}
+	if len(scopes) != 0 {
+		ar.Scopes = make(map[string]string, len(scopes))
+		for id, s := range scopes {
+			if _, ok := vulnReport.Packages[id]; ok {
+				ar.Scopes[id] = s
 			}
-			je.Error(w, resp, http.StatusInternalServerError)
+		}
+		out = ar
+	}
+	if base != nil {
+		ar.BaseImage = base
+		ar.Origins = baseimage.Origins(vulnReport, base)
+		out = ar
+	}
+	if len(as) != 0 {
+		ar.Annotations = as
+		out = ar
+	}
+	if len(eols) != 0 {
+		ar.EndOfLife = eols
+		out = ar
+	}
+	if !legacy {
+		ar.PURLs = purl.Report(vulnReport.Packages, vulnReport.Environments, vulnReport.Distributions, vulnReport.Repositories)
+		if len(ar.PURLs) != 0 {
+			out = ar
+		}
+	}
+	return out, ar
+}
+
+// ScanIndexReport matches the IndexReport in the request body, for index
+// reports the indexer didn't produce, e.g. ones converted from an SBOM.
+//
+// The order of the report's layers isn't known, so any layer attribution
+// lists only the layers that introduced packages. Neither are the scopes of
+// its packages, so limiting the report to a scope has no effect.
+func scanIndexReport(w http.ResponseWriter, r *http.Request, service matcher.Service) {
+	ctx := r.Context()
+	var ir claircore.IndexReport
+	if err := json.NewDecoder(r.Body).Decode(&ir); err != nil {
+		apiError(w, clairerror.Wrap(clairerror.Invalid, err, "failed to deserialize index report"), "")
+		return
+	}
+	// The vulnerability store expects every package to have a source, which
+	// reports from the indexer always do.
+	for _, p := range ir.Packages {
+		if p == nil {
+			apiError(w, clairerror.New(clairerror.Invalid, "index report has a null package"), "")
+			return
+		}
+		if p.Source == nil {
+			p.Source = &claircore.Package{}
+		}
+	}
+	vulnReport, err := service.Scan(ctx, &ir)
+	if err != nil {
+		apiError(w, err, "failed to start scan")
+		return
+	}
+	writeVulnerabilityReport(ctx, w, r, service, vulnReport, nil, nil, nil, nil, nil)
+}
+
+// WriteVulnerabilityReport writes the report, with any VEX annotations, in
+// the format the request asked for: JSON, a record stream, a signed
+// document, or an attestation. If the request asked for layer attribution,
+// order is used as the manifest's layers. Scopes are the packages' scopes,
+// keyed by package id, and development dependencies are left out if the
+// request asked for only runtime ones. If base is the manifest's base image,
+// each vulnerability is tagged with whether it was inherited from it. As are
+// the manifest's annotations and eols, its end of life distributions,
+// included as they are. Clients of the original API version get none of the
+// scopes, the base image, the annotations, or the end of life distributions.
+func writeVulnerabilityReport(ctx context.Context, w http.ResponseWriter, r *http.Request, service matcher.Service, vulnReport *claircore.VulnerabilityReport, order []claircore.Digest, scopes map[string]string, base *baseimage.Base, as map[string]string, eols []eol.Notice) {
+	if only, _ := runtimeOnly(r); only {
+		vulnReport = withoutDevelopment(vulnReport, scopes)
+	}
+	out, ar := annotateReport(ctx, service, vulnReport, scopes, base, as, eols)
+	ss := ar.VEX
+	v2 := wantsVulnerabilityReportV2(r)
+	if v2 {
+		ar.Layers = layers.Attribute(vulnReport.Environments, order)
+		layers.AttributeVulnerabilities(ar.Layers, vulnReport)
+		out = ar
+	}
+	if wantsAttestationEnvelope(r) {
+		s, _ := signingMatcher(service)
+		env, err := attestation.Seal(reportStatement(ctx, r, service, vulnReport.Hash, out), s)
+		var b []byte
+		if err == nil {
+			b, err = json.Marshal(env)
+		}
+		if err != nil {
+			apiError(w, err, "failed to sign attestation")
 			return
 		}
+		w.Header().Set("content-type", AttestationEnvelopeType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+		return
+	}
+	if wantsSignedReport(r) {
+		s, _ := signingMatcher(service)
+		b, err := s.Sign(out)
+		if err != nil {
+			apiError(w, err, "failed to sign report")
+			return
+		}
+		w.Header().Set("content-type", SignedReportType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+		return
+	}
 
-		defer writerError(w, &err)()
+	var err error
+	defer writerError(w, &err)()
+	if wantsAttestation(r) {
+		w.Header().Set("content-type", AttestationType)
 		w.WriteHeader(http.StatusOK)
-		err = json.NewEncoder(w).Encode(vulnReport)
+		err = json.NewEncoder(w).Encode(reportStatement(ctx, r, service, vulnReport.Hash, out))
+		return
+	}
+	if wantsReportStream(r) {
+		w.Header().Set("content-type", ReportStreamType)
+		w.WriteHeader(http.StatusOK)
+		err = writeReportStream(w, vulnReport, ss)
+		return
+	}
+	if v2 {
+		w.Header().Set("content-type", VulnerabilityReportV2Type)
 	}
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(out)
 }
@@ -0,0 +1,210 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// TestScanIndexReport confirms a posted index report is matched as-is, with
+// missing package sources filled in.
+func TestScanIndexReport(t *testing.T) {
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("c", 64))
+	h := VulnerabilityReportHandler(
+		&matcher.Mock{
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				for id, p := range ir.Packages {
+					if p.Source == nil {
+						t.Errorf("package %s: missing source", id)
+					}
+				}
+				return &claircore.VulnerabilityReport{Hash: ir.Hash, Packages: ir.Packages}, nil
+			},
+		},
+		&indexer.Mock{},
+	)
+
+	ir := claircore.IndexReport{
+		Hash: d,
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "busybox", Version: "1.31.1-r19"},
+		},
+	}
+	b, err := json.Marshal(&ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodPost, VulnerabilityReportPath, bytes.NewReader(b)))
+	if got, want := rr.Code, http.StatusOK; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+	var got claircore.VulnerabilityReport
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Hash.String() != d.String() || len(got.Packages) != 1 {
+		t.Errorf("unexpected report: %+v", got)
+	}
+
+	rr = httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodPost, VulnerabilityReportPath, strings.NewReader("{")))
+	if got, want := rr.Code, http.StatusBadRequest; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+}
+
+// TestVulnerabilityReportV2 confirms layer attribution is only added when
+// asked for.
+func TestVulnerabilityReportV2(t *testing.T) {
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("c", 64))
+	l := claircore.MustParseDigest("sha256:" + strings.Repeat("d", 64))
+	h := VulnerabilityReportHandler(
+		&matcher.Mock{
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return &claircore.VulnerabilityReport{
+					Hash:                   ir.Hash,
+					Packages:               ir.Packages,
+					Environments:           ir.Environments,
+					PackageVulnerabilities: map[string][]string{"1": {"v1"}},
+				}, nil
+			},
+		},
+		&indexer.Mock{},
+	)
+	b, err := json.Marshal(&claircore.IndexReport{
+		Hash: d,
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "busybox", Version: "1.31.1-r19"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{IntroducedIn: l}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v2 := range []bool{false, true} {
+		req := httptest.NewRequest(http.MethodPost, VulnerabilityReportPath, bytes.NewReader(b))
+		if v2 {
+			req.Header.Set("accept", VulnerabilityReportV2Type)
+		}
+		rr := httptest.NewRecorder()
+		h(rr, req)
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		var got struct {
+			Layers []struct {
+				Hash            string   `json:"hash"`
+				Index           int      `json:"index"`
+				Vulnerabilities []string `json:"vulnerabilities"`
+			} `json:"layers"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !v2 {
+			if len(got.Layers) != 0 {
+				t.Errorf("unexpected layers: %+v", got.Layers)
+			}
+			continue
+		}
+		if got, want := rr.Header().Get("content-type"), VulnerabilityReportV2Type; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if len(got.Layers) != 1 || got.Layers[0].Hash != l.String() || got.Layers[0].Index != -1 ||
+			len(got.Layers[0].Vulnerabilities) != 1 {
+			t.Errorf("unexpected layers: %+v", got.Layers)
+		}
+	}
+}
+
+// ScopedIndexer is an indexer reporting the scope of packages.
+type scopedIndexer struct {
+	*indexer.Mock
+	scopes map[string]string
+}
+
+func (s *scopedIndexer) Scopes(context.Context, *claircore.IndexReport) (map[string]string, error) {
+	return s.scopes, nil
+}
+
+func TestVulnerabilityReportScope(t *testing.T) {
+	d := claircore.MustParseDigest("sha256:" + strings.Repeat("e", 64))
+	ir := &claircore.IndexReport{
+		Hash: d,
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "flask", PackageDB: "python:app"},
+			"2": {ID: "2", Name: "pytest", PackageDB: "python:app"},
+		},
+	}
+	h := VulnerabilityReportHandler(
+		&matcher.Mock{
+			LatestUpdateOperation_: func(context.Context) (uuid.UUID, error) {
+				return uuid.Nil, nil
+			},
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return &claircore.VulnerabilityReport{
+					Hash:     ir.Hash,
+					Packages: ir.Packages,
+					Vulnerabilities: map[string]*claircore.Vulnerability{
+						"v1": {ID: "v1"},
+						"v2": {ID: "v2"},
+					},
+					PackageVulnerabilities: map[string][]string{"1": {"v1"}, "2": {"v2"}},
+				}, nil
+			},
+		},
+		&scopedIndexer{
+			Mock: &indexer.Mock{
+				IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+					return ir, true, nil
+				},
+			},
+			scopes: map[string]string{"1": "runtime", "2": "development"},
+		},
+	)
+
+	for _, tc := range []struct {
+		query string
+		code  int
+		pkgs  int
+	}{
+		{"", http.StatusOK, 2},
+		{"?scope=runtime", http.StatusOK, 1},
+		{"?scope=test", http.StatusBadRequest, 0},
+	} {
+		req := httptest.NewRequest(http.MethodGet, VulnerabilityReportPath+d.String()+tc.query, nil)
+		rr := httptest.NewRecorder()
+		h(rr, req)
+		if got, want := rr.Code, tc.code; got != want {
+			t.Fatalf("%q: got: %d, want: %d", tc.query, got, want)
+		}
+		if tc.code != http.StatusOK {
+			continue
+		}
+		var got struct {
+			Packages        map[string]interface{} `json:"packages"`
+			Vulnerabilities map[string]interface{} `json:"vulnerabilities"`
+			Scopes          map[string]string      `json:"package_scopes"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Packages) != tc.pkgs || len(got.Vulnerabilities) != tc.pkgs || len(got.Scopes) != tc.pkgs {
+			t.Errorf("%q: unexpected report: %+v", tc.query, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package indexer
+
+import (
+	"context"
+	"sort"
+
+	"github.com/quay/claircore"
+)
+
+// AffectedPager is an interface for reporting the manifests affected by a set
+// of vulnerabilities a page at a time.
+type AffectedPager interface {
+	// AffectedManifestsPage reports up to size of the affected manifests, in
+	// order of hash starting at next, along with the hash starting the
+	// following page, if there is one. Only vulnerabilities affecting
+	// manifests in the page are included.
+	AffectedManifestsPage(ctx context.Context, vulns []claircore.Vulnerability, next string, size int) (*claircore.AffectedManifests, string, error)
+}
+
+// PageAffected returns the page of the affected manifests described by
+// AffectedPager, for implementations without a better way to find it.
+func PageAffected(a *claircore.AffectedManifests, next string, size int) (*claircore.AffectedManifests, string) {
+	hs := make([]string, 0, len(a.VulnerableManifests))
+	for h := range a.VulnerableManifests {
+		if h >= next {
+			hs = append(hs, h)
+		}
+	}
+	sort.Strings(hs)
+	var following string
+	if len(hs) > size {
+		following = hs[size]
+		hs = hs[:size]
+	}
+	return Subset(a, hs), following
+}
+
+// Subset returns the parts of the affected manifests concerning the provided
+// manifests.
+func Subset(a *claircore.AffectedManifests, hs []string) *claircore.AffectedManifests {
+	out := claircore.NewAffectedManifests()
+	for _, h := range hs {
+		ids, ok := a.VulnerableManifests[h]
+		if !ok {
+			continue
+		}
+		out.VulnerableManifests[h] = ids
+		for _, id := range ids {
+			out.Vulnerabilities[id] = a.Vulnerabilities[id]
+		}
+	}
+	return &out
+}
@@ -0,0 +1,80 @@
+// Package annotations records arbitrary key/value annotations clients attach
+// to index reports, such as the owning team, the environment, or the commit
+// an image was built from.
+//
+// Annotations are kept apart from the index reports themselves, so they
+// survive a manifest being indexed again.
+package annotations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/quay/claircore"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// These are the limits on annotations.
+const (
+	// DefaultMaxKeys is the number of annotations a manifest may have if no
+	// other limit is configured.
+	DefaultMaxKeys = 64
+	// MaxKeyLength is the longest a key may be, in bytes.
+	MaxKeyLength = 128
+	// MaxValueLength is the longest a value may be, in bytes.
+	MaxValueLength = 4096
+)
+
+// ErrTooMany is returned by Annotate when a patch would leave a manifest with
+// more annotations than allowed.
+var ErrTooMany = clairerror.New(clairerror.Invalid, "annotations: too many annotations")
+
+// Annotator is implemented by indexers that record annotations.
+type Annotator interface {
+	// Annotations returns the manifest's annotations, or nil if it has
+	// none.
+	Annotations(ctx context.Context, manifest claircore.Digest) (map[string]string, error)
+}
+
+// Patch is a change to a manifest's annotations, in the manner of a JSON
+// merge patch: keys with a value are set, and keys with a nil value are
+// removed.
+type Patch map[string]*string
+
+// Validate reports whether the patch's keys and values are acceptable.
+func (p Patch) Validate() error {
+	for k, v := range p {
+		switch {
+		case k == "":
+			return errors.New("annotations: empty key")
+		case len(k) > MaxKeyLength:
+			return fmt.Errorf("annotations: key %.32q... longer than %d bytes", k, MaxKeyLength)
+		case !utf8.ValidString(k):
+			return fmt.Errorf("annotations: key %q isn't valid UTF-8", k)
+		case v == nil:
+		case len(*v) > MaxValueLength:
+			return fmt.Errorf("annotations: value of %q longer than %d bytes", k, MaxValueLength)
+		case !utf8.ValidString(*v):
+			return fmt.Errorf("annotations: value of %q isn't valid UTF-8", k)
+		}
+	}
+	return nil
+}
+
+// Split returns the keys the patch sets, with their values, and the keys it
+// removes.
+func (p Patch) split() (map[string]string, []string) {
+	set := make(map[string]string, len(p))
+	del := []string{}
+	for k, v := range p {
+		if v == nil {
+			del = append(del, k)
+			continue
+		}
+		set[k] = *v
+	}
+	return set, del
+}
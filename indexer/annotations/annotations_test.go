@@ -0,0 +1,48 @@
+package annotations
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	var p Patch
+	if err := json.Unmarshal([]byte(`{"team":"payments","env":null}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	set, del := p.split()
+	if got, want := len(set), 1; got != want || set["team"] != "payments" {
+		t.Errorf("unexpected set keys: %v", set)
+	}
+	sort.Strings(del)
+	if len(del) != 1 || del[0] != "env" {
+		t.Errorf("unexpected removed keys: %v", del)
+	}
+}
+
+func TestPatchValidate(t *testing.T) {
+	long := strings.Repeat("x", MaxValueLength+1)
+	bad := "\xff"
+	tt := []struct {
+		name  string
+		patch Patch
+	}{
+		{name: "EmptyKey", patch: Patch{"": nil}},
+		{name: "LongKey", patch: Patch{strings.Repeat("k", MaxKeyLength+1): nil}},
+		{name: "InvalidKey", patch: Patch{bad: nil}},
+		{name: "LongValue", patch: Patch{"k": &long}},
+		{name: "InvalidValue", patch: Patch{"k": &bad}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.patch.Validate(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
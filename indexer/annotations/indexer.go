@@ -0,0 +1,129 @@
+package annotations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Indexer wraps an indexer.Service and records annotations on the manifests
+// it indexes.
+//
+// Annotations are kept per tenant: each tenant sees and changes only the
+// annotations it set on a manifest, and requests without a tenant share
+// their own set.
+type Indexer struct {
+	indexer.Service
+	pool    *pgxpool.Pool
+	maxKeys int
+	tenant  func(context.Context) (string, bool)
+}
+
+var (
+	_ indexer.Service = (*Indexer)(nil)
+	_ Annotator       = (*Indexer)(nil)
+)
+
+// NewIndexer returns an Indexer recording annotations in the database behind
+// pool, which must be the indexer's database. If maxKeys isn't positive,
+// DefaultMaxKeys is used.
+//
+// The tenant function reports the tenant of a request, as
+// tenant.FromContext does. If it's nil, every request shares one set of
+// annotations.
+func NewIndexer(s indexer.Service, pool *pgxpool.Pool, maxKeys int, tenant func(context.Context) (string, bool)) *Indexer {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	return &Indexer{
+		Service: s,
+		pool:    pool,
+		maxKeys: maxKeys,
+		tenant:  tenant,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Scope returns the tenant the request's annotations are kept under, or the
+// empty string for requests without one.
+func (i *Indexer) scope(ctx context.Context) string {
+	if i.tenant == nil {
+		return ""
+	}
+	t, _ := i.tenant(ctx)
+	return t
+}
+
+const (
+	selectAnnotations = `
+SELECT annotations FROM manifest_annotations WHERE tenant = $1 AND manifest_hash = $2;`
+	upsertAnnotations = `
+INSERT INTO manifest_annotations (tenant, manifest_hash, annotations, updated)
+VALUES ($1, $2, $3::jsonb - $4::text[], $5)
+ON CONFLICT (tenant, manifest_hash) DO UPDATE SET
+	annotations = (manifest_annotations.annotations || $3::jsonb) - $4::text[],
+	updated = EXCLUDED.updated
+RETURNING annotations;`
+	deleteAnnotations = `
+DELETE FROM manifest_annotations WHERE tenant = $1 AND manifest_hash = $2;`
+)
+
+// Annotations implements Annotator, returning the annotations the request's
+// tenant set.
+func (i *Indexer) Annotations(ctx context.Context, manifest claircore.Digest) (map[string]string, error) {
+	var as map[string]string
+	err := i.pool.QueryRow(ctx, selectAnnotations, i.scope(ctx), manifest.String()).Scan(&as)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("annotations: failed to read annotations: %w", err)
+	}
+	return as, nil
+}
+
+// Annotate applies the patch to the annotations the request's tenant set on
+// the manifest, returning the result. The patch must have been checked with
+// Validate.
+//
+// Callers should make sure the manifest has been indexed; annotations on
+// any digest are accepted.
+func (i *Indexer) Annotate(ctx context.Context, manifest claircore.Digest, p Patch) (map[string]string, error) {
+	set, del := p.split()
+	t := i.scope(ctx)
+	tx, err := i.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("annotations: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	var as map[string]string
+	if err := tx.QueryRow(ctx, upsertAnnotations, t, manifest.String(), set, del, time.Now()).Scan(&as); err != nil {
+		return nil, fmt.Errorf("annotations: failed to update annotations: %w", err)
+	}
+	switch {
+	case len(as) > i.maxKeys:
+		return nil, ErrTooMany
+	case len(as) == 0:
+		if _, err := tx.Exec(ctx, deleteAnnotations, t, manifest.String()); err != nil {
+			return nil, fmt.Errorf("annotations: failed to remove annotations: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("annotations: failed to commit: %w", err)
+	}
+	if as == nil {
+		as = map[string]string{}
+	}
+	return as, nil
+}
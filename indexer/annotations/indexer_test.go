@@ -0,0 +1,100 @@
+package annotations
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+	"github.com/remind101/migrate"
+
+	"github.com/quay/clair/v4/indexer/annotations/migrations"
+)
+
+type tenantKey struct{}
+
+func testTenant(ctx context.Context) (string, bool) {
+	t, ok := ctx.Value(tenantKey{}).(string)
+	return t, ok
+}
+
+// TestTenants confirms tenants only see and change the annotations they set,
+// and that annotations set before they were kept per tenant belong to
+// requests without a tenant.
+func TestTenants(t *testing.T) {
+	integration.Skip(t)
+	ctx := context.Background()
+	if os.Getenv(integration.EnvPGConnString) == "" {
+		os.Setenv(integration.EnvPGConnString, `host=localhost port=5432 user=clair dbname=clair sslmode=disable`)
+	}
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	defer db.Close(ctx, t)
+	pool, err := pgxpool.ConnectConfig(ctx, db.Config())
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	defer pool.Close()
+	sdb := stdlib.OpenDB(*db.Config().ConnConfig)
+	defer sdb.Close()
+	migrator := migrate.NewPostgresMigrator(sdb)
+	migrator.Table = migrations.MigrationTable
+
+	m := claircore.MustParseDigest("sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a")
+	if err := migrator.Exec(migrate.Up, migrations.Migrations[:1]...); err != nil {
+		t.Fatalf("failed to perform migrations: %v", err)
+	}
+	const legacy = `INSERT INTO manifest_annotations (manifest_hash, annotations, updated) VALUES ($1, '{"team":"legacy"}', $2);`
+	if _, err := pool.Exec(ctx, legacy, m.String(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Exec(migrate.Up, migrations.Migrations...); err != nil {
+		t.Fatalf("failed to perform migrations: %v", err)
+	}
+
+	i := NewIndexer(nil, pool, 0, testTenant)
+	a := context.WithValue(ctx, tenantKey{}, "a")
+	b := context.WithValue(ctx, tenantKey{}, "b")
+	check := func(ctx context.Context, want map[string]string) {
+		t.Helper()
+		got, err := i.Annotations(ctx, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	}
+	value := func(v string) *string { return &v }
+
+	check(ctx, map[string]string{"team": "legacy"})
+	check(a, nil)
+	check(b, nil)
+
+	if _, err := i.Annotate(a, m, Patch{"team": value("a")}); err != nil {
+		t.Fatal(err)
+	}
+	check(a, map[string]string{"team": "a"})
+	check(b, nil)
+
+	if _, err := i.Annotate(b, m, Patch{"team": value("b"), "env": value("prod")}); err != nil {
+		t.Fatal(err)
+	}
+	check(a, map[string]string{"team": "a"})
+	check(b, map[string]string{"team": "b", "env": "prod"})
+
+	// Removing every key of one tenant leaves the others' alone.
+	if _, err := i.Annotate(a, m, Patch{"team": nil}); err != nil {
+		t.Fatal(err)
+	}
+	check(a, nil)
+	check(b, map[string]string{"team": "b", "env": "prod"})
+	check(ctx, map[string]string{"team": "legacy"})
+}
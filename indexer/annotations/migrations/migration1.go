@@ -0,0 +1,16 @@
+package migrations
+
+const (
+	// migration1 adds a table recording the annotations clients attach to
+	// manifests.
+	migration1 = `
+	--- a relation recording a manifest's annotations, kept apart from the
+	--- manifest so they outlive it being indexed again
+	CREATE TABLE IF NOT EXISTS manifest_annotations
+	(
+		manifest_hash text PRIMARY KEY,
+		annotations   jsonb NOT NULL,
+		updated       timestamptz NOT NULL
+	);
+	`
+)
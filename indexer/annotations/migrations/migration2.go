@@ -0,0 +1,13 @@
+package migrations
+
+const (
+	// migration2 keys annotations by tenant as well as manifest, so tenants
+	// sharing a manifest don't see each other's annotations.
+	migration2 = `
+	--- annotations set before this migration were shared by every tenant;
+	--- they're kept as those of requests without a tenant.
+	ALTER TABLE manifest_annotations ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT '';
+	ALTER TABLE manifest_annotations DROP CONSTRAINT manifest_annotations_pkey;
+	ALTER TABLE manifest_annotations ADD PRIMARY KEY (tenant, manifest_hash);
+	`
+)
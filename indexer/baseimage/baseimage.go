@@ -0,0 +1,144 @@
+// Package baseimage identifies the probable base image of indexed manifests,
+// so vulnerabilities can be told apart by who can fix them: the base image's
+// maintainers, or the application's.
+//
+// Base images aren't declared anywhere an indexer can see. Instead, a
+// manifest's base is taken to be the longest run of its leading layers that
+// other indexed manifests start with as well: either a manifest made of
+// exactly those layers, which is the base image itself, or enough manifests
+// sharing them that they're probably built on the same one.
+package baseimage
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// These are the origins of a vulnerability.
+const (
+	// OriginBase is a vulnerability only found in packages that came with
+	// the base image.
+	OriginBase = "base"
+	// OriginApplication is a vulnerability found in packages added by the
+	// layers built on top of the base image.
+	OriginApplication = "application"
+)
+
+// DefaultMinShared is the number of other manifests that must start with the
+// same layers for them to be taken as a base image, if none of the manifests
+// is made of exactly those layers.
+const DefaultMinShared = 2
+
+// Base is a manifest's probable base image.
+type Base struct {
+	// Manifest is the indexed manifest made of exactly the base layers, if
+	// there is one.
+	Manifest *claircore.Digest `json:"manifest,omitempty"`
+	// Layers are the manifest's layers that came with the base image, in
+	// order.
+	Layers []claircore.Digest `json:"layers"`
+	// Shared is the number of other indexed manifests starting with the
+	// same layers.
+	Shared int `json:"shared"`
+}
+
+// Detector reports manifests' base images.
+type Detector interface {
+	// Base returns the manifest's probable base image, or nil if it doesn't
+	// appear to have one.
+	Base(ctx context.Context, manifest claircore.Digest) (*Base, error)
+}
+
+// Inherited reports whether every one of the layers came with the base
+// image. No layers at all aren't inherited.
+func (b *Base) Inherited(ls []claircore.Digest) bool {
+	if b == nil || len(ls) == 0 {
+		return false
+	}
+	in := make(map[string]bool, len(b.Layers))
+	for _, l := range b.Layers {
+		in[l.String()] = true
+	}
+	for _, l := range ls {
+		if !in[l.String()] {
+			return false
+		}
+	}
+	return true
+}
+
+// Origins reports the origin of each of the report's vulnerabilities, keyed
+// by vulnerability id.
+//
+// A vulnerability is inherited from the base image if every package it
+// affects was only introduced by base layers; a package that application
+// layers install again isn't fixed by updating the base image alone.
+func Origins(vr *claircore.VulnerabilityReport, b *Base) map[string]string {
+	if b == nil {
+		return nil
+	}
+	out := make(map[string]string, len(vr.Vulnerabilities))
+	for id, vs := range vr.PackageVulnerabilities {
+		var ls []claircore.Digest
+		for _, e := range vr.Environments[id] {
+			if e != nil {
+				ls = append(ls, e.IntroducedIn)
+			}
+		}
+		o := OriginApplication
+		if b.Inherited(ls) {
+			o = OriginBase
+		}
+		for _, v := range vs {
+			if out[v] != OriginApplication {
+				out[v] = o
+			}
+		}
+	}
+	return out
+}
+
+// Detect returns the number of leading layers of self that make up its
+// probable base image, and the key of the candidate made of exactly those
+// layers, if any. Candidates are the layer lists of other manifests, keyed
+// by manifest.
+//
+// Candidates with the same layers as self don't count: they're the same
+// image under another name. A prefix is a base image if some candidate is
+// made of exactly it, or at least minShared candidates start with it. The
+// longest such prefix wins. If there's none, n is 0.
+func detect(self []int64, cands map[string][]int64, minShared int) (n int, manifest string, shared int) {
+	// prefixes[k] counts the candidates sharing the first k+1 layers.
+	prefixes := make([]int, len(self))
+	exact := make(map[int]string)
+	for k, ls := range cands {
+		p := 0
+		for p < len(self) && p < len(ls) && self[p] == ls[p] {
+			p++
+		}
+		if p == len(self) && len(ls) == len(self) {
+			continue
+		}
+		if p == len(self) {
+			// A manifest built on top of self: self is its base, not the
+			// other way around.
+			p--
+		}
+		for i := 0; i < p; i++ {
+			prefixes[i]++
+		}
+		if p > 0 && len(ls) == p {
+			if m, ok := exact[p]; !ok || k < m {
+				exact[p] = k
+			}
+		}
+	}
+	for p := len(self) - 1; p > 0; p-- {
+		m, ok := exact[p]
+		if ok || prefixes[p-1] >= minShared {
+			return p, m, prefixes[p-1]
+		}
+	}
+	return 0, "", 0
+}
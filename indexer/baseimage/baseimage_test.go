@@ -0,0 +1,105 @@
+package baseimage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		Name     string
+		Self     []int64
+		Cands    map[string][]int64
+		N        int
+		Manifest string
+		Shared   int
+	}{
+		{
+			Name:  "Alone",
+			Self:  []int64{1, 2, 3},
+			Cands: map[string][]int64{},
+		},
+		{
+			Name:     "Exact",
+			Self:     []int64{1, 2, 3},
+			Cands:    map[string][]int64{"base": {1, 2}},
+			N:        2,
+			Manifest: "base",
+			Shared:   1,
+		},
+		{
+			Name:   "Shared",
+			Self:   []int64{1, 2, 3, 4},
+			Cands:  map[string][]int64{"a": {1, 2, 3, 5}, "b": {1, 2, 3, 6}, "c": {1, 7}},
+			N:      3,
+			Shared: 2,
+		},
+		{
+			Name:  "TooFewShared",
+			Self:  []int64{1, 2, 3},
+			Cands: map[string][]int64{"a": {1, 2, 5}},
+		},
+		{
+			Name: "LongerShared",
+			Self: []int64{1, 2, 3, 4},
+			Cands: map[string][]int64{
+				"base": {1},
+				"a":    {1, 2, 3, 5},
+				"b":    {1, 2, 3, 6},
+			},
+			N:      3,
+			Shared: 2,
+		},
+		{
+			Name:  "Renamed",
+			Self:  []int64{1, 2},
+			Cands: map[string][]int64{"copy": {1, 2}, "other": {1, 2}},
+		},
+		{
+			Name:   "Children",
+			Self:   []int64{1, 2},
+			Cands:  map[string][]int64{"a": {1, 2, 3}, "b": {1, 2, 4}},
+			N:      1,
+			Shared: 2,
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			n, m, shared := detect(tc.Self, tc.Cands, DefaultMinShared)
+			if n != tc.N || m != tc.Manifest || shared != tc.Shared {
+				t.Errorf("got: (%d, %q, %d), want: (%d, %q, %d)", n, m, shared, tc.N, tc.Manifest, tc.Shared)
+			}
+		})
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	base := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	app := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	vr := &claircore.VulnerabilityReport{
+		Environments: map[string][]*claircore.Environment{
+			"1": {{IntroducedIn: base}},
+			"2": {{IntroducedIn: app}},
+			"3": {{IntroducedIn: base}, {IntroducedIn: app}},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"v1", "v2"},
+			"2": {"v2"},
+			"3": {"v3"},
+		},
+	}
+	got := Origins(vr, &Base{Layers: []claircore.Digest{base}})
+	want := map[string]string{
+		"v1": OriginBase,
+		"v2": OriginApplication,
+		"v3": OriginApplication,
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got := Origins(vr, nil); got != nil {
+		t.Errorf("got: %v, want: nil", got)
+	}
+}
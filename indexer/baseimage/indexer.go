@@ -0,0 +1,137 @@
+package baseimage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/layers"
+)
+
+// MaxCandidates bounds the number of other manifests compared against, so
+// manifests built on very popular base images don't cost a scan of the
+// whole index.
+const maxCandidates = 5000
+
+// SelectLayers returns the layer ids of the manifest and of manifests
+// starting with the same layer, the manifest first.
+const selectLayers = `
+WITH self AS (
+	SELECT ml.manifest_id, ml.i, ml.layer_id
+	FROM manifest_layer ml
+	JOIN manifest m ON m.id = ml.manifest_id
+	WHERE m.hash = $1
+),
+cand AS (
+	SELECT DISTINCT ml.manifest_id
+	FROM manifest_layer ml
+	JOIN self s ON s.i = 0 AND ml.i = 0 AND ml.layer_id = s.layer_id
+	WHERE ml.manifest_id <> s.manifest_id
+	LIMIT $2
+)
+SELECT m.hash, array_agg(ml.layer_id ORDER BY ml.i), m.hash = $1 AS self
+FROM manifest_layer ml
+JOIN manifest m ON m.id = ml.manifest_id
+WHERE ml.manifest_id IN (SELECT manifest_id FROM self UNION SELECT manifest_id FROM cand)
+GROUP BY m.hash
+ORDER BY self DESC;`
+
+// Indexer wraps an indexer.Service to detect the base images of indexed
+// manifests, by comparing their layers with the rest of the index.
+type Indexer struct {
+	indexer.Service
+	pool      *pgxpool.Pool
+	minShared int
+}
+
+var (
+	_ indexer.Service = (*Indexer)(nil)
+	_ Detector        = (*Indexer)(nil)
+)
+
+// NewIndexer returns an Indexer reading from the indexer's database. If
+// minShared isn't positive, DefaultMinShared is used.
+func NewIndexer(s indexer.Service, pool *pgxpool.Pool, minShared int) *Indexer {
+	if minShared <= 0 {
+		minShared = DefaultMinShared
+	}
+	return &Indexer{Service: s, pool: pool, minShared: minShared}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Base implements Detector. A manifest that hasn't been indexed has no base
+// image.
+//
+// The layers are reported as the wrapped indexer lists them, if it can, so
+// they match the ones in its index reports.
+func (i *Indexer) Base(ctx context.Context, manifest claircore.Digest) (*Base, error) {
+	rows, err := i.pool.Query(ctx, selectLayers, manifest.String(), maxCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("baseimage: failed to query layers: %w", err)
+	}
+	defer rows.Close()
+	var self []int64
+	cands := make(map[string][]int64)
+	for rows.Next() {
+		var h string
+		var ls []int64
+		var isSelf bool
+		if err := rows.Scan(&h, &ls, &isSelf); err != nil {
+			return nil, fmt.Errorf("baseimage: failed to read layers: %w", err)
+		}
+		if isSelf {
+			self = ls
+			continue
+		}
+		cands[h] = ls
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("baseimage: failed to query layers: %w", err)
+	}
+	n, m, shared := detect(self, cands, i.minShared)
+	if n == 0 {
+		return nil, nil
+	}
+
+	order, err := i.layers(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if len(order) != len(self) {
+		// Reindexed in the meantime.
+		return nil, nil
+	}
+	b := Base{
+		Layers: order[:n],
+		Shared: shared,
+	}
+	if m != "" {
+		d, err := claircore.ParseDigest(m)
+		if err != nil {
+			return nil, fmt.Errorf("baseimage: bad manifest digest: %w", err)
+		}
+		b.Manifest = &d
+	}
+	return &b, nil
+}
+
+// Layers returns the manifest's layers from the first layers.Lister among
+// the wrapped indexers.
+func (i *Indexer) layers(ctx context.Context, manifest claircore.Digest) ([]claircore.Digest, error) {
+	var l layers.Lister
+	if !indexer.Walk(i.Service, func(s interface{}) bool {
+		var ok bool
+		l, ok = s.(layers.Lister)
+		return ok
+	}) {
+		return nil, fmt.Errorf("baseimage: indexer can't list layers")
+	}
+	return l.Layers(ctx, manifest)
+}
@@ -0,0 +1,119 @@
+// Package budget bounds how much of each layer the indexer scans, so
+// pathological images with millions of files are indexed in part instead of
+// exhausting the indexer's memory.
+//
+// Layers over budget are cut short: the indexer sees the layer's entries up
+// to the one that would exceed the budget, as an uncompressed tar with a
+// digest of its own. Index reports refer to the original layer, and record
+// that it was truncated.
+package budget
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Limits is a per-layer budget. A zero field doesn't limit anything.
+type Limits struct {
+	// Files is the most tar entries scanned in a layer.
+	Files int64
+	// Size is the most bytes of file contents scanned in a layer,
+	// uncompressed.
+	Size int64
+}
+
+// These are the reasons a layer is truncated.
+const (
+	ReasonFiles = "files"
+	ReasonSize  = "size"
+)
+
+// Usage is how much of a layer was scanned.
+type Usage struct {
+	Files int64
+	Size  int64
+	// Reason is why the layer was truncated, or empty if it wasn't.
+	Reason string
+}
+
+// Truncated reports whether the layer was truncated.
+func (u *Usage) Truncated() bool {
+	return u.Reason != ""
+}
+
+// Copy writes the layer read from r to w as an uncompressed tar, stopping at
+// the first entry that would exceed the limits, and reports what was
+// written. The layer may be gzip or zstd compressed or not at all.
+//
+// The same layer and limits always produce the same output. A layer within
+// the limits is read to the end.
+func (l Limits) Copy(w io.Writer, r io.Reader) (*Usage, error) {
+	rc, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	tw := tar.NewWriter(w)
+	var u Usage
+	for {
+		h, err := tr.Next()
+		switch {
+		case errors.Is(err, io.EOF):
+			return &u, tw.Close()
+		case err != nil:
+			return nil, fmt.Errorf("budget: reading layer: %w", err)
+		}
+		switch {
+		case l.Files > 0 && u.Files+1 > l.Files:
+			u.Reason = ReasonFiles
+		case l.Size > 0 && u.Size+h.Size > l.Size:
+			u.Reason = ReasonSize
+		}
+		if u.Truncated() {
+			return &u, tw.Close()
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return nil, fmt.Errorf("budget: writing layer: %w", err)
+		}
+		n, err := io.Copy(tw, tr)
+		if err != nil {
+			return nil, fmt.Errorf("budget: copying %q: %w", h.Name, err)
+		}
+		u.Files++
+		u.Size += n
+	}
+}
+
+// Decompress returns a reader for the uncompressed layer, which may be
+// gzip or zstd compressed or not at all.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("budget: reading layer: %w", err)
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		z, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("budget: reading layer: %w", err)
+		}
+		return z, nil
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		z, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("budget: reading layer: %w", err)
+		}
+		return z.IOReadCloser(), nil
+	}
+	return ioutil.NopCloser(br), nil
+}
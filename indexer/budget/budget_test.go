@@ -0,0 +1,98 @@
+package budget
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+// Layer returns a tar of files with the named contents.
+func layer(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for n, c := range files {
+		h := &tar.Header{
+			Name:     string(rune('a'+n)) + ".txt",
+			Mode:     0644,
+			Size:     int64(len(c)),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func count(t *testing.T, b []byte) int {
+	t.Helper()
+	tr := tar.NewReader(bytes.NewReader(b))
+	n := 0
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			return n
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+}
+
+func TestCopy(t *testing.T) {
+	l := layer(t, "one", "two", "three")
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(l)
+	zw.Close()
+
+	for _, tc := range []struct {
+		Name   string
+		Limits Limits
+		In     []byte
+		Files  int64
+		Reason string
+	}{
+		{"Unlimited", Limits{}, l, 3, ""},
+		{"Within", Limits{Files: 3, Size: 11}, l, 3, ""},
+		{"Files", Limits{Files: 2}, l, 2, ReasonFiles},
+		{"Size", Limits{Size: 7}, l, 2, ReasonSize},
+		{"Gzip", Limits{Files: 1}, gz.Bytes(), 1, ReasonFiles},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			u, err := tc.Limits.Copy(&out, bytes.NewReader(tc.In))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := u.Files, tc.Files; got != want {
+				t.Errorf("got: %d files, want: %d", got, want)
+			}
+			if got, want := u.Reason, tc.Reason; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+			if got, want := count(t, out.Bytes()), int(tc.Files); got != want {
+				t.Errorf("got: %d entries written, want: %d", got, want)
+			}
+			// The output must be the same every time, as its digest is
+			// computed ahead of serving it.
+			var again bytes.Buffer
+			if _, err := tc.Limits.Copy(&again, bytes.NewReader(tc.In)); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), again.Bytes()) {
+				t.Error("output differs between copies")
+			}
+		})
+	}
+}
@@ -0,0 +1,455 @@
+package budget
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/layers"
+)
+
+// Truncation records a layer that was cut short.
+type Truncation struct {
+	Layer claircore.Digest `json:"layer"`
+	// Files and Size are how many entries and bytes of file contents were
+	// scanned.
+	Files int64 `json:"files"`
+	Size  int64 `json:"size"`
+	// Reason is the limit that was hit: "files" or "size".
+	Reason string `json:"reason"`
+}
+
+// Indexer wraps an indexer.Service, truncating layers over budget before the
+// indexer scans them.
+//
+// Every layer is read once before indexing to check it against the budget;
+// layers found within budget aren't checked again. Truncated layers are
+// handed to the indexer's fetcher from a listener on the loopback
+// interface, guarded by a random token, like the layercache Indexer.
+type Indexer struct {
+	indexer.Service
+	limits Limits
+	pool   *pgxpool.Pool
+	c      *http.Client
+	base   string
+	token  string
+
+	mu      sync.Mutex
+	sources map[string]map[string]source
+
+	truncated metric.Int64Counter
+}
+
+// Source is where a truncated layer's original is fetched from.
+type source struct {
+	uri     string
+	headers http.Header
+}
+
+var (
+	_ indexer.Service = (*Indexer)(nil)
+	_ layers.Lister   = (*Indexer)(nil)
+)
+
+// NewIndexer returns an Indexer enforcing the limits, recording its checks
+// in the database. Layers are fetched with the client; a nil client means
+// http.DefaultClient. The loopback server is stopped when the Context is
+// canceled.
+func NewIndexer(ctx context.Context, s indexer.Service, pool *pgxpool.Pool, c *http.Client, l Limits) (*Indexer, error) {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	tok := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, tok); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	i := &Indexer{
+		Service: s,
+		limits:  l,
+		pool:    pool,
+		c:       c,
+		base:    "http://" + ln.Addr().String() + "/",
+		token:   hex.EncodeToString(tok),
+		sources: make(map[string]map[string]source),
+		truncated: metric.Must(otel.Meter("clair")).NewInt64Counter(
+			"clair_indexer_layers_truncated_total",
+			metric.WithDescription("number of layers truncated for exceeding the scan budget"),
+		),
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(i.serve)}
+	go srv.Serve(ln)
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	return i, nil
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+//
+// The wrapped indexer is handed a copy of the manifest with truncated
+// layers in place of the ones over budget. The returned report refers to the
+// original layers.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/budget/Indexer.Index").
+		Str("manifest", m.Hash.String()).
+		Logger()
+	ds := make([]*claircore.Digest, len(m.Layers))
+	eg, ectx := errgroup.WithContext(ctx)
+	for n, l := range m.Layers {
+		n, l := n, l
+		eg.Go(func() error {
+			d, err := i.check(ectx, l)
+			if err != nil {
+				// Let the indexer fetch the layer and report whatever's
+				// wrong with it.
+				log.Warn().
+					Err(err).
+					Str("layer", l.Hash.String()).
+					Msg("unable to check layer against budget")
+				return nil
+			}
+			ds[n] = d
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(b)
+	srcs := make(map[string]source)
+	orig := make(map[string]claircore.Digest)
+	cp := *m
+	cp.Layers = make([]*claircore.Layer, len(m.Layers))
+	for n, l := range m.Layers {
+		cp.Layers[n] = l
+		d := ds[n]
+		if d == nil {
+			continue
+		}
+		srcs[d.String()] = source{uri: l.URI, headers: l.Headers}
+		orig[d.String()] = l.Hash
+		lc := *l
+		lc.Hash = *d
+		lc.URI = i.base + id + "/" + d.String()
+		lc.Headers = map[string][]string{
+			"Authorization": {"Bearer " + i.token},
+		}
+		cp.Layers[n] = &lc
+	}
+	if len(srcs) == 0 {
+		return i.Service.Index(ctx, m)
+	}
+	log.Info().
+		Int("count", len(srcs)).
+		Msg("indexing truncated layers")
+	i.mu.Lock()
+	i.sources[id] = srcs
+	i.mu.Unlock()
+	defer func() {
+		i.mu.Lock()
+		delete(i.sources, id)
+		i.mu.Unlock()
+	}()
+	ir, err := i.Service.Index(ctx, &cp)
+	if ir != nil {
+		ir = restore(ir, orig)
+	}
+	return ir, err
+}
+
+const (
+	selectCheck = `SELECT truncated_hash IS NULL FROM layer_budget WHERE layer_hash = $1 AND max_files = $2 AND max_size = $3;`
+	upsertCheck = `
+INSERT INTO layer_budget (layer_hash, max_files, max_size, files, size, reason, truncated_hash, checked)
+VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+ON CONFLICT (layer_hash, max_files, max_size) DO UPDATE SET
+	files = EXCLUDED.files,
+	size = EXCLUDED.size,
+	reason = EXCLUDED.reason,
+	truncated_hash = EXCLUDED.truncated_hash,
+	checked = EXCLUDED.checked;`
+	selectOriginals = `SELECT DISTINCT truncated_hash, layer_hash FROM layer_budget WHERE truncated_hash = ANY($1);`
+	selectTruncated = `
+SELECT b.layer_hash, b.files, b.size, b.reason
+FROM manifest_layer ml
+JOIN manifest m ON m.id = ml.manifest_id
+JOIN layer l ON l.id = ml.layer_id
+JOIN layer_budget b ON b.truncated_hash = l.hash
+WHERE m.hash = $1
+ORDER BY ml.i;`
+)
+
+// Check reads the layer and returns the digest of its truncated form, or
+// nil if it's within budget.
+//
+// Truncated layers are always read again, rather than trusting a digest
+// recorded earlier, so the digest is the one serve will produce.
+func (i *Indexer) check(ctx context.Context, l *claircore.Layer) (*claircore.Digest, error) {
+	var ok bool
+	err := i.pool.QueryRow(ctx, selectCheck, l.Hash.String(), i.limits.Files, i.limits.Size).Scan(&ok)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("budget: failed to look up layer: %w", err)
+	case ok:
+		return nil, nil
+	}
+
+	rc, err := i.fetch(ctx, source{uri: l.URI, headers: l.Headers})
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	h := sha256.New()
+	u, err := i.limits.Copy(h, rc)
+	if err != nil {
+		return nil, err
+	}
+	var d *claircore.Digest
+	var th *string
+	if u.Truncated() {
+		dd, err := claircore.NewDigest("sha256", h.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+		d = &dd
+		s := dd.String()
+		th = &s
+		i.truncated.Add(ctx, 1)
+		zerolog.Ctx(ctx).Info().
+			Str("component", "indexer/budget/Indexer.check").
+			Str("layer", l.Hash.String()).
+			Int64("files", u.Files).
+			Int64("size", u.Size).
+			Str("reason", u.Reason).
+			Msg("layer over budget, truncating")
+	}
+	if _, err := i.pool.Exec(ctx, upsertCheck,
+		l.Hash.String(), i.limits.Files, i.limits.Size, u.Files, u.Size, u.Reason, th); err != nil {
+		return nil, fmt.Errorf("budget: failed to record layer: %w", err)
+	}
+	return d, nil
+}
+
+// Fetch returns the body of the layer at its source.
+func (i *Indexer) fetch(ctx context.Context, src source) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range src.headers {
+		req.Header[k] = vs
+	}
+	res, err := i.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, fmt.Errorf("unexpected response fetching layer: %s", res.Status)
+	}
+	return res.Body, nil
+}
+
+// Serve hands truncated layers to the indexer's fetcher.
+func (i *Indexer) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("authorization") != "Bearer "+i.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ctx := r.Context()
+	p := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(p) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	i.mu.Lock()
+	src, ok := i.sources[p[0]][p[1]]
+	i.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	rc, err := i.fetch(ctx, src)
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "indexer/budget/Indexer.serve").
+			Str("layer", p[1]).
+			Err(err).
+			Msg("unable to fetch layer")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("content-type", "application/x-tar")
+	// An error here leaves the fetcher with a layer that doesn't match its
+	// digest, which it reports.
+	i.limits.Copy(w, rc)
+}
+
+// Restore returns a copy of the report referring to the original layers
+// instead of their truncated forms.
+func restore(ir *claircore.IndexReport, orig map[string]claircore.Digest) *claircore.IndexReport {
+	if len(orig) == 0 {
+		return ir
+	}
+	out := *ir
+	out.Environments = make(map[string][]*claircore.Environment, len(ir.Environments))
+	for id, envs := range ir.Environments {
+		es := make([]*claircore.Environment, len(envs))
+		for n, e := range envs {
+			es[n] = e
+			if d, ok := orig[e.IntroducedIn.String()]; ok {
+				ec := *e
+				ec.IntroducedIn = d
+				es[n] = &ec
+			}
+		}
+		out.Environments[id] = es
+	}
+	return &out
+}
+
+// Originals returns the original layers of any of the digests that are
+// truncated layers, keyed by the truncated layer's digest.
+func (i *Indexer) originals(ctx context.Context, ds []string) (map[string]claircore.Digest, error) {
+	if len(ds) == 0 {
+		return nil, nil
+	}
+	rows, err := i.pool.Query(ctx, selectOriginals, ds)
+	if err != nil {
+		return nil, fmt.Errorf("budget: failed to query layers: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string]claircore.Digest)
+	for rows.Next() {
+		var t, o string
+		if err := rows.Scan(&t, &o); err != nil {
+			return nil, fmt.Errorf("budget: failed to read layer: %w", err)
+		}
+		d, err := claircore.ParseDigest(o)
+		if err != nil {
+			return nil, fmt.Errorf("budget: bad layer digest: %w", err)
+		}
+		out[t] = d
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("budget: failed to query layers: %w", err)
+	}
+	return out, nil
+}
+
+// IndexReport implements indexer.Reporter.
+func (i *Indexer) IndexReport(ctx context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	ir, ok, err := i.Service.IndexReport(ctx, d)
+	if err != nil || !ok {
+		return ir, ok, err
+	}
+	seen := make(map[string]bool)
+	var ds []string
+	for _, envs := range ir.Environments {
+		for _, e := range envs {
+			if s := e.IntroducedIn.String(); !seen[s] {
+				seen[s] = true
+				ds = append(ds, s)
+			}
+		}
+	}
+	orig, err := i.originals(ctx, ds)
+	if err != nil {
+		return nil, false, err
+	}
+	return restore(ir, orig), true, nil
+}
+
+// Layers implements layers.Lister, if the wrapped indexer can list layers.
+// Truncated layers are listed as the originals.
+func (i *Indexer) Layers(ctx context.Context, manifest claircore.Digest) ([]claircore.Digest, error) {
+	var l layers.Lister
+	if !indexer.Walk(i.Service, func(s interface{}) bool {
+		var ok bool
+		l, ok = s.(layers.Lister)
+		return ok
+	}) {
+		return nil, nil
+	}
+	ls, err := l.Layers(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+	ds := make([]string, len(ls))
+	for n, d := range ls {
+		ds[n] = d.String()
+	}
+	orig, err := i.originals(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	for n, d := range ls {
+		if o, ok := orig[d.String()]; ok {
+			ls[n] = o
+		}
+	}
+	return ls, nil
+}
+
+// Truncated returns the manifest's truncated layers, in order, or nil if
+// none were.
+func (i *Indexer) Truncated(ctx context.Context, manifest claircore.Digest) ([]Truncation, error) {
+	rows, err := i.pool.Query(ctx, selectTruncated, manifest.String())
+	if err != nil {
+		return nil, fmt.Errorf("budget: failed to query truncated layers: %w", err)
+	}
+	defer rows.Close()
+	var out []Truncation
+	for rows.Next() {
+		var t Truncation
+		var l string
+		if err := rows.Scan(&l, &t.Files, &t.Size, &t.Reason); err != nil {
+			return nil, fmt.Errorf("budget: failed to read truncated layer: %w", err)
+		}
+		if t.Layer, err = claircore.ParseDigest(l); err != nil {
+			return nil, fmt.Errorf("budget: bad layer digest: %w", err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("budget: failed to query truncated layers: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,23 @@
+package migrations
+
+const (
+	// migration1 adds a table recording layers checked against the scan
+	// budget.
+	migration1 = `
+	--- a relation recording a layer's check against a budget, and the
+	--- digest of its truncated form if it was over
+	CREATE TABLE IF NOT EXISTS layer_budget
+	(
+		layer_hash     text NOT NULL,
+		max_files      bigint NOT NULL,
+		max_size       bigint NOT NULL,
+		files          bigint NOT NULL,
+		size           bigint NOT NULL,
+		reason         text NOT NULL DEFAULT '',
+		truncated_hash text,
+		checked        timestamptz NOT NULL,
+		PRIMARY KEY (layer_hash, max_files, max_size)
+	);
+	CREATE INDEX IF NOT EXISTS layer_budget_truncated_idx ON layer_budget (truncated_hash);
+	`
+)
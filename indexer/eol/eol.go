@@ -0,0 +1,183 @@
+// Package eol detects distributions past their end of life.
+//
+// Distributions past their end of life get no new security data, so
+// vulnerabilities found in them after that date are never reported: images
+// built on them look clean while getting less so. Reports flag them instead.
+package eol
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+// DateFormat is the layout of end of life dates.
+const DateFormat = "2006-01-02"
+
+// Release is a distribution release and the date its security support ended.
+type Release struct {
+	// DID and VersionID are the os-release ID and VERSION_ID of the
+	// release, e.g. "debian" and "9".
+	DID       string
+	VersionID string
+	// Date is when the release stopped receiving security updates,
+	// including any free long-term support.
+	Date time.Time
+}
+
+// Notice reports a distribution in a manifest that's past its end of life.
+type Notice struct {
+	DID       string `json:"did"`
+	VersionID string `json:"version_id"`
+	// Name is the distribution's pretty name, if known.
+	Name string `json:"name,omitempty"`
+	// Date is formatted as DateFormat.
+	Date string `json:"date"`
+}
+
+// Checker reports the end of life distributions among a report's.
+type Checker interface {
+	EndOfLife(dists map[string]*claircore.Distribution) []Notice
+}
+
+// Detector is a Checker using a table of releases.
+type Detector struct {
+	releases map[string][]Release
+	now      func() time.Time
+}
+
+var _ Checker = (*Detector)(nil)
+
+// NewDetector returns a Detector knowing the Builtin releases and the extra
+// ones. Extra releases override builtin ones for the same DID and VersionID.
+func NewDetector(extra []Release) *Detector {
+	d := &Detector{
+		releases: make(map[string][]Release),
+		now:      time.Now,
+	}
+	seen := make(map[[2]string]bool)
+	// Extra releases go first, so they're found instead of the builtin
+	// ones they override.
+	for _, rs := range [][]Release{extra, Builtin} {
+		for _, r := range rs {
+			k := [2]string{r.DID, r.VersionID}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			d.releases[r.DID] = append(d.releases[r.DID], r)
+		}
+	}
+	return d
+}
+
+// EndOfLife implements Checker.
+//
+// A distribution matches a release with the same DID if its VersionID is the
+// release's or a point release of it, e.g. "7.9" for "7". Notices are sorted
+// by DID and VersionID.
+func (d *Detector) EndOfLife(dists map[string]*claircore.Distribution) []Notice {
+	now := d.now()
+	var out []Notice
+	seen := make(map[[2]string]bool)
+	for _, dist := range dists {
+		if dist == nil {
+			continue
+		}
+		r, ok := d.release(dist)
+		if !ok || r.Date.After(now) {
+			continue
+		}
+		k := [2]string{r.DID, r.VersionID}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, Notice{
+			DID:       r.DID,
+			VersionID: r.VersionID,
+			Name:      dist.PrettyName,
+			Date:      r.Date.Format(DateFormat),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].DID != out[j].DID {
+			return out[i].DID < out[j].DID
+		}
+		return out[i].VersionID < out[j].VersionID
+	})
+	return out
+}
+
+// Release returns the release the distribution belongs to. The most specific
+// match wins.
+func (d *Detector) release(dist *claircore.Distribution) (Release, bool) {
+	var out Release
+	var ok bool
+	for _, r := range d.releases[dist.DID] {
+		v := dist.VersionID
+		if v != r.VersionID && !strings.HasPrefix(v, r.VersionID+".") {
+			continue
+		}
+		if !ok || len(r.VersionID) > len(out.VersionID) {
+			out, ok = r, true
+		}
+	}
+	return out, ok
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse(DateFormat, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Builtin lists the end of life dates of releases of common distributions.
+//
+// Dates are the end of the support a distribution's security data covers:
+// Debian's LTS, but not the paid extended support of Ubuntu, Red Hat, and
+// others.
+var Builtin = []Release{
+	{DID: "alpine", VersionID: "3.3", Date: date("2017-11-01")},
+	{DID: "alpine", VersionID: "3.4", Date: date("2018-05-01")},
+	{DID: "alpine", VersionID: "3.5", Date: date("2018-11-01")},
+	{DID: "alpine", VersionID: "3.6", Date: date("2019-05-01")},
+	{DID: "alpine", VersionID: "3.7", Date: date("2019-11-01")},
+	{DID: "alpine", VersionID: "3.8", Date: date("2020-05-01")},
+	{DID: "alpine", VersionID: "3.9", Date: date("2020-11-01")},
+	{DID: "alpine", VersionID: "3.10", Date: date("2021-05-01")},
+	{DID: "alpine", VersionID: "3.11", Date: date("2021-11-01")},
+	{DID: "alpine", VersionID: "3.12", Date: date("2022-05-01")},
+	{DID: "alpine", VersionID: "3.13", Date: date("2022-11-01")},
+	{DID: "alpine", VersionID: "3.14", Date: date("2023-05-01")},
+	{DID: "alpine", VersionID: "3.15", Date: date("2023-11-01")},
+	{DID: "alpine", VersionID: "3.16", Date: date("2024-05-23")},
+	{DID: "alpine", VersionID: "3.17", Date: date("2024-11-22")},
+	{DID: "alpine", VersionID: "3.18", Date: date("2025-05-09")},
+	{DID: "amzn", VersionID: "2018.03", Date: date("2023-12-31")},
+	{DID: "amzn", VersionID: "2", Date: date("2026-06-30")},
+	{DID: "centos", VersionID: "6", Date: date("2020-11-30")},
+	{DID: "centos", VersionID: "7", Date: date("2024-06-30")},
+	{DID: "centos", VersionID: "8", Date: date("2021-12-31")},
+	{DID: "debian", VersionID: "7", Date: date("2018-05-31")},
+	{DID: "debian", VersionID: "8", Date: date("2020-06-30")},
+	{DID: "debian", VersionID: "9", Date: date("2022-06-30")},
+	{DID: "debian", VersionID: "10", Date: date("2024-06-30")},
+	{DID: "debian", VersionID: "11", Date: date("2026-08-31")},
+	{DID: "ol", VersionID: "5", Date: date("2017-06-30")},
+	{DID: "ol", VersionID: "6", Date: date("2021-03-01")},
+	{DID: "rhel", VersionID: "3", Date: date("2010-10-31")},
+	{DID: "rhel", VersionID: "4", Date: date("2012-02-29")},
+	{DID: "rhel", VersionID: "5", Date: date("2017-03-31")},
+	{DID: "rhel", VersionID: "6", Date: date("2020-11-30")},
+	{DID: "rhel", VersionID: "7", Date: date("2024-06-30")},
+	{DID: "ubuntu", VersionID: "12.04", Date: date("2017-04-28")},
+	{DID: "ubuntu", VersionID: "14.04", Date: date("2019-04-25")},
+	{DID: "ubuntu", VersionID: "16.04", Date: date("2021-04-30")},
+	{DID: "ubuntu", VersionID: "18.04", Date: date("2023-05-31")},
+	{DID: "ubuntu", VersionID: "20.04", Date: date("2025-05-31")},
+}
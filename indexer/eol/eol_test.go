@@ -0,0 +1,81 @@
+package eol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestDetector(t *testing.T) {
+	d := NewDetector([]Release{
+		{DID: "debian", VersionID: "12", Date: date("2026-06-10")},
+		// Overrides the builtin release.
+		{DID: "ubuntu", VersionID: "20.04", Date: date("2030-04-02")},
+	})
+	d.now = func() time.Time { return date("2026-10-15") }
+
+	tt := []struct {
+		name  string
+		dists map[string]*claircore.Distribution
+		want  []Notice
+	}{
+		{
+			name: "Stretch",
+			dists: map[string]*claircore.Distribution{
+				"1": {DID: "debian", VersionID: "9", PrettyName: "Debian GNU/Linux 9 (stretch)"},
+			},
+			want: []Notice{
+				{DID: "debian", VersionID: "9", Name: "Debian GNU/Linux 9 (stretch)", Date: "2022-06-30"},
+			},
+		},
+		{
+			name: "PointRelease",
+			dists: map[string]*claircore.Distribution{
+				"1": {DID: "centos", VersionID: "6.10"},
+				"2": {DID: "alpine", VersionID: "3.12.0"},
+			},
+			want: []Notice{
+				{DID: "alpine", VersionID: "3.12", Date: "2022-05-01"},
+				{DID: "centos", VersionID: "6", Date: "2020-11-30"},
+			},
+		},
+		{
+			name: "NotAPointRelease",
+			dists: map[string]*claircore.Distribution{
+				"1": {DID: "alpine", VersionID: "3.190"},
+			},
+		},
+		{
+			name: "Extra",
+			dists: map[string]*claircore.Distribution{
+				"1": {DID: "debian", VersionID: "12"},
+			},
+			want: []Notice{
+				{DID: "debian", VersionID: "12", Date: "2026-06-10"},
+			},
+		},
+		{
+			name: "Overridden",
+			dists: map[string]*claircore.Distribution{
+				"1": {DID: "ubuntu", VersionID: "20.04"},
+			},
+		},
+		{
+			name: "Supported",
+			dists: map[string]*claircore.Distribution{
+				"1": {DID: "debian", VersionID: "13"},
+				"2": {DID: "rhel", VersionID: "9"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := d.EndOfLife(tc.dists)
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}
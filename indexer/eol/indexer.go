@@ -0,0 +1,67 @@
+package eol
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Indexer wraps an indexer.Service to detect end of life distributions in
+// indexed manifests.
+//
+// The wrapped service may be a remote indexer: detection only needs the
+// distributions in a report, so vulnerability reports served by a matcher
+// are flagged the same way.
+type Indexer struct {
+	indexer.Service
+	*Detector
+}
+
+var (
+	_ indexer.Service = (*Indexer)(nil)
+	_ Checker         = (*Indexer)(nil)
+)
+
+// NewIndexer returns an Indexer using the Detector.
+func NewIndexer(s indexer.Service, d *Detector) *Indexer {
+	return &Indexer{Service: s, Detector: d}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+//
+// End of life distributions in the report are logged.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	ir, err := i.Service.Index(ctx, m)
+	if err != nil || ir == nil || !ir.Success {
+		return ir, err
+	}
+	for _, n := range i.EndOfLife(ir.Distributions) {
+		zerolog.Ctx(ctx).Info().
+			Str("component", "indexer/eol/Indexer.Index").
+			Stringer("manifest", m.Hash).
+			Str("did", n.DID).
+			Str("version_id", n.VersionID).
+			Str("date", n.Date).
+			Msg("manifest has an end of life distribution")
+	}
+	return ir, nil
+}
+
+// Find returns the Checker among the wrapped indexers, if there is one.
+func Find(s indexer.Service) (Checker, bool) {
+	var c Checker
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		c, ok = s.(Checker)
+		return ok
+	})
+	return c, ok
+}
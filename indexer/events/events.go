@@ -0,0 +1,230 @@
+// Package events records when manifests start and finish indexing, so the
+// notifier can relay the events to systems waiting on an index, like build
+// pipelines, without them polling.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/eol"
+	"github.com/quay/clair/v4/indexer/signature"
+)
+
+// These are the kinds of index events.
+const (
+	Started  = "started"
+	Finished = "finished"
+	Failed   = "failed"
+	// EndOfLife follows Finished when the manifest has a distribution past
+	// its end of life.
+	EndOfLife = "end_of_life"
+)
+
+// Kinds lists every kind of index event.
+var Kinds = []string{Started, Finished, Failed, EndOfLife}
+
+// These are the classes of errors reported by Failed events.
+const (
+	// ClassCanceled is reported when the request was canceled.
+	ClassCanceled = "canceled"
+	// ClassTimeout is reported when the request ran out of time.
+	ClassTimeout = "timeout"
+	// ClassRejected is reported when the manifest was refused, e.g. for
+	// failing signature verification.
+	ClassRejected = "rejected"
+	// ClassIndex is reported for every other failure.
+	ClassIndex = "index"
+)
+
+// Event is a point in the indexing of a manifest.
+type Event struct {
+	// Seq orders events. It's only meaningful for the indexer the event was
+	// retrieved from.
+	Seq      int64  `json:"seq"`
+	Kind     string `json:"kind"`
+	Manifest string `json:"manifest_hash"`
+	// ErrorClass and Error are only set for Failed events.
+	ErrorClass string `json:"error_class,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// EndOfLife is only set for EndOfLife events.
+	EndOfLife []eol.Notice `json:"end_of_life,omitempty"`
+	Time      time.Time    `json:"time"`
+}
+
+// Source returns index events in order.
+type Source interface {
+	// IndexEvents returns up to limit events with a sequence number greater
+	// than after.
+	IndexEvents(ctx context.Context, after int64, limit int) ([]Event, error)
+}
+
+// Recorder wraps an indexer.Service and records an event when indexing a
+// manifest starts and when it finishes or fails.
+type Recorder struct {
+	indexer.Service
+	pool *pgxpool.Pool
+	// EOL, if set, is used to record an EndOfLife event after a manifest
+	// with end of life distributions finishes indexing.
+	EOL eol.Checker
+}
+
+var (
+	_ indexer.Service = (*Recorder)(nil)
+	_ Source          = (*Recorder)(nil)
+)
+
+// NewRecorder returns a Recorder keeping events in the database behind pool,
+// which must be the indexer's database.
+func NewRecorder(s indexer.Service, pool *pgxpool.Pool) *Recorder {
+	return &Recorder{
+		Service: s,
+		pool:    pool,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (r *Recorder) Unwrap() indexer.Service {
+	return r.Service
+}
+
+// Index implements indexer.Indexer.
+//
+// Failing to record an event is logged and otherwise ignored.
+func (r *Recorder) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	r.record(ctx, &Event{Kind: Started, Manifest: m.Hash.String()})
+	ir, err := r.Service.Index(ctx, m)
+	ev := Event{Kind: Finished, Manifest: m.Hash.String()}
+	switch {
+	case err != nil:
+		ev.Kind, ev.ErrorClass, ev.Error = Failed, classify(ctx, err), err.Error()
+	case ir != nil && !ir.Success:
+		ev.Kind, ev.ErrorClass, ev.Error = Failed, ClassIndex, ir.Err
+	}
+	// The request's context may be done by now, but the event should still
+	// be recorded.
+	rctx, done := context.WithTimeout(context.Background(), 10*time.Second)
+	defer done()
+	rctx = zerolog.Ctx(ctx).WithContext(rctx)
+	r.record(rctx, &ev)
+	if ev.Kind == Finished && r.EOL != nil && ir != nil {
+		if ns := r.EOL.EndOfLife(ir.Distributions); len(ns) != 0 {
+			r.record(rctx, &Event{Kind: EndOfLife, Manifest: ev.Manifest, EndOfLife: ns})
+		}
+	}
+	return ir, err
+}
+
+// Classify returns the error class of an error from Index.
+func classify(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	case errors.Is(err, signature.ErrRejected):
+		return ClassRejected
+	}
+	// Libindex doesn't always wrap the context's error.
+	switch ctx.Err() {
+	case context.Canceled:
+		return ClassCanceled
+	case context.DeadlineExceeded:
+		return ClassTimeout
+	}
+	return ClassIndex
+}
+
+func (r *Recorder) record(ctx context.Context, ev *Event) {
+	const insert = `INSERT INTO index_event (kind, manifest_hash, error_class, error, end_of_life) VALUES ($1, $2, $3, $4, $5);`
+	var notices []byte
+	if len(ev.EndOfLife) != 0 {
+		// Can't fail: Notices are only strings.
+		notices, _ = json.Marshal(ev.EndOfLife)
+	}
+	if _, err := r.pool.Exec(ctx, insert, ev.Kind, ev.Manifest, ev.ErrorClass, ev.Error, notices); err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "indexer/events/Recorder.record").
+			Str("manifest", ev.Manifest).
+			Str("kind", ev.Kind).
+			Err(err).
+			Msg("failed to record index event")
+	}
+}
+
+// IndexEvents implements Source.
+//
+// Sequence numbers are handed out when the row is written, not when it
+// commits, so an event may become visible after one with a greater number.
+// Events written in the last few seconds are held back to give their
+// neighbours time to commit.
+func (r *Recorder) IndexEvents(ctx context.Context, after int64, limit int) ([]Event, error) {
+	const query = `SELECT seq, kind, manifest_hash, error_class, error, end_of_life, ts FROM index_event
+WHERE seq > $1 AND ts < clock_timestamp() - '5 seconds'::interval
+ORDER BY seq
+LIMIT $2`
+	rows, err := r.pool.Query(ctx, query, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("events: failed to select events: %w", err)
+	}
+	defer rows.Close()
+	out := []Event{}
+	for rows.Next() {
+		var ev Event
+		var notices []byte
+		if err := rows.Scan(&ev.Seq, &ev.Kind, &ev.Manifest, &ev.ErrorClass, &ev.Error, &notices, &ev.Time); err != nil {
+			return nil, fmt.Errorf("events: failed to scan event: %w", err)
+		}
+		if notices != nil {
+			if err := json.Unmarshal(notices, &ev.EndOfLife); err != nil {
+				return nil, fmt.Errorf("events: failed to decode event: %w", err)
+			}
+		}
+		out = append(out, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("events: failed to select events: %w", err)
+	}
+	return out, nil
+}
+
+// Prune deletes events recorded before the provided time.
+func (r *Recorder) Prune(ctx context.Context, before time.Time) (int64, error) {
+	const del = `DELETE FROM index_event WHERE ts < $1;`
+	tag, err := r.pool.Exec(ctx, del, before)
+	if err != nil {
+		return 0, fmt.Errorf("events: failed to prune events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Run prunes events older than the retention every interval, until the
+// context is canceled.
+func (r *Recorder) Run(ctx context.Context, retention, interval time.Duration) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/events/Recorder.Run").
+		Logger()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		n, err := r.Prune(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to prune index events")
+			continue
+		}
+		log.Debug().Int64("count", n).Msg("pruned index events")
+	}
+}
@@ -0,0 +1,20 @@
+package migrations
+
+const (
+	// migration1 adds a table of index lifecycle events, so the notifier can
+	// relay them.
+	migration1 = `
+	--- a relation recording when manifests started and finished indexing,
+	--- in the order it happened
+	CREATE TABLE IF NOT EXISTS index_event
+	(
+		seq           bigserial PRIMARY KEY,
+		kind          text NOT NULL,
+		manifest_hash text NOT NULL,
+		error_class   text NOT NULL DEFAULT '',
+		error         text NOT NULL DEFAULT '',
+		ts            timestamptz NOT NULL DEFAULT clock_timestamp()
+	);
+	CREATE INDEX IF NOT EXISTS index_event_ts_idx ON index_event (ts);
+	`
+)
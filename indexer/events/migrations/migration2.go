@@ -0,0 +1,9 @@
+package migrations
+
+const (
+	// migration2 adds the end of life distributions reported by EndOfLife
+	// events.
+	migration2 = `
+	ALTER TABLE index_event ADD COLUMN IF NOT EXISTS end_of_life jsonb;
+	`
+)
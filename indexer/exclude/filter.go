@@ -0,0 +1,150 @@
+// Package exclude removes packages matching configured rules from index
+// reports.
+package exclude
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Filter removes packages from index reports by where they were found or by
+// name.
+type Filter struct {
+	paths    []rule
+	packages []rule
+}
+
+type rule struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// NewFilter returns a Filter excluding packages found in package databases
+// matching any of the path globs, and packages with names matching any of
+// the name globs.
+//
+// In globs, "*" matches within a path segment, "**" matches across
+// segments, and "?" matches a single character. Paths are relative to the
+// root of the image, so a leading "/" is ignored.
+func NewFilter(paths, packages []string) (*Filter, error) {
+	var f Filter
+	for _, p := range paths {
+		re, err := compile(strings.TrimPrefix(p, "/"))
+		if err != nil {
+			return nil, err
+		}
+		f.paths = append(f.paths, rule{pattern: p, re: re})
+	}
+	for _, p := range packages {
+		re, err := compile(p)
+		if err != nil {
+			return nil, err
+		}
+		f.packages = append(f.packages, rule{pattern: p, re: re})
+	}
+	return &f, nil
+}
+
+// Compile turns a glob into an anchored regular expression.
+func compile(glob string) (*regexp.Regexp, error) {
+	if glob == "" {
+		return nil, fmt.Errorf("exclude: empty pattern")
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("exclude: bad pattern %q: %w", glob, err)
+	}
+	return re, nil
+}
+
+// Exclusion records a package removed from an index report.
+type Exclusion struct {
+	Package   string `json:"package"`
+	Version   string `json:"version"`
+	PackageDB string `json:"package_db,omitempty"`
+	// Rule is the pattern that matched.
+	Rule string `json:"rule"`
+}
+
+// Apply returns a copy of the report without the excluded packages, and what
+// was removed. The report itself isn't modified.
+//
+// A package found in several package databases is only removed if all of
+// them are excluded; otherwise only the excluded environments are.
+func (f *Filter) Apply(ir *claircore.IndexReport) (*claircore.IndexReport, []Exclusion) {
+	if ir == nil || (len(f.paths) == 0 && len(f.packages) == 0) {
+		return ir, nil
+	}
+	out := *ir
+	out.Packages = make(map[string]*claircore.Package, len(ir.Packages))
+	out.Environments = make(map[string][]*claircore.Environment, len(ir.Environments))
+	var ex []Exclusion
+	for id, pkg := range ir.Packages {
+		if r, ok := match(f.packages, pkg.Name); ok {
+			ex = append(ex, Exclusion{Package: pkg.Name, Version: pkg.Version, Rule: r})
+			continue
+		}
+		envs := ir.Environments[id]
+		kept := envs
+		if len(f.paths) != 0 {
+			kept = make([]*claircore.Environment, 0, len(envs))
+			for _, env := range envs {
+				if r, ok := match(f.paths, strings.TrimPrefix(env.PackageDB, "/")); ok {
+					ex = append(ex, Exclusion{
+						Package:   pkg.Name,
+						Version:   pkg.Version,
+						PackageDB: env.PackageDB,
+						Rule:      r,
+					})
+					continue
+				}
+				kept = append(kept, env)
+			}
+		}
+		if len(envs) != 0 && len(kept) == 0 {
+			continue
+		}
+		out.Packages[id] = pkg
+		if envs != nil {
+			out.Environments[id] = kept
+		}
+	}
+	return &out, ex
+}
+
+// Excludes reports whether packages with the name are excluded.
+func (f *Filter) Excludes(name string) bool {
+	_, ok := match(f.packages, name)
+	return ok
+}
+
+func match(rs []rule, s string) (string, bool) {
+	for _, r := range rs {
+		if r.re.MatchString(s) {
+			return r.pattern, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,167 @@
+package exclude
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+func TestGlob(t *testing.T) {
+	tt := []struct {
+		glob  string
+		match []string
+		miss  []string
+	}{
+		{
+			glob:  "**/testdata/**",
+			match: []string{"testdata/a", "src/app/testdata/go.sum", "a/testdata/b/c"},
+			miss:  []string{"src/testdata", "src/mytestdata/a"},
+		},
+		{
+			glob:  "usr/share/*/requirements.txt",
+			match: []string{"usr/share/app/requirements.txt"},
+			miss:  []string{"usr/share/a/b/requirements.txt", "usr/share/requirements.txt"},
+		},
+		{
+			glob:  "python?-test*",
+			match: []string{"python3-testtools", "python2-test"},
+			miss:  []string{"python-test", "python3-mock"},
+		},
+		{
+			glob:  "lib.so(1)",
+			match: []string{"lib.so(1)"},
+			miss:  []string{"libxso1"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.glob, func(t *testing.T) {
+			re, err := compile(tc.glob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tc.match {
+				if !re.MatchString(s) {
+					t.Errorf("%q didn't match %q", tc.glob, s)
+				}
+			}
+			for _, s := range tc.miss {
+				if re.MatchString(s) {
+					t.Errorf("%q matched %q", tc.glob, s)
+				}
+			}
+		})
+	}
+}
+
+func testReport() *claircore.IndexReport {
+	env := func(db string) *claircore.Environment {
+		return &claircore.Environment{PackageDB: db}
+	}
+	return &claircore.IndexReport{
+		Hash: claircore.MustParseDigest("sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1"},
+			"2": {ID: "2", Name: "requests", Version: "2.0.0"},
+			"3": {ID: "3", Name: "urllib3", Version: "1.26.0"},
+			"4": {ID: "4", Name: "pytest", Version: "6.2.0"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {env("var/lib/dpkg/status")},
+			"2": {env("app/tests/fixtures/site-packages")},
+			"3": {env("app/tests/fixtures/site-packages"), env("usr/lib/python3/site-packages")},
+			"4": {env("usr/lib/python3/site-packages")},
+		},
+		Success: true,
+	}
+}
+
+func TestApply(t *testing.T) {
+	f, err := NewFilter([]string{"/**/fixtures/**"}, []string{"py*test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir := testReport()
+	got, ex := f.Apply(ir)
+
+	var ids []string
+	for id := range got.Packages {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if got, want := ids, []string{"1", "3"}; !cmp.Equal(got, want) {
+		t.Errorf("packages: got: %v, want: %v", got, want)
+	}
+	if envs := got.Environments["3"]; len(envs) != 1 || envs[0].PackageDB != "usr/lib/python3/site-packages" {
+		t.Errorf("unexpected environments: %v", envs)
+	}
+	if _, ok := got.Environments["2"]; ok {
+		t.Error("environments for excluded package kept")
+	}
+	if got, want := len(ex), 3; got != want {
+		t.Errorf("exclusions: got: %d, want: %d (%+v)", got, want, ex)
+	}
+	for _, e := range ex {
+		switch e.Package {
+		case "pytest":
+			if e.Rule != "py*test" || e.PackageDB != "" {
+				t.Errorf("unexpected exclusion: %+v", e)
+			}
+		case "requests", "urllib3":
+			if e.Rule != "/**/fixtures/**" || e.PackageDB != "app/tests/fixtures/site-packages" {
+				t.Errorf("unexpected exclusion: %+v", e)
+			}
+		default:
+			t.Errorf("unexpected exclusion: %+v", e)
+		}
+	}
+
+	// The original is left alone.
+	if got, want := len(ir.Packages), 4; got != want {
+		t.Errorf("original packages: got: %d, want: %d", got, want)
+	}
+	if got, want := len(ir.Environments["3"]), 2; got != want {
+		t.Errorf("original environments: got: %d, want: %d", got, want)
+	}
+}
+
+func TestBadPattern(t *testing.T) {
+	if _, err := NewFilter([]string{""}, nil); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestAffectedManifests(t *testing.T) {
+	ctx := context.Background()
+	f, err := NewFilter(nil, []string{"pytest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := NewIndexer(&indexer.Mock{
+		AffectedManifests_: func(ctx context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+			a := claircore.NewAffectedManifests()
+			a.Vulnerabilities["1"] = &claircore.Vulnerability{ID: "1", Package: &claircore.Package{Name: "pytest"}}
+			a.Vulnerabilities["2"] = &claircore.Vulnerability{ID: "2", Package: &claircore.Package{Name: "openssl"}}
+			a.VulnerableManifests["sha256:a"] = []string{"1"}
+			a.VulnerableManifests["sha256:b"] = []string{"1", "2"}
+			return &a, nil
+		},
+	}, f)
+	a, err := i.AffectedManifests(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.Vulnerabilities["1"]; ok {
+		t.Error("excluded vulnerability kept")
+	}
+	if _, ok := a.VulnerableManifests["sha256:a"]; ok {
+		t.Error("manifest only affected by excluded package kept")
+	}
+	if got, want := a.VulnerableManifests["sha256:b"], []string{"2"}; !cmp.Equal(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package exclude
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Indexer wraps an indexer.Service and removes excluded packages from the
+// index reports it returns.
+//
+// Manifests are still scanned in full and stored unfiltered, so changing the
+// rules takes effect without reindexing.
+type Indexer struct {
+	indexer.Service
+	filter *Filter
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer using the provided Filter.
+func NewIndexer(s indexer.Service, f *Filter) *Indexer {
+	return &Indexer{
+		Service: s,
+		filter:  f,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	ir, err := i.Service.Index(ctx, m)
+	if err != nil {
+		return ir, err
+	}
+	ir, ex := i.filter.Apply(ir)
+	if len(ex) != 0 {
+		zerolog.Ctx(ctx).Debug().
+			Str("component", "indexer/exclude/Indexer.Index").
+			Str("manifest", m.Hash.String()).
+			Int("count", len(ex)).
+			Msg("excluded packages from index report")
+	}
+	return ir, nil
+}
+
+// IndexReport implements indexer.Reporter.
+func (i *Indexer) IndexReport(ctx context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	ir, ok, err := i.Service.IndexReport(ctx, d)
+	if err != nil || !ok {
+		return ir, ok, err
+	}
+	ir, _ = i.filter.Apply(ir)
+	return ir, true, nil
+}
+
+// Excluded returns what was removed from the manifest's index report, or nil
+// if there's no report.
+func (i *Indexer) Excluded(ctx context.Context, d claircore.Digest) ([]Exclusion, error) {
+	ir, ok, err := i.Service.IndexReport(ctx, d)
+	if err != nil || !ok {
+		return nil, err
+	}
+	_, ex := i.filter.Apply(ir)
+	return ex, nil
+}
+
+// AffectedManifests implements indexer.Affected.
+//
+// Vulnerabilities in packages excluded by name are dropped. The package
+// database isn't known here, so path rules don't apply.
+func (i *Indexer) AffectedManifests(ctx context.Context, vulns []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+	a, err := i.Service.AffectedManifests(ctx, vulns)
+	if err != nil || a == nil {
+		return a, err
+	}
+	drop := make(map[string]bool)
+	for id, v := range a.Vulnerabilities {
+		if v.Package != nil && i.filter.Excludes(v.Package.Name) {
+			drop[id] = true
+		}
+	}
+	if len(drop) == 0 {
+		return a, nil
+	}
+	out := claircore.NewAffectedManifests()
+	for id, v := range a.Vulnerabilities {
+		if !drop[id] {
+			out.Vulnerabilities[id] = v
+		}
+	}
+	for m, ids := range a.VulnerableManifests {
+		var kept []string
+		for _, id := range ids {
+			if !drop[id] {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) != 0 {
+			out.VulnerableManifests[m] = kept
+		}
+	}
+	return &out, nil
+}
@@ -0,0 +1,331 @@
+// Package gc implements garbage collection of index reports.
+//
+// Claircore doesn't record when a manifest was last used, so the Tracker
+// records accesses in a side table and the Collector removes manifests (and
+// then layers) nobody has asked about recently.
+package gc
+
+import (
+	"context"
+	"hash/fnv"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// DefaultInterval is the default time between collection runs.
+	DefaultInterval = 6 * time.Hour
+	// DefaultBatchSize is the default number of manifests or layers deleted
+	// in a single transaction.
+	DefaultBatchSize = 500
+)
+
+// Opts configures a Collector.
+type Opts struct {
+	// Interval is the time between collection runs.
+	Interval time.Duration
+	// MaxAge is how long a manifest may go unused before it's collected.
+	// Zero disables age-based collection.
+	MaxAge time.Duration
+	// MaxReports is the number of most recently used manifests to keep per
+	// repository. Manifests from an unknown repository count as one
+	// repository. Zero disables count-based collection.
+	MaxReports int
+	// BatchSize is the number of rows deleted per transaction.
+	BatchSize int
+}
+
+// Collector periodically deletes stale manifests and orphaned layers from
+// the indexer database.
+type Collector struct {
+	pool *pgxpool.Pool
+	opts Opts
+
+	manifests metric.Int64Counter
+	layers    metric.Int64Counter
+}
+
+// NewCollector returns a Collector working against the database behind pool,
+// which must be the indexer's database.
+func NewCollector(pool *pgxpool.Pool, opts Opts) *Collector {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	meter := metric.Must(otel.Meter("clair"))
+	return &Collector{
+		pool: pool,
+		opts: opts,
+		manifests: meter.NewInt64Counter(
+			"clair_indexer_gc_manifests_deleted",
+			metric.WithDescription("number of manifests removed by index report garbage collection"),
+		),
+		layers: meter.NewInt64Counter(
+			"clair_indexer_gc_layers_deleted",
+			metric.WithDescription("number of layers removed by index report garbage collection"),
+		),
+	}
+}
+
+// Run collects on the configured interval until the context is canceled.
+func (c *Collector) Run(ctx context.Context) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/gc/Collector.Run").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().
+		Str("interval", c.opts.Interval.String()).
+		Str("max_age", c.opts.MaxAge.String()).
+		Int("max_reports", c.opts.MaxReports).
+		Msg("starting index report gc")
+
+	t := time.NewTicker(c.opts.Interval)
+	defer t.Stop()
+	for {
+		ms, ls, err := c.CollectAll(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("index report gc failed")
+		}
+		log.Info().
+			Int64("manifests", ms).
+			Int64("layers", ls).
+			Msg("index report gc done")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// CollectAll calls Collect until there's nothing left to collect, reporting
+// how many manifests and layers were removed in total.
+func (c *Collector) CollectAll(ctx context.Context) (manifests, layers int64, err error) {
+	for {
+		m, l, err := c.Collect(ctx)
+		if err != nil {
+			return manifests, layers, err
+		}
+		manifests, layers = manifests+m, layers+l
+		if m < int64(c.opts.BatchSize) && l < int64(c.opts.BatchSize) {
+			return manifests, layers, nil
+		}
+	}
+}
+
+// LockKey is the advisory lock taken by collection transactions, so that
+// multiple indexers don't collect at the same time.
+var lockKey = func() int64 {
+	h := fnv.New64a()
+	io.WriteString(h, "clair-indexer-gc")
+	return int64(h.Sum64())
+}()
+
+const (
+	tryLock = `SELECT pg_try_advisory_xact_lock($1);`
+	// Manifests indexed before tracking was enabled have no access record;
+	// start their clock now. Also drop records for manifests that no longer
+	// exist, so they don't count against MaxReports.
+	seedAccess = `
+INSERT INTO manifest_access (manifest_hash, last_seen)
+SELECT hash, now() FROM manifest
+ON CONFLICT (manifest_hash) DO NOTHING;`
+	pruneAccess = `
+DELETE FROM manifest_access a
+WHERE NOT EXISTS (SELECT 1 FROM manifest m WHERE m.hash = a.manifest_hash);`
+	selectManifests = `
+SELECT m.id, m.hash
+FROM manifest m
+JOIN (
+	SELECT manifest_hash, last_seen,
+		row_number() OVER (PARTITION BY repository ORDER BY last_seen DESC) AS n
+	FROM manifest_access
+) a ON a.manifest_hash = m.hash
+WHERE ($1::timestamptz IS NOT NULL AND a.last_seen < $1::timestamptz)
+	OR ($2::bigint > 0 AND a.n > $2::bigint)
+ORDER BY a.last_seen
+LIMIT $3;`
+	selectLayers = `
+SELECT l.id, l.hash
+FROM layer l
+WHERE NOT EXISTS (SELECT 1 FROM manifest_layer ml WHERE ml.layer_id = l.id)
+LIMIT $1;`
+	// TableExists reports whether the table exists. Tables for optional
+	// features may, even if the feature isn't configured now.
+	tableExists = `SELECT to_regclass($1) IS NOT NULL;`
+)
+
+// Deletion is a statement removing rows referencing manifests or layers.
+// Statements take either the ids or, if byHash is set, the hashes.
+//
+// Statements for tables created by optional features name the table, and are
+// skipped if it doesn't exist.
+type deletion struct {
+	q      string
+	byHash bool
+	table  string
+}
+
+// DeleteManifests removes every row referencing the manifests, then the
+// manifests.
+var deleteManifests = []deletion{
+	{q: `DELETE FROM indexreport WHERE manifest_id = ANY($1);`},
+	{q: `DELETE FROM manifest_index WHERE manifest_id = ANY($1);`},
+	{q: `DELETE FROM manifest_layer WHERE manifest_id = ANY($1);`},
+	{q: `DELETE FROM scanned_manifest WHERE manifest_id = ANY($1);`},
+	{q: `DELETE FROM scannerlist WHERE manifest_hash = ANY($1);`, byHash: true},
+	{q: `DELETE FROM manifest_access WHERE manifest_hash = ANY($1);`, byHash: true, table: "manifest_access"},
+	{q: `DELETE FROM manifest_signature WHERE manifest_hash = ANY($1);`, byHash: true, table: "manifest_signature"},
+	{q: `DELETE FROM content_scan WHERE manifest_hash = ANY($1);`, byHash: true, table: "content_scan"},
+	{q: `DELETE FROM manifest_referrers WHERE manifest_hash = ANY($1);`, byHash: true, table: "manifest_referrers"},
+	{q: `DELETE FROM manifest_inventory WHERE manifest_hash = ANY($1);`, byHash: true, table: "manifest_inventory"},
+	{q: `DELETE FROM manifest_annotations WHERE manifest_hash = ANY($1);`, byHash: true, table: "manifest_annotations"},
+	{q: `DELETE FROM reindex_manifest WHERE manifest_hash = ANY($1);`, byHash: true, table: "reindex_manifest"},
+	{q: `DELETE FROM tenant_manifest WHERE manifest_hash = ANY($1);`, byHash: true, table: "tenant_manifest"},
+	{q: `DELETE FROM manifest WHERE id = ANY($1);`},
+}
+
+// DeleteLayers removes every row referencing the layers, then the layers.
+var deleteLayers = []deletion{
+	{q: `DELETE FROM scanned_layer WHERE layer_id = ANY($1);`},
+	{q: `DELETE FROM package_scanartifact WHERE layer_id = ANY($1);`},
+	{q: `DELETE FROM dist_scanartifact WHERE layer_id = ANY($1);`},
+	{q: `DELETE FROM repo_scanartifact WHERE layer_id = ANY($1);`},
+	{q: `DELETE FROM layer_budget WHERE layer_hash = ANY($1);`, byHash: true, table: "layer_budget"},
+	{q: `DELETE FROM layer WHERE id = ANY($1);`},
+}
+
+// Collect deletes up to one batch of stale manifests and one batch of
+// orphaned layers, reporting how many of each were removed.
+//
+// If another process is collecting, Collect does nothing.
+func (c *Collector) Collect(ctx context.Context) (manifests, layers int64, err error) {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var ok bool
+	if err := tx.QueryRow(ctx, tryLock, lockKey).Scan(&ok); err != nil {
+		return 0, 0, err
+	}
+	if !ok {
+		zerolog.Ctx(ctx).Debug().Msg("another process is collecting")
+		return 0, 0, nil
+	}
+	if _, err := tx.Exec(ctx, seedAccess); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.Exec(ctx, pruneAccess); err != nil {
+		return 0, 0, err
+	}
+
+	var cutoff *time.Time
+	if c.opts.MaxAge > 0 {
+		t := time.Now().Add(-c.opts.MaxAge)
+		cutoff = &t
+	}
+	var ids []int64
+	var hashes []string
+	rows, err := tx.Query(ctx, selectManifests, cutoff, c.opts.MaxReports, c.opts.BatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		ids = append(ids, id)
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	if err := removeManifests(ctx, tx, ids, hashes); err != nil {
+		return 0, 0, err
+	}
+
+	// Layers are only considered after manifests are gone, so a single run
+	// cleans up after the manifests it removed.
+	manifests = int64(len(hashes))
+	ids, hashes = ids[:0], hashes[:0]
+	rows, err = tx.Query(ctx, selectLayers, c.opts.BatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		ids = append(ids, id)
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	if err := removeLayers(ctx, tx, ids, hashes); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+	layers = int64(len(ids))
+	c.manifests.Add(ctx, manifests)
+	c.layers.Add(ctx, layers)
+	return manifests, layers, nil
+}
+
+// RemoveManifests runs deleteManifests for the manifests.
+func removeManifests(ctx context.Context, tx pgx.Tx, ids []int64, hashes []string) error {
+	return remove(ctx, tx, deleteManifests, ids, hashes)
+}
+
+// RemoveLayers runs deleteLayers for the layers.
+func removeLayers(ctx context.Context, tx pgx.Tx, ids []int64, hashes []string) error {
+	return remove(ctx, tx, deleteLayers, ids, hashes)
+}
+
+// Remove runs the deletions for the rows with the provided ids and hashes,
+// which must be in the same order.
+func remove(ctx context.Context, tx pgx.Tx, ds []deletion, ids []int64, hashes []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, d := range ds {
+		if d.table != "" {
+			var ok bool
+			if err := tx.QueryRow(ctx, tableExists, d.table).Scan(&ok); err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		var arg interface{} = ids
+		if d.byHash {
+			arg = hashes
+		}
+		if _, err := tx.Exec(ctx, d.q, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/quay/claircore"
+	libmigrations "github.com/quay/claircore/libindex/migrations"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+	"github.com/remind101/migrate"
+
+	budgetmigrations "github.com/quay/clair/v4/indexer/budget/migrations"
+	"github.com/quay/clair/v4/indexer/gc/migrations"
+	signaturemigrations "github.com/quay/clair/v4/indexer/signature/migrations"
+)
+
+func testPool(ctx context.Context, t *testing.T) (*pgxpool.Pool, func()) {
+	if os.Getenv(integration.EnvPGConnString) == "" {
+		os.Setenv(integration.EnvPGConnString, `host=localhost port=5432 user=clair dbname=clair sslmode=disable`)
+	}
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, db.Config())
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	sdb := stdlib.OpenDB(*db.Config().ConnConfig)
+	defer sdb.Close()
+	for _, m := range []struct {
+		table string
+		ms    []migrate.Migration
+	}{
+		{libmigrations.MigrationTable, libmigrations.Migrations},
+		{migrations.MigrationTable, migrations.Migrations},
+		// Tables for a couple of optional features, to check that rows
+		// referencing collected manifests and layers go with them. The
+		// rest are left out, to check that missing tables are skipped.
+		{signaturemigrations.MigrationTable, signaturemigrations.Migrations},
+		{budgetmigrations.MigrationTable, budgetmigrations.Migrations},
+	} {
+		migrator := migrate.NewPostgresMigrator(sdb)
+		migrator.Table = m.table
+		if err := migrator.Exec(migrate.Up, m.ms...); err != nil {
+			t.Fatalf("failed to perform migrations: %v", err)
+		}
+	}
+	return pool, func() {
+		pool.Close()
+		db.Close(ctx, t)
+	}
+}
+
+// Insert adds a manifest with a single layer and an index report.
+func insert(ctx context.Context, t *testing.T, pool *pgxpool.Pool, manifest, layer string) {
+	for _, q := range []struct {
+		sql  string
+		args []interface{}
+	}{
+		{`INSERT INTO manifest (hash) VALUES ($1) ON CONFLICT DO NOTHING;`, []interface{}{manifest}},
+		{`INSERT INTO layer (hash) VALUES ($1) ON CONFLICT DO NOTHING;`, []interface{}{layer}},
+		{`INSERT INTO manifest_layer (manifest_id, layer_id, i)
+		SELECT m.id, l.id, 0 FROM manifest m, layer l WHERE m.hash = $1 AND l.hash = $2;`, []interface{}{manifest, layer}},
+		{`INSERT INTO indexreport (manifest_id, state, scan_result)
+		SELECT id, 'IndexFinished', '{}' FROM manifest WHERE hash = $1;`, []interface{}{manifest}},
+	} {
+		if _, err := pool.Exec(ctx, q.sql, q.args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func digest(t *testing.T, i int) claircore.Digest {
+	d, err := claircore.ParseDigest(fmt.Sprintf("sha256:%064x", i))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func count(ctx context.Context, t *testing.T, pool *pgxpool.Pool, table string) (n int) {
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM `+table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCollect(t *testing.T) {
+	integration.Skip(t)
+	ctx := zlog.Test(context.Background(), t)
+
+	t.Run("MaxAge", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		pool, cleanup := testPool(ctx, t)
+		defer cleanup()
+		tr := NewTracker(nil, pool)
+		// Two manifests sharing a layer, and one with its own.
+		old, fresh, lonely := digest(t, 1), digest(t, 2), digest(t, 3)
+		insert(ctx, t, pool, old.String(), "sha256:shared")
+		insert(ctx, t, pool, fresh.String(), "sha256:shared")
+		insert(ctx, t, pool, lonely.String(), "sha256:lonely")
+		tr.touch(ctx, fresh, "")
+		if _, err := pool.Exec(ctx, `INSERT INTO manifest_access VALUES ($1, now() - '2 days'::interval), ($2, now() - '2 days'::interval);`,
+			old.String(), lonely.String()); err != nil {
+			t.Fatal(err)
+		}
+
+		c := NewCollector(pool, Opts{MaxAge: 24 * time.Hour})
+		ms, ls, err := c.Collect(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ms, int64(2); got != want {
+			t.Errorf("manifests: got: %d, want: %d", got, want)
+		}
+		if got, want := ls, int64(1); got != want {
+			t.Errorf("layers: got: %d, want: %d", got, want)
+		}
+		if got, want := count(ctx, t, pool, "indexreport"), 1; got != want {
+			t.Errorf("indexreports: got: %d, want: %d", got, want)
+		}
+		if got, want := count(ctx, t, pool, "layer"), 1; got != want {
+			t.Errorf("layers remaining: got: %d, want: %d", got, want)
+		}
+	})
+
+	t.Run("MaxReports", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		pool, cleanup := testPool(ctx, t)
+		defer cleanup()
+		tr := NewTracker(nil, pool)
+		// Five manifests from one repository, and two from another.
+		for i := 0; i < 7; i++ {
+			repo := "quay.io/org/app"
+			if i >= 5 {
+				repo = "quay.io/org/other"
+			}
+			d := digest(t, i)
+			insert(ctx, t, pool, d.String(), d.String())
+			tr.touch(ctx, d, repo)
+		}
+
+		c := NewCollector(pool, Opts{MaxReports: 3})
+		ms, _, err := c.Collect(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ms, int64(2); got != want {
+			t.Errorf("manifests: got: %d, want: %d", got, want)
+		}
+		if got, want := count(ctx, t, pool, "manifest"), 5; got != want {
+			t.Errorf("manifests remaining: got: %d, want: %d", got, want)
+		}
+	})
+}
+
+func TestDelete(t *testing.T) {
+	integration.Skip(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool, cleanup := testPool(ctx, t)
+	defer cleanup()
+	// Two manifests sharing a layer, and one with its own.
+	a, b, c := digest(t, 1), digest(t, 2), digest(t, 3)
+	insert(ctx, t, pool, a.String(), "sha256:shared")
+	insert(ctx, t, pool, b.String(), "sha256:shared")
+	insert(ctx, t, pool, c.String(), "sha256:lonely")
+
+	for _, d := range []claircore.Digest{a, b, c} {
+		if _, err := pool.Exec(ctx, `INSERT INTO manifest_signature (manifest_hash, status, checked) VALUES ($1, 'verified', now());`, d.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []string{"sha256:shared", "sha256:lonely"} {
+		if _, err := pool.Exec(ctx, `INSERT INTO layer_budget (layer_hash, max_files, max_size, files, size, checked) VALUES ($1, 1, 1, 0, 0, now());`, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, ls, err := Delete(ctx, pool, a, c, digest(t, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(deleted), 2; got != want {
+		t.Errorf("manifests: got: %d, want: %d", got, want)
+	}
+	if got, want := ls, int64(1); got != want {
+		t.Errorf("layers: got: %d, want: %d", got, want)
+	}
+	if got, want := count(ctx, t, pool, "manifest"), 1; got != want {
+		t.Errorf("manifests remaining: got: %d, want: %d", got, want)
+	}
+	if got, want := count(ctx, t, pool, "layer"), 1; got != want {
+		t.Errorf("layers remaining: got: %d, want: %d", got, want)
+	}
+	if got, want := count(ctx, t, pool, "manifest_signature"), 1; got != want {
+		t.Errorf("signatures remaining: got: %d, want: %d", got, want)
+	}
+	if got, want := count(ctx, t, pool, "layer_budget"), 1; got != want {
+		t.Errorf("budgets remaining: got: %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package gc
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+)
+
+const (
+	selectByHash     = `SELECT id, hash FROM manifest WHERE hash = ANY($1);`
+	selectLayersUsed = `SELECT DISTINCT layer_id FROM manifest_layer WHERE manifest_id = ANY($1);`
+	selectOrphaned   = `
+SELECT l.id, l.hash
+FROM layer l
+WHERE l.id = ANY($1)
+	AND NOT EXISTS (SELECT 1 FROM manifest_layer ml WHERE ml.layer_id = l.id);`
+)
+
+// Delete removes the manifests with the provided digests, along with their
+// index reports and any of their layers no other manifest uses. Unknown
+// digests are ignored.
+//
+// Delete works against the database behind pool, which must be the indexer's
+// database, and doesn't need a Collector to be configured.
+func Delete(ctx context.Context, pool *pgxpool.Pool, ds ...claircore.Digest) (deleted []claircore.Digest, layers int64, err error) {
+	want := make([]string, len(ds))
+	for i, d := range ds {
+		want[i] = d.String()
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var ids []int64
+	var hashes []string
+	rows, err := tx.Query(ctx, selectByHash, want)
+	if err != nil {
+		return nil, 0, err
+	}
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		ids = append(ids, id)
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return nil, 0, nil
+	}
+
+	var lids []int64
+	rows, err = tx.Query(ctx, selectLayersUsed, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		lids = append(lids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := removeManifests(ctx, tx, ids, hashes); err != nil {
+		return nil, 0, err
+	}
+
+	// Only now can it be known which of the layers are unused.
+	var orphans []int64
+	var orphanHashes []string
+	rows, err = tx.Query(ctx, selectOrphaned, lids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		orphans = append(orphans, id)
+		orphanHashes = append(orphanHashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := removeLayers(ctx, tx, orphans, orphanHashes); err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, err
+	}
+	deleted = make([]claircore.Digest, 0, len(hashes))
+	for _, h := range hashes {
+		d, err := claircore.ParseDigest(h)
+		if err != nil {
+			return nil, 0, err
+		}
+		deleted = append(deleted, d)
+	}
+	return deleted, int64(len(orphans)), nil
+}
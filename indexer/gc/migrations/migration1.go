@@ -0,0 +1,22 @@
+package migrations
+
+const (
+	// migration1 adds access tracking for manifests, so that stale index
+	// reports can be garbage collected.
+	migration1 = `
+	--- a relation recording the last time a manifest was indexed or its
+	--- index report was requested
+	CREATE TABLE IF NOT EXISTS manifest_access
+	(
+		manifest_hash text PRIMARY KEY,
+		last_seen     timestamptz NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS manifest_access_last_seen_idx ON manifest_access (last_seen);
+	`
+	// migration2 records the repository manifests were fetched from, so that
+	// max_reports applies per repository.
+	migration2 = `
+	ALTER TABLE manifest_access ADD COLUMN IF NOT EXISTS repository text;
+	CREATE INDEX IF NOT EXISTS manifest_access_repository_idx ON manifest_access (repository, last_seen);
+	`
+)
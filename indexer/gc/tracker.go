@@ -0,0 +1,90 @@
+package gc
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Tracker wraps an indexer.Service and records when manifests are used, so
+// the Collector can tell which index reports are stale.
+type Tracker struct {
+	indexer.Service
+	pool *pgxpool.Pool
+}
+
+var _ indexer.Service = (*Tracker)(nil)
+
+// NewTracker returns a Tracker recording accesses in the database behind
+// pool, which must be the indexer's database.
+func NewTracker(s indexer.Service, pool *pgxpool.Pool) *Tracker {
+	return &Tracker{
+		Service: s,
+		pool:    pool,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (t *Tracker) Unwrap() indexer.Service {
+	return t.Service
+}
+
+const touchManifest = `
+INSERT INTO manifest_access (manifest_hash, last_seen, repository) VALUES ($1, now(), NULLIF($2, ''))
+ON CONFLICT (manifest_hash) DO UPDATE SET
+	last_seen = EXCLUDED.last_seen,
+	repository = COALESCE(EXCLUDED.repository, manifest_access.repository);`
+
+// Touch marks the manifest as used, and records the repository it was
+// fetched from if it's not empty. Failures are logged and otherwise ignored;
+// at worst a report is collected early and has to be recreated.
+func (t *Tracker) touch(ctx context.Context, d claircore.Digest, repo string) {
+	if _, err := t.pool.Exec(ctx, touchManifest, d.String(), repo); err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "indexer/gc/Tracker.touch").
+			Str("manifest", d.String()).
+			Err(err).
+			Msg("failed to record manifest access")
+	}
+}
+
+// Repository names the repository the manifest was fetched from, like
+// "quay.io/org/app", using its layers' registry blob URLs. It's empty if the
+// layers didn't come from a registry.
+func repository(m *claircore.Manifest) string {
+	for _, l := range m.Layers {
+		u, err := url.Parse(l.URI)
+		if err != nil || !strings.HasPrefix(u.Path, "/v2/") {
+			continue
+		}
+		p := strings.TrimPrefix(u.Path, "/v2/")
+		if i := strings.LastIndex(p, "/blobs/"); i > 0 {
+			return u.Host + "/" + p[:i]
+		}
+	}
+	return ""
+}
+
+// Index implements indexer.Indexer.
+//
+// The manifest is marked as used before indexing starts, so that the
+// Collector doesn't remove it out from under the indexer.
+func (t *Tracker) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	t.touch(ctx, m.Hash, repository(m))
+	return t.Service.Index(ctx, m)
+}
+
+// IndexReport implements indexer.Reporter.
+func (t *Tracker) IndexReport(ctx context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	ir, ok, err := t.Service.IndexReport(ctx, d)
+	if err == nil && ok {
+		t.touch(ctx, d, "")
+	}
+	return ir, ok, err
+}
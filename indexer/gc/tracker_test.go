@@ -0,0 +1,46 @@
+package gc
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestRepository(t *testing.T) {
+	tt := []struct {
+		name string
+		uris []string
+		want string
+	}{
+		{
+			name: "Registry",
+			uris: []string{"https://quay.io/v2/org/app/blobs/sha256:aa"},
+			want: "quay.io/org/app",
+		},
+		{
+			name: "Port",
+			uris: []string{"http://localhost:5000/v2/app/blobs/sha256:aa"},
+			want: "localhost:5000/app",
+		},
+		{
+			name: "NotRegistry",
+			uris: []string{"https://example.com/layers/sha256:aa", "file:///tmp/layer.tar"},
+		},
+		{
+			name: "SkipsNonRegistry",
+			uris: []string{"file:///tmp/layer.tar", "https://quay.io/v2/org/app/blobs/sha256:aa"},
+			want: "quay.io/org/app",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &claircore.Manifest{}
+			for _, u := range tc.uris {
+				m.Layers = append(m.Layers, &claircore.Layer{URI: u})
+			}
+			if got := repository(m); got != tc.want {
+				t.Errorf("got: %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package hook
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ClamAV is a Scanner handing layers to a clamd daemon with the INSTREAM
+// command.
+//
+// Clamd unpacks the layer's archives itself, as long as its ScanArchive
+// option is on. Layers larger than its StreamMaxLength fail to scan.
+type ClamAV struct {
+	network string
+	address string
+}
+
+var _ Scanner = (*ClamAV)(nil)
+
+// NewClamAV returns a ClamAV talking to the clamd at addr, which must be a
+// "unix" URL naming a socket, like "unix:///run/clamd.scan/clamd.sock", or a
+// "tcp" URL, like "tcp://localhost:3310".
+func NewClamAV(addr string) (*ClamAV, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "unix":
+		return &ClamAV{network: "unix", address: u.Path}, nil
+	case "tcp":
+		return &ClamAV{network: "tcp", address: u.Host}, nil
+	}
+	return nil, fmt.Errorf("unknown clamd address scheme %q", u.Scheme)
+}
+
+// ClamChunk is the size of the chunks the stream is sent in.
+const clamChunk = 32 * 1024
+
+// Scan implements Scanner.
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) ([]Finding, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("clamav: %w", err)
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	werr := c.send(conn, r)
+	// Clamd answers early and hangs up if the stream is too long, so look for
+	// a reply even if sending failed.
+	reply, rerr := bufio.NewReader(conn).ReadString(0)
+	if rerr != nil {
+		if werr != nil {
+			return nil, fmt.Errorf("clamav: %w", werr)
+		}
+		return nil, fmt.Errorf("clamav: failed to read reply: %w", rerr)
+	}
+	return parseClamReply(reply)
+}
+
+// Send writes an INSTREAM command with the contents of r.
+func (c *ClamAV) send(w io.Writer, r io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+clamChunk)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, err := w.Write(buf[:4+n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// A zero-length chunk ends the stream.
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// ParseClamReply interprets clamd's reply to INSTREAM, which is one of:
+//
+//	stream: OK
+//	stream: <signature> FOUND
+//	<message> ERROR
+func parseClamReply(reply string) ([]Finding, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	msg := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case msg == "OK":
+		return nil, nil
+	case strings.HasSuffix(msg, " FOUND"):
+		return []Finding{{Name: strings.TrimSuffix(msg, " FOUND")}}, nil
+	case strings.HasSuffix(msg, " ERROR"):
+		return nil, fmt.Errorf("clamav: %s", strings.TrimSuffix(msg, " ERROR"))
+	}
+	return nil, fmt.Errorf("clamav: unexpected reply %q", reply)
+}
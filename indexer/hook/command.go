@@ -0,0 +1,49 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Command is a Scanner running an external program for each layer.
+//
+// The layer is written to the program's standard input, and the program must
+// write a JSON array of Findings to its standard output and exit 0.
+type Command struct {
+	argv []string
+}
+
+var _ Scanner = (*Command)(nil)
+
+// NewCommand returns a Command running the program and arguments in argv.
+func NewCommand(argv []string) (*Command, error) {
+	if len(argv) == 0 || argv[0] == "" {
+		return nil, fmt.Errorf("no command provided")
+	}
+	return &Command{argv: argv}, nil
+}
+
+// Scan implements Scanner.
+func (c *Command) Scan(ctx context.Context, r io.Reader) ([]Finding, error) {
+	cmd := exec.CommandContext(ctx, c.argv[0], c.argv[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = r
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", c.argv[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", c.argv[0], err)
+	}
+	var fs []Finding
+	if err := json.Unmarshal(stdout.Bytes(), &fs); err != nil {
+		return nil, fmt.Errorf("%s: bad output: %w", c.argv[0], err)
+	}
+	return fs, nil
+}
@@ -0,0 +1,331 @@
+// Package hook runs external scanners, like ClamAV, over the contents of a
+// manifest's layers while it's indexed, and records what they find.
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Finding is something a Scanner reported in a layer.
+type Finding struct {
+	// Name is what was found, e.g. a malware signature name.
+	Name string `json:"name"`
+	// Path is the file it was found in, if the scanner reports one.
+	Path   string `json:"path,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Result is the outcome of a hook scanning a layer.
+type Result struct {
+	Layer    claircore.Digest `json:"layer"`
+	Scanner  string           `json:"scanner"`
+	Findings []Finding        `json:"findings"`
+	// Error is set if the scan didn't complete. Layers are scanned again the
+	// next time a manifest containing them is indexed.
+	Error   string    `json:"error,omitempty"`
+	Scanned time.Time `json:"scanned"`
+}
+
+// Scanner inspects layer contents.
+type Scanner interface {
+	// Scan reads a layer as it's served, usually a compressed tar, and
+	// returns what it found. It need not read all of r.
+	Scan(ctx context.Context, r io.Reader) ([]Finding, error)
+}
+
+// Hook is a named Scanner.
+type Hook struct {
+	Name    string
+	Scanner Scanner
+}
+
+// Indexer wraps an indexer.Service and runs hooks over each layer of the
+// manifests it indexes, alongside the wrapped indexer.
+//
+// Each layer is fetched once and streamed to every hook at the same time.
+// A layer a hook has already scanned cleanly in another manifest isn't
+// scanned by it again. Hook failures are recorded and don't fail indexing.
+//
+// It needs to be wrapped by anything adding credentials to layers.
+type Indexer struct {
+	indexer.Service
+	hooks   []Hook
+	pool    *pgxpool.Pool
+	client  *http.Client
+	timeout time.Duration
+	// Sem, if not nil, bounds the layers being scanned at once.
+	sem chan struct{}
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer running the provided hooks and recording
+// results in the database behind pool, which must be the indexer's database.
+//
+// Layers are fetched with the provided client, or http.DefaultClient if nil.
+// Scanning a layer is abandoned after timeout. If concurrent is positive, at
+// most that many layers are scanned at once, across all manifests.
+func NewIndexer(s indexer.Service, pool *pgxpool.Pool, c *http.Client, hooks []Hook, timeout time.Duration, concurrent int) *Indexer {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	i := &Indexer{
+		Service: s,
+		hooks:   hooks,
+		pool:    pool,
+		client:  c,
+		timeout: timeout,
+	}
+	if concurrent > 0 {
+		i.sem = make(chan struct{}, concurrent)
+	}
+	return i
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/hook/Indexer.Index").
+		Str("manifest", m.Hash.String()).
+		Logger()
+	ctx = log.WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		i.scan(ctx, m)
+	}()
+	ir, err := i.Service.Index(ctx, m)
+	<-done
+	return ir, err
+}
+
+const (
+	selectPrevious = `
+SELECT DISTINCT ON (scanner) scanner, findings, scanned FROM content_scan
+WHERE layer_hash = $1 AND error = ''
+ORDER BY scanner, scanned DESC;`
+	upsertResult = `
+INSERT INTO content_scan (manifest_hash, layer_hash, layer_index, scanner, findings, error, scanned)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (manifest_hash, layer_hash, scanner) DO UPDATE SET
+	layer_index = EXCLUDED.layer_index,
+	findings = EXCLUDED.findings,
+	error = EXCLUDED.error,
+	scanned = EXCLUDED.scanned;`
+	selectResults = `
+SELECT layer_hash, scanner, findings, error, scanned FROM content_scan
+WHERE manifest_hash = $1
+ORDER BY layer_index, scanner;`
+)
+
+// Scan runs the hooks over the manifest's layers and records the results.
+// Failures are logged.
+func (i *Indexer) scan(ctx context.Context, m *claircore.Manifest) {
+	log := zerolog.Ctx(ctx)
+	for n, l := range m.Layers {
+		prev, err := i.previous(ctx, l.Hash)
+		if err != nil {
+			log.Warn().Err(err).Str("layer", l.Hash.String()).Msg("unable to look up previous scans")
+		}
+		var todo []Hook
+		rs := make([]Result, 0, len(i.hooks))
+		for _, h := range i.hooks {
+			if r, ok := prev[h.Name]; ok {
+				rs = append(rs, r)
+				continue
+			}
+			todo = append(todo, h)
+		}
+		if len(todo) != 0 {
+			rs = append(rs, i.scanLayer(ctx, l, todo)...)
+		}
+		for _, r := range rs {
+			ev := log.Debug()
+			if len(r.Findings) != 0 {
+				ev = log.Warn()
+			}
+			ev.Str("layer", l.Hash.String()).
+				Str("scanner", r.Scanner).
+				Int("findings", len(r.Findings)).
+				Str("error", r.Error).
+				Msg("scanned layer contents")
+			if err := i.record(ctx, m.Hash, n, &r); err != nil {
+				log.Warn().Err(err).Str("layer", l.Hash.String()).Msg("unable to record scan")
+			}
+		}
+	}
+}
+
+// Previous returns the clean scans of the layer made in any manifest, keyed
+// by scanner.
+func (i *Indexer) previous(ctx context.Context, d claircore.Digest) (map[string]Result, error) {
+	rows, err := i.pool.Query(ctx, selectPrevious, d.String())
+	if err != nil {
+		return nil, fmt.Errorf("hook: failed to read previous scans: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string]Result)
+	for rows.Next() {
+		r := Result{Layer: d}
+		var b []byte
+		if err := rows.Scan(&r.Scanner, &b, &r.Scanned); err != nil {
+			return nil, fmt.Errorf("hook: failed to read previous scans: %w", err)
+		}
+		if err := json.Unmarshal(b, &r.Findings); err != nil {
+			return nil, fmt.Errorf("hook: failed to read previous scans: %w", err)
+		}
+		out[r.Scanner] = r
+	}
+	return out, rows.Err()
+}
+
+func (i *Indexer) record(ctx context.Context, m claircore.Digest, n int, r *Result) error {
+	b, err := json.Marshal(r.Findings)
+	if err != nil {
+		return err
+	}
+	_, err = i.pool.Exec(ctx, upsertResult, m.String(), r.Layer.String(), n, r.Scanner, b, r.Error, r.Scanned)
+	if err != nil {
+		return fmt.Errorf("hook: failed to record scan: %w", err)
+	}
+	return nil
+}
+
+// ScanLayer fetches the layer once, streaming it to all the hooks.
+func (i *Indexer) scanLayer(ctx context.Context, l *claircore.Layer, hooks []Hook) []Result {
+	out := make([]Result, len(hooks))
+	now := time.Now()
+	for n, h := range hooks {
+		out[n] = Result{
+			Layer:    l.Hash,
+			Scanner:  h.Name,
+			Findings: []Finding{},
+			Scanned:  now,
+		}
+	}
+	if i.sem != nil {
+		select {
+		case i.sem <- struct{}{}:
+			defer func() { <-i.sem }()
+		case <-ctx.Done():
+			for n := range out {
+				out[n].Error = ctx.Err().Error()
+			}
+			return out
+		}
+	}
+	ctx, done := context.WithTimeout(ctx, i.timeout)
+	defer done()
+	rc, err := i.fetch(ctx, l)
+	if err != nil {
+		for n := range out {
+			out[n].Error = err.Error()
+		}
+		return out
+	}
+	defer rc.Close()
+
+	ws := make([]io.Writer, len(hooks))
+	pws := make([]*io.PipeWriter, len(hooks))
+	var wg sync.WaitGroup
+	for n, h := range hooks {
+		pr, pw := io.Pipe()
+		ws[n], pws[n] = pw, pw
+		wg.Add(1)
+		go func(r *Result, s Scanner) {
+			defer wg.Done()
+			fs, err := s.Scan(ctx, pr)
+			// Keep the other hooks fed if this one stopped early.
+			io.Copy(ioutil.Discard, pr)
+			switch {
+			case err != nil:
+				r.Error = err.Error()
+			case fs != nil:
+				r.Findings = fs
+			}
+		}(&out[n], h.Scanner)
+	}
+	_, err = io.Copy(io.MultiWriter(ws...), rc)
+	for _, pw := range pws {
+		pw.CloseWithError(err)
+	}
+	wg.Wait()
+	if err != nil {
+		// A hook that finished early didn't see the whole layer either.
+		for n := range out {
+			if out[n].Error == "" {
+				out[n].Error = fmt.Sprintf("failed to read layer: %v", err)
+			}
+		}
+	}
+	return out
+}
+
+func (i *Indexer) fetch(ctx context.Context, l *claircore.Layer) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.URI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch layer: %w", err)
+	}
+	for k, vs := range l.Headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	res, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch layer: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("failed to fetch layer: unexpected status: %s", res.Status)
+	}
+	return res.Body, nil
+}
+
+// Results returns the hook results for the manifest's layers, in layer
+// order, or nil if it's never been scanned.
+func (i *Indexer) Results(ctx context.Context, d claircore.Digest) ([]Result, error) {
+	rows, err := i.pool.Query(ctx, selectResults, d.String())
+	if err != nil {
+		return nil, fmt.Errorf("hook: failed to read results: %w", err)
+	}
+	defer rows.Close()
+	var out []Result
+	for rows.Next() {
+		var r Result
+		var layer string
+		var b []byte
+		if err := rows.Scan(&layer, &r.Scanner, &b, &r.Error, &r.Scanned); err != nil {
+			return nil, fmt.Errorf("hook: failed to read results: %w", err)
+		}
+		if r.Layer, err = claircore.ParseDigest(layer); err != nil {
+			return nil, fmt.Errorf("hook: failed to read results: %w", err)
+		}
+		if err := json.Unmarshal(b, &r.Findings); err != nil {
+			return nil, fmt.Errorf("hook: failed to read results: %w", err)
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("hook: failed to read results: %w", err)
+	}
+	return out, nil
+}
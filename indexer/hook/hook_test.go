@@ -0,0 +1,156 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+// Clamd is a fake clamd answering INSTREAM, finding anything containing
+// "EICAR".
+func clamd(t *testing.T) (string, func()) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString(0)
+				if err != nil || cmd != "zINSTREAM\x00" {
+					io.WriteString(conn, "UNKNOWN COMMAND\x00")
+					return
+				}
+				var body bytes.Buffer
+				for {
+					var n uint32
+					if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+						return
+					}
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(&body, r, int64(n)); err != nil {
+						return
+					}
+				}
+				reply := "stream: OK\x00"
+				if strings.Contains(body.String(), "EICAR") {
+					reply = "stream: Eicar-Signature FOUND\x00"
+				}
+				io.WriteString(conn, reply)
+			}(conn)
+		}
+	}()
+	return "tcp://" + l.Addr().String(), func() { l.Close() }
+}
+
+func TestClamAV(t *testing.T) {
+	ctx := context.Background()
+	addr, done := clamd(t)
+	defer done()
+	c, err := NewClamAV(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := c.Scan(ctx, strings.NewReader(strings.Repeat("clean ", 20000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 0 {
+		t.Errorf("unexpected findings: %v", fs)
+	}
+	fs, err = c.Scan(ctx, strings.NewReader("X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Finding{{Name: "Eicar-Signature"}}; !cmp.Equal(fs, want) {
+		t.Error(cmp.Diff(fs, want))
+	}
+	if _, err := parseClamReply("INSTREAM size limit exceeded. ERROR\x00"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+type scannerFunc func(context.Context, io.Reader) ([]Finding, error)
+
+func (f scannerFunc) Scan(ctx context.Context, r io.Reader) ([]Finding, error) { return f(ctx, r) }
+
+// TestScanLayer checks that every hook sees the whole layer, even when
+// another stops reading early.
+func TestScanLayer(t *testing.T) {
+	ctx := context.Background()
+	layer := strings.Repeat("layer contents ", 10000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, layer)
+	}))
+	defer srv.Close()
+	d, err := claircore.ParseDigest("sha256:" + strings.Repeat("a", 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &claircore.Layer{
+		Hash:    d,
+		URI:     srv.URL,
+		Headers: map[string][]string{"Authorization": {"Bearer token"}},
+	}
+	i := NewIndexer(nil, nil, srv.Client(), nil, time.Minute, 0)
+
+	var got string
+	rs := i.scanLayer(ctx, l, []Hook{
+		{Name: "whole", Scanner: scannerFunc(func(_ context.Context, r io.Reader) ([]Finding, error) {
+			b, err := ioutil.ReadAll(r)
+			got = string(b)
+			return []Finding{{Name: "thing", Path: "etc/thing"}}, err
+		})},
+		{Name: "quitter", Scanner: scannerFunc(func(_ context.Context, r io.Reader) ([]Finding, error) {
+			r.Read(make([]byte, 10))
+			return nil, errors.New("gave up")
+		})},
+	})
+	if got != layer {
+		t.Errorf("hook read %d bytes, want %d", len(got), len(layer))
+	}
+	if len(rs) != 2 {
+		t.Fatalf("got %d results, want 2", len(rs))
+	}
+	if rs[0].Error != "" || len(rs[0].Findings) != 1 {
+		t.Errorf("unexpected result: %+v", rs[0])
+	}
+	if rs[1].Error != "gave up" || rs[1].Findings == nil {
+		t.Errorf("unexpected result: %+v", rs[1])
+	}
+
+	l.Headers = nil
+	rs = i.scanLayer(ctx, l, []Hook{{Name: "whole", Scanner: scannerFunc(func(context.Context, io.Reader) ([]Finding, error) {
+		t.Error("scanner called for unfetchable layer")
+		return nil, nil
+	})}})
+	if rs[0].Error == "" {
+		t.Error("expected fetch error")
+	}
+}
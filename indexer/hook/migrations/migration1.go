@@ -0,0 +1,21 @@
+package migrations
+
+const (
+	// migration1 adds a table recording what content hooks found in a
+	// manifest's layers.
+	migration1 = `
+	--- a relation recording a content hook's scan of a layer, per manifest
+	CREATE TABLE IF NOT EXISTS content_scan
+	(
+		manifest_hash text NOT NULL,
+		layer_hash    text NOT NULL,
+		layer_index   integer NOT NULL,
+		scanner       text NOT NULL,
+		findings      jsonb NOT NULL DEFAULT '[]',
+		error         text NOT NULL DEFAULT '',
+		scanned       timestamptz NOT NULL,
+		PRIMARY KEY (manifest_hash, layer_hash, scanner)
+	);
+	CREATE INDEX IF NOT EXISTS content_scan_layer_idx ON content_scan (layer_hash, scanner);
+	`
+)
@@ -0,0 +1,329 @@
+package hook
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/quay/claircore"
+)
+
+// These are the scopes a Scope reports packages in, as the Detail of its
+// findings.
+const (
+	// ScopeRuntime is for packages the application needs to run.
+	ScopeRuntime = "runtime"
+	// ScopeDevelopment is for packages only needed to build or test the
+	// application.
+	ScopeDevelopment = "development"
+)
+
+// Scope is a Scanner reporting whether the Python packages named in a
+// layer's dependency manifests are runtime or development dependencies.
+//
+// It understands Pipfile.lock, poetry.lock, and requirements files, where
+// files like "requirements-dev.txt" or "requirements/test.txt" list
+// development dependencies. Each finding's Name is a package name normalized
+// as described in PEP 503, its Path is the manifest naming it, and its Detail
+// is the scope. A package named as a runtime dependency anywhere in the layer
+// is reported as one.
+type Scope struct{}
+
+var _ Scanner = (*Scope)(nil)
+
+// NewScope returns a Scope.
+func NewScope() *Scope {
+	return &Scope{}
+}
+
+// ScopeMaxFile is the largest dependency manifest read. Anything larger
+// isn't one a person wrote.
+const scopeMaxFile = 8 << 20
+
+// Scan implements Scanner.
+func (*Scope) Scan(ctx context.Context, r io.Reader) ([]Finding, error) {
+	rd, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	type found struct {
+		path  string
+		scope string
+	}
+	names := make(map[string]found)
+	add := func(p, scope string, ns []string) {
+		for _, n := range ns {
+			n = NormalizePythonName(n)
+			if n == "" {
+				continue
+			}
+			if f, ok := names[n]; ok && f.scope == ScopeRuntime {
+				continue
+			}
+			names[n] = found{path: p, scope: scope}
+		}
+	}
+
+	tr := tar.NewReader(rd)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("scope: reading layer: %w", err)
+		}
+		if h.Typeflag != tar.TypeReg || h.Size > scopeMaxFile {
+			continue
+		}
+		p := strings.TrimPrefix(path.Clean("/"+h.Name), "/")
+		kind := manifestKind(p)
+		if kind == "" {
+			continue
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("scope: reading %q: %w", p, err)
+		}
+		switch kind {
+		case "pipfile":
+			run, dev, err := parsePipfileLock(b)
+			if err != nil {
+				// A broken manifest says nothing about the packages.
+				continue
+			}
+			add(p, ScopeRuntime, run)
+			add(p, ScopeDevelopment, dev)
+		case "poetry":
+			run, dev := parsePoetryLock(b)
+			add(p, ScopeRuntime, run)
+			add(p, ScopeDevelopment, dev)
+		case ScopeRuntime, ScopeDevelopment:
+			add(p, kind, parseRequirements(b))
+		}
+	}
+
+	out := make([]Finding, 0, len(names))
+	for n, f := range names {
+		out = append(out, Finding{Name: n, Path: f.path, Detail: f.scope})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Decompress returns a reader for the uncompressed layer, which may be
+// gzip or zstd compressed or not at all.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("scope: reading layer: %w", err)
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		z, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("scope: reading layer: %w", err)
+		}
+		return z, nil
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		z, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("scope: reading layer: %w", err)
+		}
+		return z.IOReadCloser(), nil
+	}
+	return ioutil.NopCloser(br), nil
+}
+
+// ManifestKind reports how to read the file at p: "pipfile", "poetry",
+// or, for requirements files, the scope of what they list. Files that
+// aren't dependency manifests get an empty string.
+func manifestKind(p string) string {
+	base := path.Base(p)
+	switch base {
+	case "Pipfile.lock":
+		return "pipfile"
+	case "poetry.lock":
+		return "poetry"
+	case "requirements.txt":
+		return ScopeRuntime
+	}
+	if path.Base(path.Dir(p)) == "requirements" && strings.HasSuffix(base, ".txt") {
+		if devRequirements.MatchString(strings.TrimSuffix(base, ".txt")) {
+			return ScopeDevelopment
+		}
+		return ScopeRuntime
+	}
+	if strings.HasPrefix(base, "requirements") || strings.HasSuffix(base, "requirements.txt") {
+		if strings.HasSuffix(base, ".txt") && devRequirements.MatchString(base) {
+			return ScopeDevelopment
+		}
+	}
+	return ""
+}
+
+// DevRequirements matches the names of requirements files listing
+// development dependencies, like "requirements-dev.txt",
+// "test-requirements.txt", or "requirements/test.txt".
+var devRequirements = regexp.MustCompile(`(?i)(^|[-_.])(dev|develop|development|test|tests|testing|lint|docs|build)([-_.]|$)`)
+
+// ParsePipfileLock returns the packages in the "default" and "develop"
+// sections of a Pipfile.lock.
+func parsePipfileLock(b []byte) (run, dev []string, err error) {
+	var lock struct {
+		Default map[string]json.RawMessage `json:"default"`
+		Develop map[string]json.RawMessage `json:"develop"`
+	}
+	if err := json.Unmarshal(b, &lock); err != nil {
+		return nil, nil, err
+	}
+	for n := range lock.Default {
+		run = append(run, n)
+	}
+	for n := range lock.Develop {
+		dev = append(dev, n)
+	}
+	return run, dev, nil
+}
+
+// ParsePoetryLock returns the packages in a poetry.lock, by category.
+// Lock files from newer versions of Poetry have no categories, so every
+// package in them is reported as a runtime dependency.
+func parsePoetryLock(b []byte) (run, dev []string) {
+	var name, category string
+	flush := func() {
+		switch {
+		case name == "":
+		case category == "dev":
+			dev = append(dev, name)
+		default:
+			run = append(run, name)
+		}
+		name, category = "", ""
+	}
+	inPackage := false
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(l, "[") {
+			flush()
+			inPackage = l == "[[package]]"
+			continue
+		}
+		if !inPackage {
+			continue
+		}
+		i := strings.IndexByte(l, '=')
+		if i == -1 {
+			continue
+		}
+		k, v := strings.TrimSpace(l[:i]), strings.Trim(strings.TrimSpace(l[i+1:]), `"'`)
+		switch k {
+		case "name":
+			name = v
+		case "category":
+			category = v
+		}
+	}
+	flush()
+	return run, dev
+}
+
+// ParseRequirements returns the packages named in a pip requirements file.
+// Options, includes, and editable or URL requirements are skipped.
+func parseRequirements(b []byte) []string {
+	var out []string
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		l := s.Text()
+		if i := strings.Index(l, "#"); i != -1 {
+			l = l[:i]
+		}
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "-") || strings.Contains(l, "://") {
+			continue
+		}
+		if i := strings.IndexAny(l, " \t;=<>!~[@"); i != -1 {
+			l = l[:i]
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+var pythonNameSep = regexp.MustCompile(`[-_.]+`)
+
+// ScopeLister is implemented by indexers that know the scope of a report's
+// packages.
+type ScopeLister interface {
+	// Scopes returns the scope of each package in the report whose scope
+	// is known, keyed by package id.
+	Scopes(context.Context, *claircore.IndexReport) (map[string]string, error)
+}
+
+var _ ScopeLister = (*Indexer)(nil)
+
+// Scopes implements ScopeLister, using the findings of any Scope hooks.
+// Only Python packages are considered, as those are the only language
+// packages the scope of can be determined.
+func (i *Indexer) Scopes(ctx context.Context, ir *claircore.IndexReport) (map[string]string, error) {
+	names := make(map[string]bool)
+	for _, h := range i.hooks {
+		if _, ok := h.Scanner.(*Scope); ok {
+			names[h.Name] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	rs, err := i.Results(ctx, ir.Hash)
+	if err != nil {
+		return nil, err
+	}
+	scope := make(map[string]string)
+	for _, r := range rs {
+		if !names[r.Scanner] {
+			continue
+		}
+		for _, f := range r.Findings {
+			if f.Detail == ScopeRuntime || scope[f.Name] == "" {
+				scope[f.Name] = f.Detail
+			}
+		}
+	}
+	if len(scope) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string)
+	for id, p := range ir.Packages {
+		if p == nil || !strings.HasPrefix(p.PackageDB, "python:") {
+			continue
+		}
+		if s, ok := scope[NormalizePythonName(p.Name)]; ok {
+			out[id] = s
+		}
+	}
+	return out, nil
+}
+
+// NormalizePythonName normalizes a Python package name as described in PEP
+// 503, so differently spelled names for a package compare equal.
+func NormalizePythonName(n string) string {
+	return pythonNameSep.ReplaceAllString(strings.ToLower(strings.TrimSpace(n)), "-")
+}
@@ -0,0 +1,69 @@
+package hook
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScope(t *testing.T) {
+	ctx := context.Background()
+	files := map[string]string{
+		"app/Pipfile.lock": `{
+	"default": {"Requests": {}, "flask": {}},
+	"develop": {"pytest": {}, "requests": {}}
+}`,
+		"svc/poetry.lock": `[[package]]
+name = "Black"
+category = "dev"
+
+[[package]]
+name = "zope.interface"
+category = "main"
+
+[package.dependencies]
+setuptools = "*"
+`,
+		"svc/requirements-dev.txt": "# tools\nmypy==0.790\n-r requirements.txt\nflake8>=3 ; python_version > '3'\n",
+		"svc/requirements.txt":     "flask\n",
+		"svc/README.txt":           "pylint\n",
+	}
+	var buf bytes.Buffer
+	z := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(z)
+	for _, n := range []string{"app/Pipfile.lock", "svc/poetry.lock", "svc/requirements-dev.txt", "svc/requirements.txt", "svc/README.txt"} {
+		if err := tw.WriteHeader(&tar.Header{Name: "./" + n, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(files[n]))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[n])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewScope().Scan(ctx, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Finding{
+		{Name: "black", Path: "svc/poetry.lock", Detail: ScopeDevelopment},
+		{Name: "flake8", Path: "svc/requirements-dev.txt", Detail: ScopeDevelopment},
+		{Name: "flask", Path: "app/Pipfile.lock", Detail: ScopeRuntime},
+		{Name: "mypy", Path: "svc/requirements-dev.txt", Detail: ScopeDevelopment},
+		{Name: "pytest", Path: "app/Pipfile.lock", Detail: ScopeDevelopment},
+		{Name: "requests", Path: "app/Pipfile.lock", Detail: ScopeRuntime},
+		{Name: "zope-interface", Path: "svc/poetry.lock", Detail: ScopeRuntime},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
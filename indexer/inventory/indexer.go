@@ -0,0 +1,193 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Indexer wraps an indexer.Service, recording when manifests are indexed,
+// and lists the manifests with index reports.
+type Indexer struct {
+	indexer.Service
+	pool *pgxpool.Pool
+	// Annotations is set if the annotations package's tables are present.
+	annotations bool
+	tenant      func(context.Context) (string, bool)
+}
+
+var (
+	_ indexer.Service = (*Indexer)(nil)
+	_ Lister          = (*Indexer)(nil)
+)
+
+// NewIndexer returns an Indexer using the database behind pool, which must
+// be the indexer's database. If annotations is set, the database must have
+// the annotations tables, and manifests are listed with the annotations the
+// request's tenant set, as reported by the tenant function.
+func NewIndexer(s indexer.Service, pool *pgxpool.Pool, annotations bool, tenant func(context.Context) (string, bool)) *Indexer {
+	return &Indexer{
+		Service:     s,
+		pool:        pool,
+		annotations: annotations,
+		tenant:      tenant,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+//
+// Failing to record the time is logged and otherwise ignored.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	ir, err := i.Service.Index(ctx, m)
+	if err != nil || ir == nil || !ir.Success {
+		return ir, err
+	}
+	const upsert = `
+INSERT INTO manifest_inventory (manifest_hash, first_indexed, last_indexed) VALUES ($1, now(), now())
+ON CONFLICT (manifest_hash) DO UPDATE SET last_indexed = EXCLUDED.last_indexed;`
+	// The request's context may be done by now, but the time should still
+	// be recorded.
+	rctx, done := context.WithTimeout(context.Background(), 10*time.Second)
+	defer done()
+	if _, err := i.pool.Exec(rctx, upsert, m.Hash.String()); err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "indexer/inventory/Indexer.Index").
+			Str("manifest", m.Hash.String()).
+			Err(err).
+			Msg("failed to record manifest in inventory")
+	}
+	return ir, nil
+}
+
+// List implements Lister.
+func (i *Indexer) List(ctx context.Context, q *Query) (*Page, error) {
+	if len(q.Annotations) != 0 && !i.annotations {
+		return nil, ErrNoAnnotations
+	}
+	size := q.PageSize()
+
+	var b strings.Builder
+	args := []interface{}{q.Next}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	b.WriteString(`SELECT r.manifest_hash, r.state, inv.first_indexed, inv.last_indexed, `)
+	if i.annotations {
+		b.WriteString(`COALESCE(a.annotations, '{}'::jsonb)`)
+	} else {
+		b.WriteString(`'{}'::jsonb`)
+	}
+	b.WriteString(`
+FROM indexreport r
+LEFT JOIN manifest_inventory inv ON inv.manifest_hash = r.manifest_hash`)
+	if i.annotations {
+		var t string
+		if i.tenant != nil {
+			t, _ = i.tenant(ctx)
+		}
+		b.WriteString(`
+LEFT JOIN manifest_annotations a ON a.manifest_hash = r.manifest_hash AND a.tenant = ` + arg(t))
+	}
+	b.WriteString(`
+WHERE r.manifest_hash >= $1`)
+	if !q.IndexedBefore.IsZero() {
+		b.WriteString(` AND inv.last_indexed < ` + arg(q.IndexedBefore))
+	}
+	if !q.IndexedAfter.IsZero() {
+		b.WriteString(` AND inv.last_indexed >= ` + arg(q.IndexedAfter))
+	}
+	if len(q.Annotations) != 0 {
+		b.WriteString(` AND a.annotations @> ` + arg(q.Annotations) + `::jsonb`)
+	}
+	// One extra row tells whether there's another page.
+	b.WriteString(`
+ORDER BY r.manifest_hash
+LIMIT ` + arg(size+1) + `;`)
+
+	rows, err := i.pool.Query(ctx, b.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("inventory: failed to list manifests: %w", err)
+	}
+	defer rows.Close()
+	p := &Page{Manifests: []Manifest{}}
+	for rows.Next() {
+		var m Manifest
+		var h string
+		if err := rows.Scan(&h, &m.State, &m.FirstIndexed, &m.LastIndexed, &m.Annotations); err != nil {
+			return nil, fmt.Errorf("inventory: failed to scan manifest: %w", err)
+		}
+		if len(p.Manifests) == size {
+			p.Next = h
+			break
+		}
+		m.Hash, err = claircore.ParseDigest(h)
+		if err != nil {
+			return nil, fmt.Errorf("inventory: bad manifest hash %q: %w", h, err)
+		}
+		if len(m.Annotations) == 0 {
+			m.Annotations = nil
+		}
+		m.Scanners = []Scanner{}
+		p.Manifests = append(p.Manifests, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("inventory: failed to list manifests: %w", err)
+	}
+	rows.Close()
+	if err := i.scanners(ctx, p.Manifests); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Scanners fills in the scanners of the manifests.
+func (i *Indexer) scanners(ctx context.Context, ms []Manifest) error {
+	if len(ms) == 0 {
+		return nil
+	}
+	const query = `
+SELECT sm.manifest_hash, s.name, s.version, s.kind
+FROM scanned_manifest sm
+JOIN scanner s ON s.id = sm.scanner_id
+WHERE sm.manifest_hash = ANY($1::text[])
+ORDER BY s.kind, s.name;`
+	hs := make([]string, len(ms))
+	idx := make(map[string]int, len(ms))
+	for n, m := range ms {
+		hs[n] = m.Hash.String()
+		idx[hs[n]] = n
+	}
+	rows, err := i.pool.Query(ctx, query, hs)
+	if err != nil {
+		return fmt.Errorf("inventory: failed to list scanners: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var h string
+		var s Scanner
+		if err := rows.Scan(&h, &s.Name, &s.Version, &s.Kind); err != nil {
+			return fmt.Errorf("inventory: failed to scan scanner: %w", err)
+		}
+		if n, ok := idx[h]; ok {
+			ms[n].Scanners = append(ms[n].Scanners, s)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("inventory: failed to list scanners: %w", err)
+	}
+	return nil
+}
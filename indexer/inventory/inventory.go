@@ -0,0 +1,96 @@
+// Package inventory lists the manifests an indexer has indexed, with when
+// they were indexed, their state, the scanners that indexed them, and their
+// annotations.
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/quay/claircore"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+)
+
+// MaxPageSize is the most manifests listed at once.
+const MaxPageSize = 1000
+
+// ErrNotConfigured is returned by Listers wrapping indexers that don't list
+// manifests.
+var ErrNotConfigured = clairerror.New(clairerror.Unimplemented, "inventory: not configured")
+
+// ErrNoAnnotations is returned by List for queries filtering on annotations
+// when annotations aren't recorded.
+var ErrNoAnnotations = clairerror.New(clairerror.Invalid, "inventory: annotations are not recorded")
+
+// Lister is implemented by indexers that can list their manifests.
+type Lister interface {
+	List(ctx context.Context, q *Query) (*Page, error)
+}
+
+// Query selects manifests to list.
+type Query struct {
+	// Size is the most manifests to return. It's capped at MaxPageSize,
+	// which is also the default.
+	Size int
+	// Next is the first manifest hash to return, as reported by the
+	// previous Page. Manifests are listed in order of their hash.
+	Next string
+	// IndexedBefore and IndexedAfter, if not zero, limit the manifests to
+	// ones last indexed in that window. Manifests indexed before the
+	// inventory was enabled have no times, and are left out.
+	IndexedBefore time.Time
+	IndexedAfter  time.Time
+	// Annotations limits the manifests to ones with all of these
+	// annotations.
+	Annotations map[string]string
+}
+
+// PageSize returns the number of manifests the query asks for, within the
+// limits.
+func (q *Query) PageSize() int {
+	if q.Size <= 0 || q.Size > MaxPageSize {
+		return MaxPageSize
+	}
+	return q.Size
+}
+
+// Page is a page of listed manifests.
+type Page struct {
+	Manifests []Manifest
+	// Next is the Query.Next for the following page, if there is one.
+	Next string
+}
+
+// Manifest is an indexed manifest.
+type Manifest struct {
+	Hash  claircore.Digest `json:"manifest_hash"`
+	State string           `json:"state"`
+	// FirstIndexed and LastIndexed are when the manifest was first and
+	// last submitted and indexed successfully. They're only known for
+	// manifests indexed since the inventory was enabled.
+	FirstIndexed *time.Time `json:"first_indexed,omitempty"`
+	LastIndexed  *time.Time `json:"last_indexed,omitempty"`
+	// Scanners are the scanners the manifest was indexed with.
+	Scanners    []Scanner         `json:"scanners"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Scanner is a versioned scanner.
+type Scanner struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// Find returns the Lister among the wrapped indexers, if there is one.
+func Find(s indexer.Service) (Lister, bool) {
+	var l Lister
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		l, ok = s.(Lister)
+		return ok
+	})
+	return l, ok
+}
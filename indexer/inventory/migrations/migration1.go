@@ -0,0 +1,15 @@
+package migrations
+
+const (
+	// migration1 adds the record of when manifests were indexed.
+	migration1 = `
+	--- a relation recording when a manifest was first and last indexed
+	CREATE TABLE IF NOT EXISTS manifest_inventory
+	(
+		manifest_hash text PRIMARY KEY,
+		first_indexed timestamptz NOT NULL,
+		last_indexed  timestamptz NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS manifest_inventory_last_indexed_idx ON manifest_inventory (last_indexed);
+	`
+)
@@ -0,0 +1,306 @@
+package layercache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Indexer wraps an indexer.Service and serves the layers it fetches from a
+// Store, fetching and storing the ones that aren't there.
+//
+// Like the upload Indexer, layers are handed to the indexer's fetcher from a
+// listener on the loopback interface, guarded by a random token. Layers
+// already served from the loopback interface aren't cached.
+//
+// With a nil Store, layers are only relayed, so fetches go through the
+// Indexer's client, e.g. to limit how many happen at once.
+type Indexer struct {
+	indexer.Service
+	store  Store
+	c      *http.Client
+	prefix string
+	base   string
+	token  string
+
+	mu      sync.Mutex
+	sources map[string]map[string]source
+
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+}
+
+// Source is where a layer is fetched from on a miss.
+type source struct {
+	uri     string
+	headers http.Header
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer caching layers in the Store, if not nil,
+// under keys starting with prefix. Layers are fetched with the client; a nil client
+// means http.DefaultClient. The loopback server is stopped when the Context
+// is canceled.
+func NewIndexer(ctx context.Context, s indexer.Service, st Store, c *http.Client, prefix string) (*Indexer, error) {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	tok := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, tok); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	meter := metric.Must(otel.Meter("clair"))
+	i := &Indexer{
+		Service: s,
+		store:   st,
+		c:       c,
+		prefix:  prefix,
+		base:    "http://" + ln.Addr().String() + "/",
+		token:   hex.EncodeToString(tok),
+		sources: make(map[string]map[string]source),
+		hits: meter.NewInt64Counter(
+			"clair_indexer_layer_cache_hits_total",
+			metric.WithDescription("number of layers served from the layer cache"),
+		),
+		misses: meter.NewInt64Counter(
+			"clair_indexer_layer_cache_misses_total",
+			metric.WithDescription("number of layers fetched on a layer cache miss"),
+		),
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(i.serve)}
+	go srv.Serve(ln)
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	return i, nil
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+//
+// The wrapped indexer is handed a copy of the manifest, so callers don't see
+// the rewritten layers.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(b)
+	srcs := make(map[string]source)
+	cp := *m
+	cp.Layers = make([]*claircore.Layer, len(m.Layers))
+	for n, l := range m.Layers {
+		cp.Layers[n] = l
+		if !cacheable(l.URI) {
+			continue
+		}
+		srcs[l.Hash.String()] = source{uri: l.URI, headers: l.Headers}
+		lc := *l
+		lc.URI = i.base + id + "/" + l.Hash.String()
+		lc.Headers = map[string][]string{
+			"Authorization": {"Bearer " + i.token},
+		}
+		cp.Layers[n] = &lc
+	}
+	if len(srcs) == 0 {
+		return i.Service.Index(ctx, m)
+	}
+	i.mu.Lock()
+	i.sources[id] = srcs
+	i.mu.Unlock()
+	defer func() {
+		i.mu.Lock()
+		delete(i.sources, id)
+		i.mu.Unlock()
+	}()
+	return i.Service.Index(ctx, &cp)
+}
+
+// Cacheable reports whether the layer at the URI should be cached.
+func cacheable(uri string) bool {
+	if uri == "" {
+		return false
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip == nil || !ip.IsLoopback()
+}
+
+// Key returns the Store key for the layer.
+func (i *Indexer) key(d claircore.Digest) string {
+	return i.prefix + d.Algorithm() + "/" + hex.EncodeToString(d.Checksum())
+}
+
+// Serve hands layers to the indexer's fetcher, from the Store if possible.
+func (i *Indexer) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("authorization") != "Bearer "+i.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/layercache/Indexer.serve").
+		Logger()
+	p := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(p) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	d, err := claircore.ParseDigest(p[1])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log = log.With().Str("layer", d.String()).Logger()
+	key := i.key(d)
+
+	if i.store != nil {
+		rc, err := i.store.Open(ctx, key)
+		switch {
+		case err == nil:
+			defer rc.Close()
+			i.hits.Add(ctx, 1)
+			log.Debug().Msg("serving cached layer")
+			w.Header().Set("content-type", "application/octet-stream")
+			io.Copy(w, rc)
+			return
+		case errors.Is(err, ErrNotFound):
+		default:
+			log.Warn().Err(err).Msg("unable to read layer cache")
+		}
+		i.misses.Add(ctx, 1)
+	}
+
+	i.mu.Lock()
+	src, ok := i.sources[p[0]][d.String()]
+	i.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if i.store == nil {
+		// The fetcher checks the digest, so there's no need to spool the
+		// layer first.
+		if err := i.relay(ctx, w, src); err != nil {
+			log.Warn().Err(err).Msg("unable to fetch layer")
+		}
+		return
+	}
+	f, size, sum, err := i.fetch(ctx, d, src)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to fetch layer")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	w.Header().Set("content-type", "application/octet-stream")
+	if _, err := io.Copy(w, f); err != nil {
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	if err := i.store.Put(ctx, key, f, size, sum); err != nil {
+		log.Warn().Err(err).Msg("unable to write layer cache")
+	}
+}
+
+// Relay copies the layer from its source to w.
+func (i *Indexer) relay(ctx context.Context, w http.ResponseWriter, src source) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.uri, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return err
+	}
+	for k, vs := range src.headers {
+		req.Header[k] = vs
+	}
+	res, err := i.c.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected response fetching layer: %s", res.Status)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return err
+	}
+	w.Header().Set("content-type", "application/octet-stream")
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// Fetch downloads the layer from its source into a temporary file, checking
+// its digest. The returned file is positioned at the start.
+func (i *Indexer) fetch(ctx context.Context, d claircore.Digest, src source) (*os.File, int64, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.uri, nil)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	for k, vs := range src.headers {
+		req.Header[k] = vs
+	}
+	res, err := i.c.Do(req)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, 0, "", fmt.Errorf("unexpected response fetching layer: %s", res.Status)
+	}
+	f, err := ioutil.TempFile("", "clair-layercache-")
+	if err != nil {
+		return nil, 0, "", err
+	}
+	vh, sh := d.Hash(), sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, vh, sh), res.Body)
+	if err == nil && !bytes.Equal(vh.Sum(nil), d.Checksum()) {
+		err = fmt.Errorf("layer contents do not match digest %q", d)
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, 0, "", err
+	}
+	return f, n, hex.EncodeToString(sh.Sum(nil)), nil
+}
@@ -0,0 +1,198 @@
+package layercache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+func digestOf(t *testing.T, b []byte) claircore.Digest {
+	sum := sha256.Sum256(b)
+	d, err := claircore.ParseDigest("sha256:" + hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// TestIndexer checks that layers are fetched from their source once, and
+// from the cache after.
+func TestIndexer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir, err := ioutil.TempDir("", "layercache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	st, err := NewFilesystem(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := []byte("layer contents")
+	var mu sync.Mutex
+	fetches := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") != "Bearer registry" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+		w.Write(layer)
+	}))
+	defer origin.Close()
+	// Layers served from loopback addresses aren't cached, so the origin
+	// is addressed by name instead.
+	uri := strings.Replace(origin.URL, "127.0.0.1", "origin.test", 1) + "/layer"
+	c := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, origin.Listener.Addr().String())
+		},
+	}}
+
+	// The mock indexer fetches layers like the real one does.
+	mock := &indexer.Mock{
+		Index_: func(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			for _, l := range m.Layers {
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.URI, nil)
+				if err != nil {
+					return nil, err
+				}
+				req.Header = l.Headers
+				res, err := http.DefaultClient.Do(req)
+				if err != nil {
+					return nil, err
+				}
+				b, err := ioutil.ReadAll(res.Body)
+				res.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				if res.StatusCode != http.StatusOK || !bytes.Equal(b, layer) {
+					t.Errorf("unexpected response: %s: %q", res.Status, b)
+				}
+			}
+			return &claircore.IndexReport{Hash: m.Hash, Success: true}, nil
+		},
+	}
+	idx, err := NewIndexer(ctx, mock, st, c, "layers/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := digestOf(t, layer)
+	for n := 0; n < 2; n++ {
+		m := &claircore.Manifest{
+			Hash: digestOf(t, []byte("manifest")),
+			Layers: []*claircore.Layer{{
+				Hash:    d,
+				URI:     uri,
+				Headers: map[string][]string{"Authorization": {"Bearer registry"}},
+			}},
+		}
+		if _, err := idx.Index(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Layers[0].URI, uri; got != want {
+			t.Errorf("caller's manifest modified: got: %q, want: %q", got, want)
+		}
+	}
+	if got, want := fetches, 1; got != want {
+		t.Errorf("got: %d fetches, want: %d", got, want)
+	}
+}
+
+// TestSwift checks storing and reading layers with v1 authentication,
+// including authenticating again once a token expires.
+func TestSwift(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	objs := make(map[string][]byte)
+	token := "one"
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/auth/v1.0" {
+			if r.Header.Get("X-Auth-User") != "test:tester" || r.Header.Get("X-Auth-Key") != "testing" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-Auth-Token", token)
+			w.Header().Set("X-Storage-Url", srv.URL+"/v1/AUTH_test")
+			return
+		}
+		if r.Header.Get("X-Auth-Token") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			if r.Header.Get("X-Delete-After") != "3600" {
+				t.Errorf("unexpected X-Delete-After: %q", r.Header.Get("X-Delete-After"))
+			}
+			b, _ := ioutil.ReadAll(r.Body)
+			objs[r.URL.Path] = b
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			b, ok := objs[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(b)
+		}
+	}))
+	defer srv.Close()
+
+	s, err := NewSwift(srv.Client(), SwiftOpts{
+		AuthURL:   srv.URL + "/auth/v1.0",
+		Username:  "test:tester",
+		Password:  "testing",
+		Container: "layers",
+		TTL:       time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, "sha256/abc"); err != ErrNotFound {
+		t.Errorf("got: %v, want: %v", err, ErrNotFound)
+	}
+	b := []byte("layer contents")
+	if err := s.Put(ctx, "sha256/abc", bytes.NewReader(b), int64(len(b)), ""); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	token = "two"
+	mu.Unlock()
+	rc, err := s.Open(ctx, "sha256/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	got, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, b) {
+		t.Errorf("got: %q, want: %q", got, b)
+	}
+}
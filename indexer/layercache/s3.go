@@ -0,0 +1,166 @@
+package layercache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	awsauth "github.com/quay/clair/v4/internal/aws"
+)
+
+// S3 is a Store keeping layers in an S3 compatible bucket, like MinIO.
+//
+// Layers older than the TTL are treated as missing and deleted when next
+// read. A lifecycle rule on the bucket is needed to reclaim layers that are
+// never read again.
+type S3 struct {
+	c            *http.Client
+	endpoint     *url.URL
+	bucket       string
+	region       string
+	pathStyle    bool
+	storageClass string
+	creds        *awsauth.Provider
+	ttl          time.Duration
+}
+
+var _ Store = (*S3)(nil)
+
+// S3Opts configures an S3 store.
+type S3Opts struct {
+	Bucket string
+	Region string
+	// Endpoint is the API root. If empty, the AWS endpoint for the region is
+	// used.
+	Endpoint string
+	// PathStyle puts the bucket in the path instead of the hostname, as MinIO
+	// usually requires.
+	PathStyle    bool
+	StorageClass string
+	// Credentials provides credentials for requests. If nil, the default
+	// credential chain is used.
+	Credentials *awsauth.Provider
+	TTL         time.Duration
+}
+
+// NewS3 returns an S3 store. A nil client means http.DefaultClient.
+func NewS3(c *http.Client, opts S3Opts) (*S3, error) {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("s3 layer cache requires a bucket")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Credentials == nil {
+		opts.Credentials = awsauth.NewProvider(c, awsauth.Credentials{}, opts.Region, "")
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	ep := opts.Endpoint
+	if ep == "" {
+		ep = fmt.Sprintf("https://s3.%s.amazonaws.com/", opts.Region)
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse s3 endpoint: %v", err)
+	}
+	return &S3{
+		c:            c,
+		endpoint:     u,
+		bucket:       opts.Bucket,
+		region:       opts.Region,
+		pathStyle:    opts.PathStyle,
+		storageClass: opts.StorageClass,
+		creds:        opts.Credentials,
+		ttl:          opts.TTL,
+	}, nil
+}
+
+// ObjectURL returns the URL for the object at key.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return &u
+}
+
+// Do signs and sends a request without a body.
+func (s *S3) do(ctx context.Context, method, key string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := s.creds.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	awsauth.Sign(req, nil, creds, s.region, "s3", time.Now())
+	return s.c.Do(req)
+}
+
+// Open implements Store.
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, key)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer res.Body.Close()
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("unexpected response reading %q: %s: %s", key, res.Status, msg)
+	}
+	mod, err := http.ParseTime(res.Header.Get("Last-Modified"))
+	if err == nil && time.Since(mod) > s.ttl {
+		res.Body.Close()
+		if res, err := s.do(ctx, http.MethodDelete, key); err == nil {
+			res.Body.Close()
+		}
+		return nil, ErrNotFound
+	}
+	return res.Body, nil
+}
+
+// Put implements Store.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, sum string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), ioutil.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if s.storageClass != "" {
+		req.Header.Set("X-Amz-Storage-Class", s.storageClass)
+	}
+	creds, err := s.creds.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	awsauth.SignHashed(req, sum, creds, s.region, "s3", time.Now())
+	res, err := s.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected response writing %q: %s: %s", key, res.Status, msg)
+	}
+	return nil
+}
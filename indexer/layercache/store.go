@@ -0,0 +1,147 @@
+// Package layercache keeps the layers an indexer fetches in a Store, so
+// indexing a layer again, possibly from another indexer, doesn't fetch it
+// from the registry again.
+//
+// Besides a directory on local disk, layers can be kept in S3 compatible
+// object storage, like MinIO, or OpenStack Swift, for on-premises
+// deployments that share a cache between indexers.
+package layercache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultTTL is how long layers are kept if not configured.
+const DefaultTTL = 24 * time.Hour
+
+// SweepInterval is how often Filesystem.Run removes expired layers.
+const SweepInterval = 10 * time.Minute
+
+// ErrNotFound is returned by Store.Open for layers that aren't stored, or
+// have expired.
+var ErrNotFound = errors.New("layercache: layer not found")
+
+// Store holds layer contents.
+type Store interface {
+	// Open returns the contents stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Put stores size bytes read from r under key. The sum is the
+	// hex-encoded SHA-256 of the contents.
+	Put(ctx context.Context, key string, r io.Reader, size int64, sum string) error
+}
+
+// Filesystem is a Store keeping layers in a directory on local disk.
+type Filesystem struct {
+	dir string
+	ttl time.Duration
+}
+
+var _ Store = (*Filesystem)(nil)
+
+// NewFilesystem returns a Filesystem keeping layers in dir for ttl. The
+// directory is created if it doesn't exist.
+//
+// If dir is empty, a directory in os.TempDir is used.
+func NewFilesystem(dir string, ttl time.Duration) (*Filesystem, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "clair-layers")
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Filesystem{dir: dir, ttl: ttl}, nil
+}
+
+func (f *Filesystem) path(key string) string {
+	return filepath.Join(f.dir, strings.ReplaceAll(key, "/", "-"))
+}
+
+// Open implements Store.
+func (f *Filesystem) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	p := f.path(key)
+	fd, err := os.Open(p)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+	if time.Since(fi.ModTime()) > f.ttl {
+		fd.Close()
+		os.Remove(p)
+		return nil, ErrNotFound
+	}
+	return fd, nil
+}
+
+// Put implements Store.
+//
+// Layers are written to a temporary file and renamed into place, so they're
+// never seen partially written.
+func (f *Filesystem) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	tmp, err := ioutil.TempFile(f.dir, ".partial-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(key))
+}
+
+// Run removes expired layers until the Context is canceled.
+func (f *Filesystem) Run(ctx context.Context) {
+	t := time.NewTicker(SweepInterval)
+	defer t.Stop()
+	for {
+		f.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Sweep removes expired layers and abandoned partial ones.
+func (f *Filesystem) sweep(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/layercache/Filesystem.sweep").
+		Logger()
+	fs, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to list cached layers")
+		return
+	}
+	for _, fi := range fs {
+		if fi.IsDir() || time.Since(fi.ModTime()) < f.ttl {
+			continue
+		}
+		p := filepath.Join(f.dir, fi.Name())
+		if err := os.Remove(p); err != nil {
+			log.Warn().Err(err).Str("file", p).Msg("unable to remove cached layer")
+		}
+	}
+}
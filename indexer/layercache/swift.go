@@ -0,0 +1,269 @@
+package layercache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Swift is a Store keeping layers in an OpenStack Swift container.
+//
+// Layers are written with an "X-Delete-After" header, so Swift removes them
+// once they expire.
+type Swift struct {
+	c    *http.Client
+	opts SwiftOpts
+
+	mu      sync.Mutex
+	token   string
+	storage string
+}
+
+var _ Store = (*Swift)(nil)
+
+// SwiftOpts configures a Swift store.
+type SwiftOpts struct {
+	// AuthURL is the identity endpoint. A Keystone v3 endpoint, ending in
+	// "/v3", is authenticated against with a password. Anything else is
+	// treated as a v1 endpoint, like Swift's TempAuth.
+	AuthURL  string
+	Username string
+	Password string
+	// Project and Domain scope Keystone v3 tokens. The domain is also used
+	// for the user, and defaults to "Default".
+	Project string
+	Domain  string
+	// Region selects among the object-store endpoints in a Keystone v3
+	// catalog. If empty, the first public endpoint is used.
+	Region    string
+	Container string
+	TTL       time.Duration
+}
+
+// NewSwift returns a Swift store. A nil client means http.DefaultClient.
+func NewSwift(c *http.Client, opts SwiftOpts) (*Swift, error) {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	if opts.AuthURL == "" || opts.Container == "" {
+		return nil, fmt.Errorf("swift layer cache requires an auth_url and container")
+	}
+	if opts.Domain == "" {
+		opts.Domain = "Default"
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	return &Swift{c: c, opts: opts}, nil
+}
+
+// Do sends a request for the object at key, authenticating first if
+// needed, and once more if the token was rejected.
+func (s *Swift) do(ctx context.Context, method, key string, body io.Reader, size int64, h http.Header) (*http.Response, error) {
+	for retry := true; ; retry = false {
+		tok, storage, err := s.auth(ctx)
+		if err != nil {
+			return nil, err
+		}
+		u := strings.TrimSuffix(storage, "/") + "/" + s.opts.Container + "/" + key
+		req, err := http.NewRequestWithContext(ctx, method, u, body)
+		if err != nil {
+			return nil, err
+		}
+		for k, vs := range h {
+			req.Header[k] = vs
+		}
+		if body != nil {
+			req.Body = ioutil.NopCloser(body)
+			req.ContentLength = size
+		}
+		req.Header.Set("X-Auth-Token", tok)
+		res, err := s.c.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		// A body can't be resent, so uploads aren't retried.
+		if res.StatusCode != http.StatusUnauthorized || !retry || body != nil {
+			return res, nil
+		}
+		res.Body.Close()
+		s.mu.Lock()
+		s.token = ""
+		s.mu.Unlock()
+	}
+}
+
+// Open implements Store.
+func (s *Swift) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer res.Body.Close()
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("unexpected response reading %q: %s: %s", key, res.Status, msg)
+	}
+}
+
+// Put implements Store.
+func (s *Swift) Put(ctx context.Context, key string, r io.Reader, size int64, _ string) error {
+	h := http.Header{}
+	h.Set("Content-Type", "application/octet-stream")
+	h.Set("X-Delete-After", strconv.FormatInt(int64(s.opts.TTL/time.Second), 10))
+	res, err := s.do(ctx, http.MethodPut, key, r, size, h)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		if res.StatusCode == http.StatusUnauthorized {
+			s.mu.Lock()
+			s.token = ""
+			s.mu.Unlock()
+		}
+		return fmt.Errorf("unexpected response writing %q: %s: %s", key, res.Status, msg)
+	}
+	return nil
+}
+
+// Auth returns a token and the storage URL, authenticating if there's no
+// token.
+func (s *Swift) auth(ctx context.Context) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" {
+		return s.token, s.storage, nil
+	}
+	var err error
+	if strings.HasSuffix(strings.TrimSuffix(s.opts.AuthURL, "/"), "/v3") {
+		s.token, s.storage, err = s.keystone(ctx)
+	} else {
+		s.token, s.storage, err = s.tempAuth(ctx)
+	}
+	if err != nil {
+		s.token = ""
+		return "", "", fmt.Errorf("swift authentication failed: %w", err)
+	}
+	return s.token, s.storage, nil
+}
+
+// TempAuth authenticates against a v1 endpoint.
+func (s *Swift) tempAuth(ctx context.Context) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.opts.AuthURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("X-Auth-User", s.opts.Username)
+	req.Header.Set("X-Auth-Key", s.opts.Password)
+	res, err := s.c.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return "", "", fmt.Errorf("unexpected response: %s", res.Status)
+	}
+	tok, storage := res.Header.Get("X-Auth-Token"), res.Header.Get("X-Storage-Url")
+	if tok == "" || storage == "" {
+		return "", "", fmt.Errorf("response missing token or storage url")
+	}
+	return tok, storage, nil
+}
+
+// Keystone authenticates against a Keystone v3 endpoint with a password,
+// and finds the object-store endpoint in the catalog.
+func (s *Swift) keystone(ctx context.Context) (string, string, error) {
+	type name struct {
+		Name string `json:"name"`
+	}
+	var in struct {
+		Auth struct {
+			Identity struct {
+				Methods  []string `json:"methods"`
+				Password struct {
+					User struct {
+						Name     string `json:"name"`
+						Domain   name   `json:"domain"`
+						Password string `json:"password"`
+					} `json:"user"`
+				} `json:"password"`
+			} `json:"identity"`
+			Scope struct {
+				Project struct {
+					Name   string `json:"name"`
+					Domain name   `json:"domain"`
+				} `json:"project"`
+			} `json:"scope"`
+		} `json:"auth"`
+	}
+	in.Auth.Identity.Methods = []string{"password"}
+	in.Auth.Identity.Password.User.Name = s.opts.Username
+	in.Auth.Identity.Password.User.Domain.Name = s.opts.Domain
+	in.Auth.Identity.Password.User.Password = s.opts.Password
+	in.Auth.Scope.Project.Name = s.opts.Project
+	in.Auth.Scope.Project.Domain.Name = s.opts.Domain
+	b, err := json.Marshal(&in)
+	if err != nil {
+		return "", "", err
+	}
+	u := strings.TrimSuffix(s.opts.AuthURL, "/") + "/auth/tokens"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := s.c.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		return "", "", fmt.Errorf("unexpected response: %s", res.Status)
+	}
+	var out struct {
+		Token struct {
+			Catalog []struct {
+				Type      string `json:"type"`
+				Endpoints []struct {
+					Interface string `json:"interface"`
+					Region    string `json:"region"`
+					URL       string `json:"url"`
+				} `json:"endpoints"`
+			} `json:"catalog"`
+		} `json:"token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return "", "", err
+	}
+	tok := res.Header.Get("X-Subject-Token")
+	if tok == "" {
+		return "", "", fmt.Errorf("response missing token")
+	}
+	for _, svc := range out.Token.Catalog {
+		if svc.Type != "object-store" {
+			continue
+		}
+		for _, ep := range svc.Endpoints {
+			if ep.Interface == "public" && (s.opts.Region == "" || ep.Region == s.opts.Region) {
+				return tok, ep.URL, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("no object-store endpoint in catalog")
+}
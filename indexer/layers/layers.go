@@ -0,0 +1,156 @@
+// Package layers attributes the contents of index and vulnerability reports
+// to the layers that introduced them, so findings can be routed to whoever
+// owns a layer: the base image's maintainers or the application's.
+package layers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Layer is what one layer of a manifest introduced.
+type Layer struct {
+	Hash claircore.Digest `json:"hash"`
+	// Index is the layer's position in the manifest, starting from the
+	// base, or -1 if the order isn't known.
+	Index int `json:"index"`
+	// Packages are the IDs of the packages the layer introduced.
+	Packages []string `json:"packages"`
+	// Vulnerabilities are the IDs of the vulnerabilities affecting those
+	// packages. It's only present in vulnerability reports.
+	Vulnerabilities []string `json:"vulnerabilities,omitempty"`
+}
+
+// Attribute groups the packages of the environments by the layer that
+// introduced them.
+//
+// If order is the manifest's layers, in order, the result lists every layer
+// in that order, even ones that introduced nothing. Otherwise, only layers
+// that introduced packages are listed, ordered by digest, with an Index of
+// -1.
+func Attribute(envs map[string][]*claircore.Environment, order []claircore.Digest) []Layer {
+	byLayer := make(map[string][]string)
+	for id, es := range envs {
+		seen := make(map[string]bool, len(es))
+		for _, e := range es {
+			if e == nil {
+				continue
+			}
+			l := e.IntroducedIn.String()
+			if seen[l] {
+				continue
+			}
+			seen[l] = true
+			byLayer[l] = append(byLayer[l], id)
+		}
+	}
+	var out []Layer
+	if order != nil {
+		out = make([]Layer, len(order))
+		for i, d := range order {
+			out[i] = Layer{Hash: d, Index: i, Packages: byLayer[d.String()]}
+		}
+	} else {
+		out = make([]Layer, 0, len(byLayer))
+		for l, ids := range byLayer {
+			d, err := claircore.ParseDigest(l)
+			if err != nil {
+				continue
+			}
+			out = append(out, Layer{Hash: d, Index: -1, Packages: ids})
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Hash.String() < out[j].Hash.String() })
+	}
+	for i := range out {
+		if out[i].Packages == nil {
+			out[i].Packages = []string{}
+		}
+		sort.Strings(out[i].Packages)
+	}
+	return out
+}
+
+// AttributeVulnerabilities fills in the Vulnerabilities of the layers from
+// the report's package to vulnerability mapping.
+func AttributeVulnerabilities(ls []Layer, vr *claircore.VulnerabilityReport) {
+	for i := range ls {
+		seen := make(map[string]bool)
+		for _, p := range ls[i].Packages {
+			for _, v := range vr.PackageVulnerabilities[p] {
+				if !seen[v] {
+					seen[v] = true
+					ls[i].Vulnerabilities = append(ls[i].Vulnerabilities, v)
+				}
+			}
+		}
+		if ls[i].Vulnerabilities == nil {
+			ls[i].Vulnerabilities = []string{}
+		}
+		sort.Strings(ls[i].Vulnerabilities)
+	}
+}
+
+// Lister reports the layers of a manifest, in order.
+type Lister interface {
+	Layers(ctx context.Context, manifest claircore.Digest) ([]claircore.Digest, error)
+}
+
+// Indexer wraps an indexer.Service to report the layers of indexed
+// manifests, as recorded by libindex.
+type Indexer struct {
+	indexer.Service
+	pool *pgxpool.Pool
+}
+
+var (
+	_ indexer.Service = (*Indexer)(nil)
+	_ Lister          = (*Indexer)(nil)
+)
+
+// NewIndexer returns an Indexer reading from the indexer's database.
+func NewIndexer(s indexer.Service, pool *pgxpool.Pool) *Indexer {
+	return &Indexer{Service: s, pool: pool}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Layers implements Lister. A manifest that hasn't been indexed has no
+// layers.
+func (i *Indexer) Layers(ctx context.Context, manifest claircore.Digest) ([]claircore.Digest, error) {
+	const query = `SELECT l.hash
+FROM manifest_layer ml
+JOIN manifest m ON m.id = ml.manifest_id
+JOIN layer l ON l.id = ml.layer_id
+WHERE m.hash = $1
+ORDER BY ml.i;`
+	rows, err := i.pool.Query(ctx, query, manifest.String())
+	if err != nil {
+		return nil, fmt.Errorf("layers: failed to query layers: %w", err)
+	}
+	defer rows.Close()
+	out := []claircore.Digest{}
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, fmt.Errorf("layers: failed to read layer: %w", err)
+		}
+		d, err := claircore.ParseDigest(s)
+		if err != nil {
+			return nil, fmt.Errorf("layers: bad layer digest: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("layers: failed to query layers: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,53 @@
+package layers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestAttribute(t *testing.T) {
+	base := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	mid := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	app := claircore.MustParseDigest("sha256:" + strings.Repeat("c", 64))
+	envs := map[string][]*claircore.Environment{
+		"1": {{IntroducedIn: base}},
+		"2": {{IntroducedIn: app}, {IntroducedIn: app}},
+		"3": {{IntroducedIn: base}, nil},
+	}
+	vr := &claircore.VulnerabilityReport{
+		PackageVulnerabilities: map[string][]string{
+			"1": {"v1", "v2"},
+			"3": {"v1"},
+		},
+	}
+
+	t.Run("Ordered", func(t *testing.T) {
+		got := Attribute(envs, []claircore.Digest{base, mid, app})
+		AttributeVulnerabilities(got, vr)
+		want := []Layer{
+			{Hash: base, Index: 0, Packages: []string{"1", "3"}, Vulnerabilities: []string{"v1", "v2"}},
+			{Hash: mid, Index: 1, Packages: []string{}, Vulnerabilities: []string{}},
+			{Hash: app, Index: 2, Packages: []string{"2"}, Vulnerabilities: []string{}},
+		}
+		if !cmp.Equal(got, want, cmp.Comparer(digestEqual)) {
+			t.Error(cmp.Diff(got, want, cmp.Comparer(digestEqual)))
+		}
+	})
+	t.Run("Unordered", func(t *testing.T) {
+		got := Attribute(envs, nil)
+		want := []Layer{
+			{Hash: base, Index: -1, Packages: []string{"1", "3"}},
+			{Hash: app, Index: -1, Packages: []string{"2"}},
+		}
+		if !cmp.Equal(got, want, cmp.Comparer(digestEqual)) {
+			t.Error(cmp.Diff(got, want, cmp.Comparer(digestEqual)))
+		}
+	})
+}
+
+func digestEqual(a, b claircore.Digest) bool {
+	return a.String() == b.String()
+}
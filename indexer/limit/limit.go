@@ -0,0 +1,164 @@
+// Package limit bounds how much indexing work happens at once.
+package limit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer"
+)
+
+// ErrQueueFull is returned by Index when too many requests are already
+// waiting, or a request waited longer than the queue timeout.
+var ErrQueueFull = clairerror.New(clairerror.Overloaded, "limit: too many index requests queued")
+
+// Indexer wraps an indexer.Service, indexing at most a fixed number of
+// manifests at once. Other requests wait in a queue, in no particular order.
+type Indexer struct {
+	indexer.Service
+	sem     chan struct{}
+	queue   int64
+	timeout time.Duration
+
+	waiting  int64
+	queued   metric.Int64UpDownCounter
+	rejected metric.Int64Counter
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer indexing at most n manifests at once.
+//
+// If queue is positive, requests arriving while that many are already
+// waiting are rejected. If timeout is positive, requests waiting longer are
+// rejected.
+func NewIndexer(s indexer.Service, n, queue int, timeout time.Duration) *Indexer {
+	meter := metric.Must(otel.Meter("clair"))
+	return &Indexer{
+		Service: s,
+		sem:     make(chan struct{}, n),
+		queue:   int64(queue),
+		timeout: timeout,
+		queued: meter.NewInt64UpDownCounter(
+			"clair_indexer_index_queued",
+			metric.WithDescription("number of index requests waiting to start"),
+		),
+		rejected: meter.NewInt64Counter(
+			"clair_indexer_index_rejected_total",
+			metric.WithDescription("number of index requests rejected because the queue was full"),
+		),
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	select {
+	case i.sem <- struct{}{}:
+	default:
+		if err := i.wait(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+	defer func() { <-i.sem }()
+	return i.Service.Index(ctx, m)
+}
+
+// Wait waits for a slot in the semaphore, giving up if the queue is full or
+// the wait is too long.
+func (i *Indexer) wait(ctx context.Context, m *claircore.Manifest) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/limit/Indexer.wait").
+		Str("manifest", m.Hash.String()).
+		Logger()
+	n := atomic.AddInt64(&i.waiting, 1)
+	defer atomic.AddInt64(&i.waiting, -1)
+	if i.queue > 0 && n > i.queue {
+		i.rejected.Add(ctx, 1)
+		log.Debug().Msg("queue full")
+		return ErrQueueFull
+	}
+	i.queued.Add(ctx, 1)
+	defer i.queued.Add(ctx, -1)
+	var timeout <-chan time.Time
+	if i.timeout > 0 {
+		t := time.NewTimer(i.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	log.Debug().Msg("waiting to index")
+	select {
+	case i.sem <- struct{}{}:
+		return nil
+	case <-timeout:
+		i.rejected.Add(ctx, 1)
+		return ErrQueueFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Transport is an http.RoundTripper making at most a fixed number of
+// requests at once. A request counts until its response body is closed, so
+// it limits downloads, not just round trips.
+type Transport struct {
+	next http.RoundTripper
+	sem  chan struct{}
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// NewTransport returns a Transport making at most n requests with next at
+// once. A nil next means http.DefaultTransport.
+func NewTransport(next http.RoundTripper, n int) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{
+		next: next,
+		sem:  make(chan struct{}, n),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		<-t.sem
+		return nil, err
+	}
+	res.Body = &releaser{ReadCloser: res.Body, sem: t.sem}
+	return res, nil
+}
+
+// Releaser frees a slot in the semaphore when closed.
+type releaser struct {
+	io.ReadCloser
+	sem  chan struct{}
+	done int32
+}
+
+func (r *releaser) Close() error {
+	if atomic.CompareAndSwapInt32(&r.done, 0, 1) {
+		<-r.sem
+	}
+	return r.ReadCloser.Close()
+}
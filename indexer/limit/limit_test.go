@@ -0,0 +1,102 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// TestIndexer checks that index requests past the limit wait, and that
+// ones past the queue are refused.
+func TestIndexer(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var n int32
+	mock := &indexer.Mock{
+		Index_: func(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			atomic.AddInt32(&n, 1)
+			started <- struct{}{}
+			<-release
+			return &claircore.IndexReport{Hash: m.Hash}, nil
+		},
+	}
+	i := NewIndexer(mock, 1, 1, 0)
+
+	var wg sync.WaitGroup
+	index := func(i *Indexer) {
+		defer wg.Done()
+		if _, err := i.Index(ctx, &claircore.Manifest{}); err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Add(2)
+	go index(i)
+	<-started
+	go index(i)
+	// Wait for the second request to be queued.
+	for atomic.LoadInt64(&i.waiting) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := i.Index(ctx, &claircore.Manifest{}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("got: %v, want: %v", err, ErrQueueFull)
+	}
+	if got, want := atomic.LoadInt32(&n), int32(1); got != want {
+		t.Errorf("got: %d running, want: %d", got, want)
+	}
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	wg.Wait()
+
+	t.Run("Timeout", func(t *testing.T) {
+		i := NewIndexer(mock, 1, 0, 10*time.Millisecond)
+		wg.Add(1)
+		go index(i)
+		<-started
+		if _, err := i.Index(ctx, &claircore.Manifest{}); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("got: %v, want: %v", err, ErrQueueFull)
+		}
+		release <- struct{}{}
+		wg.Wait()
+	})
+}
+
+// TestTransport checks that a request holds its slot until the response body
+// is closed.
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	c := &http.Client{Transport: NewTransport(srv.Client().Transport, 1)}
+
+	res, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got: %v, want: %v", err, context.DeadlineExceeded)
+	}
+	res.Body.Close()
+	res, err = c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
@@ -0,0 +1,118 @@
+// Package mediatype decides which layers of a submitted manifest are
+// indexed, by their media types.
+//
+// Manifests submitted to the indexer may name each layer's media type.
+// Foreign layers, like the base layers of Windows images, are
+// non-distributable: registries don't serve them, and they're fetched from
+// the URLs in their descriptors instead. Layers of other artifacts, like
+// Helm charts or signatures, aren't filesystems at all. Layers without a
+// media type are always indexed.
+package mediatype
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Layer media types the indexer can read.
+const (
+	DockerLayer  = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+	OCILayer     = "application/vnd.oci.image.layer.v1.tar"
+	OCILayerGzip = "application/vnd.oci.image.layer.v1.tar+gzip"
+	OCILayerZstd = "application/vnd.oci.image.layer.v1.tar+zstd"
+
+	DockerForeignLayer      = "application/vnd.docker.image.rootfs.foreign.diff.tar.gzip"
+	OCINondistributable     = "application/vnd.oci.image.layer.nondistributable.v1.tar"
+	OCINondistributableGzip = "application/vnd.oci.image.layer.nondistributable.v1.tar+gzip"
+	OCINondistributableZstd = "application/vnd.oci.image.layer.nondistributable.v1.tar+zstd"
+)
+
+// ErrUnsupported is returned for manifests with layers the indexer can't
+// read.
+var ErrUnsupported = errors.New("unsupported layer media type")
+
+// Foreign reports whether the media type is that of a foreign, or
+// non-distributable, layer.
+func Foreign(mt string) bool {
+	switch base(mt) {
+	case DockerForeignLayer, OCINondistributable, OCINondistributableGzip, OCINondistributableZstd:
+		return true
+	}
+	return false
+}
+
+// Supported reports whether the indexer can read layers with the media type.
+// An empty media type is taken to be supported.
+func Supported(mt string) bool {
+	switch base(mt) {
+	case "", DockerLayer, OCILayer, OCILayerGzip, OCILayerZstd:
+		return true
+	}
+	return Foreign(mt)
+}
+
+// Base returns the media type without parameters.
+func base(mt string) string {
+	if i := strings.IndexByte(mt, ';'); i != -1 {
+		mt = mt[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// Layer is a layer of a submitted manifest, with its media type.
+type Layer struct {
+	claircore.Layer
+	MediaType string `json:"media_type,omitempty"`
+}
+
+// Manifest is a submitted manifest, with the media types of its layers.
+type Manifest struct {
+	Hash   claircore.Digest `json:"hash"`
+	Layers []*Layer         `json:"layers"`
+}
+
+// Policy decides which layers are indexed.
+//
+// The zero Policy indexes foreign layers and rejects manifests with
+// unsupported layers.
+type Policy struct {
+	// SkipForeign leaves out foreign layers.
+	SkipForeign bool
+	// SkipUnsupported leaves out layers with unsupported media types,
+	// instead of rejecting the manifest.
+	SkipUnsupported bool
+}
+
+// Apply returns the manifest to index, and the layers that were left out of
+// it.
+//
+// ErrUnsupported is returned if the manifest has a layer with an unsupported
+// media type and the Policy doesn't skip those, or if every layer was left
+// out.
+func (p Policy) Apply(m *Manifest) (*claircore.Manifest, []*Layer, error) {
+	out := claircore.Manifest{
+		Hash:   m.Hash,
+		Layers: make([]*claircore.Layer, 0, len(m.Layers)),
+	}
+	var skipped []*Layer
+	for _, l := range m.Layers {
+		switch {
+		case l == nil:
+			continue
+		case !Supported(l.MediaType) && !p.SkipUnsupported:
+			return nil, nil, fmt.Errorf("%w: layer %v is %q", ErrUnsupported, l.Hash, l.MediaType)
+		case !Supported(l.MediaType), Foreign(l.MediaType) && p.SkipForeign:
+			skipped = append(skipped, l)
+			continue
+		}
+		l := l.Layer
+		out.Layers = append(out.Layers, &l)
+	}
+	if len(out.Layers) == 0 && len(skipped) != 0 {
+		return nil, nil, fmt.Errorf("%w: no layers left to index", ErrUnsupported)
+	}
+	return &out, skipped, nil
+}
@@ -0,0 +1,62 @@
+package mediatype
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestPolicy(t *testing.T) {
+	digest := func(c string) claircore.Digest {
+		return claircore.MustParseDigest("sha256:" + strings.Repeat(c, 64))
+	}
+	layer := func(c, mt string) *Layer {
+		return &Layer{Layer: claircore.Layer{Hash: digest(c)}, MediaType: mt}
+	}
+	m := &Manifest{
+		Hash: digest("f"),
+		Layers: []*Layer{
+			layer("a", DockerForeignLayer),
+			layer("b", OCILayerGzip+"; foo=bar"),
+			layer("c", ""),
+			layer("d", "application/vnd.cncf.helm.chart.content.v1.tar+gzip"),
+		},
+	}
+	for _, tc := range []struct {
+		Name    string
+		Policy  Policy
+		Layers  int
+		Skipped int
+		Err     bool
+	}{
+		{Name: "Default", Err: true},
+		{Name: "SkipUnsupported", Policy: Policy{SkipUnsupported: true}, Layers: 3, Skipped: 1},
+		{Name: "SkipBoth", Policy: Policy{SkipForeign: true, SkipUnsupported: true}, Layers: 2, Skipped: 2},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, skipped, err := tc.Policy.Apply(m)
+			if tc.Err {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("got: %v, want: %v", err, ErrUnsupported)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Hash.String() != m.Hash.String() {
+				t.Errorf("got hash: %v, want: %v", got.Hash, m.Hash)
+			}
+			if len(got.Layers) != tc.Layers || len(skipped) != tc.Skipped {
+				t.Errorf("got: %d layers, %d skipped, want: %d layers, %d skipped", len(got.Layers), len(skipped), tc.Layers, tc.Skipped)
+			}
+		})
+	}
+
+	only := &Manifest{Hash: digest("f"), Layers: []*Layer{layer("a", DockerForeignLayer)}}
+	if _, _, err := (Policy{SkipForeign: true}).Apply(only); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("got: %v, want: %v", err, ErrUnsupported)
+	}
+}
@@ -0,0 +1,155 @@
+package referrers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Indexer wraps an indexer.Service and discovers manifests' referrers after
+// they're indexed, recording them and adding the packages of attached SBOMs
+// to the index reports it returns.
+//
+// Manifests are stored as the indexer found them, so the SBOM packages are
+// added every time a report is read. They aren't considered when finding
+// affected manifests.
+//
+// It needs to be wrapped by anything adding credentials to layers, so the
+// same credentials can be used to fetch referrers.
+type Indexer struct {
+	indexer.Service
+	f     *Fetcher
+	pool  *pgxpool.Pool
+	sboms bool
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer using the provided Fetcher and recording
+// referrers in the database behind pool, which must be the indexer's
+// database.
+//
+// If sboms is set, packages from attached SBOMs are added to index reports.
+func NewIndexer(s indexer.Service, f *Fetcher, pool *pgxpool.Pool, sboms bool) *Indexer {
+	return &Indexer{
+		Service: s,
+		f:       f,
+		pool:    pool,
+		sboms:   sboms,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+const (
+	upsertReferrers = `
+INSERT INTO manifest_referrers (manifest_hash, referrers, sbom, checked)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (manifest_hash) DO UPDATE SET
+	referrers = EXCLUDED.referrers,
+	sbom = EXCLUDED.sbom,
+	checked = EXCLUDED.checked;`
+	selectReferrers = `
+SELECT referrers FROM manifest_referrers WHERE manifest_hash = $1;`
+	selectSBOM = `
+SELECT sbom FROM manifest_referrers WHERE manifest_hash = $1;`
+)
+
+// Index implements indexer.Indexer.
+//
+// Referrers are discovered every time a manifest is indexed, so artifacts
+// attached since are picked up. Failing to discover them doesn't fail
+// indexing; the last referrers discovered are kept.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/referrers/Indexer.Index").
+		Str("manifest", m.Hash.String()).
+		Logger()
+	ir, err := i.Service.Index(ctx, m)
+	if err != nil || !ir.Success {
+		return ir, err
+	}
+	rs, extra, err := i.f.Discover(ctx, m, i.sboms)
+	switch {
+	case errors.Is(err, ErrNoRegistry):
+		return ir, nil
+	case err != nil:
+		log.Warn().Err(err).Msg("unable to discover referrers")
+		return i.merge(ctx, ir)
+	}
+	log.Debug().
+		Int("count", len(rs)).
+		Msg("discovered referrers")
+	if rs == nil {
+		rs = []Referrer{}
+	}
+	var sb interface{}
+	if extra != nil {
+		sb = extra
+	}
+	if _, err := i.pool.Exec(ctx, upsertReferrers, m.Hash.String(), rs, sb, time.Now()); err != nil {
+		return nil, fmt.Errorf("referrers: failed to record referrers: %w", err)
+	}
+	return Merge(ir, extra, ""), nil
+}
+
+// IndexReport implements indexer.Reporter.
+func (i *Indexer) IndexReport(ctx context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	ir, ok, err := i.Service.IndexReport(ctx, d)
+	if !ok || err != nil {
+		return ir, ok, err
+	}
+	ir, err = i.merge(ctx, ir)
+	if err != nil {
+		return nil, false, err
+	}
+	return ir, true, nil
+}
+
+// Merge adds the recorded SBOM packages to the report.
+func (i *Indexer) merge(ctx context.Context, ir *claircore.IndexReport) (*claircore.IndexReport, error) {
+	if !i.sboms {
+		return ir, nil
+	}
+	var b []byte
+	err := i.pool.QueryRow(ctx, selectSBOM, ir.Hash.String()).Scan(&b)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ir, nil
+	case err != nil:
+		return nil, fmt.Errorf("referrers: failed to read sbom packages: %w", err)
+	case b == nil:
+		return ir, nil
+	}
+	var extra claircore.IndexReport
+	if err := json.Unmarshal(b, &extra); err != nil {
+		return nil, fmt.Errorf("referrers: malformed sbom packages: %w", err)
+	}
+	return Merge(ir, &extra, ""), nil
+}
+
+// Referrers returns the recorded referrers of the manifest, or nil if
+// they've never been discovered.
+func (i *Indexer) Referrers(ctx context.Context, d claircore.Digest) ([]Referrer, error) {
+	var rs []Referrer
+	err := i.pool.QueryRow(ctx, selectReferrers, d.String()).Scan(&rs)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("referrers: failed to read referrers: %w", err)
+	}
+	return rs, nil
+}
@@ -0,0 +1,121 @@
+package referrers
+
+import (
+	"github.com/quay/claircore"
+)
+
+// Merge returns a copy of the report with the packages of extra added,
+// along with their distributions, repositories and environments. Neither
+// report is modified.
+//
+// Packages with the same name and version as one already in the report are
+// skipped. Added records have their IDs prefixed, so they don't collide
+// with the report's own; distributions and repositories already in the
+// report are reused instead.
+func Merge(ir, extra *claircore.IndexReport, prefix string) *claircore.IndexReport {
+	out := *ir
+	if extra == nil || len(extra.Packages) == 0 {
+		return &out
+	}
+	out.Packages = make(map[string]*claircore.Package, len(ir.Packages)+len(extra.Packages))
+	out.Distributions = make(map[string]*claircore.Distribution, len(ir.Distributions)+len(extra.Distributions))
+	out.Repositories = make(map[string]*claircore.Repository, len(ir.Repositories)+len(extra.Repositories))
+	out.Environments = make(map[string][]*claircore.Environment, len(ir.Environments)+len(extra.Environments))
+	type nv struct{ name, version string }
+	seen := make(map[nv]bool, len(ir.Packages))
+	for id, p := range ir.Packages {
+		out.Packages[id] = p
+		seen[nv{p.Name, p.Version}] = true
+	}
+	for id, d := range ir.Distributions {
+		out.Distributions[id] = d
+	}
+	for id, r := range ir.Repositories {
+		out.Repositories[id] = r
+	}
+	for id, es := range ir.Environments {
+		out.Environments[id] = es
+	}
+
+	// Map the extra report's distributions and repositories to IDs in the
+	// output, only adding them when first used.
+	dists := make(map[string]string)
+	dist := func(id string) string {
+		if id == "" {
+			return ""
+		}
+		if nid, ok := dists[id]; ok {
+			return nid
+		}
+		d, ok := extra.Distributions[id]
+		if !ok {
+			return ""
+		}
+		nid := prefix + id
+		for oid, od := range ir.Distributions {
+			if od.DID == d.DID && od.VersionID == d.VersionID {
+				nid = oid
+				break
+			}
+		}
+		if _, ok := out.Distributions[nid]; !ok {
+			nd := *d
+			nd.ID = nid
+			out.Distributions[nid] = &nd
+		}
+		dists[id] = nid
+		return nid
+	}
+	repos := make(map[string]string)
+	repo := func(id string) string {
+		if nid, ok := repos[id]; ok {
+			return nid
+		}
+		r, ok := extra.Repositories[id]
+		if !ok {
+			return ""
+		}
+		nid := prefix + id
+		for oid, or := range ir.Repositories {
+			if or.Name == r.Name && or.URI == r.URI {
+				nid = oid
+				break
+			}
+		}
+		if _, ok := out.Repositories[nid]; !ok {
+			nr := *r
+			nr.ID = nid
+			out.Repositories[nid] = &nr
+		}
+		repos[id] = nid
+		return nid
+	}
+
+	for id, p := range extra.Packages {
+		k := nv{p.Name, p.Version}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		np := *p
+		np.ID = prefix + id
+		out.Packages[np.ID] = &np
+		var es []*claircore.Environment
+		for _, e := range extra.Environments[id] {
+			if e == nil {
+				continue
+			}
+			ne := *e
+			ne.DistributionID = dist(e.DistributionID)
+			ne.RepositoryIDs = nil
+			for _, rid := range e.RepositoryIDs {
+				if nid := repo(rid); nid != "" {
+					ne.RepositoryIDs = append(ne.RepositoryIDs, nid)
+				}
+			}
+			es = append(es, &ne)
+		}
+		out.Environments[np.ID] = es
+	}
+	return &out
+}
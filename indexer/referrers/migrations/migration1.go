@@ -0,0 +1,16 @@
+package migrations
+
+const (
+	// migration1 adds a table recording the artifacts attached to a manifest
+	// and the packages found in its attached SBOMs.
+	migration1 = `
+	--- a relation recording the latest referrers discovered for a manifest
+	CREATE TABLE IF NOT EXISTS manifest_referrers
+	(
+		manifest_hash text PRIMARY KEY,
+		referrers     jsonb NOT NULL,
+		sbom          jsonb,
+		checked       timestamptz NOT NULL
+	);
+	`
+)
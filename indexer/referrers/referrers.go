@@ -0,0 +1,305 @@
+// Package referrers discovers the artifacts attached to manifests in their
+// registry, such as SBOMs and provenance attestations, with the OCI
+// referrers API.
+//
+// Packages listed in attached SBOMs can be added to index reports, to cover
+// what the indexer can't find in the layers themselves.
+package referrers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/sbom"
+)
+
+// These are the kinds of referrers.
+const (
+	SBOM       = "sbom"
+	Provenance = "provenance"
+	Signature  = "signature"
+	Other      = "other"
+)
+
+// Referrer is an artifact attached to a manifest.
+type Referrer struct {
+	// Digest is the digest of the referrer's manifest.
+	Digest string `json:"digest"`
+	// ArtifactType is the referrer's artifact type, e.g.
+	// "application/spdx+json".
+	ArtifactType string `json:"artifact_type"`
+	// Kind is one of SBOM, Provenance, Signature or Other.
+	Kind string `json:"kind"`
+	// Annotations are the referrer's annotations, which usually include
+	// when and by what it was created.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Packages is the number of packages added from an SBOM.
+	Packages int `json:"packages,omitempty"`
+	// Error is why an SBOM couldn't be used, if it couldn't.
+	Error string `json:"error,omitempty"`
+}
+
+// Kinds maps artifact types to kinds of referrers.
+var kinds = map[string]string{
+	"application/spdx+json":                            SBOM,
+	"application/vnd.cyclonedx+json":                   SBOM,
+	"application/vnd.in-toto+json":                     Provenance,
+	"application/vnd.dev.sigstore.bundle+json":         Provenance,
+	"application/vnd.dev.sigstore.bundle.v0.3+json":    Provenance,
+	"application/vnd.dev.cosign.artifact.sig.v1+json":  Signature,
+	"application/vnd.cncf.notary.signature":            Signature,
+	"application/vnd.dev.cosign.simplesigning.v1+json": Signature,
+}
+
+// Kind returns the kind of referrer for an artifact type.
+func kind(artifactType string) string {
+	// Media type parameters, like a CycloneDX version, don't change the
+	// kind.
+	if i := strings.IndexByte(artifactType, ';'); i != -1 {
+		artifactType = artifactType[:i]
+	}
+	if k, ok := kinds[strings.TrimSpace(artifactType)]; ok {
+		return k
+	}
+	return Other
+}
+
+// MaxResponse bounds the size of manifests and SBOMs read from a registry.
+const maxResponse = 32 << 20
+
+// MaxReferrers bounds the referrers considered for a manifest.
+const maxReferrers = 64
+
+const (
+	mediaOCIIndex    = `application/vnd.oci.image.index.v1+json`
+	mediaOCIManifest = `application/vnd.oci.image.manifest.v1+json`
+)
+
+// ErrNoRegistry is returned by Discover for manifests whose layers aren't
+// fetched from a registry.
+var ErrNoRegistry = errors.New("layers aren't fetched from a registry")
+
+// Fetcher discovers manifests' referrers.
+type Fetcher struct {
+	client *http.Client
+}
+
+// NewFetcher returns a Fetcher using the provided client, or
+// http.DefaultClient if nil.
+func NewFetcher(c *http.Client) *Fetcher {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return &Fetcher{client: c}
+}
+
+// Descriptor is the part of an OCI descriptor used here.
+type descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType"`
+	Annotations  map[string]string `json:"annotations"`
+}
+
+// Discover returns the referrers of the manifest, found in the registry its
+// layers are fetched from. If sboms is set, it also returns an index report
+// of the packages in the attached SBOMs, or nil if there are none.
+//
+// Requests reuse the first layer's headers, so credentials added for the
+// layers are used for the referrers, too. Registries without the referrers
+// API are asked for the referrers tag instead.
+func (f *Fetcher) Discover(ctx context.Context, m *claircore.Manifest, sboms bool) ([]Referrer, *claircore.IndexReport, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/referrers/Fetcher.Discover").
+		Str("manifest", m.Hash.String()).
+		Logger()
+	if len(m.Layers) == 0 {
+		return nil, nil, ErrNoRegistry
+	}
+	l := m.Layers[0]
+	u, err := url.Parse(l.URI)
+	if err != nil {
+		return nil, nil, err
+	}
+	i := strings.LastIndex(u.Path, "/blobs/")
+	if !strings.HasPrefix(u.Path, "/v2/") || i == -1 {
+		return nil, nil, ErrNoRegistry
+	}
+	repo := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path[:i+1]}
+
+	b, err := f.get(ctx, repo, "referrers/"+m.Hash.String(), l.Headers, mediaOCIIndex)
+	if errors.Is(err, errNotFound) {
+		// The referrers tag schema, for registries that predate the API.
+		tag := strings.Replace(m.Hash.String(), ":", "-", 1)
+		b, err = f.get(ctx, repo, "manifests/"+tag, l.Headers, mediaOCIIndex)
+	}
+	switch {
+	case errors.Is(err, errNotFound):
+		return nil, nil, nil
+	case err != nil:
+		return nil, nil, err
+	}
+	var idx struct {
+		Manifests []descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, nil, fmt.Errorf("malformed referrers index: %w", err)
+	}
+	if len(idx.Manifests) > maxReferrers {
+		log.Warn().
+			Int("count", len(idx.Manifests)).
+			Int("max", maxReferrers).
+			Msg("too many referrers, ignoring the rest")
+		idx.Manifests = idx.Manifests[:maxReferrers]
+	}
+
+	var out []Referrer
+	var report *claircore.IndexReport
+	for _, d := range idx.Manifests {
+		r := Referrer{
+			Digest:       d.Digest,
+			ArtifactType: d.ArtifactType,
+			Annotations:  d.Annotations,
+		}
+		r.Kind = kind(r.ArtifactType)
+		if r.Kind == SBOM && sboms {
+			ir, err := f.sbom(ctx, repo, d, l.Headers)
+			if err != nil {
+				log.Info().Err(err).
+					Str("referrer", d.Digest).
+					Msg("unable to use attached sbom")
+				r.Error = err.Error()
+			} else {
+				r.Packages = len(ir.Packages)
+				prefix := fmt.Sprintf("sbom%d-", len(out))
+				if report == nil {
+					report = &claircore.IndexReport{Hash: m.Hash}
+				}
+				report = Merge(report, ir, prefix)
+			}
+		}
+		out = append(out, r)
+	}
+	return out, report, nil
+}
+
+// Sbom fetches the SBOM referrer and returns an index report of its
+// packages.
+func (f *Fetcher) sbom(ctx context.Context, repo *url.URL, d descriptor, h map[string][]string) (*claircore.IndexReport, error) {
+	dg, err := claircore.ParseDigest(d.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("malformed referrer digest: %w", err)
+	}
+	b, err := f.blob(ctx, repo, "manifests/", dg, h, mediaOCIManifest)
+	if err != nil {
+		return nil, err
+	}
+	var man struct {
+		Layers []descriptor `json:"layers"`
+		Blobs  []descriptor `json:"blobs"`
+	}
+	if err := json.Unmarshal(b, &man); err != nil {
+		return nil, fmt.Errorf("malformed referrer manifest: %w", err)
+	}
+	// Artifact manifests name their contents "blobs"; image manifests
+	// repurposed for artifacts name them "layers".
+	ls := append(man.Layers, man.Blobs...)
+	if len(ls) == 0 {
+		return nil, errors.New("referrer has no contents")
+	}
+	// The document is the layer with an SBOM media type, or the only layer.
+	doc := ls[0]
+	for _, l := range ls {
+		if kind(l.MediaType) == SBOM {
+			doc = l
+			break
+		}
+	}
+	if doc.Size > maxResponse {
+		return nil, errors.New("sbom too large")
+	}
+	dg, err = claircore.ParseDigest(doc.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("malformed sbom digest: %w", err)
+	}
+	b, err = f.blob(ctx, repo, "blobs/", dg, h, "")
+	if err != nil {
+		return nil, err
+	}
+	sd, err := sbom.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	return sd.IndexReport(ctx)
+}
+
+// Blob fetches the content addressed by the digest and checks it matches.
+func (f *Fetcher) blob(ctx context.Context, repo *url.URL, prefix string, d claircore.Digest, h map[string][]string, accept string) ([]byte, error) {
+	if d.Algorithm() != "sha256" {
+		return nil, fmt.Errorf("unsupported digest algorithm %q", d.Algorithm())
+	}
+	b, err := f.get(ctx, repo, prefix+d.String(), h, accept)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(b); !bytes.Equal(sum[:], d.Checksum()) {
+		return nil, fmt.Errorf("%q doesn't match its digest", d)
+	}
+	return b, nil
+}
+
+// ErrNotFound is used internally for 404 responses.
+var errNotFound = errors.New("not found")
+
+// Get fetches p, relative to the repository URL.
+func (f *Fetcher) get(ctx context.Context, repo *url.URL, p string, h map[string][]string, accept string) ([]byte, error) {
+	u, err := repo.Parse(p)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range h {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if accept != "" {
+		req.Header.Set("accept", accept)
+	}
+	res, err := f.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", u.Path, errNotFound)
+	default:
+		return nil, fmt.Errorf("%s: unexpected response: %s", u.Path, res.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponse+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxResponse {
+		return nil, fmt.Errorf("%s: response too large", u.Path)
+	}
+	return b, nil
+}
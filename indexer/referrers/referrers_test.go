@@ -0,0 +1,223 @@
+package referrers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+const testManifest = `sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08`
+
+// Registry is a test registry serving referrers from memory.
+type registry struct {
+	*httptest.Server
+	content map[string][]byte
+}
+
+func newRegistry(t *testing.T) *registry {
+	r := &registry{content: make(map[string][]byte)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("authorization"), "Bearer t0k3n"; got != want {
+			t.Errorf("authorization: got: %q, want: %q", got, want)
+		}
+		b, ok := r.content[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	}))
+	return r
+}
+
+func (r *registry) manifest() *claircore.Manifest {
+	return &claircore.Manifest{
+		Hash: claircore.MustParseDigest(testManifest),
+		Layers: []*claircore.Layer{{
+			URI:     r.URL + "/v2/library/app/blobs/sha256:" + strings.Repeat("0", 64),
+			Headers: map[string][]string{"Authorization": {"Bearer t0k3n"}},
+		}},
+	}
+}
+
+// Put stores the content under its digest, returning the digest.
+func (r *registry) put(kind string, b []byte) string {
+	sum := sha256.Sum256(b)
+	d := "sha256:" + hex.EncodeToString(sum[:])
+	r.content["/v2/library/app/"+kind+"/"+d] = b
+	return d
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Attach adds an SBOM and a provenance attestation referring to the test
+// manifest, returning the referrers index.
+func (r *registry) attach(t *testing.T) []byte {
+	doc, err := ioutil.ReadFile(filepath.Join("..", "..", "sbom", "testdata", "cyclonedx.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dd := r.put("blobs", doc)
+	sm := r.put("manifests", mustJSON(t, map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     mediaOCIManifest,
+		"artifactType":  "application/vnd.cyclonedx+json",
+		"layers": []interface{}{map[string]interface{}{
+			"mediaType": "application/vnd.cyclonedx+json",
+			"digest":    dd,
+			"size":      len(doc),
+		}},
+	}))
+	return mustJSON(t, map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     mediaOCIIndex,
+		"manifests": []interface{}{
+			map[string]interface{}{
+				"mediaType":    mediaOCIManifest,
+				"digest":       sm,
+				"artifactType": "application/vnd.cyclonedx+json",
+			},
+			map[string]interface{}{
+				"mediaType":    mediaOCIManifest,
+				"digest":       "sha256:" + strings.Repeat("1", 64),
+				"artifactType": "application/vnd.in-toto+json",
+				"annotations": map[string]string{
+					"org.opencontainers.image.created": "2021-01-01T00:00:00Z",
+				},
+			},
+		},
+	})
+}
+
+func TestDiscover(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	for _, tc := range []struct {
+		Name string
+		Path string
+	}{
+		{Name: "API", Path: "/v2/library/app/referrers/" + testManifest},
+		{Name: "Tag", Path: "/v2/library/app/manifests/" + strings.Replace(testManifest, ":", "-", 1)},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := newRegistry(t)
+			defer r.Close()
+			r.content[tc.Path] = r.attach(t)
+			f := NewFetcher(r.Client())
+
+			rs, ir, err := f.Discover(ctx, r.manifest(), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rs) != 2 {
+				t.Fatalf("got %d referrers, want 2", len(rs))
+			}
+			if got, want := rs[0].Kind, SBOM; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+			if rs[0].Error != "" || rs[0].Packages == 0 {
+				t.Errorf("unexpected sbom referrer: %+v", rs[0])
+			}
+			if got, want := rs[1].Kind, Provenance; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+			if rs[1].Annotations["org.opencontainers.image.created"] == "" {
+				t.Errorf("missing annotations: %+v", rs[1])
+			}
+			if ir == nil || len(ir.Packages) != rs[0].Packages {
+				t.Fatalf("unexpected sbom report: %+v", ir)
+			}
+			for id := range ir.Packages {
+				if !strings.HasPrefix(id, "sbom0-") {
+					t.Errorf("unprefixed package ID %q", id)
+				}
+			}
+
+			// Without SBOM ingestion, SBOMs are only recorded.
+			rs, ir, err = f.Discover(ctx, r.manifest(), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rs) != 2 || ir != nil || rs[0].Packages != 0 {
+				t.Errorf("unexpected result: %+v, %+v", rs, ir)
+			}
+		})
+	}
+
+	t.Run("None", func(t *testing.T) {
+		r := newRegistry(t)
+		defer r.Close()
+		rs, ir, err := NewFetcher(r.Client()).Discover(ctx, r.manifest(), true)
+		if err != nil || rs != nil || ir != nil {
+			t.Errorf("unexpected result: %+v, %+v, %v", rs, ir, err)
+		}
+	})
+
+	t.Run("NoRegistry", func(t *testing.T) {
+		m := &claircore.Manifest{
+			Hash:   claircore.MustParseDigest(testManifest),
+			Layers: []*claircore.Layer{{URI: "https://example.com/layer.tar"}},
+		}
+		if _, _, err := NewFetcher(nil).Discover(ctx, m, true); err != ErrNoRegistry {
+			t.Errorf("got: %v, want: %v", err, ErrNoRegistry)
+		}
+	})
+}
+
+func TestMerge(t *testing.T) {
+	debian := &claircore.Distribution{ID: "1", DID: "debian", VersionID: "10"}
+	ir := &claircore.IndexReport{
+		Hash:          claircore.MustParseDigest(testManifest),
+		Packages:      map[string]*claircore.Package{"1": {ID: "1", Name: "openssl", Version: "1.1.1d"}},
+		Distributions: map[string]*claircore.Distribution{"1": debian},
+		Environments:  map[string][]*claircore.Environment{"1": {{DistributionID: "1"}}},
+	}
+	extra := &claircore.IndexReport{
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1d"},
+			"2": {ID: "2", Name: "jinja2", Version: "2.10"},
+			"3": {ID: "3", Name: "zlib", Version: "1.2.11"},
+		},
+		Distributions: map[string]*claircore.Distribution{"1": {ID: "1", DID: "debian", VersionID: "10"}},
+		Repositories:  map[string]*claircore.Repository{"1": {ID: "1", Name: "pypi"}},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+			"2": {{RepositoryIDs: []string{"1"}}},
+			"3": {{DistributionID: "1"}},
+		},
+	}
+	got := Merge(ir, extra, "x-")
+	if len(ir.Packages) != 1 {
+		t.Error("original report modified")
+	}
+	if len(got.Packages) != 3 {
+		t.Fatalf("got %d packages, want 3", len(got.Packages))
+	}
+	if _, ok := got.Packages["x-1"]; ok {
+		t.Error("duplicate package added")
+	}
+	if got, want := got.Environments["x-3"][0].DistributionID, "1"; got != want {
+		t.Errorf("distribution: got: %q, want: %q", got, want)
+	}
+	if got, want := got.Environments["x-2"][0].RepositoryIDs, []string{"x-1"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("repositories: got: %q, want: %q", got, want)
+	}
+	if len(got.Distributions) != 1 || len(got.Repositories) != 1 {
+		t.Errorf("unexpected distributions or repositories: %v, %v", got.Distributions, got.Repositories)
+	}
+}
@@ -0,0 +1,262 @@
+// Package registry adds credentials to layer requests, so that manifests
+// submitted for indexing don't need layer URLs with embedded authorization.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+)
+
+// DefaultTokenLifetime is used for tokens that don't say when they expire,
+// per the distribution token spec.
+const DefaultTokenLifetime = 60 * time.Second
+
+// Authorizer computes Authorization headers for layer URLs based on the
+// configured per-registry credentials.
+type Authorizer struct {
+	creds  map[string]Credential
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedHeader
+}
+
+type cachedHeader struct {
+	value  string
+	expire time.Time
+}
+
+// NewAuthorizer returns an Authorizer using the provided credentials, keyed
+// by registry hostname (including the port, if any).
+//
+// If client is nil, http.DefaultClient is used.
+func NewAuthorizer(creds map[string]Credential, client *http.Client) (*Authorizer, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	cs := make(map[string]Credential, len(creds))
+	for host, c := range creds {
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("registry %q: %w", host, err)
+		}
+		if c.Cloud != "" {
+			cc, err := newCloudCredential(c.Cloud, c.ClientID, client)
+			if err != nil {
+				return nil, fmt.Errorf("registry %q: %w", host, err)
+			}
+			c.cloud = cc
+		}
+		cs[host] = c
+	}
+	return &Authorizer{
+		creds:  cs,
+		client: client,
+		cache:  make(map[string]cachedHeader),
+	}, nil
+}
+
+// Authorize adds an Authorization header to the layer, if it's fetched from a
+// configured registry and doesn't already have one.
+func (a *Authorizer) Authorize(ctx context.Context, l *claircore.Layer) error {
+	if hasAuthorization(l.Headers) {
+		return nil
+	}
+	u, err := url.Parse(l.URI)
+	if err != nil {
+		return err
+	}
+	cred, ok := a.creds[u.Host]
+	if !ok {
+		return nil
+	}
+	h, err := a.header(ctx, u, &cred)
+	if err != nil {
+		return fmt.Errorf("registry %q: %w", u.Host, err)
+	}
+	if h == "" {
+		return nil
+	}
+	if l.Headers == nil {
+		l.Headers = make(map[string][]string)
+	}
+	l.Headers["Authorization"] = []string{h}
+	return nil
+}
+
+func hasAuthorization(h map[string][]string) bool {
+	for k := range h {
+		if strings.EqualFold(k, "authorization") {
+			return true
+		}
+	}
+	return false
+}
+
+// Header returns the Authorization header value for the URL, from the cache
+// if possible.
+func (a *Authorizer) header(ctx context.Context, u *url.URL, c *Credential) (string, error) {
+	if c.Token != "" {
+		return "Bearer " + c.Token, nil
+	}
+	repo := repository(u.Path)
+	key := u.Host + "/" + repo
+	a.mu.Lock()
+	ch, ok := a.cache[key]
+	a.mu.Unlock()
+	if ok && time.Now().Before(ch.expire) {
+		return ch.value, nil
+	}
+
+	v, lifetime, err := a.exchange(ctx, u, repo, c)
+	if err != nil {
+		return "", err
+	}
+	a.mu.Lock()
+	// Leave some slack so a header isn't handed out just before it expires.
+	a.cache[key] = cachedHeader{value: v, expire: time.Now().Add(lifetime * 9 / 10)}
+	a.mu.Unlock()
+	return v, nil
+}
+
+// Repository pulls the repository name out of a blob URL path, like
+// "/v2/library/ubuntu/blobs/sha256:...".
+func repository(p string) string {
+	p = strings.TrimPrefix(p, "/v2/")
+	if i := strings.LastIndex(p, "/blobs/"); i != -1 {
+		p = p[:i]
+	}
+	return p
+}
+
+// Exchange follows the registry's authentication challenge. See
+// https://docs.docker.com/registry/spec/auth/token/ for the protocol.
+func (a *Authorizer) exchange(ctx context.Context, u *url.URL, repo string, c *Credential) (string, time.Duration, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/registry/Authorizer.exchange").
+		Str("registry", u.Host).
+		Str("repository", repo).
+		Logger()
+	user, pass, err := c.basic(ctx, u.Host)
+	if err != nil {
+		return "", 0, err
+	}
+
+	ping := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/v2/"}
+	req, err := http.NewRequest(http.MethodGet, ping.String(), nil)
+	if err != nil {
+		return "", 0, err
+	}
+	res, err := a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", 0, err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		// No authentication needed, apparently.
+		log.Debug().Int("status", res.StatusCode).Msg("registry didn't challenge")
+		return "", DefaultTokenLifetime, nil
+	}
+	scheme, params := parseChallenge(res.Header.Get("www-authenticate"))
+	switch strings.ToLower(scheme) {
+	case "basic":
+		req.SetBasicAuth(user, pass)
+		return req.Header.Get("authorization"), DefaultTokenLifetime, nil
+	case "bearer":
+	default:
+		return "", 0, fmt.Errorf("unsupported challenge %q", scheme)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", 0, fmt.Errorf("bad challenge realm %q", params["realm"])
+	}
+	q := realm.Query()
+	if s := params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	q.Set("scope", "repository:"+repo+":pull")
+	realm.RawQuery = q.Encode()
+	req, err = http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.SetBasicAuth(user, pass)
+	res, err = a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token request failed: %s", res.Status)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tok); err != nil {
+		return "", 0, fmt.Errorf("bad token response: %w", err)
+	}
+	t := tok.Token
+	if t == "" {
+		t = tok.AccessToken
+	}
+	if t == "" {
+		return "", 0, fmt.Errorf("token response contained no token")
+	}
+	lifetime := DefaultTokenLifetime
+	if tok.ExpiresIn > 0 {
+		lifetime = time.Duration(tok.ExpiresIn) * time.Second
+	}
+	log.Debug().Dur("lifetime", lifetime).Msg("exchanged credentials for token")
+	return "Bearer " + t, lifetime, nil
+}
+
+// ParseChallenge parses a WWW-Authenticate header of the form:
+//
+//	Bearer realm="https://auth.example.com/token",service="example.com"
+func parseChallenge(h string) (string, map[string]string) {
+	h = strings.TrimSpace(h)
+	i := strings.IndexByte(h, ' ')
+	if i == -1 {
+		return h, nil
+	}
+	scheme, rest := h[:i], h[i+1:]
+	params := make(map[string]string)
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.IndexByte(rest, '=')
+		if eq == -1 {
+			break
+		}
+		k := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+		var v string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end == -1 {
+				v, rest = rest[1:], ""
+			} else {
+				v, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end == -1 {
+				v, rest = rest, ""
+			} else {
+				v, rest = rest[:end], rest[end:]
+			}
+		}
+		params[k] = v
+	}
+	return scheme, params
+}
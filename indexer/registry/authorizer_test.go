@@ -0,0 +1,198 @@
+package registry
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+// NewRegistry returns a test server implementing the token flow, expecting
+// the provided username and password.
+func newRegistry(t *testing.T, user, pass string) (*httptest.Server, *int) {
+	var exchanges int
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("www-authenticate", `Bearer realm="`+srv.URL+`/token",service="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok || u != user || p != pass {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		q := r.URL.Query()
+		if got, want := q.Get("scope"), "repository:library/ubuntu:pull"; got != want {
+			t.Errorf("scope: got: %q, want: %q", got, want)
+		}
+		if got, want := q.Get("service"), "test"; got != want {
+			t.Errorf("service: got: %q, want: %q", got, want)
+		}
+		exchanges++
+		w.Header().Set("content-type", "application/json")
+		w.Write([]byte(`{"token":"t0k3n","expires_in":300}`))
+	})
+	srv = httptest.NewServer(mux)
+	return srv, &exchanges
+}
+
+func testLayer(host string) *claircore.Layer {
+	return &claircore.Layer{
+		URI: "http://" + host + "/v2/library/ubuntu/blobs/sha256:" +
+			"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	srv, exchanges := newRegistry(t, "user", "pass")
+	defer srv.Close()
+	host := srv.Listener.Addr().String()
+
+	t.Run("Basic", func(t *testing.T) {
+		a, err := NewAuthorizer(map[string]Credential{
+			host: {Username: "user", Password: "pass"},
+		}, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		*exchanges = 0
+		for i := 0; i < 3; i++ {
+			l := testLayer(host)
+			if err := a.Authorize(ctx, l); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := l.Headers["Authorization"], "Bearer t0k3n"; len(got) != 1 || got[0] != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+		}
+		if got, want := *exchanges, 1; got != want {
+			t.Errorf("token exchanges: got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("BadPassword", func(t *testing.T) {
+		a, err := NewAuthorizer(map[string]Credential{
+			host: {Username: "user", Password: "wrong"},
+		}, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Authorize(ctx, testLayer(host)); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("Token", func(t *testing.T) {
+		a, err := NewAuthorizer(map[string]Credential{
+			host: {Token: "static"},
+		}, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := testLayer(host)
+		if err := a.Authorize(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := l.Headers["Authorization"], "Bearer static"; len(got) != 1 || got[0] != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	})
+	t.Run("Existing", func(t *testing.T) {
+		a, err := NewAuthorizer(map[string]Credential{
+			host: {Token: "static"},
+		}, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := testLayer(host)
+		l.Headers = map[string][]string{"authorization": {"Basic Zm9vOmJhcg=="}}
+		if err := a.Authorize(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := l.Headers["Authorization"]; ok {
+			t.Error("existing header overwritten")
+		}
+	})
+	t.Run("Unconfigured", func(t *testing.T) {
+		a, err := NewAuthorizer(map[string]Credential{
+			"registry.example.com": {Token: "static"},
+		}, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := testLayer(host)
+		if err := a.Authorize(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+		if l.Headers != nil {
+			t.Errorf("unexpected headers: %v", l.Headers)
+		}
+	})
+	t.Run("Helper", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("helper is a shell script")
+		}
+		dir, err := ioutil.TempDir("", "registry")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		script := "#!/bin/sh\ncat >/dev/null\necho '{\"Username\":\"user\",\"Secret\":\"pass\"}'\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, "docker-credential-test"), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		path := os.Getenv("PATH")
+		os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+		defer os.Setenv("PATH", path)
+
+		a, err := NewAuthorizer(map[string]Credential{
+			host: {Helper: "test"},
+		}, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := testLayer(host)
+		if err := a.Authorize(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := l.Headers["Authorization"], "Bearer t0k3n"; len(got) != 1 || got[0] != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	})
+}
+
+func TestNewAuthorizerInvalid(t *testing.T) {
+	for _, c := range []Credential{
+		{},
+		{Token: "a", Username: "b"},
+		{Helper: "ecr-login", Token: "a"},
+	} {
+		if _, err := NewAuthorizer(map[string]Credential{"example.com": c}, nil); err == nil {
+			t.Errorf("%+v: expected error", c)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="example.com",scope=repository:a/b:pull`)
+	if got, want := scheme, "Bearer"; got != want {
+		t.Errorf("scheme: got: %q, want: %q", got, want)
+	}
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "example.com",
+		"scope":   "repository:a/b:pull",
+	}
+	for k, v := range want {
+		if got := params[k]; got != v {
+			t.Errorf("%s: got: %q, want: %q", k, got, v)
+		}
+	}
+}
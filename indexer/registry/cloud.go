@@ -0,0 +1,221 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	awsauth "github.com/quay/clair/v4/internal/aws"
+	"github.com/quay/clair/v4/internal/azure"
+	"github.com/quay/clair/v4/internal/gcp"
+)
+
+// These are the cloud identities a Credential can use in place of a stored
+// secret.
+const (
+	// CloudAWS uses the AWS credentials found in the environment, e.g. from
+	// IRSA or an instance role, to request an ECR authorization token.
+	CloudAWS = "aws"
+	// CloudGCP uses an access token from the metadata server, e.g. from GKE
+	// workload identity, for Container Registry and Artifact Registry.
+	CloudGCP = "gcp"
+	// CloudAzure uses a managed identity token, exchanged for an ACR refresh
+	// token.
+	CloudAzure = "azure"
+)
+
+// CloudCredential returns registry credentials derived from a cloud identity.
+type cloudCredential interface {
+	basic(ctx context.Context, host string) (string, string, error)
+}
+
+// NewCloudCredential returns the cloudCredential for the named cloud.
+func newCloudCredential(cloud, clientID string, c *http.Client) (cloudCredential, error) {
+	switch cloud {
+	case CloudAWS:
+		return &ecrCredential{
+			c:     c,
+			creds: awsauth.NewProvider(c, awsauth.Credentials{}, "", ""),
+		}, nil
+	case CloudGCP:
+		return &gcrCredential{
+			token: gcp.NewTokenSource(c, nil, gcp.RegistryScope).Token,
+		}, nil
+	case CloudAzure:
+		return &acrCredential{
+			c:      c,
+			token:  azure.NewManagedIdentity(c, azure.ManagementResource, clientID).Authorization,
+			scheme: "https",
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown cloud %q", cloud)
+	}
+}
+
+// EcrHost matches ECR registry hostnames, capturing the region and the
+// partition's domain suffix.
+var ecrHost = regexp.MustCompile(`^\d{12}\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.(amazonaws\.com(?:\.cn)?)$`)
+
+// EcrCredential requests authorization tokens from ECR's
+// GetAuthorizationToken API.
+type ecrCredential struct {
+	c     *http.Client
+	creds *awsauth.Provider
+	// endpoint overrides the regional API endpoint, for tests.
+	endpoint string
+
+	mu    sync.Mutex
+	cache map[string]ecrToken
+}
+
+type ecrToken struct {
+	user, pass string
+	expire     time.Time
+}
+
+func (e *ecrCredential) basic(ctx context.Context, host string) (string, string, error) {
+	ms := ecrHost.FindStringSubmatch(host)
+	if ms == nil {
+		return "", "", fmt.Errorf("%q is not an ECR registry", host)
+	}
+	region := ms[1]
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if t, ok := e.cache[region]; ok && time.Now().Before(t.expire) {
+		return t.user, t.pass, nil
+	}
+
+	creds, err := e.creds.Credentials(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	u := e.endpoint
+	if u == "" {
+		u = fmt.Sprintf("https://api.ecr.%s.%s/", region, ms[2])
+	}
+	body := []byte(`{}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+	awsauth.Sign(req, body, creds, region, "ecr", time.Now())
+	res, err := e.c.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to request ecr token: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", "", fmt.Errorf("unexpected response requesting ecr token: %s: %s", res.Status, msg)
+	}
+	var out struct {
+		Data []struct {
+			Token     string  `json:"authorizationToken"`
+			ExpiresAt float64 `json:"expiresAt"`
+		} `json:"authorizationData"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return "", "", fmt.Errorf("failed to decode ecr token: %w", err)
+	}
+	if len(out.Data) == 0 {
+		return "", "", fmt.Errorf("ecr returned no token")
+	}
+	// The token is the base64 encoding of "user:password".
+	b, err := base64.StdEncoding.DecodeString(out.Data[0].Token)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode ecr token: %w", err)
+	}
+	i := bytes.IndexByte(b, ':')
+	if i == -1 {
+		return "", "", fmt.Errorf("malformed ecr token")
+	}
+	t := ecrToken{
+		user: string(b[:i]),
+		pass: string(b[i+1:]),
+		// Tokens last 12 hours; refresh well before that.
+		expire: time.Unix(int64(out.Data[0].ExpiresAt), 0).Add(-10 * time.Minute),
+	}
+	if e.cache == nil {
+		e.cache = make(map[string]ecrToken)
+	}
+	e.cache[region] = t
+	return t.user, t.pass, nil
+}
+
+// GcrCredential uses an OAuth2 access token as the password, which both
+// Container Registry and Artifact Registry accept.
+type gcrCredential struct {
+	token func(context.Context) (string, error)
+}
+
+func (g *gcrCredential) basic(ctx context.Context, _ string) (string, string, error) {
+	t, err := g.token(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return "oauth2accesstoken", t, nil
+}
+
+// AcrCredential exchanges an Azure AD token for an ACR refresh token, which
+// is used as the password for the registry's token service.
+//
+// See https://github.com/Azure/acr/blob/main/docs/AAD-OAuth.md for the
+// protocol.
+type acrCredential struct {
+	c *http.Client
+	// token returns an Authorization header value for Azure AD.
+	token  func(context.Context) (string, error)
+	scheme string
+}
+
+// AcrUser is the username ACR expects alongside a refresh token.
+const acrUser = "00000000-0000-0000-0000-000000000000"
+
+func (a *acrCredential) basic(ctx context.Context, host string) (string, string, error) {
+	h, err := a.token(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	v := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"access_token": {strings.TrimPrefix(h, "Bearer ")},
+	}
+	u := url.URL{Scheme: a.scheme, Host: host, Path: "/oauth2/exchange"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(v.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := a.c.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to exchange acr token: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", "", fmt.Errorf("unexpected response exchanging acr token: %s: %s", res.Status, msg)
+	}
+	var out struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return "", "", fmt.Errorf("failed to decode acr token: %w", err)
+	}
+	if out.RefreshToken == "" {
+		return "", "", fmt.Errorf("acr returned no refresh token")
+	}
+	return acrUser, out.RefreshToken, nil
+}
@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	awsauth "github.com/quay/clair/v4/internal/aws"
+)
+
+func TestECRCredential(t *testing.T) {
+	ctx := context.Background()
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if got, want := r.Header.Get("X-Amz-Target"), "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken"; got != want {
+			t.Errorf("target: got: %q, want: %q", got, want)
+		}
+		if got, want := r.Header.Get("Authorization"), "/us-west-2/ecr/aws4_request"; !strings.Contains(got, want) {
+			t.Errorf("authorization: got: %q, want scope: %q", got, want)
+		}
+		tok := base64.StdEncoding.EncodeToString([]byte("AWS:s3cr3t"))
+		exp := time.Now().Add(12 * time.Hour).Unix()
+		fmt.Fprintf(w, `{"authorizationData":[{"authorizationToken":%q,"expiresAt":%d}]}`, tok, exp)
+	}))
+	defer srv.Close()
+
+	e := &ecrCredential{
+		c: srv.Client(),
+		creds: awsauth.NewProvider(srv.Client(), awsauth.Credentials{
+			AccessKeyID:     "AKID",
+			SecretAccessKey: "SECRET",
+		}, "", ""),
+		endpoint: srv.URL,
+	}
+	for i := 0; i < 2; i++ {
+		u, p, err := e.basic(ctx, "123456789012.dkr.ecr.us-west-2.amazonaws.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u != "AWS" || p != "s3cr3t" {
+			t.Errorf("got: %q:%q, want: %q:%q", u, p, "AWS", "s3cr3t")
+		}
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("token requests: got: %d, want: %d", got, want)
+	}
+
+	if _, _, err := e.basic(ctx, "quay.io"); err == nil {
+		t.Error("expected error for non-ECR host")
+	}
+}
+
+func TestACRCredential(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/oauth2/exchange"; got != want {
+			t.Errorf("path: got: %q, want: %q", got, want)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if got, want := r.PostForm.Get("grant_type"), "access_token"; got != want {
+			t.Errorf("grant_type: got: %q, want: %q", got, want)
+		}
+		if got, want := r.PostForm.Get("access_token"), "aad"; got != want {
+			t.Errorf("access_token: got: %q, want: %q", got, want)
+		}
+		if got, want := r.PostForm.Get("service"), r.Host; got != want {
+			t.Errorf("service: got: %q, want: %q", got, want)
+		}
+		fmt.Fprint(w, `{"refresh_token":"refresh"}`)
+	}))
+	defer srv.Close()
+
+	a := &acrCredential{
+		c: srv.Client(),
+		token: func(context.Context) (string, error) {
+			return "Bearer aad", nil
+		},
+		scheme: "http",
+	}
+	u, p, err := a.basic(ctx, srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != acrUser || p != "refresh" {
+		t.Errorf("got: %q:%q, want: %q:%q", u, p, acrUser, "refresh")
+	}
+}
+
+func TestCloudValidate(t *testing.T) {
+	for _, c := range []Credential{
+		{Cloud: "ibm"},
+		{Cloud: CloudAWS, ClientID: "id"},
+		{Cloud: CloudGCP, Token: "t0k3n"},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v: expected error", c)
+		}
+	}
+	if err := (&Credential{Cloud: CloudAzure, ClientID: "id"}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Credential is how to authenticate to a registry.
+//
+// Exactly one of Token, Helper, Cloud, or Username and Password should be
+// set.
+type Credential struct {
+	// Username and Password are exchanged for a bearer token using the
+	// registry's token service, or sent as-is if the registry asks for basic
+	// authentication.
+	Username string
+	Password string
+	// Token is a bearer token sent as-is.
+	Token string
+	// Helper is the name of a docker credential helper, e.g. "ecr-login" or
+	// "gcr". The program "docker-credential-<Helper>" must be in the PATH,
+	// and is asked for a username and password on every token exchange.
+	Helper string
+	// Cloud is the cloud whose workload identity is exchanged for registry
+	// credentials: one of CloudAWS, CloudGCP, or CloudAzure.
+	Cloud string
+	// ClientID selects a user-assigned managed identity when Cloud is
+	// CloudAzure. If empty, the system-assigned identity is used.
+	ClientID string
+	cloud    cloudCredential
+}
+
+// Validate reports whether the Credential is well-formed.
+func (c *Credential) Validate() error {
+	n := 0
+	if c.Token != "" {
+		n++
+	}
+	if c.Helper != "" {
+		n++
+	}
+	if c.Cloud != "" {
+		n++
+	}
+	if c.Username != "" || c.Password != "" {
+		n++
+	}
+	switch n {
+	case 0:
+		return fmt.Errorf("no credential provided")
+	case 1:
+	default:
+		return fmt.Errorf("only one of token, helper, cloud, or username and password may be provided")
+	}
+	switch c.Cloud {
+	case "", CloudAWS, CloudGCP:
+		if c.ClientID != "" {
+			return fmt.Errorf("client id is only used with cloud %q", CloudAzure)
+		}
+	case CloudAzure:
+	default:
+		return fmt.Errorf("unknown cloud %q", c.Cloud)
+	}
+	return nil
+}
+
+// Basic returns the username and password for the credential, running the
+// helper or asking the cloud if needed.
+func (c *Credential) basic(ctx context.Context, host string) (string, string, error) {
+	if c.cloud != nil {
+		return c.cloud.basic(ctx, host)
+	}
+	if c.Helper == "" {
+		return c.Username, c.Password, nil
+	}
+	// See https://github.com/docker/docker-credential-helpers for the
+	// protocol.
+	cmd := exec.CommandContext(ctx, "docker-credential-"+c.Helper, "get")
+	cmd.Stdin = strings.NewReader(host)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("credential helper %q failed: %w: %s", c.Helper, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	var res struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return "", "", fmt.Errorf("credential helper %q: bad output: %w", c.Helper, err)
+	}
+	return res.Username, res.Secret, nil
+}
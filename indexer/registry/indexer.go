@@ -0,0 +1,52 @@
+package registry
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Indexer wraps an indexer.Service and authorizes layer requests before
+// handing manifests to it.
+type Indexer struct {
+	indexer.Service
+	auth *Authorizer
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer using the provided Authorizer.
+func NewIndexer(s indexer.Service, a *Authorizer) *Indexer {
+	return &Indexer{
+		Service: s,
+		auth:    a,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+//
+// Layers that can't be authorized are passed along untouched; fetching them
+// will fail with a more useful error if they really did need credentials.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/registry/Indexer.Index").
+		Str("manifest", m.Hash.String()).
+		Logger()
+	for _, l := range m.Layers {
+		if err := i.auth.Authorize(ctx, l); err != nil {
+			log.Warn().
+				Err(err).
+				Str("layer", l.Hash.String()).
+				Msg("unable to authorize layer request")
+		}
+	}
+	return i.Service.Index(ctx, m)
+}
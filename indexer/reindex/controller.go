@@ -0,0 +1,310 @@
+// Package reindex indexes manifests again after the scanners that produced
+// their index reports are upgraded.
+//
+// Libindex only notices a manifest was scanned by old scanners when it's
+// submitted again, so until then its index report is stale. Claircore records
+// which scanner versions scanned each manifest, but not where the layers came
+// from, so the Recorder keeps that in a side table and the Controller
+// resubmits stale manifests a few at a time.
+package reindex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+const (
+	// DefaultInterval is the default time between reindexing batches.
+	DefaultInterval = time.Minute
+	// DefaultBatchSize is the default number of manifests reindexed per
+	// batch.
+	DefaultBatchSize = 10
+	// RetryAfter is how long a manifest that failed to reindex is left
+	// alone.
+	RetryAfter = 24 * time.Hour
+)
+
+// Scanner identifies a version of a scanner.
+type Scanner struct {
+	Name    string
+	Version string
+	Kind    string
+}
+
+// Opts configures a Controller.
+type Opts struct {
+	// Scanners are the scanners the indexer currently runs.
+	Scanners []Scanner
+	// Interval is the time between batches.
+	Interval time.Duration
+	// BatchSize is the number of manifests reindexed per batch, which
+	// together with Interval limits the rate of reindexing.
+	BatchSize int
+}
+
+// Controller periodically reindexes manifests scanned by older versions of
+// the current scanners.
+type Controller struct {
+	pool *pgxpool.Pool
+	idx  indexer.Indexer
+	opts Opts
+
+	names, versions, kinds []string
+
+	reindexed metric.Int64Counter
+	failed    metric.Int64Counter
+
+	drainOnce sync.Once
+	drain     chan struct{}
+}
+
+// NewController returns a Controller finding stale manifests in the database
+// behind pool, which must be the indexer's database, and reindexing them with
+// idx.
+func NewController(pool *pgxpool.Pool, idx indexer.Indexer, opts Opts) *Controller {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	meter := metric.Must(otel.Meter("clair"))
+	c := &Controller{
+		pool:  pool,
+		idx:   idx,
+		opts:  opts,
+		drain: make(chan struct{}),
+		reindexed: meter.NewInt64Counter(
+			"clair_indexer_reindex_manifests_total",
+			metric.WithDescription("number of manifests indexed again after a scanner upgrade"),
+		),
+		failed: meter.NewInt64Counter(
+			"clair_indexer_reindex_manifests_failed_total",
+			metric.WithDescription("number of manifests that failed to be indexed again after a scanner upgrade"),
+		),
+	}
+	for _, s := range opts.Scanners {
+		c.names = append(c.names, s.Name)
+		c.versions = append(c.versions, s.Version)
+		c.kinds = append(c.kinds, s.Kind)
+	}
+	return c
+}
+
+// Drain makes Run return once the batch in progress, if any, is done,
+// instead of starting another one. The batch still stops early if Run's
+// context is canceled.
+func (c *Controller) Drain() {
+	c.drainOnce.Do(func() { close(c.drain) })
+}
+
+// Run reindexes on the configured interval until the context is canceled,
+// or until Drain is called and the batch in progress is done.
+func (c *Controller) Run(ctx context.Context) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/reindex/Controller.Run").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().
+		Str("interval", c.opts.Interval.String()).
+		Int("batch_size", c.opts.BatchSize).
+		Msg("starting reindex controller")
+
+	t := time.NewTicker(c.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.drain:
+			return nil
+		default:
+		}
+		n, err := c.Reindex(ctx)
+		switch {
+		case err != nil:
+			log.Error().Err(err).Msg("reindex failed")
+		case n != 0:
+			log.Info().Int("manifests", n).Msg("reindexed stale manifests")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.drain:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// LockKey is the advisory lock taken while claiming manifests, so that
+// multiple indexers don't reindex the same ones.
+var lockKey = func() int64 {
+	h := fnv.New64a()
+	io.WriteString(h, "clair-indexer-reindex")
+	return int64(h.Sum64())
+}()
+
+const (
+	tryLock = `SELECT pg_try_advisory_xact_lock($1);`
+	// Rows for manifests that no longer exist, e.g. because they were
+	// garbage collected, are dropped. Recent rows are kept, as the manifest
+	// may still be being indexed.
+	pruneManifests = `
+DELETE FROM reindex_manifest r
+WHERE r.recorded < now() - '1 hour'::interval
+	AND NOT EXISTS (SELECT 1 FROM manifest m WHERE m.hash = r.manifest_hash);`
+	// A manifest is stale if it was scanned by a different version of a
+	// current scanner, and not by the current version.
+	selectStale = `
+WITH current (name, version, kind) AS (
+	SELECT * FROM unnest($1::text[], $2::text[], $3::text[])
+)
+SELECT r.manifest_hash, r.layers
+FROM reindex_manifest r
+JOIN manifest m ON m.hash = r.manifest_hash
+WHERE (r.attempted IS NULL OR r.attempted < $4::timestamptz)
+	AND EXISTS (
+		SELECT 1
+		FROM scanned_manifest sm
+		JOIN scanner s ON s.id = sm.scanner_id
+		JOIN current c ON c.name = s.name AND c.kind = s.kind AND c.version <> s.version
+		WHERE sm.manifest_id = m.id
+			AND NOT EXISTS (
+				SELECT 1
+				FROM scanned_manifest sm2
+				JOIN scanner s2 ON s2.id = sm2.scanner_id
+				WHERE sm2.manifest_id = m.id
+					AND s2.name = c.name AND s2.kind = c.kind AND s2.version = c.version
+			)
+	)
+ORDER BY r.recorded
+LIMIT $5;`
+	claimManifests = `
+UPDATE reindex_manifest SET attempted = now() WHERE manifest_hash = ANY($1);`
+)
+
+// Reindex claims one batch of stale manifests and reindexes them, reporting
+// how many succeeded.
+//
+// If another process is claiming manifests, Reindex does nothing.
+func (c *Controller) Reindex(ctx context.Context) (int, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/reindex/Controller.Reindex").
+		Logger()
+	ms, err := c.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, m := range ms {
+		ir, err := c.idx.Index(ctx, m)
+		if err == nil && !ir.Success {
+			err = fmt.Errorf("index failed: %s", ir.Err)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return n, ctx.Err()
+			}
+			log.Warn().Err(err).
+				Str("manifest", m.Hash.String()).
+				Msg("failed to reindex manifest")
+			c.failed.Add(ctx, 1)
+			continue
+		}
+		n++
+		c.reindexed.Add(ctx, 1)
+	}
+	return n, nil
+}
+
+// Claim returns a batch of stale manifests, marking them as attempted so
+// they aren't picked up again until RetryAfter has passed.
+func (c *Controller) claim(ctx context.Context) ([]*claircore.Manifest, error) {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var ok bool
+	if err := tx.QueryRow(ctx, tryLock, lockKey).Scan(&ok); err != nil {
+		return nil, err
+	}
+	if !ok {
+		zerolog.Ctx(ctx).Debug().Msg("another process is reindexing")
+		return nil, nil
+	}
+	if _, err := tx.Exec(ctx, pruneManifests); err != nil {
+		return nil, err
+	}
+
+	var ms []*claircore.Manifest
+	var hashes []string
+	rows, err := tx.Query(ctx, selectStale,
+		c.names, c.versions, c.kinds, time.Now().Add(-RetryAfter), c.opts.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var hash string
+		var b []byte
+		if err := rows.Scan(&hash, &b); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m, err := manifest(hash, b)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ms = append(ms, m)
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	if _, err := tx.Exec(ctx, claimManifests, hashes); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// Manifest rebuilds a manifest from its recorded layers.
+func manifest(hash string, b []byte) (*claircore.Manifest, error) {
+	d, err := claircore.ParseDigest(hash)
+	if err != nil {
+		return nil, err
+	}
+	var ls []layer
+	if err := json.Unmarshal(b, &ls); err != nil {
+		return nil, err
+	}
+	m := &claircore.Manifest{Hash: d, Layers: make([]*claircore.Layer, len(ls))}
+	for i, l := range ls {
+		ld, err := claircore.ParseDigest(l.Hash)
+		if err != nil {
+			return nil, err
+		}
+		m.Layers[i] = &claircore.Layer{Hash: ld, URI: l.URI}
+	}
+	return m, nil
+}
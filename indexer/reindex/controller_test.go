@@ -0,0 +1,161 @@
+package reindex
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/quay/claircore"
+	libmigrations "github.com/quay/claircore/libindex/migrations"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+	"github.com/remind101/migrate"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/reindex/migrations"
+)
+
+func testPool(ctx context.Context, t *testing.T) (*pgxpool.Pool, func()) {
+	if os.Getenv(integration.EnvPGConnString) == "" {
+		os.Setenv(integration.EnvPGConnString, `host=localhost port=5432 user=clair dbname=clair sslmode=disable`)
+	}
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, db.Config())
+	if err != nil {
+		t.Fatalf("failed to create connpool: %v", err)
+	}
+	sdb := stdlib.OpenDB(*db.Config().ConnConfig)
+	defer sdb.Close()
+	for _, m := range []struct {
+		table string
+		ms    []migrate.Migration
+	}{
+		{libmigrations.MigrationTable, libmigrations.Migrations},
+		{migrations.MigrationTable, migrations.Migrations},
+	} {
+		migrator := migrate.NewPostgresMigrator(sdb)
+		migrator.Table = m.table
+		if err := migrator.Exec(migrate.Up, m.ms...); err != nil {
+			t.Fatalf("failed to perform migrations: %v", err)
+		}
+	}
+	return pool, func() {
+		pool.Close()
+		db.Close(ctx, t)
+	}
+}
+
+func digest(i int) claircore.Digest {
+	return claircore.MustParseDigest(fmt.Sprintf("sha256:%064x", i))
+}
+
+// Scanned records the manifest as scanned by the scanner versions.
+func scanned(ctx context.Context, t *testing.T, pool *pgxpool.Pool, manifest string, ss ...Scanner) {
+	if _, err := pool.Exec(ctx, `INSERT INTO manifest (hash) VALUES ($1) ON CONFLICT DO NOTHING;`, manifest); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range ss {
+		for _, q := range []struct {
+			sql  string
+			args []interface{}
+		}{
+			{`INSERT INTO scanner (name, version, kind) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;`, []interface{}{s.Name, s.Version, s.Kind}},
+			{`INSERT INTO scanned_manifest (manifest_id, scanner_id)
+			SELECT m.id, s.id FROM manifest m, scanner s
+			WHERE m.hash = $1 AND s.name = $2 AND s.version = $3 AND s.kind = $4;`, []interface{}{manifest, s.Name, s.Version, s.Kind}},
+		} {
+			if _, err := pool.Exec(ctx, q.sql, q.args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestReindex(t *testing.T) {
+	integration.Skip(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool, cleanup := testPool(ctx, t)
+	defer cleanup()
+
+	dpkgOld := Scanner{Name: "dpkg", Version: "1", Kind: "package"}
+	dpkg := Scanner{Name: "dpkg", Version: "2", Kind: "package"}
+	osrelease := Scanner{Name: "os-release", Version: "1", Kind: "distribution"}
+
+	var got []string
+	idx := &indexer.Mock{
+		Index_: func(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			got = append(got, m.Hash.String())
+			if len(m.Layers) != 1 || m.Layers[0].URI == "" {
+				t.Errorf("unexpected layers: %+v", m.Layers)
+			}
+			if m.Hash.String() == digest(2).String() {
+				return &claircore.IndexReport{Hash: m.Hash, Err: "layer gone"}, nil
+			}
+			scanned(ctx, t, pool, m.Hash.String(), dpkg)
+			return &claircore.IndexReport{Hash: m.Hash, Success: true}, nil
+		},
+	}
+	r := NewRecorder(idx, pool)
+	record := func(d claircore.Digest) {
+		m := &claircore.Manifest{
+			Hash: d,
+			Layers: []*claircore.Layer{{
+				Hash:    digest(100),
+				URI:     "https://registry.example.com/v2/app/blobs/" + digest(100).String(),
+				Headers: map[string][]string{"Authorization": {"Bearer secret"}},
+			}},
+		}
+		if _, err := r.Index(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 1 and 2 were scanned by the old dpkg scanner, 3 by the current one.
+	// 4 is stale but was indexed before recording started.
+	for _, n := range []int{1, 2, 3} {
+		record(digest(n))
+	}
+	got = nil
+	scanned(ctx, t, pool, digest(1).String(), dpkgOld, osrelease)
+	scanned(ctx, t, pool, digest(2).String(), dpkgOld, osrelease)
+	scanned(ctx, t, pool, digest(3).String(), dpkg, osrelease)
+	scanned(ctx, t, pool, digest(4).String(), dpkgOld, osrelease)
+
+	var headers int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM reindex_manifest WHERE layers::text LIKE '%secret%'`).Scan(&headers); err != nil {
+		t.Fatal(err)
+	}
+	if headers != 0 {
+		t.Error("layer headers recorded")
+	}
+
+	c := NewController(pool, r, Opts{Scanners: []Scanner{dpkg, osrelease}})
+	n, err := c.Reindex(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("got: %d reindexed, want: 1", n)
+	}
+	sort.Strings(got)
+	if want := []string{digest(1).String(), digest(2).String()}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	// The success is no longer stale, and the failure is left alone for a
+	// while.
+	got = nil
+	if _, err := c.Reindex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("unexpected reindex: %v", got)
+	}
+}
@@ -0,0 +1,17 @@
+package migrations
+
+const (
+	// migration1 adds a table keeping the layers of indexed manifests, so
+	// they can be indexed again after scanners are upgraded.
+	migration1 = `
+	--- a relation recording where an indexed manifest's layers were fetched
+	--- from, and when it was last indexed again
+	CREATE TABLE IF NOT EXISTS reindex_manifest
+	(
+		manifest_hash text PRIMARY KEY,
+		layers        jsonb NOT NULL,
+		recorded      timestamptz NOT NULL,
+		attempted     timestamptz
+	);
+	`
+)
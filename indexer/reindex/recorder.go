@@ -0,0 +1,70 @@
+package reindex
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Recorder wraps an indexer.Service and keeps the layers of the manifests it
+// indexes, so the Controller can index them again.
+type Recorder struct {
+	indexer.Service
+	pool *pgxpool.Pool
+}
+
+var _ indexer.Service = (*Recorder)(nil)
+
+// NewRecorder returns a Recorder keeping manifests in the database behind
+// pool, which must be the indexer's database.
+func NewRecorder(s indexer.Service, pool *pgxpool.Pool) *Recorder {
+	return &Recorder{
+		Service: s,
+		pool:    pool,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (r *Recorder) Unwrap() indexer.Service {
+	return r.Service
+}
+
+// Layer is a layer as it's kept. Headers aren't kept, as they usually hold
+// short-lived credentials; wrappers like the registry credentials one add
+// them again when the manifest is reindexed.
+type layer struct {
+	Hash string `json:"hash"`
+	URI  string `json:"uri"`
+}
+
+const recordManifest = `
+INSERT INTO reindex_manifest (manifest_hash, layers, recorded) VALUES ($1, $2, now())
+ON CONFLICT (manifest_hash) DO UPDATE SET layers = EXCLUDED.layers, recorded = EXCLUDED.recorded;`
+
+// Index implements indexer.Indexer.
+//
+// Failing to record the manifest is logged and otherwise ignored; the
+// manifest just won't be indexed again automatically.
+func (r *Recorder) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	ls := make([]layer, len(m.Layers))
+	for i, l := range m.Layers {
+		ls[i] = layer{Hash: l.Hash.String(), URI: l.URI}
+	}
+	b, err := json.Marshal(ls)
+	if err == nil {
+		_, err = r.pool.Exec(ctx, recordManifest, m.Hash.String(), b)
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "indexer/reindex/Recorder.Index").
+			Str("manifest", m.Hash.String()).
+			Err(err).
+			Msg("failed to record manifest")
+	}
+	return r.Service.Index(ctx, m)
+}
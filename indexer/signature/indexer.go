@@ -0,0 +1,106 @@
+package signature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Indexer wraps an indexer.Service and verifies manifests' signatures before
+// handing them to it, recording the outcome.
+//
+// It needs to be wrapped by anything adding credentials to layers, so the
+// same credentials can be used to fetch signatures.
+type Indexer struct {
+	indexer.Service
+	v       *Verifier
+	pool    *pgxpool.Pool
+	enforce bool
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer using the provided Verifier and recording
+// outcomes in the database behind pool, which must be the indexer's
+// database.
+//
+// If enforce is set, manifests that don't verify aren't indexed.
+func NewIndexer(s indexer.Service, v *Verifier, pool *pgxpool.Pool, enforce bool) *Indexer {
+	return &Indexer{
+		Service: s,
+		v:       v,
+		pool:    pool,
+		enforce: enforce,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+const (
+	upsertStatus = `
+INSERT INTO manifest_signature (manifest_hash, status, signer, reason, checked)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (manifest_hash) DO UPDATE SET
+	status = EXCLUDED.status,
+	signer = EXCLUDED.signer,
+	reason = EXCLUDED.reason,
+	checked = EXCLUDED.checked;`
+	selectStatus = `
+SELECT status, signer, reason, checked FROM manifest_signature WHERE manifest_hash = $1;`
+)
+
+// Index implements indexer.Indexer.
+//
+// If verification is enforced, an error wrapping ErrRejected is returned for
+// manifests that don't verify.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/signature/Indexer.Index").
+		Str("manifest", m.Hash.String()).
+		Logger()
+	st := i.v.Verify(ctx, m)
+	log.Debug().
+		Str("status", st.Status).
+		Str("signer", st.Signer).
+		Str("reason", st.Reason).
+		Msg("verified signatures")
+	if _, err := i.pool.Exec(ctx, upsertStatus, m.Hash.String(), st.Status, st.Signer, st.Reason, st.Checked); err != nil {
+		return nil, fmt.Errorf("signature: failed to record status: %w", err)
+	}
+	if i.enforce && st.Status != Verified {
+		msg := st.Status
+		if st.Reason != "" {
+			msg += ": " + st.Reason
+		}
+		log.Info().
+			Str("status", st.Status).
+			Msg("rejected manifest")
+		return nil, fmt.Errorf("%w: %s", ErrRejected, msg)
+	}
+	return i.Service.Index(ctx, m)
+}
+
+// Signature returns the recorded outcome of verifying the manifest's
+// signatures, or nil if it's never been checked.
+func (i *Indexer) Signature(ctx context.Context, d claircore.Digest) (*Status, error) {
+	var st Status
+	err := i.pool.QueryRow(ctx, selectStatus, d.String()).
+		Scan(&st.Status, &st.Signer, &st.Reason, &st.Checked)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("signature: failed to read status: %w", err)
+	}
+	return &st, nil
+}
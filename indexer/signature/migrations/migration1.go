@@ -0,0 +1,17 @@
+package migrations
+
+const (
+	// migration1 adds a table recording the outcome of verifying a manifest's
+	// signatures.
+	migration1 = `
+	--- a relation recording the latest signature verification for a manifest
+	CREATE TABLE IF NOT EXISTS manifest_signature
+	(
+		manifest_hash text PRIMARY KEY,
+		status        text NOT NULL,
+		signer        text NOT NULL DEFAULT '',
+		reason        text NOT NULL DEFAULT '',
+		checked       timestamptz NOT NULL
+	);
+	`
+)
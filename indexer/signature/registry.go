@@ -0,0 +1,132 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// ErrUnsigned is used internally when the registry has no signatures.
+var errUnsigned = errors.New("no signatures")
+
+// MaxResponse bounds the size of manifests and payloads read from a registry.
+const maxResponse = 4 << 20
+
+const (
+	mediaOCIManifest    = `application/vnd.oci.image.manifest.v1+json`
+	mediaDockerManifest = `application/vnd.docker.distribution.manifest.v2+json`
+	mediaSimpleSigning  = `application/vnd.dev.cosign.simplesigning.v1+json`
+)
+
+// Fetch retrieves the manifest's signatures from the registry its layers
+// are fetched from.
+//
+// Requests reuse the first layer's headers, so credentials added for the
+// layers are used for the signatures, too.
+func (v *Verifier) fetch(ctx context.Context, m *claircore.Manifest) ([]*signed, error) {
+	if len(m.Layers) == 0 {
+		return nil, errors.New("manifest has no layers")
+	}
+	l := m.Layers[0]
+	u, err := url.Parse(l.URI)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndex(u.Path, "/blobs/")
+	if !strings.HasPrefix(u.Path, "/v2/") || i == -1 {
+		return nil, errors.New("layers aren't fetched from a registry")
+	}
+	repo := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path[:i+1]}
+	tag := strings.Replace(m.Hash.String(), ":", "-", 1) + ".sig"
+
+	b, err := v.get(ctx, repo, "manifests/"+tag, l.Headers, mediaOCIManifest+", "+mediaDockerManifest)
+	if err != nil {
+		return nil, err
+	}
+	var sm struct {
+		Layers []struct {
+			MediaType   string            `json:"mediaType"`
+			Digest      string            `json:"digest"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(b, &sm); err != nil {
+		return nil, fmt.Errorf("malformed signature manifest: %w", err)
+	}
+	var sigs []*signed
+	for _, sl := range sm.Layers {
+		if sl.MediaType != mediaSimpleSigning {
+			continue
+		}
+		d, err := claircore.ParseDigest(sl.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("malformed signature layer: %w", err)
+		}
+		b, err := v.get(ctx, repo, "blobs/"+d.String(), l.Headers, "")
+		if err != nil {
+			return nil, err
+		}
+		if d.Algorithm() != "sha256" {
+			return nil, fmt.Errorf("unsupported digest algorithm %q", d.Algorithm())
+		}
+		if sum := sha256.Sum256(b); !bytes.Equal(sum[:], d.Checksum()) {
+			return nil, fmt.Errorf("payload %q doesn't match its digest", d)
+		}
+		sigs = append(sigs, &signed{payload: b, annotations: sl.Annotations})
+	}
+	if len(sigs) == 0 {
+		return nil, errUnsigned
+	}
+	return sigs, nil
+}
+
+// Get fetches p, relative to the repository URL. A missing manifest means
+// the image is unsigned.
+func (v *Verifier) get(ctx context.Context, repo *url.URL, p string, h map[string][]string, accept string) ([]byte, error) {
+	u, err := repo.Parse(p)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range h {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if accept != "" {
+		req.Header.Set("accept", accept)
+	}
+	res, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusOK:
+	case res.StatusCode == http.StatusNotFound && strings.HasPrefix(p, "manifests/"):
+		return nil, errUnsigned
+	default:
+		return nil, fmt.Errorf("%s: unexpected response: %s", u.Path, res.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponse+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxResponse {
+		return nil, fmt.Errorf("%s: response too large", u.Path)
+	}
+	return b, nil
+}
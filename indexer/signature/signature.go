@@ -0,0 +1,43 @@
+// Package signature verifies cosign signatures on manifests before they're
+// indexed, so scanning and provenance policy can live in one service.
+//
+// Signatures are found the way cosign stores them: as an OCI artifact tagged
+// "sha256-<hex>.sig" in the repository the manifest's layers are fetched
+// from. Both signatures made with a configured key and "keyless" signatures
+// made with a Fulcio certificate and logged in Rekor are understood.
+package signature
+
+import (
+	"time"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// These are the possible values of Status.Status.
+const (
+	// Verified means a signature verified against a configured key or root.
+	Verified = "verified"
+	// Unsigned means the registry has no signatures for the manifest.
+	Unsigned = "unsigned"
+	// Invalid means there are signatures, but none verified.
+	Invalid = "invalid"
+	// Failed means the signatures couldn't be checked, usually because the
+	// registry couldn't be reached.
+	Failed = "error"
+)
+
+// Status is the outcome of verifying a manifest's signatures.
+type Status struct {
+	Status string `json:"status"`
+	// Signer names the key or certificate identity that verified the
+	// manifest, if one did.
+	Signer string `json:"signer,omitempty"`
+	// Reason explains why the manifest didn't verify.
+	Reason string `json:"reason,omitempty"`
+	// Checked is when verification happened.
+	Checked time.Time `json:"checked"`
+}
+
+// ErrRejected is returned when indexing a manifest that didn't verify, if
+// verification is enforced.
+var ErrRejected = clairerror.New(clairerror.Forbidden, "signature: manifest rejected")
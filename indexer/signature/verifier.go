@@ -0,0 +1,366 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+// Identity constrains the certificates accepted for keyless signatures.
+// Empty members match anything.
+type Identity struct {
+	// Issuer is the OIDC issuer that authenticated the signer, like
+	// "https://token.actions.githubusercontent.com".
+	Issuer string
+	// Subject is the signer's email address or URI.
+	Subject string
+}
+
+// Opts configures a Verifier.
+//
+// At least one of Keys or Roots must be provided. Roots needs RekorKeys, as
+// Fulcio certificates are only valid at the time the transparency log says
+// the signature was made, and Identities, as Fulcio issues certificates to
+// anyone.
+type Opts struct {
+	// Keys are trusted public keys, by name. The name is reported as the
+	// signer of manifests verified with the key.
+	Keys map[string]crypto.PublicKey
+	// Roots are the trusted Fulcio root certificates.
+	Roots *x509.CertPool
+	// RekorKeys are the trusted Rekor public keys.
+	RekorKeys []crypto.PublicKey
+	// Identities restricts which keyless signers are trusted.
+	Identities []Identity
+	// Client is used to talk to registries. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+}
+
+// Verifier checks manifests' signatures.
+type Verifier struct {
+	keys       map[string]crypto.PublicKey
+	roots      *x509.CertPool
+	rekor      []crypto.PublicKey
+	identities []Identity
+	client     *http.Client
+}
+
+// NewVerifier returns a Verifier using the provided Opts.
+func NewVerifier(o *Opts) (*Verifier, error) {
+	if len(o.Keys) == 0 && o.Roots == nil {
+		return nil, errors.New("signature: no keys or roots configured")
+	}
+	if o.Roots != nil && len(o.RekorKeys) == 0 {
+		return nil, errors.New("signature: roots configured without rekor keys")
+	}
+	if o.Roots != nil && len(o.Identities) == 0 {
+		return nil, errors.New("signature: roots configured without identities")
+	}
+	c := o.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return &Verifier{
+		keys:       o.Keys,
+		roots:      o.Roots,
+		rekor:      o.RekorKeys,
+		identities: o.Identities,
+		client:     c,
+	}, nil
+}
+
+// ParsePublicKey parses a PEM-encoded PKIX public key.
+func ParsePublicKey(b []byte) (crypto.PublicKey, error) {
+	p, _ := pem.Decode(b)
+	if p == nil {
+		return nil, errors.New("signature: no PEM data found")
+	}
+	return x509.ParsePKIXPublicKey(p.Bytes)
+}
+
+// ParseCertificates parses a series of PEM-encoded certificates.
+func ParseCertificates(b []byte) ([]*x509.Certificate, error) {
+	var cs []*x509.Certificate
+	for {
+		var p *pem.Block
+		p, b = pem.Decode(b)
+		if p == nil {
+			break
+		}
+		if p.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(p.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+	if len(cs) == 0 {
+		return nil, errors.New("signature: no certificates found")
+	}
+	return cs, nil
+}
+
+// Verify reports whether any of the manifest's signatures verify.
+//
+// Verify never fails; problems are reported in the returned Status.
+func (v *Verifier) Verify(ctx context.Context, m *claircore.Manifest) Status {
+	st := Status{Checked: time.Now()}
+	sigs, err := v.fetch(ctx, m)
+	switch {
+	case errors.Is(err, errUnsigned):
+		st.Status = Unsigned
+		return st
+	case err != nil:
+		st.Status = Failed
+		st.Reason = err.Error()
+		return st
+	}
+	reasons := make([]string, 0, len(sigs))
+	for _, s := range sigs {
+		signer, err := v.verify(s, m.Hash)
+		if err == nil {
+			st.Status = Verified
+			st.Signer = signer
+			return st
+		}
+		reasons = append(reasons, err.Error())
+	}
+	st.Status = Invalid
+	st.Reason = strings.Join(reasons, "; ")
+	return st
+}
+
+// Cosign annotations on signature layers.
+const (
+	annotationSignature   = `dev.cosignproject.cosign/signature`
+	annotationCertificate = `dev.sigstore.cosign/certificate`
+	annotationChain       = `dev.sigstore.cosign/chain`
+	annotationBundle      = `dev.sigstore.cosign/bundle`
+)
+
+// Signed is a single signature: a simple signing payload and its annotations.
+type signed struct {
+	payload     []byte
+	annotations map[string]string
+}
+
+// Payload is the part of a simple signing payload that's checked.
+type payload struct {
+	Critical struct {
+		Image struct {
+			Digest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// Verify checks a single signature, returning the signer.
+func (v *Verifier) verify(s *signed, d claircore.Digest) (string, error) {
+	var p payload
+	if err := json.Unmarshal(s.payload, &p); err != nil {
+		return "", fmt.Errorf("malformed payload: %w", err)
+	}
+	if got := p.Critical.Image.Digest; got != d.String() {
+		return "", fmt.Errorf("payload is for manifest %q", got)
+	}
+	sig, err := base64.StdEncoding.DecodeString(s.annotations[annotationSignature])
+	if err != nil || len(sig) == 0 {
+		return "", errors.New("missing or malformed signature")
+	}
+	for name, k := range v.keys {
+		if verifySignature(k, s.payload, sig) {
+			return name, nil
+		}
+	}
+	if v.roots == nil || s.annotations[annotationCertificate] == "" {
+		return "", errors.New("no configured key verified the signature")
+	}
+	return v.verifyKeyless(s, sig)
+}
+
+// VerifyKeyless checks a signature made with a Fulcio certificate.
+func (v *Verifier) verifyKeyless(s *signed, sig []byte) (string, error) {
+	certs, err := ParseCertificates([]byte(s.annotations[annotationCertificate]))
+	if err != nil {
+		return "", fmt.Errorf("malformed certificate: %w", err)
+	}
+	cert := certs[0]
+	inter := x509.NewCertPool()
+	if c := s.annotations[annotationChain]; c != "" {
+		chain, err := ParseCertificates([]byte(c))
+		if err != nil {
+			return "", fmt.Errorf("malformed certificate chain: %w", err)
+		}
+		for _, c := range chain {
+			inter.AddCert(c)
+		}
+	}
+	signedAt, err := v.verifyBundle(s, sig)
+	if err != nil {
+		return "", err
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: inter,
+		CurrentTime:   signedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return "", fmt.Errorf("certificate not trusted: %w", err)
+	}
+	if !verifySignature(cert.PublicKey, s.payload, sig) {
+		return "", errors.New("signature doesn't match certificate")
+	}
+	issuer, subject := identity(cert)
+	if !v.trusted(issuer, subject) {
+		return "", fmt.Errorf("signer %q from %q not trusted", subject, issuer)
+	}
+	return subject, nil
+}
+
+// Trusted reports whether the identity matches a configured Identity.
+func (v *Verifier) trusted(issuer, subject string) bool {
+	for _, id := range v.identities {
+		if (id.Issuer == "" || id.Issuer == issuer) &&
+			(id.Subject == "" || id.Subject == subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bundle is a Rekor entry attached to a signature.
+type bundle struct {
+	SET     []byte        `json:"SignedEntryTimestamp"`
+	Payload bundlePayload `json:"Payload"`
+}
+
+// BundlePayload is the signed part of a bundle. The members are in the
+// order of the canonical encoding.
+type bundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// Rekord is the part of a "hashedrekord" entry that's checked.
+type rekord struct {
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content []byte `json:"content"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// VerifyBundle checks that the signature was logged in Rekor, returning when.
+func (v *Verifier) verifyBundle(s *signed, sig []byte) (time.Time, error) {
+	raw := s.annotations[annotationBundle]
+	if raw == "" {
+		return time.Time{}, errors.New("missing transparency log bundle")
+	}
+	var b bundle
+	if err := json.Unmarshal([]byte(raw), &b); err != nil {
+		return time.Time{}, fmt.Errorf("malformed transparency log bundle: %w", err)
+	}
+	canon, err := json.Marshal(&b.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ok := false
+	for _, k := range v.rekor {
+		ok = ok || verifySignature(k, canon, b.SET)
+	}
+	if !ok {
+		return time.Time{}, errors.New("transparency log bundle not signed by a trusted key")
+	}
+	body, err := base64.StdEncoding.DecodeString(b.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed transparency log entry: %w", err)
+	}
+	var r rekord
+	if err := json.Unmarshal(body, &r); err != nil {
+		return time.Time{}, fmt.Errorf("malformed transparency log entry: %w", err)
+	}
+	sum := sha256.Sum256(s.payload)
+	h := r.Spec.Data.Hash
+	if h.Algorithm != "sha256" || h.Value != hex.EncodeToString(sum[:]) ||
+		!bytes.Equal(r.Spec.Signature.Content, sig) {
+		return time.Time{}, errors.New("transparency log entry is for a different signature")
+	}
+	return time.Unix(b.Payload.IntegratedTime, 0), nil
+}
+
+// Fulcio certificate extensions naming the OIDC issuer. The first holds the
+// raw string, the second a DER-encoded UTF8String.
+var (
+	oidIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Identity pulls the OIDC issuer and subject out of a Fulcio certificate.
+func identity(c *x509.Certificate) (issuer, subject string) {
+	for _, ext := range c.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var s string
+			if _, err := asn1.Unmarshal(ext.Value, &s); err == nil {
+				issuer = s
+			}
+		case ext.Id.Equal(oidIssuer) && issuer == "":
+			issuer = string(ext.Value)
+		}
+	}
+	switch {
+	case len(c.EmailAddresses) != 0:
+		subject = c.EmailAddresses[0]
+	case len(c.URIs) != 0:
+		subject = c.URIs[0].String()
+	}
+	return issuer, subject
+}
+
+// VerifySignature reports whether sig is a valid signature of msg by k.
+func verifySignature(k crypto.PublicKey, msg, sig []byte) bool {
+	sum := sha256.Sum256(msg)
+	switch k := k.(type) {
+	case *ecdsa.PublicKey:
+		var es struct{ R, S *big.Int }
+		rest, err := asn1.Unmarshal(sig, &es)
+		if err != nil || len(rest) != 0 {
+			return false
+		}
+		return ecdsa.Verify(k, sum[:], es.R, es.S)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, msg, sig)
+	}
+	return false
+}
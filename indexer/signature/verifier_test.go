@@ -0,0 +1,352 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+const testManifest = `sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08`
+
+// Registry is a test registry serving signature manifests and payloads
+// from memory.
+type registry struct {
+	*httptest.Server
+	content map[string][]byte
+}
+
+func newRegistry(t *testing.T) *registry {
+	r := &registry{content: make(map[string][]byte)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("authorization"), "Bearer t0k3n"; got != want {
+			t.Errorf("authorization: got: %q, want: %q", got, want)
+		}
+		b, ok := r.content[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	}))
+	return r
+}
+
+func (r *registry) manifest() *claircore.Manifest {
+	return &claircore.Manifest{
+		Hash: claircore.MustParseDigest(testManifest),
+		Layers: []*claircore.Layer{{
+			URI:     r.URL + "/v2/library/ubuntu/blobs/sha256:" + strings.Repeat("0", 64),
+			Headers: map[string][]string{"Authorization": {"Bearer t0k3n"}},
+		}},
+	}
+}
+
+// Sign adds a signature layer for the manifest. If extra is provided, the
+// annotations it returns are added alongside the signature.
+func (r *registry) sign(t *testing.T, digest string, k *ecdsa.PrivateKey, extra func(payload, sig []byte) map[string]string) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"example.com/library/ubuntu"},`+
+		`"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+	sum := sha256.Sum256(payload)
+	pd := "sha256:" + hex.EncodeToString(sum[:])
+	r.content["/v2/library/ubuntu/blobs/"+pd] = payload
+	sig := sign(t, k, payload)
+	ann := map[string]string{annotationSignature: base64.StdEncoding.EncodeToString(sig)}
+	if extra != nil {
+		for k, v := range extra(payload, sig) {
+			ann[k] = v
+		}
+	}
+	m := map[string]interface{}{
+		"schemaVersion": 2,
+		"layers": []interface{}{map[string]interface{}{
+			"mediaType":   mediaSimpleSigning,
+			"digest":      pd,
+			"size":        len(payload),
+			"annotations": ann,
+		}},
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.content["/v2/library/ubuntu/manifests/"+strings.Replace(testManifest, ":", "-", 1)+".sig"] = b
+}
+
+func sign(t *testing.T, k *ecdsa.PrivateKey, msg []byte) []byte {
+	sum := sha256.Sum256(msg)
+	r, s, err := ecdsa.Sign(rand.Reader, k, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestVerifyKey(t *testing.T) {
+	ctx := context.Background()
+	key, other := newKey(t), newKey(t)
+	v, err := NewVerifier(&Opts{
+		Keys: map[string]crypto.PublicKey{"cosign.pub": &key.PublicKey},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name   string
+		setup  func(*testing.T, *registry)
+		status string
+		signer string
+	}{
+		{
+			name:   "Verified",
+			setup:  func(t *testing.T, r *registry) { r.sign(t, testManifest, key, nil) },
+			status: Verified,
+			signer: "cosign.pub",
+		},
+		{
+			name:   "Unsigned",
+			setup:  func(*testing.T, *registry) {},
+			status: Unsigned,
+		},
+		{
+			name:   "WrongKey",
+			setup:  func(t *testing.T, r *registry) { r.sign(t, testManifest, other, nil) },
+			status: Invalid,
+		},
+		{
+			name: "WrongManifest",
+			setup: func(t *testing.T, r *registry) {
+				r.sign(t, "sha256:"+strings.Repeat("a", 64), key, nil)
+			},
+			status: Invalid,
+		},
+		{
+			name: "Tampered",
+			setup: func(t *testing.T, r *registry) {
+				r.sign(t, testManifest, key, nil)
+				for p := range r.content {
+					if strings.Contains(p, "/blobs/") {
+						r.content[p] = append(r.content[p], ' ')
+					}
+				}
+			},
+			status: Failed,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newRegistry(t)
+			defer r.Close()
+			tc.setup(t, r)
+			st := v.Verify(ctx, r.manifest())
+			t.Logf("%+v", st)
+			if got, want := st.Status, tc.status; got != want {
+				t.Errorf("status: got: %q, want: %q", got, want)
+			}
+			if got, want := st.Signer, tc.signer; got != want {
+				t.Errorf("signer: got: %q, want: %q", got, want)
+			}
+		})
+	}
+}
+
+// Fulcio is a test certificate authority and transparency log.
+type fulcio struct {
+	root    *x509.Certificate
+	rootKey *ecdsa.PrivateKey
+	rekor   *ecdsa.PrivateKey
+}
+
+func newFulcio(t *testing.T) *fulcio {
+	f := fulcio{rootKey: newKey(t), rekor: newKey(t)}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test fulcio"},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &f.rootKey.PublicKey, f.rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.root, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &f
+}
+
+// Issue returns a short-lived signing certificate valid around the provided
+// time, and its key.
+func (f *fulcio) issue(t *testing.T, at time.Time, issuer, email string) (string, *ecdsa.PrivateKey) {
+	k := newKey(t)
+	iss, err := asn1.Marshal(issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       at.Add(-time.Minute),
+		NotAfter:        at.Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{email},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: iss}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, f.root, &k.PublicKey, f.rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), k
+}
+
+// Bundle returns a transparency log bundle for the signature.
+func (f *fulcio) bundle(t *testing.T, at time.Time, payload, sig []byte, cert string) string {
+	sum := sha256.Sum256(payload)
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]interface{}{
+			"data": map[string]interface{}{
+				"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(sum[:])},
+			},
+			"signature": map[string]interface{}{
+				"content":   sig,
+				"publicKey": map[string][]byte{"content": []byte(cert)},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bundle{Payload: bundlePayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: at.Unix(),
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+		LogIndex:       1,
+	}}
+	canon, err := json.Marshal(&b.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SET = sign(t, f.rekor, canon)
+	out, err := json.Marshal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestVerifyKeyless(t *testing.T) {
+	ctx := context.Background()
+	const issuer = "https://accounts.example.com"
+	f := newFulcio(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(f.root)
+	// Signed an hour ago, so the certificate has expired since.
+	at := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	tt := []struct {
+		name       string
+		identities []Identity
+		email      string
+		status     string
+	}{
+		{
+			name:       "Issuer",
+			identities: []Identity{{Issuer: issuer}},
+			email:      "signer@example.com",
+			status:     Verified,
+		},
+		{
+			name:       "Identity",
+			identities: []Identity{{Issuer: issuer, Subject: "signer@example.com"}},
+			email:      "signer@example.com",
+			status:     Verified,
+		},
+		{
+			name:       "Untrusted",
+			identities: []Identity{{Issuer: issuer, Subject: "signer@example.com"}},
+			email:      "mallory@example.com",
+			status:     Invalid,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NewVerifier(&Opts{
+				Roots:      roots,
+				RekorKeys:  []crypto.PublicKey{&f.rekor.PublicKey},
+				Identities: tc.identities,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := newRegistry(t)
+			defer r.Close()
+			cert, k := f.issue(t, at, issuer, tc.email)
+			r.sign(t, testManifest, k, func(payload, sig []byte) map[string]string {
+				return map[string]string{
+					annotationCertificate: cert,
+					annotationBundle:      f.bundle(t, at, payload, sig, cert),
+				}
+			})
+			st := v.Verify(ctx, r.manifest())
+			t.Logf("%+v", st)
+			if got, want := st.Status, tc.status; got != want {
+				t.Errorf("status: got: %q, want: %q", got, want)
+			}
+			if st.Status == Verified {
+				if got, want := st.Signer, tc.email; got != want {
+					t.Errorf("signer: got: %q, want: %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewVerifierNoIdentities(t *testing.T) {
+	f := newFulcio(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(f.root)
+	_, err := NewVerifier(&Opts{
+		Roots:     roots,
+		RekorKeys: []crypto.PublicKey{&f.rekor.PublicKey},
+	})
+	if err == nil {
+		t.Error("expected error for roots without identities")
+	}
+}
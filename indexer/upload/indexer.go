@@ -0,0 +1,106 @@
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+// Indexer wraps an indexer.Service and points layers without a URI at their
+// uploaded contents.
+//
+// The indexer only knows how to fetch layers over HTTP, so uploads are served
+// to it from a listener on the loopback interface, guarded by a random token.
+type Indexer struct {
+	indexer.Service
+	store *Store
+	base  string
+	token string
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer using layers from the Store. The loopback
+// server is stopped when the Context is canceled.
+func NewIndexer(ctx context.Context, s indexer.Service, st *Store) (*Indexer, error) {
+	tok := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, tok); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	i := &Indexer{
+		Service: s,
+		store:   st,
+		base:    "http://" + ln.Addr().String() + "/",
+		token:   hex.EncodeToString(tok),
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(i.serve)}
+	go srv.Serve(ln)
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	return i, nil
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Store returns the Store uploads are kept in.
+func (i *Indexer) Store() *Store {
+	return i.store
+}
+
+// Index implements indexer.Indexer.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/upload/Indexer.Index").
+		Str("manifest", m.Hash.String()).
+		Logger()
+	for _, l := range m.Layers {
+		if l.URI != "" || !i.store.Has(l.Hash) {
+			continue
+		}
+		l.URI = i.base + l.Hash.String()
+		l.Headers = map[string][]string{
+			"Authorization": {"Bearer " + i.token},
+		}
+		log.Debug().Str("layer", l.Hash.String()).Msg("using uploaded layer")
+	}
+	return i.Service.Index(ctx, m)
+}
+
+// Serve hands uploaded layers to the indexer's fetcher.
+func (i *Indexer) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("authorization") != "Bearer "+i.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	d, err := claircore.ParseDigest(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, err := i.store.Open(d)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("content-type", "application/octet-stream")
+	io.Copy(w, f)
+}
@@ -0,0 +1,165 @@
+// Package upload lets clients push layer contents to an indexer directly,
+// for layers living somewhere the indexer can't fetch them from.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// These are the defaults used for zero values passed to NewStore.
+const (
+	DefaultMaxSize = 2 << 30
+	DefaultMaxAge  = time.Hour
+)
+
+// SweepInterval is how often Run removes expired uploads.
+const SweepInterval = time.Minute
+
+// These errors are returned by Put for rejected uploads.
+var (
+	ErrTooLarge       = clairerror.New(clairerror.TooLarge, "upload: layer exceeds maximum size")
+	ErrDigestMismatch = clairerror.New(clairerror.Invalid, "upload: layer contents do not match digest")
+)
+
+// Store keeps uploaded layers in a directory on local disk.
+//
+// Uploads are removed once they're older than the maximum age, so clients
+// must index a manifest soon after uploading its layers.
+type Store struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+}
+
+// NewStore returns a Store keeping layers in dir, which is created if it
+// doesn't exist.
+//
+// If dir is empty, a directory in os.TempDir is used.
+func NewStore(dir string, maxSize int64, maxAge time.Duration) (*Store, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "clair-uploads")
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{
+		dir:     dir,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+	}, nil
+}
+
+// MaxSize reports the largest layer the Store accepts.
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *Store) path(d claircore.Digest) string {
+	return filepath.Join(s.dir, d.Algorithm()+"-"+hex.EncodeToString(d.Checksum()))
+}
+
+// Put stores the layer read from r, which must hash to d.
+func (s *Store) Put(ctx context.Context, d claircore.Digest, r io.Reader) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/upload/Store.Put").
+		Str("layer", d.String()).
+		Logger()
+	f, err := ioutil.TempFile(s.dir, ".upload-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		// Harmless once the file has been renamed into place.
+		os.Remove(f.Name())
+	}()
+
+	h := d.Hash()
+	n, err := io.Copy(f, io.TeeReader(io.LimitReader(r, s.maxSize+1), h))
+	switch {
+	case err != nil:
+		return err
+	case n > s.maxSize:
+		return ErrTooLarge
+	case !bytes.Equal(h.Sum(nil), d.Checksum()):
+		return ErrDigestMismatch
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), s.path(d)); err != nil {
+		return err
+	}
+	log.Debug().Int64("size", n).Msg("layer uploaded")
+	return nil
+}
+
+// Has reports whether the layer has been uploaded and not yet expired.
+func (s *Store) Has(d claircore.Digest) bool {
+	fi, err := os.Stat(s.path(d))
+	return err == nil && time.Since(fi.ModTime()) < s.maxAge
+}
+
+// Open opens the uploaded layer.
+func (s *Store) Open(d claircore.Digest) (*os.File, error) {
+	return os.Open(s.path(d))
+}
+
+// Run removes expired uploads until the Context is canceled.
+func (s *Store) Run(ctx context.Context) {
+	t := time.NewTicker(SweepInterval)
+	defer t.Stop()
+	for {
+		s.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Sweep removes expired uploads and abandoned partial ones.
+func (s *Store) sweep(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "indexer/upload/Store.sweep").
+		Logger()
+	fs, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to list uploads")
+		return
+	}
+	for _, fi := range fs {
+		if fi.IsDir() || time.Since(fi.ModTime()) < s.maxAge {
+			continue
+		}
+		// Partial uploads get the same grace period, which is plenty.
+		p := filepath.Join(s.dir, fi.Name())
+		if err := os.Remove(p); err != nil {
+			log.Warn().Err(err).Str("file", p).Msg("unable to remove upload")
+			continue
+		}
+		if !strings.HasPrefix(fi.Name(), ".upload-") {
+			log.Debug().Str("file", p).Msg("removed expired upload")
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+)
+
+func digestOf(t *testing.T, b []byte) claircore.Digest {
+	sum := sha256.Sum256(b)
+	d, err := claircore.ParseDigest("sha256:" + hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "upload-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir, 16, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := []byte("layer contents")
+	d := digestOf(t, layer)
+	if s.Has(d) {
+		t.Error("layer present before upload")
+	}
+	if err := s.Put(ctx, d, bytes.NewReader([]byte("something else"))); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("got: %v, want: %v", err, ErrDigestMismatch)
+	}
+	big := bytes.Repeat([]byte("x"), 17)
+	if err := s.Put(ctx, digestOf(t, big), bytes.NewReader(big)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got: %v, want: %v", err, ErrTooLarge)
+	}
+	if err := s.Put(ctx, d, bytes.NewReader(layer)); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Has(d) {
+		t.Error("layer missing after upload")
+	}
+
+	// Only the one layer should be left behind.
+	fs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(fs), 1; got != want {
+		t.Errorf("got: %d files, want: %d", got, want)
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir, err := ioutil.TempDir("", "upload-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := []byte("layer contents")
+	d := digestOf(t, layer)
+	if err := s.Put(ctx, d, bytes.NewReader(layer)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []*claircore.Layer
+	i, err := NewIndexer(ctx, &indexer.Mock{
+		Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+			got = m.Layers
+			return &claircore.IndexReport{}, nil
+		},
+	}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := digestOf(t, []byte("other"))
+	m := &claircore.Manifest{
+		Layers: []*claircore.Layer{
+			{Hash: d},
+			{Hash: other, URI: "https://example.com/" + other.String()},
+		},
+	}
+	if _, err := i.Index(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if got[1].URI != "https://example.com/"+other.String() {
+		t.Errorf("layer with URI modified: %q", got[1].URI)
+	}
+	if !strings.HasPrefix(got[0].URI, "http://127.0.0.1:") {
+		t.Fatalf("unexpected URI: %q", got[0].URI)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, got[0].URI, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusUnauthorized; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	req.Header = got[0].Headers
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, layer) {
+		t.Errorf("got: %q, want: %q", b, layer)
+	}
+}
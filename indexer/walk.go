@@ -0,0 +1,21 @@
+package indexer
+
+// Walk calls f with s and then with each indexer it wraps, outermost first,
+// until f returns true. Wrappers expose the indexer they wrap with an
+// "Unwrap() Service" method. Walk reports whether f returned true.
+func Walk(s interface{}, f func(interface{}) bool) bool {
+	type unwrapper interface {
+		Unwrap() Service
+	}
+	for s != nil {
+		if f(s) {
+			return true
+		}
+		u, ok := s.(unwrapper)
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+	return false
+}
@@ -2,13 +2,31 @@ package initialize
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
 
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/admin"
+	"github.com/quay/clair/v4/archive"
+	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/config"
+	"github.com/quay/clair/v4/dbauth"
+	"github.com/quay/clair/v4/embedded"
 	"github.com/quay/clair/v4/httptransport"
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/gc"
+	awsauth "github.com/quay/clair/v4/internal/aws"
+	"github.com/quay/clair/v4/internal/gcp"
 	"github.com/quay/clair/v4/introspection"
+	"github.com/quay/clair/v4/lock"
+	"github.com/quay/clair/v4/logging"
 	"github.com/quay/clair/v4/matcher"
 	notifier "github.com/quay/clair/v4/notifier/service"
+	"github.com/quay/clair/v4/tenant"
+	"github.com/quay/clair/v4/updaters"
 )
 
 type Init struct {
@@ -31,6 +49,36 @@ type Init struct {
 	// Introspection provides metrics and trace exporters,
 	// a pprof diagnostics server, and a healthz endpoint
 	Introspection *introspection.Server
+	// LogLevels holds the current log levels, which may be changed via the
+	// introspection server.
+	LogLevels *logging.Levels
+	// Any embedded databases started for the configured services.
+	embedded []*embedded.Database
+	// Any proxies authenticating to databases with cloud identities.
+	proxies []*dbauth.Proxy
+	// The tenant records, if tenancy is configured.
+	tenants *tenant.Store
+	// The report archiver, if archival is configured.
+	arch *archive.Archiver
+	// The Redis locker, if Redis locks are configured.
+	locks *lock.Redis
+	// The index report collector, if garbage collection is configured.
+	collector *gc.Collector
+	// The admin API backends for the services run locally.
+	admin admin.Services
+	// The client layers are fetched with, if fetches are limited.
+	fetchClient *http.Client
+	// The bandwidth limit and cache shared by updaters, if configured.
+	updaterHTTP *updaters.Shared
+
+	// Background jobs drained on shutdown, and the goroutines running them.
+	drainers []drainer
+	jobs     sync.WaitGroup
+	// Goroutines that stop once the GlobalCTX is done, like ones closing
+	// connection pools.
+	bg sync.WaitGroup
+	// Set once Shutdown is called.
+	draining int32
 }
 
 // New wil begin an init process and return
@@ -47,20 +95,52 @@ func New(conf config.Config) (*Init, error) {
 		return nil, err
 	}
 
+	// start an embedded database if any service asked for one. this
+	// rewrites the connection strings, so must happen before services.
+	err = i.Embedded()
+	if err != nil {
+		return nil, err
+	}
+
+	// likewise, connection strings for databases using iam authentication
+	// are pointed at local proxies.
+	err = i.DatabaseAuth()
+	if err != nil {
+		i.Close()
+		return nil, err
+	}
+
 	// init services. Indexer and Matcher
 	// fields will be initialized here.
 	err = i.Services()
 	if err != nil {
+		i.Close()
+		return nil, err
+	}
+
+	// start integrations with other systems, which need the services.
+	err = i.Integrations()
+	if err != nil {
+		i.Close()
 		return nil, err
 	}
 
 	// init introspection.
 	// a returned nil means no introspection configured
 	// a returned error means initialization failed
-	i.Introspection, err = introspection.New(i.GlobalCTX, conf, nil)
+	i.Introspection, err = introspection.New(i.GlobalCTX, conf, i.healthy)
 	if err != nil {
 		return nil, err
 	}
+	i.Introspection.WithLogLevels(i.LogLevels)
+	if a := conf.Auth.Admin; a != nil && a.MTLS == nil {
+		err := i.Introspection.WithAuth(func(next http.Handler) (http.Handler, error) {
+			return httptransport.AdminAuthHandler(&conf, next)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// init http transport.
 	// init will either succeed or fail.
@@ -68,6 +148,112 @@ func New(conf config.Config) (*Init, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !conf.Auth.Any() && conf.Auth.Admin == nil {
+		zerolog.Ctx(i.GlobalCTX).Warn().
+			Str("component", "init/New").
+			Msg("authentication not configured, admin API disabled")
+	}
+	i.HttpTransport.WithAdmin(i.admin)
 
 	return i, nil
 }
+
+// Embedded starts an embedded database for every configured connection
+// string using the embedded scheme, and replaces the connection string with
+// one pointing at the running server.
+func (i *Init) Embedded() error {
+	for _, cs := range []*string{
+		&i.conf.Indexer.ConnString,
+		&i.conf.Matcher.ConnString,
+		&i.conf.Notifier.ConnString,
+	} {
+		if !embedded.Is(*cs) {
+			continue
+		}
+		db, err := embedded.Start(i.GlobalCTX, *cs)
+		if err != nil {
+			return err
+		}
+		i.embedded = append(i.embedded, db)
+		*cs = db.ConnString
+	}
+	return nil
+}
+
+// DatabaseAuth starts a proxy for every configured connection string using
+// IAM authentication, and replaces the connection string with one pointing
+// at the proxy.
+func (i *Init) DatabaseAuth() error {
+	for _, db := range []struct {
+		iam *config.DatabaseIAM
+		cs  []*string
+	}{
+		{i.conf.Indexer.IAM, []*string{&i.conf.Indexer.ConnString, &i.conf.Indexer.ReadConnString}},
+		{i.conf.Matcher.IAM, []*string{&i.conf.Matcher.ConnString, &i.conf.Matcher.ReadConnString}},
+		{i.conf.Notifier.IAM, []*string{&i.conf.Notifier.ConnString}},
+	} {
+		if db.iam == nil {
+			continue
+		}
+		tok, err := databaseToken(db.iam)
+		if err != nil {
+			return clairerror.Wrap(clairerror.Internal, err, "failed to configure database iam authentication")
+		}
+		for _, cs := range db.cs {
+			if *cs == "" {
+				continue
+			}
+			p, err := dbauth.Start(i.GlobalCTX, *cs, tok)
+			if err != nil {
+				return clairerror.Wrap(clairerror.Internal, err, "failed to start database authentication proxy")
+			}
+			i.proxies = append(i.proxies, p)
+			*cs = p.ConnString
+		}
+	}
+	return nil
+}
+
+func databaseToken(conf *config.DatabaseIAM) (dbauth.TokenFunc, error) {
+	switch conf.Cloud {
+	case dbauth.CloudAWS:
+		p := awsauth.NewProvider(nil, awsauth.Credentials{}, conf.Region, conf.RoleARN)
+		return dbauth.RDSToken(p, conf.Region), nil
+	case dbauth.CloudGCP:
+		var sa *gcp.ServiceAccount
+		if f := conf.CredentialsFile; f != "" {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			sa, err = gcp.ParseServiceAccount(b)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return dbauth.CloudSQLToken(gcp.NewTokenSource(nil, sa, gcp.SQLLoginScope)), nil
+	}
+	return nil, fmt.Errorf("unknown cloud %q", conf.Cloud)
+}
+
+// Close releases resources that outlive the GlobalCTX, namely any embedded
+// databases and authentication proxies.
+func (i *Init) Close() error {
+	log := zerolog.Ctx(i.GlobalCTX).With().Str("component", "init/Init.Close").Logger()
+	var err error
+	for _, p := range i.proxies {
+		if e := p.Close(); e != nil {
+			log.Warn().Err(e).Msg("failed to stop database authentication proxy")
+			err = e
+		}
+	}
+	i.proxies = nil
+	for _, db := range i.embedded {
+		if e := db.Close(); e != nil {
+			log.Warn().Err(e).Msg("failed to stop embedded database")
+			err = e
+		}
+	}
+	i.embedded = nil
+	return err
+}
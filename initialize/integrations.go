@@ -0,0 +1,57 @@
+package initialize
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/integrations/quay"
+)
+
+// Integrations starts any configured integrations with other systems.
+//
+// Must be called after Services.
+func (i *Init) Integrations() error {
+	conf := i.conf.Integrations.Quay
+	if conf == nil {
+		return nil
+	}
+	log := zerolog.Ctx(i.GlobalCTX).With().
+		Str("component", "init/Init.Integrations").
+		Logger()
+	c, err := quay.NewClient(conf.URL, conf.Token, nil)
+	if err != nil {
+		return clairerror.Wrap(clairerror.Internal, err, "failed to configure quay integration")
+	}
+	q := quay.New(&quay.Opts{
+		Client:           c,
+		Indexer:          i.Indexer,
+		Matcher:          i.Matcher,
+		Namespaces:       conf.Namespaces,
+		BackfillInterval: conf.BackfillInterval,
+		HookSecret:       conf.HookSecret,
+		Concurrency:      conf.Concurrency,
+	})
+	if conf.HookAddr != "" {
+		ln, err := net.Listen("tcp", conf.HookAddr)
+		if err != nil {
+			return clairerror.Wrap(clairerror.Internal, err, "failed to start quay hook server")
+		}
+		srv := &http.Server{
+			Handler:     q,
+			BaseContext: func(net.Listener) context.Context { return i.GlobalCTX },
+		}
+		go srv.Serve(ln)
+		i.background(func() {
+			<-i.GlobalCTX.Done()
+			srv.Close()
+		})
+		log.Info().Str("addr", ln.Addr().String()).Msg("quay hook server listening")
+	}
+	go q.Run(i.GlobalCTX)
+	log.Info().Str("url", conf.URL).Msg("quay integration configured")
+	return nil
+}
@@ -2,36 +2,74 @@ package initialize
 
 import (
 	"context"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+
+	"github.com/quay/clair/v4/logging"
 )
 
 // Logging will set the global logging level for Clair,
 // create a global logger embedded into a CTX,
 // and sets this CTX as our application's GlobalCTX.
 func (i *Init) Logging() error {
-	// global log level
-	level := LogLevel(i.conf.LogLevel)
-	zerolog.SetGlobalLevel(level)
+	conf := i.conf.Logging
+	var out io.Writer = os.Stderr
+	if strings.EqualFold(conf.Format, "console") || strings.EqualFold(i.conf.LogLevel, "debug-color") {
+		out = zerolog.ConsoleWriter{Out: os.Stderr}
+	}
+
+	// global log level, along with any per-component overrides
+	levels, err := logging.NewLevels(LogLevel(i.conf.LogLevel), conf.Components)
+	if err != nil {
+		return err
+	}
+	i.LogLevels = levels
+	log.Logger = zerolog.New(levels.Writer(out))
 
 	// attach global logger to ctx
 	i.GlobalCTX, i.GlobalCancel = context.WithCancel(context.Background())
 	globalLogger := log.With().Timestamp().Logger()
+	if s := conf.Sampling; s != nil {
+		globalLogger = globalLogger.Sample(sampler(s.Burst, s.Period, s.Every))
+	}
 	i.GlobalCTX = globalLogger.WithContext(i.GlobalCTX)
 
 	globalLogger.Info().Str("component", "init/Init.Logging").Msg("logging initialized")
 	return nil
 }
 
+// Sampler returns a zerolog.Sampler for debug and info messages.
+func sampler(burst uint32, period time.Duration, every uint32) zerolog.Sampler {
+	var s zerolog.Sampler
+	if every != 0 {
+		s = &zerolog.BasicSampler{N: every}
+	}
+	if burst != 0 {
+		if period == 0 {
+			period = time.Second
+		}
+		s = &zerolog.BurstSampler{
+			Burst:       burst,
+			Period:      period,
+			NextSampler: s,
+		}
+	}
+	return zerolog.LevelSampler{
+		TraceSampler: s,
+		DebugSampler: s,
+		InfoSampler:  s,
+	}
+}
+
 func LogLevel(level string) zerolog.Level {
 	level = strings.ToLower(level)
 	switch level {
 	case "debug-color":
-		// set global logger to use ConsoleWriter for colorized output
-		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 		return zerolog.DebugLevel
 	case "debug":
 		return zerolog.DebugLevel
@@ -1,20 +1,87 @@
 package initialize
 
 import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"database/sql"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"time"
 
+	"github.com/jackc/pgx/v4/pgxpool"
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/dpkg"
 	"github.com/quay/claircore/libindex"
+	libindexmigrations "github.com/quay/claircore/libindex/migrations"
 	"github.com/quay/claircore/libvuln"
 	"github.com/quay/claircore/libvuln/driver"
+	libvulnmigrations "github.com/quay/claircore/libvuln/migrations"
+	"github.com/quay/claircore/python"
+	"github.com/quay/claircore/rhel"
+	"github.com/quay/claircore/rpm"
+	"github.com/remind101/migrate"
 	"github.com/rs/zerolog"
 	"gopkg.in/square/go-jose.v2/jwt"
 
+	"github.com/quay/clair/v4/admin"
+	"github.com/quay/clair/v4/affected"
+	"github.com/quay/clair/v4/archive"
 	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/config"
+	"github.com/quay/clair/v4/history"
+	historymigrations "github.com/quay/clair/v4/history/migrations"
 	"github.com/quay/clair/v4/httptransport"
 	"github.com/quay/clair/v4/httptransport/client"
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/annotations"
+	annotationsmigrations "github.com/quay/clair/v4/indexer/annotations/migrations"
+	"github.com/quay/clair/v4/indexer/baseimage"
+	"github.com/quay/clair/v4/indexer/budget"
+	budgetmigrations "github.com/quay/clair/v4/indexer/budget/migrations"
+	"github.com/quay/clair/v4/indexer/eol"
+	"github.com/quay/clair/v4/indexer/events"
+	eventsmigrations "github.com/quay/clair/v4/indexer/events/migrations"
+	"github.com/quay/clair/v4/indexer/exclude"
+	"github.com/quay/clair/v4/indexer/gc"
+	gcmigrations "github.com/quay/clair/v4/indexer/gc/migrations"
+	"github.com/quay/clair/v4/indexer/hook"
+	hookmigrations "github.com/quay/clair/v4/indexer/hook/migrations"
+	"github.com/quay/clair/v4/indexer/inventory"
+	inventorymigrations "github.com/quay/clair/v4/indexer/inventory/migrations"
+	"github.com/quay/clair/v4/indexer/layercache"
+	"github.com/quay/clair/v4/indexer/layers"
+	"github.com/quay/clair/v4/indexer/limit"
+	"github.com/quay/clair/v4/indexer/referrers"
+	referrersmigrations "github.com/quay/clair/v4/indexer/referrers/migrations"
+	"github.com/quay/clair/v4/indexer/registry"
+	"github.com/quay/clair/v4/indexer/reindex"
+	reindexmigrations "github.com/quay/clair/v4/indexer/reindex/migrations"
+	"github.com/quay/clair/v4/indexer/signature"
+	signaturemigrations "github.com/quay/clair/v4/indexer/signature/migrations"
+	"github.com/quay/clair/v4/indexer/upload"
+	awsauth "github.com/quay/clair/v4/internal/aws"
+	"github.com/quay/clair/v4/internal/gcp"
+	"github.com/quay/clair/v4/lock"
+	"github.com/quay/clair/v4/matcher"
+	"github.com/quay/clair/v4/matcher/cache"
+	"github.com/quay/clair/v4/matcher/dryrun"
+	"github.com/quay/clair/v4/matcher/remote"
+	clairnotifier "github.com/quay/clair/v4/notifier"
+	notifiermigrations "github.com/quay/clair/v4/notifier/migrations"
 	notifier "github.com/quay/clair/v4/notifier/service"
+	"github.com/quay/clair/v4/replica"
+	"github.com/quay/clair/v4/severity"
+	"github.com/quay/clair/v4/signing"
+	"github.com/quay/clair/v4/tenant"
+	tenantmigrations "github.com/quay/clair/v4/tenant/migrations"
+	"github.com/quay/clair/v4/updaters"
+	updatermigrations "github.com/quay/clair/v4/updaters/migrations"
+	"github.com/quay/clair/v4/vex"
+	vexmigrations "github.com/quay/clair/v4/vex/migrations"
 )
 
 const (
@@ -37,172 +104,456 @@ func (i *Init) Services() error {
 	switch i.conf.Mode {
 	case config.ComboMode:
 		// configure two local services via claircore libraries
-		opts := libindex.Opts{
-			ConnString:           i.conf.Indexer.ConnString,
-			ScanLockRetry:        time.Duration(i.conf.Indexer.ScanLockRetry) * time.Second,
-			LayerScanConcurrency: i.conf.Indexer.LayerScanConcurrency,
-			Migrations:           i.conf.Indexer.Migrations,
-			Airgap:               i.conf.Indexer.Airgap,
-		}
-		if i.conf.Indexer.Scanner.Package != nil {
-			opts.ScannerConfig.Package = make(map[string]func(interface{}) error, len(i.conf.Indexer.Scanner.Package))
-			for name, node := range i.conf.Indexer.Scanner.Package {
-				opts.ScannerConfig.Package[name] = node.Decode
-			}
+		opts, err := i.libindexOpts()
+		if err != nil {
+			return err
 		}
-		if i.conf.Indexer.Scanner.Dist != nil {
-			opts.ScannerConfig.Dist = make(map[string]func(interface{}) error, len(i.conf.Indexer.Scanner.Dist))
-			for name, node := range i.conf.Indexer.Scanner.Dist {
-				opts.ScannerConfig.Dist[name] = node.Decode
-			}
+		libI, err := libindex.New(i.GlobalCTX, opts)
+		if err != nil {
+			return clairerror.Wrap(clairerror.Internal, err, "failed to initialize libindex")
 		}
-		if i.conf.Indexer.Scanner.Repo != nil {
-			opts.ScannerConfig.Repo = make(map[string]func(interface{}) error, len(i.conf.Indexer.Scanner.Repo))
-			for name, node := range i.conf.Indexer.Scanner.Repo {
-				opts.ScannerConfig.Repo[name] = node.Decode
-			}
+		idx, err := i.indexerReplica(libI)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerLayers(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerBudget(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerBaseImages(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerCache(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerExclude(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerGC(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerArchive(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerSignatures(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerReferrers(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerAnnotations(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerInventory(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerHooks(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerRegistries(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerReindex(idx, libI)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerTenancy(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerEndOfLife(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerEvents(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerUploads(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerLimit(idx)
+		if err != nil {
+			return err
+		}
+		if err := i.adminIndexer(); err != nil {
+			return err
+		}
+		i.Indexer = idx
+		if !i.conf.Combo.Matcher() {
+			log.Info().Msg("matcher and notifier disabled")
+			break
+		}
+
+		updaterSets, updaterConfigs, overrides, err := i.updaterOverrides()
+		if err != nil {
+			return err
 		}
-		libI, err := libindex.New(i.GlobalCTX, &opts)
+		updaterClient, err := i.updaterClient()
 		if err != nil {
-			return clairerror.ErrNotInitialized{Msg: "failed to initialize libindex: " + err.Error()}
+			return err
 		}
-		updaterConfigs := make(map[string]driver.ConfigUnmarshaler)
-		for name, node := range i.conf.Updaters.Config {
-			updaterConfigs[name] = node.Decode
+		matchers, err := i.matcherPlugins()
+		if err != nil {
+			return err
 		}
-		libV, err := libvuln.New(i.GlobalCTX, &libvuln.Opts{
+		vulnOpts := &libvuln.Opts{
 			MaxConnPool:     int32(i.conf.Matcher.MaxConnPool),
 			ConnString:      i.conf.Matcher.ConnString,
 			Migrations:      i.conf.Matcher.Migrations,
-			UpdaterSets:     i.conf.Updaters.Sets,
+			UpdaterSets:     updaterSets,
 			UpdateInterval:  i.conf.Matcher.Period,
 			UpdaterConfigs:  updaterConfigs,
 			UpdateRetention: i.conf.Matcher.UpdateRetention,
-		})
+			Matchers:        matchers,
+			Client:          updaterClient,
+
+			// Updates are run by updateLeader, so they can be drained.
+			DisableBackgroundUpdates: true,
+		}
+		libV, err := libvuln.New(i.GlobalCTX, vulnOpts)
 		if err != nil {
 			return fmt.Errorf("failed to initialize libvuln: %v", err)
 		}
+		if err := i.updateLeader(libV); err != nil {
+			return err
+		}
+		ms, err := i.matcherReplica(libV)
+		if err != nil {
+			return err
+		}
+		ms, err = i.matcherCache(ms)
+		if err != nil {
+			return err
+		}
+		if overrides != nil {
+			ms = updaters.NewMatcher(ms, overrides)
+		}
+		ms, err = i.matcherSeverity(ms)
+		if err != nil {
+			return err
+		}
+		ms, err = i.matcherArchive(ms)
+		if err != nil {
+			return err
+		}
+		ms, err = i.matcherAffected(ms)
+		if err != nil {
+			return err
+		}
+		ms, err = i.matcherSigning(ms)
+		if err != nil {
+			return err
+		}
+		m, err := i.matcherVEX(ms)
+		if err != nil {
+			return err
+		}
+		m, err = i.matcherHistory(m)
+		if err != nil {
+			return err
+		}
+		i.adminMatcher(libV, vulnOpts.Matchers)
+		i.Matcher = m
+		if !i.conf.Combo.Notifier() {
+			log.Info().Msg("notifier disabled")
+			break
+		}
 
 		c, _, err := i.conf.Client(nil, notifierClaim)
 		if err != nil {
 			return err
 		}
+		owners, err := i.notifierOwners()
+		if err != nil {
+			return err
+		}
 
 		n, err := notifier.New(i.GlobalCTX, notifier.Opts{
 			DeliveryInterval: i.conf.Notifier.DeliveryInterval,
 			ConnString:       i.conf.Notifier.ConnString,
-			Indexer:          libI,
-			Matcher:          libV,
+			Indexer:          idx,
+			Matcher:          m,
 			Client:           c,
 			Migrations:       i.conf.Notifier.Migrations,
 			PollInterval:     i.conf.Notifier.PollInterval,
+			MaxAge:           i.conf.Notifier.Retention.MaxAge,
+			PruneInterval:    i.conf.Notifier.Retention.Interval,
+			CoalesceWindow:   i.conf.Notifier.CoalesceWindow,
 			DisableSummary:   i.conf.Notifier.DisableSummary,
+			ClaimBatch:       i.conf.Notifier.DeliveryBatchSize,
+			ClaimLease:       i.conf.Notifier.DeliveryLease,
+			LayerAttribution: i.conf.Notifier.LayerAttribution,
 			Webhook:          i.conf.Notifier.Webhook,
 			AMQP:             i.conf.Notifier.AMQP,
 			STOMP:            i.conf.Notifier.STOMP,
+			PubSub:           i.conf.Notifier.PubSub,
+			AzureServiceBus:  i.conf.Notifier.AzureServiceBus,
+			AWS:              i.conf.Notifier.AWS,
+			NATS:             i.conf.Notifier.NATS,
+			PagerDuty:        i.conf.Notifier.PagerDuty,
+			OpsGenie:         i.conf.Notifier.OpsGenie,
+			Jira:             i.conf.Notifier.Jira,
+			DefectDojo:       i.conf.Notifier.DefectDojo,
+			Redis:            i.conf.Notifier.Redis,
+
+			DeliveryConcurrency: i.conf.Notifier.DeliveryConcurrency,
+			DeliveryRateLimit:   i.conf.Notifier.DeliveryRateLimit,
+			DeliveryBurst:       i.conf.Notifier.DeliveryBurst,
+
+			Annotations: i.conf.Notifier.Annotations,
+			Owners:      owners,
 		})
 		if err != nil {
-			return &clairerror.ErrNotInitialized{
-				Msg: "notifier failed to initialize: " + err.Error(),
-			}
+			return clairerror.Wrap(clairerror.Internal, err, "notifier failed to initialize")
 		}
+		i.job(n, n.Wait)
 
-		i.Indexer = libI
-		i.Matcher = libV
-		i.Notifier = n
+		nt, err := i.notifierTenancy(n)
+		if err != nil {
+			return err
+		}
+
+		i.adminNotifier()
+		i.Notifier = nt
 	case config.IndexerMode:
 		// configure just a local indexer
-		opts := libindex.Opts{
-			ConnString:           i.conf.Indexer.ConnString,
-			ScanLockRetry:        time.Duration(i.conf.Indexer.ScanLockRetry) * time.Second,
-			LayerScanConcurrency: i.conf.Indexer.LayerScanConcurrency,
-			Migrations:           i.conf.Indexer.Migrations,
-			Airgap:               i.conf.Indexer.Airgap,
-		}
-		if i.conf.Indexer.Scanner.Package != nil {
-			opts.ScannerConfig.Package = make(map[string]func(interface{}) error, len(i.conf.Indexer.Scanner.Package))
-			for name, node := range i.conf.Indexer.Scanner.Package {
-				opts.ScannerConfig.Package[name] = node.Decode
-			}
+		opts, err := i.libindexOpts()
+		if err != nil {
+			return err
 		}
-		if i.conf.Indexer.Scanner.Dist != nil {
-			opts.ScannerConfig.Dist = make(map[string]func(interface{}) error, len(i.conf.Indexer.Scanner.Dist))
-			for name, node := range i.conf.Indexer.Scanner.Dist {
-				opts.ScannerConfig.Dist[name] = node.Decode
-			}
+		libI, err := libindex.New(i.GlobalCTX, opts)
+		if err != nil {
+			return clairerror.Wrap(clairerror.Internal, err, "failed to initialize libindex")
 		}
-		if i.conf.Indexer.Scanner.Repo != nil {
-			opts.ScannerConfig.Repo = make(map[string]func(interface{}) error, len(i.conf.Indexer.Scanner.Repo))
-			for name, node := range i.conf.Indexer.Scanner.Repo {
-				opts.ScannerConfig.Repo[name] = node.Decode
-			}
+		idx, err := i.indexerReplica(libI)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerLayers(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerBudget(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerBaseImages(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerCache(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerExclude(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerGC(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerArchive(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerSignatures(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerReferrers(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerAnnotations(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerInventory(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerHooks(idx)
+		if err != nil {
+			return err
 		}
-		libI, err := libindex.New(i.GlobalCTX, &opts)
+		idx, err = i.indexerRegistries(idx)
 		if err != nil {
-			return clairerror.ErrNotInitialized{Msg: "failed to initialize libindex: " + err.Error()}
+			return err
+		}
+		idx, err = i.indexerReindex(idx, libI)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerTenancy(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerEndOfLife(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerEvents(idx)
+		if err != nil {
+			return err
+		}
+		idx, err = i.indexerUploads(idx)
+		if err != nil {
+			return err
 		}
-		i.Indexer = libI
+		idx, err = i.indexerLimit(idx)
+		if err != nil {
+			return err
+		}
+		if err := i.adminIndexer(); err != nil {
+			return err
+		}
+		i.Indexer = idx
 		i.Matcher = nil
 	case config.MatcherMode:
-		updaterConfigs := make(map[string]driver.ConfigUnmarshaler)
-		for name, node := range i.conf.Updaters.Config {
-			updaterConfigs[name] = node.Decode
+		updaterSets, updaterConfigs, overrides, err := i.updaterOverrides()
+		if err != nil {
+			return err
+		}
+		updaterClient, err := i.updaterClient()
+		if err != nil {
+			return err
 		}
 		// configure a local matcher but a remote indexer
-		libV, err := libvuln.New(i.GlobalCTX, &libvuln.Opts{
+		matchers, err := i.matcherPlugins()
+		if err != nil {
+			return err
+		}
+		vulnOpts := &libvuln.Opts{
 			MaxConnPool:     int32(i.conf.Matcher.MaxConnPool),
 			ConnString:      i.conf.Matcher.ConnString,
 			Migrations:      i.conf.Matcher.Migrations,
-			UpdaterSets:     i.conf.Updaters.Sets,
+			UpdaterSets:     updaterSets,
 			UpdateInterval:  i.conf.Matcher.Period,
 			UpdaterConfigs:  updaterConfigs,
 			UpdateRetention: i.conf.Matcher.UpdateRetention,
-		})
+			Matchers:        matchers,
+			Client:          updaterClient,
+
+			// Updates are run by updateLeader, so they can be drained.
+			DisableBackgroundUpdates: true,
+		}
+		libV, err := libvuln.New(i.GlobalCTX, vulnOpts)
 		if err != nil {
 			return fmt.Errorf("failed to initialize libvuln: %v", err)
 		}
+		if err := i.updateLeader(libV); err != nil {
+			return err
+		}
+		ms, err := i.matcherReplica(libV)
+		if err != nil {
+			return err
+		}
+		ms, err = i.matcherCache(ms)
+		if err != nil {
+			return err
+		}
+		if overrides != nil {
+			ms = updaters.NewMatcher(ms, overrides)
+		}
+		ms, err = i.matcherSeverity(ms)
+		if err != nil {
+			return err
+		}
+		ms, err = i.matcherArchive(ms)
+		if err != nil {
+			return err
+		}
+		ms, err = i.matcherAffected(ms)
+		if err != nil {
+			return err
+		}
+		ms, err = i.matcherSigning(ms)
+		if err != nil {
+			return err
+		}
+		m, err := i.matcherVEX(ms)
+		if err != nil {
+			return err
+		}
+		m, err = i.matcherHistory(m)
+		if err != nil {
+			return err
+		}
 		// matcher mode needs a remote indexer client
-		c, auth, err := i.conf.Client(nil, intraserviceClaim)
+		c, auth, err := i.conf.IntraserviceClient(nil, intraserviceClaim)
 		switch {
 		case err != nil:
 			return err
 		case !auth && i.conf.Auth.Any():
-			return &clairerror.ErrNotInitialized{
-				Msg: "client authorization required but not provided",
-			}
+			return clairerror.New(clairerror.Internal, "client authorization required but not provided")
 		default: // OK
 		}
 		remoteIndexer, err := client.NewHTTP(i.GlobalCTX,
 			client.WithAddr(i.conf.Matcher.IndexerAddr),
-			client.WithClient(c))
+			client.WithClient(c),
+			i.clientRetry())
 		if err != nil {
 			return err
 		}
-		i.Indexer = remoteIndexer
-		i.Matcher = libV
+		// Affected manifests are looked up here, so they're scoped here as
+		// well if the tenant records are reachable.
+		var idx indexer.Service = remoteIndexer
+		if i.conf.Tenancy.ConnString != "" {
+			idx, err = i.indexerTenancy(idx)
+			if err != nil {
+				return err
+			}
+		}
+		// Vulnerability reports are served here, so end of life
+		// distributions are detected here as well.
+		idx, err = i.indexerEndOfLife(idx)
+		if err != nil {
+			return err
+		}
+		i.adminMatcher(libV, vulnOpts.Matchers)
+		i.Indexer = idx
+		i.Matcher = m
 	case config.NotifierMode:
 		// notifier uses a remote indexer and matcher
-		c, auth, err := i.conf.Client(nil, intraserviceClaim)
+		c, auth, err := i.conf.IntraserviceClient(nil, intraserviceClaim)
 		switch {
 		case err != nil:
 			return err
 		case !auth && i.conf.Auth.Any():
-			return &clairerror.ErrNotInitialized{
-				Msg: "client authorization required but not provided",
-			}
+			return clairerror.New(clairerror.Internal, "client authorization required but not provided")
 		default: // OK
 		}
 
 		remoteIndexer, err := client.NewHTTP(i.GlobalCTX,
 			client.WithAddr(i.conf.Notifier.IndexerAddr),
-			client.WithClient(c))
+			client.WithClient(c),
+			i.clientRetry())
 		if err != nil {
 			return err
 		}
 
 		remoteMatcher, err := client.NewHTTP(i.GlobalCTX,
 			client.WithAddr(i.conf.Notifier.MatcherAddr),
-			client.WithClient(c))
+			client.WithClient(c),
+			i.clientRetry())
 		if err != nil {
 			return err
 		}
@@ -211,6 +562,10 @@ func (i *Init) Services() error {
 		if err != nil {
 			return err
 		}
+		owners, err := i.notifierOwners()
+		if err != nil {
+			return err
+		}
 
 		n, err := notifier.New(i.GlobalCTX, notifier.Opts{
 			DeliveryInterval: i.conf.Notifier.DeliveryInterval,
@@ -220,18 +575,41 @@ func (i *Init) Services() error {
 			Client:           c,
 			Migrations:       i.conf.Notifier.Migrations,
 			PollInterval:     i.conf.Notifier.PollInterval,
+			MaxAge:           i.conf.Notifier.Retention.MaxAge,
+			PruneInterval:    i.conf.Notifier.Retention.Interval,
+			CoalesceWindow:   i.conf.Notifier.CoalesceWindow,
 			Webhook:          i.conf.Notifier.Webhook,
 			AMQP:             i.conf.Notifier.AMQP,
 			STOMP:            i.conf.Notifier.STOMP,
+			PubSub:           i.conf.Notifier.PubSub,
+			AzureServiceBus:  i.conf.Notifier.AzureServiceBus,
+			AWS:              i.conf.Notifier.AWS,
+			NATS:             i.conf.Notifier.NATS,
+			PagerDuty:        i.conf.Notifier.PagerDuty,
+			OpsGenie:         i.conf.Notifier.OpsGenie,
+			Jira:             i.conf.Notifier.Jira,
+			DefectDojo:       i.conf.Notifier.DefectDojo,
+			Redis:            i.conf.Notifier.Redis,
+
+			DeliveryConcurrency: i.conf.Notifier.DeliveryConcurrency,
+			DeliveryRateLimit:   i.conf.Notifier.DeliveryRateLimit,
+			DeliveryBurst:       i.conf.Notifier.DeliveryBurst,
+
+			Annotations: i.conf.Notifier.Annotations,
+			Owners:      owners,
 		})
 		if err != nil {
-			return &clairerror.ErrNotInitialized{
-				Msg: "notifier failed to initialize: " + err.Error(),
-			}
+			return clairerror.Wrap(clairerror.Internal, err, "notifier failed to initialize")
+		}
+		i.job(n, n.Wait)
+		nt, err := i.notifierTenancy(n)
+		if err != nil {
+			return err
 		}
+		i.adminNotifier()
 		i.Indexer = remoteIndexer
 		i.Matcher = remoteMatcher
-		i.Notifier = n
+		i.Notifier = nt
 
 	default:
 		return fmt.Errorf("could not determine passed in mode: %v", i.conf.Mode)
@@ -240,7 +618,1433 @@ func (i *Init) Services() error {
 	return nil
 }
 
-var (
-	intraserviceClaim = jwt.Claims{Issuer: httptransport.IntraserviceIssuer}
-	notifierClaim     = jwt.Claims{Issuer: NotifierIssuer}
-)
+// MatcherPlugins returns the configured remote matchers.
+func (i *Init) matcherPlugins() ([]driver.Matcher, error) {
+	var out []driver.Matcher
+	for _, p := range i.conf.Matcher.Plugins {
+		m, err := remote.NewMatcher(remote.Opts{
+			Name:      p.Name,
+			URL:       p.URL,
+			Token:     p.Token,
+			BatchSize: p.BatchSize,
+			Timeout:   p.Timeout,
+		})
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure matcher plugin")
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// LibindexOpts returns the options for the local Libindex.
+func (i *Init) libindexOpts() (*libindex.Opts, error) {
+	conf := &i.conf.Indexer
+	opts := libindex.Opts{
+		ConnString:           conf.ConnString,
+		ScanLockRetry:        time.Duration(conf.ScanLockRetry) * time.Second,
+		LayerScanConcurrency: conf.LayerScanConcurrency,
+		Migrations:           conf.Migrations,
+		Airgap:               conf.Airgap,
+	}
+	if conf.Concurrency.Scan != 0 {
+		opts.LayerScanConcurrency = conf.Concurrency.Scan
+	}
+	if conf.Scanner.Package != nil {
+		opts.ScannerConfig.Package = make(map[string]func(interface{}) error, len(conf.Scanner.Package))
+		for name, node := range conf.Scanner.Package {
+			opts.ScannerConfig.Package[name] = node.Decode
+		}
+	}
+	if conf.Scanner.Dist != nil {
+		opts.ScannerConfig.Dist = make(map[string]func(interface{}) error, len(conf.Scanner.Dist))
+		for name, node := range conf.Scanner.Dist {
+			opts.ScannerConfig.Dist[name] = node.Decode
+		}
+	}
+	if conf.Scanner.Repo != nil {
+		opts.ScannerConfig.Repo = make(map[string]func(interface{}) error, len(conf.Scanner.Repo))
+		for name, node := range conf.Scanner.Repo {
+			opts.ScannerConfig.Repo[name] = node.Decode
+		}
+	}
+	if sc := &conf.Scanner; len(sc.Enable) != 0 || len(sc.Disable) != 0 {
+		known := make(map[string]bool, len(ecosystems))
+		for _, e := range ecosystems {
+			known[e.Name] = true
+		}
+		set := func(names []string) (map[string]bool, error) {
+			m := make(map[string]bool, len(names))
+			for _, name := range names {
+				if !known[name] {
+					return nil, clairerror.New(clairerror.Internal, fmt.Sprintf("unknown scanner ecosystem %q", name))
+				}
+				m[name] = true
+			}
+			return m, nil
+		}
+		enabled, err := set(sc.Enable)
+		if err != nil {
+			return nil, err
+		}
+		disabled, err := set(sc.Disable)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range ecosystems {
+			if (len(enabled) != 0 && !enabled[e.Name]) || disabled[e.Name] {
+				continue
+			}
+			e.Add(i.GlobalCTX, &opts)
+		}
+		// Libindex would use every ecosystem instead.
+		if len(opts.Ecosystems) == 0 {
+			return nil, clairerror.New(clairerror.Internal, "every scanner ecosystem disabled")
+		}
+	}
+	return &opts, nil
+}
+
+// Ecosystems are the ecosystems libindex uses by default, by name. Leaving
+// Opts.Ecosystems nil gets all of them.
+var ecosystems = []struct {
+	Name string
+	Add  func(context.Context, *libindex.Opts)
+}{
+	{"dpkg", func(ctx context.Context, o *libindex.Opts) {
+		o.Ecosystems = append(o.Ecosystems, dpkg.NewEcosystem(ctx))
+	}},
+	{"alpine", func(ctx context.Context, o *libindex.Opts) {
+		o.Ecosystems = append(o.Ecosystems, alpine.NewEcosystem(ctx))
+	}},
+	{"rhel", func(ctx context.Context, o *libindex.Opts) {
+		o.Ecosystems = append(o.Ecosystems, rhel.NewEcosystem(ctx))
+	}},
+	{"rpm", func(ctx context.Context, o *libindex.Opts) {
+		o.Ecosystems = append(o.Ecosystems, rpm.NewEcosystem(ctx))
+	}},
+	{"python", func(ctx context.Context, o *libindex.Opts) {
+		o.Ecosystems = append(o.Ecosystems, python.NewEcosystem(ctx))
+	}},
+}
+
+var (
+	intraserviceClaim = jwt.Claims{Issuer: httptransport.IntraserviceIssuer}
+	notifierClaim     = jwt.Claims{Issuer: NotifierIssuer}
+)
+
+// UpdateLeader starts a matcher.Leader running updates with the provided
+// Libvuln, unless updaters are disabled. Without leader election, the Leader
+// runs updates without contending for a lock.
+func (i *Init) updateLeader(libV *libvuln.Libvuln) error {
+	if i.conf.Matcher.DisableUpdaters {
+		return nil
+	}
+	if !i.conf.Matcher.LeaderElection {
+		l := matcher.NewLocalLeader(libV, i.conf.Matcher.Period)
+		i.job(l, func() { l.Run(i.GlobalCTX) })
+		return nil
+	}
+	lk, err := i.locker()
+	if err != nil {
+		return err
+	}
+	if lk != nil {
+		l := matcher.NewLeaderWithLock(lk, libV, i.conf.Matcher.Period, 0)
+		i.job(l, func() { l.Run(i.GlobalCTX) })
+		return nil
+	}
+	cfg, err := pgxpool.ParseConfig(i.conf.Matcher.ConnString)
+	if err != nil {
+		return clairerror.Wrap(clairerror.Internal, err, "failed to parse matcher connstring")
+	}
+	// Only one connection is ever needed, to hold the lock.
+	cfg.MaxConns = 1
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create leader election pool")
+	}
+	l := matcher.NewLeader(pool, libV, i.conf.Matcher.Period, 0)
+	i.job(l, func() {
+		defer pool.Close()
+		l.Run(i.GlobalCTX)
+	})
+	return nil
+}
+
+// Locker returns the Redis locker, if configured. A nil Locker means
+// Postgres advisory locks are used.
+func (i *Init) locker() (lock.Locker, error) {
+	conf := &i.conf.Locks
+	if conf.Backend != "redis" {
+		return nil, nil
+	}
+	if i.locks != nil {
+		return i.locks, nil
+	}
+	r, err := lock.NewRedis(conf.RedisURL, conf.TTL)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure redis locks")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		r.Close()
+	})
+	i.locks = r
+	return r, nil
+}
+
+// IndexerReplica wraps the indexer to read index reports from a replica, if
+// configured.
+func (i *Init) indexerReplica(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	if conf.ReadConnString == "" {
+		return idx, nil
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ReadConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer read connstring")
+	}
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer replica pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return replica.NewIndexer(idx, pool), nil
+}
+
+// IndexerLayers wraps the indexer to report the layers of indexed manifests,
+// for index reports with layer attribution. The replica is used, if
+// configured, like for the reports themselves.
+func (i *Init) indexerLayers(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	connString := conf.ConnString
+	if conf.ReadConnString != "" {
+		connString = conf.ReadConnString
+	}
+	cfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 2
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer layers pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return layers.NewIndexer(idx, pool), nil
+}
+
+// IndexerBaseImages wraps the indexer to detect the base images of indexed
+// manifests, if configured. It reads the manifests' layers like
+// indexerLayers, from the replica if there is one.
+func (i *Init) indexerBaseImages(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	if conf.BaseImages == nil {
+		return idx, nil
+	}
+	connString := conf.ConnString
+	if conf.ReadConnString != "" {
+		connString = conf.ReadConnString
+	}
+	cfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 2
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer base image pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return baseimage.NewIndexer(idx, pool, conf.BaseImages.MinShared), nil
+}
+
+// IndexerExclude wraps the indexer to remove excluded packages from index
+// reports, if any rules are configured.
+//
+// This is just outside the replica wrapper, so everything else sees the
+// filtered reports.
+func (i *Init) indexerExclude(idx indexer.Service) (indexer.Service, error) {
+	ex := i.conf.Indexer.Exclude
+	if ex == nil || (len(ex.Paths) == 0 && len(ex.Packages) == 0) {
+		return idx, nil
+	}
+	f, err := exclude.NewFilter(ex.Paths, ex.Packages)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure indexer exclusions")
+	}
+	return exclude.NewIndexer(idx, f), nil
+}
+
+// MatcherReplica wraps the matcher to generate vulnerability reports using a
+// replica, if configured.
+func (i *Init) matcherReplica(libV *libvuln.Libvuln) (matcher.Service, error) {
+	conf := &i.conf.Matcher
+	if conf.ReadConnString == "" {
+		return libV, nil
+	}
+	r, err := libvuln.New(i.GlobalCTX, &libvuln.Opts{
+		MaxConnPool: int32(conf.MaxConnPool),
+		ConnString:  conf.ReadConnString,
+		// The replica is only used for matching, so it needs no updaters.
+		UpdaterSets: []string{},
+
+		DisableBackgroundUpdates: true,
+	})
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to initialize matcher replica")
+	}
+	return replica.NewMatcher(libV, r), nil
+}
+
+// IndexerGC wraps the indexer to track manifest use and starts index report
+// garbage collection, if a policy is configured.
+func (i *Init) indexerGC(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	if !conf.GC.Enabled() {
+		return idx, nil
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = gcmigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, gcmigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform indexer gc migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer gc pool")
+	}
+	c := gc.NewCollector(pool, gc.Opts{
+		Interval:   conf.GC.Interval,
+		MaxAge:     conf.GC.MaxAge,
+		MaxReports: conf.GC.MaxReports,
+	})
+	i.background(func() {
+		defer pool.Close()
+		c.Run(i.GlobalCTX)
+	})
+	i.collector = c
+	return gc.NewTracker(idx, pool), nil
+}
+
+// IndexerRegistries wraps the indexer to add credentials to layer requests,
+// if any registries are configured.
+func (i *Init) indexerRegistries(idx indexer.Service) (indexer.Service, error) {
+	regs := i.conf.Indexer.Registries
+	if len(regs) == 0 {
+		return idx, nil
+	}
+	creds := make(map[string]registry.Credential, len(regs))
+	for host, r := range regs {
+		creds[host] = registry.Credential{
+			Username: r.Username,
+			Password: r.Password,
+			Token:    r.Token,
+			Helper:   r.Helper,
+			Cloud:    r.Cloud,
+			ClientID: r.ClientID,
+		}
+	}
+	a, err := registry.NewAuthorizer(creds, nil)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure registry credentials")
+	}
+	return registry.NewIndexer(idx, a), nil
+}
+
+// IndexerReindex wraps the indexer to record manifests and starts
+// reindexing them after scanner upgrades, if configured.
+//
+// This needs to be outside the registry credentials wrapper, so reindexed
+// manifests get credentials added again.
+func (i *Init) indexerReindex(idx indexer.Service, libI *libindex.Libindex) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	if conf.Reindex == nil {
+		return idx, nil
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = reindexmigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, reindexmigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform indexer reindex migrations")
+		}
+	}
+	scnrs, err := scanners(i.GlobalCTX, libI.Opts)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to list indexer scanners")
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer reindex pool")
+	}
+	r := reindex.NewRecorder(idx, pool)
+	c := reindex.NewController(pool, r, reindex.Opts{
+		Scanners:  scnrs,
+		Interval:  conf.Reindex.Interval,
+		BatchSize: conf.Reindex.BatchSize,
+	})
+	// The Recorder shares the pool, so it's only closed once the
+	// GlobalCTX is done, not when the controller is drained.
+	i.job(c, func() { c.Run(i.GlobalCTX) })
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return r, nil
+}
+
+// IndexerEvents wraps the indexer to record index events, if configured.
+//
+// This needs to be inside the uploads wrapper, which the HTTP API expects to
+// be outermost.
+func (i *Init) indexerEvents(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	if conf.Events == nil {
+		return idx, nil
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = eventsmigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, eventsmigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform indexer events migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer events pool")
+	}
+	r := events.NewRecorder(idx, pool)
+	if c, ok := eol.Find(idx); ok {
+		r.EOL = c
+	}
+	i.background(func() {
+		defer pool.Close()
+		r.Run(i.GlobalCTX, conf.Events.Retention, time.Hour)
+	})
+	return r, nil
+}
+
+// IndexerEndOfLife wraps the indexer to detect distributions past their end
+// of life, if configured.
+//
+// This needs to be inside the events wrapper, so it can record end of life
+// events.
+func (i *Init) indexerEndOfLife(idx indexer.Service) (indexer.Service, error) {
+	conf := i.conf.Indexer.EndOfLife
+	if conf == nil {
+		return idx, nil
+	}
+	rs := make([]eol.Release, len(conf.Releases))
+	for j, r := range conf.Releases {
+		// Matchers don't validate the indexer's configuration.
+		if err := r.Validate(); err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure end of life detection")
+		}
+		d, _ := time.Parse(eol.DateFormat, r.Date)
+		rs[j] = eol.Release{DID: r.ID, VersionID: r.VersionID, Date: d}
+	}
+	return eol.NewIndexer(idx, eol.NewDetector(rs)), nil
+}
+
+// Scanners returns the versions of the scanners libindex runs.
+func scanners(ctx context.Context, o *libindex.Opts) ([]reindex.Scanner, error) {
+	type versioned interface {
+		Name() string
+		Version() string
+		Kind() string
+	}
+	var out []reindex.Scanner
+	seen := make(map[string]bool)
+	add := func(s versioned) {
+		if seen[s.Name()] {
+			return
+		}
+		seen[s.Name()] = true
+		out = append(out, reindex.Scanner{Name: s.Name(), Version: s.Version(), Kind: s.Kind()})
+	}
+	for _, e := range o.Ecosystems {
+		ps, err := e.PackageScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range ps {
+			add(s)
+		}
+		ds, err := e.DistributionScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range ds {
+			add(s)
+		}
+		rs, err := e.RepositoryScanners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range rs {
+			add(s)
+		}
+	}
+	return out, nil
+}
+
+// IndexerSignatures wraps the indexer to verify manifest signatures, if
+// configured.
+//
+// This needs to be inside the registry credentials wrapper, so signatures
+// are fetched with the same credentials as layers.
+func (i *Init) indexerSignatures(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	sig := conf.Signatures
+	if sig == nil {
+		return idx, nil
+	}
+	opts := signature.Opts{
+		Keys: make(map[string]crypto.PublicKey, len(sig.Keys)),
+	}
+	for _, p := range sig.Keys {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, "failed to read signature key")
+		}
+		k, err := signature.ParsePublicKey(b)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, fmt.Sprintf("failed to parse signature key %q", p))
+		}
+		opts.Keys[p] = k
+	}
+	for _, p := range sig.RekorKeys {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, "failed to read rekor key")
+		}
+		k, err := signature.ParsePublicKey(b)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, fmt.Sprintf("failed to parse rekor key %q", p))
+		}
+		opts.RekorKeys = append(opts.RekorKeys, k)
+	}
+	if len(sig.FulcioRoots) != 0 {
+		opts.Roots = x509.NewCertPool()
+	}
+	for _, p := range sig.FulcioRoots {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, "failed to read fulcio roots")
+		}
+		cs, err := signature.ParseCertificates(b)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, fmt.Sprintf("failed to parse fulcio roots %q", p))
+		}
+		for _, c := range cs {
+			opts.Roots.AddCert(c)
+		}
+	}
+	for _, id := range sig.Identities {
+		opts.Identities = append(opts.Identities, signature.Identity{
+			Issuer:  id.Issuer,
+			Subject: id.Subject,
+		})
+	}
+	v, err := signature.NewVerifier(&opts)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure signature verification")
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = signaturemigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, signaturemigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform indexer signature migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer signature pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return signature.NewIndexer(idx, v, pool, sig.Mode == "enforce"), nil
+}
+
+// IndexerReferrers wraps the indexer to discover manifests' referrers, if
+// configured.
+//
+// Like signatures, this needs to be inside the registry credentials wrapper.
+func (i *Init) indexerReferrers(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	ref := conf.Referrers
+	if ref == nil {
+		return idx, nil
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = referrersmigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, referrersmigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform indexer referrers migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer referrers pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return referrers.NewIndexer(idx, referrers.NewFetcher(nil), pool, ref.IngestSBOMs), nil
+}
+
+// IndexerAnnotations wraps the indexer to record annotations on index
+// reports, if configured.
+func (i *Init) indexerAnnotations(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	a := conf.Annotations
+	if a == nil {
+		return idx, nil
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = annotationsmigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, annotationsmigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform indexer annotations migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer annotations pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return annotations.NewIndexer(idx, pool, a.MaxKeys, tenant.FromContext), nil
+}
+
+// IndexerInventory wraps the indexer to record when manifests are indexed
+// and list them, if configured.
+func (i *Init) indexerInventory(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	if !conf.Inventory {
+		return idx, nil
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = inventorymigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, inventorymigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform indexer inventory migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer inventory pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return inventory.NewIndexer(idx, pool, conf.Annotations != nil, tenant.FromContext), nil
+}
+
+// IndexerHooks wraps the indexer to run content hooks over layers, if
+// configured.
+//
+// Like signatures, this needs to be inside the registry credentials wrapper.
+func (i *Init) indexerHooks(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	h := conf.Hooks
+	if h == nil || len(h.Scanners) == 0 {
+		return idx, nil
+	}
+	hooks := make([]hook.Hook, 0, len(h.Scanners))
+	for _, sc := range h.Scanners {
+		var s hook.Scanner
+		var err error
+		switch sc.Kind {
+		case "clamav":
+			s, err = hook.NewClamAV(sc.Address)
+		case "command":
+			s, err = hook.NewCommand(sc.Command)
+		case "scope":
+			s = hook.NewScope()
+		}
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, fmt.Sprintf("failed to configure hook scanner %q", sc.Name))
+		}
+		hooks = append(hooks, hook.Hook{Name: sc.Name, Scanner: s})
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = hookmigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, hookmigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform indexer hook migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer hook pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return hook.NewIndexer(idx, pool, i.layerClient(), hooks, h.Timeout, conf.Concurrency.Walk), nil
+}
+
+// IndexerBudget wraps the indexer to truncate layers over the configured scan
+// limits. It sits inside the layer cache, so the layers it reads ahead of
+// indexing are only fetched once.
+func (i *Init) indexerBudget(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer
+	l := conf.ScanLimits
+	if !l.Enabled() {
+		return idx, nil
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = budgetmigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, budgetmigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform indexer budget migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer budget pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	// The default client is used: the layers are fetched through the
+	// indexer's fetch limits once they're served, and holding a fetch slot
+	// here as well could deadlock.
+	b, err := budget.NewIndexer(i.GlobalCTX, idx, pool, nil, budget.Limits{
+		Files: l.MaxFiles,
+		Size:  l.MaxSize,
+	})
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to create indexer budget")
+	}
+	return b, nil
+}
+
+// TenantStore returns the tenant records, connecting on first use.
+func (i *Init) tenantStore() (*tenant.Store, error) {
+	if i.tenants != nil {
+		return i.tenants, nil
+	}
+	conf := &i.conf.Tenancy
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = tenantmigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, tenantmigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform tenancy migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse tenancy connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create tenancy pool")
+	}
+	i.tenants = tenant.NewStore(pool)
+	return i.tenants, nil
+}
+
+// IndexerTenancy wraps the indexer to scope index reports to tenants, if
+// tenancy is configured.
+func (i *Init) indexerTenancy(idx indexer.Service) (indexer.Service, error) {
+	if !i.conf.Tenancy.Enabled() {
+		return idx, nil
+	}
+	st, err := i.tenantStore()
+	if err != nil {
+		return nil, err
+	}
+	return tenant.NewIndexer(idx, st), nil
+}
+
+// NotifierOwners returns the tenant records notification delivery is scoped
+// with, if tenancy is configured.
+func (i *Init) notifierOwners() (clairnotifier.Owners, error) {
+	if !i.conf.Tenancy.Enabled() {
+		return nil, nil
+	}
+	st, err := i.tenantStore()
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// NotifierTenancy wraps the notifier to scope notifications to tenants, if
+// tenancy is configured.
+func (i *Init) notifierTenancy(n notifier.Service) (notifier.Service, error) {
+	if !i.conf.Tenancy.Enabled() {
+		return n, nil
+	}
+	st, err := i.tenantStore()
+	if err != nil {
+		return nil, err
+	}
+	return tenant.NewNotifier(n, st), nil
+}
+
+// IndexerCache wraps the indexer to cache the layers it fetches, if
+// configured.
+//
+// This needs to be inside the registries wrapper, so layers are fetched on a
+// miss with the credentials it adds.
+func (i *Init) indexerCache(idx indexer.Service) (indexer.Service, error) {
+	conf := i.conf.Indexer.Cache
+	if conf == nil {
+		if i.conf.Indexer.Concurrency.Fetch == 0 {
+			return idx, nil
+		}
+		// Layers are relayed without caching, so libindex's fetches can
+		// be limited.
+		c, err := layercache.NewIndexer(i.GlobalCTX, idx, nil, i.layerClient(), "")
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, "failed to start layer relay server")
+		}
+		return c, nil
+	}
+	var st layercache.Store
+	var err error
+	switch conf.Backend {
+	case "s3":
+		creds := awsauth.NewProvider(nil, awsauth.Credentials{
+			AccessKeyID:     conf.S3.AccessKeyID,
+			SecretAccessKey: conf.S3.SecretAccessKey,
+			SessionToken:    conf.S3.SessionToken,
+		}, conf.S3.Region, conf.S3.RoleARN)
+		st, err = layercache.NewS3(nil, layercache.S3Opts{
+			Bucket:       conf.S3.Bucket,
+			Region:       conf.S3.Region,
+			Endpoint:     conf.S3.Endpoint,
+			PathStyle:    conf.S3.PathStyle,
+			StorageClass: conf.S3.StorageClass,
+			Credentials:  creds,
+			TTL:          conf.TTL,
+		})
+	case "swift":
+		st, err = layercache.NewSwift(nil, layercache.SwiftOpts{
+			AuthURL:   conf.Swift.AuthURL,
+			Username:  conf.Swift.Username,
+			Password:  conf.Swift.Password,
+			Project:   conf.Swift.Project,
+			Domain:    conf.Swift.Domain,
+			Region:    conf.Swift.Region,
+			Container: conf.Swift.Container,
+			TTL:       conf.TTL,
+		})
+	default:
+		var fs *layercache.Filesystem
+		fs, err = layercache.NewFilesystem(conf.Dir, conf.TTL)
+		if err == nil {
+			go fs.Run(i.GlobalCTX)
+			st = fs
+		}
+	}
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure layer cache")
+	}
+	c, err := layercache.NewIndexer(i.GlobalCTX, idx, st, i.layerClient(), conf.Prefix)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to start layer cache server")
+	}
+	return c, nil
+}
+
+// LayerClient returns the client layers are fetched with: one limiting the
+// fetches in flight, if configured, or else nil for the default client.
+func (i *Init) layerClient() *http.Client {
+	n := i.conf.Indexer.Concurrency.Fetch
+	if n == 0 {
+		return nil
+	}
+	if i.fetchClient == nil {
+		i.fetchClient = &http.Client{Transport: limit.NewTransport(nil, n)}
+	}
+	return i.fetchClient
+}
+
+// IndexerLimit wraps the indexer to bound the manifests indexed at once, if
+// configured.
+func (i *Init) indexerLimit(idx indexer.Service) (indexer.Service, error) {
+	conf := &i.conf.Indexer.Concurrency
+	if conf.Index == 0 {
+		return idx, nil
+	}
+	return limit.NewIndexer(idx, conf.Index, conf.Queue, conf.QueueTimeout), nil
+}
+
+// IndexerUploads wraps the indexer to use uploaded layers, if uploads are
+// configured.
+func (i *Init) indexerUploads(idx indexer.Service) (indexer.Service, error) {
+	conf := i.conf.Indexer.Uploads
+	if conf == nil {
+		return idx, nil
+	}
+	st, err := upload.NewStore(conf.Dir, conf.MaxSize, conf.MaxAge)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to create layer upload directory")
+	}
+	go st.Run(i.GlobalCTX)
+	u, err := upload.NewIndexer(i.GlobalCTX, idx, st)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to start layer upload server")
+	}
+	return u, nil
+}
+
+// IndexerArchive wraps the indexer to archive index reports, if configured.
+func (i *Init) indexerArchive(idx indexer.Service) (indexer.Service, error) {
+	if !i.conf.Archive.Archives("index") {
+		return idx, nil
+	}
+	a, err := i.archiver()
+	if err != nil {
+		return nil, err
+	}
+	return archive.NewIndexer(idx, a), nil
+}
+
+// MatcherSeverity wraps the matcher to normalize severities, if configured.
+func (i *Init) matcherSeverity(m matcher.Service) (matcher.Service, error) {
+	conf := i.conf.Matcher.Severity
+	if conf == nil {
+		return m, nil
+	}
+	parse := func(name string) (claircore.Severity, error) {
+		var s claircore.Severity
+		if name == "" {
+			return s, nil
+		}
+		err := s.UnmarshalText([]byte(name))
+		return s, err
+	}
+	mapping := func(in map[string]string) (map[string]claircore.Severity, error) {
+		out := make(map[string]claircore.Severity, len(in))
+		for k, v := range in {
+			s, err := parse(v)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = s
+		}
+		return out, nil
+	}
+	global, err := mapping(conf.Mapping)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure severity mapping")
+	}
+	rules := make([]severity.Rule, len(conf.Namespaces))
+	for j, ns := range conf.Namespaces {
+		r := &rules[j]
+		r.Updater = ns.Namespace
+		if r.Mapping, err = mapping(ns.Mapping); err == nil {
+			if r.Floor, err = parse(ns.Floor); err == nil {
+				r.Ceiling, err = parse(ns.Ceiling)
+			}
+		}
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, fmt.Sprintf("failed to configure severity namespace %q", ns.Namespace))
+		}
+	}
+	n, err := severity.NewNormalizer(global, rules)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure severity normalization")
+	}
+	return severity.NewMatcher(m, n), nil
+}
+
+// MatcherArchive wraps the matcher to archive vulnerability reports, if
+// configured.
+func (i *Init) matcherArchive(m matcher.Service) (matcher.Service, error) {
+	if !i.conf.Archive.Archives("vulnerability") {
+		return m, nil
+	}
+	a, err := i.archiver()
+	if err != nil {
+		return nil, err
+	}
+	return archive.NewMatcher(m, a), nil
+}
+
+// MatcherAffected wraps the matcher to look up vulnerabilities for the
+// affected manifests API, using the replica if one is configured.
+func (i *Init) matcherAffected(m matcher.Service) (matcher.Service, error) {
+	conf := &i.conf.Matcher
+	connString := conf.ConnString
+	if conf.ReadConnString != "" {
+		connString = conf.ReadConnString
+	}
+	cfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse matcher connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create matcher lookup pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return affected.NewMatcher(m, affected.NewStore(pool)), nil
+}
+
+// MatcherSigning wraps the matcher to sign vulnerability reports, if
+// configured.
+func (i *Init) matcherSigning(m matcher.Service) (matcher.Service, error) {
+	conf := i.conf.Matcher.ReportSigning
+	if conf == nil {
+		return m, nil
+	}
+	b, err := ioutil.ReadFile(conf.Key)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to read report signing key")
+	}
+	s, err := signing.NewSigner(b, conf.KeyID)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to create report signer")
+	}
+	return signing.NewMatcher(m, s), nil
+}
+
+// Archiver returns the Archiver shared by the indexer and matcher, creating
+// it on first use.
+func (i *Init) archiver() (*archive.Archiver, error) {
+	if i.arch != nil {
+		return i.arch, nil
+	}
+	conf := &i.conf.Archive
+	var st archive.Store
+	var err error
+	switch conf.Name {
+	case "s3":
+		creds := awsauth.NewProvider(nil, awsauth.Credentials{
+			AccessKeyID:     conf.S3.AccessKeyID,
+			SecretAccessKey: conf.S3.SecretAccessKey,
+			SessionToken:    conf.S3.SessionToken,
+		}, conf.S3.Region, conf.S3.RoleARN)
+		st, err = archive.NewS3(nil, archive.S3Opts{
+			Bucket:       conf.S3.Bucket,
+			Region:       conf.S3.Region,
+			Endpoint:     conf.S3.Endpoint,
+			PathStyle:    conf.S3.PathStyle,
+			StorageClass: conf.S3.StorageClass,
+			Credentials:  creds,
+		})
+	case "gcs":
+		var sa *gcp.ServiceAccount
+		if f := conf.GCS.CredentialsFile; f != "" {
+			b, rerr := ioutil.ReadFile(f)
+			if rerr != nil {
+				return nil, clairerror.Wrap(clairerror.Internal, rerr, "failed to read archive credentials")
+			}
+			sa, err = gcp.ParseServiceAccount(b)
+			if err != nil {
+				break
+			}
+		}
+		st, err = archive.NewGCS(nil, archive.GCSOpts{
+			Bucket:       conf.GCS.Bucket,
+			Endpoint:     conf.GCS.Endpoint,
+			StorageClass: conf.GCS.StorageClass,
+			Credentials:  sa,
+		})
+	default:
+		err = fmt.Errorf("unknown archive store %q", conf.Name)
+	}
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure archive")
+	}
+	i.arch = archive.NewArchiver(i.GlobalCTX, st, conf.Prefix)
+	return i.arch, nil
+}
+
+// MatcherCache wraps the matcher to cache vulnerability reports, if
+// configured.
+func (i *Init) matcherCache(m matcher.Service) (matcher.Service, error) {
+	conf := i.conf.Matcher.Cache
+	if conf == nil {
+		return m, nil
+	}
+	var st cache.Store
+	switch conf.Backend {
+	case "redis":
+		r, err := cache.NewRedis(conf.RedisURL, conf.TTL)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure report cache")
+		}
+		st = r
+	default:
+		st = cache.NewMemory(conf.Size, conf.TTL)
+	}
+	c := cache.NewMatcher(m, st)
+	if conf.Precompute > 0 {
+		return cache.NewWarmer(i.GlobalCTX, c, conf.Precompute, cache.WarmInterval), nil
+	}
+	return c, nil
+}
+
+// MatcherVEX wraps the matcher to apply VEX documents, if configured.
+func (i *Init) matcherVEX(m matcher.Service) (matcher.Service, error) {
+	conf := &i.conf.Matcher
+	if conf.VEX == nil {
+		return m, nil
+	}
+	var static []*vex.Document
+	for _, p := range conf.VEX.Documents {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, "failed to read vex document")
+		}
+		d, err := vex.Parse(b)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, fmt.Sprintf("failed to parse vex document %q", p))
+		}
+		static = append(static, d)
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = vexmigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, vexmigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform matcher vex migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse matcher connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create matcher vex pool")
+	}
+	filter := conf.VEX.Mode != "annotate"
+	return vex.NewMatcher(i.GlobalCTX, m, vex.NewStore(pool), static, filter), nil
+}
+
+// MatcherHistory wraps the matcher to record report history, if configured.
+func (i *Init) matcherHistory(m matcher.Service) (matcher.Service, error) {
+	conf := &i.conf.Matcher
+	if conf.History == nil {
+		return m, nil
+	}
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = historymigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, historymigrations.Migrations...); err != nil {
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform matcher history migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse matcher connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create matcher history pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	return history.NewMatcher(m, history.NewStore(pool, conf.History.Retain)), nil
+}
+
+// UpdaterOverrides returns the updater sets and configuration to hand to
+// libvuln, and the Overrides in use if runtime overrides are configured.
+//
+// The sets are always wrapped, so that updater freshness is reported, but
+// without runtime overrides there's nothing to change them.
+func (i *Init) updaterOverrides() ([]string, map[string]driver.ConfigUnmarshaler, *updaters.Overrides, error) {
+	sets := i.conf.Updaters.Sets
+	cfgs := make(map[string]driver.ConfigUnmarshaler)
+	for name, node := range i.conf.Updaters.Config {
+		cfgs[name] = node.Decode
+		// The wrapped factories are configured under their own names, but
+		// updaters keep theirs.
+		cfgs[updaters.Prefix+name] = node.Decode
+	}
+	egress, err := updaters.NewEgress(cfgs)
+	if err != nil {
+		return nil, nil, nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure updater egress")
+	}
+	shared, err := i.updaterShared()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if shared != nil {
+		egress.Wrap(shared)
+	}
+	if !i.conf.Updaters.Overrides {
+		o := updaters.NewOverrides(i.GlobalCTX, nil)
+		return updaters.Register(o, egress, sets), cfgs, nil, nil
+	}
+	conf := &i.conf.Matcher
+	if conf.Migrations {
+		db, err := sql.Open("pgx", conf.ConnString)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to open db: %v", err)
+		}
+		defer db.Close()
+		migrator := migrate.NewPostgresMigrator(db)
+		migrator.Table = updatermigrations.MigrationTable
+		if err := migrator.Exec(migrate.Up, updatermigrations.Migrations...); err != nil {
+			return nil, nil, nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to perform matcher updater migrations")
+		}
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, nil, nil, clairerror.Wrap(clairerror.Internal, err, "failed to parse matcher connstring")
+	}
+	cfg.MaxConns = 5
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return nil, nil, nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create matcher updater pool")
+	}
+	o := updaters.NewOverrides(i.GlobalCTX, updaters.NewStore(pool))
+	return updaters.Register(o, egress, sets), cfgs, o, nil
+}
+
+// UpdaterShared returns the bandwidth limit and cache shared by updaters'
+// clients, or nil if neither is configured.
+func (i *Init) updaterShared() (*updaters.Shared, error) {
+	conf := &i.conf.Updaters.HTTP
+	if conf.MaxBandwidth == 0 && conf.CacheDir == "" {
+		return nil, nil
+	}
+	if i.updaterHTTP == nil {
+		s, err := updaters.NewShared(conf.CacheDir, conf.MaxBandwidth)
+		if err != nil {
+			return nil, clairerror.Wrap(clairerror.Internal, err, "failed to configure updater http client")
+		}
+		i.updaterHTTP = s
+	}
+	return i.updaterHTTP, nil
+}
+
+// UpdaterClient returns the client updaters fetch with, absent their own
+// egress configuration: one sharing a bandwidth limit and cache, if
+// configured, or else nil for the default client.
+func (i *Init) updaterClient() (*http.Client, error) {
+	s, err := i.updaterShared()
+	if s == nil || err != nil {
+		return nil, err
+	}
+	return s.Client(), nil
+}
+
+// ClientRetry returns the retry Option for intra-service clients.
+func (i *Init) clientRetry() client.Option {
+	conf := &i.conf.IntraServiceClient
+	return client.WithRetry(client.RetryOptions{
+		Retries:          conf.Retries,
+		Backoff:          conf.Backoff,
+		MaxBackoff:       conf.MaxBackoff,
+		Timeout:          conf.Timeout,
+		BreakerThreshold: conf.BreakerThreshold,
+		BreakerCooldown:  conf.BreakerCooldown,
+	})
+}
+
+// AdminIndexer sets up the indexer's admin API.
+func (i *Init) adminIndexer() error {
+	conf := &i.conf.Indexer
+	sets := []admin.Migrations{
+		{Table: libindexmigrations.MigrationTable, Migrations: libindexmigrations.Migrations},
+	}
+	if conf.GC.Enabled() {
+		sets = append(sets, admin.Migrations{Table: gcmigrations.MigrationTable, Migrations: gcmigrations.Migrations})
+	}
+	if conf.Reindex != nil {
+		sets = append(sets, admin.Migrations{Table: reindexmigrations.MigrationTable, Migrations: reindexmigrations.Migrations})
+	}
+	if conf.Signatures != nil {
+		sets = append(sets, admin.Migrations{Table: signaturemigrations.MigrationTable, Migrations: signaturemigrations.Migrations})
+	}
+	if conf.Referrers != nil {
+		sets = append(sets, admin.Migrations{Table: referrersmigrations.MigrationTable, Migrations: referrersmigrations.Migrations})
+	}
+	if conf.Annotations != nil {
+		sets = append(sets, admin.Migrations{Table: annotationsmigrations.MigrationTable, Migrations: annotationsmigrations.Migrations})
+	}
+	if conf.Inventory {
+		sets = append(sets, admin.Migrations{Table: inventorymigrations.MigrationTable, Migrations: inventorymigrations.Migrations})
+	}
+	if conf.Hooks != nil {
+		sets = append(sets, admin.Migrations{Table: hookmigrations.MigrationTable, Migrations: hookmigrations.Migrations})
+	}
+	if conf.ScanLimits.Enabled() {
+		sets = append(sets, admin.Migrations{Table: budgetmigrations.MigrationTable, Migrations: budgetmigrations.Migrations})
+	}
+	cfg, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return clairerror.Wrap(clairerror.Internal, err, "failed to parse indexer connstring")
+	}
+	cfg.MaxConns = 2
+	pool, err := pgxpool.ConnectConfig(i.GlobalCTX, cfg)
+	if err != nil {
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to create indexer admin pool")
+	}
+	i.background(func() {
+		<-i.GlobalCTX.Done()
+		pool.Close()
+	})
+	i.admin.Indexer = admin.NewIndexer(pool, i.collector, admin.NewMigrator(conf.ConnString, sets...))
+	return nil
+}
+
+// AdminMatcher sets up the matcher's admin API.
+//
+// The matchers are used for dry runs. Libvuln adds its default matchers to
+// the ones in its Opts, so these should be taken from the Opts after
+// libvuln.New.
+func (i *Init) adminMatcher(libV *libvuln.Libvuln, matchers []driver.Matcher) {
+	conf := &i.conf.Matcher
+	sets := []admin.Migrations{
+		{Table: libvulnmigrations.MigrationTable, Migrations: libvulnmigrations.Migrations},
+	}
+	if i.conf.Updaters.Overrides {
+		sets = append(sets, admin.Migrations{Table: updatermigrations.MigrationTable, Migrations: updatermigrations.Migrations})
+	}
+	if conf.VEX != nil {
+		sets = append(sets, admin.Migrations{Table: vexmigrations.MigrationTable, Migrations: vexmigrations.Migrations})
+	}
+	if conf.History != nil {
+		sets = append(sets, admin.Migrations{Table: historymigrations.MigrationTable, Migrations: historymigrations.Migrations})
+	}
+	i.admin.Matcher = admin.NewMatcher(libV, dryrun.NewRunner(matchers), admin.NewMigrator(conf.ConnString, sets...))
+}
+
+// AdminNotifier sets up the notifier's admin API.
+func (i *Init) adminNotifier() {
+	i.admin.Notifier = admin.NewMigrator(i.conf.Notifier.ConnString,
+		admin.Migrations{Table: notifiermigrations.MigrationTable, Migrations: notifiermigrations.Migrations})
+}
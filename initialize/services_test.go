@@ -0,0 +1,51 @@
+package initialize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/clair/v4/config"
+)
+
+func TestLibindexOpts(t *testing.T) {
+	tt := []struct {
+		name    string
+		enable  []string
+		disable []string
+		want    int
+		err     bool
+	}{
+		{name: "Default"},
+		{name: "Enable", enable: []string{"dpkg", "python"}, want: 2},
+		{name: "Disable", disable: []string{"python", "rpm"}, want: 3},
+		{name: "EnableDisable", enable: []string{"dpkg", "python"}, disable: []string{"python"}, want: 1},
+		{name: "Unknown", disable: []string{"cobol"}, err: true},
+		{name: "EnableUnknown", enable: []string{"dpkg", "java"}, err: true},
+		{name: "All", disable: []string{"dpkg", "alpine", "rhel", "rpm", "python"}, err: true},
+		{name: "EnableAllDisabled", enable: []string{"python"}, disable: []string{"python"}, err: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			i := &Init{GlobalCTX: context.Background()}
+			i.conf.Indexer.Scanner = config.ScannerConfig{Enable: tc.enable, Disable: tc.disable}
+			opts, err := i.libindexOpts()
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				t.Log(err)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Nil Ecosystems means libindex's defaults.
+			if got, want := len(opts.Ecosystems), tc.want; got != want {
+				t.Errorf("ecosystems: got: %d, want: %d", got, want)
+			}
+			if got, want := opts.Ecosystems == nil, len(tc.enable)+len(tc.disable) == 0; got != want {
+				t.Errorf("defaults: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package initialize
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Drainer is implemented by background jobs that can stop taking on work
+// while finishing what they're doing.
+type drainer interface {
+	Drain()
+}
+
+// Job runs f, a background job, in a goroutine. On shutdown, d is drained
+// and f is given until the drain timeout to return.
+func (i *Init) job(d drainer, f func()) {
+	i.drainers = append(i.drainers, d)
+	i.jobs.Add(1)
+	go func() {
+		defer i.jobs.Done()
+		f()
+	}()
+}
+
+// Background runs f in a goroutine that's expected to return soon after the
+// GlobalCTX is done, like one closing a connection pool. Shutdown waits for
+// it briefly.
+func (i *Init) background(f func()) {
+	i.bg.Add(1)
+	go func() {
+		defer i.bg.Done()
+		f()
+	}()
+}
+
+// Healthy reports whether the process is serving, for the introspection
+// server's health check. It's false once shutdown has begun, so load
+// balancers stop sending requests.
+func (i *Init) healthy() bool {
+	return atomic.LoadInt32(&i.draining) == 0
+}
+
+// ShutdownGrace is how long Shutdown waits for background goroutines once
+// the GlobalCTX is canceled.
+const shutdownGrace = 5 * time.Second
+
+// Shutdown stops Clair in order:
+//
+// - The HTTP API stops accepting connections, and in-flight requests, like
+// index and vulnerability report requests, are given until ctx is done to
+// finish.
+// - Background jobs, like the updater leader, reindexing, and the notifier's
+// processors and deliveries, stop starting new runs and are given until ctx
+// is done to finish the one in progress.
+// Updates committed by a finished run don't have to be fetched again.
+// - The GlobalCTX is canceled, aborting whatever is still running, and
+// connection pools are closed.
+//
+// Resources outliving the GlobalCTX are released as by Close.
+func (i *Init) Shutdown(ctx context.Context) error {
+	log := zerolog.Ctx(i.GlobalCTX).With().Str("component", "init/Init.Shutdown").Logger()
+	atomic.StoreInt32(&i.draining, 1)
+	start := time.Now()
+
+	for _, d := range i.drainers {
+		d.Drain()
+	}
+	var wg sync.WaitGroup
+	if i.HttpTransport != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := i.HttpTransport.Shutdown(ctx); err != nil {
+				log.Warn().Err(err).Msg("in-flight requests didn't finish in time")
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if !wait(ctx, &i.jobs) {
+			log.Warn().Msg("background jobs didn't finish in time")
+		}
+	}()
+	wg.Wait()
+	log.Info().Dur("elapsed", time.Since(start)).Msg("drained")
+
+	i.GlobalCancel()
+	gctx, done := context.WithTimeout(context.Background(), shutdownGrace)
+	defer done()
+	if !wait(gctx, &i.bg) {
+		log.Warn().Msg("background goroutines didn't stop in time")
+	}
+	if i.Introspection != nil {
+		i.Introspection.Close()
+	}
+	return i.Close()
+}
+
+// Wait waits for the WaitGroup, reporting false if ctx is done first.
+func wait(ctx context.Context, wg *sync.WaitGroup) bool {
+	ch := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	select {
+	case <-ch:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,282 @@
+package quay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Client talks to Quay's API and registry.
+type Client struct {
+	base   *url.URL
+	token  string
+	client *http.Client
+}
+
+// NewClient returns a Client for the Quay instance at the base URL, using the
+// OAuth access token.
+//
+// If client is nil, http.DefaultClient is used.
+func NewClient(base, token string, client *http.Client) (*Client, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{base: u, token: token, client: client}, nil
+}
+
+// ErrNotFound is returned when Quay doesn't know about a repository, tag, or
+// manifest.
+var errNotFound = errors.New("quay: not found")
+
+// Repositories returns the names of the repositories in the namespace, in
+// "namespace/name" form.
+func (c *Client) Repositories(ctx context.Context, namespace string) ([]string, error) {
+	var out []string
+	next := ""
+	for {
+		v := url.Values{"namespace": {namespace}}
+		if next != "" {
+			v.Set("next_page", next)
+		}
+		var res struct {
+			Repositories []struct {
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+			} `json:"repositories"`
+			NextPage string `json:"next_page"`
+		}
+		if err := c.api(ctx, http.MethodGet, "api/v1/repository?"+v.Encode(), nil, &res); err != nil {
+			return nil, err
+		}
+		for _, r := range res.Repositories {
+			out = append(out, r.Namespace+"/"+r.Name)
+		}
+		if res.NextPage == "" {
+			return out, nil
+		}
+		next = res.NextPage
+	}
+}
+
+// Tag is an active tag in a repository.
+type Tag struct {
+	Name           string `json:"name"`
+	ManifestDigest string `json:"manifest_digest"`
+	IsManifestList bool   `json:"is_manifest_list"`
+}
+
+// Tags returns the active tags in the repository. If name isn't empty, only
+// the tag with that name is returned.
+func (c *Client) Tags(ctx context.Context, repo, name string) ([]Tag, error) {
+	var out []Tag
+	for page := 1; ; page++ {
+		v := url.Values{
+			"onlyActiveTags": {"true"},
+			"limit":          {"100"},
+			"page":           {strconv.Itoa(page)},
+		}
+		if name != "" {
+			v.Set("specificTag", name)
+		}
+		var res struct {
+			Tags          []Tag `json:"tags"`
+			HasAdditional bool  `json:"has_additional"`
+		}
+		if err := c.api(ctx, http.MethodGet, "api/v1/repository/"+repo+"/tag/?"+v.Encode(), nil, &res); err != nil {
+			return nil, err
+		}
+		out = append(out, res.Tags...)
+		if !res.HasAdditional {
+			return out, nil
+		}
+	}
+}
+
+// Manifest is a manifest as Quay describes it.
+type manifest struct {
+	Digest         string `json:"digest"`
+	IsManifestList bool   `json:"is_manifest_list"`
+	// The raw manifest, used to find the manifests in a list.
+	ManifestData string `json:"manifest_data"`
+	Layers       []struct {
+		BlobDigest string   `json:"blob_digest"`
+		IsRemote   bool     `json:"is_remote"`
+		URLs       []string `json:"urls"`
+	} `json:"layers"`
+}
+
+// Manifest returns the manifest with the digest in the repository.
+func (c *Client) manifest(ctx context.Context, repo, digest string) (*manifest, error) {
+	var m manifest
+	if err := c.api(ctx, http.MethodGet, "api/v1/repository/"+repo+"/manifest/"+digest, nil, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Children returns the digests of the manifests in a manifest list.
+func (m *manifest) children() ([]string, error) {
+	var l struct {
+		Manifests []struct {
+			Digest string `json:"digest"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal([]byte(m.ManifestData), &l); err != nil {
+		return nil, err
+	}
+	out := make([]string, len(l.Manifests))
+	for i, m := range l.Manifests {
+		out[i] = m.Digest
+	}
+	return out, nil
+}
+
+// ClairManifest returns a claircore.Manifest for the image manifest, with
+// layers fetched from Quay's registry using a pull token for the repository.
+func (c *Client) clairManifest(ctx context.Context, repo string, m *manifest) (*claircore.Manifest, error) {
+	d, err := claircore.ParseDigest(m.Digest)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := c.pullToken(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	out := &claircore.Manifest{Hash: d}
+	for _, l := range m.Layers {
+		ld, err := claircore.ParseDigest(l.BlobDigest)
+		if err != nil {
+			return nil, err
+		}
+		layer := &claircore.Layer{Hash: ld}
+		switch {
+		case l.IsRemote && len(l.URLs) != 0:
+			// Foreign layers, like Windows base layers, aren't in Quay.
+			layer.URI = l.URLs[0]
+		default:
+			layer.URI = c.base.ResolveReference(&url.URL{Path: "v2/" + repo + "/blobs/" + l.BlobDigest}).String()
+			layer.Headers = map[string][]string{"Authorization": {"Bearer " + tok}}
+		}
+		out.Layers = append(out.Layers, layer)
+	}
+	return out, nil
+}
+
+// PullToken returns a registry token allowing pulls from the repository.
+func (c *Client) pullToken(ctx context.Context, repo string) (string, error) {
+	v := url.Values{
+		"service": {c.base.Host},
+		"scope":   {"repository:" + repo + ":pull"},
+	}
+	u := c.base.ResolveReference(&url.URL{Path: "v2/auth", RawQuery: v.Encode()})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	// Quay accepts OAuth access tokens as the password of this special user.
+	req.SetBasicAuth("$oauthtoken", c.token)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("quay: unable to get pull token for %q: %s", repo, res.Status)
+	}
+	var tok struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+	return tok.Token, nil
+}
+
+// Status values Quay reports for a manifest's security information.
+const (
+	StatusQueued  = "queued"
+	StatusScanned = "scanned"
+)
+
+// SecurityStatus returns the status of the manifest's security information.
+func (c *Client) SecurityStatus(ctx context.Context, repo, digest string) (string, error) {
+	var res struct {
+		Status string `json:"status"`
+	}
+	if err := c.api(ctx, http.MethodGet, securityPath(repo, digest), nil, &res); err != nil {
+		return "", err
+	}
+	return res.Status, nil
+}
+
+// Report sends the manifest's vulnerability report to Quay's security scan
+// API, which marks it as scanned.
+func (c *Client) Report(ctx context.Context, repo, digest string, vr *claircore.VulnerabilityReport) error {
+	body := struct {
+		Status string                         `json:"status"`
+		Report *claircore.VulnerabilityReport `json:"vulnerability_report"`
+	}{Status: StatusScanned, Report: vr}
+	return c.api(ctx, http.MethodPut, securityPath(repo, digest), &body, nil)
+}
+
+func securityPath(repo, digest string) string {
+	return "api/v1/repository/" + repo + "/manifest/" + digest + "/security"
+}
+
+// Api makes a request to Quay's API, relative to the base URL.
+func (c *Client) api(ctx context.Context, method, p string, in, out interface{}) error {
+	u, err := c.base.Parse(p)
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("accept", "application/json")
+	if in != nil {
+		req.Header.Set("content-type", "application/json")
+	}
+	req.Header.Set("authorization", "Bearer "+c.token)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected response from quay: %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,368 @@
+// Package quay integrates Clair with a Quay registry.
+//
+// Manifests pushed to Quay, announced by its repository push notifications,
+// are indexed and matched, and the vulnerability reports are sent to Quay's
+// security scan API. A periodic backfill does the same for manifests Quay
+// still has queued, so nothing is missed while Clair is down or before the
+// integration was set up.
+package quay
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+const (
+	// QueueSize is the number of manifests waiting to be indexed before hook
+	// requests are refused and the backfill waits.
+	QueueSize = 1024
+	// ProcessTimeout bounds indexing, matching, and reporting one manifest.
+	ProcessTimeout = 10 * time.Minute
+)
+
+// Opts configures an Integration.
+type Opts struct {
+	Client  *Client
+	Indexer indexer.Service
+	Matcher matcher.Service
+	// Namespaces to backfill. If empty, there's no backfill.
+	Namespaces       []string
+	BackfillInterval time.Duration
+	// HookSecret must match the "secret" query parameter of notifications.
+	HookSecret  string
+	Concurrency int
+}
+
+// Integration indexes manifests from Quay and reports the results back.
+type Integration struct {
+	client     *Client
+	indexer    indexer.Service
+	matcher    matcher.Service
+	namespaces []string
+	interval   time.Duration
+	secret     string
+	workers    int
+	queue      chan job
+
+	mu      sync.Mutex
+	pending map[job]struct{}
+
+	processed metric.Int64Counter
+	failed    metric.Int64Counter
+}
+
+// Job is a manifest to process. If the digest is empty, the tag is resolved
+// first.
+type job struct {
+	repo   string
+	tag    string
+	digest string
+}
+
+// New returns an Integration. Nothing happens until Run is called.
+func New(o *Opts) *Integration {
+	meter := metric.Must(otel.Meter("clair"))
+	return &Integration{
+		client:     o.Client,
+		indexer:    o.Indexer,
+		matcher:    o.Matcher,
+		namespaces: o.Namespaces,
+		interval:   o.BackfillInterval,
+		secret:     o.HookSecret,
+		workers:    o.Concurrency,
+		queue:      make(chan job, QueueSize),
+		pending:    make(map[job]struct{}),
+		processed: meter.NewInt64Counter(
+			"clair_quay_manifests_processed_total",
+			metric.WithDescription("number of manifests indexed and reported to quay"),
+		),
+		failed: meter.NewInt64Counter(
+			"clair_quay_manifests_failed_total",
+			metric.WithDescription("number of manifests that couldn't be indexed or reported to quay"),
+		),
+	}
+}
+
+// Run processes queued manifests and runs the backfill until the context is
+// canceled.
+func (i *Integration) Run(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "integrations/quay/Integration.Run").
+		Logger()
+	ctx = log.WithContext(ctx)
+	var wg sync.WaitGroup
+	for n := 0; n < i.workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			i.work(ctx)
+		}()
+	}
+	defer wg.Wait()
+	if len(i.namespaces) == 0 {
+		<-ctx.Done()
+		return
+	}
+	t := time.NewTicker(i.interval)
+	defer t.Stop()
+	for {
+		if err := i.Backfill(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("backfill failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Enqueue adds a job unless it's already waiting. If wait is set, it blocks
+// until there's room in the queue; otherwise it reports whether the job
+// could be added.
+func (i *Integration) enqueue(ctx context.Context, j job, wait bool) bool {
+	i.mu.Lock()
+	if _, ok := i.pending[j]; ok {
+		i.mu.Unlock()
+		return true
+	}
+	i.pending[j] = struct{}{}
+	i.mu.Unlock()
+	if wait {
+		select {
+		case i.queue <- j:
+			return true
+		case <-ctx.Done():
+		}
+	} else {
+		select {
+		case i.queue <- j:
+			return true
+		default:
+		}
+	}
+	i.mu.Lock()
+	delete(i.pending, j)
+	i.mu.Unlock()
+	return false
+}
+
+func (i *Integration) work(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "integrations/quay/Integration.work").
+		Logger()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-i.queue:
+			i.mu.Lock()
+			delete(i.pending, j)
+			i.mu.Unlock()
+			pctx, done := context.WithTimeout(ctx, ProcessTimeout)
+			err := i.process(pctx, j)
+			done()
+			if err != nil {
+				log.Warn().Err(err).
+					Str("repository", j.repo).
+					Str("tag", j.tag).
+					Str("manifest", j.digest).
+					Msg("failed to process manifest")
+				i.failed.Add(ctx, 1)
+			}
+		}
+	}
+}
+
+// Process indexes and matches the manifest and reports the results to Quay.
+// Manifest lists are expanded into jobs for each manifest in them.
+func (i *Integration) process(ctx context.Context, j job) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "integrations/quay/Integration.process").
+		Str("repository", j.repo).
+		Logger()
+	if j.digest == "" {
+		tags, err := i.client.Tags(ctx, j.repo, j.tag)
+		if err != nil {
+			return err
+		}
+		for _, t := range tags {
+			if t.Name == j.tag {
+				j.digest = t.ManifestDigest
+			}
+		}
+		if j.digest == "" {
+			// Deleted or moved since the notification was sent.
+			log.Debug().Str("tag", j.tag).Msg("tag not found")
+			return nil
+		}
+	}
+	log = log.With().Str("manifest", j.digest).Logger()
+
+	m, err := i.client.manifest(ctx, j.repo, j.digest)
+	if err != nil {
+		return err
+	}
+	if m.IsManifestList {
+		ds, err := m.children()
+		if err != nil {
+			return err
+		}
+		for _, d := range ds {
+			// Workers can't wait on the queue they drain. Anything dropped is
+			// left queued in Quay for the backfill.
+			if !i.enqueue(ctx, job{repo: j.repo, digest: d}, false) {
+				log.Warn().Str("child", d).Msg("queue full, dropping manifest")
+			}
+		}
+		return nil
+	}
+	cm, err := i.client.clairManifest(ctx, j.repo, m)
+	if err != nil {
+		return err
+	}
+	// Failures aren't reported, so the manifest stays queued in Quay and is
+	// tried again by the next backfill.
+	ir, err := i.indexer.Index(ctx, cm)
+	if err != nil {
+		return err
+	}
+	vr, err := i.matcher.Scan(ctx, ir)
+	if err != nil {
+		return err
+	}
+	if err := i.client.Report(ctx, j.repo, j.digest, vr); err != nil {
+		return err
+	}
+	log.Debug().
+		Int("vulnerabilities", len(vr.Vulnerabilities)).
+		Msg("reported to quay")
+	i.processed.Add(ctx, 1)
+	return nil
+}
+
+// Backfill queues every manifest in the configured namespaces that Quay
+// doesn't have security information for yet.
+//
+// A repository that can't be read is skipped; it's retried next time.
+func (i *Integration) Backfill(ctx context.Context) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "integrations/quay/Integration.Backfill").
+		Logger()
+	var queued int
+	for _, ns := range i.namespaces {
+		repos, err := i.client.Repositories(ctx, ns)
+		if err != nil {
+			return err
+		}
+		for _, repo := range repos {
+			n, err := i.backfill(ctx, repo)
+			queued += n
+			switch {
+			case ctx.Err() != nil:
+				return ctx.Err()
+			case err != nil:
+				log.Warn().Err(err).Str("repository", repo).Msg("failed to backfill repository")
+			}
+		}
+	}
+	log.Info().Int("queued", queued).Msg("backfill done")
+	return nil
+}
+
+// Backfill queues the repository's queued manifests, returning how many
+// there were.
+func (i *Integration) backfill(ctx context.Context, repo string) (int, error) {
+	tags, err := i.client.Tags(ctx, repo, "")
+	if err != nil {
+		return 0, err
+	}
+	var ds []string
+	for _, t := range tags {
+		if !t.IsManifestList {
+			ds = append(ds, t.ManifestDigest)
+			continue
+		}
+		m, err := i.client.manifest(ctx, repo, t.ManifestDigest)
+		if err != nil {
+			return 0, err
+		}
+		cs, err := m.children()
+		if err != nil {
+			return 0, err
+		}
+		ds = append(ds, cs...)
+	}
+	var queued int
+	seen := make(map[string]bool, len(ds))
+	for _, d := range ds {
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		st, err := i.client.SecurityStatus(ctx, repo, d)
+		switch {
+		case errors.Is(err, errNotFound):
+			continue
+		case err != nil:
+			return queued, err
+		case st != StatusQueued:
+			continue
+		}
+		if !i.enqueue(ctx, job{repo: repo, digest: d}, true) {
+			return queued, ctx.Err()
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// Notification is the payload of Quay's repository push notification.
+type notification struct {
+	Repository  string   `json:"repository"`
+	UpdatedTags []string `json:"updated_tags"`
+}
+
+// ServeHTTP accepts Quay's repository push notifications and queues the
+// updated tags.
+func (i *Integration) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "integrations/quay/Integration.ServeHTTP").
+		Logger()
+	if r.Method != http.MethodPost {
+		w.Header().Set("allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("secret")), []byte(i.secret)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var n notification
+	if err := json.NewDecoder(r.Body).Decode(&n); err != nil || n.Repository == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, t := range n.UpdatedTags {
+		if !i.enqueue(ctx, job{repo: n.Repository, tag: t}, false) {
+			log.Warn().Str("repository", n.Repository).Msg("queue full, refusing notification")
+			// Quay retries failed notifications.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
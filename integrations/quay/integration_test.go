@@ -0,0 +1,265 @@
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+const (
+	testToken = "0auth"
+	testRepo  = "org/app"
+)
+
+func digest(n int) string {
+	return fmt.Sprintf("sha256:%064x", n)
+}
+
+// FakeQuay serves the parts of Quay's API the integration uses, for one
+// repository.
+type fakeQuay struct {
+	sync.Mutex
+	// Tags maps tag names to manifest digests.
+	tags map[string]string
+	// Lists maps manifest list digests to their manifests.
+	lists  map[string][]string
+	status map[string]string
+	// Reported records the manifests reports were sent for.
+	reported []string
+}
+
+func (f *fakeQuay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if r.URL.Path == "/v2/auth" {
+		if u, p, ok := r.BasicAuth(); !ok || u != "$oauthtoken" || p != testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"token":"pull"}`)
+		return
+	}
+	if r.Header.Get("authorization") != "Bearer "+testToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	enc := json.NewEncoder(w)
+	p := r.URL.Path
+	switch {
+	case p == "/api/v1/repository":
+		ns := r.URL.Query().Get("namespace")
+		enc.Encode(map[string]interface{}{
+			"repositories": []map[string]string{{"namespace": ns, "name": "app"}},
+		})
+	case p == "/api/v1/repository/"+testRepo+"/tag/":
+		want := r.URL.Query().Get("specificTag")
+		var tags []Tag
+		for n, d := range f.tags {
+			if want != "" && n != want {
+				continue
+			}
+			_, list := f.lists[d]
+			tags = append(tags, Tag{Name: n, ManifestDigest: d, IsManifestList: list})
+		}
+		enc.Encode(map[string]interface{}{"tags": tags})
+	case strings.HasSuffix(p, "/security"):
+		d := strings.TrimSuffix(strings.TrimPrefix(p, "/api/v1/repository/"+testRepo+"/manifest/"), "/security")
+		switch r.Method {
+		case http.MethodGet:
+			st, ok := f.status[d]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			enc.Encode(map[string]string{"status": st})
+		case http.MethodPut:
+			var body struct {
+				Status string                         `json:"status"`
+				Report *claircore.VulnerabilityReport `json:"vulnerability_report"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Report == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.status[d] = body.Status
+			f.reported = append(f.reported, d)
+		}
+	case strings.HasPrefix(p, "/api/v1/repository/"+testRepo+"/manifest/"):
+		d := strings.TrimPrefix(p, "/api/v1/repository/"+testRepo+"/manifest/")
+		if cs, ok := f.lists[d]; ok {
+			var l struct {
+				Manifests []map[string]string `json:"manifests"`
+			}
+			for _, c := range cs {
+				l.Manifests = append(l.Manifests, map[string]string{"digest": c})
+			}
+			b, _ := json.Marshal(&l)
+			enc.Encode(map[string]interface{}{
+				"digest":           d,
+				"is_manifest_list": true,
+				"manifest_data":    string(b),
+			})
+			return
+		}
+		enc.Encode(map[string]interface{}{
+			"digest": d,
+			"layers": []map[string]interface{}{{"blob_digest": digest(1000)}},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeQuay) Reported() []string {
+	f.Lock()
+	defer f.Unlock()
+	out := append([]string(nil), f.reported...)
+	sort.Strings(out)
+	return out
+}
+
+func newIntegration(t *testing.T, srv *httptest.Server) (*Integration, *[]*claircore.Manifest) {
+	c, err := NewClient(srv.URL, testToken, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var indexed []*claircore.Manifest
+	i := New(&Opts{
+		Client: c,
+		Indexer: &indexer.Mock{
+			Index_: func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				indexed = append(indexed, m)
+				return &claircore.IndexReport{Hash: m.Hash, Success: true}, nil
+			},
+		},
+		Matcher: &matcher.Mock{
+			Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+			},
+		},
+		Namespaces:       []string{"org"},
+		BackfillInterval: time.Hour,
+		HookSecret:       "s3cr3t",
+		Concurrency:      2,
+	})
+	return i, &indexed
+}
+
+// WaitFor polls until the fake has reported n manifests.
+func waitFor(t *testing.T, f *fakeQuay, n int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if r := f.Reported(); len(r) >= n {
+			return r
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d reports, got: %v", n, f.Reported())
+	return nil
+}
+
+func TestHook(t *testing.T) {
+	ctx, done := context.WithCancel(zlog.Test(context.Background(), t))
+	defer done()
+	f := &fakeQuay{
+		tags:   map[string]string{"latest": digest(1), "multi": digest(10)},
+		lists:  map[string][]string{digest(10): {digest(11), digest(12)}},
+		status: map[string]string{},
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	i, indexed := newIntegration(t, srv)
+	i.namespaces = nil
+	go i.Run(ctx)
+
+	body := `{"repository":"org/app","updated_tags":["latest","multi","gone"]}`
+	tt := []struct {
+		name   string
+		method string
+		secret string
+		want   int
+	}{
+		{"BadSecret", http.MethodPost, "nope", http.StatusUnauthorized},
+		{"BadMethod", http.MethodGet, "s3cr3t", http.StatusMethodNotAllowed},
+		{"OK", http.MethodPost, "s3cr3t", http.StatusAccepted},
+	}
+	for _, tc := range tt {
+		req := httptest.NewRequest(tc.method, "/?secret="+tc.secret, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		i.ServeHTTP(rec, req)
+		if got, want := rec.Code, tc.want; got != want {
+			t.Errorf("%s: got: %d, want: %d", tc.name, got, want)
+		}
+	}
+
+	got := waitFor(t, f, 3)
+	if want := []string{digest(1), digest(11), digest(12)}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	m := (*indexed)[0]
+	if got, want := len(m.Layers), 1; got != want {
+		t.Fatalf("got: %d layers, want: %d", got, want)
+	}
+	l := m.Layers[0]
+	if got, want := l.URI, srv.URL+"/v2/org/app/blobs/"+digest(1000); got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := l.Headers["Authorization"], []string{"Bearer pull"}; !cmp.Equal(got, want) {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	ctx, done := context.WithCancel(zlog.Test(context.Background(), t))
+	defer done()
+	f := &fakeQuay{
+		tags: map[string]string{
+			"latest": digest(1),
+			"old":    digest(2),
+			"again":  digest(1),
+			"multi":  digest(10),
+		},
+		lists: map[string][]string{digest(10): {digest(11), digest(12)}},
+		status: map[string]string{
+			digest(1):  StatusQueued,
+			digest(2):  StatusScanned,
+			digest(11): StatusScanned,
+			digest(12): StatusQueued,
+		},
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	i, _ := newIntegration(t, srv)
+	go i.Run(ctx)
+
+	got := waitFor(t, f, 2)
+	if want := []string{digest(1), digest(12)}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	// Nothing's left queued, so another run finds nothing.
+	if err := i.Backfill(ctx); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got, want := len(f.Reported()), 2; got != want {
+		t.Errorf("got: %d reports, want: %d", got, want)
+	}
+}
@@ -0,0 +1,341 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials are AWS credentials.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is needed for temporary credentials.
+	SessionToken string
+	// Expires is the zero Time for credentials that don't expire.
+	Expires time.Time
+}
+
+// Valid reports whether the credentials can be used for a request made
+// around now.
+func (c *Credentials) valid(now time.Time) bool {
+	if c.AccessKeyID == "" {
+		return false
+	}
+	// Refresh a few minutes early to account for clock skew and request
+	// latency.
+	return c.Expires.IsZero() || now.Add(5*time.Minute).Before(c.Expires)
+}
+
+// These are the default endpoints for the credential sources reached over
+// the network.
+const (
+	DefaultMetadataEndpoint  = "http://169.254.169.254/"
+	DefaultContainerEndpoint = "http://169.254.170.2"
+)
+
+// ErrNoCredentials is returned when no credential source is available.
+var ErrNoCredentials = errors.New("no aws credentials found")
+
+// Provider finds, refreshes, and caches credentials.
+//
+// Base credentials come from the first of:
+//
+//   - static credentials passed to NewProvider
+//   - the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN
+//     environment variables
+//   - a web identity token, as configured by the AWS_WEB_IDENTITY_TOKEN_FILE
+//     and AWS_ROLE_ARN environment variables (e.g. EKS service accounts)
+//   - the ECS container credentials endpoint
+//   - the EC2 instance metadata service's instance role
+//
+// If a role is configured, the base credentials are then used to assume it.
+//
+// Provider is safe for concurrent use.
+type Provider struct {
+	c       *http.Client
+	static  Credentials
+	region  string
+	roleARN string
+
+	// These are overridden in tests.
+	getenv    func(string) string
+	metadata  string
+	container string
+	sts       string
+
+	mu  sync.Mutex
+	cur Credentials
+}
+
+// NewProvider returns a Provider. The region selects the STS endpoint used
+// to assume roles; if empty, the global endpoint is used.
+//
+// A nil client means http.DefaultClient.
+func NewProvider(c *http.Client, static Credentials, region, roleARN string) *Provider {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	sts := "https://sts.amazonaws.com/"
+	if region != "" {
+		sts = fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+	}
+	return &Provider{
+		c:         c,
+		static:    static,
+		region:    region,
+		roleARN:   roleARN,
+		getenv:    os.Getenv,
+		metadata:  DefaultMetadataEndpoint,
+		container: DefaultContainerEndpoint,
+		sts:       sts,
+	}
+}
+
+// Credentials returns valid credentials, refreshing them if needed.
+func (p *Provider) Credentials(ctx context.Context) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cur.valid(time.Now()) {
+		return p.cur, nil
+	}
+	c, err := p.base(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if p.roleARN != "" {
+		c, err = p.assumeRole(ctx, c)
+		if err != nil {
+			return Credentials{}, err
+		}
+	}
+	p.cur = c
+	return c, nil
+}
+
+func (p *Provider) base(ctx context.Context) (Credentials, error) {
+	if p.static.AccessKeyID != "" {
+		return p.static, nil
+	}
+	if id := p.getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return Credentials{
+			AccessKeyID:     id,
+			SecretAccessKey: p.getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    p.getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	if f, arn := p.getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), p.getenv("AWS_ROLE_ARN"); f != "" && arn != "" {
+		return p.webIdentity(ctx, f, arn)
+	}
+	if rel := p.getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		return p.fromContainer(ctx, p.container+rel, "")
+	}
+	if full := p.getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"); full != "" {
+		return p.fromContainer(ctx, full, p.getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"))
+	}
+	return p.fromInstance(ctx)
+}
+
+// MetadataCredentials is the format both the ECS and EC2 metadata services
+// return credentials in.
+type metadataCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (m *metadataCredentials) credentials() Credentials {
+	return Credentials{
+		AccessKeyID:     m.AccessKeyID,
+		SecretAccessKey: m.SecretAccessKey,
+		SessionToken:    m.Token,
+		Expires:         m.Expiration,
+	}
+}
+
+func (p *Provider) fromContainer(ctx context.Context, u, auth string) (Credentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	var m metadataCredentials
+	if err := p.getJSON(req, &m); err != nil {
+		return Credentials{}, fmt.Errorf("failed to fetch container credentials: %w", err)
+	}
+	return m.credentials(), nil
+}
+
+func (p *Provider) fromInstance(ctx context.Context) (Credentials, error) {
+	// Don't wait long for a metadata service that isn't there.
+	ctx, done := context.WithTimeout(ctx, 5*time.Second)
+	defer done()
+	const tokenHeader = "X-Aws-Ec2-Metadata-Token"
+	base, err := url.Parse(p.metadata)
+	if err != nil {
+		return Credentials{}, err
+	}
+	mkReq := func(method, path string) (*http.Request, error) {
+		u, err := base.Parse(path)
+		if err != nil {
+			return nil, err
+		}
+		return http.NewRequestWithContext(ctx, method, u.String(), nil)
+	}
+
+	req, err := mkReq(http.MethodPut, "latest/api/token")
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+	tok, err := p.getString(req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("%w: instance metadata unavailable: %v", ErrNoCredentials, err)
+	}
+
+	req, err = mkReq(http.MethodGet, "latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set(tokenHeader, tok)
+	roles, err := p.getString(req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("%w: no instance role: %v", ErrNoCredentials, err)
+	}
+	role := strings.TrimSpace(strings.SplitN(roles, "\n", 2)[0])
+	if role == "" {
+		return Credentials{}, fmt.Errorf("%w: no instance role", ErrNoCredentials)
+	}
+
+	req, err = mkReq(http.MethodGet, "latest/meta-data/iam/security-credentials/"+url.PathEscape(role))
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set(tokenHeader, tok)
+	var m metadataCredentials
+	if err := p.getJSON(req, &m); err != nil {
+		return Credentials{}, fmt.Errorf("failed to fetch instance role credentials: %w", err)
+	}
+	return m.credentials(), nil
+}
+
+// StsResponse is the subset of the AssumeRole and AssumeRoleWithWebIdentity
+// responses we need.
+type stsResponse struct {
+	Result struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"Credentials"`
+	} `xml:",any"`
+}
+
+func (p *Provider) webIdentity(ctx context.Context, file, arn string) (Credentials, error) {
+	tok, err := ioutil.ReadFile(file)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read web identity token: %w", err)
+	}
+	v := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {arn},
+		"RoleSessionName":  {"clair"},
+		"WebIdentityToken": {strings.TrimSpace(string(tok))},
+	}
+	// This call is authenticated by the token, so isn't signed.
+	return p.callSTS(ctx, v, nil)
+}
+
+func (p *Provider) assumeRole(ctx context.Context, base Credentials) (Credentials, error) {
+	v := url.Values{
+		"Action":          {"AssumeRole"},
+		"Version":         {"2011-06-15"},
+		"RoleArn":         {p.roleARN},
+		"RoleSessionName": {"clair"},
+	}
+	return p.callSTS(ctx, v, &base)
+}
+
+func (p *Provider) callSTS(ctx context.Context, v url.Values, creds *Credentials) (Credentials, error) {
+	body := []byte(v.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.sts, bytes.NewReader(body))
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if creds != nil {
+		region := p.region
+		if region == "" {
+			region = "us-east-1"
+		}
+		Sign(req, body, *creds, region, "sts", time.Now())
+	}
+	res, err := p.c.Do(req)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return Credentials{}, fmt.Errorf("%s failed: %s: %s", v.Get("Action"), res.Status, msg)
+	}
+	var r stsResponse
+	if err := xml.NewDecoder(res.Body).Decode(&r); err != nil {
+		return Credentials{}, fmt.Errorf("failed to decode %s response: %w", v.Get("Action"), err)
+	}
+	c := r.Result.Credentials
+	if c.AccessKeyID == "" {
+		return Credentials{}, fmt.Errorf("%s returned no credentials", v.Get("Action"))
+	}
+	return Credentials{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+		Expires:         c.Expiration,
+	}, nil
+}
+
+func (p *Provider) do(req *http.Request) ([]byte, error) {
+	res, err := p.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %s", res.Status)
+	}
+	return b, nil
+}
+
+func (p *Provider) getString(req *http.Request) (string, error) {
+	b, err := p.do(req)
+	return string(b), err
+}
+
+func (p *Provider) getJSON(req *http.Request, v interface{}) error {
+	b, err := p.do(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,166 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func TestProviderEnvironment(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider(nil, Credentials{}, "", "")
+	p.getenv = env(map[string]string{
+		"AWS_ACCESS_KEY_ID":     "id",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+	})
+	c, err := p.Credentials(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.AccessKeyID, "id"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	// Static credentials take precedence.
+	p = NewProvider(nil, Credentials{AccessKeyID: "static", SecretAccessKey: "s"}, "", "")
+	p.getenv = env(map[string]string{"AWS_ACCESS_KEY_ID": "id"})
+	c, err = p.Credentials(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.AccessKeyID, "static"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestProviderInstance(t *testing.T) {
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			fmt.Fprint(w, "imds-token")
+			return
+		case r.Header.Get("X-Aws-Ec2-Metadata-Token") != "imds-token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/":
+			fmt.Fprint(w, "clair-role\n")
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/clair-role":
+			fetches++
+			fmt.Fprintf(w, `{"AccessKeyId":"instance","SecretAccessKey":"s","Token":"t","Expiration":%q}`, exp.Format(time.RFC3339))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewProvider(srv.Client(), Credentials{}, "", "")
+	p.getenv = env(nil)
+	p.metadata = srv.URL + "/"
+	for i := 0; i < 2; i++ {
+		c, err := p.Credentials(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := c.AccessKeyID, "instance"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := c.SessionToken, "t"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if !c.Expires.Equal(exp) {
+			t.Errorf("got: %v, want: %v", c.Expires, exp)
+		}
+	}
+	if got, want := fetches, 1; got != want {
+		t.Errorf("got: %d fetches, want: %d", got, want)
+	}
+}
+
+func TestProviderWebIdentity(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokFile, []byte("jwt\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		action := r.Form.Get("Action")
+		calls = append(calls, action)
+		switch action {
+		case "AssumeRoleWithWebIdentity":
+			if got, want := r.Form.Get("WebIdentityToken"), "jwt"; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+			if r.Header.Get("Authorization") != "" {
+				t.Error("web identity request should not be signed")
+			}
+			fmt.Fprint(w, stsBody(action, "web"))
+		case "AssumeRole":
+			if got, want := r.Form.Get("RoleArn"), "arn:aws:iam::1:role/target"; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+			if !strings.Contains(r.Header.Get("Authorization"), "Credential=web/") {
+				t.Errorf("unexpected authorization: %q", r.Header.Get("Authorization"))
+			}
+			fmt.Fprint(w, stsBody(action, "assumed"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewProvider(srv.Client(), Credentials{}, "us-west-2", "arn:aws:iam::1:role/target")
+	p.getenv = env(map[string]string{
+		"AWS_WEB_IDENTITY_TOKEN_FILE": tokFile,
+		"AWS_ROLE_ARN":                "arn:aws:iam::1:role/pod",
+	})
+	p.sts = srv.URL + "/"
+	c, err := p.Credentials(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.AccessKeyID, "assumed"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := strings.Join(calls, ","), "AssumeRoleWithWebIdentity,AssumeRole"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func stsBody(action, id string) string {
+	return fmt.Sprintf(`<%[1]sResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <%[1]sResult>
+    <Credentials>
+      <AccessKeyId>%[2]s</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>%[3]s</Expiration>
+    </Credentials>
+  </%[1]sResult>
+  <ResponseMetadata>
+    <RequestId>c6104cbe-af31-11e0-8154-cbc7ccf896c7</RequestId>
+  </ResponseMetadata>
+</%[1]sResponse>`, action, id, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,154 @@
+// Package aws implements authentication to AWS APIs without the AWS SDK.
+package aws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sign adds AWS Signature Version 4 headers to the request, signing every
+// header already set. The body must be the request's entire body.
+//
+// See https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func Sign(req *http.Request, body []byte, creds Credentials, region, service string, now time.Time) {
+	sum := sha256.Sum256(body)
+	SignHashed(req, hex.EncodeToString(sum[:]), creds, region, service, now)
+}
+
+// SignHashed is like Sign, but takes the hex-encoded SHA-256 of the body
+// instead of the body, for bodies too large to hold in memory.
+func SignHashed(req *http.Request, payload string, creds Credentials, region, service string, now time.Time) {
+	const (
+		algorithm = "AWS4-HMAC-SHA256"
+		long      = "20060102T150405Z"
+		short     = "20060102"
+	)
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(long))
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	hs := map[string]string{"host": req.URL.Host}
+	for k, vs := range req.Header {
+		hs[strings.ToLower(k)] = strings.TrimSpace(strings.Join(vs, ","))
+	}
+	names := make([]string, 0, len(hs))
+	for k := range hs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + hs[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	p := req.URL.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	canon := strings.Join([]string{
+		req.Method,
+		p,
+		canonicalQuery(req.URL.Query()),
+		canonHeaders.String(),
+		signed,
+		payload,
+	}, "\n")
+	scope := strings.Join([]string{now.Format(short), region, service, "aws4_request"}, "/")
+	csum := sha256.Sum256([]byte(canon))
+	toSign := strings.Join([]string{algorithm, now.Format(long), scope, hex.EncodeToString(csum[:])}, "\n")
+
+	k := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), now.Format(short))
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	k = hmacSHA256(k, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(k, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, creds.AccessKeyID, scope, signed, sig))
+}
+
+// Presign adds AWS Signature Version 4 query parameters to the URL, making
+// it usable without other credentials until it expires. Only the host is
+// signed. The payload is the hex-encoded SHA-256 of the body, or
+// "UNSIGNED-PAYLOAD".
+//
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func Presign(u *url.URL, method, payload string, creds Credentials, region, service string, now time.Time, expires time.Duration) {
+	const (
+		algorithm = "AWS4-HMAC-SHA256"
+		long      = "20060102T150405Z"
+		short     = "20060102"
+	)
+	now = now.UTC()
+	scope := strings.Join([]string{now.Format(short), region, service, "aws4_request"}, "/")
+	q := u.Query()
+	q.Set("X-Amz-Algorithm", algorithm)
+	q.Set("X-Amz-Credential", creds.AccessKeyID+"/"+scope)
+	q.Set("X-Amz-Date", now.Format(long))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if creds.SessionToken != "" {
+		q.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	cq := canonicalQuery(q)
+	canon := strings.Join([]string{
+		method,
+		p,
+		cq,
+		"host:" + u.Host + "\n",
+		"host",
+		payload,
+	}, "\n")
+	csum := sha256.Sum256([]byte(canon))
+	toSign := strings.Join([]string{algorithm, now.Format(long), scope, hex.EncodeToString(csum[:])}, "\n")
+
+	k := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), now.Format(short))
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	k = hmacSHA256(k, "aws4_request")
+	u.RawQuery = cq + "&X-Amz-Signature=" + hex.EncodeToString(hmacSHA256(k, toSign))
+}
+
+func canonicalQuery(v url.Values) string {
+	ks := make([]string, 0, len(v))
+	for k := range v {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	var b strings.Builder
+	for _, k := range ks {
+		vs := append([]string(nil), v[k]...)
+		sort.Strings(vs)
+		for _, x := range vs {
+			if b.Len() != 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(k) + "=" + strings.ReplaceAll(url.QueryEscape(x), "+", "%20"))
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	io.WriteString(h, data)
+	return h.Sum(nil)
+}
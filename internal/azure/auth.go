@@ -0,0 +1,243 @@
+// Package azure implements authentication to Azure APIs without the Azure
+// SDK.
+package azure
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceBusResource is the token audience for Service Bus.
+const ServiceBusResource = "https://servicebus.azure.net/"
+
+// ManagementResource is the token audience for Azure Resource Manager, which
+// Container Registry accepts in exchange for registry tokens.
+const ManagementResource = "https://management.azure.com/"
+
+// MetadataTokenURL is the instance metadata service's managed identity
+// endpoint.
+const MetadataTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// Authorizer returns values for a request's Authorization header.
+type Authorizer interface {
+	Authorization(context.Context) (string, error)
+}
+
+// ConnectionString is a parsed Service Bus connection string, as shown in
+// the Azure portal.
+type ConnectionString struct {
+	// Endpoint is the namespace's URL, e.g.
+	// "sb://example.servicebus.windows.net/".
+	Endpoint            string
+	SharedAccessKeyName string
+	SharedAccessKey     string
+	// EntityPath is the queue or topic, for entity scoped connection
+	// strings.
+	EntityPath string
+}
+
+// ParseConnectionString parses a connection string of the form
+// "Endpoint=sb://...;SharedAccessKeyName=...;SharedAccessKey=...".
+func ParseConnectionString(s string) (ConnectionString, error) {
+	var cs ConnectionString
+	for _, kv := range strings.Split(s, ";") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		i := strings.IndexByte(kv, '=')
+		if i == -1 {
+			return cs, fmt.Errorf("malformed connection string element %q", kv)
+		}
+		k, v := kv[:i], kv[i+1:]
+		switch strings.ToLower(k) {
+		case "endpoint":
+			cs.Endpoint = v
+		case "sharedaccesskeyname":
+			cs.SharedAccessKeyName = v
+		case "sharedaccesskey":
+			cs.SharedAccessKey = v
+		case "entitypath":
+			cs.EntityPath = v
+		}
+	}
+	if cs.Endpoint == "" || cs.SharedAccessKeyName == "" || cs.SharedAccessKey == "" {
+		return cs, errors.New("connection string requires Endpoint, SharedAccessKeyName, and SharedAccessKey")
+	}
+	return cs, nil
+}
+
+// Namespace reports the namespace's host, e.g.
+// "example.servicebus.windows.net".
+func (cs *ConnectionString) Namespace() (string, error) {
+	u, err := url.Parse(cs.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse endpoint: %v", err)
+	}
+	return u.Host, nil
+}
+
+// SAS creates shared access signatures from a shared access key.
+//
+// SAS is safe for concurrent use.
+type SAS struct {
+	resource string
+	keyName  string
+	key      string
+	// ttl is how long signatures are valid for.
+	ttl time.Duration
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+var _ Authorizer = (*SAS)(nil)
+
+// NewSAS returns a SAS signing for the resource URI, e.g.
+// "https://example.servicebus.windows.net/queue".
+func NewSAS(resource, keyName, key string) *SAS {
+	return &SAS{
+		resource: resource,
+		keyName:  keyName,
+		key:      key,
+		ttl:      time.Hour,
+	}
+}
+
+// Authorization implements Authorizer.
+func (s *SAS) Authorization(_ context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.token != "" && now.Add(5*time.Minute).Before(s.expiry) {
+		return s.token, nil
+	}
+	s.expiry = now.Add(s.ttl)
+	s.token = s.sign(s.expiry)
+	return s.token, nil
+}
+
+// Sign returns a signature expiring at exp.
+//
+// See https://docs.microsoft.com/en-us/azure/service-bus-messaging/service-bus-sas
+func (s *SAS) sign(exp time.Time) string {
+	res := url.QueryEscape(s.resource)
+	se := strconv.FormatInt(exp.Unix(), 10)
+	h := hmac.New(sha256.New, []byte(s.key))
+	io.WriteString(h, res+"\n"+se)
+	sig := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	return fmt.Sprintf("SharedAccessSignature sr=%s&sig=%s&se=%s&skn=%s",
+		res, url.QueryEscape(sig), se, url.QueryEscape(s.keyName))
+}
+
+// ManagedIdentity requests tokens for an Azure managed identity.
+//
+// Tokens come from the App Service identity endpoint if the IDENTITY_ENDPOINT
+// and IDENTITY_HEADER environment variables are set, and from the instance
+// metadata service otherwise.
+//
+// ManagedIdentity is safe for concurrent use.
+type ManagedIdentity struct {
+	c        *http.Client
+	resource string
+	clientID string
+
+	// These are overridden in tests.
+	getenv   func(string) string
+	metadata string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+var _ Authorizer = (*ManagedIdentity)(nil)
+
+// NewManagedIdentity returns a ManagedIdentity requesting tokens for the
+// resource. The clientID selects a user-assigned identity; if empty, the
+// system-assigned identity is used.
+//
+// A nil client means http.DefaultClient.
+func NewManagedIdentity(c *http.Client, resource, clientID string) *ManagedIdentity {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return &ManagedIdentity{
+		c:        c,
+		resource: resource,
+		clientID: clientID,
+		getenv:   os.Getenv,
+		metadata: MetadataTokenURL,
+	}
+}
+
+// Authorization implements Authorizer.
+func (m *ManagedIdentity) Authorization(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Refresh a few minutes early to account for clock skew and request
+	// latency.
+	if m.token != "" && time.Now().Add(5*time.Minute).Before(m.expiry) {
+		return "Bearer " + m.token, nil
+	}
+
+	v := url.Values{"resource": {m.resource}}
+	if m.clientID != "" {
+		v.Set("client_id", m.clientID)
+	}
+	var u string
+	hdr := make(http.Header)
+	if ep, sec := m.getenv("IDENTITY_ENDPOINT"), m.getenv("IDENTITY_HEADER"); ep != "" && sec != "" {
+		v.Set("api-version", "2019-08-01")
+		u = ep + "?" + v.Encode()
+		hdr.Set("X-Identity-Header", sec)
+	} else {
+		v.Set("api-version", "2018-02-01")
+		u = m.metadata + "?" + v.Encode()
+		hdr.Set("Metadata", "true")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header = hdr
+	res, err := m.c.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request managed identity token: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", fmt.Errorf("unexpected response requesting managed identity token: %s: %s", res.Status, msg)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		// The metadata service reports this as a string.
+		ExpiresOn json.Number `json:"expires_on"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("failed to decode managed identity token: %w", err)
+	}
+	exp, err := strconv.ParseInt(tok.ExpiresOn.String(), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse managed identity token expiry: %w", err)
+	}
+	m.token = tok.AccessToken
+	m.expiry = time.Unix(exp, 0)
+	return "Bearer " + m.token, nil
+}
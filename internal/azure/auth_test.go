@@ -0,0 +1,82 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseConnectionString(t *testing.T) {
+	cs, err := ParseConnectionString("Endpoint=sb://example.servicebus.windows.net/;SharedAccessKeyName=send;SharedAccessKey=a2V5PQ==;EntityPath=q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key is base64 and may end in "=".
+	if got, want := cs.SharedAccessKey, "a2V5PQ=="; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := cs.EntityPath, "q"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	ns, err := cs.Namespace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ns, "example.servicebus.windows.net"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if _, err := ParseConnectionString("Endpoint=sb://example.servicebus.windows.net/"); err == nil {
+		t.Error("expected error for missing key")
+	}
+}
+
+func TestSAS(t *testing.T) {
+	s := NewSAS("https://example.servicebus.windows.net/q", "send", "secret")
+	got := s.sign(time.Unix(1600000000, 0))
+	// The signature was computed independently as
+	// base64(hmac_sha256("secret", "https%3A%2F%2Fexample.servicebus.windows.net%2Fq\n1600000000")).
+	want := "SharedAccessSignature sr=https%3A%2F%2Fexample.servicebus.windows.net%2Fq" +
+		"&sig=ZIbKok9i58oyp%2BbUbGhtKNoHJSi04K7ztdvdUMAvBxg%3D&se=1600000000&skn=send"
+	if got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestManagedIdentity(t *testing.T) {
+	ctx := context.Background()
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		q := r.URL.Query()
+		switch {
+		case r.Header.Get("Metadata") != "true":
+			w.WriteHeader(http.StatusBadRequest)
+		case q.Get("resource") != ServiceBusResource:
+			w.WriteHeader(http.StatusBadRequest)
+		case q.Get("client_id") != "client":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			fmt.Fprintf(w, `{"access_token":"tok","expires_on":"%d","token_type":"Bearer"}`, time.Now().Add(time.Hour).Unix())
+		}
+	}))
+	defer srv.Close()
+
+	m := NewManagedIdentity(srv.Client(), ServiceBusResource, "client")
+	m.getenv = func(string) string { return "" }
+	m.metadata = srv.URL
+	for i := 0; i < 2; i++ {
+		got, err := m.Authorization(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "Bearer tok"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("got: %d calls, want: %d", got, want)
+	}
+}
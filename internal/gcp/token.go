@@ -0,0 +1,195 @@
+// Package gcp implements authentication to Google Cloud APIs without the
+// cloud client libraries.
+package gcp
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// These are OAuth2 scopes for the APIs Clair uses.
+const (
+	PubSubScope  = "https://www.googleapis.com/auth/pubsub"
+	StorageScope = "https://www.googleapis.com/auth/devstorage.read_write"
+	// RegistryScope allows pulling from Container Registry and Artifact
+	// Registry.
+	RegistryScope = "https://www.googleapis.com/auth/cloud-platform.read-only"
+	// SQLLoginScope allows logging in to Cloud SQL databases with IAM
+	// database authentication.
+	SQLLoginScope = "https://www.googleapis.com/auth/sqlservice.login"
+)
+
+const (
+	defaultTokenURI = "https://oauth2.googleapis.com/token"
+	// MetadataTokenURL is the metadata server's token endpoint, which
+	// supports GCE service accounts and GKE workload identity.
+	MetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	jwtBearerGrant   = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+// ServiceAccount is the subset of a GCP service account key file we need.
+type ServiceAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	key          *rsa.PrivateKey
+}
+
+// ParseServiceAccount parses a service account key, in the JSON format
+// issued by GCP.
+func ParseServiceAccount(b []byte) (*ServiceAccount, error) {
+	var sa ServiceAccount
+	if err := json.Unmarshal(b, &sa); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file: %v", err)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, errors.New("credentials file missing client_email or private_key")
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = defaultTokenURI
+	}
+	blk, _ := pem.Decode([]byte(sa.PrivateKey))
+	if blk == nil {
+		return nil, errors.New("credentials file private_key is not PEM encoded")
+	}
+	k, err := x509.ParsePKCS8PrivateKey(blk.Bytes)
+	if err != nil {
+		k, err = x509.ParsePKCS1PrivateKey(blk.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	rk, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("credentials file private_key is not an RSA key")
+	}
+	sa.key = rk
+	return &sa, nil
+}
+
+// TokenSource mints and caches OAuth2 access tokens.
+//
+// TokenSource is safe for concurrent use.
+type TokenSource struct {
+	c     *http.Client
+	sa    *ServiceAccount
+	scope string
+	// metadata is the metadata server's token URL, used if sa is nil.
+	metadata string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewTokenSource returns a TokenSource for the scope. Tokens are minted for
+// the service account if provided, or else requested from the metadata
+// server.
+//
+// A nil client means http.DefaultClient.
+func NewTokenSource(c *http.Client, sa *ServiceAccount, scope string) *TokenSource {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return &TokenSource{
+		c:        c,
+		sa:       sa,
+		scope:    scope,
+		metadata: MetadataTokenURL,
+	}
+}
+
+// Token returns a valid access token, refreshing it if needed.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Refresh a minute early to account for clock skew and request latency.
+	if s.token != "" && time.Now().Add(time.Minute).Before(s.expiry) {
+		return s.token, nil
+	}
+	var req *http.Request
+	var err error
+	if s.sa != nil {
+		req, err = s.assertionRequest(ctx)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, s.metadata, nil)
+		if err == nil {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	res, err := s.c.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status requesting access token: %s", res.Status)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("failed to decode access token: %v", err)
+	}
+	s.token = tok.AccessToken
+	s.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// AssertionRequest constructs a JWT bearer grant request, as described in
+// RFC 7523.
+func (s *TokenSource) assertionRequest(ctx context.Context) (*http.Request, error) {
+	opts := (&jose.SignerOptions{}).WithType("JWT")
+	if s.sa.PrivateKeyID != "" {
+		opts = opts.WithHeader(jose.HeaderKey("kid"), s.sa.PrivateKeyID)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: s.sa.key}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jwt signer: %v", err)
+	}
+	now := time.Now()
+	cl := struct {
+		jwt.Claims
+		Scope string `json:"scope"`
+	}{
+		Claims: jwt.Claims{
+			Issuer:   s.sa.ClientEmail,
+			Audience: jwt.Audience{s.sa.TokenURI},
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Scope: s.scope,
+	}
+	assertion, err := jwt.Signed(signer).Claims(cl).CompactSerialize()
+	if err != nil {
+		return nil, err
+	}
+	v := url.Values{
+		"grant_type": {jwtBearerGrant},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.sa.TokenURI, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
@@ -0,0 +1,458 @@
+// Package graphql implements enough of GraphQL to serve read-only queries
+// over a fixed schema: the query language, including fragments, variables,
+// and the @skip and @include directives, execution against object types
+// whose fields are resolved by functions, and introspection of the types
+// described in the schema definition language.
+//
+// It exists only because no maintained GraphQL library is among this
+// module's dependencies, and should be replaced by one, such as
+// github.com/graph-gophers/graphql-go, when that changes. Until then it
+// should stay this small: anything a client can send is bounded by the
+// Schema's limits before any field is resolved.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Schema is the set of types a query is executed against.
+//
+// Only object types and scalars are supported: there are no interfaces,
+// unions, or input types beyond what Args returns.
+type Schema struct {
+	// Query is the root query type.
+	Query *Object
+	// MaxDepth is the deepest a query's fields may nest. Zero means no
+	// limit.
+	MaxDepth int
+	// MaxFields is the most fields a query may select, counting a
+	// fragment's fields every time it's spread. Zero means no limit.
+	MaxFields int
+	// Types describe the schema's types for introspection, as returned by
+	// ParseTypes. The __schema and __type fields are only queryable if it's
+	// set.
+	Types []*TypeDef
+
+	once sync.Once
+	root *Object
+}
+
+// Query returns the root query type, with the introspection fields if the
+// schema has Types.
+func (s *Schema) query() *Object {
+	s.once.Do(func() {
+		s.root = s.Query
+		if s.Types != nil {
+			s.root = introspection(s)
+		}
+	})
+	return s.root
+}
+
+// Object is an object type.
+type Object struct {
+	Name   string
+	Fields map[string]*FieldDef
+}
+
+// FieldDef is a field of an object type.
+type FieldDef struct {
+	// Type is the object type the field resolves to, or nil if it resolves
+	// to a scalar, or list of scalars, encoded as-is.
+	Type *Object
+	// Args lists the arguments the field accepts.
+	Args []string
+	// Resolve returns the field's value given the parent object's value and
+	// the arguments. Lists of objects must be returned as []interface{}.
+	Resolve func(ctx context.Context, src interface{}, args Args) (interface{}, error)
+}
+
+// Args are a field's arguments, with variables substituted.
+type Args map[string]interface{}
+
+// Int returns the named argument as an int, or def if it's missing.
+func (a Args) Int(name string, def int) (int, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case float64: // From JSON-encoded variables.
+		if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q: not an integer", name)
+}
+
+// String returns the named argument as a string, or def if it's missing.
+// Enum values are returned as strings.
+func (a Args) String(name string, def string) (string, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return def, nil
+	case string:
+		return v, nil
+	case Enum:
+		return string(v), nil
+	}
+	return "", fmt.Errorf("argument %q: not a string", name)
+}
+
+// Request is a GraphQL request, as POSTed in JSON.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response.
+//
+// Data is nil if the request failed before execution started.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is an error reported in a Response.
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Pos         `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Execute parses, validates, and executes the request.
+//
+// Fields are resolved one at a time, in the order they're selected.
+func Execute(ctx context.Context, s *Schema, req *Request) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		e := &Error{Message: err.Error()}
+		if se, ok := err.(*SyntaxError); ok {
+			e.Message = se.Msg
+			e.Locations = []Pos{se.Pos}
+		}
+		return &Response{Errors: []*Error{e}}
+	}
+	op, err := operation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{err.(*Error)}}
+	}
+	ex := &executor{
+		schema: s,
+		doc:    doc,
+		vars:   make(map[string]interface{}),
+		open:   make(map[*FieldDef]bool),
+	}
+	for _, v := range op.Vars {
+		val, ok := req.Variables[v.Name]
+		switch {
+		case ok:
+		case v.Default != nil:
+			val = v.Default
+		case v.NonNull:
+			ex.errorf(op.Pos, nil, "variable %q is required", "$"+v.Name)
+		}
+		if ok && val == nil && v.NonNull {
+			ex.errorf(op.Pos, nil, "variable %q must not be null", "$"+v.Name)
+		}
+		ex.vars[v.Name] = val
+	}
+	q := s.query()
+	ex.validate(q, op.Selections, 1, nil)
+	if len(ex.errs) != 0 {
+		return &Response{Errors: ex.errs}
+	}
+	data := ex.selections(ctx, q, nil, op.Selections, nil)
+	return &Response{Data: data, Errors: ex.errs}
+}
+
+// Operation picks the operation to execute.
+func operation(doc *Document, name string) (*Operation, error) {
+	var op *Operation
+	switch {
+	case name == "" && len(doc.Operations) == 1:
+		op = doc.Operations[0]
+	case name == "":
+		return nil, &Error{Message: "operationName is required for documents with multiple operations"}
+	default:
+		for _, o := range doc.Operations {
+			if o.Name == name {
+				op = o
+			}
+		}
+		if op == nil {
+			return nil, &Error{Message: fmt.Sprintf("unknown operation %q", name)}
+		}
+	}
+	if op.Type != "query" {
+		return nil, &Error{Message: fmt.Sprintf("%s operations are not supported", op.Type), Locations: []Pos{op.Pos}}
+	}
+	return op, nil
+}
+
+type executor struct {
+	schema *Schema
+	doc    *Document
+	vars   map[string]interface{}
+	errs   []*Error
+	// Fields counts the fields seen by validate.
+	fields int
+	// Open tracks the introspection fields validate is within.
+	open map[*FieldDef]bool
+}
+
+func (ex *executor) errorf(pos Pos, path []interface{}, format string, args ...interface{}) {
+	e := &Error{
+		Message:   fmt.Sprintf(format, args...),
+		Locations: []Pos{pos},
+	}
+	if path != nil {
+		e.Path = append([]interface{}{}, path...)
+	}
+	ex.errs = append(ex.errs, e)
+}
+
+// Validate checks the selections against the type, reporting problems as
+// errors. The active fragments are tracked to catch cycles.
+func (ex *executor) validate(t *Object, sels []Selection, depth int, active []string) {
+	max := ex.schema.MaxDepth
+	if strings.HasPrefix(t.Name, "__") {
+		max = maxIntrospectionDepth
+	}
+	if max > 0 && depth > max {
+		ex.errorf(sels[0].position(), nil, "query is nested deeper than %d fields", max)
+		return
+	}
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *Field:
+			ex.fields++
+			if max := ex.schema.MaxFields; max > 0 && ex.fields > max {
+				if ex.fields == max+1 {
+					ex.errorf(sel.Pos, nil, "query selects more than %d fields", max)
+				}
+				return
+			}
+			if sel.Name == "__typename" {
+				if sel.Selections != nil {
+					ex.errorf(sel.Pos, nil, "field %q must not have a selection", sel.Name)
+				}
+				continue
+			}
+			def, ok := t.Fields[sel.Name]
+			if !ok {
+				ex.errorf(sel.Pos, nil, "cannot query field %q on type %q", sel.Name, t.Name)
+				continue
+			}
+		args:
+			for _, a := range sel.Args {
+				for _, n := range def.Args {
+					if a.Name == n {
+						continue args
+					}
+				}
+				ex.errorf(sel.Pos, nil, "unknown argument %q on field %q", a.Name, sel.Name)
+			}
+			switch {
+			case def.Type == nil && sel.Selections != nil:
+				ex.errorf(sel.Pos, nil, "field %q must not have a selection", sel.Name)
+			case def.Type != nil && sel.Selections == nil:
+				ex.errorf(sel.Pos, nil, "field %q of type %q must have a selection", sel.Name, def.Type.Name)
+			case def.Type != nil && strings.HasPrefix(def.Type.Name, "__") && sel.Name != "ofType":
+				// Introspection types refer to each other, so a small query
+				// could otherwise select the whole schema many times over.
+				// Only type references may nest.
+				if ex.open[def] {
+					ex.errorf(sel.Pos, nil, "field %q is selected within itself", sel.Name)
+					continue
+				}
+				ex.open[def] = true
+				ex.validate(def.Type, sel.Selections, depth+1, active)
+				delete(ex.open, def)
+			case def.Type != nil:
+				ex.validate(def.Type, sel.Selections, depth+1, active)
+			}
+		case *FragmentSpread:
+			f, ok := ex.doc.Fragments[sel.Name]
+			if !ok {
+				ex.errorf(sel.Pos, nil, "unknown fragment %q", sel.Name)
+				continue
+			}
+			for _, a := range active {
+				if a == sel.Name {
+					ex.errorf(sel.Pos, nil, "fragment %q spreads itself", sel.Name)
+					return
+				}
+			}
+			if f.On != t.Name {
+				ex.errorf(sel.Pos, nil, "fragment %q on %q can't be spread in %q", f.Name, f.On, t.Name)
+				continue
+			}
+			ex.validate(t, f.Selections, depth, append(active, sel.Name))
+		case *InlineFragment:
+			if sel.On != "" && sel.On != t.Name {
+				ex.errorf(sel.Pos, nil, "fragment on %q can't be spread in %q", sel.On, t.Name)
+				continue
+			}
+			ex.validate(t, sel.Selections, depth, active)
+		}
+	}
+}
+
+// Include reports whether the directives allow a selection.
+func (ex *executor) include(ds []*Directive) bool {
+	for _, d := range ds {
+		for _, a := range d.Args {
+			if a.Name != "if" {
+				continue
+			}
+			v, _ := ex.value(a.Value).(bool)
+			switch d.Name {
+			case "skip":
+				if v {
+					return false
+				}
+			case "include":
+				if !v {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Value substitutes variables into a value.
+func (ex *executor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case Variable:
+		return ex.vars[string(v)]
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = ex.value(v[i])
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k := range v {
+			out[k] = ex.value(v[k])
+		}
+		return out
+	}
+	return v
+}
+
+// Collect gathers the fields selected, merging ones with the same response
+// key.
+func (ex *executor) collect(sels []Selection, keys *[]string, fields map[string][]*Field) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *Field:
+			if !ex.include(sel.Directives) {
+				continue
+			}
+			k := sel.Key()
+			if _, ok := fields[k]; !ok {
+				*keys = append(*keys, k)
+			}
+			fields[k] = append(fields[k], sel)
+		case *FragmentSpread:
+			if ex.include(sel.Directives) {
+				ex.collect(ex.doc.Fragments[sel.Name].Selections, keys, fields)
+			}
+		case *InlineFragment:
+			if ex.include(sel.Directives) {
+				ex.collect(sel.Selections, keys, fields)
+			}
+		}
+	}
+}
+
+func (ex *executor) selections(ctx context.Context, t *Object, src interface{}, sels []Selection, path []interface{}) orderedMap {
+	var keys []string
+	fields := make(map[string][]*Field)
+	ex.collect(sels, &keys, fields)
+	out := make(orderedMap, 0, len(keys))
+	for _, k := range keys {
+		fs := fields[k]
+		f := fs[0]
+		fpath := append(path[:len(path):len(path)], k)
+		if f.Name == "__typename" {
+			out = append(out, entry{k, t.Name})
+			continue
+		}
+		def := t.Fields[f.Name]
+		args := make(Args, len(f.Args))
+		for _, a := range f.Args {
+			args[a.Name] = ex.value(a.Value)
+		}
+		v, err := def.Resolve(ctx, src, args)
+		if err != nil {
+			ex.errorf(f.Pos, fpath, "%v", err)
+			out = append(out, entry{k, nil})
+			continue
+		}
+		var sub []Selection
+		for _, f := range fs {
+			sub = append(sub, f.Selections...)
+		}
+		out = append(out, entry{k, ex.complete(ctx, def.Type, v, sub, fpath)})
+	}
+	return out
+}
+
+func (ex *executor) complete(ctx context.Context, t *Object, v interface{}, sels []Selection, path []interface{}) interface{} {
+	if t == nil || v == nil {
+		return v
+	}
+	if l, ok := v.([]interface{}); ok {
+		out := make([]interface{}, len(l))
+		for i := range l {
+			out[i] = ex.complete(ctx, t, l[i], sels, append(path[:len(path):len(path)], i))
+		}
+		return out
+	}
+	return ex.selections(ctx, t, v, sels, path)
+}
+
+// OrderedMap is a JSON object whose members are encoded in order.
+type orderedMap []entry
+
+type entry struct {
+	key   string
+	value interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range m {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(e.key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,388 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testSchema() *Schema {
+	person := &Object{Name: "Person"}
+	person.Fields = map[string]*FieldDef{
+		"name": {
+			Resolve: func(_ context.Context, src interface{}, _ Args) (interface{}, error) {
+				return src.(string), nil
+			},
+		},
+		"friends": {
+			Type: person,
+			Args: []string{"first"},
+			Resolve: func(_ context.Context, _ interface{}, args Args) (interface{}, error) {
+				n, err := args.Int("first", 2)
+				if err != nil {
+					return nil, err
+				}
+				out := []interface{}{}
+				for _, f := range []string{"ann", "bob", "cat"}[:n] {
+					out = append(out, f)
+				}
+				return out, nil
+			},
+		},
+		"broken": {
+			Resolve: func(context.Context, interface{}, Args) (interface{}, error) {
+				return nil, errors.New("broken")
+			},
+		},
+	}
+	return &Schema{
+		Query: &Object{
+			Name: "Query",
+			Fields: map[string]*FieldDef{
+				"me": {
+					Type: person,
+					Resolve: func(context.Context, interface{}, Args) (interface{}, error) {
+						return "me", nil
+					},
+				},
+			},
+		},
+		MaxDepth:  3,
+		MaxFields: 20,
+	}
+}
+
+func TestExecute(t *testing.T) {
+	ctx := context.Background()
+	s := testSchema()
+	tt := []struct {
+		Name string
+		Req  Request
+		Want string
+	}{
+		{
+			Name: "Simple",
+			Req:  Request{Query: `{ me { name } }`},
+			Want: `{"data":{"me":{"name":"me"}}}`,
+		},
+		{
+			Name: "AliasVariablesFragments",
+			Req: Request{
+				Query: `query Q($n: Int = 1, $skip: Boolean!) {
+  me {
+    who: name
+    ...F
+    friends(first: $n) { name __typename }
+    name @skip(if: $skip)
+  }
+}
+# Fragments may come after their use.
+fragment F on Person { friends(first: $n) { who: name } }`,
+				Variables: map[string]interface{}{"n": 2.0, "skip": true},
+			},
+			Want: `{"data":{"me":{"who":"me","friends":[{"who":"ann","name":"ann","__typename":"Person"},{"who":"bob","name":"bob","__typename":"Person"}]}}}`,
+		},
+		{
+			Name: "FieldError",
+			Req:  Request{Query: `{ me { broken name } }`},
+			Want: `{"data":{"me":{"broken":null,"name":"me"}},"errors":[{"message":"broken","locations":[{"line":1,"column":8}],"path":["me","broken"]}]}`,
+		},
+		{
+			Name: "UnknownField",
+			Req:  Request{Query: `{ me { age } }`},
+			Want: `{"errors":[{"message":"cannot query field \"age\" on type \"Person\"","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			Name: "TooDeep",
+			Req:  Request{Query: `{ me { friends { friends { name } } } }`},
+			Want: `{"errors":[{"message":"query is nested deeper than 3 fields","locations":[{"line":1,"column":28}]}]}`,
+		},
+		{
+			Name: "TooMany",
+			Req: Request{Query: `{ me { ...A ...A } }
+fragment A on Person { ...B ...B ...B }
+fragment B on Person { name name name name }`},
+			Want: `{"errors":[{"message":"query selects more than 20 fields","locations":[{"line":3,"column":39}]}]}`,
+		},
+		{
+			Name: "Cycle",
+			Req:  Request{Query: `{ me { ...F } } fragment F on Person { ...F }`},
+			Want: `{"errors":[{"message":"fragment \"F\" spreads itself","locations":[{"line":1,"column":40}]}]}`,
+		},
+		{
+			Name: "Syntax",
+			Req:  Request{Query: `{ me { name }`},
+			Want: `{"errors":[{"message":"unexpected end of document","locations":[{"line":1,"column":14}]}]}`,
+		},
+		{
+			Name: "Mutation",
+			Req:  Request{Query: `mutation { me { name } }`},
+			Want: `{"errors":[{"message":"mutation operations are not supported","locations":[{"line":1,"column":1}]}]}`,
+		},
+		{
+			Name: "MissingVariable",
+			Req:  Request{Query: `query ($n: Int!) { me { friends(first: $n) { name } } }`},
+			Want: `{"errors":[{"message":"variable \"$n\" is required","locations":[{"line":1,"column":1}]}]}`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			b, err := json.Marshal(Execute(ctx, s, &tc.Req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tc.Want {
+				t.Errorf("\ngot:  %s\nwant: %s", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	doc, err := Parse(`{ f(a: -1.5e3, b: "q\"é", c: [1 two null], d: {e: true}, g: """ block "" """) }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := doc.Operations[0].Selections[0].(*Field)
+	got := make(map[string]interface{})
+	for _, a := range f.Args {
+		got[a.Name] = a.Value
+	}
+	want := map[string]interface{}{
+		"a": -1500.0,
+		"b": "q\"é",
+		"c": []interface{}{1, Enum("two"), nil},
+		"d": map[string]interface{}{"e": true},
+		"g": `block ""`,
+	}
+	gb, _ := json.Marshal(got)
+	wb, _ := json.Marshal(want)
+	if string(gb) != string(wb) {
+		t.Errorf("got: %s, want: %s", gb, wb)
+	}
+}
+
+// IntrospectionQuery is the query GraphQL tools use to discover a schema.
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types {
+      ...FullType
+    }
+    directives {
+      name
+      description
+      locations
+      args {
+        ...InputValue
+      }
+    }
+  }
+}
+
+fragment FullType on __Type {
+  kind
+  name
+  description
+  fields(includeDeprecated: true) {
+    name
+    description
+    args {
+      ...InputValue
+    }
+    type {
+      ...TypeRef
+    }
+    isDeprecated
+    deprecationReason
+  }
+  inputFields {
+    ...InputValue
+  }
+  interfaces {
+    ...TypeRef
+  }
+  enumValues(includeDeprecated: true) {
+    name
+    description
+    isDeprecated
+    deprecationReason
+  }
+  possibleTypes {
+    ...TypeRef
+  }
+}
+
+fragment InputValue on __InputValue {
+  name
+  description
+  type { ...TypeRef }
+  defaultValue
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType {
+    kind
+    name
+    ofType {
+      kind
+      name
+      ofType {
+        kind
+        name
+        ofType {
+          kind
+          name
+          ofType {
+            kind
+            name
+            ofType {
+              kind
+              name
+              ofType {
+                kind
+                name
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestIntrospection(t *testing.T) {
+	ctx := context.Background()
+	types, err := ParseTypes(`type Query {
+  me: Person
+}
+
+# Person is someone.
+type Person {
+  name: String!
+  friends(
+    # How many to return.
+    first: Int = 2
+  ): [Person!]!
+  broken: String
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := testSchema()
+	s.MaxFields = 500
+	s.Types = types
+
+	t.Run("Standard", func(t *testing.T) {
+		res := Execute(ctx, s, &Request{Query: introspectionQuery})
+		if len(res.Errors) != 0 {
+			t.Fatalf("errors: %v", res.Errors)
+		}
+		b, err := json.Marshal(res.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Schema struct {
+				QueryType struct{ Name string }
+				Types     []struct {
+					Kind, Name string
+					Fields     []struct {
+						Name string
+						Args []struct {
+							Name, Description, DefaultValue string
+						}
+						Type json.RawMessage
+					}
+				}
+				Directives []struct{ Name string }
+			} `json:"__schema"`
+		}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := got.Schema.QueryType.Name, "Query"; got != want {
+			t.Errorf("queryType: got %q, want %q", got, want)
+		}
+		if got, want := len(got.Schema.Directives), 2; got != want {
+			t.Errorf("directives: got %d, want %d", got, want)
+		}
+		seen := make(map[string]bool)
+		for _, ty := range got.Schema.Types {
+			seen[ty.Name] = true
+			if ty.Name != "Person" {
+				continue
+			}
+			if got, want := len(ty.Fields), 3; got != want {
+				t.Fatalf("Person fields: got %d, want %d", got, want)
+			}
+			f := ty.Fields[1]
+			if f.Name != "friends" || len(f.Args) != 1 {
+				t.Fatalf("unexpected field: %+v", f)
+			}
+			if a := f.Args[0]; a.Name != "first" || a.Description != "How many to return." || a.DefaultValue != "2" {
+				t.Errorf("unexpected argument: %+v", a)
+			}
+			const want = `{"kind":"NON_NULL","name":null,"ofType":{"kind":"LIST","name":null,"ofType":{"kind":"NON_NULL","name":null,"ofType":{"kind":"OBJECT","name":"Person","ofType":null}}}}`
+			if got := string(f.Type); got != want {
+				t.Errorf("\ngot:  %s\nwant: %s", got, want)
+			}
+		}
+		for _, n := range []string{"Query", "Person", "String", "Int", "Boolean", "__Schema", "__Type", "__TypeKind"} {
+			if !seen[n] {
+				t.Errorf("missing type %q", n)
+			}
+		}
+	})
+
+	tt := []struct {
+		Name string
+		Req  Request
+		Want string
+	}{
+		{
+			Name: "Type",
+			Req:  Request{Query: `{ __type(name: "Person") { kind name description fields { name } } }`},
+			Want: `{"data":{"__type":{"kind":"OBJECT","name":"Person","description":"Person is someone.","fields":[{"name":"name"},{"name":"friends"},{"name":"broken"}]}}}`,
+		},
+		{
+			Name: "Enum",
+			Req:  Request{Query: `{ __type(name: "__TypeKind") { kind enumValues { name } } }`},
+			Want: `{"data":{"__type":{"kind":"ENUM","enumValues":[{"name":"SCALAR"},{"name":"OBJECT"},{"name":"INTERFACE"},{"name":"UNION"},{"name":"ENUM"},{"name":"INPUT_OBJECT"},{"name":"LIST"},{"name":"NON_NULL"}]}}}`,
+		},
+		{
+			Name: "UnknownType",
+			Req:  Request{Query: `{ __type(name: "Nobody") { name } me { name } }`},
+			Want: `{"data":{"__type":null,"me":{"name":"me"}}}`,
+		},
+		{
+			Name: "Nested",
+			Req:  Request{Query: `{ __schema { types { fields { type { fields { name } } } } } }`},
+			Want: `{"errors":[{"message":"field \"fields\" is selected within itself","locations":[{"line":1,"column":38}]}]}`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			b, err := json.Marshal(Execute(ctx, s, &tc.Req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tc.Want {
+				t.Errorf("\ngot:  %s\nwant: %s", got, tc.Want)
+			}
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		res := Execute(ctx, testSchema(), &Request{Query: `{ __schema { types { name } } }`})
+		if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, "__schema") {
+			t.Errorf("unexpected response: %+v", res)
+		}
+	})
+}
@@ -0,0 +1,338 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+)
+
+// IntrospectionTypes are the built-in scalars and the types introspection
+// queries are answered with.
+const introspectionTypes = `
+"The String scalar type represents textual data, represented as UTF-8 character sequences."
+scalar String
+"The Int scalar type represents non-fractional signed whole numeric values between -2^31 and 2^31-1."
+scalar Int
+"The Float scalar type represents signed double-precision fractional values."
+scalar Float
+"The Boolean scalar type represents true or false."
+scalar Boolean
+"The ID scalar type represents a unique identifier, serialized as a string."
+scalar ID
+
+type __Schema {
+  types: [__Type!]!
+  queryType: __Type!
+  mutationType: __Type
+  subscriptionType: __Type
+  directives: [__Directive!]!
+}
+
+type __Type {
+  kind: __TypeKind!
+  name: String
+  description: String
+  fields(includeDeprecated: Boolean = false): [__Field!]
+  interfaces: [__Type!]
+  possibleTypes: [__Type!]
+  enumValues(includeDeprecated: Boolean = false): [__EnumValue!]
+  inputFields: [__InputValue!]
+  ofType: __Type
+}
+
+type __Field {
+  name: String!
+  description: String
+  args: [__InputValue!]!
+  type: __Type!
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __InputValue {
+  name: String!
+  description: String
+  type: __Type!
+  defaultValue: String
+}
+
+type __EnumValue {
+  name: String!
+  description: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+enum __TypeKind {
+  SCALAR
+  OBJECT
+  INTERFACE
+  UNION
+  ENUM
+  INPUT_OBJECT
+  LIST
+  NON_NULL
+}
+
+type __Directive {
+  name: String!
+  description: String
+  locations: [__DirectiveLocation!]!
+  args: [__InputValue!]!
+}
+
+enum __DirectiveLocation {
+  QUERY
+  MUTATION
+  SUBSCRIPTION
+  FIELD
+  FRAGMENT_DEFINITION
+  FRAGMENT_SPREAD
+  INLINE_FRAGMENT
+  SCHEMA
+  SCALAR
+  OBJECT
+  FIELD_DEFINITION
+  ARGUMENT_DEFINITION
+  INTERFACE
+  UNION
+  ENUM
+  ENUM_VALUE
+  INPUT_OBJECT
+  INPUT_FIELD_DEFINITION
+}
+`
+
+// MaxIntrospectionDepth bounds how deeply introspection types' fields may
+// nest, in place of the Schema's MaxDepth: type references are nested
+// deeply by design.
+const maxIntrospectionDepth = 16
+
+type directive struct {
+	Name        string
+	Description string
+	Locations   []interface{}
+	Args        []*FieldType
+}
+
+// Directives are the directives the executor implements.
+var directives = []interface{}{
+	&directive{
+		Name:        "skip",
+		Description: "Directs the executor to skip this field or fragment when the `if` argument is true.",
+		Locations:   []interface{}{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args: []*FieldType{{
+			Name:        "if",
+			Description: "Skipped when true.",
+			Type:        &TypeRef{Kind: "NON_NULL", OfType: &TypeRef{Name: "Boolean"}},
+		}},
+	},
+	&directive{
+		Name:        "include",
+		Description: "Directs the executor to include this field or fragment only when the `if` argument is true.",
+		Locations:   []interface{}{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args: []*FieldType{{
+			Name:        "if",
+			Description: "Included when true.",
+			Type:        &TypeRef{Kind: "NON_NULL", OfType: &TypeRef{Name: "Boolean"}},
+		}},
+	},
+}
+
+// Introspection returns a copy of the schema's query type with the
+// __schema and __type fields added.
+func introspection(s *Schema) *Object {
+	builtin, err := ParseTypes(introspectionTypes)
+	if err != nil {
+		panic(fmt.Sprintf("graphql: bad introspection types: %v", err))
+	}
+	var types []interface{}
+	byName := make(map[string]*TypeDef)
+	for _, ts := range [][]*TypeDef{s.Types, builtin} {
+		for _, t := range ts {
+			if _, ok := byName[t.Name]; ok {
+				continue
+			}
+			byName[t.Name] = t
+			types = append(types, &TypeRef{Name: t.Name})
+		}
+	}
+	def := func(src interface{}) *TypeDef {
+		if r := src.(*TypeRef); r.Kind == "" {
+			return byName[r.Name]
+		}
+		return nil
+	}
+	// Resolvers mustn't return typed nils, or the executor would select
+	// fields of them.
+	str := func(s string) interface{} {
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+	fields := func(fs []*FieldType) []interface{} {
+		out := make([]interface{}, len(fs))
+		for i, f := range fs {
+			out[i] = f
+		}
+		return out
+	}
+	resolve := func(f func(src interface{}) interface{}) func(context.Context, interface{}, Args) (interface{}, error) {
+		return func(_ context.Context, src interface{}, _ Args) (interface{}, error) {
+			return f(src), nil
+		}
+	}
+	noDeprecation := map[string]*FieldDef{
+		"isDeprecated":      {Resolve: resolve(func(interface{}) interface{} { return false })},
+		"deprecationReason": {Resolve: resolve(func(interface{}) interface{} { return nil })},
+	}
+
+	typ := &Object{Name: "__Type"}
+	field := &Object{Name: "__Field"}
+	input := &Object{Name: "__InputValue"}
+	enumValue := &Object{Name: "__EnumValue"}
+	dir := &Object{Name: "__Directive"}
+	schema := &Object{Name: "__Schema"}
+	typ.Fields = map[string]*FieldDef{
+		"kind": {Resolve: resolve(func(src interface{}) interface{} {
+			if r := src.(*TypeRef); r.Kind != "" {
+				return r.Kind
+			}
+			if d := def(src); d != nil {
+				return d.Kind
+			}
+			return "SCALAR"
+		})},
+		"name": {Resolve: resolve(func(src interface{}) interface{} {
+			return str(src.(*TypeRef).Name)
+		})},
+		"description": {Resolve: resolve(func(src interface{}) interface{} {
+			if d := def(src); d != nil {
+				return str(d.Description)
+			}
+			return nil
+		})},
+		"fields": {
+			Type: field,
+			Args: []string{"includeDeprecated"},
+			Resolve: resolve(func(src interface{}) interface{} {
+				if d := def(src); d != nil && d.Kind == "OBJECT" {
+					return fields(d.Fields)
+				}
+				return nil
+			}),
+		},
+		"interfaces": {
+			Type: typ,
+			Resolve: resolve(func(src interface{}) interface{} {
+				if d := def(src); d != nil && d.Kind == "OBJECT" {
+					return []interface{}{}
+				}
+				return nil
+			}),
+		},
+		"possibleTypes": {Type: typ, Resolve: resolve(func(interface{}) interface{} { return nil })},
+		"enumValues": {
+			Type: enumValue,
+			Args: []string{"includeDeprecated"},
+			Resolve: resolve(func(src interface{}) interface{} {
+				d := def(src)
+				if d == nil || d.Kind != "ENUM" {
+					return nil
+				}
+				out := make([]interface{}, len(d.EnumValues))
+				for i, v := range d.EnumValues {
+					out[i] = v
+				}
+				return out
+			}),
+		},
+		"inputFields": {Type: input, Resolve: resolve(func(interface{}) interface{} { return nil })},
+		"ofType": {
+			Type: typ,
+			Resolve: resolve(func(src interface{}) interface{} {
+				if r := src.(*TypeRef); r.OfType != nil {
+					return r.OfType
+				}
+				return nil
+			}),
+		},
+	}
+	field.Fields = map[string]*FieldDef{
+		"name":        {Resolve: resolve(func(src interface{}) interface{} { return src.(*FieldType).Name })},
+		"description": {Resolve: resolve(func(src interface{}) interface{} { return str(src.(*FieldType).Description) })},
+		"args": {
+			Type:    input,
+			Resolve: resolve(func(src interface{}) interface{} { return fields(src.(*FieldType).Args) }),
+		},
+		"type": {
+			Type:    typ,
+			Resolve: resolve(func(src interface{}) interface{} { return src.(*FieldType).Type }),
+		},
+		"isDeprecated":      noDeprecation["isDeprecated"],
+		"deprecationReason": noDeprecation["deprecationReason"],
+	}
+	input.Fields = map[string]*FieldDef{
+		"name":        field.Fields["name"],
+		"description": field.Fields["description"],
+		"type": {
+			Type:    typ,
+			Resolve: resolve(func(src interface{}) interface{} { return src.(*FieldType).Type }),
+		},
+		"defaultValue": {Resolve: resolve(func(src interface{}) interface{} {
+			if d := src.(*FieldType).Default; d != nil {
+				return *d
+			}
+			return nil
+		})},
+	}
+	enumValue.Fields = map[string]*FieldDef{
+		"name":              {Resolve: resolve(func(src interface{}) interface{} { return src.(*EnumValue).Name })},
+		"description":       {Resolve: resolve(func(src interface{}) interface{} { return str(src.(*EnumValue).Description) })},
+		"isDeprecated":      noDeprecation["isDeprecated"],
+		"deprecationReason": noDeprecation["deprecationReason"],
+	}
+	dir.Fields = map[string]*FieldDef{
+		"name":        {Resolve: resolve(func(src interface{}) interface{} { return src.(*directive).Name })},
+		"description": {Resolve: resolve(func(src interface{}) interface{} { return str(src.(*directive).Description) })},
+		"locations":   {Resolve: resolve(func(src interface{}) interface{} { return src.(*directive).Locations })},
+		"args": {
+			Type:    input,
+			Resolve: resolve(func(src interface{}) interface{} { return fields(src.(*directive).Args) }),
+		},
+	}
+	schema.Fields = map[string]*FieldDef{
+		"types": {Type: typ, Resolve: resolve(func(interface{}) interface{} { return types })},
+		"queryType": {
+			Type:    typ,
+			Resolve: resolve(func(interface{}) interface{} { return &TypeRef{Name: s.Query.Name} }),
+		},
+		"mutationType":     {Type: typ, Resolve: resolve(func(interface{}) interface{} { return nil })},
+		"subscriptionType": {Type: typ, Resolve: resolve(func(interface{}) interface{} { return nil })},
+		"directives":       {Type: dir, Resolve: resolve(func(interface{}) interface{} { return directives })},
+	}
+
+	q := &Object{Name: s.Query.Name, Fields: make(map[string]*FieldDef, len(s.Query.Fields)+2)}
+	for n, f := range s.Query.Fields {
+		q.Fields[n] = f
+	}
+	q.Fields["__schema"] = &FieldDef{
+		Type:    schema,
+		Resolve: resolve(func(interface{}) interface{} { return s }),
+	}
+	q.Fields["__type"] = &FieldDef{
+		Type: typ,
+		Args: []string{"name"},
+		Resolve: func(_ context.Context, _ interface{}, args Args) (interface{}, error) {
+			n, err := args.String("name", "")
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := byName[n]; !ok {
+				return nil, nil
+			}
+			return &TypeRef{Name: n}, nil
+		},
+	}
+	return q
+}
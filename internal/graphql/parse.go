@@ -0,0 +1,719 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Pos is a location in a query document.
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Document is a parsed query document.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is an operation definition.
+type Operation struct {
+	Pos        Pos
+	Type       string // "query", "mutation", or "subscription"
+	Name       string
+	Vars       []*VarDef
+	Directives []*Directive
+	Selections []Selection
+}
+
+// VarDef is a variable definition.
+type VarDef struct {
+	Name    string
+	NonNull bool
+	Default interface{}
+}
+
+// Fragment is a fragment definition.
+type Fragment struct {
+	Pos        Pos
+	Name       string
+	On         string
+	Directives []*Directive
+	Selections []Selection
+}
+
+// Selection is a *Field, *FragmentSpread, or *InlineFragment.
+type Selection interface {
+	position() Pos
+}
+
+// Field is a field selection.
+type Field struct {
+	Pos        Pos
+	Alias      string
+	Name       string
+	Args       []*Arg
+	Directives []*Directive
+	Selections []Selection
+}
+
+// FragmentSpread is a named fragment's use.
+type FragmentSpread struct {
+	Pos        Pos
+	Name       string
+	Directives []*Directive
+}
+
+// InlineFragment is an anonymous fragment.
+type InlineFragment struct {
+	Pos        Pos
+	On         string
+	Directives []*Directive
+	Selections []Selection
+}
+
+func (f *Field) position() Pos          { return f.Pos }
+func (f *FragmentSpread) position() Pos { return f.Pos }
+func (f *InlineFragment) position() Pos { return f.Pos }
+
+// Key returns the field's name in the response.
+func (f *Field) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Arg is an argument to a field or directive.
+type Arg struct {
+	Name  string
+	Value interface{}
+}
+
+// Directive is a directive, like @skip.
+type Directive struct {
+	Name string
+	Args []*Arg
+}
+
+// Values in a document are represented as nil, bool, int, float64, string,
+// Enum, Variable, []interface{}, or map[string]interface{}.
+type (
+	// Enum is an enum value.
+	Enum string
+	// Variable is a reference to a variable.
+	Variable string
+)
+
+// SyntaxError is returned for documents that don't parse.
+type SyntaxError struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+// Token kinds.
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind int
+	val  string
+	pos  Pos
+}
+
+// Lexer splits a document into tokens.
+type lexer struct {
+	src       string
+	off       int
+	line, col int
+	// If descs is set, desc holds the comments immediately before the last
+	// token, which the schema definition language uses as descriptions.
+	descs bool
+	desc  string
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: Pos{l.line, l.col}, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) advance(n int) {
+	for _, r := range l.src[l.off : l.off+n] {
+		if r == '\n' {
+			l.line++
+			l.col = 1
+			continue
+		}
+		l.col++
+	}
+	l.off += n
+}
+
+func (l *lexer) next() (token, error) {
+	// Skip ignored tokens: whitespace, commas, and comments.
+	var desc []string
+skip:
+	for l.off < len(l.src) {
+		c := l.src[l.off]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.advance(1)
+			continue
+		case c == '#':
+			n := strings.IndexByte(l.src[l.off:], '\n')
+			if n == -1 {
+				n = len(l.src) - l.off
+			}
+			if l.descs {
+				desc = append(desc, strings.TrimSpace(l.src[l.off+1:l.off+n]))
+			}
+			l.advance(n)
+			continue
+		case strings.HasPrefix(l.src[l.off:], "\ufeff"):
+			l.advance(len("\ufeff"))
+			continue
+		}
+		break skip
+	}
+	l.desc = strings.Join(desc, "\n")
+	pos := Pos{l.line, l.col}
+	if l.off == len(l.src) {
+		return token{kind: tokEOF, pos: pos}, nil
+	}
+	rest := l.src[l.off:]
+	c := rest[0]
+	switch {
+	case strings.HasPrefix(rest, "..."):
+		l.advance(3)
+		return token{kind: tokPunct, val: "...", pos: pos}, nil
+	case strings.IndexByte("!$&()/:=@[]{}|", c) != -1:
+		l.advance(1)
+		return token{kind: tokPunct, val: rest[:1], pos: pos}, nil
+	case c == '_' || isLetter(c):
+		n := 1
+		for n < len(rest) && (rest[n] == '_' || isLetter(rest[n]) || isDigit(rest[n])) {
+			n++
+		}
+		l.advance(n)
+		return token{kind: tokName, val: rest[:n], pos: pos}, nil
+	case c == '-' || isDigit(c):
+		return l.number(pos)
+	case strings.HasPrefix(rest, `"""`):
+		return l.blockString(pos)
+	case c == '"':
+		return l.string(pos)
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return token{}, l.errorf("unexpected character %q", r)
+}
+
+func (l *lexer) number(pos Pos) (token, error) {
+	rest := l.src[l.off:]
+	n := 0
+	if rest[n] == '-' {
+		n++
+	}
+	digits := func() int {
+		s := n
+		for n < len(rest) && isDigit(rest[n]) {
+			n++
+		}
+		return n - s
+	}
+	if digits() == 0 {
+		return token{}, l.errorf("malformed number")
+	}
+	kind := tokInt
+	if n < len(rest) && rest[n] == '.' {
+		kind = tokFloat
+		n++
+		if digits() == 0 {
+			return token{}, l.errorf("malformed number")
+		}
+	}
+	if n < len(rest) && (rest[n] == 'e' || rest[n] == 'E') {
+		kind = tokFloat
+		n++
+		if n < len(rest) && (rest[n] == '+' || rest[n] == '-') {
+			n++
+		}
+		if digits() == 0 {
+			return token{}, l.errorf("malformed number")
+		}
+	}
+	l.advance(n)
+	return token{kind: kind, val: rest[:n], pos: pos}, nil
+}
+
+func (l *lexer) string(pos Pos) (token, error) {
+	rest := l.src[l.off:]
+	var b strings.Builder
+	for n := 1; n < len(rest); {
+		c := rest[n]
+		switch {
+		case c == '"':
+			l.advance(n + 1)
+			return token{kind: tokString, val: b.String(), pos: pos}, nil
+		case c == '\n' || c == '\r':
+			return token{}, l.errorf("unterminated string")
+		case c == '\\':
+			if n+1 == len(rest) {
+				return token{}, l.errorf("unterminated string")
+			}
+			switch e := rest[n+1]; e {
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if n+6 > len(rest) {
+					return token{}, l.errorf("malformed unicode escape")
+				}
+				r, err := strconv.ParseUint(rest[n+2:n+6], 16, 16)
+				if err != nil {
+					return token{}, l.errorf("malformed unicode escape")
+				}
+				b.WriteRune(rune(r))
+				n += 4
+			default:
+				return token{}, l.errorf("unknown escape %q", e)
+			}
+			n += 2
+		default:
+			b.WriteByte(c)
+			n++
+		}
+	}
+	return token{}, l.errorf("unterminated string")
+}
+
+// BlockString lexes a """-delimited string. Common indentation isn't
+// removed.
+func (l *lexer) blockString(pos Pos) (token, error) {
+	rest := l.src[l.off+3:]
+	var b strings.Builder
+	for n := 0; n < len(rest); {
+		switch {
+		case strings.HasPrefix(rest[n:], `\"""`):
+			b.WriteString(`"""`)
+			n += 4
+		case strings.HasPrefix(rest[n:], `"""`):
+			l.advance(3 + n + 3)
+			return token{kind: tokString, val: strings.TrimSpace(b.String()), pos: pos}, nil
+		default:
+			b.WriteByte(rest[n])
+			n++
+		}
+	}
+	return token{}, l.errorf("unterminated block string")
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// Parser is a recursive descent parser over the lexer's tokens, with one
+// token of lookahead.
+type parser struct {
+	lex lexer
+	tok token
+}
+
+// Parse parses a query document. Only executable definitions are allowed.
+func Parse(src string) (*Document, error) {
+	p := parser{lex: lexer{src: src, line: 1, col: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.is(tokPunct, "{"):
+			pos := p.tok.pos
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Pos: pos, Type: "query", Selections: sel})
+		case p.is(tokName, "query"), p.is(tokName, "mutation"), p.is(tokName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.is(tokName, "fragment"):
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.Fragments[f.Name]; ok {
+				return nil, &SyntaxError{Pos: f.Pos, Msg: fmt.Sprintf("duplicate fragment %q", f.Name)}
+			}
+			doc.Fragments[f.Name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, &SyntaxError{Pos: p.tok.pos, Msg: "no operations in document"}
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *parser) is(kind int, val string) bool {
+	return p.tok.kind == kind && p.tok.val == val
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return &SyntaxError{Pos: p.tok.pos, Msg: "unexpected end of document"}
+	}
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf("unexpected %q", p.tok.val)}
+}
+
+// Skip consumes the punctuator if it's next, reporting whether it was.
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.is(tokPunct, punct) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.is(tokPunct, punct) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected()
+	}
+	n := p.tok.val
+	return n, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Pos: p.tok.pos, Type: p.tok.val}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.tok.kind == tokName {
+		if op.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.is(tokPunct, ")") {
+			v, err := p.varDef()
+			if err != nil {
+				return nil, err
+			}
+			op.Vars = append(op.Vars, v)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if op.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) varDef() (*VarDef, error) {
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	n, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	v := &VarDef{Name: n}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if v.NonNull, err = p.typeRef(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		if v.Default, err = p.value(true); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// TypeRef consumes a type reference, reporting whether it's non-null.
+func (p *parser) typeRef() (bool, error) {
+	if ok, err := p.skip("["); err != nil {
+		return false, err
+	} else if ok {
+		if _, err := p.typeRef(); err != nil {
+			return false, err
+		}
+		if err := p.expect("]"); err != nil {
+			return false, err
+		}
+	} else if _, err := p.name(); err != nil {
+		return false, err
+	}
+	return p.skip("!")
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	f := &Fragment{Pos: p.tok.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.Name == "on" {
+		return nil, &SyntaxError{Pos: f.Pos, Msg: `fragment can't be named "on"`}
+	}
+	if !p.is(tokName, "on") {
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if f.On, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if f.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var out []Selection
+	for !p.is(tokPunct, "}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if len(out) == 0 {
+		return nil, &SyntaxError{Pos: p.tok.pos, Msg: "empty selection set"}
+	}
+	return out, p.advance()
+}
+
+func (p *parser) selection() (Selection, error) {
+	pos := p.tok.pos
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.tok.kind == tokName && p.tok.val != "on" {
+			s := &FragmentSpread{Pos: pos, Name: p.tok.val}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			s.Directives, err = p.directives()
+			return s, err
+		}
+		f := &InlineFragment{Pos: pos}
+		if p.is(tokName, "on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if f.On, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if f.Directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		f.Selections, err = p.selectionSet()
+		return f, err
+	}
+	f := &Field{Pos: pos}
+	var err error
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.Alias = f.Name
+		if f.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.Args, err = p.args(); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.is(tokPunct, "{") {
+		if f.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) args() ([]*Arg, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+	var out []*Arg
+	for !p.is(tokPunct, ")") {
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &Arg{Name: n, Value: v})
+	}
+	return out, p.advance()
+}
+
+func (p *parser) directives() ([]*Directive, error) {
+	var out []*Directive
+	for p.is(tokPunct, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &Directive{Name: n, Args: args})
+	}
+	return out, nil
+}
+
+// Value parses a value. Constant values can't contain variables.
+func (p *parser) value(constant bool) (interface{}, error) {
+	t := p.tok
+	switch t.kind {
+	case tokInt:
+		i, err := strconv.Atoi(t.val)
+		if err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("bad integer %q", t.val)}
+		}
+		return i, p.advance()
+	case tokFloat:
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("bad float %q", t.val)}
+		}
+		return f, p.advance()
+	case tokString:
+		return t.val, p.advance()
+	case tokName:
+		var v interface{}
+		switch t.val {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+		default:
+			v = Enum(t.val)
+		}
+		return v, p.advance()
+	}
+	switch {
+	case p.is(tokPunct, "$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.name()
+		return Variable(n), err
+	case p.is(tokPunct, "["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		out := []interface{}{}
+		for !p.is(tokPunct, "]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, p.advance()
+	case p.is(tokPunct, "{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		out := map[string]interface{}{}
+		for !p.is(tokPunct, "}") {
+			n, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if out[n], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return out, p.advance()
+	}
+	return nil, p.unexpected()
+}
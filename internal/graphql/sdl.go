@@ -0,0 +1,254 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TypeDef is a named type from a schema definition, as reported by
+// introspection.
+type TypeDef struct {
+	Kind        string // "OBJECT", "ENUM", or "SCALAR"
+	Name        string
+	Description string
+	Fields      []*FieldType
+	EnumValues  []*EnumValue
+}
+
+// FieldType describes an object type's field, or a field's argument.
+type FieldType struct {
+	Name        string
+	Description string
+	Args        []*FieldType
+	Type        *TypeRef
+	// Default is an argument's default value in the query language, if it
+	// has one.
+	Default *string
+}
+
+// EnumValue is one of an enum type's values.
+type EnumValue struct {
+	Name        string
+	Description string
+}
+
+// TypeRef refers to a type. Wrapping types have a Kind of "LIST" or
+// "NON_NULL" and refer to OfType; named types have only a Name.
+type TypeRef struct {
+	Kind   string
+	Name   string
+	OfType *TypeRef
+}
+
+// ParseTypes parses type definitions in the schema definition language.
+// Object, enum, and scalar types are allowed, and are described by the
+// string or comments preceding them.
+func ParseTypes(src string) ([]*TypeDef, error) {
+	p := parser{lex: lexer{src: src, line: 1, col: 1, descs: true}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var out []*TypeDef
+	seen := make(map[string]bool)
+	for p.tok.kind != tokEOF {
+		desc, err := p.description()
+		if err != nil {
+			return nil, err
+		}
+		pos := p.tok.pos
+		var t *TypeDef
+		switch {
+		case p.is(tokName, "type"):
+			t, err = p.objectDef()
+		case p.is(tokName, "enum"):
+			t, err = p.enumDef()
+		case p.is(tokName, "scalar"):
+			t = &TypeDef{Kind: "SCALAR"}
+			if err = p.advance(); err == nil {
+				t.Name, err = p.name()
+			}
+		default:
+			return nil, p.unexpected()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[t.Name] {
+			return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("duplicate type %q", t.Name)}
+		}
+		seen[t.Name] = true
+		t.Description = desc
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// Description consumes a definition's description: a string, or else the
+// comments before the next token.
+func (p *parser) description() (string, error) {
+	if p.tok.kind == tokString {
+		d := p.tok.val
+		return d, p.advance()
+	}
+	return p.lex.desc, nil
+}
+
+func (p *parser) objectDef() (*TypeDef, error) {
+	t := &TypeDef{Kind: "OBJECT"}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if t.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.is(tokPunct, "}") {
+		f, err := p.fieldType(true)
+		if err != nil {
+			return nil, err
+		}
+		t.Fields = append(t.Fields, f)
+	}
+	return t, p.advance()
+}
+
+func (p *parser) enumDef() (*TypeDef, error) {
+	t := &TypeDef{Kind: "ENUM"}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if t.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.is(tokPunct, "}") {
+		desc, err := p.description()
+		if err != nil {
+			return nil, err
+		}
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		t.EnumValues = append(t.EnumValues, &EnumValue{Name: n, Description: desc})
+	}
+	return t, p.advance()
+}
+
+// FieldType parses a field definition, or an argument definition if field
+// isn't set.
+func (p *parser) fieldType(field bool) (*FieldType, error) {
+	desc, err := p.description()
+	if err != nil {
+		return nil, err
+	}
+	f := &FieldType{Description: desc}
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if field {
+		if ok, err := p.skip("("); err != nil {
+			return nil, err
+		} else if ok {
+			for !p.is(tokPunct, ")") {
+				a, err := p.fieldType(false)
+				if err != nil {
+					return nil, err
+				}
+				f.Args = append(f.Args, a)
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if f.Type, err = p.typeOf(); err != nil {
+		return nil, err
+	}
+	if !field {
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			v, err := p.value(true)
+			if err != nil {
+				return nil, err
+			}
+			d := literal(v)
+			f.Default = &d
+		}
+	}
+	return f, nil
+}
+
+// TypeOf parses a type reference.
+func (p *parser) typeOf() (*TypeRef, error) {
+	var t *TypeRef
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		of, err := p.typeOf()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		t = &TypeRef{Kind: "LIST", OfType: of}
+	} else {
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		t = &TypeRef{Name: n}
+	}
+	if ok, err := p.skip("!"); err != nil {
+		return nil, err
+	} else if ok {
+		t = &TypeRef{Kind: "NON_NULL", OfType: t}
+	}
+	return t, nil
+}
+
+// Literal formats a constant value in the query language.
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		// JSON's escapes are a subset of GraphQL's.
+		b, _ := json.Marshal(v)
+		return string(b)
+	case Enum:
+		return string(v)
+	case []interface{}:
+		s := make([]string, len(v))
+		for i := range v {
+			s[i] = literal(v[i])
+		}
+		return "[" + strings.Join(s, ", ") + "]"
+	case map[string]interface{}:
+		s := make([]string, 0, len(v))
+		for k := range v {
+			s = append(s, k+": "+literal(v[k]))
+		}
+		sort.Strings(s)
+		return "{" + strings.Join(s, ", ") + "}"
+	}
+	return "null"
+}
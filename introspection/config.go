@@ -0,0 +1,93 @@
+package introspection
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/quay/clair/v4/config"
+)
+
+// MaxConfigSize is the largest configuration accepted for validation.
+const maxConfigSize = 1 << 20
+
+// ConfigValidation is the response to a configuration validation request.
+type ConfigValidation struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+	// Changes lists the settings that differ from the running
+	// configuration, as dotted paths, e.g. "matcher.period".
+	Changes []string `json:"changes,omitempty"`
+	// Config is the validated configuration, with defaults applied and
+	// secrets redacted.
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// withConfig adds endpoints for inspecting the running configuration and
+// validating a new one.
+//
+// A GET of ConfigEndpoint reports the configuration the process loaded,
+// including defaults, with secrets redacted. A POST of a YAML configuration
+// to ConfigValidateEndpoint reports whether it's valid and which settings
+// differ from the running configuration. The "mode" parameter selects the
+// mode to validate for, defaulting to the running one.
+func (i *Server) withConfig() {
+	i.HandleFunc(ConfigEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("allow", "GET")
+			http.Error(w, "endpoint only allows GET", http.StatusMethodNotAllowed)
+			return
+		}
+		out, err := config.Redact(&i.conf)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	})
+	i.HandleFunc(ConfigValidateEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("allow", "POST")
+			http.Error(w, "endpoint only allows POST", http.StatusMethodNotAllowed)
+			return
+		}
+		b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigSize))
+		if err != nil {
+			http.Error(w, "failed to read request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		res := ConfigValidation{Valid: true}
+		var conf config.Config
+		if err := yaml.Unmarshal(b, &conf); err != nil {
+			res.Valid, res.Error = false, "failed to decode yaml config: "+err.Error()
+		} else {
+			conf.Mode = r.URL.Query().Get("mode")
+			if conf.Mode == "" {
+				conf.Mode = i.conf.Mode
+			}
+			if err := config.Validate(&conf); err != nil {
+				res.Valid, res.Error = false, err.Error()
+			}
+		}
+		if res.Valid {
+			if res.Changes, err = config.Changes(&i.conf, &conf); err == nil {
+				res.Config, err = config.Redact(&conf)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		zerolog.Ctx(r.Context()).Debug().
+			Str("component", "introspection/Server.withConfig").
+			Bool("valid", res.Valid).
+			Strs("changes", res.Changes).
+			Msg("configuration validated")
+		w.Header().Set("content-type", "application/json")
+		json.NewEncoder(w).Encode(&res)
+	})
+}
@@ -2,7 +2,10 @@ package introspection
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/pprof"
@@ -17,6 +20,7 @@ import (
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"github.com/quay/clair/v4/config"
+	"github.com/quay/clair/v4/logging"
 )
 
 const (
@@ -27,6 +31,9 @@ const (
 	Jaeger                   = "jaeger"
 	DefaultJaegerEndpoint    = "localhost:6831"
 	HealthEndpoint           = "/healthz"
+	LogLevelEndpoint         = "/debug/loglevel"
+	ConfigEndpoint           = "/debug/config"
+	ConfigValidateEndpoint   = "/debug/config/validate"
 	DefaultIntrospectionAddr = ":8089"
 )
 
@@ -65,6 +72,7 @@ func New(ctx context.Context, conf config.Config, health func() bool) (*Server,
 	}
 
 	// check for health
+	i.health = health
 	if health == nil {
 		logger.Warn().Msg("no health check configured; unconditionally reporting OK")
 		i.health = func() bool { return true }
@@ -124,11 +132,20 @@ func New(ctx context.Context, conf config.Config, health func() bool) (*Server,
 		logger.Info().Msg("no distributed tracing enabled")
 	}
 
+	// require client certificates if configured
+	if m := conf.Auth.Admin; m != nil && m.MTLS != nil {
+		if err := i.withMTLS(m.MTLS); err != nil {
+			return nil, fmt.Errorf("error configuring mtls: %v", err)
+		}
+		logger.Info().Msg("client certificates required")
+	}
+
 	// configure diagnostics
 	err := i.withDiagnostics(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error configuring diagnostics: %v", err)
 	}
+	i.withConfig()
 
 	// attach Introspection to server, this works because we embed http.ServeMux
 	i.Server.Handler = i
@@ -156,6 +173,66 @@ func (i *Server) withDiagnostics(_ context.Context) error {
 	return nil
 }
 
+// WithMTLS configures the server to use TLS, requiring clients to present a
+// certificate issued by one of the configured CAs.
+func (i *Server) withMTLS(m *config.AuthMTLS) error {
+	cert, err := tls.LoadX509KeyPair(m.Cert, m.Key)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(m.ClientCA)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return fmt.Errorf("no certificates in %q", m.ClientCA)
+	}
+	i.Server.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	return nil
+}
+
+// WithAuth puts every endpoint but the health check behind the authentication
+// middleware returned by mw, so probes keep working.
+func (i *Server) WithAuth(mw func(http.Handler) (http.Handler, error)) error {
+	h, err := mw(i.ServeMux)
+	if err != nil {
+		return err
+	}
+	i.Server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == HealthEndpoint {
+			i.ServeMux.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+	return nil
+}
+
+// ListenAndServe listens on the configured address, using TLS if client
+// certificates are required.
+func (i *Server) ListenAndServe() error {
+	if i.Server.TLSConfig != nil {
+		return i.Server.ListenAndServeTLS("", "")
+	}
+	return i.Server.ListenAndServe()
+}
+
+// WithLogLevels adds an endpoint for inspecting and changing the log levels.
+//
+// A GET reports the current levels, and a PUT replaces them.
+func (i *Server) WithLogLevels(l *logging.Levels) {
+	if l == nil {
+		return
+	}
+	i.Handle(LogLevelEndpoint, l.Handler())
+}
+
 // withStdOut configures the stdout exporter for distributed tracing
 func (i *Server) withStdOut(_ context.Context, traceOpts []sdktrace.TracerProviderOption) error {
 	exporter, err := stdout.NewExporter()
@@ -0,0 +1,159 @@
+// Package lock provides distributed locks kept in Redis, for deployments
+// where taking Postgres advisory locks is a bottleneck or database
+// connections are scarce.
+//
+// A lock is held for a TTL and refreshed in the background while it's in use,
+// so a process that goes away without releasing its locks only blocks others
+// until the TTL runs out.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+)
+
+// Locker takes named, deployment-wide locks.
+//
+// Both methods return a context derived from the passed one, which is
+// canceled if the lock is lost, and a function releasing the lock. Work done
+// under the lock should use the returned context.
+type Locker interface {
+	// TryLock takes the lock if it's free. If it's not, the returned context
+	// and function are nil.
+	TryLock(ctx context.Context, key string) (context.Context, context.CancelFunc, error)
+	// Lock waits for the lock until the passed context is canceled.
+	Lock(ctx context.Context, key string) (context.Context, context.CancelFunc, error)
+}
+
+// KeyPrefix is prepended to the keys of locks kept in Redis.
+const KeyPrefix = "clair:lock:"
+
+// DefaultRetry is how often Lock tries to take a held lock.
+const DefaultRetry = 500 * time.Millisecond
+
+// Redis is a Locker keeping locks in Redis.
+type Redis struct {
+	c     *redis.Client
+	ttl   time.Duration
+	retry time.Duration
+}
+
+var _ Locker = (*Redis)(nil)
+
+// NewRedis returns a Redis using the server at the URL, e.g.
+// "redis://:password@localhost:6379/0". Locks not refreshed expire after
+// ttl.
+func NewRedis(url string, ttl time.Duration) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &Redis{c: redis.NewClient(opts), ttl: ttl, retry: DefaultRetry}, nil
+}
+
+// Close closes the connection to Redis.
+func (r *Redis) Close() error {
+	return r.c.Close()
+}
+
+// These scripts only touch the lock if it still holds the caller's token, so
+// a lock that expired and was taken by another process is left alone.
+const (
+	refreshScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+	releaseScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+)
+
+// TryLock implements Locker.
+func (r *Redis) TryLock(ctx context.Context, key string) (context.Context, context.CancelFunc, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, nil, err
+	}
+	tok := hex.EncodeToString(b[:])
+	k := KeyPrefix + key
+	ok, err := r.c.SetNX(ctx, k, tok, r.ttl).Result()
+	if err != nil || !ok {
+		return nil, nil, err
+	}
+
+	lctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go r.refresh(lctx, cancel, done, k, tok)
+	release := func() {
+		cancel()
+		<-done
+		// Use a fresh context, as the caller's may be why the lock is being
+		// released.
+		ctx, done := context.WithTimeout(context.Background(), r.ttl)
+		defer done()
+		if err := r.c.Eval(ctx, releaseScript, []string{k}, tok).Err(); err != nil {
+			zerolog.Ctx(lctx).Warn().
+				Str("component", "lock/Redis.release").
+				Str("key", key).
+				Err(err).
+				Msg("failed to release lock; it will expire")
+		}
+	}
+	return lctx, release, nil
+}
+
+// Refresh extends the lock until the context is canceled, and cancels it if
+// the lock is lost.
+func (r *Redis) refresh(ctx context.Context, cancel context.CancelFunc, done chan<- struct{}, k, tok string) {
+	defer close(done)
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "lock/Redis.refresh").
+		Str("key", k).
+		Logger()
+	t := time.NewTicker(r.ttl / 3)
+	defer t.Stop()
+	held := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		n, err := r.c.Eval(ctx, refreshScript, []string{k}, tok, r.ttl.Milliseconds()).Int64()
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil && time.Since(held) < r.ttl:
+			// Transient errors are fine as long as the lock can't have
+			// expired yet.
+			log.Debug().Err(err).Msg("failed to refresh lock")
+		case err != nil:
+			log.Warn().Err(err).Msg("lost lock")
+			cancel()
+			return
+		case n == 0:
+			log.Warn().Msg("lost lock")
+			cancel()
+			return
+		default:
+			held = time.Now()
+		}
+	}
+}
+
+// Lock implements Locker.
+func (r *Redis) Lock(ctx context.Context, key string) (context.Context, context.CancelFunc, error) {
+	t := time.NewTicker(r.retry)
+	defer t.Stop()
+	for {
+		lctx, release, err := r.TryLock(ctx, key)
+		if err != nil || lctx != nil {
+			return lctx, release, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
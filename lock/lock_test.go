@@ -0,0 +1,201 @@
+package lock
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+)
+
+// FakeServer speaks just enough RESP to serve the commands Redis uses:
+// SET with NX, and EVAL of the refresh and release scripts. Expiry isn't
+// modeled; the test drops keys to simulate it.
+type fakeServer struct {
+	l net.Listener
+
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeServer{l: l, keys: make(map[string]string)}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f
+}
+
+func (f *fakeServer) URL() string { return "redis://" + f.l.Addr().String() + "/0" }
+
+func (f *fakeServer) Close() { f.l.Close() }
+
+func (f *fakeServer) Drop(k string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.keys, k)
+}
+
+func (f *fakeServer) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.keys)
+}
+
+func (f *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		switch strings.ToUpper(cmd[0]) {
+		case "PING":
+			fmt.Fprint(c, "+PONG\r\n")
+		case "SET":
+			if _, ok := f.keys[cmd[1]]; ok {
+				fmt.Fprint(c, "$-1\r\n")
+				break
+			}
+			f.keys[cmd[1]] = cmd[2]
+			fmt.Fprint(c, "+OK\r\n")
+		case "EVAL":
+			// EVAL script numkeys key token ...
+			k, tok := cmd[3], cmd[4]
+			if f.keys[k] != tok {
+				fmt.Fprint(c, ":0\r\n")
+				break
+			}
+			if cmd[1] == releaseScript {
+				delete(f.keys, k)
+			}
+			fmt.Fprint(c, ":1\r\n")
+		default:
+			fmt.Fprintf(c, "-ERR unknown command %q\r\n", cmd[0])
+		}
+		f.mu.Unlock()
+	}
+}
+
+// ReadCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, n)
+	for i := range out {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sz, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, sz+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		out[i] = string(b[:sz])
+	}
+	return out, nil
+}
+
+func TestRedis(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv := newFakeServer(t)
+	defer srv.Close()
+	r, err := NewRedis(srv.URL(), 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.retry = 10 * time.Millisecond
+
+	lctx, release, err := r.TryLock(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lctx == nil {
+		t.Fatal("lock not taken")
+	}
+	if c, _, err := r.TryLock(ctx, "a"); err != nil || c != nil {
+		t.Fatalf("held lock taken again: %v", err)
+	}
+
+	// A waiter gets the lock once it's released.
+	got := make(chan error, 1)
+	go func() {
+		c, rel, err := r.Lock(ctx, "a")
+		if err == nil {
+			rel()
+		}
+		if err == nil && c == nil {
+			err = fmt.Errorf("no context returned")
+		}
+		got <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	release()
+	if lctx.Err() == nil {
+		t.Error("lock context not canceled on release")
+	}
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter never took the lock")
+	}
+	if n := srv.Len(); n != 0 {
+		t.Errorf("%d keys left after release", n)
+	}
+
+	// A lost lock cancels its context.
+	lctx, release, err = r.TryLock(ctx, "b")
+	if err != nil || lctx == nil {
+		t.Fatalf("lock not taken: %v", err)
+	}
+	defer release()
+	srv.Drop(KeyPrefix + "b")
+	select {
+	case <-lctx.Done():
+	case <-time.After(time.Second):
+		t.Error("lock context not canceled after the lock was lost")
+	}
+
+	// Waiting gives up with the caller's context.
+	if _, _, err := r.TryLock(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+	wctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := r.Lock(wctx, "c"); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
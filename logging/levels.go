@@ -0,0 +1,209 @@
+// Package logging holds the process-wide log level state, so that levels can
+// be changed while Clair is running.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Levels is a base log level plus overrides for components.
+//
+// A component override applies to every logger whose "component" field has
+// the override's key as a prefix; the longest matching key wins. For
+// example, an override for "notifier/" applies to all notifier loggers.
+type Levels struct {
+	mu         sync.RWMutex
+	base       zerolog.Level
+	components map[string]zerolog.Level
+	keys       []string // component keys, longest first
+}
+
+// State is the serializable form of Levels.
+type State struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+// NewLevels returns Levels with the provided base level and component
+// overrides.
+func NewLevels(base zerolog.Level, components map[string]string) (*Levels, error) {
+	l := &Levels{}
+	cs, err := parseComponents(components)
+	if err != nil {
+		return nil, err
+	}
+	l.set(base, cs)
+	return l, nil
+}
+
+// ParseLevel parses a level name, e.g. "debug". Unlike zerolog.ParseLevel,
+// the empty string is an error.
+func ParseLevel(s string) (zerolog.Level, error) {
+	lvl, err := zerolog.ParseLevel(strings.ToLower(s))
+	if err != nil || s == "" {
+		return zerolog.NoLevel, fmt.Errorf("unknown log level %q", s)
+	}
+	return lvl, nil
+}
+
+func parseComponents(in map[string]string) (map[string]zerolog.Level, error) {
+	out := make(map[string]zerolog.Level, len(in))
+	for k, v := range in {
+		lvl, err := ParseLevel(v)
+		if err != nil {
+			return nil, fmt.Errorf("component %q: %w", k, err)
+		}
+		out[k] = lvl
+	}
+	return out, nil
+}
+
+// Set replaces the base level and component overrides with the ones in the
+// State.
+func (l *Levels) Set(s State) error {
+	base, err := ParseLevel(s.Level)
+	if err != nil {
+		return err
+	}
+	cs, err := parseComponents(s.Components)
+	if err != nil {
+		return err
+	}
+	l.set(base, cs)
+	return nil
+}
+
+func (l *Levels) set(base zerolog.Level, cs map[string]zerolog.Level) {
+	keys := make([]string, 0, len(cs))
+	min := base
+	for k, lvl := range cs {
+		keys = append(keys, k)
+		if lvl < min {
+			min = lvl
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.base, l.components, l.keys = base, cs, keys
+	// The global level gates event creation, so it has to let through the
+	// most verbose level anything wants. The Writer does the rest.
+	zerolog.SetGlobalLevel(min)
+}
+
+// State reports the current levels.
+func (l *Levels) State() State {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	s := State{Level: l.base.String()}
+	if len(l.components) != 0 {
+		s.Components = make(map[string]string, len(l.components))
+		for k, lvl := range l.components {
+			s.Components[k] = lvl.String()
+		}
+	}
+	return s
+}
+
+// Enabled reports whether an event at the level for the component should be
+// written.
+func (l *Levels) Enabled(lvl zerolog.Level, component string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, k := range l.keys {
+		if strings.HasPrefix(component, k) {
+			return lvl >= l.components[k]
+		}
+	}
+	return lvl >= l.base
+}
+
+// Writer returns a zerolog.LevelWriter writing to w, dropping events not
+// enabled for their component.
+func (l *Levels) Writer(w io.Writer) zerolog.LevelWriter {
+	return &writer{l: l, w: w}
+}
+
+type writer struct {
+	l *Levels
+	w io.Writer
+}
+
+var componentKey = []byte(`"component":"`)
+
+// Write implements io.Writer.
+func (w *writer) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// WriteLevel implements zerolog.LevelWriter.
+func (w *writer) WriteLevel(lvl zerolog.Level, p []byte) (int, error) {
+	if lvl != zerolog.NoLevel && !w.l.Enabled(lvl, component(p)) {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+// Component pulls the "component" field out of an encoded event. If the
+// field occurs more than once, the last one wins.
+func component(p []byte) string {
+	i := bytes.LastIndex(p, componentKey)
+	if i == -1 {
+		return ""
+	}
+	p = p[i+len(componentKey):]
+	if i := bytes.IndexByte(p, '"'); i != -1 {
+		return string(p[:i])
+	}
+	return ""
+}
+
+// Handler returns an http.Handler reporting the current levels on GET and
+// replacing them on PUT.
+//
+// A PUT body is a State, e.g.:
+//
+//	{"level":"info","components":{"notifier/":"debug"}}
+func (l *Levels) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var s State
+			if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+				http.Error(w, fmt.Sprintf("failed to deserialize request: %v", err), http.StatusBadRequest)
+				return
+			}
+			if err := l.Set(s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			zerolog.Ctx(r.Context()).Info().
+				Str("component", "logging/Levels.Handler").
+				Str("level", s.Level).
+				Interface("components", s.Components).
+				Msg("log levels changed")
+		default:
+			w.Header().Set("allow", "GET, PUT")
+			http.Error(w, "endpoint only allows GET or PUT", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		json.NewEncoder(w).Encode(l.State())
+	})
+}
@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWriter(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	l, err := NewLevels(zerolog.InfoLevel, map[string]string{
+		"notifier/":         "debug",
+		"notifier/webhook/": "error",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := zerolog.GlobalLevel(), zerolog.DebugLevel; got != want {
+		t.Errorf("global level: got: %v, want: %v", got, want)
+	}
+	var buf bytes.Buffer
+	log := zerolog.New(l.Writer(&buf))
+
+	tt := []struct {
+		component string
+		level     zerolog.Level
+		want      bool
+	}{
+		{"indexer/Controller.Index", zerolog.DebugLevel, false},
+		{"indexer/Controller.Index", zerolog.InfoLevel, true},
+		{"notifier/Processor.create", zerolog.DebugLevel, true},
+		{"notifier/webhook/Deliverer.Deliver", zerolog.WarnLevel, false},
+		{"notifier/webhook/Deliverer.Deliver", zerolog.ErrorLevel, true},
+		{"", zerolog.DebugLevel, false},
+	}
+	for _, tc := range tt {
+		buf.Reset()
+		log.WithLevel(tc.level).Str("component", tc.component).Msg("test")
+		if got := buf.Len() != 0; got != tc.want {
+			t.Errorf("%q at %v: got: %v, want: %v", tc.component, tc.level, got, tc.want)
+		}
+	}
+}
+
+func TestComponent(t *testing.T) {
+	tt := []struct {
+		in   string
+		want string
+	}{
+		{`{"level":"info","message":"x"}`, ""},
+		{`{"component":"a/B.c","message":"x"}`, "a/B.c"},
+		{`{"component":"a","component":"b"}`, "b"},
+		{`{"message":"\"component\":\"x\""}`, ""},
+	}
+	for _, tc := range tt {
+		if got := component([]byte(tc.in)); got != tc.want {
+			t.Errorf("%s: got: %q, want: %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	l, err := NewLevels(zerolog.InfoLevel, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(l.Handler())
+	defer srv.Close()
+
+	put := func(body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := put(`{"level":"warn","components":{"matcher/":"debug"}}`)
+	defer res.Body.Close()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+	var s State
+	if err := json.NewDecoder(res.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Level, "warn"; got != want {
+		t.Errorf("level: got: %q, want: %q", got, want)
+	}
+	if got, want := s.Components["matcher/"], "debug"; got != want {
+		t.Errorf("component: got: %q, want: %q", got, want)
+	}
+	if !l.Enabled(zerolog.DebugLevel, "matcher/Controller.Scan") || l.Enabled(zerolog.InfoLevel, "indexer/") {
+		t.Error("levels not applied")
+	}
+
+	bad := put(`{"level":"loud"}`)
+	bad.Body.Close()
+	if got, want := bad.StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if got, want := l.State().Level, "warn"; got != want {
+		t.Errorf("level changed by bad request: got: %q, want: %q", got, want)
+	}
+}
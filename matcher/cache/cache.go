@@ -0,0 +1,143 @@
+// Package cache caches vulnerability reports, so that repeated requests for
+// the same manifest don't redo the matching.
+//
+// Reports are keyed by the manifest, the contents of its index report, and
+// the latest update operation. Once updaters change the vulnerability
+// database, the latest update operation changes and earlier entries are no
+// longer used.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+// RefInterval is how long the latest update operation is remembered before
+// being checked again, bounding how stale a cached report can be after an
+// update.
+const RefInterval = 10 * time.Second
+
+// Matcher wraps a matcher.Service and caches the reports it generates.
+type Matcher struct {
+	matcher.Service
+	store Store
+
+	mu      sync.Mutex
+	ref     uuid.UUID
+	checked time.Time
+
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+}
+
+var _ matcher.Service = (*Matcher)(nil)
+
+// NewMatcher returns a Matcher caching reports in the Store.
+func NewMatcher(s matcher.Service, store Store) *Matcher {
+	meter := metric.Must(otel.Meter("clair"))
+	return &Matcher{
+		Service: s,
+		store:   store,
+		hits: meter.NewInt64Counter(
+			"clair_matcher_report_cache_hits_total",
+			metric.WithDescription("number of vulnerability reports served from the cache"),
+		),
+		misses: meter.NewInt64Counter(
+			"clair_matcher_report_cache_misses_total",
+			metric.WithDescription("number of vulnerability reports generated on a cache miss"),
+		),
+	}
+}
+
+// Unwrap returns the wrapped matcher.Service.
+func (m *Matcher) Unwrap() matcher.Service {
+	return m.Service
+}
+
+// Scan implements matcher.Scanner.
+//
+// Cache errors are logged and otherwise ignored.
+func (m *Matcher) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "matcher/cache/Matcher.Scan").
+		Str("manifest", ir.Hash.String()).
+		Logger()
+	key, err := m.key(ctx, ir)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to compute cache key")
+		return m.Service.Scan(ctx, ir)
+	}
+
+	b, ok, err := m.store.Get(ctx, key)
+	switch {
+	case err != nil:
+		log.Warn().Err(err).Msg("failed to read report cache")
+	case ok:
+		var vr claircore.VulnerabilityReport
+		if err := json.Unmarshal(b, &vr); err == nil {
+			m.hits.Add(ctx, 1)
+			return &vr, nil
+		}
+		log.Warn().Err(err).Msg("discarding malformed cache entry")
+	}
+	m.misses.Add(ctx, 1)
+
+	vr, err := m.Service.Scan(ctx, ir)
+	if err != nil {
+		return nil, err
+	}
+	if b, err := json.Marshal(vr); err == nil {
+		if err := m.store.Set(ctx, key, b); err != nil {
+			log.Warn().Err(err).Msg("failed to write report cache")
+		}
+	}
+	return vr, nil
+}
+
+// Key returns the cache key for the index report.
+func (m *Matcher) key(ctx context.Context, ir *claircore.IndexReport) (string, error) {
+	ref, err := m.latest(ctx)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(ir); err != nil {
+		return "", err
+	}
+	return ir.Hash.String() + ":" + ref.String() + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Latest returns the latest update operation, checking at most once per
+// RefInterval.
+func (m *Matcher) latest(ctx context.Context) (uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Since(m.checked) < RefInterval {
+		return m.ref, nil
+	}
+	ref, err := m.Service.LatestUpdateOperation(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	m.ref, m.checked = ref, time.Now()
+	return ref, nil
+}
+
+// SetLatest records ref as the latest update operation, as if just checked.
+func (m *Matcher) setLatest(ref uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ref, m.checked = ref, time.Now()
+}
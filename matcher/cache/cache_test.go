@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+// TestMatcher checks that reports are reused until the vulnerability
+// database changes.
+func TestMatcher(t *testing.T) {
+	ctx := context.Background()
+	ref := uuid.New()
+	scans := 0
+	mock := &matcher.Mock{
+		LatestUpdateOperation_: func(context.Context) (uuid.UUID, error) {
+			return ref, nil
+		},
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			scans++
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+	}
+	m := NewMatcher(mock, NewMemory(10, time.Hour))
+	d, err := claircore.ParseDigest("sha256:" + fmt.Sprintf("%064x", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir := &claircore.IndexReport{Hash: d, State: "IndexFinished", Success: true}
+
+	for i := 0; i < 3; i++ {
+		vr, err := m.Scan(ctx, ir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := vr.Hash.String(), d.String(); got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+	if got, want := scans, 1; got != want {
+		t.Errorf("got: %d scans, want: %d", got, want)
+	}
+
+	// A new update operation invalidates the entry, once noticed.
+	ref = uuid.New()
+	m.checked = time.Time{}
+	if _, err := m.Scan(ctx, ir); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := scans, 2; got != want {
+		t.Errorf("got: %d scans, want: %d", got, want)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2, time.Hour)
+	for _, k := range []string{"a", "b", "a", "c"} {
+		if err := m.Set(ctx, k, []byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := m.Len(), 2; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if _, ok, _ := m.Get(ctx, "b"); ok {
+		t.Error("least recently used entry not evicted")
+	}
+	if v, ok, _ := m.Get(ctx, "a"); !ok || string(v) != "a" {
+		t.Errorf("got: %q, %v", v, ok)
+	}
+
+	m = NewMemory(2, -time.Second)
+	m.Set(ctx, "a", []byte("a"))
+	if _, ok, _ := m.Get(ctx, "a"); ok {
+		t.Error("expired entry returned")
+	}
+}
+
+// TestWarmer checks that recently requested reports are regenerated once the
+// vulnerability database changes.
+func TestWarmer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	refs := make(chan uuid.UUID, 1)
+	polled := make(chan struct{}, 1)
+	var mu sync.Mutex
+	ref := uuid.New()
+	scanned := make(chan string, 10)
+	mock := &matcher.Mock{
+		LatestUpdateOperation_: func(context.Context) (uuid.UUID, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			select {
+			case ref = <-refs:
+			default:
+			}
+			select {
+			case polled <- struct{}{}:
+			default:
+			}
+			return ref, nil
+		},
+		Scan_: func(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			scanned <- ir.Hash.String()
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+	}
+	w := NewWarmer(ctx, NewMatcher(mock, NewMemory(10, time.Hour)), 2, 10*time.Millisecond)
+	// Wait for the Warmer to see the initial update operation.
+	<-polled
+
+	var want []string
+	for i := 1; i <= 3; i++ {
+		d, err := claircore.ParseDigest("sha256:" + fmt.Sprintf("%064x", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Scan(ctx, &claircore.IndexReport{Hash: d}); err != nil {
+			t.Fatal(err)
+		}
+		<-scanned
+		want = append([]string{d.String()}, want...)
+	}
+	// Only the two most recent manifests are remembered.
+	want = want[:2]
+
+	refs <- uuid.New()
+	for _, w := range want {
+		select {
+		case got := <-scanned:
+			if got != w {
+				t.Errorf("got: %q, want: %q", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for regeneration")
+		}
+	}
+	// The regenerated reports are served from the cache.
+	if _, err := w.Scan(ctx, &claircore.IndexReport{Hash: claircore.MustParseDigest(want[0])}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-scanned:
+		t.Errorf("unexpected scan of %q", got)
+	default:
+	}
+}
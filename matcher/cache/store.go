@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Store holds encoded reports.
+type Store interface {
+	// Get returns the value stored for key, if any.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value for key.
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// Memory is an in-process Store, evicting the least recently used entries
+// once full.
+type Memory struct {
+	size int
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory returns a Memory holding up to size entries, each for at most
+// ttl.
+func NewMemory(size int, ttl time.Duration) *Memory {
+	return &Memory{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get implements Store.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*entry)
+	if time.Now().After(e.expires) {
+		m.ll.Remove(el)
+		delete(m.items, key)
+		return nil, false, nil
+	}
+	m.ll.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Set implements Store.
+func (m *Memory) Set(_ context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	exp := time.Now().Add(m.ttl)
+	if el, ok := m.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value, e.expires = value, exp
+		m.ll.MoveToFront(el)
+		return nil
+	}
+	m.items[key] = m.ll.PushFront(&entry{key: key, value: value, expires: exp})
+	for m.ll.Len() > m.size {
+		el := m.ll.Back()
+		m.ll.Remove(el)
+		delete(m.items, el.Value.(*entry).key)
+	}
+	return nil
+}
+
+// Len reports the number of entries held.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+// Redis is a Store kept in Redis, so that it's shared between matchers.
+type Redis struct {
+	c   *redis.Client
+	ttl time.Duration
+}
+
+var _ Store = (*Redis)(nil)
+
+// KeyPrefix is prepended to keys stored in Redis.
+const KeyPrefix = "clair:report:"
+
+// NewRedis returns a Redis using the server at the URL, e.g.
+// "redis://:password@localhost:6379/0". Entries expire after ttl.
+func NewRedis(url string, ttl time.Duration) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &Redis{c: redis.NewClient(opts), ttl: ttl}, nil
+}
+
+// Get implements Store.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := r.c.Get(ctx, KeyPrefix+key).Bytes()
+	switch {
+	case err == redis.Nil:
+		return nil, false, nil
+	case err != nil:
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Set implements Store.
+func (r *Redis) Set(ctx context.Context, key string, value []byte) error {
+	return r.c.Set(ctx, KeyPrefix+key, value, r.ttl).Err()
+}
+
+// Close closes the connection to Redis.
+func (r *Redis) Close() error {
+	return r.c.Close()
+}
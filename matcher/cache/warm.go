@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+// WarmInterval is how often a Warmer checks for a new update operation.
+const WarmInterval = time.Minute
+
+// Warmer wraps a Matcher and regenerates the reports of the most recently
+// requested manifests whenever the vulnerability database is updated, so the
+// first request after an update is served from the cache.
+type Warmer struct {
+	*Matcher
+	size int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+
+	warmed metric.Int64Counter
+}
+
+var _ matcher.Service = (*Warmer)(nil)
+
+// NewWarmer returns a Warmer remembering the index reports of the size most
+// recently requested manifests. It checks for a new update operation every
+// interval until the context is canceled.
+func NewWarmer(ctx context.Context, m *Matcher, size int, interval time.Duration) *Warmer {
+	meter := metric.Must(otel.Meter("clair"))
+	w := &Warmer{
+		Matcher: m,
+		size:    size,
+		ll:      list.New(),
+		items:   make(map[string]*list.Element),
+		warmed: meter.NewInt64Counter(
+			"clair_matcher_report_precomputed_total",
+			metric.WithDescription("number of vulnerability reports regenerated after an update"),
+		),
+	}
+	go w.loop(ctx, interval)
+	return w
+}
+
+// Unwrap returns the wrapped Matcher.
+func (w *Warmer) Unwrap() matcher.Service {
+	return w.Matcher
+}
+
+// Scan implements matcher.Scanner.
+func (w *Warmer) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	vr, err := w.Matcher.Scan(ctx, ir)
+	if err != nil {
+		return nil, err
+	}
+	w.remember(ir)
+	return vr, nil
+}
+
+// Remember makes the index report the most recently requested.
+func (w *Warmer) remember(ir *claircore.IndexReport) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	k := ir.Hash.String()
+	if el, ok := w.items[k]; ok {
+		el.Value = ir
+		w.ll.MoveToFront(el)
+		return
+	}
+	w.items[k] = w.ll.PushFront(ir)
+	for w.ll.Len() > w.size {
+		el := w.ll.Back()
+		w.ll.Remove(el)
+		delete(w.items, el.Value.(*claircore.IndexReport).Hash.String())
+	}
+}
+
+// Recent returns the remembered index reports, most recent first.
+func (w *Warmer) recent() []*claircore.IndexReport {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]*claircore.IndexReport, 0, w.ll.Len())
+	for el := w.ll.Front(); el != nil; el = el.Next() {
+		out = append(out, el.Value.(*claircore.IndexReport))
+	}
+	return out
+}
+
+// Loop regenerates reports each time the latest update operation changes.
+// The update operation current at startup isn't treated as a change.
+func (w *Warmer) loop(ctx context.Context, interval time.Duration) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "matcher/cache/Warmer.loop").
+		Logger()
+	ctx = log.WithContext(ctx)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	var last uuid.UUID
+	for {
+		ref, err := w.Matcher.Service.LatestUpdateOperation(ctx)
+		switch {
+		case err != nil:
+			log.Warn().Err(err).Msg("unable to check latest update operation")
+		case last == uuid.Nil:
+			last = ref
+		case ref != last:
+			last = ref
+			w.warm(ctx, ref)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Warm regenerates the reports of the remembered manifests as of the update
+// operation.
+func (w *Warmer) warm(ctx context.Context, ref uuid.UUID) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "matcher/cache/Warmer.warm").
+		Stringer("update_operation", ref).
+		Logger()
+	// Key new entries by the update operation just seen, rather than
+	// whatever the Matcher last remembered.
+	w.Matcher.setLatest(ref)
+	irs := w.recent()
+	log.Info().Int("count", len(irs)).Msg("regenerating recent reports")
+	n := 0
+	for _, ir := range irs {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := w.Matcher.Scan(ctx, ir); err != nil {
+			log.Warn().Err(err).
+				Str("manifest", ir.Hash.String()).
+				Msg("failed to regenerate report")
+			continue
+		}
+		w.warmed.Add(ctx, 1)
+		n++
+	}
+	log.Info().Int("count", n).Msg("regenerated recent reports")
+}
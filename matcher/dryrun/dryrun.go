@@ -0,0 +1,239 @@
+package dryrun
+
+import (
+	"context"
+	"sort"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/rs/zerolog"
+)
+
+// Runner matches index reports against snapshots the way the matcher
+// matches them against its database.
+type Runner struct {
+	matchers []driver.Matcher
+}
+
+// NewRunner returns a Runner using the provided matchers, which should be the
+// ones the matcher is configured with.
+//
+// Remote matchers don't use the database, so they're skipped.
+func NewRunner(ms []driver.Matcher) *Runner {
+	r := &Runner{}
+	for _, m := range ms {
+		if _, ok := m.(driver.RemoteMatcher); ok {
+			continue
+		}
+		r.matchers = append(r.matchers, m)
+	}
+	return r
+}
+
+// Match returns the snapshot's vulnerabilities affecting the report's
+// packages, keyed by package ID.
+func (r *Runner) Match(ctx context.Context, s *Snapshot, ir *claircore.IndexReport) (map[string][]*claircore.Vulnerability, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "matcher/dryrun/Runner.Match").
+		Str("manifest", ir.Hash.String()).
+		Logger()
+	out := make(map[string][]*claircore.Vulnerability)
+	records := ir.IndexRecords()
+	for _, m := range r.matchers {
+		dbSide, authoritative := false, false
+		if f, ok := m.(driver.VersionFilter); ok {
+			dbSide, authoritative = true, f.VersionAuthoritative()
+		}
+		cs := m.Query()
+		n := 0
+		for _, rec := range records {
+			if rec.Package == nil || !m.Filter(rec) {
+				continue
+			}
+			for _, v := range s.candidates(rec) {
+				if !constrained(cs, rec, v) {
+					continue
+				}
+				if dbSide && !inRange(rec, v) {
+					continue
+				}
+				if !authoritative {
+					ok, err := m.Vulnerable(ctx, rec, v)
+					if err != nil {
+						return nil, err
+					}
+					if !ok {
+						continue
+					}
+				}
+				out[rec.Package.ID] = append(out[rec.Package.ID], v)
+				n++
+			}
+		}
+		log.Debug().
+			Str("matcher", m.Name()).
+			Int("matched", n).
+			Msg("matched snapshot")
+	}
+	return out, nil
+}
+
+// Constrained reports whether the vulnerability satisfies the matcher's
+// constraints for the record.
+func constrained(cs []driver.MatchConstraint, rec *claircore.IndexRecord, v *claircore.Vulnerability) bool {
+	d, vd := rec.Distribution, v.Dist
+	if d == nil {
+		d = &claircore.Distribution{}
+	}
+	if vd == nil {
+		vd = &claircore.Distribution{}
+	}
+	for _, c := range cs {
+		var ok bool
+		switch c {
+		case driver.PackageName, driver.PackageSourceName:
+			// Already narrowed down by Snapshot.candidates.
+			ok = true
+		case driver.PackageModule:
+			ok = rec.Package.Module == v.Package.Module
+		case driver.DistributionDID:
+			ok = d.DID == vd.DID
+		case driver.DistributionName:
+			ok = d.Name == vd.Name
+		case driver.DistributionVersionID:
+			ok = d.VersionID == vd.VersionID
+		case driver.DistributionVersion:
+			ok = d.Version == vd.Version
+		case driver.DistributionVersionCodeName:
+			ok = d.VersionCodeName == vd.VersionCodeName
+		case driver.DistributionPrettyName:
+			ok = d.PrettyName == vd.PrettyName
+		case driver.DistributionCPE:
+			ok = d.CPE.String() == vd.CPE.String()
+		case driver.DistributionArch:
+			ok = d.Arch == vd.Arch
+		case driver.RepositoryName:
+			var name, vname string
+			if rec.Repository != nil {
+				name = rec.Repository.Name
+			}
+			if v.Repo != nil {
+				vname = v.Repo.Name
+			}
+			ok = name == vname
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// InRange does the version filtering the database does for matchers that
+// opt in to it.
+func inRange(rec *claircore.IndexRecord, v *claircore.Vulnerability) bool {
+	nv := &rec.Package.NormalizedVersion
+	return v.Range != nil && v.Range.Lower.Kind == nv.Kind && v.Range.Contains(nv)
+}
+
+// Finding is a vulnerability affecting a package.
+type Finding struct {
+	PackageID     string                   `json:"package_id"`
+	Package       *claircore.Package       `json:"package"`
+	Vulnerability *claircore.Vulnerability `json:"vulnerability"`
+}
+
+// Diff is how a manifest's vulnerability report changes with a snapshot.
+type Diff struct {
+	Manifest claircore.Digest `json:"manifest_hash"`
+	// Added are findings only present with the snapshot.
+	Added []Finding `json:"added"`
+	// Removed are findings only present without the snapshot.
+	Removed []Finding `json:"removed"`
+	// Unchanged is the number of findings from the snapshot's updaters
+	// present either way.
+	Unchanged int `json:"unchanged"`
+}
+
+// Run matches the index report against the snapshot and compares the
+// result with the report's current vulnerability report.
+func (r *Runner) Run(ctx context.Context, s *Snapshot, ir *claircore.IndexReport, cur *claircore.VulnerabilityReport) (*Diff, error) {
+	matched, err := r.Match(ctx, s, ir)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(s, ir, cur, matched), nil
+}
+
+// Compare reports the difference between the findings in the current
+// vulnerability report from the snapshot's updaters and the findings matched
+// against the snapshot.
+//
+// Findings are compared by package, updater, vulnerability name and fixed
+// version, as the snapshot's vulnerabilities have no database IDs. A changed
+// fixed version is reported as a removal and an addition.
+func Compare(s *Snapshot, ir *claircore.IndexReport, cur *claircore.VulnerabilityReport, matched map[string][]*claircore.Vulnerability) *Diff {
+	type key struct {
+		pkg, updater, name, pkgname, fixed string
+	}
+	mk := func(id string, v *claircore.Vulnerability) key {
+		k := key{pkg: id, updater: v.Updater, name: v.Name, fixed: v.FixedInVersion}
+		if v.Package != nil {
+			k.pkgname = v.Package.Name
+		}
+		return k
+	}
+	before := make(map[key]*claircore.Vulnerability)
+	if cur != nil {
+		for id, vids := range cur.PackageVulnerabilities {
+			for _, vid := range vids {
+				v, ok := cur.Vulnerabilities[vid]
+				if !ok || !s.Replaces(v.Updater) {
+					continue
+				}
+				before[mk(id, v)] = v
+			}
+		}
+	}
+	after := make(map[key]*claircore.Vulnerability)
+	for id, vs := range matched {
+		for _, v := range vs {
+			after[mk(id, v)] = v
+		}
+	}
+
+	d := &Diff{
+		Manifest: ir.Hash,
+		Added:    []Finding{},
+		Removed:  []Finding{},
+	}
+	for k, v := range after {
+		if _, ok := before[k]; ok {
+			d.Unchanged++
+			continue
+		}
+		d.Added = append(d.Added, Finding{PackageID: k.pkg, Package: ir.Packages[k.pkg], Vulnerability: v})
+	}
+	for k, v := range before {
+		if _, ok := after[k]; ok {
+			continue
+		}
+		d.Removed = append(d.Removed, Finding{PackageID: k.pkg, Package: ir.Packages[k.pkg], Vulnerability: v})
+	}
+	sortFindings(d.Added)
+	sortFindings(d.Removed)
+	return d
+}
+
+func sortFindings(fs []Finding) {
+	sort.Slice(fs, func(i, j int) bool {
+		a, b := fs[i], fs[j]
+		if a.PackageID != b.PackageID {
+			return a.PackageID < b.PackageID
+		}
+		if a.Vulnerability.Name != b.Vulnerability.Name {
+			return a.Vulnerability.Name < b.Vulnerability.Name
+		}
+		return a.Vulnerability.Updater < b.Vulnerability.Updater
+	})
+}
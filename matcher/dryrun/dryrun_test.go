@@ -0,0 +1,123 @@
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/debian"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/zlog"
+)
+
+var buster = &claircore.Distribution{
+	ID:        "1",
+	DID:       "debian",
+	Name:      "Debian GNU/Linux",
+	Version:   "10 (buster)",
+	VersionID: "10",
+}
+
+func testReport() *claircore.IndexReport {
+	return &claircore.IndexReport{
+		Hash: claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64)),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1d-0+deb10u3", Kind: claircore.BINARY},
+			"2": {ID: "2", Name: "libc6", Version: "2.28-10", Kind: claircore.BINARY,
+				Source: &claircore.Package{Name: "glibc", Version: "2.28-10", Kind: claircore.SOURCE}},
+		},
+		Distributions: map[string]*claircore.Distribution{"1": buster},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{DistributionID: "1"}},
+			"2": {{DistributionID: "1"}},
+		},
+	}
+}
+
+func vuln(updater, name, pkg, fixed string) *claircore.Vulnerability {
+	d := *buster
+	d.ID = ""
+	return &claircore.Vulnerability{
+		Updater:        updater,
+		Name:           name,
+		Package:        &claircore.Package{Name: pkg, Kind: claircore.BINARY},
+		Dist:           &d,
+		FixedInVersion: fixed,
+	}
+}
+
+func load(t *testing.T, vs ...*claircore.Vulnerability) *Snapshot {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	for _, v := range vs {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := Load(strings.NewReader(b.String()), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLoad(t *testing.T) {
+	s := load(t, vuln("debian-buster", "CVE-1", "openssl", ""), vuln("other", "CVE-2", "zlib", ""))
+	if got, want := s.Len(), 2; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if got := s.Updaters(); len(got) != 2 || got[0] != "debian-buster" || got[1] != "other" {
+		t.Errorf("unexpected updaters: %v", got)
+	}
+
+	if _, err := Load(strings.NewReader(`{"name":"CVE-1","package":{"name":"openssl"}}`), 0); err == nil {
+		t.Error("loaded vulnerability without an updater")
+	}
+	in := strings.Repeat(`{"updater":"u","package":{"name":"p"}}`+"\n", 3)
+	if _, err := Load(strings.NewReader(in), 2); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got: %v, want: %v", err, ErrTooLarge)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := testReport()
+	r := NewRunner([]driver.Matcher{&debian.Matcher{}})
+
+	// The current report has an openssl vulnerability from the replaced
+	// updater that the snapshot drops, and one from another updater that
+	// must be left alone.
+	cur := &claircore.VulnerabilityReport{
+		Hash: ir.Hash,
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"10": vuln("debian-buster", "CVE-OLD", "openssl", ""),
+			"11": vuln("osv", "CVE-OTHER", "openssl", ""),
+			"12": vuln("debian-buster", "CVE-KEPT", "openssl", "1.1.1g-1"),
+		},
+		PackageVulnerabilities: map[string][]string{"1": {"10", "11", "12"}},
+	}
+	src := vuln("debian-buster", "CVE-NEW", "glibc", "0")
+	src.Package.Kind = claircore.SOURCE
+	s := load(t,
+		vuln("debian-buster", "CVE-KEPT", "openssl", "1.1.1g-1"),
+		src,
+		// Fixed in an earlier version, so not a finding.
+		vuln("debian-buster", "CVE-FIXED", "openssl", "1.1.1a-1"),
+	)
+	d, err := r.Run(ctx, s, ir, cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Added) != 1 || d.Added[0].PackageID != "2" || d.Added[0].Vulnerability.Name != "CVE-NEW" {
+		t.Errorf("unexpected additions: %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].PackageID != "1" || d.Removed[0].Vulnerability.Name != "CVE-OLD" {
+		t.Errorf("unexpected removals: %+v", d.Removed)
+	}
+	if got, want := d.Unchanged, 1; got != want {
+		t.Errorf("unchanged: got: %d, want: %d", got, want)
+	}
+}
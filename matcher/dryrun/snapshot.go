@@ -0,0 +1,116 @@
+// Package dryrun matches manifests against a candidate snapshot of
+// vulnerability data, without writing it to the matcher's database, and
+// reports how the results would change.
+//
+// This is for checking what a new or modified updater would do before
+// letting it loose on the production database.
+package dryrun
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/quay/claircore"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// ErrTooLarge is returned by Load for snapshots with more than the allowed
+// number of vulnerabilities.
+var ErrTooLarge = clairerror.New(clairerror.TooLarge, "dryrun: snapshot too large")
+
+// Snapshot is a candidate set of vulnerabilities staged in memory.
+//
+// A Snapshot takes the place of everything in the database from the updaters
+// it has vulnerabilities for. Other updaters' vulnerabilities are left as
+// they are.
+type Snapshot struct {
+	updaters map[string]struct{}
+	// Byname indexes the vulnerabilities by package name and kind.
+	byname map[pkgKey][]*claircore.Vulnerability
+	count  int
+}
+
+type pkgKey struct {
+	name, kind string
+}
+
+// Load reads a snapshot of newline-delimited JSON vulnerabilities, the
+// format served by the updates export endpoint. Vulnerabilities without an
+// updater or a package name are rejected.
+//
+// If max is positive, snapshots with more vulnerabilities than that report
+// ErrTooLarge.
+func Load(r io.Reader, max int) (*Snapshot, error) {
+	s := &Snapshot{
+		updaters: make(map[string]struct{}),
+		byname:   make(map[pkgKey][]*claircore.Vulnerability),
+	}
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var v claircore.Vulnerability
+		err := dec.Decode(&v)
+		switch {
+		case errors.Is(err, io.EOF):
+			return s, nil
+		case err != nil:
+			return nil, fmt.Errorf("dryrun: malformed vulnerability %d: %w", s.count+1, err)
+		}
+		s.count++
+		if max > 0 && s.count > max {
+			return nil, ErrTooLarge
+		}
+		if v.Updater == "" {
+			return nil, fmt.Errorf("dryrun: vulnerability %d: missing updater", s.count)
+		}
+		if v.Package == nil || v.Package.Name == "" {
+			return nil, fmt.Errorf("dryrun: vulnerability %d: missing package name", s.count)
+		}
+		// Snapshot vulnerabilities need IDs distinct from each other, but
+		// not from the database's: they're never in the same report.
+		v.ID = fmt.Sprintf("dryrun-%d", s.count)
+		s.updaters[v.Updater] = struct{}{}
+		k := pkgKey{name: v.Package.Name, kind: v.Package.Kind}
+		s.byname[k] = append(s.byname[k], &v)
+	}
+}
+
+// Len reports the number of vulnerabilities in the snapshot.
+func (s *Snapshot) Len() int {
+	return s.count
+}
+
+// Updaters reports the updaters the snapshot replaces, sorted.
+func (s *Snapshot) Updaters() []string {
+	out := make([]string, 0, len(s.updaters))
+	for u := range s.updaters {
+		out = append(out, u)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Replaces reports whether vulnerabilities from the named updater are
+// replaced by the snapshot.
+func (s *Snapshot) Replaces(updater string) bool {
+	_, ok := s.updaters[updater]
+	return ok
+}
+
+// Candidates returns the vulnerabilities for the record's package or its
+// source package, the same way the database is first narrowed down.
+func (s *Snapshot) candidates(r *claircore.IndexRecord) []*claircore.Vulnerability {
+	p := r.Package
+	out := s.byname[pkgKey{name: p.Name, kind: p.Kind}]
+	if p.Source != nil && p.Source.Name != "" {
+		src := s.byname[pkgKey{name: p.Source.Name, kind: p.Source.Kind}]
+		if len(src) != 0 {
+			out = append(append(make([]*claircore.Vulnerability, 0, len(out)+len(src)), out...), src...)
+		}
+	}
+	return out
+}
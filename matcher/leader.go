@@ -0,0 +1,239 @@
+package matcher
+
+import (
+	"context"
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// Updater is the interface for running vulnerability database updates.
+//
+// Claircore's Libvuln implements this.
+type Updater interface {
+	FetchUpdates(context.Context) error
+}
+
+// DefaultLeaderRetry is how often a Leader that does not hold the lock tries
+// to acquire it.
+const DefaultLeaderRetry = time.Minute
+
+// LeaderKey names the lock guarding the update loop.
+const leaderKey = "clair-matcher-updaters"
+
+// LeaderLock is the lock Leaders contend for.
+//
+// The lock package's Lockers implement this.
+type LeaderLock interface {
+	// TryLock takes the lock if it's free, returning a context that's
+	// canceled if the lock is lost and a function releasing the lock. If the
+	// lock is held elsewhere, both are nil.
+	TryLock(ctx context.Context, key string) (context.Context, context.CancelFunc, error)
+}
+
+// Leader runs updates on an interval, but only while holding a lock. When
+// several matchers share a database, exactly one of them runs updaters and
+// the rest only serve requests.
+//
+// If the leader goes away, its lock is freed, so another process takes over
+// within the retry period.
+type Leader struct {
+	lock     LeaderLock
+	u        Updater
+	interval time.Duration
+	retry    time.Duration
+
+	drainOnce sync.Once
+	drain     chan struct{}
+}
+
+// NewLeader returns a Leader running u every interval, contending for a
+// session-level advisory lock. The pool should point at the same database the
+// Updater writes to.
+func NewLeader(pool *pgxpool.Pool, u Updater, interval, retry time.Duration) *Leader {
+	return NewLeaderWithLock(&pgLock{pool: pool}, u, interval, retry)
+}
+
+// NewLeaderWithLock returns a Leader running u every interval, contending for
+// the provided lock.
+func NewLeaderWithLock(lock LeaderLock, u Updater, interval, retry time.Duration) *Leader {
+	if retry <= 0 {
+		retry = DefaultLeaderRetry
+	}
+	return &Leader{
+		lock:     lock,
+		u:        u,
+		interval: interval,
+		retry:    retry,
+		drain:    make(chan struct{}),
+	}
+}
+
+// NewLocalLeader returns a Leader running u every interval without contending
+// for a lock, for a matcher that runs updaters regardless of other processes.
+func NewLocalLeader(u Updater, interval time.Duration) *Leader {
+	return NewLeaderWithLock(localLock{}, u, interval, 0)
+}
+
+// Drain makes Run return once the update run in progress, if any, is done,
+// instead of starting another one. The run still stops early if Run's
+// context is canceled.
+func (l *Leader) Drain() {
+	l.drainOnce.Do(func() { close(l.drain) })
+}
+
+// Run contends for leadership and runs updates while elected.
+//
+// Run blocks until the provided context is canceled, or until Drain is
+// called and the update run in progress is done.
+func (l *Leader) Run(ctx context.Context) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "matcher/Leader.Run").
+		Logger()
+	ctx = log.WithContext(ctx)
+
+	t := time.NewTicker(l.retry)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.drain:
+			return nil
+		default:
+		}
+		ok, err := l.lead(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			log.Warn().Err(err).Msg("leader election failed")
+		case !ok:
+			log.Debug().Msg("another process is running updaters")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.drain:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Lead attempts to take the lock and, if successful, runs updates until the
+// context is canceled or the lock is lost.
+//
+// The reported bool is whether the lock was taken.
+func (l *Leader) lead(ctx context.Context) (bool, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "matcher/Leader.lead").
+		Logger()
+	lctx, release, err := l.lock.TryLock(ctx, leaderKey)
+	if err != nil {
+		return false, err
+	}
+	if lctx == nil {
+		return false, nil
+	}
+	defer release()
+	log.Info().Msg("elected leader; running updaters")
+
+	t := time.NewTicker(l.interval)
+	defer t.Stop()
+	for {
+		if lctx.Err() != nil {
+			if ctx.Err() == nil {
+				log.Warn().Msg("lost leadership")
+			}
+			return true, nil
+		}
+		select {
+		case <-l.drain:
+			// Releasing the lock lets another process take over.
+			log.Info().Msg("draining; giving up leadership")
+			return true, nil
+		default:
+		}
+		if err := l.u.FetchUpdates(lctx); err != nil {
+			log.Error().Err(err).Msg("error running updaters")
+		}
+		select {
+		case <-lctx.Done():
+		case <-l.drain:
+		case <-t.C:
+		}
+	}
+}
+
+// LocalLock is a LeaderLock that's always free.
+type localLock struct{}
+
+// TryLock implements LeaderLock.
+func (localLock) TryLock(ctx context.Context, _ string) (context.Context, context.CancelFunc, error) {
+	lctx, cancel := context.WithCancel(ctx)
+	return lctx, cancel, nil
+}
+
+// PgLockCheck is how often a held advisory lock's session is checked.
+const pgLockCheck = 10 * time.Second
+
+// PgLock is a LeaderLock using session-level advisory locks.
+type pgLock struct {
+	pool *pgxpool.Pool
+}
+
+// TryLock implements LeaderLock.
+func (p *pgLock) TryLock(ctx context.Context, key string) (context.Context, context.CancelFunc, error) {
+	h := fnv.New64a()
+	io.WriteString(h, key)
+	id := int64(h.Sum64())
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The lock is tied to the session, so the connection must not go back
+	// into the pool while it's held. Closing it is the simplest way to drop
+	// the lock in every case.
+	drop := func() {
+		conn.Conn().Close(context.Background())
+		conn.Release()
+	}
+	var ok bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1);`, id).Scan(&ok); err != nil {
+		drop()
+		return nil, nil, err
+	}
+	if !ok {
+		drop()
+		return nil, nil, nil
+	}
+
+	lctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(pgLockCheck)
+		defer t.Stop()
+		for {
+			select {
+			case <-lctx.Done():
+				return
+			case <-t.C:
+			}
+			// Make sure the session, and with it the lock, is still around.
+			if _, err := conn.Exec(lctx, `SELECT 1;`); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+	return lctx, func() {
+		cancel()
+		<-done
+		drop()
+	}, nil
+}
@@ -0,0 +1,121 @@
+package matcher
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore/test/integration"
+	"github.com/quay/zlog"
+)
+
+type countUpdater struct {
+	n int64
+}
+
+func (c *countUpdater) FetchUpdates(_ context.Context) error {
+	atomic.AddInt64(&c.n, 1)
+	return nil
+}
+
+func (c *countUpdater) Count() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+// TestLeader checks that only one of two Leaders runs updates, and that the
+// second takes over once the first goes away.
+func TestLeader(t *testing.T) {
+	integration.Skip(t)
+	ctx := zlog.Test(context.Background(), t)
+	if os.Getenv(integration.EnvPGConnString) == "" {
+		os.Setenv(integration.EnvPGConnString, `host=localhost port=5432 user=clair dbname=clair sslmode=disable`)
+	}
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close(ctx, t)
+	pool, err := pgxpool.ConnectConfig(ctx, db.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	const (
+		interval = 50 * time.Millisecond
+		retry    = 100 * time.Millisecond
+	)
+	var a, b countUpdater
+	actx, acancel := context.WithCancel(ctx)
+	defer acancel()
+	bctx, bcancel := context.WithCancel(ctx)
+	defer bcancel()
+
+	go NewLeader(pool, &a, interval, retry).Run(actx)
+	// Give the first Leader a head start so it wins the election.
+	time.Sleep(retry)
+	go NewLeader(pool, &b, interval, retry).Run(bctx)
+	time.Sleep(5 * retry)
+	if got := a.Count(); got == 0 {
+		t.Error("first leader never ran updates")
+	}
+	if got := b.Count(); got != 0 {
+		t.Errorf("second leader ran updates %d times while not elected", got)
+	}
+
+	acancel()
+	time.Sleep(5 * retry)
+	if got := b.Count(); got == 0 {
+		t.Error("second leader did not take over")
+	}
+}
+
+// SlowUpdater blocks in FetchUpdates until released.
+type slowUpdater struct {
+	started, release chan struct{}
+	done             int64
+}
+
+func (s *slowUpdater) FetchUpdates(ctx context.Context) error {
+	s.started <- struct{}{}
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	atomic.AddInt64(&s.done, 1)
+	return nil
+}
+
+// TestLeaderDrain checks that a draining Leader finishes the update run in
+// progress and then returns, without its context being canceled.
+func TestLeaderDrain(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	u := &slowUpdater{started: make(chan struct{}, 1), release: make(chan struct{})}
+	l := NewLocalLeader(u, time.Millisecond)
+	errc := make(chan error, 1)
+	go func() { errc <- l.Run(ctx) }()
+
+	<-u.started
+	l.Drain()
+	select {
+	case err := <-errc:
+		t.Fatalf("returned before the run finished: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(u.release)
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("didn't return after draining")
+	}
+	if got, want := atomic.LoadInt64(&u.done), int64(1); got != want {
+		t.Errorf("got: %d runs, want: %d", got, want)
+	}
+}
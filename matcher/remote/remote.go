@@ -0,0 +1,221 @@
+// Package remote implements a claircore matcher backed by an external HTTP
+// service, for vulnerability sources that aren't in claircore.
+//
+// Clair POSTs a batch of index records to the service as JSON:
+//
+//	{"records": [{"package": {...}, "distribution": {...}, "repository": {...}}]}
+//
+// The objects are shaped as in index and vulnerability reports. The service
+// responds with the vulnerabilities affecting each package, keyed by the
+// package's ID:
+//
+//	{"vulnerabilities": {"<package id>": [{"id": "...", "name": "CVE-...", ...}]}}
+//
+// Vulnerabilities from the service aren't stored in Clair's database.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/rs/zerolog"
+)
+
+// Defaults for the Matcher.
+const (
+	DefaultBatchSize = 500
+	DefaultTimeout   = 30 * time.Second
+)
+
+// Record is an index record, as sent to the service.
+type Record struct {
+	Package      *claircore.Package      `json:"package"`
+	Distribution *claircore.Distribution `json:"distribution,omitempty"`
+	Repository   *claircore.Repository   `json:"repository,omitempty"`
+}
+
+// Request is the body of a request to the service.
+type Request struct {
+	Records []Record `json:"records"`
+}
+
+// Response is the body of a response from the service.
+type Response struct {
+	Vulnerabilities map[string][]*claircore.Vulnerability `json:"vulnerabilities"`
+}
+
+// Matcher asks an HTTP service which vulnerabilities affect packages.
+type Matcher struct {
+	name    string
+	url     string
+	token   string
+	client  *http.Client
+	batch   int
+	timeout time.Duration
+}
+
+var (
+	_ driver.Matcher       = (*Matcher)(nil)
+	_ driver.RemoteMatcher = (*Matcher)(nil)
+)
+
+// Opts configures a Matcher.
+type Opts struct {
+	// Name identifies the matcher. Vulnerabilities from the service are
+	// reported with it as the updater, if they don't name one, and their IDs
+	// are prefixed with it so they can't collide with Clair's.
+	Name string
+	// URL is where records are POSTed.
+	URL string
+	// Token, if set, is sent as a bearer token.
+	Token string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+	// BatchSize is the most records sent in one request. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+	// Timeout bounds each request. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// NewMatcher returns a Matcher configured by o.
+func NewMatcher(o Opts) (*Matcher, error) {
+	if o.Name == "" {
+		return nil, fmt.Errorf("remote: matcher needs a name")
+	}
+	if o.URL == "" {
+		return nil, fmt.Errorf("remote: matcher %q needs a url", o.Name)
+	}
+	m := &Matcher{
+		name:    o.Name,
+		url:     o.URL,
+		token:   o.Token,
+		client:  o.Client,
+		batch:   o.BatchSize,
+		timeout: o.Timeout,
+	}
+	if m.client == nil {
+		m.client = http.DefaultClient
+	}
+	if m.batch <= 0 {
+		m.batch = DefaultBatchSize
+	}
+	if m.timeout <= 0 {
+		m.timeout = DefaultTimeout
+	}
+	return m, nil
+}
+
+// Name implements driver.Matcher.
+func (m *Matcher) Name() string { return "remote-" + m.name }
+
+// Filter implements driver.Matcher.
+//
+// Every package is sent to the service, which decides what it knows about.
+func (m *Matcher) Filter(r *claircore.IndexRecord) bool {
+	return r.Package != nil
+}
+
+// Query implements driver.Matcher.
+//
+// It's never called, because the Matcher is a driver.RemoteMatcher.
+func (m *Matcher) Query() []driver.MatchConstraint { return nil }
+
+// Vulnerable implements driver.Matcher.
+//
+// It's never called, because the Matcher is a driver.RemoteMatcher.
+func (m *Matcher) Vulnerable(context.Context, *claircore.IndexRecord, *claircore.Vulnerability) (bool, error) {
+	return false, nil
+}
+
+// QueryRemoteMatcher implements driver.RemoteMatcher.
+//
+// If any batch fails, no matches are returned, rather than some of them. The
+// matcher controller logs the error and carries on without this matcher.
+func (m *Matcher) QueryRemoteMatcher(ctx context.Context, records []*claircore.IndexRecord) (map[string][]*claircore.Vulnerability, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "matcher/remote/Matcher.QueryRemoteMatcher").
+		Str("matcher", m.name).
+		Logger()
+	out := make(map[string][]*claircore.Vulnerability)
+	for len(records) != 0 {
+		n := m.batch
+		if n > len(records) {
+			n = len(records)
+		}
+		res, err := m.query(ctx, records[:n])
+		if err != nil {
+			return nil, fmt.Errorf("remote: matcher %q: %w", m.name, err)
+		}
+		for _, r := range records[:n] {
+			id := r.Package.ID
+			for _, v := range res.Vulnerabilities[id] {
+				if v == nil {
+					continue
+				}
+				if v.Updater == "" {
+					v.Updater = m.name
+				}
+				v.ID = m.name + "-" + v.ID
+				if v.Package == nil {
+					v.Package = r.Package
+				}
+				out[id] = append(out[id], v)
+			}
+		}
+		records = records[n:]
+	}
+	log.Debug().
+		Int("packages", len(out)).
+		Msg("remote matches")
+	return out, nil
+}
+
+// Query sends one batch of records.
+func (m *Matcher) query(ctx context.Context, records []*claircore.IndexRecord) (*Response, error) {
+	body := Request{Records: make([]Record, len(records))}
+	for i, r := range records {
+		body.Records[i] = Record{
+			Package:      r.Package,
+			Distribution: r.Distribution,
+			Repository:   r.Repository,
+		}
+	}
+	b, err := json.Marshal(&body)
+	if err != nil {
+		return nil, err
+	}
+	ctx, done := context.WithTimeout(ctx, m.timeout)
+	defer done()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("accept", "application/json")
+	if m.token != "" {
+		req.Header.Set("authorization", "Bearer "+m.token)
+	}
+	res, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("unexpected response: %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	var out Response
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("malformed response: %w", err)
+	}
+	return &out, nil
+}
@@ -0,0 +1,90 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+// TestMatcher checks that records are sent in batches and the service's
+// matches come back keyed by package.
+func TestMatcher(t *testing.T) {
+	ctx := context.Background()
+	var batches []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("authorization"), "Bearer secret"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batches = append(batches, len(req.Records))
+		res := Response{Vulnerabilities: make(map[string][]*claircore.Vulnerability)}
+		for _, rec := range req.Records {
+			if rec.Package.Name == "vulnerable" {
+				res.Vulnerabilities[rec.Package.ID] = []*claircore.Vulnerability{
+					{ID: "1", Name: "PROPRIETARY-1"},
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(&res)
+	}))
+	defer srv.Close()
+
+	m, err := NewMatcher(Opts{
+		Name:      "vendor",
+		URL:       srv.URL,
+		Token:     "secret",
+		Client:    srv.Client(),
+		BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []*claircore.IndexRecord{
+		{Package: &claircore.Package{ID: "1", Name: "fine"}},
+		{Package: &claircore.Package{ID: "2", Name: "vulnerable"}},
+		{Package: &claircore.Package{ID: "3", Name: "vulnerable"}},
+	}
+	got, err := m.QueryRemoteMatcher(ctx, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || batches[0] != 2 || batches[1] != 1 {
+		t.Errorf("got batches: %v, want: [2 1]", batches)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got: %d packages, want: 2", len(got))
+	}
+	v := got["3"][0]
+	if got, want := v.ID, "vendor-1"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := v.Updater, "vendor"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := v.Package.ID, "3"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		m, err := NewMatcher(Opts{Name: "vendor", URL: srv.URL, Client: srv.Client()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.QueryRemoteMatcher(ctx, records); err == nil {
+			t.Error("wanted an error")
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/quay/clair/v4/middleware/auth"
+	"github.com/quay/clair/v4/middleware/requestid"
+)
+
+// Version is the version of the Record schema.
+//
+// Fields may be added to Record without changing this value. Any other change
+// must increment it.
+const Version = 1
+
+// Record is a single audit log entry.
+type Record struct {
+	Version    int       `json:"version"`
+	Time       time.Time `json:"time"`
+	Principal  Principal `json:"principal"`
+	RemoteAddr string    `json:"remote_addr"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	// Manifest is the manifest digest the request concerned, if any.
+	Manifest  string `json:"manifest_digest,omitempty"`
+	Status    int    `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	// RequestID is the ID the request was tagged with, if any.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Principal identifies who made a request.
+type Principal struct {
+	// Authenticated reports whether the authentication middleware verified
+	// the request's credentials.
+	Authenticated bool   `json:"authenticated"`
+	Issuer        string `json:"issuer,omitempty"`
+	Subject       string `json:"subject,omitempty"`
+}
+
+// Handler returns an http.Handler that writes a Record for every request to
+// the provided Sink.
+//
+// Handler should wrap any authentication middleware, so that rejected
+// requests are recorded as well.
+func Handler(next http.Handler, s Sink) http.Handler {
+	return &handler{
+		next: next,
+		sink: s,
+	}
+}
+
+type handler struct {
+	next http.Handler
+	sink Sink
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher, if the wrapped ResponseWriter does.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+	ctx, v := auth.WithVerified(r.Context())
+	r = r.WithContext(ctx)
+	h.next.ServeHTTP(sw, r)
+
+	rec := Record{
+		Version:    Version,
+		Time:       start.UTC(),
+		Principal:  principal(r, v),
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Manifest:   manifest(r, sw.Header()),
+		Status:     sw.code,
+		LatencyMS:  time.Since(start).Milliseconds(),
+	}
+	rec.RequestID, _ = requestid.FromContext(r.Context())
+	if err := h.sink.Write(r.Context(), &rec); err != nil {
+		zerolog.Ctx(r.Context()).Error().
+			Str("component", "middleware/audit/handler.ServeHTTP").
+			Err(err).
+			Msg("failed to write audit record")
+	}
+}
+
+// Principal identifies who made the request, using what the authentication
+// middleware verified.
+//
+// Claims the middleware learned, like those of introspected opaque tokens, are
+// used if present. Otherwise the bearer token is read, which is safe to do
+// without checking its signature only because the middleware has.
+func principal(r *http.Request, v *auth.Verified) Principal {
+	var p Principal
+	if !v.Authenticated {
+		return p
+	}
+	p.Authenticated = true
+	if v.Claims != nil {
+		p.Issuer, _ = v.Claims["iss"].(string)
+		p.Subject, _ = v.Claims["sub"].(string)
+		return p
+	}
+	for _, h := range r.Header["Authorization"] {
+		if !strings.HasPrefix(h, "Bearer ") {
+			continue
+		}
+		tok, err := jwt.ParseSigned(strings.TrimPrefix(h, "Bearer "))
+		if err != nil {
+			continue
+		}
+		var cl jwt.Claims
+		if err := tok.UnsafeClaimsWithoutVerification(&cl); err != nil {
+			continue
+		}
+		p.Issuer = cl.Issuer
+		p.Subject = cl.Subject
+		break
+	}
+	return p
+}
+
+// Manifest finds the manifest digest for the request, either from the request
+// path or the "Location" header of the response.
+func manifest(r *http.Request, h http.Header) string {
+	for _, p := range []string{r.URL.Path, h.Get("location")} {
+		if p == "" {
+			continue
+		}
+		if d, err := claircore.ParseDigest(path.Base(p)); err == nil {
+			return d.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quay/zlog"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+const digest = "sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a"
+
+var key = []byte("deadbeefdeadbeef")
+
+func token(t *testing.T, key []byte) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: key}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := jwt.Signed(signer).Claims(jwt.Claims{Issuer: "quay", Subject: "ci-bot"}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestHandler(t *testing.T) {
+	type testcase struct {
+		Name   string
+		Path   string
+		Status int
+		// Key signs the request's token, if not nil.
+		Key []byte
+		// Unchecked skips the authentication middleware.
+		Unchecked bool
+		// Location is set as the response's location header, if not empty.
+		Location string
+		Want     Record
+	}
+	tt := []testcase{
+		{
+			Name:   "PathDigest",
+			Path:   "/matcher/api/v1/vulnerability_report/" + digest,
+			Status: http.StatusOK,
+			Key:    key,
+			Want: Record{
+				Version:   Version,
+				Principal: Principal{Authenticated: true, Issuer: "quay", Subject: "ci-bot"},
+				Method:    http.MethodGet,
+				Path:      "/matcher/api/v1/vulnerability_report/" + digest,
+				Manifest:  digest,
+				Status:    http.StatusOK,
+			},
+		},
+		{
+			Name:     "LocationDigest",
+			Path:     "/indexer/api/v1/index_report",
+			Status:   http.StatusCreated,
+			Key:      key,
+			Location: "/indexer/api/v1/index_report/" + digest,
+			Want: Record{
+				Version:   Version,
+				Principal: Principal{Authenticated: true, Issuer: "quay", Subject: "ci-bot"},
+				Method:    http.MethodGet,
+				Path:      "/indexer/api/v1/index_report",
+				Manifest:  digest,
+				Status:    http.StatusCreated,
+			},
+		},
+		{
+			Name:   "Rejected",
+			Path:   "/indexer/api/v1/index_state",
+			Status: http.StatusOK,
+			Key:    []byte("not the right key"),
+			Want: Record{
+				Version: Version,
+				Method:  http.MethodGet,
+				Path:    "/indexer/api/v1/index_state",
+				Status:  http.StatusUnauthorized,
+			},
+		},
+		{
+			// A token isn't trusted unless the authentication middleware
+			// verified it.
+			Name:      "Unverified",
+			Path:      "/indexer/api/v1/index_state",
+			Status:    http.StatusOK,
+			Key:       key,
+			Unchecked: true,
+			Want: Record{
+				Version: Version,
+				Method:  http.MethodGet,
+				Path:    "/indexer/api/v1/index_state",
+				Status:  http.StatusOK,
+			},
+		},
+		{
+			Name:      "Anonymous",
+			Path:      "/indexer/api/v1/index_state",
+			Status:    http.StatusOK,
+			Unchecked: true,
+			Want: Record{
+				Version: Version,
+				Method:  http.MethodGet,
+				Path:    "/indexer/api/v1/index_state",
+				Status:  http.StatusOK,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(context.Background(), t)
+			var buf bytes.Buffer
+			var next http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.Location != "" {
+					w.Header().Set("location", tc.Location)
+				}
+				w.WriteHeader(tc.Status)
+			})
+			if !tc.Unchecked {
+				psk, err := auth.NewPSK(key, []string{"quay"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				next = auth.Handler(next, psk)
+			}
+			h := Handler(next, NewWriterSink(&buf))
+
+			req := httptest.NewRequest(http.MethodGet, tc.Path, nil).WithContext(ctx)
+			if tc.Key != nil {
+				req.Header.Set("authorization", "Bearer "+token(t, tc.Key))
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			var got Record
+			if err := json.NewDecoder(&buf).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Time.IsZero() {
+				t.Error("record missing timestamp")
+			}
+			tc.Want.Time = got.Time
+			tc.Want.RemoteAddr = got.RemoteAddr
+			tc.Want.LatencyMS = got.LatencyMS
+			if got != tc.Want {
+				t.Errorf("got: %+v, want: %+v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestHTTPSinkClosed(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	s := NewHTTPSink(ctx, srv.URL, nil, srv.Client())
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(ctx, &Record{Method: http.MethodGet, Path: "/"}); err == nil {
+		t.Error("expected error writing to closed sink")
+	}
+}
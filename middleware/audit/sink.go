@@ -0,0 +1,185 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Sink is the destination for audit Records.
+type Sink interface {
+	Write(context.Context, *Record) error
+	io.Closer
+}
+
+// WriterSink writes newline-delimited JSON records to an io.Writer.
+type writerSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	c   io.Closer
+}
+
+// NewFileSink returns a Sink appending records to the file at the named path.
+//
+// The path "-" writes to stdout.
+func NewFileSink(p string) (Sink, error) {
+	if p == "-" {
+		return &writerSink{enc: json.NewEncoder(os.Stdout), c: nopCloser{}}, nil
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &writerSink{enc: json.NewEncoder(f), c: f}, nil
+}
+
+// NewWriterSink returns a Sink writing records to w.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{enc: json.NewEncoder(w), c: nopCloser{}}
+}
+
+func (s *writerSink) Write(_ context.Context, r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(r)
+}
+
+func (s *writerSink) Close() error {
+	return s.c.Close()
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// SyslogSink writes records to syslog at the "info" priority.
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink returns a Sink writing to the syslog daemon described by
+// network and addr. If both are empty, the local daemon is used.
+func NewSyslogSink(network, addr, tag string) (Sink, error) {
+	if tag == "" {
+		tag = "clair-audit"
+	}
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) Write(_ context.Context, r *Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.w.Info(string(b))
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
+
+// HTTPSink POSTs records to a remote endpoint.
+//
+// Records are queued and sent in the background so that a slow collector
+// doesn't add latency to API requests. If the queue fills, Write reports an
+// error instead of blocking. Records written after Close are dropped with an
+// error.
+type httpSink struct {
+	url     string
+	headers http.Header
+	c       *http.Client
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	q      chan []byte
+	closed bool
+}
+
+// QueueSize is the number of records an HTTP sink will buffer.
+const queueSize = 1024
+
+// NewHTTPSink returns a Sink sending each record as the JSON body of a POST to
+// url. If c is nil, http.DefaultClient is used.
+func NewHTTPSink(ctx context.Context, url string, headers http.Header, c *http.Client) Sink {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	s := &httpSink{
+		url:     url,
+		headers: headers,
+		c:       c,
+		q:       make(chan []byte, queueSize),
+		done:    make(chan struct{}),
+	}
+	go s.run(ctx)
+	return s
+}
+
+func (s *httpSink) Write(_ context.Context, r *Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return fmt.Errorf("audit sink closed; dropped record for %s %s", r.Method, r.Path)
+	}
+	select {
+	case s.q <- b:
+	default:
+		return fmt.Errorf("audit queue full; dropped record for %s %s", r.Method, r.Path)
+	}
+	return nil
+}
+
+// Close stops accepting records and waits for the queue to drain.
+func (s *httpSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.q)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+func (s *httpSink) run(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "middleware/audit/httpSink.run").
+		Logger()
+	defer close(s.done)
+	for b := range s.q {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to create audit request")
+			continue
+		}
+		for k, vs := range s.headers {
+			req.Header[k] = vs
+		}
+		req.Header.Set("content-type", "application/json")
+		res, err := s.c.Do(req)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to send audit record")
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode >= 300 {
+			log.Error().Str("status", res.Status).Msg("audit endpoint rejected record")
+		}
+	}
+}
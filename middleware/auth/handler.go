@@ -12,17 +12,73 @@ type Checker interface {
 	Check(context.Context, *http.Request) bool
 }
 
+// ClaimsChecker is a Checker that learns the claims of the requests it
+// allows, for tokens other middleware can't read themselves, like opaque
+// tokens.
+type ClaimsChecker interface {
+	Checker
+	CheckClaims(context.Context, *http.Request) (map[string]interface{}, bool)
+}
+
+type claimsKey struct{}
+
+// Claims returns the claims a ClaimsChecker learned while allowing the
+// request with the Context.
+func Claims(ctx context.Context) (map[string]interface{}, bool) {
+	cl, ok := ctx.Value(claimsKey{}).(map[string]interface{})
+	return cl, ok
+}
+
+type verifiedKey struct{}
+
+// Verified is where the authentication middleware records the outcome of
+// authenticating a request, for middleware wrapping it. Those can't see the
+// Context it passes on.
+type Verified struct {
+	// Authenticated reports whether a Checker allowed the request.
+	Authenticated bool
+	// Claims are the claims a ClaimsChecker learned, if any.
+	Claims map[string]interface{}
+}
+
+// WithVerified returns a Context the authentication middleware records its
+// outcome in, and the Verified it's recorded to. The Verified must only be
+// read once the request is served.
+func WithVerified(ctx context.Context) (context.Context, *Verified) {
+	v := &Verified{}
+	return context.WithValue(ctx, verifiedKey{}, v), v
+}
+
+// Check runs the Checker, reporting claims if it's a ClaimsChecker.
+func check(ctx context.Context, c Checker, r *http.Request) (map[string]interface{}, bool) {
+	if cc, ok := c.(ClaimsChecker); ok {
+		return cc.CheckClaims(ctx, r)
+	}
+	return nil, c.Check(ctx, r)
+}
+
 type handler struct {
 	auth Checker
 	next http.Handler
 }
 
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if !h.auth.Check(r.Context(), r) {
+	ctx := r.Context()
+	intra := new(bool)
+	ctx = context.WithValue(ctx, intraserviceKey{}, intra)
+	cl, ok := check(ctx, h.auth, r)
+	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
-	h.next.ServeHTTP(w, r)
+	if v, ok := ctx.Value(verifiedKey{}).(*Verified); ok {
+		v.Authenticated = true
+		v.Claims = cl
+	}
+	if cl != nil {
+		ctx = context.WithValue(ctx, claimsKey{}, cl)
+	}
+	h.next.ServeHTTP(w, r.WithContext(ctx))
 }
 
 // Handler returns a http.Handler that gates access to the passed Handler behind
@@ -45,12 +101,18 @@ type any []Checker
 
 // Check implements Checker.
 func (a any) Check(ctx context.Context, r *http.Request) bool {
+	_, ok := a.CheckClaims(ctx, r)
+	return ok
+}
+
+// CheckClaims implements ClaimsChecker.
+func (a any) CheckClaims(ctx context.Context, r *http.Request) (map[string]interface{}, bool) {
 	for _, c := range a {
-		if ok := c.Check(ctx, r); ok {
-			return true
+		if cl, ok := check(ctx, c, r); ok {
+			return cl, true
 		}
 	}
-	return false
+	return nil, false
 }
 
 // Fail is a Checker that always fails.
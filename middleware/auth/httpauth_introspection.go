@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// IntrospectionOpts configures an Introspection.
+type IntrospectionOpts struct {
+	// Endpoint is the URL of the introspection endpoint.
+	Endpoint string
+	// ClientID and ClientSecret are presented to the endpoint with HTTP
+	// basic authentication, if ClientID is set.
+	ClientID     string
+	ClientSecret string
+	// Roots are the certificates trusted when calling the endpoint. If nil,
+	// the system's are used.
+	Roots *x509.CertPool
+	// Audience, if set, is an audience tokens must be issued for.
+	Audience string
+	// Issuers are the accepted issuers. If empty, any issuer is.
+	Issuers []string
+	// CacheTTL is how long an answer from the endpoint is reused.
+	CacheTTL time.Duration
+}
+
+// Introspection implements the Checker interface.
+//
+// When Check is called the bearer token on the incoming http request is
+// sent to an OAuth2 token introspection endpoint, as described in RFC 7662,
+// which reports whether it's active and what its claims are. This allows
+// opaque tokens, which can't be verified locally.
+//
+// Answers are cached, so a client reusing a token doesn't cost a call to the
+// endpoint per request.
+type Introspection struct {
+	endpoint string
+	id, sec  string
+	client   *http.Client
+	aud      string
+	iss      []string
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]introspected
+}
+
+// Introspected is a cached answer from the endpoint.
+type introspected struct {
+	claims map[string]interface{}
+	active bool
+	until  time.Time
+}
+
+// IntrospectionCacheMax bounds the number of cached answers. Expired ones are
+// dropped once it's reached.
+const introspectionCacheMax = 10000
+
+// NewIntrospection returns an Introspection using the provided
+// IntrospectionOpts.
+func NewIntrospection(o *IntrospectionOpts) (*Introspection, error) {
+	u, err := url.Parse(o.Endpoint)
+	switch {
+	case o.Endpoint == "":
+		return nil, errors.New("introspection: no endpoint configured")
+	case err != nil:
+		return nil, fmt.Errorf("introspection: bad endpoint: %w", err)
+	case u.Scheme != "http" && u.Scheme != "https":
+		return nil, fmt.Errorf("introspection: bad endpoint %q", o.Endpoint)
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if o.Roots != nil {
+		t.TLSClientConfig = &tls.Config{RootCAs: o.Roots}
+	}
+	ttl := o.CacheTTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return &Introspection{
+		endpoint: u.String(),
+		id:       o.ClientID,
+		sec:      o.ClientSecret,
+		client:   &http.Client{Transport: t, Timeout: 10 * time.Second},
+		aud:      o.Audience,
+		iss:      o.Issuers,
+		ttl:      ttl,
+		cache:    make(map[[sha256.Size]byte]introspected),
+	}, nil
+}
+
+// Check implements Checker.
+func (i *Introspection) Check(ctx context.Context, r *http.Request) bool {
+	_, ok := i.CheckClaims(ctx, r)
+	return ok
+}
+
+// CheckClaims implements ClaimsChecker.
+func (i *Introspection) CheckClaims(ctx context.Context, r *http.Request) (map[string]interface{}, bool) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "middleware/auth/Introspection.Check").
+		Logger()
+
+	tok, ok := fromHeader(r)
+	if !ok {
+		return nil, false
+	}
+	key := sha256.Sum256([]byte(tok))
+	now := time.Now()
+	i.mu.Lock()
+	e, ok := i.cache[key]
+	i.mu.Unlock()
+	if !ok || !now.Before(e.until) {
+		var err error
+		e, err = i.introspect(ctx, tok, now)
+		if err != nil {
+			// Not cached, so the next request tries again.
+			log.Warn().Err(err).Msg("failed to introspect token")
+			return nil, false
+		}
+		i.mu.Lock()
+		if len(i.cache) >= introspectionCacheMax {
+			for k, v := range i.cache {
+				if !now.Before(v.until) {
+					delete(i.cache, k)
+				}
+			}
+		}
+		if len(i.cache) < introspectionCacheMax {
+			i.cache[key] = e
+		}
+		i.mu.Unlock()
+	}
+	if !e.active {
+		return nil, false
+	}
+	if i.aud != "" && !contains(stringsClaim(e.claims["aud"]), i.aud) {
+		log.Debug().Msg("could not verify audience")
+		return nil, false
+	}
+	if iss, _ := e.claims["iss"].(string); len(i.iss) != 0 && !contains(i.iss, iss) {
+		log.Debug().Str("iss", iss).Msg("could not verify issuer")
+		return nil, false
+	}
+	return e.claims, true
+}
+
+// Introspect asks the endpoint about the token.
+func (i *Introspection) introspect(ctx context.Context, tok string, now time.Time) (introspected, error) {
+	var out introspected
+	body := url.Values{
+		"token":           {tok},
+		"token_type_hint": {"access_token"},
+	}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.endpoint, strings.NewReader(body))
+	if err != nil {
+		return out, err
+	}
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	req.Header.Set("accept", "application/json")
+	if i.id != "" {
+		req.SetBasicAuth(url.QueryEscape(i.id), url.QueryEscape(i.sec))
+	}
+	res, err := i.client.Do(req)
+	if err != nil {
+		return out, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return out, fmt.Errorf("%s: unexpected response: %s", i.endpoint, res.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&out.claims); err != nil {
+		return out, fmt.Errorf("%s: malformed response: %w", i.endpoint, err)
+	}
+	out.active, _ = out.claims["active"].(bool)
+	out.until = now.Add(i.ttl)
+	if !out.active {
+		return out, nil
+	}
+	if exp, ok := out.claims["exp"].(float64); ok {
+		t := time.Unix(int64(exp), 0)
+		if !now.Before(t) {
+			out.active = false
+		} else if t.Before(out.until) {
+			out.until = t
+		}
+	}
+	if nbf, ok := out.claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0)) {
+		// Not valid yet: check again next time.
+		out.active = false
+		out.until = now
+	}
+	return out, nil
+}
+
+// StringsClaim returns the strings in a claim that's either a string or a
+// list of them, like "aud".
+func stringsClaim(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
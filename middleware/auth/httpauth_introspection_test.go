@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIntrospection(t *testing.T) {
+	ctx := context.Background()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if id, sec, ok := r.BasicAuth(); !ok || id != "clair" || sec != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		resp := map[string]interface{}{"active": false}
+		switch r.PostForm.Get("token") {
+		case "good":
+			resp = map[string]interface{}{
+				"active": true,
+				"sub":    "quay",
+				"iss":    testIssuer,
+				"aud":    []string{testAudience, "other"},
+				"scope":  "reports.read",
+				"exp":    time.Now().Add(time.Hour).Unix(),
+			}
+		case "elsewhere":
+			resp = map[string]interface{}{
+				"active": true,
+				"iss":    "https://example.com",
+				"aud":    testAudience,
+			}
+		case "expired":
+			resp = map[string]interface{}{
+				"active": true,
+				"exp":    time.Now().Add(-time.Minute).Unix(),
+			}
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+	in, err := NewIntrospection(&IntrospectionOpts{
+		Endpoint:     srv.URL,
+		ClientID:     "clair",
+		ClientSecret: "secret",
+		Audience:     testAudience,
+		Issuers:      []string{testIssuer},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := func(tok string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tok != "" {
+			r.Header.Set("authorization", "Bearer "+tok)
+		}
+		return r
+	}
+
+	for tok, want := range map[string]bool{
+		"good":      true,
+		"elsewhere": false,
+		"expired":   false,
+		"unknown":   false,
+		"":          false,
+	} {
+		if got := in.Check(ctx, req(tok)); got != want {
+			t.Errorf("%q: got: %v, want: %v", tok, got, want)
+		}
+	}
+
+	t.Run("Cached", func(t *testing.T) {
+		before := atomic.LoadInt32(&calls)
+		cl, ok := in.CheckClaims(ctx, req("good"))
+		if !ok {
+			t.Fatal("good token rejected")
+		}
+		if got := atomic.LoadInt32(&calls); got != before {
+			t.Errorf("endpoint called %d more times", got-before)
+		}
+		if got, want := cl["scope"], "reports.read"; got != want {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		var sub interface{}
+		h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cl, _ := Claims(r.Context())
+			sub = cl["sub"]
+		}), in)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req("good"))
+		if w.Code != http.StatusOK || sub != "quay" {
+			t.Errorf("got: %d, %v", w.Code, sub)
+		}
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, req("unknown"))
+		if got, want := w.Code, http.StatusUnauthorized; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+}
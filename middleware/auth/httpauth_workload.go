@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// TokenFile is a token kept in a file by something else, like the kubelet
+// for projected service account tokens or a SPIFFE helper for JWT-SVIDs.
+//
+// Those rotate the token by replacing the file, so it's read again whenever
+// it changes.
+type TokenFile struct {
+	path string
+
+	mu  sync.Mutex
+	tok string
+	mod time.Time
+	exp time.Time
+}
+
+// NewTokenFile returns a TokenFile reading the token at path.
+func NewTokenFile(path string) *TokenFile {
+	return &TokenFile{path: path}
+}
+
+// Token returns the current token.
+func (f *TokenFile) Token() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	valid := f.tok != "" && (f.exp.IsZero() || now.Before(f.exp))
+	fi, err := os.Stat(f.path)
+	switch {
+	case err != nil && valid:
+		// The file may be in the middle of being replaced.
+		return f.tok, nil
+	case err != nil:
+		return "", err
+	case valid && fi.ModTime().Equal(f.mod):
+		return f.tok, nil
+	}
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+	tok := strings.TrimSpace(string(b))
+	wt, err := jwt.ParseSigned(tok)
+	if err != nil {
+		return "", fmt.Errorf("%s: malformed token: %w", f.path, err)
+	}
+	var cl jwt.Claims
+	if err := wt.UnsafeClaimsWithoutVerification(&cl); err != nil {
+		return "", fmt.Errorf("%s: malformed token: %w", f.path, err)
+	}
+	var exp time.Time
+	if cl.Expiry != nil {
+		exp = cl.Expiry.Time()
+		if !now.Before(exp) {
+			return "", fmt.Errorf("%s: token expired at %v", f.path, exp)
+		}
+	}
+	f.tok, f.mod, f.exp = tok, fi.ModTime(), exp
+	return f.tok, nil
+}
+
+// WorkloadOpts configures a Workload.
+type WorkloadOpts struct {
+	// JWKS is the URL or path of the key set tokens are verified with.
+	JWKS string
+	// Roots are the certificates trusted when fetching JWKS. If nil, the
+	// system's are used.
+	Roots *x509.CertPool
+	// Token, if not nil, is presented when fetching JWKS.
+	Token *TokenFile
+	// Audience is the audience tokens must be issued for.
+	Audience string
+	// Issuers are the accepted issuers. If empty, any issuer is.
+	Issuers []string
+	// Subjects are the accepted subjects.
+	Subjects []string
+}
+
+// Workload implements the Checker interface.
+//
+// When Check is called the JWT on the incoming http request will be
+// validated as a workload identity: a Kubernetes service account token or a
+// SPIFFE JWT-SVID issued to one of the configured subjects.
+type Workload struct {
+	keys *keySet
+	aud  string
+	iss  []string
+	sub  []string
+}
+
+// NewWorkload returns a Workload using the provided WorkloadOpts.
+func NewWorkload(o *WorkloadOpts) (*Workload, error) {
+	if o.Audience == "" {
+		return nil, errors.New("workload: no audience configured")
+	}
+	if len(o.Subjects) == 0 {
+		return nil, errors.New("workload: no subjects configured")
+	}
+	ks, err := newKeySet(o.JWKS, o.Roots, o.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &Workload{
+		keys: ks,
+		aud:  o.Audience,
+		iss:  o.Issuers,
+		sub:  o.Subjects,
+	}, nil
+}
+
+// WorkloadAlgos is an allowlist of signature algorithms used for workload
+// tokens.
+var workloadAlgos = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+}
+
+// Check implements Checker.
+func (w *Workload) Check(ctx context.Context, r *http.Request) bool {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "middleware/auth/Workload.Check").
+		Logger()
+	ctx = log.WithContext(ctx)
+
+	wt, ok := fromHeader(r)
+	if !ok {
+		return false
+	}
+	tok, err := jwt.ParseSigned(wt)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to parse jwt")
+		return false
+	}
+	if len(tok.Headers) != 1 || !contains(workloadAlgos, tok.Headers[0].Algorithm) {
+		return false
+	}
+	keys, err := w.keys.lookup(ctx, tok.Headers[0].KeyID)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to fetch key set")
+		return false
+	}
+	var cl jwt.Claims
+	ok = false
+	for _, k := range keys {
+		if err := tok.Claims(k.Key, &cl); err == nil {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		log.Debug().Msg("no key verified the jwt")
+		return false
+	}
+	if err := cl.ValidateWithLeeway(jwt.Expected{
+		Audience: jwt.Audience{w.aud},
+		Time:     time.Now(),
+	}, 15*time.Second); err != nil {
+		log.Debug().Err(err).Str("sub", cl.Subject).Msg("could not validate claims")
+		return false
+	}
+	if len(w.iss) != 0 && !contains(w.iss, cl.Issuer) {
+		log.Debug().Str("iss", cl.Issuer).Msg("could not verify issuer")
+		return false
+	}
+	if !contains(w.sub, cl.Subject) {
+		log.Debug().Str("sub", cl.Subject).Msg("could not verify subject")
+		return false
+	}
+	return true
+}
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// KeySetTTL is how long a fetched key set is used before fetching it
+	// again.
+	keySetTTL = 5 * time.Minute
+	// KeySetMinInterval limits how often an unknown key ID causes the key
+	// set to be fetched again.
+	keySetMinInterval = 10 * time.Second
+)
+
+// KeySet is a JSON Web Key Set, fetched from a URL or read from a file.
+type keySet struct {
+	u      *url.URL
+	path   string
+	client *http.Client
+	token  *TokenFile
+
+	mu      sync.RWMutex
+	set     jose.JSONWebKeySet
+	fetched time.Time
+	tried   time.Time
+	mod     time.Time
+}
+
+func newKeySet(src string, roots *x509.CertPool, tok *TokenFile) (*keySet, error) {
+	if src == "" {
+		return nil, errors.New("workload: no key set configured")
+	}
+	ks := keySet{token: tok}
+	u, err := url.Parse(src)
+	switch {
+	case err == nil && (u.Scheme == "http" || u.Scheme == "https"):
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		if roots != nil {
+			t.TLSClientConfig = &tls.Config{RootCAs: roots}
+		}
+		ks.u = u
+		ks.client = &http.Client{Transport: t, Timeout: 30 * time.Second}
+	case err == nil && u.Scheme == "file":
+		ks.path = u.Path
+	default:
+		ks.path = src
+	}
+	return &ks, nil
+}
+
+// Lookup returns the keys with the ID, or every key if kid is empty.
+//
+// The key set is loaded again if it's stale, or if the ID isn't known and
+// the set hasn't been loaded recently: the key may have just been rotated in.
+func (ks *keySet) lookup(ctx context.Context, kid string) ([]jose.JSONWebKey, error) {
+	find := func() ([]jose.JSONWebKey, bool) {
+		ks.mu.RLock()
+		defer ks.mu.RUnlock()
+		k := ks.set.Keys
+		if kid != "" {
+			k = ks.set.Key(kid)
+		}
+		fresh := !ks.fetched.IsZero() && time.Since(ks.fetched) < keySetTTL
+		recent := time.Since(ks.tried) < keySetMinInterval
+		return k, recent || (fresh && len(k) != 0)
+	}
+	if k, ok := find(); ok {
+		return k, nil
+	}
+	if err := ks.load(ctx); err != nil {
+		ks.mu.RLock()
+		loaded := !ks.fetched.IsZero()
+		ks.mu.RUnlock()
+		if !loaded {
+			return nil, err
+		}
+		// Keep using what was loaded before.
+		zerolog.Ctx(ctx).Warn().
+			Err(err).
+			Msg("failed to reload key set")
+	}
+	k, _ := find()
+	return k, nil
+}
+
+// Load fetches or reads the key set.
+func (ks *keySet) load(ctx context.Context) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.tried = time.Now()
+	var (
+		rd  io.Reader
+		mod time.Time
+	)
+	if ks.u != nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.u.String(), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("accept", "application/json, application/jwk-set+json")
+		if ks.token != nil {
+			t, err := ks.token.Token()
+			if err != nil {
+				return err
+			}
+			req.Header.Set("authorization", "Bearer "+t)
+		}
+		res, err := ks.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: unexpected response: %s", ks.u, res.Status)
+		}
+		rd = res.Body
+	} else {
+		fi, err := os.Stat(ks.path)
+		if err != nil {
+			return err
+		}
+		mod = fi.ModTime()
+		if !ks.fetched.IsZero() && mod.Equal(ks.mod) {
+			ks.fetched = time.Now()
+			return nil
+		}
+		f, err := os.Open(ks.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		rd = f
+	}
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(io.LimitReader(rd, 1<<20)).Decode(&set); err != nil {
+		return fmt.Errorf("malformed key set: %w", err)
+	}
+	ks.set, ks.fetched, ks.mod = set, time.Now(), mod
+	return nil
+}
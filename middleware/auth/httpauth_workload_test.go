@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testIssuer   = "https://kubernetes.default.svc.cluster.local"
+	testSubject  = "system:serviceaccount:clair:clair"
+	testAudience = "clair"
+)
+
+func workloadKey(t *testing.T, kid string) *jose.JSONWebKey {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &jose.JSONWebKey{Key: k, KeyID: kid, Algorithm: string(jose.RS256), Use: "sig"}
+}
+
+func workloadToken(t *testing.T, k *jose.JSONWebKey, cl jwt.Claims) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: k}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := jwt.Signed(signer).Claims(cl).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestWorkload(t *testing.T) {
+	ctx := context.Background()
+	key, other := workloadKey(t, "one"), workloadKey(t, "two")
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.Public()}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewEncoder(w).Encode(&set); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+	w, err := NewWorkload(&WorkloadOpts{
+		JWKS:     srv.URL,
+		Audience: testAudience,
+		Issuers:  []string{testIssuer},
+		Subjects: []string{testSubject},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	good := jwt.Claims{
+		Issuer:   testIssuer,
+		Subject:  testSubject,
+		Audience: jwt.Audience{testAudience},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+	tt := []struct {
+		name  string
+		key   *jose.JSONWebKey
+		claim func(*jwt.Claims)
+		want  bool
+	}{
+		{name: "OK", key: key, want: true},
+		{name: "UnknownKey", key: other, want: false},
+		{
+			name:  "Audience",
+			key:   key,
+			claim: func(cl *jwt.Claims) { cl.Audience = jwt.Audience{"https://kubernetes.default.svc"} },
+			want:  false,
+		},
+		{
+			name:  "Subject",
+			key:   key,
+			claim: func(cl *jwt.Claims) { cl.Subject = "system:serviceaccount:default:default" },
+			want:  false,
+		},
+		{
+			name:  "Issuer",
+			key:   key,
+			claim: func(cl *jwt.Claims) { cl.Issuer = "https://example.com" },
+			want:  false,
+		},
+		{
+			name:  "Expired",
+			key:   key,
+			claim: func(cl *jwt.Claims) { cl.Expiry = jwt.NewNumericDate(now.Add(-time.Hour)) },
+			want:  false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := good
+			if tc.claim != nil {
+				tc.claim(&cl)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("authorization", "Bearer "+workloadToken(t, tc.key, cl))
+			if got, want := w.Check(ctx, req), tc.want; got != want {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWorkloadFile(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "workload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key := workloadKey(t, "one")
+	b, err := json.Marshal(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.Public()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "bundle.json")
+	if err := ioutil.WriteFile(p, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWorkload(&WorkloadOpts{
+		JWKS:     p,
+		Audience: testAudience,
+		Subjects: []string{"spiffe://example.org/clair"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("authorization", "Bearer "+workloadToken(t, key, jwt.Claims{
+		Subject:  "spiffe://example.org/clair",
+		Audience: jwt.Audience{testAudience},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}))
+	if !w.Check(ctx, req) {
+		t.Error("check failed")
+	}
+}
+
+func TestTokenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key := workloadKey(t, "one")
+	p := filepath.Join(dir, "token")
+	write := func(sub string, mod time.Time) string {
+		tok := workloadToken(t, key, jwt.Claims{
+			Subject: sub,
+			Expiry:  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		})
+		if err := ioutil.WriteFile(p, []byte(tok+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+
+	f := NewTokenFile(p)
+	now := time.Now()
+	want := write("first", now.Add(-time.Minute))
+	got, err := f.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	// Rotated.
+	want = write("second", now)
+	got, err = f.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	// Briefly missing while being replaced.
+	if err := os.Remove(p); err != nil {
+		t.Fatal(err)
+	}
+	got, err = f.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+type intraserviceKey struct{}
+
+// Intraservice wraps a Checker that only allows tokens minted by other Clair
+// services, like one with a key clients aren't given.
+//
+// Requests it allows are reported by FromIntraservice. The claims of a token
+// can't be used to decide this, as any client able to mint tokens can claim
+// whatever issuer it likes.
+func Intraservice(c Checker) Checker {
+	return &intraservice{c}
+}
+
+type intraservice struct {
+	next Checker
+}
+
+// Check implements Checker.
+func (i *intraservice) Check(ctx context.Context, r *http.Request) bool {
+	_, ok := i.CheckClaims(ctx, r)
+	return ok
+}
+
+// CheckClaims implements ClaimsChecker.
+func (i *intraservice) CheckClaims(ctx context.Context, r *http.Request) (map[string]interface{}, bool) {
+	cl, ok := check(ctx, i.next, r)
+	if ok {
+		if m, mark := ctx.Value(intraserviceKey{}).(*bool); mark {
+			*m = true
+		}
+	}
+	return cl, ok
+}
+
+// FromIntraservice reports whether the request was allowed by a Checker
+// wrapped with Intraservice.
+func FromIntraservice(r *http.Request) bool {
+	m, ok := r.Context().Value(intraserviceKey{}).(*bool)
+	return ok && *m
+}
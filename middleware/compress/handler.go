@@ -1,7 +1,6 @@
 package compress
 
 import (
-	"fmt"
 	"io"
 	"mime"
 	"net/http"
@@ -13,23 +12,66 @@ import (
 	"github.com/klauspost/compress/flate"
 	"github.com/klauspost/compress/gzip"
 	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
+// DefaultMinSize is the default smallest response body that will be
+// compressed. Anything smaller is likely to grow, or at least not shrink
+// enough to be worth the CPU time.
+const DefaultMinSize = 1024
+
+// Options configures a compression Handler.
+type Options struct {
+	// MinSize is the smallest response body, in bytes, that will be
+	// compressed. If zero, DefaultMinSize is used. Negative values compress
+	// every response.
+	MinSize int
+	// Level is the compression level, from 1 (fastest) to 9 (smallest). If
+	// zero, the fastest level is used.
+	Level int
+}
+
 // Handler wraps the provided http.Handler and provides transparent body
 // compression based on a Request's "Accept-Encoding" header.
 func Handler(next http.Handler) http.Handler {
+	return WithOptions(next, Options{})
+}
+
+// WithOptions is like Handler, but allows for configuring the compression
+// behavior.
+func WithOptions(next http.Handler, o Options) http.Handler {
+	switch {
+	case o.MinSize == 0:
+		o.MinSize = DefaultMinSize
+	case o.MinSize < 0:
+		o.MinSize = 0
+	}
+	switch {
+	case o.Level <= 0:
+		o.Level = 1
+	case o.Level > 9:
+		o.Level = 9
+	}
 	h := handler{
-		next: next,
+		next:    next,
+		minSize: o.MinSize,
 	}
-	h.snappy.New = func() interface{} {
+	h.pool[encSnappy].New = func() interface{} {
 		return snappy.NewBufferedWriter(nil)
 	}
-	h.gzip.New = func() interface{} {
-		w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+	h.pool[encGzip].New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, o.Level)
+		return w
+	}
+	h.pool[encDeflate].New = func() interface{} {
+		w, _ := flate.NewWriter(nil, o.Level)
 		return w
 	}
-	h.flate.New = func() interface{} {
-		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+	zl := zstd.EncoderLevelFromZstd(o.Level)
+	h.pool[encZstd].New = func() interface{} {
+		// The concurrency is limited because there's already a goroutine
+		// per request.
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zl), zstd.WithEncoderConcurrency(1))
 		return w
 	}
 
@@ -38,17 +80,38 @@ func Handler(next http.Handler) http.Handler {
 
 var _ http.Handler = (*handler)(nil)
 
-// handler performs transparent HTTP body compression.
-type handler struct {
-	snappy, gzip, flate sync.Pool
-	next                http.Handler
+// Encoding identifies a supported content-coding.
+type encoding int
+
+const (
+	encIdentity encoding = iota
+	encGzip
+	encDeflate
+	encSnappy
+	encZstd
+	encMax
+)
+
+var encodingName = [...]string{
+	encIdentity: "identity",
+	encGzip:     "gzip",
+	encDeflate:  "deflate",
+	encSnappy:   "snappy", // Nonstandard
+	encZstd:     "zstd",
 }
 
-// Header is an interface that has the http.ResponseWriter's Header-related
-// methods.
-type header interface {
-	Header() http.Header
-	WriteHeader(int)
+// Encoder is the interface the pooled compressors implement.
+type encoder interface {
+	io.WriteCloser
+	Reset(io.Writer)
+	Flush() error
+}
+
+// handler performs transparent HTTP body compression.
+type handler struct {
+	pool    [encMax]sync.Pool
+	next    http.Handler
+	minSize int
 }
 
 // ParseAccept parses an "Accept-Encoding" header.
@@ -64,19 +127,15 @@ func parseAccept(h string) ([]accept, map[string]struct{}) {
 	ret := make([]accept, 0, len(segs))
 	nok := make(map[string]struct{})
 	for _, s := range segs {
-		a := accept{}
+		a := accept{Q: 1}
 		t, param, err := mime.ParseMediaType(s)
 		if err != nil {
 			continue
 		}
 		a.Type = t
 		if q, ok := param["q"]; ok {
-			if q == "0" {
-				nok[t] = struct{}{}
-				continue
-			}
-			qv, err := strconv.ParseFloat(param["q"], 64)
-			if err != nil {
+			qv, err := strconv.ParseFloat(q, 64)
+			if err != nil || qv == 0 {
 				nok[t] = struct{}{}
 				continue
 			}
@@ -96,47 +155,24 @@ type accept struct {
 	Q    float64
 }
 
-// ServeHTTP implements http.Handler.
-func (c *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ae, nok := parseAccept(r.Header.Get("accept-encoding"))
-	if ae == nil {
-		// If there was no header, play it cool.
-		c.next.ServeHTTP(w, r)
-		return
-	}
-	var (
-		flusher http.Flusher
-		pusher  http.Pusher
-		cw      io.WriteCloser
-	)
-	flusher, _ = w.(http.Flusher)
-	pusher, _ = w.(http.Pusher)
-
+// Negotiate picks the encoding to use, reporting false if no acceptable
+// encoding exists.
+func negotiate(ae []accept, nok map[string]struct{}) (encoding, bool) {
 	// Find the first accept-encoding we support.
-	// See https://www.w3.org/Protocols/rfc2616/rfc2616-sec14.html#sec14.1 for
-	// all the sematics.
+	// See https://tools.ietf.org/html/rfc7231#section-5.3.4 for all the
+	// semantics.
 	for _, a := range ae {
 		switch a.Type {
+		case "zstd":
+			return encZstd, true
 		case "gzip":
-			w.Header().Set("content-encoding", "gzip")
-			gz := c.gzip.Get().(*gzip.Writer)
-			gz.Reset(w)
-			defer c.gzip.Put(gz)
-			cw = gz
+			return encGzip, true
 		case "deflate":
-			w.Header().Set("content-encoding", "deflate")
-			z := c.flate.Get().(*flate.Writer)
-			z.Reset(w)
-			defer c.flate.Put(z)
-			cw = z
-		case "snappy": // Nonstandard
-			w.Header().Set("content-encoding", "snappy")
-			s := c.snappy.Get().(*snappy.Writer)
-			s.Reset(w)
-			defer c.snappy.Put(s)
-			cw = s
+			return encDeflate, true
+		case "snappy":
+			return encSnappy, true
 		case "identity":
-			w.Header().Set("content-encoding", "identity")
+			return encIdentity, true
 		case "*":
 			// If we hit a star, it's technically OK to return any encoding not
 			// already specified. So, attempt to use gzip and then identity and
@@ -144,88 +180,186 @@ func (c *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			// Clients that do extremely weird things like
 			//	*;q=1.0, gzip;q=0.1, identity;q=0.1"
 			// deserve extremely weird replies.
-			_, gznok := nok["gzip"]
-			_, idnok := nok["identity"]
-			switch {
-			case !gznok:
-				w.Header().Set("content-encoding", "gzip")
-				gz := c.gzip.Get().(*gzip.Writer)
-				gz.Reset(w)
-				defer c.gzip.Put(gz)
-				cw = gz
-			case !idnok:
-				w.Header().Set("content-encoding", "identity")
-			default:
-				w.WriteHeader(http.StatusNotAcceptable)
-				return
+			if _, gznok := nok["gzip"]; !gznok {
+				return encGzip, true
 			}
-		default:
-			continue
-		}
-		break
-	}
-	// Do some setup so we can see the error, albeit as a trailer.
-	if cw != nil {
-		const errHeader = `Clair-Error`
-		w.Header().Add("trailer", errHeader)
-		defer func() {
-			if err := cw.Close(); err != nil {
-				w.Header().Add(errHeader, err.Error())
+			if _, idnok := nok["identity"]; !idnok {
+				return encIdentity, true
 			}
-		}()
+			return encIdentity, false
+		}
 	}
+	// Identity is always acceptable unless explicitly refused.
+	_, idnok := nok["identity"]
+	_, starnok := nok["*"]
+	return encIdentity, !idnok && !starnok
+}
 
-	// Nw is the http.ResponseWriter for our next http.Handler.
-	var nw http.ResponseWriter
-	// This is a giant truth table to make anonymous types that satisfy as many
-	// optional interfaces as possible.
-	//
-	// We care about 3 interfaces, so there are 2^3 == 8 combinations.
+// ServeHTTP implements http.Handler.
+func (c *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("vary", "accept-encoding")
+	ae, nok := parseAccept(r.Header.Get("accept-encoding"))
+	if ae == nil && len(nok) == 0 {
+		// If there was no header, play it cool.
+		c.next.ServeHTTP(w, r)
+		return
+	}
+	enc, ok := negotiate(ae, nok)
 	switch {
-	case flusher == nil && pusher == nil && cw == nil:
-		nw = w
-	case flusher == nil && pusher == nil && cw != nil:
-		nw = struct {
-			header
-			io.Writer
-		}{w, cw}
-	case flusher == nil && pusher != nil && cw == nil:
-		nw = struct {
-			http.ResponseWriter
-			http.Pusher
-		}{w, pusher}
-	case flusher == nil && pusher != nil && cw != nil:
-		nw = struct {
-			header
-			io.Writer
-			http.Pusher
-		}{w, cw, pusher}
-	case flusher != nil && pusher == nil && cw == nil:
-		nw = struct {
-			http.ResponseWriter
-			http.Flusher
-		}{w, flusher}
-	case flusher != nil && pusher == nil && cw != nil:
-		nw = struct {
-			header
-			io.Writer
-			http.Flusher
-		}{w, cw, flusher}
-	case flusher != nil && pusher != nil && cw == nil:
-		nw = struct {
-			http.ResponseWriter
-			http.Flusher
-			http.Pusher
-		}{w, flusher, pusher}
-	case flusher != nil && pusher != nil && cw != nil:
+	case !ok:
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	case enc == encIdentity, r.Method == http.MethodHead:
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	cw := &writer{
+		ResponseWriter: w,
+		h:              c,
+		enc:            enc,
+		code:           http.StatusOK,
+	}
+	defer cw.Close()
+	var nw http.ResponseWriter = cw
+	if p, ok := w.(http.Pusher); ok {
 		nw = struct {
-			header
-			io.Writer
-			http.Flusher
+			*writer
 			http.Pusher
-		}{w, cw, flusher, pusher}
-	default:
-		panic(fmt.Sprintf("unexpected type combination: %T/%T/%T", flusher, pusher, cw))
+		}{cw, p}
 	}
 	c.next.ServeHTTP(nw, r)
 }
+
+// Writer delays choosing whether to compress a response until it's seen
+// enough of the body to know it's worthwhile.
+type writer struct {
+	http.ResponseWriter
+	h   *handler
+	enc encoding
+
+	code        int
+	wroteHeader bool
+	// Decided is set once the choice to compress or not has been made, at
+	// which point the header has been sent.
+	decided bool
+	buf     []byte
+	cw      encoder
+}
+
+var (
+	_ http.ResponseWriter = (*writer)(nil)
+	_ http.Flusher        = (*writer)(nil)
+)
+
+// WriteHeader implements http.ResponseWriter.
+func (w *writer) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+	// Responses without a body, or bodies already encoded by the handler,
+	// shouldn't be touched.
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified ||
+		w.Header().Get("content-encoding") != "" {
+		w.decide(false)
+		return
+	}
+	if cl := w.Header().Get("content-length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < w.h.minSize {
+			w.decide(false)
+		}
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (w *writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.cw != nil {
+			return w.cw.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.h.minSize {
+		w.decide(true)
+		if err := w.flushBuf(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher.
+//
+// Flushing commits to compressing the response, because the rest of the body
+// may be arbitrarily large.
+func (w *writer) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.decide(true)
+		w.flushBuf()
+	}
+	if w.cw != nil {
+		w.cw.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Decide sends the header and sets up the compressor, if requested.
+func (w *writer) decide(compress bool) {
+	w.decided = true
+	if compress {
+		h := w.Header()
+		h.Set("content-encoding", encodingName[w.enc])
+		h.Del("content-length")
+		cw := w.h.pool[w.enc].Get().(encoder)
+		cw.Reset(w.ResponseWriter)
+		w.cw = cw
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+}
+
+func (w *writer) flushBuf() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.cw != nil {
+		_, err = w.cw.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// Close finishes the response, writing out any buffered body and returning
+// the compressor to its pool.
+func (w *writer) Close() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		// Too small to bother with.
+		w.decide(false)
+	}
+	if err := w.flushBuf(); err != nil {
+		return err
+	}
+	if w.cw == nil {
+		return nil
+	}
+	err := w.cw.Close()
+	w.h.pool[w.enc].Put(w.cw)
+	w.cw = nil
+	return err
+}
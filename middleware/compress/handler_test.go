@@ -0,0 +1,82 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestHandler(t *testing.T) {
+	big := bytes.Repeat([]byte("clair "), 1024)
+	small := []byte("clair")
+	type testcase struct {
+		Name     string
+		Accept   string
+		Body     []byte
+		Status   int
+		Encoding string
+	}
+	tt := []testcase{
+		{Name: "NoHeader", Body: big, Status: http.StatusOK},
+		{Name: "Gzip", Accept: "gzip", Body: big, Status: http.StatusOK, Encoding: "gzip"},
+		{Name: "Zstd", Accept: "zstd", Body: big, Status: http.StatusOK, Encoding: "zstd"},
+		{Name: "Preference", Accept: "gzip;q=0.5, zstd", Body: big, Status: http.StatusOK, Encoding: "zstd"},
+		{Name: "Refused", Accept: "zstd;q=0, gzip", Body: big, Status: http.StatusOK, Encoding: "gzip"},
+		{Name: "Star", Accept: "*", Body: big, Status: http.StatusOK, Encoding: "gzip"},
+		{Name: "Small", Accept: "gzip", Body: small, Status: http.StatusOK},
+		{Name: "NotModified", Accept: "gzip", Status: http.StatusNotModified},
+		{Name: "Unknown", Accept: "br", Body: big, Status: http.StatusOK},
+	}
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.Status)
+				w.Write(tc.Body)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.Accept != "" {
+				req.Header.Set("accept-encoding", tc.Accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tc.Status; got != want {
+				t.Errorf("got: %d, want: %d", got, want)
+			}
+			if got, want := res.Header.Get("content-encoding"), tc.Encoding; got != want {
+				t.Fatalf("got: %q, want: %q", got, want)
+			}
+			var r io.Reader = res.Body
+			switch tc.Encoding {
+			case "gzip":
+				gz, err := gzip.NewReader(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r = gz
+			case "zstd":
+				z, err := zstd.NewReader(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer z.Close()
+				r = z
+			}
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tc.Body) {
+				t.Errorf("body mismatch: got %d bytes, want %d bytes", len(got), len(tc.Body))
+			}
+		})
+	}
+}
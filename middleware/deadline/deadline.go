@@ -0,0 +1,167 @@
+// Package deadline carries request deadlines across HTTP hops.
+//
+// Clients bound how long a request may take with the "X-Request-Timeout"
+// header. The server applies it to the request's Context, so work done on the
+// request's behalf, like indexing a manifest, stops once the client has
+// given up, and intra-service clients pass what's left of it along.
+package deadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	je "github.com/quay/claircore/pkg/jsonerr"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Header is the request header holding the timeout.
+const Header = "X-Request-Timeout"
+
+// Parse parses a timeout, either a number of seconds or a duration as
+// understood by time.ParseDuration, e.g. "30" or "1m30s".
+func Parse(v string) (time.Duration, error) {
+	var d time.Duration
+	if n, err := strconv.ParseFloat(v, 64); err == nil {
+		d = time.Duration(n * float64(time.Second))
+	} else if d, err = time.ParseDuration(v); err != nil {
+		return 0, fmt.Errorf("deadline: bad timeout %q", v)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("deadline: timeout %q must be positive", v)
+	}
+	return d, nil
+}
+
+// Format formats a timeout for the Header, to the millisecond.
+func Format(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10) + "ms"
+}
+
+// These are the reasons a request's work is recorded as cut short.
+const (
+	// ReasonDeadline is for requests whose client-supplied timeout expired.
+	ReasonDeadline = "deadline"
+	// ReasonCanceled is for requests whose client went away.
+	ReasonCanceled = "canceled"
+)
+
+// Handler applies client-supplied timeouts to requests, and records requests
+// whose work was cut short by the client.
+//
+// Route reports the route a request is handled by, for use as a metric
+// label. If nil, the request's path is used.
+//
+// Requests with a malformed timeout are answered with a 400. If the timeout
+// expires and the handler responds with a server error, a 504 is sent
+// instead.
+func Handler(next http.Handler, route func(*http.Request) string) http.Handler {
+	if route == nil {
+		route = func(r *http.Request) string { return r.URL.Path }
+	}
+	canceled := metric.Must(otel.Meter("clair")).NewInt64Counter(
+		"clair_http_request_cancellations_total",
+		metric.WithDescription("number of http requests whose work was cut short by the client, by reason"),
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		timeout := time.Duration(0)
+		if v := r.Header.Get(Header); v != "" {
+			var err error
+			timeout, err = Parse(v)
+			if err != nil {
+				resp := &je.Response{
+					Code:    "bad-request",
+					Message: err.Error(),
+				}
+				je.Error(w, resp, http.StatusBadRequest)
+				return
+			}
+			var done context.CancelFunc
+			ctx, done = context.WithTimeout(ctx, timeout)
+			defer done()
+			r = r.WithContext(ctx)
+		}
+		start := time.Now()
+		next.ServeHTTP(&writer{ResponseWriter: w, ctx: ctx}, r)
+
+		var reason string
+		switch err := ctx.Err(); {
+		case err == nil:
+			return
+		case errors.Is(err, context.DeadlineExceeded):
+			reason = ReasonDeadline
+		default:
+			reason = ReasonCanceled
+		}
+		p := route(r)
+		canceled.Add(ctx, 1, label.String("http_path", p), label.String("reason", reason))
+		ev := zerolog.Ctx(ctx).Info().
+			Str("component", "middleware/deadline/Handler").
+			Str("method", r.Method).
+			Str("path", p).
+			Str("reason", reason).
+			Dur("elapsed", time.Since(start))
+		if timeout != 0 {
+			ev = ev.Dur("timeout", timeout)
+		}
+		ev.Msg("request canceled by client")
+	})
+}
+
+// Writer replaces server errors with a 504 once the client's timeout has
+// expired, as the error is most likely the cancellation.
+type writer struct {
+	http.ResponseWriter
+	ctx context.Context
+}
+
+func (w *writer) WriteHeader(code int) {
+	if code >= http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		code = http.StatusGatewayTimeout
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher, if the wrapped ResponseWriter does.
+func (w *writer) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Transport returns an http.RoundTripper that sets the Header on requests
+// whose Context has a deadline, so the remote service stops when the caller
+// would.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	d, ok := ctx.Deadline()
+	if !ok {
+		return t.next.RoundTrip(r)
+	}
+	r = r.Clone(ctx)
+	r.Header.Set(Header, Format(time.Until(d)))
+	return t.next.RoundTrip(r)
+}
@@ -0,0 +1,100 @@
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"30":     30 * time.Second,
+		"0.5":    500 * time.Millisecond,
+		"1m30s":  90 * time.Second,
+		"1500ms": 1500 * time.Millisecond,
+	} {
+		got, err := Parse(in)
+		if err != nil {
+			t.Errorf("%q: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: got %v, want %v", in, got, want)
+		}
+	}
+	for _, in := range []string{"", "soon", "0", "-5s"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+	if got, err := Parse(Format(1500 * time.Millisecond)); err != nil || got != 1500*time.Millisecond {
+		t.Errorf("round trip: got %v, %v", got, err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			http.Error(w, r.Context().Err().Error(), http.StatusInternalServerError)
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}), nil)
+
+	for _, tc := range []struct {
+		Header string
+		Want   int
+	}{
+		{"", http.StatusNoContent},
+		{"10ms", http.StatusGatewayTimeout},
+		{"never", http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.Header != "" {
+			r.Header.Set(Header, tc.Header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got := w.Code; got != tc.Want {
+			t.Errorf("%q: got status %d, want %d", tc.Header, got, tc.Want)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(Header)
+	}))
+	defer srv.Close()
+	c := &http.Client{Transport: Transport(nil)}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	d, err := Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d <= 0 || d > time.Minute {
+		t.Errorf("forwarded timeout %v out of range", d)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	res, err = c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got != "" {
+		t.Errorf("unexpected timeout %q without a deadline", got)
+	}
+}
@@ -0,0 +1,236 @@
+// Package rbac authorizes API requests by mapping roles or scopes in the
+// request's JWT to the operations they permit.
+package rbac
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	je "github.com/quay/claircore/pkg/jsonerr"
+	"github.com/rs/zerolog"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+// Permission names a class of operations.
+type Permission string
+
+// These are the permissions routes can require.
+const (
+	// IndexerWrite allows submitting manifests and layers for indexing and
+	// deleting index reports.
+	IndexerWrite Permission = "indexer.write"
+	// ReportsRead allows reading index and vulnerability reports and
+	// related information.
+	ReportsRead Permission = "reports.read"
+	// NotificationsRead allows reading notifications.
+	NotificationsRead Permission = "notifications.read"
+	// NotificationsWrite allows deleting notifications.
+	NotificationsWrite Permission = "notifications.write"
+	// Admin allows everything, including changing Clair's state and the
+	// internal API.
+	Admin Permission = "admin"
+)
+
+var permissions = []Permission{IndexerWrite, ReportsRead, NotificationsRead, NotificationsWrite, Admin}
+
+// ParsePermission returns the named Permission.
+func ParsePermission(s string) (Permission, error) {
+	for _, p := range permissions {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown permission %q", s)
+}
+
+// Rule is the Permission needed for requests matching a path and method.
+//
+// A Path ending in "/" matches every path with it as a prefix, like an
+// http.ServeMux pattern. Otherwise, the path must match exactly. An empty
+// Methods matches every method.
+type Rule struct {
+	Path       string
+	Methods    []string
+	Permission Permission
+}
+
+func (r *Rule) match(req *http.Request) bool {
+	p := req.URL.Path
+	if strings.HasSuffix(r.Path, "/") {
+		if !strings.HasPrefix(p, r.Path) {
+			return false
+		}
+	} else if p != r.Path {
+		return false
+	}
+	if len(r.Methods) == 0 {
+		return true
+	}
+	for _, m := range r.Methods {
+		if m == req.Method {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy decides which requests are permitted.
+type Policy struct {
+	// Claim is the JWT claim holding the principal's roles. It may be a
+	// list of strings or a single space-separated string, like the OAuth
+	// "scope" claim.
+	Claim string
+	// Roles maps role names to the permissions they grant. A claimed value
+	// that isn't a role but names a Permission grants that Permission.
+	Roles map[string][]Permission
+	// Default is granted to every principal.
+	Default []Permission
+	// Rules are checked in order; the first match decides the Permission
+	// needed. Requests matching no Rule need Admin.
+	Rules []Rule
+	// Public are path prefixes that need no permissions.
+	Public []string
+	// Trusted reports whether a request comes from another Clair service.
+	// Those requests are permitted everything.
+	Trusted func(*http.Request) bool
+}
+
+// Handler returns an http.Handler that rejects requests not permitted by the
+// Policy.
+//
+// Handler should be wrapped by authentication middleware, as it doesn't
+// verify tokens itself.
+func Handler(next http.Handler, p *Policy) http.Handler {
+	return &handler{next: next, p: p}
+}
+
+type handler struct {
+	next http.Handler
+	p    *Policy
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	need := h.p.needs(r)
+	if need == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	cl, _ := claims(r)
+	if h.p.Trusted != nil && h.p.Trusted(r) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if h.p.granted(cl.values(h.p.Claim), need) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	zerolog.Ctx(r.Context()).Debug().
+		Str("component", "middleware/rbac/handler.ServeHTTP").
+		Str("path", r.URL.Path).
+		Str("method", r.Method).
+		Str("sub", cl.sub).
+		Str("permission", string(need)).
+		Msg("rejecting request lacking permission")
+	resp := &je.Response{
+		Code:    "forbidden",
+		Message: fmt.Sprintf("request requires the %q permission", need),
+	}
+	je.Error(w, resp, http.StatusForbidden)
+}
+
+// Needs reports the Permission needed for the request, or "" if it's public.
+func (p *Policy) needs(r *http.Request) Permission {
+	for _, pre := range p.Public {
+		if strings.HasPrefix(r.URL.Path, pre) {
+			return ""
+		}
+	}
+	for i := range p.Rules {
+		if p.Rules[i].match(r) {
+			return p.Rules[i].Permission
+		}
+	}
+	return Admin
+}
+
+// Granted reports whether the claimed values grant the Permission.
+func (p *Policy) granted(vs []string, need Permission) bool {
+	has := func(ps []Permission) bool {
+		for _, g := range ps {
+			if g == need || g == Admin {
+				return true
+			}
+		}
+		return false
+	}
+	if has(p.Default) {
+		return true
+	}
+	for _, v := range vs {
+		if ps, ok := p.Roles[v]; ok {
+			if has(ps) {
+				return true
+			}
+			continue
+		}
+		if g, err := ParsePermission(v); err == nil && has([]Permission{g}) {
+			return true
+		}
+	}
+	return false
+}
+
+type tokenClaims struct {
+	iss, sub string
+	all      map[string]interface{}
+}
+
+// Values returns the strings in the named claim.
+func (c *tokenClaims) values(name string) []string {
+	switch v := c.all[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Claims pulls the claims out of the request's bearer token, without
+// checking its signature. Claims learned by the authentication middleware,
+// like those of introspected opaque tokens, are used if present.
+func claims(r *http.Request) (tokenClaims, bool) {
+	var out tokenClaims
+	if all, ok := auth.Claims(r.Context()); ok {
+		out.all = all
+		out.iss, _ = all["iss"].(string)
+		out.sub, _ = all["sub"].(string)
+		return out, true
+	}
+	for _, h := range r.Header["Authorization"] {
+		if !strings.HasPrefix(h, "Bearer ") {
+			continue
+		}
+		tok, err := jwt.ParseSigned(strings.TrimPrefix(h, "Bearer "))
+		if err != nil {
+			continue
+		}
+		var std jwt.Claims
+		if err := tok.UnsafeClaimsWithoutVerification(&std, &out.all); err != nil {
+			continue
+		}
+		out.iss, out.sub = std.Issuer, std.Subject
+		return out, true
+	}
+	return out, false
+}
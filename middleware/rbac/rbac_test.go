@@ -0,0 +1,134 @@
+package rbac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func token(t *testing.T, iss string, extra map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte("deadbeefdeadbeef")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := jwt.Signed(signer).Claims(jwt.Claims{Issuer: iss, Subject: "someone"}).Claims(extra).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestHandler(t *testing.T) {
+	const (
+		index   = "/indexer/api/v1/index_report"
+		report  = "/indexer/api/v1/index_report/"
+		openapi = "/openapi/v1"
+		intra   = "/matcher/api/v1/internal/update_diff/"
+	)
+	p := &Policy{
+		Claim: "roles",
+		Roles: map[string][]Permission{
+			"ci":     {IndexerWrite, ReportsRead},
+			"viewer": {ReportsRead},
+		},
+		Rules: []Rule{
+			{Path: index, Permission: IndexerWrite},
+			{Path: report, Methods: []string{http.MethodGet}, Permission: ReportsRead},
+			{Path: report, Methods: []string{http.MethodDelete}, Permission: IndexerWrite},
+		},
+		Public: []string{openapi},
+		Trusted: func(r *http.Request) bool {
+			cl, _ := claims(r)
+			return cl.iss == "clair-intraservice"
+		},
+	}
+	type testcase struct {
+		Name   string
+		Method string
+		Path   string
+		Token  string
+		Status int
+	}
+	tt := []testcase{
+		{
+			Name:   "Role",
+			Method: http.MethodPost,
+			Path:   index,
+			Token:  token(t, "quay", map[string]interface{}{"roles": []string{"ci"}}),
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "RoleLacking",
+			Method: http.MethodDelete,
+			Path:   report + "sha256:abc",
+			Token:  token(t, "quay", map[string]interface{}{"roles": []string{"viewer"}}),
+			Status: http.StatusForbidden,
+		},
+		{
+			Name:   "RoleMethod",
+			Method: http.MethodGet,
+			Path:   report + "sha256:abc",
+			Token:  token(t, "quay", map[string]interface{}{"roles": []string{"viewer"}}),
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "Scope",
+			Method: http.MethodPost,
+			Path:   index,
+			Token:  token(t, "quay", map[string]interface{}{"roles": "reports.read indexer.write"}),
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "Admin",
+			Method: http.MethodGet,
+			Path:   intra,
+			Token:  token(t, "quay", map[string]interface{}{"roles": []string{"admin"}}),
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "Unlisted",
+			Method: http.MethodGet,
+			Path:   intra,
+			Token:  token(t, "quay", map[string]interface{}{"roles": []string{"ci"}}),
+			Status: http.StatusForbidden,
+		},
+		{
+			Name:   "Trusted",
+			Method: http.MethodGet,
+			Path:   intra,
+			Token:  token(t, "clair-intraservice", nil),
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "Public",
+			Method: http.MethodGet,
+			Path:   openapi,
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "NoRoles",
+			Method: http.MethodGet,
+			Path:   report + "sha256:abc",
+			Token:  token(t, "quay", nil),
+			Status: http.StatusForbidden,
+		},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := Handler(next, p)
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.Method, tc.Path, nil)
+			if tc.Token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.Token)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if got, want := w.Code, tc.Status; got != want {
+				t.Errorf("got: %d, want: %d", got, want)
+			}
+		})
+	}
+}
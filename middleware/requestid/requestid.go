@@ -0,0 +1,95 @@
+// Package requestid tags requests with an ID, so the work done on behalf of
+// one request can be followed through the logs of every Clair service it
+// touches.
+//
+// Clients may choose a request's ID with the "X-Request-Id" header, otherwise
+// the server picks one. Either way, the ID is sent back in the response,
+// added to the request's logger, and passed along by intra-service clients.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Header is the request and response header holding the ID.
+const Header = "X-Request-Id"
+
+// MaxLen is the longest ID accepted from a client. Longer or otherwise
+// unusable IDs are replaced.
+const MaxLen = 128
+
+type ctxKey struct{}
+
+// WithID returns a Context carrying the ID.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext reports the request ID carried by the Context, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Valid reports whether id is usable as a request ID: not empty, not too
+// long, and only printable ASCII without spaces.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Handler attaches an ID to every request, honoring one sent by the client
+// if it's Valid. The ID is set on the response before next runs, so error
+// responses carry it too, and the request's logger logs it as
+// "request_id".
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !Valid(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(Header, id)
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx).With().
+			Str("request_id", id).
+			Logger()
+		ctx = log.WithContext(WithID(ctx, id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Transport returns an http.RoundTripper that sets the Header on requests
+// whose Context carries an ID, so the remote service logs the same one.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	id, ok := FromContext(ctx)
+	if !ok {
+		return t.next.RoundTrip(r)
+	}
+	r = r.Clone(ctx)
+	r.Header.Set(Header, id)
+	return t.next.RoundTrip(r)
+}
@@ -0,0 +1,75 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var seen string
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := FromContext(r.Context())
+		if !ok {
+			t.Error("no id in context")
+		}
+		seen = id
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+
+	for _, tc := range []struct {
+		Name   string
+		Header string
+		Honor  bool
+	}{
+		{"Client", "abc-123", true},
+		{"None", "", false},
+		{"Space", "abc 123", false},
+		{"Long", strings.Repeat("a", MaxLen+1), false},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.Header != "" {
+				req.Header.Set(Header, tc.Header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			got := rec.Header().Get(Header)
+			if got != seen {
+				t.Errorf("response has %q, handler saw %q", got, seen)
+			}
+			if !Valid(got) {
+				t.Errorf("invalid id %q", got)
+			}
+			if honored := got == tc.Header; honored != tc.Honor {
+				t.Errorf("got: %q, sent: %q", got, tc.Header)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-got", r.Header.Get(Header))
+	}))
+	defer srv.Close()
+	c := &http.Client{Transport: Transport(srv.Client().Transport)}
+
+	req, err := http.NewRequestWithContext(WithID(context.Background(), "abc-123"), http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.Header.Get("x-got"), "abc-123"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if req.Header.Get(Header) != "" {
+		t.Error("caller's request modified")
+	}
+}
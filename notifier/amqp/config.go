@@ -7,17 +7,44 @@ import (
 	"io/ioutil"
 	"net/url"
 	"strings"
+	"time"
+
+	"github.com/quay/clair/v4/notifier"
+	samqp "github.com/streadway/amqp"
 )
 
 type TLS struct {
 	// The filesystem path where a root CA can be read.
 	RootCA string `yaml:"root_ca"`
 	// The filesystem path where a tls certificate can be read.
+	//
+	// The certificate and key are optional, but must be provided together.
+	// If provided, they're presented to brokers as a client certificate.
 	Cert string `yaml:"cert"`
 	// The filesystem path where a tls private key can be read.
 	Key string `yaml:"key"`
 }
 
+// These are the supported SASL mechanisms.
+const (
+	SASLPlain    = "plain"
+	SASLAMQPlain = "amqplain"
+	SASLExternal = "external"
+)
+
+// SASL configures how the deliverer authenticates to brokers.
+type SASL struct {
+	// One of "plain" (the default), "amqplain", or "external".
+	//
+	// "external" authenticates with the TLS client certificate, and so
+	// requires one to be configured.
+	Mechanism string `yaml:"mechanism"`
+	// The credentials used by "plain" and "amqplain". If unset, the
+	// credentials in the broker URI are used.
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
+}
+
 // Exchange are the required fields necessary to check
 // the existence of an Exchange
 //
@@ -53,13 +80,26 @@ type Config struct {
 	// If 0 or 1 is provided no rollup occurs and each notification is delivered
 	// separately.
 	Rollup int
+	// The largest message body, in bytes, of notifications delivered when
+	// Direct is true. Blocks of notifications that would be larger are
+	// split, so they aren't rejected by the broker's frame limits.
+	//
+	// If 0, blocks are only split by Rollup.
+	MaxMessageSize int `yaml:"max_message_size"`
 	// The AMQP exchange notifications will be delivered to.
 	// A passive declare is performed and if the exchange does not exist
 	// the declare will fail.
 	Exchange Exchange `yaml:"exchange"`
 	// The routing key used to route notifications to the desired queue.
 	RoutingKey string `yaml:"routing_key"`
+	// Tenants maps tenants to the routing keys their notifications are
+	// published with, instead of RoutingKey, when tenancy is configured.
+	// Tenants not listed aren't delivered notifications.
+	Tenants map[string]string `yaml:"tenants"`
 	// The callback url where notifications are retrieved.
+	//
+	// If Direct is true, it's optional, and is sent in a header with each
+	// message for consumers to retrieve the whole set.
 	Callback string
 	callback url.URL
 	// A list of AMQP compliant URI scheme. see: https://www.rabbitmq.com/uri-spec.html
@@ -71,6 +111,26 @@ type Config struct {
 	URIs []string `yaml:"uris"`
 	TLS  *TLS     `yaml:"tls"`
 	tls  *tls.Config
+	// SASL configures authentication to brokers.
+	//
+	// If nil, the credentials in the broker URI are used.
+	SASL *SASL `yaml:"sasl"`
+	sasl []samqp.Authentication
+	// Heartbeat is the interval heartbeats are requested at. Zero uses the
+	// broker's interval.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// Reconnect configures the backoff between failed attempts to connect
+	// to the brokers.
+	Reconnect *notifier.Reconnect `yaml:"reconnect"`
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+	// CloudEvents, if provided, wraps messages in CloudEvents envelopes.
+	//
+	// In binary mode, attributes are sent as "cloudEvents:"-prefixed
+	// message headers.
+	CloudEvents *notifier.CloudEvents `yaml:"cloudevents"`
 }
 
 // Validate confirms configuration is valid and fills in private members
@@ -84,21 +144,13 @@ func (c *Config) Validate() (Config, error) {
 		return conf, fmt.Errorf("AMQP config requires the routing key field")
 	}
 	for _, uri := range c.URIs {
-		if strings.HasPrefix(uri, "amqps://") {
-			if c.TLS.RootCA == "" {
-				return conf, fmt.Errorf("amqps:// broker requires tls_root_ca")
-			}
-			if c.TLS.Cert == "" {
-				return conf, fmt.Errorf("amqps:// broker requires tls_cert")
-			}
-			if c.TLS.Key == "" {
-				return conf, fmt.Errorf("amqps:// broker requires tls_key")
-			}
+		if strings.HasPrefix(uri, "amqps://") && c.TLS == nil {
+			return conf, fmt.Errorf("amqps:// broker requires tls configuration")
 		}
 	}
 
 	if c.TLS != nil {
-		if c.TLS.Cert == "" || c.TLS.Key == "" {
+		if (c.TLS.Cert == "") != (c.TLS.Key == "") {
 			return conf, fmt.Errorf("both tls cert and key are required")
 		}
 		TLS := tls.Config{}
@@ -117,21 +169,99 @@ func (c *Config) Validate() (Config, error) {
 			TLS.RootCAs.AppendCertsFromPEM(ca)
 		}
 
-		var err error
-		var cert tls.Certificate
-		if cert, err = tls.LoadX509KeyPair(c.TLS.Cert, c.TLS.Key); err != nil {
-			return conf, fmt.Errorf("failed to read x509 cert and key pair: %v", err)
+		if c.TLS.Cert != "" {
+			cert, err := tls.LoadX509KeyPair(c.TLS.Cert, c.TLS.Key)
+			if err != nil {
+				return conf, fmt.Errorf("failed to read x509 cert and key pair: %v", err)
+			}
+			TLS.Certificates = append(TLS.Certificates, cert)
 		}
-		TLS.Certificates = append(TLS.Certificates, cert)
 		conf.tls = &TLS
 	}
 
-	if !c.Direct {
+	if c.SASL != nil {
+		switch strings.ToLower(c.SASL.Mechanism) {
+		case "", SASLPlain:
+			if c.SASL.Username != "" {
+				conf.sasl = []samqp.Authentication{&samqp.PlainAuth{Username: c.SASL.Username, Password: c.SASL.Password}}
+			}
+		case SASLAMQPlain:
+			conf.sasl = []samqp.Authentication{&samqp.AMQPlainAuth{Username: c.SASL.Username, Password: c.SASL.Password}}
+		case SASLExternal:
+			if conf.tls == nil || len(conf.tls.Certificates) == 0 {
+				return conf, fmt.Errorf("sasl mechanism %q requires a tls client certificate", c.SASL.Mechanism)
+			}
+			conf.sasl = []samqp.Authentication{externalAuth{}}
+		default:
+			if strings.HasPrefix(strings.ToLower(c.SASL.Mechanism), "scram") {
+				// SCRAM needs the client to answer the broker's
+				// challenges, which the AMQP client doesn't support.
+				return conf, fmt.Errorf("unsupported sasl mechanism %q: the amqp client can't answer the broker's challenges; use %q over tls or %q", c.SASL.Mechanism, SASLPlain, SASLExternal)
+			}
+			return conf, fmt.Errorf("unsupported sasl mechanism %q", c.SASL.Mechanism)
+		}
+	}
+
+	if c.MaxMessageSize < 0 {
+		return conf, fmt.Errorf("max_message_size can't be negative")
+	}
+	if !c.Direct || c.Callback != "" {
 		callback, err := url.Parse(c.Callback)
 		if err != nil {
 			return conf, fmt.Errorf("failed to parse callback url")
 		}
 		conf.callback = *callback
 	}
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+
+	ce, err := c.CloudEvents.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.CloudEvents = ce
 	return conf, nil
 }
+
+// ExternalAuth is the SASL EXTERNAL mechanism, where the broker takes the
+// identity from the TLS client certificate.
+type externalAuth struct{}
+
+func (externalAuth) Mechanism() string { return "EXTERNAL" }
+func (externalAuth) Response() string  { return "" }
+
+// Publishing returns the message for a JSON body, wrapped in a CloudEvent of
+// the provided type, id, and subject if configured.
+func (c *Config) publishing(typ, id, subject string, b []byte) (samqp.Publishing, error) {
+	msg := samqp.Publishing{
+		ContentType: "application/json",
+		AppId:       "clairV4-notifier",
+		Body:        b,
+	}
+	ce := c.CloudEvents
+	if ce == nil {
+		return msg, nil
+	}
+	ev := ce.Event(typ, id, subject, b)
+	var err error
+	msg.Body, msg.ContentType, err = ce.Encode(ev)
+	if err != nil {
+		return msg, err
+	}
+	msg.MessageId = ev.ID
+	msg.Timestamp = ev.Time
+	if ce.Mode == notifier.Binary {
+		msg.Headers = samqp.Table{}
+		for k, v := range ev.Attributes() {
+			// The content type property stands in for the
+			// "datacontenttype" attribute.
+			if k != "datacontenttype" {
+				msg.Headers["cloudEvents:"+k] = v
+			}
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,38 @@
+package amqp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigSASL(t *testing.T) {
+	tt := []struct {
+		Mechanism string
+		Err       string
+	}{
+		{Mechanism: ""},
+		{Mechanism: "plain"},
+		{Mechanism: "AMQPLAIN"},
+		{Mechanism: "external", Err: "requires a tls client certificate"},
+		{Mechanism: "scram-sha-256", Err: "can't answer the broker's challenges"},
+		{Mechanism: "SCRAM-SHA-1", Err: "can't answer the broker's challenges"},
+		{Mechanism: "gssapi", Err: "unsupported sasl mechanism"},
+	}
+	for _, tc := range tt {
+		c := Config{
+			Exchange:   Exchange{Name: "clair", Type: "direct"},
+			RoutingKey: "notifications",
+			Callback:   "http://clair-notifier/notifier/api/v1/notification",
+			SASL:       &SASL{Mechanism: tc.Mechanism, Username: "clair", Password: "hunter2"},
+		}
+		_, err := c.Validate()
+		switch {
+		case tc.Err == "" && err != nil:
+			t.Errorf("%q: unexpected error: %v", tc.Mechanism, err)
+		case tc.Err != "" && err == nil:
+			t.Errorf("%q: expected error", tc.Mechanism)
+		case tc.Err != "" && !strings.Contains(err.Error(), tc.Err):
+			t.Errorf("%q: got: %v, want: %q", tc.Mechanism, err, tc.Err)
+		}
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/google/uuid"
 	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/notifier"
-	samqp "github.com/streadway/amqp"
 )
 
 // Deliverer is an AMQP deliverer which publishes a notifier.Callback to the
@@ -29,15 +28,24 @@ func New(conf Config) (*Deliverer, error) {
 	if c, err = conf.Validate(); err != nil {
 		return nil, err
 	}
-	fo := &failOver{
-		Config: c,
-	}
+	fo := newFailOver(c)
 	return &Deliverer{
 		conf: c,
 		fo:   fo,
 	}, nil
 }
 
+// ForTenant implements notifier.TenantDeliverer.
+func (d *Deliverer) ForTenant(t string) (notifier.Deliverer, bool) {
+	key, ok := d.conf.Tenants[t]
+	if !ok {
+		return nil, false
+	}
+	c := *d
+	c.conf.RoutingKey = key
+	return &c, true
+}
+
 func (d *Deliverer) Name() string {
 	return fmt.Sprintf("amqp-%s", d.conf.Exchange.Name)
 }
@@ -45,17 +53,18 @@ func (d *Deliverer) Name() string {
 func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	conn, err := d.fo.Connection(ctx)
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 
 	ch, err := conn.Channel()
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	defer ch.Close()
 
 	callback := d.conf.callback
 	callback.Path = path.Join(callback.Path, nID.String())
+	d.conf.Filter.SetQuery(&callback)
 
 	cb := notifier.Callback{
 		NotificationID: nID,
@@ -63,12 +72,11 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	}
 	b, err := json.Marshal(&cb)
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
-	msg := samqp.Publishing{
-		ContentType: "application/json",
-		AppId:       "clairV4-notifier",
-		Body:        b,
+	msg, err := d.conf.publishing(notifier.EventCallback, nID.String(), nID.String(), b)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	err = ch.Publish(
 		d.conf.Exchange.Name,
@@ -78,7 +86,7 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 		msg,
 	)
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	return nil
 }
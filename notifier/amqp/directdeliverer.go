@@ -1,10 +1,9 @@
 package amqp
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"path"
 
 	"github.com/google/uuid"
 	clairerror "github.com/quay/clair/v4/clair-error"
@@ -30,9 +29,7 @@ func NewDirectDeliverer(conf Config) (*DirectDeliverer, error) {
 	if c, err = conf.Validate(); err != nil {
 		return nil, err
 	}
-	fo := &failOver{
-		Config: c,
-	}
+	fo := newFailOver(c)
 	return &DirectDeliverer{
 		conf: c,
 		n:    []notifier.Notification{},
@@ -40,6 +37,18 @@ func NewDirectDeliverer(conf Config) (*DirectDeliverer, error) {
 	}, nil
 }
 
+// ForTenant implements notifier.TenantDeliverer.
+func (d *DirectDeliverer) ForTenant(t string) (notifier.Deliverer, bool) {
+	key, ok := d.conf.Tenants[t]
+	if !ok {
+		return nil, false
+	}
+	c := *d
+	c.conf.RoutingKey = key
+	c.n = nil
+	return &c, true
+}
+
 func (d *DirectDeliverer) Name() string {
 	return fmt.Sprintf("amqp-direct-%s", d.conf.Exchange.Name)
 }
@@ -59,51 +68,62 @@ func (d *DirectDeliverer) Notifications(ctx context.Context, n []notifier.Notifi
 	return nil
 }
 
-func (d *DirectDeliverer) Deliver(ctx context.Context, _ uuid.UUID) error {
+// Deliver publishes the buffered notifications, split into messages as
+// configured, in a single transaction. Every message carries headers placing
+// it in the notification set.
+func (d *DirectDeliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	conn, err := d.fo.Connection(ctx)
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 
 	ch, err := conn.Channel()
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	defer ch.Close()
 
 	err = ch.Tx()
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	// TODO: can tx.Rollback be safely defered?
 
-	// block loop publishing smaller blocks of max(rollup) length via reslicing.
-	var rollup int = d.conf.Rollup
-	if rollup == 0 {
-		rollup++
+	// With a callback configured, consumers missing part of the set can
+	// page through all of it.
+	var callback string
+	if d.conf.Callback != "" {
+		u := d.conf.callback
+		u.Path = path.Join(u.Path, nID.String())
+		d.conf.Filter.SetQuery(&u)
+		callback = u.String()
 	}
-
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	var currentBlock []notifier.Notification
-	for bs, be := 0, rollup; bs < len(d.n); bs, be = be, be+rollup {
-		buf.Reset()
-		// if block-end exceeds array bounds, slice block underflow.
-		// next block-start will cause loop to exit.
-		if be > len(d.n) {
-			be = len(d.n)
+	chunks, err := notifier.Chunks(d.n, d.conf.Rollup, d.conf.MaxMessageSize)
+	if err != nil {
+		ch.TxRollback()
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	for _, c := range chunks {
+		// A block of a single notification is about that manifest.
+		var subject string
+		if len(c.Notifications) == 1 {
+			subject = c.Notifications[0].Manifest.String()
 		}
-
-		currentBlock = d.n[bs:be]
-		err := enc.Encode(&currentBlock)
+		// Chunking is deterministic, so a redelivered chunk keeps its id.
+		id := fmt.Sprintf("%s/%d", nID, c.Seq)
+		msg, err := d.conf.publishing(notifier.EventNotifications, id, subject, c.Body)
 		if err != nil {
 			ch.TxRollback()
-			return &clairerror.ErrDeliveryFailed{err}
+			return &clairerror.ErrDeliveryFailed{E: err}
+		}
+		if msg.Headers == nil {
+			msg.Headers = samqp.Table{}
 		}
-		msg := samqp.Publishing{
-			ContentType: "application/json",
-			AppId:       "clairV4-notifier",
-			Body:        buf.Bytes(),
+		msg.Headers[notifier.HeaderNotificationID] = nID.String()
+		msg.Headers[notifier.HeaderChunk] = int32(c.Seq)
+		msg.Headers[notifier.HeaderChunks] = int32(c.Total)
+		if callback != "" {
+			msg.Headers[notifier.HeaderCallback] = callback
 		}
 		err = ch.Publish(
 			d.conf.Exchange.Name,
@@ -114,13 +134,13 @@ func (d *DirectDeliverer) Deliver(ctx context.Context, _ uuid.UUID) error {
 		)
 		if err != nil {
 			ch.TxRollback()
-			return &clairerror.ErrDeliveryFailed{err}
+			return &clairerror.ErrDeliveryFailed{E: err}
 		}
 	}
 
 	err = ch.TxCommit()
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	return nil
 }
@@ -7,6 +7,8 @@ import (
 
 	"github.com/rs/zerolog"
 	samqp "github.com/streadway/amqp"
+
+	"github.com/quay/clair/v4/notifier"
 )
 
 // failOver will return the first successful connection made against the provided
@@ -16,7 +18,15 @@ import (
 type failOver struct {
 	Config
 	sync.RWMutex
-	conn *samqp.Connection
+	conn    *samqp.Connection
+	backoff *notifier.Backoff
+}
+
+func newFailOver(c Config) *failOver {
+	return &failOver{
+		Config:  c,
+		backoff: notifier.NewBackoff(c.Reconnect),
+	}
 }
 
 // Connection returns an AMQP connection to the first broker which successfully
@@ -38,15 +48,23 @@ func (f *failOver) Connection(ctx context.Context) (*samqp.Connection, error) {
 	}
 	f.RUnlock()
 
+	if err := f.backoff.Allow(); err != nil {
+		return nil, err
+	}
+	cfg := samqp.Config{
+		SASL:            f.sasl,
+		Heartbeat:       f.Heartbeat,
+		TLSClientConfig: f.tls,
+	}
 	for _, uri := range f.URIs {
-		// safe to always call DialTLS per docs:
-		// 'DialTLS will use the provided tls.Config when it encounters an amqps:// scheme and will dial a plain connection when it encounters an amqp:// scheme.'
-		conn, err := samqp.DialTLS(uri, f.tls)
+		// the tls.Config is only used for amqps:// schemes; amqp:// schemes
+		// dial a plain connection.
+		conn, err := samqp.DialConfig(uri, cfg)
 		if err != nil {
 			if conn != nil {
 				conn.Close()
 			}
-			log.Info().Str("broker", uri).Msg("failed to connect to AMQP broker. attempting next broker")
+			log.Info().Err(err).Str("broker", uri).Msg("failed to connect to AMQP broker. attempting next broker")
 			continue
 		}
 		ch, err := conn.Channel()
@@ -79,6 +97,7 @@ func (f *failOver) Connection(ctx context.Context) (*samqp.Connection, error) {
 			}
 		}
 		ch.Close()
+		f.backoff.Succeeded()
 
 		f.Lock()
 		defer f.Unlock()
@@ -91,5 +110,6 @@ func (f *failOver) Connection(ctx context.Context) (*samqp.Connection, error) {
 		}
 		return f.conn, nil
 	}
-	return nil, fmt.Errorf("all failover URIs failed to connect")
+	wait := f.backoff.Failed()
+	return nil, fmt.Errorf("all failover URIs failed to connect, retrying in %v", wait)
 }
@@ -0,0 +1,50 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/annotations"
+)
+
+// AttachAnnotations sets the Annotations of the notifications to those of
+// their manifest, retrieving each manifest's annotations once.
+//
+// If the indexer doesn't record annotations, the notifications are left as
+// they are.
+func (p *Processor) attachAnnotations(ctx context.Context, ns []Notification) error {
+	a, ok := annotator(p.indexer)
+	if !ok {
+		return nil
+	}
+	seen := make(map[string]map[string]string)
+	for i := range ns {
+		n := &ns[i]
+		k := n.Manifest.String()
+		as, ok := seen[k]
+		if !ok {
+			var err error
+			as, err = a.Annotations(ctx, n.Manifest)
+			if err != nil {
+				return err
+			}
+			seen[k] = as
+		}
+		if len(as) != 0 {
+			n.Annotations = as
+		}
+	}
+	return nil
+}
+
+// Annotator finds an annotations.Annotator among the wrapped indexers, if
+// there is one.
+func annotator(s indexer.Service) (annotations.Annotator, bool) {
+	var a annotations.Annotator
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		a, ok = s.(annotations.Annotator)
+		return ok
+	})
+	return a, ok
+}
@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AttemptStatus is the outcome of a delivery attempt.
+type AttemptStatus string
+
+const (
+	// The deliverer accepted the notification id.
+	AttemptDelivered AttemptStatus = "delivered"
+	// The deliverer failed to deliver the notification id. Delivery is
+	// retried on the next interval.
+	AttemptFailed AttemptStatus = "failed"
+	// No notifications passed the deliverer's filter, so nothing was sent.
+	AttemptFiltered AttemptStatus = "filtered"
+)
+
+// Attempt records a single try at delivering a notification id.
+type Attempt struct {
+	// the id of the delivered notifications
+	NotificationID uuid.UUID `json:"notification_id"`
+	// the name of the deliverer making the attempt
+	Deliverer string `json:"deliverer"`
+	// where the deliverer sends notifications, if it reports it
+	Target string `json:"target,omitempty"`
+	// when the attempt finished
+	TS time.Time `json:"timestamp"`
+	// the outcome of the attempt
+	Status AttemptStatus `json:"status"`
+	// the response code the target returned, if there was one
+	ResponseCode int `json:"response_code,omitempty"`
+	// why the attempt failed
+	Error string `json:"error,omitempty"`
+}
+
+// Attempter implements persistence methods for Attempt models
+type Attempter interface {
+	// PutAttempt records a delivery attempt.
+	PutAttempt(ctx context.Context, a Attempt) error
+	// Attempts returns the delivery attempts for the provided notification
+	// id, oldest first.
+	Attempts(ctx context.Context, id uuid.UUID) ([]Attempt, error)
+}
+
+// DeliveryStatus reports a deliverer's attempts at delivering a notification
+// id.
+type DeliveryStatus struct {
+	// the name of the deliverer
+	Deliverer string `json:"deliverer"`
+	// where the deliverer sends notifications, if it reports it
+	Target string `json:"target,omitempty"`
+	// the deliverer's attempts, oldest first
+	Attempts []Attempt `json:"attempts"`
+	// when delivery is next expected to be attempted, if the notification
+	// id is still waiting to be delivered
+	NextAttempt *time.Time `json:"next_attempt,omitempty"`
+}
@@ -0,0 +1,136 @@
+package aws
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Config provides configuration for an SNS or SQS deliverer.
+//
+// Exactly one of "TopicARN" or "QueueURL" must be provided.
+type Config struct {
+	// Configures the deliverer to deliver notifications directly to the
+	// configured topic or queue.
+	//
+	// If true "Callback" is ignored.
+	// If false a notifier.Callback is delivered and clients utilize the
+	// pagination API to retrieve.
+	Direct bool `yaml:"direct"`
+	// Specifies the number of notifications delivered in single message
+	// when Direct is true.
+	//
+	// Ignored if Direct is not true
+	// If 0 or 1 is provided no rollup occurs and each notification is delivered
+	// separately.
+	Rollup int `yaml:"rollup"`
+	// The callback url where notifications are retrieved.
+	Callback string `yaml:"callback"`
+	callback url.URL
+	// The SNS topic notifications will be published to.
+	// The topic must already exist.
+	TopicARN string `yaml:"topic_arn"`
+	// The SQS queue notifications will be sent to.
+	// The queue must already exist.
+	QueueURL string `yaml:"queue_url"`
+	// The region of the topic or queue. If empty, it's taken from the topic
+	// ARN or queue URL.
+	Region string `yaml:"region"`
+	// The SNS API root. Mostly useful for emulators and testing.
+	//
+	// SQS requests are always sent to the queue URL.
+	Endpoint string `yaml:"endpoint"`
+	url      *url.URL
+	// A role to assume before publishing.
+	//
+	// Credentials are otherwise found as the AWS SDKs do: from the
+	// environment, a web identity token, or the container or instance role.
+	RoleARN string `yaml:"role_arn"`
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+}
+
+// Validate confirms configuration is valid and fills in private members
+// with parsed values on success.
+func (c *Config) Validate() (Config, error) {
+	conf := *c
+	switch {
+	case c.TopicARN == "" && c.QueueURL == "":
+		return conf, fmt.Errorf("aws config requires the topic_arn or queue_url field")
+	case c.TopicARN != "" && c.QueueURL != "":
+		return conf, fmt.Errorf("aws config requires only one of the topic_arn or queue_url fields")
+	}
+
+	if !c.Direct {
+		callback, err := url.Parse(c.Callback)
+		if err != nil {
+			return conf, fmt.Errorf("failed to parse callback url")
+		}
+		conf.callback = *callback
+	}
+
+	if c.QueueURL != "" {
+		u, err := url.Parse(c.QueueURL)
+		if err != nil {
+			return conf, fmt.Errorf("failed to parse queue url: %v", err)
+		}
+		conf.url = u
+		if conf.Region == "" {
+			conf.Region = queueRegion(u)
+		}
+	} else {
+		if conf.Region == "" {
+			// arn:partition:sns:region:account:name
+			if fs := strings.Split(c.TopicARN, ":"); len(fs) == 6 {
+				conf.Region = fs[3]
+			}
+		}
+		ep := c.Endpoint
+		if ep == "" && conf.Region != "" {
+			ep = fmt.Sprintf("https://sns.%s.amazonaws.com/", conf.Region)
+		}
+		u, err := url.Parse(ep)
+		if err != nil {
+			return conf, fmt.Errorf("failed to parse endpoint url: %v", err)
+		}
+		conf.url = u
+	}
+	if conf.Region == "" {
+		return conf, fmt.Errorf("aws config requires the region field")
+	}
+
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+	return conf, nil
+}
+
+// Name reports the topic or queue name.
+func (c *Config) name() string {
+	if c.QueueURL != "" {
+		return "sqs-" + path.Base(c.url.Path)
+	}
+	fs := strings.Split(c.TopicARN, ":")
+	return "sns-" + fs[len(fs)-1]
+}
+
+// QueueRegion reports the region in a queue URL, which look like
+// "https://sqs.us-east-2.amazonaws.com/123456789012/queue" or, in the
+// legacy form, "https://us-east-2.queue.amazonaws.com/123456789012/queue".
+func queueRegion(u *url.URL) string {
+	ls := strings.Split(u.Hostname(), ".")
+	switch {
+	case len(ls) > 2 && ls[0] == "sqs":
+		return ls[1]
+	case len(ls) > 2 && ls[1] == "queue":
+		return ls[0]
+	}
+	return ""
+}
@@ -0,0 +1,191 @@
+// Package aws implements notification delivery to AWS SNS topics and SQS
+// queues.
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	awsauth "github.com/quay/clair/v4/internal/aws"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer is an SNS or SQS deliverer which sends a notifier.Callback to
+// the configured topic or queue.
+type Deliverer struct {
+	conf Config
+	pub  *publisher
+}
+
+// New returns a new SNS or SQS Deliverer.
+//
+// If client is nil, http.DefaultClient is used.
+func New(conf Config, client *http.Client) (*Deliverer, error) {
+	var c Config
+	var err error
+	if c, err = conf.Validate(); err != nil {
+		return nil, err
+	}
+	return &Deliverer{
+		conf: c,
+		pub:  newPublisher(c, client),
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return d.conf.name()
+}
+
+// Deliver implements the notifier.Deliverer interface.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	callback := d.conf.callback
+	callback.Path = path.Join(callback.Path, nID.String())
+	d.conf.Filter.SetQuery(&callback)
+
+	cb := notifier.Callback{
+		NotificationID: nID,
+		Callback:       callback,
+	}
+	b, err := json.Marshal(&cb)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	if err := d.pub.Publish(ctx, nID, b); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	return nil
+}
+
+// MaxBatch is the most messages SNS and SQS accept in one batch call.
+const maxBatch = 10
+
+// Publisher does the actual API calls to SNS or SQS.
+type publisher struct {
+	c      *http.Client
+	creds  *awsauth.Provider
+	url    string
+	region string
+	topic  string
+}
+
+func newPublisher(conf Config, client *http.Client) *publisher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &publisher{
+		c:      client,
+		creds:  awsauth.NewProvider(client, awsauth.Credentials{}, conf.Region, conf.RoleARN),
+		url:    conf.url.String(),
+		region: conf.Region,
+		topic:  conf.TopicARN,
+	}
+}
+
+// Publish sends the provided payloads using the batch APIs.
+//
+// SNS and SQS accept at most 10 messages per call, so larger deliveries may
+// partially succeed. A retried delivery may then duplicate messages, which
+// consumers must already tolerate.
+func (p *publisher) Publish(ctx context.Context, nID uuid.UUID, data ...[]byte) error {
+	for bs := 0; bs < len(data); bs += maxBatch {
+		be := bs + maxBatch
+		if be > len(data) {
+			be = len(data)
+		}
+		if err := p.publishBatch(ctx, nID, data[bs:be]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *publisher) publishBatch(ctx context.Context, nID uuid.UUID, data [][]byte) error {
+	form := url.Values{}
+	set := form.Set
+	service := "sqs"
+	if p.topic != "" {
+		service = "sns"
+		set("Action", "PublishBatch")
+		set("Version", "2010-03-31")
+		set("TopicArn", p.topic)
+		for i, b := range data {
+			pre := "PublishBatchRequestEntries.member." + strconv.Itoa(i+1) + "."
+			set(pre+"Id", strconv.Itoa(i))
+			set(pre+"Message", string(b))
+			set(pre+"MessageAttributes.entry.1.Name", "notification_id")
+			set(pre+"MessageAttributes.entry.1.Value.DataType", "String")
+			set(pre+"MessageAttributes.entry.1.Value.StringValue", nID.String())
+		}
+	} else {
+		set("Action", "SendMessageBatch")
+		set("Version", "2012-11-05")
+		for i, b := range data {
+			pre := "SendMessageBatchRequestEntry." + strconv.Itoa(i+1) + "."
+			set(pre+"Id", strconv.Itoa(i))
+			set(pre+"MessageBody", string(b))
+			set(pre+"MessageAttribute.1.Name", "notification_id")
+			set(pre+"MessageAttribute.1.Value.DataType", "String")
+			set(pre+"MessageAttribute.1.Value.StringValue", nID.String())
+		}
+	}
+	body := []byte(form.Encode())
+
+	creds, err := p.creds.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	awsauth.Sign(req, body, creds, p.region, service, time.Now())
+	res, err := p.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return &clairerror.ErrRequestFail{
+			Code:   res.StatusCode,
+			Status: res.Status,
+		}
+	}
+	var r batchResponse
+	if err := xml.NewDecoder(res.Body).Decode(&r); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	failed := append(r.Result.Failed, r.Result.Errors...)
+	if len(failed) != 0 {
+		return fmt.Errorf("%d of %d messages failed: %s: %s", len(failed), len(data), failed[0].Code, failed[0].Message)
+	}
+	return nil
+}
+
+// BatchResponse is the subset of the PublishBatch and SendMessageBatch
+// responses we need.
+type batchResponse struct {
+	Result struct {
+		// SNS
+		Failed []batchError `xml:"Failed>member"`
+		// SQS
+		Errors []batchError `xml:"BatchResultErrorEntry"`
+	} `xml:",any"`
+}
+
+type batchError struct {
+	ID      string `xml:"Id"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
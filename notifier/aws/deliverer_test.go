@@ -0,0 +1,241 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	awsauth "github.com/quay/clair/v4/internal/aws"
+	"github.com/quay/clair/v4/notifier"
+)
+
+const callback = "http://clair-notifier/notifier/api/v1/notification"
+
+// fakeAPI is a minimal SNS and SQS API.
+type fakeAPI struct {
+	sync.Mutex
+	calls    int
+	messages []string
+	attrs    []string
+	// fail causes the last entry of every batch to be reported as failed.
+	fail bool
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.calls++
+	var n int
+	switch r.Form.Get("Action") {
+	case "PublishBatch":
+		for i := 1; ; i++ {
+			pre := fmt.Sprintf("PublishBatchRequestEntries.member.%d.", i)
+			m, ok := r.Form[pre+"Message"]
+			if !ok {
+				break
+			}
+			f.messages = append(f.messages, m[0])
+			f.attrs = append(f.attrs, r.Form.Get(pre+"MessageAttributes.entry.1.Value.StringValue"))
+			n++
+		}
+		fmt.Fprint(w, `<PublishBatchResponse><PublishBatchResult><Successful/>`)
+		if f.fail {
+			fmt.Fprintf(w, `<Failed><member><Id>%d</Id><Code>InternalError</Code><Message>oops</Message></member></Failed>`, n-1)
+		}
+		fmt.Fprint(w, `</PublishBatchResult><ResponseMetadata><RequestId>x</RequestId></ResponseMetadata></PublishBatchResponse>`)
+	case "SendMessageBatch":
+		for i := 1; ; i++ {
+			pre := fmt.Sprintf("SendMessageBatchRequestEntry.%d.", i)
+			m, ok := r.Form[pre+"MessageBody"]
+			if !ok {
+				break
+			}
+			f.messages = append(f.messages, m[0])
+			f.attrs = append(f.attrs, r.Form.Get(pre+"MessageAttribute.1.Value.StringValue"))
+			n++
+		}
+		fmt.Fprint(w, `<SendMessageBatchResponse><SendMessageBatchResult>`)
+		if f.fail {
+			fmt.Fprintf(w, `<BatchResultErrorEntry><Id>%d</Id><Code>InternalError</Code><Message>oops</Message></BatchResultErrorEntry>`, n-1)
+		}
+		fmt.Fprint(w, `</SendMessageBatchResult></SendMessageBatchResponse>`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// setup returns a fake API and Configs for a topic and queue pointing at it.
+func setup(t *testing.T) (*fakeAPI, []Config, func()) {
+	f := &fakeAPI{}
+	srv := httptest.NewServer(f)
+	return f, []Config{
+		{
+			Callback: callback,
+			TopicARN: "arn:aws:sns:us-east-2:123456789012:notifications",
+			Endpoint: srv.URL + "/",
+		},
+		{
+			Callback: callback,
+			QueueURL: srv.URL + "/123456789012/notifications",
+			Region:   "us-east-2",
+		},
+	}, srv.Close
+}
+
+func staticCreds() *awsauth.Provider {
+	return awsauth.NewProvider(nil, awsauth.Credentials{AccessKeyID: "id", SecretAccessKey: "secret"}, "", "")
+}
+
+func TestConfig(t *testing.T) {
+	tt := []struct {
+		conf   Config
+		region string
+		name   string
+	}{
+		{
+			conf:   Config{Direct: true, TopicARN: "arn:aws:sns:eu-west-1:123456789012:clair"},
+			region: "eu-west-1",
+			name:   "sns-clair",
+		},
+		{
+			conf:   Config{Direct: true, QueueURL: "https://sqs.ap-south-1.amazonaws.com/123456789012/clair"},
+			region: "ap-south-1",
+			name:   "sqs-clair",
+		},
+		{
+			conf:   Config{Direct: true, QueueURL: "https://us-east-2.queue.amazonaws.com/123456789012/clair"},
+			region: "us-east-2",
+			name:   "sqs-clair",
+		},
+	}
+	for _, tc := range tt {
+		c, err := tc.conf.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := c.Region, tc.region; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := c.name(), tc.name; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+	}
+
+	if _, err := (&Config{}).Validate(); err == nil {
+		t.Error("expected error for missing topic and queue")
+	}
+	both := Config{TopicARN: tt[0].conf.TopicARN, QueueURL: tt[1].conf.QueueURL}
+	if _, err := both.Validate(); err == nil {
+		t.Error("expected error for both topic and queue")
+	}
+}
+
+func TestDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, confs, cleanup := setup(t)
+	defer cleanup()
+	for _, conf := range confs {
+		f.Lock()
+		f.messages, f.attrs = nil, nil
+		f.Unlock()
+		d, err := New(conf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.pub.creds = staticCreds()
+		nID := uuid.New()
+		if err := d.Deliver(ctx, nID); err != nil {
+			t.Fatal(err)
+		}
+
+		f.Lock()
+		if got, want := len(f.messages), 1; got != want {
+			t.Fatalf("%s: got: %d messages, want: %d", d.Name(), got, want)
+		}
+		var cb notifier.Callback
+		if err := json.Unmarshal([]byte(f.messages[0]), &cb); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := cb.NotificationID, nID; got != want {
+			t.Errorf("%s: got: %v, want: %v", d.Name(), got, want)
+		}
+		if got, want := cb.Callback.String(), callback+"/"+nID.String(); got != want {
+			t.Errorf("%s: got: %v, want: %v", d.Name(), got, want)
+		}
+		if got, want := f.attrs[0], nID.String(); got != want {
+			t.Errorf("%s: got: %v, want: %v", d.Name(), got, want)
+		}
+		f.Unlock()
+	}
+}
+
+func TestDirectDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, confs, cleanup := setup(t)
+	defer cleanup()
+	for _, conf := range confs {
+		f.Lock()
+		f.calls, f.messages, f.fail = 0, nil, false
+		f.Unlock()
+		conf.Direct = true
+		conf.Rollup = 2
+		d, err := NewDirectDeliverer(conf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.pub.creds = staticCreds()
+		ns := make([]notifier.Notification, 25)
+		for i := range ns {
+			ns[i].ID = uuid.New()
+		}
+		if err := d.Notifications(ctx, ns); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Deliver(ctx, uuid.New()); err != nil {
+			t.Fatal(err)
+		}
+
+		f.Lock()
+		// 25 notifications with a rollup of 2 should be 13 messages, sent in
+		// 2 batches.
+		if got, want := len(f.messages), 13; got != want {
+			t.Errorf("%s: got: %d messages, want: %d", d.Name(), got, want)
+		}
+		if got, want := f.calls, 2; got != want {
+			t.Errorf("%s: got: %d calls, want: %d", d.Name(), got, want)
+		}
+		var total int
+		for _, m := range f.messages {
+			var got []json.RawMessage
+			if err := json.Unmarshal([]byte(m), &got); err != nil {
+				t.Fatal(err)
+			}
+			total += len(got)
+		}
+		if got, want := total, len(ns); got != want {
+			t.Errorf("%s: got: %d notifications, want: %d", d.Name(), got, want)
+		}
+		f.fail = true
+		f.Unlock()
+
+		if err := d.Deliver(ctx, uuid.New()); err == nil {
+			t.Errorf("%s: expected error for failed batch entry", d.Name())
+		}
+	}
+}
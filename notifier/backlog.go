@@ -0,0 +1,15 @@
+package notifier
+
+import "time"
+
+// Backlog summarizes the notification ids waiting on delivery, for judging
+// whether the notifier is keeping up.
+type Backlog struct {
+	// Pending is the number of notification ids in created status.
+	Pending int64 `json:"pending"`
+	// Failed is the number of notification ids in delivery failed status.
+	Failed int64 `json:"failed"`
+	// Oldest is when the oldest notification id in created status was
+	// created, if there are any.
+	Oldest *time.Time `json:"oldest_pending,omitempty"`
+}
@@ -0,0 +1,85 @@
+package notifier
+
+import "github.com/quay/claircore"
+
+// ChangeKind is a way a vulnerability's effect on a manifest changed
+// relative to the previous update operation.
+type ChangeKind string
+
+const (
+	// Introduced means the vulnerability newly affects the manifest.
+	Introduced ChangeKind = "introduced"
+	// Fixed means a fix newly became available.
+	Fixed ChangeKind = "fixed"
+	// SeverityChanged means the vulnerability's severity changed.
+	SeverityChanged ChangeKind = "severity_changed"
+)
+
+// Change describes how the vulnerability in a notification differs from
+// what affected the manifest as of the previous update operation.
+//
+// Notifications with the "added" reason are always "introduced". Ones with
+// the "changed" reason replace a previous version of the vulnerability, and
+// may have no kinds if nothing summarized here changed.
+type Change struct {
+	Kinds                  []ChangeKind `json:"kinds"`
+	PreviousSeverity       string       `json:"previous_severity,omitempty"`
+	Severity               string       `json:"severity"`
+	PreviousFixedInVersion string       `json:"previous_fixed_in_version,omitempty"`
+	FixedInVersion         string       `json:"fixed_in_version,omitempty"`
+}
+
+// IntroducedChange returns the Change for a vulnerability newly affecting a
+// manifest.
+func introducedChange(v *claircore.Vulnerability) *Change {
+	return &Change{
+		Kinds:          []ChangeKind{Introduced},
+		Severity:       v.NormalizedSeverity.String(),
+		FixedInVersion: v.FixedInVersion,
+	}
+}
+
+// UpdatedChange returns the Change from the previous version of a
+// vulnerability to the current one.
+func updatedChange(prev, cur *claircore.Vulnerability) *Change {
+	c := Change{
+		Kinds:                  []ChangeKind{},
+		PreviousSeverity:       prev.NormalizedSeverity.String(),
+		Severity:               cur.NormalizedSeverity.String(),
+		PreviousFixedInVersion: prev.FixedInVersion,
+		FixedInVersion:         cur.FixedInVersion,
+	}
+	if prev.FixedInVersion == "" && cur.FixedInVersion != "" {
+		c.Kinds = append(c.Kinds, Fixed)
+	}
+	if prev.NormalizedSeverity != cur.NormalizedSeverity {
+		c.Kinds = append(c.Kinds, SeverityChanged)
+	}
+	return &c
+}
+
+// VulnKey identifies a vulnerability across update operations. Updaters
+// replace a vulnerability by removing the old record and adding a new one
+// with the same name for the same package and namespace.
+func vulnKey(v *claircore.Vulnerability) string {
+	return key(v.Name, v.Package, v.Dist, v.Repo)
+}
+
+// SummaryKey is vulnKey for a VulnSummary.
+func summaryKey(v *VulnSummary) string {
+	return key(v.Name, v.Package, v.Distribution, v.Repo)
+}
+
+func key(name string, p *claircore.Package, d *claircore.Distribution, r *claircore.Repository) string {
+	var pkg, dist, repo string
+	if p != nil {
+		pkg = p.Name
+	}
+	if d != nil {
+		dist = d.DID + ":" + d.VersionID
+	}
+	if r != nil {
+		repo = r.Name
+	}
+	return name + "\x00" + pkg + "\x00" + dist + "\x00" + repo
+}
@@ -0,0 +1,97 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/quay/claircore"
+)
+
+func TestDiffNotifications(t *testing.T) {
+	const manifest = `sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`
+	pkg := &claircore.Package{Name: "openssl", Version: "1.1.1"}
+	old := []*claircore.Vulnerability{
+		{ID: "1", Name: "CVE-2021-0001", Package: pkg, NormalizedSeverity: claircore.Medium},
+		{ID: "2", Name: "CVE-2021-0002", Package: pkg, NormalizedSeverity: claircore.Low},
+		{ID: "3", Name: "CVE-2021-0003", Package: pkg, NormalizedSeverity: claircore.Low},
+	}
+	cur := []*claircore.Vulnerability{
+		{ID: "4", Name: "CVE-2021-0001", Package: pkg, NormalizedSeverity: claircore.High, FixedInVersion: "1.1.2"},
+		{ID: "5", Name: "CVE-2021-0004", Package: pkg, NormalizedSeverity: claircore.Medium},
+		{ID: "6", Name: "CVE-2021-0002", Package: pkg, NormalizedSeverity: claircore.Low},
+	}
+	affected := func(vs []*claircore.Vulnerability) *claircore.AffectedManifests {
+		a := claircore.AffectedManifests{
+			Vulnerabilities:     make(map[string]*claircore.Vulnerability),
+			VulnerableManifests: make(map[string][]string),
+		}
+		for _, v := range vs {
+			a.Vulnerabilities[v.ID] = v
+			a.VulnerableManifests[manifest] = append(a.VulnerableManifests[manifest], v.ID)
+		}
+		return &a
+	}
+	summary := func(v *claircore.Vulnerability) VulnSummary {
+		var s VulnSummary
+		s.FromVulnerability(v)
+		return s
+	}
+	d := claircore.MustParseDigest(manifest)
+	want := []Notification{
+		{
+			Manifest:      d,
+			Reason:        Changed,
+			Vulnerability: summary(cur[0]),
+			Change: &Change{
+				Kinds:            []ChangeKind{Fixed, SeverityChanged},
+				PreviousSeverity: "Medium",
+				Severity:         "High",
+				FixedInVersion:   "1.1.2",
+			},
+		},
+		{
+			Manifest:      d,
+			Reason:        Added,
+			Vulnerability: summary(cur[1]),
+			Change: &Change{
+				Kinds:    []ChangeKind{Introduced},
+				Severity: "Medium",
+			},
+		},
+		{
+			Manifest:      d,
+			Reason:        Changed,
+			Vulnerability: summary(cur[2]),
+			Change: &Change{
+				Kinds:            []ChangeKind{},
+				PreviousSeverity: "Low",
+				Severity:         "Low",
+			},
+		},
+		{
+			Manifest:      d,
+			Reason:        Removed,
+			Vulnerability: summary(old[2]),
+		},
+	}
+	opts := cmpopts.IgnoreUnexported(claircore.Digest{})
+
+	got, err := diffNotifications(affected(cur), affected(old), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(got, want, opts) {
+		t.Error(cmp.Diff(got, want, opts))
+	}
+
+	// Summarized, only the first of each reason is kept.
+	got, err = diffNotifications(affected(cur), affected(old), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Notification{want[0], want[1], want[3]}
+	if !cmp.Equal(got, want, opts) {
+		t.Error(cmp.Diff(got, want, opts))
+	}
+}
@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Headers set by direct deliverers on every message, so consumers can tell
+// when they've received all of a notification set split across messages.
+const (
+	// HeaderNotificationID is the notification ID the message belongs to.
+	HeaderNotificationID = "clair-notification-id"
+	// HeaderChunk is the message's position in the set, starting at 1.
+	HeaderChunk = "clair-chunk"
+	// HeaderChunks is the number of messages the set was split into.
+	HeaderChunks = "clair-chunks"
+	// HeaderCallback is the URL the whole set can be paged through at, if
+	// the deliverer has a callback configured.
+	HeaderCallback = "clair-callback"
+)
+
+// Chunk is a block of notifications a direct deliverer sends in one
+// message.
+type Chunk struct {
+	Notifications []Notification
+	// Body is the JSON array of Notifications, as json.Encoder writes it.
+	Body []byte
+	// Seq is the chunk's position, starting at 1, of Total.
+	Seq, Total int
+}
+
+// Chunks splits the notifications into blocks of at most rollup
+// notifications and, if maxSize is positive, at most maxSize bytes of
+// encoded JSON. A rollup of 0 or 1 puts every notification in its own
+// block.
+//
+// A notification encoding to more than maxSize bytes by itself can't be
+// split, and is returned in a chunk of its own.
+func Chunks(ns []Notification, rollup, maxSize int) ([]Chunk, error) {
+	if rollup < 1 {
+		rollup = 1
+	}
+	var out []Chunk
+	var buf bytes.Buffer
+	start := 0
+	flush := func(end int) {
+		buf.WriteString("]\n")
+		b := make([]byte, buf.Len())
+		copy(b, buf.Bytes())
+		out = append(out, Chunk{Notifications: ns[start:end], Body: b})
+		buf.Reset()
+		start = end
+	}
+	for i := range ns {
+		b, err := json.Marshal(&ns[i])
+		if err != nil {
+			return nil, err
+		}
+		if n := i - start; n != 0 {
+			// Room for the separator, this notification and the closing
+			// bracket and newline.
+			full := n == rollup ||
+				(maxSize > 0 && buf.Len()+1+len(b)+2 > maxSize)
+			if full {
+				flush(i)
+			}
+		}
+		if i == start {
+			buf.WriteByte('[')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.Write(b)
+	}
+	if start < len(ns) {
+		flush(len(ns))
+	}
+	for i := range out {
+		out[i].Seq, out[i].Total = i+1, len(out)
+	}
+	return out, nil
+}
@@ -0,0 +1,63 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestChunks(t *testing.T) {
+	m := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	ns := make([]Notification, 5)
+	for i := range ns {
+		ns[i] = Notification{Manifest: m, Vulnerability: VulnSummary{Name: strings.Repeat("x", 10*(i+1))}}
+	}
+	one, err := json.Marshal(&ns[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		Name    string
+		Rollup  int
+		MaxSize int
+		Want    []int
+	}{
+		{Name: "Default", Want: []int{1, 1, 1, 1, 1}},
+		{Name: "Rollup", Rollup: 2, Want: []int{2, 2, 1}},
+		{Name: "Unsplit", Rollup: 10, Want: []int{5}},
+		{Name: "Size", Rollup: 10, MaxSize: 2*len(one) + 4, Want: []int{2, 2, 1}},
+		{Name: "Oversize", Rollup: 10, MaxSize: 10, Want: []int{1, 1, 1, 1, 1}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			cs, err := Chunks(ns, tc.Rollup, tc.MaxSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cs) != len(tc.Want) {
+				t.Fatalf("got %d chunks, want %d", len(cs), len(tc.Want))
+			}
+			for i, c := range cs {
+				if got, want := len(c.Notifications), tc.Want[i]; got != want {
+					t.Errorf("chunk %d: got %d notifications, want %d", i, got, want)
+				}
+				if c.Seq != i+1 || c.Total != len(cs) {
+					t.Errorf("chunk %d: got %d of %d", i, c.Seq, c.Total)
+				}
+				if tc.MaxSize > 0 && len(c.Notifications) > 1 && len(c.Body) > tc.MaxSize {
+					t.Errorf("chunk %d: %d bytes exceeds %d", i, len(c.Body), tc.MaxSize)
+				}
+				var buf bytes.Buffer
+				if err := json.NewEncoder(&buf).Encode(&c.Notifications); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(c.Body, buf.Bytes()) {
+					t.Errorf("chunk %d: got: %q, want: %q", i, c.Body, buf.Bytes())
+				}
+			}
+		})
+	}
+}
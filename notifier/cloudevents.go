@@ -0,0 +1,132 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// These are the CloudEvents content modes.
+const (
+	// Structured mode puts the whole event, attributes and data, in the
+	// message body.
+	Structured = "structured"
+	// Binary mode leaves the message body as the event data and carries the
+	// attributes in transport headers.
+	Binary = "binary"
+)
+
+// These are the CloudEvents "type" attributes of events the notifier sends.
+const (
+	// EventCallback is the type of an event whose data is a Callback.
+	EventCallback = "io.projectquay.clair.notification.callback.v1"
+	// EventNotifications is the type of an event whose data is a list of
+	// Notifications.
+	EventNotifications = "io.projectquay.clair.notification.v1"
+	// EventIndex is the type of an event whose data is an index event.
+	EventIndex = "io.projectquay.clair.index.v1"
+)
+
+// DefaultEventSource is the CloudEvents "source" attribute used if one isn't
+// configured.
+const DefaultEventSource = "/clair/notifier"
+
+// CloudEventsContentType is the media type of a structured mode CloudEvent
+// encoded as JSON.
+const CloudEventsContentType = "application/cloudevents+json"
+
+// CloudEvents configures wrapping deliveries in CloudEvents 1.0 envelopes.
+//
+// A nil CloudEvents leaves deliveries as-is.
+type CloudEvents struct {
+	// Either "structured" (the default) or "binary".
+	Mode string `yaml:"mode" json:"mode"`
+	// The "source" attribute of events, a URI-reference identifying this
+	// Clair. Defaults to "/clair/notifier".
+	Source string `yaml:"source" json:"source"`
+}
+
+// Validate confirms the CloudEvents configuration is valid and returns a copy
+// with defaults filled in.
+func (c *CloudEvents) Validate() (*CloudEvents, error) {
+	if c == nil {
+		return nil, nil
+	}
+	out := *c
+	switch out.Mode {
+	case "":
+		out.Mode = Structured
+	case Structured, Binary:
+	default:
+		return nil, fmt.Errorf("invalid cloudevents: unknown mode %q", c.Mode)
+	}
+	if out.Source == "" {
+		out.Source = DefaultEventSource
+	}
+	if _, err := url.Parse(out.Source); err != nil {
+		return nil, fmt.Errorf("invalid cloudevents: bad source: %v", err)
+	}
+	return &out, nil
+}
+
+// CloudEvent is a CloudEvents 1.0 event with JSON data.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// Event returns a new CloudEvent with the provided type, id, subject, and
+// data, which must be JSON.
+//
+// The id must be derived from what the event is about, like a notification
+// ID, so that redelivering it produces the same id and consumers can
+// deduplicate.
+func (c *CloudEvents) Event(typ, id, subject string, data []byte) *CloudEvent {
+	return &CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Source:          c.Source,
+		Type:            typ,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            json.RawMessage(data),
+	}
+}
+
+// Attributes returns the event's context attributes, keyed by name, for use
+// as transport headers in binary mode.
+func (e *CloudEvent) Attributes() map[string]string {
+	m := map[string]string{
+		"specversion":     e.SpecVersion,
+		"id":              e.ID,
+		"source":          e.Source,
+		"type":            e.Type,
+		"time":            e.Time.Format(time.RFC3339Nano),
+		"datacontenttype": e.DataContentType,
+	}
+	if e.Subject != "" {
+		m["subject"] = e.Subject
+	}
+	return m
+}
+
+// Encode returns the message body and content type for the event in the
+// configured mode.
+func (c *CloudEvents) Encode(e *CloudEvent) ([]byte, string, error) {
+	if c.Mode == Binary {
+		return e.Data, e.DataContentType, nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, CloudEventsContentType, nil
+}
@@ -0,0 +1,111 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// CoalesceLock is the distributed lock held while merging notification ids,
+// so that only one Delivery merges a given set.
+const coalesceLock = "notifier-coalesce"
+
+// Coalesce merges the notification ids in created status into one, once the
+// oldest of them is older than the Delivery's Window. It returns the
+// notification ids that should be delivered now.
+//
+// Notification ids are held back while the window is open, so that the
+// notifications of a burst of update operations are delivered together.
+func (d *Delivery) coalesce(ctx context.Context, created []uuid.UUID) ([]uuid.UUID, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("deliverer", d.Deliverer.Name()).
+		Uint8("id", d.id).
+		Str("component", "notifier/delivery/Delivery.coalesce").Logger()
+	if len(created) == 0 {
+		return created, nil
+	}
+
+	rs := make([]Receipt, 0, len(created))
+	for _, id := range created {
+		r, err := d.store.Receipt(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	sort.SliceStable(rs, func(i, j int) bool { return rs[i].TS.Before(rs[j].TS) })
+	if open := d.Window - time.Since(rs[0].TS); open > 0 {
+		log.Debug().
+			Int("pending", len(rs)).
+			Dur("remaining", open).
+			Msg("coalescing window open, holding notifications")
+		return nil, nil
+	}
+	if len(rs) == 1 {
+		return created, nil
+	}
+
+	ok, err := d.distLock.TryLock(ctx, coalesceLock)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		log.Debug().Msg("another process is coalescing notifications")
+		return nil, nil
+	}
+	defer d.distLock.Unlock()
+
+	sets := make([][]Notification, 0, len(rs))
+	replaces := make([]uuid.UUID, 0, len(rs))
+	for _, r := range rs {
+		ns, _, err := d.store.Notifications(ctx, r.NotificationID, nil)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, ns)
+		replaces = append(replaces, r.NotificationID)
+	}
+	opts := CoalesceOpts{
+		NotificationID: uuid.New(),
+		UpdateID:       rs[len(rs)-1].UOID,
+		Replaces:       replaces,
+		Notifications:  coalesceNotifications(sets),
+	}
+	if err := d.store.CoalesceNotifications(ctx, opts); err != nil {
+		return nil, fmt.Errorf("failed to coalesce notifications: %w", err)
+	}
+	log.Info().
+		Int("merged", len(replaces)).
+		Int("notifications", len(opts.Notifications)).
+		Str("notification_id", opts.NotificationID.String()).
+		Msg("coalesced notifications")
+	return []uuid.UUID{opts.NotificationID}, nil
+}
+
+// CoalesceNotifications merges sets of notifications, oldest first, into
+// one. Only the latest notification about a vulnerability affecting a
+// manifest is kept.
+func coalesceNotifications(sets [][]Notification) []Notification {
+	type pair struct {
+		manifest string
+		vuln     string
+	}
+	idx := make(map[pair]int)
+	var out []Notification
+	for _, ns := range sets {
+		for _, n := range ns {
+			k := pair{manifest: n.Manifest.String(), vuln: summaryKey(&n.Vulnerability)}
+			if i, ok := idx[k]; ok {
+				out[i] = n
+				continue
+			}
+			idx[k] = len(out)
+			out = append(out, n)
+		}
+	}
+	return out
+}
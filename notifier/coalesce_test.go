@@ -0,0 +1,123 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+// Locker is a distlock.Locker that always succeeds.
+type locker struct{}
+
+func (locker) Lock(context.Context, string) error            { return nil }
+func (locker) TryLock(context.Context, string) (bool, error) { return true, nil }
+func (locker) Unlock() error                                 { return nil }
+
+type nopDeliverer struct{}
+
+func (nopDeliverer) Name() string                             { return "nop" }
+func (nopDeliverer) Deliver(context.Context, uuid.UUID) error { return nil }
+
+func TestCoalesceNotifications(t *testing.T) {
+	m1 := claircore.MustParseDigest(manifestAdd)
+	m2 := claircore.MustParseDigest(manifestRemoved)
+	vuln := func(name string) VulnSummary {
+		return VulnSummary{Name: name, Package: &claircore.Package{Name: "openssl"}}
+	}
+	sets := [][]Notification{
+		{
+			{Manifest: m1, Reason: Added, Vulnerability: vuln("CVE-1")},
+			{Manifest: m2, Reason: Added, Vulnerability: vuln("CVE-1")},
+		},
+		{
+			{Manifest: m1, Reason: Removed, Vulnerability: vuln("CVE-1")},
+			{Manifest: m1, Reason: Added, Vulnerability: vuln("CVE-2")},
+		},
+	}
+	want := []Notification{
+		{Manifest: m1, Reason: Removed, Vulnerability: vuln("CVE-1")},
+		{Manifest: m2, Reason: Added, Vulnerability: vuln("CVE-1")},
+		{Manifest: m1, Reason: Added, Vulnerability: vuln("CVE-2")},
+	}
+	opts := cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+	if got := coalesceNotifications(sets); !cmp.Equal(got, want, opts) {
+		t.Error(cmp.Diff(got, want, opts))
+	}
+}
+
+func TestDeliveryCoalesce(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	m := claircore.MustParseDigest(manifestAdd)
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	uos := []uuid.UUID{uuid.New(), uuid.New()}
+	var created time.Time
+	var got *CoalesceOpts
+	store := &MockStore{
+		Receipt_: func(_ context.Context, id uuid.UUID) (Receipt, error) {
+			for i := range ids {
+				if ids[i] == id {
+					return Receipt{
+						NotificationID: id,
+						UOID:           uos[i],
+						Status:         Created,
+						TS:             created.Add(time.Duration(i) * time.Second),
+					}, nil
+				}
+			}
+			t.Fatalf("unexpected id: %v", id)
+			return Receipt{}, nil
+		},
+		Notifications_: func(_ context.Context, id uuid.UUID, _ *Page) ([]Notification, Page, error) {
+			n := Notification{Manifest: m, Reason: Added, Vulnerability: VulnSummary{Name: "CVE-1"}}
+			return []Notification{n}, Page{}, nil
+		},
+		CoalesceNotifications_: func(_ context.Context, opts CoalesceOpts) error {
+			got = &opts
+			return nil
+		},
+	}
+	d := NewDelivery(0, nopDeliverer{}, time.Second, store, locker{})
+	d.Window = time.Minute
+
+	t.Run("Open", func(t *testing.T) {
+		created, got = time.Now(), nil
+		out, err := d.coalesce(ctx, ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 0 {
+			t.Errorf("got: %v, want: nothing to deliver", out)
+		}
+		if got != nil {
+			t.Error("unexpected coalesce")
+		}
+	})
+	t.Run("Closed", func(t *testing.T) {
+		created, got = time.Now().Add(-time.Hour), nil
+		// Out of order, to check the latest update operation is used.
+		out, err := d.coalesce(ctx, []uuid.UUID{ids[1], ids[0]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil {
+			t.Fatal("notifications not coalesced")
+		}
+		if want := []uuid.UUID{got.NotificationID}; !cmp.Equal(out, want) {
+			t.Error(cmp.Diff(out, want))
+		}
+		if !cmp.Equal(got.Replaces, ids) {
+			t.Error(cmp.Diff(got.Replaces, ids))
+		}
+		if got.UpdateID != uos[1] {
+			t.Errorf("got: %v, want: %v", got.UpdateID, uos[1])
+		}
+		if n := len(got.Notifications); n != 1 {
+			t.Errorf("got: %d notifications, want: 1", n)
+		}
+	})
+}
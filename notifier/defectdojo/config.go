@@ -0,0 +1,79 @@
+package defectdojo
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// DefaultTestTitle is the title of the tests findings are imported into if
+// one is not configured.
+const DefaultTestTitle = "Clair {manifest}"
+
+// Config provides configuration for a DefectDojo deliverer.
+type Config struct {
+	// The DefectDojo instance's base URL, e.g. "https://defectdojo.example.com".
+	URL string `yaml:"url"`
+	url *url.URL
+	// The API v2 key findings are imported with.
+	Token string `yaml:"token" secret:"true"`
+	// The name of the product findings are imported into.
+	Product string `yaml:"product"`
+	// The name of the product's engagement findings are imported into.
+	Engagement string `yaml:"engagement"`
+	// The title of the engagement's test findings are imported into. It may
+	// contain the placeholder "{manifest}", so that every manifest's findings
+	// are tracked in a test of their own.
+	//
+	// Defaults to "Clair {manifest}".
+	TestTitle string `yaml:"test_title"`
+	// If set, the product, engagement, and test are created if they don't
+	// exist. New products get the named product type, which must exist.
+	ProductType string `yaml:"product_type"`
+	// Tags added to imported findings.
+	Tags []string `yaml:"tags"`
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+}
+
+// Validate confirms configuration is valid and fills in private members
+// with parsed values on success.
+func (c *Config) Validate() (Config, error) {
+	conf := *c
+	if c.URL == "" {
+		return conf, fmt.Errorf("defectdojo config requires the url field")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return conf, fmt.Errorf("failed to parse url: %v", err)
+	}
+	conf.url = u
+	if c.Token == "" {
+		return conf, fmt.Errorf("defectdojo config requires the token field")
+	}
+	if c.Product == "" {
+		return conf, fmt.Errorf("defectdojo config requires the product field")
+	}
+	if c.Engagement == "" {
+		return conf, fmt.Errorf("defectdojo config requires the engagement field")
+	}
+	if conf.TestTitle == "" {
+		conf.TestTitle = DefaultTestTitle
+	}
+	for _, t := range c.Tags {
+		if t == "" || strings.ContainsAny(t, " ,") {
+			return conf, fmt.Errorf("defectdojo config: bad tag %q", t)
+		}
+	}
+
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+	return conf, nil
+}
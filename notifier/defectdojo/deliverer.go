@@ -0,0 +1,270 @@
+// Package defectdojo delivers notifications as DefectDojo findings, using the
+// import API of API v2.
+package defectdojo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// ScanType is the DefectDojo parser findings are uploaded for.
+const scanType = "Generic Findings Import"
+
+// Deliverer imports the vulnerabilities affecting manifests into DefectDojo
+// as findings of the configured product and engagement.
+//
+// Findings are reimported into a test per configured test title, so
+// DefectDojo deduplicates them by vulnerability, package, and manifest.
+// Notifications that a vulnerability was removed mitigate its finding.
+type Deliverer struct {
+	conf   Config
+	client *http.Client
+	n      []notifier.Notification
+}
+
+// New returns a new DefectDojo Deliverer.
+//
+// If client is nil, http.DefaultClient is used.
+func New(conf Config, client *http.Client) (*Deliverer, error) {
+	c, err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Deliverer{
+		conf:   c,
+		client: client,
+		n:      []notifier.Notification{},
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return "defectdojo"
+}
+
+// Target implements notifier.Targeter.
+func (d *Deliverer) Target() string {
+	return d.conf.url.String()
+}
+
+// Notifications implements notifier.DirectDeliverer.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = append(d.n[:0], n...)
+	return nil
+}
+
+// Deliver implements the notifier.Deliverer interface.
+//
+// One import is made per test. If one fails, the tests before it are
+// reimported during the retry, which DefectDojo deduplicates.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/defectdojo/Deliverer.Deliver").
+		Stringer("notification_id", nID).
+		Logger()
+	tests := make(map[string][]finding)
+	for i := range d.n {
+		n := &d.n[i]
+		t := strings.ReplaceAll(d.conf.TestTitle, "{manifest}", n.Manifest.String())
+		tests[t] = append(tests[t], newFinding(n))
+	}
+	titles := make([]string, 0, len(tests))
+	for t := range tests {
+		titles = append(titles, t)
+	}
+	sort.Strings(titles)
+	for _, t := range titles {
+		if err := d.reimport(ctx, t, tests[t]); err != nil {
+			return &clairerror.ErrDeliveryFailed{E: err}
+		}
+	}
+	log.Debug().
+		Int("tests", len(titles)).
+		Int("findings", len(d.n)).
+		Msg("delivered to defectdojo")
+	return nil
+}
+
+// Finding is a finding in the format of DefectDojo's generic findings
+// parser.
+type finding struct {
+	Title            string   `json:"title"`
+	Description      string   `json:"description"`
+	Severity         string   `json:"severity"`
+	Mitigation       string   `json:"mitigation,omitempty"`
+	References       string   `json:"references,omitempty"`
+	CVE              string   `json:"cve,omitempty"`
+	VulnIDFromTool   string   `json:"vuln_id_from_tool"`
+	UniqueIDFromTool string   `json:"unique_id_from_tool"`
+	ComponentName    string   `json:"component_name,omitempty"`
+	ComponentVersion string   `json:"component_version,omitempty"`
+	Active           bool     `json:"active"`
+	IsMitigated      bool     `json:"is_mitigated"`
+	Tags             []string `json:"tags,omitempty"`
+}
+
+// NewFinding returns the finding for the notification.
+func newFinding(n *notifier.Notification) finding {
+	v := &n.Vulnerability
+	f := finding{
+		Title:            v.Name,
+		Description:      description(n),
+		Severity:         severity(v.Severity),
+		VulnIDFromTool:   v.Name,
+		UniqueIDFromTool: uniqueID(n),
+		Active:           n.Reason != notifier.Removed,
+		IsMitigated:      n.Reason == notifier.Removed,
+	}
+	if v.Package != nil {
+		f.Title = fmt.Sprintf("%s in %s %s", v.Name, v.Package.Name, v.Package.Version)
+		f.ComponentName = v.Package.Name
+		f.ComponentVersion = v.Package.Version
+	}
+	if strings.HasPrefix(v.Name, "CVE-") {
+		f.CVE = v.Name
+	}
+	if v.FixedInVersion != "" {
+		f.Mitigation = "Upgrade to " + v.FixedInVersion + "."
+	}
+	f.References = strings.Join(strings.Fields(v.Links), "\n")
+	return f
+}
+
+// Severity maps a Clair severity to a DefectDojo one.
+func severity(s string) string {
+	switch s {
+	case "Critical", "High", "Medium", "Low":
+		return s
+	}
+	return "Info"
+}
+
+// UniqueID returns the ID identifying findings for the notification's
+// vulnerability, package, and manifest.
+func uniqueID(n *notifier.Notification) string {
+	v := &n.Vulnerability
+	h := sha256.New()
+	io.WriteString(h, v.Name)
+	h.Write([]byte{0})
+	if v.Package != nil {
+		io.WriteString(h, v.Package.Name)
+		h.Write([]byte{0})
+		io.WriteString(h, v.Package.Version)
+	}
+	h.Write([]byte{0})
+	io.WriteString(h, n.Manifest.String())
+	return "clair-" + hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// Description returns the description of a finding, in Markdown.
+func description(n *notifier.Notification) string {
+	v := &n.Vulnerability
+	var b strings.Builder
+	fmt.Fprintf(&b, "Clair found **%s** in manifest `%s`.\n\n", v.Name, n.Manifest)
+	fmt.Fprintf(&b, "* Severity: %s\n", v.Severity)
+	if v.Package != nil {
+		fmt.Fprintf(&b, "* Package: %s %s\n", v.Package.Name, v.Package.Version)
+	}
+	switch {
+	case v.Distribution != nil:
+		fmt.Fprintf(&b, "* Distribution: %s %s\n", v.Distribution.Name, v.Distribution.Version)
+	case v.Repo != nil:
+		fmt.Fprintf(&b, "* Repository: %s\n", v.Repo.Name)
+	}
+	if v.FixedInVersion != "" {
+		fmt.Fprintf(&b, "* Fixed in: %s\n", v.FixedInVersion)
+	}
+	if v.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", v.Description)
+	}
+	fmt.Fprintf(&b, "\nNotification: %s\n", n.ID)
+	return b.String()
+}
+
+// Reimport uploads the findings to the test with the title.
+//
+// Earlier findings missing from the upload are left alone, as a set of
+// notifications only holds what changed.
+func (d *Deliverer) reimport(ctx context.Context, title string, fs []finding) error {
+	for i := range fs {
+		fs[i].Tags = d.conf.Tags
+	}
+	file, err := json.Marshal(map[string]interface{}{"findings": fs})
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"scan_type", scanType},
+		{"product_name", d.conf.Product},
+		{"engagement_name", d.conf.Engagement},
+		{"test_title", title},
+		{"close_old_findings", "false"},
+		{"auto_create_context", strconv.FormatBool(d.conf.ProductType != "")},
+	}
+	if d.conf.ProductType != "" {
+		fields = append(fields, [2]string{"product_type_name", d.conf.ProductType})
+	}
+	if len(d.conf.Tags) != 0 {
+		fields = append(fields, [2]string{"tags", strings.Join(d.conf.Tags, ",")})
+	}
+	for _, f := range fields {
+		if err := w.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	fw, err := w.CreateFormFile("file", "clair.json")
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(file); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	u, err := d.conf.url.Parse(strings.TrimSuffix(d.conf.url.Path, "/") + "/api/v2/reimport-scan/")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), &body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("content-type", w.FormDataContentType())
+	req.Header.Set("authorization", "Token "+d.conf.Token)
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected response from defectdojo: %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package defectdojo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// FakeDojo is a minimal DefectDojo API, recording reimports.
+type fakeDojo struct {
+	sync.Mutex
+	imports []reimport
+	fail    bool
+}
+
+type reimport struct {
+	Form     map[string]string
+	Findings []finding
+}
+
+func (f *fakeDojo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if r.Header.Get("authorization") != "Token t0k3n" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if f.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost || r.URL.Path != "/api/v2/reimport-scan/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	im := reimport{Form: make(map[string]string)}
+	for k, v := range r.MultipartForm.Value {
+		im.Form[k] = v[0]
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	var doc struct {
+		Findings []finding `json:"findings"`
+	}
+	if err := json.NewDecoder(file).Decode(&doc); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	im.Findings = doc.Findings
+	f.imports = append(f.imports, im)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{}`))
+}
+
+func notification(name string, sev claircore.Severity, reason notifier.Reason, manifest string) notifier.Notification {
+	return notifier.Notification{
+		ID:       uuid.New(),
+		Manifest: claircore.MustParseDigest("sha256:" + manifest),
+		Reason:   reason,
+		Vulnerability: notifier.VulnSummary{
+			Name:           name,
+			Severity:       sev.String(),
+			Package:        &claircore.Package{Name: "openssl", Version: "1.1.1"},
+			FixedInVersion: "1.1.2",
+			Links:          "https://example.com/" + name,
+		},
+	}
+}
+
+const (
+	manifestA = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	manifestB = "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+)
+
+func TestDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := &fakeDojo{}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	d, err := New(Config{
+		URL:         srv.URL,
+		Token:       "t0k3n",
+		Product:     "images",
+		Engagement:  "clair",
+		ProductType: "containers",
+		Tags:        []string{"clair"},
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.Notifications(ctx, []notifier.Notification{
+		notification("CVE-2021-0001", claircore.Critical, notifier.Added, manifestA),
+		notification("CVE-2021-0002", claircore.Negligible, notifier.Added, manifestA),
+		notification("CVE-2021-0001", claircore.Critical, notifier.Removed, manifestB),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	if got, want := len(f.imports), 2; got != want {
+		t.Fatalf("got: %d imports, want: %d", got, want)
+	}
+	byTest := make(map[string]reimport)
+	for _, im := range f.imports {
+		if got, want := im.Form["scan_type"], scanType; got != want {
+			t.Errorf("scan type: got: %q, want: %q", got, want)
+		}
+		if got, want := im.Form["auto_create_context"], "true"; got != want {
+			t.Errorf("auto create: got: %q, want: %q", got, want)
+		}
+		if got, want := im.Form["close_old_findings"], "false"; got != want {
+			t.Errorf("close old findings: got: %q, want: %q", got, want)
+		}
+		byTest[im.Form["test_title"]] = im
+	}
+	a, ok := byTest["Clair sha256:"+manifestA]
+	if !ok {
+		t.Fatalf("no import for manifest A: %v", byTest)
+	}
+	if got, want := len(a.Findings), 2; got != want {
+		t.Fatalf("got: %d findings, want: %d", got, want)
+	}
+	fa := a.Findings[0]
+	if got, want := fa.Severity, "Critical"; got != want {
+		t.Errorf("severity: got: %q, want: %q", got, want)
+	}
+	if got, want := a.Findings[1].Severity, "Info"; got != want {
+		t.Errorf("severity: got: %q, want: %q", got, want)
+	}
+	if got, want := fa.CVE, "CVE-2021-0001"; got != want {
+		t.Errorf("cve: got: %q, want: %q", got, want)
+	}
+	if !fa.Active || fa.IsMitigated {
+		t.Errorf("unexpected status: active: %v, mitigated: %v", fa.Active, fa.IsMitigated)
+	}
+	b := byTest["Clair sha256:"+manifestB]
+	if len(b.Findings) != 1 {
+		t.Fatalf("got: %d findings, want: 1", len(b.Findings))
+	}
+	fb := b.Findings[0]
+	if fb.Active || !fb.IsMitigated {
+		t.Errorf("unexpected status: active: %v, mitigated: %v", fb.Active, fb.IsMitigated)
+	}
+	if fa.UniqueIDFromTool == fb.UniqueIDFromTool {
+		t.Error("findings in different manifests share an id")
+	}
+}
+
+func TestDelivererFailure(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := &fakeDojo{fail: true}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	d, err := New(Config{URL: srv.URL, Token: "t0k3n", Product: "images", Engagement: "clair"}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := notification("CVE-2021-0001", claircore.Critical, notifier.Added, manifestA)
+	if err := d.Notifications(ctx, []notifier.Notification{n}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	ok := Config{URL: "https://defectdojo.example.com", Token: "t0k3n", Product: "images", Engagement: "clair"}
+	tt := []struct {
+		name   string
+		modify func(*Config)
+		ok     bool
+	}{
+		{"Defaults", func(*Config) {}, true},
+		{"NoURL", func(c *Config) { c.URL = "" }, false},
+		{"NoToken", func(c *Config) { c.Token = "" }, false},
+		{"NoProduct", func(c *Config) { c.Product = "" }, false},
+		{"NoEngagement", func(c *Config) { c.Engagement = "" }, false},
+		{"BadTag", func(c *Config) { c.Tags = []string{"two words"} }, false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := ok
+			tc.modify(&c)
+			conf, err := c.Validate()
+			if got := err == nil; got != tc.ok {
+				t.Errorf("got: %v, want: %v (%v)", got, tc.ok, err)
+			}
+			if err == nil && conf.TestTitle != DefaultTestTitle {
+				t.Errorf("test title: got: %q, want: %q", conf.TestTitle, DefaultTestTitle)
+			}
+		})
+	}
+}
@@ -16,6 +16,24 @@ type Deliverer interface {
 	Deliver(ctx context.Context, nID uuid.UUID) error
 }
 
+// Targeter is an optional interface a Deliverer may implement to report
+// where it sends notifications. It's recorded alongside delivery attempts.
+type Targeter interface {
+	Target() string
+}
+
+// TenantDeliverer is an optional interface a Deliverer may implement to send
+// each tenant's notifications to a target of the tenant's own. Deliverers
+// must implement it to be used when tenancy is configured.
+type TenantDeliverer interface {
+	// ForTenant returns a Deliverer sending to the tenant's target, or
+	// reports false if the tenant has none.
+	//
+	// The returned Deliverer is only used for a single delivery, and
+	// implements DirectDeliverer if the TenantDeliverer does.
+	ForTenant(tenant string) (Deliverer, bool)
+}
+
 // DirectDeliverer implementations are used in coordination with the Deliverer interface.
 //
 // DirectDeliverer(s) expect this method to be called prior to their Deliverer methods.
@@ -3,6 +3,10 @@ package notifier
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,45 +20,88 @@ import (
 type Delivery struct {
 	// a Deliverer implemention to invoke.
 	Deliverer Deliverer
+	// an optional Filter selecting which notifications are delivered.
+	//
+	// Must have been returned by Filter.Validate.
+	Filter *Filter
+	// an optional window for coalescing notifications.
+	//
+	// If set, notification ids created within the window are merged into one
+	// before delivery. See Delivery.coalesce.
+	Window time.Duration
+	// the number of notification ids claimed at a time. Smaller batches
+	// spread work more evenly between notifier replicas.
+	//
+	// If zero, DefaultClaimBatch is used.
+	BatchSize int
+	// how long claimed notification ids are reserved for this Delivery. If
+	// the process goes away, other replicas deliver them once it's up.
+	//
+	// If zero, DefaultClaimLease is used.
+	Lease time.Duration
+	// an optional RateLimiter, shared with the other Deliveries sending to
+	// the same targets.
+	Limiter *RateLimiter
+	// optional Owners, scoping delivery to tenants. If set, each tenant's
+	// notifications are delivered to its own target, and the Deliverer must
+	// implement TenantDeliverer. See Delivery.doTenants.
+	Owners Owners
 	// the interval at which we will attempt delivery of notifications.
 	interval time.Duration
 	// a store to retrieve notifications and update their receipts
 	store Store
-	// distributed lock used for mutual exclusion
+	// distributed lock used for mutual exclusion while coalescing
 	distLock distlock.Locker
+	// identifies this Delivery's claims in the store
+	owner string
 	// a integer id used for logging
 	id uint8
+
+	drainOnce sync.Once
+	drain     chan struct{}
 }
 
+// These are the defaults for claiming notification ids.
+const (
+	DefaultClaimBatch = 10
+	DefaultClaimLease = 5 * time.Minute
+)
+
 func NewDelivery(id int, d Deliverer, interval time.Duration, store Store, distLock distlock.Locker) *Delivery {
+	host, _ := os.Hostname()
 	return &Delivery{
 		Deliverer: d,
 		interval:  interval,
 		store:     store,
 		distLock:  distLock,
+		owner:     fmt.Sprintf("%s/%s/%d", host, uuid.New(), id),
 		id:        uint8(id),
+		drain:     make(chan struct{}),
 	}
 }
 
+// Drain makes Run return once the delivery run in progress, if any, is done,
+// instead of starting another one.
+func (d *Delivery) Drain() {
+	d.drainOnce.Do(func() { close(d.drain) })
+}
+
 // Deliver begins delivering notifications.
 //
 // Canceling the ctx will end delivery.
 func (d *Delivery) Deliver(ctx context.Context) {
-	log := zerolog.Ctx(ctx).With().Uint8("id", d.id).
-		Str("deliverer", d.Deliverer.Name()).
-		Str("component", "notifier/delivery/Delivery.Deliver").Logger()
-	log.Info().Msg("delivering notifications")
-	go d.deliver(ctx)
+	go d.Run(ctx)
 }
 
-// deliver is intended to be ran as a go routine.
-//
-// implements a blocking event loop via a time.Ticker
-func (d *Delivery) deliver(ctx context.Context) error {
+// Run implements a blocking event loop via a time.Ticker. It returns when the
+// ctx is canceled, or when Drain is called and the delivery run in progress
+// is done.
+func (d *Delivery) Run(ctx context.Context) error {
 	log := zerolog.Ctx(ctx).With().
 		Str("deliverer", d.Deliverer.Name()).
 		Uint8("id", d.id).
-		Str("component", "notifier/delivery/Delivery.deliver").Logger()
+		Str("component", "notifier/delivery/Delivery.Run").Logger()
+	log.Info().Msg("delivering notifications")
 
 	ticker := time.NewTicker(d.interval)
 	defer ticker.Stop()
@@ -62,6 +109,8 @@ func (d *Delivery) deliver(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-d.drain:
+			return nil
 		case <-ticker.C:
 			log.Debug().Msg("delivery tick")
 			err := d.RunDelivery(ctx)
@@ -74,6 +123,10 @@ func (d *Delivery) deliver(ctx context.Context) error {
 
 // RunDelivery determines notifications to deliver and
 // calls the implemented Deliverer to perform the actions.
+//
+// Notification ids are claimed in batches before they're delivered, so any
+// number of notifier replicas may run deliveries against the same store
+// without delivering a notification id twice.
 func (d *Delivery) RunDelivery(ctx context.Context) error {
 	log := zerolog.Ctx(ctx).With().
 		Str("deliverer", d.Deliverer.Name()).
@@ -86,6 +139,11 @@ func (d *Delivery) RunDelivery(ctx context.Context) error {
 		return err
 	} else {
 		log.Info().Int("created", len(created)).Msg("notification ids in created status")
+		if d.Window > 0 {
+			if created, err = d.coalesce(ctx, created); err != nil {
+				return err
+			}
+		}
 		toDeliver = append(toDeliver, created...)
 	}
 
@@ -97,27 +155,109 @@ func (d *Delivery) RunDelivery(ctx context.Context) error {
 		toDeliver = append(toDeliver, failed...)
 	}
 
-	for _, nID := range toDeliver {
-		ok, err := d.distLock.TryLock(ctx, nID.String())
+	batch, lease := d.BatchSize, d.Lease
+	if batch <= 0 {
+		batch = DefaultClaimBatch
+	}
+	if lease <= 0 {
+		lease = DefaultClaimLease
+	}
+	for len(toDeliver) != 0 && ctx.Err() == nil {
+		claimed, err := d.store.Claim(ctx, d.owner, lease, toDeliver, batch)
 		if err != nil {
-			// distlock failed, back off till next tick
+			// store failed, back off till next tick
 			return err
 		}
-		if !ok {
-			log.Debug().Str("notification_id", nID.String()).Msg("another process is deliverying this notification")
-			// another process is working on this notification
-			continue
+		if len(claimed) == 0 {
+			// everything left is being delivered by other processes
+			log.Debug().Int("remaining", len(toDeliver)).Msg("no notification ids left to claim")
+			return nil
+		}
+		log.Debug().Int("claimed", len(claimed)).Msg("claimed notification ids")
+		if err := d.deliverClaimed(ctx, claimed, time.Now().Add(lease)); err != nil {
+			return err
+		}
+		toDeliver = without(toDeliver, claimed)
+	}
+	return nil
+}
+
+// DeliverClaimed delivers the claimed notification ids, releasing each claim
+// as it goes. Notification ids left once the lease is nearly up are released
+// undelivered, as another process may claim them any moment.
+func (d *Delivery) deliverClaimed(ctx context.Context, claimed []uuid.UUID, until time.Time) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("deliverer", d.Deliverer.Name()).
+		Uint8("id", d.id).
+		Str("component", "notifier/delivery/Delivery.deliverClaimed").Logger()
+	margin := d.Lease / 10
+	if margin <= 0 {
+		margin = DefaultClaimLease / 10
+	}
+	i := 0
+	defer func() {
+		for _, nID := range claimed[i:] {
+			d.release(ctx, nID)
+		}
+	}()
+	for ; i < len(claimed); i++ {
+		nID := claimed[i]
+		if time.Until(until) < margin {
+			log.Info().
+				Int("remaining", len(claimed)-i).
+				Msg("claim nearly expired, leaving notification ids for later")
+			return nil
+		}
+		if d.Limiter != nil {
+			ok, err := d.Limiter.Wait(ctx, target(d.Deliverer), until.Add(-margin))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				log.Info().
+					Int("remaining", len(claimed)-i).
+					Msg("rate limit outlasts claim, leaving notification ids for later")
+				return nil
+			}
 		}
 		// an error means we should back off until next tick
-		err = d.do(ctx, nID)
-		d.distLock.Unlock()
+		err := d.do(ctx, nID)
+		d.release(ctx, nID)
 		if err != nil {
+			i++
 			return err
 		}
 	}
 	return nil
 }
 
+// Release gives up the claim on the notification id. A claim that can't be
+// released lapses on its own, so this only logs failures.
+func (d *Delivery) release(ctx context.Context, nID uuid.UUID) {
+	if err := d.store.Release(ctx, d.owner, nID); err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "notifier/delivery/Delivery.release").
+			Str("notification_id", nID.String()).
+			Err(err).
+			Msg("failed to release claim")
+	}
+}
+
+// Without returns the ids not in drop.
+func without(ids, drop []uuid.UUID) []uuid.UUID {
+	skip := make(map[uuid.UUID]bool, len(drop))
+	for _, id := range drop {
+		skip[id] = true
+	}
+	out := ids[:0:0]
+	for _, id := range ids {
+		if !skip[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
 // do performs the delivery of notifications via the composed
 // deliverer
 //
@@ -127,37 +267,56 @@ func (d *Delivery) do(ctx context.Context, nID uuid.UUID) error {
 		Str("deliverer", d.Deliverer.Name()).
 		Uint8("id", d.id).
 		Str("component", "notifier/delivery/Delivery.do").Logger()
+	if d.Owners != nil {
+		return d.doTenants(ctx, nID)
+	}
 
-	// if we have a direct deliverer provide the notifications to it.
-	if dd, ok := d.Deliverer.(DirectDeliverer); ok {
-		log.Debug().Msg("providing direct deliverer notifications")
+	// if we have a direct deliverer or a filter, we need the notifications.
+	dd, direct := d.Deliverer.(DirectDeliverer)
+	skip := false
+	if direct || d.Filter != nil {
 		notifications, _, err := d.store.Notifications(ctx, nID, nil)
 		if err != nil {
 			return err
 		}
-		err = dd.Notifications(ctx, notifications)
-		if err != nil {
-			return err
+		if d.Filter != nil {
+			notifications = d.Filter.Apply(notifications)
+			// nothing this deliverer cares about, so there's nothing to
+			// deliver. the notification is acked as if it had been.
+			skip = len(notifications) == 0
 		}
-	}
-
-	// deliver the notification
-	err := d.Deliverer.Deliver(ctx, nID)
-	if err != nil {
-		var dErr clairerror.ErrDeliveryFailed
-		if errors.As(err, &dErr) {
-			// OK for this to fail, notification will stay in Created status.
-			// store is failing, lets back off it tho until next tick.
-			log.Info().Str("notifcation_id", nID.String()).Msg("failed to deliver notifications")
-			err := d.store.SetDeliveryFailed(ctx, nID)
+		if direct && !skip {
+			log.Debug().Msg("providing direct deliverer notifications")
+			err = dd.Notifications(ctx, notifications)
 			if err != nil {
 				return err
 			}
-			return nil
 		}
-		return err
 	}
-	err = d.store.SetDelivered(ctx, nID)
+
+	if skip {
+		log.Debug().Str("notifcation_id", nID.String()).Msg("no notifications passed filter")
+		d.record(ctx, d.Deliverer, nID, nil, true)
+	} else {
+		// deliver the notification
+		err := d.Deliverer.Deliver(ctx, nID)
+		d.record(ctx, d.Deliverer, nID, err, false)
+		if err != nil {
+			var dErr clairerror.ErrDeliveryFailed
+			if errors.As(err, &dErr) {
+				// OK for this to fail, notification will stay in Created status.
+				// store is failing, lets back off it tho until next tick.
+				log.Info().Str("notifcation_id", nID.String()).Msg("failed to deliver notifications")
+				err := d.store.SetDeliveryFailed(ctx, nID)
+				if err != nil {
+					return err
+				}
+				return nil
+			}
+			return err
+		}
+	}
+	err := d.store.SetDelivered(ctx, nID)
 	if err != nil {
 		// the message was delivered, but we can't ack this in our db
 		// it will be delivered again unless deleted before next interval
@@ -166,7 +325,7 @@ func (d *Delivery) do(ctx context.Context, nID uuid.UUID) error {
 
 	// if we successfully performed direct delivery
 	// we can delete notification id
-	if _, ok := d.Deliverer.(DirectDeliverer); ok {
+	if direct {
 		err := d.store.SetDeleted(ctx, nID)
 		if err != nil {
 			return err
@@ -175,3 +334,109 @@ func (d *Delivery) do(ctx context.Context, nID uuid.UUID) error {
 	log.Info().Str("notifcation_id", nID.String()).Msg("successfully delivered notifications")
 	return nil
 }
+
+// doTenants delivers the notifications of each tenant to the tenant's own
+// target. Notifications about manifests no tenant submitted, and those of
+// tenants without a target, aren't delivered anywhere.
+//
+// If delivery to one tenant fails, the notification id is delivered to every
+// tenant again later, so tenants may see it more than once.
+func (d *Delivery) doTenants(ctx context.Context, nID uuid.UUID) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("deliverer", d.Deliverer.Name()).
+		Uint8("id", d.id).
+		Str("component", "notifier/delivery/Delivery.doTenants").Logger()
+
+	td, ok := d.Deliverer.(TenantDeliverer)
+	if !ok {
+		return fmt.Errorf("deliverer %q can't deliver to tenants", d.Deliverer.Name())
+	}
+	notifications, _, err := d.store.Notifications(ctx, nID, nil)
+	if err != nil {
+		return err
+	}
+	byTenant, err := ByTenant(ctx, d.Owners, d.Filter.Apply(notifications))
+	if err != nil {
+		return err
+	}
+	tenants := make([]string, 0, len(byTenant))
+	for t := range byTenant {
+		tenants = append(tenants, t)
+	}
+	sort.Strings(tenants)
+
+	delivered := false
+	for _, t := range tenants {
+		dl, ok := td.ForTenant(t)
+		if !ok {
+			log.Debug().Str("tenant", t).Msg("no target for tenant")
+			continue
+		}
+		if dd, ok := dl.(DirectDeliverer); ok {
+			if err := dd.Notifications(ctx, byTenant[t]); err != nil {
+				return err
+			}
+		}
+		err := dl.Deliver(ctx, nID)
+		d.record(ctx, dl, nID, err, false)
+		if err != nil {
+			var dErr clairerror.ErrDeliveryFailed
+			if errors.As(err, &dErr) {
+				log.Info().Str("notifcation_id", nID.String()).
+					Str("tenant", t).
+					Msg("failed to deliver notifications")
+				return d.store.SetDeliveryFailed(ctx, nID)
+			}
+			return err
+		}
+		delivered = true
+	}
+	if !delivered {
+		log.Debug().Str("notifcation_id", nID.String()).Msg("no notifications for tenants with targets")
+		d.record(ctx, d.Deliverer, nID, nil, true)
+	}
+	if err := d.store.SetDelivered(ctx, nID); err != nil {
+		return err
+	}
+	if _, direct := d.Deliverer.(DirectDeliverer); direct {
+		if err := d.store.SetDeleted(ctx, nID); err != nil {
+			return err
+		}
+	}
+	log.Info().Str("notifcation_id", nID.String()).Msg("successfully delivered notifications")
+	return nil
+}
+
+// record persists the outcome of an attempt to deliver with dl.
+//
+// Failing to record an attempt doesn't fail the delivery, so the notification
+// isn't delivered twice on account of it.
+func (d *Delivery) record(ctx context.Context, dl Deliverer, nID uuid.UUID, err error, filtered bool) {
+	a := Attempt{
+		NotificationID: nID,
+		Deliverer:      dl.Name(),
+		TS:             time.Now(),
+		Status:         AttemptDelivered,
+	}
+	if t, ok := dl.(Targeter); ok {
+		a.Target = t.Target()
+	}
+	switch {
+	case filtered:
+		a.Status = AttemptFiltered
+	case err != nil:
+		a.Status = AttemptFailed
+		a.Error = err.Error()
+		var rErr *clairerror.ErrRequestFail
+		if errors.As(err, &rErr) {
+			a.ResponseCode = rErr.Code
+		}
+	}
+	if err := d.store.PutAttempt(ctx, a); err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "notifier/delivery/Delivery.record").
+			Str("notification_id", nID.String()).
+			Err(err).
+			Msg("failed to record delivery attempt")
+	}
+}
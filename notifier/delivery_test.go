@@ -0,0 +1,123 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+)
+
+// CountingDeliverer records how many times each notification id is
+// delivered.
+type countingDeliverer struct {
+	mu sync.Mutex
+	n  map[uuid.UUID]int
+}
+
+func (*countingDeliverer) Name() string { return "counting" }
+func (d *countingDeliverer) Deliver(_ context.Context, id uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.n[id]++
+	return nil
+}
+
+// TestDeliveryClaims confirms deliveries sharing a store deliver every
+// notification id exactly once, and release their claims.
+func TestDeliveryClaims(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ids := make([]uuid.UUID, 25)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	var mu sync.Mutex
+	claims := make(map[uuid.UUID]string)
+	done := make(map[uuid.UUID]bool)
+	store := &MockStore{
+		Created_: func(context.Context) ([]uuid.UUID, error) {
+			return ids, nil
+		},
+		Failed_: func(context.Context) ([]uuid.UUID, error) {
+			return nil, nil
+		},
+		Claim_: func(_ context.Context, owner string, _ time.Duration, cand []uuid.UUID, limit int) ([]uuid.UUID, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var out []uuid.UUID
+			for _, id := range cand {
+				if len(out) == limit {
+					break
+				}
+				if _, ok := claims[id]; ok || done[id] {
+					continue
+				}
+				claims[id] = owner
+				out = append(out, id)
+			}
+			return out, nil
+		},
+		Release_: func(_ context.Context, owner string, id uuid.UUID) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if claims[id] != owner {
+				t.Errorf("%v: released by %q, claimed by %q", id, owner, claims[id])
+			}
+			delete(claims, id)
+			return nil
+		},
+		SetDelivered_: func(_ context.Context, id uuid.UUID) error {
+			mu.Lock()
+			defer mu.Unlock()
+			done[id] = true
+			return nil
+		},
+		PutAttempt_: func(context.Context, Attempt) error { return nil },
+	}
+	d := &countingDeliverer{n: make(map[uuid.UUID]int)}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		dl := NewDelivery(i, d, time.Second, store, locker{})
+		dl.BatchSize = 4
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := dl.RunDelivery(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		if got := d.n[id]; got != 1 {
+			t.Errorf("%v: delivered %d times", id, got)
+		}
+	}
+	if len(claims) != 0 {
+		t.Errorf("%d claims left", len(claims))
+	}
+}
+
+// TestDrain confirms a drained Delivery and Processor return without their
+// context being canceled.
+func TestDrain(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	d := NewDelivery(0, &countingDeliverer{}, time.Hour, &MockStore{}, locker{})
+	p := NewProcessor(0, locker{}, nil, nil, &MockStore{})
+	done := make(chan struct{}, 2)
+	go func() { d.Run(ctx); done <- struct{}{} }()
+	go func() { p.Run(ctx, make(chan Event)); done <- struct{}{} }()
+
+	d.Drain()
+	p.Drain()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("didn't return after draining")
+		}
+	}
+}
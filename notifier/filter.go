@@ -0,0 +1,194 @@
+package notifier
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer/events"
+)
+
+// Filter selects which notifications a deliverer receives.
+//
+// Every configured condition must hold for a notification to be delivered.
+// A nil or zero Filter matches everything.
+type Filter struct {
+	// The lowest severity delivered, e.g. "High".
+	//
+	// Must be one of the claircore severities: "Unknown", "Negligible",
+	// "Low", "Medium", "High", or "Critical".
+	MinSeverity string `yaml:"min_severity" json:"min_severity"`
+	minSeverity claircore.Severity
+	// An allowlist of namespaces. A notification matches if its
+	// distribution's ID or name, or its repository's name, is in the list.
+	Namespaces []string `yaml:"namespaces" json:"namespaces"`
+	// Only deliver notifications for vulnerabilities with a fix available.
+	FixedOnly bool `yaml:"fixed_only" json:"fixed_only"`
+	// The kinds of index events delivered: "started", "finished", or
+	// "failed".
+	//
+	// Index events are only delivered if listed here, and only by deliverers
+	// implementing EventDeliverer.
+	IndexEvents []string `yaml:"index_events" json:"index_events"`
+}
+
+// Validate confirms the Filter is valid and returns a copy with private
+// members filled in.
+func (f *Filter) Validate() (*Filter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	c := *f
+	if f.MinSeverity != "" {
+		sev, ok := ParseSeverity(f.MinSeverity)
+		if !ok {
+			return nil, fmt.Errorf("invalid filter: unknown severity %q", f.MinSeverity)
+		}
+		c.minSeverity = sev
+	}
+Kinds:
+	for _, k := range f.IndexEvents {
+		for _, known := range events.Kinds {
+			if k == known {
+				continue Kinds
+			}
+		}
+		return nil, fmt.Errorf("invalid filter: unknown index event %q", k)
+	}
+	return &c, nil
+}
+
+// ParseSeverity is like claircore.Severity's UnmarshalText, but only accepts
+// complete severity names.
+func ParseSeverity(s string) (claircore.Severity, bool) {
+	for sev := claircore.Unknown; sev <= claircore.Critical; sev++ {
+		if sev.String() == s {
+			return sev, true
+		}
+	}
+	return claircore.Unknown, false
+}
+
+// Match reports whether the Notification passes the Filter.
+//
+// The Filter must have been returned by Validate.
+func (f *Filter) Match(n *Notification) bool {
+	if f == nil {
+		return true
+	}
+	v := &n.Vulnerability
+	if f.minSeverity != claircore.Unknown {
+		// An unparsable severity is treated as Unknown.
+		if sev, _ := ParseSeverity(v.Severity); sev < f.minSeverity {
+			return false
+		}
+	}
+	if f.FixedOnly && v.FixedInVersion == "" {
+		return false
+	}
+	if len(f.Namespaces) != 0 {
+		var names []string
+		if d := v.Distribution; d != nil {
+			names = append(names, d.DID, d.Name)
+		}
+		if r := v.Repo; r != nil {
+			names = append(names, r.Name)
+		}
+		found := false
+	Search:
+		for _, ns := range f.Namespaces {
+			for _, n := range names {
+				if n != "" && n == ns {
+					found = true
+					break Search
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchEvent reports whether index events of the kind pass the Filter.
+//
+// Unlike notifications, a nil Filter matches no index events.
+func (f *Filter) MatchEvent(kind string) bool {
+	if f == nil {
+		return false
+	}
+	for _, k := range f.IndexEvents {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the Notifications that pass the Filter.
+func (f *Filter) Apply(ns []Notification) []Notification {
+	if f == nil {
+		return ns
+	}
+	out := make([]Notification, 0, len(ns))
+	for i := range ns {
+		if f.Match(&ns[i]) {
+			out = append(out, ns[i])
+		}
+	}
+	return out
+}
+
+// Query parameters the notification page endpoint filters by.
+const (
+	queryMinSeverity = "min_severity"
+	queryNamespace   = "namespace"
+	queryFixedOnly   = "fixed_only"
+)
+
+// SetQuery adds the Filter's notification conditions to the URL's query, so
+// clients of callback deliverers fetching the notification page only see the
+// notifications the Filter passes.
+func (f *Filter) SetQuery(u *url.URL) {
+	if f == nil {
+		return
+	}
+	q := u.Query()
+	if f.MinSeverity != "" {
+		q.Set(queryMinSeverity, f.MinSeverity)
+	}
+	for _, ns := range f.Namespaces {
+		q.Add(queryNamespace, ns)
+	}
+	if f.FixedOnly {
+		q.Set(queryFixedOnly, "true")
+	}
+	u.RawQuery = q.Encode()
+}
+
+// FilterFromQuery returns the validated Filter described by the query
+// parameters SetQuery adds, or nil if there are none.
+func FilterFromQuery(q url.Values) (*Filter, error) {
+	var f Filter
+	var ok bool
+	if v := q.Get(queryMinSeverity); v != "" {
+		f.MinSeverity, ok = v, true
+	}
+	if vs := q[queryNamespace]; len(vs) != 0 {
+		f.Namespaces, ok = vs, true
+	}
+	if v := q.Get(queryFixedOnly); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: bad %q value %q", queryFixedOnly, v)
+		}
+		f.FixedOnly, ok = b, true
+	}
+	if !ok {
+		return nil, nil
+	}
+	return f.Validate()
+}
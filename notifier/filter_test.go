@@ -0,0 +1,158 @@
+package notifier
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+func TestFilter(t *testing.T) {
+	ubuntu := &claircore.Distribution{DID: "ubuntu", Name: "Ubuntu"}
+	pypi := &claircore.Repository{Name: "pypi"}
+	ns := []Notification{
+		{Vulnerability: VulnSummary{Name: "0", Severity: "Critical", FixedInVersion: "1.0", Distribution: ubuntu}},
+		{Vulnerability: VulnSummary{Name: "1", Severity: "Critical", Distribution: ubuntu}},
+		{Vulnerability: VulnSummary{Name: "2", Severity: "Low", FixedInVersion: "1.0", Repo: pypi}},
+		{Vulnerability: VulnSummary{Name: "3", Severity: "High"}},
+		{Vulnerability: VulnSummary{Name: "4", Severity: ""}},
+	}
+	tt := []struct {
+		Name   string
+		Filter *Filter
+		Want   []string
+	}{
+		{Name: "Nil", Want: []string{"0", "1", "2", "3", "4"}},
+		{Name: "Zero", Filter: &Filter{}, Want: []string{"0", "1", "2", "3", "4"}},
+		{Name: "Severity", Filter: &Filter{MinSeverity: "High"}, Want: []string{"0", "1", "3"}},
+		{Name: "Fixed", Filter: &Filter{FixedOnly: true}, Want: []string{"0", "2"}},
+		{Name: "Namespace", Filter: &Filter{Namespaces: []string{"ubuntu", "pypi"}}, Want: []string{"0", "1", "2"}},
+		{Name: "Combined", Filter: &Filter{MinSeverity: "Critical", FixedOnly: true, Namespaces: []string{"ubuntu"}}, Want: []string{"0"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			f, err := tc.Filter.Validate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := f.Apply(ns)
+			if len(got) != len(tc.Want) {
+				t.Fatalf("got: %d notifications, want: %d", len(got), len(tc.Want))
+			}
+			for i := range got {
+				if got, want := got[i].Vulnerability.Name, tc.Want[i]; got != want {
+					t.Errorf("got: %q, want: %q", got, want)
+				}
+			}
+
+			// A callback's filter parameters select the same notifications.
+			u, _ := url.Parse("http://clair/notifier/api/v1/notification/1?page_size=10")
+			f.SetQuery(u)
+			qf, err := FilterFromQuery(u.Query())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(qf.Apply(ns)), len(tc.Want); got != want {
+				t.Errorf("query %q: got: %d notifications, want: %d", u.RawQuery, got, want)
+			}
+		})
+	}
+
+	t.Run("BadQuery", func(t *testing.T) {
+		if _, err := FilterFromQuery(url.Values{"fixed_only": {"maybe"}}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("BadSeverity", func(t *testing.T) {
+		f := &Filter{MinSeverity: "igh"}
+		if _, err := f.Validate(); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+type filterDeliverer struct {
+	delivered []uuid.UUID
+	got       []Notification
+}
+
+func (d *filterDeliverer) Name() string { return "filter-test" }
+
+func (d *filterDeliverer) Deliver(_ context.Context, id uuid.UUID) error {
+	d.delivered = append(d.delivered, id)
+	return nil
+}
+
+func (d *filterDeliverer) Notifications(_ context.Context, n []Notification) error {
+	d.got = append(d.got, n...)
+	return nil
+}
+
+func TestDeliveryFilter(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, err := (&Filter{MinSeverity: "High"}).Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, high := uuid.New(), uuid.New()
+	var delivered, deleted []uuid.UUID
+	var attempts []Attempt
+	store := &MockStore{
+		Notifications_: func(_ context.Context, id uuid.UUID, _ *Page) ([]Notification, Page, error) {
+			sev := "Low"
+			if id == high {
+				sev = "High"
+			}
+			return []Notification{
+				{ID: id, Vulnerability: VulnSummary{Severity: sev}},
+			}, Page{}, nil
+		},
+		SetDelivered_: func(_ context.Context, id uuid.UUID) error {
+			delivered = append(delivered, id)
+			return nil
+		},
+		SetDeleted_: func(_ context.Context, id uuid.UUID) error {
+			deleted = append(deleted, id)
+			return nil
+		},
+		PutAttempt_: func(_ context.Context, a Attempt) error {
+			attempts = append(attempts, a)
+			return nil
+		},
+	}
+	dd := &filterDeliverer{}
+	d := NewDelivery(0, dd, 0, store, nil)
+	d.Filter = f
+	for _, id := range []uuid.UUID{low, high} {
+		if err := d.do(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := len(dd.delivered), 1; got != want || dd.delivered[0] != high {
+		t.Errorf("got: %v, want: [%v]", dd.delivered, high)
+	}
+	if got, want := len(dd.got), 1; got != want {
+		t.Errorf("got: %d notifications, want: %d", got, want)
+	}
+	// Both are acked, so the filtered one isn't retried.
+	if got, want := len(delivered), 2; got != want {
+		t.Errorf("delivered: got: %d, want: %d", got, want)
+	}
+	if got, want := len(deleted), 2; got != want {
+		t.Errorf("deleted: got: %d, want: %d", got, want)
+	}
+	// Both attempts are recorded, noting which one was filtered.
+	if got, want := len(attempts), 2; got != want {
+		t.Fatalf("attempts: got: %d, want: %d", got, want)
+	}
+	for i, want := range []AttemptStatus{AttemptFiltered, AttemptDelivered} {
+		if got := attempts[i].Status; got != want {
+			t.Errorf("attempt %d: got: %q, want: %q", i, got, want)
+		}
+	}
+}
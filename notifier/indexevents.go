@@ -0,0 +1,131 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/quay/claircore/pkg/distlock"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer/events"
+)
+
+// EventDeliverer is an optional interface a Deliverer may implement to
+// deliver index events.
+type EventDeliverer interface {
+	// DeliverIndexEvent pushes the event to subscribed clients.
+	//
+	// If delivery fails a clairerror.ErrDeliveryFailed error must be returned.
+	DeliverIndexEvent(ctx context.Context, ev *events.Event) error
+}
+
+// EventCursor persists the sequence number of the last index event relayed,
+// so relaying resumes where it left off.
+type EventCursor interface {
+	// IndexEventCursor returns the saved sequence number, or 0 if there
+	// isn't one.
+	IndexEventCursor(ctx context.Context) (int64, error)
+	// SetIndexEventCursor saves the sequence number.
+	SetIndexEventCursor(ctx context.Context, seq int64) error
+}
+
+// EventBatch is the number of index events requested at once.
+const eventBatch = 100
+
+// EventRelayLock is the distributed lock held while relaying index events,
+// so only one notifier process relays them.
+const eventRelayLock = "notifier-index-events"
+
+// EventRelay delivers the index events an indexer records.
+type EventRelay struct {
+	Source    events.Source
+	Deliverer EventDeliverer
+	// Filter selects the events delivered. Events it doesn't match are
+	// skipped.
+	//
+	// Must have been returned by Filter.Validate.
+	Filter *Filter
+	Cursor EventCursor
+	Locker distlock.Locker
+	// The interval at which new events are looked for.
+	Interval time.Duration
+}
+
+// Run relays events every interval, until the context is canceled.
+func (r *EventRelay) Run(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/EventRelay.Run").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Strs("kinds", r.Filter.IndexEvents).Msg("relaying index events")
+	t := time.NewTicker(r.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := r.RunOnce(ctx); err != nil {
+			log.Warn().Err(err).Msg("failed to relay index events")
+		}
+	}
+}
+
+// RunOnce relays the events recorded since the last run.
+//
+// Delivery stops at the first event that fails, to be attempted again on the
+// next run. The cursor is saved after every batch, so events may be delivered
+// again if the process exits partway through one.
+func (r *EventRelay) RunOnce(ctx context.Context) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/EventRelay.RunOnce").
+		Logger()
+	ok, err := r.Locker.TryLock(ctx, eventRelayLock)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		log.Debug().Msg("another process is relaying index events")
+		return nil
+	}
+	defer r.Locker.Unlock()
+
+	seq, err := r.Cursor.IndexEventCursor(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		evs, err := r.Source.IndexEvents(ctx, seq, eventBatch)
+		if err != nil {
+			return err
+		}
+		for i := range evs {
+			ev := &evs[i]
+			if r.Filter.MatchEvent(ev.Kind) {
+				if err := r.Deliverer.DeliverIndexEvent(ctx, ev); err != nil {
+					// Keep what was delivered before this one.
+					if serr := r.Cursor.SetIndexEventCursor(ctx, seq); serr != nil {
+						log.Warn().Err(serr).Msg("failed to save index event cursor")
+					}
+					return err
+				}
+				log.Debug().
+					Int64("seq", ev.Seq).
+					Str("kind", ev.Kind).
+					Str("manifest", ev.Manifest).
+					Msg("delivered index event")
+			}
+			seq = ev.Seq
+		}
+		if len(evs) == 0 {
+			return nil
+		}
+		if err := r.Cursor.SetIndexEventCursor(ctx, seq); err != nil {
+			return err
+		}
+		if len(evs) < eventBatch {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/indexer/events"
+)
+
+// EventLog is an events.Source, EventCursor, and EventDeliverer over slices.
+type eventLog struct {
+	evs       []events.Event
+	cursor    int64
+	delivered []int64
+	// Fail, if not zero, is the sequence number delivery fails at.
+	fail int64
+}
+
+func (l *eventLog) IndexEvents(_ context.Context, after int64, limit int) ([]events.Event, error) {
+	var out []events.Event
+	for _, ev := range l.evs {
+		if ev.Seq > after && len(out) < limit {
+			out = append(out, ev)
+		}
+	}
+	return out, nil
+}
+
+func (l *eventLog) IndexEventCursor(context.Context) (int64, error) { return l.cursor, nil }
+
+func (l *eventLog) SetIndexEventCursor(_ context.Context, seq int64) error {
+	l.cursor = seq
+	return nil
+}
+
+func (l *eventLog) DeliverIndexEvent(_ context.Context, ev *events.Event) error {
+	if ev.Seq == l.fail {
+		return errors.New("delivery failed")
+	}
+	l.delivered = append(l.delivered, ev.Seq)
+	return nil
+}
+
+func TestEventRelay(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := &eventLog{}
+	for i := int64(1); i <= 2*eventBatch+10; i++ {
+		kind := events.Started
+		if i%2 == 0 {
+			kind = events.Finished
+		}
+		l.evs = append(l.evs, events.Event{Seq: i, Kind: kind})
+	}
+	f, err := (&Filter{IndexEvents: []string{events.Finished}}).Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &EventRelay{
+		Source:    l,
+		Deliverer: l,
+		Filter:    f,
+		Cursor:    l,
+		Locker:    locker{},
+	}
+
+	l.fail = 50
+	if err := r.RunOnce(ctx); err == nil {
+		t.Error("expected error")
+	}
+	if got, want := l.cursor, int64(49); got != want {
+		t.Errorf("cursor: got: %d, want: %d", got, want)
+	}
+
+	l.fail = 0
+	if err := r.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var want []int64
+	for i := int64(2); i <= 2*eventBatch+10; i += 2 {
+		want = append(want, i)
+	}
+	if !cmp.Equal(l.delivered, want) {
+		t.Error(cmp.Diff(l.delivered, want))
+	}
+	if got, want := l.cursor, int64(2*eventBatch+10); got != want {
+		t.Errorf("cursor: got: %d, want: %d", got, want)
+	}
+}
+
+func TestFilterIndexEvents(t *testing.T) {
+	if _, err := (&Filter{IndexEvents: []string{"exploded"}}).Validate(); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+	var f *Filter
+	if f.MatchEvent(events.Failed) {
+		t.Error("nil filter matched an index event")
+	}
+}
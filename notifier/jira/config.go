@@ -0,0 +1,110 @@
+package jira
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+const (
+	// DefaultIssueType is the type of issues created if one is not
+	// configured.
+	DefaultIssueType = "Bug"
+	// DefaultMinSeverity is the lowest Clair severity that opens an issue if
+	// one is not configured.
+	DefaultMinSeverity = "High"
+)
+
+// Config provides configuration for a Jira deliverer.
+type Config struct {
+	// The Jira instance's base URL, e.g. "https://example.atlassian.net".
+	URL string `yaml:"url"`
+	url *url.URL
+	// The user the token belongs to. If set, requests use basic
+	// authentication, as Jira Cloud API tokens need. Otherwise, the token is
+	// sent as a bearer token, as Jira Data Center personal access tokens
+	// need.
+	User string `yaml:"user"`
+	// The API token or personal access token.
+	Token string `yaml:"token" secret:"true"`
+	// The key of the project issues are created in.
+	Project string `yaml:"project"`
+	// The type of issues created. Defaults to "Bug".
+	IssueType string `yaml:"issue_type"`
+	// Labels added to created issues.
+	Labels []string `yaml:"labels"`
+	// The lowest Clair severity that opens an issue, e.g. "High".
+	//
+	// Must be one of the claircore severities: "Unknown", "Negligible",
+	// "Low", "Medium", "High", or "Critical".
+	MinSeverity string `yaml:"min_severity"`
+	minSeverity claircore.Severity
+	// A map of Clair severities to Jira priority names. Issues for severities
+	// not in the map get the project's default priority.
+	Priorities map[string]string `yaml:"priorities"`
+	// Additional fields set on created issues, by Jira field ID, e.g.
+	// "customfield_10010" or "components".
+	//
+	// String values may contain placeholders replaced with details of the
+	// notification: "{vulnerability}", "{severity}", "{package}",
+	// "{version}", "{fixed_in}", "{manifest}", and "{reason}".
+	Fields map[string]interface{} `yaml:"fields"`
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+}
+
+// Validate confirms configuration is valid and fills in private members
+// with parsed values on success.
+func (c *Config) Validate() (Config, error) {
+	conf := *c
+	if c.URL == "" {
+		return conf, fmt.Errorf("jira config requires the url field")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return conf, fmt.Errorf("failed to parse url: %v", err)
+	}
+	conf.url = u
+	if c.Token == "" {
+		return conf, fmt.Errorf("jira config requires the token field")
+	}
+	if c.Project == "" {
+		return conf, fmt.Errorf("jira config requires the project field")
+	}
+	if conf.IssueType == "" {
+		conf.IssueType = DefaultIssueType
+	}
+
+	min := c.MinSeverity
+	if min == "" {
+		min = DefaultMinSeverity
+	}
+	sev, ok := notifier.ParseSeverity(min)
+	if !ok {
+		return conf, fmt.Errorf("jira config: unknown severity %q", min)
+	}
+	conf.minSeverity = sev
+	for k := range c.Priorities {
+		if _, ok := notifier.ParseSeverity(k); !ok {
+			return conf, fmt.Errorf("jira config: unknown severity %q", k)
+		}
+	}
+	for k := range c.Fields {
+		switch k {
+		case "project", "issuetype", "summary", "description", "labels":
+			return conf, fmt.Errorf("jira config: field %q can't be overridden", k)
+		}
+	}
+
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+	return conf, nil
+}
@@ -0,0 +1,330 @@
+// Package jira delivers notifications as Jira issues, using the REST API v2.
+package jira
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer opens a Jira issue for every vulnerability affecting a manifest
+// at or above the configured severity.
+//
+// Issues are deduplicated by vulnerability and manifest: if an issue already
+// exists, a comment is added to it instead. Notifications that a
+// vulnerability was removed only comment on existing issues.
+type Deliverer struct {
+	conf   Config
+	client *http.Client
+	n      []notifier.Notification
+}
+
+// New returns a new Jira Deliverer.
+//
+// If client is nil, http.DefaultClient is used.
+func New(conf Config, client *http.Client) (*Deliverer, error) {
+	c, err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Deliverer{
+		conf:   c,
+		client: client,
+		n:      []notifier.Notification{},
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return "jira"
+}
+
+// Target implements notifier.Targeter.
+func (d *Deliverer) Target() string {
+	return d.conf.url.String()
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// Only notifications at or above the configured severity are kept.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = d.n[:0]
+	for _, n := range n {
+		// An unparsable severity is treated as Unknown.
+		if sev, _ := notifier.ParseSeverity(n.Vulnerability.Severity); sev >= d.conf.minSeverity {
+			d.n = append(d.n, n)
+		}
+	}
+	return nil
+}
+
+// Deliver implements the notifier.Deliverer interface.
+//
+// Issues are created or commented on one at a time. If one fails, the ones
+// before it will be found and commented on during the retry.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/jira/Deliverer.Deliver").
+		Stringer("notification_id", nID).
+		Logger()
+	// Issues for vulnerabilities seen earlier in this set, by dedup label.
+	// A vulnerability may be reported for several packages in a manifest.
+	seen := make(map[string]string)
+	var created, commented int
+	for i := range d.n {
+		n := &d.n[i]
+		label := dedupLabel(n)
+		key, ok := seen[label]
+		if !ok {
+			var err error
+			key, err = d.find(ctx, label)
+			if err != nil {
+				return &clairerror.ErrDeliveryFailed{E: err}
+			}
+		}
+		switch {
+		case key != "":
+			if err := d.comment(ctx, key, n); err != nil {
+				return &clairerror.ErrDeliveryFailed{E: err}
+			}
+			commented++
+		case n.Reason == notifier.Removed:
+			// Nothing to update.
+		default:
+			var err error
+			key, err = d.create(ctx, n, label)
+			if err != nil {
+				return &clairerror.ErrDeliveryFailed{E: err}
+			}
+			created++
+		}
+		seen[label] = key
+	}
+	log.Debug().
+		Int("created", created).
+		Int("commented", commented).
+		Msg("delivered to jira")
+	return nil
+}
+
+// DedupLabel returns the label identifying issues for the notification's
+// vulnerability and manifest. Jira labels can't contain spaces, so it's a
+// digest.
+func dedupLabel(n *notifier.Notification) string {
+	h := sha256.New()
+	io.WriteString(h, n.Vulnerability.Name)
+	h.Write([]byte{0})
+	io.WriteString(h, n.Manifest.String())
+	return "clair-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Find returns the key of the newest issue with the label, or an empty string
+// if there isn't one.
+func (d *Deliverer) find(ctx context.Context, label string) (string, error) {
+	v := url.Values{
+		"jql":        {fmt.Sprintf("project = %q AND labels = %q ORDER BY created DESC", d.conf.Project, label)},
+		"fields":     {"key"},
+		"maxResults": {"1"},
+	}
+	var res struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	if err := d.do(ctx, http.MethodGet, "rest/api/2/search?"+v.Encode(), nil, http.StatusOK, &res); err != nil {
+		return "", err
+	}
+	if len(res.Issues) == 0 {
+		return "", nil
+	}
+	return res.Issues[0].Key, nil
+}
+
+// MaxSummary is the longest summary Jira accepts.
+const maxSummary = 255
+
+func (d *Deliverer) create(ctx context.Context, n *notifier.Notification, label string) (string, error) {
+	v := &n.Vulnerability
+	summary := fmt.Sprintf("%s %s vulnerability in %s", v.Name, v.Severity, n.Manifest)
+	if v.Package != nil {
+		summary = fmt.Sprintf("%s %s vulnerability in %s %s in %s",
+			v.Name, v.Severity, v.Package.Name, v.Package.Version, n.Manifest)
+	}
+	if len(summary) > maxSummary {
+		summary = summary[:maxSummary]
+	}
+	labels := make([]string, 0, len(d.conf.Labels)+1)
+	labels = append(labels, d.conf.Labels...)
+	labels = append(labels, label)
+
+	fields := make(map[string]interface{}, len(d.conf.Fields)+6)
+	r := replacer(n)
+	for k, f := range d.conf.Fields {
+		fields[k] = expand(r, f)
+	}
+	fields["project"] = map[string]string{"key": d.conf.Project}
+	fields["issuetype"] = map[string]string{"name": d.conf.IssueType}
+	fields["summary"] = summary
+	fields["description"] = description(n)
+	fields["labels"] = labels
+	if p, ok := d.conf.Priorities[v.Severity]; ok {
+		fields["priority"] = map[string]string{"name": p}
+	}
+
+	var res struct {
+		Key string `json:"key"`
+	}
+	body := map[string]interface{}{"fields": fields}
+	if err := d.do(ctx, http.MethodPost, "rest/api/2/issue", body, http.StatusCreated, &res); err != nil {
+		return "", err
+	}
+	return res.Key, nil
+}
+
+func (d *Deliverer) comment(ctx context.Context, key string, n *notifier.Notification) error {
+	v := &n.Vulnerability
+	what := v.Name
+	if v.Package != nil {
+		what = fmt.Sprintf("%s in %s %s", v.Name, v.Package.Name, v.Package.Version)
+	}
+	var b strings.Builder
+	switch n.Reason {
+	case notifier.Removed:
+		fmt.Fprintf(&b, "Clair no longer reports %s in manifest %s.", what, n.Manifest)
+	default:
+		fmt.Fprintf(&b, "Clair reported %s as %s in manifest %s again.", what, n.Reason, n.Manifest)
+		if v.FixedInVersion != "" {
+			fmt.Fprintf(&b, "\nFixed in: %s", v.FixedInVersion)
+		}
+	}
+	fmt.Fprintf(&b, "\nNotification: %s", n.ID)
+	body := map[string]string{"body": b.String()}
+	return d.do(ctx, http.MethodPost, "rest/api/2/issue/"+url.PathEscape(key)+"/comment", body, http.StatusCreated, nil)
+}
+
+// Description returns the description of a new issue, in Jira's wiki markup.
+func description(n *notifier.Notification) string {
+	v := &n.Vulnerability
+	var b strings.Builder
+	fmt.Fprintf(&b, "Clair found *%s* in manifest {{%s}}.\n\n", v.Name, n.Manifest)
+	fmt.Fprintf(&b, "* Severity: %s\n", v.Severity)
+	if v.Package != nil {
+		fmt.Fprintf(&b, "* Package: %s %s\n", v.Package.Name, v.Package.Version)
+	}
+	switch {
+	case v.Distribution != nil:
+		fmt.Fprintf(&b, "* Distribution: %s %s\n", v.Distribution.Name, v.Distribution.Version)
+	case v.Repo != nil:
+		fmt.Fprintf(&b, "* Repository: %s\n", v.Repo.Name)
+	}
+	if v.FixedInVersion != "" {
+		fmt.Fprintf(&b, "* Fixed in: %s\n", v.FixedInVersion)
+	}
+	for _, l := range strings.Fields(v.Links) {
+		fmt.Fprintf(&b, "* %s\n", l)
+	}
+	if v.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", v.Description)
+	}
+	fmt.Fprintf(&b, "\nNotification: %s\n", n.ID)
+	return b.String()
+}
+
+// Replacer returns a Replacer for the placeholders allowed in configured
+// fields.
+func replacer(n *notifier.Notification) *strings.Replacer {
+	v := &n.Vulnerability
+	var pkg, ver string
+	if v.Package != nil {
+		pkg, ver = v.Package.Name, v.Package.Version
+	}
+	return strings.NewReplacer(
+		"{vulnerability}", v.Name,
+		"{severity}", v.Severity,
+		"{package}", pkg,
+		"{version}", ver,
+		"{fixed_in}", v.FixedInVersion,
+		"{manifest}", n.Manifest.String(),
+		"{reason}", string(n.Reason),
+	)
+}
+
+// Expand replaces placeholders in every string in the value.
+func expand(r *strings.Replacer, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.Replace(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = expand(r, e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = expand(r, e)
+		}
+		return out
+	}
+	return v
+}
+
+// Do makes a request to the Jira API, relative to the configured URL.
+func (d *Deliverer) do(ctx context.Context, method, p string, in interface{}, want int, out interface{}) error {
+	u, err := d.conf.url.Parse(strings.TrimSuffix(d.conf.url.Path, "/") + "/" + p)
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("accept", "application/json")
+	if in != nil {
+		req.Header.Set("content-type", "application/json")
+	}
+	if d.conf.User != "" {
+		req.SetBasicAuth(d.conf.User, d.conf.Token)
+	} else {
+		req.Header.Set("authorization", "Bearer "+d.conf.Token)
+	}
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != want {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected response from jira: %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
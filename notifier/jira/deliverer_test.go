@@ -0,0 +1,251 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// FakeJira is a minimal Jira REST API, supporting label searches, issue
+// creation, and comments.
+type fakeJira struct {
+	sync.Mutex
+	issues   []issue
+	comments map[string][]string
+	fail     bool
+}
+
+type issue struct {
+	Key    string
+	Fields map[string]interface{}
+}
+
+var labelQuery = regexp.MustCompile(`labels = "([^"]+)"`)
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if u, p, ok := r.BasicAuth(); !ok || u != "clair@example.com" || p != "t0k3n" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if f.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/search":
+		m := labelQuery.FindStringSubmatch(r.URL.Query().Get("jql"))
+		type key struct {
+			Key string `json:"key"`
+		}
+		res := struct {
+			Issues []key `json:"issues"`
+		}{Issues: []key{}}
+		for i := len(f.issues) - 1; i >= 0 && m != nil; i-- {
+			for _, l := range f.issues[i].Fields["labels"].([]interface{}) {
+				if l == m[1] {
+					res.Issues = append(res.Issues, key{f.issues[i].Key})
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(&res)
+	case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+		var req struct {
+			Fields map[string]interface{} `json:"fields"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		k := fmt.Sprintf("SEC-%d", len(f.issues)+1)
+		f.issues = append(f.issues, issue{Key: k, Fields: req.Fields})
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":"%d","key":%q}`, len(f.issues), k)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comment"):
+		k := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/rest/api/2/issue/"), "/comment")
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if f.comments == nil {
+			f.comments = make(map[string][]string)
+		}
+		f.comments[k] = append(f.comments[k], req.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func notification(name string, sev claircore.Severity, reason notifier.Reason, pkg string) notifier.Notification {
+	return notifier.Notification{
+		ID:       uuid.New(),
+		Manifest: claircore.MustParseDigest("sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"),
+		Reason:   reason,
+		Vulnerability: notifier.VulnSummary{
+			Name:     name,
+			Severity: sev.String(),
+			Package:  &claircore.Package{Name: pkg, Version: "1.1.1"},
+			Distribution: &claircore.Distribution{
+				Name: "Ubuntu",
+			},
+			Links: "https://example.com/" + name,
+		},
+	}
+}
+
+func testConfig(u string) Config {
+	return Config{
+		URL:        u,
+		User:       "clair@example.com",
+		Token:      "t0k3n",
+		Project:    "SEC",
+		Labels:     []string{"clair"},
+		Priorities: map[string]string{"Critical": "Highest"},
+		Fields: map[string]interface{}{
+			"components":        []interface{}{map[string]interface{}{"name": "{package}"}},
+			"customfield_10010": "{vulnerability}",
+		},
+	}
+}
+
+func deliver(ctx context.Context, t *testing.T, d *Deliverer, ns ...notifier.Notification) error {
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	return d.Deliver(ctx, uuid.New())
+}
+
+func TestDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := &fakeJira{}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	d, err := New(testConfig(srv.URL), srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = deliver(ctx, t, d,
+		notification("CVE-2021-0001", claircore.Critical, notifier.Added, "openssl"),
+		// Same vulnerability and manifest, so it's added to the same issue.
+		notification("CVE-2021-0001", claircore.Critical, notifier.Added, "libssl"),
+		notification("CVE-2021-0002", claircore.High, notifier.Added, "openssl"),
+		// Below the threshold.
+		notification("CVE-2021-0003", claircore.Medium, notifier.Added, "openssl"),
+		// No issue to update.
+		notification("CVE-2021-0004", claircore.High, notifier.Removed, "openssl"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Lock()
+	if got, want := len(f.issues), 2; got != want {
+		t.Fatalf("got: %d issues, want: %d", got, want)
+	}
+	is := f.issues[0]
+	if got, want := is.Fields["priority"], map[string]interface{}{"name": "Highest"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("priority: got: %v, want: %v", got, want)
+	}
+	if got, want := is.Fields["customfield_10010"], "CVE-2021-0001"; got != want {
+		t.Errorf("custom field: got: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint(is.Fields["components"]), "[map[name:openssl]]"; got != want {
+		t.Errorf("components: got: %v, want: %v", got, want)
+	}
+	if got, want := len(is.Fields["labels"].([]interface{})), 2; got != want {
+		t.Errorf("labels: got: %d, want: %d", got, want)
+	}
+	if _, ok := f.issues[1].Fields["priority"]; ok {
+		t.Error("unexpected priority on unmapped severity")
+	}
+	if got, want := len(f.comments["SEC-1"]), 1; got != want {
+		t.Errorf("SEC-1: got: %d comments, want: %d", got, want)
+	}
+	f.Unlock()
+
+	// A later notification for the same vulnerability and manifest comments
+	// instead of opening another issue.
+	err = deliver(ctx, t, d,
+		notification("CVE-2021-0002", claircore.High, notifier.Changed, "openssl"),
+		notification("CVE-2021-0001", claircore.Critical, notifier.Removed, "openssl"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Lock()
+	defer f.Unlock()
+	if got, want := len(f.issues), 2; got != want {
+		t.Errorf("got: %d issues, want: %d", got, want)
+	}
+	if got, want := len(f.comments["SEC-1"]), 2; got != want {
+		t.Errorf("SEC-1: got: %d comments, want: %d", got, want)
+	}
+	if got, want := len(f.comments["SEC-2"]), 1; got != want {
+		t.Errorf("SEC-2: got: %d comments, want: %d", got, want)
+	}
+	if c := f.comments["SEC-1"]; !strings.Contains(c[len(c)-1], "no longer") {
+		t.Errorf("unexpected comment: %q", c[len(c)-1])
+	}
+}
+
+func TestDelivererFailure(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := &fakeJira{fail: true}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	d, err := New(testConfig(srv.URL), srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deliver(ctx, t, d, notification("CVE-2021-0001", claircore.Critical, notifier.Added, "openssl")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	ok := Config{URL: "https://example.atlassian.net", Token: "t0k3n", Project: "SEC"}
+	tt := []struct {
+		name   string
+		modify func(*Config)
+		ok     bool
+	}{
+		{"Defaults", func(*Config) {}, true},
+		{"NoURL", func(c *Config) { c.URL = "" }, false},
+		{"NoToken", func(c *Config) { c.Token = "" }, false},
+		{"NoProject", func(c *Config) { c.Project = "" }, false},
+		{"BadMinSeverity", func(c *Config) { c.MinSeverity = "Severe" }, false},
+		{"BadPriority", func(c *Config) { c.Priorities = map[string]string{"Loud": "Highest"} }, false},
+		{"ReservedField", func(c *Config) { c.Fields = map[string]interface{}{"summary": "x"} }, false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := ok
+			tc.modify(&c)
+			conf, err := c.Validate()
+			if got := err == nil; got != tc.ok {
+				t.Errorf("got: %v, want: %v (%v)", got, tc.ok, err)
+			}
+			if err == nil && conf.IssueType != DefaultIssueType {
+				t.Errorf("issue type: got: %q, want: %q", conf.IssueType, DefaultIssueType)
+			}
+		})
+	}
+}
@@ -182,7 +182,7 @@ func (m *Manager) bump(ctx context.Context) error {
 
 	err := m.store.BumpExpiration(ctx, kp.ID, delta)
 	switch {
-	case errors.As(err, &clairerror.ErrKeyNotFound{}):
+	case errors.Is(err, clairerror.NotFound):
 		newKP, err := m.genKeyPair(ctx)
 		if err != nil {
 			return err
@@ -22,7 +22,7 @@ type KeyStore interface {
 	// Keys returns all stored public keys.
 	Keys(ctx context.Context) ([]Key, error)
 	// KeyByID returns a public key if exists.
-	// Returns clairerror.ErrKeyNotFound if key does not exist.
+	// Returns a clairerror.NotFound error if key does not exist.
 	KeyByID(ctx context.Context, ID uuid.UUID) (Key, error)
 	// PutKey persists a public key with an initial expiration of n + current time.
 	//
@@ -30,7 +30,7 @@ type KeyStore interface {
 	PutKey(ctx context.Context, ID uuid.UUID, key *rsa.PublicKey, n time.Duration) error
 	// DeleteKey removes a public key from the keystore.
 	//
-	// Returns clairerror.ErrKeyNotFound if key does not exist.
+	// Returns a clairerror.NotFound error if key does not exist.
 	DeleteKey(ctx context.Context, ID uuid.UUID) error
 	// BumpExpiration sets the public key's expiration to n +
 	// current time.
@@ -0,0 +1,101 @@
+package notifier
+
+import (
+	"context"
+	"sort"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/baseimage"
+)
+
+// AttributeLayers sets the Layers of the notifications to the layers of
+// their manifest that introduced a package with the vulnerable package's
+// name, and, if the indexer detected a base image for the manifest, their
+// Origin.
+//
+// Each manifest's index report and base image are retrieved once. Manifests
+// that are no longer indexed are left unattributed.
+func (p *Processor) attributeLayers(ctx context.Context, ns []Notification) error {
+	reports := make(map[string]*claircore.IndexReport)
+	bases := make(map[string]*baseimage.Base)
+	det, detOK := baseDetector(p.indexer)
+	for i := range ns {
+		n := &ns[i]
+		if n.Vulnerability.Package == nil {
+			continue
+		}
+		k := n.Manifest.String()
+		ir, ok := reports[k]
+		if !ok {
+			var err error
+			ir, _, err = p.indexer.IndexReport(ctx, n.Manifest)
+			if err != nil {
+				return err
+			}
+			reports[k] = ir
+		}
+		if ir == nil {
+			continue
+		}
+		n.Layers = introducedIn(ir, n.Vulnerability.Package.Name)
+		if !detOK || len(n.Layers) == 0 {
+			continue
+		}
+		b, ok := bases[k]
+		if !ok {
+			var err error
+			b, err = det.Base(ctx, n.Manifest)
+			if err != nil {
+				return err
+			}
+			bases[k] = b
+		}
+		if b == nil {
+			continue
+		}
+		n.Origin = baseimage.OriginApplication
+		if b.Inherited(n.Layers) {
+			n.Origin = baseimage.OriginBase
+		}
+	}
+	return nil
+}
+
+// BaseDetector finds a baseimage.Detector among the wrapped indexers, if
+// there is one.
+func baseDetector(s indexer.Service) (baseimage.Detector, bool) {
+	var d baseimage.Detector
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		d, ok = s.(baseimage.Detector)
+		return ok
+	})
+	return d, ok
+}
+
+// IntroducedIn returns the layers that introduced packages with the name,
+// ordered by digest.
+func introducedIn(ir *claircore.IndexReport, name string) []claircore.Digest {
+	seen := make(map[string]bool)
+	var out []claircore.Digest
+	for id, p := range ir.Packages {
+		if p == nil || p.Name != name {
+			continue
+		}
+		for _, e := range ir.Environments[id] {
+			if e == nil {
+				continue
+			}
+			k := e.IntroducedIn.String()
+			if k == "" || seen[k] {
+				continue
+			}
+			seen[k] = true
+			out = append(out, e.IntroducedIn)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].String() < out[j].String() })
+	return out
+}
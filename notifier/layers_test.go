@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/baseimage"
+)
+
+func TestIntroducedIn(t *testing.T) {
+	base := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	app := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	ir := &claircore.IndexReport{
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl"},
+			"2": {ID: "2", Name: "openssl"},
+			"3": {ID: "3", Name: "zlib"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{IntroducedIn: app}},
+			"2": {{IntroducedIn: base}, {IntroducedIn: app}},
+			"3": {{IntroducedIn: base}},
+		},
+	}
+	got := introducedIn(ir, "openssl")
+	if len(got) != 2 || got[0].String() != base.String() || got[1].String() != app.String() {
+		t.Errorf("got: %v", got)
+	}
+	if got := introducedIn(ir, "curl"); len(got) != 0 {
+		t.Errorf("got: %v, want none", got)
+	}
+}
+
+// BaseMock is an indexer.Mock that detects the same base image for every
+// manifest.
+type baseMock struct {
+	*indexer.Mock
+	base *baseimage.Base
+}
+
+func (m *baseMock) Base(_ context.Context, _ claircore.Digest) (*baseimage.Base, error) {
+	return m.base, nil
+}
+
+func TestAttributeOrigin(t *testing.T) {
+	base := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	app := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	ir := &claircore.IndexReport{
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl"},
+			"2": {ID: "2", Name: "zlib"},
+		},
+		Environments: map[string][]*claircore.Environment{
+			"1": {{IntroducedIn: app}},
+			"2": {{IntroducedIn: base}},
+		},
+	}
+	m := &baseMock{
+		Mock: &indexer.Mock{
+			IndexReport_: func(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error) {
+				return ir, true, nil
+			},
+		},
+		base: &baseimage.Base{Layers: []claircore.Digest{base}},
+	}
+	ns := []Notification{
+		{Vulnerability: VulnSummary{Package: &claircore.Package{Name: "openssl"}}},
+		{Vulnerability: VulnSummary{Package: &claircore.Package{Name: "zlib"}}},
+	}
+	p := &Processor{indexer: m}
+	if err := p.attributeLayers(context.Background(), ns); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ns[0].Origin, baseimage.OriginApplication; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := ns[1].Origin, baseimage.OriginBase; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	// Without a base image, there's no origin to report.
+	m.base = nil
+	ns[0].Origin, ns[1].Origin = "", ""
+	if err := p.attributeLayers(context.Background(), ns); err != nil {
+		t.Fatal(err)
+	}
+	if ns[0].Origin != "" || ns[1].Origin != "" {
+		t.Errorf("got: %q, %q, want none", ns[0].Origin, ns[1].Origin)
+	}
+}
@@ -0,0 +1,22 @@
+package migrations
+
+const (
+	// migration2 records delivery attempts, so operators can tell whether
+	// and when a notification was delivered
+	migration2 = `
+	--- a relation recording every attempt a deliverer made at delivering
+	--- a notification id
+	CREATE TABLE IF NOT EXISTS delivery_attempt
+	(
+		id              bigserial PRIMARY KEY,
+		notification_id uuid NOT NULL REFERENCES notification ON DELETE CASCADE,
+		deliverer       text NOT NULL,
+		target          text NOT NULL DEFAULT '',
+		ts              timestamptz NOT NULL,
+		status          text NOT NULL,
+		response_code   integer NOT NULL DEFAULT 0,
+		error           text NOT NULL DEFAULT ''
+	);
+	CREATE INDEX delivery_attempt_idx ON delivery_attempt (notification_id, ts);
+	`
+)
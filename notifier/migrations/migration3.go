@@ -0,0 +1,14 @@
+package migrations
+
+const (
+	// migration3 orders receipts by creation, so clients streaming
+	// notifications can resume where they left off
+	migration3 = `
+	--- seq is the receipt's position in creation order. created is set when
+	--- the row is written rather than when the transaction started, so
+	--- readers can hold back rows whose neighbours may not have committed yet.
+	ALTER TABLE receipt ADD COLUMN IF NOT EXISTS seq bigserial;
+	ALTER TABLE receipt ADD COLUMN IF NOT EXISTS created timestamptz NOT NULL DEFAULT clock_timestamp();
+	CREATE UNIQUE INDEX IF NOT EXISTS receipt_seq_idx ON receipt (seq);
+	`
+)
@@ -0,0 +1,14 @@
+package migrations
+
+const (
+	// migration4 keeps the position of the last index event relayed, so the
+	// relay resumes where it left off
+	migration4 = `
+	--- a single row holding the sequence number of the last index event
+	--- the notifier relayed
+	CREATE TABLE IF NOT EXISTS index_event_cursor (
+		id  int PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+		seq bigint NOT NULL
+	);
+	`
+)
@@ -0,0 +1,14 @@
+package migrations
+
+const (
+	// migration5 adds leases to receipts, so notifier replicas claim
+	// disjoint sets of notification ids to deliver
+	migration5 = `
+	--- the notifier instance delivering the notification id, and when its
+	--- claim lapses if it goes away without finishing
+	ALTER TABLE receipt ADD COLUMN IF NOT EXISTS claimed_by text;
+	ALTER TABLE receipt ADD COLUMN IF NOT EXISTS claimed_until timestamptz;
+	CREATE INDEX IF NOT EXISTS receipt_deliverable_idx ON receipt (seq)
+		WHERE status IN ('created', 'delivery_failed');
+	`
+)
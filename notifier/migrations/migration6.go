@@ -0,0 +1,39 @@
+package migrations
+
+const (
+	// migration6 partitions notification bodies by the day they were
+	// created, so old ones are removed by dropping a partition instead of
+	// deleting rows
+	migration6 = `
+	--- existing bodies are kept in a partition of their own, as if created
+	--- before any other. their indexes are renamed out of the way.
+	ALTER TABLE notification_body RENAME TO notification_body_legacy;
+	ALTER INDEX notification_body_pkey RENAME TO notification_body_legacy_pkey;
+	ALTER INDEX notification_body_idx RENAME TO notification_body_legacy_idx;
+	ALTER TABLE notification_body_legacy ADD COLUMN created timestamptz NOT NULL DEFAULT '-infinity';
+
+	--- notification bodies, partitioned by UTC day. partitions are named
+	--- "notification_body_YYYYMMDD" and created ahead of time by the
+	--- notifier; rows without one land in the default partition.
+	CREATE TABLE notification_body
+	(
+		id              uuid NOT NULL,
+		notification_id uuid REFERENCES notification,
+		body            jsonb NOT NULL,
+		created         timestamptz NOT NULL DEFAULT clock_timestamp(),
+		PRIMARY KEY (id, created)
+	) PARTITION BY RANGE (created);
+	CREATE INDEX notification_body_idx ON notification_body (notification_id, id);
+	CREATE TABLE notification_body_default PARTITION OF notification_body DEFAULT;
+
+	DO $$
+	DECLARE
+		today timestamptz := date_trunc('day', now() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+	BEGIN
+		EXECUTE format('ALTER TABLE notification_body ATTACH PARTITION notification_body_legacy FOR VALUES FROM (MINVALUE) TO (%L)', today);
+		EXECUTE format('CREATE TABLE %I PARTITION OF notification_body FOR VALUES FROM (%L) TO (%L)',
+			'notification_body_' || to_char(today AT TIME ZONE 'UTC', 'YYYYMMDD'), today, today + interval '1 day');
+	END
+	$$;
+	`
+)
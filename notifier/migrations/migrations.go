@@ -18,4 +18,39 @@ var Migrations = []migrate.Migration{
 			return err
 		},
 	},
+	{
+		ID: 2,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration2)
+			return err
+		},
+	},
+	{
+		ID: 3,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration3)
+			return err
+		},
+	},
+	{
+		ID: 4,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration4)
+			return err
+		},
+	},
+	{
+		ID: 5,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration5)
+			return err
+		},
+	},
+	{
+		ID: 6,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration6)
+			return err
+		},
+	},
 }
@@ -26,7 +26,7 @@ func (m *MockKeyStore) Keys(ctx context.Context) ([]Key, error) {
 }
 
 // KeyByID returns a public key if exists.
-// Returns clairerror.ErrKeyNotFound if key does not exist.
+// Returns a clairerror.NotFound error if key does not exist.
 func (m *MockKeyStore) KeyByID(ctx context.Context, ID uuid.UUID) (Key, error) {
 	return m.KeyByID_(ctx, ID)
 }
@@ -41,7 +41,7 @@ func (m *MockKeyStore) PutKey(ctx context.Context, ID uuid.UUID, key *rsa.Public
 
 // DeleteKey removes a public key from the keystore.
 //
-// Returns clairerror.ErrKeyNotFound if key does not exist.
+// Returns a clairerror.NotFound error if key does not exist.
 func (m *MockKeyStore) DeleteKey(ctx context.Context, ID uuid.UUID) error {
 	return m.DeleteKey(ctx, ID)
 }
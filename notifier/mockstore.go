@@ -2,24 +2,36 @@ package notifier
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 // MockStore implements a mock Store.
 type MockStore struct {
-	Notifications_        func(ctx context.Context, id uuid.UUID, page *Page) ([]Notification, Page, error)
-	PutNotifications_     func(ctx context.Context, opts PutOpts) error
-	PutReceipt_           func(ctx context.Context, updater string, r Receipt) error
-	DeleteNotitfications_ func(ctx context.Context, id uuid.UUID) error
-	Receipt_              func(ctx context.Context, id uuid.UUID) (Receipt, error)
-	ReceiptByUOID_        func(ctx context.Context, id uuid.UUID) (Receipt, error)
-	Created_              func(ctx context.Context) ([]uuid.UUID, error)
-	Failed_               func(ctx context.Context) ([]uuid.UUID, error)
-	Deleted_              func(ctx context.Context) ([]uuid.UUID, error)
-	SetDelivered_         func(ctx context.Context, id uuid.UUID) error
-	SetDeliveredFailed_   func(ctx context.Context, id uuid.UUID) error
-	SetDeleted_           func(ctx context.Context, id uuid.UUID) error
+	Notifications_         func(ctx context.Context, id uuid.UUID, page *Page) ([]Notification, Page, error)
+	PutNotifications_      func(ctx context.Context, opts PutOpts) error
+	PutReceipt_            func(ctx context.Context, updater string, r Receipt) error
+	DeleteNotitfications_  func(ctx context.Context, id uuid.UUID) error
+	CoalesceNotifications_ func(ctx context.Context, opts CoalesceOpts) error
+	Receipt_               func(ctx context.Context, id uuid.UUID) (Receipt, error)
+	ReceiptByUOID_         func(ctx context.Context, id uuid.UUID) (Receipt, error)
+	Created_               func(ctx context.Context) ([]uuid.UUID, error)
+	Failed_                func(ctx context.Context) ([]uuid.UUID, error)
+	Deleted_               func(ctx context.Context) ([]uuid.UUID, error)
+	SetDelivered_          func(ctx context.Context, id uuid.UUID) error
+	SetDeliveredFailed_    func(ctx context.Context, id uuid.UUID) error
+	SetDeleted_            func(ctx context.Context, id uuid.UUID) error
+	FailedReceipts_        func(ctx context.Context) ([]Receipt, error)
+	SetCreated_            func(ctx context.Context, ids ...uuid.UUID) (int64, error)
+	Claim_                 func(ctx context.Context, owner string, lease time.Duration, ids []uuid.UUID, limit int) ([]uuid.UUID, error)
+	Release_               func(ctx context.Context, owner string, id uuid.UUID) error
+	ReceiptsAfter_         func(ctx context.Context, seq int64, limit int) ([]Receipt, error)
+	Backlog_               func(ctx context.Context) (Backlog, error)
+	PruneNotifications_    func(ctx context.Context, before time.Time, limit int) (int64, error)
+	PurgeDelivered_        func(ctx context.Context, uoid uuid.UUID) (int64, error)
+	PutAttempt_            func(ctx context.Context, a Attempt) error
+	Attempts_              func(ctx context.Context, id uuid.UUID) ([]Attempt, error)
 }
 
 // Notifications retrieves the list of notifications associated with a
@@ -61,6 +73,12 @@ func (m *MockStore) DeleteNotifications(ctx context.Context, id uuid.UUID) error
 	return m.DeleteNotitfications_(ctx, id)
 }
 
+// CoalesceNotifications persists the provided notifications under a new
+// notification id and sets the ones they replace to deleted status.
+func (m *MockStore) CoalesceNotifications(ctx context.Context, opts CoalesceOpts) error {
+	return m.CoalesceNotifications_(ctx, opts)
+}
+
 // Receipt returns the Receipt for a given notification id
 func (m *MockStore) Receipt(ctx context.Context, id uuid.UUID) (Receipt, error) {
 	return m.Receipt_(ctx, id)
@@ -100,3 +118,53 @@ func (m *MockStore) SetDeliveryFailed(ctx context.Context, id uuid.UUID) error {
 func (m *MockStore) SetDeleted(ctx context.Context, id uuid.UUID) error {
 	return m.SetDeleted_(ctx, id)
 }
+
+// FailedReceipts returns the receipts in delivery failed status
+func (m *MockStore) FailedReceipts(ctx context.Context) ([]Receipt, error) {
+	return m.FailedReceipts_(ctx)
+}
+
+// SetCreated returns the provided notification ids to created status
+func (m *MockStore) SetCreated(ctx context.Context, ids ...uuid.UUID) (int64, error) {
+	return m.SetCreated_(ctx, ids...)
+}
+
+// Claim leases up to limit of the provided notification ids to owner.
+func (m *MockStore) Claim(ctx context.Context, owner string, lease time.Duration, ids []uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return m.Claim_(ctx, owner, lease, ids, limit)
+}
+
+// Release ends owner's lease on the notification id.
+func (m *MockStore) Release(ctx context.Context, owner string, id uuid.UUID) error {
+	return m.Release_(ctx, owner, id)
+}
+
+// ReceiptsAfter returns receipts created after the provided sequence number
+func (m *MockStore) ReceiptsAfter(ctx context.Context, seq int64, limit int) ([]Receipt, error) {
+	return m.ReceiptsAfter_(ctx, seq, limit)
+}
+
+// Backlog counts the notification ids waiting on delivery
+func (m *MockStore) Backlog(ctx context.Context) (Backlog, error) {
+	return m.Backlog_(ctx)
+}
+
+// PruneNotifications removes notification ids created before the provided time
+func (m *MockStore) PruneNotifications(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return m.PruneNotifications_(ctx, before, limit)
+}
+
+// PurgeDelivered removes delivered notifications for the provided update operation
+func (m *MockStore) PurgeDelivered(ctx context.Context, uoid uuid.UUID) (int64, error) {
+	return m.PurgeDelivered_(ctx, uoid)
+}
+
+// PutAttempt records a delivery attempt
+func (m *MockStore) PutAttempt(ctx context.Context, a Attempt) error {
+	return m.PutAttempt_(ctx, a)
+}
+
+// Attempts returns the delivery attempts for the provided notification id
+func (m *MockStore) Attempts(ctx context.Context, id uuid.UUID) ([]Attempt, error) {
+	return m.Attempts_(ctx, id)
+}
@@ -0,0 +1,118 @@
+package nats
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// TLS configures TLS connections to the NATS servers.
+type TLS struct {
+	// The filesystem path where a root CA can be read.
+	RootCA string `yaml:"root_ca"`
+	// The filesystem path where a tls certificate can be read.
+	//
+	// Optional; if provided Key must be provided as well.
+	Cert string `yaml:"cert"`
+	// The filesystem path where a tls private key can be read.
+	Key string `yaml:"key"`
+}
+
+// Config provides configuration for a NATS deliverer.
+type Config struct {
+	// Configures the NATS delivery to deliver notifications directly to
+	// the configured Subject.
+	//
+	// If true "Callback" is ignored.
+	// If false a notifier.Callback is delivered to the subject and clients
+	// utilize the pagination API to retrieve.
+	Direct bool `yaml:"direct"`
+	// Specifies the number of notifications delivered in single NATS message
+	// when Direct is true.
+	//
+	// Ignored if Direct is not true
+	// If 0 or 1 is provided no rollup occurs and each notification is delivered
+	// separately.
+	Rollup int `yaml:"rollup"`
+	// The callback url where notifications are retrieved.
+	Callback string `yaml:"callback"`
+	callback url.URL
+	// The subject messages will be published to.
+	Subject string `yaml:"subject"`
+	// A list of NATS server URLs to connect to.
+	URIs []string `yaml:"uris"`
+	// Publish to a JetStream stream bound to Subject and wait for the
+	// stream to acknowledge each message.
+	//
+	// If false, messages are published with core NATS and are only
+	// delivered to subscribers connected at the time.
+	JetStream bool `yaml:"jetstream"`
+	// The filesystem path where a NATS credentials file (containing a
+	// user JWT and NKey seed) can be read.
+	CredentialsFile string `yaml:"credentials_file"`
+	// optional tls portion of config
+	TLS *TLS `yaml:"tls"`
+	tls *tls.Config
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+}
+
+// Validate confirms configuration is valid and fills in private members
+// with parsed values on success.
+func (c *Config) Validate() (Config, error) {
+	conf := *c
+	if c.Subject == "" {
+		return conf, fmt.Errorf("nats config requires the subject field")
+	}
+	if len(c.URIs) == 0 {
+		return conf, fmt.Errorf("nats config requires at least one uri")
+	}
+
+	if !c.Direct {
+		callback, err := url.Parse(c.Callback)
+		if err != nil {
+			return conf, fmt.Errorf("failed to parse callback url")
+		}
+		conf.callback = *callback
+	}
+
+	if c.TLS != nil {
+		if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+			return conf, fmt.Errorf("both tls cert and key are required")
+		}
+		TLS := tls.Config{}
+		if pool, err := x509.SystemCertPool(); err != nil {
+			TLS.RootCAs = x509.NewCertPool()
+		} else {
+			TLS.RootCAs = pool
+		}
+		if c.TLS.RootCA != "" {
+			ca, err := ioutil.ReadFile(c.TLS.RootCA)
+			if err != nil {
+				return conf, fmt.Errorf("failed to read tls root ca: %v", err)
+			}
+			TLS.RootCAs.AppendCertsFromPEM(ca)
+		}
+		if c.TLS.Cert != "" {
+			cert, err := tls.LoadX509KeyPair(c.TLS.Cert, c.TLS.Key)
+			if err != nil {
+				return conf, fmt.Errorf("failed to read x509 cert and key pair: %v", err)
+			}
+			TLS.Certificates = append(TLS.Certificates, cert)
+		}
+		conf.tls = &TLS
+	}
+
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+	return conf, nil
+}
@@ -0,0 +1,76 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	gonats "github.com/nats-io/nats.go"
+)
+
+const (
+	// ConnectTimeout bounds the time spent dialing a NATS server.
+	connectTimeout = 10 * time.Second
+	// PublishTimeout bounds the time spent waiting for a published message to
+	// be accepted.
+	publishTimeout = 30 * time.Second
+)
+
+// Connect dials the configured servers.
+func connect(conf *Config) (*gonats.Conn, error) {
+	opts := []gonats.Option{
+		gonats.Name("clair-notifier"),
+		gonats.Timeout(connectTimeout),
+	}
+	if conf.CredentialsFile != "" {
+		opts = append(opts, gonats.UserCredentials(conf.CredentialsFile))
+	}
+	if conf.tls != nil {
+		opts = append(opts, gonats.Secure(conf.tls))
+	}
+	return gonats.Connect(strings.Join(conf.URIs, ","), opts...)
+}
+
+// PubAck is the response JetStream sends for a published message.
+type pubAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// Publish sends a message to the configured subject.
+//
+// For JetStream, this waits for the stream's acknowledgement. Otherwise, it
+// waits for the server to process the message.
+func publish(ctx context.Context, nc *gonats.Conn, conf *Config, b []byte) error {
+	// The client library insists on a deadline.
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	if !conf.JetStream {
+		if err := nc.Publish(conf.Subject, b); err != nil {
+			return err
+		}
+		return nc.FlushWithContext(ctx)
+	}
+
+	m, err := nc.RequestWithContext(ctx, conf.Subject, b)
+	if err != nil {
+		return fmt.Errorf("no acknowledgement from jetstream: %w", err)
+	}
+	var ack pubAck
+	if err := json.Unmarshal(m.Data, &ack); err != nil {
+		return fmt.Errorf("unexpected jetstream response %q: %w", m.Data, err)
+	}
+	if ack.Error != nil {
+		return fmt.Errorf("jetstream error %d: %s", ack.Error.Code, ack.Error.Description)
+	}
+	if ack.Stream == "" {
+		return fmt.Errorf("unexpected jetstream response %q", m.Data)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/google/uuid"
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer is a NATS deliverer which publishes a notifier.Callback to the
+// configured subject.
+type Deliverer struct {
+	conf Config
+}
+
+// New returns a new NATS Deliverer.
+func New(conf Config) (*Deliverer, error) {
+	c, err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &Deliverer{
+		conf: c,
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return fmt.Sprintf("nats-%s", d.conf.Subject)
+}
+
+// Deliver implements the notifier.Deliverer interface.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	nc, err := connect(&d.conf)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	defer nc.Close()
+
+	callback := d.conf.callback
+	callback.Path = path.Join(callback.Path, nID.String())
+	d.conf.Filter.SetQuery(&callback)
+
+	cb := notifier.Callback{
+		NotificationID: nID,
+		Callback:       callback,
+	}
+	b, err := json.Marshal(&cb)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	if err := publish(ctx, nc, &d.conf, b); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package nats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// FakeServer speaks just enough of the NATS protocol to accept publishes and
+// answer JetStream-style requests.
+type fakeServer struct {
+	l net.Listener
+
+	mu  sync.Mutex
+	msg []message
+}
+
+type message struct {
+	Subject string
+	Data    []byte
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeServer{l: l}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f
+}
+
+func (f *fakeServer) URI() string { return "nats://" + f.l.Addr().String() }
+
+func (f *fakeServer) Close() { f.l.Close() }
+
+func (f *fakeServer) Messages() []message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]message(nil), f.msg...)
+}
+
+func (f *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	fmt.Fprint(c, "INFO {\"server_id\":\"fake\",\"version\":\"2.2.0\",\"proto\":1,\"max_payload\":1048576}\r\n")
+	r := bufio.NewReader(c)
+	// Subscriptions, keyed by subject prefix, to their sid.
+	subs := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fs := strings.Fields(line)
+		if len(fs) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fs[0]) {
+		case "PING":
+			fmt.Fprint(c, "PONG\r\n")
+		case "SUB":
+			subs[strings.TrimSuffix(fs[1], "*")] = fs[len(fs)-1]
+		case "PUB":
+			n, _ := strconv.Atoi(fs[len(fs)-1])
+			b := make([]byte, n+2)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.msg = append(f.msg, message{Subject: fs[1], Data: b[:n]})
+			f.mu.Unlock()
+			if len(fs) != 4 {
+				continue
+			}
+			// Acknowledge like a JetStream stream would.
+			reply := fs[2]
+			for pfx, sid := range subs {
+				if strings.HasPrefix(reply, pfx) {
+					ack := `{"stream":"clair","seq":1}`
+					fmt.Fprintf(c, "MSG %s %s %d\r\n%s\r\n", reply, sid, len(ack), ack)
+				}
+			}
+		}
+	}
+}
+
+func TestDeliverer(t *testing.T) {
+	const callback = "http://clair-notifier/notifier/api/v1/notification/"
+	for _, js := range []bool{false, true} {
+		js := js
+		t.Run(fmt.Sprintf("JetStream=%v", js), func(t *testing.T) {
+			ctx := zlog.Test(context.Background(), t)
+			f := newFakeServer(t)
+			defer f.Close()
+
+			d, err := New(Config{
+				Callback:  callback,
+				Subject:   "clair.notifications",
+				URIs:      []string{f.URI()},
+				JetStream: js,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			id := uuid.New()
+			if err := d.Deliver(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+
+			ms := f.Messages()
+			if got, want := len(ms), 1; got != want {
+				t.Fatalf("got: %d messages, want: %d", got, want)
+			}
+			if got, want := ms[0].Subject, "clair.notifications"; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+			var cb notifier.Callback
+			if err := json.Unmarshal(ms[0].Data, &cb); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := cb.NotificationID, id; got != want {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+			if got, want := cb.Callback.String(), callback+id.String(); got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDirectDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := newFakeServer(t)
+	defer f.Close()
+
+	d, err := NewDirectDeliverer(Config{
+		Direct:    true,
+		Rollup:    2,
+		Subject:   "clair.notifications",
+		URIs:      []string{f.URI()},
+		JetStream: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := make([]notifier.Notification, 5)
+	for i := range ns {
+		ns[i].ID = uuid.New()
+	}
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := f.Messages()
+	if got, want := len(ms), 3; got != want {
+		t.Fatalf("got: %d messages, want: %d", got, want)
+	}
+	var total int
+	for _, m := range ms {
+		// Notifications with an empty manifest digest don't round-trip, so
+		// just count them.
+		var block []json.RawMessage
+		if err := json.Unmarshal(m.Data, &block); err != nil {
+			t.Fatal(err)
+		}
+		total += len(block)
+	}
+	if got, want := total, len(ns); got != want {
+		t.Errorf("got: %d notifications, want: %d", got, want)
+	}
+}
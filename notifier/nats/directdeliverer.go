@@ -0,0 +1,84 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// DirectDeliverer is a NATS deliverer which publishes notifications directly
+// to the configured subject.
+type DirectDeliverer struct {
+	conf Config
+	n    []notifier.Notification
+}
+
+// NewDirectDeliverer returns a new NATS DirectDeliverer.
+func NewDirectDeliverer(conf Config) (*DirectDeliverer, error) {
+	c, err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &DirectDeliverer{
+		conf: c,
+		n:    []notifier.Notification{},
+	}, nil
+}
+
+func (d *DirectDeliverer) Name() string {
+	return fmt.Sprintf("nats-direct-%s", d.conf.Subject)
+}
+
+// Notifications will copy the provided notifications into a buffer for NATS
+// delivery.
+func (d *DirectDeliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	// if we can reslice instead of allocate do so.
+	if len(n) <= len(d.n) {
+		d.n = d.n[:len(n)]
+		copy(d.n, n)
+		return nil
+	}
+	tmp := make([]notifier.Notification, len(n), len(n))
+	copy(tmp, n)
+	d.n = tmp
+	return nil
+}
+
+// Deliver implements the notifier.Deliverer interface.
+//
+// NATS has no transactions, so if publishing a block fails the blocks before
+// it will have been delivered, and will be delivered again on retry.
+func (d *DirectDeliverer) Deliver(ctx context.Context, _ uuid.UUID) error {
+	nc, err := connect(&d.conf)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	defer nc.Close()
+
+	// block loop publishing smaller blocks of max(rollup) length via reslicing.
+	var rollup int = d.conf.Rollup
+	if rollup == 0 {
+		rollup++
+	}
+
+	for bs, be := 0, rollup; bs < len(d.n); bs, be = be, be+rollup {
+		// if block-end exceeds array bounds, slice block underflow.
+		// next block-start will cause loop to exit.
+		if be > len(d.n) {
+			be = len(d.n)
+		}
+		currentBlock := d.n[bs:be]
+		b, err := json.Marshal(&currentBlock)
+		if err != nil {
+			return &clairerror.ErrDeliveryFailed{E: err}
+		}
+		if err := publish(ctx, nc, &d.conf, b); err != nil {
+			return &clairerror.ErrDeliveryFailed{E: err}
+		}
+	}
+	return nil
+}
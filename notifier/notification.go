@@ -30,4 +30,19 @@ type Notification struct {
 	Manifest      claircore.Digest `json:"manifest"`
 	Reason        Reason           `json:"reason"`
 	Vulnerability VulnSummary      `json:"vulnerability"`
+	// Change describes how the vulnerability's effect on the manifest
+	// differs from the previous update operation. It's not present for
+	// notifications with the "removed" reason.
+	Change *Change `json:"change,omitempty"`
+	// Layers are the layers of the manifest that introduced the affected
+	// package, when the notifier is configured to attribute them.
+	Layers []claircore.Digest `json:"layers,omitempty"`
+	// Origin is "base" if those layers all came with the manifest's base
+	// image, or "application" if not, when the notifier is configured to
+	// attribute layers and the indexer detected a base image for the
+	// manifest.
+	Origin string `json:"origin,omitempty"`
+	// Annotations are the key/value pairs clients attached to the manifest,
+	// when the notifier is configured to include them.
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
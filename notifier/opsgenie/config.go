@@ -0,0 +1,117 @@
+package opsgenie
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+const (
+	// DefaultEndpoint is the Alert API endpoint used if one is not
+	// configured.
+	DefaultEndpoint = "https://api.opsgenie.com/v2/alerts"
+	// DefaultMinSeverity is the lowest Clair severity that creates an alert
+	// if one is not configured.
+	DefaultMinSeverity = "High"
+	// DefaultSource is the alert source reported if one is not configured.
+	DefaultSource = "clair"
+)
+
+// DefaultPriorities maps Clair severities to OpsGenie priorities.
+var DefaultPriorities = map[string]string{
+	claircore.Unknown.String():    "P5",
+	claircore.Negligible.String(): "P5",
+	claircore.Low.String():        "P4",
+	claircore.Medium.String():     "P3",
+	claircore.High.String():       "P2",
+	claircore.Critical.String():   "P1",
+}
+
+// Config provides configuration for an OpsGenie deliverer.
+type Config struct {
+	// An API key of an OpsGenie "API" integration.
+	APIKey string `yaml:"api_key" secret:"true"`
+	// The Alert API endpoint. Accounts in the EU region should use
+	// "https://api.eu.opsgenie.com/v2/alerts".
+	Endpoint string `yaml:"endpoint"`
+	endpoint *url.URL
+	// The lowest Clair severity that creates an alert, e.g. "High".
+	//
+	// Must be one of the claircore severities: "Unknown", "Negligible",
+	// "Low", "Medium", "High", or "Critical".
+	MinSeverity string `yaml:"min_severity"`
+	minSeverity claircore.Severity
+	// Overrides for the mapping of Clair severities to OpsGenie priorities.
+	// OpsGenie priorities are "P1" through "P5".
+	Priorities map[string]string `yaml:"priorities"`
+	priorities map[string]string
+	// The alert source, e.g. the Clair instance's hostname.
+	Source string `yaml:"source"`
+	// Tags added to every alert.
+	Tags []string `yaml:"tags"`
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+}
+
+// Validate confirms configuration is valid and fills in private members
+// with parsed values on success.
+func (c *Config) Validate() (Config, error) {
+	conf := *c
+	if c.APIKey == "" {
+		return conf, fmt.Errorf("opsgenie config requires the api_key field")
+	}
+
+	ep := c.Endpoint
+	if ep == "" {
+		ep = DefaultEndpoint
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return conf, fmt.Errorf("failed to parse endpoint url: %v", err)
+	}
+	conf.endpoint = u
+
+	min := c.MinSeverity
+	if min == "" {
+		min = DefaultMinSeverity
+	}
+	sev, ok := notifier.ParseSeverity(min)
+	if !ok {
+		return conf, fmt.Errorf("opsgenie config: unknown severity %q", min)
+	}
+	conf.minSeverity = sev
+
+	conf.priorities = make(map[string]string, len(DefaultPriorities))
+	for k, v := range DefaultPriorities {
+		conf.priorities[k] = v
+	}
+	for k, v := range c.Priorities {
+		if _, ok := notifier.ParseSeverity(k); !ok {
+			return conf, fmt.Errorf("opsgenie config: unknown severity %q", k)
+		}
+		v = strings.ToUpper(v)
+		switch v {
+		case "P1", "P2", "P3", "P4", "P5":
+		default:
+			return conf, fmt.Errorf("opsgenie config: unknown opsgenie priority %q", v)
+		}
+		conf.priorities[k] = v
+	}
+
+	if conf.Source == "" {
+		conf.Source = DefaultSource
+	}
+
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+	return conf, nil
+}
@@ -0,0 +1,220 @@
+// Package opsgenie delivers notifications as OpsGenie alerts, using the Alert
+// API.
+package opsgenie
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer creates an OpsGenie alert for every notification at or above the
+// configured severity.
+//
+// Alerts are deduplicated by an alias made from the manifest and the
+// vulnerability, so repeated notifications about the same vulnerability in
+// the same manifest add to the open alert's count instead of creating a new
+// one.
+type Deliverer struct {
+	conf   Config
+	client *http.Client
+	n      []notifier.Notification
+}
+
+// New returns a new OpsGenie Deliverer.
+//
+// If client is nil, http.DefaultClient is used.
+func New(conf Config, client *http.Client) (*Deliverer, error) {
+	c, err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Deliverer{
+		conf:   c,
+		client: client,
+		n:      []notifier.Notification{},
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return "opsgenie"
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// Only notifications that should create an alert are kept.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = d.n[:0]
+	for _, n := range n {
+		if d.alerts(&n) {
+			d.n = append(d.n, n)
+		}
+	}
+	return nil
+}
+
+// Alerts reports whether the notification should create an alert.
+func (d *Deliverer) alerts(n *notifier.Notification) bool {
+	if n.Reason == notifier.Removed {
+		return false
+	}
+	// An unparsable severity is treated as Unknown.
+	sev, _ := notifier.ParseSeverity(n.Vulnerability.Severity)
+	return sev >= d.conf.minSeverity
+}
+
+// Deliver implements the notifier.Deliverer interface.
+//
+// Alerts are sent one at a time. If one fails, the ones before it will be
+// sent again on retry, which OpsGenie deduplicates by alias.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/opsgenie/Deliverer.Deliver").
+		Stringer("notification_id", nID).
+		Logger()
+	for i := range d.n {
+		if err := d.send(ctx, d.alert(&d.n[i])); err != nil {
+			return &clairerror.ErrDeliveryFailed{E: err}
+		}
+	}
+	log.Debug().Int("count", len(d.n)).Msg("created alerts")
+	return nil
+}
+
+// Alert is an Alert API create alert request.
+type alert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Priority    string            `json:"priority"`
+}
+
+// Limits on the lengths of alert fields OpsGenie accepts, in characters.
+const (
+	maxMessage     = 130
+	maxAlias       = 512
+	maxDescription = 15000
+)
+
+func (d *Deliverer) alert(n *notifier.Notification) *alert {
+	v := &n.Vulnerability
+	msg := fmt.Sprintf("%s %s", v.Severity, v.Name)
+	if v.Package != nil {
+		msg = fmt.Sprintf("%s %s in %s %s", v.Severity, v.Name, v.Package.Name, v.Package.Version)
+	}
+	prio, ok := d.conf.priorities[v.Severity]
+	if !ok {
+		prio = "P5"
+	}
+
+	desc := v.Description
+	if v.Links != "" {
+		desc += "\n\n" + strings.Join(strings.Fields(v.Links), "\n")
+	}
+	details := map[string]string{
+		"notification_id": n.ID.String(),
+		"manifest":        n.Manifest.String(),
+		"reason":          string(n.Reason),
+		"vulnerability":   v.Name,
+		"severity":        v.Severity,
+	}
+	if v.Package != nil {
+		details["package"] = v.Package.Name
+		details["version"] = v.Package.Version
+	}
+	if v.FixedInVersion != "" {
+		details["fixed_in_version"] = v.FixedInVersion
+	}
+	switch {
+	case v.Distribution != nil:
+		details["distribution"] = v.Distribution.Name
+	case v.Repo != nil:
+		details["repository"] = v.Repo.Name
+	}
+
+	return &alert{
+		Message:     truncate(msg, maxMessage),
+		Alias:       alias(n),
+		Description: truncate(strings.TrimSpace(desc), maxDescription),
+		Tags:        d.conf.Tags,
+		Details:     details,
+		Entity:      n.Manifest.String(),
+		Source:      d.conf.Source,
+		Priority:    prio,
+	}
+}
+
+// Alias identifies the alert for a vulnerability in a manifest.
+//
+// The package is part of it, as a vulnerability can affect several packages
+// in a manifest and each should be fixed. Aliases too long for OpsGenie are
+// replaced with a hash of the whole alias, so that distinct alerts sharing a
+// long prefix aren't merged.
+func alias(n *notifier.Notification) string {
+	v := &n.Vulnerability
+	a := n.Manifest.String() + "/" + v.Name
+	if v.Package != nil {
+		a += "/" + v.Package.Name
+	}
+	if utf8.RuneCountInString(a) > maxAlias {
+		sum := sha256.Sum256([]byte(a))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return a
+}
+
+// Truncate returns at most the first n characters of s.
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+func (d *Deliverer) send(ctx context.Context, a *alert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, d.conf.endpoint.String(), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("authorization", "GenieKey "+d.conf.APIKey)
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected response from opsgenie: %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
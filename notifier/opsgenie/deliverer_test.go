@@ -0,0 +1,232 @@
+package opsgenie
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// fakeOpsGenie is a minimal Alert API endpoint. It deduplicates alerts by
+// alias, counting repeats, as OpsGenie does for open alerts.
+type fakeOpsGenie struct {
+	sync.Mutex
+	alerts map[string]alert
+	count  map[string]int
+	fail   bool
+}
+
+func (f *fakeOpsGenie) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if r.Header.Get("authorization") != "GenieKey key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if f.fail {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	var a alert
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if f.alerts == nil {
+		f.alerts = make(map[string]alert)
+		f.count = make(map[string]int)
+	}
+	if _, ok := f.alerts[a.Alias]; !ok {
+		f.alerts[a.Alias] = a
+	}
+	f.count[a.Alias]++
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"result":"Request will be processed","took":0.01,"requestId":"x"}`))
+}
+
+func notification(sev claircore.Severity, reason notifier.Reason) notifier.Notification {
+	return notifier.Notification{
+		ID:       uuid.New(),
+		Manifest: claircore.MustParseDigest("sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"),
+		Reason:   reason,
+		Vulnerability: notifier.VulnSummary{
+			Name:     "CVE-2021-0001",
+			Severity: sev.String(),
+			Package:  &claircore.Package{Name: "openssl", Version: "1.1.1"},
+			Distribution: &claircore.Distribution{
+				Name: "Ubuntu",
+			},
+			Links: "https://example.com/CVE-2021-0001",
+		},
+	}
+}
+
+func TestDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := &fakeOpsGenie{}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	d, err := New(Config{
+		APIKey:     "key",
+		Endpoint:   srv.URL,
+		Priorities: map[string]string{"High": "p1"},
+		Tags:       []string{"clair"},
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	high := notification(claircore.High, notifier.Added)
+	high.Vulnerability.Name = "CVE-2021-0002"
+	ns := []notifier.Notification{
+		notification(claircore.Critical, notifier.Added),
+		high,
+		notification(claircore.Medium, notifier.Added),
+		notification(claircore.Critical, notifier.Removed),
+	}
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	// A later notification about the same vulnerability in the same
+	// manifest updates the existing alert.
+	if err := d.Notifications(ctx, []notifier.Notification{notification(claircore.Critical, notifier.Changed)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	if got, want := len(f.alerts), 2; got != want {
+		t.Fatalf("got: %d alerts, want: %d", got, want)
+	}
+	crit := f.alerts[alias(&ns[0])]
+	if got, want := f.count[crit.Alias], 2; got != want {
+		t.Errorf("got: %d requests for %q, want: %d", got, crit.Alias, want)
+	}
+	if got, want := crit.Priority, "P1"; got != want {
+		t.Errorf("got priority: %q, want: %q", got, want)
+	}
+	if got, want := f.alerts[alias(&high)].Priority, "P1"; got != want {
+		t.Errorf("got priority: %q, want: %q", got, want)
+	}
+	if got, want := crit.Details["package"], "openssl"; got != want {
+		t.Errorf("got package: %q, want: %q", got, want)
+	}
+	if got, want := crit.Entity, ns[0].Manifest.String(); got != want {
+		t.Errorf("got entity: %q, want: %q", got, want)
+	}
+	if got, want := len(crit.Tags), 1; got != want {
+		t.Errorf("got: %d tags, want: %d", got, want)
+	}
+	if len(crit.Message) > maxMessage {
+		t.Errorf("message too long: %q", crit.Message)
+	}
+}
+
+func TestDelivererFailure(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := &fakeOpsGenie{fail: true}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	d, err := New(Config{APIKey: "key", Endpoint: srv.URL}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notifications(ctx, []notifier.Notification{notification(claircore.Critical, notifier.Added)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tt := []struct {
+		name string
+		conf Config
+		ok   bool
+	}{
+		{"Defaults", Config{APIKey: "key"}, true},
+		{"NoKey", Config{}, false},
+		{"BadMinSeverity", Config{APIKey: "key", MinSeverity: "Severe"}, false},
+		{"BadMapping", Config{APIKey: "key", Priorities: map[string]string{"High": "P0"}}, false},
+		{"BadClairSeverity", Config{APIKey: "key", Priorities: map[string]string{"Loud": "P1"}}, false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.conf.Validate()
+			if got := err == nil; got != tc.ok {
+				t.Errorf("got: %v, want: %v (%v)", got, tc.ok, err)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tt := []struct {
+		name string
+		in   string
+		n    int
+		want string
+	}{
+		{"Short", "openssl", 10, "openssl"},
+		{"Exact", "openssl", 7, "openssl"},
+		{"ASCII", "openssl", 4, "open"},
+		{"Multibyte", "ÿÿÿÿ", 3, "ÿÿÿ"},
+		{"Mixed", "aé日本", 3, "aé日"},
+		{"Zero", "日本", 0, ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := truncate(tc.in, tc.n)
+			if got != tc.want {
+				t.Errorf("got: %q, want: %q", got, tc.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("invalid UTF-8: %q", got)
+			}
+		})
+	}
+}
+
+// TestAlias confirms aliases too long for OpsGenie are shortened without
+// merging alerts that differ only past the limit.
+func TestAlias(t *testing.T) {
+	n := notification(claircore.High, notifier.Added)
+	if got, want := alias(&n), n.Manifest.String()+"/CVE-2021-0001/openssl"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	long := strings.Repeat("ü", maxAlias)
+	a, b := n, n
+	a.Vulnerability.Package = &claircore.Package{Name: long + "a"}
+	b.Vulnerability.Package = &claircore.Package{Name: long + "b"}
+	aa, ab := alias(&a), alias(&b)
+	if aa == ab {
+		t.Errorf("distinct alerts share alias %q", aa)
+	}
+	for _, s := range []string{aa, ab} {
+		if utf8.RuneCountInString(s) > maxAlias {
+			t.Errorf("alias too long: %q", s)
+		}
+	}
+	if got, want := alias(&a), aa; got != want {
+		t.Errorf("alias not stable: got: %q, want: %q", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package pagerduty
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+const (
+	// DefaultEndpoint is the Events API v2 endpoint used if one is not
+	// configured.
+	DefaultEndpoint = "https://events.pagerduty.com/v2/enqueue"
+	// DefaultMinSeverity is the lowest Clair severity that opens an incident
+	// if one is not configured.
+	DefaultMinSeverity = "High"
+	// DefaultSource is the event source reported if one is not configured.
+	DefaultSource = "clair"
+)
+
+// DefaultSeverities maps Clair severities to PagerDuty severities.
+var DefaultSeverities = map[string]string{
+	claircore.Unknown.String():    "info",
+	claircore.Negligible.String(): "info",
+	claircore.Low.String():        "info",
+	claircore.Medium.String():     "warning",
+	claircore.High.String():       "error",
+	claircore.Critical.String():   "critical",
+}
+
+// Config provides configuration for a PagerDuty deliverer.
+type Config struct {
+	// The integration's routing key.
+	RoutingKey string `yaml:"routing_key" secret:"true"`
+	// The Events API v2 endpoint. Mostly useful for testing.
+	Endpoint string `yaml:"endpoint"`
+	endpoint *url.URL
+	// The lowest Clair severity that opens an incident, e.g. "High".
+	//
+	// Must be one of the claircore severities: "Unknown", "Negligible",
+	// "Low", "Medium", "High", or "Critical".
+	MinSeverity string `yaml:"min_severity"`
+	minSeverity claircore.Severity
+	// Overrides for the mapping of Clair severities to PagerDuty severities.
+	// PagerDuty severities are "critical", "error", "warning", and "info".
+	Severities map[string]string `yaml:"severities"`
+	severities map[string]string
+	// The event source, e.g. the Clair instance's hostname.
+	Source string `yaml:"source"`
+	// An optional URL linked from the incident, typically a page showing
+	// the affected manifest. "{manifest}" is replaced with the manifest
+	// digest.
+	LinkTemplate string `yaml:"link_template"`
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+}
+
+// Validate confirms configuration is valid and fills in private members
+// with parsed values on success.
+func (c *Config) Validate() (Config, error) {
+	conf := *c
+	if c.RoutingKey == "" {
+		return conf, fmt.Errorf("pagerduty config requires the routing_key field")
+	}
+
+	ep := c.Endpoint
+	if ep == "" {
+		ep = DefaultEndpoint
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return conf, fmt.Errorf("failed to parse endpoint url: %v", err)
+	}
+	conf.endpoint = u
+
+	min := c.MinSeverity
+	if min == "" {
+		min = DefaultMinSeverity
+	}
+	sev, ok := notifier.ParseSeverity(min)
+	if !ok {
+		return conf, fmt.Errorf("pagerduty config: unknown severity %q", min)
+	}
+	conf.minSeverity = sev
+
+	conf.severities = make(map[string]string, len(DefaultSeverities))
+	for k, v := range DefaultSeverities {
+		conf.severities[k] = v
+	}
+	for k, v := range c.Severities {
+		if _, ok := notifier.ParseSeverity(k); !ok {
+			return conf, fmt.Errorf("pagerduty config: unknown severity %q", k)
+		}
+		v = strings.ToLower(v)
+		switch v {
+		case "critical", "error", "warning", "info":
+		default:
+			return conf, fmt.Errorf("pagerduty config: unknown pagerduty severity %q", v)
+		}
+		conf.severities[k] = v
+	}
+
+	if conf.Source == "" {
+		conf.Source = DefaultSource
+	}
+
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+	return conf, nil
+}
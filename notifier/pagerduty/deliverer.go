@@ -0,0 +1,260 @@
+// Package pagerduty delivers notifications as PagerDuty incidents, using the
+// Events API v2.
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer opens a PagerDuty incident for every notification at or above
+// the configured severity.
+//
+// Incidents are deduplicated by notification ID, so redelivering a set of
+// notifications doesn't open duplicate incidents.
+type Deliverer struct {
+	conf   Config
+	client *http.Client
+	n      []notifier.Notification
+}
+
+// New returns a new PagerDuty Deliverer.
+//
+// If client is nil, http.DefaultClient is used.
+func New(conf Config, client *http.Client) (*Deliverer, error) {
+	c, err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Deliverer{
+		conf:   c,
+		client: client,
+		n:      []notifier.Notification{},
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return "pagerduty"
+}
+
+// Notifications implements notifier.DirectDeliverer.
+//
+// Only notifications that should open an incident are kept.
+func (d *Deliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	d.n = d.n[:0]
+	for _, n := range n {
+		if d.triggers(&n) {
+			d.n = append(d.n, n)
+		}
+	}
+	return nil
+}
+
+// Triggers reports whether the notification should open an incident.
+func (d *Deliverer) triggers(n *notifier.Notification) bool {
+	if n.Reason == notifier.Removed {
+		return false
+	}
+	// An unparsable severity is treated as Unknown.
+	sev, _ := notifier.ParseSeverity(n.Vulnerability.Severity)
+	return sev >= d.conf.minSeverity
+}
+
+// Deliver implements the notifier.Deliverer interface.
+//
+// Events are sent one at a time. Rate limited events are retried after the
+// wait PagerDuty asks for. If one fails, the ones before it will be sent again
+// on retry, which PagerDuty deduplicates.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/pagerduty/Deliverer.Deliver").
+		Stringer("notification_id", nID).
+		Logger()
+	for i := range d.n {
+		if err := d.send(ctx, d.event(&d.n[i])); err != nil {
+			return &clairerror.ErrDeliveryFailed{E: err}
+		}
+	}
+	log.Debug().Int("count", len(d.n)).Msg("triggered incidents")
+	return nil
+}
+
+// Event is an Events API v2 event.
+type event struct {
+	RoutingKey  string  `json:"routing_key"`
+	EventAction string  `json:"event_action"`
+	DedupKey    string  `json:"dedup_key"`
+	Payload     payload `json:"payload"`
+	Links       []link  `json:"links,omitempty"`
+}
+
+type payload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails *notifier.Notification `json:"custom_details,omitempty"`
+}
+
+type link struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+}
+
+// MaxSummary is the longest summary PagerDuty accepts.
+const maxSummary = 1024
+
+func (d *Deliverer) event(n *notifier.Notification) *event {
+	v := &n.Vulnerability
+	summary := fmt.Sprintf("%s vulnerability %s %s manifest %s", v.Severity, v.Name, n.Reason, n.Manifest)
+	if v.Package != nil {
+		summary = fmt.Sprintf("%s vulnerability %s in %s %s %s manifest %s",
+			v.Severity, v.Name, v.Package.Name, v.Package.Version, n.Reason, n.Manifest)
+	}
+	if len(summary) > maxSummary {
+		summary = summary[:maxSummary]
+	}
+	var group string
+	switch {
+	case v.Distribution != nil:
+		group = v.Distribution.Name
+	case v.Repo != nil:
+		group = v.Repo.Name
+	}
+	sev, ok := d.conf.severities[v.Severity]
+	if !ok {
+		sev = "info"
+	}
+
+	ev := &event{
+		RoutingKey:  d.conf.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    n.ID.String(),
+		Payload: payload{
+			Summary:       summary,
+			Source:        d.conf.Source,
+			Severity:      sev,
+			Component:     n.Manifest.String(),
+			Group:         group,
+			Class:         "vulnerability",
+			CustomDetails: n,
+		},
+	}
+	if t := d.conf.LinkTemplate; t != "" {
+		ev.Links = append(ev.Links, link{
+			Href: strings.ReplaceAll(t, "{manifest}", n.Manifest.String()),
+			Text: "Manifest",
+		})
+	}
+	for _, l := range strings.Fields(v.Links) {
+		ev.Links = append(ev.Links, link{Href: l})
+	}
+	return ev
+}
+
+// Limits on retrying rate limited events.
+const (
+	maxRetries = 3
+	// DefaultRetryWait is how long to wait if a rate limited response doesn't
+	// have a Retry-After header.
+	defaultRetryWait = 5 * time.Second
+	// Waits longer than maxRetryWait fail the delivery instead, leaving the
+	// retry to the notifier.
+	maxRetryWait = time.Minute
+)
+
+// RateLimited is returned when PagerDuty rejects an event for exceeding its
+// rate limit.
+type rateLimited struct {
+	wait time.Duration
+}
+
+func (e *rateLimited) Error() string {
+	return fmt.Sprintf("rate limited by pagerduty: retry after %v", e.wait)
+}
+
+// Send sends the event, waiting out and retrying rate limited responses.
+func (d *Deliverer) send(ctx context.Context, ev *event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	for try := 0; ; try++ {
+		err := d.post(ctx, b)
+		var rl *rateLimited
+		if !errors.As(err, &rl) || try == maxRetries || rl.wait > maxRetryWait {
+			return err
+		}
+		zerolog.Ctx(ctx).Debug().
+			Str("component", "notifier/pagerduty/Deliverer.send").
+			Dur("wait", rl.wait).
+			Msg("rate limited, waiting to retry")
+		t := time.NewTimer(rl.wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (d *Deliverer) post(ctx context.Context, b []byte) error {
+	req, err := http.NewRequest(http.MethodPost, d.conf.endpoint.String(), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("content-type", "application/json")
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusAccepted:
+		return nil
+	case http.StatusTooManyRequests:
+		return &rateLimited{wait: retryAfter(res.Header.Get("retry-after"), time.Now())}
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("unexpected response from pagerduty: %s: %s", res.Status, bytes.TrimSpace(msg))
+}
+
+// RetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func retryAfter(h string, now time.Time) time.Duration {
+	if h == "" {
+		return defaultRetryWait
+	}
+	if s, err := strconv.Atoi(h); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return defaultRetryWait
+}
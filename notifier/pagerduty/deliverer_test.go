@@ -0,0 +1,205 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// fakePagerDuty is a minimal Events API v2 endpoint.
+type fakePagerDuty struct {
+	sync.Mutex
+	events []event
+	fail   bool
+	// Limited is the number of requests to rate limit before accepting
+	// events.
+	limited  int
+	requests int
+}
+
+func (f *fakePagerDuty) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests++
+	if f.fail || f.limited > 0 {
+		f.limited--
+		w.Header().Set("retry-after", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	var ev event
+	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.events = append(f.events, ev)
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"status":"success","message":"Event processed"}`))
+}
+
+func notification(sev claircore.Severity, reason notifier.Reason) notifier.Notification {
+	return notifier.Notification{
+		ID:       uuid.New(),
+		Manifest: claircore.MustParseDigest("sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"),
+		Reason:   reason,
+		Vulnerability: notifier.VulnSummary{
+			Name:     "CVE-2021-0001",
+			Severity: sev.String(),
+			Package:  &claircore.Package{Name: "openssl", Version: "1.1.1"},
+			Distribution: &claircore.Distribution{
+				Name: "Ubuntu",
+			},
+			Links: "https://example.com/CVE-2021-0001",
+		},
+	}
+}
+
+func TestDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := &fakePagerDuty{}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	d, err := New(Config{
+		RoutingKey:   "key",
+		Endpoint:     srv.URL,
+		Severities:   map[string]string{"High": "critical"},
+		LinkTemplate: "https://clair.example.com/manifest/{manifest}",
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := []notifier.Notification{
+		notification(claircore.Critical, notifier.Added),
+		notification(claircore.High, notifier.Added),
+		notification(claircore.Medium, notifier.Added),
+		notification(claircore.Critical, notifier.Removed),
+	}
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	if got, want := len(f.events), 2; got != want {
+		t.Fatalf("got: %d events, want: %d", got, want)
+	}
+	for i, want := range []string{"critical", "critical"} {
+		ev := f.events[i]
+		if got := ev.Payload.Severity; got != want {
+			t.Errorf("event %d: got severity: %q, want: %q", i, got, want)
+		}
+		if got, want := ev.DedupKey, ns[i].ID.String(); got != want {
+			t.Errorf("event %d: got dedup key: %q, want: %q", i, got, want)
+		}
+		if got, want := ev.RoutingKey, "key"; got != want {
+			t.Errorf("event %d: got routing key: %q, want: %q", i, got, want)
+		}
+		if got, want := ev.Payload.Group, "Ubuntu"; got != want {
+			t.Errorf("event %d: got group: %q, want: %q", i, got, want)
+		}
+		if got, want := len(ev.Links), 2; got != want {
+			t.Errorf("event %d: got: %d links, want: %d", i, got, want)
+		}
+	}
+}
+
+func TestDelivererFailure(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := &fakePagerDuty{fail: true}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	d, err := New(Config{RoutingKey: "key", Endpoint: srv.URL}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notifications(ctx, []notifier.Notification{notification(claircore.Critical, notifier.Added)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err == nil {
+		t.Error("expected error")
+	}
+	f.Lock()
+	defer f.Unlock()
+	if got, want := f.requests, maxRetries+1; got != want {
+		t.Errorf("got: %d requests, want: %d", got, want)
+	}
+}
+
+func TestDelivererRateLimited(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f := &fakePagerDuty{limited: 2}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	d, err := New(Config{RoutingKey: "key", Endpoint: srv.URL}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notifications(ctx, []notifier.Notification{notification(claircore.Critical, notifier.Added)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	f.Lock()
+	defer f.Unlock()
+	if got, want := len(f.events), 1; got != want {
+		t.Errorf("got: %d events, want: %d", got, want)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tt := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", defaultRetryWait},
+		{"30", 30 * time.Second},
+		{"Fri, 01 Jan 2021 00:00:10 GMT", 10 * time.Second},
+		{"Thu, 31 Dec 2020 23:59:00 GMT", 0},
+		{"soon", defaultRetryWait},
+	}
+	for _, tc := range tt {
+		if got := retryAfter(tc.in, now); got != tc.want {
+			t.Errorf("%q: got: %v, want: %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tt := []struct {
+		name string
+		conf Config
+		ok   bool
+	}{
+		{"Defaults", Config{RoutingKey: "key"}, true},
+		{"NoKey", Config{}, false},
+		{"BadMinSeverity", Config{RoutingKey: "key", MinSeverity: "Severe"}, false},
+		{"BadMapping", Config{RoutingKey: "key", Severities: map[string]string{"High": "loud"}}, false},
+		{"BadClairSeverity", Config{RoutingKey: "key", Severities: map[string]string{"Loud": "error"}}, false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.conf.Validate()
+			if got := err == nil; got != tc.ok {
+				t.Errorf("got: %v, want: %v (%v)", got, tc.ok, err)
+			}
+		})
+	}
+}
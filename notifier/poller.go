@@ -107,9 +107,8 @@ func (p *Poller) onTick(ctx context.Context, c chan<- Event) {
 		}
 		latest := uo[0]
 		// confirm notifications were never created for this UOID.
-		var errNoReceipt clairerror.ErrNoReceipt
 		_, err := p.store.ReceiptByUOID(ctx, latest.Ref)
-		if errors.As(err, &errNoReceipt) {
+		if errors.Is(err, clairerror.NotFound) {
 			e := Event{
 				updater: updater,
 				uo:      latest,
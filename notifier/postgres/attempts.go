@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// putAttempt records a delivery attempt.
+func putAttempt(ctx context.Context, pool *pgxpool.Pool, a notifier.Attempt) error {
+	const (
+		query = `INSERT INTO delivery_attempt
+(notification_id, deliverer, target, ts, status, response_code, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	)
+
+	_, err := pool.Exec(ctx, query,
+		a.NotificationID.String(),
+		a.Deliverer,
+		a.Target,
+		a.TS,
+		string(a.Status),
+		a.ResponseCode,
+		a.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// attempts returns the delivery attempts for the provided notification id,
+// oldest first.
+func attempts(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID) ([]notifier.Attempt, error) {
+	const (
+		query = `SELECT deliverer, target, ts, status, response_code, error
+FROM delivery_attempt WHERE notification_id = $1 ORDER BY ts, id`
+	)
+
+	rows, err := pool.Query(ctx, query, id.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to select delivery attempts: %w", err)
+	}
+	defer rows.Close()
+	as := []notifier.Attempt{}
+	for rows.Next() {
+		a := notifier.Attempt{NotificationID: id}
+		var st string
+		if err := rows.Scan(&a.Deliverer, &a.Target, &a.TS, &st, &a.ResponseCode, &a.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery attempt: %w", err)
+		}
+		a.Status = notifier.AttemptStatus(st)
+		as = append(as, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select delivery attempts: %w", err)
+	}
+	return as, nil
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// backlog counts the receipts waiting on delivery.
+func backlog(ctx context.Context, pool *pgxpool.Pool) (notifier.Backlog, error) {
+	const (
+		query = `SELECT
+	count(*) FILTER (WHERE status = 'created'),
+	count(*) FILTER (WHERE status = 'delivery_failed'),
+	min(created) FILTER (WHERE status = 'created')
+FROM receipt`
+	)
+
+	var b notifier.Backlog
+	var oldest *time.Time
+	if err := pool.QueryRow(ctx, query).Scan(&b.Pending, &b.Failed, &oldest); err != nil {
+		return b, fmt.Errorf("failed to count receipts: %w", err)
+	}
+	b.Oldest = oldest
+	return b, nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// claim leases up to limit of the provided notification ids to owner, if
+// they're still waiting for delivery and nobody else holds a lease on them.
+//
+// rows locked by a concurrent claim are skipped rather than waited on, so
+// replicas claiming at the same time end up with disjoint sets.
+func claim(ctx context.Context, pool *pgxpool.Pool, owner string, lease time.Duration, ids []uuid.UUID, limit int) ([]uuid.UUID, error) {
+	const (
+		query = `
+		UPDATE receipt
+		SET claimed_by = $1, claimed_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE notification_id IN (
+			SELECT notification_id FROM receipt
+			WHERE notification_id = ANY($3)
+				AND status IN ('created', 'delivery_failed')
+				AND (claimed_until IS NULL OR claimed_until < CURRENT_TIMESTAMP)
+			ORDER BY status = 'delivery_failed', seq
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING notification_id;`
+	)
+	if len(ids) == 0 || limit <= 0 {
+		return []uuid.UUID{}, nil
+	}
+	in := make([]string, len(ids))
+	for i, id := range ids {
+		in[i] = id.String()
+	}
+	rows, err := pool.Query(ctx, query, owner, lease.Seconds(), in, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notification ids: %w", err)
+	}
+	defer rows.Close()
+	out := make([]uuid.UUID, 0, limit)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to claim notification ids: %w", err)
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim notification ids: %w", err)
+	}
+	return out, nil
+}
+
+// release ends owner's lease on the notification id. a lease held by someone
+// else is left alone.
+func release(ctx context.Context, pool *pgxpool.Pool, owner string, id uuid.UUID) error {
+	const (
+		query = `
+		UPDATE receipt SET claimed_by = NULL, claimed_until = NULL
+		WHERE notification_id = $1 AND claimed_by = $2;`
+	)
+	if _, err := pool.Exec(ctx, query, id.String(), owner); err != nil {
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to retrieve receipt for notification id %s", id))
+	}
+	return nil
+}
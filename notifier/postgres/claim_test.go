@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// TestClaim confirms notification ids are leased to one owner at a time,
+// and only while they're waiting for delivery.
+func TestClaim(t *testing.T) {
+	integration.Skip(t)
+	ctx := context.Background()
+	sx, store, _, teardown := TestStore(ctx, t)
+	defer teardown()
+
+	digest, _ := claircore.ParseDigest("sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a")
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		opts := notifier.PutOpts{
+			Updater:        updater,
+			UpdateID:       uuid.New(),
+			NotificationID: uuid.New(),
+			Notifications:  []notifier.Notification{{Manifest: digest, Reason: "added"}},
+		}
+		if err := store.PutNotifications(ctx, opts); err != nil {
+			t.Fatalf("failed to put notifications: %v", err)
+		}
+		ids[i] = opts.NotificationID
+	}
+	if err := store.SetDelivered(ctx, ids[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := store.Claim(ctx, "a", time.Minute, ids, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := store.Claim(ctx, "b", time.Minute, ids, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 1 || len(b) != 1 || a[0] == b[0] {
+		t.Fatalf("got: %v and %v, want one distinct id each", a, b)
+	}
+	if b[0] == ids[2] || a[0] == ids[2] {
+		t.Errorf("delivered notification id claimed")
+	}
+
+	// Releasing someone else's claim does nothing.
+	if err := store.Release(ctx, "b", a[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Claim(ctx, "b", time.Minute, a, 1); err != nil || len(got) != 0 {
+		t.Errorf("got: %v, %v, want nothing claimed", got, err)
+	}
+	if err := store.Release(ctx, "a", a[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Claim(ctx, "b", time.Minute, a, 1); err != nil || len(got) != 1 {
+		t.Errorf("got: %v, %v, want released id claimed", got, err)
+	}
+
+	// An expired lease may be claimed again.
+	if _, err := sx.ExecContext(ctx, `UPDATE receipt SET claimed_until = CURRENT_TIMESTAMP - '1 second'::interval`); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Claim(ctx, "c", time.Minute, ids, 10); err != nil || len(got) != 2 {
+		t.Errorf("got: %v, %v, want 2 expired claims", got, err)
+	}
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore/pkg/microbatch"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// coalesceNotifications inserts the provided notifications under a new
+// notification id with a receipt in created status, and sets the receipts of
+// the notification ids they replace to deleted status.
+//
+// these operations occur under a transaction, so the replaced notification
+// ids are never delivered alongside the new one.
+func coalesceNotifications(ctx context.Context, pool *pgxpool.Pool, opts notifier.CoalesceOpts) error {
+	const (
+		insertNotification    = `INSERT INTO notification (id) VALUES ($1);`
+		insertNotifcationBody = `INSERT INTO notification_body (id, notification_id, body) VALUES ($1, $2, $3);`
+		insertReceipt         = `INSERT INTO receipt (notification_id, uo_id, status, ts) VALUES ($1, $2, 'created', CURRENT_TIMESTAMP);`
+		deleteReplaced        = `
+		UPDATE receipt SET status = 'deleted', ts = CURRENT_TIMESTAMP
+		WHERE notification_id = ANY($1) AND status = 'created'
+			AND (claimed_until IS NULL OR claimed_until < CURRENT_TIMESTAMP);`
+	)
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
+	}
+	defer tx.Rollback(ctx)
+
+	ids := make([]string, len(opts.Replaces))
+	for i, id := range opts.Replaces {
+		ids[i] = id.String()
+	}
+	tag, err := tx.Exec(ctx, deleteReplaced, ids)
+	if err != nil {
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
+	}
+	if got, want := tag.RowsAffected(), int64(len(ids)); got != want {
+		return clairerror.New(clairerror.Conflict, fmt.Sprintf("failed to persist notification associated with id %s: %d of %d replaced notification ids no longer in created status or are being delivered", opts.NotificationID, want-got, want))
+	}
+
+	if _, err := tx.Exec(ctx, insertNotification, opts.NotificationID); err != nil {
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
+	}
+	mBatch := microbatch.NewInsert(tx, batchSize, batchTO)
+	for _, notification := range opts.Notifications {
+		notification.ID = uuid.New()
+		if err := mBatch.Queue(ctx, insertNotifcationBody, notification.ID, opts.NotificationID, notificationJSONB(notification)); err != nil {
+			return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
+		}
+	}
+	if err := mBatch.Done(ctx); err != nil {
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
+	}
+	if _, err := tx.Exec(ctx, insertReceipt, opts.NotificationID, opts.UpdateID); err != nil {
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
+	}
+	return nil
+}
@@ -21,7 +21,7 @@ func created(ctx context.Context, pool *pgxpool.Pool) ([]uuid.UUID, error) {
 		var id uuid.UUID
 		err := rows.Scan(&id)
 		if err != nil {
-			return nil, clairerror.ErrCreated{err}
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to retrieve created notification ids")
 		}
 		ids = append(ids, id)
 	}
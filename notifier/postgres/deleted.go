@@ -21,7 +21,7 @@ func deleted(ctx context.Context, pool *pgxpool.Pool) ([]uuid.UUID, error) {
 		var id uuid.UUID
 		err := rows.Scan(&id)
 		if err != nil {
-			return nil, clairerror.ErrCreated{err}
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to retrieve deleted notification ids")
 		}
 		ids = append(ids, id)
 	}
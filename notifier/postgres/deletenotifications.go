@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -24,12 +25,12 @@ func deleteNotifications(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID)
 
 	tx, err := pool.Begin(ctx)
 	if err != nil {
-		return clairerror.ErrDeleteNotification{id, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("notifications associated with id %s were not deleted", id))
 	}
 
 	tag, err := tx.Exec(ctx, deleteNotifications, id.String())
 	if err != nil {
-		return clairerror.ErrDeleteNotification{id, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("notifications associated with id %s were not deleted", id))
 	}
 	if tag.RowsAffected() <= 0 {
 		log.Warn().Str("notification_id", id.String()).Msg("no notification bodies deleted")
@@ -37,7 +38,7 @@ func deleteNotifications(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID)
 
 	tag, err = tx.Exec(ctx, deleteReceipt, id.String())
 	if err != nil {
-		return clairerror.ErrDeleteNotification{id, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("notifications associated with id %s were not deleted", id))
 	}
 	if tag.RowsAffected() <= 0 {
 		log.Warn().Str("notification_id", id.String()).Msg("no notification receipt deleted")
@@ -45,7 +46,7 @@ func deleteNotifications(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID)
 
 	tag, err = tx.Exec(ctx, deleteNotificationID, id.String())
 	if err != nil {
-		return clairerror.ErrDeleteNotification{id, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("notifications associated with id %s were not deleted", id))
 	}
 	if tag.RowsAffected() <= 0 {
 		log.Warn().Str("notification_id", id.String()).Msg("no notification id deleted")
@@ -53,7 +54,7 @@ func deleteNotifications(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID)
 
 	err = tx.Commit(ctx)
 	if err != nil {
-		return clairerror.ErrDeleteNotification{id, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("notifications associated with id %s were not deleted", id))
 	}
 	return nil
 }
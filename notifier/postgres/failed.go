@@ -21,7 +21,7 @@ func failed(ctx context.Context, pool *pgxpool.Pool) ([]uuid.UUID, error) {
 		var id uuid.UUID
 		err := rows.Scan(&id)
 		if err != nil {
-			return nil, clairerror.ErrFailed{err}
+			return nil, clairerror.Wrap(clairerror.DependencyUnavailable, err, "failed to retrieve failed notification ids")
 		}
 		ids = append(ids, id)
 	}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// indexEventCursor returns the sequence number of the last index event
+// relayed, or 0 if none have been.
+func indexEventCursor(ctx context.Context, pool *pgxpool.Pool) (int64, error) {
+	const query = `SELECT seq FROM index_event_cursor WHERE id = 1;`
+	var seq int64
+	err := pool.QueryRow(ctx, query).Scan(&seq)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("failed to select index event cursor: %w", err)
+	}
+	return seq, nil
+}
+
+// setIndexEventCursor saves the sequence number of the last index event
+// relayed.
+func setIndexEventCursor(ctx context.Context, pool *pgxpool.Pool, seq int64) error {
+	const query = `INSERT INTO index_event_cursor (id, seq) VALUES (1, $1)
+ON CONFLICT (id) DO UPDATE SET seq = EXCLUDED.seq;`
+	if _, err := pool.Exec(ctx, query, seq); err != nil {
+		return fmt.Errorf("failed to update index event cursor: %w", err)
+	}
+	return nil
+}
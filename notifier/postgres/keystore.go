@@ -91,7 +91,7 @@ func (k *KeyStore) KeyByID(ctx context.Context, ID uuid.UUID) (notifier.Key, err
 	err := row.Scan(&key.ID, &key.Expiration, &der)
 	switch err {
 	case pgx.ErrNoRows:
-		return notifier.Key{}, clairerror.ErrKeyNotFound{ID}
+		return notifier.Key{}, keyNotFound(ID)
 	case nil:
 		// hop out
 	default:
@@ -134,7 +134,7 @@ func (k *KeyStore) DeleteKey(ctx context.Context, ID uuid.UUID) error {
 		return err
 	}
 	if tag.RowsAffected() <= 0 {
-		return clairerror.ErrKeyNotFound{ID}
+		return keyNotFound(ID)
 	}
 	return nil
 }
@@ -148,7 +148,7 @@ func (k *KeyStore) BumpExpiration(ctx context.Context, ID uuid.UUID, n time.Dura
 		return err
 	}
 	if tag.RowsAffected() <= 0 {
-		return clairerror.ErrKeyNotFound{ID}
+		return keyNotFound(ID)
 	}
 	return nil
 }
@@ -178,3 +178,8 @@ func derToRSAPublic(der []byte) (*rsa.PublicKey, error) {
 	}
 	return rsaPub, nil
 }
+
+// KeyNotFound returns the error reported for a key that doesn't exist.
+func keyNotFound(id uuid.UUID) error {
+	return clairerror.New(clairerror.NotFound, "key with id "+id.String()+" not found")
+}
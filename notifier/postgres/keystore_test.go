@@ -151,8 +151,8 @@ func testKeyStore(t *testing.T) {
 		}
 
 		_, err = keystore.KeyByID(ctx, kp.ID)
-		if !errors.As(err, &clairerror.ErrKeyNotFound{}) {
-			t.Errorf("got: %v, wanted: %v", err, clairerror.ErrKeyNotFound{kp.ID})
+		if !errors.Is(err, clairerror.NotFound) {
+			t.Errorf("got: %v, wanted: %v", err, clairerror.NotFound)
 		}
 	}
 }
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -30,7 +31,7 @@ func notifications(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID, page *
 			var n notifier.Notification
 			err := rows.Scan(&n.ID, &n)
 			if err != nil {
-				return nil, p, clairerror.ErrBadNotification{id, err}
+				return nil, p, clairerror.Wrap(clairerror.Internal, err, fmt.Sprintf("notification associated with id %s is malformed", id))
 			}
 			notifications = append(notifications, n)
 		}
@@ -59,7 +60,7 @@ func notifications(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID, page *
 		var n notifier.Notification
 		err := rows.Scan(&n.ID, &n)
 		if err != nil {
-			return nil, notifier.Page{}, clairerror.ErrBadNotification{id, err}
+			return nil, notifier.Page{}, clairerror.Wrap(clairerror.Internal, err, fmt.Sprintf("notification associated with id %s is malformed", id))
 		}
 		notifications = append(notifications, n)
 	}
@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// partitionLockKey is the advisory lock taken by transactions managing
+// notification body partitions, so that multiple notifiers don't do it at
+// the same time.
+var partitionLockKey = func() int64 {
+	h := fnv.New64a()
+	io.WriteString(h, "clair-notifier-partitions")
+	return int64(h.Sum64())
+}()
+
+const (
+	// partitionPrefix and partitionLayout name the daily partitions of
+	// notification_body for the UTC day they start on.
+	partitionPrefix = "notification_body_"
+	partitionLayout = "20060102"
+	// legacyPartition holds the notification bodies that existed when the
+	// table was partitioned.
+	legacyPartition = "notification_body_legacy"
+
+	partitionTryLock = `SELECT pg_try_advisory_xact_lock($1);`
+	listPartitions   = `
+SELECT c.relname
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'notification_body'::regclass;`
+	createPartition = `CREATE TABLE IF NOT EXISTS %s PARTITION OF notification_body FOR VALUES FROM ('%s') TO ('%s');`
+	dropPartition   = `DROP TABLE IF EXISTS %s;`
+	legacyEmpty     = `SELECT NOT EXISTS (SELECT 1 FROM ` + legacyPartition + `);`
+)
+
+// partitionDay reports the day a partition starts on, if it's one of the
+// daily partitions.
+func partitionDay(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, partitionPrefix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(partitionLayout, strings.TrimPrefix(name, partitionPrefix))
+	return t, err == nil
+}
+
+// partitionName returns the name of the daily partition holding the time.
+func partitionName(t time.Time) string {
+	return partitionPrefix + t.UTC().Format(partitionLayout)
+}
+
+// lockPartitions begins a transaction holding the partition lock. If
+// another process holds it, the returned transaction is nil.
+func lockPartitions(ctx context.Context, pool *pgxpool.Pool) (pgx.Tx, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tx: %w", err)
+	}
+	var ok bool
+	if err := tx.QueryRow(ctx, partitionTryLock, partitionLockKey).Scan(&ok); err != nil {
+		tx.Rollback(ctx)
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if !ok {
+		tx.Rollback(ctx)
+		zerolog.Ctx(ctx).Debug().
+			Str("component", "notifier/postgres/lockPartitions").
+			Msg("another process is managing partitions")
+		return nil, nil
+	}
+	return tx, nil
+}
+
+// partitions returns the names of notification_body's partitions.
+func partitions(ctx context.Context, tx pgx.Tx) (map[string]bool, error) {
+	rows, err := tx.Query(ctx, listPartitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	defer rows.Close()
+	ps := make(map[string]bool)
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		ps[n] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	return ps, nil
+}
+
+// ensurePartitions creates the daily partitions from today through the day
+// holding until, returning how many were created.
+//
+// A day whose notification bodies already landed in the default partition
+// can't be given a partition of its own; it's skipped and logged.
+func ensurePartitions(ctx context.Context, pool *pgxpool.Pool, until time.Time) (int, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/postgres/ensurePartitions").
+		Logger()
+	tx, err := lockPartitions(ctx, pool)
+	if tx == nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	ps, err := partitions(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for ; !day.After(until); day = day.AddDate(0, 0, 1) {
+		name := partitionName(day)
+		if ps[name] {
+			continue
+		}
+		q := fmt.Sprintf(createPartition,
+			pgx.Identifier{name}.Sanitize(),
+			day.Format(time.RFC3339),
+			day.AddDate(0, 0, 1).Format(time.RFC3339))
+		// Run each in a savepoint, so a day that can't be created doesn't
+		// stop the rest.
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return n, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		if _, err := sp.Exec(ctx, q); err != nil {
+			sp.Rollback(ctx)
+			log.Warn().
+				Err(err).
+				Str("partition", name).
+				Msg("unable to create partition")
+			continue
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return n, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		log.Debug().
+			Str("partition", name).
+			Msg("created partition")
+		n++
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return n, nil
+}
+
+// dropPartitions drops the daily partitions ending at or before the
+// provided time, and the legacy partition once it's empty, returning how
+// many were dropped.
+func dropPartitions(ctx context.Context, pool *pgxpool.Pool, before time.Time) (int, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/postgres/dropPartitions").
+		Logger()
+	tx, err := lockPartitions(ctx, pool)
+	if tx == nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	ps, err := partitions(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	var drop []string
+	for name := range ps {
+		day, ok := partitionDay(name)
+		if ok && !day.AddDate(0, 0, 1).After(before) {
+			drop = append(drop, name)
+		}
+	}
+	if ps[legacyPartition] {
+		var empty bool
+		if err := tx.QueryRow(ctx, legacyEmpty).Scan(&empty); err != nil {
+			return 0, fmt.Errorf("failed to check legacy partition: %w", err)
+		}
+		if empty {
+			drop = append(drop, legacyPartition)
+		}
+	}
+	for _, name := range drop {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(dropPartition, pgx.Identifier{name}.Sanitize())); err != nil {
+			return 0, fmt.Errorf("failed to drop partition %q: %w", name, err)
+		}
+		log.Debug().
+			Str("partition", name).
+			Msg("dropped partition")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return len(drop), nil
+}
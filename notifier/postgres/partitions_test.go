@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestPartitionName(t *testing.T) {
+	ts := time.Date(2021, time.March, 4, 23, 30, 0, 0, time.FixedZone("", -2*60*60))
+	name := partitionName(ts)
+	if got, want := name, "notification_body_20210305"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	day, ok := partitionDay(name)
+	if !ok {
+		t.Fatal("not a daily partition")
+	}
+	if got, want := day, time.Date(2021, time.March, 5, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	for _, n := range []string{legacyPartition, "notification_body_default"} {
+		if _, ok := partitionDay(n); ok {
+			t.Errorf("%q: unexpectedly a daily partition", n)
+		}
+	}
+}
+
+// TestPartitions confirms partitions are created ahead of time and dropped
+// once expired.
+func TestPartitions(t *testing.T) {
+	integration.Skip(t)
+	ctx := context.Background()
+	_, store, _, teardown := TestStore(ctx, t)
+	defer teardown()
+
+	n, err := store.EnsurePartitions(ctx, time.Now().Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The migration creates today's partition.
+	if got, want := n, 2; got != want {
+		t.Errorf("got: %d created, want: %d", got, want)
+	}
+	if n, err := store.EnsurePartitions(ctx, time.Now().Add(48*time.Hour)); err != nil || n != 0 {
+		t.Errorf("got: %d created, %v; want: 0 created", n, err)
+	}
+
+	// The legacy partition is empty, so it's dropped along with every daily
+	// partition.
+	n, err = store.DropPartitions(ctx, time.Now().Add(96*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 4; got != want {
+		t.Errorf("got: %d dropped, want: %d", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// pruneLockKey is the advisory lock taken by pruning transactions, so that
+// multiple notifiers don't prune at the same time.
+var pruneLockKey = func() int64 {
+	h := fnv.New64a()
+	io.WriteString(h, "clair-notifier-prune")
+	return int64(h.Sum64())
+}()
+
+const (
+	// superseded matches receipts whose update operation is no longer the
+	// latest for its updater. Only these receipts may be removed; the poller
+	// would otherwise create notifications for the latest update operation
+	// again.
+	superseded = `EXISTS (
+		SELECT 1 FROM notifier_update_operation newer
+		WHERE newer.updater = uo.updater AND newer.ts > uo.ts)`
+	// removable matches receipts with something left to remove.
+	removable = `(` + superseded + `
+	OR EXISTS (SELECT 1 FROM notification_body b WHERE b.notification_id = r.notification_id))`
+
+	pruneTryLock = `SELECT pg_try_advisory_xact_lock($1);`
+	selectPrune  = `
+SELECT r.notification_id
+FROM receipt r
+JOIN notifier_update_operation uo ON uo.uo_id = r.uo_id
+WHERE uo.ts < $1 AND ` + removable + `
+LIMIT $2;`
+	selectPurge = `
+SELECT r.notification_id
+FROM receipt r
+JOIN notifier_update_operation uo ON uo.uo_id = r.uo_id
+WHERE r.uo_id = $1 AND r.status IN ('delivered', 'deleted') AND ` + removable + `;`
+	pruneUpdateOperations = `
+DELETE FROM notifier_update_operation uo
+WHERE uo.ts < $1
+	AND NOT EXISTS (SELECT 1 FROM receipt r WHERE r.uo_id = uo.uo_id)
+	AND ` + superseded + `;`
+)
+
+// removeNotifications removes the notifications for the notification ids in
+// $1. Receipts for the latest update operation of an updater are kept in
+// "deleted" status.
+var removeNotifications = []string{
+	`DELETE FROM notification_body WHERE notification_id = ANY($1);`,
+	`DELETE FROM receipt r USING notifier_update_operation uo
+	WHERE r.notification_id = ANY($1) AND uo.uo_id = r.uo_id AND ` + superseded + `;`,
+	`UPDATE receipt SET status = 'deleted', ts = CURRENT_TIMESTAMP WHERE notification_id = ANY($1);`,
+	`DELETE FROM notification n
+	WHERE n.id = ANY($1) AND NOT EXISTS (SELECT 1 FROM receipt r WHERE r.notification_id = n.id);`,
+}
+
+// pruneNotifications removes up to limit notification ids whose update
+// operation was seen before the provided time.
+func pruneNotifications(ctx context.Context, pool *pgxpool.Pool, before time.Time, limit int) (int64, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var ok bool
+	if err := tx.QueryRow(ctx, pruneTryLock, pruneLockKey).Scan(&ok); err != nil {
+		return 0, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if !ok {
+		zerolog.Ctx(ctx).Debug().
+			Str("component", "notifier/postgres/pruneNotifications").
+			Msg("another process is pruning")
+		return 0, nil
+	}
+
+	ids, err := selectIDs(ctx, tx, selectPrune, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	if err := remove(ctx, tx, ids); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, pruneUpdateOperations, before); err != nil {
+		return 0, fmt.Errorf("failed to prune update operations: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return int64(len(ids)), nil
+}
+
+// purgeDelivered removes the delivered or deleted notifications created for
+// the provided update operation.
+func purgeDelivered(ctx context.Context, pool *pgxpool.Pool, uoid uuid.UUID) (int64, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ids, err := selectIDs(ctx, tx, selectPurge, uoid.String())
+	if err != nil {
+		return 0, err
+	}
+	if err := remove(ctx, tx, ids); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return int64(len(ids)), nil
+}
+
+// selectIDs returns the notification ids returned by the query.
+func selectIDs(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select notifications: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan notification id: %w", err)
+		}
+		ids = append(ids, id.String())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select notifications: %w", err)
+	}
+	return ids, nil
+}
+
+// remove runs removeNotifications for the ids.
+func remove(ctx context.Context, tx pgx.Tx, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, q := range removeNotifications {
+		if _, err := tx.Exec(ctx, q, ids); err != nil {
+			return fmt.Errorf("failed to remove notifications: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// TestPrune confirms pruning removes notifications for superseded update
+// operations, and keeps the receipt for the latest one.
+func TestPrune(t *testing.T) {
+	integration.Skip(t)
+	ctx := context.Background()
+	_, store, _, teardown := TestStore(ctx, t)
+	defer teardown()
+
+	digest, _ := claircore.ParseDigest("sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a")
+	put := func() (uuid.UUID, uuid.UUID) {
+		opts := notifier.PutOpts{
+			Updater:        updater,
+			UpdateID:       uuid.New(),
+			NotificationID: uuid.New(),
+			Notifications:  []notifier.Notification{{Manifest: digest, Reason: "added"}},
+		}
+		if err := store.PutNotifications(ctx, opts); err != nil {
+			t.Fatalf("failed to put notifications: %v", err)
+		}
+		return opts.UpdateID, opts.NotificationID
+	}
+	oldUO, oldID := put()
+	newUO, newID := put()
+
+	n, err := store.PruneNotifications(ctx, time.Now().Add(time.Minute), notifier.PruneBatchSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(2); got != want {
+		t.Errorf("got: %d pruned, want: %d", got, want)
+	}
+
+	if _, err := store.ReceiptByUOID(ctx, oldUO); !errors.Is(err, clairerror.NotFound) {
+		t.Errorf("superseded receipt not removed: %v", err)
+	}
+	r, err := store.ReceiptByUOID(ctx, newUO)
+	if err != nil {
+		t.Fatalf("latest receipt removed: %v", err)
+	}
+	if got, want := r.Status, notifier.Deleted; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	for _, id := range []uuid.UUID{oldID, newID} {
+		ns, _, err := store.Notifications(ctx, id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ns) != 0 {
+			t.Errorf("notifications for %v not removed", id)
+		}
+	}
+
+	// Nothing is left to prune.
+	n, err = store.PruneNotifications(ctx, time.Now().Add(time.Minute), notifier.PruneBatchSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(0); got != want {
+		t.Errorf("got: %d pruned, want: %d", got, want)
+	}
+}
+
+// TestPurgeDelivered confirms only delivered notifications are purged.
+func TestPurgeDelivered(t *testing.T) {
+	integration.Skip(t)
+	ctx := context.Background()
+	_, store, _, teardown := TestStore(ctx, t)
+	defer teardown()
+
+	digest, _ := claircore.ParseDigest("sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a")
+	opts := notifier.PutOpts{
+		Updater:        updater,
+		UpdateID:       uuid.New(),
+		NotificationID: uuid.New(),
+		Notifications:  []notifier.Notification{{Manifest: digest, Reason: "added"}},
+	}
+	if err := store.PutNotifications(ctx, opts); err != nil {
+		t.Fatalf("failed to put notifications: %v", err)
+	}
+
+	n, err := store.PurgeDelivered(ctx, opts.UpdateID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(0); got != want {
+		t.Errorf("got: %d purged before delivery, want: %d", got, want)
+	}
+
+	if err := store.SetDelivered(ctx, opts.NotificationID); err != nil {
+		t.Fatal(err)
+	}
+	n, err = store.PurgeDelivered(ctx, opts.UpdateID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(1); got != want {
+		t.Errorf("got: %d purged, want: %d", got, want)
+	}
+	ns, _, err := store.Notifications(ctx, opts.NotificationID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns) != 0 {
+		t.Error("notifications not removed")
+	}
+}
@@ -36,17 +36,17 @@ func putNotifications(ctx context.Context, pool *pgxpool.Pool, opts notifier.Put
 	)
 	tx, err := pool.Begin(ctx)
 	if err != nil {
-		return clairerror.ErrPutNotifications{opts.NotificationID, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
 	}
 	defer tx.Rollback(ctx)
 
 	// insert into identity table
 	tag, err := tx.Exec(ctx, insertNotification, opts.NotificationID)
 	if err != nil {
-		return clairerror.ErrPutNotifications{opts.NotificationID, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
 	}
 	if tag.RowsAffected() <= 0 {
-		return clairerror.ErrPutNotifications{opts.NotificationID, fmt.Errorf("no rows affected when inserting notification identity")}
+		return clairerror.New(clairerror.Internal, fmt.Sprintf("failed to persist notification associated with id %s: no rows affected when inserting notification identity", opts.NotificationID))
 	}
 
 	// batch insert notifications
@@ -55,32 +55,32 @@ func putNotifications(ctx context.Context, pool *pgxpool.Pool, opts notifier.Put
 		id := uuid.New()
 		notification.ID = id
 		if err := mBatch.Queue(ctx, insertNotifcationBody, id, opts.NotificationID, notificationJSONB(notification)); err != nil {
-			return clairerror.ErrPutNotifications{opts.NotificationID, err}
+			return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
 		}
 	}
 	err = mBatch.Done(ctx)
 	if err != nil {
-		return clairerror.ErrPutNotifications{opts.NotificationID, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
 	}
 
 	// update known update operations
 	_, err = tx.Exec(ctx, insertUpdateOperation, opts.Updater, opts.UpdateID)
 	if err != nil {
-		return clairerror.ErrPutNotifications{opts.NotificationID, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
 	}
 
 	// create receipt
 	tag, err = tx.Exec(ctx, insertReceipt, opts.NotificationID, opts.UpdateID)
 	if err != nil {
-		return clairerror.ErrPutNotifications{opts.NotificationID, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
 	}
 	if tag.RowsAffected() <= 0 {
-		return clairerror.ErrPutNotifications{opts.NotificationID, fmt.Errorf("no rows affected when creating a receipt")}
+		return clairerror.New(clairerror.Internal, fmt.Sprintf("failed to persist notification associated with id %s: no rows affected when creating a receipt", opts.NotificationID))
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
-		return clairerror.ErrPutNotifications{opts.NotificationID, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to persist notification associated with id %s", opts.NotificationID))
 	}
 	return nil
 }
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
@@ -13,7 +14,8 @@ import (
 
 // receipt returns a receipt for a given notification id
 //
-// if the receipt does not exist a ErrNoReceipt is returned
+// if the receipt does not exist an error of kind clairerror.NotFound is
+// returned
 func receipt(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID) (notifier.Receipt, error) {
 	const (
 		query = `SELECT uo_id, notification_id, status, ts FROM receipt WHERE notification_id = $1`
@@ -29,9 +31,9 @@ func receipt(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID) (notifier.Re
 	)
 	switch {
 	case errors.Is(err, pgx.ErrNoRows):
-		return r, clairerror.ErrNoReceipt{id}
+		return r, clairerror.New(clairerror.NotFound, fmt.Sprintf("no receipt exists for notification id %s", id))
 	case err != nil:
-		return r, clairerror.ErrReceipt{id, err}
+		return r, clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to retrieve receipt for notification id %s", id))
 	}
 
 	return r, nil
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// receiptsAfter returns up to limit receipts with a sequence number greater
+// than seq, skipping deleted ones.
+//
+// Sequence numbers are handed out when the row is written, not when the
+// transaction commits, so a receipt may become visible after one with a
+// greater number. Receipts written in the last few seconds are held back to
+// give their neighbours time to commit; receipts are the last thing
+// putNotifications writes before committing.
+func receiptsAfter(ctx context.Context, pool *pgxpool.Pool, seq int64, limit int) ([]notifier.Receipt, error) {
+	const (
+		query = `SELECT seq, uo_id, notification_id, status, ts FROM receipt
+WHERE seq > $1 AND status <> 'deleted' AND created < clock_timestamp() - '5 seconds'::interval
+ORDER BY seq
+LIMIT $2`
+	)
+
+	rows, err := pool.Query(ctx, query, seq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select receipts: %w", err)
+	}
+	defer rows.Close()
+	rs := []notifier.Receipt{}
+	for rows.Next() {
+		var r notifier.Receipt
+		if err := rows.Scan(&r.Seq, &r.UOID, &r.NotificationID, &r.Status, &r.TS); err != nil {
+			return nil, fmt.Errorf("failed to scan receipt: %w", err)
+		}
+		rs = append(rs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select receipts: %w", err)
+	}
+	return rs, nil
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// TestReceiptsAfter confirms receipts are returned in creation order after
+// the provided sequence number, skipping deleted and very recent ones.
+func TestReceiptsAfter(t *testing.T) {
+	integration.Skip(t)
+	ctx := context.Background()
+	sx, store, _, teardown := TestStore(ctx, t)
+	defer teardown()
+
+	digest, _ := claircore.ParseDigest("sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a")
+	put := func() uuid.UUID {
+		opts := notifier.PutOpts{
+			Updater:        updater,
+			UpdateID:       uuid.New(),
+			NotificationID: uuid.New(),
+			Notifications:  []notifier.Notification{{Manifest: digest, Reason: "added"}},
+		}
+		if err := store.PutNotifications(ctx, opts); err != nil {
+			t.Fatalf("failed to put notifications: %v", err)
+		}
+		return opts.NotificationID
+	}
+	first, deleted, last := put(), put(), put()
+	if err := store.SetDeleted(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := store.ReceiptsAfter(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(rs), 0; got != want {
+		t.Fatalf("got: %d recent receipts, want: %d", got, want)
+	}
+
+	if _, err := sx.ExecContext(ctx, `UPDATE receipt SET created = created - '1 minute'::interval`); err != nil {
+		t.Fatal(err)
+	}
+	rs, err = store.ReceiptsAfter(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(rs), 2; got != want {
+		t.Fatalf("got: %d receipts, want: %d", got, want)
+	}
+	if got, want := rs[0].NotificationID, first; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := rs[1].NotificationID, last; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	rs, err = store.ReceiptsAfter(ctx, rs[0].Seq, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].NotificationID != last {
+		t.Errorf("got: %+v, want: only %v", rs, last)
+	}
+}
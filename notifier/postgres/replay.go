@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// failedReceipts returns the receipts in "delivery_failed" status, oldest
+// first.
+func failedReceipts(ctx context.Context, pool *pgxpool.Pool) ([]notifier.Receipt, error) {
+	const (
+		query = `SELECT uo_id, notification_id, status, ts FROM receipt WHERE status = 'delivery_failed' ORDER BY ts`
+	)
+
+	rows, err := pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select receipts: %w", err)
+	}
+	defer rows.Close()
+	rs := []notifier.Receipt{}
+	for rows.Next() {
+		var r notifier.Receipt
+		if err := rows.Scan(&r.UOID, &r.NotificationID, &r.Status, &r.TS); err != nil {
+			return nil, fmt.Errorf("failed to scan receipt: %w", err)
+		}
+		rs = append(rs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select receipts: %w", err)
+	}
+	return rs, nil
+}
+
+// setCreated will update the receipts' status to "created" for the provided
+// notification ids, unless they've been deleted.
+func setCreated(ctx context.Context, pool *pgxpool.Pool, ids []uuid.UUID) (int64, error) {
+	const (
+		query = `UPDATE receipt SET status = 'created', ts = CURRENT_TIMESTAMP
+WHERE notification_id = ANY($1) AND status IN ('delivered', 'delivery_failed')`
+	)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.String()
+	}
+	tag, err := pool.Exec(ctx, query, s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update receipts: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// TestReplay confirms failed notifications are listed and can be returned to
+// created status, and deleted ones are left alone.
+func TestReplay(t *testing.T) {
+	integration.Skip(t)
+	ctx := context.Background()
+	_, store, _, teardown := TestStore(ctx, t)
+	defer teardown()
+
+	digest, _ := claircore.ParseDigest("sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a")
+	put := func() uuid.UUID {
+		opts := notifier.PutOpts{
+			Updater:        updater,
+			UpdateID:       uuid.New(),
+			NotificationID: uuid.New(),
+			Notifications:  []notifier.Notification{{Manifest: digest, Reason: "added"}},
+		}
+		if err := store.PutNotifications(ctx, opts); err != nil {
+			t.Fatalf("failed to put notifications: %v", err)
+		}
+		return opts.NotificationID
+	}
+	failed, deleted := put(), put()
+	if err := store.SetDeliveryFailed(ctx, failed); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetDeleted(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := store.FailedReceipts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(rs), 1; got != want {
+		t.Fatalf("got: %d failed receipts, want: %d", got, want)
+	}
+	if got, want := rs[0].NotificationID, failed; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	n, err := store.SetCreated(ctx, failed, deleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(1); got != want {
+		t.Errorf("got: %d replayed, want: %d", got, want)
+	}
+	for id, want := range map[uuid.UUID]notifier.Status{
+		failed:  notifier.Created,
+		deleted: notifier.Deleted,
+	} {
+		r, err := store.Receipt(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Status; got != want {
+			t.Errorf("%v: got: %q, want: %q", id, got, want)
+		}
+	}
+}
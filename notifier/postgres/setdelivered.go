@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -17,10 +18,10 @@ func setDelivered(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID) error {
 
 	tag, err := pool.Exec(ctx, query, id.String())
 	if err != nil {
-		return clairerror.ErrReceipt{id, err}
+		return clairerror.Wrap(clairerror.DependencyUnavailable, err, fmt.Sprintf("failed to retrieve receipt for notification id %s", id))
 	}
 	if tag.RowsAffected() <= 0 {
-		return clairerror.ErrNoReceipt{id}
+		return clairerror.New(clairerror.NotFound, fmt.Sprintf("no receipt exists for notification id %s", id))
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -13,6 +14,8 @@ type Store struct {
 	pool *pgxpool.Pool
 }
 
+var _ notifier.Partitioner = (*Store)(nil)
+
 func NewStore(pool *pgxpool.Pool) *Store {
 	return &Store{pool}
 }
@@ -52,6 +55,12 @@ func (s *Store) DeleteNotifications(ctx context.Context, id uuid.UUID) error {
 	return deleteNotifications(ctx, s.pool, id)
 }
 
+// CoalesceNotifications persists the provided notifications under a new
+// notification id and sets the ones they replace to deleted status.
+func (s *Store) CoalesceNotifications(ctx context.Context, opts notifier.CoalesceOpts) error {
+	return coalesceNotifications(ctx, s.pool, opts)
+}
+
 // Receipt returns the Receipt for a given notification id
 func (s *Store) Receipt(ctx context.Context, id uuid.UUID) (notifier.Receipt, error) {
 	return receipt(ctx, s.pool, id)
@@ -91,3 +100,84 @@ func (s *Store) SetDeliveryFailed(ctx context.Context, id uuid.UUID) error {
 func (s *Store) SetDeleted(ctx context.Context, id uuid.UUID) error {
 	return setDeleted(ctx, s.pool, id)
 }
+
+// Claim leases up to limit of the provided notification ids to owner, if
+// they're waiting for delivery and not leased by anyone else.
+func (s *Store) Claim(ctx context.Context, owner string, lease time.Duration, ids []uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return claim(ctx, s.pool, owner, lease, ids, limit)
+}
+
+// Release ends owner's lease on the notification id.
+func (s *Store) Release(ctx context.Context, owner string, id uuid.UUID) error {
+	return release(ctx, s.pool, owner, id)
+}
+
+// FailedReceipts returns the receipts in delivery failed status, oldest
+// first.
+func (s *Store) FailedReceipts(ctx context.Context) ([]notifier.Receipt, error) {
+	return failedReceipts(ctx, s.pool)
+}
+
+// SetCreated returns the provided notification ids to created status, so
+// they're delivered again. Deleted notifications are left alone.
+func (s *Store) SetCreated(ctx context.Context, ids ...uuid.UUID) (int64, error) {
+	return setCreated(ctx, s.pool, ids)
+}
+
+// ReceiptsAfter returns up to limit receipts created after the provided
+// sequence number, in creation order. Deleted receipts are skipped.
+func (s *Store) ReceiptsAfter(ctx context.Context, seq int64, limit int) ([]notifier.Receipt, error) {
+	return receiptsAfter(ctx, s.pool, seq, limit)
+}
+
+// Backlog counts the notification ids waiting on delivery.
+func (s *Store) Backlog(ctx context.Context) (notifier.Backlog, error) {
+	return backlog(ctx, s.pool)
+}
+
+// PruneNotifications removes up to limit notification ids created before
+// the provided time, along with their notifications.
+//
+// If another process is pruning, PruneNotifications does nothing.
+func (s *Store) PruneNotifications(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return pruneNotifications(ctx, s.pool, before, limit)
+}
+
+// PurgeDelivered removes the notifications created for the provided update
+// operation if they've been delivered or deleted.
+func (s *Store) PurgeDelivered(ctx context.Context, uoid uuid.UUID) (int64, error) {
+	return purgeDelivered(ctx, s.pool, uoid)
+}
+
+// EnsurePartitions creates the notification_body partitions needed to hold
+// notifications created from now until the provided time.
+func (s *Store) EnsurePartitions(ctx context.Context, until time.Time) (int, error) {
+	return ensurePartitions(ctx, s.pool, until)
+}
+
+// DropPartitions drops the notification_body partitions holding only
+// notifications created before the provided time.
+func (s *Store) DropPartitions(ctx context.Context, before time.Time) (int, error) {
+	return dropPartitions(ctx, s.pool, before)
+}
+
+// PutAttempt records a delivery attempt.
+func (s *Store) PutAttempt(ctx context.Context, a notifier.Attempt) error {
+	return putAttempt(ctx, s.pool, a)
+}
+
+// Attempts returns the delivery attempts for the provided notification id,
+// oldest first.
+func (s *Store) Attempts(ctx context.Context, id uuid.UUID) ([]notifier.Attempt, error) {
+	return attempts(ctx, s.pool, id)
+}
+
+// IndexEventCursor implements notifier.EventCursor.
+func (s *Store) IndexEventCursor(ctx context.Context) (int64, error) {
+	return indexEventCursor(ctx, s.pool)
+}
+
+// SetIndexEventCursor implements notifier.EventCursor.
+func (s *Store) SetIndexEventCursor(ctx context.Context, seq int64) error {
+	return setIndexEventCursor(ctx, s.pool, seq)
+}
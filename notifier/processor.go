@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/quay/claircore"
@@ -28,6 +29,16 @@ type Processor struct {
 	// NoSummary is a little awkward to use, but reversing the boolean this way
 	// makes the defaults line up better.
 
+	// LayerAttribution controls whether notifications record the layers that
+	// introduced the affected package. This costs an index report lookup per
+	// affected manifest.
+	LayerAttribution bool
+
+	// Annotations controls whether notifications carry the annotations of
+	// their manifest. This costs an annotations lookup per affected
+	// manifest.
+	Annotations bool
+
 	// distributed lock used for mutual exclusion
 	distLock distlock.Locker
 	// a handle to an indexer service
@@ -38,6 +49,9 @@ type Processor struct {
 	store Store
 	// a integer id used for logging
 	id uint8
+
+	drainOnce sync.Once
+	drain     chan struct{}
 }
 
 func NewProcessor(id int, distLock distlock.Locker, indexer indexer.Service, matcher matcher.Service, store Store) *Processor {
@@ -47,35 +61,46 @@ func NewProcessor(id int, distLock distlock.Locker, indexer indexer.Service, mat
 		matcher:  matcher,
 		store:    store,
 		id:       uint8(id),
+		drain:    make(chan struct{}),
 	}
 }
 
+// Drain makes Run return once the event in progress, if any, is processed,
+// instead of taking another one.
+func (p *Processor) Drain() {
+	p.drainOnce.Do(func() { close(p.drain) })
+}
+
 // Process is an async method which receives new UOs as events,
 // creates notifications, persists these notifications,
 // and updates the notifier system with the "latest" seen UOID.
 //
 // Canceling the ctx will end the processing.
 func (p *Processor) Process(ctx context.Context, c <-chan Event) {
-	go p.process(ctx, c)
+	go p.Run(ctx, c)
 }
 
-// process is intended to be ran as a go routine.
-//
-// implements the blocking event loop of a processor.
-func (p *Processor) process(ctx context.Context, c <-chan Event) {
+// Run implements the blocking event loop of a processor. It returns when the
+// ctx is canceled, or when Drain is called and the event in progress is
+// processed.
+func (p *Processor) Run(ctx context.Context, c <-chan Event) {
 	log := zerolog.Ctx(ctx).With().
 		Uint8("processor_id", p.id).
-		Str("component", "notifier/processor/Processor.process").Logger()
+		Str("component", "notifier/processor/Processor.Run").Logger()
 
 	log.Debug().Msg("processing events")
 	for {
 		select {
 		case <-ctx.Done():
 			log.Info().Msg("ctx canceld. ending event processing")
+			return
+		case <-p.drain:
+			log.Info().Msg("draining. ending event processing")
+			return
 		case e := <-c:
 			uoid := e.uo.Ref.String()
 			log := zerolog.Ctx(ctx).With().
-				Str("component", "notifier/processor/Processor.process").
+				Str("component", "notifier/processor/Processor.Run").
 				Str("updater", e.updater).
 				Str("UOID", uoid).
 				Uint8("processor_id", p.id).
@@ -150,39 +175,19 @@ func (p *Processor) create(ctx context.Context, e Event, prev uuid.UUID) error {
 		return nil
 	}
 
-	notifications := []Notification{}
-	create := func(r Reason, affected *claircore.AffectedManifests) error {
-		for manifest, vulns := range affected.VulnerableManifests {
-			digest, err := claircore.ParseDigest(manifest)
-			if err != nil {
-				return err
-			}
-			// The vulns slice is sorted most severe to lease severe.
-			for i := range vulns {
-				vuln := affected.Vulnerabilities[vulns[i]]
-
-				n := Notification{
-					Manifest: digest,
-					Reason:   r,
-				}
-				n.Vulnerability.FromVulnerability(vuln)
-
-				notifications = append(notifications, n)
-
-				if !p.NoSummary {
-					break
-				}
-			}
-		}
-		return nil
-	}
-	err = create(Added, added)
+	notifications, err := diffNotifications(added, removed, !p.NoSummary)
 	if err != nil {
 		return err
 	}
-	err = create(Removed, removed)
-	if err != nil {
-		return err
+	if p.LayerAttribution {
+		if err := p.attributeLayers(ctx, notifications); err != nil {
+			return fmt.Errorf("failed to attribute layers: %v", err)
+		}
+	}
+	if p.Annotations {
+		if err := p.attachAnnotations(ctx, notifications); err != nil {
+			return fmt.Errorf("failed to attach annotations: %v", err)
+		}
 	}
 	opts := PutOpts{
 		Updater:        e.updater,
@@ -214,10 +219,9 @@ func (p *Processor) safe(ctx context.Context, e Event) (bool, uuid.UUID) {
 		Logger()
 
 	// confirm we are not making duplicate notifications
-	var errNoReceipt clairerror.ErrNoReceipt
 	_, err := p.store.ReceiptByUOID(ctx, e.uo.Ref)
 	switch {
-	case errors.As(err, &errNoReceipt):
+	case errors.Is(err, clairerror.NotFound):
 		// hop out of switch
 	case err != nil:
 		log.Error().Err(err).Msg("received error getting receipt by UOID")
@@ -259,3 +263,87 @@ func (p *Processor) safe(ctx context.Context, e Event) (bool, uuid.UUID) {
 	}
 	return true, prev.Ref
 }
+
+// DiffNotifications creates notifications for the manifests affected by the
+// added and removed vulnerabilities.
+//
+// A vulnerability both removed from and added to a manifest was replaced by a
+// new version of itself, and is reported once with the "changed" reason and
+// a Change describing the difference. If summary is set, only the most severe
+// vulnerability for each manifest and reason is reported.
+func diffNotifications(added, removed *claircore.AffectedManifests, summary bool) ([]Notification, error) {
+	notifications := []Notification{}
+	type seen struct {
+		manifest string
+		reason   Reason
+	}
+	summarized := make(map[seen]bool)
+	emit := func(manifest string, n Notification) {
+		k := seen{manifest: manifest, reason: n.Reason}
+		if summary && summarized[k] {
+			return
+		}
+		summarized[k] = true
+		notifications = append(notifications, n)
+	}
+	type pair struct {
+		manifest string
+		vuln     string
+	}
+	// Previous versions of vulnerabilities, by manifest.
+	prev := make(map[pair]*claircore.Vulnerability)
+	for manifest, vulns := range removed.VulnerableManifests {
+		for _, id := range vulns {
+			v := removed.Vulnerabilities[id]
+			k := pair{manifest: manifest, vuln: vulnKey(v)}
+			if _, ok := prev[k]; !ok {
+				prev[k] = v
+			}
+		}
+	}
+	replaced := make(map[pair]bool)
+
+	// The vulns slices are sorted most severe to least severe.
+	for manifest, vulns := range added.VulnerableManifests {
+		digest, err := claircore.ParseDigest(manifest)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range vulns {
+			v := added.Vulnerabilities[id]
+			n := Notification{
+				Manifest: digest,
+				Reason:   Added,
+			}
+			n.Vulnerability.FromVulnerability(v)
+			k := pair{manifest: manifest, vuln: vulnKey(v)}
+			if old, ok := prev[k]; ok && !replaced[k] {
+				replaced[k] = true
+				n.Reason = Changed
+				n.Change = updatedChange(old, v)
+			} else {
+				n.Change = introducedChange(v)
+			}
+			emit(manifest, n)
+		}
+	}
+	for manifest, vulns := range removed.VulnerableManifests {
+		digest, err := claircore.ParseDigest(manifest)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range vulns {
+			v := removed.Vulnerabilities[id]
+			if replaced[pair{manifest: manifest, vuln: vulnKey(v)}] {
+				continue
+			}
+			n := Notification{
+				Manifest: digest,
+				Reason:   Removed,
+			}
+			n.Vulnerability.FromVulnerability(v)
+			emit(manifest, n)
+		}
+	}
+	return notifications, nil
+}
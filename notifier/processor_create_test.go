@@ -52,6 +52,10 @@ var (
 				Name:        affectedManifestsAdd.Vulnerabilities[vulnAdd.ID].Name,
 				Severity:    claircore.Unknown.String(),
 			},
+			Change: &Change{
+				Kinds:    []ChangeKind{Introduced},
+				Severity: claircore.Unknown.String(),
+			},
 		},
 		{
 			Manifest: claircore.MustParseDigest(manifestRemoved),
@@ -50,7 +50,7 @@ func testSafe(t *testing.T) {
 	ctx := context.TODO()
 	sm := &MockStore{
 		ReceiptByUOID_: func(ctx context.Context, id uuid.UUID) (Receipt, error) {
-			return Receipt{}, clairerror.ErrNoReceipt{}
+			return Receipt{}, clairerror.New(clairerror.NotFound, "no receipt")
 		},
 	}
 	mm := &matcher.Mock{
@@ -106,7 +106,7 @@ func testUnsafeMatcherErr(t *testing.T) {
 	ctx := context.TODO()
 	sm := &MockStore{
 		ReceiptByUOID_: func(ctx context.Context, id uuid.UUID) (Receipt, error) {
-			return Receipt{}, clairerror.ErrNoReceipt{}
+			return Receipt{}, clairerror.New(clairerror.NotFound, "no receipt")
 		},
 	}
 	mm := &matcher.Mock{
@@ -136,7 +136,7 @@ func testUnsafeStaleUOID(t *testing.T) {
 	ctx := context.TODO()
 	sm := &MockStore{
 		ReceiptByUOID_: func(ctx context.Context, id uuid.UUID) (Receipt, error) {
-			return Receipt{}, clairerror.ErrNoReceipt{}
+			return Receipt{}, clairerror.New(clairerror.NotFound, "no receipt")
 		},
 	}
 	mm := &matcher.Mock{
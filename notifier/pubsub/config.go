@@ -0,0 +1,107 @@
+package pubsub
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/quay/clair/v4/internal/gcp"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// DefaultEndpoint is the Pub/Sub API root used if one is not configured.
+const DefaultEndpoint = "https://pubsub.googleapis.com/"
+
+// Config provides configuration for a Pub/Sub deliverer.
+type Config struct {
+	// Configures the Pub/Sub delivery to deliver notifications directly to
+	// the configured Topic.
+	//
+	// If true "Callback" is ignored.
+	// If false a notifier.Callback is delivered to the topic and clients
+	// utilize the pagination API to retrieve.
+	Direct bool `yaml:"direct"`
+	// Specifies the number of notifications delivered in single Pub/Sub message
+	// when Direct is true.
+	//
+	// Ignored if Direct is not true
+	// If 0 or 1 is provided no rollup occurs and each notification is delivered
+	// separately.
+	Rollup int `yaml:"rollup"`
+	// The callback url where notifications are retrieved.
+	Callback string `yaml:"callback"`
+	callback url.URL
+	// The GCP project the topic belongs to.
+	Project string `yaml:"project"`
+	// The topic notifications will be published to.
+	// The topic must already exist.
+	Topic string `yaml:"topic"`
+	// The filesystem path where a service account key, in the JSON format
+	// issued by GCP, can be read.
+	//
+	// If empty, credentials are obtained from the metadata server, which
+	// supports GCE service accounts and GKE workload identity.
+	CredentialsFile string `yaml:"credentials_file"`
+	credentials     *gcp.ServiceAccount
+	// The Pub/Sub API root. Mostly useful for emulators and testing.
+	Endpoint string `yaml:"endpoint"`
+	endpoint *url.URL
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+}
+
+// Validate confirms configuration is valid and fills in private members
+// with parsed values on success.
+func (c *Config) Validate() (Config, error) {
+	conf := *c
+	if c.Project == "" {
+		return conf, fmt.Errorf("pubsub config requires the project field")
+	}
+	if c.Topic == "" {
+		return conf, fmt.Errorf("pubsub config requires the topic field")
+	}
+
+	if !c.Direct {
+		callback, err := url.Parse(c.Callback)
+		if err != nil {
+			return conf, fmt.Errorf("failed to parse callback url")
+		}
+		conf.callback = *callback
+	}
+
+	ep := c.Endpoint
+	if ep == "" {
+		ep = DefaultEndpoint
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return conf, fmt.Errorf("failed to parse endpoint url: %v", err)
+	}
+	conf.endpoint = u
+
+	if c.CredentialsFile != "" {
+		b, err := ioutil.ReadFile(c.CredentialsFile)
+		if err != nil {
+			return conf, fmt.Errorf("failed to read credentials file: %v", err)
+		}
+		sa, err := gcp.ParseServiceAccount(b)
+		if err != nil {
+			return conf, err
+		}
+		conf.credentials = sa
+	}
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+	return conf, nil
+}
+
+// publishURL reports the URL for the topic's publish method.
+func (c *Config) publishURL() (*url.URL, error) {
+	return c.endpoint.Parse(fmt.Sprintf("v1/projects/%s/topics/%s:publish",
+		url.PathEscape(c.Project), url.PathEscape(c.Topic)))
+}
@@ -0,0 +1,139 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/google/uuid"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/internal/gcp"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer is a Pub/Sub deliverer which publishes a notifier.Callback to the
+// configured topic.
+type Deliverer struct {
+	conf Config
+	pub  *publisher
+}
+
+// New returns a new Pub/Sub Deliverer.
+//
+// If client is nil, http.DefaultClient is used.
+func New(conf Config, client *http.Client) (*Deliverer, error) {
+	var c Config
+	var err error
+	if c, err = conf.Validate(); err != nil {
+		return nil, err
+	}
+	pub, err := newPublisher(c, client)
+	if err != nil {
+		return nil, err
+	}
+	return &Deliverer{
+		conf: c,
+		pub:  pub,
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return fmt.Sprintf("pubsub-%s", d.conf.Topic)
+}
+
+// Deliver implements the notifier.Deliverer interface.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	callback := d.conf.callback
+	callback.Path = path.Join(callback.Path, nID.String())
+	d.conf.Filter.SetQuery(&callback)
+
+	cb := notifier.Callback{
+		NotificationID: nID,
+		Callback:       callback,
+	}
+	b, err := json.Marshal(&cb)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	if err := d.pub.Publish(ctx, nID, b); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	return nil
+}
+
+// Message is a Pub/Sub message as described by the REST API.
+type message struct {
+	Data       string            `json:"data"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Publisher does the actual API calls to Pub/Sub.
+type publisher struct {
+	c   *http.Client
+	ts  *gcp.TokenSource
+	url string
+}
+
+func newPublisher(conf Config, client *http.Client) (*publisher, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u, err := conf.publishURL()
+	if err != nil {
+		return nil, err
+	}
+	return &publisher{
+		c:   client,
+		url: u.String(),
+		ts:  gcp.NewTokenSource(client, conf.credentials, gcp.PubSubScope),
+	}, nil
+}
+
+// Publish sends the provided payloads as a single publish call, so they're
+// accepted or rejected together.
+func (p *publisher) Publish(ctx context.Context, nID uuid.UUID, data ...[]byte) error {
+	var req struct {
+		Messages []message `json:"messages"`
+	}
+	for _, b := range data {
+		req.Messages = append(req.Messages, message{
+			Data: base64.StdEncoding.EncodeToString(b),
+			Attributes: map[string]string{
+				"notification_id": nID.String(),
+				"content_type":    "application/json",
+				"app_id":          "clairV4-notifier",
+			},
+		})
+	}
+	b, err := json.Marshal(&req)
+	if err != nil {
+		return err
+	}
+	tok, err := p.ts.Token(ctx)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer "+tok)
+	res, err := p.c.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return &clairerror.ErrRequestFail{
+			Code:   res.StatusCode,
+			Status: res.Status,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+const callback = "http://clair-notifier/notifier/api/v1/notification"
+
+// fakePubSub is a minimal Pub/Sub API and token endpoint.
+type fakePubSub struct {
+	sync.Mutex
+	key      *rsa.PrivateKey
+	tokens   int
+	messages []message
+}
+
+func (f *fakePubSub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	switch {
+	case r.URL.Path == "/token":
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		tok, err := jwt.ParseSigned(r.Form.Get("assertion"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var cl jwt.Claims
+		if err := tok.Claims(&f.key.PublicKey, &cl); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.tokens++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token",
+			"expires_in":   3600,
+		})
+	case strings.HasSuffix(r.URL.Path, "/topics/notifications:publish"):
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Messages []message `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.messages = append(f.messages, req.Messages...)
+		w.Write([]byte(`{"messageIds":[]}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// setup returns a fake Pub/Sub service, a Config pointing at it, and a
+// function to clean up after the test.
+func setup(t *testing.T) (*fakePubSub, Config, func()) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakePubSub{key: key}
+	srv := httptest.NewServer(f)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(map[string]string{
+		"client_email": "clair@example.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    srv.URL + "/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "pubsub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+	credFile := filepath.Join(dir, "key.json")
+	if err := ioutil.WriteFile(credFile, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return f, Config{
+		Project:         "clair",
+		Topic:           "notifications",
+		Callback:        callback,
+		CredentialsFile: credFile,
+		Endpoint:        srv.URL + "/",
+	}, cleanup
+}
+
+func TestDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, conf, cleanup := setup(t)
+	defer cleanup()
+	d, err := New(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nID := uuid.New()
+	for i := 0; i < 2; i++ {
+		if err := d.Deliver(ctx, nID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	if got, want := f.tokens, 1; got != want {
+		t.Errorf("got: %d tokens minted, want: %d", got, want)
+	}
+	if got, want := len(f.messages), 2; got != want {
+		t.Fatalf("got: %d messages, want: %d", got, want)
+	}
+	b, err := base64.StdEncoding.DecodeString(f.messages[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cb notifier.Callback
+	if err := json.Unmarshal(b, &cb); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cb.NotificationID, nID; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := cb.Callback.String(), callback+"/"+nID.String(); got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := f.messages[0].Attributes["notification_id"], nID.String(); got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestDirectDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, conf, cleanup := setup(t)
+	defer cleanup()
+	conf.Direct = true
+	conf.Rollup = 2
+	d, err := NewDirectDeliverer(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := make([]notifier.Notification, 5)
+	for i := range ns {
+		ns[i].ID = uuid.New()
+	}
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	// 5 notifications with a rollup of 2 should be 3 messages.
+	if got, want := len(f.messages), 3; got != want {
+		t.Fatalf("got: %d messages, want: %d", got, want)
+	}
+	var total int
+	for _, m := range f.messages {
+		b, err := base64.StdEncoding.DecodeString(m.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []json.RawMessage
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		total += len(got)
+	}
+	if got, want := total, len(ns); got != want {
+		t.Errorf("got: %d notifications, want: %d", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RateLimiter bounds how many deliveries per second are made to each
+// target, shared by every Delivery using it. Receivers such as webhooks
+// often rate limit their callers, and deliveries refused that way only come
+// back around on the next tick.
+//
+// Deliveries waiting on the limit keep their claim on the notification id,
+// so a RateLimiter doesn't let other replicas deliver it sooner.
+type RateLimiter struct {
+	rate  float64
+	burst int
+
+	mu      sync.Mutex
+	targets map[string]*time.Time
+
+	queued    metric.Int64UpDownCounter
+	throttled metric.Int64Counter
+	deferred  metric.Int64Counter
+}
+
+// NewRateLimiter returns a RateLimiter allowing rate deliveries per second
+// to each target, with bursts of up to burst deliveries. A burst less than 1
+// is treated as 1.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	meter := metric.Must(otel.Meter("clair"))
+	return &RateLimiter{
+		rate:    rate,
+		burst:   burst,
+		targets: make(map[string]*time.Time),
+		queued: meter.NewInt64UpDownCounter(
+			"clair_notifier_delivery_queued",
+			metric.WithDescription("number of deliveries waiting on a target's rate limit"),
+		),
+		throttled: meter.NewInt64Counter(
+			"clair_notifier_delivery_throttled_total",
+			metric.WithDescription("number of deliveries that waited on a target's rate limit"),
+		),
+		deferred: meter.NewInt64Counter(
+			"clair_notifier_delivery_deferred_total",
+			metric.WithDescription("number of deliveries left for later because the rate limit outlasted their claim"),
+		),
+	}
+}
+
+// Target returns the key deliveries by d are limited under: its target, if
+// it reports one, or else its name.
+func target(d Deliverer) string {
+	if t, ok := d.(Targeter); ok {
+		if s := t.Target(); s != "" {
+			return s
+		}
+	}
+	return d.Name()
+}
+
+// Wait blocks until a delivery to the target fits in the rate limit,
+// reporting false without waiting if that's after the deadline. A zero
+// deadline means there is none.
+func (l *RateLimiter) Wait(ctx context.Context, target string, deadline time.Time) (bool, error) {
+	l.mu.Lock()
+	now := time.Now()
+	next, ok := l.targets[target]
+	if !ok {
+		next = new(time.Time)
+		l.targets[target] = next
+	}
+	// The schedule may lag behind the present by a burst's worth of
+	// deliveries; anything older is forgotten.
+	interval := time.Duration(float64(time.Second) / l.rate)
+	if floor := now.Add(-time.Duration(l.burst-1) * interval); next.Before(floor) {
+		*next = floor
+	}
+	d := next.Sub(now)
+	if d > 0 && !deadline.IsZero() && now.Add(d).After(deadline) {
+		l.mu.Unlock()
+		l.deferred.Add(ctx, 1)
+		return false, nil
+	}
+	*next = next.Add(interval)
+	l.mu.Unlock()
+	if d <= 0 {
+		return true, nil
+	}
+
+	zerolog.Ctx(ctx).Debug().
+		Str("component", "notifier/RateLimiter.Wait").
+		Str("target", target).
+		Dur("wait", d).
+		Msg("waiting on rate limit")
+	l.throttled.Add(ctx, 1)
+	l.queued.Add(ctx, 1)
+	defer l.queued.Add(ctx, -1)
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
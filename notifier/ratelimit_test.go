@@ -0,0 +1,53 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewRateLimiter(20, 2)
+
+	// The burst goes through at once.
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		ok, err := l.Wait(ctx, "a", time.Time{})
+		if err != nil || !ok {
+			t.Fatalf("got: %v, %v", ok, err)
+		}
+	}
+	if d := time.Since(start); d > 25*time.Millisecond {
+		t.Errorf("burst waited %v", d)
+	}
+
+	// The next one waits its turn.
+	ok, err := l.Wait(ctx, "a", time.Time{})
+	if err != nil || !ok {
+		t.Fatalf("got: %v, %v", ok, err)
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("waited %v, want at least 50ms", d)
+	}
+
+	// Other targets have their own limit.
+	start = time.Now()
+	if ok, err := l.Wait(ctx, "b", time.Time{}); err != nil || !ok {
+		t.Fatalf("got: %v, %v", ok, err)
+	}
+	if d := time.Since(start); d > 25*time.Millisecond {
+		t.Errorf("other target waited %v", d)
+	}
+
+	// A wait past the deadline doesn't happen, and doesn't use up a turn.
+	l.Wait(ctx, "b", time.Time{})
+	ok, err = l.Wait(ctx, "b", time.Now().Add(time.Millisecond))
+	if err != nil || ok {
+		t.Errorf("got: %v, %v, want: false, <nil>", ok, err)
+	}
+	ok, err = l.Wait(ctx, "b", time.Now().Add(time.Second))
+	if err != nil || !ok {
+		t.Errorf("got: %v, %v", ok, err)
+	}
+}
@@ -30,4 +30,7 @@ type Receipt struct {
 	Status Status
 	// the timestamp of the last status update
 	TS time.Time
+	// the receipt's position in the order receipts were created, used to
+	// resume streams of notifications
+	Seq int64
 }
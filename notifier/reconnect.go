@@ -0,0 +1,86 @@
+package notifier
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// These are the defaults used for zero values in Reconnect.
+const (
+	DefaultReconnectBackoff    = time.Second
+	DefaultReconnectMaxBackoff = time.Minute
+)
+
+// ErrBackoff is returned by Backoff.Allow while waiting out a backoff.
+var ErrBackoff = errors.New("waiting to reconnect after failed attempt")
+
+// Reconnect configures backoff between failed attempts to connect to a
+// broker, so that an unreachable broker isn't hammered by every delivery.
+type Reconnect struct {
+	// Backoff is the wait after the first failed attempt. It doubles with
+	// every consecutive failure, up to MaxBackoff.
+	Backoff    time.Duration `yaml:"backoff" json:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff" json:"max_backoff"`
+}
+
+// Backoff tracks failed connection attempts per a Reconnect.
+//
+// The zero value uses the defaults. Backoff is safe for concurrent use.
+type Backoff struct {
+	conf Reconnect
+
+	mu       sync.Mutex
+	failures int
+	next     time.Time
+}
+
+// NewBackoff returns a Backoff configured by r, which may be nil.
+func NewBackoff(r *Reconnect) *Backoff {
+	b := &Backoff{}
+	if r != nil {
+		b.conf = *r
+	}
+	if b.conf.Backoff <= 0 {
+		b.conf.Backoff = DefaultReconnectBackoff
+	}
+	if b.conf.MaxBackoff <= 0 {
+		b.conf.MaxBackoff = DefaultReconnectMaxBackoff
+	}
+	return b
+}
+
+// Allow reports ErrBackoff if a connection attempt shouldn't be made yet.
+func (b *Backoff) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().Before(b.next) {
+		return ErrBackoff
+	}
+	return nil
+}
+
+// Failed records a failed attempt and returns the time until the next one
+// is allowed.
+func (b *Backoff) Failed() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wait := b.conf.Backoff
+	for i := 0; i < b.failures && wait < b.conf.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > b.conf.MaxBackoff {
+		wait = b.conf.MaxBackoff
+	}
+	b.failures++
+	b.next = time.Now().Add(wait)
+	return wait
+}
+
+// Succeeded records a successful attempt, resetting the backoff.
+func (b *Backoff) Succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.next = time.Time{}
+}
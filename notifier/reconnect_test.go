@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := NewBackoff(&Reconnect{Backoff: time.Second, MaxBackoff: 3 * time.Second})
+	if err := b.Allow(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		if got := b.Failed(); got != want {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	}
+	if err := b.Allow(); err != ErrBackoff {
+		t.Errorf("got: %v, want: %v", err, ErrBackoff)
+	}
+	b.Succeeded()
+	if err := b.Allow(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got, want := b.Failed(), time.Second; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
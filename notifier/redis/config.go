@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// DefaultMaxLen is the approximate number of entries a stream is trimmed to
+// if a length is not configured.
+const DefaultMaxLen = 10000
+
+// Config provides configuration for a Redis Streams deliverer.
+type Config struct {
+	// The Redis server to connect to, e.g.
+	// "redis://:password@localhost:6379/0". Use the "rediss" scheme for TLS.
+	URL  string `yaml:"url"`
+	opts *redis.Options
+	// The stream entries are added to.
+	Stream string `yaml:"stream"`
+	// The approximate number of entries the stream is trimmed to when adding
+	// one, so that it doesn't grow without bound when nothing consumes it.
+	//
+	// If 0, DefaultMaxLen is used. A negative number disables trimming.
+	MaxLen int64 `yaml:"max_len"`
+	// Configures the deliverer to add notifications directly to the stream.
+	//
+	// If true "Callback" is ignored.
+	// If false a notifier.Callback is added to the stream and clients
+	// utilize the pagination API to retrieve.
+	Direct bool `yaml:"direct"`
+	// Specifies the number of notifications in a single stream entry when
+	// Direct is true.
+	//
+	// Ignored if Direct is not true
+	// If 0 or 1 is provided no rollup occurs and each notification is added
+	// separately.
+	Rollup int `yaml:"rollup"`
+	// The callback url where notifications are retrieved.
+	Callback string `yaml:"callback"`
+	callback url.URL
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+}
+
+// Validate confirms configuration is valid and fills in private members
+// with parsed values on success.
+func (c *Config) Validate() (Config, error) {
+	conf := *c
+	if c.URL == "" {
+		return conf, fmt.Errorf("redis config requires the url field")
+	}
+	opts, err := redis.ParseURL(c.URL)
+	if err != nil {
+		return conf, fmt.Errorf("failed to parse redis url: %v", err)
+	}
+	conf.opts = opts
+	if c.Stream == "" {
+		return conf, fmt.Errorf("redis config requires the stream field")
+	}
+	switch {
+	case c.MaxLen == 0:
+		conf.MaxLen = DefaultMaxLen
+	case c.MaxLen < 0:
+		conf.MaxLen = 0
+	}
+
+	if !c.Direct {
+		callback, err := url.Parse(c.Callback)
+		if err != nil {
+			return conf, fmt.Errorf("failed to parse callback url")
+		}
+		conf.callback = *callback
+	}
+
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+	return conf, nil
+}
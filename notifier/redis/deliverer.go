@@ -0,0 +1,114 @@
+// Package redis delivers notifications to a Redis stream, for deployments
+// that already run Redis but no message broker.
+//
+// Every entry has a "notification_id" field and a "data" field holding JSON:
+// a notifier.Callback, or for direct delivery, an array of notifications.
+// Index events instead have an "index_event" field holding the event's
+// sequence number, and the event as "data". Consumers read the stream with
+// XREAD or a consumer group.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer/events"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer adds a notifier.Callback to the configured stream.
+type Deliverer struct {
+	conf Config
+	c    *redis.Client
+}
+
+// New returns a new Redis Deliverer.
+func New(conf Config) (*Deliverer, error) {
+	c, err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &Deliverer{
+		conf: c,
+		c:    redis.NewClient(c.opts),
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return fmt.Sprintf("redis-%s", d.conf.Stream)
+}
+
+// Target implements notifier.Targeter.
+func (d *Deliverer) Target() string {
+	return target(&d.conf)
+}
+
+// Deliver implements the notifier.Deliverer interface.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	callback := d.conf.callback
+	callback.Path = path.Join(callback.Path, nID.String())
+	d.conf.Filter.SetQuery(&callback)
+
+	cb := notifier.Callback{
+		NotificationID: nID,
+		Callback:       callback,
+	}
+	b, err := json.Marshal(&cb)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	if err := d.c.XAdd(ctx, xaddArgs(&d.conf, nID, b)).Err(); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	return nil
+}
+
+// DeliverIndexEvent implements notifier.EventDeliverer.
+func (d *Deliverer) DeliverIndexEvent(ctx context.Context, ev *events.Event) error {
+	return deliverIndexEvent(ctx, d.c, &d.conf, ev)
+}
+
+// DeliverIndexEvent adds the index event to the configured stream.
+func deliverIndexEvent(ctx context.Context, c *redis.Client, conf *Config, ev *events.Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	args := &redis.XAddArgs{
+		Stream:       conf.Stream,
+		MaxLenApprox: conf.MaxLen,
+		Values: []interface{}{
+			"index_event", strconv.FormatInt(ev.Seq, 10),
+			"data", b,
+		},
+	}
+	if err := c.XAdd(ctx, args).Err(); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	return nil
+}
+
+// XaddArgs returns the arguments for adding an entry to the configured
+// stream.
+func xaddArgs(conf *Config, nID uuid.UUID, data []byte) *redis.XAddArgs {
+	return &redis.XAddArgs{
+		Stream:       conf.Stream,
+		MaxLenApprox: conf.MaxLen,
+		Values: []interface{}{
+			"notification_id", nID.String(),
+			"data", data,
+		},
+	}
+}
+
+// Target reports the server and stream, without any credentials.
+func target(conf *Config) string {
+	return fmt.Sprintf("redis://%s/%d/%s", conf.opts.Addr, conf.opts.DB, conf.Stream)
+}
@@ -0,0 +1,238 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+// FakeServer speaks just enough RESP to accept XADD commands, alone or in a
+// transaction.
+type fakeServer struct {
+	l net.Listener
+
+	mu      sync.Mutex
+	entries [][]string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeServer{l: l}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f
+}
+
+func (f *fakeServer) URL() string { return "redis://" + f.l.Addr().String() + "/0" }
+
+func (f *fakeServer) Close() { f.l.Close() }
+
+// Entries returns the arguments of every XADD executed.
+func (f *fakeServer) Entries() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.entries...)
+}
+
+func (f *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	var queued [][]string
+	inTx := false
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		switch name := strings.ToUpper(cmd[0]); {
+		case name == "MULTI":
+			inTx, queued = true, nil
+			fmt.Fprint(c, "+OK\r\n")
+		case name == "EXEC":
+			fmt.Fprintf(c, "*%d\r\n", len(queued))
+			for _, q := range queued {
+				f.xadd(c, q)
+			}
+			inTx = false
+		case inTx:
+			queued = append(queued, cmd)
+			fmt.Fprint(c, "+QUEUED\r\n")
+		case name == "XADD":
+			f.xadd(c, cmd)
+		case name == "PING":
+			fmt.Fprint(c, "+PONG\r\n")
+		default:
+			fmt.Fprintf(c, "-ERR unknown command %q\r\n", cmd[0])
+		}
+	}
+}
+
+func (f *fakeServer) xadd(w io.Writer, cmd []string) {
+	f.mu.Lock()
+	f.entries = append(f.entries, cmd[1:])
+	id := fmt.Sprintf("%d-0", len(f.entries))
+	f.mu.Unlock()
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(id), id)
+}
+
+// ReadCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, n)
+	for i := range out {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sz, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, sz+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		out[i] = string(b[:sz])
+	}
+	return out, nil
+}
+
+// Fields returns the entry's fields, after the stream name and trimming
+// arguments.
+func fields(t *testing.T, entry []string) map[string]string {
+	t.Helper()
+	i := 0
+	for ; i < len(entry) && entry[i] != "*"; i++ {
+	}
+	if i == len(entry) {
+		t.Fatalf("no id in entry: %q", entry)
+	}
+	out := make(map[string]string)
+	for kv := entry[i+1:]; len(kv) >= 2; kv = kv[2:] {
+		out[kv[0]] = kv[1]
+	}
+	return out
+}
+
+func TestDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv := newFakeServer(t)
+	defer srv.Close()
+
+	d, err := New(Config{
+		URL:      srv.URL(),
+		Stream:   "clair",
+		Callback: "http://clair-notifier/notifier/api/v1/notification/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	if err := d.Deliver(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	es := srv.Entries()
+	if len(es) != 1 {
+		t.Fatalf("got: %d entries, want: 1", len(es))
+	}
+	want := []string{"clair", "maxlen", "~", strconv.Itoa(DefaultMaxLen)}
+	for i := range want {
+		if got := es[0][i]; !strings.EqualFold(got, want[i]) {
+			t.Errorf("argument %d: got: %q, want: %q", i, got, want[i])
+		}
+	}
+	fs := fields(t, es[0])
+	if got, want := fs["notification_id"], id.String(); got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	var cb notifier.Callback
+	if err := json.Unmarshal([]byte(fs["data"]), &cb); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cb.Callback.String(), "http://clair-notifier/notifier/api/v1/notification/"+id.String(); got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestDirectDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv := newFakeServer(t)
+	defer srv.Close()
+
+	d, err := NewDirectDeliverer(Config{
+		URL:    srv.URL(),
+		Stream: "clair",
+		MaxLen: -1,
+		Direct: true,
+		Rollup: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	ns := make([]notifier.Notification, 5)
+	for i := range ns {
+		ns[i] = notifier.Notification{ID: uuid.New(), Manifest: m, Reason: notifier.Added}
+	}
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	if err := d.Deliver(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	es := srv.Entries()
+	if len(es) != 3 {
+		t.Fatalf("got: %d entries, want: 3", len(es))
+	}
+	var got int
+	for _, e := range es {
+		if strings.EqualFold(e[1], "maxlen") {
+			t.Errorf("unexpected trimming: %q", e)
+		}
+		fs := fields(t, e)
+		if fs["notification_id"] != id.String() {
+			t.Errorf("got: %q, want: %q", fs["notification_id"], id)
+		}
+		var block []notifier.Notification
+		if err := json.Unmarshal([]byte(fs["data"]), &block); err != nil {
+			t.Fatal(err)
+		}
+		got += len(block)
+	}
+	if got != len(ns) {
+		t.Errorf("got: %d notifications, want: %d", got, len(ns))
+	}
+}
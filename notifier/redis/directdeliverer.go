@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer/events"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// DirectDeliverer adds notifications directly to the configured stream.
+type DirectDeliverer struct {
+	conf Config
+	c    *redis.Client
+	n    []notifier.Notification
+}
+
+// NewDirectDeliverer returns a new Redis DirectDeliverer.
+func NewDirectDeliverer(conf Config) (*DirectDeliverer, error) {
+	c, err := conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &DirectDeliverer{
+		conf: c,
+		c:    redis.NewClient(c.opts),
+		n:    []notifier.Notification{},
+	}, nil
+}
+
+func (d *DirectDeliverer) Name() string {
+	return fmt.Sprintf("redis-direct-%s", d.conf.Stream)
+}
+
+// Target implements notifier.Targeter.
+func (d *DirectDeliverer) Target() string {
+	return target(&d.conf)
+}
+
+// Notifications will copy the provided notifications into a buffer for Redis
+// delivery.
+func (d *DirectDeliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	// if we can reslice instead of allocate do so.
+	if len(n) <= len(d.n) {
+		d.n = d.n[:len(n)]
+		copy(d.n, n)
+		return nil
+	}
+	tmp := make([]notifier.Notification, len(n), len(n))
+	copy(tmp, n)
+	d.n = tmp
+	return nil
+}
+
+// Deliver implements the notifier.Deliverer interface.
+//
+// Every block is added in a single transaction, so either all of them are
+// delivered or none are.
+func (d *DirectDeliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	if len(d.n) == 0 {
+		return nil
+	}
+	// block loop adding smaller blocks of max(rollup) length via reslicing.
+	var rollup int = d.conf.Rollup
+	if rollup == 0 {
+		rollup++
+	}
+
+	_, err := d.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for bs, be := 0, rollup; bs < len(d.n); bs, be = be, be+rollup {
+			// if block-end exceeds array bounds, slice block underflow.
+			// next block-start will cause loop to exit.
+			if be > len(d.n) {
+				be = len(d.n)
+			}
+			currentBlock := d.n[bs:be]
+			b, err := json.Marshal(&currentBlock)
+			if err != nil {
+				return err
+			}
+			p.XAdd(ctx, xaddArgs(&d.conf, nID, b))
+		}
+		return nil
+	})
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	return nil
+}
+
+// DeliverIndexEvent implements notifier.EventDeliverer.
+func (d *DirectDeliverer) DeliverIndexEvent(ctx context.Context, ev *events.Event) error {
+	return deliverIndexEvent(ctx, d.c, &d.conf, ev)
+}
@@ -0,0 +1,170 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// DefaultRetentionInterval is the default time between pruning runs.
+	DefaultRetentionInterval = time.Hour
+	// PruneBatchSize is the number of notification ids removed in a single
+	// call to Pruner.PruneNotifications.
+	PruneBatchSize = 500
+	// PartitionsAhead is how far ahead of time partitions are created for
+	// stores implementing Partitioner.
+	PartitionsAhead = 7 * 24 * time.Hour
+)
+
+// Pruner implements removal of notifications that are no longer wanted.
+//
+// Implementations must not forget that notifications were created for the
+// latest update operation of an updater, or the Poller would create them
+// again.
+type Pruner interface {
+	// PruneNotifications removes up to limit notification ids created
+	// before the provided time, along with their notifications, and reports
+	// how many were removed.
+	//
+	// If another process is pruning, PruneNotifications does nothing.
+	PruneNotifications(ctx context.Context, before time.Time, limit int) (int64, error)
+	// PurgeDelivered removes the notifications created for the provided
+	// update operation if they've been delivered or deleted, and reports
+	// how many notification ids were removed.
+	PurgeDelivered(ctx context.Context, uoid uuid.UUID) (int64, error)
+}
+
+// Partitioner is implemented by stores keeping notification bodies in
+// time-based partitions, which must be created before notifications are put
+// in them and are cheaper to drop whole than to prune row by row. Only the
+// bodies are partitioned; notification ids and receipts are always pruned
+// row by row.
+type Partitioner interface {
+	// EnsurePartitions creates the partitions needed to hold notifications
+	// created from now until the provided time, and reports how many were
+	// created.
+	EnsurePartitions(ctx context.Context, until time.Time) (int, error)
+	// DropPartitions drops the partitions holding only notifications
+	// created before the provided time, and reports how many were dropped.
+	//
+	// The notification ids and receipts of dropped notifications are left
+	// for PruneNotifications.
+	DropPartitions(ctx context.Context, before time.Time) (int, error)
+}
+
+// Retention periodically prunes notifications older than a maximum age.
+//
+// If the store is a Partitioner, Retention also keeps partitions created
+// ahead of time, even without a maximum age, and drops expired partitions
+// before pruning.
+type Retention struct {
+	store    Pruner
+	maxAge   time.Duration
+	interval time.Duration
+
+	pruned metric.Int64Counter
+}
+
+// NewRetention returns a Retention removing notifications older than maxAge
+// every interval. If maxAge is 0, nothing is removed.
+func NewRetention(maxAge, interval time.Duration, store Pruner) *Retention {
+	if interval <= 0 {
+		interval = DefaultRetentionInterval
+	}
+	meter := metric.Must(otel.Meter("clair"))
+	return &Retention{
+		store:    store,
+		maxAge:   maxAge,
+		interval: interval,
+		pruned: meter.NewInt64Counter(
+			"clair_notifier_notifications_pruned",
+			metric.WithDescription("number of notification ids removed by the retention policy"),
+		),
+	}
+}
+
+// Run prunes on the configured interval until the context is canceled.
+func (r *Retention) Run(ctx context.Context) error {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/Retention.Run").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().
+		Str("interval", r.interval.String()).
+		Str("max_age", r.maxAge.String()).
+		Msg("starting notification retention")
+
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		if _, err := r.Partition(ctx); err != nil {
+			log.Error().Err(err).Msg("partition maintenance failed")
+		}
+		if r.maxAge > 0 {
+			n, err := r.Prune(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("notification pruning failed")
+			}
+			log.Info().
+				Int64("notifications", n).
+				Msg("notification pruning done")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Partition creates partitions PartitionsAhead of time, if the store is a
+// Partitioner, reporting how many were created.
+func (r *Retention) Partition(ctx context.Context) (int, error) {
+	p, ok := r.store.(Partitioner)
+	if !ok {
+		return 0, nil
+	}
+	n, err := p.EnsurePartitions(ctx, time.Now().Add(PartitionsAhead))
+	if n != 0 {
+		zerolog.Ctx(ctx).Info().
+			Int("partitions", n).
+			Msg("created partitions")
+	}
+	return n, err
+}
+
+// Prune removes all notifications older than the maximum age, reporting how
+// many notification ids were removed.
+//
+// If the store is a Partitioner, expired partitions are dropped first.
+func (r *Retention) Prune(ctx context.Context) (int64, error) {
+	before := time.Now().Add(-r.maxAge)
+	if p, ok := r.store.(Partitioner); ok {
+		n, err := p.DropPartitions(ctx, before)
+		if err != nil {
+			return 0, err
+		}
+		if n != 0 {
+			zerolog.Ctx(ctx).Info().
+				Int("partitions", n).
+				Msg("dropped partitions")
+		}
+	}
+	var total int64
+	for {
+		n, err := r.store.PruneNotifications(ctx, before, PruneBatchSize)
+		total += n
+		r.pruned.Add(ctx, n)
+		if err != nil {
+			return total, err
+		}
+		if n < PruneBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRetentionPrune confirms Prune keeps pruning until a batch comes up
+// short.
+func TestRetentionPrune(t *testing.T) {
+	ctx := context.Background()
+	batches := []int64{PruneBatchSize, PruneBatchSize, 3}
+	calls := 0
+	store := &MockStore{
+		PruneNotifications_: func(_ context.Context, before time.Time, limit int) (int64, error) {
+			if time.Since(before) < time.Hour {
+				t.Errorf("cutoff too recent: %v", before)
+			}
+			n := batches[calls]
+			calls++
+			return n, nil
+		},
+	}
+	r := NewRetention(time.Hour, 0, store)
+	n, err := r.Prune(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(2*PruneBatchSize+3); got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if got, want := calls, len(batches); got != want {
+		t.Errorf("got: %d calls, want: %d", got, want)
+	}
+}
+
+// PartitionStore is a MockStore that's also a Partitioner.
+type partitionStore struct {
+	*MockStore
+	ensure func(context.Context, time.Time) (int, error)
+	drop   func(context.Context, time.Time) (int, error)
+}
+
+func (s *partitionStore) EnsurePartitions(ctx context.Context, until time.Time) (int, error) {
+	return s.ensure(ctx, until)
+}
+
+func (s *partitionStore) DropPartitions(ctx context.Context, before time.Time) (int, error) {
+	return s.drop(ctx, before)
+}
+
+// TestRetentionPartitions confirms partitions are made ahead of time and
+// dropped before rows are pruned.
+func TestRetentionPartitions(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	store := &partitionStore{
+		MockStore: &MockStore{
+			PruneNotifications_: func(context.Context, time.Time, int) (int64, error) {
+				calls = append(calls, "prune")
+				return 0, nil
+			},
+		},
+		ensure: func(_ context.Context, until time.Time) (int, error) {
+			if time.Until(until) < PartitionsAhead-time.Minute {
+				t.Errorf("partitions not made far enough ahead: %v", until)
+			}
+			calls = append(calls, "ensure")
+			return 1, nil
+		},
+		drop: func(_ context.Context, before time.Time) (int, error) {
+			if time.Since(before) < time.Hour {
+				t.Errorf("cutoff too recent: %v", before)
+			}
+			calls = append(calls, "drop")
+			return 1, nil
+		},
+	}
+	r := NewRetention(time.Hour, 0, store)
+	if _, err := r.Partition(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Prune(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(calls, ","), "ensure,drop,prune"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
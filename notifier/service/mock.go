@@ -14,6 +14,12 @@ var _ Service = (*Mock)(nil)
 type Mock struct {
 	Notifications_       func(ctx context.Context, id uuid.UUID, page *notifier.Page) ([]notifier.Notification, notifier.Page, error)
 	DeleteNotifications_ func(ctx context.Context, id uuid.UUID) error
+	PurgeDelivered_      func(ctx context.Context, uoid uuid.UUID) (int64, error)
+	DeadLetters_         func(ctx context.Context) ([]notifier.Receipt, error)
+	Replay_              func(ctx context.Context, ids ...uuid.UUID) (int64, error)
+	Deliveries_          func(ctx context.Context, id uuid.UUID) ([]notifier.DeliveryStatus, error)
+	ReceiptsAfter_       func(ctx context.Context, seq int64, limit int) ([]notifier.Receipt, error)
+	Backlog_             func(ctx context.Context) (notifier.Backlog, error)
 	KeyStore_            func(ctx context.Context) notifier.KeyStore
 	KeyManager_          func(ctx context.Context) *keymanager.Manager
 }
@@ -26,6 +32,30 @@ func (m *Mock) DeleteNotifications(ctx context.Context, id uuid.UUID) error {
 	return m.DeleteNotifications_(ctx, id)
 }
 
+func (m *Mock) PurgeDelivered(ctx context.Context, uoid uuid.UUID) (int64, error) {
+	return m.PurgeDelivered_(ctx, uoid)
+}
+
+func (m *Mock) DeadLetters(ctx context.Context) ([]notifier.Receipt, error) {
+	return m.DeadLetters_(ctx)
+}
+
+func (m *Mock) Replay(ctx context.Context, ids ...uuid.UUID) (int64, error) {
+	return m.Replay_(ctx, ids...)
+}
+
+func (m *Mock) Deliveries(ctx context.Context, id uuid.UUID) ([]notifier.DeliveryStatus, error) {
+	return m.Deliveries_(ctx, id)
+}
+
+func (m *Mock) ReceiptsAfter(ctx context.Context, seq int64, limit int) ([]notifier.Receipt, error) {
+	return m.ReceiptsAfter_(ctx, seq, limit)
+}
+
+func (m *Mock) Backlog(ctx context.Context) (notifier.Backlog, error) {
+	return m.Backlog_(ctx)
+}
+
 func (m *Mock) KeyStore(ctx context.Context) notifier.KeyStore {
 	return m.KeyStore_(ctx)
 }
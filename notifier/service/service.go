@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,19 +17,31 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/events"
 	"github.com/quay/clair/v4/matcher"
 	"github.com/quay/clair/v4/notifier"
 	namqp "github.com/quay/clair/v4/notifier/amqp"
+	naws "github.com/quay/clair/v4/notifier/aws"
+	"github.com/quay/clair/v4/notifier/defectdojo"
+	"github.com/quay/clair/v4/notifier/jira"
 	"github.com/quay/clair/v4/notifier/keymanager"
 	"github.com/quay/clair/v4/notifier/migrations"
+	"github.com/quay/clair/v4/notifier/nats"
+	"github.com/quay/clair/v4/notifier/opsgenie"
+	"github.com/quay/clair/v4/notifier/pagerduty"
 	"github.com/quay/clair/v4/notifier/postgres"
+	"github.com/quay/clair/v4/notifier/pubsub"
+	nredis "github.com/quay/clair/v4/notifier/redis"
+	"github.com/quay/clair/v4/notifier/servicebus"
 	"github.com/quay/clair/v4/notifier/stomp"
 	"github.com/quay/clair/v4/notifier/webhook"
 )
 
 const (
 	processors = 4
-	deliveries = 4
+	// DefaultDeliveryConcurrency is the number of notification ids
+	// delivered at once if Opts doesn't say.
+	DefaultDeliveryConcurrency = 4
 )
 
 // Service is an interface wrapping ClairV4's notifier functionality.
@@ -39,6 +52,24 @@ type Service interface {
 	Notifications(ctx context.Context, id uuid.UUID, page *notifier.Page) ([]notifier.Notification, notifier.Page, error)
 	// Deletes the provided notification id
 	DeleteNotifications(ctx context.Context, id uuid.UUID) error
+	// Removes the delivered notifications created for the provided update
+	// operation, reporting how many notification ids were removed.
+	PurgeDelivered(ctx context.Context, uoid uuid.UUID) (int64, error)
+	// Returns the receipts for notifications that failed delivery, oldest
+	// first.
+	DeadLetters(ctx context.Context) ([]notifier.Receipt, error)
+	// Queues the provided notification ids to be delivered again, or every
+	// notification that failed delivery if none are provided, reporting how
+	// many were queued.
+	Replay(ctx context.Context, ids ...uuid.UUID) (int64, error)
+	// Reports each deliverer's attempts at delivering the provided
+	// notification id.
+	Deliveries(ctx context.Context, id uuid.UUID) ([]notifier.DeliveryStatus, error)
+	// Returns up to limit receipts created after the receipt with the
+	// provided sequence number, oldest first, for streaming notifications.
+	ReceiptsAfter(ctx context.Context, seq int64, limit int) ([]notifier.Receipt, error)
+	// Counts the notification ids waiting on delivery.
+	Backlog(ctx context.Context) (notifier.Backlog, error)
 	// KeyStore returns the notifier's KeyStore.
 	KeyStore(ctx context.Context) notifier.KeyStore
 	// KeyManager returns the notifier's KeyManager.
@@ -52,6 +83,26 @@ type service struct {
 	store      notifier.Store
 	keystore   notifier.KeyStore
 	keymanager *keymanager.Manager
+	deliveries []*notifier.Delivery
+	interval   time.Duration
+
+	// The processors and deliveries, and the goroutines running them.
+	drainers []interface{ Drain() }
+	running  sync.WaitGroup
+}
+
+// Drain stops the processors and deliveries from taking on more work. Wait
+// returns once they've finished what they're doing.
+func (s *service) Drain() {
+	for _, d := range s.drainers {
+		d.Drain()
+	}
+}
+
+// Wait blocks until the processors and deliveries return, which they do once
+// drained or once the context passed to New is canceled.
+func (s *service) Wait() {
+	s.running.Wait()
 }
 
 func (s *service) Notifications(ctx context.Context, id uuid.UUID, page *notifier.Page) ([]notifier.Notification, notifier.Page, error) {
@@ -62,6 +113,90 @@ func (s *service) DeleteNotifications(ctx context.Context, id uuid.UUID) error {
 	return s.store.SetDeleted(ctx, id)
 }
 
+func (s *service) PurgeDelivered(ctx context.Context, uoid uuid.UUID) (int64, error) {
+	return s.store.PurgeDelivered(ctx, uoid)
+}
+
+func (s *service) DeadLetters(ctx context.Context) ([]notifier.Receipt, error) {
+	return s.store.FailedReceipts(ctx)
+}
+
+func (s *service) Replay(ctx context.Context, ids ...uuid.UUID) (int64, error) {
+	if len(ids) == 0 {
+		var err error
+		if ids, err = s.store.Failed(ctx); err != nil {
+			return 0, err
+		}
+	}
+	return s.store.SetCreated(ctx, ids...)
+}
+
+// Deliveries returns a DeliveryStatus for every configured deliverer, followed
+// by any deliverers no longer configured that attempted delivery.
+//
+// The next attempt is estimated from the delivery interval, as deliverers
+// retry on every tick until the notification id is delivered.
+func (s *service) Deliveries(ctx context.Context, id uuid.UUID) ([]notifier.DeliveryStatus, error) {
+	r, err := s.store.Receipt(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	as, err := s.store.Attempts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []notifier.DeliveryStatus{}
+	idx := make(map[string]int)
+	for _, d := range s.deliveries {
+		n := d.Deliverer.Name()
+		if _, ok := idx[n]; ok {
+			continue
+		}
+		st := notifier.DeliveryStatus{Deliverer: n, Attempts: []notifier.Attempt{}}
+		if t, ok := d.Deliverer.(notifier.Targeter); ok {
+			st.Target = t.Target()
+		}
+		idx[n] = len(out)
+		out = append(out, st)
+	}
+	configured := len(out)
+	for _, a := range as {
+		i, ok := idx[a.Deliverer]
+		if !ok {
+			i = len(out)
+			idx[a.Deliverer] = i
+			out = append(out, notifier.DeliveryStatus{
+				Deliverer: a.Deliverer,
+				Target:    a.Target,
+				Attempts:  []notifier.Attempt{},
+			})
+		}
+		out[i].Attempts = append(out[i].Attempts, a)
+	}
+
+	if r.Status != notifier.Created && r.Status != notifier.DeliveryFailed {
+		return out, nil
+	}
+	for i := range out[:configured] {
+		last := r.TS
+		if n := len(out[i].Attempts); n != 0 {
+			last = out[i].Attempts[n-1].TS
+		}
+		next := last.Add(s.interval)
+		out[i].NextAttempt = &next
+	}
+	return out, nil
+}
+
+func (s *service) ReceiptsAfter(ctx context.Context, seq int64, limit int) ([]notifier.Receipt, error) {
+	return s.store.ReceiptsAfter(ctx, seq, limit)
+}
+
+func (s *service) Backlog(ctx context.Context) (notifier.Backlog, error) {
+	return s.store.Backlog(ctx)
+}
+
 func (s *service) KeyStore(_ context.Context) notifier.KeyStore {
 	return s.keystore
 }
@@ -74,15 +209,48 @@ func (s *service) KeyManager(_ context.Context) *keymanager.Manager {
 type Opts struct {
 	PollInterval     time.Duration
 	DeliveryInterval time.Duration
+	// ClaimBatch and ClaimLease configure how notification ids are claimed
+	// for delivery. See notifier.Delivery.
+	ClaimBatch       int
+	ClaimLease       time.Duration
 	Migrations       bool
 	ConnString       string
 	Matcher          matcher.Service
 	Indexer          indexer.Service
 	DisableSummary   bool
+	LayerAttribution bool
+	MaxAge           time.Duration
+	PruneInterval    time.Duration
+	CoalesceWindow   time.Duration
 	Client           *http.Client
 	Webhook          *webhook.Config
 	AMQP             *namqp.Config
 	STOMP            *stomp.Config
+	PubSub           *pubsub.Config
+	AzureServiceBus  *servicebus.Config
+	AWS              *naws.Config
+	NATS             *nats.Config
+	PagerDuty        *pagerduty.Config
+	OpsGenie         *opsgenie.Config
+	Jira             *jira.Config
+	DefectDojo       *defectdojo.Config
+	Redis            *nredis.Config
+
+	// DeliveryConcurrency is the number of notification ids delivered at
+	// once. If zero, DefaultDeliveryConcurrency is used.
+	DeliveryConcurrency int
+	// DeliveryRateLimit, if positive, is the most deliveries per second made
+	// to each target, allowing bursts of DeliveryBurst.
+	DeliveryRateLimit float64
+	DeliveryBurst     int
+
+	// Annotations includes the annotations of each affected manifest in its
+	// notifications.
+	Annotations bool
+	// Owners, if set, scopes delivery to tenants: each tenant's
+	// notifications are only delivered to the targets configured for it.
+	// Only deliverers implementing notifier.TenantDeliverer can be used.
+	Owners notifier.Owners
 }
 
 // New kicks off the notifier subsystem.
@@ -94,6 +262,9 @@ func New(ctx context.Context, opts Opts) (*service, error) {
 		Str("component", "notifier/service/Init").
 		Logger()
 	ctx = log.WithContext(ctx)
+	if opts.DeliveryConcurrency <= 0 {
+		opts.DeliveryConcurrency = DefaultDeliveryConcurrency
+	}
 
 	// initialize store and dist lock pool
 	store, keystore, lockPool, err := storeInit(ctx, opts)
@@ -113,11 +284,27 @@ func New(ctx context.Context, opts Opts) (*service, error) {
 		testModeInit(ctx, &opts)
 	}
 
+	// kick off retention: partition maintenance, and pruning if a
+	// retention policy is configured. partitions are made before the
+	// poller starts putting notifications in them.
+	r := notifier.NewRetention(opts.MaxAge, opts.PruneInterval, store)
+	if _, err := r.Partition(ctx); err != nil {
+		log.Warn().Err(err).Msg("partition maintenance failed")
+	}
+	go r.Run(ctx)
+
 	// kick off the poller
 	log.Info().Str("interval", opts.PollInterval.String()).Msg("initializing poller")
 	poller := notifier.NewPoller(opts.PollInterval, store, opts.Matcher)
 	c := poller.Poll(ctx)
 
+	s := &service{
+		store:      store,
+		keymanager: kmgr,
+		keystore:   keystore,
+		interval:   opts.DeliveryInterval,
+	}
+
 	// kick off the processors
 	log.Info().Int("count", processors).Msg("initializing processors")
 	for i := 0; i < processors; i++ {
@@ -131,30 +318,114 @@ func New(ctx context.Context, opts Opts) (*service, error) {
 			store,
 		)
 		p.NoSummary = opts.DisableSummary
-		p.Process(ctx, c)
+		p.LayerAttribution = opts.LayerAttribution
+		p.Annotations = opts.Annotations
+		s.drainers = append(s.drainers, p)
+		s.running.Add(1)
+		go func() {
+			defer s.running.Done()
+			p.Run(ctx, c)
+		}()
 	}
 
 	// kick off configured deliverer type
+	var ds []*notifier.Delivery
 	switch {
 	case opts.Webhook != nil:
-		if err := webhookDeliveries(ctx, opts, lockPool, store, kmgr); err != nil {
-			return nil, err
-		}
+		ds, err = webhookDeliveries(ctx, opts, lockPool, store, kmgr)
 	case opts.AMQP != nil:
-		if err := amqpDeliveries(ctx, opts, lockPool, store); err != nil {
-			return nil, err
-		}
+		ds, err = amqpDeliveries(ctx, opts, lockPool, store)
 	case opts.STOMP != nil:
-		if err := stompDeliveries(ctx, opts, lockPool, store); err != nil {
-			return nil, err
+		ds, err = stompDeliveries(ctx, opts, lockPool, store)
+	case opts.PubSub != nil:
+		ds, err = pubsubDeliveries(ctx, opts, lockPool, store)
+	case opts.AzureServiceBus != nil:
+		ds, err = servicebusDeliveries(ctx, opts, lockPool, store)
+	case opts.AWS != nil:
+		ds, err = awsDeliveries(ctx, opts, lockPool, store)
+	case opts.NATS != nil:
+		ds, err = natsDeliveries(ctx, opts, lockPool, store)
+	case opts.PagerDuty != nil:
+		ds, err = pagerdutyDeliveries(ctx, opts, lockPool, store)
+	case opts.OpsGenie != nil:
+		ds, err = opsgenieDeliveries(ctx, opts, lockPool, store)
+	case opts.Jira != nil:
+		ds, err = jiraDeliveries(ctx, opts, lockPool, store)
+	case opts.DefectDojo != nil:
+		ds, err = defectdojoDeliveries(ctx, opts, lockPool, store)
+	case opts.Redis != nil:
+		ds, err = redisDeliveries(ctx, opts, lockPool, store)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var limiter *notifier.RateLimiter
+	if opts.DeliveryRateLimit > 0 {
+		limiter = notifier.NewRateLimiter(opts.DeliveryRateLimit, opts.DeliveryBurst)
+	}
+	for _, d := range ds {
+		if opts.Owners != nil {
+			if _, ok := d.Deliverer.(notifier.TenantDeliverer); !ok {
+				return nil, fmt.Errorf("deliverer %q can't deliver to tenants", d.Deliverer.Name())
+			}
+			d.Owners = opts.Owners
+		}
+	}
+	for _, d := range ds {
+		d.Window = opts.CoalesceWindow
+		d.BatchSize = opts.ClaimBatch
+		d.Lease = opts.ClaimLease
+		d.Limiter = limiter
+		s.drainers = append(s.drainers, d)
+		s.running.Add(1)
+		go func(d *notifier.Delivery) {
+			defer s.running.Done()
+			d.Run(ctx)
+		}(d)
+	}
+	s.deliveries = ds
+
+	// kick off relaying index events, if the deliverer is configured for
+	// them and the indexer records them
+	if len(ds) != 0 && ds[0].Filter != nil && len(ds[0].Filter.IndexEvents) != 0 {
+		ed, ok := ds[0].Deliverer.(notifier.EventDeliverer)
+		src, srcOK := eventSource(opts.Indexer)
+		switch {
+		case opts.Owners != nil:
+			// Index events aren't scoped to tenants, so they'd be sent
+			// to every tenant's target.
+			log.Warn().Msg("index events aren't delivered with tenancy configured")
+		case !ok:
+			log.Warn().Str("deliverer", ds[0].Deliverer.Name()).
+				Msg("deliverer doesn't support index events")
+		case !srcOK:
+			log.Warn().Msg("indexer doesn't support index events")
+		default:
+			relay := &notifier.EventRelay{
+				Source:    src,
+				Deliverer: ed,
+				Filter:    ds[0].Filter,
+				Cursor:    store,
+				Locker:    pgdl.NewPool(lockPool, 0),
+				Interval:  opts.DeliveryInterval,
+			}
+			go relay.Run(ctx)
 		}
 	}
 
-	return &service{
-		store:      store,
-		keymanager: kmgr,
-		keystore:   keystore,
-	}, nil
+	return s, nil
+}
+
+// EventSource finds the events.Source among the wrapped indexers, if there is
+// one.
+func eventSource(s indexer.Service) (events.Source, bool) {
+	var src events.Source
+	ok := indexer.Walk(s, func(s interface{}) bool {
+		var ok bool
+		src, ok = s.(events.Source)
+		return ok
+	})
+	return src, ok
 }
 
 // testModeInit will inject a mock Indexer and Matcher into opts
@@ -222,35 +493,33 @@ func keyManagerInit(ctx context.Context, keystore notifier.KeyStore) (*keymanage
 	return mgr, nil
 }
 
-func webhookDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store, keymanager *keymanager.Manager) error {
+func webhookDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store, keymanager *keymanager.Manager) ([]*notifier.Delivery, error) {
 	log := zerolog.Ctx(ctx).With().
 		Str("component", "notifier/service/webhookInit").
 		Logger()
 	ctx = log.WithContext(ctx)
-	log.Info().Int("count", deliveries).Msg("initializing webhook deliverers")
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing webhook deliverers")
 
 	conf, err := opts.Webhook.Validate()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	ds := make([]*notifier.Delivery, 0, deliveries)
-	for i := 0; i < deliveries; i++ {
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
 		distLock := pgdl.NewPool(lockPool, 0)
 		wh, err := webhook.New(conf, opts.Client, keymanager)
 		if err != nil {
-			return fmt.Errorf("failed to create webhook deliverer: %v", err)
+			return nil, fmt.Errorf("failed to create webhook deliverer: %v", err)
 		}
 		delivery := notifier.NewDelivery(i, wh, opts.DeliveryInterval, store, distLock)
+		delivery.Filter = conf.Filter
 		ds = append(ds, delivery)
 	}
-	for _, d := range ds {
-		d.Deliver(ctx)
-	}
-	return nil
+	return ds, nil
 }
 
-func amqpDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) error {
+func amqpDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
 	log := zerolog.Ctx(ctx).With().
 		Str("component", "notifier/service/amqpInit").
 		Logger()
@@ -258,41 +527,39 @@ func amqpDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, stor
 
 	conf, err := opts.AMQP.Validate()
 	if err != nil {
-		return fmt.Errorf("amqp validation failed: %v", err)
+		return nil, fmt.Errorf("amqp validation failed: %v", err)
 	}
 
 	if len(conf.URIs) == 0 {
 		log.Warn().Msg("amqp delivery was configured with no broker URIs to connect to. delivery of notifications will not occur.")
-		return nil
+		return nil, nil
 	}
 
-	ds := make([]*notifier.Delivery, 0, deliveries)
-	for i := 0; i < deliveries; i++ {
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
 		distLock := pgdl.NewPool(lockPool, 0)
 		if conf.Direct {
 			q, err := namqp.NewDirectDeliverer(conf)
 			if err != nil {
-				return fmt.Errorf("failed to create AMQP deliverer: %v", err)
+				return nil, fmt.Errorf("failed to create AMQP deliverer: %v", err)
 			}
 			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
 			ds = append(ds, delivery)
 		} else {
 			q, err := namqp.New(conf)
 			if err != nil {
-				return fmt.Errorf("failed to create AMQP deliverer: %v", err)
+				return nil, fmt.Errorf("failed to create AMQP deliverer: %v", err)
 			}
 			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
 			ds = append(ds, delivery)
 		}
 	}
-	for _, d := range ds {
-		d.Deliver(ctx)
-	}
-
-	return nil
+	return ds, nil
 }
 
-func stompDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) error {
+func stompDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
 	log := zerolog.Ctx(ctx).With().
 		Str("component", "notifier/service/stompInit").
 		Logger()
@@ -300,36 +567,318 @@ func stompDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, sto
 
 	conf, err := opts.STOMP.Validate()
 	if err != nil {
-		return fmt.Errorf("stomp validation failed: %v", err)
+		return nil, fmt.Errorf("stomp validation failed: %v", err)
 	}
 
 	if len(conf.URIs) == 0 {
 		log.Warn().Msg("stomp delivery was configured with no broker URIs to connect to. delivery of notifications will not occur.")
-		return nil
+		return nil, nil
 	}
 
-	ds := make([]*notifier.Delivery, 0, deliveries)
-	for i := 0; i < deliveries; i++ {
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
 		distLock := pgdl.NewPool(lockPool, 0)
 		if conf.Direct {
 			q, err := stomp.NewDirectDeliverer(conf)
 			if err != nil {
-				return fmt.Errorf("failed to create STOMP direct deliverer: %v", err)
+				return nil, fmt.Errorf("failed to create STOMP direct deliverer: %v", err)
 			}
 			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
 			ds = append(ds, delivery)
 		} else {
 			q, err := stomp.New(conf)
 			if err != nil {
-				return fmt.Errorf("failed to create STOMP deliverer: %v", err)
+				return nil, fmt.Errorf("failed to create STOMP deliverer: %v", err)
 			}
 			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
 			ds = append(ds, delivery)
 		}
 	}
-	for _, d := range ds {
-		d.Deliver(ctx)
+	return ds, nil
+}
+
+func pubsubDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/service/pubsubInit").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing pubsub deliverers")
+
+	conf, err := opts.PubSub.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("pubsub validation failed: %v", err)
 	}
 
-	return nil
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
+		distLock := pgdl.NewPool(lockPool, 0)
+		if conf.Direct {
+			q, err := pubsub.NewDirectDeliverer(conf, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create pubsub direct deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		} else {
+			q, err := pubsub.New(conf, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create pubsub deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		}
+	}
+	return ds, nil
+}
+
+func servicebusDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/service/servicebusInit").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing servicebus deliverers")
+
+	conf, err := opts.AzureServiceBus.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("servicebus validation failed: %v", err)
+	}
+
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
+		distLock := pgdl.NewPool(lockPool, 0)
+		if conf.Direct {
+			q, err := servicebus.NewDirectDeliverer(conf, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create servicebus direct deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		} else {
+			q, err := servicebus.New(conf, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create servicebus deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		}
+	}
+	return ds, nil
+}
+
+func awsDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/service/awsInit").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing aws deliverers")
+
+	conf, err := opts.AWS.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("aws validation failed: %v", err)
+	}
+
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
+		distLock := pgdl.NewPool(lockPool, 0)
+		if conf.Direct {
+			q, err := naws.NewDirectDeliverer(conf, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create aws direct deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		} else {
+			q, err := naws.New(conf, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create aws deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		}
+	}
+	return ds, nil
+}
+
+func natsDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/service/natsInit").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing nats deliverers")
+
+	conf, err := opts.NATS.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("nats validation failed: %v", err)
+	}
+
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
+		distLock := pgdl.NewPool(lockPool, 0)
+		if conf.Direct {
+			q, err := nats.NewDirectDeliverer(conf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create nats direct deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		} else {
+			q, err := nats.New(conf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create nats deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		}
+	}
+	return ds, nil
+}
+
+func pagerdutyDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/service/pagerdutyInit").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing pagerduty deliverers")
+
+	conf, err := opts.PagerDuty.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("pagerduty validation failed: %v", err)
+	}
+
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
+		distLock := pgdl.NewPool(lockPool, 0)
+		q, err := pagerduty.New(conf, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pagerduty deliverer: %v", err)
+		}
+		delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+		delivery.Filter = conf.Filter
+		ds = append(ds, delivery)
+	}
+	return ds, nil
+}
+
+func opsgenieDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/service/opsgenieInit").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing opsgenie deliverers")
+
+	conf, err := opts.OpsGenie.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("opsgenie validation failed: %v", err)
+	}
+
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
+		distLock := pgdl.NewPool(lockPool, 0)
+		q, err := opsgenie.New(conf, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create opsgenie deliverer: %v", err)
+		}
+		delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+		delivery.Filter = conf.Filter
+		ds = append(ds, delivery)
+	}
+	return ds, nil
+}
+
+func jiraDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/service/jiraInit").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing jira deliverers")
+
+	conf, err := opts.Jira.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("jira validation failed: %v", err)
+	}
+
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
+		distLock := pgdl.NewPool(lockPool, 0)
+		q, err := jira.New(conf, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create jira deliverer: %v", err)
+		}
+		delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+		delivery.Filter = conf.Filter
+		ds = append(ds, delivery)
+	}
+	return ds, nil
+}
+
+func defectdojoDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/service/defectdojoInit").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing defectdojo deliverers")
+
+	conf, err := opts.DefectDojo.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("defectdojo validation failed: %v", err)
+	}
+
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
+		distLock := pgdl.NewPool(lockPool, 0)
+		q, err := defectdojo.New(conf, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create defectdojo deliverer: %v", err)
+		}
+		delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+		delivery.Filter = conf.Filter
+		ds = append(ds, delivery)
+	}
+	return ds, nil
+}
+
+func redisDeliveries(ctx context.Context, opts Opts, lockPool *pgxpool.Pool, store notifier.Store) ([]*notifier.Delivery, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "notifier/service/redisInit").
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Int("count", opts.DeliveryConcurrency).Msg("initializing redis deliverers")
+
+	conf, err := opts.Redis.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("redis validation failed: %v", err)
+	}
+
+	ds := make([]*notifier.Delivery, 0, opts.DeliveryConcurrency)
+	for i := 0; i < opts.DeliveryConcurrency; i++ {
+		distLock := pgdl.NewPool(lockPool, 0)
+		if conf.Direct {
+			q, err := nredis.NewDirectDeliverer(conf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create redis direct deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		} else {
+			q, err := nredis.New(conf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create redis deliverer: %v", err)
+			}
+			delivery := notifier.NewDelivery(i, q, opts.DeliveryInterval, store, distLock)
+			delivery.Filter = conf.Filter
+			ds = append(ds, delivery)
+		}
+	}
+	return ds, nil
 }
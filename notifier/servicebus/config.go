@@ -0,0 +1,113 @@
+package servicebus
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/quay/clair/v4/internal/azure"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Config provides configuration for an Azure Service Bus deliverer.
+//
+// Either "ConnectionString" or "Namespace" must be provided. With a
+// namespace, the deliverer authenticates as the managed identity.
+type Config struct {
+	// Configures the deliverer to deliver notifications directly to the
+	// configured queue or topic.
+	//
+	// If true "Callback" is ignored.
+	// If false a notifier.Callback is delivered and clients utilize the
+	// pagination API to retrieve.
+	Direct bool `yaml:"direct"`
+	// Specifies the number of notifications delivered in single message
+	// when Direct is true.
+	//
+	// Ignored if Direct is not true
+	// If 0 or 1 is provided no rollup occurs and each notification is delivered
+	// separately.
+	Rollup int `yaml:"rollup"`
+	// The callback url where notifications are retrieved.
+	Callback string `yaml:"callback"`
+	callback url.URL
+	// A connection string with a shared access key, as shown in the Azure
+	// portal.
+	ConnectionString string `yaml:"connection_string" secret:"true"`
+	// The namespace's host, e.g. "example.servicebus.windows.net".
+	//
+	// Ignored if ConnectionString is provided.
+	Namespace string `yaml:"namespace"`
+	// The queue or topic notifications will be sent to.
+	// The entity must already exist.
+	//
+	// May be omitted if the connection string includes an EntityPath.
+	Queue string `yaml:"queue"`
+	// The client ID of a user-assigned managed identity. If empty, the
+	// system-assigned identity is used.
+	ClientID string `yaml:"client_id"`
+	// The Service Bus API root. Mostly useful for emulators and testing.
+	Endpoint string `yaml:"endpoint"`
+	endpoint *url.URL
+	sas      *azure.ConnectionString
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
+}
+
+// Validate confirms configuration is valid and fills in private members
+// with parsed values on success.
+func (c *Config) Validate() (Config, error) {
+	conf := *c
+	ns := c.Namespace
+	if c.ConnectionString != "" {
+		cs, err := azure.ParseConnectionString(c.ConnectionString)
+		if err != nil {
+			return conf, err
+		}
+		if ns, err = cs.Namespace(); err != nil {
+			return conf, err
+		}
+		if conf.Queue == "" {
+			conf.Queue = cs.EntityPath
+		}
+		conf.sas = &cs
+	}
+	if ns == "" {
+		return conf, fmt.Errorf("servicebus config requires the connection_string or namespace field")
+	}
+	conf.Namespace = ns
+	if conf.Queue == "" {
+		return conf, fmt.Errorf("servicebus config requires the queue field")
+	}
+
+	if !c.Direct {
+		callback, err := url.Parse(c.Callback)
+		if err != nil {
+			return conf, fmt.Errorf("failed to parse callback url")
+		}
+		conf.callback = *callback
+	}
+
+	ep := c.Endpoint
+	if ep == "" {
+		ep = "https://" + ns + "/"
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return conf, fmt.Errorf("failed to parse endpoint url: %v", err)
+	}
+	conf.endpoint = u
+
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+	return conf, nil
+}
+
+// EntityURL reports the URL of the queue or topic.
+func (c *Config) entityURL() (*url.URL, error) {
+	return c.endpoint.Parse(url.PathEscape(c.Queue))
+}
@@ -0,0 +1,152 @@
+// Package servicebus implements notification delivery to Azure Service Bus
+// queues and topics.
+package servicebus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/google/uuid"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/internal/azure"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// Deliverer is a Service Bus deliverer which sends a notifier.Callback to
+// the configured queue or topic.
+type Deliverer struct {
+	conf Config
+	pub  *publisher
+}
+
+// New returns a new Service Bus Deliverer.
+//
+// If client is nil, http.DefaultClient is used.
+func New(conf Config, client *http.Client) (*Deliverer, error) {
+	var c Config
+	var err error
+	if c, err = conf.Validate(); err != nil {
+		return nil, err
+	}
+	pub, err := newPublisher(c, client)
+	if err != nil {
+		return nil, err
+	}
+	return &Deliverer{
+		conf: c,
+		pub:  pub,
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return fmt.Sprintf("servicebus-%s", d.conf.Queue)
+}
+
+// Deliver implements the notifier.Deliverer interface.
+func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	callback := d.conf.callback
+	callback.Path = path.Join(callback.Path, nID.String())
+	d.conf.Filter.SetQuery(&callback)
+
+	cb := notifier.Callback{
+		NotificationID: nID,
+		Callback:       callback,
+	}
+	b, err := json.Marshal(&cb)
+	if err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	if err := d.pub.Publish(ctx, nID, b); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	return nil
+}
+
+// Message is a message in the Service Bus REST API's batch format.
+type message struct {
+	Body             string            `json:"Body"`
+	BrokerProperties map[string]string `json:"BrokerProperties,omitempty"`
+	UserProperties   map[string]string `json:"UserProperties,omitempty"`
+}
+
+// Publisher does the actual API calls to Service Bus.
+type publisher struct {
+	c    *http.Client
+	auth azure.Authorizer
+	url  string
+}
+
+func newPublisher(conf Config, client *http.Client) (*publisher, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u, err := conf.entityURL()
+	if err != nil {
+		return nil, err
+	}
+	var auth azure.Authorizer
+	if cs := conf.sas; cs != nil {
+		res := fmt.Sprintf("https://%s/%s", conf.Namespace, conf.Queue)
+		auth = azure.NewSAS(res, cs.SharedAccessKeyName, cs.SharedAccessKey)
+	} else {
+		auth = azure.NewManagedIdentity(client, azure.ServiceBusResource, conf.ClientID)
+	}
+	msgs, err := u.Parse(path.Join(u.Path, "messages"))
+	if err != nil {
+		return nil, err
+	}
+	return &publisher{
+		c:    client,
+		auth: auth,
+		url:  msgs.String(),
+	}, nil
+}
+
+// Publish sends the provided payloads as a single batch, so they're accepted
+// or rejected together.
+func (p *publisher) Publish(ctx context.Context, nID uuid.UUID, data ...[]byte) error {
+	msgs := make([]message, 0, len(data))
+	for _, b := range data {
+		msgs = append(msgs, message{
+			Body: string(b),
+			BrokerProperties: map[string]string{
+				"ContentType": "application/json",
+			},
+			UserProperties: map[string]string{
+				"notification_id": nID.String(),
+				"app_id":          "clairV4-notifier",
+			},
+		})
+	}
+	b, err := json.Marshal(msgs)
+	if err != nil {
+		return err
+	}
+	auth, err := p.auth.Authorization(ctx)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/vnd.microsoft.servicebus.json")
+	r.Header.Set("Authorization", auth)
+	res, err := p.c.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		return &clairerror.ErrRequestFail{
+			Code:   res.StatusCode,
+			Status: res.Status,
+		}
+	}
+	return nil
+}
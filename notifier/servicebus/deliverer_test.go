@@ -0,0 +1,166 @@
+package servicebus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/notifier"
+)
+
+const callback = "http://clair-notifier/notifier/api/v1/notification"
+
+// fakeServiceBus is a minimal Service Bus API.
+type fakeServiceBus struct {
+	sync.Mutex
+	batches  int
+	messages []message
+}
+
+func (f *fakeServiceBus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	switch {
+	case r.URL.Path != "/notifications/messages":
+		w.WriteHeader(http.StatusNotFound)
+	case !strings.HasPrefix(r.Header.Get("Authorization"), "SharedAccessSignature sr=https%3A%2F%2Fexample.servicebus.windows.net%2Fnotifications&"):
+		w.WriteHeader(http.StatusUnauthorized)
+	case r.Header.Get("Content-Type") != "application/vnd.microsoft.servicebus.json":
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	default:
+		var msgs []message
+		if err := json.NewDecoder(r.Body).Decode(&msgs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.batches++
+		f.messages = append(f.messages, msgs...)
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// setup returns a fake Service Bus and a Config pointing at it.
+func setup(t *testing.T) (*fakeServiceBus, Config, func()) {
+	f := &fakeServiceBus{}
+	srv := httptest.NewServer(f)
+	return f, Config{
+		Callback: callback,
+		ConnectionString: "Endpoint=sb://example.servicebus.windows.net/;" +
+			"SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=c2VjcmV0;EntityPath=notifications",
+		Endpoint: srv.URL + "/",
+	}, srv.Close
+}
+
+func TestConfig(t *testing.T) {
+	_, conf, cleanup := setup(t)
+	defer cleanup()
+	c, err := conf.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Namespace, "example.servicebus.windows.net"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := c.Queue, "notifications"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	mi := Config{Direct: true, Namespace: "example.servicebus.windows.net"}
+	if _, err := mi.Validate(); err == nil {
+		t.Error("expected error for missing queue")
+	}
+	mi.Queue = "q"
+	c, err = mi.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.endpoint.String(), "https://example.servicebus.windows.net/"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if _, err := (&Config{Queue: "q"}).Validate(); err == nil {
+		t.Error("expected error for missing namespace")
+	}
+}
+
+func TestDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, conf, cleanup := setup(t)
+	defer cleanup()
+	d, err := New(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nID := uuid.New()
+	if err := d.Deliver(ctx, nID); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	if got, want := len(f.messages), 1; got != want {
+		t.Fatalf("got: %d messages, want: %d", got, want)
+	}
+	var cb notifier.Callback
+	if err := json.Unmarshal([]byte(f.messages[0].Body), &cb); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cb.NotificationID, nID; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := cb.Callback.String(), callback+"/"+nID.String(); got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := f.messages[0].UserProperties["notification_id"], nID.String(); got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestDirectDeliverer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, conf, cleanup := setup(t)
+	defer cleanup()
+	conf.Direct = true
+	conf.Rollup = 2
+	d, err := NewDirectDeliverer(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := make([]notifier.Notification, 5)
+	for i := range ns {
+		ns[i].ID = uuid.New()
+	}
+	if err := d.Notifications(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	// 5 notifications with a rollup of 2 should be 3 messages in 1 batch.
+	if got, want := len(f.messages), 3; got != want {
+		t.Fatalf("got: %d messages, want: %d", got, want)
+	}
+	if got, want := f.batches, 1; got != want {
+		t.Errorf("got: %d batches, want: %d", got, want)
+	}
+	var total int
+	for _, m := range f.messages {
+		var got []json.RawMessage
+		if err := json.Unmarshal([]byte(m.Body), &got); err != nil {
+			t.Fatal(err)
+		}
+		total += len(got)
+	}
+	if got, want := total, len(ns); got != want {
+		t.Errorf("got: %d notifications, want: %d", got, want)
+	}
+}
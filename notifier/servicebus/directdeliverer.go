@@ -0,0 +1,94 @@
+package servicebus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/notifier"
+)
+
+// DirectDeliverer is a Service Bus deliverer which sends notifications
+// directly to the configured queue or topic.
+type DirectDeliverer struct {
+	conf Config
+	n    []notifier.Notification
+	pub  *publisher
+}
+
+// NewDirectDeliverer returns a new Service Bus DirectDeliverer.
+//
+// If client is nil, http.DefaultClient is used.
+func NewDirectDeliverer(conf Config, client *http.Client) (*DirectDeliverer, error) {
+	var c Config
+	var err error
+	if c, err = conf.Validate(); err != nil {
+		return nil, err
+	}
+	pub, err := newPublisher(c, client)
+	if err != nil {
+		return nil, err
+	}
+	return &DirectDeliverer{
+		conf: c,
+		n:    []notifier.Notification{},
+		pub:  pub,
+	}, nil
+}
+
+func (d *DirectDeliverer) Name() string {
+	return fmt.Sprintf("servicebus-direct-%s", d.conf.Queue)
+}
+
+// Notifications will copy the provided notifications into a buffer for
+// delivery.
+func (d *DirectDeliverer) Notifications(ctx context.Context, n []notifier.Notification) error {
+	// if we can reslice instead of allocate do so.
+	if len(n) <= len(d.n) {
+		d.n = d.n[:len(n)]
+		copy(d.n, n)
+		return nil
+	}
+	tmp := make([]notifier.Notification, len(n), len(n))
+	copy(tmp, n)
+	d.n = tmp
+	return nil
+}
+
+// Deliver implements the notifier.Deliverer interface.
+//
+// All blocks are sent in a single batch, so a failure will not result in a
+// partial delivery.
+func (d *DirectDeliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
+	// block loop publishing smaller blocks of max(rollup) length via reslicing.
+	var rollup int = d.conf.Rollup
+	if rollup == 0 {
+		rollup++
+	}
+
+	var msgs [][]byte
+	for bs, be := 0, rollup; bs < len(d.n); bs, be = be, be+rollup {
+		// if block-end exceeds array bounds, slice block underflow.
+		// next block-start will cause loop to exit.
+		if be > len(d.n) {
+			be = len(d.n)
+		}
+		currentBlock := d.n[bs:be]
+		b, err := json.Marshal(&currentBlock)
+		if err != nil {
+			return &clairerror.ErrDeliveryFailed{E: err}
+		}
+		msgs = append(msgs, b)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	if err := d.pub.Publish(ctx, nID, msgs...); err != nil {
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	return nil
+}
@@ -6,12 +6,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/url"
+	"time"
+
+	"github.com/quay/clair/v4/notifier"
 )
 
 type TLS struct {
 	// The filesystem path where a root CA can be read.
 	RootCA string `yaml:"root_ca"`
 	// The filesystem path where a tls certificate can be read.
+	//
+	// The certificate and key are optional, but must be provided together.
+	// If provided, they're presented to brokers as a client certificate.
 	Cert string `yaml:"cert"`
 	// The filesystem path where a tls private key can be read.
 	Key string `yaml:"key"`
@@ -19,7 +25,7 @@ type TLS struct {
 
 type Login struct {
 	Login    string `yaml:"login"`
-	Passcode string `yaml:"passcode"`
+	Passcode string `yaml:"passcode" secret:"true"`
 }
 
 type Config struct {
@@ -37,11 +43,24 @@ type Config struct {
 	// If 0 or 1 is provided no rollup occurs and each notification is delivered
 	// separately.
 	Rollup int `yaml:"rollup"`
+	// The largest message body, in bytes, of notifications delivered when
+	// Direct is true. Blocks of notifications that would be larger are
+	// split, so they aren't rejected by the broker's frame limits.
+	//
+	// If 0, blocks are only split by Rollup.
+	MaxMessageSize int `yaml:"max_message_size"`
 	// The callback url where notifications are retrieved.
+	//
+	// If Direct is true, it's optional, and is sent in a header with each
+	// message for consumers to retrieve the whole set.
 	Callback string `yaml:"callback"`
 	callback url.URL
 	// the destination messages will be delivered to
 	Destination string `yaml:"destination"`
+	// Tenants maps tenants to the destinations their notifications are
+	// delivered to, instead of Destination, when tenancy is configured.
+	// Tenants not listed aren't delivered notifications.
+	Tenants map[string]string `yaml:"tenants"`
 	// a list of URIs to send messages to.
 	// a linear search of this list is always performed.
 	URIs []string `yaml:"uris"`
@@ -50,12 +69,25 @@ type Config struct {
 	tls *tls.Config
 	// optional user login portion of config
 	Login *Login `yaml:"user"`
+	// Heartbeat is the interval heartbeats are sent and expected at. Zero
+	// disables heartbeats.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// Reconnect configures the backoff between failed attempts to connect
+	// to the brokers.
+	Reconnect *notifier.Reconnect `yaml:"reconnect"`
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter"`
 }
 
 func (c *Config) Validate() (Config, error) {
 	conf := *c
 
-	if !c.Direct {
+	if c.MaxMessageSize < 0 {
+		return conf, fmt.Errorf("max_message_size can't be negative")
+	}
+	if !c.Direct || c.Callback != "" {
 		var u *url.URL
 		var err error
 		if u, err = url.Parse(c.Callback); err != nil {
@@ -65,7 +97,7 @@ func (c *Config) Validate() (Config, error) {
 	}
 
 	if c.TLS != nil {
-		if c.TLS.Cert == "" || c.TLS.Key == "" {
+		if (c.TLS.Cert == "") != (c.TLS.Key == "") {
 			return conf, fmt.Errorf("both tls cert and key are required")
 		}
 		TLS := tls.Config{}
@@ -84,14 +116,20 @@ func (c *Config) Validate() (Config, error) {
 			TLS.RootCAs.AppendCertsFromPEM(ca)
 		}
 
-		var err error
-		var cert tls.Certificate
-		if cert, err = tls.LoadX509KeyPair(c.TLS.Cert, c.TLS.Key); err != nil {
-			return conf, fmt.Errorf("failed to read x509 cert and key pair: %v", err)
+		if c.TLS.Cert != "" {
+			cert, err := tls.LoadX509KeyPair(c.TLS.Cert, c.TLS.Key)
+			if err != nil {
+				return conf, fmt.Errorf("failed to read x509 cert and key pair: %v", err)
+			}
+			TLS.Certificates = append(TLS.Certificates, cert)
 		}
-		TLS.Certificates = append(TLS.Certificates, cert)
 		conf.tls = &TLS
 	}
 
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
 	return conf, nil
 }
@@ -25,15 +25,24 @@ func New(conf Config) (*Deliverer, error) {
 	if c, err = conf.Validate(); err != nil {
 		return nil, err
 	}
-	fo := &failOver{
-		Config: c,
-	}
+	fo := newFailOver(c)
 	return &Deliverer{
 		conf: c,
 		fo:   fo,
 	}, nil
 }
 
+// ForTenant implements notifier.TenantDeliverer.
+func (d *Deliverer) ForTenant(t string) (notifier.Deliverer, bool) {
+	dest, ok := d.conf.Tenants[t]
+	if !ok {
+		return nil, false
+	}
+	c := *d
+	c.conf.Destination = dest
+	return &c, true
+}
+
 func (d *Deliverer) Name() string {
 	return fmt.Sprintf("stomp-%s", d.conf.Destination)
 }
@@ -41,12 +50,13 @@ func (d *Deliverer) Name() string {
 func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	conn, err := d.fo.Connection(ctx)
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	defer conn.Disconnect()
 
 	callback := d.conf.callback
 	callback.Path = path.Join(callback.Path, nID.String())
+	d.conf.Filter.SetQuery(&callback)
 
 	cb := notifier.Callback{
 		NotificationID: nID,
@@ -54,12 +64,12 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	}
 	b, err := json.Marshal(&cb)
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 
 	err = conn.Send(d.conf.Destination, "application/json", b, gostomp.SendOpt.Receipt)
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	return nil
 }
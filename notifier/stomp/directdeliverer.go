@@ -1,12 +1,13 @@
 package stomp
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"path"
+	"strconv"
 
 	gostomp "github.com/go-stomp/stomp"
+	"github.com/go-stomp/stomp/frame"
 	"github.com/google/uuid"
 	clairerror "github.com/quay/clair/v4/clair-error"
 	"github.com/quay/clair/v4/notifier"
@@ -26,9 +27,7 @@ func NewDirectDeliverer(conf Config) (*DirectDeliverer, error) {
 	if c, err = conf.Validate(); err != nil {
 		return nil, err
 	}
-	fo := &failOver{
-		Config: c,
-	}
+	fo := newFailOver(c)
 	return &DirectDeliverer{
 		conf: c,
 		n:    []notifier.Notification{},
@@ -36,6 +35,18 @@ func NewDirectDeliverer(conf Config) (*DirectDeliverer, error) {
 	}, nil
 }
 
+// ForTenant implements notifier.TenantDeliverer.
+func (d *DirectDeliverer) ForTenant(t string) (notifier.Deliverer, bool) {
+	dest, ok := d.conf.Tenants[t]
+	if !ok {
+		return nil, false
+	}
+	c := *d
+	c.conf.Destination = dest
+	c.n = nil
+	return &c, true
+}
+
 func (d *DirectDeliverer) Name() string {
 	return fmt.Sprintf("stomp-direct-%s", d.conf.Destination)
 }
@@ -55,51 +66,55 @@ func (d *DirectDeliverer) Notifications(ctx context.Context, n []notifier.Notifi
 	return nil
 }
 
+// Deliver sends the buffered notifications, split into messages as
+// configured, in a single transaction. Every message carries headers placing
+// it in the notification set.
 func (d *DirectDeliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	conn, err := d.fo.Connection(ctx)
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	defer conn.Disconnect()
 
 	tx, err := conn.BeginWithError()
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 
-	// block loop publishing smaller blocks of max(rollup) length via reslicing.
-	var rollup int = d.conf.Rollup
-	if rollup == 0 {
-		rollup++
+	// With a callback configured, consumers missing part of the set can
+	// page through all of it.
+	var callback string
+	if d.conf.Callback != "" {
+		u := d.conf.callback
+		u.Path = path.Join(u.Path, nID.String())
+		d.conf.Filter.SetQuery(&u)
+		callback = u.String()
 	}
-
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	var currentBlock []notifier.Notification
-	for bs, be := 0, rollup; bs < len(d.n); bs, be = be, be+rollup {
-		buf.Reset()
-		// if block-end exceeds array bounds, slice block underflow.
-		// next block-start will cause loop to exit.
-		if be > len(d.n) {
-			be = len(d.n)
+	chunks, err := notifier.Chunks(d.n, d.conf.Rollup, d.conf.MaxMessageSize)
+	if err != nil {
+		tx.Abort()
+		return &clairerror.ErrDeliveryFailed{E: err}
+	}
+	for _, c := range chunks {
+		opts := []func(*frame.Frame) error{
+			gostomp.SendOpt.Receipt,
+			gostomp.SendOpt.Header(notifier.HeaderNotificationID, nID.String()),
+			gostomp.SendOpt.Header(notifier.HeaderChunk, strconv.Itoa(c.Seq)),
+			gostomp.SendOpt.Header(notifier.HeaderChunks, strconv.Itoa(c.Total)),
 		}
-
-		currentBlock = d.n[bs:be]
-		err := enc.Encode(&currentBlock)
-		if err != nil {
-			tx.Abort()
-			return &clairerror.ErrDeliveryFailed{err}
+		if callback != "" {
+			opts = append(opts, gostomp.SendOpt.Header(notifier.HeaderCallback, callback))
 		}
-		err = tx.Send(d.conf.Destination, "application/json", buf.Bytes(), gostomp.SendOpt.Receipt)
+		err = tx.Send(d.conf.Destination, "application/json", c.Body, opts...)
 		if err != nil {
 			tx.Abort()
-			return &clairerror.ErrDeliveryFailed{err}
+			return &clairerror.ErrDeliveryFailed{E: err}
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return &clairerror.ErrDeliveryFailed{err}
+		return &clairerror.ErrDeliveryFailed{E: err}
 	}
 	return nil
 }
@@ -9,6 +9,8 @@ import (
 
 	gostomp "github.com/go-stomp/stomp"
 	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/notifier"
 )
 
 // failOver will return the first successful connection made against the provided
@@ -17,6 +19,14 @@ import (
 // failOver is safe for concurrent usage.
 type failOver struct {
 	Config
+	backoff *notifier.Backoff
+}
+
+func newFailOver(c Config) *failOver {
+	return &failOver{
+		Config:  c,
+		backoff: notifier.NewBackoff(c.Reconnect),
+	}
 }
 
 // Dial will dial the provided uri in accordance with the
@@ -31,6 +41,9 @@ func (f *failOver) Dial(uri string) (*gostomp.Conn, error) {
 	if f.Login != nil {
 		opts = append(opts, gostomp.ConnOpt.Login(f.Login.Login, f.Login.Passcode))
 	}
+	if f.Heartbeat > 0 {
+		opts = append(opts, gostomp.ConnOpt.HeartBeat(f.Heartbeat, f.Heartbeat))
+	}
 
 	var conn io.ReadWriteCloser
 	var err error
@@ -69,13 +82,18 @@ func (f *failOver) Connection(ctx context.Context) (*gostomp.Conn, error) {
 		Logger()
 	ctx = log.WithContext(ctx)
 
+	if err := f.backoff.Allow(); err != nil {
+		return nil, err
+	}
 	for _, uri := range f.URIs {
 		conn, err := f.Dial(uri)
 		if err != nil {
-			log.Debug().Str("broker", uri).Msg("failed to dial broker. attempting next")
+			log.Debug().Err(err).Str("broker", uri).Msg("failed to dial broker. attempting next")
 			continue
 		}
+		f.backoff.Succeeded()
 		return conn, nil
 	}
-	return nil, fmt.Errorf("exhausted all brokers and unable to make connection.")
+	wait := f.backoff.Failed()
+	return nil, fmt.Errorf("exhausted all brokers and unable to make connection, retrying in %v", wait)
 }
@@ -2,6 +2,7 @@ package notifier
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -21,11 +22,43 @@ type PutOpts struct {
 	Notifications []Notification
 }
 
+// CoalesceOpts is provided to Notificationer.CoalesceNotifications with the
+// notification ids to merge and the notifications replacing them.
+type CoalesceOpts struct {
+	// the notification id the merged notifications are retrievable with
+	NotificationID uuid.UUID
+	// the update operation the new receipt is recorded against, normally
+	// the latest of the merged notification ids'
+	UpdateID uuid.UUID
+	// the notification ids being merged
+	Replaces []uuid.UUID
+	// the notifications to persist under the new notification id
+	Notifications []Notification
+}
+
 // Store is an aggregate interface implementing all methods
 // necessary for a notifier persistence layer
 type Store interface {
 	Notificationer
 	Receipter
+	Claimer
+	Attempter
+	Pruner
+}
+
+// Claimer implements leases on notification ids, so notifier replicas
+// sharing a store deliver disjoint sets of them.
+type Claimer interface {
+	// Claim leases up to limit of the provided notification ids to owner
+	// until the lease is up, and returns the ones it leased.
+	//
+	// Only notification ids in created or delivery failed status that
+	// aren't leased, or whose lease is up, may be leased. Concurrent calls
+	// must not lease the same notification id.
+	Claim(ctx context.Context, owner string, lease time.Duration, ids []uuid.UUID, limit int) ([]uuid.UUID, error)
+	// Release ends owner's lease on the notification id. Releasing a lease
+	// that's up or held by someone else is not an error.
+	Release(ctx context.Context, owner string, id uuid.UUID) error
 }
 
 // Notificationer implements persistence methods for Notification models
@@ -66,6 +99,13 @@ type Notificationer interface {
 	// set deleted after some period of time, thus this condition should not
 	// be checked.
 	DeleteNotifications(ctx context.Context, id uuid.UUID) error
+	// CoalesceNotifications persists the provided notifications under a new
+	// notification id in created status, and sets the notification ids they
+	// replace to deleted status.
+	//
+	// If any of the replaced notification ids is no longer in created status,
+	// nothing is changed and an error is returned.
+	CoalesceNotifications(ctx context.Context, opts CoalesceOpts) error
 }
 
 // Receipter implements persistence methods for Receipt models
@@ -86,4 +126,22 @@ type Receipter interface {
 	SetDeliveryFailed(ctx context.Context, id uuid.UUID) error
 	// SetDeleted marks the provided notification id as deleted
 	SetDeleted(ctx context.Context, id uuid.UUID) error
+	// FailedReceipts returns the Receipts in delivery failed status, oldest
+	// first.
+	FailedReceipts(ctx context.Context) ([]Receipt, error)
+	// SetCreated returns the provided notification ids to created status, so
+	// they're delivered again, and reports how many were changed.
+	//
+	// Notifications in deleted status must be left alone.
+	SetCreated(ctx context.Context, ids ...uuid.UUID) (int64, error)
+	// ReceiptsAfter returns up to limit receipts created after the receipt
+	// with the provided sequence number, in creation order. Receipts in
+	// deleted status are skipped.
+	//
+	// Receipts must be returned in an order that never changes, so a caller
+	// passing the last sequence number it saw doesn't miss any. This may mean
+	// holding back very recent receipts.
+	ReceiptsAfter(ctx context.Context, seq int64, limit int) ([]Receipt, error)
+	// Backlog counts the notification ids waiting on delivery.
+	Backlog(ctx context.Context) (Backlog, error)
 }
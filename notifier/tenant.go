@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// Owners reports which tenants submitted manifests.
+type Owners interface {
+	// Owners returns the tenants that submitted each of the manifests,
+	// keyed by digest. Manifests no tenant submitted are left out.
+	Owners(ctx context.Context, ds []claircore.Digest) (map[string][]string, error)
+}
+
+// ByTenant groups the notifications by the tenants that submitted their
+// manifests. A notification appears under every tenant that submitted its
+// manifest, and under none if no tenant did.
+func ByTenant(ctx context.Context, o Owners, ns []Notification) (map[string][]Notification, error) {
+	if len(ns) == 0 {
+		return nil, nil
+	}
+	ds := make([]claircore.Digest, 0, len(ns))
+	seen := make(map[string]bool, len(ns))
+	for _, n := range ns {
+		k := n.Manifest.String()
+		if !seen[k] {
+			seen[k] = true
+			ds = append(ds, n.Manifest)
+		}
+	}
+	owners, err := o.Owners(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]Notification)
+	for _, n := range ns {
+		for _, t := range owners[n.Manifest.String()] {
+			out[t] = append(out[t], n)
+		}
+	}
+	return out, nil
+}
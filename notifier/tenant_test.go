@@ -0,0 +1,125 @@
+package notifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+type mapOwners map[string][]string
+
+func (o mapOwners) Owners(_ context.Context, ds []claircore.Digest) (map[string][]string, error) {
+	out := make(map[string][]string)
+	for _, d := range ds {
+		if ts, ok := o[d.String()]; ok {
+			out[d.String()] = ts
+		}
+	}
+	return out, nil
+}
+
+// TenantDeliverer records what each tenant's target is sent.
+type tenantDeliverer struct {
+	targets map[string]bool
+	got     map[string][]Notification
+}
+
+func (d *tenantDeliverer) Name() string { return "tenant-test" }
+
+func (d *tenantDeliverer) Deliver(_ context.Context, _ uuid.UUID) error {
+	panic("delivered without a tenant")
+}
+
+func (d *tenantDeliverer) Notifications(_ context.Context, _ []Notification) error {
+	panic("delivered without a tenant")
+}
+
+func (d *tenantDeliverer) ForTenant(t string) (Deliverer, bool) {
+	if !d.targets[t] {
+		return nil, false
+	}
+	return &tenantTarget{parent: d, tenant: t}, true
+}
+
+type tenantTarget struct {
+	parent *tenantDeliverer
+	tenant string
+	n      []Notification
+}
+
+func (d *tenantTarget) Name() string   { return "tenant-test" }
+func (d *tenantTarget) Target() string { return d.tenant }
+
+func (d *tenantTarget) Notifications(_ context.Context, n []Notification) error {
+	d.n = n
+	return nil
+}
+
+func (d *tenantTarget) Deliver(_ context.Context, _ uuid.UUID) error {
+	d.parent.got[d.tenant] = append(d.parent.got[d.tenant], d.n...)
+	return nil
+}
+
+func TestDeliveryTenants(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	a := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	b := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	c := claircore.MustParseDigest("sha256:" + strings.Repeat("c", 64))
+	owners := mapOwners{
+		a.String(): {"team-a"},
+		b.String(): {"team-a", "team-b"},
+	}
+	shared, unowned := uuid.New(), uuid.New()
+	var attempts []Attempt
+	store := &MockStore{
+		Notifications_: func(_ context.Context, id uuid.UUID, _ *Page) ([]Notification, Page, error) {
+			if id == unowned {
+				return []Notification{{ID: id, Manifest: c}}, Page{}, nil
+			}
+			return []Notification{
+				{ID: id, Manifest: a},
+				{ID: id, Manifest: b},
+				{ID: id, Manifest: c},
+			}, Page{}, nil
+		},
+		SetDelivered_: func(_ context.Context, _ uuid.UUID) error { return nil },
+		SetDeleted_:   func(_ context.Context, _ uuid.UUID) error { return nil },
+		PutAttempt_: func(_ context.Context, a Attempt) error {
+			attempts = append(attempts, a)
+			return nil
+		},
+	}
+	td := &tenantDeliverer{
+		// team-b has no target, so isn't sent anything.
+		targets: map[string]bool{"team-a": true, "team-c": true},
+		got:     make(map[string][]Notification),
+	}
+	d := NewDelivery(0, td, 0, store, nil)
+	d.Owners = owners
+	for _, id := range []uuid.UUID{shared, unowned} {
+		if err := d.do(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := len(td.got), 1; got != want {
+		t.Fatalf("got notifications for %d tenants, want %d: %v", got, want, td.got)
+	}
+	got := td.got["team-a"]
+	if len(got) != 2 || got[0].Manifest.String() != a.String() || got[1].Manifest.String() != b.String() {
+		t.Errorf("team-a: got: %v, want: [%v %v]", got, a, b)
+	}
+	if got, want := len(attempts), 2; got != want {
+		t.Fatalf("attempts: got: %d, want: %d", got, want)
+	}
+	if got, want := attempts[0].Target, "team-a"; got != want {
+		t.Errorf("attempt target: got: %q, want: %q", got, want)
+	}
+	if got, want := attempts[1].Status, AttemptFiltered; got != want {
+		t.Errorf("attempt status: got: %q, want: %q", got, want)
+	}
+}
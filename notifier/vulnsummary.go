@@ -1,6 +1,10 @@
 package notifier
 
-import "github.com/quay/claircore"
+import (
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/purl"
+)
 
 // VulnSummary summarizes a vulnerability which triggered
 // a notification
@@ -13,6 +17,10 @@ type VulnSummary struct {
 	Severity       string                  `json:"severity"`
 	FixedInVersion string                  `json:"fixed_in_version"`
 	Links          string                  `json:"links"`
+	// PURL is the package URL of the vulnerable package as the
+	// vulnerability names it, so usually without a version, if its
+	// ecosystem has a package URL type.
+	PURL string `json:"purl,omitempty"`
 }
 
 func (vs *VulnSummary) FromVulnerability(v *claircore.Vulnerability) {
@@ -25,5 +33,6 @@ func (vs *VulnSummary) FromVulnerability(v *claircore.Vulnerability) {
 		Severity:       v.NormalizedSeverity.String(),
 		FixedInVersion: v.FixedInVersion,
 		Links:          v.Links,
+		PURL:           purl.For(v.Package, v.Dist, v.Repo),
 	}
 }
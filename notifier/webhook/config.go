@@ -1,10 +1,15 @@
 package webhook
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/quay/clair/v4/notifier"
 )
 
 // Config provides configuration for an Webhook deliverer.
@@ -12,16 +17,40 @@ type Config struct {
 	// the URL where our webhook will be delivered
 	Target string `yaml:"target" json:"target"`
 	target *url.URL
+	// Tenants maps tenants to the URLs their webhooks are delivered to,
+	// instead of Target, when tenancy is configured. Tenants not listed
+	// aren't sent webhooks.
+	Tenants map[string]string `yaml:"tenants" json:"tenants"`
+	tenants map[string]*url.URL
 	// the callback url where notifications can be received
 	// the notification will be appended to this url
 	Callback string `yaml:"callback" json:"callback"`
 	callback *url.URL
-	// any htp headers necessary for the request to Target
-	Headers http.Header `yaml:"headers" json:"headers"`
+	// any htp headers necessary for the request to Target, e.g. an
+	// Authorization header for a gateway in front of it.
+	Headers http.Header `yaml:"headers" json:"headers" secret:"true"`
+	// the URL of an HTTP, HTTPS, or SOCKS5 proxy webhooks are sent through,
+	// instead of the one named by the environment.
+	Proxy string `yaml:"proxy" json:"proxy"`
+	proxy *url.URL
+	// the filesystem path where a PEM bundle of CAs trusted for Target can be
+	// read, in addition to the system's.
+	RootCA string `yaml:"root_ca" json:"root_ca"`
+	tls    *tls.Config
 	// whether the webhook deliverer will sign out going.
 	// if true webhooks will be sent with a jwt signed by
 	// the notifier's private key.
 	Signed bool `yaml:"signed" json:"signed"`
+	// a shared secret used to compute an HMAC-SHA256 of the request body.
+	// if set, webhooks will be sent with the signature in the
+	// "X-Clair-Signature" header.
+	SigningSecret string `yaml:"signing_secret" json:"signing_secret" secret:"true"`
+	// Filter selects which notifications are delivered.
+	//
+	// If nil, every notification is delivered.
+	Filter *notifier.Filter `yaml:"filter" json:"filter"`
+	// CloudEvents, if provided, wraps webhooks in CloudEvents envelopes.
+	CloudEvents *notifier.CloudEvents `yaml:"cloudevents" json:"cloudevents"`
 }
 
 // Validate will return a copy of the Config on success.
@@ -34,6 +63,17 @@ func (c *Config) Validate() (Config, error) {
 	}
 	conf.target = target
 
+	if len(c.Tenants) != 0 {
+		conf.tenants = make(map[string]*url.URL, len(c.Tenants))
+		for t, v := range c.Tenants {
+			u, err := url.Parse(v)
+			if err != nil {
+				return conf, fmt.Errorf("failed to parse target url for tenant %q", t)
+			}
+			conf.tenants[t] = u
+		}
+	}
+
 	// require trailing slash so url.Parse() can easily
 	// append notification id.
 	if !strings.HasSuffix(c.Callback, "/") {
@@ -46,10 +86,70 @@ func (c *Config) Validate() (Config, error) {
 	}
 	conf.callback = callback
 
-	if conf.Headers == nil {
-		conf.Headers = map[string][]string{}
+	if c.Proxy != "" {
+		proxy, err := url.Parse(c.Proxy)
+		if err != nil {
+			return conf, fmt.Errorf("failed to parse proxy url: %v", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return conf, fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+		}
+		conf.proxy = proxy
+	}
+
+	if c.RootCA != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		ca, err := ioutil.ReadFile(c.RootCA)
+		if err != nil {
+			return conf, fmt.Errorf("failed to read root ca: %v", err)
+		}
+		if !pool.AppendCertsFromPEM(ca) {
+			return conf, fmt.Errorf("no certificates found in root ca %q", c.RootCA)
+		}
+		conf.tls = &tls.Config{RootCAs: pool}
+	}
+
+	// Headers from the configuration file aren't canonicalized, so do it
+	// here to keep them from doubling up with the ones set below.
+	conf.Headers = make(http.Header, len(c.Headers)+1)
+	for k, vs := range c.Headers {
+		for _, v := range vs {
+			conf.Headers.Add(k, v)
+		}
 	}
 	conf.Headers.Set("Content-Type", "application/json")
 
+	filter, err := c.Filter.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.Filter = filter
+
+	ce, err := c.CloudEvents.Validate()
+	if err != nil {
+		return conf, err
+	}
+	conf.CloudEvents = ce
 	return conf, nil
 }
+
+// Transport returns a transport honoring the configured proxy and root CA, or
+// nil if neither is configured. Validate must be called first.
+func (c *Config) transport() *http.Transport {
+	if c.proxy == nil && c.tls == nil {
+		return nil
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if c.proxy != nil {
+		tr.Proxy = http.ProxyURL(c.proxy)
+	}
+	if c.tls != nil {
+		tr.TLSClientConfig = c.tls
+	}
+	return tr
+}
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/indexer/events"
 	"github.com/quay/clair/v4/notifier"
 	"github.com/quay/clair/v4/notifier/keymanager"
 	"github.com/rs/zerolog"
@@ -26,6 +28,10 @@ type Deliverer struct {
 }
 
 // New returns a new webhook Deliverer
+//
+// If the configuration names a proxy or root CA, webhooks are sent with a
+// client of the Deliverer's own instead of the provided one, so they don't
+// carry Clair's intraservice authorization.
 func New(conf Config, client *http.Client, keymanager *keymanager.Manager) (*Deliverer, error) {
 	var c Config
 	var err error
@@ -35,6 +41,9 @@ func New(conf Config, client *http.Client, keymanager *keymanager.Manager) (*Del
 	if client == nil {
 		client = http.DefaultClient
 	}
+	if tr := c.transport(); tr != nil {
+		client = &http.Client{Transport: tr, Timeout: client.Timeout}
+	}
 	return &Deliverer{
 		conf: c,
 		c:    client,
@@ -46,6 +55,23 @@ func (d *Deliverer) Name() string {
 	return "webhook"
 }
 
+// Target implements notifier.Targeter.
+func (d *Deliverer) Target() string {
+	return d.conf.Target
+}
+
+// ForTenant implements notifier.TenantDeliverer.
+func (d *Deliverer) ForTenant(t string) (notifier.Deliverer, bool) {
+	u, ok := d.conf.tenants[t]
+	if !ok {
+		return nil, false
+	}
+	c := *d
+	c.conf.Target = u.String()
+	c.conf.target = u
+	return &c, true
+}
+
 // sign will use the provided private key to sign and attach a jwt to the provided
 // request.
 func (d *Deliverer) sign(ctx context.Context, req *http.Request, kp keymanager.KeyPair) error {
@@ -86,6 +112,7 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	if err != nil {
 		return err
 	}
+	d.conf.Filter.SetQuery(callback)
 
 	wh := notifier.Callback{
 		NotificationID: nID,
@@ -95,13 +122,68 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 	if err != nil {
 		return err
 	}
+
+	log.Info().Str("notification_id", nID.String()).
+		Str("callback", callback.String()).
+		Str("target", d.conf.Target).
+		Msg("dispatching webhook")
+	return d.post(log.WithContext(ctx), notifier.EventCallback, nID.String(), b)
+}
+
+// DeliverIndexEvent implements notifier.EventDeliverer.
+//
+// DeliverIndexEvent POSTS the index event to the configured target.
+func (d *Deliverer) DeliverIndexEvent(ctx context.Context, ev *events.Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().
+		Str("component", "notifier/webhook/deliverer.DeliverIndexEvent").
+		Str("kind", ev.Kind).
+		Str("manifest", ev.Manifest).
+		Str("target", d.conf.Target).
+		Msg("dispatching index event webhook")
+	return d.post(ctx, notifier.EventIndex, strconv.FormatInt(ev.Seq, 10), b)
+}
+
+// Post sends the body to the configured target, wrapping it in a CloudEvent
+// of the provided type and id if configured to, and signing it.
+func (d *Deliverer) post(ctx context.Context, evType, id string, b []byte) error {
+	log := zerolog.Ctx(ctx)
+	var err error
+	header := d.conf.Headers.Clone()
+	if ce := d.conf.CloudEvents; ce != nil {
+		ev := ce.Event(evType, id, id, b)
+		var ct string
+		b, ct, err = ce.Encode(ev)
+		if err != nil {
+			return err
+		}
+		header.Set("Content-Type", ct)
+		if ce.Mode == notifier.Binary {
+			for k, v := range ev.Attributes() {
+				// The content type header stands in for the
+				// "datacontenttype" attribute.
+				if k != "datacontenttype" {
+					header.Set("ce-"+k, v)
+				}
+			}
+		}
+	}
 	buf := bytes.NewReader(b)
 
 	req := &http.Request{
-		URL:    d.conf.target,
-		Header: d.conf.Headers,
-		Body:   ioutil.NopCloser(buf),
-		Method: http.MethodPost,
+		URL:           d.conf.target,
+		Header:        header,
+		Body:          ioutil.NopCloser(buf),
+		ContentLength: int64(len(b)),
+		Method:        http.MethodPost,
+	}
+	req = req.WithContext(ctx)
+
+	if d.conf.SigningSecret != "" {
+		req.Header.Set(SignatureHeader, signature([]byte(d.conf.SigningSecret), time.Now(), b))
 	}
 
 	// sign a jwt using key manager's private key
@@ -117,11 +199,6 @@ func (d *Deliverer) Deliver(ctx context.Context, nID uuid.UUID) error {
 		log.Debug().Msg("successfully signed request")
 	}
 
-	log.Info().Str("notification_id", nID.String()).
-		Str("callback", callback.String()).
-		Str("target", d.conf.Target).
-		Msg("dispatching webhook")
-
 	resp, err := d.c.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
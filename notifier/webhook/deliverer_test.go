@@ -6,13 +6,17 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"path"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
@@ -33,6 +37,53 @@ var (
 func TestDeliverer(t *testing.T) {
 	t.Run("TestSign", testSign)
 	t.Run("TestDeliverer", testDeliverer)
+	t.Run("TestHMAC", testHMAC)
+	t.Run("TestCloudEvents", testCloudEvents)
+	t.Run("TestProxy", testProxy)
+	t.Run("TestRootCA", testRootCA)
+	t.Run("TestTenants", testTenants)
+}
+
+// testTenants confirms tenants' webhooks are sent to their own targets.
+func testTenants(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, r.URL.Path)
+		},
+	))
+	defer server.Close()
+	ctx := zlog.Test(context.Background(), t)
+	d, err := New(Config{
+		Callback: callback,
+		Target:   server.URL + "/global",
+		Tenants:  map[string]string{"team-a": server.URL + "/team-a"},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create new webhook deliverer: %v", err)
+	}
+	if _, ok := d.ForTenant("team-b"); ok {
+		t.Error("got a target for an unlisted tenant")
+	}
+	td, ok := d.ForTenant("team-a")
+	if !ok {
+		t.Fatal("no target for a listed tenant")
+	}
+	if err := td.Deliver(ctx, noteID); err != nil {
+		t.Fatalf("got: %v, wanted: nil", err)
+	}
+	if got, want := td.(notifier.Targeter).Target(), server.URL+"/team-a"; got != want {
+		t.Errorf("target: got: %q, want: %q", got, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"/team-a"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
 }
 
 // testSign confirms the deliverer correctly signs a webhook
@@ -165,6 +216,223 @@ func testDeliverer(t *testing.T) {
 	}
 }
 
+// testHMAC confirms the deliverer attaches a signature header a receiver can
+// verify with the shared secret.
+func testHMAC(t *testing.T) {
+	t.Parallel()
+	const secret = "deadbeef"
+
+	errCh := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			errCh <- VerifySignature([]byte(secret), r.Header.Get(SignatureHeader), b, DefaultSignatureTolerance)
+		},
+	))
+	defer server.Close()
+	ctx := zlog.Test(context.Background(), t)
+	d, err := New(Config{
+		Callback:      callback,
+		Target:        server.URL,
+		SigningSecret: secret,
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create new webhook deliverer: %v", err)
+	}
+	if err := d.Deliver(ctx, noteID); err != nil {
+		t.Fatalf("got: %v, wanted: nil", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("signature verification failed: %v", err)
+	}
+
+	// Check that tampering and stale timestamps are caught.
+	body := []byte(`{}`)
+	old := signature([]byte(secret), time.Now().Add(-time.Hour), body)
+	if err := VerifySignature([]byte(secret), old, body, DefaultSignatureTolerance); err == nil {
+		t.Error("stale signature accepted")
+	}
+	cur := signature([]byte(secret), time.Now(), body)
+	if err := VerifySignature([]byte(secret), cur, []byte(`{"x":1}`), DefaultSignatureTolerance); err == nil {
+		t.Error("signature accepted for modified body")
+	}
+	if err := VerifySignature([]byte("nope"), cur, body, DefaultSignatureTolerance); err == nil {
+		t.Error("signature accepted with wrong secret")
+	}
+}
+
+// testCloudEvents confirms the deliverer wraps webhooks in CloudEvents in both
+// content modes.
+func testCloudEvents(t *testing.T) {
+	t.Parallel()
+	type result struct {
+		header http.Header
+		body   []byte
+	}
+	resCh := make(chan result, 1)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			resCh <- result{header: r.Header, body: b}
+		},
+	))
+	defer server.Close()
+	ctx := zlog.Test(context.Background(), t)
+
+	for _, mode := range []string{notifier.Structured, notifier.Binary} {
+		d, err := New(Config{
+			Callback:    callback,
+			Target:      server.URL,
+			CloudEvents: &notifier.CloudEvents{Mode: mode},
+		}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create new webhook deliverer: %v", err)
+		}
+		if err := d.Deliver(ctx, noteID); err != nil {
+			t.Fatalf("got: %v, wanted: nil", err)
+		}
+		res := <-resCh
+
+		var ev notifier.CloudEvent
+		var data []byte
+		switch mode {
+		case notifier.Structured:
+			if got, want := res.header.Get("content-type"), notifier.CloudEventsContentType; got != want {
+				t.Errorf("%s: got: %q, want: %q", mode, got, want)
+			}
+			if err := json.Unmarshal(res.body, &ev); err != nil {
+				t.Fatalf("%s: %v", mode, err)
+			}
+			data = ev.Data
+		case notifier.Binary:
+			if got, want := res.header.Get("content-type"), "application/json"; got != want {
+				t.Errorf("%s: got: %q, want: %q", mode, got, want)
+			}
+			ev.SpecVersion = res.header.Get("ce-specversion")
+			ev.ID = res.header.Get("ce-id")
+			ev.Source = res.header.Get("ce-source")
+			ev.Type = res.header.Get("ce-type")
+			ev.Subject = res.header.Get("ce-subject")
+			data = res.body
+		}
+		// The id is the notification ID, so redeliveries can be
+		// deduplicated.
+		want := notifier.CloudEvent{
+			SpecVersion: "1.0",
+			ID:          noteID.String(),
+			Source:      notifier.DefaultEventSource,
+			Type:        notifier.EventCallback,
+			Subject:     noteID.String(),
+		}
+		got := notifier.CloudEvent{
+			SpecVersion: ev.SpecVersion,
+			ID:          ev.ID,
+			Source:      ev.Source,
+			Type:        ev.Type,
+			Subject:     ev.Subject,
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("%s: %v", mode, cmp.Diff(got, want))
+		}
+		var cb notifier.Callback
+		if err := json.Unmarshal(data, &cb); err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if !cmp.Equal(cb.NotificationID, noteID) {
+			t.Errorf("%s: got: %v, wanted: %v", mode, cb.NotificationID, noteID)
+		}
+	}
+}
+
+// testProxy confirms the deliverer sends webhooks through the configured
+// proxy, with the configured headers.
+func testProxy(t *testing.T) {
+	t.Parallel()
+	reqCh := make(chan *http.Request, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			reqCh <- r
+		},
+	))
+	defer proxy.Close()
+	ctx := zlog.Test(context.Background(), t)
+	d, err := New(Config{
+		Callback: callback,
+		Target:   "http://receiver.invalid/hook",
+		Proxy:    proxy.URL,
+		Headers:  http.Header{"authorization": {"Bearer token"}},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create new webhook deliverer: %v", err)
+	}
+	if err := d.Deliver(ctx, noteID); err != nil {
+		t.Fatalf("got: %v, wanted: nil", err)
+	}
+	r := <-reqCh
+	if got, want := r.URL.String(), "http://receiver.invalid/hook"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := r.Header["Authorization"], []string{"Bearer token"}; !cmp.Equal(got, want) {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	if _, err := New(Config{
+		Callback: callback,
+		Target:   "http://receiver.invalid/hook",
+		Proxy:    "ftp://proxy.invalid",
+	}, nil, nil); err == nil {
+		t.Error("unsupported proxy scheme accepted")
+	}
+}
+
+// testRootCA confirms the deliverer trusts the configured root CA.
+func testRootCA(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {},
+	))
+	defer server.Close()
+	ctx := zlog.Test(context.Background(), t)
+
+	f, err := ioutil.TempFile("", "webhook-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	err = pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := New(Config{
+		Callback: callback,
+		Target:   server.URL,
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create new webhook deliverer: %v", err)
+	}
+	if err := d.Deliver(ctx, noteID); err == nil {
+		t.Error("delivered to untrusted server")
+	}
+
+	d, err = New(Config{
+		Callback: callback,
+		Target:   server.URL,
+		RootCA:   f.Name(),
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create new webhook deliverer: %v", err)
+	}
+	if err := d.Deliver(ctx, noteID); err != nil {
+		t.Errorf("got: %v, wanted: nil", err)
+	}
+}
+
 func genKeyPair(t *testing.T, n int) (kps []keymanager.KeyPair) {
 	reader := rand.Reader
 	bitSize := 2048
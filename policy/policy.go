@@ -0,0 +1,104 @@
+// Package policy evaluates Rego policies against vulnerability reports.
+//
+// Policies are ordinary Rego modules in the "clair" package. Any values
+// produced by the "deny" rule are reported as violations, and a manifest is
+// allowed if there are none. For example:
+//
+//	package clair
+//
+//	deny[msg] {
+//		v := input.vulnerabilities[_]
+//		v.normalized_severity == "Critical"
+//		msg := sprintf("%s is critical", [v.name])
+//	}
+//
+// The input document is the JSON form of the manifest's VulnerabilityReport.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+)
+
+// Query is the Rego query evaluated to find violations.
+const Query = `data.clair.deny`
+
+// Decision is the outcome of evaluating policy against a manifest.
+type Decision struct {
+	ManifestHash claircore.Digest `json:"manifest_hash"`
+	Allow        bool             `json:"allow"`
+	// Violations holds the values produced by the "deny" rule. These are
+	// usually strings, but may be any JSON value.
+	Violations []interface{} `json:"violations"`
+}
+
+// Engine evaluates a set of compiled policies.
+type Engine struct {
+	q rego.PreparedEvalQuery
+}
+
+// New loads and compiles the Rego modules and data files found at the
+// provided paths. Directories are loaded recursively.
+func New(ctx context.Context, paths []string) (*Engine, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "policy/New").
+		Logger()
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("policy: no paths provided")
+	}
+	q, err := rego.New(
+		rego.Query(Query),
+		rego.Load(paths, nil),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("policy: unable to prepare query: %w", err)
+	}
+	log.Info().
+		Strs("paths", paths).
+		Msg("loaded policies")
+	return &Engine{q: q}, nil
+}
+
+// Evaluate reports the policy decision for the provided VulnerabilityReport.
+func (e *Engine) Evaluate(ctx context.Context, vr *claircore.VulnerabilityReport) (*Decision, error) {
+	// Round-trip through JSON so policy authors see the same document as API
+	// consumers.
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(vr); err != nil {
+		return nil, fmt.Errorf("policy: unable to encode input: %w", err)
+	}
+	var input interface{}
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	if err := dec.Decode(&input); err != nil {
+		return nil, fmt.Errorf("policy: unable to decode input: %w", err)
+	}
+
+	rs, err := e.q.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("policy: evaluation failed: %w", err)
+	}
+	d := Decision{
+		ManifestHash: vr.Hash,
+		Violations:   []interface{}{},
+	}
+	// An undefined "deny" rule produces no results, which is the same as no
+	// violations.
+	for _, r := range rs {
+		for _, x := range r.Expressions {
+			vs, ok := x.Value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("policy: %q produced %T, not a set", Query, x.Value)
+			}
+			d.Violations = append(d.Violations, vs...)
+		}
+	}
+	d.Allow = len(d.Violations) == 0
+	return &d, nil
+}
@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+func TestEvaluate(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	e, err := New(ctx, []string{"testdata"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		Name  string
+		Vulns map[string]*claircore.Vulnerability
+		Want  []interface{}
+	}{
+		{
+			Name: "Clean",
+			Want: []interface{}{},
+		},
+		{
+			Name: "Low",
+			Vulns: map[string]*claircore.Vulnerability{
+				"1": {ID: "1", Name: "CVE-2021-0001", NormalizedSeverity: claircore.Low},
+			},
+			Want: []interface{}{},
+		},
+		{
+			Name: "Critical",
+			Vulns: map[string]*claircore.Vulnerability{
+				"1": {ID: "1", Name: "CVE-2021-0001", NormalizedSeverity: claircore.Low},
+				"2": {ID: "2", Name: "CVE-2021-0002", NormalizedSeverity: claircore.Critical},
+			},
+			Want: []interface{}{"CVE-2021-0002 is critical"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			d, err := e.Evaluate(ctx, &claircore.VulnerabilityReport{
+				Vulnerabilities: tc.Vulns,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := d.Allow, len(tc.Want) == 0; got != want {
+				t.Errorf("allow: got: %v, want: %v", got, want)
+			}
+			if !cmp.Equal(d.Violations, tc.Want) {
+				t.Error(cmp.Diff(d.Violations, tc.Want))
+			}
+		})
+	}
+}
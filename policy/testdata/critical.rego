@@ -0,0 +1,7 @@
+package clair
+
+deny[msg] {
+	v := input.vulnerabilities[_]
+	v.normalized_severity == "Critical"
+	msg := sprintf("%s is critical", [v.name])
+}
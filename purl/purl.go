@@ -0,0 +1,145 @@
+// Package purl generates package URLs for the packages in reports and
+// notifications, so they can be correlated with SBOMs and the findings of
+// other scanners.
+//
+// See https://github.com/package-url/purl-spec.
+package purl
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Namespaces maps distribution IDs to the purl type and namespace of their
+// packages. Distributions not listed here only get package URLs for their
+// language packages.
+var namespaces = map[string][2]string{
+	"alpine":        {"apk", "alpine"},
+	"amzn":          {"rpm", "amazon"},
+	"centos":        {"rpm", "centos"},
+	"debian":        {"deb", "debian"},
+	"fedora":        {"rpm", "fedora"},
+	"ol":            {"rpm", "oracle"},
+	"opensuse":      {"rpm", "opensuse"},
+	"opensuse-leap": {"rpm", "opensuse"},
+	"photon":        {"rpm", "photon"},
+	"rhel":          {"rpm", "redhat"},
+	"sles":          {"rpm", "suse"},
+	"ubuntu":        {"deb", "ubuntu"},
+}
+
+// For returns the package URL of the package, found in the distribution and
+// repository, either of which may be nil. It returns an empty string if the
+// package's ecosystem has no package URL type.
+//
+// The package URL has no version if the package doesn't, as is the case
+// for the packages named in vulnerabilities. Operating system packages have
+// a "distro" qualifier of the distribution's ID and version ID, e.g.
+// "debian-10".
+func For(p *claircore.Package, d *claircore.Distribution, r *claircore.Repository) string {
+	if p == nil || p.Name == "" {
+		return ""
+	}
+	q := make(map[string]string)
+	var typ, ns, name, version string
+	switch {
+	case r != nil && r.Name == "pypi" || strings.HasPrefix(p.PackageDB, "python:"):
+		// Python package names are normalized as described in PEP 503,
+		// which the purl spec asks for.
+		typ = "pypi"
+		name = strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(p.Name))
+		version = p.Version
+	case d != nil && d.DID != "":
+		tn, ok := namespaces[d.DID]
+		if !ok {
+			return ""
+		}
+		typ, ns, name, version = tn[0], tn[1], p.Name, p.Version
+		if d.VersionID != "" {
+			q["distro"] = d.DID + "-" + d.VersionID
+		}
+		if p.Arch != "" {
+			q["arch"] = p.Arch
+		}
+		// RPM versions include the epoch, which is a qualifier in package
+		// URLs.
+		if i := strings.IndexByte(version, ':'); typ == "rpm" && i != -1 {
+			q["epoch"] = version[:i]
+			version = version[i+1:]
+		}
+	default:
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("pkg:")
+	b.WriteString(typ)
+	b.WriteByte('/')
+	if ns != "" {
+		b.WriteString(escape(ns))
+		b.WriteByte('/')
+	}
+	b.WriteString(escape(name))
+	if version != "" {
+		b.WriteByte('@')
+		b.WriteString(escape(version))
+	}
+	if len(q) != 0 {
+		ks := make([]string, 0, len(q))
+		for k := range q {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		for i, k := range ks {
+			if i == 0 {
+				b.WriteByte('?')
+			} else {
+				b.WriteByte('&')
+			}
+			b.WriteString(k)
+			b.WriteByte('=')
+			b.WriteString(escape(q[k]))
+		}
+	}
+	return b.String()
+}
+
+// Report returns the package URLs of the packages in the report, keyed by
+// package ID. Packages without a package URL are left out.
+//
+// A package found in more than one environment gets the package URL of the
+// first one with a distribution or repository.
+func Report(pkgs map[string]*claircore.Package, envs map[string][]*claircore.Environment, dists map[string]*claircore.Distribution, repos map[string]*claircore.Repository) map[string]string {
+	out := make(map[string]string, len(pkgs))
+	for id, p := range pkgs {
+		var d *claircore.Distribution
+		var r *claircore.Repository
+		for _, env := range envs[id] {
+			if env == nil {
+				continue
+			}
+			d = dists[env.DistributionID]
+			for _, rid := range env.RepositoryIDs {
+				if r = repos[rid]; r != nil {
+					break
+				}
+			}
+			if d != nil || r != nil {
+				break
+			}
+		}
+		if s := For(p, d, r); s != "" {
+			out[id] = s
+		}
+	}
+	return out
+}
+
+// Escape percent-encodes a component of a package URL. The "@" is escaped
+// so versions can be told from names.
+func escape(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "@", "%40")
+}
@@ -0,0 +1,98 @@
+package purl
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+)
+
+func TestFor(t *testing.T) {
+	debian := &claircore.Distribution{DID: "debian", VersionID: "10"}
+	rhel := &claircore.Distribution{DID: "rhel", VersionID: "8"}
+	pypi := &claircore.Repository{Name: "pypi", URI: "https://pypi.org/simple"}
+	for _, tc := range []struct {
+		Name string
+		Pkg  *claircore.Package
+		Dist *claircore.Distribution
+		Repo *claircore.Repository
+		Want string
+	}{
+		{
+			Name: "Debian",
+			Pkg:  &claircore.Package{Name: "libc6", Version: "2.28-10", Arch: "amd64"},
+			Dist: debian,
+			Want: "pkg:deb/debian/libc6@2.28-10?arch=amd64&distro=debian-10",
+		},
+		{
+			Name: "DebianEpoch",
+			Pkg:  &claircore.Package{Name: "tzdata", Version: "1:2021a-0+deb10u1"},
+			Dist: debian,
+			Want: "pkg:deb/debian/tzdata@1:2021a-0+deb10u1?distro=debian-10",
+		},
+		{
+			Name: "RPMEpoch",
+			Pkg:  &claircore.Package{Name: "openssl-libs", Version: "1:1.1.1g-15.el8_3", Arch: "x86_64"},
+			Dist: rhel,
+			Want: "pkg:rpm/redhat/openssl-libs@1.1.1g-15.el8_3?arch=x86_64&distro=rhel-8&epoch=1",
+		},
+		{
+			Name: "Vulnerability",
+			Pkg:  &claircore.Package{Name: "glibc"},
+			Dist: debian,
+			Want: "pkg:deb/debian/glibc?distro=debian-10",
+		},
+		{
+			Name: "Python",
+			Pkg:  &claircore.Package{Name: "Flask_Cors", Version: "3.0.8", PackageDB: "python:usr/lib/python3/site-packages"},
+			Dist: debian,
+			Want: "pkg:pypi/flask-cors@3.0.8",
+		},
+		{
+			Name: "PythonRepository",
+			Pkg:  &claircore.Package{Name: "requests"},
+			Repo: pypi,
+			Want: "pkg:pypi/requests",
+		},
+		{
+			Name: "UnknownDistribution",
+			Pkg:  &claircore.Package{Name: "busybox", Version: "1.31"},
+			Dist: &claircore.Distribution{DID: "clearlinux"},
+		},
+		{
+			Name: "Nothing",
+			Pkg:  &claircore.Package{Name: "busybox", Version: "1.31"},
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := For(tc.Pkg, tc.Dist, tc.Repo); got != tc.Want {
+				t.Errorf("got: %q, want: %q", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	pkgs := map[string]*claircore.Package{
+		"1": {Name: "libc6", Version: "2.28-10"},
+		"2": {Name: "requests", Version: "2.25.1"},
+		"3": {Name: "orphan", Version: "1"},
+	}
+	envs := map[string][]*claircore.Environment{
+		"1": {{DistributionID: "1"}},
+		"2": {{DistributionID: "1", RepositoryIDs: []string{"1"}}},
+	}
+	dists := map[string]*claircore.Distribution{
+		"1": {DID: "debian", VersionID: "10"},
+	}
+	repos := map[string]*claircore.Repository{
+		"1": {Name: "pypi"},
+	}
+	want := map[string]string{
+		"1": "pkg:deb/debian/libc6@2.28-10?distro=debian-10",
+		"2": "pkg:pypi/requests@2.25.1",
+	}
+	if got := Report(pkgs, envs, dists, repos); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
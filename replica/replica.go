@@ -0,0 +1,112 @@
+// Package replica routes report reads to Postgres read replicas, keeping
+// writes and everything else on the primary.
+//
+// Replicas lag the primary, so reads that miss or fail on a replica are
+// retried against the primary. A report that was just written is still
+// found, just not as cheaply.
+package replica
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// Indexer wraps an indexer.Service and reads index reports from a replica
+// of the indexer's database.
+type Indexer struct {
+	indexer.Service
+	pool *pgxpool.Pool
+}
+
+var _ indexer.Service = (*Indexer)(nil)
+
+// NewIndexer returns an Indexer reading index reports from the database
+// behind pool, which must be a replica of the indexer's database.
+func NewIndexer(s indexer.Service, pool *pgxpool.Pool) *Indexer {
+	return &Indexer{
+		Service: s,
+		pool:    pool,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// This is the query libindex uses, which is stable as long as the indexer's
+// migrations are.
+const selectIndexReport = `
+SELECT scan_result
+FROM indexreport
+	JOIN manifest ON manifest.hash = $1
+WHERE indexreport.manifest_id = manifest.id;`
+
+// IndexReport implements indexer.Reporter.
+func (i *Indexer) IndexReport(ctx context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	var ir claircore.IndexReport
+	err := i.pool.QueryRow(ctx, selectIndexReport, d.String()).Scan(&ir)
+	switch {
+	case err == nil:
+		return &ir, true, nil
+	case errors.Is(err, pgx.ErrNoRows):
+		// Possibly not replicated yet.
+	default:
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "replica/Indexer.IndexReport").
+			Str("manifest", d.String()).
+			Err(err).
+			Msg("failed to read from replica, using primary")
+	}
+	return i.Service.IndexReport(ctx, d)
+}
+
+// Scanner is the subset of matcher.Service run against the replica.
+type Scanner interface {
+	Scan(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error)
+}
+
+// Matcher wraps a matcher.Service and generates vulnerability reports using
+// a replica of the matcher's database.
+type Matcher struct {
+	matcher.Service
+	replica Scanner
+}
+
+var _ matcher.Service = (*Matcher)(nil)
+
+// NewMatcher returns a Matcher generating vulnerability reports with r,
+// which must be using a replica of the matcher's database.
+func NewMatcher(s matcher.Service, r Scanner) *Matcher {
+	return &Matcher{
+		Service: s,
+		replica: r,
+	}
+}
+
+// Unwrap returns the wrapped matcher.Service.
+func (m *Matcher) Unwrap() matcher.Service {
+	return m.Service
+}
+
+// Scan implements matcher.Scanner.
+func (m *Matcher) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	vr, err := m.replica.Scan(ctx, ir)
+	if err == nil {
+		return vr, nil
+	}
+	zerolog.Ctx(ctx).Warn().
+		Str("component", "replica/Matcher.Scan").
+		Str("manifest", ir.Hash.String()).
+		Err(err).
+		Msg("failed to scan using replica, using primary")
+	return m.Service.Scan(ctx, ir)
+}
@@ -0,0 +1,59 @@
+package replica
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestMatcherScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := &claircore.IndexReport{
+		Hash: claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`),
+	}
+	var primary int
+	m := &matcher.Mock{
+		Scan_: func(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			primary++
+			return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+		},
+	}
+
+	t.Run("Replica", func(t *testing.T) {
+		primary = 0
+		r := &matcher.Mock{
+			Scan_: func(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return &claircore.VulnerabilityReport{Hash: ir.Hash}, nil
+			},
+		}
+		if _, err := NewMatcher(m, r).Scan(ctx, ir); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := primary, 0; got != want {
+			t.Errorf("primary scans: got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("Fallback", func(t *testing.T) {
+		primary = 0
+		r := &matcher.Mock{
+			Scan_: func(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+				return nil, errors.New("expected")
+			},
+		}
+		vr, err := NewMatcher(m, r).Scan(ctx, ir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := vr.Hash.String(), ir.Hash.String(); got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := primary, 1; got != want {
+			t.Errorf("primary scans: got: %d, want: %d", got, want)
+		}
+	})
+}
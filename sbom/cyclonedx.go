@@ -0,0 +1,57 @@
+package sbom
+
+import (
+	"encoding/json"
+)
+
+// CycloneDX documents are described at https://cyclonedx.org/specification/.
+// Only the parts needed to recover components are decoded.
+type cdxDoc struct {
+	Metadata struct {
+		Component *cdxComponent `json:"component"`
+	} `json:"metadata"`
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string         `json:"type"`
+	Name       string         `json:"name"`
+	Version    string         `json:"version"`
+	PURL       string         `json:"purl"`
+	Components []cdxComponent `json:"components"`
+}
+
+func parseCycloneDX(b []byte) (*Document, error) {
+	var doc cdxDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	d := &Document{}
+	if c := doc.Metadata.Component; c != nil {
+		d.Name = c.Name
+		// Components of the subject, e.g. an image's layers, are walked too.
+		doc.Components = append(doc.Components, c.Components...)
+	}
+	var walk func([]cdxComponent)
+	walk = func(cs []cdxComponent) {
+		for _, c := range cs {
+			switch c.Type {
+			case "operating-system":
+				if d.OS == nil {
+					d.OS = &OS{ID: c.Name, VersionID: c.Version}
+				}
+			default:
+				if c.PURL != "" {
+					d.Components = append(d.Components, Component{
+						Name:    c.Name,
+						Version: c.Version,
+						PURL:    c.PURL,
+					})
+				}
+			}
+			walk(c.Components)
+		}
+	}
+	walk(doc.Components)
+	return d, nil
+}
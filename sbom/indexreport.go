@@ -0,0 +1,281 @@
+package sbom
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/debian"
+	"github.com/quay/claircore/pkg/pep440"
+	"github.com/quay/claircore/python"
+	"github.com/quay/claircore/ubuntu"
+	"github.com/rs/zerolog"
+)
+
+// PURL is a parsed package URL, as described at
+// https://github.com/package-url/purl-spec.
+type purl struct {
+	Type       string
+	Namespace  string
+	Name       string
+	Version    string
+	Qualifiers url.Values
+}
+
+func parsePURL(s string) (*purl, error) {
+	if !strings.HasPrefix(s, "pkg:") {
+		return nil, fmt.Errorf("invalid package URL %q: missing scheme", s)
+	}
+	s = strings.TrimPrefix(s, "pkg:")
+	if i := strings.IndexByte(s, '#'); i != -1 {
+		s = s[:i]
+	}
+	p := &purl{Qualifiers: url.Values{}}
+	if i := strings.IndexByte(s, '?'); i != -1 {
+		q, err := url.ParseQuery(s[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid package URL %q: %w", s, err)
+		}
+		p.Qualifiers, s = q, s[:i]
+	}
+	if i := strings.LastIndexByte(s, '@'); i != -1 {
+		v, err := url.PathUnescape(s[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid package URL %q: %w", s, err)
+		}
+		p.Version, s = v, s[:i]
+	}
+	s = strings.Trim(s, "/")
+	i := strings.IndexByte(s, '/')
+	if i == -1 {
+		return nil, fmt.Errorf("invalid package URL %q: missing name", s)
+	}
+	p.Type = strings.ToLower(s[:i])
+	s = s[i+1:]
+	if i := strings.LastIndexByte(s, '/'); i != -1 {
+		ns, err := url.PathUnescape(s[:i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid package URL %q: %w", s, err)
+		}
+		p.Namespace, s = ns, s[i+1:]
+	}
+	n, err := url.PathUnescape(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid package URL %q: %w", s, err)
+	}
+	p.Name = n
+	return p, nil
+}
+
+// IndexReport returns an index report equivalent to what the indexer would
+// produce for the packages in the document.
+//
+// The manifest hash is the document's digest. Components whose package URL
+// can't be parsed are skipped. Operating system packages are only matched if
+// their distribution can be determined, either from the package URL's
+// "distro" qualifier or the document's OS, and is one of Debian, Ubuntu, or
+// Alpine.
+func (d *Document) IndexReport(ctx context.Context) (*claircore.IndexReport, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "sbom/Document.IndexReport").
+		Logger()
+	h, err := claircore.NewDigest("sha256", d.Digest[:])
+	if err != nil {
+		return nil, err
+	}
+	ir := &claircore.IndexReport{
+		Hash:          h,
+		State:         "IndexFinished",
+		Packages:      make(map[string]*claircore.Package),
+		Distributions: make(map[string]*claircore.Distribution),
+		Repositories:  make(map[string]*claircore.Repository),
+		Environments:  make(map[string][]*claircore.Environment),
+		Success:       true,
+	}
+	// Distributions are keyed by "id-version_id", as in the "distro"
+	// qualifier.
+	dists := make(map[string]string)
+	distID := func(id, versionID string) (string, error) {
+		key := id + "-" + versionID
+		if did, ok := dists[key]; ok {
+			return did, nil
+		}
+		dist, err := distribution(ctx, id, versionID)
+		if err != nil {
+			return "", err
+		}
+		var did string
+		if dist != nil {
+			did = strconv.Itoa(len(ir.Distributions) + 1)
+			dist.ID = did
+			ir.Distributions[did] = dist
+		} else {
+			log.Debug().Str("distro", key).Msg("unsupported distribution")
+		}
+		dists[key] = did
+		return did, nil
+	}
+	var pypi string
+
+	for _, c := range d.Components {
+		p, err := parsePURL(c.PURL)
+		if err != nil {
+			log.Debug().Err(err).Msg("skipping component")
+			continue
+		}
+		pkg := &claircore.Package{
+			ID:        strconv.Itoa(len(ir.Packages) + 1),
+			Name:      p.Name,
+			Version:   p.Version,
+			Kind:      claircore.BINARY,
+			Arch:      p.Qualifiers.Get("arch"),
+			PackageDB: "sbom:" + p.Type,
+			// The vulnerability store expects every package to have a
+			// source, even an empty one.
+			Source: &claircore.Package{},
+		}
+		if pkg.Version == "" {
+			pkg.Version = c.Version
+		}
+		env := &claircore.Environment{
+			PackageDB:    pkg.PackageDB,
+			IntroducedIn: h,
+		}
+		switch p.Type {
+		case "deb", "apk", "rpm":
+			if up := p.Qualifiers.Get("upstream"); up != "" {
+				// Upstream is the source package's name, optionally with
+				// its version.
+				name, version := up, pkg.Version
+				if i := strings.IndexByte(up, '@'); i != -1 {
+					name, version = up[:i], up[i+1:]
+				}
+				pkg.Source = &claircore.Package{
+					Name:    name,
+					Version: version,
+					Kind:    claircore.SOURCE,
+				}
+			}
+			id, versionID := "", ""
+			if distro := p.Qualifiers.Get("distro"); distro != "" {
+				if i := strings.LastIndexByte(distro, '-'); i != -1 {
+					id, versionID = distro[:i], distro[i+1:]
+				}
+			}
+			if id == "" && d.OS != nil {
+				id, versionID = d.OS.ID, d.OS.VersionID
+			}
+			if id == "" {
+				id = p.Namespace
+			}
+			if env.DistributionID, err = distID(strings.ToLower(id), versionID); err != nil {
+				return nil, err
+			}
+		case "pypi":
+			// This mirrors claircore's python package scanner.
+			pkg.Name = strings.ToLower(pkg.Name)
+			pkg.Kind = claircore.SOURCE
+			pkg.RepositoryHint = python.Repository.URI
+			if v, err := pep440.Parse(pkg.Version); err == nil {
+				pkg.Version = v.String()
+				pkg.NormalizedVersion = v.Version()
+			}
+			if pypi == "" {
+				pypi = strconv.Itoa(len(ir.Repositories) + 1)
+				r := python.Repository
+				r.ID = pypi
+				ir.Repositories[pypi] = &r
+			}
+			env.RepositoryIDs = []string{pypi}
+		}
+		ir.Packages[pkg.ID] = pkg
+		ir.Environments[pkg.ID] = []*claircore.Environment{env}
+	}
+	return ir, nil
+}
+
+// DistScanner is the part of claircore's distribution scanners used here.
+type distScanner interface {
+	Scan(context.Context, *claircore.Layer) ([]*claircore.Distribution, error)
+}
+
+// Distribution returns the distribution claircore would detect for the
+// os-release(5) ID and VERSION_ID, or nil if it's not supported.
+//
+// Matchers compare distributions field by field against the ones the updaters
+// record, so rather than guess at every field, a minimal os-release file is
+// handed to the distribution scanner for the ID.
+func distribution(ctx context.Context, id, versionID string) (*claircore.Distribution, error) {
+	var s distScanner
+	var osRelease string
+	switch id {
+	case "debian":
+		major := versionID
+		if i := strings.IndexByte(major, '.'); i != -1 {
+			major = major[:i]
+		}
+		s = &debian.DistributionScanner{}
+		osRelease = fmt.Sprintf("ID=debian\nVERSION_ID=%q\nPRETTY_NAME=\"Debian GNU/Linux %s\"\n", major, major)
+	case "ubuntu":
+		var codename ubuntu.Release
+		for r, v := range ubuntu.ReleaseToVersionID {
+			if v == versionID {
+				codename = r
+				break
+			}
+		}
+		if codename == "" {
+			return nil, nil
+		}
+		s = &ubuntu.DistributionScanner{}
+		osRelease = fmt.Sprintf("ID=ubuntu\nVERSION_ID=%q\nVERSION_CODENAME=%s\n", versionID, codename)
+	case "alpine":
+		// Alpine's security database is per major.minor release.
+		v := strings.SplitN(strings.TrimPrefix(versionID, "v"), ".", 3)
+		if len(v) < 2 {
+			return nil, nil
+		}
+		s = &alpine.DistributionScanner{}
+		osRelease = fmt.Sprintf("ID=alpine\nVERSION_ID=%s\nPRETTY_NAME=\"Alpine Linux v%s.%s\"\n", versionID, v[0], v[1])
+	default:
+		return nil, nil
+	}
+
+	f, err := ioutil.TempFile("", "clair-sbom.")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     "etc/os-release",
+		Mode:     0644,
+		Size:     int64(len(osRelease)),
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write([]byte(osRelease)); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	var l claircore.Layer
+	l.SetLocal(f.Name())
+	ds, err := s.Scan(ctx, &l)
+	if err != nil || len(ds) == 0 {
+		return nil, err
+	}
+	// The scanners return shared values, so copy before setting an ID.
+	dist := *ds[0]
+	return &dist, nil
+}
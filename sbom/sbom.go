@@ -0,0 +1,99 @@
+// Package sbom turns software bills of materials into index reports, so that
+// artifacts Clair can't index itself can still be matched against the
+// vulnerability database.
+//
+// CycloneDX and SPDX JSON documents are supported. Only components with a
+// package URL are used, as that's what identifies their ecosystem.
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Format is the kind of an SBOM document.
+type Format string
+
+// These are the supported document formats.
+const (
+	CycloneDX Format = "cyclonedx"
+	SPDX      Format = "spdx"
+)
+
+// Document is a parsed SBOM document.
+type Document struct {
+	Format Format
+	// Name is the document's name, if it has one.
+	Name string
+	// Digest is the sha256 digest of the document.
+	Digest     [sha256.Size]byte
+	Components []Component
+	// OS is the operating system the document describes, if it names one.
+	// Components that don't say which distribution they're from are assumed
+	// to be from it.
+	OS *OS
+}
+
+// Component is a package listed in an SBOM.
+type Component struct {
+	Name    string
+	Version string
+	// PURL is the component's package URL, if it has one.
+	PURL string
+}
+
+// OS is an operating system listed in an SBOM, identified like in
+// os-release(5).
+type OS struct {
+	ID        string
+	VersionID string
+}
+
+// ErrUnknownFormat is returned by Parse, wrapped in a ParseError, for
+// documents that aren't CycloneDX or SPDX.
+var ErrUnknownFormat = errors.New("unknown document format")
+
+// ParseError is returned by Parse for malformed documents.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "sbom: " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Parse parses a CycloneDX or SPDX JSON document.
+func Parse(b []byte) (*Document, error) {
+	var probe struct {
+		BOMFormat   string `json:"bomFormat"`
+		SPDXVersion string `json:"spdxVersion"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, &ParseError{fmt.Errorf("invalid document: %w", err)}
+	}
+	var d *Document
+	var f Format
+	var err error
+	switch {
+	case probe.BOMFormat == "CycloneDX":
+		f = CycloneDX
+		d, err = parseCycloneDX(b)
+	case probe.SPDXVersion != "":
+		f = SPDX
+		d, err = parseSPDX(b)
+	default:
+		return nil, &ParseError{ErrUnknownFormat}
+	}
+	if err != nil {
+		return nil, &ParseError{fmt.Errorf("invalid %s document: %w", f, err)}
+	}
+	d.Format = f
+	d.Digest = sha256.Sum256(b)
+	return d, nil
+}
@@ -0,0 +1,191 @@
+package sbom
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/claircore"
+	"github.com/quay/zlog"
+)
+
+func load(t *testing.T, name string) *Document {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		name       string
+		format     Format
+		os         *OS
+		components int
+	}{
+		{"cyclonedx.json", CycloneDX, &OS{ID: "debian", VersionID: "10"}, 3},
+		{"spdx.json", SPDX, &OS{ID: "alpine", VersionID: "3.12.0"}, 2},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			d := load(t, tc.name)
+			if got, want := d.Format, tc.format; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+			if !cmp.Equal(d.OS, tc.os) {
+				t.Error(cmp.Diff(d.OS, tc.os))
+			}
+			if got, want := len(d.Components), tc.components; got != want {
+				t.Errorf("got: %d components, want: %d", got, want)
+			}
+		})
+	}
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := Parse([]byte(`{"@context": "https://openvex.dev/ns"}`))
+		if !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("got: %v, want: %v", err, ErrUnknownFormat)
+		}
+	})
+}
+
+func TestParsePURL(t *testing.T) {
+	tt := []struct {
+		in   string
+		want purl
+	}{
+		{
+			in: "pkg:deb/debian/openssl@1.1.1d-0%2Bdeb10u3?arch=amd64&distro=debian-10",
+			want: purl{
+				Type: "deb", Namespace: "debian", Name: "openssl", Version: "1.1.1d-0+deb10u3",
+				Qualifiers: map[string][]string{"arch": {"amd64"}, "distro": {"debian-10"}},
+			},
+		},
+		{
+			in: "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1#sub/path",
+			want: purl{
+				Type: "maven", Namespace: "org.apache.logging.log4j", Name: "log4j-core", Version: "2.14.1",
+				Qualifiers: map[string][]string{},
+			},
+		},
+		{
+			in: "pkg:npm/%40angular/core@12.0.0",
+			want: purl{
+				Type: "npm", Namespace: "@angular", Name: "core", Version: "12.0.0",
+				Qualifiers: map[string][]string{},
+			},
+		},
+	}
+	for _, tc := range tt {
+		got, err := parsePURL(tc.in)
+		if err != nil {
+			t.Errorf("%s: %v", tc.in, err)
+			continue
+		}
+		if !cmp.Equal(*got, tc.want) {
+			t.Errorf("%s: %s", tc.in, cmp.Diff(*got, tc.want))
+		}
+	}
+	for _, in := range []string{"deb/debian/openssl", "pkg:openssl"} {
+		if _, err := parsePURL(in); err == nil {
+			t.Errorf("%s: expected error", in)
+		}
+	}
+}
+
+// Record is the part of an index record checked by TestIndexReport.
+type record struct {
+	Name, Version, Kind, Source string
+	DistID, DistVersionID       string
+	Repo                        string
+}
+
+func TestIndexReport(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		name string
+		want map[string]record
+	}{
+		{
+			name: "cyclonedx.json",
+			want: map[string]record{
+				"openssl":   {Name: "openssl", Version: "1.1.1d-0+deb10u3", Kind: claircore.BINARY, Source: "openssl", DistID: "debian", DistVersionID: "10"},
+				"libssl1.1": {Name: "libssl1.1", Version: "1.1.1d-0+deb10u3", Kind: claircore.BINARY, Source: "openssl", DistID: "debian", DistVersionID: "10"},
+				"jinja2":    {Name: "jinja2", Version: "2.10", Kind: claircore.SOURCE, Repo: "pypi"},
+			},
+		},
+		{
+			name: "spdx.json",
+			want: map[string]record{
+				"busybox":  {Name: "busybox", Version: "1.31.1-r19", Kind: claircore.BINARY, Source: "busybox", DistID: "alpine", DistVersionID: "3.12"},
+				"left-pad": {Name: "left-pad", Version: "1.3.0", Kind: claircore.BINARY},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			d := load(t, tc.name)
+			ir, err := d.IndexReport(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := ir.Hash.String(), "sha256:"; len(got) <= len(want) {
+				t.Errorf("unexpected hash: %q", got)
+			}
+			got := make(map[string]record)
+			for _, r := range ir.IndexRecords() {
+				rec := record{
+					Name:    r.Package.Name,
+					Version: r.Package.Version,
+					Kind:    r.Package.Kind,
+					Source:  r.Package.Source.Name,
+				}
+				if r.Distribution != nil {
+					rec.DistID, rec.DistVersionID = r.Distribution.DID, r.Distribution.VersionID
+				}
+				if r.Repository != nil {
+					rec.Repo = r.Repository.Name
+				}
+				got[rec.Name] = rec
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		id, versionID string
+		want          string // VersionCodeName, or empty if unsupported
+	}{
+		{"debian", "10.4", "buster"},
+		{"ubuntu", "18.04", "bionic"},
+		{"ubuntu", "99.04", ""},
+		{"fedora", "32", ""},
+	}
+	for _, tc := range tt {
+		d, err := distribution(ctx, tc.id, tc.versionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if d != nil {
+			got = d.VersionCodeName
+		}
+		if got != tc.want {
+			t.Errorf("%s-%s: got: %q, want: %q", tc.id, tc.versionID, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package sbom
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// SPDX documents are described at https://spdx.github.io/spdx-spec/. Only the
+// parts needed to recover packages are decoded.
+type spdxDoc struct {
+	Name     string `json:"name"`
+	Packages []struct {
+		Name         string `json:"name"`
+		VersionInfo  string `json:"versionInfo"`
+		Purpose      string `json:"primaryPackagePurpose"`
+		ExternalRefs []struct {
+			Category string `json:"referenceCategory"`
+			Type     string `json:"referenceType"`
+			Locator  string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+func parseSPDX(b []byte) (*Document, error) {
+	var doc spdxDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	d := &Document{Name: doc.Name}
+	for _, p := range doc.Packages {
+		if p.Purpose == "OPERATING-SYSTEM" || p.Purpose == "OPERATING_SYSTEM" {
+			if d.OS == nil {
+				d.OS = &OS{ID: p.Name, VersionID: p.VersionInfo}
+			}
+			continue
+		}
+		for _, r := range p.ExternalRefs {
+			// The category is spelled with a hyphen in SPDX 2.3 and an
+			// underscore before it.
+			cat := strings.Replace(r.Category, "_", "-", -1)
+			if cat != "PACKAGE-MANAGER" || r.Type != "purl" {
+				continue
+			}
+			d.Components = append(d.Components, Component{
+				Name:    p.Name,
+				Version: p.VersionInfo,
+				PURL:    r.Locator,
+			})
+			break
+		}
+	}
+	return d, nil
+}
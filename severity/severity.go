@@ -0,0 +1,174 @@
+// Package severity rewrites the normalized severities of vulnerabilities, so
+// that the vocabularies of different vendors can be mapped onto one scale
+// and pinned within bounds chosen by the operator.
+//
+// Rewriting happens in the matcher, so vulnerability reports and the update
+// diffs notifications are built from both see the same severities.
+package severity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+// Rule adjusts the severities of vulnerabilities from matching updaters.
+type Rule struct {
+	// Updater is matched against the prefix of a vulnerability's updater
+	// name, e.g. "RHEL8" or "ubuntu". An empty Updater matches everything.
+	Updater string
+	// Mapping maps a vendor's severity, compared case-insensitively, to a
+	// normalized severity. Entries here take precedence over the
+	// Normalizer's Mapping.
+	Mapping map[string]claircore.Severity
+	// Floor and Ceiling bound the normalized severity. claircore.Unknown
+	// leaves that side unbounded.
+	Floor, Ceiling claircore.Severity
+}
+
+// Normalizer decides the normalized severity of vulnerabilities.
+type Normalizer struct {
+	// Mapping maps a vendor's severity, compared case-insensitively, to a
+	// normalized severity.
+	Mapping map[string]claircore.Severity
+	// Rules are consulted in order; the first one matching a
+	// vulnerability's updater applies.
+	Rules []Rule
+}
+
+// NewNormalizer returns a Normalizer with its mappings' keys folded to lower
+// case.
+func NewNormalizer(mapping map[string]claircore.Severity, rules []Rule) (*Normalizer, error) {
+	n := &Normalizer{
+		Mapping: fold(mapping),
+		Rules:   make([]Rule, len(rules)),
+	}
+	for i, r := range rules {
+		if r.Floor != claircore.Unknown && r.Ceiling != claircore.Unknown && r.Floor > r.Ceiling {
+			return nil, fmt.Errorf("severity: rule %q: floor %v is above ceiling %v", r.Updater, r.Floor, r.Ceiling)
+		}
+		r.Mapping = fold(r.Mapping)
+		n.Rules[i] = r
+	}
+	return n, nil
+}
+
+func fold(m map[string]claircore.Severity) map[string]claircore.Severity {
+	out := make(map[string]claircore.Severity, len(m))
+	for k, v := range m {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+// Normalize returns the normalized severity for the vulnerability.
+func (n *Normalizer) Normalize(v *claircore.Vulnerability) claircore.Severity {
+	sev := v.NormalizedSeverity
+	key := strings.ToLower(strings.TrimSpace(v.Severity))
+	var rule *Rule
+	for i := range n.Rules {
+		if strings.HasPrefix(v.Updater, n.Rules[i].Updater) {
+			rule = &n.Rules[i]
+			break
+		}
+	}
+	if s, ok := n.Mapping[key]; ok {
+		sev = s
+	}
+	if rule == nil {
+		return sev
+	}
+	if s, ok := rule.Mapping[key]; ok {
+		sev = s
+	}
+	if rule.Floor != claircore.Unknown && sev < rule.Floor {
+		sev = rule.Floor
+	}
+	if rule.Ceiling != claircore.Unknown && sev > rule.Ceiling {
+		sev = rule.Ceiling
+	}
+	return sev
+}
+
+// Apply returns the vulnerability with its normalized severity rewritten. The
+// passed vulnerability is returned if it needs no change, otherwise a copy.
+func (n *Normalizer) Apply(v *claircore.Vulnerability) *claircore.Vulnerability {
+	sev := n.Normalize(v)
+	if sev == v.NormalizedSeverity {
+		return v
+	}
+	c := *v
+	c.NormalizedSeverity = sev
+	return &c
+}
+
+// Matcher wraps a matcher.Service and normalizes the severities of the
+// vulnerabilities it returns.
+type Matcher struct {
+	matcher.Service
+	n *Normalizer
+}
+
+var _ matcher.Service = (*Matcher)(nil)
+
+// NewMatcher returns a Matcher.
+func NewMatcher(s matcher.Service, n *Normalizer) *Matcher {
+	return &Matcher{Service: s, n: n}
+}
+
+// Unwrap returns the wrapped matcher.Service.
+func (m *Matcher) Unwrap() matcher.Service {
+	return m.Service
+}
+
+// Scan implements matcher.Scanner.
+//
+// The returned report is a copy if any severity changed, as the wrapped
+// matcher may hand out shared, cached reports.
+func (m *Matcher) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	vr, err := m.Service.Scan(ctx, ir)
+	if err != nil || vr == nil {
+		return vr, err
+	}
+	var out *claircore.VulnerabilityReport
+	for id, v := range vr.Vulnerabilities {
+		nv := m.n.Apply(v)
+		if nv == v {
+			continue
+		}
+		if out == nil {
+			c := *vr
+			c.Vulnerabilities = make(map[string]*claircore.Vulnerability, len(vr.Vulnerabilities))
+			for k, v := range vr.Vulnerabilities {
+				c.Vulnerabilities[k] = v
+			}
+			out = &c
+		}
+		out.Vulnerabilities[id] = nv
+	}
+	if out == nil {
+		return vr, nil
+	}
+	return out, nil
+}
+
+// UpdateDiff implements matcher.Differ.
+func (m *Matcher) UpdateDiff(ctx context.Context, prev, cur uuid.UUID) (*driver.UpdateDiff, error) {
+	d, err := m.Service.UpdateDiff(ctx, prev, cur)
+	if err != nil || d == nil {
+		return d, err
+	}
+	for i := range d.Added {
+		d.Added[i].NormalizedSeverity = m.n.Normalize(&d.Added[i])
+	}
+	for i := range d.Removed {
+		d.Removed[i].NormalizedSeverity = m.n.Normalize(&d.Removed[i])
+	}
+	return d, nil
+}
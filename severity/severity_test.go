@@ -0,0 +1,94 @@
+package severity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+func TestNormalize(t *testing.T) {
+	n, err := NewNormalizer(
+		map[string]claircore.Severity{"Important": claircore.High, "moderate": claircore.Medium},
+		[]Rule{
+			{Updater: "RHEL", Mapping: map[string]claircore.Severity{"important": claircore.Critical}},
+			{Updater: "alpine", Floor: claircore.Low, Ceiling: claircore.Medium},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		Updater, Severity string
+		In, Want          claircore.Severity
+	}{
+		{"debian", "Important", claircore.Unknown, claircore.High},
+		{"debian", "MODERATE", claircore.Low, claircore.Medium},
+		{"debian", "Whatever", claircore.Low, claircore.Low},
+		{"RHEL8-updater", "Important", claircore.High, claircore.Critical},
+		{"RHEL8-updater", "Moderate", claircore.Low, claircore.Medium},
+		{"alpine-main-v3.12", "", claircore.Critical, claircore.Medium},
+		{"alpine-main-v3.12", "", claircore.Unknown, claircore.Low},
+		{"alpine-main-v3.12", "Important", claircore.Unknown, claircore.Medium},
+	}
+	for _, tc := range tt {
+		v := &claircore.Vulnerability{Updater: tc.Updater, Severity: tc.Severity, NormalizedSeverity: tc.In}
+		if got := n.Normalize(v); got != tc.Want {
+			t.Errorf("%s/%q/%v: got %v, want %v", tc.Updater, tc.Severity, tc.In, got, tc.Want)
+		}
+	}
+
+	if _, err := NewNormalizer(nil, []Rule{{Floor: claircore.High, Ceiling: claircore.Low}}); err == nil {
+		t.Error("expected error for inverted bounds")
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	ctx := context.Background()
+	orig := &claircore.VulnerabilityReport{
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"1": {ID: "1", Severity: "Important", NormalizedSeverity: claircore.Unknown},
+			"2": {ID: "2", Severity: "Low", NormalizedSeverity: claircore.Low},
+		},
+	}
+	n, err := NewNormalizer(map[string]claircore.Severity{"important": claircore.High}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMatcher(&matcher.Mock{
+		Scan_: func(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+			return orig, nil
+		},
+		UpdateDiff_: func(context.Context, uuid.UUID, uuid.UUID) (*driver.UpdateDiff, error) {
+			return &driver.UpdateDiff{
+				Added: []claircore.Vulnerability{{Severity: "important"}},
+			}, nil
+		},
+	}, n)
+
+	vr, err := m.Scan(ctx, &claircore.IndexReport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vr.Vulnerabilities["1"].NormalizedSeverity; got != claircore.High {
+		t.Errorf("got %v, want %v", got, claircore.High)
+	}
+	if vr.Vulnerabilities["2"] != orig.Vulnerabilities["2"] {
+		t.Error("unchanged vulnerability was copied")
+	}
+	if orig.Vulnerabilities["1"].NormalizedSeverity != claircore.Unknown {
+		t.Error("wrapped matcher's report was modified")
+	}
+
+	d, err := m.UpdateDiff(ctx, uuid.Nil, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Added[0].NormalizedSeverity; got != claircore.High {
+		t.Errorf("got %v, want %v", got, claircore.High)
+	}
+}
@@ -0,0 +1,193 @@
+// Package signing signs vulnerability reports as JWS documents, so systems
+// receiving an archived or relayed report can verify that Clair produced it
+// and that it hasn't been changed since.
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/quay/clair/v4/matcher"
+)
+
+// MediaType is the media type of a signed report, and the "typ" header of
+// its JWS.
+const MediaType = "application/vnd.clair.report.v1+jws"
+
+// Version is the version of the Document format.
+const Version = "v1"
+
+// Document is the payload of a signed report.
+type Document struct {
+	Version  string      `json:"version"`
+	IssuedAt time.Time   `json:"issued_at"`
+	Report   interface{} `json:"report"`
+}
+
+// Signer signs reports with a private key.
+type Signer struct {
+	signer jose.Signer
+	public jose.JSONWebKey
+	priv   crypto.Signer
+	hash   crypto.Hash
+
+	// Now is used for a Document's IssuedAt. Tests may replace it.
+	now func() time.Time
+}
+
+// NewSigner returns a Signer using the PEM encoded private key. RSA, ECDSA
+// (P-256, P-384, and P-521), and Ed25519 keys are supported, in PKCS #8,
+// PKCS #1, or SEC 1 form.
+//
+// If keyID is empty, the key's RFC 7638 thumbprint is used.
+func NewSigner(key []byte, keyID string) (*Signer, error) {
+	priv, err := parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var alg jose.SignatureAlgorithm
+	var pub crypto.PublicKey
+	h := crypto.SHA256
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		alg, pub = jose.RS256, k.Public()
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			alg = jose.ES256
+		case elliptic.P384():
+			alg, h = jose.ES384, crypto.SHA384
+		case elliptic.P521():
+			alg, h = jose.ES512, crypto.SHA512
+		default:
+			return nil, fmt.Errorf("signing: unsupported curve %q", k.Curve.Params().Name)
+		}
+		pub = k.Public()
+	case ed25519.PrivateKey:
+		alg, pub, h = jose.EdDSA, k.Public(), 0
+	default:
+		return nil, fmt.Errorf("signing: unsupported key type %T", priv)
+	}
+	jwk := jose.JSONWebKey{Key: pub, Algorithm: string(alg), Use: "sig"}
+	if keyID == "" {
+		tp, err := jwk.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("signing: unable to compute thumbprint: %w", err)
+		}
+		keyID = base64.RawURLEncoding.EncodeToString(tp)
+	}
+	jwk.KeyID = keyID
+	opts := (&jose.SignerOptions{}).WithType(MediaType)
+	s, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: alg,
+		Key:       jose.JSONWebKey{Key: priv, KeyID: keyID},
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	return &Signer{
+		signer: s,
+		public: jwk,
+		priv:   priv.(crypto.Signer),
+		hash:   h,
+		now:    time.Now,
+	}, nil
+}
+
+// ParseKey decodes the first PEM block holding a private key.
+func parseKey(b []byte) (crypto.PrivateKey, error) {
+	for {
+		var blk *pem.Block
+		blk, b = pem.Decode(b)
+		if blk == nil {
+			return nil, errors.New("signing: no private key found")
+		}
+		switch blk.Type {
+		case "PRIVATE KEY":
+			return x509.ParsePKCS8PrivateKey(blk.Bytes)
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(blk.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(blk.Bytes)
+		}
+	}
+}
+
+// Sign returns the report wrapped in a Document, signed and in JWS compact
+// serialization.
+func (s *Signer) Sign(report interface{}) ([]byte, error) {
+	b, err := json.Marshal(&Document{
+		Version:  Version,
+		IssuedAt: s.now().UTC(),
+		Report:   report,
+	})
+	if err != nil {
+		return nil, err
+	}
+	obj, err := s.signer.Sign(b)
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	out, err := obj.CompactSerialize()
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	return []byte(out), nil
+}
+
+// SignBytes returns a raw signature over b, for formats other than JWS,
+// like DSSE envelopes. ECDSA signatures are ASN.1 encoded and RSA ones use
+// PKCS #1 v1.5, both over the digest the key's JWS algorithm uses.
+func (s *Signer) SignBytes(b []byte) ([]byte, error) {
+	digest := b
+	if s.hash != 0 {
+		h := s.hash.New()
+		h.Write(b)
+		digest = h.Sum(nil)
+	}
+	sig, err := s.priv.Sign(rand.Reader, digest, s.hash)
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	return sig, nil
+}
+
+// KeyID returns the ID of the signing key.
+func (s *Signer) KeyID() string {
+	return s.public.KeyID
+}
+
+// Keys returns the key set verifiers should use.
+func (s *Signer) Keys() *jose.JSONWebKeySet {
+	return &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{s.public}}
+}
+
+// Matcher is a matcher.Service with a Signer, which the HTTP API uses to
+// serve signed reports.
+type Matcher struct {
+	matcher.Service
+	*Signer
+}
+
+// NewMatcher returns a Matcher signing reports from "m" with "s".
+func NewMatcher(m matcher.Service, s *Signer) *Matcher {
+	return &Matcher{Service: m, Signer: s}
+}
+
+// Unwrap returns the wrapped matcher.Service.
+func (m *Matcher) Unwrap() matcher.Service {
+	return m.Service
+}
@@ -0,0 +1,120 @@
+package tenant
+
+import (
+	"net/http"
+	"strings"
+
+	je "github.com/quay/claircore/pkg/jsonerr"
+	"github.com/rs/zerolog"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+// Source is where a request's tenant is taken from.
+type Source string
+
+// These are the supported tenant sources.
+const (
+	// Subject uses the "sub" claim of the request's JWT.
+	Subject Source = "subject"
+	// FromHeader uses the value of the Clair-Tenant header.
+	FromHeader Source = "header"
+)
+
+// Handler returns an http.Handler that determines the tenant of every
+// request and adds it to the request's Context.
+//
+// Requests that intra reports as coming from another Clair service are
+// trusted to name their tenant in the Clair-Tenant header, or to have none.
+// This must be decided by how the request was authenticated, not by the
+// claims of its token. Requests for paths
+// with one of the unscoped prefixes may also have no tenant. All other
+// requests without a tenant are rejected.
+//
+// Handler should be wrapped by any authentication middleware, as it doesn't
+// verify tokens itself.
+func Handler(next http.Handler, src Source, intra func(*http.Request) bool, unscoped ...string) http.Handler {
+	return &handler{
+		next:     next,
+		src:      src,
+		intra:    intra,
+		unscoped: unscoped,
+	}
+}
+
+type handler struct {
+	next     http.Handler
+	src      Source
+	intra    func(*http.Request) bool
+	unscoped []string
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cl, hasToken := claims(r)
+	var t string
+	intra := h.intra != nil && h.intra(r)
+	switch {
+	case intra:
+		t = r.Header.Get(Header)
+	case h.src == Subject && hasToken:
+		t = cl.Subject
+	case h.src == FromHeader:
+		t = r.Header.Get(Header)
+	}
+	if t == "" && !intra && !h.exempt(r.URL.Path) {
+		zerolog.Ctx(ctx).Debug().
+			Str("component", "tenant/handler.ServeHTTP").
+			Str("path", r.URL.Path).
+			Msg("rejecting request without tenant")
+		resp := &je.Response{
+			Code:    "forbidden",
+			Message: "request does not identify a tenant",
+		}
+		je.Error(w, resp, http.StatusForbidden)
+		return
+	}
+	if t != "" {
+		log := zerolog.Ctx(ctx).With().Str("tenant", t).Logger()
+		ctx = log.WithContext(WithTenant(ctx, t))
+		r = r.WithContext(ctx)
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+func (h *handler) exempt(p string) bool {
+	for _, pre := range h.unscoped {
+		if strings.HasPrefix(p, pre) {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims pulls the claims out of the request's bearer token, without
+// checking its signature. Claims learned by the authentication middleware,
+// like those of introspected opaque tokens, are used if present.
+func claims(r *http.Request) (jwt.Claims, bool) {
+	var cl jwt.Claims
+	if all, ok := auth.Claims(r.Context()); ok {
+		cl.Issuer, _ = all["iss"].(string)
+		cl.Subject, _ = all["sub"].(string)
+		return cl, true
+	}
+	for _, h := range r.Header["Authorization"] {
+		if !strings.HasPrefix(h, "Bearer ") {
+			continue
+		}
+		tok, err := jwt.ParseSigned(strings.TrimPrefix(h, "Bearer "))
+		if err != nil {
+			continue
+		}
+		if err := tok.UnsafeClaimsWithoutVerification(&cl); err != nil {
+			continue
+		}
+		return cl, true
+	}
+	return cl, false
+}
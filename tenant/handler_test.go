@@ -0,0 +1,159 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/quay/clair/v4/middleware/auth"
+)
+
+var (
+	clientKey = []byte("deadbeefdeadbeef")
+	intraKey  = []byte("cafebabecafebabe")
+)
+
+func token(t *testing.T, key []byte, iss, sub string) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: key}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := jwt.Signed(signer).Claims(jwt.Claims{Issuer: iss, Subject: sub}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+// Anonymous allows requests without a token, so the Handler can be tested
+// with requests naming their tenant in a header.
+type anonymous struct{}
+
+func (anonymous) Check(_ context.Context, r *http.Request) bool {
+	return r.Header.Get("authorization") == ""
+}
+
+// Authed wraps the Handler like the server does, only trusting tokens signed
+// with the intraservice key as coming from another service.
+func authed(t *testing.T, h http.Handler) http.Handler {
+	intra, err := auth.NewPSK(intraKey, []string{"clair-intraservice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := auth.NewPSK(clientKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth.Handler(h, auth.Intraservice(intra), client, anonymous{})
+}
+
+func TestHandler(t *testing.T) {
+	type testcase struct {
+		Name   string
+		Source Source
+		Path   string
+		Token  string
+		Header string
+		Status int
+		Tenant string
+	}
+	const report = "/indexer/api/v1/index_report/"
+	tt := []testcase{
+		{
+			Name:   "Subject",
+			Source: Subject,
+			Path:   report,
+			Token:  token(t, clientKey, "quay", "team-a"),
+			Header: "team-b",
+			Status: http.StatusOK,
+			Tenant: "team-a",
+		},
+		{
+			Name:   "SubjectMissing",
+			Source: Subject,
+			Path:   report,
+			Token:  token(t, clientKey, "quay", ""),
+			Status: http.StatusForbidden,
+		},
+		{
+			Name:   "Header",
+			Source: FromHeader,
+			Path:   report,
+			Header: "team-b",
+			Status: http.StatusOK,
+			Tenant: "team-b",
+		},
+		{
+			Name:   "HeaderMissing",
+			Source: FromHeader,
+			Path:   report,
+			Status: http.StatusForbidden,
+		},
+		{
+			Name:   "Intraservice",
+			Source: Subject,
+			Path:   report,
+			Token:  token(t, intraKey, "clair-intraservice", ""),
+			Header: "team-c",
+			Status: http.StatusOK,
+			Tenant: "team-c",
+		},
+		{
+			Name:   "IntraserviceUnscoped",
+			Source: Subject,
+			Path:   report,
+			Token:  token(t, intraKey, "clair-intraservice", ""),
+			Status: http.StatusOK,
+		},
+		{
+			Name:   "ClientIntraservice",
+			Source: Subject,
+			Path:   report,
+			Token:  token(t, clientKey, "clair-intraservice", ""),
+			Header: "team-c",
+			Status: http.StatusForbidden,
+		},
+		{
+			Name:   "ClientIntraserviceSubject",
+			Source: Subject,
+			Path:   report,
+			Token:  token(t, clientKey, "clair-intraservice", "team-a"),
+			Header: "team-c",
+			Status: http.StatusOK,
+			Tenant: "team-a",
+		},
+		{
+			Name:   "Unscoped",
+			Source: FromHeader,
+			Path:   "/indexer/api/v1/internal/affected_manifest/",
+			Status: http.StatusOK,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var got string
+			h := authed(t, Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = FromContext(r.Context())
+			}), tc.Source, auth.FromIntraservice, "/indexer/api/v1/internal/"))
+			req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			if tc.Token != "" {
+				req.Header.Set("authorization", "Bearer "+tc.Token)
+			}
+			if tc.Header != "" {
+				req.Header.Set(Header, tc.Header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, tc.Status; got != want {
+				t.Errorf("status: got: %d, want: %d", got, want)
+			}
+			if got, want := got, tc.Tenant; got != want {
+				t.Errorf("tenant: got: %q, want: %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	"github.com/quay/clair/v4/indexer"
+	"github.com/quay/clair/v4/indexer/inventory"
+)
+
+// Indexer wraps an indexer.Service, recording the tenant submitting each
+// manifest and hiding index reports from tenants that didn't submit them.
+//
+// Requests without a tenant are passed through untouched.
+type Indexer struct {
+	indexer.Service
+	store *Store
+}
+
+var (
+	_ indexer.Service       = (*Indexer)(nil)
+	_ indexer.AffectedPager = (*Indexer)(nil)
+	_ inventory.Lister      = (*Indexer)(nil)
+)
+
+// NewIndexer returns an Indexer using the provided Store.
+func NewIndexer(s indexer.Service, st *Store) *Indexer {
+	return &Indexer{
+		Service: s,
+		store:   st,
+	}
+}
+
+// Unwrap returns the wrapped indexer.Service.
+func (i *Indexer) Unwrap() indexer.Service {
+	return i.Service
+}
+
+// Index implements indexer.Indexer.
+//
+// The manifest is recorded as the tenant's before indexing, so that a
+// concurrent request for the report doesn't spuriously fail.
+func (i *Indexer) Index(ctx context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+	if t, ok := FromContext(ctx); ok {
+		if err := i.store.Record(ctx, t, m.Hash); err != nil {
+			return nil, err
+		}
+	}
+	return i.Service.Index(ctx, m)
+}
+
+// IndexReport implements indexer.Reporter.
+//
+// Reports for manifests the tenant hasn't submitted are reported as not
+// existing, so as to not reveal which manifests other tenants have.
+func (i *Indexer) IndexReport(ctx context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	if t, ok := FromContext(ctx); ok {
+		owns, err := i.store.Owns(ctx, t, d)
+		if err != nil {
+			return nil, false, err
+		}
+		if !owns {
+			zerolog.Ctx(ctx).Debug().
+				Str("component", "tenant/Indexer.IndexReport").
+				Str("manifest", d.String()).
+				Msg("manifest not submitted by tenant")
+			return nil, false, nil
+		}
+	}
+	return i.Service.IndexReport(ctx, d)
+}
+
+// List implements inventory.Lister, if the wrapped indexer lists manifests.
+//
+// Only manifests the tenant has submitted are listed. Pages are filled from
+// as many of the wrapped indexer's pages as it takes.
+func (i *Indexer) List(ctx context.Context, q *inventory.Query) (*inventory.Page, error) {
+	l, ok := inventory.Find(i.Service)
+	if !ok {
+		return nil, inventory.ErrNotConfigured
+	}
+	t, ok := FromContext(ctx)
+	if !ok {
+		return l.List(ctx, q)
+	}
+	size := q.PageSize()
+	out := &inventory.Page{Manifests: []inventory.Manifest{}}
+	inner := *q
+	for {
+		p, err := l.List(ctx, &inner)
+		if err != nil {
+			return nil, err
+		}
+		ds := make([]claircore.Digest, len(p.Manifests))
+		for n, m := range p.Manifests {
+			ds[n] = m.Hash
+		}
+		owned, err := i.store.Owned(ctx, t, ds)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range p.Manifests {
+			if !owned[m.Hash.String()] {
+				continue
+			}
+			if len(out.Manifests) == size {
+				out.Next = m.Hash.String()
+				return out, nil
+			}
+			out.Manifests = append(out.Manifests, m)
+		}
+		if p.Next == "" {
+			return out, nil
+		}
+		inner.Next = p.Next
+	}
+}
+
+// AffectedManifests implements indexer.Affected.
+//
+// Only manifests the tenant has submitted are reported.
+func (i *Indexer) AffectedManifests(ctx context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+	a, err := i.Service.AffectedManifests(ctx, vs)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := FromContext(ctx)
+	if !ok {
+		return a, nil
+	}
+	owned, err := i.store.hashes(ctx, ownedManifests, t, affectedHashes(a))
+	if err != nil {
+		return nil, err
+	}
+	return indexer.Subset(a, owned), nil
+}
+
+// AffectedManifestsPage implements indexer.AffectedPager.
+//
+// Only manifests the tenant has submitted are reported, and the page is
+// picked out of them by the database.
+func (i *Indexer) AffectedManifestsPage(ctx context.Context, vs []claircore.Vulnerability, next string, size int) (*claircore.AffectedManifests, string, error) {
+	a, err := i.Service.AffectedManifests(ctx, vs)
+	if err != nil {
+		return nil, "", err
+	}
+	t, ok := FromContext(ctx)
+	if !ok {
+		p, n := indexer.PageAffected(a, next, size)
+		return p, n, nil
+	}
+	hs, err := i.store.Page(ctx, t, affectedHashes(a), next, size+1)
+	if err != nil {
+		return nil, "", err
+	}
+	var following string
+	if len(hs) > size {
+		following = hs[size]
+		hs = hs[:size]
+	}
+	return indexer.Subset(a, hs), following, nil
+}
+
+// AffectedHashes returns the hashes of the affected manifests.
+func affectedHashes(a *claircore.AffectedManifests) []string {
+	hs := make([]string, 0, len(a.VulnerableManifests))
+	for h := range a.VulnerableManifests {
+		hs = append(hs, h)
+	}
+	return hs
+}
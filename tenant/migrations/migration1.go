@@ -0,0 +1,14 @@
+package migrations
+
+const (
+	// migration1 adds the record of which tenants submitted which manifests.
+	migration1 = `
+	--- a relation recording the tenants a manifest was submitted by
+	CREATE TABLE IF NOT EXISTS tenant_manifest
+	(
+		tenant        text NOT NULL,
+		manifest_hash text NOT NULL,
+		PRIMARY KEY (tenant, manifest_hash)
+	);
+	`
+)
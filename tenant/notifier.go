@@ -0,0 +1,123 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/quay/claircore"
+
+	"github.com/quay/clair/v4/notifier"
+	"github.com/quay/clair/v4/notifier/service"
+)
+
+// Notifier wraps a notifier service.Service, only returning notifications
+// about manifests the requesting tenant submitted.
+//
+// Requests without a tenant are passed through untouched.
+type Notifier struct {
+	service.Service
+	store *Store
+}
+
+var (
+	_ service.Service = (*Notifier)(nil)
+	_ notifier.Owners = (*Store)(nil)
+)
+
+// NewNotifier returns a Notifier using the provided Store.
+func NewNotifier(s service.Service, st *Store) *Notifier {
+	return &Notifier{
+		Service: s,
+		store:   st,
+	}
+}
+
+// Notifications implements service.Service.
+//
+// Notifications are filtered after paging, so a page may hold fewer
+// notifications than requested, or none, without being the last.
+func (n *Notifier) Notifications(ctx context.Context, id uuid.UUID, page *notifier.Page) ([]notifier.Notification, notifier.Page, error) {
+	ns, p, err := n.Service.Notifications(ctx, id, page)
+	t, ok := FromContext(ctx)
+	if err != nil || !ok {
+		return ns, p, err
+	}
+	ds := make([]claircore.Digest, 0, len(ns))
+	for _, nt := range ns {
+		ds = append(ds, nt.Manifest)
+	}
+	owned, err := n.store.Owned(ctx, t, ds)
+	if err != nil {
+		return nil, p, err
+	}
+	out := ns[:0]
+	for _, nt := range ns {
+		if owned[nt.Manifest.String()] {
+			out = append(out, nt)
+		}
+	}
+	return out, p, nil
+}
+
+// DeleteNotifications implements service.Service.
+//
+// A notification covers manifests belonging to any number of tenants, so
+// tenants aren't allowed to delete them.
+func (n *Notifier) DeleteNotifications(ctx context.Context, id uuid.UUID) error {
+	if _, ok := FromContext(ctx); ok {
+		return ErrForbidden
+	}
+	return n.Service.DeleteNotifications(ctx, id)
+}
+
+// PurgeDelivered implements service.Service.
+//
+// Tenants aren't allowed to purge notifications, for the same reason they
+// can't delete them.
+func (n *Notifier) PurgeDelivered(ctx context.Context, uoid uuid.UUID) (int64, error) {
+	if _, ok := FromContext(ctx); ok {
+		return 0, ErrForbidden
+	}
+	return n.Service.PurgeDelivered(ctx, uoid)
+}
+
+// DeadLetters implements service.Service.
+//
+// Failed deliveries aren't scoped to a tenant, so tenants may not see them.
+func (n *Notifier) DeadLetters(ctx context.Context) ([]notifier.Receipt, error) {
+	if _, ok := FromContext(ctx); ok {
+		return nil, ErrForbidden
+	}
+	return n.Service.DeadLetters(ctx)
+}
+
+// Replay implements service.Service.
+//
+// Tenants aren't allowed to replay notifications, as every subscriber would
+// receive them again.
+func (n *Notifier) Replay(ctx context.Context, ids ...uuid.UUID) (int64, error) {
+	if _, ok := FromContext(ctx); ok {
+		return 0, ErrForbidden
+	}
+	return n.Service.Replay(ctx, ids...)
+}
+
+// Deliveries implements service.Service.
+//
+// Delivery isn't scoped to a tenant, so tenants may not see it.
+func (n *Notifier) Deliveries(ctx context.Context, id uuid.UUID) ([]notifier.DeliveryStatus, error) {
+	if _, ok := FromContext(ctx); ok {
+		return nil, ErrForbidden
+	}
+	return n.Service.Deliveries(ctx, id)
+}
+
+// Backlog implements service.Service.
+//
+// Delivery isn't scoped to a tenant, so tenants may not see it.
+func (n *Notifier) Backlog(ctx context.Context) (notifier.Backlog, error) {
+	if _, ok := FromContext(ctx); ok {
+		return notifier.Backlog{}, ErrForbidden
+	}
+	return n.Service.Backlog(ctx)
+}
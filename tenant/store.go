@@ -0,0 +1,115 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/quay/claircore"
+)
+
+// Store records which tenants have submitted which manifests.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the database behind pool, which must have
+// had the migrations in the migrations package applied.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+const recordManifest = `
+INSERT INTO tenant_manifest (tenant, manifest_hash) VALUES ($1, $2)
+ON CONFLICT DO NOTHING;`
+
+// Record notes that the tenant submitted the manifest.
+func (s *Store) Record(ctx context.Context, t string, d claircore.Digest) error {
+	_, err := s.pool.Exec(ctx, recordManifest, t, d.String())
+	return err
+}
+
+const ownedManifests = `
+SELECT manifest_hash FROM tenant_manifest
+WHERE tenant = $1 AND manifest_hash = ANY($2::text[]);`
+
+// Owned reports which of the manifests the tenant has submitted.
+func (s *Store) Owned(ctx context.Context, t string, ds []claircore.Digest) (map[string]bool, error) {
+	hs := make([]string, len(ds))
+	for i, d := range ds {
+		hs[i] = d.String()
+	}
+	owned, err := s.hashes(ctx, ownedManifests, t, hs)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(owned))
+	for _, h := range owned {
+		out[h] = true
+	}
+	return out, nil
+}
+
+const manifestOwners = `
+SELECT manifest_hash, tenant FROM tenant_manifest
+WHERE manifest_hash = ANY($1::text[]);`
+
+// Owners implements notifier.Owners.
+func (s *Store) Owners(ctx context.Context, ds []claircore.Digest) (map[string][]string, error) {
+	hs := make([]string, len(ds))
+	for i, d := range ds {
+		hs[i] = d.String()
+	}
+	rows, err := s.pool.Query(ctx, manifestOwners, hs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string][]string)
+	for rows.Next() {
+		var h, t string
+		if err := rows.Scan(&h, &t); err != nil {
+			return nil, err
+		}
+		out[h] = append(out[h], t)
+	}
+	return out, rows.Err()
+}
+
+const pageManifests = `
+SELECT manifest_hash FROM tenant_manifest
+WHERE tenant = $1 AND manifest_hash = ANY($2::text[]) AND manifest_hash >= $3
+ORDER BY manifest_hash
+LIMIT $4;`
+
+// Page reports up to n of the manifest hashes the tenant has submitted, in
+// order, starting at next.
+func (s *Store) Page(ctx context.Context, t string, hs []string, next string, n int) ([]string, error) {
+	return s.hashes(ctx, pageManifests, t, hs, next, n)
+}
+
+// Hashes runs a query returning manifest hashes.
+func (s *Store) hashes(ctx context.Context, q string, args ...interface{}) ([]string, error) {
+	rows, err := s.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// Owns reports whether the tenant has submitted the manifest.
+func (s *Store) Owns(ctx context.Context, t string, d claircore.Digest) (bool, error) {
+	m, err := s.Owned(ctx, t, []claircore.Digest{d})
+	if err != nil {
+		return false, err
+	}
+	return m[d.String()], nil
+}
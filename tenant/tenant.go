@@ -0,0 +1,34 @@
+// Package tenant scopes manifests, reports, and notifications to the tenant
+// that submitted them, so that one Clair can be shared by teams that must not
+// see each other's data.
+//
+// A request's tenant comes from its JWT subject or a header, depending on
+// configuration. Requests between Clair services carry the tenant of the
+// request that caused them in a header.
+package tenant
+
+import (
+	"context"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// Header is the HTTP header carrying a tenant.
+const Header = "Clair-Tenant"
+
+// ErrForbidden is returned for operations a tenant isn't allowed to perform.
+var ErrForbidden = clairerror.New(clairerror.Forbidden, "tenant: operation not permitted")
+
+type ctxKey struct{}
+
+// WithTenant returns a Context carrying the tenant.
+func WithTenant(ctx context.Context, t string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromContext reports the tenant carried by the Context. The second value
+// is false for requests not scoped to a tenant.
+func FromContext(ctx context.Context) (string, bool) {
+	t, ok := ctx.Value(ctxKey{}).(string)
+	return t, ok && t != ""
+}
@@ -0,0 +1,152 @@
+package updaters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Egress holds the HTTP clients of updater sets and updaters configured to
+// fetch through their own proxy or from mirrors, keyed by name.
+//
+// Sets and updaters not present use the client libvuln hands them.
+type Egress map[string]*http.Client
+
+// EgressConfig is the part of an updater set's or updater's configuration
+// block read by NewEgress. The updater itself is free to ignore it.
+type EgressConfig struct {
+	// Proxy is the URL of the proxy requests are made through, instead of
+	// the one named by the environment.
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// Mirror maps URL prefixes to their replacements. Requests for a URL
+	// starting with a key are sent to the value with the rest of the URL
+	// appended. The longest matching key is used.
+	Mirror map[string]string `json:"mirror" yaml:"mirror"`
+}
+
+// NewEgress reads the egress configuration out of the provided configuration
+// blocks, returning a client for every name that has any.
+func NewEgress(cfgs map[string]driver.ConfigUnmarshaler) (Egress, error) {
+	e := make(Egress)
+	for name, cfg := range cfgs {
+		if strings.HasPrefix(name, Prefix) {
+			continue
+		}
+		var ec EgressConfig
+		if err := cfg(&ec); err != nil {
+			// Not every block is an object. Whatever it is, the updater will
+			// report it.
+			continue
+		}
+		if ec.Proxy == "" && len(ec.Mirror) == 0 {
+			continue
+		}
+		c, err := ec.client()
+		if err != nil {
+			return nil, fmt.Errorf("updater %q: %w", name, err)
+		}
+		e[name] = c
+	}
+	return e, nil
+}
+
+// Wrap wraps the transport of every client with the bandwidth limit and
+// cache in s.
+func (e Egress) Wrap(s *Shared) {
+	for _, c := range e {
+		c.Transport = s.Wrap(c.Transport)
+	}
+}
+
+// Client builds an http.Client from http.DefaultTransport, which is what
+// libvuln uses absent other configuration.
+func (ec *EgressConfig) client() (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if ec.Proxy != "" {
+		u, err := parseBase(ec.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("bad proxy: %w", err)
+		}
+		tr.Proxy = http.ProxyURL(u)
+	}
+	if len(ec.Mirror) == 0 {
+		return &http.Client{Transport: tr}, nil
+	}
+	m := &mirror{next: tr}
+	for from, to := range ec.Mirror {
+		if _, err := parseBase(from); err != nil {
+			return nil, fmt.Errorf("bad mirrored URL: %w", err)
+		}
+		if _, err := parseBase(to); err != nil {
+			return nil, fmt.Errorf("bad mirror URL: %w", err)
+		}
+		m.from = append(m.from, from)
+		m.to = append(m.to, to)
+	}
+	sort.Sort(m)
+	return &http.Client{Transport: m}, nil
+}
+
+// ParseBase parses an absolute URL.
+func parseBase(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute URL", s)
+	}
+	return u, nil
+}
+
+// Mirror is an http.RoundTripper rewriting request URLs by prefix.
+//
+// The prefixes are kept sorted longest first, so the first match is the
+// longest.
+type mirror struct {
+	next     http.RoundTripper
+	from, to []string
+}
+
+func (m *mirror) Len() int           { return len(m.from) }
+func (m *mirror) Less(i, j int) bool { return len(m.from[i]) > len(m.from[j]) }
+func (m *mirror) Swap(i, j int) {
+	m.from[i], m.from[j] = m.from[j], m.from[i]
+	m.to[i], m.to[j] = m.to[j], m.to[i]
+}
+
+// RoundTrip implements http.RoundTripper.
+func (m *mirror) RoundTrip(r *http.Request) (*http.Response, error) {
+	s := r.URL.String()
+	for i, from := range m.from {
+		if !strings.HasPrefix(s, from) {
+			continue
+		}
+		u, err := url.Parse(m.to[i] + s[len(from):])
+		if err != nil {
+			return nil, fmt.Errorf("updaters: unable to rewrite %q: %w", s, err)
+		}
+		r = r.Clone(r.Context())
+		r.URL = u
+		r.Host = ""
+		break
+	}
+	return m.next.RoundTrip(r)
+}
+
+// Egressed is an Updater using its own client, whatever client it's later
+// handed.
+type egressed struct {
+	driver.Updater
+	client *http.Client
+}
+
+// Configure implements driver.Configurable.
+func (u *egressed) Configure(ctx context.Context, f driver.ConfigUnmarshaler, _ *http.Client) error {
+	return u.Updater.(driver.Configurable).Configure(ctx, f, u.client)
+}
@@ -0,0 +1,93 @@
+package updaters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/updater"
+)
+
+func TestEgress(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+	}))
+	defer srv.Close()
+	block := func(s string) driver.ConfigUnmarshaler {
+		return func(v interface{}) error { return json.Unmarshal([]byte(s), v) }
+	}
+	e, err := NewEgress(map[string]driver.ConfigUnmarshaler{
+		"mirrored": block(`{"mirror":{
+			"http://upstream.invalid/": "` + srv.URL + `/mirror/",
+			"http://upstream.invalid/deep/": "` + srv.URL + `/deep/"
+		}}`),
+		"plain":  block(`{"url":"elsewhere"}`),
+		"scalar": block(`"string"`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e["plain"]; ok {
+		t.Error("client created without egress configuration")
+	}
+	c, ok := e["mirrored"]
+	if !ok {
+		t.Fatal("no client for mirrored updater")
+	}
+	for _, tc := range []struct{ in, want string }{
+		{"http://upstream.invalid/feed.json", "/mirror/feed.json"},
+		{"http://upstream.invalid/deep/feed.json", "/deep/feed.json"},
+	} {
+		res, err := c.Get(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got != tc.want {
+			t.Errorf("%s: got: %q, want: %q", tc.in, got, tc.want)
+		}
+	}
+
+	if _, err := NewEgress(map[string]driver.ConfigUnmarshaler{
+		"bad": block(`{"proxy":"not a url"}`),
+	}); err == nil {
+		t.Error("expected error for bad proxy")
+	}
+}
+
+// TestFactoryEgress checks that updaters keep their egress client when
+// libvuln configures them.
+func TestFactoryEgress(t *testing.T) {
+	ctx := context.Background()
+	inner := &testFactory{
+		updaters: []*testUpdater{{name: "egress-one"}, {name: "egress-two"}},
+	}
+	updater.Register("egress-test", inner)
+	set, one := &http.Client{}, &http.Client{}
+	sets := Register(NewOverrides(ctx, nil), Egress{"egress-test": set, "egress-one": one}, []string{"egress-test"})
+	f := updater.Registered()[sets[0]]
+	if err := f.(driver.Configurable).Configure(ctx, noConfig, http.DefaultClient); err != nil {
+		t.Fatal(err)
+	}
+	s, err := f.UpdaterSet(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range s.Updaters() {
+		if err := u.(driver.Configurable).Configure(ctx, noConfig, http.DefaultClient); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.updaters[0].client != one {
+		t.Error("updater client not used")
+	}
+	if inner.updaters[1].client != set {
+		t.Error("set client not used")
+	}
+}
+
+func noConfig(interface{}) error { return nil }
@@ -0,0 +1,189 @@
+package updaters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/updater"
+	"github.com/rs/zerolog"
+)
+
+// Prefix is prepended to the name of an updater set to name its wrapped
+// factory in the updater registry.
+//
+// The registry doesn't allow replacing factories, so the wrapped ones are
+// registered alongside the originals and selected by name instead.
+const Prefix = "override/"
+
+// Register registers a factory consulting the Overrides for each registered
+// updater set named in sets, or every registered set if sets is nil. It
+// returns the names of the wrapped factories, to be used in place of sets
+// when configuring libvuln.
+//
+// Configuration for the wrapped factories must also be provided under the
+// prefixed names. The sets and updaters named in e use their client from e
+// instead of the one libvuln provides.
+func Register(o *Overrides, e Egress, sets []string) []string {
+	fs := updater.Registered()
+	if sets == nil {
+		sets = make([]string, 0, len(fs))
+		for name := range fs {
+			if strings.HasPrefix(name, Prefix) {
+				continue
+			}
+			sets = append(sets, name)
+		}
+		sort.Strings(sets)
+	}
+	out := make([]string, 0, len(sets))
+	for _, name := range sets {
+		f, ok := fs[name]
+		if !ok {
+			continue
+		}
+		if _, ok := fs[Prefix+name]; !ok {
+			updater.Register(Prefix+name, &factory{name: name, inner: f, o: o, egress: e})
+		}
+		out = append(out, Prefix+name)
+	}
+	return out
+}
+
+// Factory wraps an UpdaterSetFactory, applying overrides to the set and its
+// updaters every time the set is constructed. The updaters are instrumented
+// to report their freshness.
+type factory struct {
+	name   string
+	inner  driver.UpdaterSetFactory
+	o      *Overrides
+	egress Egress
+
+	mu      sync.Mutex
+	static  driver.ConfigUnmarshaler
+	client  *http.Client
+	applied json.RawMessage
+}
+
+var (
+	_ driver.UpdaterSetFactory = (*factory)(nil)
+	_ driver.Configurable      = (*factory)(nil)
+)
+
+// Configure implements driver.Configurable.
+//
+// The configuration is handed to the wrapped factory, unless an override
+// configuration is in place.
+func (f *factory) Configure(ctx context.Context, cfg driver.ConfigUnmarshaler, c *http.Client) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ec, ok := f.egress[f.name]; ok {
+		c = ec
+	}
+	f.static, f.client = cfg, c
+	ov, _ := f.o.Get(ctx, f.name)
+	return f.configure(ctx, ov.Config)
+}
+
+// Configure configures the wrapped factory with the override configuration,
+// or the static configuration if there's no override. The caller must hold
+// the lock.
+func (f *factory) configure(ctx context.Context, override json.RawMessage) error {
+	inner, ok := f.inner.(driver.Configurable)
+	if !ok {
+		return nil
+	}
+	cfg := f.static
+	switch {
+	case override != nil:
+		cfg = decoder(override)
+	case cfg == nil:
+		// An override was removed and there's nothing to go back to, so
+		// configure the defaults.
+		cfg = func(interface{}) error { return nil }
+	}
+	if err := inner.Configure(ctx, cfg, f.client); err != nil {
+		return err
+	}
+	f.applied = override
+	return nil
+}
+
+// UpdaterSet implements driver.UpdaterSetFactory.
+func (f *factory) UpdaterSet(ctx context.Context) (driver.UpdaterSet, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("component", "updaters/factory.UpdaterSet").
+		Str("set", f.name).
+		Logger()
+	out := driver.NewUpdaterSet()
+	setOv, _ := f.o.Get(ctx, f.name)
+	if setOv.Disabled {
+		log.Info().Msg("updater set disabled by override")
+		return out, nil
+	}
+	f.mu.Lock()
+	var err error
+	if !bytes.Equal(setOv.Config, f.applied) {
+		err = f.configure(ctx, setOv.Config)
+	}
+	client := f.client
+	f.mu.Unlock()
+	if err != nil {
+		return out, err
+	}
+
+	set, err := f.inner.UpdaterSet(ctx)
+	if err != nil {
+		return out, err
+	}
+	for _, u := range set.Updaters() {
+		ov, _ := f.o.Get(ctx, u.Name())
+		if ov.Disabled {
+			log.Info().Str("updater", u.Name()).Msg("updater disabled by override")
+			continue
+		}
+		uc, egress := f.egress[u.Name()]
+		if !egress {
+			_, egress = f.egress[f.name]
+			uc = client
+		}
+		if c, ok := u.(driver.Configurable); ok && ov.Config != nil {
+			if err := c.Configure(ctx, decoder(ov.Config), uc); err != nil {
+				log.Warn().Err(err).Str("updater", u.Name()).Msg("failed to apply override configuration, excluding updater")
+				continue
+			}
+			u = &configured{Updater: u, cfg: ov.Config}
+		}
+		if _, ok := u.(driver.Configurable); ok && egress {
+			// Libvuln configures updaters with its own client after this.
+			u = &egressed{Updater: u, client: uc}
+		}
+		if err := out.Add(&instrumented{Updater: u, m: freshness}); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// Configured is an Updater with an override configuration, which replaces
+// whatever configuration it's later handed.
+type configured struct {
+	driver.Updater
+	cfg json.RawMessage
+}
+
+// Configure implements driver.Configurable.
+func (u *configured) Configure(ctx context.Context, _ driver.ConfigUnmarshaler, c *http.Client) error {
+	return u.Updater.(driver.Configurable).Configure(ctx, decoder(u.cfg), c)
+}
+
+func decoder(b json.RawMessage) driver.ConfigUnmarshaler {
+	return func(v interface{}) error {
+		return json.Unmarshal(b, v)
+	}
+}
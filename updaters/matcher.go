@@ -0,0 +1,24 @@
+package updaters
+
+import (
+	"github.com/quay/clair/v4/matcher"
+)
+
+// Matcher wraps a matcher.Service whose updaters consult the Overrides, so
+// that the overrides can be managed alongside it.
+type Matcher struct {
+	matcher.Service
+	*Overrides
+}
+
+var _ matcher.Service = (*Matcher)(nil)
+
+// NewMatcher returns a Matcher.
+func NewMatcher(s matcher.Service, o *Overrides) *Matcher {
+	return &Matcher{Service: s, Overrides: o}
+}
+
+// Unwrap returns the wrapped matcher.Service.
+func (m *Matcher) Unwrap() matcher.Service {
+	return m.Service
+}
@@ -0,0 +1,152 @@
+package updaters
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Freshness records how recently each updater ran successfully, for
+// alerting on stale vulnerability data.
+//
+// Only the process running the updaters has anything to report. With leader
+// election, that's whichever matcher holds the lock.
+var freshness = newFreshnessMetrics()
+
+// FreshnessMetrics holds the latest run of every updater and reports it on
+// collection.
+type freshnessMetrics struct {
+	mu   sync.Mutex
+	runs map[string]run
+}
+
+// Run is the outcome of an updater's latest successful run.
+type run struct {
+	Finished time.Time
+	Duration time.Duration
+	// Vulns is the number of vulnerabilities parsed the last time the
+	// updater's data changed, or -1 if it hasn't changed since startup.
+	Vulns int64
+}
+
+func newFreshnessMetrics() *freshnessMetrics {
+	f := &freshnessMetrics{runs: make(map[string]run)}
+	var success, duration, vulns metric.Float64ValueObserver
+	b := metric.Must(otel.Meter("clair")).NewBatchObserver(func(_ context.Context, res metric.BatchObserverResult) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for name, r := range f.runs {
+			ls := []label.KeyValue{label.String("updater", name)}
+			res.Observe(ls,
+				success.Observation(float64(r.Finished.UnixNano())/1e9),
+				duration.Observation(r.Duration.Seconds()),
+			)
+			if r.Vulns >= 0 {
+				res.Observe(ls, vulns.Observation(float64(r.Vulns)))
+			}
+		}
+	})
+	success = b.NewFloat64ValueObserver(
+		"clair_updater_last_success_timestamp",
+		metric.WithDescription("unix time an updater last finished successfully"),
+	)
+	duration = b.NewFloat64ValueObserver(
+		"clair_updater_last_run_duration",
+		metric.WithDescription("seconds an updater's last successful run took"),
+	)
+	vulns = b.NewFloat64ValueObserver(
+		"clair_updater_vulnerabilities_total",
+		metric.WithDescription("number of vulnerabilities an updater last provided"),
+	)
+	return f
+}
+
+// Record records a successful run. A negative vulns keeps the previously
+// recorded count, for runs where the data didn't change.
+func (f *freshnessMetrics) record(name string, start time.Time, vulns int64) {
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	prev, ok := f.runs[name]
+	if vulns < 0 {
+		vulns = -1
+		if ok {
+			vulns = prev.Vulns
+		}
+	}
+	f.runs[name] = run{
+		Finished: now,
+		Duration: now.Sub(start),
+		Vulns:    vulns,
+	}
+}
+
+// Get returns the latest run recorded for the updater.
+func (f *freshnessMetrics) get(name string) (run, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.runs[name]
+	return r, ok
+}
+
+// Instrumented is an Updater that records its runs in the freshness metrics.
+//
+// A run is timed from the start of Fetch. It counts as successful once Fetch
+// reports the data unchanged or Parse returns, as the vulnerability store
+// isn't visible from here.
+type instrumented struct {
+	driver.Updater
+	m *freshnessMetrics
+
+	mu    sync.Mutex
+	start time.Time
+}
+
+var (
+	_ driver.Updater      = (*instrumented)(nil)
+	_ driver.Configurable = (*instrumented)(nil)
+)
+
+// Fetch implements driver.Updater.
+func (u *instrumented) Fetch(ctx context.Context, fp driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	start := time.Now()
+	u.mu.Lock()
+	u.start = start
+	u.mu.Unlock()
+	rc, next, err := u.Updater.Fetch(ctx, fp)
+	if errors.Is(err, driver.Unchanged) {
+		u.m.record(u.Name(), start, -1)
+	}
+	return rc, next, err
+}
+
+// Parse implements driver.Updater.
+func (u *instrumented) Parse(ctx context.Context, rc io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	vs, err := u.Updater.Parse(ctx, rc)
+	if err != nil {
+		return vs, err
+	}
+	u.mu.Lock()
+	start := u.start
+	u.mu.Unlock()
+	u.m.record(u.Name(), start, int64(len(vs)))
+	return vs, nil
+}
+
+// Configure implements driver.Configurable, configuring the wrapped Updater
+// if it's configurable.
+func (u *instrumented) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	if cfg, ok := u.Updater.(driver.Configurable); ok {
+		return cfg.Configure(ctx, f, c)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package migrations
+
+const (
+	// migration1 adds storage for runtime updater overrides.
+	migration1 = `
+	--- a relation holding overrides for updater sets and updaters, by name
+	CREATE TABLE IF NOT EXISTS updater_override
+	(
+		name     text PRIMARY KEY,
+		disabled boolean     NOT NULL DEFAULT false,
+		config   jsonb,
+		updated  timestamptz NOT NULL DEFAULT now()
+	);
+	`
+)
@@ -0,0 +1,161 @@
+// Package updaters allows updater sets and updaters to be disabled and
+// reconfigured while Clair is running.
+//
+// Overrides are keyed by name: either the name of an updater set (e.g.
+// "ubuntu") or the name of a single updater (e.g. "ubuntu-focal-updater").
+// They're kept in the matcher's database, so every matcher sharing it picks
+// them up, and they survive restarts.
+package updaters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// RefreshInterval is how often Overrides reloads stored overrides, to pick up
+// changes made through other matcher instances.
+const RefreshInterval = time.Minute
+
+// ErrInvalid is returned, wrapped, for overrides that can't be applied.
+var ErrInvalid = clairerror.New(clairerror.Invalid, "updaters: invalid override")
+
+// Override changes how an updater set or updater runs.
+type Override struct {
+	// Disabled excludes the updater set or updater from update runs.
+	Disabled bool `json:"disabled"`
+	// Config, if present, is used in place of the configuration from the
+	// config file. It must be a JSON object and is decoded the same way as
+	// the configuration block it replaces.
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// Validate reports whether the Override can be applied to the name.
+func (o *Override) Validate(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalid)
+	}
+	cfg := bytes.TrimSpace(o.Config)
+	if len(cfg) == 0 || bytes.Equal(cfg, []byte("null")) {
+		o.Config = nil
+		return nil
+	}
+	if cfg[0] != '{' || !json.Valid(cfg) {
+		return fmt.Errorf("%w: %q: config must be a JSON object", ErrInvalid, name)
+	}
+	o.Config = json.RawMessage(cfg)
+	return nil
+}
+
+// Overrides is the current set of overrides.
+//
+// A nil Store keeps overrides in memory only.
+type Overrides struct {
+	store *Store
+
+	mu     sync.RWMutex
+	cur    map[string]Override
+	loaded time.Time
+}
+
+// NewOverrides returns Overrides backed by the Store, which may be nil.
+func NewOverrides(ctx context.Context, store *Store) *Overrides {
+	o := &Overrides{
+		store: store,
+		cur:   make(map[string]Override),
+	}
+	o.refresh(ctx, true)
+	return o
+}
+
+// Refresh reloads stored overrides if they're stale, or unconditionally if
+// force is set.
+func (o *Overrides) refresh(ctx context.Context, force bool) {
+	if o.store == nil {
+		return
+	}
+	o.mu.RLock()
+	fresh := time.Since(o.loaded) < RefreshInterval
+	o.mu.RUnlock()
+	if fresh && !force {
+		return
+	}
+
+	m, err := o.store.Overrides(ctx)
+	if err != nil {
+		// Keep using what was loaded before.
+		zerolog.Ctx(ctx).Warn().
+			Str("component", "updaters/Overrides.refresh").
+			Err(err).
+			Msg("failed to load updater overrides")
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.cur = m
+	o.loaded = time.Now()
+}
+
+// Get returns the override for the name, if any.
+func (o *Overrides) Get(ctx context.Context, name string) (Override, bool) {
+	o.refresh(ctx, false)
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	ov, ok := o.cur[name]
+	return ov, ok
+}
+
+// List returns all the overrides, keyed by name.
+func (o *Overrides) List(ctx context.Context) map[string]Override {
+	o.refresh(ctx, false)
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	out := make(map[string]Override, len(o.cur))
+	for k, v := range o.cur {
+		out[k] = v
+	}
+	return out
+}
+
+// Set stores the override for the name, replacing any existing one. It
+// takes effect at the next update run.
+func (o *Overrides) Set(ctx context.Context, name string, ov Override) error {
+	if err := ov.Validate(name); err != nil {
+		return err
+	}
+	if o.store != nil {
+		if err := o.store.Put(ctx, name, ov); err != nil {
+			return err
+		}
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.cur[name] = ov
+	return nil
+}
+
+// Delete removes the override for the name, reporting whether it existed.
+func (o *Overrides) Delete(ctx context.Context, name string) (bool, error) {
+	ok := false
+	if o.store != nil {
+		var err error
+		ok, err = o.store.Delete(ctx, name)
+		if err != nil {
+			return false, err
+		}
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, had := o.cur[name]; had {
+		ok = true
+	}
+	delete(o.cur, name)
+	return ok, nil
+}
@@ -0,0 +1,72 @@
+package updaters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Store persists updater overrides in the matcher's database.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the database behind pool, which must have
+// had the updater migrations applied.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+const putOverride = `
+INSERT INTO updater_override (name, disabled, config) VALUES ($1, $2, $3)
+ON CONFLICT (name) DO UPDATE SET disabled = EXCLUDED.disabled, config = EXCLUDED.config, updated = now();`
+
+// Put stores the override for the named updater or updater set, replacing any
+// existing one.
+func (s *Store) Put(ctx context.Context, name string, o Override) error {
+	var cfg []byte
+	if len(o.Config) != 0 {
+		cfg = o.Config
+	}
+	if _, err := s.pool.Exec(ctx, putOverride, name, o.Disabled, cfg); err != nil {
+		return fmt.Errorf("updaters: failed to store override: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the override for the name, reporting whether it existed.
+func (s *Store) Delete(ctx context.Context, name string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM updater_override WHERE name = $1;`, name)
+	if err != nil {
+		return false, fmt.Errorf("updaters: failed to delete override: %w", err)
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+// Overrides returns every stored override, keyed by name.
+func (s *Store) Overrides(ctx context.Context) (map[string]Override, error) {
+	rows, err := s.pool.Query(ctx, `SELECT name, disabled, config FROM updater_override;`)
+	if err != nil {
+		return nil, fmt.Errorf("updaters: failed to load overrides: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string]Override)
+	for rows.Next() {
+		var name string
+		var o Override
+		var cfg []byte
+		if err := rows.Scan(&name, &o.Disabled, &cfg); err != nil {
+			return nil, fmt.Errorf("updaters: failed to load overrides: %w", err)
+		}
+		if len(cfg) != 0 {
+			o.Config = json.RawMessage(cfg)
+		}
+		out[name] = o
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("updaters: failed to load overrides: %w", err)
+	}
+	return out, nil
+}
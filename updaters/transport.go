@@ -0,0 +1,324 @@
+package updaters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Shared holds the bandwidth limit and response cache common to every
+// updater's HTTP client, so they're shared however many clients there are.
+type Shared struct {
+	bucket *bucket
+	dir    string
+}
+
+// NewShared returns a Shared limiting updaters to maxBandwidth bytes per
+// second, if positive, and caching responses in dir, if not empty. The
+// directory is created if needed.
+func NewShared(dir string, maxBandwidth int64) (*Shared, error) {
+	s := &Shared{dir: dir}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("updaters: unable to create cache directory: %w", err)
+		}
+	}
+	if maxBandwidth > 0 {
+		s.bucket = &bucket{rate: float64(maxBandwidth)}
+	}
+	return s, nil
+}
+
+// Wrap returns next limited and cached by s. A nil next means
+// http.DefaultTransport.
+func (s *Shared) Wrap(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if s.bucket != nil {
+		next = &throttle{next: next, b: s.bucket}
+	}
+	if s.dir != "" {
+		next = &cache{next: next, dir: s.dir}
+	}
+	return next
+}
+
+// Client returns a client using http.DefaultTransport, wrapped by s.
+func (s *Shared) Client() *http.Client {
+	return &http.Client{Transport: s.Wrap(nil)}
+}
+
+// Bucket spaces out reads to keep to a rate, in bytes per second.
+//
+// Every read reserves the time its bytes take at the rate, after whatever
+// has already been reserved, and waits out the reservation.
+type bucket struct {
+	rate float64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// Wait blocks until n bytes fit in the rate, or the context is done.
+func (b *bucket) wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	d := b.next.Sub(now)
+	b.next = b.next.Add(time.Duration(float64(n) / b.rate * float64(time.Second)))
+	b.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Chunk is the most that's read from a throttled body at once, so a large
+// read doesn't burst past the rate.
+func (b *bucket) chunk() int {
+	n := int(b.rate / 10)
+	switch {
+	case n < 512:
+		n = 512
+	case n > 32*1024:
+		n = 32 * 1024
+	}
+	return n
+}
+
+// Throttle is an http.RoundTripper limiting how fast response bodies are
+// read.
+type throttle struct {
+	next http.RoundTripper
+	b    *bucket
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *throttle) RoundTrip(r *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = &throttledBody{ReadCloser: res.Body, ctx: r.Context(), b: t.b}
+	return res, nil
+}
+
+type throttledBody struct {
+	io.ReadCloser
+	ctx context.Context
+	b   *bucket
+}
+
+// Read implements io.Reader.
+func (t *throttledBody) Read(p []byte) (int, error) {
+	if max := t.b.chunk(); len(p) > max {
+		p = p[:max]
+	}
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := t.b.wait(t.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// Cache is an http.RoundTripper caching GET responses with validators on
+// disk, and revalidating them on later requests.
+//
+// Requests with their own conditional headers are passed through untouched,
+// as the updater is already keeping track of what it has.
+type cache struct {
+	next http.RoundTripper
+	dir  string
+}
+
+// CacheEntry is the metadata kept next to a cached body.
+type cacheEntry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *cache) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet ||
+		r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" ||
+		r.Header.Get("Range") != "" {
+		return c.next.RoundTrip(r)
+	}
+	key := c.key(r.URL.String())
+	ent, ok := c.load(key)
+	if ok && ent.URL == r.URL.String() {
+		r = r.Clone(r.Context())
+		if ent.ETag != "" {
+			r.Header.Set("If-None-Match", ent.ETag)
+		}
+		if ent.LastModified != "" {
+			r.Header.Set("If-Modified-Since", ent.LastModified)
+		}
+	} else {
+		ok = false
+	}
+	res, err := c.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case ok && res.StatusCode == http.StatusNotModified:
+		f, err := os.Open(filepath.Join(c.dir, key))
+		if err != nil {
+			// The body went missing; the caller gets the 304 it didn't ask
+			// for, and the next run fetches it again.
+			os.Remove(filepath.Join(c.dir, key+".json"))
+			return res, nil
+		}
+		res.Body.Close()
+		h := ent.Header.Clone()
+		for _, k := range []string{"Date", "Expires", "Cache-Control"} {
+			if v := res.Header.Get(k); v != "" {
+				h.Set(k, v)
+			}
+		}
+		out := &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Proto:      res.Proto,
+			ProtoMajor: res.ProtoMajor,
+			ProtoMinor: res.ProtoMinor,
+			Header:     h,
+			Body:       f,
+			Request:    res.Request,
+		}
+		if fi, err := f.Stat(); err == nil {
+			out.ContentLength = fi.Size()
+		}
+		return out, nil
+	case res.StatusCode == http.StatusOK:
+		e := cacheEntry{
+			URL:          r.URL.String(),
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			Header:       res.Header.Clone(),
+		}
+		if e.ETag == "" && e.LastModified == "" {
+			return res, nil
+		}
+		// Transparently decompressed bodies no longer match their headers.
+		if res.Uncompressed {
+			e.Header.Del("Content-Encoding")
+			e.Header.Del("Content-Length")
+		}
+		tmp, err := ioutil.TempFile(c.dir, key+".*.tmp")
+		if err != nil {
+			return res, nil
+		}
+		res.Body = &cacheFill{ReadCloser: res.Body, c: c, key: key, e: &e, f: tmp}
+	}
+	return res, nil
+}
+
+// Key names the cache files for a URL.
+func (c *cache) key(u string) string {
+	s := sha256.Sum256([]byte(u))
+	return hex.EncodeToString(s[:])
+}
+
+// Load reads the metadata for key, reporting whether it's present.
+func (c *cache) load(key string) (*cacheEntry, bool) {
+	b, err := ioutil.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, false
+	}
+	return &e, true
+}
+
+// CacheFill copies a response body into the cache as it's read. The entry
+// is only stored if the body is read to the end.
+type cacheFill struct {
+	io.ReadCloser
+	c    *cache
+	key  string
+	e    *cacheEntry
+	f    *os.File
+	done bool
+	bad  bool
+}
+
+// Read implements io.Reader.
+func (c *cacheFill) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if n > 0 && !c.bad {
+		if _, werr := c.f.Write(p[:n]); werr != nil {
+			c.bad = true
+		}
+	}
+	if err == io.EOF {
+		c.done = true
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (c *cacheFill) Close() error {
+	err := c.ReadCloser.Close()
+	name := c.f.Name()
+	if cerr := c.f.Close(); cerr != nil {
+		c.bad = true
+	}
+	if !c.done || c.bad || c.commit(name) != nil {
+		os.Remove(name)
+	}
+	return err
+}
+
+// Commit moves the body into place and writes its metadata. The metadata
+// is written last, so an entry is never used without its body.
+func (c *cacheFill) commit(name string) error {
+	dir, key := c.c.dir, c.key
+	os.Remove(filepath.Join(dir, key+".json"))
+	if err := os.Rename(name, filepath.Join(dir, key)); err != nil {
+		return err
+	}
+	b, err := json.Marshal(c.e)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, key+".json"))
+}
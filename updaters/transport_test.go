@@ -0,0 +1,122 @@
+package updaters
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "updaters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const body = "vulnerability database"
+	var full, unchanged int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			full++
+			w.Write([]byte(body))
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			unchanged++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	s, err := NewShared(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := s.Client()
+	get := func(path string) string {
+		t.Helper()
+		res, err := c.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	for i := 0; i < 3; i++ {
+		if got := get("/db"); got != body {
+			t.Errorf("%d: got: %q, want: %q", i, got, body)
+		}
+	}
+	if full != 1 || unchanged != 2 {
+		t.Errorf("got %d full and %d unchanged responses, want 1 and 2", full, unchanged)
+	}
+
+	// Responses without validators aren't cached.
+	full = 0
+	get("/plain")
+	get("/plain")
+	if full != 2 {
+		t.Errorf("got %d full responses, want 2", full)
+	}
+
+	// Updaters tracking their own validators are left alone.
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/db", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("If-None-Match", `"v1"`)
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusNotModified; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	body := strings.Repeat("x", 2048)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	s, err := NewShared("", 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := s.Client()
+	start := time.Now()
+	// Two bodies at 4 KiB/s take a second, less the first read.
+	for i := 0; i < 2; i++ {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != len(body) {
+			t.Fatalf("got %d bytes, want %d", len(b), len(body))
+		}
+	}
+	if d := time.Since(start); d < 700*time.Millisecond {
+		t.Errorf("read 4 KiB at 4 KiB/s in %v", d)
+	}
+}
@@ -0,0 +1,255 @@
+package updaters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/updater"
+)
+
+type testUpdater struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func (u *testUpdater) Name() string { return u.name }
+
+func (u *testUpdater) Fetch(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	return nil, "", driver.Unchanged
+}
+
+func (u *testUpdater) Parse(context.Context, io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	return nil, nil
+}
+
+func (u *testUpdater) Configure(_ context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	var cfg struct {
+		URL string `json:"url"`
+	}
+	if err := f(&cfg); err != nil {
+		return err
+	}
+	u.url = cfg.URL
+	u.client = c
+	return nil
+}
+
+type testFactory struct {
+	url      string
+	updaters []*testUpdater
+}
+
+func (f *testFactory) UpdaterSet(context.Context) (driver.UpdaterSet, error) {
+	s := driver.NewUpdaterSet()
+	for _, u := range f.updaters {
+		if err := s.Add(u); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+func (f *testFactory) Configure(_ context.Context, cfg driver.ConfigUnmarshaler, _ *http.Client) error {
+	var fc struct {
+		URL string `json:"url" yaml:"url"`
+	}
+	if err := cfg(&fc); err != nil {
+		return err
+	}
+	f.url = fc.URL
+	return nil
+}
+
+func names(t *testing.T, f driver.UpdaterSetFactory) []string {
+	t.Helper()
+	s, err := f.UpdaterSet(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, u := range s.Updaters() {
+		out = append(out, u.Name())
+	}
+	sort.Strings(out)
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestFactory checks that overrides are applied to wrapped factories as they
+// change.
+func TestFactory(t *testing.T) {
+	ctx := context.Background()
+	inner := &testFactory{
+		updaters: []*testUpdater{{name: "one"}, {name: "two"}},
+	}
+	updater.Register("test", inner)
+	o := NewOverrides(ctx, nil)
+	sets := Register(o, nil, []string{"test", "missing"})
+	if got, want := sets, []string{Prefix + "test"}; !equal(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	f := updater.Registered()[Prefix+"test"]
+	static := func(v interface{}) error {
+		return json.Unmarshal([]byte(`{"url":"static"}`), v)
+	}
+	if err := f.(driver.Configurable).Configure(ctx, static, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := inner.url, "static"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	if got, want := names(t, f), []string{"one", "two"}; !equal(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if err := o.Set(ctx, "two", Override{Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(t, f), []string{"one"}; !equal(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if err := o.Set(ctx, "test", Override{Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(t, f); len(got) != 0 {
+		t.Errorf("got: %v, want: none", got)
+	}
+
+	if err := o.Set(ctx, "test", Override{Config: json.RawMessage(`{"url":"override"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Set(ctx, "one", Override{Config: json.RawMessage(`{"url":"updater"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	names(t, f)
+	if got, want := inner.url, "override"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := inner.updaters[0].url, "updater"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	if ok, err := o.Delete(ctx, "test"); err != nil || !ok {
+		t.Fatalf("delete: %v, %v", ok, err)
+	}
+	names(t, f)
+	if got, want := inner.url, "static"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Override Override
+		Valid    bool
+	}{
+		{Name: "ok", Override: Override{Disabled: true}, Valid: true},
+		{Name: "ok", Override: Override{Config: json.RawMessage(`{"url":""}`)}, Valid: true},
+		{Name: "ok", Override: Override{Config: json.RawMessage(`null`)}, Valid: true},
+		{Name: "", Override: Override{}, Valid: false},
+		{Name: "array", Override: Override{Config: json.RawMessage(`[]`)}, Valid: false},
+	}
+	for _, tc := range tt {
+		err := tc.Override.Validate(tc.Name)
+		switch {
+		case tc.Valid && err != nil:
+			t.Errorf("%q: unexpected error: %v", tc.Name, err)
+		case !tc.Valid && !errors.Is(err, ErrInvalid):
+			t.Errorf("%q: got: %v, want: %v", tc.Name, err, ErrInvalid)
+		}
+	}
+}
+
+// ChangingUpdater is a testUpdater whose data changes on every other fetch.
+type changingUpdater struct {
+	testUpdater
+	fetches int
+}
+
+func (u *changingUpdater) Fetch(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	u.fetches++
+	if u.fetches%2 == 0 {
+		return nil, "", driver.Unchanged
+	}
+	return ioutil.NopCloser(strings.NewReader("")), "", nil
+}
+
+func (u *changingUpdater) Parse(context.Context, io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	return []*claircore.Vulnerability{{Name: "one"}, {Name: "two"}}, nil
+}
+
+// TestInstrumented checks that runs are recorded, keeping the vulnerability
+// count across runs where the data didn't change.
+func TestInstrumented(t *testing.T) {
+	ctx := context.Background()
+	m := &freshnessMetrics{runs: make(map[string]run)}
+
+	u := &instrumented{Updater: &testUpdater{name: "unchanged"}, m: m}
+	if _, _, err := u.Fetch(ctx, ""); !errors.Is(err, driver.Unchanged) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, ok := m.get("unchanged")
+	if !ok {
+		t.Fatal("run not recorded")
+	}
+	if r.Vulns != -1 {
+		t.Errorf("got: %d vulnerabilities, want: none reported", r.Vulns)
+	}
+
+	u = &instrumented{Updater: &changingUpdater{testUpdater: testUpdater{name: "changing"}}, m: m}
+	rc, _, err := u.Fetch(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.get("changing"); ok {
+		t.Error("run recorded before parsing")
+	}
+	if _, err := u.Parse(ctx, rc); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := m.get("changing")
+	if got, want := first.Vulns, int64(2); got != want {
+		t.Errorf("got: %d vulnerabilities, want: %d", got, want)
+	}
+	if _, _, err := u.Fetch(ctx, ""); !errors.Is(err, driver.Unchanged) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := m.get("changing")
+	if got, want := second.Vulns, int64(2); got != want {
+		t.Errorf("got: %d vulnerabilities, want: %d", got, want)
+	}
+	if second.Finished.Before(first.Finished) {
+		t.Errorf("finish time went backwards: %v, %v", first.Finished, second.Finished)
+	}
+
+	if err := u.Configure(ctx, func(v interface{}) error {
+		return json.Unmarshal([]byte(`{"url":"configured"}`), v)
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.Updater.(*changingUpdater).url, "configured"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
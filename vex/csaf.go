@@ -0,0 +1,161 @@
+package vex
+
+import (
+	"encoding/json"
+)
+
+// CSAF documents are described at
+// https://docs.oasis-open.org/csaf/csaf/v2.0/csaf-v2.0.html. Only the parts
+// needed to recover VEX statements are decoded.
+type csafDoc struct {
+	Document struct {
+		Tracking struct {
+			ID string `json:"id"`
+		} `json:"tracking"`
+	} `json:"document"`
+	ProductTree struct {
+		Branches         []csafBranch       `json:"branches"`
+		FullProductNames []csafProduct      `json:"full_product_names"`
+		Relationships    []csafRelationship `json:"relationships"`
+	} `json:"product_tree"`
+	Vulnerabilities []struct {
+		CVE string `json:"cve"`
+		IDs []struct {
+			Text string `json:"text"`
+		} `json:"ids"`
+		ProductStatus map[string][]string `json:"product_status"`
+		Flags         []struct {
+			Label      string   `json:"label"`
+			ProductIDs []string `json:"product_ids"`
+		} `json:"flags"`
+		Threats []struct {
+			Category   string   `json:"category"`
+			Details    string   `json:"details"`
+			ProductIDs []string `json:"product_ids"`
+		} `json:"threats"`
+	} `json:"vulnerabilities"`
+}
+
+type csafBranch struct {
+	Branches []csafBranch `json:"branches"`
+	Product  *csafProduct `json:"product"`
+}
+
+type csafProduct struct {
+	ProductID string `json:"product_id"`
+	Name      string `json:"name"`
+	Helper    struct {
+		PURL string `json:"purl"`
+		CPE  string `json:"cpe"`
+	} `json:"product_identification_helper"`
+}
+
+type csafRelationship struct {
+	Product   string      `json:"product_reference"`
+	RelatesTo string      `json:"relates_to_product_reference"`
+	Full      csafProduct `json:"full_product_name"`
+}
+
+// CsafStatus maps CSAF product status categories to Statuses.
+var csafStatus = []struct {
+	category string
+	status   Status
+}{
+	{"known_not_affected", NotAffected},
+	{"known_affected", Affected},
+	{"fixed", Fixed},
+	{"under_investigation", UnderInvestigation},
+}
+
+func parseCSAF(b []byte) (*Document, error) {
+	var doc csafDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	// Resolve CSAF's product IDs to Products.
+	products := make(map[string]Product)
+	var walk func([]csafBranch)
+	walk = func(bs []csafBranch) {
+		for _, b := range bs {
+			if b.Product != nil {
+				products[b.Product.ProductID] = Product{ID: b.Product.identifier()}
+			}
+			walk(b.Branches)
+		}
+	}
+	walk(doc.ProductTree.Branches)
+	for _, p := range doc.ProductTree.FullProductNames {
+		products[p.ProductID] = Product{ID: p.identifier()}
+	}
+	for _, r := range doc.ProductTree.Relationships {
+		products[r.Full.ProductID] = Product{
+			ID:            products[r.RelatesTo].ID,
+			Subcomponents: []string{products[r.Product].ID},
+		}
+	}
+
+	d := &Document{ID: doc.Document.Tracking.ID}
+	for _, v := range doc.Vulnerabilities {
+		name := v.CVE
+		var aliases []string
+		for _, id := range v.IDs {
+			if name == "" {
+				name = id.Text
+				continue
+			}
+			aliases = append(aliases, id.Text)
+		}
+		if name == "" {
+			continue
+		}
+		justification := make(map[string]string)
+		for _, f := range v.Flags {
+			for _, id := range f.ProductIDs {
+				justification[id] = f.Label
+			}
+		}
+		impact := make(map[string]string)
+		for _, t := range v.Threats {
+			if t.Category != "impact" {
+				continue
+			}
+			for _, id := range t.ProductIDs {
+				impact[id] = t.Details
+			}
+		}
+		for _, cs := range csafStatus {
+			status := cs.status
+			ids := v.ProductStatus[cs.category]
+			// CSAF allows different justifications per product, so emit a
+			// statement per product.
+			for _, id := range ids {
+				p, ok := products[id]
+				if !ok || p.ID == "" {
+					p = Product{ID: id}
+				}
+				d.Statements = append(d.Statements, Statement{
+					Vulnerability: name,
+					Aliases:       aliases,
+					Products:      []Product{p},
+					Status:        status,
+					Justification: justification[id],
+					Impact:        impact[id],
+				})
+			}
+		}
+	}
+	return d, nil
+}
+
+// Identifier returns the most specific identifier for the product.
+func (p *csafProduct) identifier() string {
+	switch {
+	case p.Helper.PURL != "":
+		return p.Helper.PURL
+	case p.Helper.CPE != "":
+		return p.Helper.CPE
+	default:
+		return p.Name
+	}
+}
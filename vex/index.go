@@ -0,0 +1,221 @@
+package vex
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/quay/claircore"
+)
+
+// Index organizes NotAffected Statements for matching against reports.
+//
+// Statements with other statuses don't suppress anything, so they're not
+// indexed.
+type Index struct {
+	byVuln map[string][]*Statement
+}
+
+// NewIndex returns an Index of the Documents' statements.
+func NewIndex(docs ...*Document) *Index {
+	x := &Index{
+		byVuln: make(map[string][]*Statement),
+	}
+	for _, d := range docs {
+		for i := range d.Statements {
+			s := &d.Statements[i]
+			if s.Status != NotAffected {
+				continue
+			}
+			for _, n := range append([]string{s.Vulnerability}, s.Aliases...) {
+				n = strings.ToUpper(n)
+				x.byVuln[n] = append(x.byVuln[n], s)
+			}
+		}
+	}
+	return x
+}
+
+// Len reports the number of indexed statements, counting once per name.
+func (x *Index) Len() int {
+	n := 0
+	for _, ss := range x.byVuln {
+		n += len(ss)
+	}
+	return n
+}
+
+// Suppression records that a Statement marks a package as not affected by a
+// vulnerability in a report.
+type Suppression struct {
+	PackageID       string     `json:"package_id"`
+	VulnerabilityID string     `json:"vulnerability_id"`
+	Statement       *Statement `json:"statement"`
+}
+
+// Match returns the report's package and vulnerability pairs marked not
+// affected.
+func (x *Index) Match(vr *claircore.VulnerabilityReport) []Suppression {
+	if x == nil || len(x.byVuln) == 0 {
+		return nil
+	}
+	var out []Suppression
+	for pkgID, vulnIDs := range vr.PackageVulnerabilities {
+		pkg, ok := vr.Packages[pkgID]
+		if !ok {
+			continue
+		}
+	Vulns:
+		for _, vulnID := range vulnIDs {
+			v, ok := vr.Vulnerabilities[vulnID]
+			if !ok {
+				continue
+			}
+			for _, name := range names(v) {
+				for _, s := range x.byVuln[name] {
+					if s.applies(vr.Hash, pkg) {
+						out = append(out, Suppression{
+							PackageID:       pkgID,
+							VulnerabilityID: vulnID,
+							Statement:       s,
+						})
+						continue Vulns
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Names returns the candidate names for a vulnerability: its name, and any
+// identifier-looking words in its name or links. Updaters don't agree on
+// where the CVE ID goes.
+func names(v *claircore.Vulnerability) []string {
+	out := []string{strings.ToUpper(v.Name)}
+	split := func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-')
+	}
+	for _, f := range [...]string{v.Name, v.Links} {
+		for _, w := range strings.FieldsFunc(f, split) {
+			if strings.Count(w, "-") != 0 {
+				out = append(out, strings.ToUpper(w))
+			}
+		}
+	}
+	return out
+}
+
+// Applies reports whether the Statement covers the package in the manifest.
+func (s *Statement) applies(manifest claircore.Digest, pkg *claircore.Package) bool {
+	digest := manifest.String()
+	for _, p := range s.Products {
+		id, err := url.PathUnescape(p.ID)
+		if err != nil {
+			id = p.ID
+		}
+		candidates := p.Subcomponents
+		switch {
+		case digest != "" && strings.Contains(id, digest):
+			if len(candidates) == 0 {
+				// The statement covers the whole image.
+				return true
+			}
+		case strings.Contains(id, "sha256:"):
+			// Some other image.
+			continue
+		case len(candidates) == 0:
+			candidates = []string{p.ID}
+		}
+		for _, c := range candidates {
+			if componentMatch(c, pkg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ComponentMatch reports whether the identifier names the package, or its
+// source package. Package URLs without a version match any version.
+func componentMatch(id string, pkg *claircore.Package) bool {
+	name, version := id, ""
+	switch {
+	case strings.HasPrefix(id, "pkg:"):
+		name, version = parsePURL(id)
+	case strings.HasPrefix(id, "cpe:"):
+		return false
+	}
+	if name == "" {
+		return false
+	}
+	check := func(p *claircore.Package) bool {
+		return p != nil && p.Name == name && (version == "" || p.Version == version)
+	}
+	return check(pkg) || check(pkg.Source)
+}
+
+// ParsePURL returns the name and version from a package URL, like
+// "pkg:deb/debian/openssl@1.1.1n-0+deb11u3?arch=amd64".
+func parsePURL(p string) (name, version string) {
+	p = strings.TrimPrefix(p, "pkg:")
+	if i := strings.IndexByte(p, '#'); i != -1 {
+		p = p[:i]
+	}
+	if i := strings.IndexByte(p, '?'); i != -1 {
+		p = p[:i]
+	}
+	if i := strings.LastIndexByte(p, '@'); i != -1 {
+		p, version = p[:i], p[i+1:]
+	}
+	if i := strings.LastIndexByte(p, '/'); i != -1 {
+		p = p[i+1:]
+	}
+	name, err := url.PathUnescape(p)
+	if err != nil {
+		name = p
+	}
+	if v, err := url.PathUnescape(version); err == nil {
+		version = v
+	}
+	return name, version
+}
+
+// Filter removes the suppressed package and vulnerability pairs from the
+// report, along with any vulnerabilities no longer referenced.
+func Filter(vr *claircore.VulnerabilityReport, ss []Suppression) {
+	if len(ss) == 0 {
+		return
+	}
+	drop := make(map[string]map[string]struct{})
+	for _, s := range ss {
+		m, ok := drop[s.PackageID]
+		if !ok {
+			m = make(map[string]struct{})
+			drop[s.PackageID] = m
+		}
+		m[s.VulnerabilityID] = struct{}{}
+	}
+	used := make(map[string]struct{})
+	for pkgID, vulnIDs := range vr.PackageVulnerabilities {
+		m := drop[pkgID]
+		keep := vulnIDs[:0]
+		for _, id := range vulnIDs {
+			if _, ok := m[id]; ok {
+				continue
+			}
+			keep = append(keep, id)
+			used[id] = struct{}{}
+		}
+		if len(keep) == 0 {
+			delete(vr.PackageVulnerabilities, pkgID)
+			continue
+		}
+		vr.PackageVulnerabilities[pkgID] = keep
+	}
+	for id := range vr.Vulnerabilities {
+		if _, ok := used[id]; !ok {
+			delete(vr.Vulnerabilities, id)
+		}
+	}
+}
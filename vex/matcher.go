@@ -0,0 +1,181 @@
+package vex
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/quay/claircore"
+	"github.com/rs/zerolog"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+	"github.com/quay/clair/v4/matcher"
+)
+
+// RefreshInterval is how often a Matcher reloads stored documents, to pick up
+// changes made through other matcher instances.
+const RefreshInterval = time.Minute
+
+// ErrNoStore is returned when modifying documents of a Matcher without a
+// Store.
+var ErrNoStore = clairerror.New(clairerror.Unimplemented, "vex: no document store configured")
+
+// Matcher wraps a matcher.Service and applies VEX statements to its
+// vulnerability reports.
+//
+// In filter mode, report entries marked not affected are removed. Otherwise,
+// reports are left alone and Annotations reports the entries instead.
+type Matcher struct {
+	matcher.Service
+	store  *Store
+	static []*Document
+	filter bool
+
+	mu     sync.RWMutex
+	stored []*Document
+	index  *Index
+	loaded time.Time
+	fp     string
+}
+
+var _ matcher.Service = (*Matcher)(nil)
+
+// NewMatcher returns a Matcher using the static documents and, if store is
+// not nil, the documents in the Store.
+func NewMatcher(ctx context.Context, s matcher.Service, store *Store, static []*Document, filter bool) *Matcher {
+	m := &Matcher{
+		Service: s,
+		store:   store,
+		static:  static,
+		filter:  filter,
+	}
+	m.refresh(ctx, true)
+	return m
+}
+
+// Unwrap returns the wrapped matcher.Service.
+func (m *Matcher) Unwrap() matcher.Service {
+	return m.Service
+}
+
+// Refresh rebuilds the Index if it's stale, or unconditionally if force is
+// set.
+func (m *Matcher) refresh(ctx context.Context, force bool) {
+	m.mu.RLock()
+	fresh := m.index != nil && time.Since(m.loaded) < RefreshInterval
+	m.mu.RUnlock()
+	if fresh && !force {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !force && m.index != nil && time.Since(m.loaded) < RefreshInterval {
+		return
+	}
+	if m.store != nil {
+		docs, err := m.store.Documents(ctx)
+		if err != nil {
+			// Keep using what was loaded before.
+			zerolog.Ctx(ctx).Warn().
+				Str("component", "vex/Matcher.refresh").
+				Err(err).
+				Msg("failed to load vex documents")
+		} else {
+			m.stored = docs
+		}
+	}
+	docs := make([]*Document, 0, len(m.static)+len(m.stored))
+	docs = append(docs, m.static...)
+	docs = append(docs, m.stored...)
+	m.index = NewIndex(docs...)
+	m.loaded = time.Now()
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(docs); err == nil {
+		m.fp = hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+func (m *Matcher) suppressions(ctx context.Context, vr *claircore.VulnerabilityReport) []Suppression {
+	m.refresh(ctx, false)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.index.Match(vr)
+}
+
+// Scan implements matcher.Scanner.
+func (m *Matcher) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	vr, err := m.Service.Scan(ctx, ir)
+	if err != nil || !m.filter {
+		return vr, err
+	}
+	ss := m.suppressions(ctx, vr)
+	if len(ss) != 0 {
+		zerolog.Ctx(ctx).Debug().
+			Str("component", "vex/Matcher.Scan").
+			Str("manifest", vr.Hash.String()).
+			Int("count", len(ss)).
+			Msg("suppressing not affected vulnerabilities")
+	}
+	Filter(vr, ss)
+	return vr, nil
+}
+
+// Annotations reports the entries of the report that VEX statements mark as
+// not affected. It returns nil in filter mode, as those entries have already
+// been removed.
+func (m *Matcher) Annotations(ctx context.Context, vr *claircore.VulnerabilityReport) []Suppression {
+	if m.filter {
+		return nil
+	}
+	return m.suppressions(ctx, vr)
+}
+
+// Fingerprint returns a digest of the documents in use, which changes
+// whenever they do.
+func (m *Matcher) Fingerprint(ctx context.Context) string {
+	m.refresh(ctx, false)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fp
+}
+
+// Documents returns every document in use.
+func (m *Matcher) Documents(ctx context.Context) []*Document {
+	m.refresh(ctx, false)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]*Document, 0, len(m.static)+len(m.stored))
+	out = append(out, m.static...)
+	out = append(out, m.stored...)
+	return out
+}
+
+// Put stores a document and starts using it.
+func (m *Matcher) Put(ctx context.Context, b []byte) (*Document, error) {
+	if m.store == nil {
+		return nil, ErrNoStore
+	}
+	d, err := m.store.Put(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	m.refresh(ctx, true)
+	return d, nil
+}
+
+// Delete removes a stored document, reporting whether it existed.
+func (m *Matcher) Delete(ctx context.Context, id string) (bool, error) {
+	if m.store == nil {
+		return false, ErrNoStore
+	}
+	ok, err := m.store.Delete(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	m.refresh(ctx, true)
+	return ok, nil
+}
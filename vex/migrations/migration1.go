@@ -0,0 +1,15 @@
+package migrations
+
+const (
+	// migration1 adds storage for uploaded VEX documents.
+	migration1 = `
+	--- a relation holding VEX documents, as uploaded
+	CREATE TABLE IF NOT EXISTS vex_document
+	(
+		id       text PRIMARY KEY,
+		format   text        NOT NULL,
+		document bytea       NOT NULL,
+		added    timestamptz NOT NULL DEFAULT now()
+	);
+	`
+)
@@ -0,0 +1,86 @@
+package vex
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OpenVEX documents are described at https://github.com/openvex/spec.
+//
+// Both the current form, where vulnerabilities and products are objects, and
+// the earlier form, where they're strings, are accepted.
+type openvexDoc struct {
+	ID         string             `json:"@id"`
+	Statements []openvexStatement `json:"statements"`
+}
+
+type openvexStatement struct {
+	Vulnerability openvexVuln      `json:"vulnerability"`
+	Products      []openvexProduct `json:"products"`
+	Subcomponents []openvexProduct `json:"subcomponents"`
+	Status        string           `json:"status"`
+	Justification string           `json:"justification"`
+	Impact        string           `json:"impact_statement"`
+}
+
+type openvexVuln struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+func (v *openvexVuln) UnmarshalJSON(b []byte) error {
+	if len(b) != 0 && b[0] == '"' {
+		return json.Unmarshal(b, &v.Name)
+	}
+	type plain openvexVuln
+	return json.Unmarshal(b, (*plain)(v))
+}
+
+type openvexProduct struct {
+	ID            string           `json:"@id"`
+	Subcomponents []openvexProduct `json:"subcomponents"`
+}
+
+func (p *openvexProduct) UnmarshalJSON(b []byte) error {
+	if len(b) != 0 && b[0] == '"' {
+		return json.Unmarshal(b, &p.ID)
+	}
+	type plain openvexProduct
+	return json.Unmarshal(b, (*plain)(p))
+}
+
+func parseOpenVEX(b []byte) (*Document, error) {
+	var doc openvexDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	d := &Document{
+		ID:         doc.ID,
+		Statements: make([]Statement, 0, len(doc.Statements)),
+	}
+	for i, s := range doc.Statements {
+		if s.Vulnerability.Name == "" {
+			return nil, fmt.Errorf("statement %d: missing vulnerability", i)
+		}
+		st := Statement{
+			Vulnerability: s.Vulnerability.Name,
+			Aliases:       s.Vulnerability.Aliases,
+			Status:        Status(s.Status),
+			Justification: s.Justification,
+			Impact:        s.Impact,
+		}
+		for _, p := range s.Products {
+			prod := Product{ID: p.ID}
+			for _, sub := range p.Subcomponents {
+				prod.Subcomponents = append(prod.Subcomponents, sub.ID)
+			}
+			// Statement-level subcomponents apply to every product.
+			for _, sub := range s.Subcomponents {
+				prod.Subcomponents = append(prod.Subcomponents, sub.ID)
+			}
+			st.Products = append(st.Products, prod)
+		}
+		d.Statements = append(d.Statements, st)
+	}
+	return d, nil
+}
@@ -0,0 +1,70 @@
+package vex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Store persists uploaded VEX documents in the matcher's database.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore returns a Store using the database behind pool, which must have
+// had the vex migrations applied.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+const putDocument = `
+INSERT INTO vex_document (id, format, document) VALUES ($1, $2, $3)
+ON CONFLICT (id) DO UPDATE SET format = EXCLUDED.format, document = EXCLUDED.document, added = now();`
+
+// Put parses and stores a document, replacing any with the same ID.
+func (s *Store) Put(ctx context.Context, b []byte) (*Document, error) {
+	d, err := Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.pool.Exec(ctx, putDocument, d.ID, string(d.Format), b); err != nil {
+		return nil, fmt.Errorf("vex: failed to store document: %w", err)
+	}
+	return d, nil
+}
+
+// Delete removes the document with the ID, reporting whether it existed.
+func (s *Store) Delete(ctx context.Context, id string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM vex_document WHERE id = $1;`, id)
+	if err != nil {
+		return false, fmt.Errorf("vex: failed to delete document: %w", err)
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+// Documents returns every stored document.
+func (s *Store) Documents(ctx context.Context) ([]*Document, error) {
+	rows, err := s.pool.Query(ctx, `SELECT id, document FROM vex_document ORDER BY added;`)
+	if err != nil {
+		return nil, fmt.Errorf("vex: failed to load documents: %w", err)
+	}
+	defer rows.Close()
+	var out []*Document
+	for rows.Next() {
+		var id string
+		var b []byte
+		if err := rows.Scan(&id, &b); err != nil {
+			return nil, fmt.Errorf("vex: failed to load documents: %w", err)
+		}
+		d, err := Parse(b)
+		if err != nil {
+			return nil, fmt.Errorf("stored document %q: %w", id, err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("vex: failed to load documents: %w", err)
+	}
+	return out, nil
+}
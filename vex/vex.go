@@ -0,0 +1,134 @@
+// Package vex applies Vulnerability Exploitability eXchange statements to
+// vulnerability reports, so that findings a vendor has declared not
+// applicable can be suppressed.
+//
+// OpenVEX and CSAF VEX documents are supported.
+package vex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	clairerror "github.com/quay/clair/v4/clair-error"
+)
+
+// Status is the VEX status of a vulnerability in a product.
+type Status string
+
+// These are the statuses common to OpenVEX and CSAF.
+const (
+	NotAffected        Status = "not_affected"
+	Affected           Status = "affected"
+	Fixed              Status = "fixed"
+	UnderInvestigation Status = "under_investigation"
+)
+
+// Format is the kind of a VEX document.
+type Format string
+
+// These are the supported document formats.
+const (
+	OpenVEX Format = "openvex"
+	CSAF    Format = "csaf"
+)
+
+// Document is a parsed VEX document.
+type Document struct {
+	// ID is the document's own identifier, or a digest of its contents if it
+	// has none.
+	ID         string      `json:"id"`
+	Format     Format      `json:"format"`
+	Statements []Statement `json:"statements"`
+}
+
+// Statement is a single claim about a vulnerability's effect on some
+// products.
+type Statement struct {
+	// Vulnerability is the vulnerability's name, e.g. a CVE ID.
+	Vulnerability string `json:"vulnerability"`
+	// Aliases are other names for the vulnerability.
+	Aliases       []string  `json:"aliases,omitempty"`
+	Products      []Product `json:"products"`
+	Status        Status    `json:"status"`
+	Justification string    `json:"justification,omitempty"`
+	// Impact is a free-form explanation of the status.
+	Impact string `json:"impact_statement,omitempty"`
+	// Document is the ID of the Document the Statement came from.
+	Document string `json:"document"`
+}
+
+// Product identifies what a Statement is about.
+//
+// The ID is a package URL, a name, or some other identifier. If the ID names
+// a container image by digest, the Subcomponents (if any) narrow the
+// statement to the packages in the image with those identifiers.
+type Product struct {
+	ID            string   `json:"id"`
+	Subcomponents []string `json:"subcomponents,omitempty"`
+}
+
+// ErrUnknownFormat is returned by Parse, wrapped in a ParseError, for
+// documents that aren't OpenVEX or CSAF.
+var ErrUnknownFormat = errors.New("unknown document format")
+
+// ParseError is returned by Parse for malformed documents.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "vex: " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is clairerror.Invalid.
+func (e *ParseError) Is(target error) bool {
+	return target == clairerror.Invalid
+}
+
+// Parse parses an OpenVEX or CSAF VEX document.
+func Parse(b []byte) (*Document, error) {
+	var probe struct {
+		Context  json.RawMessage `json:"@context"`
+		Document *struct {
+			CSAFVersion string `json:"csaf_version"`
+		} `json:"document"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, &ParseError{fmt.Errorf("invalid document: %w", err)}
+	}
+	var d *Document
+	var f Format
+	var err error
+	switch {
+	case bytes.Contains(probe.Context, []byte("openvex")):
+		f = OpenVEX
+		d, err = parseOpenVEX(b)
+	case probe.Document != nil && probe.Document.CSAFVersion != "":
+		f = CSAF
+		d, err = parseCSAF(b)
+	default:
+		return nil, &ParseError{ErrUnknownFormat}
+	}
+	if err != nil {
+		return nil, &ParseError{fmt.Errorf("invalid %s document: %w", f, err)}
+	}
+	d.Format = f
+	if d.ID == "" {
+		sum := sha256.Sum256(b)
+		d.ID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	for i := range d.Statements {
+		d.Statements[i].Document = d.ID
+		d.Statements[i].Status = Status(strings.ToLower(string(d.Statements[i].Status)))
+	}
+	return d, nil
+}
@@ -0,0 +1,213 @@
+package vex
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+const manifest = "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func load(t *testing.T, name string) *Document {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		file       string
+		id         string
+		format     Format
+		statements int
+		first      Statement
+	}{
+		{
+			file:       "openvex.json",
+			id:         "https://example.com/vex/2023-0001",
+			format:     OpenVEX,
+			statements: 2,
+			first: Statement{
+				Vulnerability: "CVE-2023-0001",
+				Status:        NotAffected,
+				Justification: "vulnerable_code_not_in_execute_path",
+			},
+		},
+		{
+			file:       "openvex-v0.0.1.json",
+			id:         "https://example.com/vex/legacy",
+			format:     OpenVEX,
+			statements: 1,
+			first: Statement{
+				Vulnerability: "CVE-2023-0003",
+				Status:        NotAffected,
+				Justification: "component_not_present",
+			},
+		},
+		{
+			file:       "csaf.json",
+			id:         "EXAMPLE-2023-0004",
+			format:     CSAF,
+			statements: 1,
+			first: Statement{
+				Vulnerability: "CVE-2023-0004",
+				Status:        NotAffected,
+				Justification: "vulnerable_code_not_present",
+				Impact:        "The affected code is not compiled in.",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.file, func(t *testing.T) {
+			d := load(t, tc.file)
+			if got, want := d.ID, tc.id; got != want {
+				t.Errorf("id: got: %q, want: %q", got, want)
+			}
+			if got, want := d.Format, tc.format; got != want {
+				t.Errorf("format: got: %q, want: %q", got, want)
+			}
+			if got, want := len(d.Statements), tc.statements; got != want {
+				t.Fatalf("statements: got: %d, want: %d", got, want)
+			}
+			s := d.Statements[0]
+			if got, want := s.Vulnerability, tc.first.Vulnerability; got != want {
+				t.Errorf("vulnerability: got: %q, want: %q", got, want)
+			}
+			if got, want := s.Status, tc.first.Status; got != want {
+				t.Errorf("status: got: %q, want: %q", got, want)
+			}
+			if got, want := s.Justification, tc.first.Justification; got != want {
+				t.Errorf("justification: got: %q, want: %q", got, want)
+			}
+			if got, want := s.Impact, tc.first.Impact; got != want {
+				t.Errorf("impact: got: %q, want: %q", got, want)
+			}
+			if got, want := s.Document, tc.id; got != want {
+				t.Errorf("document: got: %q, want: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseUnknown(t *testing.T) {
+	_, err := Parse([]byte(`{"spdxVersion":"SPDX-2.3"}`))
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got: %v, want: %v", err, ErrUnknownFormat)
+	}
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Errorf("got: %T, want: *ParseError", err)
+	}
+}
+
+// Report returns a VulnerabilityReport with a vulnerability per package.
+func report() *claircore.VulnerabilityReport {
+	return &claircore.VulnerabilityReport{
+		Hash: claircore.MustParseDigest(manifest),
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1n-0+deb11u3"},
+			"2": {ID: "2", Name: "openssl", Version: "1.1.1n-0+deb11u4"},
+			"3": {ID: "3", Name: "zlib", Version: "1.2.11"},
+			"4": {ID: "4", Name: "libcurl4", Version: "7.74.0", Source: &claircore.Package{Name: "curl", Version: "7.74.0"}},
+			"5": {ID: "5", Name: "libxml2", Version: "2.9.13"},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"a": {ID: "a", Name: "CVE-2023-0001"},
+			"b": {ID: "b", Name: "DSA-0001-1 openssl", Links: "https://security-tracker.debian.org/tracker/GHSA-aaaa-bbbb-cccc"},
+			"c": {ID: "c", Name: "CVE-2023-0002"},
+			"d": {ID: "d", Name: "CVE-2023-0003"},
+			"e": {ID: "e", Name: "EXSA-2023:0004: libxml2 security update", Links: "https://example.com/CVE-2023-0004"},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"a", "b"},
+			"2": {"a"},
+			"3": {"c"},
+			"4": {"d"},
+			"5": {"e"},
+		},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	x := NewIndex(load(t, "openvex.json"), load(t, "openvex-v0.0.1.json"), load(t, "csaf.json"))
+	vr := report()
+	ss := x.Match(vr)
+	got := make(map[string]bool)
+	for _, s := range ss {
+		got[s.PackageID+"/"+s.VulnerabilityID] = true
+	}
+	want := map[string]bool{
+		// the subcomponent of the image, by name and by alias
+		"1/a": true,
+		"1/b": true,
+		// a source package, any version
+		"4/d": true,
+		// a CSAF component of a platform, by a CVE in the links
+		"5/e": true,
+	}
+	for k := range want {
+		if !got[k] {
+			t.Errorf("missing suppression %s", k)
+		}
+	}
+	for k := range got {
+		if !want[k] {
+			t.Errorf("unexpected suppression %s", k)
+		}
+	}
+
+	// Some other image shouldn't get the image-scoped statement.
+	other := report()
+	other.Hash = claircore.MustParseDigest("sha256:" + "0000000000000000000000000000000000000000000000000000000000000000")
+	for _, s := range x.Match(other) {
+		if s.PackageID == "1" {
+			t.Errorf("statement for another image applied: %+v", s)
+		}
+	}
+
+	Filter(vr, ss)
+	if got, want := vr.PackageVulnerabilities["2"], []string{"a"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("package 2: got: %v, want: %v", got, want)
+	}
+	for _, id := range []string{"1", "4", "5"} {
+		if _, ok := vr.PackageVulnerabilities[id]; ok {
+			t.Errorf("package %s: not filtered", id)
+		}
+	}
+	for _, id := range []string{"b", "d", "e"} {
+		if _, ok := vr.Vulnerabilities[id]; ok {
+			t.Errorf("vulnerability %s: not removed", id)
+		}
+	}
+	// "a" is still referenced by package 2.
+	if _, ok := vr.Vulnerabilities["a"]; !ok {
+		t.Error("vulnerability a: removed")
+	}
+}
+
+func TestParsePURL(t *testing.T) {
+	tt := []struct {
+		in, name, version string
+	}{
+		{"pkg:deb/debian/openssl@1.1.1n-0+deb11u3?arch=amd64", "openssl", "1.1.1n-0+deb11u3"},
+		{"pkg:rpm/redhat/libxml2", "libxml2", ""},
+		{"pkg:npm/%40angular/core@12.0.0#sub", "core", "12.0.0"},
+		{"pkg:golang/golang.org/x/net@v0.0.1", "net", "v0.0.1"},
+	}
+	for _, tc := range tt {
+		name, version := parsePURL(tc.in)
+		if name != tc.name || version != tc.version {
+			t.Errorf("%s: got: %q %q, want: %q %q", tc.in, name, version, tc.name, tc.version)
+		}
+	}
+}